@@ -15,6 +15,10 @@ type Container struct {
 	Auth                services.AuthServiceInterface
 	UserSvc             services.UserServiceInterface
 	UserRepo            *services.UserRepository
+	APITokenRepo        *services.APITokenRepository               // 个人访问令牌
+	TokenBlacklist      *services.TokenBlacklist                   // JWT吊销检查
+	NotifyPrefRepo      *services.NotificationPreferenceRepository // 通知偏好
+	NotificationRepo    *services.NotificationRepository           // 通知记录
 	MultiBucket         *services.MultiBucketStorage   // 多桶存储服务（7桶架构）
 	StatsRepo           *services.StatisticsRepository
 	HistoryRepo         *services.HistoryRepository
@@ -24,9 +28,16 @@ type Container struct {
 	PrivateMsgRepo      *services.PrivateMessageRepository
 	ResourceRepo        *services.ResourceRepository
 	ResourceCommentRepo *services.ResourceCommentRepository
+	ContentTransferRepo *services.ContentTransferRepository // 内容所有权转移
+	AuditRepo           *services.AuditRepository           // 管理员操作审计
 	ResourceImageSvc    *services.ResourceImageService // 资源图片服务
 	UploadMgr           *services.UploadManager
-	CacheSvc            *services.CacheService // 缓存服务
+	CacheSvc            *services.CacheService         // 缓存服务
+	HotArticleRefresher *services.HotArticleRefresher  // 热门文章缓存刷新
+	ViewDedup           *services.ViewDeduplicator     // 文章/资源浏览次数去重
+	TokenCleaner        *services.TokenCleaner         // 过期令牌定时清理
+	TempFileSweeper     *services.TempFileSweeper      // temp-files桶过期对象清理
+	StatsAggregator     *services.StatisticsAggregator // 每日统计汇总
 	CodeRepo            services.CodeRepository
 	CodeExecutor        services.CodeExecutor
 	Config              *config.Config // 配置
@@ -36,8 +47,22 @@ type Container struct {
 func New(cfg *config.Config, db *services.Database) (*Container, error) {
 	// 初始化管理员检查器（性能优化）
 	utils.InitAdminChecker(cfg)
+	// 初始化跳转目标白名单
+	utils.InitRedirectValidator(cfg)
+	// 确定新密码哈希使用的bcrypt成本（可选按主机性能校准）
+	bcryptCost := utils.ConfigurePasswordHashing(&cfg.SecurityPassword)
+	utils.GetLogger().Info("bcrypt成本已确定", "cost", bcryptCost, "calibrated", cfg.SecurityPassword.CalibrateCost)
+	// 初始化内容审核（敏感词过滤）
+	if err := utils.ConfigureContentModeration(&cfg.Moderation); err != nil {
+		return nil, fmt.Errorf("内容审核初始化失败: %w", err)
+	}
 
 	userRepo := services.NewUserRepository(db)
+	apiTokenRepo := services.NewAPITokenRepository(db, cfg)
+	refreshTokenRepo := services.NewRefreshTokenRepository(db, cfg)
+	tokenBlacklist := services.NewTokenBlacklist(cfg, userRepo)
+	notifyPrefRepo := services.NewNotificationPreferenceRepository(db, cfg)
+	notificationRepo := services.NewNotificationRepository(db, notifyPrefRepo)
 	statsRepo := services.NewStatisticsRepository(db, cfg)
 	historyRepo := services.NewHistoryRepository(db, cfg)
 	cumulativeRepo := services.NewCumulativeStatsRepository(db, cfg)
@@ -46,7 +71,10 @@ func New(cfg *config.Config, db *services.Database) (*Container, error) {
 	privateMsgRepo := services.NewPrivateMessageRepository(db)
 	resourceRepo := services.NewResourceRepository(db, cfg)
 	resourceCommentRepo := services.NewResourceCommentRepository(db, cfg)
-	authService := services.NewAuthService(cfg, userRepo, historyRepo)
+	contentTransferRepo := services.NewContentTransferRepository(db)
+	auditRepo := services.NewAuditRepository(db, cfg)
+	emailSender := services.NewEmailSender(&cfg.SMTP)
+	authService := services.NewAuthService(cfg, userRepo, historyRepo, refreshTokenRepo, tokenBlacklist, emailSender)
 	userService := services.NewUserService(userRepo)
 
 	// 初始化多桶存储服务（7桶架构）
@@ -57,11 +85,25 @@ func New(cfg *config.Config, db *services.Database) (*Container, error) {
 		return nil, fmt.Errorf("多桶存储服务初始化失败: %w", err)
 	}
 
-	uploadMgr := services.NewUploadManager(db, multiBucketStorage, cfg)
+	uploadScanner := services.NewUploadScanService(multiBucketStorage, &cfg.Antivirus)
+	uploadMgr := services.NewUploadManager(db, multiBucketStorage, uploadScanner, cfg)
 	resourceImageSvc := services.NewResourceImageService(multiBucketStorage)
+	tempFileSweeper := services.NewTempFileSweeper(db, multiBucketStorage, cfg)
+	tempFileSweeper.Start()
 
 	// 初始化缓存服务
 	cacheService := services.NewCacheService(articleRepo, cfg)
+	hotArticleRefresher := services.NewHotArticleRefresher(cacheService, articleRepo, cfg)
+	hotArticleRefresher.Start()
+	viewDedup := services.NewViewDeduplicator(cfg)
+
+	// 定时清理过期令牌
+	tokenCleaner := services.NewTokenCleaner(db, cfg)
+	tokenCleaner.Start()
+
+	// 每日统计汇总
+	statsAggregator := services.NewStatisticsAggregator(statsRepo, cfg)
+	statsAggregator.Start()
 
 	// 初始化代码仓库和执行器
 	codeRepo := services.NewCodeRepository(db)
@@ -78,6 +120,10 @@ func New(cfg *config.Config, db *services.Database) (*Container, error) {
 		Auth:                authService,
 		UserSvc:             userService,
 		UserRepo:            userRepo,
+		APITokenRepo:        apiTokenRepo,
+		TokenBlacklist:      tokenBlacklist,
+		NotifyPrefRepo:      notifyPrefRepo,
+		NotificationRepo:    notificationRepo,
 		MultiBucket:         multiBucketStorage,
 		StatsRepo:           statsRepo,
 		HistoryRepo:         historyRepo,
@@ -87,9 +133,16 @@ func New(cfg *config.Config, db *services.Database) (*Container, error) {
 		PrivateMsgRepo:      privateMsgRepo,
 		ResourceRepo:        resourceRepo,
 		ResourceCommentRepo: resourceCommentRepo,
+		ContentTransferRepo: contentTransferRepo,
+		AuditRepo:           auditRepo,
 		ResourceImageSvc:    resourceImageSvc,
 		UploadMgr:           uploadMgr,
 		CacheSvc:            cacheService,
+		HotArticleRefresher: hotArticleRefresher,
+		ViewDedup:           viewDedup,
+		TokenCleaner:        tokenCleaner,
+		TempFileSweeper:     tempFileSweeper,
+		StatsAggregator:     statsAggregator,
 		CodeRepo:            codeRepo,
 		CodeExecutor:        codeExecutor,
 		Config:              cfg,
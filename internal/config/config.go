@@ -2,9 +2,14 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"path"
+	"regexp"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"gopkg.in/yaml.v3"
 )
@@ -29,6 +34,9 @@ type Config struct {
 	BucketTempFiles         BucketConfig                  `yaml:"bucket_temp_files" json:"bucket_temp_files"`
 	BucketSystemAssets      BucketConfig                  `yaml:"bucket_system_assets" json:"bucket_system_assets"`
 	CodeExecutor            CodeExecutorConfig            `yaml:"code_executor" json:"code_executor"`
+	CodeCollab              CodeCollabConfig              `yaml:"code_collab" json:"code_collab"`
+	CodeShare               CodeShareConfig               `yaml:"code_share" json:"code_share"`
+	Readiness               ReadinessConfig               `yaml:"readiness" json:"readiness"`
 	WebSocket               WebSocketConfig               `yaml:"websocket" json:"websocket"`
 	RateLimiter             RateLimiterConfig             `yaml:"rate_limiter" json:"rate_limiter"`
 	Cache                   CacheConfig                   `yaml:"cache" json:"cache"`
@@ -65,6 +73,18 @@ type Config struct {
 	MinioAdvanced           MinioAdvancedConfig           `yaml:"minio_advanced" json:"minio_advanced"`
 	DatabaseQueryAdvanced   DatabaseQueryAdvancedConfig   `yaml:"database_query_advanced" json:"database_query_advanced"`
 	StatisticsQueryExtended StatisticsQueryExtendedConfig `yaml:"statistics_query_extended" json:"statistics_query_extended"`
+	APIToken                APITokenConfig                `yaml:"api_token" json:"api_token"`
+	Comments                CommentsConfig                `yaml:"comments" json:"comments"`
+	StartupRetry            StartupRetryConfig            `yaml:"startup_retry" json:"startup_retry"`
+	SMTP                    SMTPConfig                    `yaml:"smtp" json:"smtp"`
+	Alerts                  AlertsConfig                  `yaml:"alerts" json:"alerts"`
+	Redirect                RedirectConfig                `yaml:"redirect" json:"redirect"`
+	Moderation              ModerationConfig              `yaml:"moderation" json:"moderation"`
+	Demotion                DemotionConfig                `yaml:"demotion" json:"demotion"`
+	TokenCleanup            TokenCleanupConfig            `yaml:"token_cleanup" json:"token_cleanup"`
+	Reading                 ReadingConfig                 `yaml:"reading" json:"reading"`
+	StatsAggregation        StatsAggregationConfig        `yaml:"stats_aggregation" json:"stats_aggregation"`
+	Antivirus               AntivirusConfig               `yaml:"antivirus" json:"antivirus"`
 }
 
 // AppConfig 应用信息配置
@@ -86,6 +106,8 @@ type ServerConfig struct {
 	ShutdownTimeout          int    `yaml:"shutdown_timeout" json:"shutdown_timeout"`                       // 优雅关闭超时（秒）
 	StartupHealthCheckDelay  int    `yaml:"startup_health_check_delay" json:"startup_health_check_delay"`   // 启动后健康检查延迟（毫秒）
 	HealthCheckClientTimeout int    `yaml:"health_check_client_timeout" json:"health_check_client_timeout"` // 健康检查客户端超时（秒）
+	// TrustedProxies 可信反向代理的IP或网段，只有来自这些地址的请求才采信 X-Forwarded-For/X-Real-IP（为空表示不信任任何代理）
+	TrustedProxies []string `yaml:"trusted_proxies" json:"trusted_proxies"`
 }
 
 // JWTConfig JWT配置
@@ -125,8 +147,12 @@ type LogConfig struct {
 
 // SecurityConfig 安全配置
 type SecurityConfig struct {
-	MaxLoginAttempts int `yaml:"max_login_attempts" json:"max_login_attempts"`
-	MaxRequestSizeMB int `yaml:"max_request_size_mb" json:"max_request_size_mb"` // 最大请求体大小（MB）
+	MaxLoginAttempts    int `yaml:"max_login_attempts" json:"max_login_attempts"`
+	LockoutMinutes      int `yaml:"lockout_minutes" json:"lockout_minutes"`             // 连续失败达到上限后锁定时长（分钟）
+	LoginWarnRemaining  int `yaml:"login_warn_remaining" json:"login_warn_remaining"`   // 剩余尝试次数不超过该值时在错误中提示（0表示不提示）
+	MaxRequestSizeMB    int `yaml:"max_request_size_mb" json:"max_request_size_mb"`     // 最大请求体大小（MB）
+	JSONMaxSizeKB       int `yaml:"json_max_size_kb" json:"json_max_size_kb"`           // 资源元数据等JSON接口的请求体上限（KB）
+	MultipartOverheadKB int `yaml:"multipart_overhead_kb" json:"multipart_overhead_kb"` // 上传接口在文件大小上限之外允许的multipart表单开销（KB）
 }
 
 // AdminConfig 管理员配置
@@ -155,18 +181,36 @@ type MinIOConfig struct {
 
 // BucketConfig 通用桶配置（7桶架构）
 type BucketConfig struct {
-	Name                 string  `yaml:"name" json:"name"`                                       // 桶名称
-	PublicBaseURL        string  `yaml:"public_base_url" json:"public_base_url"`                 // 公共访问基础URL
-	MaxAvatarSizeMB      float64 `yaml:"max_avatar_size_mb" json:"max_avatar_size_mb"`           // 头像最大大小（仅user-avatars）
-	MaxHistory           int     `yaml:"max_history" json:"max_history"`                         // 历史版本数（仅user-avatars）
-	AutoCleanup          bool    `yaml:"auto_cleanup" json:"auto_cleanup"`                       // 是否自动清理
-	ChunkSizeMB          int     `yaml:"chunk_size_mb" json:"chunk_size_mb"`                     // 分片大小（仅resource-chunks）
-	MaxImageSizeKB       int     `yaml:"max_image_size_kb" json:"max_image_size_kb"`             // 图片最大大小
-	MaxImagesPerResource int     `yaml:"max_images_per_resource" json:"max_images_per_resource"` // 每个资源最大图片数
-	ArchiveAfterDays     int     `yaml:"archive_after_days" json:"archive_after_days"`           // 多少天后归档
-	AutoExpireHours      int     `yaml:"auto_expire_hours" json:"auto_expire_hours"`             // 自动过期时间（仅temp-files）
-	PublicRead           *bool   `yaml:"public_read" json:"public_read"`                         // 是否公开读取（nil=默认true）
-	CacheControl         string  `yaml:"cache_control" json:"cache_control"`                     // 缓存控制头
+	Name                 string   `yaml:"name" json:"name"`                                       // 桶名称
+	PublicBaseURL        string   `yaml:"public_base_url" json:"public_base_url"`                 // 公共访问基础URL
+	MaxAvatarSizeMB      float64  `yaml:"max_avatar_size_mb" json:"max_avatar_size_mb"`           // 头像最大大小（仅user-avatars）
+	MaxHistory           int      `yaml:"max_history" json:"max_history"`                         // 历史版本数（仅user-avatars）
+	AutoCleanup          bool     `yaml:"auto_cleanup" json:"auto_cleanup"`                       // 是否自动清理
+	ChunkSizeMB          int      `yaml:"chunk_size_mb" json:"chunk_size_mb"`                     // 分片大小（仅resource-chunks）
+	MaxImageSizeKB       int      `yaml:"max_image_size_kb" json:"max_image_size_kb"`             // 图片最大大小
+	MaxImagesPerResource int      `yaml:"max_images_per_resource" json:"max_images_per_resource"` // 每个资源最大图片数
+	ArchiveAfterDays     int      `yaml:"archive_after_days" json:"archive_after_days"`           // 多少天后归档
+	AutoExpireHours      int      `yaml:"auto_expire_hours" json:"auto_expire_hours"`             // 自动过期时间（仅temp-files）
+	PublicRead           *bool    `yaml:"public_read" json:"public_read"`                         // 是否公开读取（nil=默认true）
+	CacheControl         string   `yaml:"cache_control" json:"cache_control"`                     // 缓存控制头
+	PublicPaths          []string `yaml:"public_paths" json:"public_paths"`                       // 公开读取时允许公开访问的对象路径（path.Match语法，空表示整个桶）
+	PresignExpiryMinutes int      `yaml:"presign_expiry_minutes" json:"presign_expiry_minutes"`   // 非公开对象预签名URL的有效期（分钟）
+}
+
+// IsPublicObject 对象是否可以通过公共URL直接访问（私有桶或不匹配 public_paths 的对象需要预签名）
+func (b BucketConfig) IsPublicObject(objectPath string) bool {
+	if b.PublicRead != nil && !*b.PublicRead {
+		return false
+	}
+	if len(b.PublicPaths) == 0 {
+		return true
+	}
+	for _, pattern := range b.PublicPaths {
+		if ok, _ := path.Match(pattern, objectPath); ok {
+			return true
+		}
+	}
+	return false
 }
 
 // CodeExecutorConfig 代码执行器配置
@@ -177,6 +221,27 @@ type CodeExecutorConfig struct {
 	RateLimit    int    `yaml:"rate_limit" json:"rate_limit"`       // 限流：每分钟执行次数
 }
 
+// ReadinessConfig 就绪检查（/ready）配置
+type ReadinessConfig struct {
+	CheckTimeoutMs       int  `yaml:"check_timeout_ms" json:"check_timeout_ms"`             // 单个依赖检查的超时（毫秒）
+	ProbeCacheSeconds    int  `yaml:"probe_cache_seconds" json:"probe_cache_seconds"`       // MinIO/Piston 探测结果缓存时间（秒，0表示不缓存）
+	CodeExecutorCritical bool `yaml:"code_executor_critical" json:"code_executor_critical"` // 代码执行服务不可用时是否判定为未就绪
+}
+
+// CodeCollabConfig 代码协作编辑配置
+type CodeCollabConfig struct {
+	SessionTTLMinutes   int `yaml:"session_ttl_minutes" json:"session_ttl_minutes"`     // 协作会话有效期（分钟）
+	SnapshotIntervalSec int `yaml:"snapshot_interval_sec" json:"snapshot_interval_sec"` // 将协作中的代码写回代码片段的间隔（秒）
+	MaxParticipants     int `yaml:"max_participants" json:"max_participants"`           // 单个会话最多同时在线人数
+	MaxCodeBytes        int `yaml:"max_code_bytes" json:"max_code_bytes"`               // 单次编辑提交的代码最大字节数
+}
+
+// CodeShareConfig 代码分享链接配置
+type CodeShareConfig struct {
+	DefaultExpiryHours int `yaml:"default_expiry_hours" json:"default_expiry_hours"` // 未指定有效期时的默认有效期（小时，0表示永不过期）
+	MaxExpiryHours     int `yaml:"max_expiry_hours" json:"max_expiry_hours"`         // 允许设置的最长有效期（小时，0表示不限制）
+}
+
 // WebSocketConfig WebSocket配置
 type WebSocketConfig struct {
 	WriteWait            int `yaml:"write_wait" json:"write_wait"`                           // 写操作超时（秒）
@@ -189,6 +254,16 @@ type WebSocketConfig struct {
 	WriteBufferSize      int `yaml:"write_buffer_size" json:"write_buffer_size"`             // 写缓冲区大小（字节）
 	BroadcastBufferSize  int `yaml:"broadcast_buffer_size" json:"broadcast_buffer_size"`     // 广播channel缓冲区大小
 	ClientSendBufferSize int `yaml:"client_send_buffer_size" json:"client_send_buffer_size"` // 客户端发送channel缓冲区大小
+	SlowClientMaxDrops   int `yaml:"slow_client_max_drops" json:"slow_client_max_drops"`     // 窗口内发送缓冲区满导致丢弃消息超过该次数时断开客户端（0表示不断开）
+	SlowClientWindowSec  int `yaml:"slow_client_window_sec" json:"slow_client_window_sec"`   // 慢客户端丢弃计数窗口（秒）
+	// 超过 max_message_size 但未超过 oversize_hard_limit 的消息会被丢弃并回复错误，超过硬上限时连接直接关闭
+	OversizeHardLimit     int `yaml:"oversize_hard_limit" json:"oversize_hard_limit"`         // 单条消息硬上限（字节）
+	OversizeMaxViolations int `yaml:"oversize_max_violations" json:"oversize_max_violations"` // 窗口内超大消息达到该次数时断开并临时禁止连接（0表示不处理）
+	OversizeWindowSec     int `yaml:"oversize_window_sec" json:"oversize_window_sec"`         // 超大消息计数窗口（秒）
+	OversizeBanSec        int `yaml:"oversize_ban_sec" json:"oversize_ban_sec"`               // 禁止重新连接的时长（秒）
+	MaxConnections        int `yaml:"max_connections" json:"max_connections"`                 // 全局最大连接数，达到后拒绝新连接（0表示不限制）
+	// MaxConnectionsPerUser 每个用户的最大连接数：1 表示单连接（新连接替换旧连接），大于1时允许多端同时在线，超出时关闭最早的连接
+	MaxConnectionsPerUser int `yaml:"max_connections_per_user" json:"max_connections_per_user"`
 }
 
 // RateLimiterItemConfig 限流器单项配置
@@ -200,11 +275,24 @@ type RateLimiterItemConfig struct {
 
 // RateLimiterConfig 限流器配置
 type RateLimiterConfig struct {
-	Global          RateLimiterItemConfig `yaml:"global" json:"global"`                       // 全局API限流
-	Login           RateLimiterItemConfig `yaml:"login" json:"login"`                         // 登录限流
-	Register        RateLimiterItemConfig `yaml:"register" json:"register"`                   // 注册限流
-	CleanupInterval int                   `yaml:"cleanup_interval" json:"cleanup_interval"`   // 清理间隔（分钟）
-	EntryExpireTime int                   `yaml:"entry_expire_time" json:"entry_expire_time"` // 条目过期时间（分钟）
+	Global         RateLimiterItemConfig `yaml:"global" json:"global"`                   // 全局API限流
+	Login          RateLimiterItemConfig `yaml:"login" json:"login"`                     // 登录限流
+	Register       RateLimiterItemConfig `yaml:"register" json:"register"`               // 注册限流
+	User           RateLimiterItemConfig `yaml:"user" json:"user"`                       // 登录用户限流（按用户ID，匿名请求按IP）
+	SensitiveIP    RateLimiterItemConfig `yaml:"sensitive_ip" json:"sensitive_ip"`       // 匿名敏感操作按IP合并限流（注册、找回密码、重发验证邮件共享额度）
+	SensitiveEmail RateLimiterItemConfig `yaml:"sensitive_email" json:"sensitive_email"` // 匿名敏感操作按邮箱合并限流（防邮件轰炸）
+	// SensitiveMinResponseMs 匿名敏感操作的最短响应时间（毫秒，0表示不补齐），抹平账号是否存在造成的耗时差异
+	SensitiveMinResponseMs int                   `yaml:"sensitive_min_response_ms" json:"sensitive_min_response_ms"`
+	CleanupInterval        int                   `yaml:"cleanup_interval" json:"cleanup_interval"`     // 清理间隔（分钟）
+	EntryExpireTime        int                   `yaml:"entry_expire_time" json:"entry_expire_time"`   // 条目过期时间（分钟）
+	HeadersOnSuccess       bool                  `yaml:"headers_on_success" json:"headers_on_success"` // 放行的请求也返回 X-RateLimit-* 头
+	Exempt                 RateLimitExemptConfig `yaml:"exempt" json:"exempt"`                         // 限流豁免（全局、登录、注册和用户限流器）
+}
+
+// RateLimitExemptConfig 限流豁免配置（客户端IP按 server.trusted_proxies 解析，伪造的转发头不会命中）
+type RateLimitExemptConfig struct {
+	CIDRs  []string `yaml:"cidrs" json:"cidrs"`   // 豁免的IP或网段（如内网探针、运维机器）
+	Admins bool     `yaml:"admins" json:"admins"` // 携带有效管理员JWT的请求豁免
 }
 
 // CacheItemConfig 缓存单项配置
@@ -216,14 +304,34 @@ type CacheItemConfig struct {
 
 // CacheConfig 缓存配置
 type CacheConfig struct {
-	Article                 CacheItemConfig `yaml:"article" json:"article"`                                       // 文章缓存
-	User                    CacheItemConfig `yaml:"user" json:"user"`                                             // 用户缓存
-	List                    CacheItemConfig `yaml:"list" json:"list"`                                             // 列表缓存
-	CategoriesTTLMinutes    int             `yaml:"categories_ttl_minutes" json:"categories_ttl_minutes"`         // 分类缓存有效期（分钟）
-	TagsTTLMinutes          int             `yaml:"tags_ttl_minutes" json:"tags_ttl_minutes"`                     // 标签缓存有效期（分钟）
-	ArticleDetailTTLMinutes int             `yaml:"article_detail_ttl_minutes" json:"article_detail_ttl_minutes"` // 文章详情缓存有效期（分钟）
-	OnlineCountTTLSeconds   int             `yaml:"online_count_ttl_seconds" json:"online_count_ttl_seconds"`     // 在线人数缓存有效期（秒）
-	WarmupTimeout           int             `yaml:"warmup_timeout" json:"warmup_timeout"`                         // 缓存预热超时（秒）
+	Article                 CacheItemConfig   `yaml:"article" json:"article"`                                       // 文章缓存
+	User                    CacheItemConfig   `yaml:"user" json:"user"`                                             // 用户缓存
+	List                    CacheItemConfig   `yaml:"list" json:"list"`                                             // 列表缓存
+	CategoriesTTLMinutes    int               `yaml:"categories_ttl_minutes" json:"categories_ttl_minutes"`         // 分类缓存有效期（分钟）
+	TagsTTLMinutes          int               `yaml:"tags_ttl_minutes" json:"tags_ttl_minutes"`                     // 标签缓存有效期（分钟）
+	ArticleDetailTTLMinutes int               `yaml:"article_detail_ttl_minutes" json:"article_detail_ttl_minutes"` // 文章详情缓存有效期（分钟）
+	OnlineCountTTLSeconds   int               `yaml:"online_count_ttl_seconds" json:"online_count_ttl_seconds"`     // 在线人数缓存有效期（秒）
+	WarmupTimeout           int               `yaml:"warmup_timeout" json:"warmup_timeout"`                         // 缓存预热超时（秒）
+	HotArticles             HotArticlesConfig `yaml:"hot_articles" json:"hot_articles"`                             // 热门文章缓存后台刷新
+	ViewDedup               ViewDedupConfig   `yaml:"view_dedup" json:"view_dedup"`                                 // 浏览次数去重
+}
+
+// ViewDedupConfig 浏览次数去重配置
+// 同一访客（登录用户按用户ID，匿名按IP哈希）在窗口内重复浏览同一文章/资源只计一次；
+// 记录保存在进程内LRU中，容量满或重启后会重新计数，浏览数为近似值
+type ViewDedupConfig struct {
+	WindowMinutes int `yaml:"window_minutes" json:"window_minutes"` // 去重窗口（分钟，0表示不去重）
+	Capacity      int `yaml:"capacity" json:"capacity"`             // 最多记录的访客-内容组合数
+}
+
+// HotArticlesConfig 热门文章缓存后台刷新配置
+type HotArticlesConfig struct {
+	Enabled            bool    `yaml:"enabled" json:"enabled"`                           // 是否启用后台刷新
+	TopN               int     `yaml:"top_n" json:"top_n"`                               // 刷新热度最高的前N篇文章
+	RefreshIntervalSec int     `yaml:"refresh_interval_sec" json:"refresh_interval_sec"` // 检查间隔（秒）
+	RefreshAheadSec    int     `yaml:"refresh_ahead_sec" json:"refresh_ahead_sec"`       // 缓存剩余有效期低于该值时提前刷新（秒）
+	DecayGravity       float64 `yaml:"decay_gravity" json:"decay_gravity"`               // 热度时间衰减指数（越大衰减越快，文章列表 trending 排序共用）
+	TaskTimeoutSec     int     `yaml:"task_timeout_sec" json:"task_timeout_sec"`         // 单篇文章刷新任务超时（秒）
 }
 
 // ValidationUsernameConfig 用户名验证配置
@@ -234,9 +342,22 @@ type ValidationUsernameConfig struct {
 
 // ValidationPasswordConfig 密码验证配置
 type ValidationPasswordConfig struct {
-	MinLength      int `yaml:"min_length" json:"min_length"`             // 最小长度
-	MaxLength      int `yaml:"max_length" json:"max_length"`             // 最大长度（注册）
-	MaxLengthLogin int `yaml:"max_length_login" json:"max_length_login"` // 最大长度（登录）
+	MinLength      int                  `yaml:"min_length" json:"min_length"`             // 最小长度
+	MaxLength      int                  `yaml:"max_length" json:"max_length"`             // 最大长度（注册）
+	MaxLengthLogin int                  `yaml:"max_length_login" json:"max_length_login"` // 最大长度（登录）
+	Policy         PasswordPolicyConfig `yaml:"policy" json:"policy"`                     // 复杂度策略（注册、修改和重置密码时校验）
+}
+
+// PasswordPolicyConfig 密码复杂度策略（数量为0或开关为false的规则不启用）
+type PasswordPolicyConfig struct {
+	MinLetters     int  `yaml:"min_letters" json:"min_letters"`           // 至少包含的字母数
+	MinUppercase   int  `yaml:"min_uppercase" json:"min_uppercase"`       // 至少包含的大写字母数
+	MinLowercase   int  `yaml:"min_lowercase" json:"min_lowercase"`       // 至少包含的小写字母数
+	MinDigits      int  `yaml:"min_digits" json:"min_digits"`             // 至少包含的数字数
+	MinSpecial     int  `yaml:"min_special" json:"min_special"`           // 至少包含的特殊字符数（字母数字以外的字符）
+	RejectCommon   bool `yaml:"reject_common" json:"reject_common"`       // 拒绝常见弱密码
+	CommonTopN     int  `yaml:"common_top_n" json:"common_top_n"`         // 使用内置常见密码表的前N个（0表示全部）
+	RejectUserInfo bool `yaml:"reject_user_info" json:"reject_user_info"` // 拒绝包含用户名或邮箱前缀的密码
 }
 
 // ValidationNicknameConfig 昵称验证配置
@@ -260,13 +381,15 @@ type ValidationConfig struct {
 
 // DatabaseTimeoutsConfig 数据库超时配置
 type DatabaseTimeoutsConfig struct {
-	ConnectionTimeout       int `yaml:"connection_timeout" json:"connection_timeout"`               // 连接超时（秒）
-	ReadTimeout             int `yaml:"read_timeout" json:"read_timeout"`                           // 读取超时（秒）
-	WriteTimeout            int `yaml:"write_timeout" json:"write_timeout"`                         // 写入超时（秒）
-	PoolMonitorInterval     int `yaml:"pool_monitor_interval" json:"pool_monitor_interval"`         // 连接池监控间隔（分钟）
-	PingTimeout             int `yaml:"ping_timeout" json:"ping_timeout"`                           // Ping超时（秒）
-	TestConnectionTimeout   int `yaml:"test_connection_timeout" json:"test_connection_timeout"`     // 连接测试超时（秒）
-	WarmupConnectionTimeout int `yaml:"warmup_connection_timeout" json:"warmup_connection_timeout"` // 连接预热超时（秒）
+	ConnectionTimeout       int  `yaml:"connection_timeout" json:"connection_timeout"`               // 连接超时（秒）
+	ReadTimeout             int  `yaml:"read_timeout" json:"read_timeout"`                           // 读取超时（秒）
+	WriteTimeout            int  `yaml:"write_timeout" json:"write_timeout"`                         // 写入超时（秒）
+	PoolMonitorInterval     int  `yaml:"pool_monitor_interval" json:"pool_monitor_interval"`         // 连接池监控间隔（分钟）
+	PingTimeout             int  `yaml:"ping_timeout" json:"ping_timeout"`                           // Ping超时（秒）
+	TestConnectionTimeout   int  `yaml:"test_connection_timeout" json:"test_connection_timeout"`     // 连接测试超时（秒）
+	WarmupConnectionTimeout int  `yaml:"warmup_connection_timeout" json:"warmup_connection_timeout"` // 连接预热超时（秒）
+	StatementTimeoutEnabled bool `yaml:"statement_timeout_enabled" json:"statement_timeout_enabled"` // 是否在会话级设置 max_execution_time
+	MaxExecutionTimeMS      int  `yaml:"max_execution_time_ms" json:"max_execution_time_ms"`         // 语句最大执行时间（毫秒，0表示取默认查询超时）
 }
 
 // HTTPClientConfig HTTP客户端配置
@@ -278,17 +401,20 @@ type HTTPClientConfig struct {
 
 // AuthPolicyConfig 认证策略配置
 type AuthPolicyConfig struct {
-	PasswordResetTokenExpireMinutes int `yaml:"password_reset_token_expire_minutes" json:"password_reset_token_expire_minutes"` // 密码重置token有效期（分钟）
-	ResetTokenBytes                 int `yaml:"reset_token_bytes" json:"reset_token_bytes"`                                     // 重置token字节数
-	AsyncTaskTimeout                int `yaml:"async_task_timeout" json:"async_task_timeout"`                                   // 异步任务超时（秒）
+	PasswordResetTokenExpireMinutes int    `yaml:"password_reset_token_expire_minutes" json:"password_reset_token_expire_minutes"` // 密码重置token有效期（分钟）
+	ResetTokenBytes                 int    `yaml:"reset_token_bytes" json:"reset_token_bytes"`                                     // 重置token字节数
+	AsyncTaskTimeout                int    `yaml:"async_task_timeout" json:"async_task_timeout"`                                   // 异步任务超时（秒）
+	PasswordResetURL                string `yaml:"password_reset_url" json:"password_reset_url"`                                   // 前端重置密码页面地址（token 以 ?token= 追加）
 }
 
 // MetricsConfig 实时指标配置
 type MetricsConfig struct {
-	OnlineUsersInitialCapacity int `yaml:"online_users_initial_capacity" json:"online_users_initial_capacity"` // 在线用户map初始容量
-	OnlineUserCleanupInterval  int `yaml:"online_user_cleanup_interval" json:"online_user_cleanup_interval"`   // 清理间隔（分钟）
-	OnlineUserExpireTime       int `yaml:"online_user_expire_time" json:"online_user_expire_time"`             // 用户过期时间（分钟）
-	CPUGoroutineBaseline       int `yaml:"cpu_goroutine_baseline" json:"cpu_goroutine_baseline"`               // CPU估算基准Goroutine数
+	OnlineUsersInitialCapacity int    `yaml:"online_users_initial_capacity" json:"online_users_initial_capacity"` // 在线用户map初始容量
+	OnlineUserCleanupInterval  int    `yaml:"online_user_cleanup_interval" json:"online_user_cleanup_interval"`   // 清理间隔（分钟）
+	OnlineUserExpireTime       int    `yaml:"online_user_expire_time" json:"online_user_expire_time"`             // 用户过期时间（分钟）
+	OnlineUsersMaxMultiplier   int    `yaml:"online_users_max_multiplier" json:"online_users_max_multiplier"`     // 在线用户map最大条目数 = 初始容量 × 该倍数（超出时淘汰最久未活跃的）
+	CPUGoroutineBaseline       int    `yaml:"cpu_goroutine_baseline" json:"cpu_goroutine_baseline"`               // CPU估算基准Goroutine数
+	PrometheusPrefix           string `yaml:"prometheus_prefix" json:"prometheus_prefix"`                         // Prometheus指标名前缀
 }
 
 // AsyncTasksConfig 异步任务超时配置
@@ -301,6 +427,10 @@ type AsyncTasksConfig struct {
 	UserUpdateHistoryTimeout     int `yaml:"user_update_history_timeout" json:"user_update_history_timeout"`         // 用户更新历史超时（秒）
 	MessageMarkReadTimeout       int `yaml:"message_mark_read_timeout" json:"message_mark_read_timeout"`             // 标记消息已读超时（秒）
 	ArticleViewCountTimeout      int `yaml:"article_view_count_timeout" json:"article_view_count_timeout"`           // 文章浏览计数超时（秒）
+	AdminAuditTimeout            int `yaml:"admin_audit_timeout" json:"admin_audit_timeout"`                         // 管理员审计日志写入超时（秒）
+	SnippetExecutionCountTimeout int `yaml:"snippet_execution_count_timeout" json:"snippet_execution_count_timeout"` // 代码片段执行计数超时（秒）
+	RetryMaxAttempts             int `yaml:"retry_max_attempts" json:"retry_max_attempts"`                           // 任务遇到可重试的数据库错误时的最大执行次数（1 表示不重试，可在提交时单独指定）
+	RetryBackoffBaseMS           int `yaml:"retry_backoff_base_ms" json:"retry_backoff_base_ms"`                     // 首次重试前的等待时间（毫秒），之后每次翻倍
 }
 
 // WorkerPoolConfig Worker Pool配置
@@ -308,6 +438,19 @@ type WorkerPoolConfig struct {
 	Workers            int `yaml:"workers" json:"workers"`                           // worker数量
 	QueueSize          int `yaml:"queue_size" json:"queue_size"`                     // 任务队列大小
 	DefaultTaskTimeout int `yaml:"default_task_timeout" json:"default_task_timeout"` // 默认任务超时（秒）
+
+	// 单个请求内并行查询额外启动的goroutine上限（所有并行子任务共享）
+	MaxGoroutinesPerRequest int `yaml:"max_goroutines_per_request" json:"max_goroutines_per_request"`
+
+	// 池关闭后提交任务的处理：inline=在提交者goroutine中同步执行，reject=返回错误由调用方处理
+	ShutdownPolicy string `yaml:"shutdown_policy" json:"shutdown_policy"`
+	// 关闭时等待排队和执行中任务完成的最长时间（秒），超时后放弃剩余任务
+	ShutdownTimeout int `yaml:"shutdown_timeout" json:"shutdown_timeout"`
+
+	// 队列已满时提交任务的处理：reject=立即返回错误，block=最多等待 queue_full_wait_ms 后仍无空位再返回错误
+	QueueFullPolicy    string `yaml:"queue_full_policy" json:"queue_full_policy"`
+	QueueFullWaitMS    int    `yaml:"queue_full_wait_ms" json:"queue_full_wait_ms"`       // block 策略等待队列空位的最长时间（毫秒）
+	QueueHighWaterMark int    `yaml:"queue_high_water_mark" json:"queue_high_water_mark"` // 队列长度达到该值时输出告警日志（0 表示不告警）
 }
 
 // LRUCacheDefaultsConfig LRU缓存默认配置
@@ -320,7 +463,9 @@ type LRUCacheDefaultsConfig struct {
 
 // BatchOperationsConfig 批量操作配置
 type BatchOperationsConfig struct {
-	MaxConcurrency int `yaml:"max_concurrency" json:"max_concurrency"` // 批量查询最大并发数
+	MaxConcurrency     int `yaml:"max_concurrency" json:"max_concurrency"`           // 批量查询最大并发数
+	MaxDeleteItems     int `yaml:"max_delete_items" json:"max_delete_items"`         // 批量删除自己内容时单次最多的ID数（在一个事务中完成）
+	MaxModerationItems int `yaml:"max_moderation_items" json:"max_moderation_items"` // 管理员批量修改文章状态时单次最多的ID数
 }
 
 // ObjectPoolConfig 对象池配置
@@ -331,13 +476,17 @@ type ObjectPoolConfig struct {
 
 // PerformanceMonitoringConfig 性能监控配置
 type PerformanceMonitoringConfig struct {
-	SampleRate             int     `yaml:"sample_rate" json:"sample_rate"`                             // 采样率（%）
-	MemoryGrowthWarningMB  int     `yaml:"memory_growth_warning_mb" json:"memory_growth_warning_mb"`   // 内存增长警告阈值（MB）
-	GoroutineGrowthWarning int     `yaml:"goroutine_growth_warning" json:"goroutine_growth_warning"`   // Goroutine增长警告阈值
-	DBPoolWarningThreshold float64 `yaml:"db_pool_warning_threshold" json:"db_pool_warning_threshold"` // 数据库连接池警告阈值
-	VerySlowRequestMS      int     `yaml:"very_slow_request_ms" json:"very_slow_request_ms"`           // 非常慢请求阈值（毫秒）
-	SlowRequestMS          int     `yaml:"slow_request_ms" json:"slow_request_ms"`                     // 慢请求阈值（毫秒）
-	NormalRequestLogMS     int     `yaml:"normal_request_log_ms" json:"normal_request_log_ms"`         // 正常请求日志阈值（毫秒）
+	SampleRate                   int     `yaml:"sample_rate" json:"sample_rate"`                                           // 采样率（%）
+	MemoryGrowthWarningMB        int     `yaml:"memory_growth_warning_mb" json:"memory_growth_warning_mb"`                 // 内存增长警告阈值（MB）
+	GoroutineGrowthWarning       int     `yaml:"goroutine_growth_warning" json:"goroutine_growth_warning"`                 // Goroutine增长警告阈值
+	DBPoolWarningThreshold       float64 `yaml:"db_pool_warning_threshold" json:"db_pool_warning_threshold"`               // 数据库连接池警告阈值
+	DBPoolSampleSeconds          int     `yaml:"db_pool_sample_seconds" json:"db_pool_sample_seconds"`                     // 连接池峰值采样间隔（秒）
+	DBPoolWaitCountWarning       int64   `yaml:"db_pool_wait_count_warning" json:"db_pool_wait_count_warning"`             // 一个监控周期内新增等待次数达到该值时给出调优建议
+	DBPoolWaitDurationWarningMS  int     `yaml:"db_pool_wait_duration_warning_ms" json:"db_pool_wait_duration_warning_ms"` // 一个监控周期内新增等待总时长达到该值（毫秒）时给出调优建议
+	DBPoolSuggestHeadroomPercent int     `yaml:"db_pool_suggest_headroom_percent" json:"db_pool_suggest_headroom_percent"` // 建议的 max_open_conns 在观测峰值基础上预留的余量（%）
+	VerySlowRequestMS            int     `yaml:"very_slow_request_ms" json:"very_slow_request_ms"`                         // 非常慢请求阈值（毫秒）
+	SlowRequestMS                int     `yaml:"slow_request_ms" json:"slow_request_ms"`                                   // 慢请求阈值（毫秒）
+	NormalRequestLogMS           int     `yaml:"normal_request_log_ms" json:"normal_request_log_ms"`                       // 正常请求日志阈值（毫秒）
 }
 
 // RepositoryTimeoutsConfig Repository操作超时配置
@@ -358,8 +507,10 @@ type FileUploadConfig struct {
 
 // CompressionConfig 压缩配置
 type CompressionConfig struct {
-	MinSizeBytes int `yaml:"min_size_bytes" json:"min_size_bytes"` // 最小压缩大小（字节）
-	Level        int `yaml:"level" json:"level"`                   // 压缩级别（1-9）
+	MinSizeBytes     int      `yaml:"min_size_bytes" json:"min_size_bytes"`         // 最小压缩大小（字节）
+	Level            int      `yaml:"level" json:"level"`                           // 压缩级别（1-9）
+	SkipContentTypes []string `yaml:"skip_content_types" json:"skip_content_types"` // 不压缩的内容类型（已压缩格式，以 / 结尾表示前缀匹配）
+	SkipExtensions   []string `yaml:"skip_extensions" json:"skip_extensions"`       // 不压缩的请求路径扩展名
 }
 
 // PaginationConfig 分页配置
@@ -370,16 +521,32 @@ type PaginationConfig struct {
 	MaxLimit             int `yaml:"max_limit" json:"max_limit"`                             // 最大限制数量
 	HistoryDefaultLimit  int `yaml:"history_default_limit" json:"history_default_limit"`     // 历史记录默认限制
 	AvatarHistoryMaxList int `yaml:"avatar_history_max_list" json:"avatar_history_max_list"` // 头像历史列表最大数量
+	FeedFollowingMax     int `yaml:"feed_following_max" json:"feed_following_max"`           // 关注流最多纳入的关注用户数（按关注时间取最近的）
 }
 
 // ImageUploadConfig 图片上传配置
 type ImageUploadConfig struct {
-	MaxSizeMB int `yaml:"max_size_mb" json:"max_size_mb"` // 文档和资源图片最大大小（MB）
+	MaxSizeMB        int  `yaml:"max_size_mb" json:"max_size_mb"`               // 文档和资源图片最大大小（MB）
+	MaxWidth         int  `yaml:"max_width" json:"max_width"`                   // 图片最大宽度（像素）
+	MaxHeight        int  `yaml:"max_height" json:"max_height"`                 // 图片最大高度（像素）
+	ThumbnailMaxEdge int  `yaml:"thumbnail_max_edge" json:"thumbnail_max_edge"` // 资源预览图缩略图的最长边（像素）
+	ThumbnailQuality int  `yaml:"thumbnail_quality" json:"thumbnail_quality"`   // 缩略图JPEG质量（1-100）
+	StripMetadata    bool `yaml:"strip_metadata" json:"strip_metadata"`         // 上传前移除EXIF/XMP等元数据（GPS位置、设备信息）
 }
 
-// AvatarUploadConfig 头像上传配置（前端已裁剪和压缩）
+// AvatarUploadConfig 头像上传配置
+// 默认由前端裁剪和压缩；开启 server_process 后由服务端裁剪缩放并重新编码，可接收更大的原图
 type AvatarUploadConfig struct {
-	UploadRateLimit int `yaml:"upload_rate_limit" json:"upload_rate_limit"` // 每分钟最大上传次数
+	UploadRateLimit     int     `yaml:"upload_rate_limit" json:"upload_rate_limit"`         // 每分钟最大上传次数
+	MaxSizeKB           int     `yaml:"max_size_kb" json:"max_size_kb"`                     // 头像最大大小（KB，未开启服务端处理时）
+	MaxWidth            int     `yaml:"max_width" json:"max_width"`                         // 头像最大宽度（像素，未开启服务端处理时）
+	MaxHeight           int     `yaml:"max_height" json:"max_height"`                       // 头像最大高度（像素，未开启服务端处理时）
+	MaxAspectRatio      float64 `yaml:"max_aspect_ratio" json:"max_aspect_ratio"`           // 长边/短边的最大比值（未开启服务端处理时，头像应接近正方形）
+	ServerProcess       bool    `yaml:"server_process" json:"server_process"`               // 是否在服务端裁剪缩放并重新编码为JPEG
+	ProcessMaxSizeKB    int     `yaml:"process_max_size_kb" json:"process_max_size_kb"`     // 开启服务端处理时原图最大大小（KB）
+	ProcessMaxDimension int     `yaml:"process_max_dimension" json:"process_max_dimension"` // 开启服务端处理时原图最大边长（像素，限制解码内存）
+	OutputSize          int     `yaml:"output_size" json:"output_size"`                     // 处理后的正方形边长（像素）
+	JPEGQuality         int     `yaml:"jpeg_quality" json:"jpeg_quality"`                   // 处理后的JPEG质量（1-100）
 }
 
 // DatabaseQueryConfig 数据库查询配置
@@ -430,6 +597,19 @@ type LogExtendedConfig struct {
 	RequestBodyTruncateSize int      `yaml:"request_body_truncate_size" json:"request_body_truncate_size"` // 请求体截断大小（字节）
 	SampleRateProduction    int      `yaml:"sample_rate_production" json:"sample_rate_production"`         // 生产环境采样率(%)
 	SampleRateDevelopment   int      `yaml:"sample_rate_development" json:"sample_rate_development"`       // 开发环境采样率(%)
+	AccessLogFields         []string `yaml:"access_log_fields" json:"access_log_fields"`                   // 访问日志记录的字段，可选值见 AccessLogFieldNames
+	SensitiveKeys           []string `yaml:"sensitive_keys" json:"sensitive_keys"`                         // 日志中需要脱敏的字段名/SQL列名（忽略大小写和下划线，按后缀匹配；空列表关闭脱敏）
+}
+
+// DefaultSensitiveLogKeys 默认的日志脱敏字段
+var DefaultSensitiveLogKeys = []string{
+	"password", "password_hash", "token", "secret", "api_key", "email", "phone", "authorization", "cookie",
+}
+
+// AccessLogFieldNames 访问日志可选字段
+var AccessLogFieldNames = []string{
+	"method", "path", "query", "status", "latency", "ip", "user_agent",
+	"user_id", "request_id", "headers", "request_body", "response_body",
 }
 
 // SecurityHeadersConfig 安全响应头配置
@@ -446,9 +626,13 @@ type SecurityHeadersConfig struct {
 
 // SecurityPasswordConfig 密码加密配置
 type SecurityPasswordConfig struct {
-	BcryptCostMin    int `yaml:"bcrypt_cost_min" json:"bcrypt_cost_min"`       // bcrypt最小成本
-	BcryptCostMax    int `yaml:"bcrypt_cost_max" json:"bcrypt_cost_max"`       // bcrypt最大成本
-	PasswordMaxBytes int `yaml:"password_max_bytes" json:"password_max_bytes"` // 密码最大字节数（bcrypt限制）
+	BcryptCostMin       int  `yaml:"bcrypt_cost_min" json:"bcrypt_cost_min"`             // bcrypt最小成本
+	BcryptCostMax       int  `yaml:"bcrypt_cost_max" json:"bcrypt_cost_max"`             // bcrypt最大成本
+	PasswordMaxBytes    int  `yaml:"password_max_bytes" json:"password_max_bytes"`       // 密码最大字节数（bcrypt限制）
+	BcryptCost          int  `yaml:"bcrypt_cost" json:"bcrypt_cost"`                     // 未启用校准时新哈希使用的bcrypt成本
+	CalibrateCost       bool `yaml:"calibrate_cost" json:"calibrate_cost"`               // 启动时按主机性能校准bcrypt成本
+	CalibrationTargetMs int  `yaml:"calibration_target_ms" json:"calibration_target_ms"` // 校准目标：单次哈希耗时上限（毫秒）
+	RehashOnLogin       bool `yaml:"rehash_on_login" json:"rehash_on_login"`             // 登录成功时将低于当前成本的哈希升级
 }
 
 // SecuritySQLConfig SQL注入防护配置
@@ -459,6 +643,7 @@ type SecuritySQLConfig struct {
 // ValidationExtendedConfig 验证规则扩展配置
 type ValidationExtendedConfig struct {
 	URLMinLength           int    `yaml:"url_min_length" json:"url_min_length"`                     // URL最小长度
+	URLMaxLength           int    `yaml:"url_max_length" json:"url_max_length"`                     // URL最大长度（个人资料链接）
 	PhoneFirstDigit        string `yaml:"phone_first_digit" json:"phone_first_digit"`               // 手机号首位数字
 	PhoneSecondDigitMin    string `yaml:"phone_second_digit_min" json:"phone_second_digit_min"`     // 手机号第二位最小值
 	PhoneSecondDigitMax    string `yaml:"phone_second_digit_max" json:"phone_second_digit_max"`     // 手机号第二位最大值
@@ -471,7 +656,13 @@ type ValidationExtendedConfig struct {
 
 // JWTExtendedConfig JWT扩展配置
 type JWTExtendedConfig struct {
-	TokenPrefix string `yaml:"token_prefix" json:"token_prefix"` // Token前缀（例如 "Bearer "）
+	TokenPrefix        string `yaml:"token_prefix" json:"token_prefix"`                 // Token前缀（例如 "Bearer "）
+	AccessTokenMinutes int    `yaml:"access_token_minutes" json:"access_token_minutes"` // 访问token有效期（分钟，0表示沿用 jwt.expire_hours）
+	RefreshTokenDays   int    `yaml:"refresh_token_days" json:"refresh_token_days"`     // 刷新token有效期（天）
+	RefreshTokenBytes  int    `yaml:"refresh_token_bytes" json:"refresh_token_bytes"`   // 刷新token随机字节数
+
+	BlacklistCapacity    int `yaml:"blacklist_capacity" json:"blacklist_capacity"`           // 已注销token黑名单最大条目数（LRU淘汰）
+	TokenVersionCacheSec int `yaml:"token_version_cache_sec" json:"token_version_cache_sec"` // 用户token版本缓存时间（秒）
 }
 
 // DateTimeFormatsConfig 日期时间格式配置
@@ -483,9 +674,14 @@ type DateTimeFormatsConfig struct {
 
 // MinioAdvancedConfig MinIO高级配置
 type MinioAdvancedConfig struct {
-	PolicyVersion string `yaml:"policy_version" json:"policy_version"` // S3策略版本号
-	PolicyEffect  string `yaml:"policy_effect" json:"policy_effect"`   // 策略效果
-	PolicyAction  string `yaml:"policy_action" json:"policy_action"`   // 策略允许的操作
+	PolicyVersion            string `yaml:"policy_version" json:"policy_version"`                           // S3策略版本号
+	PolicyEffect             string `yaml:"policy_effect" json:"policy_effect"`                             // 策略效果
+	PolicyAction             string `yaml:"policy_action" json:"policy_action"`                             // 策略允许的操作
+	PresignedExpirySec       int    `yaml:"presigned_expiry_sec" json:"presigned_expiry_sec"`               // 预签名下载URL有效期（秒）
+	MaxRetries               int    `yaml:"max_retries" json:"max_retries"`                                 // 对象操作遇到可重试错误时的最大重试次数（0表示不重试）
+	RetryBackoffBaseMS       int    `yaml:"retry_backoff_base_ms" json:"retry_backoff_base_ms"`             // 重试退避基数（毫秒，按2的幂递增）
+	RetryBufferMaxMB         int    `yaml:"retry_buffer_max_mb" json:"retry_buffer_max_mb"`                 // 不可Seek的上传流缓冲到内存以便重试的最大大小（MB，超过则只尝试一次）
+	TempSweepIntervalMinutes int    `yaml:"temp_sweep_interval_minutes" json:"temp_sweep_interval_minutes"` // 清理temp-files桶过期对象（auto_expire_hours）的间隔（分钟，0表示不清理）
 }
 
 // DatabaseQueryAdvancedConfig 数据库查询高级配置
@@ -497,6 +693,145 @@ type DatabaseQueryAdvancedConfig struct {
 // StatisticsQueryExtendedConfig 统计查询扩展配置
 type StatisticsQueryExtendedConfig struct {
 	DefaultDateRangeDays int `yaml:"default_date_range_days" json:"default_date_range_days"` // 默认查询日期范围（天数）
+	MaxExportRangeDays   int `yaml:"max_export_range_days" json:"max_export_range_days"`     // 单次导出的最大日期范围（天数）
+	ExportTimeoutSec     int `yaml:"export_timeout_sec" json:"export_timeout_sec"`           // 导出查询超时（秒）
+}
+
+// APITokenConfig 个人访问令牌配置
+type APITokenConfig struct {
+	TokenPrefix       string `yaml:"token_prefix" json:"token_prefix"`                 // 令牌前缀（用于区分JWT与API令牌）
+	MaxTokensPerUser  int    `yaml:"max_tokens_per_user" json:"max_tokens_per_user"`   // 每个用户最多可持有的有效令牌数
+	MaxExpireDays     int    `yaml:"max_expire_days" json:"max_expire_days"`           // 令牌最长有效期（天，0表示允许永不过期）
+	LastUsedUpdateSec int    `yaml:"last_used_update_sec" json:"last_used_update_sec"` // 更新最后使用时间的异步任务超时（秒）
+	// RouteScopes 路由组 -> 权限资源名；令牌访问该组需要 read:<资源>（GET）或 write:<资源>（其他方法）
+	RouteScopes map[string]string `yaml:"route_scopes" json:"route_scopes"`
+}
+
+// CommentsConfig 评论配置
+type CommentsConfig struct {
+	MaxDepth         int `yaml:"max_depth" json:"max_depth"`                   // 评论树最大展示层级（含一级评论，0表示不限制），更深的回复挂到最深允许层级的祖先下
+	EditWindowMin    int `yaml:"edit_window_min" json:"edit_window_min"`       // 发布后允许编辑的时间窗口（分钟，0表示不限制）
+	EditHistoryLimit int `yaml:"edit_history_limit" json:"edit_history_limit"` // 每条评论保留的编辑历史条数（0表示不保存）
+	InlineReplies    int `yaml:"inline_replies" json:"inline_replies"`         // 文章评论列表中每条一级评论内联返回的直接回复数（0表示返回完整回复树）
+}
+
+// StartupRetryConfig 启动时依赖服务（数据库、MinIO）连接重试配置
+type StartupRetryConfig struct {
+	MaxElapsedSeconds int     `yaml:"max_elapsed_seconds" json:"max_elapsed_seconds"` // 重试总时长（秒，0表示只尝试一次）
+	InitialIntervalMS int     `yaml:"initial_interval_ms" json:"initial_interval_ms"` // 首次重试间隔（毫秒）
+	MaxIntervalMS     int     `yaml:"max_interval_ms" json:"max_interval_ms"`         // 最大重试间隔（毫秒）
+	Multiplier        float64 `yaml:"multiplier" json:"multiplier"`                   // 间隔增长倍数
+}
+
+// SMTPConfig 邮件发送配置（driver 为 noop 时只记录日志不发送，便于开发环境）
+type SMTPConfig struct {
+	Driver   string `yaml:"driver" json:"driver"`       // 发送方式：smtp/noop
+	Host     string `yaml:"host" json:"host"`           // SMTP服务器地址
+	Port     int    `yaml:"port" json:"port"`           // SMTP端口
+	Username string `yaml:"username" json:"username"`   // 认证用户名（为空则不认证）
+	Password string `yaml:"password" json:"-"`          // 认证密码
+	From     string `yaml:"from" json:"from"`           // 发件人地址
+	FromName string `yaml:"from_name" json:"from_name"` // 发件人名称
+}
+
+// AlertsConfig 接口告警配置（基于内存中最近一段时间的请求统计）
+type AlertsConfig struct {
+	WindowMinutes    int     `yaml:"window_minutes" json:"window_minutes"`         // 统计窗口（分钟）
+	MinRequests      int     `yaml:"min_requests" json:"min_requests"`             // 窗口内请求数少于该值的接口不告警（避免样本过少误报）
+	ErrorRatePercent float64 `yaml:"error_rate_percent" json:"error_rate_percent"` // 错误率阈值（百分比，只统计5xx）
+	P99LatencyMs     int     `yaml:"p99_latency_ms" json:"p99_latency_ms"`         // P99延迟阈值（毫秒）
+	MaxEndpoints     int     `yaml:"max_endpoints" json:"max_endpoints"`           // 最多跟踪的接口数（超出的新接口不统计）
+
+	SlowQueryWindowMinutes int `yaml:"slow_query_window_minutes" json:"slow_query_window_minutes"` // 慢查询频次统计窗口（分钟）
+	SlowQueryMinCount      int `yaml:"slow_query_min_count" json:"slow_query_min_count"`           // 同一指纹的慢查询在窗口内达到该次数时告警
+	SlowQueryMaxPatterns   int `yaml:"slow_query_max_patterns" json:"slow_query_max_patterns"`     // 最多跟踪的慢查询指纹数（超出的新指纹不统计）
+}
+
+// RedirectConfig 跳转目标白名单（返回给客户端跳转或下载的绝对URL必须在白名单内）
+type RedirectConfig struct {
+	AllowedHosts   []string `yaml:"allowed_hosts" json:"allowed_hosts"`     // 允许的主机名（支持 *.example.com 匹配子域名；各桶 public_base_url 的主机自动允许）
+	AllowedSchemes []string `yaml:"allowed_schemes" json:"allowed_schemes"` // 允许的协议
+}
+
+// ModerationConfig 内容审核配置（评论、聊天消息、文章的敏感词过滤，支持热更新）
+type ModerationConfig struct {
+	Enabled                bool     `yaml:"enabled" json:"enabled"`                                   // 是否启用
+	Action                 string   `yaml:"action" json:"action"`                                     // 命中后的处理：reject=拒绝（400），mask=替换为掩码字符
+	Words                  []string `yaml:"words" json:"words"`                                       // 敏感词（忽略大小写、全角半角和字间的空格符号）
+	Patterns               []string `yaml:"patterns" json:"patterns"`                                 // 正则规则（匹配全角转半角、小写后的文本）
+	MaskChar               string   `yaml:"mask_char" json:"mask_char"`                               // 掩码字符
+	ViolationWindowMinutes int      `yaml:"violation_window_minutes" json:"violation_window_minutes"` // 违规计数窗口（分钟）
+	ViolationThreshold     int      `yaml:"violation_threshold" json:"violation_threshold"`           // 窗口内违规次数达到该值后禁言（0表示不禁言）
+	MuteMinutes            int      `yaml:"mute_minutes" json:"mute_minutes"`                         // 禁言时长（分钟，只限制聊天）
+}
+
+// DemotionConfig 低质量内容降权配置（热门/最受欢迎排序中压低排名，不隐藏内容）
+type DemotionConfig struct {
+	Enabled         bool    `yaml:"enabled" json:"enabled"`                   // 是否启用
+	ReportThreshold int     `yaml:"report_threshold" json:"report_threshold"` // 未被驳回的举报人数达到该值时降权（0表示不按举报降权）
+	MinViews        int     `yaml:"min_views" json:"min_views"`               // 浏览量达到该值才按点赞率判断质量
+	MinLikeRatio    float64 `yaml:"min_like_ratio" json:"min_like_ratio"`     // 点赞数/浏览量低于该值时降权（0表示不按点赞率降权）
+	Factor          float64 `yaml:"factor" json:"factor"`                     // 降权系数（排序分乘以该值，取值 (0,1]）
+}
+
+// TokenCleanupConfig 过期令牌定时清理配置
+type TokenCleanupConfig struct {
+	Enabled         bool                    `yaml:"enabled" json:"enabled"`                   // 是否启用
+	IntervalMinutes int                     `yaml:"interval_minutes" json:"interval_minutes"` // 清理间隔（分钟）
+	BatchSize       int                     `yaml:"batch_size" json:"batch_size"`             // 每批删除的行数
+	PasswordReset   TokenCleanupTableConfig `yaml:"password_reset" json:"password_reset"`     // 密码重置token（已过期或已使用）
+	RefreshTokens   TokenCleanupTableConfig `yaml:"refresh_tokens" json:"refresh_tokens"`     // 刷新token（已过期；已轮换的token保留到过期，用于重用检测）
+	APITokens       TokenCleanupTableConfig `yaml:"api_tokens" json:"api_tokens"`             // API令牌（已过期或已吊销）
+}
+
+// StatsAggregationConfig 每日统计汇总配置
+// 启用时登录/注册/接口调用先在内存中按天汇总，定时写入 user_statistics / api_statistics（按日期覆盖写入，可重复执行）；
+// 未启用时每个请求直接累加到数据库。汇总以单实例为准，多实例部署时应关闭
+type StatsAggregationConfig struct {
+	Enabled              bool   `yaml:"enabled" json:"enabled"`                               // 是否启用
+	RunAt                string `yaml:"run_at" json:"run_at"`                                 // 每天汇总前一天数据的时间（UTC，HH:MM）
+	FlushIntervalMinutes int    `yaml:"flush_interval_minutes" json:"flush_interval_minutes"` // 当天数据的写入间隔（分钟）
+}
+
+// TokenCleanupTableConfig 单个令牌表的清理配置
+type TokenCleanupTableConfig struct {
+	Enabled    bool `yaml:"enabled" json:"enabled"`         // 是否清理该表
+	GraceHours int  `yaml:"grace_hours" json:"grace_hours"` // 过期/失效超过该时长（小时）才删除
+}
+
+// ReadingConfig 文章纯文本与阅读时长估算配置
+type ReadingConfig struct {
+	WordsPerMinute int `yaml:"words_per_minute" json:"words_per_minute"` // 每分钟阅读字数（中文按字、英文按词计）
+}
+
+// 扫描器不可用时的处理策略
+const (
+	AntivirusPolicyAllow  = "allow"  // 放行并记录警告
+	AntivirusPolicyReject = "reject" // 拒绝上传
+)
+
+// AntivirusConfig 上传文件病毒扫描配置（ClamAV clamd，分片上传合并时扫描）
+type AntivirusConfig struct {
+	Enabled        bool     `yaml:"enabled" json:"enabled"`                 // 是否启用扫描
+	ClamdAddress   string   `yaml:"clamd_address" json:"clamd_address"`     // clamd TCP地址（host:port）
+	TimeoutSeconds int      `yaml:"timeout_seconds" json:"timeout_seconds"` // 单个文件的扫描超时（秒），超时按扫描器不可用处理
+	OnUnavailable  string   `yaml:"on_unavailable" json:"on_unavailable"`   // 扫描器不可用时的策略：allow（放行并记录警告）/ reject（拒绝）
+	SkipBuckets    []string `yaml:"skip_buckets" json:"skip_buckets"`       // 不扫描的桶（图片已在服务端重新编码）
+}
+
+// RedirectAllowedHosts 跳转白名单主机：配置的主机加上各桶公共访问地址的主机
+func (c *Config) RedirectAllowedHosts() []string {
+	hosts := append([]string{}, c.Redirect.AllowedHosts...)
+	buckets := []BucketConfig{
+		c.BucketUserAvatars, c.BucketResourceChunks, c.BucketResourcePreviews, c.BucketDocumentImages,
+		c.BucketArticleImages, c.BucketTempFiles, c.BucketSystemAssets,
+	}
+	for _, bucket := range buckets {
+		if u, err := url.Parse(bucket.PublicBaseURL); err == nil && u.Hostname() != "" {
+			hosts = append(hosts, u.Hostname())
+		}
+	}
+	return hosts
 }
 
 // Load 加载配置（优先级：环境变量 > 配置文件 > 默认值）
@@ -525,6 +860,7 @@ func Load() *Config {
 		fmt.Printf("Warning: Configuration validation failed: %v\n", err)
 	}
 
+	activeConfig.Store(config)
 	return config
 }
 
@@ -545,6 +881,11 @@ func getConfigFile(env string) string {
 	return ""
 }
 
+// Default 返回默认配置（不读取配置文件和环境变量，也不设置为当前配置）
+func Default() *Config {
+	return getDefaultConfig()
+}
+
 // getDefaultConfig 获取默认配置
 func getDefaultConfig() *Config {
 	return &Config{
@@ -564,6 +905,7 @@ func getDefaultConfig() *Config {
 			ShutdownTimeout:          30,
 			StartupHealthCheckDelay:  500, // 500ms
 			HealthCheckClientTimeout: 3,
+			TrustedProxies:           []string{"127.0.0.1", "::1"},
 		},
 		JWT: JWTConfig{
 			SecretKey:   getEnv("JWT_SECRET", "default_secret_key_change_in_production"),
@@ -595,8 +937,12 @@ func getDefaultConfig() *Config {
 			DropPolicy: "block",
 		},
 		Security: SecurityConfig{
-			MaxLoginAttempts: 5,
-			MaxRequestSizeMB: 10,
+			MaxLoginAttempts:    5,
+			LockoutMinutes:      15,
+			LoginWarnRemaining:  2,
+			MaxRequestSizeMB:    10,
+			JSONMaxSizeKB:       1024,
+			MultipartOverheadKB: 64,
 		},
 		Admin: AdminConfig{
 			Usernames:       []string{"admin"}, // 默认管理员
@@ -609,6 +955,11 @@ func getDefaultConfig() *Config {
 			AllowHeaders:     []string{"Origin", "Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization"},
 			AllowCredentials: true,
 		},
+		// 历史头像不公开，只有当前头像可通过公共URL访问
+		BucketUserAvatars: BucketConfig{
+			PublicPaths:          []string{"*/current.jpg"},
+			PresignExpiryMinutes: 10,
+		},
 		MinIO: MinIOConfig{
 			Endpoint:         getEnv("MINIO_ENDPOINT", "localhost:9000"),
 			AccessKeyID:      getEnv("MINIO_ACCESS_KEY", "minioadmin"),
@@ -646,17 +997,40 @@ func getDefaultConfig() *Config {
 				return 10
 			}(),
 		},
+		CodeCollab: CodeCollabConfig{
+			SessionTTLMinutes:   120,
+			SnapshotIntervalSec: 30,
+			MaxParticipants:     10,
+			MaxCodeBytes:        65536,
+		},
+		CodeShare: CodeShareConfig{
+			DefaultExpiryHours: 168,
+			MaxExpiryHours:     720,
+		},
+		Readiness: ReadinessConfig{
+			CheckTimeoutMs:       2000,
+			ProbeCacheSeconds:    10,
+			CodeExecutorCritical: false,
+		},
 		WebSocket: WebSocketConfig{
-			WriteWait:            10,
-			PongWait:             60,
-			PingPeriod:           30,
-			MaxMessageSize:       4096,
-			MaxMessageLength:     500,
-			MaxMessagesPerSecond: 3,
-			ReadBufferSize:       1024,
-			WriteBufferSize:      1024,
-			BroadcastBufferSize:  256,
-			ClientSendBufferSize: 256,
+			WriteWait:             10,
+			PongWait:              60,
+			PingPeriod:            30,
+			MaxMessageSize:        4096,
+			MaxMessageLength:      500,
+			MaxMessagesPerSecond:  3,
+			ReadBufferSize:        1024,
+			WriteBufferSize:       1024,
+			BroadcastBufferSize:   256,
+			ClientSendBufferSize:  256,
+			SlowClientMaxDrops:    20,
+			SlowClientWindowSec:   30,
+			OversizeHardLimit:     65536,
+			OversizeMaxViolations: 3,
+			OversizeWindowSec:     60,
+			OversizeBanSec:        300,
+			MaxConnections:        10000,
+			MaxConnectionsPerUser: 1,
 		},
 		RateLimiter: RateLimiterConfig{
 			Global: RateLimiterItemConfig{
@@ -674,8 +1048,25 @@ func getDefaultConfig() *Config {
 				RequestsPerMinute: 10,
 				MaxCacheSize:      1000,
 			},
-			CleanupInterval: 10,
-			EntryExpireTime: 30,
+			User: RateLimiterItemConfig{
+				Capacity:          60,
+				RequestsPerMinute: 60,
+				MaxCacheSize:      10000,
+			},
+			SensitiveIP: RateLimiterItemConfig{
+				Capacity:          10,
+				RequestsPerMinute: 5,
+				MaxCacheSize:      10000,
+			},
+			SensitiveEmail: RateLimiterItemConfig{
+				Capacity:          3,
+				RequestsPerMinute: 1,
+				MaxCacheSize:      10000,
+			},
+			SensitiveMinResponseMs: 500,
+			CleanupInterval:        10,
+			EntryExpireTime:        30,
+			HeadersOnSuccess:       true,
 		},
 		Cache: CacheConfig{
 			Article: CacheItemConfig{
@@ -698,6 +1089,18 @@ func getDefaultConfig() *Config {
 			ArticleDetailTTLMinutes: 5,
 			OnlineCountTTLSeconds:   10,
 			WarmupTimeout:           30,
+			HotArticles: HotArticlesConfig{
+				Enabled:            true,
+				TopN:               20,
+				RefreshIntervalSec: 30,
+				RefreshAheadSec:    60,
+				DecayGravity:       1.5,
+				TaskTimeoutSec:     5,
+			},
+			ViewDedup: ViewDedupConfig{
+				WindowMinutes: 30,
+				Capacity:      100000,
+			},
 		},
 		Validation: ValidationConfig{
 			Username: ValidationUsernameConfig{
@@ -708,6 +1111,13 @@ func getDefaultConfig() *Config {
 				MinLength:      6,
 				MaxLength:      50,
 				MaxLengthLogin: 100,
+				Policy: PasswordPolicyConfig{
+					MinLetters:     1,
+					MinDigits:      1,
+					RejectCommon:   true,
+					CommonTopN:     100,
+					RejectUserInfo: true,
+				},
 			},
 			Nickname: ValidationNicknameConfig{
 				MinLength: 1,
@@ -725,6 +1135,8 @@ func getDefaultConfig() *Config {
 			PingTimeout:             5,
 			TestConnectionTimeout:   10,
 			WarmupConnectionTimeout: 3,
+			StatementTimeoutEnabled: true,
+			MaxExecutionTimeMS:      0,
 		},
 		HTTPClient: HTTPClientConfig{
 			MaxIdleConns:        100,
@@ -735,12 +1147,15 @@ func getDefaultConfig() *Config {
 			PasswordResetTokenExpireMinutes: 15,
 			ResetTokenBytes:                 48,
 			AsyncTaskTimeout:                10,
+			PasswordResetURL:                "http://localhost:5173/reset-password",
 		},
 		Metrics: MetricsConfig{
 			OnlineUsersInitialCapacity: 1000,
 			OnlineUserCleanupInterval:  1,
 			OnlineUserExpireTime:       5,
+			OnlineUsersMaxMultiplier:   10,
 			CPUGoroutineBaseline:       200,
+			PrometheusPrefix:           "shequ",
 		},
 		AsyncTasks: AsyncTasksConfig{
 			ResourceViewCountTimeout:     3,
@@ -751,11 +1166,24 @@ func getDefaultConfig() *Config {
 			UserUpdateHistoryTimeout:     5,
 			MessageMarkReadTimeout:       3,
 			ArticleViewCountTimeout:      3,
+			AdminAuditTimeout:            5,
+			SnippetExecutionCountTimeout: 3,
+			RetryMaxAttempts:             1,
+			RetryBackoffBaseMS:           200,
 		},
 		WorkerPool: WorkerPoolConfig{
 			Workers:            10,
 			QueueSize:          1000,
 			DefaultTaskTimeout: 30,
+
+			MaxGoroutinesPerRequest: 8,
+
+			ShutdownPolicy:  "inline",
+			ShutdownTimeout: 10,
+
+			QueueFullPolicy:    "reject",
+			QueueFullWaitMS:    100,
+			QueueHighWaterMark: 800,
 		},
 		LRUCacheDefaults: LRUCacheDefaultsConfig{
 			Capacity:        10000,
@@ -764,20 +1192,26 @@ func getDefaultConfig() *Config {
 			CleanupInterval: 1,
 		},
 		BatchOperations: BatchOperationsConfig{
-			MaxConcurrency: 10,
+			MaxConcurrency:     10,
+			MaxDeleteItems:     100,
+			MaxModerationItems: 200,
 		},
 		ObjectPool: ObjectPoolConfig{
 			MapInitialCapacity: 16,
 			MagicBufferSize:    16,
 		},
 		PerformanceMonitoring: PerformanceMonitoringConfig{
-			SampleRate:             10,
-			MemoryGrowthWarningMB:  10,
-			GoroutineGrowthWarning: 10,
-			DBPoolWarningThreshold: 0.8,
-			VerySlowRequestMS:      1000,
-			SlowRequestMS:          500,
-			NormalRequestLogMS:     200,
+			SampleRate:                   10,
+			MemoryGrowthWarningMB:        10,
+			GoroutineGrowthWarning:       10,
+			DBPoolWarningThreshold:       0.8,
+			DBPoolSampleSeconds:          10,
+			DBPoolWaitCountWarning:       1,
+			DBPoolWaitDurationWarningMS:  100,
+			DBPoolSuggestHeadroomPercent: 25,
+			VerySlowRequestMS:            1000,
+			SlowRequestMS:                500,
+			NormalRequestLogMS:           200,
 		},
 		RepositoryTimeouts: RepositoryTimeoutsConfig{
 			DefaultQueryTimeout:  5,
@@ -793,6 +1227,17 @@ func getDefaultConfig() *Config {
 		Compression: CompressionConfig{
 			MinSizeBytes: 1024,
 			Level:        1,
+			SkipContentTypes: []string{
+				"image/jpeg", "image/png", "image/gif", "image/webp",
+				"application/zip", "application/gzip", "application/x-gzip", "application/x-7z-compressed",
+				"application/x-rar-compressed", "application/pdf", "application/octet-stream",
+				"video/", "audio/", "font/woff2",
+			},
+			SkipExtensions: []string{
+				".jpg", ".jpeg", ".png", ".gif", ".webp",
+				".zip", ".gz", ".tgz", ".rar", ".7z", ".pdf",
+				".mp4", ".mp3", ".woff2",
+			},
 		},
 		Pagination: PaginationConfig{
 			DefaultPageSize:      20,
@@ -801,9 +1246,25 @@ func getDefaultConfig() *Config {
 			MaxLimit:             100,
 			HistoryDefaultLimit:  10,
 			AvatarHistoryMaxList: 50,
+			FeedFollowingMax:     500,
 		},
 		ImageUpload: ImageUploadConfig{
-			MaxSizeMB: 5,
+			MaxSizeMB:        5,
+			MaxWidth:         8192,
+			MaxHeight:        8192,
+			ThumbnailMaxEdge: 320,
+			ThumbnailQuality: 80,
+			StripMetadata:    true,
+		},
+		AvatarUpload: AvatarUploadConfig{
+			MaxSizeKB:           5,
+			MaxWidth:            1024,
+			MaxHeight:           1024,
+			MaxAspectRatio:      1.2,
+			ProcessMaxSizeKB:    5120,
+			ProcessMaxDimension: 4096,
+			OutputSize:          256,
+			JPEGQuality:         85,
 		},
 		DatabaseQuery: DatabaseQueryConfig{
 			SlowQueryThresholdMS: 50,
@@ -840,6 +1301,11 @@ func getDefaultConfig() *Config {
 			RequestBodyTruncateSize: 512,
 			SampleRateProduction:    10,
 			SampleRateDevelopment:   100,
+			AccessLogFields: []string{
+				"method", "path", "query", "status", "latency", "ip", "user_agent",
+				"user_id", "request_id", "request_body", "response_body",
+			},
+			SensitiveKeys: append([]string(nil), DefaultSensitiveLogKeys...),
 		},
 		SecurityHeaders: SecurityHeadersConfig{
 			XFrameOptions:         "DENY",
@@ -852,15 +1318,20 @@ func getDefaultConfig() *Config {
 			HSTSMaxAge:            31536000, // 1年
 		},
 		SecurityPassword: SecurityPasswordConfig{
-			BcryptCostMin:    10,
-			BcryptCostMax:    14,
-			PasswordMaxBytes: 72,
+			BcryptCostMin:       10,
+			BcryptCostMax:       14,
+			PasswordMaxBytes:    72,
+			BcryptCost:          12,
+			CalibrateCost:       false,
+			CalibrationTargetMs: 250,
+			RehashOnLogin:       true,
 		},
 		SecuritySQL: SecuritySQLConfig{
 			KeywordsBlacklist: []string{"select", "insert", "update", "delete", "drop", "union", "exec", "script", "javascript"},
 		},
 		ValidationExtended: ValidationExtendedConfig{
 			URLMinLength:           7,
+			URLMaxLength:           100,
 			PhoneFirstDigit:        "1",
 			PhoneSecondDigitMin:    "3",
 			PhoneSecondDigitMax:    "9",
@@ -871,7 +1342,13 @@ func getDefaultConfig() *Config {
 			CommentContentMax:      1000,
 		},
 		JWTExtended: JWTExtendedConfig{
-			TokenPrefix: "Bearer ",
+			TokenPrefix:        "Bearer ",
+			AccessTokenMinutes: 30,
+			RefreshTokenDays:   30,
+			RefreshTokenBytes:  32,
+
+			BlacklistCapacity:    100000,
+			TokenVersionCacheSec: 30,
 		},
 		DateTimeFormats: DateTimeFormatsConfig{
 			DateOnly:     "2006-01-02",
@@ -879,9 +1356,14 @@ func getDefaultConfig() *Config {
 			RFC3339:      "RFC3339",
 		},
 		MinioAdvanced: MinioAdvancedConfig{
-			PolicyVersion: "2012-10-17",
-			PolicyEffect:  "Allow",
-			PolicyAction:  "s3:GetObject",
+			PolicyVersion:            "2012-10-17",
+			PolicyEffect:             "Allow",
+			PolicyAction:             "s3:GetObject",
+			PresignedExpirySec:       300,
+			MaxRetries:               3,
+			RetryBackoffBaseMS:       200,
+			RetryBufferMaxMB:         16,
+			TempSweepIntervalMinutes: 60,
 		},
 		DatabaseQueryAdvanced: DatabaseQueryAdvancedConfig{
 			QueryLogTruncateLength: 200,
@@ -889,6 +1371,94 @@ func getDefaultConfig() *Config {
 		},
 		StatisticsQueryExtended: StatisticsQueryExtendedConfig{
 			DefaultDateRangeDays: 7,
+			MaxExportRangeDays:   366,
+			ExportTimeoutSec:     120,
+		},
+		APIToken: APITokenConfig{
+			TokenPrefix:       "sqt_",
+			MaxTokensPerUser:  10,
+			MaxExpireDays:     365,
+			LastUsedUpdateSec: 3,
+			RouteScopes: map[string]string{
+				"account":   "account",
+				"articles":  "articles",
+				"chat":      "chat",
+				"messages":  "messages",
+				"resources": "resources",
+				"code":      "code",
+				"admin":     "admin",
+			},
+		},
+		Comments: CommentsConfig{
+			MaxDepth:         3,
+			EditWindowMin:    15,
+			EditHistoryLimit: 5,
+			InlineReplies:    3,
+		},
+		StartupRetry: StartupRetryConfig{
+			MaxElapsedSeconds: 60,
+			InitialIntervalMS: 500,
+			MaxIntervalMS:     10000,
+			Multiplier:        2,
+		},
+		SMTP: SMTPConfig{
+			Driver:   "noop",
+			Port:     587,
+			FromName: "社区",
+		},
+		Alerts: AlertsConfig{
+			WindowMinutes:    5,
+			MinRequests:      20,
+			ErrorRatePercent: 5,
+			P99LatencyMs:     2000,
+			MaxEndpoints:     500,
+
+			SlowQueryWindowMinutes: 10,
+			SlowQueryMinCount:      5,
+			SlowQueryMaxPatterns:   200,
+		},
+		Redirect: RedirectConfig{
+			AllowedSchemes: []string{"https", "http"},
+		},
+		Moderation: ModerationConfig{
+			Enabled:                true,
+			Action:                 "reject",
+			Words:                  []string{},
+			Patterns:               []string{},
+			MaskChar:               "*",
+			ViolationWindowMinutes: 10,
+			ViolationThreshold:     3,
+			MuteMinutes:            30,
+		},
+		Demotion: DemotionConfig{
+			Enabled:         true,
+			ReportThreshold: 5,
+			MinViews:        200,
+			MinLikeRatio:    0.002,
+			Factor:          0.3,
+		},
+		TokenCleanup: TokenCleanupConfig{
+			Enabled:         true,
+			IntervalMinutes: 60,
+			BatchSize:       1000,
+			PasswordReset:   TokenCleanupTableConfig{Enabled: true, GraceHours: 24},
+			RefreshTokens:   TokenCleanupTableConfig{Enabled: true, GraceHours: 24},
+			APITokens:       TokenCleanupTableConfig{Enabled: true, GraceHours: 720},
+		},
+		Reading: ReadingConfig{
+			WordsPerMinute: 300,
+		},
+		StatsAggregation: StatsAggregationConfig{
+			Enabled:              true,
+			RunAt:                "00:10",
+			FlushIntervalMinutes: 10,
+		},
+		Antivirus: AntivirusConfig{
+			Enabled:        false,
+			ClamdAddress:   "127.0.0.1:3310",
+			TimeoutSeconds: 60,
+			OnUnavailable:  AntivirusPolicyAllow,
+			SkipBuckets:    []string{"user-avatars", "resource-previews", "document-images", "article-images"},
 		},
 	}
 }
@@ -937,10 +1507,14 @@ func overrideWithEnvVars(config *Config) {
 	setEnvString(&config.Database.Username, "DB_USERNAME")
 	setEnvString(&config.Database.Password, "DB_PASSWORD")
 	setEnvString(&config.Database.Database, "DB_DATABASE")
+	setEnvBool(&config.DatabaseTimeouts.StatementTimeoutEnabled, "DB_STATEMENT_TIMEOUT_ENABLED")
+	setEnvInt(&config.DatabaseTimeouts.MaxExecutionTimeMS, "DB_MAX_EXECUTION_TIME_MS")
 
 	// JWT配置
 	setEnvString(&config.JWT.SecretKey, "JWT_SECRET")
 	setEnvInt(&config.JWT.ExpireHours, "JWT_EXPIRE_HOURS")
+	setEnvInt(&config.JWTExtended.AccessTokenMinutes, "JWT_ACCESS_TOKEN_MINUTES")
+	setEnvInt(&config.JWTExtended.RefreshTokenDays, "JWT_REFRESH_TOKEN_DAYS")
 
 	// 日志配置
 	setEnvString(&config.Log.Level, "LOG_LEVEL")
@@ -953,6 +1527,14 @@ func overrideWithEnvVars(config *Config) {
 	setEnvString(&config.MinIO.SecretAccessKey, "MINIO_SECRET_KEY")
 	setEnvBool(&config.MinIO.UseSSL, "MINIO_USE_SSL")
 
+	// 邮件配置
+	setEnvString(&config.SMTP.Driver, "SMTP_DRIVER")
+	setEnvString(&config.SMTP.Host, "SMTP_HOST")
+	setEnvInt(&config.SMTP.Port, "SMTP_PORT")
+	setEnvString(&config.SMTP.Username, "SMTP_USERNAME")
+	setEnvString(&config.SMTP.Password, "SMTP_PASSWORD")
+	setEnvString(&config.SMTP.From, "SMTP_FROM")
+
 	// 代码执行器配置
 	setEnvString(&config.CodeExecutor.PistonAPIURL, "PISTON_API_URL")
 	setEnvInt(&config.CodeExecutor.Timeout, "CODE_EXECUTOR_TIMEOUT")
@@ -985,6 +1567,11 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("server.mode must be one of: debug, release, test")
 	}
 
+	// 验证日志配置
+	if c.Log.Format != "json" && c.Log.Format != "text" {
+		return fmt.Errorf("log.format must be one of: json, text")
+	}
+
 	// 验证数据库配置
 	if c.Database.Host == "" {
 		return fmt.Errorf("database.host is required")
@@ -1015,6 +1602,15 @@ func (c *Config) Validate() error {
 	if c.JWT.ExpireHours <= 0 {
 		return fmt.Errorf("jwt.expire_hours must be positive")
 	}
+	if c.JWTExtended.AccessTokenMinutes < 0 {
+		return fmt.Errorf("jwt_extended.access_token_minutes cannot be negative")
+	}
+	if c.JWTExtended.RefreshTokenDays <= 0 || c.JWTExtended.RefreshTokenBytes < 16 {
+		return fmt.Errorf("jwt_extended.refresh_token_days must be positive and refresh_token_bytes at least 16")
+	}
+	if c.JWTExtended.BlacklistCapacity <= 0 || c.JWTExtended.TokenVersionCacheSec <= 0 {
+		return fmt.Errorf("jwt_extended.blacklist_capacity and token_version_cache_sec must be positive")
+	}
 
 	// 验证MinIO配置
 	if c.MinIO.Endpoint == "" {
@@ -1031,9 +1627,358 @@ func (c *Config) Validate() error {
 	if c.BucketUserAvatars.Name == "" {
 		return fmt.Errorf("bucket_user_avatars.name is required")
 	}
+	if e := c.BucketUserAvatars.PresignExpiryMinutes; e <= 0 || e > 7*24*60 {
+		return fmt.Errorf("bucket_user_avatars.presign_expiry_minutes must be between 1 and 10080")
+	}
+	for _, pattern := range c.BucketUserAvatars.PublicPaths {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("bucket_user_avatars.public_paths contains invalid pattern %q", pattern)
+		}
+	}
 	if c.BucketResourceChunks.Name == "" {
 		return fmt.Errorf("bucket_resource_chunks.name is required")
 	}
 
+	// 验证用户限流配置
+	if c.RateLimiter.User.Capacity <= 0 || c.RateLimiter.User.RequestsPerMinute <= 0 || c.RateLimiter.User.MaxCacheSize <= 0 {
+		return fmt.Errorf("rate_limiter.user capacity, requests_per_minute and max_cache_size must be positive")
+	}
+
+	// 验证匿名敏感操作限流配置
+	for name, item := range map[string]RateLimiterItemConfig{
+		"sensitive_ip":    c.RateLimiter.SensitiveIP,
+		"sensitive_email": c.RateLimiter.SensitiveEmail,
+	} {
+		if item.Capacity <= 0 || item.RequestsPerMinute <= 0 || item.MaxCacheSize <= 0 {
+			return fmt.Errorf("rate_limiter.%s capacity, requests_per_minute and max_cache_size must be positive", name)
+		}
+	}
+	if c.RateLimiter.SensitiveMinResponseMs < 0 {
+		return fmt.Errorf("rate_limiter.sensitive_min_response_ms must be non-negative")
+	}
+
+	// 验证限流豁免网段和可信代理
+	for field, entries := range map[string][]string{
+		"rate_limiter.exempt.cidrs": c.RateLimiter.Exempt.CIDRs,
+		"server.trusted_proxies":    c.Server.TrustedProxies,
+	} {
+		for _, entry := range entries {
+			if _, _, err := net.ParseCIDR(entry); err != nil && net.ParseIP(entry) == nil {
+				return fmt.Errorf("%s contains invalid IP or CIDR %q", field, entry)
+			}
+		}
+	}
+
+	// 验证热门文章刷新配置
+	if hot := c.Cache.HotArticles; hot.Enabled && (hot.TopN <= 0 || hot.RefreshIntervalSec <= 0) {
+		return fmt.Errorf("cache.hot_articles.top_n and refresh_interval_sec must be positive when enabled")
+	}
+	if c.Cache.HotArticles.DecayGravity <= 0 {
+		return fmt.Errorf("cache.hot_articles.decay_gravity must be positive")
+	}
+	if dedup := c.Cache.ViewDedup; dedup.WindowMinutes < 0 || (dedup.WindowMinutes > 0 && dedup.Capacity <= 0) {
+		return fmt.Errorf("cache.view_dedup.window_minutes must be non-negative and capacity positive when enabled")
+	}
+	if c.StatisticsQueryExtended.DefaultDateRangeDays <= 0 {
+		return fmt.Errorf("statistics_query_extended.default_date_range_days must be positive")
+	}
+
+	// 验证API令牌配置（前缀不能为空，否则无法与JWT区分）
+	if c.APIToken.TokenPrefix == "" {
+		return fmt.Errorf("api_token.token_prefix is required")
+	}
+	for group, resource := range c.APIToken.RouteScopes {
+		if resource == "" || strings.ContainsAny(resource, ":, ") {
+			return fmt.Errorf("api_token.route_scopes.%s must be a non-empty name without ':', ',' or spaces", group)
+		}
+	}
+
+	// 验证评论层级（一级评论占1层，限制层级时至少为2）
+	if c.Comments.MaxDepth < 0 || c.Comments.MaxDepth == 1 {
+		return fmt.Errorf("comments.max_depth must be 0 (unlimited) or at least 2")
+	}
+	if c.Comments.EditWindowMin < 0 || c.Comments.EditHistoryLimit < 0 {
+		return fmt.Errorf("comments.edit_window_min and edit_history_limit must be non-negative")
+	}
+	if c.Comments.InlineReplies < 0 || c.Comments.InlineReplies > c.Pagination.MaxPageSize {
+		return fmt.Errorf("comments.inline_replies must be between 0 and pagination.max_page_size")
+	}
+
+	// 验证关注流作者数量上限（限制 IN 子句大小）
+	if c.Pagination.FeedFollowingMax <= 0 {
+		return fmt.Errorf("pagination.feed_following_max must be positive")
+	}
+
+	// 验证单请求并发上限
+	if c.WorkerPool.MaxGoroutinesPerRequest <= 0 {
+		return fmt.Errorf("worker_pool.max_goroutines_per_request must be positive")
+	}
+	if p := c.WorkerPool.ShutdownPolicy; p != "inline" && p != "reject" {
+		return fmt.Errorf("worker_pool.shutdown_policy must be inline or reject")
+	}
+	if c.AsyncTasks.RetryMaxAttempts < 1 || c.AsyncTasks.RetryBackoffBaseMS <= 0 {
+		return fmt.Errorf("async_tasks.retry_max_attempts must be at least 1 and retry_backoff_base_ms must be positive")
+	}
+	if c.WorkerPool.ShutdownTimeout <= 0 {
+		return fmt.Errorf("worker_pool.shutdown_timeout must be positive")
+	}
+	if p := c.WorkerPool.QueueFullPolicy; p != "reject" && p != "block" {
+		return fmt.Errorf("worker_pool.queue_full_policy must be reject or block")
+	}
+	if c.WorkerPool.QueueFullPolicy == "block" && c.WorkerPool.QueueFullWaitMS <= 0 {
+		return fmt.Errorf("worker_pool.queue_full_wait_ms must be positive when queue_full_policy is block")
+	}
+	if m := c.WorkerPool.QueueHighWaterMark; m < 0 || m > c.WorkerPool.QueueSize {
+		return fmt.Errorf("worker_pool.queue_high_water_mark must be between 0 and queue_size")
+	}
+
+	// 验证登录锁定配置
+	if c.Security.MaxLoginAttempts <= 0 || c.Security.LockoutMinutes <= 0 {
+		return fmt.Errorf("security.max_login_attempts and lockout_minutes must be positive")
+	}
+	if c.Security.LoginWarnRemaining < 0 {
+		return fmt.Errorf("security.login_warn_remaining must be non-negative")
+	}
+
+	// 验证请求体大小限制
+	if c.Security.MaxRequestSizeMB <= 0 || c.Security.JSONMaxSizeKB <= 0 || c.Security.MultipartOverheadKB < 0 {
+		return fmt.Errorf("security.max_request_size_mb and json_max_size_kb must be positive, multipart_overhead_kb non-negative")
+	}
+
+	// 验证启动重试配置
+	if c.StartupRetry.MaxElapsedSeconds < 0 {
+		return fmt.Errorf("startup_retry.max_elapsed_seconds must be non-negative")
+	}
+	if c.StartupRetry.MaxElapsedSeconds > 0 {
+		if c.StartupRetry.InitialIntervalMS <= 0 || c.StartupRetry.MaxIntervalMS < c.StartupRetry.InitialIntervalMS {
+			return fmt.Errorf("startup_retry.initial_interval_ms must be positive and not exceed max_interval_ms")
+		}
+		if c.StartupRetry.Multiplier < 1 {
+			return fmt.Errorf("startup_retry.multiplier must be at least 1")
+		}
+	}
+
+	// 验证密码重置链接地址
+	if c.AuthPolicy.PasswordResetURL == "" {
+		return fmt.Errorf("auth_policy.password_reset_url is required")
+	}
+
+	// 验证邮件配置
+	switch c.SMTP.Driver {
+	case "noop":
+	case "smtp":
+		if c.SMTP.Host == "" || c.SMTP.Port <= 0 || c.SMTP.From == "" {
+			return fmt.Errorf("smtp.host, smtp.port and smtp.from are required when smtp.driver is smtp")
+		}
+	default:
+		return fmt.Errorf("smtp.driver must be smtp or noop")
+	}
+
+	// 验证告警配置
+	if a := c.Alerts; a.WindowMinutes <= 0 || a.MinRequests < 0 || a.ErrorRatePercent <= 0 || a.P99LatencyMs <= 0 || a.MaxEndpoints <= 0 {
+		return fmt.Errorf("alerts.window_minutes, error_rate_percent, p99_latency_ms and max_endpoints must be positive")
+	}
+	if a := c.Alerts; a.SlowQueryWindowMinutes <= 0 || a.SlowQueryMinCount <= 0 || a.SlowQueryMaxPatterns <= 0 {
+		return fmt.Errorf("alerts.slow_query_window_minutes, slow_query_min_count and slow_query_max_patterns must be positive")
+	}
+
+	// 验证图片尺寸限制
+	if c.ImageUpload.MaxWidth <= 0 || c.ImageUpload.MaxHeight <= 0 ||
+		c.AvatarUpload.MaxWidth <= 0 || c.AvatarUpload.MaxHeight <= 0 {
+		return fmt.Errorf("image_upload and avatar_upload max_width/max_height must be positive")
+	}
+	if c.AvatarUpload.MaxAspectRatio < 1 {
+		return fmt.Errorf("avatar_upload.max_aspect_ratio must be at least 1")
+	}
+	if a := c.AvatarUpload; a.MaxSizeKB <= 0 || a.ProcessMaxSizeKB <= 0 || a.ProcessMaxDimension <= 0 || a.OutputSize <= 0 {
+		return fmt.Errorf("avatar_upload.max_size_kb, process_max_size_kb, process_max_dimension and output_size must be positive")
+	}
+	if c.AvatarUpload.JPEGQuality < 1 || c.AvatarUpload.JPEGQuality > 100 {
+		return fmt.Errorf("avatar_upload.jpeg_quality must be between 1 and 100")
+	}
+	if c.ImageUpload.ThumbnailMaxEdge <= 0 {
+		return fmt.Errorf("image_upload.thumbnail_max_edge must be positive")
+	}
+	if c.ImageUpload.ThumbnailQuality < 1 || c.ImageUpload.ThumbnailQuality > 100 {
+		return fmt.Errorf("image_upload.thumbnail_quality must be between 1 and 100")
+	}
+
+	// 验证统计导出配置
+	if c.StatisticsQueryExtended.MaxExportRangeDays <= 0 || c.StatisticsQueryExtended.ExportTimeoutSec <= 0 {
+		return fmt.Errorf("statistics_query_extended.max_export_range_days and export_timeout_sec must be positive")
+	}
+
+	// 验证预签名URL有效期（S3签名最长7天）
+	if e := c.MinioAdvanced.PresignedExpirySec; e <= 0 || e > 7*24*3600 {
+		return fmt.Errorf("minio_advanced.presigned_expiry_sec must be between 1 and 604800")
+	}
+
+	// 验证内容审核配置
+	if m := c.Moderation; m.Action != "reject" && m.Action != "mask" {
+		return fmt.Errorf("moderation.action must be reject or mask")
+	}
+	if utf8.RuneCountInString(c.Moderation.MaskChar) != 1 {
+		return fmt.Errorf("moderation.mask_char must be a single character")
+	}
+	if m := c.Moderation; m.ViolationWindowMinutes <= 0 || m.ViolationThreshold < 0 || m.MuteMinutes <= 0 {
+		return fmt.Errorf("moderation.violation_window_minutes and mute_minutes must be positive, violation_threshold must not be negative")
+	}
+	for _, pattern := range c.Moderation.Patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("moderation.patterns contains invalid regexp %q: %w", pattern, err)
+		}
+	}
+
+	// 验证WebSocket慢客户端断开策略
+	if ws := c.WebSocket; ws.SlowClientMaxDrops < 0 || (ws.SlowClientMaxDrops > 0 && ws.SlowClientWindowSec <= 0) {
+		return fmt.Errorf("websocket.slow_client_max_drops must not be negative and slow_client_window_sec must be positive when enabled")
+	}
+	if ws := c.WebSocket; ws.OversizeHardLimit < ws.MaxMessageSize {
+		return fmt.Errorf("websocket.oversize_hard_limit must not be less than max_message_size")
+	}
+	if ws := c.WebSocket; ws.OversizeMaxViolations < 0 || (ws.OversizeMaxViolations > 0 && (ws.OversizeWindowSec <= 0 || ws.OversizeBanSec <= 0)) {
+		return fmt.Errorf("websocket.oversize_max_violations must not be negative and oversize_window_sec/oversize_ban_sec must be positive when enabled")
+	}
+	if cc := c.CodeCollab; cc.SessionTTLMinutes <= 0 || cc.SnapshotIntervalSec <= 0 || cc.MaxParticipants <= 0 || cc.MaxCodeBytes <= 0 {
+		return fmt.Errorf("code_collab.session_ttl_minutes, snapshot_interval_sec, max_participants and max_code_bytes must be positive")
+	}
+	if cs := c.CodeShare; cs.DefaultExpiryHours < 0 || cs.MaxExpiryHours < 0 ||
+		(cs.MaxExpiryHours > 0 && (cs.DefaultExpiryHours == 0 || cs.DefaultExpiryHours > cs.MaxExpiryHours)) {
+		return fmt.Errorf("code_share.default_expiry_hours must be between 1 and max_expiry_hours when max_expiry_hours is set, and neither may be negative")
+	}
+	if ma := c.MinioAdvanced; ma.MaxRetries < 0 || ma.RetryBufferMaxMB < 0 || (ma.MaxRetries > 0 && ma.RetryBackoffBaseMS <= 0) {
+		return fmt.Errorf("minio_advanced.max_retries and retry_buffer_max_mb must not be negative, and retry_backoff_base_ms must be positive when retries are enabled")
+	}
+	if c.MinioAdvanced.TempSweepIntervalMinutes < 0 || c.BucketTempFiles.AutoExpireHours < 0 {
+		return fmt.Errorf("minio_advanced.temp_sweep_interval_minutes and bucket_temp_files.auto_expire_hours must not be negative")
+	}
+	if c.Readiness.CheckTimeoutMs <= 0 || c.Readiness.ProbeCacheSeconds < 0 {
+		return fmt.Errorf("readiness.check_timeout_ms must be positive and probe_cache_seconds must not be negative")
+	}
+	if le := c.LogExtended; le.SampleRateProduction < 0 || le.SampleRateProduction > 100 ||
+		le.SampleRateDevelopment < 0 || le.SampleRateDevelopment > 100 || le.RequestBodyTruncateSize < 0 {
+		return fmt.Errorf("log_extended sample rates must be between 0 and 100 and request_body_truncate_size must not be negative")
+	}
+	validAccessLogFields := make(map[string]bool, len(AccessLogFieldNames))
+	for _, field := range AccessLogFieldNames {
+		validAccessLogFields[field] = true
+	}
+	for _, field := range c.LogExtended.AccessLogFields {
+		if !validAccessLogFields[field] {
+			return fmt.Errorf("log_extended.access_log_fields contains unknown field %q", field)
+		}
+	}
+	for _, key := range c.LogExtended.SensitiveKeys {
+		if strings.TrimSpace(key) == "" {
+			return fmt.Errorf("log_extended.sensitive_keys must not contain empty entries")
+		}
+	}
+	if c.DatabaseTimeouts.PoolMonitorInterval <= 0 {
+		return fmt.Errorf("database_timeouts.pool_monitor_interval must be positive")
+	}
+	if pm := c.PerformanceMonitoring; pm.DBPoolSampleSeconds <= 0 || pm.DBPoolWaitCountWarning <= 0 ||
+		pm.DBPoolWaitDurationWarningMS < 0 || pm.DBPoolSuggestHeadroomPercent < 0 {
+		return fmt.Errorf("performance_monitoring.db_pool_sample_seconds and db_pool_wait_count_warning must be positive, db_pool_wait_duration_warning_ms and db_pool_suggest_headroom_percent must not be negative")
+	}
+	if c.BatchOperations.MaxDeleteItems <= 0 {
+		return fmt.Errorf("batch_operations.max_delete_items must be positive")
+	}
+	if c.BatchOperations.MaxModerationItems <= 0 {
+		return fmt.Errorf("batch_operations.max_moderation_items must be positive")
+	}
+	if c.WebSocket.MaxConnections < 0 {
+		return fmt.Errorf("websocket.max_connections must not be negative")
+	}
+	if c.WebSocket.MaxConnectionsPerUser <= 0 {
+		return fmt.Errorf("websocket.max_connections_per_user must be positive")
+	}
+
+	// 验证过期令牌清理配置
+	if tc := c.TokenCleanup; tc.Enabled && (tc.IntervalMinutes <= 0 || tc.BatchSize <= 0) {
+		return fmt.Errorf("token_cleanup.interval_minutes and batch_size must be positive when enabled")
+	}
+	if tc := c.TokenCleanup; tc.PasswordReset.GraceHours < 0 || tc.RefreshTokens.GraceHours < 0 || tc.APITokens.GraceHours < 0 {
+		return fmt.Errorf("token_cleanup grace_hours must not be negative")
+	}
+
+	// 验证每日统计汇总配置
+	if sa := c.StatsAggregation; sa.Enabled {
+		if _, err := time.Parse("15:04", sa.RunAt); err != nil {
+			return fmt.Errorf("stats_aggregation.run_at must be HH:MM")
+		}
+		if sa.FlushIntervalMinutes <= 0 {
+			return fmt.Errorf("stats_aggregation.flush_interval_minutes must be positive when enabled")
+		}
+	}
+
+	// 验证阅读时长配置
+	if c.Reading.WordsPerMinute <= 0 {
+		return fmt.Errorf("reading.words_per_minute must be positive")
+	}
+
+	// 验证病毒扫描配置
+	if av := c.Antivirus; av.Enabled && (av.ClamdAddress == "" || av.TimeoutSeconds <= 0) {
+		return fmt.Errorf("antivirus.clamd_address is required and timeout_seconds must be positive when antivirus is enabled")
+	}
+	if p := c.Antivirus.OnUnavailable; p != AntivirusPolicyAllow && p != AntivirusPolicyReject {
+		return fmt.Errorf("antivirus.on_unavailable must be allow or reject")
+	}
+
+	// 验证低质量内容降权配置（系数必须大于0，降权只压低排名不隐藏内容）
+	if d := c.Demotion; d.Enabled && (d.Factor <= 0 || d.Factor > 1) {
+		return fmt.Errorf("demotion.factor must be in (0, 1]")
+	}
+	if d := c.Demotion; d.ReportThreshold < 0 || d.MinViews < 0 || d.MinLikeRatio < 0 || d.MinLikeRatio >= 1 {
+		return fmt.Errorf("demotion.report_threshold and min_views must not be negative, min_like_ratio must be in [0, 1)")
+	}
+
+	// 验证跳转白名单配置
+	if len(c.Redirect.AllowedSchemes) == 0 {
+		return fmt.Errorf("redirect.allowed_schemes must not be empty")
+	}
+
+	// 验证个人资料链接长度
+	if sp := c.SecurityPassword; sp.BcryptCostMin < 4 || sp.BcryptCostMax > 31 || sp.BcryptCostMin > sp.BcryptCostMax ||
+		sp.BcryptCost < sp.BcryptCostMin || sp.BcryptCost > sp.BcryptCostMax {
+		return fmt.Errorf("security_password.bcrypt_cost must be within bcrypt_cost_min and bcrypt_cost_max (4-31)")
+	}
+	if sp := c.SecurityPassword; sp.PasswordMaxBytes <= 0 || sp.PasswordMaxBytes > 72 || (sp.CalibrateCost && sp.CalibrationTargetMs <= 0) {
+		return fmt.Errorf("security_password.password_max_bytes must be between 1 and 72, and calibration_target_ms must be positive when calibrate_cost is enabled")
+	}
+	if pp := c.Validation.Password.Policy; pp.MinLetters < 0 || pp.MinUppercase < 0 || pp.MinLowercase < 0 ||
+		pp.MinDigits < 0 || pp.MinSpecial < 0 || pp.CommonTopN < 0 {
+		return fmt.Errorf("validation.password.policy counts must not be negative")
+	}
+	if pp := c.Validation.Password.Policy; max(pp.MinLetters, pp.MinUppercase+pp.MinLowercase)+pp.MinDigits+pp.MinSpecial > c.Validation.Password.MaxLength {
+		return fmt.Errorf("validation.password.policy requires more characters than validation.password.max_length allows")
+	}
+	if c.ValidationExtended.URLMaxLength < c.ValidationExtended.URLMinLength {
+		return fmt.Errorf("validation_extended.url_max_length must be at least url_min_length")
+	}
+
+	// 验证在线用户清理配置
+	if c.Metrics.OnlineUsersInitialCapacity <= 0 || c.Metrics.OnlineUserCleanupInterval <= 0 ||
+		c.Metrics.OnlineUserExpireTime <= 0 || c.Metrics.OnlineUsersMaxMultiplier <= 0 {
+		return fmt.Errorf("metrics online user capacity, cleanup interval, expire time and max multiplier must be positive")
+	}
+
+	// 验证Prometheus指标前缀（允许为空；只能包含字母、数字和下划线，且不能以数字开头）
+	if !isValidMetricPrefix(c.Metrics.PrometheusPrefix) {
+		return fmt.Errorf("metrics.prometheus_prefix must match [a-zA-Z_][a-zA-Z0-9_]*")
+	}
+
 	return nil
 }
+
+// isValidMetricPrefix 校验Prometheus指标名前缀
+func isValidMetricPrefix(prefix string) bool {
+	for i, ch := range prefix {
+		switch {
+		case ch == '_', ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z':
+		case ch >= '0' && ch <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
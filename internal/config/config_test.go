@@ -0,0 +1,385 @@
+package config
+
+import "testing"
+
+func TestValidateDemotionKeepsContentVisible(t *testing.T) {
+	useConfigFile(t)
+	base := *Load()
+	if err := base.Validate(); err != nil {
+		t.Fatalf("默认配置应通过校验: %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		modify func(d *DemotionConfig)
+	}{
+		{"系数为0会把文章排到看不见", func(d *DemotionConfig) { d.Factor = 0 }},
+		{"系数为负", func(d *DemotionConfig) { d.Factor = -0.5 }},
+		{"系数大于1会提升排名", func(d *DemotionConfig) { d.Factor = 1.5 }},
+		{"举报阈值为负", func(d *DemotionConfig) { d.ReportThreshold = -1 }},
+		{"点赞率不小于1", func(d *DemotionConfig) { d.MinLikeRatio = 1 }},
+	}
+	for _, tc := range cases {
+		cfg := base
+		tc.modify(&cfg.Demotion)
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%s: 应校验失败", tc.name)
+		}
+	}
+
+	// 关闭降权时不校验系数
+	cfg := base
+	cfg.Demotion.Enabled = false
+	cfg.Demotion.Factor = 0
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("关闭降权时系数不生效，不应校验失败: %v", err)
+	}
+}
+
+func TestValidateWebSocketOversize(t *testing.T) {
+	useConfigFile(t)
+	base := *Load()
+
+	cases := []struct {
+		name   string
+		modify func(ws *WebSocketConfig)
+	}{
+		{"硬上限小于单条消息上限", func(ws *WebSocketConfig) { ws.OversizeHardLimit = ws.MaxMessageSize - 1 }},
+		{"违规次数为负", func(ws *WebSocketConfig) { ws.OversizeMaxViolations = -1 }},
+		{"启用时窗口为0", func(ws *WebSocketConfig) { ws.OversizeWindowSec = 0 }},
+		{"启用时禁止时长为0", func(ws *WebSocketConfig) { ws.OversizeBanSec = 0 }},
+	}
+	for _, tc := range cases {
+		cfg := base
+		tc.modify(&cfg.WebSocket)
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%s: 应校验失败", tc.name)
+		}
+	}
+
+	// 不处理超大消息时不校验窗口和禁止时长
+	cfg := base
+	cfg.WebSocket.OversizeMaxViolations = 0
+	cfg.WebSocket.OversizeWindowSec = 0
+	cfg.WebSocket.OversizeBanSec = 0
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("oversize_max_violations 为0时不应校验失败: %v", err)
+	}
+}
+
+func TestValidateTokenCleanup(t *testing.T) {
+	useConfigFile(t)
+	base := *Load()
+
+	cases := []struct {
+		name   string
+		modify func(tc *TokenCleanupConfig)
+	}{
+		{"启用时间隔为0", func(tc *TokenCleanupConfig) { tc.IntervalMinutes = 0 }},
+		{"启用时批大小为0", func(tc *TokenCleanupConfig) { tc.BatchSize = 0 }},
+		{"宽限期为负", func(tc *TokenCleanupConfig) { tc.RefreshTokens.GraceHours = -1 }},
+	}
+	for _, tc := range cases {
+		cfg := base
+		tc.modify(&cfg.TokenCleanup)
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%s: 应校验失败", tc.name)
+		}
+	}
+
+	cfg := base
+	cfg.TokenCleanup.Enabled = false
+	cfg.TokenCleanup.IntervalMinutes = 0
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("未启用清理时不校验间隔: %v", err)
+	}
+}
+
+func TestValidateReadingWordsPerMinute(t *testing.T) {
+	useConfigFile(t)
+	base := *Load()
+
+	for _, wpm := range []int{0, -1} {
+		cfg := base
+		cfg.Reading.WordsPerMinute = wpm
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("words_per_minute=%d 应校验失败", wpm)
+		}
+	}
+	if base.Reading.WordsPerMinute <= 0 {
+		t.Fatalf("默认每分钟阅读字数应为正数，实际 %d", base.Reading.WordsPerMinute)
+	}
+}
+
+func TestValidateCommentInlineReplies(t *testing.T) {
+	useConfigFile(t)
+	base := *Load()
+
+	for _, n := range []int{-1, base.Pagination.MaxPageSize + 1} {
+		cfg := base
+		cfg.Comments.InlineReplies = n
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("inline_replies=%d 应校验失败", n)
+		}
+	}
+	cfg := base
+	cfg.Comments.InlineReplies = 0
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("inline_replies=0 表示返回完整回复树，应允许: %v", err)
+	}
+}
+
+func TestValidateSlowQueryAlerts(t *testing.T) {
+	useConfigFile(t)
+	base := *Load()
+
+	cases := []struct {
+		name   string
+		modify func(a *AlertsConfig)
+	}{
+		{"窗口为0", func(a *AlertsConfig) { a.SlowQueryWindowMinutes = 0 }},
+		{"次数阈值为0", func(a *AlertsConfig) { a.SlowQueryMinCount = 0 }},
+		{"指纹上限为负", func(a *AlertsConfig) { a.SlowQueryMaxPatterns = -1 }},
+	}
+	for _, tc := range cases {
+		cfg := base
+		tc.modify(&cfg.Alerts)
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%s: 应校验失败", tc.name)
+		}
+	}
+}
+
+func TestValidateTrendingSort(t *testing.T) {
+	useConfigFile(t)
+	base := *Load()
+
+	cfg := base
+	cfg.Cache.HotArticles.DecayGravity = 0
+	if err := cfg.Validate(); err == nil {
+		t.Error("衰减指数为0时应校验失败")
+	}
+
+	cfg = base
+	cfg.StatisticsQueryExtended.DefaultDateRangeDays = 0
+	if err := cfg.Validate(); err == nil {
+		t.Error("trending 排序的天数范围为0时应校验失败")
+	}
+}
+
+func TestValidateWebSocketConnectionLimits(t *testing.T) {
+	useConfigFile(t)
+	base := *Load()
+
+	cfg := base
+	cfg.WebSocket.MaxConnections = -1
+	if err := cfg.Validate(); err == nil {
+		t.Error("全局连接上限为负时应校验失败")
+	}
+
+	cfg = base
+	cfg.WebSocket.MaxConnectionsPerUser = 0
+	if err := cfg.Validate(); err == nil {
+		t.Error("每用户连接上限为0时应校验失败")
+	}
+
+	cfg = base
+	cfg.WebSocket.MaxConnections = 0
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("全局连接上限为0表示不限制，应校验通过: %v", err)
+	}
+}
+
+func TestValidateCodeShareExpiry(t *testing.T) {
+	useConfigFile(t)
+	base := *Load()
+
+	cases := []struct {
+		name   string
+		modify func(cs *CodeShareConfig)
+	}{
+		{"默认有效期为负", func(cs *CodeShareConfig) { cs.DefaultExpiryHours = -1 }},
+		{"最长有效期为负", func(cs *CodeShareConfig) { cs.MaxExpiryHours = -1 }},
+		{"限制最长有效期时默认永不过期", func(cs *CodeShareConfig) { cs.DefaultExpiryHours = 0 }},
+		{"默认有效期超过最长有效期", func(cs *CodeShareConfig) { cs.DefaultExpiryHours = cs.MaxExpiryHours + 1 }},
+	}
+	for _, tc := range cases {
+		cfg := base
+		tc.modify(&cfg.CodeShare)
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%s: 应校验失败", tc.name)
+		}
+	}
+
+	// 不限制最长有效期时允许默认永不过期
+	cfg := base
+	cfg.CodeShare = CodeShareConfig{DefaultExpiryHours: 0, MaxExpiryHours: 0}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("不限制有效期的配置应通过校验: %v", err)
+	}
+}
+
+func TestValidatePasswordPolicy(t *testing.T) {
+	useConfigFile(t)
+	base := *Load()
+
+	cfg := base
+	cfg.Validation.Password.Policy.MinDigits = -1
+	if err := cfg.Validate(); err == nil {
+		t.Error("策略中的数量为负时应校验失败")
+	}
+
+	// 各类字符的最低数量之和不能超过最大长度，否则任何密码都无法通过
+	cfg = base
+	cfg.Validation.Password.MaxLength = 10
+	cfg.Validation.Password.Policy = PasswordPolicyConfig{MinUppercase: 4, MinLowercase: 4, MinDigits: 2, MinSpecial: 1}
+	if err := cfg.Validate(); err == nil {
+		t.Error("策略要求的字符数超过最大长度时应校验失败")
+	}
+
+	cfg.Validation.Password.Policy.MinSpecial = 0
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("策略要求的字符数等于最大长度时应校验通过: %v", err)
+	}
+}
+
+func TestValidateSecurityPassword(t *testing.T) {
+	useConfigFile(t)
+	base := *Load()
+
+	cases := []struct {
+		name   string
+		modify func(sp *SecurityPasswordConfig)
+	}{
+		{"最小成本低于4", func(sp *SecurityPasswordConfig) { sp.BcryptCostMin = 3 }},
+		{"最大成本超过31", func(sp *SecurityPasswordConfig) { sp.BcryptCostMax = 32 }},
+		{"成本低于最小成本", func(sp *SecurityPasswordConfig) { sp.BcryptCost = sp.BcryptCostMin - 1 }},
+		{"成本高于最大成本", func(sp *SecurityPasswordConfig) { sp.BcryptCost = sp.BcryptCostMax + 1 }},
+		{"密码最大字节数超过bcrypt限制", func(sp *SecurityPasswordConfig) { sp.PasswordMaxBytes = 73 }},
+		{"启用校准但目标耗时为0", func(sp *SecurityPasswordConfig) { sp.CalibrateCost, sp.CalibrationTargetMs = true, 0 }},
+	}
+	for _, tc := range cases {
+		cfg := base
+		tc.modify(&cfg.SecurityPassword)
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%s: 应校验失败", tc.name)
+		}
+	}
+}
+
+func TestValidateViewDedup(t *testing.T) {
+	useConfigFile(t)
+	base := *Load()
+
+	cfg := base
+	cfg.Cache.ViewDedup = ViewDedupConfig{WindowMinutes: -1, Capacity: 100}
+	if err := cfg.Validate(); err == nil {
+		t.Error("去重窗口为负时应校验失败")
+	}
+	cfg.Cache.ViewDedup = ViewDedupConfig{WindowMinutes: 30, Capacity: 0}
+	if err := cfg.Validate(); err == nil {
+		t.Error("启用去重但容量为0时应校验失败")
+	}
+	cfg.Cache.ViewDedup = ViewDedupConfig{WindowMinutes: 0, Capacity: 0}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("关闭去重时不要求容量: %v", err)
+	}
+}
+
+func TestValidateMaxModerationItems(t *testing.T) {
+	useConfigFile(t)
+	cfg := *Load()
+	cfg.BatchOperations.MaxModerationItems = 0
+	if err := cfg.Validate(); err == nil {
+		t.Error("批量修改文章状态的上限为0时应校验失败")
+	}
+}
+
+func TestValidateDBPoolMonitor(t *testing.T) {
+	useConfigFile(t)
+	base := *Load()
+
+	cfg := base
+	cfg.DatabaseTimeouts.PoolMonitorInterval = 0
+	if err := cfg.Validate(); err == nil {
+		t.Error("连接池监控间隔为0时应校验失败")
+	}
+	cfg = base
+	cfg.PerformanceMonitoring.DBPoolWaitCountWarning = 0
+	if err := cfg.Validate(); err == nil {
+		t.Error("等待次数阈值为0时应校验失败")
+	}
+	cfg = base
+	cfg.PerformanceMonitoring.DBPoolSuggestHeadroomPercent = -1
+	if err := cfg.Validate(); err == nil {
+		t.Error("建议余量为负时应校验失败")
+	}
+	cfg = base
+	cfg.PerformanceMonitoring.DBPoolWaitDurationWarningMS = 0
+	cfg.PerformanceMonitoring.DBPoolSuggestHeadroomPercent = 0
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("等待时长阈值和余量可以为0: %v", err)
+	}
+}
+
+func TestBucketIsPublicObject(t *testing.T) {
+	private := false
+	cases := []struct {
+		name   string
+		bucket BucketConfig
+		object string
+		want   bool
+	}{
+		{"未配置路径时整个桶公开", BucketConfig{}, "alice/history/1.jpg", true},
+		{"匹配公开路径", BucketConfig{PublicPaths: []string{"*/current.jpg"}}, "alice/current.jpg", true},
+		{"不匹配公开路径", BucketConfig{PublicPaths: []string{"*/current.jpg"}}, "alice/history/1.jpg", false},
+		{"私有桶始终不公开", BucketConfig{PublicRead: &private}, "alice/current.jpg", false},
+	}
+	for _, tc := range cases {
+		if got := tc.bucket.IsPublicObject(tc.object); got != tc.want {
+			t.Errorf("%s: IsPublicObject(%q) = %v，期望 %v", tc.name, tc.object, got, tc.want)
+		}
+	}
+}
+
+func TestValidateUserAvatarsPresign(t *testing.T) {
+	useConfigFile(t)
+	base := *Load()
+
+	cfg := base
+	cfg.BucketUserAvatars.PresignExpiryMinutes = 0
+	if err := cfg.Validate(); err == nil {
+		t.Error("预签名有效期为0时应校验失败")
+	}
+	cfg.BucketUserAvatars.PresignExpiryMinutes = 7*24*60 + 1
+	if err := cfg.Validate(); err == nil {
+		t.Error("预签名有效期超过7天时应校验失败")
+	}
+	cfg = base
+	cfg.BucketUserAvatars.PublicPaths = []string{"[invalid"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("公开路径不是合法的匹配模式时应校验失败")
+	}
+}
+
+func TestValidateSensitiveLogKeys(t *testing.T) {
+	useConfigFile(t)
+	cfg := *Load()
+	cfg.LogExtended.SensitiveKeys = []string{"password", " "}
+	if err := cfg.Validate(); err == nil {
+		t.Error("脱敏字段包含空项时应校验失败")
+	}
+	cfg.LogExtended.SensitiveKeys = nil
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("空列表表示关闭脱敏: %v", err)
+	}
+}
+
+func TestValidateWorkerPoolShutdownTimeout(t *testing.T) {
+	useConfigFile(t)
+	cfg := *Load()
+	cfg.WorkerPool.ShutdownTimeout = 0
+	if err := cfg.Validate(); err == nil {
+		t.Error("关闭等待时间为0时应校验失败")
+	}
+}
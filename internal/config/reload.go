@@ -0,0 +1,159 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// activeConfig 当前生效的配置（Load 时写入，Reload 时原子替换）
+var activeConfig atomic.Pointer[Config]
+
+// reloadMu 串行化重载，避免并发 SIGHUP 交错
+var reloadMu sync.Mutex
+
+// ErrReloadNoConfigFile 找不到可重载的配置文件
+var ErrReloadNoConfigFile = errors.New("未找到配置文件，无法重载")
+
+// ConfigChange 配置重载产生的单项变更
+type ConfigChange struct {
+	Field   string      `json:"field"`   // 配置路径（yaml键）
+	Old     interface{} `json:"old"`     // 旧值（忽略的变更不记录值，避免泄露密钥等敏感配置）
+	New     interface{} `json:"new"`     // 新值
+	Applied bool        `json:"applied"` // false 表示需要重启才能生效，已忽略
+}
+
+// reloadableField 可热更新的配置项
+type reloadableField struct {
+	name string
+	get  func(c *Config) interface{}
+	set  func(dst, src *Config)
+}
+
+// reloadableFields 运行时可安全替换的配置子集（其余字段需重启生效）
+var reloadableFields = []reloadableField{
+	{"log.level",
+		func(c *Config) interface{} { return c.Log.Level },
+		func(dst, src *Config) { dst.Log.Level = src.Log.Level }},
+	{"log.format",
+		func(c *Config) interface{} { return c.Log.Format },
+		func(dst, src *Config) { dst.Log.Format = src.Log.Format }},
+	{"rate_limiter.global",
+		func(c *Config) interface{} { return c.RateLimiter.Global },
+		func(dst, src *Config) { dst.RateLimiter.Global = src.RateLimiter.Global }},
+	{"rate_limiter.login",
+		func(c *Config) interface{} { return c.RateLimiter.Login },
+		func(dst, src *Config) { dst.RateLimiter.Login = src.RateLimiter.Login }},
+	{"rate_limiter.register",
+		func(c *Config) interface{} { return c.RateLimiter.Register },
+		func(dst, src *Config) { dst.RateLimiter.Register = src.RateLimiter.Register }},
+	{"rate_limiter.headers_on_success",
+		func(c *Config) interface{} { return c.RateLimiter.HeadersOnSuccess },
+		func(dst, src *Config) { dst.RateLimiter.HeadersOnSuccess = src.RateLimiter.HeadersOnSuccess }},
+	{"rate_limiter.user",
+		func(c *Config) interface{} { return c.RateLimiter.User },
+		func(dst, src *Config) { dst.RateLimiter.User = src.RateLimiter.User }},
+	{"rate_limiter.sensitive_ip",
+		func(c *Config) interface{} { return c.RateLimiter.SensitiveIP },
+		func(dst, src *Config) { dst.RateLimiter.SensitiveIP = src.RateLimiter.SensitiveIP }},
+	{"rate_limiter.sensitive_email",
+		func(c *Config) interface{} { return c.RateLimiter.SensitiveEmail },
+		func(dst, src *Config) { dst.RateLimiter.SensitiveEmail = src.RateLimiter.SensitiveEmail }},
+	{"rate_limiter.sensitive_min_response_ms",
+		func(c *Config) interface{} { return c.RateLimiter.SensitiveMinResponseMs },
+		func(dst, src *Config) {
+			dst.RateLimiter.SensitiveMinResponseMs = src.RateLimiter.SensitiveMinResponseMs
+		}},
+	{"cache.categories_ttl_minutes",
+		func(c *Config) interface{} { return c.Cache.CategoriesTTLMinutes },
+		func(dst, src *Config) { dst.Cache.CategoriesTTLMinutes = src.Cache.CategoriesTTLMinutes }},
+	{"cache.tags_ttl_minutes",
+		func(c *Config) interface{} { return c.Cache.TagsTTLMinutes },
+		func(dst, src *Config) { dst.Cache.TagsTTLMinutes = src.Cache.TagsTTLMinutes }},
+	{"cache.article_detail_ttl_minutes",
+		func(c *Config) interface{} { return c.Cache.ArticleDetailTTLMinutes },
+		func(dst, src *Config) { dst.Cache.ArticleDetailTTLMinutes = src.Cache.ArticleDetailTTLMinutes }},
+	{"cache.online_count_ttl_seconds",
+		func(c *Config) interface{} { return c.Cache.OnlineCountTTLSeconds },
+		func(dst, src *Config) { dst.Cache.OnlineCountTTLSeconds = src.Cache.OnlineCountTTLSeconds }},
+	{"database_query.slow_query_threshold_ms",
+		func(c *Config) interface{} { return c.DatabaseQuery.SlowQueryThresholdMS },
+		func(dst, src *Config) {
+			dst.DatabaseQuery.SlowQueryThresholdMS = src.DatabaseQuery.SlowQueryThresholdMS
+		}},
+	{"profiler.slow_query_threshold_ms",
+		func(c *Config) interface{} { return c.Profiler.SlowQueryThresholdMS },
+		func(dst, src *Config) { dst.Profiler.SlowQueryThresholdMS = src.Profiler.SlowQueryThresholdMS }},
+	{"log_extended.sensitive_keys",
+		func(c *Config) interface{} { return c.LogExtended.SensitiveKeys },
+		func(dst, src *Config) { dst.LogExtended.SensitiveKeys = src.LogExtended.SensitiveKeys }},
+	{"moderation",
+		func(c *Config) interface{} { return c.Moderation },
+		func(dst, src *Config) { dst.Moderation = src.Moderation }},
+}
+
+// Current 获取当前生效的配置
+func Current() *Config {
+	return activeConfig.Load()
+}
+
+// Reload 重新读取配置文件并校验，只替换可热更新的字段
+// 返回新的配置（不可热更新的字段沿用当前值）以及变更列表；
+// 校验失败时返回错误，当前配置保持不变
+func Reload() (*Config, []ConfigChange, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	current := activeConfig.Load()
+	if current == nil {
+		return nil, nil, fmt.Errorf("配置尚未加载，无法重载")
+	}
+
+	env := getEnv("APP_ENV", "dev")
+	configFile := getConfigFile(env)
+	if configFile == "" {
+		return nil, nil, ErrReloadNoConfigFile
+	}
+
+	fresh := getDefaultConfig()
+	if err := loadFromFile(fresh, configFile); err != nil {
+		return nil, nil, fmt.Errorf("读取配置文件 %s 失败: %w", configFile, err)
+	}
+	overrideWithEnvVars(fresh)
+	if err := fresh.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("配置校验失败: %w", err)
+	}
+
+	// 以当前配置为基础，只替换可热更新的字段
+	next := *current
+	changes := make([]ConfigChange, 0)
+	for _, f := range reloadableFields {
+		oldVal, newVal := f.get(current), f.get(fresh)
+		if !reflect.DeepEqual(oldVal, newVal) {
+			f.set(&next, fresh)
+			changes = append(changes, ConfigChange{Field: f.name, Old: oldVal, New: newVal, Applied: true})
+		}
+	}
+
+	// 检测需要重启才能生效的变更：把可热更新字段对齐后逐个比较顶层配置块
+	merged := *fresh
+	for _, f := range reloadableFields {
+		f.set(&merged, current)
+	}
+	currentVal := reflect.ValueOf(*current)
+	mergedVal := reflect.ValueOf(merged)
+	configType := currentVal.Type()
+	for i := 0; i < configType.NumField(); i++ {
+		if !reflect.DeepEqual(currentVal.Field(i).Interface(), mergedVal.Field(i).Interface()) {
+			changes = append(changes, ConfigChange{
+				Field:   configType.Field(i).Tag.Get("yaml"),
+				Applied: false,
+			})
+		}
+	}
+
+	activeConfig.Store(&next)
+	return &next, changes, nil
+}
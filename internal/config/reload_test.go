@@ -0,0 +1,122 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useConfigFile 在临时目录写入以仓库 config.yaml 为基础、按 replacements 修改后的配置文件，并切换到该目录
+func useConfigFile(t *testing.T, replacements ...string) func(replacements ...string) {
+	t.Helper()
+	base, err := os.ReadFile(filepath.Join("..", "..", "config.yaml"))
+	if err != nil {
+		t.Fatalf("读取 config.yaml 失败: %v", err)
+	}
+	dir := t.TempDir()
+	write := func(replacements ...string) {
+		t.Helper()
+		content := strings.NewReplacer(replacements...).Replace(string(base))
+		if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(content), 0o600); err != nil {
+			t.Fatalf("写入配置文件失败: %v", err)
+		}
+	}
+	write(replacements...)
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+	t.Setenv("APP_ENV", "reload-test")
+	return write
+}
+
+func findChange(changes []ConfigChange, field string) (ConfigChange, bool) {
+	for _, c := range changes {
+		if c.Field == field {
+			return c, true
+		}
+	}
+	return ConfigChange{}, false
+}
+
+func TestReloadAppliesMutableFieldsOnly(t *testing.T) {
+	write := useConfigFile(t)
+	original := Load()
+
+	write(`level: "debug"`, `level: "warn"`, `port: "3001"`, `port: "4001"`)
+	next, changes, err := Reload()
+	if err != nil {
+		t.Fatalf("重载失败: %v", err)
+	}
+
+	if next.Log.Level != "warn" {
+		t.Fatalf("log.level 应热更新为 warn，实际 %q", next.Log.Level)
+	}
+	if c, ok := findChange(changes, "log.level"); !ok || !c.Applied || c.Old != "debug" || c.New != "warn" {
+		t.Fatalf("变更列表应包含已应用的 log.level，实际 %+v", changes)
+	}
+
+	if next.Server.Port != original.Server.Port {
+		t.Fatalf("server.port 需要重启才能生效，不应被替换，实际 %q", next.Server.Port)
+	}
+	if c, ok := findChange(changes, "server"); !ok || c.Applied || c.Old != nil || c.New != nil {
+		t.Fatalf("server 变更应标记为忽略且不记录值，实际 %+v", changes)
+	}
+	if Current() != next {
+		t.Fatal("重载后 Current 应返回新配置")
+	}
+	if original.Log.Level != "debug" {
+		t.Fatal("重载不应修改旧的配置对象")
+	}
+}
+
+func TestReloadRejectsInvalidConfig(t *testing.T) {
+	write := useConfigFile(t)
+	original := Load()
+
+	write(`level: "debug"`, `level: "warn"`, `on_unavailable: allow`, `on_unavailable: maybe`)
+	if _, _, err := Reload(); err == nil {
+		t.Fatal("校验失败的配置不应被应用")
+	}
+	if Current() != original || Current().Log.Level != "debug" {
+		t.Fatal("校验失败时应保留当前配置")
+	}
+}
+
+func TestReloadWithoutChanges(t *testing.T) {
+	useConfigFile(t)
+	Load()
+
+	_, changes, err := Reload()
+	if err != nil {
+		t.Fatalf("重载失败: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Fatalf("配置文件未修改时不应有变更，实际 %+v", changes)
+	}
+}
+
+func TestReloadLogFormat(t *testing.T) {
+	write := useConfigFile(t)
+	Load()
+
+	write(`format: "json"`, `format: "text"`)
+	next, changes, err := Reload()
+	if err != nil {
+		t.Fatalf("重载失败: %v", err)
+	}
+	if c, ok := findChange(changes, "log.format"); !ok || !c.Applied || next.Log.Format != "text" {
+		t.Fatalf("log.format 应热更新为 text，实际 %q %+v", next.Log.Format, changes)
+	}
+
+	write(`format: "json"`, `format: "xml"`)
+	if _, _, err := Reload(); err == nil || Current().Log.Format != "text" {
+		t.Fatalf("未知的日志格式应被拒绝并保留当前格式，实际 %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/middleware"
+	"gin/internal/models"
+	"gin/internal/services"
+	"gin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APITokenHandler 个人访问令牌处理器
+type APITokenHandler struct {
+	tokenRepo *services.APITokenRepository
+	config    *config.Config
+	logger    utils.Logger
+	resources map[string]bool // 可申请权限的资源名（来自 api_token.route_scopes）
+}
+
+// NewAPITokenHandler 创建个人访问令牌处理器
+func NewAPITokenHandler(tokenRepo *services.APITokenRepository, cfg *config.Config) *APITokenHandler {
+	resources := make(map[string]bool, len(cfg.APIToken.RouteScopes))
+	for _, resource := range cfg.APIToken.RouteScopes {
+		resources[resource] = true
+	}
+	return &APITokenHandler{
+		tokenRepo: tokenRepo,
+		config:    cfg,
+		logger:    utils.GetLogger(),
+		resources: resources,
+	}
+}
+
+// requireInteractiveSession 令牌管理只允许登录会话操作，防止令牌自我续期或越权创建
+func requireInteractiveSession(c *gin.Context) bool {
+	if c.GetString("authType") == middleware.AuthTypeAPIToken {
+		utils.ForbiddenResponse(c, "API令牌不能管理令牌，请使用登录会话")
+		return false
+	}
+	return true
+}
+
+// CreateToken 创建API令牌（明文令牌仅在响应中返回一次）
+func (h *APITokenHandler) CreateToken(c *gin.Context) {
+	userID, isOK := getUserIDOrFail(c)
+	if !isOK || !requireInteractiveSession(c) {
+		return
+	}
+
+	var req models.CreateAPITokenRequest
+	if !bindJSONOrFail(c, &req, h.logger, "CreateToken") {
+		return
+	}
+
+	// 校验权限范围并去重
+	scopes := make([]string, 0, len(req.Scopes))
+	seen := make(map[string]bool, len(req.Scopes))
+	for _, scope := range req.Scopes {
+		if !models.IsValidAPITokenScope(scope, h.resources) {
+			utils.ValidationErrorResponse(c, "不支持的权限范围: "+scope)
+			return
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+
+	// 计算过期时间（不超过配置的最长有效期）
+	maxDays := h.config.APIToken.MaxExpireDays
+	days := req.ExpiresInDays
+	if maxDays > 0 && (days == 0 || days > maxDays) {
+		days = maxDays
+	}
+	var expiresAt *time.Time
+	if days > 0 {
+		t := time.Now().UTC().AddDate(0, 0, days)
+		expiresAt = &t
+	}
+
+	plaintext, token, err := h.tokenRepo.CreateAPIToken(c.Request.Context(), userID, req.Name, scopes, expiresAt)
+	if err != nil {
+		h.logger.Warn("创建API令牌失败", "userID", userID, "error", err.Error())
+		utils.ErrorResponse(c, utils.GetHTTPStatusCode(err), err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, 201, "创建成功，请妥善保存令牌，它不会再次显示", models.CreateAPITokenResponse{
+		Token:    plaintext,
+		APIToken: *token,
+	})
+}
+
+// ListTokens 获取当前用户的API令牌列表
+func (h *APITokenHandler) ListTokens(c *gin.Context) {
+	userID, isOK := getUserIDOrFail(c)
+	if !isOK || !requireInteractiveSession(c) {
+		return
+	}
+
+	tokens, err := h.tokenRepo.ListAPITokens(c.Request.Context(), userID)
+	if err != nil {
+		utils.ErrorResponse(c, utils.GetHTTPStatusCode(err), "获取令牌列表失败")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "获取成功", gin.H{
+		"tokens": tokens,
+	})
+}
+
+// RevokeToken 吊销API令牌
+func (h *APITokenHandler) RevokeToken(c *gin.Context) {
+	userID, isOK := getUserIDOrFail(c)
+	if !isOK || !requireInteractiveSession(c) {
+		return
+	}
+
+	tokenID, isOK := parseUintParam(c, "id", "无效的令牌ID")
+	if !isOK {
+		return
+	}
+
+	if err := h.tokenRepo.RevokeAPIToken(c.Request.Context(), userID, tokenID); err != nil {
+		statusCode := utils.GetHTTPStatusCode(err)
+		if statusCode == 404 {
+			utils.NotFoundResponse(c, "令牌不存在或已吊销")
+			return
+		}
+		utils.ErrorResponse(c, statusCode, "吊销令牌失败")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "吊销成功", nil)
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gin/internal/middleware"
+	"gin/internal/services"
+	"gin/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiTokenStore 内存中的 user_api_tokens 表
+type apiTokenStore struct {
+	mu     sync.Mutex
+	tokens []apiTokenRow
+}
+
+type apiTokenRow struct {
+	id, userID          int64
+	name, hash, prefix  string
+	scopes              string
+	createdAt           time.Time
+	revokedAt, lastUsed interface{}
+}
+
+// newAPITokenRouter 按 routes.go 的方式组装令牌管理路由和一个受 articles 权限保护的路由
+func newAPITokenRouter(t *testing.T) (*gin.Engine, *apiTokenStore) {
+	t.Helper()
+	cfg := newTestConfig()
+	fake, db := newFakeDatabase(t, cfg)
+	store := &apiTokenStore{}
+
+	fake.On(`SELECT COUNT\(\*\) FROM user_api_tokens`, func([]driver.Value) testutil.Response {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		return testutil.Response{Columns: []string{"count"}, Rows: [][]driver.Value{{int64(len(store.tokens))}}}
+	})
+	fake.On(`INSERT INTO user_api_tokens`, func(args []driver.Value) testutil.Response {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		row := apiTokenRow{
+			id: int64(len(store.tokens) + 1), userID: args[0].(int64), name: args[1].(string),
+			hash: args[2].(string), prefix: args[3].(string), scopes: args[4].(string), createdAt: args[6].(time.Time),
+		}
+		store.tokens = append(store.tokens, row)
+		return testutil.Response{LastInsertID: row.id, RowsAffected: 1}
+	})
+	fake.On(`SELECT id, user_id, name, token_prefix, scopes, last_used_at, expires_at, revoked_at, created_at FROM user_api_tokens`, func([]driver.Value) testutil.Response {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		resp := testutil.Response{Columns: []string{"id", "user_id", "name", "token_prefix", "scopes", "last_used_at", "expires_at", "revoked_at", "created_at"}}
+		for _, r := range store.tokens {
+			resp.Rows = append(resp.Rows, []driver.Value{r.id, r.userID, r.name, r.prefix, r.scopes, r.lastUsed, nil, r.revokedAt, r.createdAt})
+		}
+		return resp
+	})
+	fake.On(`UPDATE user_api_tokens SET revoked_at`, func(args []driver.Value) testutil.Response {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		for i := range store.tokens {
+			r := &store.tokens[i]
+			if r.id == args[1].(int64) && r.userID == args[2].(int64) && r.revokedAt == nil {
+				r.revokedAt = args[0]
+				return testutil.Response{RowsAffected: 1}
+			}
+		}
+		return testutil.Response{}
+	})
+	fake.On(`FROM user_api_tokens t INNER JOIN user_auth`, func(args []driver.Value) testutil.Response {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		resp := testutil.Response{Columns: []string{"id", "user_id", "scopes", "expires_at", "revoked_at", "username", "email", "account_status"}}
+		for _, r := range store.tokens {
+			if r.hash == args[0].(string) {
+				resp.Rows = append(resp.Rows, []driver.Value{r.id, r.userID, r.scopes, nil, r.revokedAt, "alice", "alice@example.com", int64(1)})
+			}
+		}
+		return resp
+	})
+	fake.OnExec(`UPDATE user_api_tokens SET last_used_at`, 0, 1)
+
+	repo := services.NewAPITokenRepository(db, cfg)
+	h := NewAPITokenHandler(repo, cfg)
+	ok := func(c *gin.Context) { c.String(http.StatusOK, "ok") }
+
+	router := gin.New()
+	auth := router.Group("/api", middleware.AuthMiddleware(cfg, repo, nil))
+	account := auth.Group("/", middleware.RequireRouteGroupScope(cfg, "account"))
+	account.GET("/users/me/tokens", h.ListTokens)
+	account.POST("/users/me/tokens", h.CreateToken)
+	account.DELETE("/users/me/tokens/:id", h.RevokeToken)
+	articles := auth.Group("/", middleware.RequireRouteGroupScope(cfg, "articles"))
+	articles.GET("/articles/mine", ok)
+	articles.POST("/articles", ok)
+	return router, store
+}
+
+func TestAPITokenLifecycle(t *testing.T) {
+	router, store := newAPITokenRouter(t)
+	jwtToken := signTestJWT(t, newTestConfig(), 1, "alice")
+
+	// 创建：明文令牌只在创建响应中返回，数据库只保存哈希
+	resp := doRequest(t, router, http.MethodPost, "/api/users/me/tokens", jwtToken, map[string]interface{}{
+		"name": "ci", "scopes": []string{"read:articles"},
+	})
+	if resp.Status != http.StatusCreated {
+		t.Fatalf("创建令牌应返回201，实际 %d %s", resp.Status, resp.Body)
+	}
+	var created struct {
+		Token       string   `json:"token"`
+		ID          uint     `json:"id"`
+		TokenPrefix string   `json:"token_prefix"`
+		Scopes      []string `json:"scopes"`
+	}
+	decodeData(t, resp, &created)
+	if !strings.HasPrefix(created.Token, "sqt_") || !strings.HasPrefix(created.Token, created.TokenPrefix) {
+		t.Fatalf("创建响应应包含明文令牌，实际 %+v", created)
+	}
+	sum := sha256.Sum256([]byte(created.Token))
+	if stored := store.tokens[0]; stored.hash != hex.EncodeToString(sum[:]) || strings.Contains(stored.hash, created.Token) {
+		t.Fatal("数据库应只保存令牌的哈希")
+	}
+
+	list := doRequest(t, router, http.MethodGet, "/api/users/me/tokens", jwtToken, nil)
+	if list.Status != http.StatusOK || strings.Contains(list.Body, created.Token) || !strings.Contains(list.Body, created.TokenPrefix) {
+		t.Fatalf("令牌列表不应再次返回明文令牌: %d %s", list.Status, list.Body)
+	}
+
+	// 用令牌认证，并按权限范围限制
+	if r := doRequest(t, router, http.MethodGet, "/api/articles/mine", created.Token, nil); r.Status != http.StatusOK {
+		t.Fatalf("read:articles 令牌应能读取文章，实际 %d %s", r.Status, r.Body)
+	}
+	if r := doRequest(t, router, http.MethodPost, "/api/articles", created.Token, map[string]string{}); r.Status != http.StatusForbidden {
+		t.Fatalf("read:articles 令牌不能写文章，实际 %d", r.Status)
+	}
+	if r := doRequest(t, router, http.MethodGet, "/api/users/me/tokens", created.Token, nil); r.Status != http.StatusForbidden {
+		t.Fatalf("没有 account 权限的令牌不能管理令牌，实际 %d", r.Status)
+	}
+	if r := doRequest(t, router, http.MethodGet, "/api/articles/mine", "sqt_unknown", nil); r.Status != http.StatusUnauthorized {
+		t.Fatalf("未知令牌应返回401，实际 %d", r.Status)
+	}
+
+	// 吊销后令牌不能再认证
+	if r := doRequest(t, router, http.MethodDelete, "/api/users/me/tokens/1", jwtToken, nil); r.Status != http.StatusOK {
+		t.Fatalf("吊销令牌应成功，实际 %d %s", r.Status, r.Body)
+	}
+	if r := doRequest(t, router, http.MethodGet, "/api/articles/mine", created.Token, nil); r.Status != http.StatusUnauthorized {
+		t.Fatalf("已吊销的令牌应返回401，实际 %d", r.Status)
+	}
+	if r := doRequest(t, router, http.MethodDelete, "/api/users/me/tokens/1", jwtToken, nil); r.Status != http.StatusNotFound {
+		t.Fatalf("重复吊销应返回404，实际 %d", r.Status)
+	}
+}
+
+func TestAPITokenCannotManageTokens(t *testing.T) {
+	router, _ := newAPITokenRouter(t)
+	jwtToken := signTestJWT(t, newTestConfig(), 1, "alice")
+
+	resp := doRequest(t, router, http.MethodPost, "/api/users/me/tokens", jwtToken, map[string]interface{}{
+		"name": "full", "scopes": []string{"write"},
+	})
+	var created struct {
+		Token string `json:"token"`
+	}
+	decodeData(t, resp, &created)
+
+	// 即使拥有全部权限，令牌也不能创建新令牌（防止自我续期）
+	r := doRequest(t, router, http.MethodPost, "/api/users/me/tokens", created.Token, map[string]interface{}{
+		"name": "child", "scopes": []string{"write"},
+	})
+	if r.Status != http.StatusForbidden {
+		t.Fatalf("API令牌不能创建令牌，实际 %d %s", r.Status, r.Body)
+	}
+
+	bad := doRequest(t, router, http.MethodPost, "/api/users/me/tokens", jwtToken, map[string]interface{}{
+		"name": "bad", "scopes": []string{"delete:everything"},
+	})
+	if bad.Status != http.StatusUnprocessableEntity {
+		t.Fatalf("不支持的权限范围应返回422，实际 %d", bad.Status)
+	}
+}
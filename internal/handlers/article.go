@@ -2,8 +2,11 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"gin/internal/config"
@@ -19,21 +22,53 @@ type ArticleHandler struct {
 	articleRepo *services.ArticleRepository
 	userRepo    *services.UserRepository
 	cacheSvc    *services.CacheService
+	auditRepo   *services.AuditRepository
+	viewDedup   *services.ViewDeduplicator // 浏览次数去重
 	logger      utils.Logger
 	config      *config.Config
 }
 
 // NewArticleHandler 创建文章处理器
-func NewArticleHandler(articleRepo *services.ArticleRepository, userRepo *services.UserRepository, cacheSvc *services.CacheService, cfg *config.Config) *ArticleHandler {
+func NewArticleHandler(articleRepo *services.ArticleRepository, userRepo *services.UserRepository, cacheSvc *services.CacheService, auditRepo *services.AuditRepository, viewDedup *services.ViewDeduplicator, cfg *config.Config) *ArticleHandler {
 	return &ArticleHandler{
 		articleRepo: articleRepo,
 		userRepo:    userRepo,
 		cacheSvc:    cacheSvc,
+		auditRepo:   auditRepo,
+		viewDedup:   viewDedup,
 		logger:      utils.GetLogger(),
 		config:      cfg,
 	}
 }
 
+// resolveTagIDs 合并已有标签ID和按名称批量创建/获取的标签ID（去重，保持顺序）
+// 标签创建失败不影响文章保存，只记录日志
+func (h *ArticleHandler) resolveTagIDs(ctx context.Context, tagIDs []uint, tagNames []string) []uint {
+	result := make([]uint, 0, len(tagIDs)+len(tagNames))
+	seen := make(map[uint]bool, len(tagIDs)+len(tagNames))
+	add := func(id uint) {
+		if id > 0 && !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	for _, id := range tagIDs {
+		add(id)
+	}
+
+	if len(tagNames) > 0 {
+		nameIDs, err := h.articleRepo.CreateOrGetTags(ctx, tagNames)
+		if err != nil {
+			h.logger.Warn("批量处理标签失败", "count", len(tagNames), "error", err.Error())
+			return result
+		}
+		for _, name := range tagNames {
+			add(nameIDs[strings.TrimSpace(name)])
+		}
+	}
+	return result
+}
+
 // CreateArticle 创建文章
 func (h *ArticleHandler) CreateArticle(c *gin.Context) {
 	userID, isOK := getUserIDOrFail(c)
@@ -45,20 +80,13 @@ func (h *ArticleHandler) CreateArticle(c *gin.Context) {
 	if !bindJSONOrFail(c, &req, h.logger, "CreateArticle") {
 		return
 	}
+	if !moderateOrFail(c, userID, &req.Title, &req.Description, &req.Content) {
+		return
+	}
 
 	// 处理标签（创建新标签或获取已有标签ID）
 	ctx := c.Request.Context()
-	var tagIDs []uint
-	tagIDs = append(tagIDs, req.TagIDs...)
-
-	for _, tagName := range req.TagNames {
-		if tagName != "" {
-			tagID, err := h.articleRepo.CreateOrGetTag(ctx, tagName)
-			if err == nil {
-				tagIDs = append(tagIDs, tagID)
-			}
-		}
-	}
+	tagIDs := h.resolveTagIDs(ctx, req.TagIDs, req.TagNames)
 
 	// 创建文章
 	article := &models.Article{
@@ -81,10 +109,6 @@ func (h *ArticleHandler) CreateArticle(c *gin.Context) {
 
 	h.logger.Info("创建文章成功", "articleID", article.ID, "userID", userID, "title", article.Title)
 
-	// 失效相关缓存
-	h.cacheSvc.InvalidateArticleCategories()
-	h.cacheSvc.InvalidateArticleTags()
-
 	// 广播新文章通知（WebSocket实时推送）
 	go func() {
 		// 获取完整的文章信息用于广播
@@ -93,7 +117,7 @@ func (h *ArticleHandler) CreateArticle(c *gin.Context) {
 			h.logger.Warn("获取完整文章信息失败，无法发送WebSocket通知", "articleID", article.ID, "error", err.Error())
 			return
 		}
-		NotifyNewArticle(fullArticle)
+		NotifyNewArticle(article.UserID, fullArticle)
 	}()
 
 	utils.SuccessResponse(c, 201, "创建成功", gin.H{
@@ -112,9 +136,28 @@ func (h *ArticleHandler) GetArticleDetail(c *gin.Context) {
 
 	// 获取当前用户ID（可能未登录）
 	userID, _ := utils.GetUserIDFromContext(c)
-
 	ctx := c.Request.Context()
-	article, err := h.articleRepo.GetArticleByID(ctx, uint(articleID), userID)
+
+	// 先查询版本信息：ETag 未变化时直接返回304，不再加载详情
+	// 304 同样计入浏览次数（客户端重新打开了文章，只是复用了本地内容）
+	version, err := h.articleRepo.GetArticleVersion(ctx, uint(articleID), userID)
+	if err != nil {
+		h.logger.Warn("获取文章版本失败", "articleID", articleID, "error", err.Error())
+		utils.ErrorResponse(c, utils.GetHTTPStatusCode(err), "文章不存在")
+		return
+	}
+	etag := articleETag(uint(articleID), version)
+	c.Header("ETag", etag)
+	c.Header("Last-Modified", version.UpdatedAt.UTC().Format(http.TimeFormat))
+	c.Header("Cache-Control", "private, no-cache")
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		h.incrementViewCount(c, uint(articleID), userID)
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	// 文章详情走缓存（热门文章由后台刷新器提前预热）
+	article, err := h.cacheSvc.GetArticleDetail(ctx, uint(articleID), userID)
 	if err != nil {
 		h.logger.Warn("获取文章详情失败", "articleID", articleID, "error", err.Error())
 		statusCode := utils.GetHTTPStatusCode(err)
@@ -122,18 +165,38 @@ func (h *ArticleHandler) GetArticleDetail(c *gin.Context) {
 		return
 	}
 
-	// 增加浏览次数（使用Worker Pool，避免无限制goroutine）
+	h.incrementViewCount(c, uint(articleID), userID)
+
+	h.logger.Info("获取文章详情成功", "articleID", articleID)
+	utils.SuccessResponse(c, 200, "获取成功", article)
+}
+
+// articleETag 根据文章版本计算弱 ETag（点赞状态因用户而异，因此响应标记为 private）
+// Last-Modified 只反映内容修改时间，点赞/评论数变化不会更新它，条件请求以 ETag 为准
+func articleETag(articleID uint, v *models.ArticleVersion) string {
+	liked := 0
+	if v.IsLiked {
+		liked = 1
+	}
+	return fmt.Sprintf(`W/"a%d-%d-%x-%x-%d-%d-%d"`, articleID, v.Version, v.UpdatedAt.UnixNano(), v.AuthorUpdatedAt.UnixNano(),
+		v.LikeCount, v.CommentCount, liked)
+}
+
+// incrementViewCount 增加浏览次数（使用Worker Pool，避免无限制goroutine）
+// 同一访客在去重窗口内重复浏览不计数
+func (h *ArticleHandler) incrementViewCount(c *gin.Context, articleID uint, userID uint) {
+	if !h.viewDedup.ShouldCount(services.ViewTargetArticle, articleID, userID, c.ClientIP()) {
+		return
+	}
+
 	taskID := fmt.Sprintf("incr_view_%d", articleID)
-	err = utils.SubmitTask(taskID, func(taskCtx context.Context) error {
-		return h.articleRepo.IncrementViewCount(taskCtx, uint(articleID))
+	err := utils.SubmitTaskWithContext(c.Request.Context(), taskID, func(taskCtx context.Context) error {
+		return h.articleRepo.IncrementViewCount(taskCtx, articleID)
 	}, time.Duration(h.config.AsyncTasks.ArticleViewCountTimeout)*time.Second)
 
 	if err != nil {
 		h.logger.Debug("提交浏览次数更新任务失败", "articleID", articleID, "error", err.Error())
 	}
-
-	h.logger.Info("获取文章详情成功", "articleID", articleID)
-	utils.SuccessResponse(c, 200, "获取成功", article)
 }
 
 // GetArticleList 获取文章列表
@@ -144,8 +207,18 @@ func (h *ArticleHandler) GetArticleList(c *gin.Context) {
 		utils.ValidationErrorResponse(c, "请求参数错误")
 		return
 	}
+	query.ViewerID, _ = utils.GetUserIDFromContext(c)
 
 	ctx := c.Request.Context()
+	if query.Feed == models.ArticleFeedFollowing {
+		authorIDs, err := h.userRepo.GetFollowingIDs(ctx, query.ViewerID, h.config.Pagination.FeedFollowingMax)
+		if err != nil {
+			utils.ErrorResponse(c, utils.GetHTTPStatusCode(err), "获取文章列表失败")
+			return
+		}
+		query.AuthorIDs = authorIDs
+	}
+
 	response, err := h.articleRepo.ListArticles(ctx, query)
 	if err != nil {
 		h.logger.Error("获取文章列表失败", "error", err.Error())
@@ -180,35 +253,87 @@ func (h *ArticleHandler) UpdateArticle(c *gin.Context) {
 	}
 
 	// 处理新标签
+	ctx := c.Request.Context()
 	if len(req.TagNames) > 0 {
-		ctx := c.Request.Context()
-		for _, tagName := range req.TagNames {
-			if tagName != "" {
-				tagID, err := h.articleRepo.CreateOrGetTag(ctx, tagName)
-				if err == nil {
-					req.TagIDs = append(req.TagIDs, tagID)
-				}
-			}
-		}
+		req.TagIDs = h.resolveTagIDs(ctx, req.TagIDs, req.TagNames)
 	}
 
-	ctx := c.Request.Context()
-	err = h.articleRepo.UpdateArticle(ctx, uint(articleID), userID, req)
+	version, err := h.articleRepo.UpdateArticle(ctx, uint(articleID), userID, req)
 	if err != nil {
+		if errors.Is(err, utils.ErrVersionConflict) {
+			h.logger.Info("更新文章版本冲突", "articleID", articleID, "userID", userID, "version", req.Version)
+			utils.CodeErrorResponse(c, http.StatusConflict, utils.ErrCodeVersionConflict, "文章已被修改，请刷新后重试")
+			return
+		}
 		h.logger.Error("更新文章失败", "articleID", articleID, "userID", userID, "error", err.Error())
 		statusCode := utils.GetHTTPStatusCode(err)
 		utils.ErrorResponse(c, statusCode, "更新文章失败")
 		return
 	}
 
-	h.logger.Info("更新文章成功", "articleID", articleID, "userID", userID)
+	h.logger.Info("更新文章成功", "articleID", articleID, "userID", userID, "version", version)
+
+	utils.SuccessResponse(c, 200, "更新成功", gin.H{
+		"version": version,
+	})
+}
+
+// ListRevisions 获取文章修订列表
+func (h *ArticleHandler) ListRevisions(c *gin.Context) {
+	articleID, isOK := parseUintParam(c, "id", "无效的文章ID")
+	if !isOK {
+		return
+	}
+
+	revisions, err := h.articleRepo.ListRevisions(c.Request.Context(), articleID)
+	if err != nil {
+		utils.ErrorResponse(c, utils.GetHTTPStatusCode(err), "获取修订列表失败")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "获取成功", gin.H{
+		"revisions": revisions,
+	})
+}
+
+// DiffRevisions 比较文章的两个版本（from/to 为修订ID，current 或不传 to 表示当前版本）
+func (h *ArticleHandler) DiffRevisions(c *gin.Context) {
+	articleID, isOK := parseUintParam(c, "id", "无效的文章ID")
+	if !isOK {
+		return
+	}
+
+	fromID, errFrom := parseRevisionID(c.Query("from"))
+	toID, errTo := parseRevisionID(c.DefaultQuery("to", "current"))
+	if c.Query("from") == "" || errFrom != nil || errTo != nil {
+		utils.BadRequestResponse(c, "无效的修订版本参数")
+		return
+	}
+
+	diff, err := h.articleRepo.DiffRevisions(c.Request.Context(), articleID, fromID, toID)
+	if err != nil {
+		var appErr *utils.AppError
+		if errors.As(err, &appErr) && appErr.Code == 404 {
+			utils.NotFoundResponse(c, appErr.Message)
+			return
+		}
+		utils.ErrorResponse(c, utils.GetHTTPStatusCode(err), "获取修订差异失败")
+		return
+	}
 
-	// 失效相关缓存
-	h.cacheSvc.InvalidateArticleDetail(uint(articleID))
-	h.cacheSvc.InvalidateArticleCategories()
-	h.cacheSvc.InvalidateArticleTags()
+	utils.SuccessResponse(c, 200, "获取成功", diff)
+}
 
-	utils.SuccessResponse(c, 200, "更新成功", nil)
+// parseRevisionID 解析修订ID参数（current 表示当前版本）
+func parseRevisionID(value string) (uint, error) {
+	if value == "current" {
+		return models.ArticleRevisionCurrent, nil
+	}
+	id, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return uint(id), nil
 }
 
 // DeleteArticle 删除文章
@@ -236,11 +361,6 @@ func (h *ArticleHandler) DeleteArticle(c *gin.Context) {
 
 	h.logger.Info("删除文章成功", "articleID", articleID, "userID", userID)
 
-	// 失效相关缓存
-	h.cacheSvc.InvalidateArticleDetail(uint(articleID))
-	h.cacheSvc.InvalidateArticleCategories()
-	h.cacheSvc.InvalidateArticleTags()
-
 	utils.SuccessResponse(c, 200, "删除成功", nil)
 }
 
@@ -262,21 +382,50 @@ func (h *ArticleHandler) ToggleArticleLike(c *gin.Context) {
 	isLiked, err := h.articleRepo.ToggleArticleLike(ctx, uint(articleID), userID)
 	if err != nil {
 		h.logger.Error("切换文章点赞失败", "articleID", articleID, "userID", userID, "error", err.Error())
-		statusCode := utils.GetHTTPStatusCode(err)
-		utils.ErrorResponse(c, statusCode, "操作失败")
+		utils.AppErrorResponse(c, err, "操作失败")
 		return
 	}
 
 	h.logger.Info("切换文章点赞成功", "articleID", articleID, "userID", userID, "isLiked", isLiked)
 
-	// 失效文章详情缓存（点赞数已变化）
-	h.cacheSvc.InvalidateArticleDetail(uint(articleID))
-
 	utils.SuccessResponse(c, 200, "操作成功", gin.H{
 		"is_liked": isLiked,
 	})
 }
 
+// GetArticleLikers 获取文章的点赞用户 ?page=&page_size=
+func (h *ArticleHandler) GetArticleLikers(c *gin.Context) {
+	articleID, isOK := parseUintParam(c, "id", "无效的文章ID")
+	if !isOK {
+		return
+	}
+
+	page, pageSize := parsePageParams(c, &h.config.Pagination)
+	response, err := h.articleRepo.GetArticleLikers(c.Request.Context(), articleID, page, pageSize)
+	if err != nil {
+		utils.AppErrorResponse(c, err, "获取点赞用户失败")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "获取成功", response)
+}
+
+// GetArticlePlainText 获取文章纯文本（去除Markdown格式和代码块）及字数、预计阅读时长
+func (h *ArticleHandler) GetArticlePlainText(c *gin.Context) {
+	articleID, isOK := parseUintParam(c, "id", "无效的文章ID")
+	if !isOK {
+		return
+	}
+
+	text, err := h.articleRepo.GetArticlePlainText(c.Request.Context(), articleID)
+	if err != nil {
+		utils.ErrorResponse(c, utils.GetHTTPStatusCode(err), "文章不存在")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "获取成功", text)
+}
+
 // CreateComment 创建评论
 func (h *ArticleHandler) CreateComment(c *gin.Context) {
 	userID, isOK := getUserIDOrFail(c)
@@ -295,6 +444,9 @@ func (h *ArticleHandler) CreateComment(c *gin.Context) {
 	if !bindJSONOrFail(c, &req, h.logger, "CreateComment") {
 		return
 	}
+	if !moderateOrFail(c, userID, &req.Content) {
+		return
+	}
 
 	comment := &models.ArticleComment{
 		ArticleID:     uint(articleID),
@@ -319,9 +471,6 @@ func (h *ArticleHandler) CreateComment(c *gin.Context) {
 
 	h.logger.Info("创建评论成功", "commentID", comment.ID, "articleID", articleID, "userID", userID)
 
-	// 失效文章详情缓存（评论数已变化）
-	h.cacheSvc.InvalidateArticleDetail(uint(articleID))
-
 	// 获取用户信息用于 WebSocket 通知
 	userInfo, err := GetUserWithProfile(ctx, h.userRepo, userID)
 	if err != nil {
@@ -358,7 +507,7 @@ func (h *ArticleHandler) CreateComment(c *gin.Context) {
 	})
 }
 
-// GetComments 获取评论列表
+// GetComments 获取评论列表（sort=newest|oldest|top 控制一级评论顺序；flat=true 时回复按时间平铺返回）
 func (h *ArticleHandler) GetComments(c *gin.Context) {
 	articleIDStr := c.Param("id")
 	articleID, err := strconv.ParseUint(articleIDStr, 10, 32)
@@ -374,18 +523,52 @@ func (h *ArticleHandler) GetComments(c *gin.Context) {
 	userID, _ := utils.GetUserIDFromContext(c)
 
 	ctx := c.Request.Context()
-	response, err := h.articleRepo.GetComments(ctx, uint(articleID), page, pageSize, userID)
+	response, err := h.articleRepo.GetComments(ctx, uint(articleID), page, pageSize, userID, c.Query("sort"))
 	if err != nil {
 		h.logger.Error("获取评论列表失败", "articleID", articleID, "error", err.Error())
 		statusCode := utils.GetHTTPStatusCode(err)
 		utils.ErrorResponse(c, statusCode, "获取评论失败")
 		return
 	}
+	if c.Query("flat") == "true" {
+		response.Flatten()
+	}
 
 	h.logger.Info("获取评论列表成功", "articleID", articleID, "total", response.Total)
 	utils.SuccessResponse(c, 200, "获取成功", response)
 }
 
+// GetCommentReplies 分页获取单条评论的直接回复 ?after=&limit=
+func (h *ArticleHandler) GetCommentReplies(c *gin.Context) {
+	commentID, ok := parseUintParam(c, "id", "无效的评论ID")
+	if !ok {
+		return
+	}
+
+	afterID, err := strconv.ParseUint(c.DefaultQuery("after", "0"), 10, 32)
+	if err != nil {
+		utils.BadRequestResponse(c, "无效的游标")
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(h.config.Pagination.DefaultPageSize)))
+
+	// 获取当前用户ID（可能未登录）
+	userID, _ := utils.GetUserIDFromContext(c)
+
+	replies, err := h.articleRepo.GetReplies(c.Request.Context(), commentID, uint(afterID), limit, userID)
+	if err != nil {
+		if errors.Is(err, utils.ErrResourceNotFound) {
+			utils.NotFoundResponse(c, "评论不存在")
+			return
+		}
+		h.logger.Error("获取评论回复失败", "commentID", commentID, "error", err.Error())
+		utils.InternalServerErrorResponse(c, "获取评论回复失败")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "获取成功", replies)
+}
+
 // ToggleCommentLike 切换评论点赞
 func (h *ArticleHandler) ToggleCommentLike(c *gin.Context) {
 	userID, isOK := getUserIDOrFail(c)
@@ -404,8 +587,7 @@ func (h *ArticleHandler) ToggleCommentLike(c *gin.Context) {
 	isLiked, err := h.articleRepo.ToggleCommentLike(ctx, uint(commentID), userID)
 	if err != nil {
 		h.logger.Error("切换评论点赞失败", "commentID", commentID, "userID", userID, "error", err.Error())
-		statusCode := utils.GetHTTPStatusCode(err)
-		utils.ErrorResponse(c, statusCode, "操作失败")
+		utils.AppErrorResponse(c, err, "操作失败")
 		return
 	}
 
@@ -415,6 +597,43 @@ func (h *ArticleHandler) ToggleCommentLike(c *gin.Context) {
 	})
 }
 
+// UpdateComment 编辑评论
+func (h *ArticleHandler) UpdateComment(c *gin.Context) {
+	userID, isOK := getUserIDOrFail(c)
+	if !isOK {
+		return
+	}
+
+	commentIDStr := c.Param("id")
+	commentID, err := strconv.ParseUint(commentIDStr, 10, 32)
+	if err != nil {
+		utils.BadRequestResponse(c, "无效的评论ID")
+		return
+	}
+
+	var req models.UpdateCommentRequest
+	if !bindJSONOrFail(c, &req, h.logger, "UpdateComment") {
+		return
+	}
+
+	ctx := c.Request.Context()
+	err = h.articleRepo.UpdateComment(ctx, uint(commentID), userID, req.Content)
+	if err != nil {
+		h.logger.Warn("编辑评论失败", "commentID", commentID, "userID", userID, "error", err.Error())
+		statusCode := utils.GetHTTPStatusCode(err)
+		message := "编辑评论失败"
+		var appErr *utils.AppError
+		if errors.As(err, &appErr) {
+			message = appErr.Message
+		}
+		utils.ErrorResponse(c, statusCode, message)
+		return
+	}
+
+	h.logger.Info("编辑评论成功", "commentID", commentID, "userID", userID)
+	utils.SuccessResponse(c, 200, "编辑成功", nil)
+}
+
 // DeleteComment 删除评论
 func (h *ArticleHandler) DeleteComment(c *gin.Context) {
 	userID, isOK := getUserIDOrFail(c)
@@ -442,6 +661,49 @@ func (h *ArticleHandler) DeleteComment(c *gin.Context) {
 	utils.SuccessResponse(c, 200, "删除成功", nil)
 }
 
+// BatchDeleteComments 批量删除自己的评论（任一评论不属于自己时整批失败）
+func (h *ArticleHandler) BatchDeleteComments(c *gin.Context) {
+	userID, isOK := getUserIDOrFail(c)
+	if !isOK {
+		return
+	}
+
+	ids, isOK := bindBatchIDsOrFail(c, h.config.BatchOperations.MaxDeleteItems)
+	if !isOK {
+		return
+	}
+
+	deleted, err := h.articleRepo.DeleteCommentsByUser(c.Request.Context(), userID, ids)
+	if err != nil {
+		h.logger.Warn("批量删除评论失败", "userID", userID, "count", len(ids), "error", err.Error())
+		utils.AppErrorResponse(c, err, "批量删除失败")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "删除成功", models.BatchDeleteResponse{DeletedIDs: deleted})
+}
+
+// GetCommentAncestry 获取评论的回复链（面包屑）
+func (h *ArticleHandler) GetCommentAncestry(c *gin.Context) {
+	commentID, ok := parseUintParam(c, "id", "无效的评论ID")
+	if !ok {
+		return
+	}
+
+	ancestry, err := h.articleRepo.GetCommentAncestry(c.Request.Context(), commentID)
+	if err != nil {
+		if errors.Is(err, utils.ErrResourceNotFound) {
+			utils.NotFoundResponse(c, "评论不存在")
+			return
+		}
+		h.logger.Error("获取评论回复链失败", "commentID", commentID, "error", err.Error())
+		utils.InternalServerErrorResponse(c, "获取评论回复链失败")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "获取成功", ancestry)
+}
+
 // CreateReport 创建举报
 func (h *ArticleHandler) CreateReport(c *gin.Context) {
 	userID, isOK := getUserIDOrFail(c)
@@ -482,6 +744,231 @@ func (h *ArticleHandler) CreateReport(c *gin.Context) {
 	utils.SuccessResponse(c, 201, "举报成功，我们会尽快处理", nil)
 }
 
+// ListReports 获取举报列表（管理员，status 可选：0-待处理，1-已处理，2-已驳回）
+func (h *ArticleHandler) ListReports(c *gin.Context) {
+	status := models.ReportStatusAll
+	if statusStr := c.Query("status"); statusStr != "" {
+		parsed, err := strconv.Atoi(statusStr)
+		if err != nil || parsed < models.ReportStatusPending || parsed > models.ReportStatusDismissed {
+			utils.BadRequestResponse(c, "无效的举报状态")
+			return
+		}
+		status = parsed
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(h.config.Pagination.DefaultPageSize)))
+
+	ctx := c.Request.Context()
+	response, err := h.articleRepo.ListReports(ctx, status, page, pageSize)
+	if err != nil {
+		h.logger.Error("获取举报列表失败", "status", status, "error", err.Error())
+		utils.ErrorResponse(c, utils.GetHTTPStatusCode(err), "获取举报列表失败")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "获取成功", response)
+}
+
+// ResolveReport 处理举报（管理员）
+func (h *ArticleHandler) ResolveReport(c *gin.Context) {
+	moderatorID, isOK := getUserIDOrFail(c)
+	if !isOK {
+		return
+	}
+
+	reportID, isOK := parseUintParam(c, "id", "无效的举报ID")
+	if !isOK {
+		return
+	}
+
+	var req models.ResolveReportRequest
+	if !bindJSONOrFail(c, &req, h.logger, "ResolveReport") {
+		return
+	}
+
+	ctx := c.Request.Context()
+	report, err := h.articleRepo.ResolveReport(ctx, reportID, moderatorID, req.Action)
+	if err != nil {
+		h.logger.Warn("处理举报失败", "reportID", reportID, "moderatorID", moderatorID, "action", req.Action, "error", err.Error())
+		utils.ErrorResponse(c, utils.GetHTTPStatusCode(err), err.Error())
+		return
+	}
+
+	h.logger.Info("处理举报成功", "reportID", reportID, "moderatorID", moderatorID, "action", req.Action)
+	// 只有待处理的举报可以处理，操作前状态固定为待处理
+	h.auditRepo.Record(moderatorID, models.AuditActionResolveReport, models.AuditTargetReport, reportID,
+		gin.H{"status": models.ReportStatusPending}, report, c.ClientIP())
+	utils.SuccessResponse(c, 200, "处理成功", report)
+}
+
+// RenameTag 重命名标签（管理员）
+func (h *ArticleHandler) RenameTag(c *gin.Context) {
+	moderatorID, isOK := getUserIDOrFail(c)
+	if !isOK {
+		return
+	}
+
+	tagID, isOK := parseUintParam(c, "id", "无效的标签ID")
+	if !isOK {
+		return
+	}
+
+	var req models.RenameTagRequest
+	if !bindJSONOrFail(c, &req, h.logger, "RenameTag") {
+		return
+	}
+
+	ctx := c.Request.Context()
+	before, err := h.articleRepo.GetTagArticleIDs(ctx, tagID)
+	if err != nil {
+		utils.ErrorResponse(c, utils.GetHTTPStatusCode(err), "重命名标签失败")
+		return
+	}
+
+	tag, err := h.articleRepo.RenameTag(ctx, tagID, req.Name)
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrResourceNotFound):
+			utils.NotFoundResponse(c, "标签不存在")
+		case errors.Is(err, utils.ErrDuplicateEntry):
+			utils.ErrorResponse(c, http.StatusConflict, "已存在同名标签，请使用合并")
+		case errors.Is(err, utils.ErrInvalidParameter):
+			utils.BadRequestResponse(c, "标签名称不能为空")
+		default:
+			utils.ErrorResponse(c, utils.GetHTTPStatusCode(err), "重命名标签失败")
+		}
+		return
+	}
+
+	// 文章详情中包含标签名称
+	for _, articleID := range before {
+		h.cacheSvc.InvalidateArticleDetail(articleID)
+	}
+	h.cacheSvc.InvalidateArticleLists()
+
+	h.logger.Info("重命名标签成功", "tagID", tagID, "moderatorID", moderatorID, "name", tag.Name)
+	h.auditRepo.Record(moderatorID, models.AuditActionRenameTag, models.AuditTargetTag, tagID,
+		nil, tag, c.ClientIP())
+	utils.SuccessResponse(c, 200, "重命名成功", tag)
+}
+
+// MergeTag 把路径中的标签合并到目标标签（管理员）
+func (h *ArticleHandler) MergeTag(c *gin.Context) {
+	moderatorID, isOK := getUserIDOrFail(c)
+	if !isOK {
+		return
+	}
+
+	sourceTagID, isOK := parseUintParam(c, "id", "无效的标签ID")
+	if !isOK {
+		return
+	}
+
+	var req models.MergeTagsRequest
+	if !bindJSONOrFail(c, &req, h.logger, "MergeTag") {
+		return
+	}
+	if req.TargetTagID == sourceTagID {
+		utils.BadRequestResponse(c, "不能把标签合并到自身")
+		return
+	}
+
+	result, err := h.articleRepo.MergeTags(c.Request.Context(), sourceTagID, req.TargetTagID)
+	if err != nil {
+		if errors.Is(err, utils.ErrResourceNotFound) {
+			utils.NotFoundResponse(c, "标签不存在")
+			return
+		}
+		utils.ErrorResponse(c, utils.GetHTTPStatusCode(err), "合并标签失败")
+		return
+	}
+
+	for _, articleID := range result.ArticleIDs {
+		h.cacheSvc.InvalidateArticleDetail(articleID)
+	}
+	h.cacheSvc.InvalidateArticleLists()
+
+	h.logger.Info("合并标签成功", "sourceTagID", sourceTagID, "targetTagID", req.TargetTagID, "moderatorID", moderatorID)
+	h.auditRepo.Record(moderatorID, models.AuditActionMergeTags, models.AuditTargetTag, sourceTagID,
+		nil, result, c.ClientIP())
+	utils.SuccessResponse(c, 200, "合并成功", result)
+}
+
+// BulkUpdateArticleStatus 批量修改文章状态（管理员，用于下架或删除成批的违规文章）
+func (h *ArticleHandler) BulkUpdateArticleStatus(c *gin.Context) {
+	moderatorID, isOK := getUserIDOrFail(c)
+	if !isOK {
+		return
+	}
+
+	var req models.BulkArticleStatusRequest
+	if !bindJSONOrFail(c, &req, h.logger, "BulkUpdateArticleStatus") {
+		return
+	}
+
+	seen := make(map[uint]bool, len(req.ArticleIDs))
+	ids := make([]uint, 0, len(req.ArticleIDs))
+	for _, id := range req.ArticleIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if maxItems := h.config.BatchOperations.MaxModerationItems; len(ids) > maxItems {
+		utils.BadRequestResponse(c, fmt.Sprintf("单次最多修改%d篇文章", maxItems))
+		return
+	}
+
+	result, err := h.articleRepo.AdminBulkUpdateStatus(c.Request.Context(), ids, *req.Status, moderatorID)
+	if err != nil {
+		utils.ErrorResponse(c, utils.GetHTTPStatusCode(err), "批量修改文章状态失败")
+		return
+	}
+
+	if len(result.UpdatedIDs) > 0 {
+		h.auditRepo.Record(moderatorID, models.AuditActionArticleStatus, models.AuditTargetArticle, 0,
+			nil, result, c.ClientIP())
+	}
+	utils.SuccessResponse(c, 200, "修改成功", result)
+}
+
+// DeleteUserArticles 删除指定用户的全部文章（管理员，软删除）
+func (h *ArticleHandler) DeleteUserArticles(c *gin.Context) {
+	moderatorID, isOK := getUserIDOrFail(c)
+	if !isOK {
+		return
+	}
+
+	targetID, isOK := parseUintParam(c, "id", "无效的用户ID")
+	if !isOK {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := h.userRepo.GetUserByID(ctx, targetID); err != nil {
+		if errors.Is(err, utils.ErrUserNotFound) {
+			utils.NotFoundResponse(c, "用户不存在")
+			return
+		}
+		utils.ErrorResponse(c, utils.GetHTTPStatusCode(err), "删除用户文章失败")
+		return
+	}
+
+	deleted, err := h.articleRepo.AdminDeleteByUser(ctx, targetID)
+	if err != nil {
+		utils.ErrorResponse(c, utils.GetHTTPStatusCode(err), "删除用户文章失败")
+		return
+	}
+
+	h.logger.Info("删除用户文章成功", "targetID", targetID, "moderatorID", moderatorID, "count", len(deleted))
+	if len(deleted) > 0 {
+		h.auditRepo.Record(moderatorID, models.AuditActionDeleteArticles, models.AuditTargetUser, targetID,
+			nil, gin.H{"article_ids": deleted}, c.ClientIP())
+	}
+	utils.SuccessResponse(c, 200, "删除成功", models.BatchDeleteResponse{DeletedIDs: deleted})
+}
+
 // GetCategories 获取所有分类（带缓存）
 func (h *ArticleHandler) GetCategories(c *gin.Context) {
 	ctx := c.Request.Context()
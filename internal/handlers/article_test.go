@@ -0,0 +1,435 @@
+package handlers
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"gin/internal/middleware"
+	"gin/internal/models"
+	"gin/internal/services"
+	"gin/internal/testutil"
+	"gin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestDiffRevisionsEndpoint(t *testing.T) {
+	cfg := newTestConfig()
+	fake, db := newFakeDatabase(t, cfg)
+	now := time.Now().UTC()
+	fake.OnRows(`FROM articles WHERE id = \? AND status != 2`, []string{"user_id", "title", "description", "content", "updated_at"},
+		[]driver.Value{int64(7), "标题", "", "a\nb", now})
+	fake.OnRows(`FROM article_revisions ar`, []string{"user_id", "title", "description", "content", "created_at"},
+		[]driver.Value{int64(7), "标题", "", "a", now})
+
+	h := NewArticleHandler(services.NewArticleRepository(db, cfg), nil, nil, nil, nil, cfg)
+	router := gin.New()
+	router.GET("/api/articles/:id/revisions/diff", h.DiffRevisions)
+
+	// to 默认为当前线上版本
+	resp := doRequest(t, router, http.MethodGet, "/api/articles/5/revisions/diff?from=3", "", nil)
+	if resp.Status != http.StatusOK {
+		t.Fatalf("获取差异应返回200，实际 %d %s", resp.Status, resp.Body)
+	}
+	var diff models.ArticleRevisionDiff
+	decodeData(t, resp, &diff)
+	if diff.ToRevisionID != models.ArticleRevisionCurrent || diff.Added != 1 || len(diff.Lines) != 1 || diff.Lines[0].NewText != "b" {
+		t.Fatalf("应返回与当前版本相比新增的一行，实际 %+v", diff)
+	}
+
+	for _, query := range []string{"", "?from=abc", "?from=3&to=-1", "?from=current&to=x"} {
+		if r := doRequest(t, router, http.MethodGet, "/api/articles/5/revisions/diff"+query, "", nil); r.Status != http.StatusBadRequest {
+			t.Fatalf("参数 %q 无效时应返回400，实际 %d", query, r.Status)
+		}
+	}
+}
+
+func TestToggleArticleLikeDuplicateReturnsConflict(t *testing.T) {
+	cfg := newTestConfig()
+	fake, db := newFakeDatabase(t, cfg)
+	// 并发点赞：检查时尚未点赞，插入时唯一键冲突
+	fake.OnRows(`SELECT id FROM article_likes WHERE article_id = \? AND user_id = \?`, []string{"id"})
+	fake.OnError(`INSERT INTO article_likes`, &mysql.MySQLError{Number: 1062, Message: "Duplicate entry '5-1'"})
+
+	router := gin.New()
+	router.POST("/api/articles/:id/like", middleware.AuthMiddleware(cfg, nil, nil),
+		NewArticleHandler(services.NewArticleRepository(db, cfg), nil, nil, nil, nil, cfg).ToggleArticleLike)
+	token := signTestJWT(t, cfg, 1, "alice")
+
+	resp := doRequest(t, router, http.MethodPost, "/api/articles/5/like", token, nil)
+	if resp.Status != http.StatusConflict || resp.Message != "已经点过赞了" || resp.ErrorCode != utils.ErrCodeDuplicateEntry {
+		t.Fatalf("重复点赞应返回409和明确提示，实际 %d %s", resp.Status, resp.Body)
+	}
+
+	// 其他数据库错误不暴露内部信息
+	fake.OnError(`INSERT INTO article_likes`, errors.New("connection reset"))
+	resp = doRequest(t, router, http.MethodPost, "/api/articles/5/like", token, nil)
+	if resp.Status != http.StatusInternalServerError || resp.Message != "操作失败" {
+		t.Fatalf("其他错误应返回500和通用提示，实际 %d %s", resp.Status, resp.Body)
+	}
+}
+
+func TestGetCommentAncestryEndpoint(t *testing.T) {
+	cfg := newTestConfig()
+	fake, db := newFakeDatabase(t, cfg)
+	fake.On(`FROM article_comments ac LEFT JOIN user_auth ua`, func(args []driver.Value) testutil.Response {
+		resp := testutil.Response{Columns: []string{"id", "article_id", "parent_id", "status", "snippet", "created_at",
+			"user_id", "username", "nickname", "avatar"}}
+		if args[1] == int64(1) {
+			resp.Rows = [][]driver.Value{{int64(1), int64(5), int64(0), int64(1), "一级评论", time.Now().UTC(), int64(7), "bob", "bob", ""}}
+		}
+		return resp
+	})
+	router := gin.New()
+	router.GET("/api/comments/:id/ancestry", NewArticleHandler(services.NewArticleRepository(db, cfg), nil, nil, nil, nil, cfg).GetCommentAncestry)
+
+	resp := doRequest(t, router, http.MethodGet, "/api/comments/1/ancestry", "", nil)
+	if resp.Status != http.StatusOK {
+		t.Fatalf("获取回复链应返回200，实际 %d %s", resp.Status, resp.Body)
+	}
+	var ancestry models.CommentAncestryResponse
+	decodeData(t, resp, &ancestry)
+	if ancestry.CommentID != 1 || ancestry.ArticleID != 5 || ancestry.Ancestors == nil || len(ancestry.Ancestors) != 0 {
+		t.Fatalf("一级评论应返回空的祖先列表，实际 %s", resp.Body)
+	}
+
+	if r := doRequest(t, router, http.MethodGet, "/api/comments/9/ancestry", "", nil); r.Status != http.StatusNotFound {
+		t.Fatalf("评论不存在应返回404，实际 %d", r.Status)
+	}
+	if r := doRequest(t, router, http.MethodGet, "/api/comments/abc/ancestry", "", nil); r.Status != http.StatusBadRequest {
+		t.Fatalf("无效的评论ID应返回400，实际 %d", r.Status)
+	}
+}
+
+func TestGetArticleLikersEndpoint(t *testing.T) {
+	cfg := newTestConfig()
+	fake, db := newFakeDatabase(t, cfg)
+	fake.On(`SELECT COUNT\(\*\) FROM articles WHERE id = \? AND status = 1`, func(args []driver.Value) testutil.Response {
+		visible := int64(0)
+		if args[0] == int64(5) {
+			visible = 1
+		}
+		return testutil.Response{Columns: []string{"count"}, Rows: [][]driver.Value{{visible}}}
+	})
+	fake.OnRows(`SELECT COUNT\(\*\) FROM article_likes l`, []string{"count"}, []driver.Value{int64(3)})
+	fake.OnRows(`SELECT ua.id, ua.username`, []string{"id", "username", "nickname", "avatar", "created_at"},
+		[]driver.Value{int64(2), "bob", "Bob", "", time.Now().UTC()})
+
+	router := gin.New()
+	router.GET("/api/articles/:id/likes", NewArticleHandler(services.NewArticleRepository(db, cfg), nil, nil, nil, nil, cfg).GetArticleLikers)
+
+	resp := doRequest(t, router, http.MethodGet, "/api/articles/5/likes?page=2&page_size=1", "", nil)
+	if resp.Status != http.StatusOK {
+		t.Fatalf("获取点赞用户应成功，实际 %d %s", resp.Status, resp.Body)
+	}
+	var likers models.LikersResponse
+	decodeData(t, resp, &likers)
+	if likers.Total != 3 || likers.Page != 2 || likers.TotalPages != 3 || len(likers.Users) != 1 || likers.Users[0].Nickname != "Bob" {
+		t.Fatalf("点赞用户分页结果错误: %+v", likers)
+	}
+	if args := fake.Calls(`SELECT ua.id, ua.username`)[0].Args; args[2] != int64(1) || args[3] != int64(1) {
+		t.Fatalf("LIMIT/OFFSET 应为 1/1，实际 %v", args)
+	}
+
+	// 超出上限的 page_size 使用默认值
+	doRequest(t, router, http.MethodGet, "/api/articles/5/likes?page_size=100000", "", nil)
+	if args := fake.Calls(`SELECT ua.id, ua.username`)[1].Args; args[2] != int64(cfg.Pagination.DefaultPageSize) {
+		t.Fatalf("超出上限的 page_size 应使用默认值，实际 %v", args)
+	}
+
+	if resp := doRequest(t, router, http.MethodGet, "/api/articles/9/likes", "", nil); resp.Status != http.StatusNotFound {
+		t.Fatalf("文章不可见时应返回404，实际 %d", resp.Status)
+	}
+}
+
+// getArticleDetail 请求文章详情，ifNoneMatch 非空时带上条件请求头
+func getArticleDetail(router http.Handler, path, ifNoneMatch string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestGetArticleDetailConditionalRequest(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Cache.ViewDedup.WindowMinutes = 0 // 每次浏览都计数
+	fake, db := newFakeDatabase(t, cfg)
+	updatedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	likes := int64(3)
+	fake.On(`SELECT a.version, a.updated_at`, func(args []driver.Value) testutil.Response {
+		resp := testutil.Response{Columns: []string{"version", "updated_at", "author_updated_at", "like_count", "comment_count", "liked"}}
+		if args[1] == int64(5) {
+			resp.Rows = [][]driver.Value{{int64(2), updatedAt, updatedAt, likes, int64(1), int64(0)}}
+		}
+		return resp
+	})
+	fake.OnRows(`FROM articles a INNER JOIN user_auth ua ON a.user_id = ua.id LEFT JOIN user_profile up ON ua.id = up.user_id WHERE a.id = \? AND a.status != 2`,
+		[]string{"id", "user_id", "title", "description", "content", "status", "view_count", "like_count",
+			"comment_count", "version", "created_at", "updated_at", "username", "nickname", "avatar"},
+		[]driver.Value{int64(5), int64(7), "title", "desc", "content", int64(1), int64(0), likes,
+			int64(1), int64(2), updatedAt, updatedAt, "alice", "Alice", ""})
+	fake.OnRows(`FROM article_code_blocks WHERE article_id = \?`, []string{"id"})
+	fake.OnRows(`FROM article_categories ac`, []string{"id"})
+	fake.OnRows(`FROM article_tags at`, []string{"id"})
+	fake.OnExec(`UPDATE articles SET view_count = view_count \+ 1`, 0, 1)
+
+	articleRepo := services.NewArticleRepository(db, cfg)
+	router := gin.New()
+	router.GET("/api/articles/:id", NewArticleHandler(articleRepo, nil, services.NewCacheService(articleRepo, cfg), nil,
+		services.NewViewDeduplicator(cfg), cfg).GetArticleDetail)
+
+	// ETag 匹配时返回304，不加载详情
+	etag := articleETag(5, &models.ArticleVersion{Version: 2, UpdatedAt: updatedAt, AuthorUpdatedAt: updatedAt, LikeCount: 3, CommentCount: 1})
+	w := getArticleDetail(router, "/api/articles/5", etag)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 || w.Header().Get("ETag") != etag {
+		t.Fatalf("ETag 未变化时应返回空的304，实际 %d %q", w.Code, w.Body.String())
+	}
+	if calls := fake.Calls(`FROM article_code_blocks`); len(calls) != 0 {
+		t.Fatal("304 时不应加载文章详情")
+	}
+
+	w = getArticleDetail(router, "/api/articles/5", `W/"stale"`)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != etag || w.Header().Get("Last-Modified") != updatedAt.Format(http.TimeFormat) {
+		t.Fatalf("ETag 不匹配时应返回完整详情和缓存头，实际 %d %v", w.Code, w.Header())
+	}
+	if w.Header().Get("Cache-Control") != "private, no-cache" {
+		t.Fatalf("点赞状态因用户而异，响应应为 private，实际 %q", w.Header().Get("Cache-Control"))
+	}
+
+	// 点赞数变化后 ETag 随之变化
+	likes = 4
+	if w := getArticleDetail(router, "/api/articles/5", etag); w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+		t.Fatalf("点赞数变化后旧 ETag 不应命中，实际 %d %s", w.Code, w.Header().Get("ETag"))
+	}
+
+	// 304 同样计入浏览次数
+	deadline := time.Now().Add(2 * time.Second)
+	for len(fake.Calls(`UPDATE articles SET view_count`)) < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := len(fake.Calls(`UPDATE articles SET view_count`)); n != 3 {
+		t.Fatalf("3次访问（含304）都应增加浏览次数，实际 %d 次", n)
+	}
+
+	if w := getArticleDetail(router, "/api/articles/9", etag); w.Code != http.StatusNotFound {
+		t.Fatalf("文章不存在应返回404，实际 %d", w.Code)
+	}
+}
+
+func TestETagMatches(t *testing.T) {
+	etag := `W/"a5-1"`
+	cases := []struct {
+		ifNoneMatch string
+		want        bool
+	}{
+		{"", false},
+		{`W/"a5-1"`, true},
+		{`"a5-1"`, true}, // 弱比较忽略 W/ 前缀
+		{`"x", W/"a5-1"`, true},
+		{"*", true},
+		{`W/"a5-2"`, false},
+	}
+	for _, tc := range cases {
+		if got := etagMatches(tc.ifNoneMatch, etag); got != tc.want {
+			t.Errorf("etagMatches(%q) = %v，期望 %v", tc.ifNoneMatch, got, tc.want)
+		}
+	}
+}
+
+func TestResolveTagIDs(t *testing.T) {
+	cfg := newTestConfig()
+	fake, db := newFakeDatabase(t, cfg)
+	fake.OnRows(`SELECT id, slug FROM article_tags WHERE slug IN`, []string{"id", "slug"},
+		[]driver.Value{int64(3), "go"}, []driver.Value{int64(8), "rust"})
+	h := NewArticleHandler(services.NewArticleRepository(db, cfg), nil, nil, nil, nil, cfg)
+
+	ids := h.resolveTagIDs(context.Background(), []uint{5, 3}, []string{"Go", "rust", " GO "})
+	if !slices.Equal(ids, []uint{5, 3, 8}) {
+		t.Fatalf("应合并标签ID并按顺序去重，实际 %v", ids)
+	}
+	if calls := fake.Calls(`FROM article_tags`); len(calls) != 1 {
+		t.Fatalf("标签名应一次批量查询，实际 %d 次", len(calls))
+	}
+
+	// 标签处理失败不影响已选的标签
+	fake.OnError(`FROM article_tags`, errors.New("connection refused"))
+	if ids := h.resolveTagIDs(context.Background(), []uint{5}, []string{"Kotlin"}); !slices.Equal(ids, []uint{5}) {
+		t.Fatalf("批量处理标签失败时应保留已选标签，实际 %v", ids)
+	}
+}
+
+func TestTagAdminEndpointErrors(t *testing.T) {
+	cfg := newTestConfig()
+	fake, db := newFakeDatabase(t, cfg)
+	fake.OnRows(`SELECT article_id FROM article_tag_relations WHERE tag_id = \?`, []string{"article_id"})
+	fake.OnError(`UPDATE article_tags SET name = \?, slug = \?`, &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'go' for key 'uk_slug'"})
+	h := NewArticleHandler(services.NewArticleRepository(db, cfg), nil, nil, nil, nil, cfg)
+	router := gin.New()
+	auth := middleware.AuthMiddleware(cfg, nil, nil)
+	router.PUT("/api/admin/tags/:id", auth, h.RenameTag)
+	router.POST("/api/admin/tags/:id/merge", auth, h.MergeTag)
+	token := signTestJWT(t, cfg, 1, "admin")
+
+	resp := doRequest(t, router, http.MethodPut, "/api/admin/tags/4", token, map[string]string{"name": "Go"})
+	if resp.Status != http.StatusConflict {
+		t.Fatalf("重命名为已存在的标签应返回409，实际 %d %s", resp.Status, resp.Body)
+	}
+
+	resp = doRequest(t, router, http.MethodPost, "/api/admin/tags/4/merge", token, map[string]uint{"target_tag_id": 4})
+	if resp.Status != http.StatusBadRequest || len(fake.Calls(`FOR UPDATE`)) != 0 {
+		t.Fatalf("合并到自身应在访问数据库前返回400，实际 %d %s", resp.Status, resp.Body)
+	}
+}
+
+func TestBatchDeleteCommentsEndpoint(t *testing.T) {
+	cfg := newTestConfig()
+	fake, db := newFakeDatabase(t, cfg)
+	// 评论 1 属于用户 1，评论 2 属于用户 2
+	fake.On(`SELECT id, user_id, article_id FROM article_comments WHERE id IN`, func(args []driver.Value) testutil.Response {
+		var rows [][]driver.Value
+		for _, arg := range args {
+			if id := arg.(int64); id <= 2 {
+				rows = append(rows, []driver.Value{id, id, int64(100)})
+			}
+		}
+		return testutil.Response{Columns: []string{"id", "user_id", "article_id"}, Rows: rows}
+	})
+	fake.OnExec(`UPDATE article_comments SET status = 0`, 0, 1)
+	fake.OnExec(`UPDATE articles SET comment_count`, 0, 1)
+	router := gin.New()
+	router.POST("/api/comments/batch-delete", middleware.AuthMiddleware(cfg, nil, nil),
+		NewArticleHandler(services.NewArticleRepository(db, cfg), nil, nil, nil, nil, cfg).BatchDeleteComments)
+	token := signTestJWT(t, cfg, 1, "alice")
+
+	resp := doRequest(t, router, http.MethodPost, "/api/comments/batch-delete", token, map[string][]uint{"ids": {1, 1}})
+	var result models.BatchDeleteResponse
+	decodeData(t, resp, &result)
+	if resp.Status != http.StatusOK || len(result.DeletedIDs) != 1 || result.DeletedIDs[0] != 1 {
+		t.Fatalf("删除自己的评论应返回实际删除的ID，实际 %d %s", resp.Status, resp.Body)
+	}
+
+	for _, tc := range []struct {
+		ids  []uint
+		want int
+	}{
+		{[]uint{1, 3}, http.StatusNotFound},
+		{[]uint{1, 2}, http.StatusForbidden},
+	} {
+		resp := doRequest(t, router, http.MethodPost, "/api/comments/batch-delete", token, map[string][]uint{"ids": tc.ids})
+		if resp.Status != tc.want {
+			t.Fatalf("%v 应返回 %d，实际 %d %s", tc.ids, tc.want, resp.Status, resp.Body)
+		}
+	}
+}
+
+func TestUpdateArticleVersionConflict(t *testing.T) {
+	cfg := newTestConfig()
+	fake, db := newFakeDatabase(t, cfg)
+	fake.OnRows(`SELECT user_id, version FROM articles WHERE id = \?`, []string{"user_id", "version"}, []driver.Value{int64(1), int64(3)})
+	fake.OnExec(`UPDATE articles SET`, 0, 1)
+
+	router := gin.New()
+	router.PUT("/api/articles/:id", func(c *gin.Context) { c.Set("userID", uint(1)) },
+		NewArticleHandler(services.NewArticleRepository(db, cfg), nil, nil, nil, nil, cfg).UpdateArticle)
+
+	resp := doRequest(t, router, http.MethodPut, "/api/articles/5", "", map[string]interface{}{"version": 3, "status": 1})
+	var data struct {
+		Version int `json:"version"`
+	}
+	decodeData(t, resp, &data)
+	if resp.Status != http.StatusOK || data.Version != 4 {
+		t.Fatalf("更新成功时应返回新版本号，实际 %d %s", resp.Status, resp.Body)
+	}
+
+	resp = doRequest(t, router, http.MethodPut, "/api/articles/5", "", map[string]interface{}{"version": 2, "status": 1})
+	if resp.Status != http.StatusConflict || resp.ErrorCode != utils.ErrCodeVersionConflict {
+		t.Fatalf("版本号过期时应返回409和 VERSION_CONFLICT，实际 %d %s", resp.Status, resp.Body)
+	}
+
+	// 未携带版本号时拒绝更新
+	if resp := doRequest(t, router, http.MethodPut, "/api/articles/5", "", map[string]interface{}{"status": 1}); resp.Status < 400 || resp.Status >= 500 {
+		t.Fatalf("缺少版本号时应返回参数错误，实际 %d", resp.Status)
+	}
+	if calls := fake.Calls(`UPDATE articles SET`); len(calls) != 1 {
+		t.Fatalf("只有版本号一致的请求应更新文章，实际更新 %d 次", len(calls))
+	}
+}
+
+func TestBulkUpdateArticleStatusEndpoint(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.BatchOperations.MaxModerationItems = 2
+	fake, db := newFakeDatabase(t, cfg)
+	fake.OnRows(`SELECT id, status FROM articles WHERE id IN`, []string{"id", "status"},
+		[]driver.Value{int64(1), int64(1)}, []driver.Value{int64(2), int64(0)})
+	fake.OnExec(`UPDATE articles SET status`, 0, 2)
+	fake.OnExec(`UPDATE article_(categories|tags)`, 0, 1)
+	fake.OnExec(`INSERT INTO admin_audit_log`, 1, 1)
+	h := NewArticleHandler(services.NewArticleRepository(db, cfg), nil, nil, services.NewAuditRepository(db, cfg), nil, cfg)
+	router := gin.New()
+	router.POST("/api/admin/articles/status", middleware.AuthMiddleware(cfg, nil, nil), h.BulkUpdateArticleStatus)
+	token := signTestJWT(t, cfg, 1, "admin")
+
+	// 重复的ID去重后再检查数量上限
+	resp := doRequest(t, router, http.MethodPost, "/api/admin/articles/status", token,
+		map[string]interface{}{"article_ids": []uint{1, 2, 1}, "status": 2})
+	var result models.BulkArticleStatusResult
+	decodeData(t, resp, &result)
+	if resp.Status != http.StatusOK || !slices.Equal(result.UpdatedIDs, []uint{1, 2}) {
+		t.Fatalf("批量删除应返回被修改的文章，实际 %d %s", resp.Status, resp.Body)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(fake.Calls(`INSERT INTO admin_audit_log`)) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	calls := fake.Calls(`INSERT INTO admin_audit_log`)
+	if len(calls) != 1 || calls[0].Args[0] != int64(1) || calls[0].Args[1] != models.AuditActionArticleStatus {
+		t.Fatalf("应记录一条批量修改状态的审计日志，实际 %v", calls)
+	}
+
+	resp = doRequest(t, router, http.MethodPost, "/api/admin/articles/status", token,
+		map[string]interface{}{"article_ids": []uint{1, 2, 3}, "status": 2})
+	if resp.Status != http.StatusBadRequest {
+		t.Fatalf("超过单次上限时应返回400，实际 %d %s", resp.Status, resp.Body)
+	}
+	for _, body := range []map[string]interface{}{
+		{"article_ids": []uint{1}, "status": 3}, // 无效状态
+		{"article_ids": []uint{1}},              // 缺少状态
+		{"article_ids": []uint{}, "status": 1},  // 空列表
+	} {
+		if resp := doRequest(t, router, http.MethodPost, "/api/admin/articles/status", token, body); resp.Status < 400 || resp.Status >= 500 {
+			t.Fatalf("请求 %v 应返回参数错误，实际 %d %s", body, resp.Status, resp.Body)
+		}
+	}
+	if locks := fake.Calls(`FOR UPDATE`); len(locks) != 1 {
+		t.Fatalf("无效请求不应访问文章，实际锁定 %d 次", len(locks))
+	}
+}
+
+func TestDeleteUserArticlesEndpoint(t *testing.T) {
+	cfg := newTestConfig()
+	fake, db := newFakeDatabase(t, cfg)
+	fake.OnRows(`FROM user_auth WHERE id = \?`, []string{"id"})
+	h := NewArticleHandler(services.NewArticleRepository(db, cfg), services.NewUserRepository(db), nil, nil, nil, cfg)
+	router := gin.New()
+	router.POST("/api/admin/users/:id/articles/delete", middleware.AuthMiddleware(cfg, nil, nil), h.DeleteUserArticles)
+	token := signTestJWT(t, cfg, 1, "admin")
+
+	resp := doRequest(t, router, http.MethodPost, "/api/admin/users/7/articles/delete", token, nil)
+	if resp.Status != http.StatusNotFound || len(fake.Calls(`FROM articles`)) != 0 {
+		t.Fatalf("用户不存在时应返回404且不修改文章，实际 %d %s", resp.Status, resp.Body)
+	}
+	if resp := doRequest(t, router, http.MethodPost, "/api/admin/users/abc/articles/delete", token, nil); resp.Status != http.StatusBadRequest {
+		t.Fatalf("无效的用户ID应返回400，实际 %d", resp.Status)
+	}
+}
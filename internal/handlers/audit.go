@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"gin/internal/models"
+	"gin/internal/services"
+	"gin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditHandler 管理员操作审计日志处理器
+type AuditHandler struct {
+	auditRepo *services.AuditRepository
+	logger    utils.Logger
+}
+
+// NewAuditHandler 创建审计日志处理器
+func NewAuditHandler(auditRepo *services.AuditRepository) *AuditHandler {
+	return &AuditHandler{
+		auditRepo: auditRepo,
+		logger:    utils.GetLogger(),
+	}
+}
+
+// ListAuditLog 分页查询管理员操作审计日志（仅管理员）
+func (h *AuditHandler) ListAuditLog(c *gin.Context) {
+	var query models.AuditLogQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ValidationErrorResponse(c, "查询参数错误")
+		return
+	}
+
+	result, err := h.auditRepo.ListAuditLog(c.Request.Context(), query)
+	if err != nil {
+		h.logger.Error("获取审计日志失败", "error", err.Error())
+		utils.InternalServerErrorResponse(c, "获取审计日志失败")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "获取成功", result)
+}
@@ -1,7 +1,7 @@
 package handlers
 
 import (
-	"fmt"
+	"errors"
 	"time"
 
 	"gin/internal/config"
@@ -15,15 +15,19 @@ import (
 // AuthHandler 认证处理器
 type AuthHandler struct {
 	authService services.AuthServiceInterface
+	auditRepo   *services.AuditRepository
 	config      *config.Config
+	passwords   *utils.PasswordValidator
 	logger      utils.Logger
 }
 
 // NewAuthHandler 创建认证处理器
-func NewAuthHandler(authService services.AuthServiceInterface, cfg *config.Config) *AuthHandler {
+func NewAuthHandler(authService services.AuthServiceInterface, auditRepo *services.AuditRepository, cfg *config.Config) *AuthHandler {
 	return &AuthHandler{
 		authService: authService,
+		auditRepo:   auditRepo,
 		config:      cfg,
+		passwords:   utils.NewPasswordValidator(&cfg.Validation.Password),
 		logger:      utils.GetLogger(),
 	}
 }
@@ -59,8 +63,11 @@ func (h *AuthHandler) Login(c *gin.Context) {
 			"username", req.Username,
 			"error", err.Error(),
 			"ip", reqCtx.ClientIP)
-		statusCode := utils.GetHTTPStatusCode(err)
-		utils.ErrorResponse(c, statusCode, err.Error())
+		if errors.Is(err, utils.ErrAccountLocked) {
+			utils.CodeErrorResponse(c, 423, utils.ErrCodeAccountLocked, err.Error())
+			return
+		}
+		utils.AppErrorResponse(c, err, "登录失败")
 		return
 	}
 
@@ -107,8 +114,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 			"email", utils.SanitizeEmail(req.Email),
 			"error", err.Error(),
 			"ip", reqCtx.ClientIP)
-		statusCode := utils.GetHTTPStatusCode(err)
-		utils.ErrorResponse(c, statusCode, err.Error())
+		utils.AppErrorResponse(c, err, "用户注册失败")
 		return
 	}
 
@@ -133,9 +139,54 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 		"username", username,
 		"ip", reqCtx.ClientIP)
 
+	// 请求体可选：携带刷新token时一并删除
+	var req models.LogoutRequest
+	if c.Request.ContentLength > 0 {
+		if !bindJSONOrFail(c, &req, h.logger, "Logout") {
+			return
+		}
+	}
+
+	// 当前访问token加入黑名单（API令牌认证时没有jti，不受影响）
+	jti := c.GetString("jti")
+	expiresAt := c.GetTime("tokenExpiresAt")
+	if err := h.authService.Logout(c.Request.Context(), userID, jti, expiresAt, req.RefreshToken); err != nil {
+		h.logger.Error("删除刷新token失败",
+			"userID", userID,
+			"error", err.Error(),
+			"ip", reqCtx.ClientIP)
+		utils.ErrorResponse(c, utils.GetHTTPStatusCode(err), err.Error())
+		return
+	}
+
 	utils.SuccessResponse(c, 200, "退出登录成功", gin.H{"ok": true})
 }
 
+// RefreshToken 使用刷新token换取新的访问token（刷新token同时轮换）
+func (h *AuthHandler) RefreshToken(c *gin.Context) {
+	reqCtx := extractRequestContext(c)
+
+	var req models.RefreshTokenRequest
+	if !bindJSONOrFail(c, &req, h.logger, "RefreshToken") {
+		return
+	}
+
+	response, err := h.authService.RefreshToken(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		h.logger.Warn("刷新token失败",
+			"error", err.Error(),
+			"ip", reqCtx.ClientIP)
+		if errors.Is(err, utils.ErrTokenAlreadyUsed) {
+			utils.CodeErrorResponse(c, 401, utils.ErrCodeInvalidToken, "刷新token已失效，请重新登录")
+			return
+		}
+		utils.ErrorResponse(c, utils.GetHTTPStatusCode(err), err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "刷新成功", response)
+}
+
 // validateLoginRequest 验证登录请求
 func (h *AuthHandler) validateLoginRequest(req *models.LoginRequest) error {
 	if req.Username == "" || req.Password == "" {
@@ -197,17 +248,16 @@ func (h *AuthHandler) validateRegisterRequest(req *models.RegisterRequest) error
 		return utils.ErrInvalidUsername
 	}
 
-	// 验证密码强度
-	if !utils.ValidatePasswordWithConfig(req.Password, &h.config.Validation.Password, false) {
-		return utils.NewAppError(utils.ErrInvalidPassword,
-			fmt.Sprintf("密码必须至少%d位，并包含字母和数字", h.config.Validation.Password.MinLength), 400)
-	}
-
 	// 验证邮箱格式
 	if !utils.ValidateEmail(req.Email) {
 		return utils.ErrInvalidEmail
 	}
 
+	// 验证密码强度（按配置的复杂度策略，需在用户名和邮箱清理之后）
+	if err := h.passwords.Validate(req.Password, req.Username, req.Email); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -237,8 +287,7 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 			"userID", userID,
 			"error", err.Error(),
 			"ip", reqCtx.ClientIP)
-		statusCode := utils.GetHTTPStatusCode(err)
-		utils.ErrorResponse(c, statusCode, err.Error())
+		utils.AppErrorResponse(c, err, "密码修改失败")
 		return
 	}
 
@@ -249,3 +298,126 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 
 	utils.SuccessResponse(c, 200, "密码修改成功", gin.H{"ok": true})
 }
+
+// DeleteAccount 注销当前账号（需验证密码，个人信息匿名化，已发布内容保留）
+func (h *AuthHandler) DeleteAccount(c *gin.Context) {
+	reqCtx := extractRequestContext(c)
+
+	var req models.DeleteAccountRequest
+	if !bindJSONOrFail(c, &req, h.logger, "DeleteAccount") {
+		return
+	}
+
+	userID, isOK := getUserIDOrFail(c)
+	if !isOK {
+		return
+	}
+
+	h.logger.Info("收到注销账号请求",
+		"userID", userID,
+		"ip", reqCtx.ClientIP)
+
+	ctx := c.Request.Context()
+	if err := h.authService.DeleteAccount(ctx, userID, req.Password, reqCtx.ClientIP); err != nil {
+		h.logger.Warn("注销账号失败",
+			"userID", userID,
+			"error", err.Error(),
+			"ip", reqCtx.ClientIP)
+		utils.AppErrorResponse(c, err, "注销账号失败")
+		return
+	}
+
+	h.logger.Info("注销账号成功",
+		"userID", userID,
+		"ip", reqCtx.ClientIP,
+		"duration", time.Since(reqCtx.StartTime))
+
+	utils.SuccessResponse(c, 200, "账号已注销", gin.H{"ok": true})
+}
+
+// ForgotPassword 处理找回密码请求（无论邮箱是否注册都返回相同响应）
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	reqCtx := extractRequestContext(c)
+
+	var req models.ForgotPasswordRequest
+	if !bindJSONOrFail(c, &req, h.logger, "ForgotPassword") {
+		return
+	}
+
+	if !utils.ValidateEmail(req.Email) {
+		utils.ValidationErrorResponse(c, "邮箱格式不正确")
+		return
+	}
+
+	h.logger.Info("收到找回密码请求",
+		"email", utils.SanitizeEmail(req.Email),
+		"ip", reqCtx.ClientIP)
+
+	if err := h.authService.RequestPasswordReset(c.Request.Context(), req.Email, reqCtx.ClientIP); err != nil {
+		h.logger.Error("处理找回密码请求失败",
+			"email", utils.SanitizeEmail(req.Email),
+			"error", err.Error(),
+			"ip", reqCtx.ClientIP)
+	}
+
+	utils.SuccessResponse(c, 200, "如果该邮箱已注册，重置密码邮件将很快送达", nil)
+}
+
+// ResetPassword 处理重置密码请求
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	reqCtx := extractRequestContext(c)
+
+	var req models.ResetPasswordRequest
+	if !bindJSONOrFail(c, &req, h.logger, "ResetPassword") {
+		return
+	}
+
+	if err := h.authService.ResetPassword(c.Request.Context(), req.Token, req.NewPassword); err != nil {
+		h.logger.Warn("重置密码失败",
+			"error", err.Error(),
+			"ip", reqCtx.ClientIP)
+		var appErr *utils.AppError
+		if errors.As(err, &appErr) && appErr.Code == 400 {
+			utils.BadRequestResponse(c, appErr.Message)
+			return
+		}
+		utils.ErrorResponse(c, utils.GetHTTPStatusCode(err), err.Error())
+		return
+	}
+
+	h.logger.Info("密码重置成功",
+		"ip", reqCtx.ClientIP,
+		"duration", time.Since(reqCtx.StartTime))
+
+	utils.SuccessResponse(c, 200, "密码重置成功", gin.H{"ok": true})
+}
+
+// RevokeUserSessions 管理员注销指定用户的全部会话（已签发的token立即失效）
+func (h *AuthHandler) RevokeUserSessions(c *gin.Context) {
+	reqCtx := extractRequestContext(c)
+
+	targetID, ok := parseUintParam(c, "id", "无效的用户ID")
+	if !ok {
+		return
+	}
+	adminID, _ := utils.GetUserIDFromContext(c)
+
+	if err := h.authService.RevokeAllSessions(c.Request.Context(), targetID); err != nil {
+		h.logger.Warn("注销用户全部会话失败",
+			"adminID", adminID,
+			"targetID", targetID,
+			"error", err.Error(),
+			"ip", reqCtx.ClientIP)
+		utils.ErrorResponse(c, utils.GetHTTPStatusCode(err), err.Error())
+		return
+	}
+
+	h.logger.Info("管理员注销用户全部会话",
+		"adminID", adminID,
+		"targetID", targetID,
+		"ip", reqCtx.ClientIP)
+	h.auditRepo.Record(adminID, models.AuditActionRevokeSessions, models.AuditTargetUser, targetID,
+		nil, gin.H{"sessions_revoked": true}, reqCtx.ClientIP)
+
+	utils.SuccessResponse(c, 200, "已注销该用户的全部会话", gin.H{"ok": true})
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"gin/internal/middleware"
+	"gin/internal/models"
+	"gin/internal/services"
+	"gin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// stubAuthService 只实现测试用到的方法
+type stubAuthService struct {
+	services.AuthServiceInterface
+	resetErrs map[string]error
+	blacklist *services.TokenBlacklist
+	refreshed []string // Logout 收到的刷新token
+	revoked   []uint   // RevokeAllSessions 收到的用户ID
+}
+
+func (s *stubAuthService) RequestPasswordReset(_ context.Context, email, _ string) error {
+	return s.resetErrs[email]
+}
+
+func (s *stubAuthService) Logout(_ context.Context, _ uint, jti string, expiresAt time.Time, refreshToken string) error {
+	s.blacklist.Revoke(jti, expiresAt)
+	s.refreshed = append(s.refreshed, refreshToken)
+	return nil
+}
+
+func (s *stubAuthService) RevokeAllSessions(_ context.Context, userID uint) error {
+	s.revoked = append(s.revoked, userID)
+	return nil
+}
+
+func TestLogoutInvalidatesCurrentToken(t *testing.T) {
+	cfg := newTestConfig()
+	fake, db := newFakeDatabase(t, cfg)
+	fake.OnRows(`SELECT token_version FROM user_auth WHERE id = \?`, []string{"token_version"}, []driver.Value{int64(0)})
+	blacklist := services.NewTokenBlacklist(cfg, services.NewUserRepository(db))
+	svc := &stubAuthService{blacklist: blacklist}
+
+	router := gin.New()
+	auth := router.Group("/api", middleware.AuthMiddleware(cfg, nil, blacklist))
+	auth.POST("/auth/logout", NewAuthHandler(svc, nil, cfg).Logout)
+	auth.GET("/me", func(c *gin.Context) { utils.SuccessResponse(c, http.StatusOK, "success", nil) })
+
+	token := signTestJWT(t, cfg, 5, "alice")
+	if resp := doRequest(t, router, http.MethodGet, "/api/me", token, nil); resp.Status != http.StatusOK {
+		t.Fatalf("退出登录前token应有效，实际 %d", resp.Status)
+	}
+	resp := doRequest(t, router, http.MethodPost, "/api/auth/logout", token, map[string]string{"refresh_token": "rt-1"})
+	if resp.Status != http.StatusOK {
+		t.Fatalf("退出登录应成功，实际 %d %s", resp.Status, resp.Body)
+	}
+	if len(svc.refreshed) != 1 || svc.refreshed[0] != "rt-1" {
+		t.Fatalf("应一并删除请求中的刷新token，实际 %v", svc.refreshed)
+	}
+	if resp := doRequest(t, router, http.MethodGet, "/api/me", token, nil); resp.Status != http.StatusUnauthorized {
+		t.Fatalf("退出登录后同一token应被拒绝，实际 %d", resp.Status)
+	}
+
+	// 不带请求体也可以退出登录
+	other := signTestJWT(t, cfg, 6, "bob")
+	if resp := doRequest(t, router, http.MethodPost, "/api/auth/logout", other, nil); resp.Status != http.StatusOK {
+		t.Fatalf("不带刷新token时退出登录应成功，实际 %d %s", resp.Status, resp.Body)
+	}
+}
+
+func TestForgotPasswordSameResponseRegardlessOfEmail(t *testing.T) {
+	cfg := newTestConfig()
+	svc := &stubAuthService{resetErrs: map[string]error{
+		"unknown@example.com": nil, // 服务层对未注册邮箱同样返回nil
+		"broken@example.com":  utils.ErrDatabaseInsert,
+	}}
+	router := gin.New()
+	router.POST("/api/auth/forgot-password", NewAuthHandler(svc, nil, cfg).ForgotPassword)
+
+	var bodies []string
+	for _, email := range []string{"alice@example.com", "unknown@example.com", "broken@example.com"} {
+		resp := doRequest(t, router, http.MethodPost, "/api/auth/forgot-password", "", map[string]string{"email": email})
+		if resp.Status != http.StatusOK {
+			t.Fatalf("%s: 应返回200，实际 %d %s", email, resp.Status, resp.Body)
+		}
+		bodies = append(bodies, resp.Body)
+	}
+	for _, body := range bodies[1:] {
+		if body != bodies[0] {
+			t.Fatalf("不同邮箱应返回相同响应，实际 %q / %q", bodies[0], body)
+		}
+	}
+}
+
+func TestRevokeUserSessionsRecordsAudit(t *testing.T) {
+	cfg := newTestConfig()
+	fake, db := newFakeDatabase(t, cfg)
+	fake.OnExec(`INSERT INTO admin_audit_log`, 1, 1)
+	svc := &stubAuthService{}
+
+	router := gin.New()
+	router.POST("/api/admin/users/:id/revoke-sessions", middleware.AuthMiddleware(cfg, nil, nil),
+		NewAuthHandler(svc, services.NewAuditRepository(db, cfg), cfg).RevokeUserSessions)
+
+	resp := doRequest(t, router, http.MethodPost, "/api/admin/users/7/revoke-sessions", signTestJWT(t, cfg, 1, "admin"), nil)
+	if resp.Status != http.StatusOK || len(svc.revoked) != 1 || svc.revoked[0] != 7 {
+		t.Fatalf("注销会话应成功，实际 %d %s %v", resp.Status, resp.Body, svc.revoked)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(fake.Calls(`INSERT INTO admin_audit_log`)) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	calls := fake.Calls(`INSERT INTO admin_audit_log`)
+	if len(calls) != 1 {
+		t.Fatalf("应记录一条审计日志，实际 %d 条", len(calls))
+	}
+	if args := calls[0].Args; args[0] != int64(1) || args[1] != models.AuditActionRevokeSessions || args[3] != int64(7) {
+		t.Fatalf("审计日志应记录操作人和目标用户，实际 %v", args)
+	}
+}
+
+func TestListAuditLogEndpoint(t *testing.T) {
+	cfg := newTestConfig()
+	fake, db := newFakeDatabase(t, cfg)
+	fake.OnRows(`SELECT COUNT\(\*\) FROM admin_audit_log l`, []string{"count"}, []driver.Value{int64(0)})
+	fake.OnRows(`FROM admin_audit_log l LEFT JOIN user_auth ua`, []string{"id"})
+
+	router := gin.New()
+	router.GET("/api/admin/audit-log", NewAuditHandler(services.NewAuditRepository(db, cfg)).ListAuditLog)
+
+	resp := doRequest(t, router, http.MethodGet, "/api/admin/audit-log?action=tag.merge&page=1", "", nil)
+	if resp.Status != http.StatusOK {
+		t.Fatalf("查询审计日志应成功，实际 %d %s", resp.Status, resp.Body)
+	}
+	if args := fake.Calls(`SELECT COUNT\(\*\) FROM admin_audit_log l`)[0].Args; len(args) != 1 || args[0] != models.AuditActionMergeTags {
+		t.Fatalf("应按操作类型筛选，实际 %v", args)
+	}
+	if resp := doRequest(t, router, http.MethodGet, "/api/admin/audit-log?actor_id=abc", "", nil); resp.Status != http.StatusUnprocessableEntity {
+		t.Fatalf("无效的查询参数应返回422，实际 %d", resp.Status)
+	}
+}
+
+func TestValidateRegisterRequestPasswordPolicy(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Validation.Password.Policy.MinUppercase = 1
+	h := NewAuthHandler(nil, nil, cfg)
+
+	req := &models.RegisterRequest{Username: "alice", Password: "alice12345", Email: "alice@example.com"}
+	err := h.validateRegisterRequest(req)
+	if !errors.Is(err, utils.ErrInvalidPassword) || !strings.Contains(err.Error(), "大写字母") ||
+		!strings.Contains(err.Error(), "用户名或邮箱") {
+		t.Fatalf("注册时应按策略校验密码并列出未满足的规则，实际 %v", err)
+	}
+
+	req = &models.RegisterRequest{Username: "alice", Password: "Wonderland7", Email: "alice@example.com"}
+	if err := h.validateRegisterRequest(req); err != nil {
+		t.Fatalf("满足策略的密码应通过注册校验，实际 %v", err)
+	}
+}
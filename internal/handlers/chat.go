@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"fmt"
+	"net/http"
 	"strconv"
 
 	"gin/internal/config"
@@ -41,6 +43,16 @@ func (h *ChatHandler) SendMessage(c *gin.Context) {
 		return
 	}
 
+	// 禁言中的用户不能发言
+	if until, muted := utils.ChatMutedUntil(userID); muted {
+		utils.CodeErrorResponse(c, http.StatusForbidden, utils.ErrCodeUserMuted,
+			fmt.Sprintf("您因多次发布违规内容已被禁言至 %s", until.Format("2006-01-02 15:04:05")))
+		return
+	}
+	if !moderateOrFail(c, userID, &req.Content) {
+		return
+	}
+
 	// 从请求上下文获取，避免重复查询
 	ctx := c.Request.Context()
 
@@ -55,7 +67,7 @@ func (h *ChatHandler) SendMessage(c *gin.Context) {
 	ipAddress := c.ClientIP()
 
 	// 发送消息
-	message, err := h.chatRepo.SendMessage(userID, userInfo.User.Username, userInfo.Nickname, userInfo.Avatar, req.Content, ipAddress)
+	message, _, err := h.chatRepo.SendMessage(userID, userInfo.User.Username, userInfo.Nickname, userInfo.Avatar, req.Content, ipAddress, req.ClientMsgID)
 	if err != nil {
 		handleInternalError(c, ErrSendMessageFailed, err, h.logger,
 			"userID", userID,
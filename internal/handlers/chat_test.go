@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"gin/internal/config"
+	"gin/internal/middleware"
+	"gin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// moderationTestRuns 违规和禁言状态保存在全局审核器中，每次运行使用新的用户ID，避免 -count=N 时沿用上次的禁言
+var moderationTestRuns atomic.Uint32
+
+func TestSendMessageModeration(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Moderation.Words = []string{"广告"}
+	cfg.Moderation.ViolationThreshold = 2
+	if err := utils.ConfigureContentModeration(&cfg.Moderation); err != nil {
+		t.Fatalf("配置审核失败: %v", err)
+	}
+	t.Cleanup(func() { _ = utils.ConfigureContentModeration(&config.Default().Moderation) })
+
+	// 审核拒绝和禁言都在访问数据库之前返回
+	router := gin.New()
+	router.POST("/api/chat/messages", middleware.AuthMiddleware(cfg, nil, nil), NewChatHandler(nil, nil, cfg).SendMessage)
+	token := signTestJWT(t, cfg, uint(3100+moderationTestRuns.Add(1)), "spammer")
+
+	resp := doRequest(t, router, http.MethodPost, "/api/chat/messages", token, map[string]string{"content": "广 告位招租"})
+	if resp.Status != http.StatusBadRequest || resp.ErrorCode != utils.ErrCodeContentRejected {
+		t.Fatalf("包含敏感词的消息应返回400，实际 %d %s", resp.Status, resp.Body)
+	}
+
+	// 窗口内第2次违规触发禁言，之后正常内容也不能发送
+	doRequest(t, router, http.MethodPost, "/api/chat/messages", token, map[string]string{"content": "广告"})
+	resp = doRequest(t, router, http.MethodPost, "/api/chat/messages", token, map[string]string{"content": "你好"})
+	if resp.Status != http.StatusForbidden || resp.ErrorCode != utils.ErrCodeUserMuted {
+		t.Fatalf("禁言中的用户发言应返回403，实际 %d %s", resp.Status, resp.Body)
+	}
+}
@@ -50,11 +50,19 @@ func (h *ChunkUploadHandler) InitUpload(c *gin.Context) {
 
 // UploadChunk 上传分片
 func (h *ChunkUploadHandler) UploadChunk(c *gin.Context) {
-	_, isOK := getUserIDOrFail(c)
+	userID, isOK := getUserIDOrFail(c)
 	if !isOK {
 		return
 	}
 
+	// 先解析multipart表单：请求体超限时返回413（PostForm 会忽略解析错误）
+	if _, err := c.MultipartForm(); err != nil {
+		if limit, ok := utils.MaxBytesLimit(err); ok {
+			utils.RequestTooLargeResponse(c, limit)
+			return
+		}
+	}
+
 	uploadID := c.PostForm("upload_id")
 	chunkIndexStr := c.PostForm("chunk_index")
 
@@ -97,10 +105,10 @@ func (h *ChunkUploadHandler) UploadChunk(c *gin.Context) {
 
 	// 上传分片
 	ctx := c.Request.Context()
-	err = h.uploadMgr.UploadChunk(ctx, uploadID, chunkIndex, chunkData)
+	err = h.uploadMgr.UploadChunk(ctx, uploadID, userID, chunkIndex, chunkData)
 	if err != nil {
 		h.logger.Error("上传分片失败", "uploadID", uploadID, "chunkIndex", chunkIndex, "error", err.Error())
-		utils.ErrorResponse(c, 500, "上传分片失败")
+		utils.AppErrorResponse(c, err, "上传分片失败")
 		return
 	}
 
@@ -109,7 +117,7 @@ func (h *ChunkUploadHandler) UploadChunk(c *gin.Context) {
 
 // MergeChunks 合并分片
 func (h *ChunkUploadHandler) MergeChunks(c *gin.Context) {
-	_, isOK := getUserIDOrFail(c)
+	userID, isOK := getUserIDOrFail(c)
 	if !isOK {
 		return
 	}
@@ -120,10 +128,10 @@ func (h *ChunkUploadHandler) MergeChunks(c *gin.Context) {
 	}
 
 	ctx := c.Request.Context()
-	response, err := h.uploadMgr.MergeChunks(ctx, req.UploadID)
+	response, err := h.uploadMgr.MergeChunks(ctx, req.UploadID, userID)
 	if err != nil {
 		h.logger.Error("合并分片失败", "uploadID", req.UploadID, "error", err.Error())
-		utils.ErrorResponse(c, 500, err.Error())
+		utils.AppErrorResponse(c, err, err.Error())
 		return
 	}
 
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
 	"gin/internal/config"
 	"gin/internal/models"
@@ -8,10 +9,17 @@ import (
 	"gin/internal/utils"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
+// maxSnippetKeywordLength 公开代码片段搜索关键词最大长度（字符数）
+const maxSnippetKeywordLength = 100
+
 // CodeHandler 代码处理器
 type CodeHandler struct {
 	repo     services.CodeRepository
@@ -40,6 +48,21 @@ func (h *CodeHandler) ExecuteCode(c *gin.Context) {
 		return
 	}
 
+	// 运行已保存的代码片段时关联执行记录（只能关联自己的或公开的片段）
+	var runSnippet *models.CodeSnippet
+	if req.SnippetID != nil {
+		snippet, err := h.repo.GetSnippetByID(*req.SnippetID)
+		if err != nil {
+			utils.NotFoundResponse(c, "代码片段不存在")
+			return
+		}
+		if snippet.UserID != userID && !snippet.IsPublic {
+			utils.ForbiddenResponse(c, "无权访问此代码片段")
+			return
+		}
+		runSnippet = snippet
+	}
+
 	// 执行代码
 	result, err := h.executor.Execute(c.Request.Context(), req.Language, req.Code, req.Stdin)
 	if err != nil {
@@ -61,6 +84,9 @@ func (h *CodeHandler) ExecuteCode(c *gin.Context) {
 		ExecutionTime: &result.ExecutionTime,
 		Status:        result.Status,
 	}
+	if runSnippet != nil {
+		execution.SnippetID = &runSnippet.ID
+	}
 
 	if result.MemoryUsage > 0 {
 		execution.MemoryUsage = &result.MemoryUsage
@@ -79,6 +105,9 @@ func (h *CodeHandler) ExecuteCode(c *gin.Context) {
 			"user_id", userID,
 			"language", req.Language,
 			"status", result.Status)
+		if runSnippet != nil && runSnippet.IsPublic {
+			h.incrementExecutionCount(c.Request.Context(), runSnippet.ID)
+		}
 	}
 
 	// 如果请求中包含保存标题，则保存代码片段
@@ -101,6 +130,18 @@ func (h *CodeHandler) ExecuteCode(c *gin.Context) {
 	utils.SuccessResponse(c, http.StatusOK, "执行成功", result)
 }
 
+// incrementExecutionCount 增加公开代码片段的执行次数（使用Worker Pool，不影响执行接口的响应时间）
+func (h *CodeHandler) incrementExecutionCount(ctx context.Context, snippetID uint) {
+	taskID := fmt.Sprintf("incr_snippet_exec_%d", snippetID)
+	err := utils.SubmitTaskWithContext(ctx, taskID, func(taskCtx context.Context) error {
+		return h.repo.IncrementExecutionCount(taskCtx, snippetID)
+	}, time.Duration(h.config.AsyncTasks.SnippetExecutionCountTimeout)*time.Second)
+
+	if err != nil {
+		utils.GetLogger().Debug("提交代码片段执行计数任务失败", "snippetID", snippetID, "error", err.Error())
+	}
+}
+
 // CreateSnippet 创建代码片段
 func (h *CodeHandler) CreateSnippet(c *gin.Context) {
 	var req models.SaveSnippetRequest
@@ -137,7 +178,7 @@ func (h *CodeHandler) CreateSnippet(c *gin.Context) {
 				utils.GetLogger().Warn("获取完整代码片段信息失败，无法发送WebSocket通知", "snippetID", snippet.ID, "error", err.Error())
 				return
 			}
-			NotifyNewCodeSnippet(fullSnippet)
+			NotifyNewCodeSnippet(snippet.UserID, fullSnippet)
 		}()
 	}
 
@@ -273,13 +314,34 @@ func (h *CodeHandler) DeleteSnippet(c *gin.Context) {
 
 	if err := h.repo.DeleteSnippet(id, userID); err != nil {
 		utils.GetLogger().Error("删除代码片段失败", "error", err, "snippet_id", id)
-		utils.InternalServerErrorResponse(c, "删除代码片段失败: "+err.Error())
+		utils.InternalServerErrorResponse(c, "删除代码片段失败")
 		return
 	}
 
 	utils.SuccessResponse(c, http.StatusOK, "删除成功", nil)
 }
 
+// BatchDeleteSnippets 批量删除自己的代码片段（任一片段不属于自己时整批失败）
+func (h *CodeHandler) BatchDeleteSnippets(c *gin.Context) {
+	userID, isOK := getUserIDOrFail(c)
+	if !isOK {
+		return
+	}
+
+	ids, isOK := bindBatchIDsOrFail(c, h.config.BatchOperations.MaxDeleteItems)
+	if !isOK {
+		return
+	}
+
+	deleted, err := h.repo.DeleteSnippets(c.Request.Context(), ids, userID)
+	if err != nil {
+		utils.AppErrorResponse(c, err, "批量删除失败")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "删除成功", models.BatchDeleteResponse{DeletedIDs: deleted})
+}
+
 // GetExecutions 获取执行记录列表
 func (h *CodeHandler) GetExecutions(c *gin.Context) {
 	userID, isOK := getUserIDOrFail(c)
@@ -332,6 +394,119 @@ func (h *CodeHandler) GetSharedSnippet(c *gin.Context) {
 	utils.SuccessResponse(c, http.StatusOK, "获取成功", snippet)
 }
 
+// DownloadSnippet 下载代码片段（只有创建者或公开的代码片段可以下载）
+func (h *CodeHandler) DownloadSnippet(c *gin.Context) {
+	id, isOK := parseUintParam(c, "id", "无效的ID")
+	if !isOK {
+		return
+	}
+
+	userID, isOK := getUserIDOrFail(c)
+	if !isOK {
+		return
+	}
+
+	snippet, err := h.repo.GetSnippetByID(id)
+	if err != nil {
+		utils.NotFoundResponse(c, "代码片段不存在")
+		return
+	}
+
+	if snippet.UserID != userID && !snippet.IsPublic {
+		utils.ForbiddenResponse(c, "无权访问此代码片段")
+		return
+	}
+
+	writeSnippetFile(c, snippet)
+}
+
+// DownloadSharedSnippet 通过分享令牌下载代码片段
+func (h *CodeHandler) DownloadSharedSnippet(c *gin.Context) {
+	token := c.Param("token")
+	if token == "" {
+		utils.BadRequestResponse(c, "缺少分享令牌")
+		return
+	}
+
+	snippet, err := h.repo.GetSnippetByShareToken(token)
+	if err != nil {
+		utils.NotFoundResponse(c, "分享链接无效或已过期")
+		return
+	}
+
+	writeSnippetFile(c, snippet)
+}
+
+// writeSnippetFile 以附件形式返回代码片段（文件名取自标题，扩展名和Content-Type取决于语言）
+func writeSnippetFile(c *gin.Context, snippet *models.CodeSnippet) {
+	fileType := models.GetSnippetFileType(snippet.Language)
+	c.Header("Content-Disposition", utils.EncodeFileName(snippet.DownloadFilename()))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Data(http.StatusOK, fileType.ContentType, []byte(snippet.Code))
+}
+
+// ForkSnippet 复制公开的代码片段到自己名下（副本为私有）
+func (h *CodeHandler) ForkSnippet(c *gin.Context) {
+	id, isOK := parseUintParam(c, "id", "无效的ID")
+	if !isOK {
+		return
+	}
+
+	userID, isOK := getUserIDOrFail(c)
+	if !isOK {
+		return
+	}
+
+	snippet, err := h.repo.ForkSnippet(c.Request.Context(), id, userID)
+	if err != nil {
+		if err == utils.ErrResourceNotFound {
+			utils.NotFoundResponse(c, "代码片段不存在")
+			return
+		}
+		utils.InternalServerErrorResponse(c, "复制代码片段失败")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "复制成功", snippet)
+}
+
+// CreateCollaboration 为自己的代码片段创建协作会话，返回会话令牌
+func (h *CodeHandler) CreateCollaboration(c *gin.Context) {
+	id, isOK := parseUintParam(c, "id", "无效的ID")
+	if !isOK {
+		return
+	}
+
+	userID, isOK := getUserIDOrFail(c)
+	if !isOK {
+		return
+	}
+
+	snippet, err := h.repo.GetSnippetByID(id)
+	if err != nil {
+		utils.NotFoundResponse(c, "代码片段不存在")
+		return
+	}
+	if snippet.UserID != userID {
+		utils.ForbiddenResponse(c, "只有创建者可以发起协作")
+		return
+	}
+
+	collab := &models.CodeCollaboration{
+		SnippetID:    snippet.ID,
+		SessionToken: uuid.New().String(),
+		ActiveUsers:  "[]",
+		ExpiresAt:    time.Now().UTC().Add(time.Duration(h.config.CodeCollab.SessionTTLMinutes) * time.Minute),
+	}
+	if err := h.repo.CreateCollaboration(collab); err != nil {
+		utils.GetLogger().Error("创建协作会话失败", "error", err, "snippet_id", id)
+		utils.InternalServerErrorResponse(c, "创建协作会话失败")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "创建成功", collab)
+}
+
 // GenerateShareLink 生成分享链接
 func (h *CodeHandler) GenerateShareLink(c *gin.Context) {
 	id, isOK := parseUintParam(c, "id", "无效的ID")
@@ -344,10 +519,34 @@ func (h *CodeHandler) GenerateShareLink(c *gin.Context) {
 		return
 	}
 
-	token, err := h.repo.GenerateShareToken(id, userID)
+	// 请求体可选，不传则使用默认有效期且不限访问次数
+	var req models.ShareSnippetRequest
+	if c.Request.ContentLength > 0 {
+		if !bindJSONOrFail(c, &req, nil, "") {
+			return
+		}
+	}
+
+	shareCfg := h.config.CodeShare
+	expiryHours := shareCfg.DefaultExpiryHours
+	if req.ExpiresInHours != nil {
+		expiryHours = *req.ExpiresInHours
+	}
+	if shareCfg.MaxExpiryHours > 0 && (expiryHours == 0 || expiryHours > shareCfg.MaxExpiryHours) {
+		utils.BadRequestResponse(c, fmt.Sprintf("有效期必须在1到%d小时之间", shareCfg.MaxExpiryHours))
+		return
+	}
+
+	var expiresAt *time.Time
+	if expiryHours > 0 {
+		t := time.Now().UTC().Add(time.Duration(expiryHours) * time.Hour)
+		expiresAt = &t
+	}
+
+	token, err := h.repo.GenerateShareToken(id, userID, expiresAt, req.MaxViews)
 	if err != nil {
 		utils.GetLogger().Error("生成分享令牌失败", "error", err, "snippet_id", id)
-		utils.InternalServerErrorResponse(c, "生成分享链接失败: "+err.Error())
+		utils.InternalServerErrorResponse(c, "生成分享链接失败")
 		return
 	}
 
@@ -357,11 +556,34 @@ func (h *CodeHandler) GenerateShareLink(c *gin.Context) {
 	response := models.ShareSnippetResponse{
 		ShareToken: token,
 		ShareURL:   shareURL,
+		ExpiresAt:  expiresAt,
+		MaxViews:   req.MaxViews,
 	}
 
 	utils.SuccessResponse(c, http.StatusOK, "生成成功", response)
 }
 
+// RevokeShareLink 吊销分享链接
+func (h *CodeHandler) RevokeShareLink(c *gin.Context) {
+	id, isOK := parseUintParam(c, "id", "无效的ID")
+	if !isOK {
+		return
+	}
+
+	userID, isOK := getUserIDOrFail(c)
+	if !isOK {
+		return
+	}
+
+	if err := h.repo.RevokeShareToken(id, userID); err != nil {
+		utils.GetLogger().Error("吊销分享令牌失败", "error", err, "snippet_id", id)
+		utils.NotFoundResponse(c, "代码片段不存在或无权限")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "吊销成功", nil)
+}
+
 // GetLanguages 获取支持的语言列表
 func (h *CodeHandler) GetLanguages(c *gin.Context) {
 	languages := h.executor.GetSupportedLanguages()
@@ -369,11 +591,23 @@ func (h *CodeHandler) GetLanguages(c *gin.Context) {
 }
 
 // GetPublicSnippets 获取公开的代码片段列表
+// ?language=&keyword=&search_code=true&sort_by=recent|most_executed
 func (h *CodeHandler) GetPublicSnippets(c *gin.Context) {
 	// 分页参数
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(h.config.Pagination.DefaultPageSize)))
 	language := c.Query("language") // 可选的语言筛选
+	keyword := strings.TrimSpace(c.Query("keyword"))
+	sortBy := c.DefaultQuery("sort_by", models.SnippetSortRecent)
+
+	if utf8.RuneCountInString(keyword) > maxSnippetKeywordLength {
+		utils.BadRequestResponse(c, fmt.Sprintf("关键词不能超过%d个字符", maxSnippetKeywordLength))
+		return
+	}
+	if sortBy != models.SnippetSortRecent && sortBy != models.SnippetSortMostExecuted {
+		utils.BadRequestResponse(c, "sort_by 只能是 recent 或 most_executed")
+		return
+	}
 
 	if page < 1 {
 		page = 1
@@ -384,9 +618,16 @@ func (h *CodeHandler) GetPublicSnippets(c *gin.Context) {
 
 	offset := (page - 1) * pageSize
 
-	snippets, total, err := h.repo.GetPublicSnippets(language, pageSize, offset)
+	snippets, total, err := h.repo.GetPublicSnippets(models.PublicSnippetQuery{
+		Language:   language,
+		Keyword:    keyword,
+		SearchCode: c.Query("search_code") == "true",
+		SortBy:     sortBy,
+		Limit:      pageSize,
+		Offset:     offset,
+	})
 	if err != nil {
-		utils.GetLogger().Error("获取公开代码片段列表失败", "error", err, "language", language)
+		utils.GetLogger().Error("获取公开代码片段列表失败", "error", err, "language", language, "keyword", keyword)
 		utils.InternalServerErrorResponse(c, "获取公开代码片段列表失败")
 		return
 	}
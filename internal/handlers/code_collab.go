@@ -0,0 +1,545 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/models"
+	"gin/internal/services"
+	"gin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// collabSendBufferSize is the per-participant outbound queue length
+const collabSendBufferSize = 64
+
+// errCollabRoomFull is returned when a session already has MaxParticipants participants
+var errCollabRoomFull = errors.New("collaboration session is full")
+
+// CollabHub manages collaborative editing rooms, one per collaboration session token.
+// Edits are relayed last-writer-wins: every edit carries the full document, the latest one
+// becomes the room's code and is written back to the snippet periodically.
+type CollabHub struct {
+	mu       sync.Mutex
+	rooms    map[string]*collabRoom
+	codeRepo services.CodeRepository
+	userRepo *services.UserRepository
+	config   *config.Config
+	logger   utils.Logger
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// collabRoom is a live collaboration session
+type collabRoom struct {
+	hub       *CollabHub
+	token     string
+	snippetID uint
+	expiresAt time.Time
+	expiry    *time.Timer
+
+	mu           sync.Mutex // Protects the fields below; taken after hub.mu when both are needed
+	participants map[*collabClient]struct{}
+	code         string
+	version      int64 // Incremented on every accepted edit
+	dirty        bool  // Code changed since the last snapshot
+	closed       bool  // Room removed from the hub; joins must create a new room
+}
+
+// collabClient is a participant connection
+type collabClient struct {
+	room      *collabRoom
+	conn      *websocket.Conn
+	send      chan []byte // Closed by the room (under room.mu) when the client leaves
+	userID    uint
+	username  string
+	closeOnce sync.Once
+}
+
+// collabUser is an entry of the active_users list
+type collabUser struct {
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username"`
+}
+
+var globalCollabHub *CollabHub
+
+// InitCollabHub initializes the global collaboration hub and starts the snapshot loop
+func InitCollabHub(codeRepo services.CodeRepository, userRepo *services.UserRepository, cfg *config.Config) {
+	if globalCollabHub != nil {
+		return
+	}
+	globalCollabHub = &CollabHub{
+		rooms:    make(map[string]*collabRoom),
+		codeRepo: codeRepo,
+		userRepo: userRepo,
+		config:   cfg,
+		logger:   utils.GetLogger(),
+		stopCh:   make(chan struct{}),
+	}
+	go globalCollabHub.run()
+}
+
+// ShutdownCollabHub closes every room of the global hub and saves unsaved code (no-op if not initialized)
+func ShutdownCollabHub(ctx context.Context) {
+	if globalCollabHub == nil {
+		return
+	}
+	globalCollabHub.Shutdown(ctx)
+}
+
+// run saves dirty rooms every SnapshotIntervalSec until Shutdown
+func (h *CollabHub) run() {
+	ticker := time.NewTicker(time.Duration(h.config.CodeCollab.SnapshotIntervalSec) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			h.mu.Lock()
+			rooms := make([]*collabRoom, 0, len(h.rooms))
+			for _, room := range h.rooms {
+				rooms = append(rooms, room)
+			}
+			h.mu.Unlock()
+
+			for _, room := range rooms {
+				room.snapshot(context.Background())
+			}
+		case <-h.stopCh:
+			return
+		}
+	}
+}
+
+// Shutdown stops the snapshot loop, tells participants the server is shutting down and saves every room
+func (h *CollabHub) Shutdown(ctx context.Context) {
+	h.stopOnce.Do(func() {
+		close(h.stopCh)
+	})
+
+	h.mu.Lock()
+	rooms := make([]*collabRoom, 0, len(h.rooms))
+	for token, room := range h.rooms {
+		rooms = append(rooms, room)
+		delete(h.rooms, token)
+	}
+	h.mu.Unlock()
+
+	for _, room := range rooms {
+		room.close("server_shutdown")
+		room.snapshot(ctx)
+	}
+}
+
+// join adds the client to the session's room, creating the room (and loading the snippet code) if needed
+func (h *CollabHub) join(collab *models.CodeCollaboration, client *collabClient) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	select {
+	case <-h.stopCh:
+		return fmt.Errorf("collaboration service is shutting down")
+	default:
+	}
+
+	room, exists := h.rooms[collab.SessionToken]
+	if !exists {
+		snippet, err := h.codeRepo.GetSnippetByID(collab.SnippetID)
+		if err != nil {
+			return err
+		}
+		room = &collabRoom{
+			hub:          h,
+			token:        collab.SessionToken,
+			snippetID:    collab.SnippetID,
+			expiresAt:    collab.ExpiresAt,
+			participants: make(map[*collabClient]struct{}),
+			code:         snippet.Code,
+		}
+		room.expiry = time.AfterFunc(time.Until(collab.ExpiresAt), func() { h.expire(room) })
+		h.rooms[collab.SessionToken] = room
+	}
+
+	room.mu.Lock()
+	if len(room.participants) >= h.config.CodeCollab.MaxParticipants {
+		room.mu.Unlock()
+		return errCollabRoomFull
+	}
+	client.room = room
+	room.participants[client] = struct{}{}
+	init, _ := json.Marshal(WSMessage{Type: "init", Data: map[string]interface{}{
+		"code":       room.code,
+		"version":    room.version,
+		"expires_at": room.expiresAt,
+		"users":      room.usersLocked(),
+	}})
+	client.send <- init
+	room.mu.Unlock()
+
+	h.logger.Info("Collaboration participant joined", "token", collab.SessionToken, "userID", client.userID)
+	room.membersChanged("user_joined", client)
+	return nil
+}
+
+// leave removes the client from its room; the last participant closes the room and saves the code
+func (h *CollabHub) leave(client *collabClient) {
+	room := client.room
+	h.mu.Lock()
+	room.mu.Lock()
+	if _, ok := room.participants[client]; !ok {
+		room.mu.Unlock()
+		h.mu.Unlock()
+		return
+	}
+	delete(room.participants, client)
+	close(client.send)
+	empty := len(room.participants) == 0
+	if empty && !room.closed {
+		room.closed = true
+		room.expiry.Stop()
+		if h.rooms[room.token] == room {
+			delete(h.rooms, room.token)
+		}
+	}
+	room.mu.Unlock()
+	h.mu.Unlock()
+
+	h.logger.Info("Collaboration participant left", "token", room.token, "userID", client.userID)
+	room.membersChanged("user_left", client)
+	if empty {
+		room.snapshot(context.Background())
+	}
+}
+
+// expire closes a room whose session has expired
+func (h *CollabHub) expire(room *collabRoom) {
+	h.mu.Lock()
+	if h.rooms[room.token] == room {
+		delete(h.rooms, room.token)
+	}
+	h.mu.Unlock()
+
+	h.logger.Info("Collaboration session expired", "token", room.token, "snippetID", room.snippetID)
+	room.close("session_expired")
+	room.snapshot(context.Background())
+}
+
+// close notifies all participants with msgType and disconnects them
+func (r *collabRoom) close(msgType string) {
+	data, _ := json.Marshal(WSMessage{Type: msgType, Data: map[string]interface{}{}})
+
+	r.mu.Lock()
+	r.closed = true
+	r.expiry.Stop()
+	for client := range r.participants {
+		select {
+		case client.send <- data:
+		default:
+		}
+		delete(r.participants, client)
+		close(client.send) // writePump flushes the notice, sends a close frame and closes the connection
+	}
+	r.mu.Unlock()
+
+	r.updateActiveUsers(nil)
+}
+
+// usersLocked returns the participant list; r.mu must be held
+func (r *collabRoom) usersLocked() []collabUser {
+	users := make([]collabUser, 0, len(r.participants))
+	for client := range r.participants {
+		users = append(users, collabUser{UserID: client.userID, Username: client.username})
+	}
+	return users
+}
+
+// membersChanged broadcasts the updated participant list and persists it to active_users
+func (r *collabRoom) membersChanged(msgType string, subject *collabClient) {
+	r.mu.Lock()
+	users := r.usersLocked()
+	data, _ := json.Marshal(WSMessage{Type: msgType, Data: map[string]interface{}{
+		"user_id":  subject.userID,
+		"username": subject.username,
+		"users":    users,
+	}})
+	r.broadcastLocked(data, subject)
+	r.mu.Unlock()
+
+	r.updateActiveUsers(users)
+}
+
+// updateActiveUsers writes the participant list to code_collaborations.active_users asynchronously
+func (r *collabRoom) updateActiveUsers(users []collabUser) {
+	if users == nil {
+		users = []collabUser{}
+	}
+	activeUsers, err := json.Marshal(users)
+	if err != nil {
+		return
+	}
+	taskID := fmt.Sprintf("collab_users_%s", r.token)
+	_ = utils.SubmitTask(taskID, func(ctx context.Context) error {
+		return r.hub.codeRepo.UpdateCollaborationUsers(r.token, string(activeUsers))
+	}, time.Duration(r.hub.config.WorkerPool.DefaultTaskTimeout)*time.Second)
+}
+
+// broadcastLocked queues data for every participant except skip; r.mu must be held.
+// Participants whose queue is full are disconnected (their readPump then leaves the room).
+func (r *collabRoom) broadcastLocked(data []byte, skip *collabClient) {
+	for client := range r.participants {
+		if client == skip {
+			continue
+		}
+		select {
+		case client.send <- data:
+		default:
+			r.hub.logger.Warn("Collaboration participant too slow, disconnecting", "token", r.token, "userID", client.userID)
+			client.close()
+		}
+	}
+}
+
+// applyEdit replaces the room's code (last writer wins) and relays it to the other participants.
+// It returns the new version and whether the edit was based on an outdated version.
+// Edits arriving after the room closed are dropped: the final snapshot has already been taken.
+func (r *collabRoom) applyEdit(client *collabClient, code string, baseVersion int64) (int64, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return r.version, false
+	}
+
+	conflict := baseVersion != r.version
+	r.version++
+	r.code = code
+	r.dirty = true
+
+	data, _ := json.Marshal(WSMessage{Type: "edit", Data: map[string]interface{}{
+		"code":    code,
+		"version": r.version,
+		"user_id": client.userID,
+	}})
+	r.broadcastLocked(data, client)
+	return r.version, conflict
+}
+
+// relay forwards a message (e.g. cursor position) from client to the other participants
+func (r *collabRoom) relay(client *collabClient, msgType string, payload interface{}) {
+	data, err := json.Marshal(WSMessage{Type: msgType, Data: map[string]interface{}{
+		"user_id": client.userID,
+		"data":    payload,
+	}})
+	if err != nil {
+		return
+	}
+	r.mu.Lock()
+	r.broadcastLocked(data, client)
+	r.mu.Unlock()
+}
+
+// snapshot writes the room's code back to the snippet if it changed since the last snapshot
+func (r *collabRoom) snapshot(ctx context.Context) {
+	r.mu.Lock()
+	if !r.dirty {
+		r.mu.Unlock()
+		return
+	}
+	code, version := r.code, r.version
+	r.dirty = false
+	r.mu.Unlock()
+
+	if err := r.hub.codeRepo.UpdateSnippetCode(ctx, r.snippetID, code); err != nil {
+		r.hub.logger.Error("Failed to save collaboration snapshot", "token", r.token, "snippetID", r.snippetID, "error", err.Error())
+		r.mu.Lock()
+		if r.version == version {
+			r.dirty = true // Retry on the next tick unless a newer edit already marked it
+		}
+		r.mu.Unlock()
+		return
+	}
+	r.hub.logger.Debug("Collaboration snapshot saved", "token", r.token, "snippetID", r.snippetID, "version", version)
+}
+
+// close closes the connection exactly once
+func (c *collabClient) close() {
+	c.closeOnce.Do(func() {
+		c.conn.Close()
+	})
+}
+
+// reply queues a message for this client only; it is dropped if the client already left
+func (c *collabClient) reply(msgType string, data interface{}) {
+	payload, err := json.Marshal(WSMessage{Type: msgType, Data: data})
+	if err != nil {
+		return
+	}
+	c.room.mu.Lock()
+	defer c.room.mu.Unlock()
+	if _, ok := c.room.participants[c]; !ok {
+		return
+	}
+	select {
+	case c.send <- payload:
+	default:
+	}
+}
+
+// readPump reads edit and cursor messages until the connection closes, then leaves the room
+func (c *collabClient) readPump(hub *CollabHub) {
+	defer func() {
+		hub.leave(c)
+		c.close()
+	}()
+
+	wsCfg := hub.config.WebSocket
+	// Edits carry the whole document, so the read limit follows max_code_bytes plus JSON overhead
+	c.conn.SetReadLimit(int64(hub.config.CodeCollab.MaxCodeBytes) + 1024)
+	c.conn.SetReadDeadline(time.Now().Add(time.Duration(wsCfg.PongWait) * time.Second))
+	c.conn.SetPongHandler(func(string) error {
+		c.conn.SetReadDeadline(time.Now().Add(time.Duration(wsCfg.PongWait) * time.Second))
+		return nil
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				hub.logger.Warn("Collaboration connection closed unexpectedly", "userID", c.userID, "error", err.Error())
+			}
+			return
+		}
+
+		var msg struct {
+			Type string          `json:"type"`
+			Data json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.reply("error", map[string]string{"message": "消息格式错误"})
+			continue
+		}
+
+		switch msg.Type {
+		case "edit":
+			var edit struct {
+				Code        string `json:"code"`
+				BaseVersion int64  `json:"base_version"`
+			}
+			if err := json.Unmarshal(msg.Data, &edit); err != nil {
+				c.reply("error", map[string]string{"message": "消息格式错误"})
+				continue
+			}
+			if len(edit.Code) > hub.config.CodeCollab.MaxCodeBytes {
+				c.reply("error", map[string]string{"message": "代码过长"})
+				continue
+			}
+			version, conflict := c.room.applyEdit(c, edit.Code, edit.BaseVersion)
+			c.reply("ack", map[string]interface{}{"version": version, "conflict": conflict})
+
+		case "cursor":
+			c.room.relay(c, "cursor", msg.Data)
+
+		case "heartbeat":
+			c.reply("heartbeat", map[string]int64{"timestamp": time.Now().Unix()})
+
+		default:
+			hub.logger.Debug("Unknown collaboration message type", "type", msg.Type, "userID", c.userID)
+		}
+	}
+}
+
+// writePump writes queued messages and pings until the room closes the send channel
+func (c *collabClient) writePump(hub *CollabHub) {
+	wsCfg := hub.config.WebSocket
+	ticker := time.NewTicker(time.Duration(wsCfg.PingPeriod) * time.Second)
+	defer func() {
+		ticker.Stop()
+		c.close()
+	}()
+
+	for {
+		select {
+		case message, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(time.Duration(wsCfg.WriteWait) * time.Second))
+			if !ok {
+				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				return
+			}
+
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(time.Duration(wsCfg.WriteWait) * time.Second))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// JoinCollaboration upgrades to a WebSocket joined to the collaboration session identified by :token.
+// Server messages: init, edit, ack, cursor, user_joined, user_left, session_expired, server_shutdown.
+// Joins to expired sessions are refused with 410.
+func (h *CodeHandler) JoinCollaboration(c *gin.Context) {
+	if globalCollabHub == nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "协作服务不可用")
+		return
+	}
+
+	userID, isOK := getUserIDOrFail(c)
+	if !isOK {
+		return
+	}
+
+	collab, err := h.repo.GetCollaborationByToken(c.Param("token"))
+	if err != nil {
+		utils.NotFoundResponse(c, "协作会话不存在")
+		return
+	}
+	if !time.Now().Before(collab.ExpiresAt) {
+		utils.ErrorResponse(c, http.StatusGone, "协作会话已过期")
+		return
+	}
+
+	userInfo, err := GetUserWithProfile(c.Request.Context(), globalCollabHub.userRepo, userID)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "获取用户信息失败")
+		return
+	}
+
+	upgrader := createUpgrader(h.config.CORS.AllowOrigins, &h.config.WebSocket, utils.GetLogger())
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		utils.GetLogger().Error("协作连接升级失败", "error", err.Error(), "userID", userID)
+		return
+	}
+
+	client := &collabClient{
+		conn:     conn,
+		send:     make(chan []byte, collabSendBufferSize),
+		userID:   userID,
+		username: userInfo.User.Username,
+	}
+	if err := globalCollabHub.join(collab, client); err != nil {
+		reason := "加入协作会话失败"
+		if err == errCollabRoomFull {
+			reason = "协作会话人数已满"
+		}
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, reason))
+		conn.Close()
+		return
+	}
+
+	go client.writePump(globalCollabHub)
+	client.readPump(globalCollabHub)
+}
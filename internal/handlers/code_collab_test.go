@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gin/internal/middleware"
+	"gin/internal/models"
+	"gin/internal/services"
+	"gin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// collabTestRepo 协作编辑用到的代码片段仓库方法，记录写回的代码
+type collabTestRepo struct {
+	services.CodeRepository
+	collabs map[string]*models.CodeCollaboration
+	saved   chan string // UpdateSnippetCode 写回的代码
+
+	mu          sync.Mutex
+	activeUsers []string // UpdateCollaborationUsers 写入的在线用户列表
+}
+
+func newCollabTestRepo() *collabTestRepo {
+	return &collabTestRepo{collabs: map[string]*models.CodeCollaboration{}, saved: make(chan string, 8)}
+}
+
+func (r *collabTestRepo) GetSnippetByID(id uint) (*models.CodeSnippet, error) {
+	return &models.CodeSnippet{ID: id, UserID: 1, Code: "v0"}, nil
+}
+
+func (r *collabTestRepo) GetCollaborationByToken(token string) (*models.CodeCollaboration, error) {
+	if collab, ok := r.collabs[token]; ok {
+		return collab, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func (r *collabTestRepo) UpdateCollaborationUsers(_ string, activeUsers string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.activeUsers = append(r.activeUsers, activeUsers)
+	return nil
+}
+
+func (r *collabTestRepo) UpdateSnippetCode(_ context.Context, _ uint, code string) error {
+	r.saved <- code
+	return nil
+}
+
+// expectSaved 等待下一次写回代码片段
+func (r *collabTestRepo) expectSaved(t *testing.T, want string) {
+	t.Helper()
+	select {
+	case code := <-r.saved:
+		if code != want {
+			t.Fatalf("写回的代码应为 %q，实际 %q", want, code)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("应将代码 %q 写回代码片段", want)
+	}
+}
+
+// newTestCollabHub 创建使用 repo 的协作中心，测试结束时关闭
+func newTestCollabHub(t *testing.T, repo services.CodeRepository) *CollabHub {
+	t.Helper()
+	cfg := newTestConfig()
+	cfg.CodeCollab.SnapshotIntervalSec = 3600
+	hub := &CollabHub{
+		rooms:    make(map[string]*collabRoom),
+		codeRepo: repo,
+		config:   cfg,
+		logger:   utils.GetLogger(),
+		stopCh:   make(chan struct{}),
+	}
+	t.Cleanup(func() { hub.Shutdown(context.Background()) })
+	return hub
+}
+
+// dialCollabClient 建立一个加入协作会话的真实 WebSocket 连接（跳过鉴权和用户信息查询）
+func dialCollabClient(t *testing.T, hub *CollabHub, collab *models.CodeCollaboration, userID uint) *wsTestConn {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		client := &collabClient{conn: conn, send: make(chan []byte, collabSendBufferSize), userID: userID, username: "user"}
+		if err := hub.join(collab, client); err != nil {
+			conn.Close()
+			return
+		}
+		go client.writePump(hub)
+		client.readPump(hub)
+	}))
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("建立WebSocket连接失败: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &wsTestConn{Conn: conn}
+}
+
+func TestCollabJoinEditLeave(t *testing.T) {
+	repo := newCollabTestRepo()
+	hub := newTestCollabHub(t, repo)
+	collab := &models.CodeCollaboration{SnippetID: 5, SessionToken: "tok", ExpiresAt: time.Now().Add(time.Hour)}
+
+	alice := dialCollabClient(t, hub, collab, 1)
+	if init := alice.next(t, "init"); init["code"] != "v0" || init["version"] != float64(0) {
+		t.Fatalf("加入时应收到代码片段当前的代码，实际 %v", init)
+	}
+	bob := dialCollabClient(t, hub, collab, 2)
+	bob.next(t, "init")
+	if joined := alice.next(t, "user_joined"); joined["user_id"] != float64(2) || len(joined["users"].([]interface{})) != 2 {
+		t.Fatalf("其他参与者应收到加入通知和最新的参与者列表，实际 %v", joined)
+	}
+
+	_ = bob.WriteJSON(WSMessage{Type: "edit", Data: map[string]interface{}{"code": "v1", "base_version": 0}})
+	if ack := bob.next(t, "ack"); ack["version"] != float64(1) || ack["conflict"] != false {
+		t.Fatalf("基于最新版本的编辑不应冲突，实际 %v", ack)
+	}
+	if edit := alice.next(t, "edit"); edit["code"] != "v1" || edit["user_id"] != float64(2) {
+		t.Fatalf("编辑应转发给其他参与者，实际 %v", edit)
+	}
+	_ = alice.WriteJSON(WSMessage{Type: "edit", Data: map[string]interface{}{"code": "v2", "base_version": 0}})
+	if ack := alice.next(t, "ack"); ack["version"] != float64(2) || ack["conflict"] != true {
+		t.Fatalf("基于旧版本的编辑应标记冲突（后写者生效），实际 %v", ack)
+	}
+
+	bob.Close()
+	if left := alice.next(t, "user_left"); left["user_id"] != float64(2) || len(left["users"].([]interface{})) != 1 {
+		t.Fatalf("参与者离开时应通知其他参与者，实际 %v", left)
+	}
+	select {
+	case code := <-repo.saved:
+		t.Fatalf("还有参与者时不应写回代码，实际写回 %q", code)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// 最后一个参与者离开后关闭房间并写回代码
+	alice.Close()
+	repo.expectSaved(t, "v2")
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		hub.mu.Lock()
+		_, exists := hub.rooms["tok"]
+		hub.mu.Unlock()
+		if !exists {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("最后一个参与者离开后应移除房间")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 再次加入时重新创建房间
+	carol := dialCollabClient(t, hub, collab, 3)
+	if init := carol.next(t, "init"); init["version"] != float64(0) {
+		t.Fatalf("房间关闭后再加入应重新创建房间，实际 %v", init)
+	}
+}
+
+func TestCollabSessionExpiry(t *testing.T) {
+	repo := newCollabTestRepo()
+	hub := newTestCollabHub(t, repo)
+	collab := &models.CodeCollaboration{SnippetID: 5, SessionToken: "tok", ExpiresAt: time.Now().Add(300 * time.Millisecond)}
+
+	conn := dialCollabClient(t, hub, collab, 1)
+	conn.next(t, "init")
+	_ = conn.WriteJSON(WSMessage{Type: "edit", Data: map[string]interface{}{"code": "v1", "base_version": 0}})
+	conn.next(t, "ack")
+
+	conn.next(t, "session_expired")
+	expectClosed(t, conn)
+	repo.expectSaved(t, "v1")
+
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	if len(hub.rooms) != 0 {
+		t.Fatal("会话过期后应移除房间")
+	}
+}
+
+func TestCollabPeriodicSnapshot(t *testing.T) {
+	repo := newCollabTestRepo()
+	hub := newTestCollabHub(t, repo)
+	hub.config.CodeCollab.SnapshotIntervalSec = 1
+	go hub.run()
+	collab := &models.CodeCollaboration{SnippetID: 5, SessionToken: "tok", ExpiresAt: time.Now().Add(time.Hour)}
+
+	conn := dialCollabClient(t, hub, collab, 1)
+	conn.next(t, "init")
+	_ = conn.WriteJSON(WSMessage{Type: "edit", Data: map[string]interface{}{"code": "v1", "base_version": 0}})
+	conn.next(t, "ack")
+
+	// 参与者在线时按间隔写回有改动的代码，没有新改动时不重复写回
+	repo.expectSaved(t, "v1")
+	select {
+	case code := <-repo.saved:
+		t.Fatalf("没有新的编辑时不应重复写回，实际写回 %q", code)
+	case <-time.After(1500 * time.Millisecond):
+	}
+}
+
+func TestCollabEditAfterClose(t *testing.T) {
+	repo := newCollabTestRepo()
+	hub := newTestCollabHub(t, repo)
+	collab := &models.CodeCollaboration{SnippetID: 5, SessionToken: "tok", ExpiresAt: time.Now().Add(time.Hour)}
+	client := &collabClient{send: make(chan []byte, collabSendBufferSize), userID: 1}
+	if err := hub.join(collab, client); err != nil {
+		t.Fatalf("加入协作会话失败: %v", err)
+	}
+	room := client.room
+
+	room.close("server_shutdown")
+	room.snapshot(context.Background())
+	if version, _ := room.applyEdit(client, "late", 0); version != 0 {
+		t.Fatalf("房间关闭后的编辑应被丢弃，实际版本 %d", version)
+	}
+	room.snapshot(context.Background())
+	select {
+	case code := <-repo.saved:
+		t.Fatalf("房间关闭后的编辑不应写回代码片段，实际写回 %q", code)
+	default:
+	}
+	if room.code != "v0" {
+		t.Fatalf("房间关闭后的编辑不应修改代码，实际 %q", room.code)
+	}
+}
+
+func TestJoinCollaborationChecks(t *testing.T) {
+	repo := newCollabTestRepo()
+	repo.collabs["expired"] = &models.CodeCollaboration{SnippetID: 5, SessionToken: "expired", ExpiresAt: time.Now().Add(-time.Minute)}
+	hub := newTestCollabHub(t, repo)
+	previous := globalCollabHub
+	globalCollabHub = hub
+	t.Cleanup(func() { globalCollabHub = previous })
+
+	cfg := newTestConfig()
+	router := gin.New()
+	// 与路由注册一致：code 路由组按方法检查读权限，协作连接可以修改代码，额外要求写权限
+	fakeAuth := func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		if scopes := c.GetHeader("X-Test-Scopes"); scopes != "" {
+			c.Set("authType", middleware.AuthTypeAPIToken)
+			c.Set("apiTokenScopes", strings.Split(scopes, ","))
+		}
+	}
+	code := router.Group("/api", fakeAuth, middleware.RequireRouteGroupScope(cfg, "code"))
+	code.GET("/code/collab/:token/ws", middleware.RequireScope(middleware.RouteGroupScope(cfg, "code", "write")),
+		NewCodeHandler(repo, nil, cfg).JoinCollaboration)
+
+	join := func(token, scopes string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/code/collab/"+token+"/ws", nil)
+		if scopes != "" {
+			req.Header.Set("X-Test-Scopes", scopes)
+		}
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if status := join("expired", "read:code"); status != http.StatusForbidden {
+		t.Fatalf("只读API令牌不能加入协作编辑，实际 %d", status)
+	}
+	if status := join("expired", "write:code"); status != http.StatusGone {
+		t.Fatalf("过期的协作会话应返回410，实际 %d", status)
+	}
+	if status := join("missing", ""); status != http.StatusNotFound {
+		t.Fatalf("不存在的协作会话应返回404，实际 %d", status)
+	}
+}
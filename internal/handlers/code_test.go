@@ -0,0 +1,335 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/middleware"
+	"gin/internal/models"
+	"gin/internal/services"
+	"gin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// stubCodeRepository 内存中的代码片段（只实现测试用到的方法）
+type stubCodeRepository struct {
+	services.CodeRepository
+	snippets map[uint]*models.CodeSnippet
+	shares   map[string]uint
+	// publicQuery 最近一次公开列表查询的参数
+	publicQuery *models.PublicSnippetQuery
+	// executed 异步计入执行次数的代码片段ID
+	executed chan uint
+	// shareExpiresAt、shareMaxViews 最近一次生成分享令牌的参数
+	shareExpiresAt *time.Time
+	shareMaxViews  *int64
+}
+
+func (r *stubCodeRepository) GenerateShareToken(snippetID, userID uint, expiresAt *time.Time, maxViews *int64) (string, error) {
+	if s, ok := r.snippets[snippetID]; !ok || s.UserID != userID {
+		return "", errors.New("代码片段不存在或无权限")
+	}
+	r.shareExpiresAt, r.shareMaxViews = expiresAt, maxViews
+	return "new-token", nil
+}
+
+func (r *stubCodeRepository) RevokeShareToken(snippetID, userID uint) error {
+	if s, ok := r.snippets[snippetID]; !ok || s.UserID != userID {
+		return errors.New("代码片段不存在或无权限")
+	}
+	for token, id := range r.shares {
+		if id == snippetID {
+			delete(r.shares, token)
+		}
+	}
+	return nil
+}
+
+func (r *stubCodeRepository) CreateExecution(*models.CodeExecution) error { return nil }
+
+func (r *stubCodeRepository) IncrementExecutionCount(_ context.Context, snippetID uint) error {
+	r.executed <- snippetID
+	return nil
+}
+
+func (r *stubCodeRepository) ForkSnippet(_ context.Context, snippetID, userID uint) (*models.CodeSnippet, error) {
+	source, ok := r.snippets[snippetID]
+	if !ok || (!source.IsPublic && source.UserID != userID) {
+		return nil, utils.ErrResourceNotFound
+	}
+	return &models.CodeSnippet{ID: 100, UserID: userID, Title: source.Title, Language: source.Language,
+		Code: source.Code, ForkedFrom: &source.ID}, nil
+}
+
+// stubCodeExecutor 直接返回成功结果的代码执行器
+type stubCodeExecutor struct{ services.CodeExecutor }
+
+func (stubCodeExecutor) Execute(context.Context, string, string, string) (*models.ExecuteCodeResponse, error) {
+	return &models.ExecuteCodeResponse{Output: "ok", Status: "success"}, nil
+}
+
+func (r *stubCodeRepository) GetPublicSnippets(query models.PublicSnippetQuery) ([]models.CodeSnippetWithUser, int, error) {
+	r.publicQuery = &query
+	return []models.CodeSnippetWithUser{}, 0, nil
+}
+
+func (r *stubCodeRepository) GetSnippetByID(id uint) (*models.CodeSnippet, error) {
+	if s, ok := r.snippets[id]; ok {
+		return s, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func (r *stubCodeRepository) GetSnippetByShareToken(token string) (*models.CodeSnippet, error) {
+	if id, ok := r.shares[token]; ok {
+		return r.GetSnippetByID(id)
+	}
+	return nil, errors.New("not found")
+}
+
+func newSnippetDownloadRouter() *gin.Engine {
+	cfg := newTestConfig()
+	repo := &stubCodeRepository{
+		snippets: map[uint]*models.CodeSnippet{
+			1: {ID: 1, UserID: 1, Title: "quick sort", Language: "python", Code: "print(1)\n", IsPublic: false},
+			2: {ID: 2, UserID: 1, Title: "hello.go", Language: "go", Code: "package main\n", IsPublic: true},
+		},
+		shares: map[string]uint{"share-1": 1},
+	}
+	h := NewCodeHandler(repo, nil, cfg)
+
+	router := gin.New()
+	api := router.Group("/api")
+	api.GET("/code/snippets/:id/download", middleware.AuthMiddleware(cfg, nil, nil), h.DownloadSnippet)
+	api.GET("/code/share/:token/download", h.DownloadSharedSnippet)
+	return router
+}
+
+func TestDownloadSnippetAccess(t *testing.T) {
+	router := newSnippetDownloadRouter()
+	owner := signTestJWT(t, newTestConfig(), 1, "alice")
+	other := signTestJWT(t, newTestConfig(), 2, "bob")
+
+	download := func(path, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := download("/api/code/snippets/1/download", owner)
+	if w.Code != http.StatusOK || w.Body.String() != "print(1)\n" {
+		t.Fatalf("创建者应能下载私有代码片段，实际 %d %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Disposition"); got != `attachment; filename="quick sort.py"` {
+		t.Fatalf("下载文件名应取自标题并带语言扩展名，实际 %q", got)
+	}
+	if got := w.Header().Get("Content-Type"); got != "text/x-python; charset=utf-8" {
+		t.Fatalf("Content-Type 应与语言对应，实际 %q", got)
+	}
+	if w.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Fatal("下载响应应禁止内容嗅探")
+	}
+
+	if w := download("/api/code/snippets/1/download", other); w.Code != http.StatusForbidden {
+		t.Fatalf("其他用户不能下载私有代码片段，实际 %d", w.Code)
+	}
+	if w := download("/api/code/snippets/1/download", ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("未登录不能下载，实际 %d", w.Code)
+	}
+	if w := download("/api/code/snippets/2/download", other); w.Code != http.StatusOK ||
+		w.Header().Get("Content-Disposition") != `attachment; filename="hello.go"` {
+		t.Fatalf("公开代码片段可以被其他用户下载且不重复追加扩展名，实际 %d %q", w.Code, w.Header().Get("Content-Disposition"))
+	}
+	if w := download("/api/code/snippets/99/download", owner); w.Code != http.StatusNotFound {
+		t.Fatalf("不存在的代码片段应返回404，实际 %d", w.Code)
+	}
+
+	// 分享令牌可以在未登录时下载私有代码片段
+	if w := download("/api/code/share/share-1/download", ""); w.Code != http.StatusOK || w.Body.String() != "print(1)\n" {
+		t.Fatalf("有效的分享令牌应能下载，实际 %d", w.Code)
+	}
+	if w := download("/api/code/share/bad-token/download", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("无效的分享令牌应返回404，实际 %d", w.Code)
+	}
+}
+
+func TestGetPublicSnippetsParams(t *testing.T) {
+	cfg := newTestConfig()
+	repo := &stubCodeRepository{}
+	router := gin.New()
+	router.GET("/api/code/public", NewCodeHandler(repo, nil, cfg).GetPublicSnippets)
+
+	resp := doRequest(t, router, http.MethodGet, "/api/code/public?keyword=%20sort%20&language=go&search_code=true&sort_by=most_executed&page=3&page_size=10", "", nil)
+	if resp.Status != http.StatusOK {
+		t.Fatalf("查询公开代码片段失败: %d %s", resp.Status, resp.Body)
+	}
+	want := models.PublicSnippetQuery{Language: "go", Keyword: "sort", SearchCode: true, SortBy: models.SnippetSortMostExecuted, Limit: 10, Offset: 20}
+	if *repo.publicQuery != want {
+		t.Fatalf("查询参数不正确: %+v", *repo.publicQuery)
+	}
+
+	// 超出分页上限时使用默认每页数量
+	doRequest(t, router, http.MethodGet, fmt.Sprintf("/api/code/public?page_size=%d", cfg.Pagination.MaxPageSize+1), "", nil)
+	if repo.publicQuery.Limit != cfg.Pagination.DefaultPageSize || repo.publicQuery.SortBy != models.SnippetSortRecent {
+		t.Fatalf("超出上限的 page_size 应使用默认值，默认按发布时间排序，实际 %+v", *repo.publicQuery)
+	}
+
+	repo.publicQuery = nil
+	for _, query := range []string{"sort_by=hot", "keyword=" + strings.Repeat("a", maxSnippetKeywordLength+1)} {
+		if resp := doRequest(t, router, http.MethodGet, "/api/code/public?"+query, "", nil); resp.Status != http.StatusBadRequest {
+			t.Fatalf("%s 应返回400，实际 %d", query, resp.Status)
+		}
+	}
+	if repo.publicQuery != nil {
+		t.Fatal("参数不合法时不应查询")
+	}
+}
+
+func TestExecuteSnippetCountsPublicOnly(t *testing.T) {
+	cfg := newTestConfig()
+	repo := &stubCodeRepository{
+		snippets: map[uint]*models.CodeSnippet{
+			1: {ID: 1, UserID: 1, Language: "go", IsPublic: false},
+			2: {ID: 2, UserID: 1, Language: "go", IsPublic: true},
+		},
+		executed: make(chan uint, 4),
+	}
+	router := gin.New()
+	router.POST("/api/code/execute", middleware.AuthMiddleware(cfg, nil, nil), NewCodeHandler(repo, stubCodeExecutor{}, cfg).ExecuteCode)
+	owner := signTestJWT(t, cfg, 1, "alice")
+	other := signTestJWT(t, cfg, 2, "bob")
+
+	run := func(token string, snippetID uint) int {
+		body := map[string]any{"language": "go", "code": "package main", "snippet_id": snippetID}
+		return doRequest(t, router, http.MethodPost, "/api/code/execute", token, body).Status
+	}
+
+	if status := run(other, 2); status != http.StatusOK {
+		t.Fatalf("运行公开代码片段失败: %d", status)
+	}
+	select {
+	case id := <-repo.executed:
+		if id != 2 {
+			t.Fatalf("应计入被运行的代码片段，实际 %d", id)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("运行公开代码片段后应异步增加执行次数")
+	}
+
+	if status := run(owner, 1); status != http.StatusOK {
+		t.Fatalf("创建者运行私有代码片段失败: %d", status)
+	}
+	if status := run(other, 1); status != http.StatusForbidden {
+		t.Fatalf("不能关联他人的私有代码片段，实际 %d", status)
+	}
+	if status := run(owner, 99); status != http.StatusNotFound {
+		t.Fatalf("关联不存在的代码片段应返回404，实际 %d", status)
+	}
+	select {
+	case id := <-repo.executed:
+		t.Fatalf("私有代码片段的执行不应计数，实际计入 %d", id)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestForkSnippetHandler(t *testing.T) {
+	cfg := newTestConfig()
+	repo := &stubCodeRepository{snippets: map[uint]*models.CodeSnippet{
+		1: {ID: 1, UserID: 1, Title: "私有", IsPublic: false},
+		2: {ID: 2, UserID: 1, Title: "公开", Language: "go", Code: "package main", IsPublic: true},
+	}}
+	router := gin.New()
+	router.POST("/api/code/snippets/:id/fork", middleware.AuthMiddleware(cfg, nil, nil), NewCodeHandler(repo, nil, cfg).ForkSnippet)
+	other := signTestJWT(t, cfg, 2, "bob")
+
+	resp := doRequest(t, router, http.MethodPost, "/api/code/snippets/2/fork", other, nil)
+	if resp.Status != http.StatusCreated {
+		t.Fatalf("复制公开代码片段应返回201，实际 %d %s", resp.Status, resp.Body)
+	}
+	var fork models.CodeSnippet
+	decodeData(t, resp, &fork)
+	if fork.UserID != 2 || fork.IsPublic || fork.ForkedFrom == nil || *fork.ForkedFrom != 2 {
+		t.Fatalf("副本应归属当前用户且记录来源: %+v", fork)
+	}
+
+	for path, want := range map[string]int{
+		"/api/code/snippets/1/fork":   http.StatusNotFound,
+		"/api/code/snippets/99/fork":  http.StatusNotFound,
+		"/api/code/snippets/abc/fork": http.StatusBadRequest,
+	} {
+		if resp := doRequest(t, router, http.MethodPost, path, other, nil); resp.Status != want {
+			t.Fatalf("%s 应返回 %d，实际 %d", path, want, resp.Status)
+		}
+	}
+	if resp := doRequest(t, router, http.MethodPost, "/api/code/snippets/2/fork", "", nil); resp.Status != http.StatusUnauthorized {
+		t.Fatalf("未登录不能复制，实际 %d", resp.Status)
+	}
+}
+
+func TestShareLinkExpiryAndRevoke(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.CodeShare = config.CodeShareConfig{DefaultExpiryHours: 24, MaxExpiryHours: 72}
+	repo := &stubCodeRepository{
+		snippets: map[uint]*models.CodeSnippet{1: {ID: 1, UserID: 1, Code: "print(1)\n"}},
+		shares:   map[string]uint{"share-1": 1},
+	}
+	h := NewCodeHandler(repo, nil, cfg)
+	router := gin.New()
+	auth := router.Group("/api", middleware.AuthMiddleware(cfg, nil, nil))
+	auth.POST("/code/snippets/:id/share", h.GenerateShareLink)
+	auth.DELETE("/code/snippets/:id/share", h.RevokeShareLink)
+	router.GET("/api/code/share/:token/download", h.DownloadSharedSnippet)
+	owner := signTestJWT(t, cfg, 1, "alice")
+	other := signTestJWT(t, cfg, 2, "bob")
+
+	// 不传请求体时使用默认有效期，不限访问次数
+	before := time.Now().UTC()
+	resp := doRequest(t, router, http.MethodPost, "/api/code/snippets/1/share", owner, nil)
+	if resp.Status != http.StatusOK {
+		t.Fatalf("生成分享链接失败: %d %s", resp.Status, resp.Body)
+	}
+	var share models.ShareSnippetResponse
+	decodeData(t, resp, &share)
+	if share.ShareToken != "new-token" || repo.shareMaxViews != nil || repo.shareExpiresAt == nil ||
+		repo.shareExpiresAt.Sub(before) < 24*time.Hour || repo.shareExpiresAt.Sub(before) > 24*time.Hour+time.Minute {
+		t.Fatalf("默认有效期应为 default_expiry_hours，实际 %+v %v", share, repo.shareExpiresAt)
+	}
+
+	resp = doRequest(t, router, http.MethodPost, "/api/code/snippets/1/share", owner, map[string]any{"expires_in_hours": 2, "max_views": 5})
+	if resp.Status != http.StatusOK || repo.shareMaxViews == nil || *repo.shareMaxViews != 5 ||
+		repo.shareExpiresAt.Sub(before) > 2*time.Hour+time.Minute {
+		t.Fatalf("应使用请求中的有效期和次数上限，实际 %d %v %v", resp.Status, repo.shareExpiresAt, repo.shareMaxViews)
+	}
+
+	for _, body := range []map[string]any{{"expires_in_hours": 73}, {"expires_in_hours": 0}} {
+		if resp := doRequest(t, router, http.MethodPost, "/api/code/snippets/1/share", owner, body); resp.Status != http.StatusBadRequest {
+			t.Fatalf("%v 应返回400（设置了最长有效期时不允许永不过期），实际 %d", body, resp.Status)
+		}
+	}
+	if resp := doRequest(t, router, http.MethodPost, "/api/code/snippets/1/share", owner, map[string]any{"max_views": 0}); resp.Status != http.StatusUnprocessableEntity {
+		t.Fatalf("访问次数上限至少为1，实际 %d", resp.Status)
+	}
+
+	// 吊销后旧链接失效，只有创建者可以吊销
+	if resp := doRequest(t, router, http.MethodDelete, "/api/code/snippets/1/share", other, nil); resp.Status != http.StatusNotFound {
+		t.Fatalf("非创建者吊销应返回404，实际 %d", resp.Status)
+	}
+	if resp := doRequest(t, router, http.MethodDelete, "/api/code/snippets/1/share", owner, nil); resp.Status != http.StatusOK {
+		t.Fatalf("吊销分享链接失败: %d", resp.Status)
+	}
+	if resp := doRequest(t, router, http.MethodGet, "/api/code/share/share-1/download", "", nil); resp.Status != http.StatusNotFound {
+		t.Fatalf("吊销后的分享链接应失效，实际 %d", resp.Status)
+	}
+}
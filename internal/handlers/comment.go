@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"strconv"
+
+	"gin/internal/config"
+	"gin/internal/middleware"
+	"gin/internal/models"
+	"gin/internal/services"
+	"gin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// commentTargetScopeGroups 评论实体类型对应的令牌权限路由组
+var commentTargetScopeGroups = map[string]string{
+	models.CommentTargetArticle:  "articles",
+	models.CommentTargetResource: "resources",
+}
+
+// CommentHandler 统一评论处理器（文章与资源评论使用同一响应结构）
+type CommentHandler struct {
+	articleRepo         *services.ArticleRepository
+	resourceCommentRepo *services.ResourceCommentRepository
+	config              *config.Config
+	logger              utils.Logger
+}
+
+// NewCommentHandler 创建统一评论处理器
+func NewCommentHandler(articleRepo *services.ArticleRepository, resourceCommentRepo *services.ResourceCommentRepository, cfg *config.Config) *CommentHandler {
+	return &CommentHandler{
+		articleRepo:         articleRepo,
+		resourceCommentRepo: resourceCommentRepo,
+		config:              cfg,
+		logger:              utils.GetLogger(),
+	}
+}
+
+// GetComments 获取评论树（GET /api/comments?type=article|resource&id=，文章评论支持 sort=newest|oldest|top）
+func (h *CommentHandler) GetComments(c *gin.Context) {
+	targetType := c.Query("type")
+	if !models.ValidCommentTargets[targetType] {
+		utils.BadRequestResponse(c, "无效的评论类型，仅支持 article 或 resource")
+		return
+	}
+
+	targetID, err := strconv.ParseUint(c.Query("id"), 10, 32)
+	if err != nil || targetID == 0 {
+		utils.BadRequestResponse(c, "无效的ID")
+		return
+	}
+
+	// 权限范围取决于实体类型，无法在路由组上静态声明
+	readScope := middleware.RouteGroupScope(h.config, commentTargetScopeGroups[targetType], models.APITokenScopeRead)
+	if !middleware.CheckTokenScope(c, readScope) {
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(h.config.Pagination.DefaultPageSize)))
+
+	// flat=true 时每条一级评论的回复按时间平铺（通过 parent_id 引用父评论），默认返回树
+	flat := c.Query("flat") == "true"
+
+	// 获取当前用户ID（可能未登录）
+	userID, _ := utils.GetUserIDFromContext(c)
+
+	ctx := c.Request.Context()
+	var response *models.UnifiedCommentsResponse
+	switch targetType {
+	case models.CommentTargetArticle:
+		resp, err := h.articleRepo.GetComments(ctx, uint(targetID), page, pageSize, userID, c.Query("sort"))
+		if err != nil {
+			h.logger.Error("获取文章评论失败", "articleID", targetID, "error", err.Error())
+			utils.ErrorResponse(c, utils.GetHTTPStatusCode(err), "获取评论失败")
+			return
+		}
+		if flat {
+			resp.Flatten()
+		}
+		response = models.NewUnifiedArticleComments(uint(targetID), resp)
+	case models.CommentTargetResource:
+		resp, err := h.resourceCommentRepo.GetCommentsByResourceID(ctx, uint(targetID), userID, page, pageSize)
+		if err != nil {
+			h.logger.Error("获取资源评论失败", "resourceID", targetID, "error", err.Error())
+			utils.ErrorResponse(c, utils.GetHTTPStatusCode(err), "获取评论失败")
+			return
+		}
+		if flat {
+			resp.Flatten()
+		}
+		response = models.NewUnifiedResourceComments(uint(targetID), resp)
+	}
+
+	utils.SuccessResponse(c, 200, "获取成功", response)
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"gin/internal/models"
+	"gin/internal/services"
+	"gin/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newCommentRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	cfg := newTestConfig()
+	fake, db := newFakeDatabase(t, cfg)
+	fake.OnRows(`SELECT COUNT\(\*\) FROM article_comments`, []string{"count"}, []driver.Value{int64(0)})
+	fake.OnRows(`FROM article_comments ac INNER JOIN user_auth`, []string{"id"})
+	fake.OnRows(`SELECT COUNT\(\*\) FROM resource_comments`, []string{"count"}, []driver.Value{int64(0)})
+	fake.OnRows(`FROM resource_comments WHERE resource_id = \? AND parent_id = 0`, []string{"id"})
+
+	h := NewCommentHandler(services.NewArticleRepository(db, cfg), services.NewResourceCommentRepository(db, cfg), cfg)
+	router := gin.New()
+	router.GET("/api/comments", h.GetComments)
+	return router
+}
+
+func TestUnifiedCommentsEndpoint(t *testing.T) {
+	router := newCommentRouter(t)
+
+	shape := func(path string) ([]string, map[string]interface{}) {
+		t.Helper()
+		resp := doRequest(t, router, http.MethodGet, path, "", nil)
+		if resp.Status != http.StatusOK {
+			t.Fatalf("%s 应返回200，实际 %d %s", path, resp.Status, resp.Body)
+		}
+		var data map[string]interface{}
+		decodeData(t, resp, &data)
+		keys := make([]string, 0, len(data))
+		for k := range data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		return keys, data
+	}
+
+	articleKeys, article := shape("/api/comments?type=article&id=5")
+	resourceKeys, resource := shape("/api/comments?type=resource&id=6")
+	if !reflect.DeepEqual(articleKeys, resourceKeys) {
+		t.Fatalf("两种实体的响应字段应一致\n文章: %v\n资源: %v", articleKeys, resourceKeys)
+	}
+	if article["target_type"] != "article" || resource["target_type"] != "resource" || resource["target_id"] != float64(6) {
+		t.Fatalf("target 字段错误: %v / %v", article, resource)
+	}
+	if comments, ok := resource["comments"].([]interface{}); !ok || len(comments) != 0 {
+		t.Fatalf("没有评论时应返回空数组，实际 %v", resource["comments"])
+	}
+}
+
+func TestUnifiedCommentsRejectsInvalidParams(t *testing.T) {
+	router := newCommentRouter(t)
+	for _, path := range []string{
+		"/api/comments?type=video&id=1",
+		"/api/comments?id=1",
+		"/api/comments?type=article",
+		"/api/comments?type=resource&id=abc",
+		"/api/comments?type=article&id=0",
+	} {
+		if resp := doRequest(t, router, http.MethodGet, path, "", nil); resp.Status != http.StatusBadRequest {
+			t.Errorf("%s 应返回400，实际 %d", path, resp.Status)
+		}
+	}
+}
+
+func TestGetCommentRepliesEndpoint(t *testing.T) {
+	cfg := newTestConfig()
+	fake, db := newFakeDatabase(t, cfg)
+	fake.On(`SELECT EXISTS\(SELECT 1 FROM article_comments`, func(args []driver.Value) testutil.Response {
+		return testutil.Response{Columns: []string{"exists"}, Rows: [][]driver.Value{{args[0] == int64(10)}}}
+	})
+	now := time.Now().UTC()
+	reply := func(id int64) []driver.Value {
+		return []driver.Value{id, int64(5), int64(2), int64(10), int64(10), nil, "reply",
+			int64(0), int64(0), int64(1), false, now, now, "bob", "Bob", ""}
+	}
+	fake.OnRows(`WHERE ac.parent_id = \? AND ac.id > \?`,
+		[]string{"id", "article_id", "user_id", "parent_id", "root_id", "reply_to_user_id", "content",
+			"like_count", "reply_count", "status", "is_edited", "created_at", "updated_at", "username", "nickname", "avatar"},
+		reply(13), reply(14))
+
+	router := gin.New()
+	router.GET("/api/comments/:id/replies", NewArticleHandler(services.NewArticleRepository(db, cfg), nil, nil, nil, nil, cfg).GetCommentReplies)
+
+	resp := doRequest(t, router, http.MethodGet, "/api/comments/10/replies?after=12&limit=1", "", nil)
+	if resp.Status != http.StatusOK {
+		t.Fatalf("获取回复应成功，实际 %d %s", resp.Status, resp.Body)
+	}
+	var page models.CommentRepliesResponse
+	decodeData(t, resp, &page)
+	if page.ParentID != 10 || len(page.Replies) != 1 || page.Replies[0].ID != 13 || !page.HasMore || page.NextCursor != 13 {
+		t.Fatalf("回复分页结果错误: %+v", page)
+	}
+	if args := fake.Calls(`WHERE ac.parent_id = \?`)[0].Args; args[1] != int64(12) || args[3] != int64(2) {
+		t.Fatalf("游标和 LIMIT 参数错误: %v", args)
+	}
+
+	if resp := doRequest(t, router, http.MethodGet, "/api/comments/10/replies?after=abc", "", nil); resp.Status != http.StatusBadRequest {
+		t.Fatalf("无效游标应返回400，实际 %d", resp.Status)
+	}
+	if resp := doRequest(t, router, http.MethodGet, "/api/comments/99/replies", "", nil); resp.Status != http.StatusNotFound {
+		t.Fatalf("评论不存在时应返回404，实际 %d", resp.Status)
+	}
+}
+
+func TestUnifiedCommentsSortParam(t *testing.T) {
+	cfg := newTestConfig()
+	fake, db := newFakeDatabase(t, cfg)
+	fake.OnRows(`FROM article_comments ac INNER JOIN user_auth`, []string{"id"})
+	fake.OnRows(`SELECT COUNT\(\*\) FROM article_comments`, []string{"count"}, []driver.Value{int64(0)})
+	router := gin.New()
+	router.GET("/api/comments", NewCommentHandler(services.NewArticleRepository(db, cfg), nil, cfg).GetComments)
+
+	for param, want := range map[string]string{"top": models.CommentSortTop, "oldest": models.CommentSortOldest, "hot": models.CommentSortNewest} {
+		resp := doRequest(t, router, http.MethodGet, "/api/comments?type=article&id=5&sort="+param, "", nil)
+		var data models.UnifiedCommentsResponse
+		decodeData(t, resp, &data)
+		if resp.Status != http.StatusOK || data.Sort != want {
+			t.Errorf("sort=%s 应按 %s 排序，实际 %d %q", param, want, resp.Status, data.Sort)
+		}
+	}
+}
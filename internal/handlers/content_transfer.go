@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"gin/internal/models"
+	"gin/internal/services"
+	"gin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// contentTransferListLimit 转移列表最多返回的条数
+const contentTransferListLimit = 100
+
+// ContentTransferHandler 文章/资源所有权转移处理器
+type ContentTransferHandler struct {
+	transferRepo *services.ContentTransferRepository
+	cacheSvc     *services.CacheService
+	logger       utils.Logger
+}
+
+// NewContentTransferHandler 创建所有权转移处理器
+func NewContentTransferHandler(transferRepo *services.ContentTransferRepository, cacheSvc *services.CacheService) *ContentTransferHandler {
+	return &ContentTransferHandler{
+		transferRepo: transferRepo,
+		cacheSvc:     cacheSvc,
+		logger:       utils.GetLogger(),
+	}
+}
+
+// CreateTransfer 发起所有权转移（只有作者本人可以发起，需接收者确认后生效）
+func (h *ContentTransferHandler) CreateTransfer(c *gin.Context) {
+	userID, isOK := getUserIDOrFail(c)
+	if !isOK {
+		return
+	}
+
+	var req models.CreateContentTransferRequest
+	if !bindJSONOrFail(c, &req, h.logger, "CreateTransfer") {
+		return
+	}
+
+	transfer, err := h.transferRepo.TransferContentOwnership(c.Request.Context(), req.ContentType, req.ContentID, userID, req.ToUserID)
+	if err != nil {
+		utils.AppErrorResponse(c, err, "发起转移失败")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "已发起转移，等待对方确认", transfer)
+}
+
+// ListTransfers 获取转移列表 ?direction=incoming|outgoing&pending=true
+func (h *ContentTransferHandler) ListTransfers(c *gin.Context) {
+	userID, isOK := getUserIDOrFail(c)
+	if !isOK {
+		return
+	}
+
+	direction := c.DefaultQuery("direction", "incoming")
+	if direction != "incoming" && direction != "outgoing" {
+		utils.BadRequestResponse(c, "direction 只能是 incoming 或 outgoing")
+		return
+	}
+	pendingOnly := c.Query("pending") == "true"
+
+	transfers, err := h.transferRepo.ListTransfers(c.Request.Context(), userID, direction == "incoming", pendingOnly, contentTransferListLimit)
+	if err != nil {
+		utils.AppErrorResponse(c, err, "获取转移列表失败")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "获取成功", gin.H{
+		"transfers": transfers,
+	})
+}
+
+// AcceptTransfer 接收者接受转移
+func (h *ContentTransferHandler) AcceptTransfer(c *gin.Context) {
+	userID, isOK := getUserIDOrFail(c)
+	if !isOK {
+		return
+	}
+
+	transferID, isOK := parseUintParam(c, "id", "无效的转移ID")
+	if !isOK {
+		return
+	}
+
+	transfer, err := h.transferRepo.AcceptTransfer(c.Request.Context(), transferID, userID)
+	if err != nil {
+		utils.AppErrorResponse(c, err, "接受转移失败")
+		return
+	}
+
+	// 文章详情缓存包含作者信息
+	if transfer.ContentType == models.TransferContentArticle {
+		h.cacheSvc.InvalidateArticleDetail(transfer.ContentID)
+	}
+
+	utils.SuccessResponse(c, 200, "已接受转移", transfer)
+}
+
+// RejectTransfer 接收者拒绝转移
+func (h *ContentTransferHandler) RejectTransfer(c *gin.Context) {
+	userID, isOK := getUserIDOrFail(c)
+	if !isOK {
+		return
+	}
+
+	transferID, isOK := parseUintParam(c, "id", "无效的转移ID")
+	if !isOK {
+		return
+	}
+
+	transfer, err := h.transferRepo.RejectTransfer(c.Request.Context(), transferID, userID)
+	if err != nil {
+		utils.AppErrorResponse(c, err, "拒绝转移失败")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "已拒绝转移", transfer)
+}
+
+// CancelTransfer 发起者取消转移
+func (h *ContentTransferHandler) CancelTransfer(c *gin.Context) {
+	userID, isOK := getUserIDOrFail(c)
+	if !isOK {
+		return
+	}
+
+	transferID, isOK := parseUintParam(c, "id", "无效的转移ID")
+	if !isOK {
+		return
+	}
+
+	transfer, err := h.transferRepo.CancelTransfer(c.Request.Context(), transferID, userID)
+	if err != nil {
+		utils.AppErrorResponse(c, err, "取消转移失败")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "已取消转移", transfer)
+}
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"gin/internal/middleware"
+	"gin/internal/models"
+	"gin/internal/services"
+	"gin/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// transferStore 用内存表模拟文章作者和转移记录：文章5属于用户1，用户1~3账户正常
+type transferStore struct {
+	mu        sync.Mutex
+	owners    map[int64]int64
+	transfers map[int64][]driver.Value // id -> content_type, content_id, from, to, status
+}
+
+// newTransferRouter 返回路由、内存表和用户1~3的JWT
+func newTransferRouter(t *testing.T) (*gin.Engine, *transferStore, map[uint]string) {
+	t.Helper()
+	cfg := newTestConfig()
+	fake, db := newFakeDatabase(t, cfg)
+	store := &transferStore{owners: map[int64]int64{5: 1}, transfers: make(map[int64][]driver.Value)}
+
+	fake.On(`SELECT user_id FROM articles WHERE id = \? AND status != 2 FOR UPDATE`, func(args []driver.Value) testutil.Response {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		resp := testutil.Response{Columns: []string{"user_id"}}
+		if owner, ok := store.owners[args[0].(int64)]; ok {
+			resp.Rows = [][]driver.Value{{owner}}
+		}
+		return resp
+	})
+	fake.On(`SELECT account_status FROM user_auth WHERE id = \?`, func(args []driver.Value) testutil.Response {
+		resp := testutil.Response{Columns: []string{"account_status"}}
+		if id := args[0].(int64); id >= 1 && id <= 3 {
+			resp.Rows = [][]driver.Value{{int64(models.AccountStatusNormal)}}
+		}
+		return resp
+	})
+	fake.On(`SELECT COUNT\(\*\) FROM content_ownership_transfers`, func(args []driver.Value) testutil.Response {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		count := int64(0)
+		for _, tr := range store.transfers {
+			if tr[0] == args[0] && tr[1] == args[1] && tr[4] == args[2] {
+				count++
+			}
+		}
+		return testutil.Response{Columns: []string{"count"}, Rows: [][]driver.Value{{count}}}
+	})
+	fake.On(`INSERT INTO content_ownership_transfers`, func(args []driver.Value) testutil.Response {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		id := int64(len(store.transfers) + 1)
+		store.transfers[id] = []driver.Value{args[0], args[1], args[2], args[3], args[4]}
+		return testutil.Response{LastInsertID: id, RowsAffected: 1}
+	})
+	fake.On(`FROM content_ownership_transfers WHERE id = \? FOR UPDATE`, func(args []driver.Value) testutil.Response {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		resp := testutil.Response{Columns: []string{"id", "content_type", "content_id", "from_user_id", "to_user_id", "status", "created_at"}}
+		if tr, ok := store.transfers[args[0].(int64)]; ok {
+			resp.Rows = [][]driver.Value{{args[0], tr[0], tr[1], tr[2], tr[3], tr[4], time.Now().UTC()}}
+		}
+		return resp
+	})
+	fake.On(`UPDATE articles SET user_id = \? WHERE id = \? AND user_id = \?`, func(args []driver.Value) testutil.Response {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		if store.owners[args[1].(int64)] != args[2].(int64) {
+			return testutil.Response{}
+		}
+		store.owners[args[1].(int64)] = args[0].(int64)
+		return testutil.Response{RowsAffected: 1}
+	})
+	fake.On(`UPDATE content_ownership_transfers SET status = \?`, func(args []driver.Value) testutil.Response {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		store.transfers[args[2].(int64)][4] = args[0]
+		return testutil.Response{RowsAffected: 1}
+	})
+
+	articleRepo := services.NewArticleRepository(db, cfg)
+	h := NewContentTransferHandler(services.NewContentTransferRepository(db), services.NewCacheService(articleRepo, cfg))
+	router := gin.New()
+	account := router.Group("/api/account", middleware.AuthMiddleware(cfg, nil, nil))
+	account.POST("/content-transfers", h.CreateTransfer)
+	account.POST("/content-transfers/:id/accept", h.AcceptTransfer)
+	account.POST("/content-transfers/:id/reject", h.RejectTransfer)
+	account.POST("/content-transfers/:id/cancel", h.CancelTransfer)
+
+	tokens := map[uint]string{}
+	for id, name := range map[uint]string{1: "owner", 2: "bob", 3: "carol"} {
+		tokens[id] = signTestJWT(t, cfg, id, name)
+	}
+	return router, store, tokens
+}
+
+func (s *transferStore) owner(articleID int64) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.owners[articleID]
+}
+
+func (s *transferStore) status(transferID int64) driver.Value {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.transfers[transferID][4]
+}
+
+func TestContentTransferAccept(t *testing.T) {
+	router, store, tokens := newTransferRouter(t)
+	body := map[string]interface{}{"content_type": "article", "content_id": 5, "to_user_id": 2}
+
+	// 只有作者本人可以发起
+	if r := doRequest(t, router, http.MethodPost, "/api/account/content-transfers", tokens[3], body); r.Status != http.StatusForbidden {
+		t.Fatalf("非作者发起转移应返回403，实际 %d %s", r.Status, r.Body)
+	}
+
+	resp := doRequest(t, router, http.MethodPost, "/api/account/content-transfers", tokens[1], body)
+	if resp.Status != http.StatusOK {
+		t.Fatalf("作者发起转移应成功，实际 %d %s", resp.Status, resp.Body)
+	}
+	var transfer models.ContentTransfer
+	decodeData(t, resp, &transfer)
+	if transfer.ID != 1 || transfer.Status != models.TransferStatusPending || transfer.FromUserID != 1 || transfer.ToUserID != 2 {
+		t.Fatalf("应创建待确认的转移，实际 %+v", transfer)
+	}
+	if store.owner(5) != 1 {
+		t.Fatal("接收者确认前不应修改作者")
+	}
+
+	// 同一内容不能重复发起
+	if r := doRequest(t, router, http.MethodPost, "/api/account/content-transfers", tokens[1], body); r.Status != http.StatusConflict {
+		t.Fatalf("已有待确认的转移时应返回409，实际 %d %s", r.Status, r.Body)
+	}
+	// 只有接收者可以接受
+	for _, userID := range []uint{1, 3} {
+		if r := doRequest(t, router, http.MethodPost, "/api/account/content-transfers/1/accept", tokens[userID], nil); r.Status != http.StatusForbidden {
+			t.Fatalf("用户 %d 不是接收者，接受转移应返回403，实际 %d", userID, r.Status)
+		}
+	}
+
+	resp = doRequest(t, router, http.MethodPost, "/api/account/content-transfers/1/accept", tokens[2], nil)
+	if resp.Status != http.StatusOK {
+		t.Fatalf("接收者接受转移应成功，实际 %d %s", resp.Status, resp.Body)
+	}
+	decodeData(t, resp, &transfer)
+	if transfer.Status != models.TransferStatusAccepted || transfer.RespondedAt == nil {
+		t.Fatalf("转移应标记为已接受，实际 %+v", transfer)
+	}
+	if store.owner(5) != 2 || store.status(1) != int64(models.TransferStatusAccepted) {
+		t.Fatalf("接受后文章应归属接收者并保留转移记录，实际作者 %d 状态 %v", store.owner(5), store.status(1))
+	}
+
+	// 已处理的转移不能再次操作，原作者也不能再发起
+	if r := doRequest(t, router, http.MethodPost, "/api/account/content-transfers/1/reject", tokens[2], nil); r.Status != http.StatusConflict {
+		t.Fatalf("已处理的转移应返回409，实际 %d", r.Status)
+	}
+	if r := doRequest(t, router, http.MethodPost, "/api/account/content-transfers", tokens[1], body); r.Status != http.StatusForbidden {
+		t.Fatalf("转移完成后原作者不能再发起，实际 %d", r.Status)
+	}
+}
+
+func TestContentTransferRejectAndCancel(t *testing.T) {
+	router, store, tokens := newTransferRouter(t)
+	body := map[string]interface{}{"content_type": "article", "content_id": 5, "to_user_id": 2}
+
+	if r := doRequest(t, router, http.MethodPost, "/api/account/content-transfers", tokens[1], body); r.Status != http.StatusOK {
+		t.Fatalf("发起转移失败: %d %s", r.Status, r.Body)
+	}
+	if r := doRequest(t, router, http.MethodPost, "/api/account/content-transfers/1/reject", tokens[1], nil); r.Status != http.StatusForbidden {
+		t.Fatalf("发起者不能拒绝转移，实际 %d", r.Status)
+	}
+	if r := doRequest(t, router, http.MethodPost, "/api/account/content-transfers/1/reject", tokens[2], nil); r.Status != http.StatusOK {
+		t.Fatalf("接收者拒绝转移应成功，实际 %d %s", r.Status, r.Body)
+	}
+	if store.owner(5) != 1 || store.status(1) != int64(models.TransferStatusRejected) {
+		t.Fatalf("拒绝后作者不变，实际作者 %d 状态 %v", store.owner(5), store.status(1))
+	}
+
+	// 拒绝后可以重新发起，发起者可以取消
+	if r := doRequest(t, router, http.MethodPost, "/api/account/content-transfers", tokens[1], body); r.Status != http.StatusOK {
+		t.Fatalf("拒绝后应可重新发起，实际 %d %s", r.Status, r.Body)
+	}
+	if r := doRequest(t, router, http.MethodPost, "/api/account/content-transfers/2/cancel", tokens[2], nil); r.Status != http.StatusForbidden {
+		t.Fatalf("接收者不能取消转移，实际 %d", r.Status)
+	}
+	if r := doRequest(t, router, http.MethodPost, "/api/account/content-transfers/2/cancel", tokens[1], nil); r.Status != http.StatusOK {
+		t.Fatalf("发起者取消转移应成功，实际 %d %s", r.Status, r.Body)
+	}
+	if r := doRequest(t, router, http.MethodPost, "/api/account/content-transfers/2/accept", tokens[2], nil); r.Status != http.StatusConflict {
+		t.Fatalf("已取消的转移不能再接受，实际 %d", r.Status)
+	}
+	if store.owner(5) != 1 {
+		t.Fatal("取消后作者不应改变")
+	}
+}
+
+func TestContentTransferValidation(t *testing.T) {
+	router, _, tokens := newTransferRouter(t)
+	cases := []struct {
+		name string
+		body map[string]interface{}
+		want int
+	}{
+		{"转移给自己", map[string]interface{}{"content_type": "article", "content_id": 5, "to_user_id": 1}, http.StatusBadRequest},
+		{"不支持的内容类型", map[string]interface{}{"content_type": "comment", "content_id": 5, "to_user_id": 2}, http.StatusUnprocessableEntity},
+		{"文章不存在", map[string]interface{}{"content_type": "article", "content_id": 9, "to_user_id": 2}, http.StatusNotFound},
+		{"接收者不存在", map[string]interface{}{"content_type": "article", "content_id": 5, "to_user_id": 42}, http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		if r := doRequest(t, router, http.MethodPost, "/api/account/content-transfers", tokens[1], tc.body); r.Status != tc.want {
+			t.Errorf("%s: 应返回 %d，实际 %d %s", tc.name, tc.want, r.Status, r.Body)
+		}
+	}
+	if r := doRequest(t, router, http.MethodPost, "/api/account/content-transfers/9/accept", tokens[2], nil); r.Status != http.StatusNotFound {
+		t.Fatalf("转移不存在应返回404，实际 %d", r.Status)
+	}
+}
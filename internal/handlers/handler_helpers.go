@@ -1,8 +1,13 @@
 package handlers
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
+	"gin/internal/config"
+	"gin/internal/models"
 	"gin/internal/utils"
 
 	"github.com/gin-gonic/gin"
@@ -51,6 +56,10 @@ func getUserIDOrFail(c *gin.Context) (uint, bool) {
 // 返回值：isOK
 func bindJSONOrFail(c *gin.Context, req interface{}, logger utils.Logger, funcName string) bool {
 	if err := c.ShouldBindJSON(req); err != nil {
+		if limit, ok := utils.MaxBytesLimit(err); ok {
+			utils.RequestTooLargeResponse(c, limit)
+			return false
+		}
 		if logger != nil && funcName != "" {
 			logger.Warn(funcName+"请求参数错误", "error", err.Error())
 		}
@@ -60,6 +69,16 @@ func bindJSONOrFail(c *gin.Context, req interface{}, logger utils.Logger, funcNa
 	return true
 }
 
+// moderateOrFail 对用户提交的文本做内容审核（屏蔽模式下直接替换 texts 中的敏感词）
+// 被拒绝时自动返回400响应
+func moderateOrFail(c *gin.Context, userID uint, texts ...*string) bool {
+	if err := utils.ModerateTexts(userID, texts...); err != nil {
+		utils.AppErrorResponse(c, err, "内容审核失败")
+		return false
+	}
+	return true
+}
+
 // parseUintParam 解析URL参数为uint，失败时自动返回错误响应
 // 返回值：value, isOK
 func parseUintParam(c *gin.Context, paramName string, errorMsg string) (uint, bool) {
@@ -70,3 +89,58 @@ func parseUintParam(c *gin.Context, paramName string, errorMsg string) (uint, bo
 	}
 	return value, true
 }
+
+// bindBatchIDsOrFail 绑定批量删除请求，去重后检查数量上限，失败时自动返回错误响应
+// 返回值：ids, isOK
+func bindBatchIDsOrFail(c *gin.Context, maxItems int) ([]uint, bool) {
+	var req models.BatchDeleteRequest
+	if !bindJSONOrFail(c, &req, nil, "") {
+		return nil, false
+	}
+
+	seen := make(map[uint]bool, len(req.IDs))
+	ids := make([]uint, 0, len(req.IDs))
+	for _, id := range req.IDs {
+		if id == 0 {
+			utils.BadRequestResponse(c, "无效的ID")
+			return nil, false
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) > maxItems {
+		utils.BadRequestResponse(c, fmt.Sprintf("单次最多删除%d条", maxItems))
+		return nil, false
+	}
+	return ids, true
+}
+
+// parsePageParams 解析分页参数 ?page=&page_size=（超出范围时使用默认值）
+func parsePageParams(c *gin.Context, cfg *config.PaginationConfig) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(cfg.DefaultPageSize)))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > cfg.MaxPageSize {
+		pageSize = cfg.DefaultPageSize
+	}
+	return page, pageSize
+}
+
+// etagMatches 判断 If-None-Match 是否匹配 ETag（弱比较，支持逗号分隔的多个值和 *）
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	target := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == target {
+			return true
+		}
+	}
+	return false
+}
@@ -1,21 +1,92 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
+	"sync"
+	"time"
 
+	"gin/internal/config"
 	"gin/internal/services"
+	"gin/internal/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
 // HealthHandler 健康检查处理器
 type HealthHandler struct {
-	db *services.Database
+	db     *services.Database
+	config *config.Config
+	probes []*dependencyProbe // 就绪检查依赖（按响应中的顺序）
 }
 
 // NewHealthHandler 创建健康检查处理器
-func NewHealthHandler(db *services.Database) *HealthHandler {
-	return &HealthHandler{db: db}
+func NewHealthHandler(db *services.Database, storage *services.MultiBucketStorage, executor services.CodeExecutor, cfg *config.Config) *HealthHandler {
+	cacheTTL := time.Duration(cfg.Readiness.ProbeCacheSeconds) * time.Second
+	return &HealthHandler{
+		db:     db,
+		config: cfg,
+		probes: []*dependencyProbe{
+			{name: "database", critical: true, check: func(context.Context) error { return db.HealthCheck() }},
+			{name: "storage", critical: true, cacheTTL: cacheTTL, check: storage.HealthCheck},
+			{name: "code_executor", critical: cfg.Readiness.CodeExecutorCritical, cacheTTL: cacheTTL, check: executor.Ping},
+		},
+	}
+}
+
+// dependencyStatus 单个依赖的检查结果
+type dependencyStatus struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"` // up / down
+	Critical  bool   `json:"critical"`
+	LatencyMs int64  `json:"latency_ms"`
+	Cached    bool   `json:"cached"`
+	Error     string `json:"error,omitempty"`
+}
+
+// dependencyProbe 依赖探测（cacheTTL > 0 时在有效期内复用上次结果，避免频繁请求外部服务）
+type dependencyProbe struct {
+	name     string
+	critical bool
+	cacheTTL time.Duration
+	check    func(ctx context.Context) error
+
+	mu        sync.Mutex
+	checkedAt time.Time
+	last      dependencyStatus
+}
+
+// run 执行探测；并发的就绪检查共用同一次探测
+// 不使用请求的 context，避免客户端断开导致把失败结果写入缓存
+func (p *dependencyProbe) run(timeout time.Duration) dependencyStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cacheTTL > 0 && !p.checkedAt.IsZero() && time.Since(p.checkedAt) < p.cacheTTL {
+		cached := p.last
+		cached.Cached = true
+		return cached
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := p.check(ctx)
+	status := dependencyStatus{
+		Name:      p.name,
+		Status:    "up",
+		Critical:  p.critical,
+		LatencyMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		status.Status = "down"
+		status.Error = err.Error()
+	}
+
+	p.last = status
+	p.checkedAt = time.Now()
+	return status
 }
 
 // Check 健康检查
@@ -27,16 +98,43 @@ func (h *HealthHandler) Check(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "healthy"})
 }
 
-// Ready 就绪检查
+// Ready 就绪检查（供负载均衡使用）：并发检查数据库、MinIO和代码执行服务
+// 任一关键依赖不可用时返回503，非关键依赖只在结果中标记为 down
 func (h *HealthHandler) Ready(c *gin.Context) {
-	if err := h.db.HealthCheck(); err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not ready", "error": err.Error()})
+	timeout := time.Duration(h.config.Readiness.CheckTimeoutMs) * time.Millisecond
+	checks := make([]dependencyStatus, len(h.probes))
+
+	var wg sync.WaitGroup
+	for i, probe := range h.probes {
+		wg.Add(1)
+		go func(i int, probe *dependencyProbe) {
+			defer wg.Done()
+			checks[i] = probe.run(timeout)
+		}(i, probe)
+	}
+	wg.Wait()
+
+	ready := true
+	for _, check := range checks {
+		if check.Critical && check.Status != "up" {
+			ready = false
+		}
+	}
+
+	if !ready {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not ready", "checks": checks})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"status": "ready"})
+	c.JSON(http.StatusOK, gin.H{"status": "ready", "checks": checks})
 }
 
 // Live 存活检查
 func (h *HealthHandler) Live(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "live"})
 }
+
+// Version 返回构建与运行时信息（应用版本、Go版本、git提交、构建时间、运行时长、运行模式）
+func (h *HealthHandler) Version(c *gin.Context) {
+	info := utils.GetBuildInfo(h.config.App.Name, h.config.App.Version, h.config.Server.Mode)
+	utils.SuccessResponse(c, http.StatusOK, "success", info)
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestVersionEndpoint(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.App.Name = "shequ"
+	cfg.App.Version = "1.2.3"
+	cfg.Server.Mode = "release"
+
+	previousCommit, previousBuild := utils.GitCommit, utils.BuildTime
+	utils.GitCommit, utils.BuildTime = "abc1234", "2024-01-02T03:04:05Z"
+	t.Cleanup(func() { utils.GitCommit, utils.BuildTime = previousCommit, previousBuild })
+
+	router := gin.New()
+	router.GET("/api/version", (&HealthHandler{config: cfg}).Version)
+
+	resp := doRequest(t, router, http.MethodGet, "/api/version", "", nil)
+	if resp.Status != http.StatusOK {
+		t.Fatalf("版本接口应返回200，实际 %d: %s", resp.Status, resp.Body)
+	}
+	var info utils.BuildInfo
+	decodeData(t, resp, &info)
+	if info.Name != "shequ" || info.Version != "1.2.3" || info.Mode != "release" {
+		t.Fatalf("应返回配置中的应用名、版本和运行模式，实际 %+v", info)
+	}
+	if info.GoVersion != runtime.Version() || info.GitCommit != "abc1234" || info.BuildTime != "2024-01-02T03:04:05Z" {
+		t.Fatalf("应返回Go版本和注入的构建信息，实际 %+v", info)
+	}
+	if info.StartedAt == "" || info.UptimeSeconds < 0 {
+		t.Fatalf("应返回启动时间和运行时长，实际 %+v", info)
+	}
+	if strings.Contains(resp.Body, cfg.JWT.SecretKey) {
+		t.Fatal("版本信息不应包含配置密钥")
+	}
+
+	// 未注入构建信息时字段为空字符串而不是缺失
+	utils.GitCommit, utils.BuildTime = "", ""
+	resp = doRequest(t, router, http.MethodGet, "/api/version", "", nil)
+	if !strings.Contains(resp.Body, `"git_commit":""`) || !strings.Contains(resp.Body, `"build_time":""`) {
+		t.Fatalf("未注入时构建字段应为空字符串: %s", resp.Body)
+	}
+}
+
+// countingCheck 返回记录调用次数的依赖检查，err 为检查结果
+func countingCheck(calls *atomic.Int32, err *error) func(context.Context) error {
+	return func(context.Context) error {
+		calls.Add(1)
+		return *err
+	}
+}
+
+func TestReadyDependencyStatus(t *testing.T) {
+	cfg := newTestConfig()
+	var dbCalls, storageCalls, executorCalls atomic.Int32
+	var dbErr, storageErr error
+	executorErr := errors.New("Piston API 不可用")
+	h := &HealthHandler{config: cfg, probes: []*dependencyProbe{
+		{name: "database", critical: true, check: countingCheck(&dbCalls, &dbErr)},
+		{name: "storage", critical: true, cacheTTL: time.Hour, check: countingCheck(&storageCalls, &storageErr)},
+		{name: "code_executor", critical: false, cacheTTL: time.Hour, check: countingCheck(&executorCalls, &executorErr)},
+	}}
+	router := gin.New()
+	router.GET("/ready", h.Ready)
+
+	ready := func() (int, []dependencyStatus) {
+		t.Helper()
+		resp := doRequest(t, router, http.MethodGet, "/ready", "", nil)
+		var body struct {
+			Status string             `json:"status"`
+			Checks []dependencyStatus `json:"checks"`
+		}
+		if err := json.Unmarshal([]byte(resp.Body), &body); err != nil || len(body.Checks) != 3 {
+			t.Fatalf("就绪检查应返回每个依赖的状态: %v %s", err, resp.Body)
+		}
+		return resp.Status, body.Checks
+	}
+
+	// 非关键依赖不可用时仍然就绪，但在结果中标记为 down
+	status, checks := ready()
+	if status != http.StatusOK {
+		t.Fatalf("只有非关键依赖不可用时应返回200，实际 %d", status)
+	}
+	if checks[0].Name != "database" || checks[0].Status != "up" || checks[0].LatencyMs < 0 ||
+		checks[2].Status != "down" || checks[2].Error != "Piston API 不可用" || checks[2].Critical {
+		t.Fatalf("依赖状态不正确: %+v", checks)
+	}
+
+	// 缓存有效期内复用 MinIO/Piston 的探测结果，数据库每次都检查
+	storageErr = errors.New("桶不存在")
+	status, checks = ready()
+	if status != http.StatusOK || !checks[1].Cached || checks[1].Status != "up" || checks[0].Cached {
+		t.Fatalf("缓存有效期内应复用上次的探测结果，实际 %d %+v", status, checks)
+	}
+	if dbCalls.Load() != 2 || storageCalls.Load() != 1 || executorCalls.Load() != 1 {
+		t.Fatalf("探测次数不正确: db=%d storage=%d executor=%d", dbCalls.Load(), storageCalls.Load(), executorCalls.Load())
+	}
+
+	// 关键依赖不可用时返回503
+	dbErr = errors.New("connection refused")
+	if status, checks := ready(); status != http.StatusServiceUnavailable || checks[0].Status != "down" {
+		t.Fatalf("数据库不可用时应返回503，实际 %d %+v", status, checks)
+	}
+
+	// 缓存过期后重新探测
+	h.probes[1].cacheTTL = 0
+	dbErr = nil
+	if status, checks := ready(); status != http.StatusServiceUnavailable || checks[1].Status != "down" || checks[1].Cached {
+		t.Fatalf("缓存过期后应重新探测 MinIO，实际 %d %+v", status, checks)
+	}
+}
+
+func TestNewHealthHandlerProbes(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Readiness.ProbeCacheSeconds = 7
+	cfg.Readiness.CodeExecutorCritical = true
+	h := NewHealthHandler(nil, nil, stubCodeExecutor{}, cfg)
+
+	want := []struct {
+		name     string
+		critical bool
+		cacheTTL time.Duration
+	}{
+		{"database", true, 0},
+		{"storage", true, 7 * time.Second},
+		{"code_executor", true, 7 * time.Second},
+	}
+	for i, w := range want {
+		p := h.probes[i]
+		if p.name != w.name || p.critical != w.critical || p.cacheTTL != w.cacheTTL {
+			t.Fatalf("第 %d 个依赖应为 %+v，实际 name=%s critical=%v cacheTTL=%v", i, w, p.name, p.critical, p.cacheTTL)
+		}
+	}
+}
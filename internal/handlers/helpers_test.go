@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/models"
+	"gin/internal/services"
+	"gin/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// newTestConfig 创建测试配置（固定JWT密钥）
+func newTestConfig() *config.Config {
+	gin.SetMode(gin.TestMode)
+	cfg := config.Default()
+	cfg.JWT.SecretKey = "test-secret-key"
+	return cfg
+}
+
+// newFakeDatabase 创建使用可编程驱动的数据库实例
+func newFakeDatabase(t *testing.T, cfg *config.Config) (*testutil.FakeDB, *services.Database) {
+	t.Helper()
+	fake := testutil.NewFakeDB()
+	db := fake.Open()
+	t.Cleanup(func() { db.Close() })
+	return fake, services.NewDatabaseWithDB(cfg, db)
+}
+
+// signTestJWT 签发测试用户的JWT
+func signTestJWT(t *testing.T, cfg *config.Config, userID uint, username string) string {
+	t.Helper()
+	claims := models.CreateClaims(userID, username, username+"@example.com", "", "", cfg.JWT.Issuer,
+		"jti-"+strconv.FormatUint(uint64(userID), 10), 0, time.Hour)
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(cfg.JWT.SecretKey))
+	if err != nil {
+		t.Fatalf("签发token失败: %v", err)
+	}
+	return token
+}
+
+// testResponse 解析后的统一响应
+type testResponse struct {
+	Status int
+	Body   string
+	models.CommonResponse
+}
+
+// doRequest 发送请求；body 不为 nil 时以JSON编码，token 不为空时作为 Bearer 令牌
+func doRequest(t *testing.T, router http.Handler, method, path, token string, body interface{}) testResponse {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("编码请求体失败: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	resp := testResponse{Status: w.Code, Body: w.Body.String()}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp.CommonResponse)
+	return resp
+}
+
+// decodeData 把响应的 data 字段解码到 v
+func decodeData(t *testing.T, resp testResponse, v interface{}) {
+	t.Helper()
+	data, err := json.Marshal(resp.Data)
+	if err != nil {
+		t.Fatalf("编码响应数据失败: %v", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatalf("解码响应数据失败: %v (%s)", err, resp.Body)
+	}
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"strconv"
+
+	"gin/internal/config"
+	"gin/internal/models"
+	"gin/internal/services"
+	"gin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NotificationHandler 站内通知处理器
+type NotificationHandler struct {
+	notifyRepo *services.NotificationRepository
+	config     *config.Config
+	logger     utils.Logger
+}
+
+// NewNotificationHandler 创建站内通知处理器
+func NewNotificationHandler(notifyRepo *services.NotificationRepository, cfg *config.Config) *NotificationHandler {
+	return &NotificationHandler{
+		notifyRepo: notifyRepo,
+		config:     cfg,
+		logger:     utils.GetLogger(),
+	}
+}
+
+// ListNotifications 分页获取当前用户的通知（?unread_only=true 只看未读）
+func (h *NotificationHandler) ListNotifications(c *gin.Context) {
+	userID, isOK := getUserIDOrFail(c)
+	if !isOK {
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(h.config.Pagination.DefaultPageSize)))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > h.config.Pagination.MaxPageSize {
+		pageSize = h.config.Pagination.DefaultPageSize
+	}
+	unreadOnly, _ := strconv.ParseBool(c.DefaultQuery("unread_only", "false"))
+
+	response, err := h.notifyRepo.ListForUser(c.Request.Context(), userID, page, pageSize, unreadOnly)
+	if err != nil {
+		utils.ErrorResponse(c, utils.GetHTTPStatusCode(err), "获取通知失败")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "获取成功", response)
+}
+
+// GetUnreadCount 获取当前用户的未读通知数
+func (h *NotificationHandler) GetUnreadCount(c *gin.Context) {
+	userID, isOK := getUserIDOrFail(c)
+	if !isOK {
+		return
+	}
+
+	count, err := h.notifyRepo.UnreadCount(c.Request.Context(), userID)
+	if err != nil {
+		utils.ErrorResponse(c, utils.GetHTTPStatusCode(err), "获取未读通知数失败")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "获取成功", gin.H{
+		"unread_count": count,
+	})
+}
+
+// MarkRead 标记通知已读（不传 ids 时标记全部）
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	userID, isOK := getUserIDOrFail(c)
+	if !isOK {
+		return
+	}
+
+	var req models.MarkNotificationsReadRequest
+	if c.Request.ContentLength > 0 && !bindJSONOrFail(c, &req, h.logger, "MarkNotificationsRead") {
+		return
+	}
+
+	updated, err := h.notifyRepo.MarkRead(c.Request.Context(), userID, req.IDs)
+	if err != nil {
+		utils.ErrorResponse(c, utils.GetHTTPStatusCode(err), "标记已读失败")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "标记成功", gin.H{
+		"updated": updated,
+	})
+}
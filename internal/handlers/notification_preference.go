@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"gin/internal/models"
+	"gin/internal/services"
+	"gin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxDoNotDisturbDuration 免打扰最长持续时间
+const maxDoNotDisturbDuration = 30 * 24 * time.Hour
+
+// NotificationPreferenceHandler 通知偏好处理器
+type NotificationPreferenceHandler struct {
+	prefsRepo *services.NotificationPreferenceRepository
+	logger    utils.Logger
+}
+
+// NewNotificationPreferenceHandler 创建通知偏好处理器
+func NewNotificationPreferenceHandler(prefsRepo *services.NotificationPreferenceRepository) *NotificationPreferenceHandler {
+	return &NotificationPreferenceHandler{
+		prefsRepo: prefsRepo,
+		logger:    utils.GetLogger(),
+	}
+}
+
+// GetPreferences 获取当前用户的通知偏好
+func (h *NotificationPreferenceHandler) GetPreferences(c *gin.Context) {
+	userID, isOK := getUserIDOrFail(c)
+	if !isOK {
+		return
+	}
+
+	prefs, err := h.prefsRepo.GetNotificationPreferences(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Warn("获取通知偏好失败", "userID", userID, "error", err.Error())
+		utils.ErrorResponse(c, utils.GetHTTPStatusCode(err), "获取通知偏好失败")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "获取成功", prefs)
+}
+
+// UpdatePreferences 修改当前用户的通知偏好（只修改请求中传入的事件类型和字段）
+func (h *NotificationPreferenceHandler) UpdatePreferences(c *gin.Context) {
+	userID, isOK := getUserIDOrFail(c)
+	if !isOK {
+		return
+	}
+
+	var req models.UpdateNotificationPreferencesRequest
+	if !bindJSONOrFail(c, &req, h.logger, "UpdateNotificationPreferences") {
+		return
+	}
+
+	prefs, err := h.prefsRepo.UpdateNotificationPreferences(c.Request.Context(), userID, req.Preferences)
+	if err != nil {
+		var appErr *utils.AppError
+		if errors.As(err, &appErr) && appErr.Code == 400 {
+			utils.ValidationErrorResponse(c, appErr.Message)
+			return
+		}
+		utils.ErrorResponse(c, utils.GetHTTPStatusCode(err), "更新通知偏好失败")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "更新成功", prefs)
+}
+
+// GetDoNotDisturb 获取当前用户的通知免打扰状态
+func (h *NotificationPreferenceHandler) GetDoNotDisturb(c *gin.Context) {
+	userID, isOK := getUserIDOrFail(c)
+	if !isOK {
+		return
+	}
+
+	status, err := h.prefsRepo.GetDoNotDisturb(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Warn("获取通知免打扰状态失败", "userID", userID, "error", err.Error())
+		utils.ErrorResponse(c, utils.GetHTTPStatusCode(err), "获取免打扰状态失败")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "获取成功", status)
+}
+
+// SetDoNotDisturb 设置当前用户的通知免打扰截止时间（mute_until 为空时关闭）
+// 免打扰期间不推送实时通知，通知仍写入收件箱，到期后自动恢复推送
+func (h *NotificationPreferenceHandler) SetDoNotDisturb(c *gin.Context) {
+	userID, isOK := getUserIDOrFail(c)
+	if !isOK {
+		return
+	}
+
+	var req models.SetDoNotDisturbRequest
+	if !bindJSONOrFail(c, &req, h.logger, "SetDoNotDisturb") {
+		return
+	}
+
+	var until time.Time
+	if req.MuteUntil != nil {
+		until = *req.MuteUntil
+		if !until.After(time.Now()) {
+			utils.ValidationErrorResponse(c, "免打扰截止时间必须晚于当前时间")
+			return
+		}
+		if until.Sub(time.Now()) > maxDoNotDisturbDuration {
+			utils.ValidationErrorResponse(c, "免打扰时长不能超过30天")
+			return
+		}
+	}
+
+	status, err := h.prefsRepo.SetDoNotDisturb(c.Request.Context(), userID, until)
+	if err != nil {
+		utils.ErrorResponse(c, utils.GetHTTPStatusCode(err), "设置免打扰失败")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "设置成功", status)
+}
@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"gin/internal/middleware"
+	"gin/internal/models"
+	"gin/internal/services"
+	"gin/internal/testutil"
+	"gin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// notificationPrefStore 内存中的 notification_preferences 和 notification_dnd 表
+type notificationPrefStore struct {
+	mu   sync.Mutex
+	rows map[[2]interface{}][2]bool // (user_id, event_type) -> (in_app, email)
+	dnd  map[interface{}]time.Time  // user_id -> mute_until
+}
+
+// newNotificationPrefRepo 创建读写内存偏好表和免打扰表的偏好数据访问层
+func newNotificationPrefRepo(t *testing.T) (*services.NotificationPreferenceRepository, *notificationPrefStore) {
+	t.Helper()
+	cfg := newTestConfig()
+	fake, db := newFakeDatabase(t, cfg)
+	store := &notificationPrefStore{rows: make(map[[2]interface{}][2]bool), dnd: make(map[interface{}]time.Time)}
+
+	fake.On(`SELECT user_id, event_type, in_app, email, updated_at FROM notification_preferences`, func(args []driver.Value) testutil.Response {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		resp := testutil.Response{Columns: []string{"user_id", "event_type", "in_app", "email", "updated_at"}}
+		for _, id := range args {
+			for key, v := range store.rows {
+				if key[0] == id {
+					resp.Rows = append(resp.Rows, []driver.Value{id, key[1], v[0], v[1], time.Now().UTC()})
+				}
+			}
+		}
+		return resp
+	})
+	fake.On(`INSERT INTO notification_preferences`, func(args []driver.Value) testutil.Response {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		key := [2]interface{}{args[0], args[1]}
+		row, exists := store.rows[key]
+		if !exists {
+			row = [2]bool{args[2].(bool), args[3].(bool)}
+		} else {
+			// ON DUPLICATE KEY UPDATE ... COALESCE(?, 原值)
+			if args[5] != nil {
+				row[0] = args[5].(bool)
+			}
+			if args[6] != nil {
+				row[1] = args[6].(bool)
+			}
+		}
+		store.rows[key] = row
+		return testutil.Response{RowsAffected: 1}
+	})
+	fake.On(`SELECT user_id, mute_until FROM notification_dnd`, func(args []driver.Value) testutil.Response {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		resp := testutil.Response{Columns: []string{"user_id", "mute_until"}}
+		for _, id := range args {
+			if until, ok := store.dnd[id]; ok {
+				resp.Rows = append(resp.Rows, []driver.Value{id, until})
+			}
+		}
+		return resp
+	})
+	fake.On(`INSERT INTO notification_dnd`, func(args []driver.Value) testutil.Response {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		store.dnd[args[0]] = args[1].(time.Time)
+		return testutil.Response{RowsAffected: 1}
+	})
+	fake.On(`DELETE FROM notification_dnd`, func(args []driver.Value) testutil.Response {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		delete(store.dnd, args[0])
+		return testutil.Response{RowsAffected: 1}
+	})
+
+	return services.NewNotificationPreferenceRepository(db, cfg), store
+}
+
+func TestNotificationPreferencesEndpoint(t *testing.T) {
+	cfg := newTestConfig()
+	repo, _ := newNotificationPrefRepo(t)
+	h := NewNotificationPreferenceHandler(repo)
+
+	router := gin.New()
+	auth := router.Group("/api", middleware.AuthMiddleware(cfg, nil, nil))
+	auth.GET("/users/me/notification-preferences", h.GetPreferences)
+	auth.PUT("/users/me/notification-preferences", h.UpdatePreferences)
+	token := signTestJWT(t, cfg, 1, "alice")
+
+	// 默认全部开启
+	resp := doRequest(t, router, http.MethodGet, "/api/users/me/notification-preferences", token, nil)
+	if resp.Status != http.StatusOK {
+		t.Fatalf("获取通知偏好应返回200，实际 %d %s", resp.Status, resp.Body)
+	}
+	var prefs models.NotificationPreferences
+	decodeData(t, resp, &prefs)
+	if len(prefs.Preferences) != len(models.NotificationEventTypes) {
+		t.Fatalf("应返回全部 %d 个事件类型，实际 %d", len(models.NotificationEventTypes), len(prefs.Preferences))
+	}
+	for _, p := range prefs.Preferences {
+		if !p.InApp || !p.Email {
+			t.Fatalf("默认偏好应全部开启，实际 %+v", p)
+		}
+	}
+
+	// 只修改传入的字段，其余保持不变
+	resp = doRequest(t, router, http.MethodPut, "/api/users/me/notification-preferences", token, map[string]interface{}{
+		"preferences": []map[string]interface{}{{"event_type": "comment", "in_app": false}},
+	})
+	if resp.Status != http.StatusOK {
+		t.Fatalf("修改通知偏好应返回200，实际 %d %s", resp.Status, resp.Body)
+	}
+	decodeData(t, resp, &prefs)
+	if got := prefs.Get(models.NotificationEventComment); got.InApp || !got.Email {
+		t.Fatalf("comment 应关闭站内通知、保留邮件通知，实际 %+v", got)
+	}
+	if got := prefs.Get(models.NotificationEventReply); !got.InApp || !got.Email {
+		t.Fatalf("未修改的类型应保持开启，实际 %+v", got)
+	}
+
+	resp = doRequest(t, router, http.MethodGet, "/api/users/me/notification-preferences", token, nil)
+	decodeData(t, resp, &prefs)
+	if prefs.Get(models.NotificationEventComment).InApp {
+		t.Fatal("修改后重新读取应返回新的偏好")
+	}
+
+	bad := doRequest(t, router, http.MethodPut, "/api/users/me/notification-preferences", token, map[string]interface{}{
+		"preferences": []map[string]interface{}{{"event_type": "unknown", "in_app": false}},
+	})
+	if bad.Status != http.StatusUnprocessableEntity {
+		t.Fatalf("不支持的事件类型应返回422，实际 %d %s", bad.Status, bad.Body)
+	}
+}
+
+func TestBroadcastNotificationSkipsDisabledEventTypes(t *testing.T) {
+	repo, _ := newNotificationPrefRepo(t)
+	disabled := false
+	if _, err := repo.UpdateNotificationPreferences(context.Background(), 2, []models.NotificationPreferenceUpdate{
+		{EventType: models.NotificationEventComment, InApp: &disabled},
+	}); err != nil {
+		t.Fatalf("修改通知偏好失败: %v", err)
+	}
+
+	cfg := newTestConfig()
+	alice := &Client{userID: 1, send: make(chan outboundMessage, 4)}
+	bob := &Client{userID: 2, send: make(chan outboundMessage, 4)}
+	hub := &ConnectionHub{
+		clients:   map[uint][]*Client{1: {alice}, 2: {bob}},
+		prefsRepo: repo,
+		logger:    utils.GetLogger(),
+		config:    &cfg.WebSocket,
+	}
+	received := func(c *Client) []string {
+		var types []string
+		for len(c.send) > 0 {
+			var msg WSMessage
+			if err := json.Unmarshal((<-c.send).data, &msg); err != nil {
+				t.Fatalf("解析通知失败: %v", err)
+			}
+			types = append(types, msg.Type)
+		}
+		return types
+	}
+
+	if err := hub.BroadcastNotification(models.NotificationEventComment, "article_comment", 3, nil); err != nil {
+		t.Fatalf("发送通知失败: %v", err)
+	}
+	if err := hub.BroadcastNotification(models.NotificationEventReply, "article_reply", 3, nil); err != nil {
+		t.Fatalf("发送通知失败: %v", err)
+	}
+
+	if got := received(alice); len(got) != 2 {
+		t.Fatalf("默认偏好的用户应收到全部通知，实际 %v", got)
+	}
+	if got := received(bob); len(got) != 1 || got[0] != "article_reply" {
+		t.Fatalf("关闭 comment 站内通知的用户只应收到回复通知，实际 %v", got)
+	}
+}
+
+func TestDoNotDisturbEndpoint(t *testing.T) {
+	cfg := newTestConfig()
+	repo, store := newNotificationPrefRepo(t)
+	h := NewNotificationPreferenceHandler(repo)
+
+	router := gin.New()
+	auth := router.Group("/api", middleware.AuthMiddleware(cfg, nil, nil))
+	auth.GET("/users/me/notification-dnd", h.GetDoNotDisturb)
+	auth.PUT("/users/me/notification-dnd", h.SetDoNotDisturb)
+	token := signTestJWT(t, cfg, 1, "alice")
+
+	var status models.DoNotDisturbStatus
+	decodeData(t, doRequest(t, router, http.MethodGet, "/api/users/me/notification-dnd", token, nil), &status)
+	if status.Active || status.MuteUntil != nil {
+		t.Fatalf("默认不应开启免打扰，实际 %+v", status)
+	}
+
+	for _, until := range []time.Time{time.Now().Add(-time.Minute), time.Now().Add(31 * 24 * time.Hour)} {
+		resp := doRequest(t, router, http.MethodPut, "/api/users/me/notification-dnd", token, map[string]interface{}{"mute_until": until})
+		if resp.Status != http.StatusUnprocessableEntity {
+			t.Fatalf("截止时间 %v 无效时应返回422，实际 %d %s", until, resp.Status, resp.Body)
+		}
+	}
+
+	resp := doRequest(t, router, http.MethodPut, "/api/users/me/notification-dnd", token, map[string]interface{}{"mute_until": time.Now().Add(time.Hour)})
+	decodeData(t, resp, &status)
+	if resp.Status != http.StatusOK || !status.Active || status.MuteUntil == nil {
+		t.Fatalf("设置免打扰应成功，实际 %d %s", resp.Status, resp.Body)
+	}
+
+	resp = doRequest(t, router, http.MethodPut, "/api/users/me/notification-dnd", token, map[string]interface{}{"mute_until": nil})
+	decodeData(t, resp, &status)
+	if status.Active || len(store.dnd) != 0 {
+		t.Fatalf("mute_until 为空时应关闭免打扰，实际 %+v", status)
+	}
+}
+
+func TestDoNotDisturbSuppressesPushButKeepsInbox(t *testing.T) {
+	cfg := newTestConfig()
+	repo, _ := newNotificationPrefRepo(t)
+	fake, db := newFakeDatabase(t, cfg)
+	fake.OnExec(`INSERT INTO notifications`, 0, 1)
+
+	bob := &Client{userID: 2, send: make(chan outboundMessage, 4), blocked: map[uint]bool{}}
+	previous := globalHub
+	globalHub = &ConnectionHub{
+		clients:    map[uint][]*Client{2: {bob}},
+		prefsRepo:  repo,
+		notifyRepo: services.NewNotificationRepository(db, repo),
+		logger:     utils.GetLogger(),
+		config:     &cfg.WebSocket,
+	}
+	t.Cleanup(func() { globalHub = previous })
+
+	// 截止时间按秒截断，保证至少还有0.5秒
+	status, err := repo.SetDoNotDisturb(context.Background(), 2, time.Now().Add(1500*time.Millisecond))
+	if err != nil || !status.Active {
+		t.Fatalf("设置免打扰失败: %+v %v", status, err)
+	}
+
+	message := &models.MessageResponse{ID: 9, ConversationID: 4, Sender: models.ConversationUser{ID: 1}, Content: "hi"}
+	NotifyPrivateMessage(2, message)
+	if err := globalHub.BroadcastNotification(models.NotificationEventComment, "article_comment", 1, nil); err != nil {
+		t.Fatalf("发送通知失败: %v", err)
+	}
+	if len(bob.send) != 0 {
+		t.Fatal("免打扰期间不应推送实时通知")
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for len(fake.Calls(`INSERT INTO notifications`)) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if calls := fake.Calls(`INSERT INTO notifications`); len(calls) != 1 || calls[0].Args[0] != int64(2) {
+		t.Fatalf("免打扰期间通知仍应写入收件箱，实际 %v", calls)
+	}
+
+	// 到期后自动恢复推送
+	time.Sleep(time.Until(*status.MuteUntil) + 50*time.Millisecond)
+	NotifyPrivateMessage(2, message)
+	if len(bob.send) != 1 {
+		t.Fatal("免打扰到期后应恢复推送")
+	}
+	// 等待第二条收件箱记录写入后再恢复 globalHub，避免与后台任务竞争
+	deadline = time.Now().Add(2 * time.Second)
+	for len(fake.Calls(`INSERT INTO notifications`)) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if calls := fake.Calls(`INSERT INTO notifications`); len(calls) != 2 {
+		t.Fatalf("到期后的私信也应写入收件箱，实际 %d 条", len(calls))
+	}
+}
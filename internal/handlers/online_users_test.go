@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+
+	"gin/internal/services"
+	"gin/internal/testutil"
+	"gin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newOnlineUsersRouter 用户1连接时昵称为 Old，用户2连接后账户已不存在
+func newOnlineUsersRouter(t *testing.T) (*gin.Engine, *testutil.FakeDB) {
+	t.Helper()
+	cfg := newTestConfig()
+	fake, db := newFakeDatabase(t, cfg)
+	userRepo := services.NewUserRepository(db)
+
+	previous := globalHub
+	globalHub = &ConnectionHub{
+		clients: map[uint][]*Client{
+			1: {{userID: 1, username: "alice", nickname: "Old", avatar: "old.png"}},
+			2: {{userID: 2, username: "bob", nickname: "Bob"}},
+		},
+		userRepo: userRepo,
+		logger:   utils.GetLogger(),
+	}
+	t.Cleanup(func() { globalHub = previous })
+
+	router := gin.New()
+	router.GET("/api/chat/online-users", NewChatHandler(nil, userRepo, cfg).GetOnlineUsersWS)
+	return router, fake
+}
+
+// onlineUsersByID 解析在线用户列表，按用户ID索引
+func onlineUsersByID(t *testing.T, resp testResponse) map[float64]map[string]interface{} {
+	t.Helper()
+	if resp.Status != http.StatusOK {
+		t.Fatalf("获取在线用户应成功，实际 %d %s", resp.Status, resp.Body)
+	}
+	var data struct {
+		Users []map[string]interface{} `json:"users"`
+		Count int                      `json:"count"`
+	}
+	decodeData(t, resp, &data)
+	if data.Count != 2 || len(data.Users) != 2 {
+		t.Fatalf("应返回2个在线用户，实际 %+v", data)
+	}
+	users := make(map[float64]map[string]interface{}, len(data.Users))
+	for _, u := range data.Users {
+		users[u["user_id"].(float64)] = u
+	}
+	return users
+}
+
+func TestGetOnlineUsersFreshMode(t *testing.T) {
+	router, fake := newOnlineUsersRouter(t)
+	fake.OnRows(`FROM user_auth ua LEFT JOIN user_profile up ON ua.id = up.user_id WHERE ua.id IN`,
+		[]string{"id", "username", "nickname", "avatar"}, []driver.Value{int64(1), "alice", "New", "new.png"})
+
+	// 默认返回连接时的资料，不查询数据库
+	users := onlineUsersByID(t, doRequest(t, router, http.MethodGet, "/api/chat/online-users", "", nil))
+	if users[1]["nickname"] != "Old" || users[1]["avatar"] != "old.png" {
+		t.Fatalf("默认模式应返回连接时的昵称，实际 %v", users[1])
+	}
+	if calls := fake.Calls(`FROM user_auth`); len(calls) != 0 {
+		t.Fatalf("默认模式不应查询数据库，实际 %d 次", len(calls))
+	}
+
+	users = onlineUsersByID(t, doRequest(t, router, http.MethodGet, "/api/chat/online-users?fresh=true", "", nil))
+	if users[1]["nickname"] != "New" || users[1]["avatar"] != "new.png" {
+		t.Fatalf("fresh 模式应返回更新后的昵称，实际 %v", users[1])
+	}
+	if users[2]["nickname"] != "Bob" {
+		t.Fatalf("数据库中不存在的用户应保留连接时的资料，实际 %v", users[2])
+	}
+
+	calls := fake.Calls(`FROM user_auth`)
+	if len(calls) != 1 || len(calls[0].Args) != 2 {
+		t.Fatalf("应只执行一次批量查询，实际 %v", calls)
+	}
+	ids := []int64{calls[0].Args[0].(int64), calls[0].Args[1].(int64)}
+	slices.Sort(ids)
+	if !slices.Equal(ids, []int64{1, 2}) {
+		t.Fatalf("批量查询参数应为所有在线用户ID，实际 %v", calls[0].Args)
+	}
+}
+
+func TestGetOnlineUsersFreshFallsBackOnError(t *testing.T) {
+	router, fake := newOnlineUsersRouter(t)
+	fake.OnError(`FROM user_auth ua LEFT JOIN user_profile up`, errors.New("connection refused"))
+
+	users := onlineUsersByID(t, doRequest(t, router, http.MethodGet, "/api/chat/online-users?fresh=true", "", nil))
+	if users[1]["nickname"] != "Old" {
+		t.Fatalf("查询失败时应回退到连接时的资料，实际 %v", users[1])
+	}
+}
@@ -235,14 +235,15 @@ func (h *PrivateMessageHandler) GetMessages(c *gin.Context) {
 
 	// 使用Worker Pool标记消息为已读（避免goroutine泄漏）
 	taskID := fmt.Sprintf("mark_read_%d_%d", conversationID, userID)
-	_ = utils.SubmitTask(taskID, func(taskCtx context.Context) error {
-		h.logger.Info("Marking messages as read (async task)",
+	_ = utils.SubmitTaskWithContext(c.Request.Context(), taskID, func(taskCtx context.Context) error {
+		logger := utils.ContextLogger(taskCtx, h.logger)
+		logger.Info("Marking messages as read (async task)",
 			"conversationID", conversationID,
 			"userID", userID)
 
 		hasUpdates, err := h.msgRepo.MarkAsRead(taskCtx, uint(conversationID), userID)
 		if err != nil {
-			h.logger.Error("Failed to mark messages as read",
+			logger.Error("Failed to mark messages as read",
 				"conversationID", conversationID,
 				"userID", userID,
 				"error", err.Error())
@@ -257,7 +258,7 @@ func (h *PrivateMessageHandler) GetMessages(c *gin.Context) {
 				otherUserID = conv.User2ID
 			}
 
-			h.logger.Info("Notifying sender about message read",
+			logger.Info("Notifying sender about message read",
 				"senderID", otherUserID,
 				"readerID", userID,
 				"conversationID", conversationID,
@@ -265,7 +266,7 @@ func (h *PrivateMessageHandler) GetMessages(c *gin.Context) {
 
 			NotifyMessageRead(otherUserID, uint(conversationID), userID)
 		} else {
-			h.logger.Debug("No unread messages to mark, skipping notification",
+			logger.Debug("No unread messages to mark, skipping notification",
 				"conversationID", conversationID,
 				"userID", userID)
 		}
@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gin/internal/config"
+	"gin/internal/middleware"
+	"gin/internal/services"
+	"gin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// prometheusContentType Prometheus文本格式的Content-Type
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// promBufferPool 复用输出缓冲区，减少抓取时的内存分配
+var promBufferPool = sync.Pool{
+	New: func() interface{} {
+		return bytes.NewBuffer(make([]byte, 0, 4096))
+	},
+}
+
+// labelValueEscaper 转义标签值中的反斜杠、双引号和换行
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// PrometheusHandler Prometheus指标处理器
+type PrometheusHandler struct {
+	db       *services.Database
+	cacheSvc *services.CacheService
+	prefix   string
+}
+
+// NewPrometheusHandler 创建Prometheus指标处理器
+func NewPrometheusHandler(db *services.Database, cacheSvc *services.CacheService, cfg *config.Config) *PrometheusHandler {
+	prefix := cfg.Metrics.PrometheusPrefix
+	if prefix != "" && !strings.HasSuffix(prefix, "_") {
+		prefix += "_"
+	}
+	return &PrometheusHandler{
+		db:       db,
+		cacheSvc: cacheSvc,
+		prefix:   prefix,
+	}
+}
+
+// promWriter 以Prometheus文本格式写入指标
+type promWriter struct {
+	buf    *bytes.Buffer
+	prefix string
+}
+
+// header 写入 HELP 和 TYPE 行
+func (w *promWriter) header(name, metricType, help string) {
+	w.buf.WriteString("# HELP ")
+	w.buf.WriteString(w.prefix)
+	w.buf.WriteString(name)
+	w.buf.WriteByte(' ')
+	w.buf.WriteString(help)
+	w.buf.WriteString("\n# TYPE ")
+	w.buf.WriteString(w.prefix)
+	w.buf.WriteString(name)
+	w.buf.WriteByte(' ')
+	w.buf.WriteString(metricType)
+	w.buf.WriteByte('\n')
+}
+
+// sample 写入不带标签的样本
+func (w *promWriter) sample(name string, value int64) {
+	w.buf.WriteString(w.prefix)
+	w.buf.WriteString(name)
+	w.buf.WriteByte(' ')
+	w.buf.Write(strconv.AppendInt(w.buf.AvailableBuffer(), value, 10))
+	w.buf.WriteByte('\n')
+}
+
+// sampleFloat 写入不带标签的浮点样本
+func (w *promWriter) sampleFloat(name string, value float64) {
+	w.buf.WriteString(w.prefix)
+	w.buf.WriteString(name)
+	w.buf.WriteByte(' ')
+	w.buf.Write(strconv.AppendFloat(w.buf.AvailableBuffer(), value, 'g', -1, 64))
+	w.buf.WriteByte('\n')
+}
+
+// labeledSample 写入带单个标签的样本
+func (w *promWriter) labeledSample(name, label, labelValue string, value int64) {
+	w.buf.WriteString(w.prefix)
+	w.buf.WriteString(name)
+	w.buf.WriteByte('{')
+	w.buf.WriteString(label)
+	w.buf.WriteString(`="`)
+	labelValueEscaper.WriteString(w.buf, labelValue)
+	w.buf.WriteString(`"} `)
+	w.buf.Write(strconv.AppendInt(w.buf.AvailableBuffer(), value, 10))
+	w.buf.WriteByte('\n')
+}
+
+// labeledSampleFloat 写入带单个标签的浮点样本
+func (w *promWriter) labeledSampleFloat(name, label, labelValue string, value float64) {
+	w.buf.WriteString(w.prefix)
+	w.buf.WriteString(name)
+	w.buf.WriteByte('{')
+	w.buf.WriteString(label)
+	w.buf.WriteString(`="`)
+	labelValueEscaper.WriteString(w.buf, labelValue)
+	w.buf.WriteString(`"} `)
+	w.buf.Write(strconv.AppendFloat(w.buf.AvailableBuffer(), value, 'g', -1, 64))
+	w.buf.WriteByte('\n')
+}
+
+// labelsSampleFloat 写入带多个标签的浮点样本（labels 为 name, value 交替排列）
+func (w *promWriter) labelsSampleFloat(name string, value float64, labels ...string) {
+	w.buf.WriteString(w.prefix)
+	w.buf.WriteString(name)
+	w.buf.WriteByte('{')
+	for i := 0; i+1 < len(labels); i += 2 {
+		if i > 0 {
+			w.buf.WriteByte(',')
+		}
+		w.buf.WriteString(labels[i])
+		w.buf.WriteString(`="`)
+		labelValueEscaper.WriteString(w.buf, labels[i+1])
+		w.buf.WriteByte('"')
+	}
+	w.buf.WriteString("} ")
+	w.buf.Write(strconv.AppendFloat(w.buf.AvailableBuffer(), value, 'g', -1, 64))
+	w.buf.WriteByte('\n')
+}
+
+// gauge 写入单值gauge指标
+func (w *promWriter) gauge(name, help string, value int64) {
+	w.header(name, "gauge", help)
+	w.sample(name, value)
+}
+
+// counter 写入单值counter指标
+func (w *promWriter) counter(name, help string, value int64) {
+	w.header(name, "counter", help)
+	w.sample(name, value)
+}
+
+// Metrics 以Prometheus文本格式输出运行指标
+func (h *PrometheusHandler) Metrics(c *gin.Context) {
+	buf := promBufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer promBufferPool.Put(buf)
+
+	w := &promWriter{buf: buf, prefix: h.prefix}
+
+	// HTTP请求指标（进程启动以来）
+	metrics := middleware.GetMetrics().GetMetrics()
+	w.counter("http_requests_total", "Total HTTP requests handled since start.", metrics.RequestCount)
+	w.counter("http_request_errors_total", "Total HTTP requests with status >= 400 since start.", metrics.ErrorCount)
+	w.header("http_request_duration_seconds_total", "counter", "Total HTTP request latency in seconds since start.")
+	w.sampleFloat("http_request_duration_seconds_total", metrics.TotalLatency.Seconds())
+
+	// 按接口的今日请求数（每日零点UTC重置）
+	dailyMgr := services.GetDailyMetricsManager()
+	w.header("endpoint_requests_today_total", "counter", "Requests per endpoint since 00:00 UTC.")
+	dailyMgr.RangeEndpointCalls(func(endpoint string, calls int64) {
+		w.labeledSample("endpoint_requests_today_total", "endpoint", endpoint, calls)
+	})
+	_, success, errs := dailyMgr.GetRequestTotals()
+	w.counter("requests_today_success_total", "Successful (2xx) requests since 00:00 UTC.", success)
+	w.counter("requests_today_error_total", "Failed (>= 400) requests since 00:00 UTC.", errs)
+
+	// 数据库连接池
+	if h.db != nil {
+		stats := h.db.GetStats()
+		w.gauge("db_max_open_connections", "Maximum number of open database connections.", int64(stats.MaxOpenConnections))
+		w.gauge("db_open_connections", "Number of established database connections.", int64(stats.OpenConnections))
+		w.gauge("db_in_use", "Number of database connections currently in use.", int64(stats.InUse))
+		w.gauge("db_idle", "Number of idle database connections.", int64(stats.Idle))
+		w.counter("db_wait_count_total", "Total number of connections waited for.", stats.WaitCount)
+		w.header("db_wait_duration_seconds_total", "counter", "Total time blocked waiting for a new connection.")
+		w.sampleFloat("db_wait_duration_seconds_total", stats.WaitDuration.Seconds())
+
+		// 最近一个监控周期的趋势
+		trend := h.db.GetPoolTrend()
+		w.gauge("db_in_use_peak", "Peak in-use database connections during the last pool monitor interval.", int64(trend.PeakInUse))
+		w.gauge("db_wait_count_interval", "Connections waited for during the last pool monitor interval.", trend.WaitCountDelta)
+		w.header("db_wait_duration_seconds_interval", "gauge", "Time blocked waiting for connections during the last pool monitor interval.")
+		w.sampleFloat("db_wait_duration_seconds_interval", trend.WaitDurationDelta.Seconds())
+		w.gauge("db_suggested_max_open_connections", "Suggested max open connections based on the last interval (0 when no change is needed).", int64(trend.SuggestedMaxOpenConn))
+	}
+
+	// 慢查询
+	slowStats := utils.GetGlobalSlowQueryDetector().GetStats()
+	w.counter("db_queries_total", "Total database queries recorded by the slow query detector.", int64(slowStats.TotalQueries))
+	w.counter("db_slow_queries_total", "Total database queries exceeding the slow query threshold.", int64(slowStats.SlowQueries))
+
+	// 分类/标签列表缓存命中率
+	if h.cacheSvc != nil {
+		taxonomy := h.cacheSvc.TaxonomyCacheStats()
+		names := make([]string, 0, len(taxonomy))
+		for name := range taxonomy {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		w.header("list_cache_hits_total", "counter", "Category/tag list cache hits since start.")
+		for _, name := range names {
+			w.labeledSample("list_cache_hits_total", "cache", name, int64(taxonomy[name].Hits))
+		}
+		w.header("list_cache_misses_total", "counter", "Category/tag list cache misses since start.")
+		for _, name := range names {
+			w.labeledSample("list_cache_misses_total", "cache", name, int64(taxonomy[name].Misses))
+		}
+		w.header("list_cache_hit_ratio", "gauge", "Category/tag list cache hit ratio (0-1) since start.")
+		for _, name := range names {
+			w.labeledSampleFloat("list_cache_hit_ratio", "cache", name, taxonomy[name].HitRatio)
+		}
+	}
+
+	// 异步任务 Worker Pool
+	h.writeWorkerPoolMetrics(w)
+
+	// WebSocket在线用户
+	w.gauge("ws_online_users", "Number of users connected to the chat WebSocket.", int64(GetHubOnlineCount()))
+	w.gauge("ws_connections", "Number of open chat WebSocket connections, including connections being upgraded.", int64(GetHubConnectionCount()))
+
+	c.Data(200, prometheusContentType, buf.Bytes())
+}
+
+// writeWorkerPoolMetrics 输出全局 Worker Pool 的队列和按任务类型的执行指标
+func (h *PrometheusHandler) writeWorkerPoolMetrics(w *promWriter) {
+	pool := utils.GetGlobalPool()
+	poolMetrics := pool.GetMetrics()
+	w.gauge("worker_pool_workers", "Number of running worker pool goroutines.", int64(poolMetrics.ActiveWorkers))
+	w.gauge("worker_pool_queue_length", "Number of tasks waiting in the worker pool queue.", int64(poolMetrics.QueuedTasks))
+	w.gauge("worker_pool_queue_capacity", "Capacity of the worker pool queue.", int64(poolMetrics.QueueCapacity))
+
+	types := pool.GetTaskTypeMetrics()
+	typeGauge := func(name, help string, value func(m *utils.TaskTypeMetrics) int64) {
+		w.header(name, "gauge", help)
+		for i := range types {
+			w.labeledSample(name, "task_type", types[i].Type, value(&types[i]))
+		}
+	}
+	typeCounter := func(name, help string, value func(m *utils.TaskTypeMetrics) uint64) {
+		w.header(name, "counter", help)
+		for i := range types {
+			w.labeledSample(name, "task_type", types[i].Type, int64(value(&types[i])))
+		}
+	}
+	typeGauge("worker_pool_tasks_queued", "Tasks currently queued, by task type.",
+		func(m *utils.TaskTypeMetrics) int64 { return m.Queued })
+	typeGauge("worker_pool_tasks_running", "Tasks currently running, by task type.",
+		func(m *utils.TaskTypeMetrics) int64 { return m.Running })
+	typeCounter("worker_pool_tasks_submitted_total", "Tasks accepted into the queue, by task type.",
+		func(m *utils.TaskTypeMetrics) uint64 { return m.Submitted })
+	typeCounter("worker_pool_tasks_rejected_total", "Tasks rejected because the queue was full, by task type.",
+		func(m *utils.TaskTypeMetrics) uint64 { return m.Rejected })
+	typeCounter("worker_pool_tasks_completed_total", "Tasks finished successfully, by task type.",
+		func(m *utils.TaskTypeMetrics) uint64 { return m.Completed })
+	typeCounter("worker_pool_tasks_failed_total", "Tasks that returned an error or panicked, by task type.",
+		func(m *utils.TaskTypeMetrics) uint64 { return m.Failed })
+	typeCounter("worker_pool_tasks_timeout_total", "Tasks that exceeded their timeout, by task type.",
+		func(m *utils.TaskTypeMetrics) uint64 { return m.Timeout })
+
+	const durationName = "worker_pool_task_duration_seconds"
+	w.header(durationName, "histogram", "Task execution duration in seconds, by task type.")
+	for i := range types {
+		m := &types[i]
+		for b, bound := range utils.TaskDurationBuckets {
+			w.labelsSampleFloat(durationName+"_bucket", float64(m.DurationBuckets[b]),
+				"task_type", m.Type, "le", strconv.FormatFloat(bound, 'g', -1, 64))
+		}
+		w.labelsSampleFloat(durationName+"_bucket", float64(m.DurationCount), "task_type", m.Type, "le", "+Inf")
+		w.labeledSampleFloat(durationName+"_sum", "task_type", m.Type, m.DurationSum)
+		w.labeledSample(durationName+"_count", "task_type", m.Type, int64(m.DurationCount))
+	}
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gin/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPrometheusMetricsOutput(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Metrics.PrometheusPrefix = "shequ"
+	_, db := newFakeDatabase(t, cfg)
+	services.GetDailyMetricsManager().RecordRequest(`GET /api/a"b`, 5, true, false)
+
+	router := gin.New()
+	router.GET("/metrics", NewPrometheusHandler(db, nil, cfg).Metrics)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Fatalf("应返回Prometheus文本格式，实际 %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	body := w.Body.String()
+	for _, name := range []string{"db_open_connections", "db_in_use", "db_in_use_peak", "db_wait_count_interval",
+		"db_suggested_max_open_connections", "ws_online_users", "http_requests_total", "db_slow_queries_total"} {
+		if !strings.Contains(body, "\nshequ_"+name+" ") {
+			t.Errorf("缺少指标 shequ_%s", name)
+		}
+		if !strings.Contains(body, "# TYPE shequ_"+name+" ") {
+			t.Errorf("缺少 shequ_%s 的 TYPE 行", name)
+		}
+	}
+	if !strings.Contains(body, `shequ_endpoint_requests_today_total{endpoint="GET /api/a\"b"} `) {
+		t.Errorf("按接口的请求数应转义标签值:\n%s", body)
+	}
+
+	// 每个样本行都是 "名称[{标签}] 值"，且都带有前缀
+	for _, line := range strings.Split(strings.TrimSuffix(body, "\n"), "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !strings.HasPrefix(line, "shequ_") || strings.LastIndexByte(line, ' ') <= 0 {
+			t.Errorf("格式错误的样本行: %q", line)
+		}
+	}
+}
+
+func TestPrometheusPrefixOptional(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Metrics.PrometheusPrefix = "app_"
+	if h := NewPrometheusHandler(nil, nil, cfg); h.prefix != "app_" {
+		t.Fatalf("已带下划线的前缀不应重复追加，实际 %q", h.prefix)
+	}
+	cfg.Metrics.PrometheusPrefix = ""
+	if h := NewPrometheusHandler(nil, nil, cfg); h.prefix != "" {
+		t.Fatalf("空前缀不应追加下划线，实际 %q", h.prefix)
+	}
+}
+
+func TestPrometheusListCacheMetrics(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Metrics.PrometheusPrefix = "shequ"
+	fake, db := newFakeDatabase(t, cfg)
+	fake.OnRows(`FROM article_categories ORDER BY`, []string{"id"})
+	fake.OnRows(`FROM article_tags ORDER BY`, []string{"id"})
+	cacheSvc := services.NewCacheService(services.NewArticleRepository(db, cfg), cfg)
+	for i := 0; i < 3; i++ {
+		_, _ = cacheSvc.GetArticleTags(context.Background())
+	}
+	stats := cacheSvc.TaxonomyCacheStats()["article_tags"]
+
+	router := gin.New()
+	router.GET("/metrics", NewPrometheusHandler(db, cacheSvc, cfg).Metrics)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body := w.Body.String()
+	for _, line := range []string{
+		"# TYPE shequ_list_cache_hit_ratio gauge",
+		fmt.Sprintf(`shequ_list_cache_hits_total{cache="article_tags"} %d`, stats.Hits),
+		fmt.Sprintf(`shequ_list_cache_misses_total{cache="article_tags"} %d`, stats.Misses),
+		fmt.Sprintf(`shequ_list_cache_hit_ratio{cache="article_tags"} %g`, stats.HitRatio),
+		`shequ_list_cache_hits_total{cache="article_categories"} `,
+	} {
+		if !strings.Contains(body, line) {
+			t.Errorf("缺少列表缓存指标 %q:\n%s", line, body)
+		}
+	}
+	if stats.Hits+stats.Misses != 3 {
+		t.Fatalf("命中统计应覆盖全部请求，实际 %+v", stats)
+	}
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
 	"time"
@@ -19,18 +20,24 @@ type ResourceHandler struct {
 	resourceRepo        *services.ResourceRepository
 	resourceCommentRepo *services.ResourceCommentRepository
 	resourceImageSvc    *services.ResourceImageService // 资源图片服务
+	multiBucket         *services.MultiBucketStorage   // 多桶存储（生成预签名下载URL）
+	uploadMgr           *services.UploadManager        // 分片上传（确认文件已通过安全扫描）
 	userRepo            *services.UserRepository
+	viewDedup           *services.ViewDeduplicator // 浏览次数去重
 	logger              utils.Logger
 	config              *config.Config
 }
 
 // NewResourceHandler 创建资源处理器（7桶架构）
-func NewResourceHandler(resourceRepo *services.ResourceRepository, resourceCommentRepo *services.ResourceCommentRepository, resourceImageSvc *services.ResourceImageService, userRepo *services.UserRepository, cfg *config.Config) *ResourceHandler {
+func NewResourceHandler(resourceRepo *services.ResourceRepository, resourceCommentRepo *services.ResourceCommentRepository, resourceImageSvc *services.ResourceImageService, multiBucket *services.MultiBucketStorage, uploadMgr *services.UploadManager, userRepo *services.UserRepository, viewDedup *services.ViewDeduplicator, cfg *config.Config) *ResourceHandler {
 	return &ResourceHandler{
 		resourceRepo:        resourceRepo,
 		resourceCommentRepo: resourceCommentRepo,
 		resourceImageSvc:    resourceImageSvc,
+		multiBucket:         multiBucket,
+		uploadMgr:           uploadMgr,
 		userRepo:            userRepo,
+		viewDedup:           viewDedup,
 		logger:              utils.GetLogger(),
 		config:              cfg,
 	}
@@ -72,18 +79,32 @@ func (h *ResourceHandler) CreateResource(c *gin.Context) {
 		return
 	}
 
-	// 提取文件扩展名
-	fileExt := ""
-	for i := len(req.FileName) - 1; i >= 0; i-- {
-		if req.FileName[i] == '.' {
-			fileExt = req.FileName[i+1:]
-			break
+	// 验证预览图数量和来源
+	if h.resourceImageSvc != nil {
+		maxImages := h.config.BucketResourcePreviews.MaxImagesPerResource
+		if err := h.resourceImageSvc.ValidateImageURLs(req.ImageURLs, maxImages); err != nil {
+			h.logger.Warn("预览图校验失败", "userID", userID, "imageCount", len(req.ImageURLs), "error", err.Error())
+			utils.ErrorResponse(c, utils.GetHTTPStatusCode(err), err.Error())
+			return
 		}
 	}
-	if fileExt == "" {
-		fileExt = "unknown"
+
+	// 未分片上传时 storage_path 即下载地址，必须在跳转白名单内；分片上传必须已合并并通过安全扫描
+	if req.TotalChunks == 0 {
+		if _, err := utils.ValidateRedirectTarget(req.StoragePath); err != nil {
+			h.logger.Warn("资源下载地址不在白名单内", "userID", userID, "storagePath", req.StoragePath)
+			utils.BadRequestResponse(c, "无效的资源存储地址")
+			return
+		}
+	} else if err := h.uploadMgr.EnsureUploadScanned(c.Request.Context(), req.StoragePath, userID); err != nil {
+		h.logger.Warn("资源文件未通过上传检查", "userID", userID, "storagePath", req.StoragePath, "error", err.Error())
+		utils.AppErrorResponse(c, err, "创建资源失败")
+		return
 	}
 
+	// 提取文件扩展名
+	fileExt := resourceFileExtension(req.FileName)
+
 	// 创建资源对象
 	resource := &models.Resource{
 		UserID:        userID,
@@ -115,19 +136,23 @@ func (h *ResourceHandler) CreateResource(c *gin.Context) {
 
 	// 如果有临时图片URL，移动到正式目录（7桶架构）
 	finalImageURLs := req.ImageURLs
+	var thumbnailURLs []string
 	if len(req.ImageURLs) > 0 && h.resourceImageSvc != nil {
-		movedURLs, err := h.resourceImageSvc.MovePreviewImagesToFormal(ctx, req.ImageURLs, resource.ID)
+		movedURLs, movedThumbnails, err := h.resourceImageSvc.MovePreviewImagesToFormal(ctx, req.ImageURLs, resource.ID)
 		if err != nil {
 			h.logger.Warn("移动资源图片失败", "resourceID", resource.ID, "error", err.Error())
 			// 不中断创建流程，使用原始URL
 		} else {
 			finalImageURLs = movedURLs
+			thumbnailURLs = movedThumbnails
 			h.logger.Info("成功移动资源图片", "resourceID", resource.ID, "count", len(movedURLs))
 		}
 
 		// 更新资源的图片记录
 		if len(finalImageURLs) > 0 {
-			_ = h.resourceRepo.UpdateResourceImages(ctx, resource.ID, finalImageURLs)
+			if err := h.resourceRepo.UpdateResourceImages(ctx, resource.ID, finalImageURLs, thumbnailURLs); err != nil {
+				h.logger.Warn("保存资源图片失败", "resourceID", resource.ID, "error", err.Error())
+			}
 		}
 	}
 
@@ -141,7 +166,7 @@ func (h *ResourceHandler) CreateResource(c *gin.Context) {
 			h.logger.Warn("获取完整资源信息失败，无法发送WebSocket通知", "resourceID", resource.ID, "error", err.Error())
 			return
 		}
-		NotifyNewResource(fullResource)
+		NotifyNewResource(resource.UserID, fullResource)
 	}()
 
 	utils.SuccessResponse(c, 201, "创建成功", gin.H{
@@ -149,6 +174,103 @@ func (h *ResourceHandler) CreateResource(c *gin.Context) {
 	})
 }
 
+// resourceFileExtension 提取文件扩展名（无扩展名时为 unknown）
+func resourceFileExtension(fileName string) string {
+	for i := len(fileName) - 1; i >= 0; i-- {
+		if fileName[i] == '.' && i < len(fileName)-1 {
+			return fileName[i+1:]
+		}
+	}
+	return "unknown"
+}
+
+// AddResourceVersion 作者上传资源新版本（旧版本仍可通过 ?version= 下载）
+func (h *ResourceHandler) AddResourceVersion(c *gin.Context) {
+	userID, isOK := getUserIDOrFail(c)
+	if !isOK {
+		return
+	}
+	resourceID, ok := parseUintParam(c, "id", "无效的资源ID")
+	if !ok {
+		return
+	}
+
+	var req models.AddResourceVersionRequest
+	if !bindJSONOrFail(c, &req, h.logger, "AddResourceVersion") {
+		return
+	}
+
+	maxSizeBytes := int64(h.config.FileUpload.MaxResourceSizeMB) * 1024 * 1024
+	if maxSizeBytes > 0 && req.FileSize > maxSizeBytes {
+		utils.BadRequestResponse(c, fmt.Sprintf("文件过大！当前文件 %.2fMB，最大支持 %dMB",
+			float64(req.FileSize)/(1024*1024), h.config.FileUpload.MaxResourceSizeMB))
+		return
+	}
+
+	// 未分片上传时 storage_path 即下载地址，必须在跳转白名单内；分片上传必须已合并并通过安全扫描
+	if req.TotalChunks == 0 {
+		if _, err := utils.ValidateRedirectTarget(req.StoragePath); err != nil {
+			h.logger.Warn("资源下载地址不在白名单内", "userID", userID, "storagePath", req.StoragePath)
+			utils.BadRequestResponse(c, "无效的资源存储地址")
+			return
+		}
+	} else if err := h.uploadMgr.EnsureUploadScanned(c.Request.Context(), req.StoragePath, userID); err != nil {
+		h.logger.Warn("资源文件未通过上传检查", "userID", userID, "storagePath", req.StoragePath, "error", err.Error())
+		utils.AppErrorResponse(c, err, "创建资源失败")
+		return
+	}
+
+	version, err := h.resourceRepo.AddVersion(c.Request.Context(), resourceID, userID, req, resourceFileExtension(req.FileName))
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrResourceNotFound):
+			utils.NotFoundResponse(c, "资源不存在")
+		case errors.Is(err, utils.ErrUnauthorized):
+			utils.ErrorResponse(c, 403, "只能更新自己的资源")
+		default:
+			utils.ErrorResponse(c, utils.GetHTTPStatusCode(err), "上传新版本失败")
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, 201, "上传新版本成功", version)
+}
+
+// applyRequestedVersion 按 ?version= 把资源的文件字段替换为指定版本（未指定时使用当前版本）
+// 版本不存在时直接写入错误响应并返回 false
+func (h *ResourceHandler) applyRequestedVersion(c *gin.Context, resource *models.ResourceDetailResponse) bool {
+	versionStr := c.Query("version")
+	if versionStr == "" {
+		return true
+	}
+	versionNo, err := strconv.Atoi(versionStr)
+	if err != nil || versionNo <= 0 {
+		utils.BadRequestResponse(c, "无效的版本号")
+		return false
+	}
+	if versionNo == resource.CurrentVersion {
+		return true
+	}
+
+	version, err := h.resourceRepo.GetResourceVersion(c.Request.Context(), resource.ID, versionNo)
+	if err != nil {
+		if errors.Is(err, utils.ErrResourceNotFound) {
+			utils.NotFoundResponse(c, "版本不存在")
+			return false
+		}
+		utils.ErrorResponse(c, utils.GetHTTPStatusCode(err), "获取资源版本失败")
+		return false
+	}
+
+	resource.FileName = version.FileName
+	resource.FileSize = version.FileSize
+	resource.FileHash = version.FileHash
+	resource.StoragePath = version.StoragePath
+	resource.TotalChunks = version.TotalChunks
+	resource.CurrentVersion = version.VersionNo
+	return true
+}
+
 // GetResourceDetail 获取资源详情
 func (h *ResourceHandler) GetResourceDetail(c *gin.Context) {
 	resourceIDStr := c.Param("id")
@@ -169,14 +291,16 @@ func (h *ResourceHandler) GetResourceDetail(c *gin.Context) {
 		return
 	}
 
-	// 使用Worker Pool异步增加浏览次数（避免goroutine泄漏）
-	taskID := fmt.Sprintf("incr_resource_view_%d", resourceID)
-	err = utils.SubmitTask(taskID, func(taskCtx context.Context) error {
-		return h.resourceRepo.IncrementViewCount(taskCtx, uint(resourceID))
-	}, time.Duration(h.config.AsyncTasks.ResourceViewCountTimeout)*time.Second)
+	// 使用Worker Pool异步增加浏览次数（避免goroutine泄漏），同一访客在去重窗口内重复浏览不计数
+	if h.viewDedup.ShouldCount(services.ViewTargetResource, uint(resourceID), userID, c.ClientIP()) {
+		taskID := fmt.Sprintf("incr_resource_view_%d", resourceID)
+		err = utils.SubmitTaskWithContext(ctx, taskID, func(taskCtx context.Context) error {
+			return h.resourceRepo.IncrementViewCount(taskCtx, uint(resourceID))
+		}, time.Duration(h.config.AsyncTasks.ResourceViewCountTimeout)*time.Second)
 
-	if err != nil {
-		h.logger.Debug("提交浏览次数更新任务失败", "resourceID", resourceID, "error", err.Error())
+		if err != nil {
+			h.logger.Debug("提交浏览次数更新任务失败", "resourceID", resourceID, "error", err.Error())
+		}
 	}
 
 	h.logger.Info("获取资源详情成功", "resourceID", resourceID)
@@ -222,7 +346,7 @@ func (h *ResourceHandler) ToggleResourceLike(c *gin.Context) {
 	isLiked, err := h.resourceRepo.ToggleResourceLike(ctx, uint(resourceID), userID)
 	if err != nil {
 		h.logger.Error("切换点赞失败", "resourceID", resourceID, "error", err.Error())
-		utils.ErrorResponse(c, 500, "操作失败")
+		utils.AppErrorResponse(c, err, "操作失败")
 		return
 	}
 
@@ -231,6 +355,23 @@ func (h *ResourceHandler) ToggleResourceLike(c *gin.Context) {
 	})
 }
 
+// GetResourceLikers 获取资源的点赞用户 ?page=&page_size=
+func (h *ResourceHandler) GetResourceLikers(c *gin.Context) {
+	resourceID, isOK := parseUintParam(c, "id", "无效的资源ID")
+	if !isOK {
+		return
+	}
+
+	page, pageSize := parsePageParams(c, &h.config.Pagination)
+	response, err := h.resourceRepo.GetResourceLikers(c.Request.Context(), resourceID, page, pageSize)
+	if err != nil {
+		utils.AppErrorResponse(c, err, "获取点赞用户失败")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "获取成功", response)
+}
+
 // DeleteResource 删除资源
 func (h *ResourceHandler) DeleteResource(c *gin.Context) {
 	userID, isOK := getUserIDOrFail(c)
@@ -283,6 +424,39 @@ func (h *ResourceHandler) DeleteResource(c *gin.Context) {
 	utils.SuccessResponse(c, 200, "删除成功", nil)
 }
 
+// BatchDeleteResources 批量删除自己的资源（任一资源不属于自己时整批失败）
+func (h *ResourceHandler) BatchDeleteResources(c *gin.Context) {
+	userID, isOK := getUserIDOrFail(c)
+	if !isOK {
+		return
+	}
+
+	ids, isOK := bindBatchIDsOrFail(c, h.config.BatchOperations.MaxDeleteItems)
+	if !isOK {
+		return
+	}
+
+	deleted, err := h.resourceRepo.DeleteResourcesByUser(c.Request.Context(), userID, ids)
+	if err != nil {
+		h.logger.Warn("批量删除资源失败", "userID", userID, "count", len(ids), "error", err.Error())
+		utils.AppErrorResponse(c, err, "批量删除失败")
+		return
+	}
+
+	// 异步删除预览图
+	if h.resourceImageSvc != nil {
+		go func() {
+			bgCtx := context.Background()
+			for _, id := range deleted {
+				_ = h.resourceImageSvc.DeleteResourceImages(bgCtx, id)
+			}
+		}()
+	}
+
+	h.logger.Info("批量删除资源成功", "userID", userID, "count", len(deleted))
+	utils.SuccessResponse(c, 200, "删除成功", models.BatchDeleteResponse{DeletedIDs: deleted})
+}
+
 // DownloadResource 下载资源（返回直接下载链接）
 func (h *ResourceHandler) DownloadResource(c *gin.Context) {
 	resourceIDStr := c.Param("id")
@@ -298,12 +472,9 @@ func (h *ResourceHandler) DownloadResource(c *gin.Context) {
 		utils.ErrorResponse(c, 404, "资源不存在")
 		return
 	}
-
-	// Increment download count asynchronously using Worker Pool
-	taskID := fmt.Sprintf("incr_download_%d", resourceID)
-	_ = utils.SubmitTask(taskID, func(taskCtx context.Context) error {
-		return h.resourceRepo.IncrementDownloadCount(taskCtx, uint(resourceID))
-	}, time.Duration(h.config.AsyncTasks.ResourceDownloadCountTimeout)*time.Second)
+	if !h.applyRequestedVersion(c, resource) {
+		return
+	}
 
 	// Return download URL for client to download directly from MinIO
 	// 直接返回下载链接比代理更高效
@@ -311,6 +482,18 @@ func (h *ResourceHandler) DownloadResource(c *gin.Context) {
 	if resource.TotalChunks > 0 {
 		downloadURL = fmt.Sprintf("%s/%s", h.config.BucketResourceChunks.PublicBaseURL, resource.StoragePath)
 	}
+	downloadURL, err = utils.ValidateRedirectTarget(downloadURL)
+	if err != nil {
+		h.logger.Warn("资源下载地址不在白名单内", "resourceID", resourceID, "storagePath", resource.StoragePath)
+		utils.ErrorResponse(c, 403, "下载地址无效")
+		return
+	}
+
+	// Increment download count asynchronously using Worker Pool
+	taskID := fmt.Sprintf("incr_download_%d", resourceID)
+	_ = utils.SubmitTaskWithContext(ctx, taskID, func(taskCtx context.Context) error {
+		return h.resourceRepo.IncrementDownloadCount(taskCtx, uint(resourceID))
+	}, time.Duration(h.config.AsyncTasks.ResourceDownloadCountTimeout)*time.Second)
 
 	utils.SuccessResponse(c, 200, "获取下载链接成功", gin.H{
 		"download_url": downloadURL,
@@ -318,9 +501,93 @@ func (h *ResourceHandler) DownloadResource(c *gin.Context) {
 		"file_name":    resource.FileName,
 		"file_size":    resource.FileSize,
 		"file_hash":    resource.FileHash,
+		"version_no":   resource.CurrentVersion,
 	})
 }
 
+// GetResourceDownloadURL 获取资源的限时预签名下载URL（客户端直接从MinIO下载，不占用应用带宽）
+// 分片资源返回每个分片的预签名URL；每次签发计一次下载
+func (h *ResourceHandler) GetResourceDownloadURL(c *gin.Context) {
+	userID, isOK := getUserIDOrFail(c)
+	if !isOK {
+		return
+	}
+	resourceID, ok := parseUintParam(c, "id", "无效的资源ID")
+	if !ok {
+		return
+	}
+	if h.multiBucket == nil {
+		utils.InternalServerErrorResponse(c, "存储服务未配置")
+		return
+	}
+
+	// GetResourceByID 不返回已删除的资源；审核中的资源只有作者可以下载
+	ctx := c.Request.Context()
+	resource, err := h.resourceRepo.GetResourceByID(ctx, resourceID, userID)
+	if err != nil {
+		if errors.Is(err, utils.ErrUserNotFound) {
+			utils.NotFoundResponse(c, "资源不存在")
+			return
+		}
+		utils.ErrorResponse(c, utils.GetHTTPStatusCode(err), "获取资源失败")
+		return
+	}
+	if resource.Status != models.ResourceStatusNormal && resource.UserID != userID {
+		utils.NotFoundResponse(c, "资源不存在")
+		return
+	}
+	if !h.applyRequestedVersion(c, resource) {
+		return
+	}
+
+	expiry := time.Duration(h.config.MinioAdvanced.PresignedExpirySec) * time.Second
+	expiresAt := time.Now().UTC().Add(expiry)
+	data := gin.H{
+		"total_chunks": resource.TotalChunks,
+		"file_name":    resource.FileName,
+		"file_size":    resource.FileSize,
+		"file_hash":    resource.FileHash,
+		"version_no":   resource.CurrentVersion,
+		"expires_at":   expiresAt,
+	}
+
+	if resource.TotalChunks > 0 {
+		// storage_path 存储的是 upload_id，分片位于 resource-chunks 桶
+		chunkURLs := make([]string, resource.TotalChunks)
+		for i := range chunkURLs {
+			chunkURLs[i], err = h.multiBucket.PresignGetObject(ctx, services.BucketTypeResourceChunks,
+				fmt.Sprintf("%s/chunk_%d", resource.StoragePath, i), expiry)
+			if err != nil {
+				utils.InternalServerErrorResponse(c, "生成下载链接失败")
+				return
+			}
+		}
+		data["chunk_urls"] = chunkURLs
+	} else {
+		bucketType, objectPath, found := h.multiBucket.ObjectFromPublicURL(resource.StoragePath)
+		if !found {
+			h.logger.Warn("资源不在对象存储中，无法生成预签名URL", "resourceID", resourceID, "storagePath", resource.StoragePath)
+			utils.ErrorResponse(c, 409, "该资源不支持预签名下载")
+			return
+		}
+		downloadURL, err := h.multiBucket.PresignGetObject(ctx, bucketType, objectPath, expiry)
+		if err != nil {
+			utils.InternalServerErrorResponse(c, "生成下载链接失败")
+			return
+		}
+		data["download_url"] = downloadURL
+	}
+
+	// 每签发一次链接计一次下载（与实际传输的字节数无关）
+	taskID := fmt.Sprintf("incr_download_%d", resourceID)
+	_ = utils.SubmitTaskWithContext(ctx, taskID, func(taskCtx context.Context) error {
+		return h.resourceRepo.IncrementDownloadCount(taskCtx, resourceID)
+	}, time.Duration(h.config.AsyncTasks.ResourceDownloadCountTimeout)*time.Second)
+
+	h.logger.Info("签发资源下载链接", "resourceID", resourceID, "userID", userID, "chunks", resource.TotalChunks, "expiry", expiry)
+	utils.SuccessResponse(c, 200, "获取下载链接成功", data)
+}
+
 // ProxyDownloadResource 代理下载资源（7桶架构：返回分片下载信息）
 func (h *ResourceHandler) ProxyDownloadResource(c *gin.Context) {
 	resourceIDStr := c.Param("id")
@@ -336,6 +603,9 @@ func (h *ResourceHandler) ProxyDownloadResource(c *gin.Context) {
 		utils.ErrorResponse(c, 404, "资源不存在")
 		return
 	}
+	if !h.applyRequestedVersion(c, resource) {
+		return
+	}
 
 	// 7桶架构：返回分片信息供前端下载合并
 	// storage_path现在直接存储upload_id
@@ -351,7 +621,7 @@ func (h *ResourceHandler) ProxyDownloadResource(c *gin.Context) {
 
 	// 异步增加下载次数
 	taskID := fmt.Sprintf("incr_download_%d", resourceID)
-	_ = utils.SubmitTask(taskID, func(taskCtx context.Context) error {
+	_ = utils.SubmitTaskWithContext(ctx, taskID, func(taskCtx context.Context) error {
 		return h.resourceRepo.IncrementDownloadCount(taskCtx, uint(resourceID))
 	}, time.Duration(h.config.AsyncTasks.ResourceDownloadCountTimeout)*time.Second)
 
@@ -364,6 +634,7 @@ func (h *ResourceHandler) ProxyDownloadResource(c *gin.Context) {
 		"file_name":      resource.FileName,
 		"file_size":      resource.FileSize,
 		"file_hash":      resource.FileHash,
+		"version_no":     resource.CurrentVersion,
 	})
 }
 
@@ -403,6 +674,9 @@ func (h *ResourceHandler) CreateResourceComment(c *gin.Context) {
 		utils.ValidationErrorResponse(c, "请求参数错误")
 		return
 	}
+	if !moderateOrFail(c, userID, &req.Content) {
+		return
+	}
 
 	ctx := c.Request.Context()
 
@@ -469,7 +743,7 @@ func (h *ResourceHandler) CreateResourceComment(c *gin.Context) {
 	})
 }
 
-// GetResourceComments 获取资源评论列表
+// GetResourceComments 获取资源评论列表（flat=true 时回复按时间平铺返回）
 func (h *ResourceHandler) GetResourceComments(c *gin.Context) {
 	resourceIDStr := c.Param("id")
 	resourceID, err := strconv.ParseUint(resourceIDStr, 10, 32)
@@ -492,6 +766,9 @@ func (h *ResourceHandler) GetResourceComments(c *gin.Context) {
 		utils.ErrorResponse(c, 500, "获取评论失败")
 		return
 	}
+	if c.Query("flat") == "true" {
+		response.Flatten()
+	}
 
 	h.logger.Info("获取评论列表成功", "resourceID", resourceID, "total", response.Total)
 	utils.SuccessResponse(c, 200, "获取成功", response)
@@ -515,7 +792,7 @@ func (h *ResourceHandler) ToggleResourceCommentLike(c *gin.Context) {
 	isLiked, err := h.resourceCommentRepo.ToggleCommentLike(ctx, uint(commentID), userID)
 	if err != nil {
 		h.logger.Error("切换评论点赞失败", "commentID", commentID, "error", err.Error())
-		utils.ErrorResponse(c, 500, "操作失败")
+		utils.AppErrorResponse(c, err, "操作失败")
 		return
 	}
 
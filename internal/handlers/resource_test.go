@@ -0,0 +1,392 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/middleware"
+	"gin/internal/services"
+	"gin/internal/testutil"
+	"gin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTestMultiBucket 连接本地模拟的S3服务创建多桶存储（桶均已存在，预签名在本地完成）
+func newTestMultiBucket(t *testing.T, cfg *config.Config) *services.MultiBucketStorage {
+	t.Helper()
+	return newTestMultiBucketWithObjects(t, cfg)
+}
+
+// newTestMultiBucketWithObjects 同 newTestMultiBucket，列举对象时按前缀返回 keys 中的对象（每个桶相同）
+func newTestMultiBucketWithObjects(t *testing.T, cfg *config.Config, keys ...string) *services.MultiBucketStorage {
+	t.Helper()
+	s3 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if _, ok := query["location"]; ok {
+			w.Header().Set("Content-Type", "application/xml")
+			fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?><LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/">us-east-1</LocationConstraint>`)
+			return
+		}
+		if query.Get("list-type") == "2" {
+			var contents strings.Builder
+			for _, key := range keys {
+				if strings.HasPrefix(key, query.Get("prefix")) {
+					fmt.Fprintf(&contents, `<Contents><Key>%s</Key><LastModified>2026-01-02T03:04:05.000Z</LastModified><Size>100</Size></Contents>`, key)
+				}
+			}
+			w.Header().Set("Content-Type", "application/xml")
+			fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><IsTruncated>false</IsTruncated>%s</ListBucketResult>`, contents.String())
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(s3.Close)
+
+	cfg.MinIO.Endpoint = strings.TrimPrefix(s3.URL, "http://")
+	cfg.MinIO.UseSSL = false
+	cfg.StartupRetry.MaxElapsedSeconds = 0
+	for name, bucket := range map[string]*config.BucketConfig{
+		"user-avatars": &cfg.BucketUserAvatars, "resource-chunks": &cfg.BucketResourceChunks,
+		"resource-previews": &cfg.BucketResourcePreviews, "document-images": &cfg.BucketDocumentImages,
+		"article-images": &cfg.BucketArticleImages, "temp-files": &cfg.BucketTempFiles, "system-assets": &cfg.BucketSystemAssets,
+	} {
+		bucket.Name = name
+		bucket.PublicBaseURL = "http://cdn.example.com/" + name
+	}
+
+	storage, err := services.NewMultiBucketStorage(cfg)
+	if err != nil {
+		t.Fatalf("创建多桶存储失败: %v", err)
+	}
+	return storage
+}
+
+// onResource 预设资源查询结果（status: 1 正常，其他为审核中等状态）
+func onResource(fake *testutil.FakeDB, ownerID int64, storagePath string, totalChunks, status int64) {
+	onResourceAtVersion(fake, ownerID, storagePath, totalChunks, status, 1)
+}
+
+// onResourceAtVersion 预设资源查询结果，资源当前版本为 currentVersion
+func onResourceAtVersion(fake *testutil.FakeDB, ownerID int64, storagePath string, totalChunks, status, currentVersion int64) {
+	now := time.Now().UTC()
+	fake.OnRows(`FROM resources WHERE id = \? AND status != 0`, []string{
+		"id", "user_id", "title", "description", "document", "category_id", "file_name", "file_size", "file_type", "file_extension",
+		"file_hash", "storage_path", "total_chunks", "current_version", "download_count", "view_count", "like_count", "status", "created_at", "updated_at",
+	}, []driver.Value{int64(5), ownerID, "资源", "", "", nil, "demo.zip", int64(1024), "application/zip", "zip",
+		"hash", storagePath, totalChunks, currentVersion, int64(0), int64(0), int64(0), status, now, now})
+}
+
+// waitDownloadCounts 等待异步下载计数完成，返回计数语句的执行次数
+func waitDownloadCounts(fake *testutil.FakeDB, want int) int {
+	deadline := time.Now().Add(2 * time.Second)
+	for len(fake.Calls(`UPDATE resources SET download_count`)) < want && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	return len(fake.Calls(`UPDATE resources SET download_count`))
+}
+
+func newDownloadURLRouter(t *testing.T, cfg *config.Config, db *services.Database) *gin.Engine {
+	t.Helper()
+	h := NewResourceHandler(services.NewResourceRepository(db, cfg), nil, nil, newTestMultiBucket(t, cfg), nil, nil, nil, cfg)
+	router := gin.New()
+	router.GET("/api/resources/:id/download-url", middleware.AuthMiddleware(cfg, nil, nil), h.GetResourceDownloadURL)
+	return router
+}
+
+func TestGetResourceDownloadURL(t *testing.T) {
+	cfg := newTestConfig()
+	fake, db := newFakeDatabase(t, cfg)
+	onResource(fake, 7, "http://cdn.example.com/temp-files/uploads/demo.zip", 0, 1)
+	fake.OnExec(`UPDATE resources SET download_count`, 0, 1)
+	router := newDownloadURLRouter(t, cfg, db)
+
+	resp := doRequest(t, router, http.MethodGet, "/api/resources/5/download-url", signTestJWT(t, cfg, 1, "alice"), nil)
+	if resp.Status != http.StatusOK {
+		t.Fatalf("获取下载链接应返回200，实际 %d %s", resp.Status, resp.Body)
+	}
+	var data struct {
+		DownloadURL string    `json:"download_url"`
+		ExpiresAt   time.Time `json:"expires_at"`
+		FileName    string    `json:"file_name"`
+	}
+	decodeData(t, resp, &data)
+	u, err := url.Parse(data.DownloadURL)
+	if err != nil || u.Path != "/temp-files/uploads/demo.zip" {
+		t.Fatalf("应签发对应桶和对象的链接，实际 %q", data.DownloadURL)
+	}
+	if q := u.Query(); q.Get("X-Amz-Expires") != "300" || q.Get("X-Amz-Signature") == "" {
+		t.Fatalf("链接应带签名且有效期为配置的300秒，实际 %q", data.DownloadURL)
+	}
+	if until := time.Until(data.ExpiresAt); until <= 290*time.Second || until > 300*time.Second {
+		t.Fatalf("expires_at 应为约300秒后，实际 %v", data.ExpiresAt)
+	}
+	if data.FileName != "demo.zip" {
+		t.Fatalf("应返回文件名，实际 %q", data.FileName)
+	}
+	if n := waitDownloadCounts(fake, 1); n != 1 {
+		t.Fatalf("签发一次链接应计一次下载，实际 %d 次", n)
+	}
+
+	if r := doRequest(t, router, http.MethodGet, "/api/resources/5/download-url", "", nil); r.Status != http.StatusUnauthorized {
+		t.Fatalf("未登录应返回401，实际 %d", r.Status)
+	}
+}
+
+func TestGetResourceDownloadURLChunks(t *testing.T) {
+	cfg := newTestConfig()
+	fake, db := newFakeDatabase(t, cfg)
+	onResource(fake, 7, "upload-1", 3, 1)
+	fake.OnExec(`UPDATE resources SET download_count`, 0, 1)
+	router := newDownloadURLRouter(t, cfg, db)
+
+	resp := doRequest(t, router, http.MethodGet, "/api/resources/5/download-url", signTestJWT(t, cfg, 1, "alice"), nil)
+	if resp.Status != http.StatusOK {
+		t.Fatalf("获取分片下载链接应返回200，实际 %d %s", resp.Status, resp.Body)
+	}
+	var data struct {
+		ChunkURLs   []string `json:"chunk_urls"`
+		TotalChunks int      `json:"total_chunks"`
+	}
+	decodeData(t, resp, &data)
+	if len(data.ChunkURLs) != 3 || data.TotalChunks != 3 {
+		t.Fatalf("应为每个分片签发链接，实际 %+v", data)
+	}
+	for i, raw := range data.ChunkURLs {
+		u, err := url.Parse(raw)
+		if err != nil || u.Path != fmt.Sprintf("/resource-chunks/upload-1/chunk_%d", i) || u.Query().Get("X-Amz-Signature") == "" {
+			t.Fatalf("第 %d 个分片链接错误: %q", i, raw)
+		}
+	}
+	if n := waitDownloadCounts(fake, 1); n != 1 {
+		t.Fatalf("分片资源也只计一次下载，实际 %d 次", n)
+	}
+}
+
+func TestGetResourceDownloadURLAccessRules(t *testing.T) {
+	cases := []struct {
+		name        string
+		setup       func(fake *testutil.FakeDB)
+		userID      uint
+		wantStatus  int
+		wantCounted bool
+	}{
+		{"已删除或不存在的资源", func(fake *testutil.FakeDB) {
+			fake.OnRows(`FROM resources WHERE id = \? AND status != 0`, []string{"id"})
+		}, 1, http.StatusNotFound, false},
+		{"审核中的资源对他人不可见", func(fake *testutil.FakeDB) {
+			onResource(fake, 7, "http://cdn.example.com/temp-files/a.zip", 0, 2)
+		}, 1, http.StatusNotFound, false},
+		{"审核中的资源作者可以下载", func(fake *testutil.FakeDB) {
+			onResource(fake, 7, "http://cdn.example.com/temp-files/a.zip", 0, 2)
+		}, 7, http.StatusOK, true},
+		{"外部链接不支持预签名", func(fake *testutil.FakeDB) {
+			onResource(fake, 7, "https://pan.example.com/a.zip", 0, 1)
+		}, 1, http.StatusConflict, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			fake, db := newFakeDatabase(t, cfg)
+			tc.setup(fake)
+			fake.OnExec(`UPDATE resources SET download_count`, 0, 1)
+			router := newDownloadURLRouter(t, cfg, db)
+
+			resp := doRequest(t, router, http.MethodGet, "/api/resources/5/download-url", signTestJWT(t, cfg, tc.userID, "user"), nil)
+			if resp.Status != tc.wantStatus {
+				t.Fatalf("应返回 %d，实际 %d %s", tc.wantStatus, resp.Status, resp.Body)
+			}
+			want := 0
+			if tc.wantCounted {
+				want = 1
+			}
+			if n := waitDownloadCounts(fake, want); n != want {
+				t.Fatalf("下载计数应为 %d 次，实际 %d 次", want, n)
+			}
+		})
+	}
+}
+
+func TestGetResourceDownloadURLVersion(t *testing.T) {
+	cfg := newTestConfig()
+	fake, db := newFakeDatabase(t, cfg)
+	onResourceAtVersion(fake, 7, "http://cdn.example.com/temp-files/uploads/demo-v2.zip", 0, 1, 2)
+	fake.On(`FROM resource_versions WHERE resource_id = \? AND version_no = \?`, func(args []driver.Value) testutil.Response {
+		resp := testutil.Response{Columns: []string{"id", "resource_id", "version_no", "version", "user_id", "file_name", "file_size",
+			"file_type", "file_extension", "file_hash", "storage_path", "total_chunks", "notes", "created_at"}}
+		if args[1] == int64(1) {
+			resp.Rows = [][]driver.Value{{int64(11), int64(5), int64(1), "v1", int64(7), "demo-v1.zip", int64(512),
+				"application/zip", "zip", "hash-v1", "http://cdn.example.com/temp-files/uploads/demo-v1.zip", int64(0), "", time.Now().UTC()}}
+		}
+		return resp
+	})
+	fake.OnExec(`UPDATE resources SET download_count`, 0, 1)
+	router := newDownloadURLRouter(t, cfg, db)
+	token := signTestJWT(t, cfg, 1, "alice")
+
+	type downloadData struct {
+		DownloadURL string `json:"download_url"`
+		FileName    string `json:"file_name"`
+		VersionNo   int    `json:"version_no"`
+	}
+	download := func(query string) downloadData {
+		t.Helper()
+		resp := doRequest(t, router, http.MethodGet, "/api/resources/5/download-url"+query, token, nil)
+		if resp.Status != http.StatusOK {
+			t.Fatalf("%s 应返回200，实际 %d %s", query, resp.Status, resp.Body)
+		}
+		var data downloadData
+		decodeData(t, resp, &data)
+		return data
+	}
+
+	if data := download(""); data.VersionNo != 2 || data.FileName != "demo.zip" || !strings.Contains(data.DownloadURL, "demo-v2.zip") {
+		t.Fatalf("未指定版本时应下载当前版本，实际 %+v", data)
+	}
+	if data := download("?version=1"); data.VersionNo != 1 || data.FileName != "demo-v1.zip" || !strings.Contains(data.DownloadURL, "demo-v1.zip") {
+		t.Fatalf("指定旧版本时应下载该版本的文件，实际 %+v", data)
+	}
+	if calls := fake.Calls(`FROM resource_versions WHERE resource_id = \? AND version_no = \?`); len(calls) != 1 {
+		t.Fatalf("只有指定非当前版本时才查询版本表，实际 %d 次", len(calls))
+	}
+
+	if resp := doRequest(t, router, http.MethodGet, "/api/resources/5/download-url?version=abc", token, nil); resp.Status != http.StatusBadRequest {
+		t.Fatalf("无效版本号应返回400，实际 %d", resp.Status)
+	}
+	if resp := doRequest(t, router, http.MethodGet, "/api/resources/5/download-url?version=9", token, nil); resp.Status != http.StatusNotFound {
+		t.Fatalf("版本不存在时应返回404，实际 %d", resp.Status)
+	}
+}
+
+func TestAddResourceVersionEndpoint(t *testing.T) {
+	cfg := newTestConfig()
+	fake, db := newFakeDatabase(t, cfg)
+	fake.OnRows(`SELECT user_id, current_version FROM resources`, []string{"user_id", "current_version"}, []driver.Value{int64(7), int64(1)})
+	fake.OnExec(`INSERT IGNORE INTO resource_versions`, 0, 1)
+	fake.OnExec(`^INSERT INTO resource_versions`, 12, 1)
+	fake.OnExec(`UPDATE resources SET file_name`, 0, 1)
+
+	h := NewResourceHandler(services.NewResourceRepository(db, cfg), nil, nil, nil, nil, nil, nil, cfg)
+	router := gin.New()
+	router.POST("/api/resources/:id/versions", middleware.AuthMiddleware(cfg, nil, nil), h.AddResourceVersion)
+	body := map[string]interface{}{"version": "v1.2.3", "file_name": "demo.tar.gz", "file_size": 2048,
+		"file_hash": "h2", "storage_path": "/files/demo.tar.gz", "notes": "新增功能"}
+
+	if resp := doRequest(t, router, http.MethodPost, "/api/resources/5/versions", signTestJWT(t, cfg, 1, "bob"), body); resp.Status != http.StatusForbidden {
+		t.Fatalf("非作者上传新版本应返回403，实际 %d %s", resp.Status, resp.Body)
+	}
+
+	resp := doRequest(t, router, http.MethodPost, "/api/resources/5/versions", signTestJWT(t, cfg, 7, "alice"), body)
+	if resp.Status != http.StatusCreated {
+		t.Fatalf("作者上传新版本应返回201，实际 %d %s", resp.Status, resp.Body)
+	}
+	var version map[string]interface{}
+	decodeData(t, resp, &version)
+	if version["version_no"] != float64(2) || version["version"] != "v1.2.3" || version["file_extension"] != "gz" {
+		t.Fatalf("新版本信息错误: %v", version)
+	}
+	if _, ok := version["storage_path"]; ok {
+		t.Fatal("版本信息不应直接暴露存储地址")
+	}
+
+	body["storage_path"] = "https://evil.example.com/demo.tar.gz"
+	if resp := doRequest(t, router, http.MethodPost, "/api/resources/5/versions", signTestJWT(t, cfg, 7, "alice"), body); resp.Status != http.StatusBadRequest {
+		t.Fatalf("存储地址不在白名单内应返回400，实际 %d", resp.Status)
+	}
+	if calls := fake.Calls(`^INSERT INTO resource_versions`); len(calls) != 1 {
+		t.Fatalf("被拒绝的请求不应写入版本，实际 %d 次", len(calls))
+	}
+
+	delete(body, "file_hash")
+	if resp := doRequest(t, router, http.MethodPost, "/api/resources/5/versions", signTestJWT(t, cfg, 7, "alice"), body); resp.Status != http.StatusUnprocessableEntity {
+		t.Fatalf("缺少必填字段应返回422，实际 %d", resp.Status)
+	}
+}
+
+func TestChunkUploadBodyLimit(t *testing.T) {
+	newTestConfig()
+	h := NewChunkUploadHandler(nil)
+	router := gin.New()
+	group := router.Group("/api", func(c *gin.Context) { c.Set("userID", uint(1)) }, middleware.RequestSizeLimitMiddleware(1024))
+	group.POST("/upload/init", h.InitUpload)
+	group.POST("/upload/chunk", h.UploadChunk)
+
+	// 未声明长度的请求体，只有读取时才能发现超限
+	send := func(path, contentType string, body io.Reader) testResponse {
+		req := httptest.NewRequest(http.MethodPost, path, body)
+		req.ContentLength = -1
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		resp := testResponse{Status: w.Code, Body: w.Body.String()}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp.CommonResponse)
+		return resp
+	}
+
+	resp := send("/api/upload/init", "application/json", strings.NewReader(`{"file_name":"`+strings.Repeat("a", 2048)+`"}`))
+	if resp.Status != http.StatusRequestEntityTooLarge || resp.ErrorCode != utils.ErrCodeRequestTooLarge {
+		t.Fatalf("JSON请求体超限应返回413，实际 %d %s", resp.Status, resp.Body)
+	}
+
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	_ = writer.WriteField("upload_id", "u1")
+	part, _ := writer.CreateFormFile("chunk", "chunk.bin")
+	_, _ = part.Write(bytes.Repeat([]byte{1}, 4096))
+	_ = writer.Close()
+	resp = send("/api/upload/chunk", writer.FormDataContentType(), &form)
+	if resp.Status != http.StatusRequestEntityTooLarge || resp.ErrorCode != utils.ErrCodeRequestTooLarge {
+		t.Fatalf("分片请求体超限应在解析表单时返回413，实际 %d %s", resp.Status, resp.Body)
+	}
+}
+
+func TestResourceDetailViewDedup(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Cache.ViewDedup = config.ViewDedupConfig{WindowMinutes: 30, Capacity: 100}
+	fake, db := newFakeDatabase(t, cfg)
+	onResource(fake, 1, "resources/5", 1, 1)
+	fake.OnExec(`UPDATE resources SET view_count = view_count \+ 1 WHERE id = \?`, 0, 1)
+
+	h := NewResourceHandler(services.NewResourceRepository(db, cfg), nil, nil, nil, nil, nil, services.NewViewDeduplicator(cfg), cfg)
+	router := gin.New()
+	router.GET("/api/resources/:id", h.GetResourceDetail)
+	view := func(ip string) {
+		req := httptest.NewRequest(http.MethodGet, "/api/resources/5", nil)
+		req.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("获取资源详情应返回200，实际 %d %s", w.Code, w.Body.String())
+		}
+	}
+	waitViews := func(want int) int {
+		deadline := time.Now().Add(2 * time.Second)
+		for len(fake.Calls(`SET view_count = view_count`)) < want && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		time.Sleep(50 * time.Millisecond)
+		return len(fake.Calls(`SET view_count = view_count`))
+	}
+
+	view("10.0.0.1")
+	view("10.0.0.1")
+	if n := waitViews(1); n != 1 {
+		t.Fatalf("同一访客窗口内重复浏览只应计数一次，实际 %d 次", n)
+	}
+	view("10.0.0.2")
+	if n := waitViews(2); n != 2 {
+		t.Fatalf("不同访客的浏览应分别计数，实际 %d 次", n)
+	}
+}
@@ -1,10 +1,14 @@
 package handlers
 
 import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
 	"strconv"
 	"time"
 
 	"gin/internal/config"
+	"gin/internal/models"
 	"gin/internal/services"
 	"gin/internal/utils"
 
@@ -176,3 +180,107 @@ func (h *StatisticsHandler) GetEndpointRanking(c *gin.Context) {
 
 	utils.SuccessResponse(c, 200, "获取成功", rankings)
 }
+
+// GetLatency 获取各接口（按路由模板）最近请求的延迟分位数
+func (h *StatisticsHandler) GetLatency(c *gin.Context) {
+	utils.SuccessResponse(c, 200, "获取成功", models.EndpointLatencyResponse{
+		MaxSamples:  h.config.Profiler.LatencyMaxRecords,
+		GeneratedAt: time.Now().UTC(),
+		Endpoints:   utils.GetGlobalProfiler().GetEndpointLatencyStats(),
+	})
+}
+
+// GetAlerts 获取接口告警（最近窗口内错误率或P99延迟超过阈值的接口）和频繁出现的慢查询
+func (h *StatisticsHandler) GetAlerts(c *gin.Context) {
+	monitor := services.GetEndpointAlertMonitor()
+	cfg := monitor.Config()
+	now := time.Now()
+
+	utils.SuccessResponse(c, 200, "获取成功", models.EndpointAlertsResponse{
+		WindowMinutes:   cfg.WindowMinutes,
+		MinRequests:     cfg.MinRequests,
+		GeneratedAt:     now.UTC(),
+		Alerts:          monitor.Alerts(now),
+		SlowQueryAlerts: utils.GetGlobalSlowQueryDetector().Alerts(now),
+	})
+}
+
+// ExportStatistics 按日期范围导出统计数据为CSV（type=users|apis，from/to 为日期，包含两端）
+func (h *StatisticsHandler) ExportStatistics(c *gin.Context) {
+	exportType := c.Query("type")
+	if exportType != services.StatisticsExportUsers && exportType != services.StatisticsExportAPIs {
+		utils.BadRequestResponse(c, "无效的导出类型，可选值：users、apis")
+		return
+	}
+
+	dateFormat := h.config.DateTimeFormats.DateOnly
+	from, errFrom := time.Parse(dateFormat, c.Query("from"))
+	to, errTo := time.Parse(dateFormat, c.Query("to"))
+	if errFrom != nil || errTo != nil {
+		utils.BadRequestResponse(c, "日期格式错误，应为 "+dateFormat)
+		return
+	}
+	if to.Before(from) {
+		utils.BadRequestResponse(c, "结束日期不能早于开始日期")
+		return
+	}
+	maxDays := h.config.StatisticsQueryExtended.MaxExportRangeDays
+	if int(to.Sub(from).Hours()/24)+1 > maxDays {
+		utils.BadRequestResponse(c, fmt.Sprintf("导出日期范围不能超过%d天", maxDays))
+		return
+	}
+
+	startDate := from.Format(dateFormat)
+	endDate := to.Format(dateFormat)
+	filename := fmt.Sprintf("%s_statistics_%s_%s.csv", exportType, startDate, endDate)
+
+	// 查询成功后才写响应头，之前的错误仍可返回JSON
+	writer := csv.NewWriter(c.Writer)
+	rowCount := 0
+	err := h.statsRepo.ExportStatistics(c.Request.Context(), exportType, startDate, endDate, func(record []string) error {
+		if rowCount == 0 {
+			c.Header("Content-Type", "text/csv; charset=utf-8")
+			c.Header("Content-Disposition", utils.EncodeFileName(filename))
+			c.Header("X-Content-Type-Options", "nosniff")
+			c.Status(http.StatusOK)
+		}
+		rowCount++
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+		// 定期刷新，边查询边输出
+		if rowCount%500 == 0 {
+			writer.Flush()
+			c.Writer.Flush()
+			return writer.Error()
+		}
+		return nil
+	})
+	if err != nil && rowCount == 0 {
+		h.logger.Error("导出统计数据失败",
+			"type", exportType,
+			"startDate", startDate,
+			"endDate", endDate,
+			"error", err.Error())
+		utils.ErrorResponse(c, 500, "导出统计数据失败")
+		return
+	}
+	writer.Flush()
+	if err == nil {
+		err = writer.Error()
+	}
+	if err != nil {
+		// 响应已开始输出，只能中断并记录日志
+		h.logger.Error("导出统计数据中断",
+			"type", exportType,
+			"rows", rowCount-1,
+			"error", err.Error())
+		return
+	}
+
+	h.logger.Info("导出统计数据",
+		"type", exportType,
+		"startDate", startDate,
+		"endDate", endDate,
+		"rows", rowCount-1)
+}
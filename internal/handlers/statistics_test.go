@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gin/internal/models"
+	"gin/internal/services"
+	"gin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestExportStatisticsCSV(t *testing.T) {
+	cfg := newTestConfig()
+	fake, db := newFakeDatabase(t, cfg)
+	updated := time.Date(2024, 3, 2, 8, 30, 0, 0, time.UTC)
+	fake.OnRows(`FROM user_statistics WHERE date >= \? AND date <= \?`,
+		[]string{"date", "login_count", "register_count", "updated_at"},
+		[]driver.Value{time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), int64(12), int64(3), updated},
+		[]driver.Value{time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), int64(7), int64(0), updated})
+	fake.OnRows(`FROM api_statistics WHERE date >= \? AND date <= \?`,
+		[]string{"date", "endpoint", "method", "success_count", "error_count", "total_count", "avg_latency_ms", "updated_at"},
+		[]driver.Value{time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), "/api/articles", "GET", int64(98), int64(2), int64(100), 12.345, updated})
+
+	router := gin.New()
+	router.GET("/api/admin/statistics/export", NewStatisticsHandler(services.NewStatisticsRepository(db, cfg), cfg).ExportStatistics)
+	export := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/statistics/export?"+query, nil))
+		return w
+	}
+
+	w := export("type=users&from=2024-03-01&to=2024-03-02")
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/csv") {
+		t.Fatalf("应返回CSV，实际 %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	if disposition := w.Header().Get("Content-Disposition"); !strings.Contains(disposition, "users_statistics_2024-03-01_2024-03-02.csv") {
+		t.Fatalf("Content-Disposition 应包含文件名，实际 %s", disposition)
+	}
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("解析CSV失败: %v", err)
+	}
+	want := [][]string{
+		{"date", "login_count", "register_count", "updated_at"},
+		{"2024-03-01", "12", "3", "2024-03-02 08:30:00"},
+		{"2024-03-02", "7", "0", "2024-03-02 08:30:00"},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("CSV应有表头和2行数据，实际 %v", records)
+	}
+	for i := range want {
+		if strings.Join(records[i], ",") != strings.Join(want[i], ",") {
+			t.Fatalf("第%d行应为 %v，实际 %v", i, want[i], records[i])
+		}
+	}
+	if args := fake.Calls(`FROM user_statistics`)[0].Args; args[0] != "2024-03-01" || args[1] != "2024-03-02" {
+		t.Fatalf("应按日期范围查询，实际 %v", args)
+	}
+	// 导出按 export_timeout_sec 放宽服务端语句超时（120秒）
+	if query := fake.Calls(`FROM user_statistics`)[0].Query; !strings.HasPrefix(query, "SELECT /*+ MAX_EXECUTION_TIME(") {
+		t.Fatalf("导出查询应带语句级超时提示，实际 %s", query)
+	}
+
+	w = export("type=apis&from=2024-03-01&to=2024-03-01")
+	records, _ = csv.NewReader(w.Body).ReadAll()
+	if len(records) != 2 || records[0][1] != "endpoint" || strings.Join(records[1], ",") != "2024-03-01,/api/articles,GET,98,2,100,12.35,2024-03-02 08:30:00" {
+		t.Fatalf("API统计CSV与数据不符: %v", records)
+	}
+}
+
+func TestExportStatisticsRejectsInvalidParams(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.StatisticsQueryExtended.MaxExportRangeDays = 31
+	fake, db := newFakeDatabase(t, cfg)
+	router := gin.New()
+	router.GET("/api/admin/statistics/export", NewStatisticsHandler(services.NewStatisticsRepository(db, cfg), cfg).ExportStatistics)
+
+	for _, query := range []string{
+		"type=orders&from=2024-03-01&to=2024-03-02", // 无效类型
+		"from=2024-03-01&to=2024-03-02",             // 缺少类型
+		"type=users&from=2024/03/01&to=2024-03-02",  // 日期格式错误
+		"type=users&from=2024-03-01",                // 缺少结束日期
+		"type=users&from=2024-03-05&to=2024-03-01",  // 结束早于开始
+		"type=users&from=2024-01-01&to=2024-03-01",  // 超过最大范围
+	} {
+		resp := doRequest(t, router, http.MethodGet, "/api/admin/statistics/export?"+query, "", nil)
+		if resp.Status != http.StatusBadRequest {
+			t.Fatalf("%s: 应返回400，实际 %d %s", query, resp.Status, resp.Body)
+		}
+	}
+	if calls := fake.Calls(""); len(calls) != 0 {
+		t.Fatalf("参数无效时不应查询数据库，实际 %v", calls)
+	}
+
+	fake.OnRows(`FROM user_statistics`, []string{"date", "login_count", "register_count", "updated_at"})
+
+	if resp := doRequest(t, router, http.MethodGet, "/api/admin/statistics/export?type=users&from=2024-03-01&to=2024-03-31", "", nil); resp.Status != http.StatusOK {
+		t.Fatalf("恰好为最大范围时应允许，实际 %d", resp.Status)
+	}
+}
+
+func TestGetAlertsIncludesSlowQueries(t *testing.T) {
+	cfg := newTestConfig()
+	detector := utils.GetGlobalSlowQueryDetector()
+	previous := detector.AlertRule()
+	detector.SetAlertRule(utils.SlowQueryAlertRule{MinCount: 2})
+	t.Cleanup(func() { detector.SetAlertRule(previous) })
+
+	const query = "SELECT * FROM alert_test_table WHERE id = 1"
+	detector.Record(query, time.Second, nil)
+	detector.Record(query, time.Second, nil)
+
+	router := gin.New()
+	router.GET("/api/admin/alerts", NewStatisticsHandler(nil, cfg).GetAlerts)
+	resp := doRequest(t, router, http.MethodGet, "/api/admin/alerts", "", nil)
+	if resp.Status != http.StatusOK {
+		t.Fatalf("获取告警应成功，实际 %d %s", resp.Status, resp.Body)
+	}
+	var data models.EndpointAlertsResponse
+	decodeData(t, resp, &data)
+	for _, alert := range data.SlowQueryAlerts {
+		if alert.Fingerprint == utils.SlowQueryFingerprint(query) {
+			if alert.Count < 2 || alert.MaxDurationMs != 1000 {
+				t.Fatalf("慢查询告警信息错误: %+v", alert)
+			}
+			return
+		}
+	}
+	t.Fatalf("告警接口应包含频繁出现的慢查询，实际 %+v", data.SlowQueryAlerts)
+}
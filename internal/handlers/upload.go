@@ -1,10 +1,14 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"mime/multipart"
 	"net/http"
+	"net/url"
 	"path"
 	"sort"
 	"strconv"
@@ -66,7 +70,7 @@ func (h *UploadHandler) UploadAvatar(c *gin.Context) {
 		return // 错误已在函数内处理
 	}
 
-	// 打开文件准备上传（前端已经裁剪和压缩好了）
+	// 打开文件准备上传
 	file, err := fileHeader.Open()
 	if err != nil {
 		h.logger.Error("打开上传文件失败", "userID", userID, "error", err.Error())
@@ -75,6 +79,20 @@ func (h *UploadHandler) UploadAvatar(c *gin.Context) {
 	}
 	defer file.Close()
 
+	// 未开启服务端处理时直接上传原文件（前端已裁剪和压缩），否则裁剪缩放后上传处理结果
+	var body io.Reader = file
+	size := fileHeader.Size
+	if avatarCfg := h.config.AvatarUpload; avatarCfg.ServerProcess {
+		processed, err := utils.ResizeSquareJPEG(file, avatarCfg.OutputSize, avatarCfg.JPEGQuality)
+		if err != nil {
+			h.logger.Warn("处理头像图片失败", "userID", userID, "filename", fileHeader.Filename, "error", err.Error())
+			utils.CodeErrorResponse(c, utils.GetHTTPStatusCode(err), utils.ErrCodeUploadInvalidType, "无法处理该图片，请更换图片后重试")
+			return
+		}
+		body = bytes.NewReader(processed)
+		size = int64(len(processed))
+	}
+
 	// 上传到user-avatars桶
 	timestamp := time.Now().Unix()
 	objectKey := fmt.Sprintf("%s/current.jpg", username)
@@ -91,17 +109,23 @@ func (h *UploadHandler) UploadAvatar(c *gin.Context) {
 
 	h.archiveOldAvatar(c.Request.Context(), userID, username, timestamp)
 
-	// 上传到user-avatars桶（前端已处理好，统一为JPEG格式）
+	// 上传到user-avatars桶（统一为JPEG格式）
 	contentType := "image/jpeg"
-	url, err := h.multiBucket.PutObject(c.Request.Context(), services.BucketTypeUserAvatars, objectKey, contentType, file, fileHeader.Size)
+	url, err := h.multiBucket.PutObject(c.Request.Context(), services.BucketTypeUserAvatars, objectKey, contentType, body, size)
 	if err != nil {
 		h.logger.Error("上传到对象存储失败",
 			"userID", userID,
 			"username", username,
 			"error", err.Error())
+		if errors.Is(err, utils.ErrStorageQuotaExceeded) {
+			utils.AppErrorResponse(c, err, "上传失败")
+			return
+		}
 		utils.CodeErrorResponse(c, http.StatusInternalServerError, utils.ErrCodeUploadFailed, "上传失败")
 		return
 	}
+	// 当前头像不公开时保存头像接口地址，访问时再跳转到预签名URL
+	url = currentAvatarURL(h.config, username)
 
 	// 更新数据库中的头像URL（带回滚机制）
 	dbUpdateSuccess := false
@@ -143,12 +167,12 @@ func (h *UploadHandler) UploadAvatar(c *gin.Context) {
 			if historyOldURL == "" {
 				historyOldURL = oldAvatarURL
 			}
-			_ = utils.SubmitTask(taskID, func(taskCtx context.Context) error {
+			_ = utils.SubmitTaskWithContext(c.Request.Context(), taskID, func(taskCtx context.Context) error {
 				h.historyRepo.RecordProfileChange(userID, "avatar", historyOldURL, url, reqCtx.ClientIP)
 				h.historyRepo.RecordOperationHistory(userID, username, "修改头像",
 					fmt.Sprintf("上传新头像: %s (大小: %d字节)",
 						fileHeader.Filename,
-						fileHeader.Size), reqCtx.ClientIP)
+						size), reqCtx.ClientIP)
 				return nil
 			}, time.Duration(h.config.AsyncTasks.UploadHistoryTimeout)*time.Second)
 		}
@@ -170,17 +194,18 @@ func (h *UploadHandler) UploadAvatar(c *gin.Context) {
 		"username", username,
 		"filename", fileHeader.Filename,
 		"fileSize", fileHeader.Size,
+		"storedSize", size,
 		"duration", time.Since(reqCtx.StartTime))
 
 	utils.SuccessResponse(c, 200, "上传成功", gin.H{
 		"url":  urlWithTS,
 		"mime": contentType,
-		"size": fileHeader.Size,
+		"size": size,
 	})
 
 	// 使用Worker Pool异步清理历史头像（避免goroutine泄漏）
 	taskID := fmt.Sprintf("cleanup_avatar_%s_%d", username, time.Now().Unix())
-	_ = utils.SubmitTask(taskID, func(ctx context.Context) error {
+	_ = utils.SubmitTaskWithContext(c.Request.Context(), taskID, func(ctx context.Context) error {
 		h.cleanupAvatarHistory(username)
 		return nil
 	}, time.Duration(h.config.AsyncTasks.AvatarCleanupTimeout)*time.Second)
@@ -210,13 +235,23 @@ func (h *UploadHandler) getUserInfo(c *gin.Context) (userID uint, username strin
 func (h *UploadHandler) receiveAndValidateFile(c *gin.Context, userID uint) (*multipart.FileHeader, error) {
 	fileHeader, err := c.FormFile("file")
 	if err != nil {
+		if limit, ok := utils.MaxBytesLimit(err); ok {
+			h.logger.Warn("上传头像失败：请求体过大", "userID", userID, "limit", limit)
+			utils.RequestTooLargeResponse(c, limit)
+			return nil, err
+		}
 		h.logger.Warn("上传头像失败：缺少文件", "userID", userID, "error", err.Error())
 		utils.BadRequestResponse(c, "请选择要上传的文件")
 		return nil, err
 	}
 
-	// 强制使用5KB限制（极限压缩）
-	maxSize := int64(5 * 1024) // 固定5KB
+	// 未开启服务端处理时要求前端极限压缩（默认5KB）；开启后允许上传原图，由服务端缩放
+	avatarCfg := h.config.AvatarUpload
+	maxSizeKB := avatarCfg.MaxSizeKB
+	if avatarCfg.ServerProcess {
+		maxSizeKB = avatarCfg.ProcessMaxSizeKB
+	}
+	maxSize := int64(maxSizeKB) * 1024
 
 	// 先检查文件大小（在验证器之前，这样可以给出更友好的提示）
 	if fileHeader.Size > maxSize {
@@ -227,7 +262,7 @@ func (h *UploadHandler) receiveAndValidateFile(c *gin.Context, userID uint) (*mu
 			"fileSize", fileHeader.Size,
 			"fileSizeKB", actualKB,
 			"maxAllowed", maxSize,
-			"maxAllowedKB", 5)
+			"maxAllowedKB", maxSizeKB)
 
 		// 友好的错误提示（不暴露具体数据）
 		utils.CodeErrorResponse(c, 413, utils.ErrCodeUploadTooLarge,
@@ -255,13 +290,40 @@ func (h *UploadHandler) receiveAndValidateFile(c *gin.Context, userID uint) (*mu
 		return nil, err
 	}
 
+	// 解码图片头部，校验真实格式、尺寸和宽高比（服务端处理时会居中裁剪，不限制宽高比）
+	imageValidator := utils.NewImageValidator(avatarCfg.MaxWidth, avatarCfg.MaxHeight, avatarCfg.MaxAspectRatio)
+	if avatarCfg.ServerProcess {
+		imageValidator = utils.NewImageValidator(avatarCfg.ProcessMaxDimension, avatarCfg.ProcessMaxDimension, 0)
+	}
+	info, err := imageValidator.Validate(fileHeader)
+	if err != nil {
+		h.logger.Warn("❌ 图片验证失败",
+			"userID", userID,
+			"filename", fileHeader.Filename,
+			"contentType", fileHeader.Header.Get("Content-Type"),
+			"error", err.Error())
+		switch {
+		case errors.Is(err, utils.ErrImageTooLarge):
+			utils.CodeErrorResponse(c, 413, utils.ErrCodeUploadTooLarge,
+				"图片过大，请选择更小的图片或裁剪后重试")
+		case errors.Is(err, utils.ErrImageAspectRatio):
+			utils.CodeErrorResponse(c, 400, utils.ErrCodeUploadInvalidType, "头像应为正方形，请裁剪后重试")
+		default:
+			utils.CodeErrorResponse(c, utils.GetHTTPStatusCode(err), utils.ErrCodeUploadInvalidType, "仅支持PNG或JPEG格式图片")
+		}
+		return nil, err
+	}
+
 	// 记录验证成功
 	h.logger.Info("✅ 文件验证通过",
 		"userID", userID,
 		"filename", fileHeader.Filename,
 		"fileSize", fileHeader.Size,
 		"fileSizeKB", fileHeader.Size/1024,
-		"maxAllowedKB", maxSize/1024)
+		"maxAllowedKB", maxSize/1024,
+		"format", info.Format,
+		"width", info.Width,
+		"height", info.Height)
 
 	return fileHeader, nil
 }
@@ -303,6 +365,44 @@ func (h *UploadHandler) getArchivedAvatarURL(username string, timestamp int64) s
 	return fmt.Sprintf("%s/%s", publicBase, archiveKey)
 }
 
+// currentAvatarURL 当前头像的访问地址（地址稳定，可写入数据库）
+// current.jpg 可公开访问时为桶公共URL，否则为跳转到预签名URL的头像接口
+func currentAvatarURL(cfg *config.Config, username string) string {
+	objectKey := fmt.Sprintf("%s/current.jpg", username)
+	if cfg.BucketUserAvatars.IsPublicObject(objectKey) {
+		return fmt.Sprintf("%s/%s", strings.TrimSuffix(cfg.BucketUserAvatars.PublicBaseURL, "/"), objectKey)
+	}
+	return "/api/avatars/" + url.PathEscape(username)
+}
+
+// GetAvatar 跳转到用户当前头像（头像不公开时生成短期有效的预签名URL）
+func (h *UploadHandler) GetAvatar(c *gin.Context) {
+	if h.multiBucket == nil {
+		utils.CodeErrorResponse(c, http.StatusServiceUnavailable, utils.ErrCodeUploadFailed, "服务不可用")
+		return
+	}
+
+	username := c.Param("username")
+	if username == "" || username == "." || username == ".." {
+		utils.BadRequestResponse(c, "无效的用户名")
+		return
+	}
+
+	objectKey := fmt.Sprintf("%s/current.jpg", username)
+	target, expiresAt, err := h.multiBucket.ObjectURL(c.Request.Context(), services.BucketTypeUserAvatars, objectKey)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "获取头像失败")
+		return
+	}
+
+	// 浏览器可以缓存跳转，但不能超过预签名URL的有效期
+	if !expiresAt.IsZero() {
+		maxAge := int(time.Until(expiresAt).Seconds() / 2)
+		c.Header("Cache-Control", fmt.Sprintf("private, max-age=%d", max(maxAge, 0)))
+	}
+	c.Redirect(http.StatusFound, target)
+}
+
 // cleanupAvatarHistory 清理超出限制的历史头像（7桶架构）
 func (h *UploadHandler) cleanupAvatarHistory(username string) {
 	defer func() {
@@ -400,20 +500,27 @@ func (h *UploadHandler) ListAvatarHistory(c *gin.Context) {
 		return
 	}
 
-	baseURL := h.multiBucket.GetPublicBaseURL(services.BucketTypeUserAvatars)
+	// 历史头像不公开，只返回给本人短期有效的预签名URL
 	items := make([]gin.H, 0, len(objects))
-
 	for i, obj := range objects {
 		if i >= h.config.Pagination.AvatarHistoryMaxList {
 			break
 		}
-		url := fmt.Sprintf("%s/%s", baseURL, obj.Key)
-		items = append(items, gin.H{
+		url, expiresAt, err := h.multiBucket.ObjectURL(c.Request.Context(), services.BucketTypeUserAvatars, obj.Key)
+		if err != nil {
+			utils.InternalServerErrorResponse(c, "生成历史头像地址失败")
+			return
+		}
+		item := gin.H{
 			"key":           obj.Key,
 			"url":           url,
 			"size":          obj.Size,
 			"last_modified": obj.LastModified.Unix(),
-		})
+		}
+		if !expiresAt.IsZero() {
+			item["expires_at"] = expiresAt.Unix()
+		}
+		items = append(items, item)
 	}
 
 	utils.SuccessResponse(c, 200, "OK", gin.H{"items": items})
@@ -425,51 +532,91 @@ func (h *UploadHandler) validateImageFile(header *multipart.FileHeader) error {
 	validator := utils.NewFileValidator(maxSize, []string{
 		"image/png", "image/jpeg", "image/gif", "image/webp",
 	})
-	return validator.Validate(header)
+	if err := validator.Validate(header); err != nil {
+		return err
+	}
+
+	// 解码图片头部，校验真实格式和尺寸
+	imageValidator := utils.NewImageValidator(h.config.ImageUpload.MaxWidth, h.config.ImageUpload.MaxHeight, 0)
+	_, err := imageValidator.Validate(header)
+	return err
 }
 
-// uploadImageCommon 通用图片上传处理（减少重复代码）
-func (h *UploadHandler) uploadImageCommon(c *gin.Context) (file multipart.File, header *multipart.FileHeader, err error) {
+// uploadImageCommon 通用图片上传处理（减少重复代码），返回待上传的文件内容及其大小
+func (h *UploadHandler) uploadImageCommon(c *gin.Context) (file multipart.File, header *multipart.FileHeader, size int64, err error) {
 	// 验证用户登录
 	_, err = utils.GetUserIDFromContext(c)
 	if err != nil {
 		utils.UnauthorizedResponse(c, "未登录")
-		return nil, nil, err
+		return nil, nil, 0, err
 	}
 
 	// 检查存储服务
 	if h.multiBucket == nil {
 		utils.InternalServerErrorResponse(c, "存储服务未配置")
-		return nil, nil, fmt.Errorf("storage service not available")
+		return nil, nil, 0, fmt.Errorf("storage service not available")
 	}
 
 	// 解析上传文件
 	file, header, err = c.Request.FormFile("file")
 	if err != nil {
+		if limit, ok := utils.MaxBytesLimit(err); ok {
+			h.logger.Warn("上传文件失败：请求体过大", "limit", limit)
+			utils.RequestTooLargeResponse(c, limit)
+			return nil, nil, 0, err
+		}
 		h.logger.Warn("解析上传文件失败", "error", err.Error())
 		utils.BadRequestResponse(c, "未找到上传文件")
-		return nil, nil, err
+		return nil, nil, 0, err
 	}
 
 	// 验证图片文件
 	if err = h.validateImageFile(header); err != nil {
 		h.logger.Warn("文件验证失败", "filename", header.Filename, "error", err.Error())
 		statusCode := utils.GetHTTPStatusCode(err)
-		if statusCode == 413 {
+		if errors.Is(err, utils.ErrImageTooLarge) {
+			utils.BadRequestResponse(c, fmt.Sprintf("图片尺寸不能超过%dx%d像素", h.config.ImageUpload.MaxWidth, h.config.ImageUpload.MaxHeight))
+		} else if statusCode == 413 {
 			utils.BadRequestResponse(c, fmt.Sprintf("图片大小不能超过%dMB", h.config.ImageUpload.MaxSizeMB))
 		} else {
 			utils.BadRequestResponse(c, "只能上传PNG、JPEG、GIF或WebP格式的图片")
 		}
-		return nil, nil, err
+		file.Close()
+		return nil, nil, 0, err
 	}
 
-	return file, header, nil
+	if !h.config.ImageUpload.StripMetadata {
+		return file, header, header.Size, nil
+	}
+
+	// 移除EXIF等元数据（可能包含GPS位置和设备信息）
+	data, err := io.ReadAll(file)
+	file.Close()
+	if err != nil {
+		h.logger.Warn("读取上传文件失败", "filename", header.Filename, "error", err.Error())
+		utils.BadRequestResponse(c, "读取上传文件失败")
+		return nil, nil, 0, err
+	}
+	stripped, removed := utils.StripImageMetadata(data)
+	if removed > 0 {
+		h.logger.Info("已移除图片元数据", "filename", header.Filename, "removedBytes", removed)
+	}
+
+	return memoryFile{bytes.NewReader(stripped)}, header, int64(len(stripped)), nil
 }
 
+// memoryFile 基于内存数据的 multipart.File
+type memoryFile struct {
+	*bytes.Reader
+}
+
+// Close 实现 io.Closer（内存数据无需释放）
+func (memoryFile) Close() error { return nil }
+
 // UploadResourceImage 上传资源预览图（7桶架构）
 func (h *UploadHandler) UploadResourceImage(c *gin.Context) {
 	// 通用上传预处理
-	file, header, err := h.uploadImageCommon(c)
+	file, header, size, err := h.uploadImageCommon(c)
 	if err != nil {
 		return // 错误已在 uploadImageCommon 中处理
 	}
@@ -481,23 +628,53 @@ func (h *UploadHandler) UploadResourceImage(c *gin.Context) {
 
 	// 上传到temp-files桶临时存储
 	ctx := c.Request.Context()
-	imageURL, err := h.multiBucket.PutObject(ctx, services.BucketTypeTempFiles, objectPath, "image/jpeg", file, header.Size)
+	imageURL, err := h.multiBucket.PutObject(ctx, services.BucketTypeTempFiles, objectPath, "image/jpeg", file, size)
 	if err != nil {
 		h.logger.Error("上传资源图片失败", "error", err.Error())
-		utils.InternalServerErrorResponse(c, "上传失败")
+		utils.AppErrorResponse(c, err, "上传失败")
 		return
 	}
 
-	h.logger.Info("资源图片上传成功", "filename", header.Filename, "url", imageURL)
+	// 生成缩略图（用于资源列表封面），失败不影响上传结果
+	thumbnailURL := h.uploadResourceThumbnail(ctx, file, objectPath)
+
+	h.logger.Info("资源图片上传成功", "filename", header.Filename, "url", imageURL, "thumbnail", thumbnailURL)
 	utils.SuccessResponse(c, 200, "上传成功", gin.H{
-		"image_url": imageURL,
+		"image_url":     imageURL,
+		"thumbnail_url": thumbnailURL,
 	})
 }
 
+// uploadResourceThumbnail 为资源预览图生成缩略图并上传到temp-files桶，返回缩略图URL
+// 原图不超过缩略图尺寸、无法解码（如WebP）或上传失败时返回空，列表页回退使用原图
+func (h *UploadHandler) uploadResourceThumbnail(ctx context.Context, file multipart.File, objectPath string) string {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return ""
+	}
+
+	imgCfg := h.config.ImageUpload
+	thumbnail, ok, err := utils.ThumbnailJPEG(file, imgCfg.ThumbnailMaxEdge, imgCfg.ThumbnailQuality)
+	if err != nil {
+		h.logger.Warn("生成资源图片缩略图失败", "path", objectPath, "error", err.Error())
+		return ""
+	}
+	if !ok {
+		return ""
+	}
+
+	thumbnailPath := services.PreviewThumbnailPath(objectPath)
+	thumbnailURL, err := h.multiBucket.PutObject(ctx, services.BucketTypeTempFiles, thumbnailPath, "image/jpeg", bytes.NewReader(thumbnail), int64(len(thumbnail)))
+	if err != nil {
+		h.logger.Warn("上传资源图片缩略图失败", "path", thumbnailPath, "error", err.Error())
+		return ""
+	}
+	return thumbnailURL
+}
+
 // UploadDocumentImage 上传文档图片（7桶架构）
 func (h *UploadHandler) UploadDocumentImage(c *gin.Context) {
 	// 通用上传预处理
-	file, header, err := h.uploadImageCommon(c)
+	file, header, size, err := h.uploadImageCommon(c)
 	if err != nil {
 		return // 错误已在 uploadImageCommon 中处理
 	}
@@ -510,10 +687,10 @@ func (h *UploadHandler) UploadDocumentImage(c *gin.Context) {
 
 	// 上传到document-images桶
 	ctx := c.Request.Context()
-	imageURL, err := h.multiBucket.PutObject(ctx, services.BucketTypeDocumentImages, objectPath, "image/jpeg", file, header.Size)
+	imageURL, err := h.multiBucket.PutObject(ctx, services.BucketTypeDocumentImages, objectPath, "image/jpeg", file, size)
 	if err != nil {
 		h.logger.Error("上传文档图片失败", "error", err.Error())
-		utils.InternalServerErrorResponse(c, "上传失败")
+		utils.AppErrorResponse(c, err, "上传失败")
 		return
 	}
 
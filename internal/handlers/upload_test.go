@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"gin/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestListAvatarHistoryPresigned(t *testing.T) {
+	cfg := newTestConfig()
+	storage := newTestMultiBucketWithObjects(t, cfg,
+		"alice/current.jpg", "alice/history/1.jpg", "alice/history/2.jpg", "bob/history/3.jpg")
+	router := gin.New()
+	router.GET("/api/avatar/history", middleware.AuthMiddleware(cfg, nil, nil),
+		NewUploadHandler(storage, nil, 0, 0, nil, cfg).ListAvatarHistory)
+
+	resp := doRequest(t, router, http.MethodGet, "/api/avatar/history", signTestJWT(t, cfg, 1, "alice"), nil)
+	var data struct {
+		Items []struct {
+			Key       string `json:"key"`
+			URL       string `json:"url"`
+			ExpiresAt int64  `json:"expires_at"`
+		} `json:"items"`
+	}
+	decodeData(t, resp, &data)
+	if resp.Status != http.StatusOK || len(data.Items) != 2 {
+		t.Fatalf("应只返回本人的历史头像，实际 %d %s", resp.Status, resp.Body)
+	}
+
+	// 历史头像不匹配 public_paths，只能通过预签名URL访问
+	for _, item := range data.Items {
+		u, err := url.Parse(item.URL)
+		if err != nil || u.Path != "/user-avatars/"+item.Key {
+			t.Fatalf("应签发对应对象的链接，实际 %q", item.URL)
+		}
+		if q := u.Query(); q.Get("X-Amz-Expires") != "600" || q.Get("X-Amz-Signature") == "" {
+			t.Fatalf("链接应带签名且有效期为配置的10分钟，实际 %q", item.URL)
+		}
+		if until := time.Until(time.Unix(item.ExpiresAt, 0)); until <= 9*time.Minute || until > 10*time.Minute {
+			t.Fatalf("expires_at 应为约10分钟后，实际 %d", item.ExpiresAt)
+		}
+	}
+}
+
+func TestListAvatarHistoryPublicBucket(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.BucketUserAvatars.PublicPaths = nil // 整个桶公开
+	storage := newTestMultiBucketWithObjects(t, cfg, "alice/history/1.jpg")
+	router := gin.New()
+	router.GET("/api/avatar/history", middleware.AuthMiddleware(cfg, nil, nil),
+		NewUploadHandler(storage, nil, 0, 0, nil, cfg).ListAvatarHistory)
+
+	resp := doRequest(t, router, http.MethodGet, "/api/avatar/history", signTestJWT(t, cfg, 1, "alice"), nil)
+	var data struct {
+		Items []map[string]interface{} `json:"items"`
+	}
+	decodeData(t, resp, &data)
+	if len(data.Items) != 1 || data.Items[0]["url"] != "http://cdn.example.com/user-avatars/alice/history/1.jpg" {
+		t.Fatalf("公开对象应返回公共URL，实际 %s", resp.Body)
+	}
+	if _, ok := data.Items[0]["expires_at"]; ok {
+		t.Fatalf("公共URL不应带过期时间，实际 %v", data.Items[0])
+	}
+}
+
+func TestGetAvatarRedirect(t *testing.T) {
+	get := func(router http.Handler) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/avatars/alice", nil))
+		return w
+	}
+
+	// 默认配置下 current.jpg 公开，直接跳转到公共URL
+	cfg := newTestConfig()
+	router := gin.New()
+	router.GET("/api/avatars/:username", NewUploadHandler(newTestMultiBucket(t, cfg), nil, 0, 0, nil, cfg).GetAvatar)
+	w := get(router)
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "http://cdn.example.com/user-avatars/alice/current.jpg" {
+		t.Fatalf("公开头像应跳转到公共URL，实际 %d %q", w.Code, w.Header().Get("Location"))
+	}
+	if cc := w.Header().Get("Cache-Control"); cc != "" {
+		t.Fatalf("公开头像的跳转不需要限制缓存时间，实际 %q", cc)
+	}
+
+	// 私有桶：始终预签名，缓存时间不超过有效期
+	cfg = newTestConfig()
+	private := false
+	cfg.BucketUserAvatars.PublicRead = &private
+	router = gin.New()
+	router.GET("/api/avatars/:username", NewUploadHandler(newTestMultiBucket(t, cfg), nil, 0, 0, nil, cfg).GetAvatar)
+	w = get(router)
+	location, _ := url.Parse(w.Header().Get("Location"))
+	if w.Code != http.StatusFound || location == nil || location.Path != "/user-avatars/alice/current.jpg" ||
+		location.Query().Get("X-Amz-Signature") == "" {
+		t.Fatalf("私有桶的头像应跳转到预签名URL，实际 %d %q", w.Code, w.Header().Get("Location"))
+	}
+	cc := w.Header().Get("Cache-Control")
+	maxAge, err := strconv.Atoi(strings.TrimPrefix(cc, "private, max-age="))
+	if err != nil || maxAge <= 0 || maxAge > 300 {
+		t.Fatalf("跳转的缓存时间应为预签名剩余有效期的一半，实际 %q", cc)
+	}
+}
+
+func TestCurrentAvatarURL(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.BucketUserAvatars.PublicBaseURL = "http://cdn.example.com/user-avatars/"
+	if got := currentAvatarURL(cfg, "alice"); got != "http://cdn.example.com/user-avatars/alice/current.jpg" {
+		t.Fatalf("当前头像公开时应为公共URL，实际 %q", got)
+	}
+
+	cfg.BucketUserAvatars.PublicPaths = []string{"*/public.jpg"}
+	if got := currentAvatarURL(cfg, "a b"); got != "/api/avatars/a%20b" {
+		t.Fatalf("当前头像不公开时应为头像接口地址，实际 %q", got)
+	}
+}
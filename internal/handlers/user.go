@@ -3,7 +3,9 @@ package handlers
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"gin/internal/config"
 	"gin/internal/models"
@@ -43,10 +45,15 @@ func (h *UserHandler) UpdateMe(c *gin.Context) {
 	var payload struct {
 		Avatar  string `json:"avatar"` // 头像URL
 		Profile struct {
-			Nickname string `json:"nickname"`
-			Bio      string `json:"bio"`
-			Province string `json:"province"`
-			City     string `json:"city"`
+			Nickname string  `json:"nickname"`
+			Bio      string  `json:"bio"`
+			Phone    *string `json:"phone"`    // 传空字符串表示清除
+			Gender   *int    `json:"gender"`   // 0-未知，1-男，2-女
+			Birthday *string `json:"birthday"` // YYYY-MM-DD，传空字符串表示清除
+			Province *string `json:"province"` // 传空字符串表示清除
+			City     *string `json:"city"`     // 传空字符串表示清除
+			Website  *string `json:"website"`  // 传空字符串表示清除
+			Github   *string `json:"github"`   // 传空字符串表示清除
 		} `json:"profile"`
 	}
 
@@ -76,14 +83,15 @@ func (h *UserHandler) UpdateMe(c *gin.Context) {
 		err := h.userService.UpdateUserAvatar(c.Request.Context(), prof)
 		if err != nil {
 			h.logger.Error("更新头像失败", "userID", userID, "error", err.Error())
-			statusCode := utils.GetHTTPStatusCode(err)
-			utils.ErrorResponse(c, statusCode, err.Error())
+			utils.AppErrorResponse(c, err, "更新头像失败")
 			return
 		}
 	}
 
 	// 如果有个人资料，更新个人资料
-	if payload.Profile.Nickname != "" || payload.Profile.Bio != "" {
+	p := &payload.Profile
+	if p.Nickname != "" || p.Bio != "" || p.Website != nil || p.Github != nil ||
+		p.Phone != nil || p.Gender != nil || p.Birthday != nil || p.Province != nil || p.City != nil {
 		// 验证昵称和简介
 		if payload.Profile.Nickname != "" && !utils.ValidateNicknameWithConfig(payload.Profile.Nickname, &h.config.Validation.Nickname) {
 			h.logger.Warn("昵称格式不正确", "userID", userID, "nickname", payload.Profile.Nickname)
@@ -95,6 +103,17 @@ func (h *UserHandler) UpdateMe(c *gin.Context) {
 			utils.ValidationErrorResponse(c, fmt.Sprintf("简介过长，最多%d个字符", h.config.Validation.Bio.MaxLength))
 			return
 		}
+		website, ok := h.sanitizeProfileURL(c, userID, "个人网站", payload.Profile.Website)
+		if !ok {
+			return
+		}
+		github, ok := h.sanitizeProfileURL(c, userID, "GitHub", payload.Profile.Github)
+		if !ok {
+			return
+		}
+		if !h.validateProfileDetails(c, userID, p.Phone, p.Gender, p.Birthday, p.Province, p.City) {
+			return
+		}
 
 		// 先获取当前用户信息（用于历史记录）
 		currentUser, _ := h.userService.GetUserByID(c.Request.Context(), userID)
@@ -105,6 +124,13 @@ func (h *UserHandler) UpdateMe(c *gin.Context) {
 			UserID:   userID,
 			Nickname: currentProfile.Nickname,
 			Bio:      currentProfile.Bio,
+			Phone:    currentProfile.Phone,
+			Gender:   currentProfile.Gender,
+			Birthday: currentProfile.Birthday,
+			Province: currentProfile.Province,
+			City:     currentProfile.City,
+			Website:  currentProfile.Website,
+			Github:   currentProfile.Github,
 		}
 
 		// 只在payload有值时才更新
@@ -114,12 +140,35 @@ func (h *UserHandler) UpdateMe(c *gin.Context) {
 		if payload.Profile.Bio != "" {
 			prof.Bio = utils.SanitizeString(payload.Profile.Bio)
 		}
+		if website != nil {
+			prof.Website = *website
+		}
+		if github != nil {
+			prof.Github = *github
+		}
+		if p.Phone != nil {
+			prof.Phone = strings.TrimSpace(*p.Phone)
+		}
+		if p.Gender != nil {
+			prof.Gender = p.Gender
+		}
+		if p.Birthday != nil {
+			prof.Birthday = nil
+			if birthday := strings.TrimSpace(*p.Birthday); birthday != "" {
+				prof.Birthday = &birthday
+			}
+		}
+		if p.Province != nil {
+			prof.Province = utils.SanitizeString(strings.TrimSpace(*p.Province))
+		}
+		if p.City != nil {
+			prof.City = utils.SanitizeString(strings.TrimSpace(*p.City))
+		}
 
-		err := h.userService.UpsertUserProfile(c.Request.Context(), prof)
+		err := h.userService.UpsertFullUserProfile(c.Request.Context(), prof)
 		if err != nil {
 			h.logger.Error("更新个人资料失败", "userID", userID, "error", err.Error())
-			statusCode := utils.GetHTTPStatusCode(err)
-			utils.ErrorResponse(c, statusCode, err.Error())
+			utils.AppErrorResponse(c, err, "更新个人资料失败")
 			return
 		}
 
@@ -127,7 +176,7 @@ func (h *UserHandler) UpdateMe(c *gin.Context) {
 		if h.historyRepo != nil && currentUser != nil {
 			username := currentUser.Username
 			taskID := fmt.Sprintf("profile_history_%d_%d", userID, time.Now().Unix())
-			_ = utils.SubmitTask(taskID, func(taskCtx context.Context) error {
+			_ = utils.SubmitTaskWithContext(c.Request.Context(), taskID, func(taskCtx context.Context) error {
 				// 记录昵称修改
 				if payload.Profile.Nickname != "" && payload.Profile.Nickname != currentProfile.Nickname {
 					h.historyRepo.RecordProfileChange(userID, "nickname", currentProfile.Nickname, prof.Nickname, reqCtx.ClientIP)
@@ -138,6 +187,13 @@ func (h *UserHandler) UpdateMe(c *gin.Context) {
 					h.historyRepo.RecordProfileChange(userID, "bio", currentProfile.Bio, prof.Bio, reqCtx.ClientIP)
 					h.historyRepo.RecordOperationHistory(userID, username, "修改简介", "修改个人简介", reqCtx.ClientIP)
 				}
+				// 记录链接修改
+				if website != nil && *website != currentProfile.Website {
+					h.historyRepo.RecordProfileChange(userID, "website", currentProfile.Website, *website, reqCtx.ClientIP)
+				}
+				if github != nil && *github != currentProfile.Github {
+					h.historyRepo.RecordProfileChange(userID, "github", currentProfile.Github, *github, reqCtx.ClientIP)
+				}
 				return nil
 			}, time.Duration(h.config.AsyncTasks.UserUpdateHistoryTimeout)*time.Second)
 		}
@@ -149,6 +205,52 @@ func (h *UserHandler) UpdateMe(c *gin.Context) {
 	h.returnUserProfile(c, userID)
 }
 
+// sanitizeProfileURL 校验资料链接（未传时返回nil，校验失败时已写入响应）
+func (h *UserHandler) sanitizeProfileURL(c *gin.Context, userID uint, field string, rawURL *string) (*string, bool) {
+	if rawURL == nil {
+		return nil, true
+	}
+	cleaned, ok := utils.SanitizeProfileURL(*rawURL, &h.config.ValidationExtended)
+	if !ok {
+		h.logger.Warn("资料链接格式不正确", "userID", userID, "field", field, "url", *rawURL)
+		utils.ValidationErrorResponse(c, fmt.Sprintf("%s链接无效，仅支持http/https链接，长度%d-%d个字符",
+			field, h.config.ValidationExtended.URLMinLength, h.config.ValidationExtended.URLMaxLength))
+		return nil, false
+	}
+	return &cleaned, true
+}
+
+// profileRegionMaxLength 省份、城市的最大长度（字符数，与数据库字段一致）
+const profileRegionMaxLength = 50
+
+// validateProfileDetails 校验手机号、性别、生日和地区（未传的字段跳过，空字符串表示清除），失败时已写入响应
+func (h *UserHandler) validateProfileDetails(c *gin.Context, userID uint, phone *string, gender *int, birthday, province, city *string) bool {
+	if phone != nil {
+		if value := strings.TrimSpace(*phone); value != "" && !utils.ValidatePhoneWithConfig(value, &h.config.ValidationExtended) {
+			h.logger.Warn("手机号格式不正确", "userID", userID)
+			utils.ValidationErrorResponse(c, "手机号格式不正确")
+			return false
+		}
+	}
+	if gender != nil && (*gender < 0 || *gender > 2) {
+		utils.ValidationErrorResponse(c, "性别取值无效（0-未知，1-男，2-女）")
+		return false
+	}
+	if birthday != nil {
+		if value := strings.TrimSpace(*birthday); value != "" && !utils.ValidateBirthday(value) {
+			utils.ValidationErrorResponse(c, "生日格式不正确，应为YYYY-MM-DD且不晚于今天")
+			return false
+		}
+	}
+	for _, region := range []*string{province, city} {
+		if region != nil && utf8.RuneCountInString(strings.TrimSpace(*region)) > profileRegionMaxLength {
+			utils.ValidationErrorResponse(c, fmt.Sprintf("省份和城市最多%d个字符", profileRegionMaxLength))
+			return false
+		}
+	}
+	return true
+}
+
 // GetMe 获取当前用户信息（前端统一接口）
 func (h *UserHandler) GetMe(c *gin.Context) {
 	userID, isOK := getUserIDOrFail(c)
@@ -167,8 +269,7 @@ func (h *UserHandler) returnUserProfile(c *gin.Context, userID uint) {
 	user, err := h.userService.GetUserByID(ctx, userID)
 	if err != nil {
 		h.logger.Warn("获取用户信息失败", "userID", userID, "error", err.Error())
-		statusCode := utils.GetHTTPStatusCode(err)
-		utils.ErrorResponse(c, statusCode, err.Error())
+		utils.AppErrorResponse(c, err, "获取用户信息失败")
 		return
 	}
 
@@ -179,6 +280,9 @@ func (h *UserHandler) returnUserProfile(c *gin.Context, userID uint) {
 	avatarURL := ""
 	nickname := ""
 	bio := ""
+	website := ""
+	github := ""
+	profile := gin.H{}
 	if extra != nil {
 		// 如果数据库中有头像URL，修正URL并添加时间戳防缓存
 		if extra.AvatarURL != "" {
@@ -188,6 +292,28 @@ func (h *UserHandler) returnUserProfile(c *gin.Context, userID uint) {
 		}
 		nickname = extra.Nickname
 		bio = extra.Bio
+		website = extra.Website
+		github = extra.Github
+		// 可选字段未设置时不返回
+		if extra.Gender != nil {
+			profile["gender"] = *extra.Gender
+		}
+		if extra.Birthday != nil {
+			profile["birthday"] = *extra.Birthday
+		}
+	}
+
+	profile["nickname"] = nickname
+	profile["bio"] = bio
+	profile["phone"] = ""
+	profile["province"] = ""
+	profile["city"] = ""
+	profile["website"] = website
+	profile["github"] = github
+	if extra != nil {
+		profile["phone"] = extra.Phone
+		profile["province"] = extra.Province
+		profile["city"] = extra.City
 	}
 
 	// 检查用户是否为管理员（优化：使用AdminChecker，O(1)查找）
@@ -202,14 +328,10 @@ func (h *UserHandler) returnUserProfile(c *gin.Context, userID uint) {
 		"auth_status":    user.AuthStatus,
 		"account_status": user.AccountStatus,
 		"role":           role,
-		"profile": gin.H{
-			"nickname": nickname,
-			"bio":      bio,
-			"province": "",
-			"city":     "",
-		},
-		"updatedAt": user.UpdatedAt,
+		"profile":        profile,
+		"updatedAt":      user.UpdatedAt,
 	}
+	h.attachFollowCounts(ctx, response, userID)
 
 	utils.SuccessResponse(c, 200, "获取成功", response)
 }
@@ -234,8 +356,7 @@ func (h *UserHandler) GetUserByID(c *gin.Context) {
 	user, err := h.userService.GetUserByID(ctx, targetUserID)
 	if err != nil {
 		h.logger.Warn("获取用户信息失败", "targetUserID", targetUserID, "error", err.Error())
-		statusCode := utils.GetHTTPStatusCode(err)
-		utils.ErrorResponse(c, statusCode, err.Error())
+		utils.AppErrorResponse(c, err, "获取用户信息失败")
 		return
 	}
 
@@ -262,11 +383,41 @@ func (h *UserHandler) GetUserByID(c *gin.Context) {
 		// 同时在根级提供 avatar 字段（方便前端使用）
 		response["avatar"] = profile.AvatarURL
 	}
+	h.attachFollowCounts(ctx, response, targetUserID)
 
 	h.logger.Info("获取用户信息成功", "targetUserID", targetUserID, "username", user.Username)
 	utils.SuccessResponse(c, 200, "获取用户信息成功", response)
 }
 
+// GetPublicProfile 获取用户公开主页（昵称、头像、简介及文章/资源/获赞统计）
+func (h *UserHandler) GetPublicProfile(c *gin.Context) {
+	targetUserID, isOK := parseUintParam(c, "id", "无效的用户ID")
+	if !isOK {
+		return
+	}
+
+	profile, err := h.userService.GetPublicProfile(c.Request.Context(), targetUserID)
+	if err != nil {
+		utils.ErrorResponse(c, utils.GetHTTPStatusCode(err), err.Error())
+		return
+	}
+	if profile.Avatar != "" {
+		profile.Avatar = h.fixAvatarURL(profile.Avatar, profile.Username)
+	}
+
+	utils.SuccessResponse(c, 200, "获取成功", profile)
+}
+
+// attachFollowCounts 在用户信息响应中附加粉丝数和关注数（查询失败时返回0，不影响主体信息）
+func (h *UserHandler) attachFollowCounts(ctx context.Context, response gin.H, userID uint) {
+	counts, err := h.userService.GetFollowCounts(ctx, userID)
+	if err != nil {
+		counts = &models.FollowCounts{}
+	}
+	response["followers_count"] = counts.FollowersCount
+	response["following_count"] = counts.FollowingCount
+}
+
 // fixAvatarURL 修正头像URL中的IP地址（7桶架构）
 func (h *UserHandler) fixAvatarURL(oldURL, username string) string {
 	// 如果数据库中的URL使用了错误的IP，重新构建正确的URL
@@ -275,11 +426,6 @@ func (h *UserHandler) fixAvatarURL(oldURL, username string) string {
 		return oldURL
 	}
 
-	// 移除末尾斜杠
-	if currentBase[len(currentBase)-1] == '/' {
-		currentBase = currentBase[:len(currentBase)-1]
-	}
-
-	// 重新构建正确的URL（不带时间戳，7桶架构使用current.jpg）
-	return fmt.Sprintf("%s/%s/current.jpg", currentBase, username)
+	// 重新构建正确的URL（不带时间戳，7桶架构使用current.jpg；头像不公开时为头像接口地址）
+	return currentAvatarURL(h.config, username)
 }
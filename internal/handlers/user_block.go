@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"errors"
+
+	"gin/internal/services"
+	"gin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UserBlockHandler 用户屏蔽处理器
+type UserBlockHandler struct {
+	userRepo *services.UserRepository
+	logger   utils.Logger
+}
+
+// NewUserBlockHandler 创建用户屏蔽处理器
+func NewUserBlockHandler(userRepo *services.UserRepository) *UserBlockHandler {
+	return &UserBlockHandler{
+		userRepo: userRepo,
+		logger:   utils.GetLogger(),
+	}
+}
+
+// ListBlocks 获取当前用户屏蔽的用户ID列表
+func (h *UserBlockHandler) ListBlocks(c *gin.Context) {
+	userID, isOK := getUserIDOrFail(c)
+	if !isOK {
+		return
+	}
+
+	blockedIDs, err := h.userRepo.GetBlockedIDs(c.Request.Context(), userID)
+	if err != nil {
+		utils.ErrorResponse(c, utils.GetHTTPStatusCode(err), "获取屏蔽列表失败")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "获取成功", gin.H{
+		"blocked_ids": blockedIDs,
+	})
+}
+
+// BlockUser 屏蔽用户（重复屏蔽视为成功）
+func (h *UserBlockHandler) BlockUser(c *gin.Context) {
+	userID, isOK := getUserIDOrFail(c)
+	if !isOK {
+		return
+	}
+
+	blockedID, isOK := parseUintParam(c, "id", "无效的用户ID")
+	if !isOK {
+		return
+	}
+
+	if err := h.userRepo.BlockUser(c.Request.Context(), userID, blockedID); err != nil {
+		var appErr *utils.AppError
+		switch {
+		case errors.As(err, &appErr) && appErr.Code == 400:
+			utils.BadRequestResponse(c, appErr.Message)
+		case errors.Is(err, utils.ErrUserNotFound):
+			utils.NotFoundResponse(c, "用户不存在")
+		default:
+			utils.ErrorResponse(c, utils.GetHTTPStatusCode(err), "屏蔽用户失败")
+		}
+		return
+	}
+
+	// 同步到当前WebSocket连接，聊天消息立即生效
+	NotifyBlockChanged(userID, blockedID, true)
+
+	utils.SuccessResponse(c, 200, "屏蔽成功", nil)
+}
+
+// UnblockUser 取消屏蔽用户（未屏蔽时视为成功）
+func (h *UserBlockHandler) UnblockUser(c *gin.Context) {
+	userID, isOK := getUserIDOrFail(c)
+	if !isOK {
+		return
+	}
+
+	blockedID, isOK := parseUintParam(c, "id", "无效的用户ID")
+	if !isOK {
+		return
+	}
+
+	if err := h.userRepo.UnblockUser(c.Request.Context(), userID, blockedID); err != nil {
+		utils.ErrorResponse(c, utils.GetHTTPStatusCode(err), "取消屏蔽失败")
+		return
+	}
+
+	NotifyBlockChanged(userID, blockedID, false)
+
+	utils.SuccessResponse(c, 200, "已取消屏蔽", nil)
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"testing"
+
+	"gin/internal/utils"
+)
+
+func TestNotifyBlockChangedUpdatesLiveConnections(t *testing.T) {
+	first := &Client{userID: 1, blocked: map[uint]bool{}}
+	second := &Client{userID: 1, blocked: map[uint]bool{}}
+	other := &Client{userID: 2, blocked: map[uint]bool{}}
+
+	previous := globalHub
+	globalHub = &ConnectionHub{
+		clients: map[uint][]*Client{1: {first, second}, 2: {other}},
+		logger:  utils.GetLogger(),
+	}
+	t.Cleanup(func() { globalHub = previous })
+
+	NotifyBlockChanged(1, 3, true)
+	for _, c := range []*Client{first, second} {
+		if !c.isBlocked(3) {
+			t.Fatal("屏蔽后该用户的所有连接都应过滤被屏蔽者的消息")
+		}
+		if c.isBlocked(0) {
+			t.Fatal("系统消息（senderID=0）不应被过滤")
+		}
+	}
+	if other.isBlocked(3) {
+		t.Fatal("屏蔽只影响屏蔽者本人，其他用户仍能看到")
+	}
+
+	NotifyBlockChanged(1, 3, false)
+	if first.isBlocked(3) || second.isBlocked(3) {
+		t.Fatal("取消屏蔽后应恢复接收")
+	}
+}
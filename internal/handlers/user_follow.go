@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"errors"
+
+	"gin/internal/config"
+	"gin/internal/services"
+	"gin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FollowHandler 用户关注处理器
+type FollowHandler struct {
+	userRepo *services.UserRepository
+	config   *config.Config
+	logger   utils.Logger
+}
+
+// NewFollowHandler 创建用户关注处理器
+func NewFollowHandler(userRepo *services.UserRepository, cfg *config.Config) *FollowHandler {
+	return &FollowHandler{
+		userRepo: userRepo,
+		config:   cfg,
+		logger:   utils.GetLogger(),
+	}
+}
+
+// Follow 关注用户（重复关注视为成功）
+func (h *FollowHandler) Follow(c *gin.Context) {
+	userID, isOK := getUserIDOrFail(c)
+	if !isOK {
+		return
+	}
+
+	followingID, isOK := parseUintParam(c, "id", "无效的用户ID")
+	if !isOK {
+		return
+	}
+
+	if err := h.userRepo.Follow(c.Request.Context(), userID, followingID); err != nil {
+		var appErr *utils.AppError
+		switch {
+		case errors.As(err, &appErr) && appErr.Code == 400:
+			utils.BadRequestResponse(c, appErr.Message)
+		case errors.Is(err, utils.ErrUserNotFound):
+			utils.NotFoundResponse(c, "用户不存在")
+		default:
+			utils.ErrorResponse(c, utils.GetHTTPStatusCode(err), "关注失败")
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "关注成功", nil)
+}
+
+// Unfollow 取消关注（未关注时视为成功）
+func (h *FollowHandler) Unfollow(c *gin.Context) {
+	userID, isOK := getUserIDOrFail(c)
+	if !isOK {
+		return
+	}
+
+	followingID, isOK := parseUintParam(c, "id", "无效的用户ID")
+	if !isOK {
+		return
+	}
+
+	if err := h.userRepo.Unfollow(c.Request.Context(), userID, followingID); err != nil {
+		utils.ErrorResponse(c, utils.GetHTTPStatusCode(err), "取消关注失败")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "已取消关注", nil)
+}
+
+// ListFollowing 获取当前用户关注的人
+func (h *FollowHandler) ListFollowing(c *gin.Context) {
+	userID, isOK := getUserIDOrFail(c)
+	if !isOK {
+		return
+	}
+
+	page, pageSize := h.pageParams(c)
+	response, err := h.userRepo.GetFollowing(c.Request.Context(), userID, page, pageSize)
+	if err != nil {
+		utils.ErrorResponse(c, utils.GetHTTPStatusCode(err), "获取关注列表失败")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "获取成功", response)
+}
+
+// ListFollowers 获取当前用户的粉丝
+func (h *FollowHandler) ListFollowers(c *gin.Context) {
+	userID, isOK := getUserIDOrFail(c)
+	if !isOK {
+		return
+	}
+
+	page, pageSize := h.pageParams(c)
+	response, err := h.userRepo.GetFollowers(c.Request.Context(), userID, page, pageSize)
+	if err != nil {
+		utils.ErrorResponse(c, utils.GetHTTPStatusCode(err), "获取粉丝列表失败")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "获取成功", response)
+}
+
+// pageParams 解析分页参数（超出范围时使用默认值）
+func (h *FollowHandler) pageParams(c *gin.Context) (int, int) {
+	return parsePageParams(c, &h.config.Pagination)
+}
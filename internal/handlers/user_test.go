@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"context"
+	"database/sql/driver"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gin/internal/middleware"
+	"gin/internal/models"
+	"gin/internal/services"
+	"gin/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// stubUserService 用内存保存用户资料
+type stubUserService struct {
+	services.UserServiceInterface
+	mu      sync.Mutex
+	profile models.UserExtraProfile
+	upserts int
+}
+
+func (s *stubUserService) GetUserByID(_ context.Context, id uint) (*models.User, error) {
+	return &models.User{ID: id, Username: "alice"}, nil
+}
+
+func (s *stubUserService) GetUserProfile(_ context.Context, userID uint) (*models.UserExtraProfile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prof := s.profile
+	prof.UserID = userID
+	return &prof, nil
+}
+
+func (s *stubUserService) UpsertFullUserProfile(_ context.Context, profile *models.UserExtraProfile) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.profile = *profile
+	s.upserts++
+	return nil
+}
+
+func (s *stubUserService) GetFollowCounts(context.Context, uint) (*models.FollowCounts, error) {
+	return &models.FollowCounts{}, nil
+}
+
+func newUserRouter(t *testing.T) (*gin.Engine, *stubUserService, string) {
+	t.Helper()
+	cfg := newTestConfig()
+	svc := &stubUserService{}
+	h := NewUserHandler(svc, nil, cfg)
+	router := gin.New()
+	user := router.Group("/api/user", middleware.AuthMiddleware(cfg, nil, nil))
+	user.GET("/me", h.GetMe)
+	user.PUT("/me", h.UpdateMe)
+	return router, svc, signTestJWT(t, cfg, 1, "alice")
+}
+
+func TestUpdateMeFullProfile(t *testing.T) {
+	router, svc, token := newUserRouter(t)
+
+	resp := doRequest(t, router, http.MethodPut, "/api/user/me", token, map[string]interface{}{"profile": map[string]interface{}{
+		"nickname": "Alice", "phone": "13800138000", "gender": 2, "birthday": "1995-06-01",
+		"province": " 浙江 ", "city": "杭州",
+	}})
+	if resp.Status != http.StatusOK {
+		t.Fatalf("更新资料应成功，实际 %d %s", resp.Status, resp.Body)
+	}
+	var me struct {
+		Profile map[string]interface{} `json:"profile"`
+	}
+	decodeData(t, resp, &me)
+	want := map[string]interface{}{"nickname": "Alice", "phone": "13800138000", "gender": float64(2),
+		"birthday": "1995-06-01", "province": "浙江", "city": "杭州", "website": "", "github": ""}
+	for key, value := range want {
+		if me.Profile[key] != value {
+			t.Fatalf("profile.%s 应为 %v，实际 %v", key, value, me.Profile[key])
+		}
+	}
+
+	// 只更新传入的字段，空字符串表示清除
+	resp = doRequest(t, router, http.MethodPut, "/api/user/me", token, map[string]interface{}{"profile": map[string]interface{}{
+		"city": "宁波", "birthday": "",
+	}})
+	if resp.Status != http.StatusOK {
+		t.Fatalf("部分更新应成功，实际 %d %s", resp.Status, resp.Body)
+	}
+	if svc.profile.City != "宁波" || svc.profile.Phone != "13800138000" || svc.profile.Gender == nil || svc.profile.Birthday != nil {
+		t.Fatalf("未传的字段应保留，生日应被清除，实际 %+v", svc.profile)
+	}
+	me.Profile = nil
+	decodeData(t, resp, &me)
+	if _, ok := me.Profile["birthday"]; ok {
+		t.Fatalf("未设置的生日不应返回，实际 %v", me.Profile)
+	}
+}
+
+func TestGetMeOmitsUnsetOptionalFields(t *testing.T) {
+	router, _, token := newUserRouter(t)
+
+	resp := doRequest(t, router, http.MethodGet, "/api/user/me", token, nil)
+	var me struct {
+		Profile map[string]interface{} `json:"profile"`
+	}
+	decodeData(t, resp, &me)
+	for _, key := range []string{"gender", "birthday"} {
+		if value, ok := me.Profile[key]; ok {
+			t.Fatalf("未设置的 %s 不应返回 null，实际 %v", key, value)
+		}
+	}
+	for _, key := range []string{"phone", "province", "city", "website", "github"} {
+		if me.Profile[key] != "" {
+			t.Fatalf("未设置的 %s 应为空字符串，实际 %v", key, me.Profile[key])
+		}
+	}
+}
+
+func TestUpdateMeProfileValidation(t *testing.T) {
+	router, svc, token := newUserRouter(t)
+	cases := []struct {
+		name    string
+		profile map[string]interface{}
+	}{
+		{"手机号位数不对", map[string]interface{}{"phone": "1380013800"}},
+		{"手机号第二位无效", map[string]interface{}{"phone": "12800138000"}},
+		{"性别超出范围", map[string]interface{}{"gender": 3}},
+		{"生日格式错误", map[string]interface{}{"birthday": "1995/06/01"}},
+		{"生日晚于今天", map[string]interface{}{"birthday": "2999-01-01"}},
+		{"城市过长", map[string]interface{}{"city": strings.Repeat("城", profileRegionMaxLength+1)}},
+	}
+	for _, tc := range cases {
+		resp := doRequest(t, router, http.MethodPut, "/api/user/me", token, map[string]interface{}{"profile": tc.profile})
+		if resp.Status != http.StatusUnprocessableEntity {
+			t.Errorf("%s: 应返回422，实际 %d %s", tc.name, resp.Status, resp.Body)
+		}
+	}
+	if svc.upserts != 0 {
+		t.Fatalf("校验失败时不应保存资料，实际保存 %d 次", svc.upserts)
+	}
+
+	// 空字符串表示清除，不做格式校验
+	resp := doRequest(t, router, http.MethodPut, "/api/user/me", token, map[string]interface{}{"profile": map[string]interface{}{"phone": ""}})
+	if resp.Status != http.StatusOK {
+		t.Fatalf("清除手机号应成功，实际 %d %s", resp.Status, resp.Body)
+	}
+}
+
+func TestGetPublicProfileEndpoint(t *testing.T) {
+	cfg := newTestConfig()
+	fake, db := newFakeDatabase(t, cfg)
+	fake.On(`FROM user_auth ua LEFT JOIN user_profile up ON up.user_id = ua.id WHERE ua.id = \?`, func(args []driver.Value) testutil.Response {
+		resp := testutil.Response{Columns: []string{"username", "account_status", "created_at", "nickname", "avatar", "bio",
+			"website", "github", "article_count", "resource_count", "likes_received", "followers", "following"}}
+		switch args[0] {
+		case int64(1):
+			resp.Rows = [][]driver.Value{{"alice", int64(models.AccountStatusNormal), time.Now().UTC(), "Alice", "", "", "", "",
+				int64(4), int64(2), int64(37), int64(10), int64(3)}}
+		case int64(2):
+			resp.Rows = [][]driver.Value{{"deleted_2", int64(models.AccountStatusDeleted), time.Now().UTC(), "", "", "", "", "",
+				int64(0), int64(0), int64(0), int64(0), int64(0)}}
+		}
+		return resp
+	})
+
+	router := gin.New()
+	router.GET("/api/users/:id/profile", NewUserHandler(services.NewUserService(services.NewUserRepository(db)), nil, cfg).GetPublicProfile)
+
+	resp := doRequest(t, router, http.MethodGet, "/api/users/1/profile", "", nil)
+	var profile map[string]interface{}
+	decodeData(t, resp, &profile)
+	if resp.Status != http.StatusOK || profile["article_count"] != float64(4) || profile["likes_received"] != float64(37) || profile["joined_at"] == nil {
+		t.Fatalf("应返回公开资料和统计，实际 %d %s", resp.Status, resp.Body)
+	}
+	for _, field := range []string{"email", "phone", "failed_login_count"} {
+		if _, ok := profile[field]; ok {
+			t.Fatalf("公开资料不应包含 %s", field)
+		}
+	}
+
+	resp = doRequest(t, router, http.MethodGet, "/api/users/2/profile", "", nil)
+	profile = nil
+	decodeData(t, resp, &profile)
+	if resp.Status != http.StatusOK || profile["is_deleted"] != true || profile["username"] != "" {
+		t.Fatalf("已注销用户应返回200和占位资料，实际 %d %s", resp.Status, resp.Body)
+	}
+
+	if resp := doRequest(t, router, http.MethodGet, "/api/users/9/profile", "", nil); resp.Status != http.StatusNotFound {
+		t.Fatalf("用户不存在应返回404，实际 %d", resp.Status)
+	}
+}
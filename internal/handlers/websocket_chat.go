@@ -1,9 +1,13 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -45,25 +49,70 @@ func createUpgrader(allowedOrigins []string, cfg *config.WebSocketConfig, logger
 
 // WSMessage represents a WebSocket message
 type WSMessage struct {
-	Type string      `json:"type"` // message, online_count, heartbeat, system
+	Type string      `json:"type"` // message, online_count, heartbeat, system, ack, nack
 	Data interface{} `json:"data"`
 }
 
+// outboundMessage is a message queued for delivery to clients
+type outboundMessage struct {
+	senderID uint   // Author of the content (0 for system messages, never filtered)
+	data     []byte // Encoded WSMessage
+}
+
 // Client represents a WebSocket client connection
 type Client struct {
 	hub             *ConnectionHub
 	conn            *websocket.Conn
-	send            chan []byte
+	send            chan outboundMessage
 	userID          uint
 	username        string
 	nickname        string
 	avatar          string
-	ipAddress       string     // Client IP address
-	closeOnce       sync.Once  // Ensures connection is closed only once
-	channelClosed   bool       // Track if send channel is closed
-	lastMessageTime time.Time  // Last message timestamp for rate limiting
-	messageCount    int        // Message count in current time window
-	mu              sync.Mutex // Protects rate limiting fields and channelClosed
+	ipAddress       string        // Client IP address
+	closeOnce       sync.Once     // Ensures connection is closed only once
+	channelClosed   bool          // Track if send channel is closed
+	lastMessageTime time.Time     // Last message timestamp for rate limiting
+	messageCount    int           // Message count in current time window
+	blocked         map[uint]bool // Users blocked by this client; their content is dropped in writePump
+	dropCount       int           // Messages dropped because the send buffer was full, in the current window
+	dropWindowStart time.Time     // Start of the current drop counting window
+	mu              sync.Mutex    // Protects rate limiting fields, channelClosed, blocked and drop counters
+}
+
+// recordDrop counts a message dropped on a full send buffer and reports whether the client
+// has exceeded the slow-client threshold. The counter resets once the window has elapsed.
+func (c *Client) recordDrop(maxDrops int, window time.Duration) (int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(c.dropWindowStart) > window {
+		c.dropCount = 0
+		c.dropWindowStart = now
+	}
+	c.dropCount++
+	return c.dropCount, maxDrops > 0 && c.dropCount > maxDrops
+}
+
+// isBlocked reports whether messages from senderID should be hidden from this client
+func (c *Client) isBlocked(senderID uint) bool {
+	if senderID == 0 {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.blocked[senderID]
+}
+
+// setBlocked updates the client's blocked set after the user blocks or unblocks someone
+func (c *Client) setBlocked(userID uint, blocked bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if blocked {
+		c.blocked[userID] = true
+	} else {
+		delete(c.blocked, userID)
+	}
 }
 
 // close safely closes the WebSocket connection exactly once
@@ -86,59 +135,150 @@ func (c *Client) closeSendChannel() {
 
 // ConnectionHub manages all active WebSocket connections
 type ConnectionHub struct {
-	clients    map[uint]*Client
-	broadcast  chan []byte
+	clients    map[uint][]*Client // Connections per user, oldest first (at most MaxConnectionsPerUser)
+	broadcast  chan outboundMessage
 	register   chan *Client
 	unregister chan *Client
 	mu         sync.RWMutex
 	chatRepo   *services.ChatRepository
 	userRepo   *services.UserRepository
+	prefsRepo  *services.NotificationPreferenceRepository
+	notifyRepo *services.NotificationRepository
 	logger     utils.Logger
 	config     *config.WebSocketConfig
+
+	oversizeMu sync.Mutex
+	oversize   map[uint]*oversizeRecord // Oversized-message violations per user; survives reconnects
+
+	pendingOnlineCount *outboundMessage // Latest online count that did not fit in the broadcast buffer; only touched by run
+
+	closing     bool           // Set by Shutdown (under mu); new connections are rejected afterwards
+	connections int            // Reserved connections (under mu): upgrading or with a running write pump
+	drained     []*Client      // Clients disconnected by Shutdown, force-closed if they do not flush in time
+	pumps       sync.WaitGroup // Running write pumps
+	stopCh      chan struct{}  // Closed by Shutdown to stop the run loop
+	done        chan struct{}  // Closed when the run loop has exited
+	stopOnce    sync.Once
+}
+
+// oversizeRecord tracks a user's oversized messages within the current window and any temporary ban
+type oversizeRecord struct {
+	count       int
+	windowStart time.Time
+	bannedUntil time.Time
 }
 
+// oversizeSweepSize triggers cleanup of expired oversize records once the map grows past it
+const oversizeSweepSize = 10000
+
 var (
 	globalHub *ConnectionHub
 	hubOnce   sync.Once
 )
 
 // InitConnectionHub initializes the global connection hub
-func InitConnectionHub(chatRepo *services.ChatRepository, userRepo *services.UserRepository, cfg *config.Config) {
+func InitConnectionHub(chatRepo *services.ChatRepository, userRepo *services.UserRepository, prefsRepo *services.NotificationPreferenceRepository, notifyRepo *services.NotificationRepository, cfg *config.Config) {
 	hubOnce.Do(func() {
 		globalHub = &ConnectionHub{
-			clients:    make(map[uint]*Client),
-			broadcast:  make(chan []byte, cfg.WebSocket.BroadcastBufferSize),
+			clients:    make(map[uint][]*Client),
+			broadcast:  make(chan outboundMessage, cfg.WebSocket.BroadcastBufferSize),
 			register:   make(chan *Client),
 			unregister: make(chan *Client),
 			chatRepo:   chatRepo,
 			userRepo:   userRepo,
+			prefsRepo:  prefsRepo,
+			notifyRepo: notifyRepo,
 			logger:     utils.GetLogger(),
 			config:     &cfg.WebSocket,
+			oversize:   make(map[uint]*oversizeRecord),
+			stopCh:     make(chan struct{}),
+			done:       make(chan struct{}),
 		}
 		go globalHub.run()
 	})
 }
 
-// run starts the hub's main loop
+// recordOversize counts an oversized message from userID and reports whether the user has reached
+// the violation threshold, in which case they are banned from reconnecting for OversizeBanSec
+func (h *ConnectionHub) recordOversize(userID uint) (int, bool) {
+	if h.config.OversizeMaxViolations <= 0 {
+		return 0, false
+	}
+	now := time.Now()
+	window := time.Duration(h.config.OversizeWindowSec) * time.Second
+
+	h.oversizeMu.Lock()
+	defer h.oversizeMu.Unlock()
+
+	if len(h.oversize) > oversizeSweepSize {
+		for id, rec := range h.oversize {
+			if now.Sub(rec.windowStart) > window && !now.Before(rec.bannedUntil) {
+				delete(h.oversize, id)
+			}
+		}
+	}
+
+	rec, ok := h.oversize[userID]
+	if !ok {
+		rec = &oversizeRecord{}
+		h.oversize[userID] = rec
+	}
+	if now.Sub(rec.windowStart) > window {
+		rec.count = 0
+		rec.windowStart = now
+	}
+	rec.count++
+	if rec.count < h.config.OversizeMaxViolations {
+		return rec.count, false
+	}
+
+	count := rec.count
+	rec.count = 0
+	rec.bannedUntil = now.Add(time.Duration(h.config.OversizeBanSec) * time.Second)
+	return count, true
+}
+
+// oversizeBannedUntil returns when the user's oversize ban expires, or false if they are not banned
+func (h *ConnectionHub) oversizeBannedUntil(userID uint) (time.Time, bool) {
+	h.oversizeMu.Lock()
+	defer h.oversizeMu.Unlock()
+
+	rec, ok := h.oversize[userID]
+	if !ok || !time.Now().Before(rec.bannedUntil) {
+		return time.Time{}, false
+	}
+	return rec.bannedUntil, true
+}
+
+// run starts the hub's main loop; it exits once Shutdown closes stopCh
 func (h *ConnectionHub) run() {
+	defer close(h.done)
+
 	for {
+		h.flushOnlineCount()
+
 		select {
+		case <-h.stopCh:
+			h.disconnectAll()
+			return
+
 		case client := <-h.register:
 			h.mu.Lock()
-			var oldClient *Client
-			// Check if user already has a connection
-			if existing, exists := h.clients[client.userID]; exists {
-				oldClient = existing
-				// Remove from map immediately to prevent broadcast attempting to send
-				delete(h.clients, client.userID)
-				h.logger.Info("Replacing old connection", "userID", client.userID)
+			// Replace the user's oldest connections once the per-user limit is reached
+			// (with the default limit of 1 the new connection replaces the old one)
+			conns := h.clients[client.userID]
+			var oldClients []*Client
+			if excess := len(conns) + 1 - h.maxConnectionsPerUser(); excess > 0 {
+				oldClients = append(oldClients, conns[:excess]...)
+				conns = append(conns[:0:0], conns[excess:]...)
+				h.logger.Info("Replacing old connection", "userID", client.userID, "replaced", excess)
 			}
-			// Add new client to map
-			h.clients[client.userID] = client
+			// Add new client to map; replaced clients are removed first so broadcast no longer sends to them
+			h.clients[client.userID] = append(conns, client)
 			h.mu.Unlock()
 
-			// Close old connection outside the lock (if exists)
-			if oldClient != nil {
+			// Close old connections outside the lock
+			for _, oldClient := range oldClients {
 				oldClient.closeSendChannel() // 使用安全的关闭方法，防止panic
 				oldClient.close()
 				h.logger.Info("Old connection closed", "userID", client.userID)
@@ -148,38 +288,201 @@ func (h *ConnectionHub) run() {
 			h.broadcastOnlineCount()
 
 		case client := <-h.unregister:
-			h.mu.Lock()
-			var shouldBroadcast bool
-			var onlineCount int
-
-			// Only close channel if this client is still the current connection
-			// Prevents closing already-closed channels when old connections disconnect
-			if currentClient, exists := h.clients[client.userID]; exists && currentClient == client {
-				delete(h.clients, client.userID)
-				shouldBroadcast = true
-				onlineCount = len(h.clients) // 在锁内读取准确人数
-			}
-			h.mu.Unlock()
-
-			if shouldBroadcast {
-				client.closeSendChannel() // 使用安全的关闭方法，防止panic
-				h.logger.Info("Client disconnected", "userID", client.userID, "onlineCount", onlineCount)
-				h.broadcastOnlineCountValue(onlineCount)
-			}
+			h.removeClient(client)
 
 		case message := <-h.broadcast:
+			var slowClients []*Client
 			h.mu.RLock()
-			for _, client := range h.clients {
-				select {
-				case client.send <- message:
-				default:
-					// Client's send channel is full, skip
-					h.logger.Warn("Client send buffer full", "userID", client.userID)
+			for _, conns := range h.clients {
+				for _, client := range conns {
+					select {
+					case client.send <- message:
+					default:
+						// Client's send channel is full, skip
+						h.logger.Warn("Client send buffer full", "userID", client.userID)
+						if h.recordDrop(client) {
+							slowClients = append(slowClients, client)
+						}
+					}
 				}
 			}
 			h.mu.RUnlock()
+
+			// Evict outside the read lock; removeClient takes the write lock
+			for _, client := range slowClients {
+				h.removeClient(client)
+				client.close()
+			}
+		}
+	}
+}
+
+// disconnectAll queues a server_shutdown frame for every client and closes their send channels,
+// so each write pump flushes what is still queued, sends a close frame and exits
+func (h *ConnectionHub) disconnectAll() {
+	frame, err := json.Marshal(WSMessage{Type: "server_shutdown", Data: map[string]interface{}{}})
+	if err != nil {
+		h.logger.Error("Failed to marshal shutdown message", "error", err.Error())
+	}
+
+	h.mu.Lock()
+	clients := make([]*Client, 0, len(h.clients))
+	for userID, conns := range h.clients {
+		clients = append(clients, conns...)
+		delete(h.clients, userID)
+	}
+	h.drained = clients
+	h.mu.Unlock()
+
+	for _, client := range clients {
+		if frame != nil {
+			select {
+			case client.send <- outboundMessage{data: frame}:
+			default:
+				h.logger.Warn("Client send buffer full, shutdown message dropped", "userID", client.userID)
+			}
 		}
+		client.closeSendChannel()
 	}
+	h.logger.Info("Disconnecting WebSocket clients for shutdown", "count", len(clients))
+}
+
+// Shutdown stops accepting new connections, tells every connected client the server is shutting
+// down and waits until their write pumps have flushed or ctx expires, after which the remaining
+// connections are closed. Calling it more than once is safe.
+func (h *ConnectionHub) Shutdown(ctx context.Context) error {
+	h.stopOnce.Do(func() {
+		h.mu.Lock()
+		h.closing = true
+		h.mu.Unlock()
+		close(h.stopCh)
+	})
+
+	select {
+	case <-h.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	flushed := make(chan struct{})
+	go func() {
+		h.pumps.Wait()
+		close(flushed)
+	}()
+
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		h.mu.RLock()
+		drained := h.drained
+		h.mu.RUnlock()
+		for _, client := range drained {
+			client.close()
+		}
+		h.logger.Warn("WebSocket clients did not flush before shutdown timeout", "count", len(drained))
+		return ctx.Err()
+	}
+}
+
+// ShutdownConnectionHub shuts down the global hub (no-op if not initialized)
+func ShutdownConnectionHub(ctx context.Context) error {
+	if globalHub == nil {
+		return nil
+	}
+	return globalHub.Shutdown(ctx)
+}
+
+var (
+	errHubClosing = errors.New("connection hub is shutting down")
+	errHubFull    = errors.New("connection limit reached")
+)
+
+// reserveConnection reserves a connection slot and its write pump before upgrading. It fails once
+// Shutdown has started or the global connection limit is reached. Checking and adding under mu
+// keeps pumps.Add from racing with the Wait in Shutdown.
+func (h *ConnectionHub) reserveConnection() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closing {
+		return errHubClosing
+	}
+	if h.config.MaxConnections > 0 && h.connections >= h.config.MaxConnections {
+		return errHubFull
+	}
+	h.connections++
+	h.pumps.Add(1)
+	return nil
+}
+
+// releaseConnection releases a slot taken by reserveConnection
+func (h *ConnectionHub) releaseConnection() {
+	h.mu.Lock()
+	h.connections--
+	h.mu.Unlock()
+	h.pumps.Done()
+}
+
+// maxConnectionsPerUser returns the per-user connection limit (at least 1)
+func (h *ConnectionHub) maxConnectionsPerUser() int {
+	if h.config.MaxConnectionsPerUser <= 0 {
+		return 1
+	}
+	return h.config.MaxConnectionsPerUser
+}
+
+// isClosing reports whether Shutdown has started
+func (h *ConnectionHub) isClosing() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.closing
+}
+
+// removeClient unregisters a client and closes its send channel. It must only be called
+// from the hub's run loop (other goroutines send to h.unregister instead).
+func (h *ConnectionHub) removeClient(client *Client) {
+	h.mu.Lock()
+	var shouldBroadcast bool
+	var onlineCount int
+
+	// Only close channel if this client is still registered
+	// Prevents closing already-closed channels when replaced connections disconnect
+	var removed bool
+	conns := h.clients[client.userID]
+	for i, current := range conns {
+		if current != client {
+			continue
+		}
+		removed = true
+		if len(conns) == 1 {
+			delete(h.clients, client.userID)
+			shouldBroadcast = true // 用户的最后一个连接断开，在线人数变化
+		} else {
+			h.clients[client.userID] = append(conns[:i:i], conns[i+1:]...)
+		}
+		break
+	}
+	onlineCount = len(h.clients) // 在锁内读取准确人数
+	h.mu.Unlock()
+
+	if removed {
+		client.closeSendChannel() // 使用安全的关闭方法，防止panic
+		h.logger.Info("Client disconnected", "userID", client.userID, "onlineCount", onlineCount)
+	}
+	if shouldBroadcast {
+		h.broadcastOnlineCountValue(onlineCount)
+	}
+}
+
+// recordDrop records a dropped message for the client and reports whether it should be
+// evicted as a slow client
+func (h *ConnectionHub) recordDrop(client *Client) bool {
+	window := time.Duration(h.config.SlowClientWindowSec) * time.Second
+	drops, evict := client.recordDrop(h.config.SlowClientMaxDrops, window)
+	if evict {
+		h.logger.Warn("Evicting slow client", "userID", client.userID, "drops", drops, "windowSec", h.config.SlowClientWindowSec)
+	}
+	return evict
 }
 
 // broadcastOnlineCount sends the current online count to all clients
@@ -204,7 +507,22 @@ func (h *ConnectionHub) broadcastOnlineCountValue(count int) {
 		return
 	}
 
-	h.broadcast <- data
+	// Called from the run loop, which is the only reader of h.broadcast: a blocking send
+	// deadlocks once the buffer is full, so keep the latest count for the next iteration instead
+	h.pendingOnlineCount = &outboundMessage{data: data}
+	h.flushOnlineCount()
+}
+
+// flushOnlineCount queues the pending online count if the broadcast buffer has room
+func (h *ConnectionHub) flushOnlineCount() {
+	if h.pendingOnlineCount == nil {
+		return
+	}
+	select {
+	case h.broadcast <- *h.pendingOnlineCount:
+		h.pendingOnlineCount = nil
+	default:
+	}
 }
 
 // SendToUser sends a message to a specific user
@@ -220,40 +538,71 @@ func (h *ConnectionHub) SendToUser(userID uint, msgType string, data interface{}
 		return err
 	}
 
+	// Send to every connection of the user; hold the read lock so send channels are not closed mid-send
 	h.mu.RLock()
-	client, exists := h.clients[userID]
-	h.mu.RUnlock()
+	defer h.mu.RUnlock()
 
-	if !exists {
+	conns := h.clients[userID]
+	if len(conns) == 0 {
 		// User is not online, silently ignore
 		h.logger.Debug("User not online, message not sent", "userID", userID, "type", msgType)
 		return nil
 	}
 
-	select {
-	case client.send <- msgData:
-		h.logger.Debug("Message sent to user", "userID", userID, "type", msgType)
-		return nil
-	default:
-		h.logger.Warn("Client send buffer full, message dropped", "userID", userID, "type", msgType)
-		return nil
+	for _, client := range conns {
+		select {
+		case client.send <- outboundMessage{data: msgData}:
+			h.logger.Debug("Message sent to user", "userID", userID, "type", msgType)
+		default:
+			h.logger.Warn("Client send buffer full, message dropped", "userID", userID, "type", msgType)
+			if h.recordDrop(client) {
+				// Closing the connection ends readPump, which routes the client through unregister
+				client.close()
+			}
+		}
 	}
+	return nil
 }
 
-// GetOnlineCount returns the current online count (O(1))
+// GetConnectionCount returns the number of reserved connections (open or being upgraded)
+func (h *ConnectionHub) GetConnectionCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.connections
+}
+
+// GetHubConnectionCount returns the connection count of the global hub (0 if not initialized)
+func GetHubConnectionCount() int {
+	if globalHub == nil {
+		return 0
+	}
+	return globalHub.GetConnectionCount()
+}
+
+// GetOnlineCount returns the current number of online users (O(1)); users with several
+// connections are counted once
 func (h *ConnectionHub) GetOnlineCount() int {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 	return len(h.clients)
 }
 
+// GetHubOnlineCount returns the online count of the global hub (0 if not initialized)
+func GetHubOnlineCount() int {
+	if globalHub == nil {
+		return 0
+	}
+	return globalHub.GetOnlineCount()
+}
+
 // GetOnlineUsers returns the list of online users
 func (h *ConnectionHub) GetOnlineUsers() []map[string]interface{} {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
 	users := make([]map[string]interface{}, 0, len(h.clients))
-	for _, client := range h.clients {
+	for _, conns := range h.clients {
+		client := conns[len(conns)-1] // Latest connection
 		users = append(users, map[string]interface{}{
 			"user_id":  client.userID,
 			"username": client.username,
@@ -264,6 +613,34 @@ func (h *ConnectionHub) GetOnlineUsers() []map[string]interface{} {
 	return users
 }
 
+// GetOnlineUsersFresh returns online users with username/nickname/avatar reloaded from the database
+// in a single batched query, so profile changes made after connecting are reflected.
+// Users missing from the database keep their connect-time values.
+func (h *ConnectionHub) GetOnlineUsersFresh(ctx context.Context) ([]map[string]interface{}, error) {
+	users := h.GetOnlineUsers()
+	if len(users) == 0 {
+		return users, nil
+	}
+
+	userIDs := make([]uint, 0, len(users))
+	for _, user := range users {
+		userIDs = append(userIDs, user["user_id"].(uint))
+	}
+
+	briefs, err := h.userRepo.BatchGetUserBriefs(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		if brief, ok := briefs[user["user_id"].(uint)]; ok {
+			user["username"] = brief.Username
+			user["nickname"] = brief.Nickname
+			user["avatar"] = brief.Avatar
+		}
+	}
+	return users, nil
+}
+
 // BroadcastToAll sends a message to all connected clients
 func (h *ConnectionHub) BroadcastToAll(msgType string, data interface{}) error {
 	msg := WSMessage{
@@ -278,7 +655,7 @@ func (h *ConnectionHub) BroadcastToAll(msgType string, data interface{}) error {
 	}
 
 	select {
-	case h.broadcast <- msgData:
+	case h.broadcast <- outboundMessage{data: msgData}:
 		h.logger.Debug("Broadcast message queued", "type", msgType)
 		return nil
 	default:
@@ -287,9 +664,68 @@ func (h *ConnectionHub) BroadcastToAll(msgType string, data interface{}) error {
 	}
 }
 
+// BroadcastNotification sends a notification about content written by authorID to all
+// connected clients, except users who turned off in-app notifications for the event type
+// or are in do-not-disturb mode
+// (users who blocked the author are filtered in their own writePump)
+func (h *ConnectionHub) BroadcastNotification(eventType, msgType string, authorID uint, data interface{}) error {
+	msgData, err := json.Marshal(WSMessage{Type: msgType, Data: data})
+	if err != nil {
+		h.logger.Error("Failed to marshal notification", "error", err.Error(), "type", msgType)
+		return err
+	}
+	message := outboundMessage{senderID: authorID, data: msgData}
+
+	if h.prefsRepo == nil {
+		select {
+		case h.broadcast <- message:
+			return nil
+		default:
+			h.logger.Warn("Broadcast channel full, notification dropped", "type", msgType)
+			return fmt.Errorf("broadcast channel full")
+		}
+	}
+
+	h.mu.RLock()
+	userIDs := make([]uint, 0, len(h.clients))
+	for userID := range h.clients {
+		userIDs = append(userIDs, userID)
+	}
+	h.mu.RUnlock()
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	// 偏好和免打扰查询放在锁外，避免数据库延迟阻塞连接注册
+	recipients := h.prefsRepo.FilterInAppRecipients(context.Background(), eventType, userIDs)
+	recipients = h.prefsRepo.FilterPushRecipients(context.Background(), recipients)
+
+	// 发送时持有读锁：连接只有先从map移除才会关闭send通道，与run中的广播保持一致
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, userID := range recipients {
+		for _, client := range h.clients[userID] {
+			select {
+			case client.send <- message:
+			default:
+				h.logger.Warn("Client send buffer full, notification dropped", "userID", userID, "type", msgType)
+				if h.recordDrop(client) {
+					client.close()
+				}
+			}
+		}
+	}
+
+	h.logger.Debug("Notification sent", "type", msgType, "eventType", eventType,
+		"recipients", len(recipients), "skipped", len(userIDs)-len(recipients))
+	return nil
+}
+
 // NotifyPrivateMessage sends a private message notification to a specific user
 func NotifyPrivateMessage(receiverID uint, message *models.MessageResponse) {
-	if globalHub == nil {
+	// Capture the hub once; the persist task below runs after this returns
+	hub := globalHub
+	if hub == nil {
 		utils.GetLogger().Warn("WebSocket hub not initialized, cannot send private message notification")
 		return
 	}
@@ -300,17 +736,61 @@ func NotifyPrivateMessage(receiverID uint, message *models.MessageResponse) {
 		"message_id": message.ID,
 	}
 
-	globalHub.logger.Info("Sending private message notification",
+	hub.logger.Info("Sending private message notification",
 		"receiverID", receiverID,
 		"messageID", message.ID,
 		"senderID", message.Sender.ID)
 
-	err := globalHub.SendToUser(receiverID, "private_message", data)
-	if err != nil {
-		globalHub.logger.Error("Failed to send private message notification",
+	// Users in do-not-disturb mode only get the inbox record below
+	if hub.isMuted(receiverID) {
+		hub.logger.Debug("Receiver in do-not-disturb mode, push skipped", "receiverID", receiverID)
+	} else if err := hub.SendToUser(receiverID, "private_message", data); err != nil {
+		hub.logger.Error("Failed to send private message notification",
 			"error", err.Error(),
 			"receiverID", receiverID)
 	}
+
+	hub.persistNotifications(fmt.Sprintf("notify_message_%d", message.ID), func(ctx context.Context) error {
+		return hub.notifyRepo.Create(ctx, []models.Notification{{
+			UserID:     receiverID,
+			ActorID:    message.Sender.ID,
+			EventType:  models.NotificationEventMessage,
+			TargetType: models.NotificationTargetMessage,
+			TargetID:   message.ConversationID,
+			Content:    message.Content,
+		}})
+	})
+}
+
+// isMuted reports whether the user is in do-not-disturb mode
+func (h *ConnectionHub) isMuted(userID uint) bool {
+	if h.prefsRepo == nil {
+		return false
+	}
+	return len(h.prefsRepo.FilterPushRecipients(context.Background(), []uint{userID})) == 0
+}
+
+// persistNotifications stores notification records in the background so
+// offline recipients can fetch them later
+func (h *ConnectionHub) persistNotifications(taskID string, fn func(ctx context.Context) error) {
+	if h.notifyRepo == nil {
+		return
+	}
+
+	err := utils.SubmitTask(taskID, func(ctx context.Context) error {
+		if err := fn(ctx); err != nil {
+			h.logger.Error("Failed to persist notifications",
+				"taskID", taskID,
+				"error", err.Error())
+			return err
+		}
+		return nil
+	}, 10*time.Second)
+	if err != nil {
+		h.logger.Warn("Failed to submit notification persist task",
+			"taskID", taskID,
+			"error", err.Error())
+	}
 }
 
 // NotifyMessageRead sends a message read notification to a specific user
@@ -338,9 +818,23 @@ func NotifyMessageRead(senderID uint, conversationID uint, readerID uint) {
 	}
 }
 
+// NotifyBlockChanged updates the blocked set of the blocker's live connections
+func NotifyBlockChanged(blockerID, blockedID uint, blocked bool) {
+	if globalHub == nil {
+		return
+	}
+
+	globalHub.mu.RLock()
+	defer globalHub.mu.RUnlock()
+	for _, client := range globalHub.clients[blockerID] {
+		client.setBlocked(blockedID, blocked)
+	}
+}
+
 // NotifyArticleComment broadcasts a new comment notification to all users
 func NotifyArticleComment(comment *models.ArticleComment, author *models.CommentAuthor, replyTo *models.CommentAuthor) {
-	if globalHub == nil {
+	hub := globalHub
+	if hub == nil {
 		utils.GetLogger().Warn("WebSocket hub not initialized, cannot send comment notification")
 		return
 	}
@@ -348,9 +842,11 @@ func NotifyArticleComment(comment *models.ArticleComment, author *models.Comment
 	// Determine notification type and message type
 	notifType := "new_comment"
 	msgType := "article_comment"
+	eventType := models.NotificationEventComment
 	if comment.ParentID > 0 {
 		notifType = "new_reply"
 		msgType = "article_reply"
+		eventType = models.NotificationEventReply
 	}
 
 	var replyToUserID interface{}
@@ -411,33 +907,41 @@ func NotifyArticleComment(comment *models.ArticleComment, author *models.Comment
 		"reply_to_user": replyToPayload,
 	}
 
-	globalHub.logger.Info("Broadcasting article comment notification",
+	hub.logger.Info("Broadcasting article comment notification",
 		"articleID", comment.ArticleID,
 		"commentID", comment.ID,
 		"userID", comment.UserID,
 		"type", notifType)
 
-	err := globalHub.BroadcastToAll(msgType, data)
+	err := hub.BroadcastNotification(eventType, msgType, comment.UserID, data)
 	if err != nil {
-		globalHub.logger.Error("Failed to broadcast comment notification",
+		hub.logger.Error("Failed to broadcast comment notification",
 			"error", err.Error(),
 			"articleID", comment.ArticleID,
 			"commentID", comment.ID)
 	}
+
+	hub.persistNotifications(fmt.Sprintf("notify_article_comment_%d", comment.ID), func(ctx context.Context) error {
+		return hub.notifyRepo.CreateCommentNotifications(ctx, models.NotificationTargetArticle,
+			comment.ArticleID, comment.ID, comment.ParentID, comment.UserID, comment.ReplyToUserID, comment.Content)
+	})
 }
 
 // NotifyResourceComment broadcasts a new resource comment notification to all users
 func NotifyResourceComment(comment *models.ResourceComment, author *models.CommentUser, replyTo *models.CommentUser) {
-	if globalHub == nil {
+	hub := globalHub
+	if hub == nil {
 		utils.GetLogger().Warn("WebSocket hub not initialized, cannot send resource comment notification")
 		return
 	}
 
 	notifType := "new_comment"
 	msgType := "resource_comment"
+	eventType := models.NotificationEventComment
 	if comment.ParentID > 0 {
 		notifType = "new_reply"
 		msgType = "resource_reply"
+		eventType = models.NotificationEventReply
 	}
 
 	var replyToUserID interface{}
@@ -495,22 +999,27 @@ func NotifyResourceComment(comment *models.ResourceComment, author *models.Comme
 		"reply_to_user": replyToPayload,
 	}
 
-	globalHub.logger.Info("Broadcasting resource comment notification",
+	hub.logger.Info("Broadcasting resource comment notification",
 		"resourceID", comment.ResourceID,
 		"commentID", comment.ID,
 		"userID", comment.UserID,
 		"type", notifType)
 
-	if err := globalHub.BroadcastToAll(msgType, data); err != nil {
-		globalHub.logger.Error("Failed to broadcast resource comment notification",
+	if err := hub.BroadcastNotification(eventType, msgType, comment.UserID, data); err != nil {
+		hub.logger.Error("Failed to broadcast resource comment notification",
 			"error", err.Error(),
 			"resourceID", comment.ResourceID,
 			"commentID", comment.ID)
 	}
+
+	hub.persistNotifications(fmt.Sprintf("notify_resource_comment_%d", comment.ID), func(ctx context.Context) error {
+		return hub.notifyRepo.CreateCommentNotifications(ctx, models.NotificationTargetResource,
+			comment.ResourceID, comment.ID, comment.ParentID, comment.UserID, comment.ReplyToUserID, comment.Content)
+	})
 }
 
 // NotifyNewResource broadcasts a new resource notification to all users
-func NotifyNewResource(resource interface{}) {
+func NotifyNewResource(authorID uint, resource interface{}) {
 	if globalHub == nil {
 		utils.GetLogger().Warn("WebSocket hub not initialized, cannot send new resource notification")
 		return
@@ -525,14 +1034,14 @@ func NotifyNewResource(resource interface{}) {
 	globalHub.logger.Info("Broadcasting new resource notification",
 		"resourceData", resource)
 
-	if err := globalHub.BroadcastToAll("new_resource", data); err != nil {
+	if err := globalHub.BroadcastNotification(models.NotificationEventNewContent, "new_resource", authorID, data); err != nil {
 		globalHub.logger.Error("Failed to broadcast new resource notification",
 			"error", err.Error())
 	}
 }
 
 // NotifyNewArticle broadcasts a new article notification to all users
-func NotifyNewArticle(article interface{}) {
+func NotifyNewArticle(authorID uint, article interface{}) {
 	if globalHub == nil {
 		utils.GetLogger().Warn("WebSocket hub not initialized, cannot send new article notification")
 		return
@@ -547,14 +1056,14 @@ func NotifyNewArticle(article interface{}) {
 	globalHub.logger.Info("Broadcasting new article notification",
 		"articleData", article)
 
-	if err := globalHub.BroadcastToAll("new_article", data); err != nil {
+	if err := globalHub.BroadcastNotification(models.NotificationEventNewContent, "new_article", authorID, data); err != nil {
 		globalHub.logger.Error("Failed to broadcast new article notification",
 			"error", err.Error())
 	}
 }
 
 // NotifyNewCodeSnippet broadcasts a new code snippet notification to all users
-func NotifyNewCodeSnippet(snippet interface{}) {
+func NotifyNewCodeSnippet(authorID uint, snippet interface{}) {
 	if globalHub == nil {
 		utils.GetLogger().Warn("WebSocket hub not initialized, cannot send new code snippet notification")
 		return
@@ -569,20 +1078,48 @@ func NotifyNewCodeSnippet(snippet interface{}) {
 	globalHub.logger.Info("Broadcasting new code snippet notification",
 		"snippetData", snippet)
 
-	if err := globalHub.BroadcastToAll("new_code", data); err != nil {
+	if err := globalHub.BroadcastNotification(models.NotificationEventNewContent, "new_code", authorID, data); err != nil {
 		globalHub.logger.Error("Failed to broadcast new code snippet notification",
 			"error", err.Error())
 	}
 }
 
+// readMessage reads the next message, reporting messages larger than MaxMessageSize as oversized.
+// The remainder of an oversized message is discarded so the next read starts at a message boundary.
+func (c *Client) readMessage() ([]byte, bool, error) {
+	_, r, err := c.conn.NextReader()
+	if err != nil {
+		return nil, false, err
+	}
+
+	limit := int64(c.hub.config.MaxMessageSize)
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if int64(len(data)) <= limit {
+		return data, false, nil
+	}
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return nil, true, err
+	}
+	return nil, true, nil
+}
+
 // readPump pumps messages from the WebSocket connection to the hub
 func (c *Client) readPump() {
 	defer func() {
-		c.hub.unregister <- c
+		// After shutdown the run loop no longer receives; disconnectAll has already removed the client
+		select {
+		case c.hub.unregister <- c:
+		case <-c.hub.stopCh:
+		}
 		c.close()
 	}()
 
-	c.conn.SetReadLimit(int64(c.hub.config.MaxMessageSize))
+	// The library closes the connection once the hard limit is exceeded, so only messages between
+	// MaxMessageSize and the hard limit can be answered with a structured error
+	c.conn.SetReadLimit(int64(c.hub.config.OversizeHardLimit))
 	c.conn.SetReadDeadline(time.Now().Add(time.Duration(c.hub.config.PongWait) * time.Second))
 	c.conn.SetPongHandler(func(string) error {
 		c.conn.SetReadDeadline(time.Now().Add(time.Duration(c.hub.config.PongWait) * time.Second))
@@ -590,13 +1127,31 @@ func (c *Client) readPump() {
 	})
 
 	for {
-		_, messageBytes, err := c.conn.ReadMessage()
+		messageBytes, oversized, err := c.readMessage()
 		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+			if errors.Is(err, websocket.ErrReadLimit) {
+				count, _ := c.hub.recordOversize(c.userID)
+				c.hub.logger.Warn("WebSocket message exceeded hard limit, connection closed",
+					"userID", c.userID, "limit", c.hub.config.OversizeHardLimit, "violations", count)
+			} else if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				c.hub.logger.Error("WebSocket read error", "error", err.Error(), "userID", c.userID)
 			}
 			break
 		}
+		if oversized {
+			count, banned := c.hub.recordOversize(c.userID)
+			c.hub.logger.Warn("Oversized WebSocket message dropped", "userID", c.userID,
+				"max", c.hub.config.MaxMessageSize, "violations", count, "banned", banned)
+			c.sendNack("", utils.ErrCodeMessageTooLarge, "消息过大", nil)
+			if banned {
+				// The close frame carries the reason in case the queued nack is not flushed first
+				_ = c.conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "message too large"),
+					time.Now().Add(time.Duration(c.hub.config.WriteWait)*time.Second))
+				break
+			}
+			continue
+		}
 
 		var wsMsg WSMessage
 		if err := json.Unmarshal(messageBytes, &wsMsg); err != nil {
@@ -608,29 +1163,25 @@ func (c *Client) readPump() {
 		case "heartbeat":
 			// Heartbeat - respond to client to acknowledge receipt
 			// Don't save heartbeat to database
-			heartbeatResp := WSMessage{
-				Type: "heartbeat",
-				Data: map[string]interface{}{"timestamp": time.Now().Unix()},
-			}
-			if respData, err := json.Marshal(heartbeatResp); err == nil {
-				select {
-				case c.send <- respData:
-				default:
-					c.hub.logger.Warn("Heartbeat response buffer full", "userID", c.userID)
-				}
-			}
+			c.sendReply("heartbeat", map[string]interface{}{"timestamp": time.Now().Unix()})
 
 		case "message":
 			// Chat message - save to database and broadcast
 			dataMap, ok := wsMsg.Data.(map[string]interface{})
 			if !ok {
 				c.hub.logger.Error("Invalid message data format", "userID", c.userID)
+				c.sendNack("", utils.ErrCodeInvalidInput, "消息格式错误", nil)
 				continue
 			}
 
+			// Optional client-generated ID echoed in ack/nack so the UI can reconcile optimistic sends.
+			// It is also stored with the message, so a replay after reconnecting does not create a duplicate
+			clientMsgID := parseClientMsgID(dataMap["client_msg_id"])
+
 			content, ok := dataMap["content"].(string)
 			if !ok {
 				c.hub.logger.Error("Invalid message content type", "userID", c.userID)
+				c.sendNack(clientMsgID, utils.ErrCodeInvalidInput, "消息格式错误", nil)
 				continue
 			}
 
@@ -640,6 +1191,7 @@ func (c *Client) readPump() {
 			// Validate content is not empty after trimming
 			if len(content) == 0 {
 				c.hub.logger.Warn("Empty message after trim", "userID", c.userID)
+				c.sendNack(clientMsgID, utils.ErrCodeValidationFailed, "消息不能为空", nil)
 				continue
 			}
 
@@ -647,6 +1199,7 @@ func (c *Client) readPump() {
 			messageLen := utf8.RuneCountInString(content)
 			if messageLen > c.hub.config.MaxMessageLength {
 				c.hub.logger.Warn("Message too long (characters)", "userID", c.userID, "length", messageLen, "max", c.hub.config.MaxMessageLength)
+				c.sendNack(clientMsgID, utils.ErrCodeValidationFailed, "消息过长", nil)
 				continue
 			}
 
@@ -656,6 +1209,7 @@ func (c *Client) readPump() {
 			// Reserve 600 bytes for JSON structure overhead
 			if contentBytes > c.hub.config.MaxMessageSize-600 {
 				c.hub.logger.Warn("Message too long (bytes)", "userID", c.userID, "bytes", contentBytes, "max", c.hub.config.MaxMessageSize-600)
+				c.sendNack(clientMsgID, utils.ErrCodeValidationFailed, "消息过长", nil)
 				continue
 			}
 
@@ -667,6 +1221,7 @@ func (c *Client) readPump() {
 				if c.messageCount > c.hub.config.MaxMessagesPerSecond {
 					c.mu.Unlock()
 					c.hub.logger.Warn("Rate limit exceeded", "userID", c.userID, "count", c.messageCount)
+					c.sendNack(clientMsgID, utils.ErrCodeRateLimitExceeded, "发送过于频繁", nil)
 					continue
 				}
 			} else {
@@ -675,10 +1230,34 @@ func (c *Client) readPump() {
 			}
 			c.mu.Unlock()
 
-			// Save message to database
-			message, err := c.hub.chatRepo.SendMessage(c.userID, c.username, c.nickname, c.avatar, content, c.ipAddress)
+			// Content moderation: muted users cannot chat, rejected content is not saved
+			if until, muted := utils.ChatMutedUntil(c.userID); muted {
+				c.sendNack(clientMsgID, utils.ErrCodeUserMuted, "您因多次发布违规内容已被临时禁言", &until)
+				continue
+			}
+			if err := utils.ModerateTexts(c.userID, &content); err != nil {
+				c.sendNack(clientMsgID, utils.GetErrorCode(err), "消息包含违规内容", nil)
+				continue
+			}
+
+			// Save message to database (a replayed client_msg_id resolves to the stored message)
+			message, replayed, err := c.hub.chatRepo.SendMessage(c.userID, c.username, c.nickname, c.avatar, content, c.ipAddress, clientMsgID)
 			if err != nil {
 				c.hub.logger.Error("Failed to save message", "error", err.Error(), "userID", c.userID)
+				c.sendNack(clientMsgID, utils.ErrCodeDatabaseError, "消息保存失败，请重试", nil)
+				continue
+			}
+
+			// Tell the sender the message was persisted (the broadcast payload is unchanged)
+			c.sendReply("ack", map[string]interface{}{
+				"client_msg_id": clientMsgID,
+				"server_id":     message.ID,
+				"send_time":     message.SendTime,
+				"duplicate":     replayed,
+			})
+
+			// The original send was already broadcast
+			if replayed {
 				continue
 			}
 
@@ -694,7 +1273,10 @@ func (c *Client) readPump() {
 				continue
 			}
 
-			c.hub.broadcast <- data
+			select {
+			case c.hub.broadcast <- outboundMessage{senderID: c.userID, data: data}:
+			case <-c.hub.stopCh:
+			}
 
 		default:
 			// Unknown message type
@@ -703,12 +1285,61 @@ func (c *Client) readPump() {
 	}
 }
 
+// parseClientMsgID reads the optional client_msg_id (string or number) from an inbound message
+func parseClientMsgID(v interface{}) string {
+	switch id := v.(type) {
+	case string:
+		if len(id) > maxClientMsgIDLength {
+			return strings.ToValidUTF8(id[:maxClientMsgIDLength], "")
+		}
+		return id
+	case float64:
+		return strconv.FormatFloat(id, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
+
+// maxClientMsgIDLength caps the echoed client_msg_id so clients cannot inflate replies
+const maxClientMsgIDLength = 64
+
+// sendNack tells the sender why their chat message was not saved
+func (c *Client) sendNack(clientMsgID, code, message string, mutedUntil *time.Time) {
+	data := map[string]interface{}{"client_msg_id": clientMsgID, "code": code, "message": message}
+	if mutedUntil != nil {
+		data["muted_until"] = mutedUntil.Unix()
+	}
+	c.sendReply("nack", data)
+}
+
+// sendReply queues a message for this client only
+func (c *Client) sendReply(msgType string, data interface{}) {
+	respData, err := json.Marshal(WSMessage{Type: msgType, Data: data})
+	if err != nil {
+		c.hub.logger.Error("Failed to marshal reply", "error", err.Error(), "type", msgType)
+		return
+	}
+
+	// Hold the lock so the hub cannot close the channel mid-send (e.g. when evicting this client)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.channelClosed {
+		return
+	}
+	select {
+	case c.send <- outboundMessage{data: respData}:
+	default:
+		c.hub.logger.Warn("Reply buffer full", "userID", c.userID, "type", msgType)
+	}
+}
+
 // writePump pumps messages from the hub to the WebSocket connection
 func (c *Client) writePump() {
 	ticker := time.NewTicker(time.Duration(c.hub.config.PingPeriod) * time.Second)
 	defer func() {
 		ticker.Stop()
 		c.close()
+		c.hub.releaseConnection()
 	}()
 
 	for {
@@ -717,21 +1348,34 @@ func (c *Client) writePump() {
 			c.conn.SetWriteDeadline(time.Now().Add(time.Duration(c.hub.config.WriteWait) * time.Second))
 			if !ok {
 				// Hub closed the channel
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				closeMsg := []byte{}
+				if c.hub.isClosing() {
+					closeMsg = websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutdown")
+				}
+				c.conn.WriteMessage(websocket.CloseMessage, closeMsg)
 				return
 			}
 
+			// Drop content from blocked users here so the shared broadcast stays per-message
+			if c.isBlocked(message.senderID) {
+				continue
+			}
+
 			w, err := c.conn.NextWriter(websocket.TextMessage)
 			if err != nil {
 				return
 			}
-			w.Write(message)
+			w.Write(message.data)
 
 			// Add queued messages to the current websocket message
 			n := len(c.send)
 			for i := 0; i < n; i++ {
+				queued := <-c.send
+				if c.isBlocked(queued.senderID) {
+					continue
+				}
 				w.Write([]byte{'\n'})
-				w.Write(<-c.send)
+				w.Write(queued.data)
 			}
 
 			if err := w.Close(); err != nil {
@@ -755,6 +1399,10 @@ func (h *ChatHandler) HandleWebSocket(c *gin.Context) {
 		utils.ErrorResponse(c, 500, "Chat service unavailable")
 		return
 	}
+	if globalHub.isClosing() {
+		utils.ErrorResponse(c, http.StatusServiceUnavailable, "Chat service is shutting down")
+		return
+	}
 
 	// User is already authenticated by AuthMiddleware
 	userID, err := utils.GetUserIDFromContext(c)
@@ -764,6 +1412,15 @@ func (h *ChatHandler) HandleWebSocket(c *gin.Context) {
 		return
 	}
 
+	// Users temporarily banned for repeatedly sending oversized messages cannot reconnect until the ban expires
+	if until, banned := globalHub.oversizeBannedUntil(userID); banned {
+		h.logger.Warn("WebSocket connection rejected: oversize ban", "userID", userID, "until", until)
+		c.Header("Retry-After", strconv.Itoa(int(time.Until(until).Seconds())+1))
+		utils.CodeErrorResponse(c, http.StatusTooManyRequests, utils.ErrCodeRateLimitExceeded,
+			fmt.Sprintf("发送超大消息次数过多，请在 %s 后重新连接", until.Format("2006-01-02 15:04:05")))
+		return
+	}
+
 	// 使用辅助函数获取用户信息
 	userInfo, err := GetUserWithProfile(c.Request.Context(), h.userRepo, userID)
 	if err != nil {
@@ -772,15 +1429,40 @@ func (h *ChatHandler) HandleWebSocket(c *gin.Context) {
 		return
 	}
 
+	// 加载屏蔽列表，失败时不影响连接
+	blocked := make(map[uint]bool)
+	if blockedIDs, err := h.userRepo.GetBlockedIDs(c.Request.Context(), userID); err != nil {
+		h.logger.Warn("Failed to load blocked users", "userID", userID, "error", err.Error())
+	} else {
+		for _, id := range blockedIDs {
+			blocked[id] = true
+		}
+	}
+
 	// Get client IP address before upgrade
 	clientIP := c.ClientIP()
 
 	// Create upgrader with CORS origin checking
 	upgrader := createUpgrader(h.config.CORS.AllowOrigins, &h.config.WebSocket, h.logger)
 
+	// Reserve the connection before upgrading so the global limit holds under connection storms
+	// and Shutdown waits for the write pump
+	if err := globalHub.reserveConnection(); err != nil {
+		if errors.Is(err, errHubFull) {
+			h.logger.Warn("WebSocket connection rejected: connection limit reached",
+				"userID", userID, "maxConnections", h.config.WebSocket.MaxConnections)
+			c.Header("Retry-After", "5")
+			utils.ErrorResponse(c, http.StatusServiceUnavailable, "Chat service is busy, please retry later")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusServiceUnavailable, "Chat service is shutting down")
+		return
+	}
+
 	// Upgrade HTTP connection to WebSocket
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
+		globalHub.releaseConnection()
 		h.logger.Error("Failed to upgrade to WebSocket", "error", err.Error(), "userID", userID)
 		return
 	}
@@ -789,7 +1471,7 @@ func (h *ChatHandler) HandleWebSocket(c *gin.Context) {
 	client := &Client{
 		hub:             globalHub,
 		conn:            conn,
-		send:            make(chan []byte, globalHub.config.ClientSendBufferSize),
+		send:            make(chan outboundMessage, globalHub.config.ClientSendBufferSize),
 		userID:          userID,
 		username:        userInfo.User.Username,
 		nickname:        userInfo.Nickname,
@@ -797,10 +1479,18 @@ func (h *ChatHandler) HandleWebSocket(c *gin.Context) {
 		ipAddress:       clientIP,
 		lastMessageTime: time.Now(),
 		messageCount:    0,
+		blocked:         blocked,
 	}
 
-	// Register client
-	globalHub.register <- client
+	// Register client (the hub stops receiving registrations once shut down)
+	select {
+	case globalHub.register <- client:
+	case <-globalHub.stopCh:
+		globalHub.releaseConnection()
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutdown"))
+		conn.Close()
+		return
+	}
 
 	// Start write pump in background
 	go client.writePump()
@@ -823,13 +1513,25 @@ func (h *ChatHandler) GetOnlineCountWS(c *gin.Context) {
 }
 
 // GetOnlineUsersWS returns online users from WebSocket hub
+// ?fresh=true reloads profiles from the database; by default the connect-time values are returned
 func (h *ChatHandler) GetOnlineUsersWS(c *gin.Context) {
 	if globalHub == nil {
 		utils.ErrorResponse(c, 500, "WebSocket hub not initialized")
 		return
 	}
 
-	users := globalHub.GetOnlineUsers()
+	var users []map[string]interface{}
+	if c.Query("fresh") == "true" {
+		var err error
+		users, err = globalHub.GetOnlineUsersFresh(c.Request.Context())
+		if err != nil {
+			// Fall back to the in-memory snapshot rather than failing the request
+			h.logger.Warn("Failed to load fresh online user profiles", "error", err.Error())
+			users = globalHub.GetOnlineUsers()
+		}
+	} else {
+		users = globalHub.GetOnlineUsers()
+	}
 	utils.SuccessResponse(c, 200, "Success", gin.H{
 		"users": users,
 		"count": len(users),
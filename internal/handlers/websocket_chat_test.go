@@ -0,0 +1,685 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/services"
+	"gin/internal/testutil"
+	"gin/internal/utils"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/gorilla/websocket"
+)
+
+func TestClientRecordDropWindow(t *testing.T) {
+	c := &Client{}
+	for i := 1; i <= 3; i++ {
+		if drops, evict := c.recordDrop(3, time.Minute); drops != i || evict {
+			t.Fatalf("第 %d 次丢弃未超过阈值，不应断开，实际 drops=%d evict=%v", i, drops, evict)
+		}
+	}
+	if drops, evict := c.recordDrop(3, time.Minute); drops != 4 || !evict {
+		t.Fatalf("窗口内丢弃超过阈值应断开，实际 drops=%d evict=%v", drops, evict)
+	}
+
+	// 窗口过后重新计数
+	c.dropWindowStart = time.Now().Add(-2 * time.Minute)
+	if drops, evict := c.recordDrop(3, time.Minute); drops != 1 || evict {
+		t.Fatalf("窗口过后应重新计数，实际 drops=%d evict=%v", drops, evict)
+	}
+
+	// 阈值为0表示不断开
+	c = &Client{}
+	for i := 0; i < 100; i++ {
+		if _, evict := c.recordDrop(0, time.Minute); evict {
+			t.Fatal("max_drops 为0时不应断开慢客户端")
+		}
+	}
+}
+
+func TestHubEvictsSlowClient(t *testing.T) {
+	cfg := config.Default().WebSocket
+	cfg.SlowClientMaxDrops = 3
+	cfg.SlowClientWindowSec = 30
+	hub := &ConnectionHub{
+		clients:    make(map[uint][]*Client),
+		broadcast:  make(chan outboundMessage, 16),
+		register:   make(chan *Client),
+		unregister: make(chan *Client),
+		logger:     utils.GetLogger(),
+		config:     &cfg,
+		stopCh:     make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+
+	// 慢客户端的缓冲区已满；没有真实连接，预先消耗 closeOnce 使 close 不访问 conn
+	slow := &Client{userID: 1, send: make(chan outboundMessage, 1), blocked: map[uint]bool{}}
+	slow.send <- outboundMessage{data: []byte("stuck")}
+	slow.closeOnce.Do(func() {})
+	healthy := &Client{userID: 2, send: make(chan outboundMessage, 32), blocked: map[uint]bool{}}
+	hub.clients[1] = []*Client{slow}
+	hub.clients[2] = []*Client{healthy}
+
+	go hub.run()
+	t.Cleanup(func() {
+		close(hub.stopCh)
+		<-hub.done
+	})
+
+	for i := 0; i < 3; i++ {
+		hub.broadcast <- outboundMessage{data: []byte("msg")}
+	}
+	waitForDelivered(t, healthy, 3)
+	hub.mu.RLock()
+	_, stillOnline := hub.clients[1]
+	hub.mu.RUnlock()
+	if !stillOnline {
+		t.Fatal("丢弃次数未超过阈值时不应断开")
+	}
+
+	hub.broadcast <- outboundMessage{data: []byte("msg")}
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		hub.mu.RLock()
+		_, stillOnline = hub.clients[1]
+		hub.mu.RUnlock()
+		if !stillOnline {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if stillOnline {
+		t.Fatal("缓冲区持续已满的客户端应被移出在线列表")
+	}
+	slow.mu.Lock()
+	closed := slow.channelClosed
+	slow.mu.Unlock()
+	if !closed {
+		t.Fatal("被断开的客户端应关闭发送通道")
+	}
+
+	// 正常客户端不受影响，并收到在线人数变化
+	waitForDelivered(t, healthy, 2)
+}
+
+func TestHubEvictionWithFullBroadcastBuffer(t *testing.T) {
+	cfg := config.Default().WebSocket
+	hub := &ConnectionHub{
+		clients:    make(map[uint][]*Client),
+		broadcast:  make(chan outboundMessage, 1),
+		register:   make(chan *Client),
+		unregister: make(chan *Client),
+		logger:     utils.GetLogger(),
+		config:     &cfg,
+		stopCh:     make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	slow := &Client{userID: 1, send: make(chan outboundMessage, 1), blocked: map[uint]bool{}}
+	healthy := &Client{userID: 2, send: make(chan outboundMessage, 32), blocked: map[uint]bool{}}
+	hub.clients[1] = []*Client{slow}
+	hub.clients[2] = []*Client{healthy}
+
+	// 广播缓冲区已满时在 run 中移除客户端，推送在线人数不能阻塞 run 自身
+	hub.broadcast <- outboundMessage{data: []byte("queued")}
+	removed := make(chan struct{})
+	go func() {
+		hub.removeClient(slow)
+		close(removed)
+	}()
+	select {
+	case <-removed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("广播缓冲区已满时移除客户端不应阻塞")
+	}
+
+	go hub.run()
+	t.Cleanup(func() {
+		close(hub.stopCh)
+		<-hub.done
+	})
+
+	// 缓冲区腾出空间后，下一轮循环补发最新的在线人数
+	var frames []string
+	for i := 0; i < 2; i++ {
+		select {
+		case msg := <-healthy.send:
+			frames = append(frames, string(msg.data))
+		case <-time.After(2 * time.Second):
+			t.Fatalf("应先收到排队的消息再收到在线人数，实际 %q", frames)
+		}
+	}
+	if frames[0] != "queued" || !strings.Contains(frames[1], `"online_count"`) || !strings.Contains(frames[1], `"count":1`) {
+		t.Fatalf("在线人数应在缓冲区腾出空间后补发，实际 %q", frames)
+	}
+}
+
+// waitForDelivered 从客户端的发送通道读取 n 条消息
+func waitForDelivered(t *testing.T, c *Client, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		select {
+		case <-c.send:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("客户端 %d 应收到 %d 条消息，实际 %d 条", c.userID, n, i)
+		}
+	}
+}
+
+// newTestHub 创建并启动使用指定聊天仓库的连接中心，测试结束时停止
+func newTestHub(t *testing.T, chatRepo *services.ChatRepository, cfg *config.WebSocketConfig) *ConnectionHub {
+	t.Helper()
+	hub := &ConnectionHub{
+		clients:    make(map[uint][]*Client),
+		broadcast:  make(chan outboundMessage, 16),
+		register:   make(chan *Client),
+		unregister: make(chan *Client),
+		chatRepo:   chatRepo,
+		logger:     utils.GetLogger(),
+		config:     cfg,
+		oversize:   make(map[uint]*oversizeRecord),
+		stopCh:     make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	go hub.run()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		_ = hub.Shutdown(ctx)
+	})
+	return hub
+}
+
+// wsTestConn 测试用的WebSocket客户端连接
+type wsTestConn struct {
+	*websocket.Conn
+	pending [][]byte // 已读取但尚未处理的消息（服务端可能把多条消息用换行合并为一帧）
+}
+
+// dialTestClient 建立一个连接到 hub 的真实 WebSocket 连接（跳过鉴权和用户信息查询）
+func dialTestClient(t *testing.T, hub *ConnectionHub, userID uint) *wsTestConn {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := hub.reserveConnection(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			hub.releaseConnection()
+			return
+		}
+		client := &Client{hub: hub, conn: conn, send: make(chan outboundMessage, 32), userID: userID,
+			username: "user", lastMessageTime: time.Now(), blocked: map[uint]bool{}}
+		hub.register <- client
+		go client.writePump()
+		client.readPump()
+	}))
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("建立WebSocket连接失败: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &wsTestConn{Conn: conn}
+}
+
+// next 读取下一条指定类型的消息，跳过其他类型
+func (c *wsTestConn) next(t *testing.T, msgType string) map[string]interface{} {
+	t.Helper()
+	c.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		if len(c.pending) == 0 {
+			_, frame, err := c.ReadMessage()
+			if err != nil {
+				t.Fatalf("等待 %s 消息失败: %v", msgType, err)
+			}
+			c.pending = bytes.Split(frame, []byte{'\n'})
+		}
+		line := c.pending[0]
+		c.pending = c.pending[1:]
+
+		var msg struct {
+			Type string                 `json:"type"`
+			Data map[string]interface{} `json:"data"`
+		}
+		if err := json.Unmarshal(line, &msg); err != nil {
+			t.Fatalf("解析消息失败: %v %s", err, line)
+		}
+		if msg.Type == msgType {
+			return msg.Data
+		}
+	}
+}
+
+func TestChatMessageAck(t *testing.T) {
+	cfg := newTestConfig()
+	fake, db := newFakeDatabase(t, cfg)
+	fake.OnExec(`INSERT INTO chat_messages`, 42, 1)
+	wsCfg := cfg.WebSocket
+	hub := newTestHub(t, services.NewChatRepository(db, cfg), &wsCfg)
+	conn := dialTestClient(t, hub, 1)
+
+	if err := conn.WriteJSON(WSMessage{Type: "message", Data: map[string]interface{}{"content": " 你好 ", "client_msg_id": "c-1"}}); err != nil {
+		t.Fatalf("发送消息失败: %v", err)
+	}
+	ack := conn.next(t, "ack")
+	if ack["client_msg_id"] != "c-1" || ack["server_id"] != float64(42) || ack["send_time"] == nil {
+		t.Fatalf("ack 应回显 client_msg_id 并带上服务端ID和发送时间，实际 %v", ack)
+	}
+	broadcast := conn.next(t, "message")
+	if broadcast["id"] != float64(42) || broadcast["content"] != "你好" || broadcast["server_id"] != nil {
+		t.Fatalf("广播的消息体应保持原有结构，实际 %v", broadcast)
+	}
+
+	// 数字类型的 client_msg_id 也会回显
+	_ = conn.WriteJSON(WSMessage{Type: "message", Data: map[string]interface{}{"content": "hi", "client_msg_id": 17}})
+	if ack := conn.next(t, "ack"); ack["client_msg_id"] != "17" {
+		t.Fatalf("数字 client_msg_id 应转为字符串回显，实际 %v", ack)
+	}
+}
+
+func TestChatMessageNack(t *testing.T) {
+	cfg := newTestConfig()
+	fake, db := newFakeDatabase(t, cfg)
+	fake.OnError(`INSERT INTO chat_messages`, errors.New("connection reset"))
+	wsCfg := cfg.WebSocket
+	hub := newTestHub(t, services.NewChatRepository(db, cfg), &wsCfg)
+	conn := dialTestClient(t, hub, 1)
+
+	cases := []struct {
+		name     string
+		data     interface{}
+		wantID   string
+		wantCode string
+	}{
+		{"保存失败", map[string]interface{}{"content": "hello", "client_msg_id": "c-1"}, "c-1", utils.ErrCodeDatabaseError},
+		{"内容为空", map[string]interface{}{"content": "   ", "client_msg_id": "c-2"}, "c-2", utils.ErrCodeValidationFailed},
+		{"内容过长", map[string]interface{}{"content": strings.Repeat("长", wsCfg.MaxMessageLength+1), "client_msg_id": "c-3"}, "c-3", utils.ErrCodeValidationFailed},
+		{"数据格式错误", "not-an-object", "", utils.ErrCodeInvalidInput},
+	}
+	for i, tc := range cases {
+		if i > 0 && i%wsCfg.MaxMessagesPerSecond == 0 {
+			time.Sleep(time.Second) // 避开每秒消息数限制
+		}
+		if err := conn.WriteJSON(WSMessage{Type: "message", Data: tc.data}); err != nil {
+			t.Fatalf("发送消息失败: %v", err)
+		}
+		nack := conn.next(t, "nack")
+		if nack["client_msg_id"] != tc.wantID || nack["code"] != tc.wantCode || nack["message"] == "" {
+			t.Fatalf("%s: nack 应为 %s/%s，实际 %v", tc.name, tc.wantID, tc.wantCode, nack)
+		}
+	}
+}
+
+func TestChatMessageReplayIsDeduplicated(t *testing.T) {
+	cfg := newTestConfig()
+	fake, db := newFakeDatabase(t, cfg)
+	var inserts atomic.Int64
+	fake.On(`INSERT INTO chat_messages`, func(args []driver.Value) testutil.Response {
+		// 第2次插入为重放，唯一索引冲突
+		switch inserts.Add(1) {
+		case 1:
+			return testutil.Response{LastInsertID: 42, RowsAffected: 1}
+		case 2:
+			return testutil.Response{Err: &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}}
+		default:
+			return testutil.Response{LastInsertID: 43, RowsAffected: 1}
+		}
+	})
+	now := time.Now().UTC()
+	fake.OnRows(`FROM chat_messages WHERE user_id = \? AND client_msg_id = \?`,
+		[]string{"id", "user_id", "username", "nickname", "avatar", "content", "message_type", "send_time", "status", "created_at"},
+		[]driver.Value{int64(42), int64(1), "user", nil, nil, "你好", int64(1), now, int64(1), now})
+	wsCfg := cfg.WebSocket
+	hub := newTestHub(t, services.NewChatRepository(db, cfg), &wsCfg)
+	conn := dialTestClient(t, hub, 1)
+
+	_ = conn.WriteJSON(WSMessage{Type: "message", Data: map[string]interface{}{"content": "你好", "client_msg_id": "c-1"}})
+	if ack := conn.next(t, "ack"); ack["server_id"] != float64(42) || ack["duplicate"] != false {
+		t.Fatalf("首次发送的 ack 应带上新消息ID，实际 %v", ack)
+	}
+	if msg := conn.next(t, "message"); msg["id"] != float64(42) {
+		t.Fatalf("首次发送应广播消息，实际 %v", msg)
+	}
+
+	// 重连后重放：ack 返回已保存的消息ID，不再广播
+	_ = conn.WriteJSON(WSMessage{Type: "message", Data: map[string]interface{}{"content": "你好", "client_msg_id": "c-1"}})
+	if ack := conn.next(t, "ack"); ack["server_id"] != float64(42) || ack["duplicate"] != true {
+		t.Fatalf("重放的 ack 应带上已保存的消息ID，实际 %v", ack)
+	}
+	_ = conn.WriteJSON(WSMessage{Type: "message", Data: map[string]interface{}{"content": "再见", "client_msg_id": "c-2"}})
+	conn.next(t, "ack")
+	if msg := conn.next(t, "message"); msg["id"] != float64(43) {
+		t.Fatalf("重放的消息不应再次广播，下一条广播应为新消息，实际 %v", msg)
+	}
+}
+
+func TestParseClientMsgID(t *testing.T) {
+	long := strings.Repeat("字", maxClientMsgIDLength)
+	cases := []struct {
+		input interface{}
+		want  string
+	}{
+		{"abc-1", "abc-1"},
+		{float64(123), "123"},
+		{float64(1.5), "1.5"},
+		{nil, ""},
+		{true, ""},
+		{map[string]interface{}{}, ""},
+		{long, strings.Repeat("字", maxClientMsgIDLength/3)},
+	}
+	for _, tc := range cases {
+		if got := parseClientMsgID(tc.input); got != tc.want {
+			t.Errorf("parseClientMsgID(%v) = %q，期望 %q", tc.input, got, tc.want)
+		}
+	}
+}
+
+// oversizeTestConfig 小消息上限的WebSocket配置，便于构造超大消息
+func oversizeTestConfig() *config.WebSocketConfig {
+	cfg := config.Default().WebSocket
+	cfg.MaxMessageSize = 1024
+	cfg.OversizeHardLimit = 8192
+	cfg.OversizeMaxViolations = 3
+	cfg.OversizeWindowSec = 60
+	cfg.OversizeBanSec = 300
+	return &cfg
+}
+
+func TestOversizedMessageReturnsNack(t *testing.T) {
+	cfg := newTestConfig()
+	fake, db := newFakeDatabase(t, cfg)
+	fake.OnExec(`INSERT INTO chat_messages`, 42, 1)
+	hub := newTestHub(t, services.NewChatRepository(db, cfg), oversizeTestConfig())
+	conn := dialTestClient(t, hub, 1)
+
+	big := WSMessage{Type: "message", Data: map[string]interface{}{"content": strings.Repeat("a", 2000)}}
+	if err := conn.WriteJSON(big); err != nil {
+		t.Fatalf("发送消息失败: %v", err)
+	}
+	if nack := conn.next(t, "nack"); nack["code"] != utils.ErrCodeMessageTooLarge || nack["message"] == "" {
+		t.Fatalf("超大消息应收到 MESSAGE_TOO_LARGE 错误，实际 %v", nack)
+	}
+
+	// 连接保持可用，后续正常消息照常处理
+	_ = conn.WriteJSON(WSMessage{Type: "message", Data: map[string]interface{}{"content": "hi", "client_msg_id": "c-1"}})
+	if ack := conn.next(t, "ack"); ack["client_msg_id"] != "c-1" {
+		t.Fatalf("超大消息之后连接应仍可正常发送，实际 %v", ack)
+	}
+	if _, banned := hub.oversizeBannedUntil(1); banned {
+		t.Fatal("未达到次数上限时不应禁止连接")
+	}
+}
+
+func TestRepeatedOversizedMessagesDisconnect(t *testing.T) {
+	cfg := newTestConfig()
+	_, db := newFakeDatabase(t, cfg)
+	wsCfg := oversizeTestConfig()
+	hub := newTestHub(t, services.NewChatRepository(db, cfg), wsCfg)
+	conn := dialTestClient(t, hub, 1)
+
+	big := WSMessage{Type: "message", Data: map[string]interface{}{"content": strings.Repeat("a", 2000)}}
+	for i := 0; i < wsCfg.OversizeMaxViolations; i++ {
+		if err := conn.WriteJSON(big); err != nil {
+			t.Fatalf("发送消息失败: %v", err)
+		}
+	}
+
+	// 达到次数上限后服务端以策略违规关闭连接
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var closeErr *websocket.CloseError
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			if !errors.As(err, &closeErr) || closeErr.Code != websocket.ClosePolicyViolation {
+				t.Fatalf("应以 1008 关闭连接，实际 %v", err)
+			}
+			break
+		}
+	}
+	if until, banned := hub.oversizeBannedUntil(1); !banned || time.Until(until) < 299*time.Second {
+		t.Fatalf("应临时禁止该用户重新连接 %d 秒，实际 %v %v", wsCfg.OversizeBanSec, until, banned)
+	}
+	if _, banned := hub.oversizeBannedUntil(2); banned {
+		t.Fatal("其他用户不应受影响")
+	}
+}
+
+func TestMessageOverHardLimitClosesConnection(t *testing.T) {
+	cfg := newTestConfig()
+	_, db := newFakeDatabase(t, cfg)
+	hub := newTestHub(t, services.NewChatRepository(db, cfg), oversizeTestConfig())
+	conn := dialTestClient(t, hub, 1)
+
+	_ = conn.WriteMessage(websocket.TextMessage, bytes.Repeat([]byte("a"), 10000))
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				t.Fatal("超过硬上限的消息应直接关闭连接")
+			}
+			break
+		}
+	}
+	hub.oversizeMu.Lock()
+	rec := hub.oversize[1]
+	hub.oversizeMu.Unlock()
+	if rec == nil || rec.count != 1 {
+		t.Fatalf("超过硬上限同样计入违规次数，实际 %+v", rec)
+	}
+}
+
+func TestRecordOversizeWindow(t *testing.T) {
+	hub := &ConnectionHub{config: oversizeTestConfig(), oversize: make(map[uint]*oversizeRecord)}
+	for i := 1; i < 3; i++ {
+		if count, banned := hub.recordOversize(1); count != i || banned {
+			t.Fatalf("第 %d 次违规不应禁止，实际 count=%d banned=%v", i, count, banned)
+		}
+	}
+
+	// 窗口过后重新计数
+	hub.oversize[1].windowStart = time.Now().Add(-2 * time.Minute)
+	if count, banned := hub.recordOversize(1); count != 1 || banned {
+		t.Fatalf("窗口过后应重新计数，实际 count=%d banned=%v", count, banned)
+	}
+
+	// 过期的禁止不再生效
+	hub.recordOversize(1)
+	if _, banned := hub.recordOversize(1); !banned {
+		t.Fatal("窗口内达到上限应禁止")
+	}
+	hub.oversize[1].bannedUntil = time.Now().Add(-time.Second)
+	if _, banned := hub.oversizeBannedUntil(1); banned {
+		t.Fatal("禁止到期后应允许重新连接")
+	}
+
+	// 上限为0时不处理
+	hub.config.OversizeMaxViolations = 0
+	if _, banned := hub.recordOversize(2); banned || hub.oversize[2] != nil {
+		t.Fatal("oversize_max_violations 为0时不应计数")
+	}
+}
+
+func TestHubShutdownDrainsClients(t *testing.T) {
+	cfg := newTestConfig()
+	wsCfg := cfg.WebSocket
+	hub := newTestHub(t, nil, &wsCfg)
+	conn := dialTestClient(t, hub, 1)
+	conn.next(t, "online_count")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := hub.Shutdown(ctx); err != nil {
+		t.Fatalf("所有客户端写完后应正常关闭，实际 %v", err)
+	}
+
+	// 客户端先收到 server_shutdown，再收到 going away 关闭帧
+	conn.next(t, "server_shutdown")
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err := conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Fatalf("应收到 going away 关闭帧，实际 %v", err)
+	}
+
+	select {
+	case <-hub.done:
+	default:
+		t.Fatal("关闭后 run 循环应退出")
+	}
+	if err := hub.reserveConnection(); !errors.Is(err, errHubClosing) {
+		t.Fatalf("关闭后应拒绝新连接，实际 %v", err)
+	}
+	// 重复关闭是安全的
+	if err := hub.Shutdown(ctx); err != nil {
+		t.Fatalf("重复关闭应直接返回，实际 %v", err)
+	}
+}
+
+func TestHubShutdownTimeoutClosesStuckClients(t *testing.T) {
+	cfg := config.Default().WebSocket
+	hub := &ConnectionHub{
+		clients:    make(map[uint][]*Client),
+		broadcast:  make(chan outboundMessage, 16),
+		register:   make(chan *Client),
+		unregister: make(chan *Client),
+		logger:     utils.GetLogger(),
+		config:     &cfg,
+		stopCh:     make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	// 写协程卡住的客户端：预留了连接但一直不退出；没有真实连接，预先消耗 closeOnce 使 close 不访问 conn
+	stuck := &Client{userID: 1, send: make(chan outboundMessage, 4), blocked: map[uint]bool{}}
+	stuck.closeOnce.Do(func() {})
+	hub.clients[1] = []*Client{stuck}
+	if err := hub.reserveConnection(); err != nil {
+		t.Fatalf("预留连接失败: %v", err)
+	}
+	t.Cleanup(hub.releaseConnection)
+	go hub.run()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := hub.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("超时未写完时应返回超时错误，实际 %v", err)
+	}
+	hub.mu.RLock()
+	drained := len(hub.drained)
+	hub.mu.RUnlock()
+	if drained != 1 {
+		t.Fatalf("关闭时断开的客户端应被记录以便强制关闭，实际 %d", drained)
+	}
+	if msg := <-stuck.send; !strings.Contains(string(msg.data), "server_shutdown") {
+		t.Fatalf("应先向客户端发送 server_shutdown，实际 %s", msg.data)
+	}
+	if _, ok := <-stuck.send; ok {
+		t.Fatal("关闭时应关闭客户端的发送通道")
+	}
+}
+
+// expectClosed 等待连接被服务端关闭
+func expectClosed(t *testing.T, conn *wsTestConn) {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		_, _, err := conn.ReadMessage()
+		if err == nil {
+			continue
+		}
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			t.Fatal("连接应被服务端关闭")
+		}
+		return
+	}
+}
+
+// userConnections 获取用户当前的连接数
+func userConnections(hub *ConnectionHub, userID uint) int {
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+	return len(hub.clients[userID])
+}
+
+func TestHubPerUserConnectionLimit(t *testing.T) {
+	cfg := newTestConfig()
+	wsCfg := cfg.WebSocket
+	wsCfg.MaxConnectionsPerUser = 2
+	hub := newTestHub(t, nil, &wsCfg)
+
+	first := dialTestClient(t, hub, 1)
+	first.next(t, "online_count")
+	second := dialTestClient(t, hub, 1)
+	second.next(t, "online_count")
+	if n := userConnections(hub, 1); n != 2 {
+		t.Fatalf("未超过每用户上限时应允许多端同时在线，实际 %d 个连接", n)
+	}
+
+	// 超出上限时关闭最早的连接
+	third := dialTestClient(t, hub, 1)
+	third.next(t, "online_count")
+	expectClosed(t, first)
+	if n := userConnections(hub, 1); n != 2 {
+		t.Fatalf("超出每用户上限后应保留最近的2个连接，实际 %d 个", n)
+	}
+	if hub.GetOnlineCount() != 1 {
+		t.Fatalf("在线人数按用户计算，实际 %d", hub.GetOnlineCount())
+	}
+}
+
+func TestHubSingleConnectionReplacement(t *testing.T) {
+	cfg := newTestConfig()
+	wsCfg := cfg.WebSocket
+	wsCfg.MaxConnectionsPerUser = 1
+	hub := newTestHub(t, nil, &wsCfg)
+
+	old := dialTestClient(t, hub, 1)
+	old.next(t, "online_count")
+	latest := dialTestClient(t, hub, 1)
+	latest.next(t, "online_count")
+	expectClosed(t, old)
+	if n := userConnections(hub, 1); n != 1 {
+		t.Fatalf("默认每个用户只保留最新的连接，实际 %d 个", n)
+	}
+}
+
+func TestHubGlobalConnectionLimit(t *testing.T) {
+	cfg := newTestConfig()
+	wsCfg := cfg.WebSocket
+	wsCfg.MaxConnections = 1
+	hub := newTestHub(t, nil, &wsCfg)
+
+	conn := dialTestClient(t, hub, 1)
+	conn.next(t, "online_count")
+	if err := hub.reserveConnection(); !errors.Is(err, errHubFull) {
+		t.Fatalf("达到全局上限时应拒绝新连接，实际 %v", err)
+	}
+
+	// 连接断开后释放名额
+	conn.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		err := hub.reserveConnection()
+		if err == nil {
+			hub.releaseConnection()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("连接断开后应释放名额，实际 %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
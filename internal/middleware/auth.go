@@ -1,19 +1,32 @@
 package middleware
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
 	"gin/internal/config"
 	"gin/internal/models"
+	"gin/internal/services"
 	"gin/internal/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
 )
 
+// 认证方式（存入上下文的 authType）
+const (
+	AuthTypeJWT      = "jwt"
+	AuthTypeAPIToken = "api_token"
+)
+
 // AuthMiddleware JWT认证中间件（从配置读取token前缀）
-func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
+// tokenRepo 不为空时，以 api_token.token_prefix 开头的令牌按个人访问令牌认证；
+// blacklist 不为空时拒绝已退出登录的token和注销全部会话前签发的token
+func AuthMiddleware(cfg *config.Config, tokenRepo *services.APITokenRepository, blacklist *services.TokenBlacklist) gin.HandlerFunc {
 	// 从配置读取token前缀
 	tokenPrefix := cfg.JWTExtended.TokenPrefix
 	prefixLen := len(tokenPrefix)
@@ -36,57 +49,38 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 			c.Abort()
 			return
 		}
-		claims := &models.Claims{}
-
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			// 验证签名方法
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrSignatureInvalid
-			}
-			return []byte(cfg.JWT.SecretKey), nil
-		})
-
-		if err != nil {
-			utils.GetLogger().Warn("认证失败：token解析错误", "error", err.Error(), "ip", c.ClientIP(), "path", c.Request.URL.Path)
-			utils.UnauthorizedResponse(c, "无效的token")
-			c.Abort()
-			return
-		}
 
-		if !token.Valid {
-			utils.GetLogger().Warn("认证失败：token无效", "ip", c.ClientIP(), "path", c.Request.URL.Path)
-			utils.UnauthorizedResponse(c, "无效的token")
-			c.Abort()
+		// 个人访问令牌
+		if tokenRepo != nil && strings.HasPrefix(tokenString, cfg.APIToken.TokenPrefix) {
+			authenticateAPIToken(c, cfg, tokenRepo, tokenString)
 			return
 		}
 
-		// 检查token是否过期
-		if claims.ExpiresAt != nil && claims.ExpiresAt.Time.Before(time.Now()) {
-			utils.GetLogger().Warn("认证失败：token已过期", "ip", c.ClientIP(), "path", c.Request.URL.Path)
-			utils.UnauthorizedResponse(c, "token已过期")
+		claims, err := parseJWT(cfg, tokenString)
+		if err != nil {
+			utils.GetLogger().Warn("认证失败", "reason", err.Error(), "ip", c.ClientIP(), "path", c.Request.URL.Path)
+			if errors.Is(err, utils.ErrTokenExpired) {
+				utils.UnauthorizedResponse(c, "token已过期")
+			} else {
+				utils.UnauthorizedResponse(c, "无效的token")
+			}
 			c.Abort()
 			return
 		}
-
-		// 从claims中获取用户信息
 		userID := claims.Subject
-		if userID == "" {
-			utils.GetLogger().Warn("认证失败：token中缺少用户ID", "ip", c.ClientIP(), "path", c.Request.URL.Path)
-			utils.UnauthorizedResponse(c, "无效的token")
-			c.Abort()
-			return
-		}
 
-		// 验证issuer
-		if claims.Issuer != cfg.JWT.Issuer {
-			utils.GetLogger().Warn("认证失败：token issuer不匹配", "expected", cfg.JWT.Issuer, "actual", claims.Issuer, "ip", c.ClientIP(), "path", c.Request.URL.Path)
-			utils.UnauthorizedResponse(c, "无效的token")
-			c.Abort()
+		// 检查token是否已被吊销
+		if blacklist != nil && !checkTokenNotRevoked(c, blacklist, claims) {
 			return
 		}
 
 		// 将用户信息存储到上下文中
 		c.Set("userID", userID)
+		c.Set("authType", AuthTypeJWT)
+		c.Set("jti", claims.ID)
+		if claims.ExpiresAt != nil {
+			c.Set("tokenExpiresAt", claims.ExpiresAt.Time)
+		}
 		// 从自定义claims中获取用户名、邮箱和地址信息
 		if claims.Username != "" {
 			c.Set("username", claims.Username)
@@ -101,12 +95,110 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 			c.Set("city", claims.City)
 		}
 
-		// 设置请求ID用于追踪
-		if claims.ID != "" {
-			c.Set("requestID", claims.ID)
-		}
-
 		utils.GetLogger().Debug("用户认证成功", "userID", userID, "username", claims.Username, "ip", c.ClientIP(), "path", c.Request.URL.Path)
 		c.Next()
 	}
 }
+
+// JWT吊销原因
+var (
+	errTokenLoggedOut  = errors.New("token已退出登录")
+	errTokenSuperseded = errors.New("token已被注销（签发后注销了全部会话）")
+)
+
+// parseJWT 校验JWT的签名、有效期、用户ID和签发者（AuthMiddleware 与限流豁免共用）
+func parseJWT(cfg *config.Config, tokenString string) (*models.Claims, error) {
+	claims := &models.Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		// 验证签名方法
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return []byte(cfg.JWT.SecretKey), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: token解析错误: %v", utils.ErrInvalidToken, err)
+	}
+	if !token.Valid {
+		return nil, fmt.Errorf("%w: token无效", utils.ErrInvalidToken)
+	}
+	if claims.ExpiresAt != nil && claims.ExpiresAt.Time.Before(time.Now()) {
+		return nil, utils.ErrTokenExpired
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: token中缺少用户ID", utils.ErrInvalidToken)
+	}
+	if claims.Issuer != cfg.JWT.Issuer {
+		return nil, fmt.Errorf("%w: token issuer不匹配（期望 %s，实际 %s）", utils.ErrInvalidToken, cfg.JWT.Issuer, claims.Issuer)
+	}
+	return claims, nil
+}
+
+// tokenRevocationError 检查JWT是否已退出登录（jti 黑名单）或签发后注销了全部会话（token_version），未吊销时返回 nil
+func tokenRevocationError(ctx context.Context, blacklist *services.TokenBlacklist, claims *models.Claims) error {
+	if blacklist.IsRevoked(claims.ID) {
+		return errTokenLoggedOut
+	}
+	uid, err := strconv.ParseUint(claims.Subject, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: token中用户ID无效", utils.ErrInvalidToken)
+	}
+	version, err := blacklist.CurrentVersion(ctx, uint(uid))
+	if err != nil {
+		return err
+	}
+	if claims.TokenVersion < version {
+		return errTokenSuperseded
+	}
+	return nil
+}
+
+// checkTokenNotRevoked 检查JWT是否已退出登录或已被注销全部会话，已吊销时中止请求并返回false
+func checkTokenNotRevoked(c *gin.Context, blacklist *services.TokenBlacklist, claims *models.Claims) bool {
+	err := tokenRevocationError(c.Request.Context(), blacklist, claims)
+	if err == nil {
+		return true
+	}
+
+	utils.GetLogger().Warn("认证失败：token已吊销或无法校验", "userID", claims.Subject, "tokenVersion", claims.TokenVersion,
+		"reason", err.Error(), "ip", c.ClientIP(), "path", c.Request.URL.Path)
+	switch {
+	case errors.Is(err, errTokenLoggedOut) || errors.Is(err, errTokenSuperseded):
+		utils.UnauthorizedResponse(c, "token已失效，请重新登录")
+	case errors.Is(err, utils.ErrInvalidToken) || errors.Is(err, utils.ErrUserNotFound):
+		utils.UnauthorizedResponse(c, "无效的token")
+	default:
+		utils.AppErrorResponse(c, err, "校验token状态失败")
+	}
+	c.Abort()
+	return false
+}
+
+// authenticateAPIToken 个人访问令牌认证（权限范围由路由上的 RequireScope / RequireRouteGroupScope 检查）
+func authenticateAPIToken(c *gin.Context, cfg *config.Config, tokenRepo *services.APITokenRepository, tokenString string) {
+	logger := utils.GetLogger()
+
+	identity, err := tokenRepo.AuthenticateAPIToken(c.Request.Context(), tokenString)
+	if err != nil {
+		logger.Warn("认证失败：API令牌无效", "error", err.Error(), "ip", c.ClientIP(), "path", c.Request.URL.Path)
+		utils.ErrorResponse(c, utils.GetHTTPStatusCode(err), err.Error())
+		c.Abort()
+		return
+	}
+
+	c.Set("userID", strconv.FormatUint(uint64(identity.UserID), 10))
+	c.Set("username", identity.Username)
+	c.Set("email", identity.Email)
+	c.Set("authType", AuthTypeAPIToken)
+	c.Set("apiTokenID", identity.TokenID)
+	c.Set("apiTokenScopes", identity.Scopes)
+
+	// 异步更新最后使用时间，不阻塞请求
+	tokenID := identity.TokenID
+	_ = utils.SubmitTaskWithContext(c.Request.Context(), fmt.Sprintf("touch_api_token_%d", tokenID), func(ctx context.Context) error {
+		return tokenRepo.TouchAPIToken(ctx, tokenID)
+	}, time.Duration(cfg.APIToken.LastUsedUpdateSec)*time.Second)
+
+	logger.Debug("API令牌认证成功", "userID", identity.UserID, "tokenID", identity.TokenID, "ip", c.ClientIP(), "path", c.Request.URL.Path)
+	c.Next()
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"gin/internal/config"
+	"gin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 按路由组区分的请求体大小限制
+const (
+	BodyLimitJSON   = "json"   // 资源元数据等JSON接口
+	BodyLimitAvatar = "avatar" // 头像上传
+	BodyLimitImage  = "image"  // 资源预览图和文档图片上传
+	BodyLimitChunk  = "chunk"  // 资源分片上传
+)
+
+// resolveBodyLimit 按配置计算路由组的请求体上限（字节）
+// 上传接口在文件大小上限之外加上 multipart 表单开销，且不超过全局 max_request_size_mb
+func resolveBodyLimit(cfg *config.Config, group string) int64 {
+	global := int64(cfg.Security.MaxRequestSizeMB) * 1024 * 1024
+	overhead := int64(cfg.Security.MultipartOverheadKB) * 1024
+
+	var limit int64
+	switch group {
+	case BodyLimitJSON:
+		limit = int64(cfg.Security.JSONMaxSizeKB) * 1024
+	case BodyLimitAvatar:
+		maxSizeKB := cfg.AvatarUpload.MaxSizeKB
+		if cfg.AvatarUpload.ServerProcess {
+			maxSizeKB = cfg.AvatarUpload.ProcessMaxSizeKB
+		}
+		limit = int64(maxSizeKB)*1024 + overhead
+	case BodyLimitImage:
+		limit = int64(cfg.ImageUpload.MaxSizeMB)*1024*1024 + overhead
+	case BodyLimitChunk:
+		limit = int64(cfg.FileUpload.ChunkSizeMB)*1024*1024 + overhead
+	default:
+		utils.GetLogger().Warn("未知的请求体限制分组，使用全局上限", "group", group)
+		return global
+	}
+	return min(limit, global)
+}
+
+// BodyLimitMiddleware 按路由组限制请求体大小（在全局限制之后再收紧）
+func BodyLimitMiddleware(cfg *config.Config, group string) gin.HandlerFunc {
+	return RequestSizeLimitMiddleware(resolveBodyLimit(cfg, group))
+}
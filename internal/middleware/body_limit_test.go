@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gin/internal/config"
+	"gin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestResolveBodyLimit(t *testing.T) {
+	cfg := config.Default()
+	cfg.Security.MaxRequestSizeMB = 10
+	cfg.Security.JSONMaxSizeKB = 512
+	cfg.Security.MultipartOverheadKB = 64
+	cfg.AvatarUpload.MaxSizeKB = 5
+	cfg.AvatarUpload.ServerProcess = false
+	cfg.ImageUpload.MaxSizeMB = 5
+	cfg.FileUpload.ChunkSizeMB = 20
+
+	cases := []struct {
+		group string
+		want  int64
+	}{
+		{BodyLimitJSON, 512 * 1024},
+		{BodyLimitAvatar, 5*1024 + 64*1024},
+		{BodyLimitImage, 5*1024*1024 + 64*1024},
+		{BodyLimitChunk, 10 * 1024 * 1024}, // 超过全局上限时取全局上限
+		{"unknown", 10 * 1024 * 1024},
+	}
+	for _, tc := range cases {
+		if got := resolveBodyLimit(cfg, tc.group); got != tc.want {
+			t.Errorf("%s: 请求体上限应为 %d，实际 %d", tc.group, tc.want, got)
+		}
+	}
+
+	// 服务端处理头像时使用处理前的大小上限
+	cfg.AvatarUpload.ServerProcess = true
+	cfg.AvatarUpload.ProcessMaxSizeKB = 2048
+	if got := resolveBodyLimit(cfg, BodyLimitAvatar); got != 2048*1024+64*1024 {
+		t.Errorf("服务端处理头像时应使用 process_max_size_kb，实际 %d", got)
+	}
+}
+
+func TestRequestSizeLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestSizeLimitMiddleware(16))
+	handled := 0
+	router.POST("/data", func(c *gin.Context) {
+		handled++
+		if _, err := io.ReadAll(c.Request.Body); err != nil {
+			if limit, ok := utils.MaxBytesLimit(err); ok {
+				utils.RequestTooLargeResponse(c, limit)
+				return
+			}
+		}
+		c.Status(http.StatusOK)
+	})
+
+	send := func(body string, chunked bool) (int, string) {
+		req := httptest.NewRequest(http.MethodPost, "/data", strings.NewReader(body))
+		if chunked {
+			req.ContentLength = -1 // 未声明长度（分块传输）
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp struct {
+			ErrorCode string `json:"error_code"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp.ErrorCode
+	}
+
+	if status, _ := send("small", false); status != http.StatusOK {
+		t.Fatalf("未超限的请求应正常处理，实际 %d", status)
+	}
+
+	// 声明的长度已超限：不进入处理函数，直接返回413
+	status, code := send(strings.Repeat("a", 17), false)
+	if status != http.StatusRequestEntityTooLarge || code != utils.ErrCodeRequestTooLarge || handled != 1 {
+		t.Fatalf("Content-Length 超限时应直接返回413，实际 %d %q，处理函数调用 %d 次", status, code, handled)
+	}
+
+	// 未声明长度：读取超限时由 MaxBytesReader 报错，处理函数据此返回413
+	status, code = send(strings.Repeat("a", 17), true)
+	if status != http.StatusRequestEntityTooLarge || code != utils.ErrCodeRequestTooLarge {
+		t.Fatalf("分块传输的请求体读取超限时应返回413，实际 %d %q", status, code)
+	}
+}
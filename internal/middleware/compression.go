@@ -4,6 +4,9 @@ package middleware
 import (
 	"compress/gzip"
 	"io"
+	"net/http"
+	"path"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -39,31 +42,45 @@ var gzipWriterPools = []*sync.Pool{
 	{New: func() interface{} { w, _ := gzip.NewWriterLevel(io.Discard, gzip.BestCompression); return w }},
 }
 
+// DefaultSkipContentTypes 默认不压缩的内容类型（已压缩格式，再次gzip几乎没有收益）
+var DefaultSkipContentTypes = []string{
+	"image/jpeg", "image/png", "image/gif", "image/webp",
+	"application/zip", "application/gzip", "application/x-gzip", "application/x-7z-compressed",
+	"application/x-rar-compressed", "application/pdf", "application/octet-stream",
+	"video/", "audio/", "font/woff2",
+}
+
+// DefaultSkipExtensions 默认不压缩的请求路径扩展名
+var DefaultSkipExtensions = []string{
+	".jpg", ".jpeg", ".png", ".gif", ".webp",
+	".zip", ".gz", ".tgz", ".rar", ".7z", ".pdf",
+	".mp4", ".mp3", ".woff2",
+}
+
 type gzipWriter struct {
 	gin.ResponseWriter
 	writer         *gzip.Writer
 	originalSize   int
 	compressedSize int
 	shouldCompress bool
+	decided        bool // 是否已在第一次写入时决定是否压缩
 	minSize        int
+	skipTypes      []string
 }
 
 func (g *gzipWriter) Write(data []byte) (int, error) {
-	g.originalSize += len(data)
-
-	// 如果数据小于最小压缩大小，不压缩
-	if !g.shouldCompress && g.originalSize < g.minSize {
-		return g.ResponseWriter.Write(data)
-	}
-
-	// 第一次达到最小大小时，设置压缩头
-	if !g.shouldCompress && g.originalSize >= g.minSize {
-		g.shouldCompress = true
-		g.Header().Set("Content-Encoding", "gzip")
-		g.Header().Set("Vary", "Accept-Encoding")
-		g.Header().Del("Content-Length") // 删除原始Content-Length
+	// 第一次写入时根据响应头决定整个响应是否压缩（之后不再改变，避免响应体前后编码不一致）
+	if !g.decided {
+		g.decided = true
+		g.shouldCompress = g.decideCompress(data)
+		if g.shouldCompress {
+			g.Header().Set("Content-Encoding", "gzip")
+			g.Header().Add("Vary", "Accept-Encoding")
+			g.Header().Del("Content-Length") // 删除原始Content-Length
+		}
 	}
 
+	g.originalSize += len(data)
 	if g.shouldCompress {
 		n, err := g.writer.Write(data)
 		g.compressedSize += n
@@ -77,14 +94,61 @@ func (g *gzipWriter) WriteString(s string) (int, error) {
 	return g.Write([]byte(s))
 }
 
-// WriteHeader 重写WriteHeader以支持条件压缩
-func (g *gzipWriter) WriteHeader(code int) {
-	// 检查Content-Type是否适合压缩
-	contentType := g.Header().Get("Content-Type")
-	if !shouldCompressContentType(contentType) {
-		g.shouldCompress = false
+// Flush 先刷新gzip缓冲区，流式响应（如CSV导出）才能及时送达客户端
+func (g *gzipWriter) Flush() {
+	if g.shouldCompress {
+		_ = g.writer.Flush()
+	}
+	g.ResponseWriter.Flush()
+}
+
+// decideCompress 判断响应是否需要压缩：已设置Content-Encoding、内容类型在跳过列表中、
+// 不是文本类型或响应体小于最小压缩大小时不压缩
+func (g *gzipWriter) decideCompress(firstChunk []byte) bool {
+	header := g.Header()
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(firstChunk)
+	}
+	if matchContentType(contentType, g.skipTypes) || !shouldCompressContentType(contentType) {
+		return false
 	}
-	g.ResponseWriter.WriteHeader(code)
+
+	// 优先使用声明的Content-Length，否则以第一次写入的大小判断（JSON等响应一次写完）
+	if length, err := strconv.Atoi(header.Get("Content-Length")); err == nil {
+		return length >= g.minSize
+	}
+	return len(firstChunk) >= g.minSize
+}
+
+// matchContentType 判断内容类型是否匹配列表中的任一项（以 / 结尾的项按前缀匹配，如 video/）
+func matchContentType(contentType string, types []string) bool {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0]))
+	for _, t := range types {
+		t = strings.ToLower(t)
+		if mediaType == t || (strings.HasSuffix(t, "/") && strings.HasPrefix(mediaType, t)) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasSkipExtension 判断请求路径的扩展名是否在跳过列表中
+func hasSkipExtension(urlPath string, exts []string) bool {
+	ext := strings.ToLower(path.Ext(urlPath))
+	if ext == "" {
+		return false
+	}
+	for _, e := range exts {
+		if strings.ToLower(e) == ext {
+			return true
+		}
+	}
+	return false
 }
 
 // shouldCompressContentType 判断Content-Type是否适合压缩
@@ -119,11 +183,16 @@ func getGzipWriterPool(level int) *sync.Pool {
 	}
 }
 
-// CompressionMiddleware 增强的压缩中间件
+// CompressionMiddleware 增强的压缩中间件（使用默认的跳过列表）
 // 参数：
 //   - level: 压缩级别 (1-9, 默认6)
 //   - minSize: 最小压缩大小（字节，默认1024）
 func CompressionMiddleware(level int, minSize int) gin.HandlerFunc {
+	return CompressionMiddlewareWithSkip(level, minSize, DefaultSkipContentTypes, DefaultSkipExtensions)
+}
+
+// CompressionMiddlewareWithSkip 压缩中间件，skipTypes/skipExts 中的内容类型和请求扩展名不压缩
+func CompressionMiddlewareWithSkip(level int, minSize int, skipTypes, skipExts []string) gin.HandlerFunc {
 	if level < gzip.BestSpeed || level > gzip.BestCompression {
 		level = DefaultCompressionLevel
 	}
@@ -137,7 +206,9 @@ func CompressionMiddleware(level int, minSize int) gin.HandlerFunc {
 	logger.Info("压缩中间件已初始化",
 		"level", level,
 		"minSize", minSize,
-		"levelName", getCompressionLevelName(level))
+		"levelName", getCompressionLevelName(level),
+		"skipTypes", len(skipTypes),
+		"skipExts", len(skipExts))
 
 	return func(c *gin.Context) {
 		// 检查客户端是否支持gzip
@@ -154,6 +225,13 @@ func CompressionMiddleware(level int, minSize int) gin.HandlerFunc {
 			return
 		}
 
+		// 跳过已压缩格式的文件请求（图片、压缩包等）
+		if hasSkipExtension(c.Request.URL.Path, skipExts) {
+			atomic.AddUint64(&uncompressedRequests, 1)
+			c.Next()
+			return
+		}
+
 		// 从池中获取gzip writer
 		gz := pool.Get().(*gzip.Writer)
 		defer pool.Put(gz)
@@ -165,6 +243,7 @@ func CompressionMiddleware(level int, minSize int) gin.HandlerFunc {
 			writer:         gz,
 			shouldCompress: false,
 			minSize:        minSize,
+			skipTypes:      skipTypes,
 		}
 		c.Writer = gw
 
@@ -215,7 +294,7 @@ func CompressionMiddlewareWithConfig(cfg *config.Config) gin.HandlerFunc {
 		level = BestSpeedCompressionLevel // 默认使用最快速度
 	}
 	
-	return CompressionMiddleware(level, minSize)
+	return CompressionMiddlewareWithSkip(level, minSize, cfg.Compression.SkipContentTypes, cfg.Compression.SkipExtensions)
 }
 
 // BestCompressionMiddleware 最佳压缩中间件（压缩率优先）
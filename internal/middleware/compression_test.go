@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gin/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newCompressionRouter(cfg *config.Config) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	body := bytes.Repeat([]byte("a"), 4096)
+	router := gin.New()
+	router.Use(CompressionMiddlewareWithConfig(cfg))
+	router.GET("/api/data", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"data": strings.Repeat("x", 4096)})
+	})
+	router.GET("/api/small", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+	router.GET("/api/image", func(c *gin.Context) {
+		c.Data(http.StatusOK, "image/jpeg", body)
+	})
+	router.GET("/api/archive", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/zip", body)
+	})
+	router.GET("/api/video", func(c *gin.Context) {
+		c.Data(http.StatusOK, "video/mp4", body)
+	})
+	router.GET("/files/report.pdf", func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/plain", body) // 内容类型可压缩，但扩展名在跳过列表中
+	})
+	router.GET("/api/encoded", func(c *gin.Context) {
+		c.Header("Content-Encoding", "br")
+		c.Data(http.StatusOK, "application/json", body)
+	})
+	return router
+}
+
+func compressionRequest(router http.Handler, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCompressionGzipsJSON(t *testing.T) {
+	router := newCompressionRouter(config.Default())
+
+	w := compressionRequest(router, "/api/data")
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("JSON响应应被压缩，实际 Content-Encoding=%q", w.Header().Get("Content-Encoding"))
+	}
+	reader, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("响应应为有效的gzip: %v", err)
+	}
+	data, _ := io.ReadAll(reader)
+	if !strings.Contains(string(data), strings.Repeat("x", 4096)) {
+		t.Fatal("解压后应为原始响应")
+	}
+
+	if w := compressionRequest(router, "/api/small"); w.Header().Get("Content-Encoding") != "" {
+		t.Fatal("小于最小压缩大小的响应不应压缩")
+	}
+}
+
+func TestCompressionSkipsCompressedContent(t *testing.T) {
+	router := newCompressionRouter(config.Default())
+
+	for _, path := range []string{"/api/image", "/api/archive", "/api/video", "/files/report.pdf"} {
+		w := compressionRequest(router, path)
+		if w.Header().Get("Content-Encoding") != "" {
+			t.Fatalf("%s: 已压缩格式不应再次gzip", path)
+		}
+		if w.Body.Len() != 4096 {
+			t.Fatalf("%s: 响应体应原样返回，实际 %d 字节", path, w.Body.Len())
+		}
+	}
+}
+
+func TestCompressionLeavesEncodedResponseUntouched(t *testing.T) {
+	w := compressionRequest(newCompressionRouter(config.Default()), "/api/encoded")
+	if w.Header().Get("Content-Encoding") != "br" || w.Body.Len() != 4096 {
+		t.Fatalf("已设置Content-Encoding的响应应保持不变，实际 %q %d", w.Header().Get("Content-Encoding"), w.Body.Len())
+	}
+}
+
+func TestCompressionUsesConfiguredSkipList(t *testing.T) {
+	cfg := config.Default()
+	cfg.Compression.SkipContentTypes = []string{"application/json"}
+	cfg.Compression.SkipExtensions = nil
+	router := newCompressionRouter(cfg)
+
+	if w := compressionRequest(router, "/api/data"); w.Header().Get("Content-Encoding") != "" {
+		t.Fatal("配置为跳过的内容类型不应压缩")
+	}
+	if w := compressionRequest(router, "/files/report.pdf"); w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatal("扩展名不在配置的跳过列表中时应按内容类型压缩")
+	}
+}
@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"gin/internal/config"
+	"gin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestConcurrencyMiddleware 为每个请求设置并发预算，
+// 请求内通过 utils.Parallel 执行的并行子任务共享 max_goroutines_per_request 个goroutine
+func RequestConcurrencyMiddleware(cfg *config.Config) gin.HandlerFunc {
+	limit := cfg.WorkerPool.MaxGoroutinesPerRequest
+	utils.SetDefaultGoroutineBudget(limit)
+
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(utils.WithGoroutineBudget(c.Request.Context(), limit))
+		c.Next()
+	}
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestConcurrencyMiddlewareCapsFanOut(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := config.Default()
+	cfg.WorkerPool.MaxGoroutinesPerRequest = 3
+	t.Cleanup(func() { utils.SetDefaultGoroutineBudget(config.Default().WorkerPool.MaxGoroutinesPerRequest) })
+
+	var running, peak, completed atomic.Int64
+	router := gin.New()
+	router.Use(RequestConcurrencyMiddleware(cfg))
+	router.GET("/fan-out", func(c *gin.Context) {
+		utils.ParallelForEach(c.Request.Context(), 200, func(int) {
+			n := running.Add(1)
+			for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+			}
+			time.Sleep(time.Millisecond)
+			running.Add(-1)
+			completed.Add(1)
+		})
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fan-out", nil))
+	if w.Code != http.StatusOK || completed.Load() != 200 {
+		t.Fatalf("请求应完成全部200个子任务，实际 %d %d", w.Code, completed.Load())
+	}
+	if p := peak.Load(); p > 4 {
+		t.Fatalf("单个请求的并发应不超过 max_goroutines_per_request+1=4，实际 %d", p)
+	}
+}
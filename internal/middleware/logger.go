@@ -3,7 +3,9 @@ package middleware
 import (
 	"bytes"
 	"io"
+	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -14,66 +16,73 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// responseWriter 包装gin的ResponseWriter以捕获响应体
+// 响应体最多捕获的字节数（只保留前缀，流式/大文件响应不会被整体缓存）
+const maxResponseBodyCapture = 512
+
+// responseWriter 包装gin的ResponseWriter以捕获响应体前缀
 type responseWriter struct {
 	gin.ResponseWriter
 	body *bytes.Buffer
 }
 
 func (w *responseWriter) Write(b []byte) (int, error) {
-	w.body.Write(b)
+	w.capture(b)
 	return w.ResponseWriter.Write(b)
 }
 
 func (w *responseWriter) WriteString(s string) (int, error) {
-	w.body.WriteString(s)
+	w.capture([]byte(s))
 	return w.ResponseWriter.WriteString(s)
 }
 
-var (
-	// 日志采样计数器
-	logSampleCounter uint64
-	// 是否是生产环境
-	isProduction = gin.Mode() == gin.ReleaseMode
-)
+// capture 记录响应体前缀，超出上限的部分直接丢弃
+func (w *responseWriter) capture(b []byte) {
+	if remain := maxResponseBodyCapture - w.body.Len(); remain > 0 {
+		if len(b) > remain {
+			b = b[:remain]
+		}
+		w.body.Write(b)
+	}
+}
+
+// peekedBody 已预读部分请求体的Body：先返回预读的字节，再继续读取原始Body
+type peekedBody struct {
+	io.Reader
+	closer io.Closer
+}
 
-// getLogSampleRate 从配置或环境变量获取采样率
+func (b *peekedBody) Close() error {
+	return b.closer.Close()
+}
+
+// 日志采样计数器
+var logSampleCounter uint64
+
+// getLogSampleRate 从配置或环境变量 LOG_SAMPLE_RATE 获取采样率（0-100）
 func getLogSampleRate(cfg *config.Config) int {
-	// 优先使用环境变量
-	if rate := os.Getenv("LOG_SAMPLE_RATE"); rate != "" {
-		// 简单解析，实际生产环境应使用 strconv.Atoi
-		if rate == "100" {
-			return 100 // 100%记录
-		}
-		if rate == "10" {
-			return 10 // 10%记录
-		}
-		if rate == "1" {
-			return 1 // 1%记录
-		}
+	if rate, err := strconv.Atoi(os.Getenv("LOG_SAMPLE_RATE")); err == nil && rate >= 0 && rate <= 100 {
+		return rate
 	}
-	// 从配置文件读取
-	if isProduction {
+	if cfg.Server.Mode == gin.ReleaseMode {
 		return cfg.LogExtended.SampleRateProduction
 	}
 	return cfg.LogExtended.SampleRateDevelopment
 }
 
-// shouldSample 判断是否应该记录详细日志
-func shouldSample(logSampleRate int) bool {
-	// 开发模式下总是记录
-	if !isProduction {
+// shouldSample 按采样率判断是否记录本次请求（每100个请求中记录 rate 个）
+func shouldSample(rate int) bool {
+	if rate >= 100 {
 		return true
 	}
-
-	// 生产模式下进行采样
+	if rate <= 0 {
+		return false
+	}
 	counter := atomic.AddUint64(&logSampleCounter, 1)
-	return (counter % uint64(100/logSampleRate)) == 0
+	return counter%100 < uint64(rate)
 }
 
-// shouldLogPath 判断路径是否需要详细日志（从配置读取）
+// shouldLogPath 判断路径是否需要访问日志（从配置读取）
 func shouldLogPath(path string, skipPaths []string) bool {
-	// 检查路径是否在跳过列表中
 	for _, skip := range skipPaths {
 		if strings.HasPrefix(path, skip) {
 			return false
@@ -82,148 +91,127 @@ func shouldLogPath(path string, skipPaths []string) bool {
 	return true
 }
 
-// LoggerMiddleware 自定义日志中间件（带采样，从配置读取）
+// isTextBody 请求体是否为可记录的文本内容（multipart、二进制流等不捕获）
+func isTextBody(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	return contentType == "" ||
+		strings.HasPrefix(contentType, "application/json") ||
+		strings.HasPrefix(contentType, "application/x-www-form-urlencoded") ||
+		strings.HasPrefix(contentType, "text/")
+}
+
+// captureRequestBody 预读请求体前 limit 字节用于日志，并把已读部分拼回 Body，不影响后续读取
+func captureRequestBody(c *gin.Context, limit int) string {
+	if limit <= 0 || c.Request.Body == nil || c.Request.Body == http.NoBody {
+		return ""
+	}
+	if !isTextBody(c.Request.Header.Get("Content-Type")) {
+		return ""
+	}
+
+	peek, err := io.ReadAll(io.LimitReader(c.Request.Body, int64(limit)))
+	c.Request.Body = &peekedBody{
+		Reader: io.MultiReader(bytes.NewReader(peek), c.Request.Body),
+		closer: c.Request.Body,
+	}
+	if err != nil {
+		return ""
+	}
+	return string(peek)
+}
+
+// LoggerMiddleware 访问日志中间件（从配置读取）
+// 跳过 skip_paths；其余请求按运行模式对应的采样率记录，5xx 和慢请求不受采样限制；
+// 记录的字段由 log_extended.access_log_fields 决定，请求体只在采样命中时捕获
 func LoggerMiddleware(cfg *config.Config) gin.HandlerFunc {
-	// 获取配置
-	logSampleRate := getLogSampleRate(cfg)
+	sampleRate := getLogSampleRate(cfg)
 	skipPaths := cfg.LogExtended.SkipPaths
 	truncateSize := cfg.LogExtended.RequestBodyTruncateSize
+	slowThreshold := time.Duration(cfg.PerformanceMonitoring.SlowRequestMS) * time.Millisecond
+
+	logFields := make(map[string]bool, len(cfg.LogExtended.AccessLogFields))
+	for _, field := range cfg.LogExtended.AccessLogFields {
+		logFields[field] = true
+	}
 
 	return func(c *gin.Context) {
-		// 开始时间
-		start := time.Now()
 		path := c.Request.URL.Path
-		raw := c.Request.URL.RawQuery
-
-		logger := utils.GetLogger()
+		if !shouldLogPath(path, skipPaths) {
+			c.Next()
+			return
+		}
 
-		// 判断是否需要详细日志
-		needDetailLog := shouldLogPath(path, skipPaths) && shouldSample(logSampleRate)
+		start := time.Now()
+		sampled := shouldSample(sampleRate)
 
-		// 只在采样时记录请求详情
+		// 请求体和响应体只在采样命中时捕获（减少性能开销）
 		var requestBody string
-		var requestBodySize int64
-		if needDetailLog {
-			// 记录请求头部（脱敏）
-			requestHeaders := make(map[string][]string)
-			for k, v := range c.Request.Header {
-				requestHeaders[k] = v
-			}
-			sanitizedHeaders := utils.SanitizeHeaders(requestHeaders)
-
-			// 读取并记录请求体（针对POST/PUT/PATCH）
-			if c.Request.Method == "POST" || c.Request.Method == "PUT" || c.Request.Method == "PATCH" {
-				if c.Request.Body != nil {
-					bodyBytes, err := io.ReadAll(c.Request.Body)
-					if err == nil {
-						requestBodySize = int64(len(bodyBytes))
-						// 使用对象池重新设置body（优化内存分配）
-						buf := utils.GetBuffer()
-						buf.Write(bodyBytes)
-						c.Request.Body = io.NopCloser(buf)
-						// 注意：这里不能PutBuffer，因为body还要被后续使用
-
-						// 截断大请求体，使用配置的截断大小（减少内存占用）
-						if len(bodyBytes) > truncateSize {
-							requestBody = utils.TruncateString(string(bodyBytes), truncateSize)
-						} else {
-							requestBody = string(bodyBytes)
-						}
-					}
-				}
-			}
-
-			logger.Debug("HTTP请求详情",
-				"method", c.Request.Method,
-				"path", path,
-				"query", raw,
-				"headers", sanitizedHeaders,
-				"requestBody", requestBody,
-				"requestBodySize", requestBodySize,
-				"contentType", c.Request.Header.Get("Content-Type"),
-				"ip", c.ClientIP(),
-				"userAgent", c.Request.UserAgent(),
-				"protocol", c.Request.Proto,
-				"host", c.Request.Host,
-				"sampled", true)
-		}
-
-		// 只在需要详细日志时包装ResponseWriter（减少性能开销）
+		if sampled && logFields["request_body"] {
+			requestBody = captureRequestBody(c, truncateSize)
+		}
 		var blw *responseWriter
-		if needDetailLog {
-			// 使用对象池获取Buffer
+		if sampled && logFields["response_body"] {
 			buf := utils.GetBuffer()
-			blw = &responseWriter{
-				ResponseWriter: c.Writer,
-				body:           buf,
-			}
-			c.Writer = blw
-			// 在请求结束后归还Buffer
 			defer utils.PutBuffer(buf)
+			blw = &responseWriter{ResponseWriter: c.Writer, body: buf}
+			c.Writer = blw
 		}
 
 		// 处理请求
 		c.Next()
 
-		// 计算延迟
 		latency := time.Since(start)
+		status := c.Writer.Status()
+		slow := slowThreshold > 0 && latency > slowThreshold
 
-		// 获取响应体（仅在需要时）
-		var responseBody string
-		var responseBodySize int
-		if needDetailLog && blw != nil {
-			responseBody = blw.body.String()
-			responseBodySize = len(responseBody)
-
-			// 截断大响应体（减少到512字节）
-			if responseBodySize > 512 {
-				responseBody = utils.TruncateString(responseBody, 512)
-			}
+		// 未命中采样的请求只有 5xx 或慢请求才记录
+		if !sampled && status < 500 && !slow {
+			return
 		}
 
-		// 构建日志字段（简化版，只保留关键信息）
-		fields := map[string]interface{}{
-			"status":    c.Writer.Status(),
-			"method":    c.Request.Method,
-			"path":      path,
-			"ip":        c.ClientIP(),
-			"latency":   latency.String(),
-			"latencyMs": latency.Milliseconds(),
+		fields := map[string]interface{}{"sampled": sampled}
+		if logFields["method"] {
+			fields["method"] = c.Request.Method
 		}
-
-		// 只在详细日志模式下添加额外字段
-		if needDetailLog {
-			fields["query"] = raw
-			fields["user_agent"] = c.Request.UserAgent()
-			fields["latencyMicros"] = latency.Microseconds()
-			fields["time"] = start.Format(time.RFC3339)
-			fields["requestBodySize"] = requestBodySize
-			fields["responseBodySize"] = responseBodySize
-			fields["responseBody"] = responseBody
-			fields["contentType"] = c.Writer.Header().Get("Content-Type")
-			fields["sampled"] = true
+		if logFields["path"] {
+			fields["path"] = path
 		}
-
-		// 添加用户信息（如果已认证）- 包含IP、用户名、邮箱、地址
-		if userID, exists := c.Get("userID"); exists {
-			fields["user_id"] = userID
+		if logFields["query"] && c.Request.URL.RawQuery != "" {
+			fields["query"] = c.Request.URL.RawQuery
 		}
-		if username, exists := c.Get("username"); exists {
-			fields["username"] = username
+		if logFields["status"] {
+			fields["status"] = status
 		}
-		if email, exists := c.Get("email"); exists {
-			fields["email"] = email
+		if logFields["latency"] {
+			fields["latency"] = latency.String()
+			fields["latencyMs"] = latency.Milliseconds()
 		}
-		if province, exists := c.Get("province"); exists {
-			fields["province"] = province
+		if logFields["ip"] {
+			fields["ip"] = c.ClientIP()
 		}
-		if city, exists := c.Get("city"); exists {
-			fields["city"] = city
+		if logFields["user_agent"] {
+			fields["user_agent"] = c.Request.UserAgent()
 		}
-
-		// 添加请求ID（如果存在）
-		if requestID, exists := c.Get("requestID"); exists {
-			fields["request_id"] = requestID
+		if logFields["user_id"] {
+			if userID, exists := c.Get("userID"); exists {
+				fields["user_id"] = userID
+			}
+		}
+		if logFields["request_id"] {
+			if requestID, exists := c.Get("requestID"); exists {
+				fields["request_id"] = requestID
+			}
+		}
+		if logFields["headers"] && sampled {
+			fields["headers"] = utils.SanitizeHeaders(c.Request.Header)
+		}
+		if requestBody != "" {
+			fields["requestBody"] = requestBody
+			fields["requestBodySize"] = c.Request.ContentLength
+		}
+		if blw != nil {
+			fields["responseBody"] = blw.body.String()
+			fields["responseBodySize"] = c.Writer.Size()
 		}
 
 		// 添加错误信息（如果有）
@@ -231,24 +219,16 @@ func LoggerMiddleware(cfg *config.Config) gin.HandlerFunc {
 			fields["errors"] = c.Errors.String()
 		}
 
-		// 根据状态码选择日志级别（简化逻辑）
-		status := c.Writer.Status()
+		logger := utils.GetLogger()
 		switch {
 		case status >= 500:
-			// 服务器错误总是记录
 			logger.Error("HTTP请求失败", fields)
+		case slow:
+			logger.Warn("慢请求检测", fields)
 		case status >= 400:
-			// 客户端错误记录警告
 			logger.Warn("HTTP请求错误", fields)
-		case latency > 500*time.Millisecond:
-			// 慢请求总是记录
-			logger.Warn("慢请求检测", fields)
-		case needDetailLog:
-			// 采样的正常请求记录Info
-			logger.Info("HTTP请求完成", fields)
 		default:
-			// 其他正常请求只记录Debug（生产环境通常不会输出）
-			logger.Debug("HTTP请求完成", fields)
+			logger.Info("HTTP请求完成", fields)
 		}
 	}
 }
@@ -0,0 +1,211 @@
+package middleware
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// captureAccessLogs 把全局日志器换成写入临时文件的同步 JSON 日志器，返回读取已写入日志的函数
+func captureAccessLogs(t *testing.T) func() []map[string]interface{} {
+	t.Helper()
+	file, err := os.Create(filepath.Join(t.TempDir(), "stdout.log"))
+	if err != nil {
+		t.Fatalf("创建日志文件失败: %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = file // stdout 输出的写入目标在创建日志器时确定
+	err = utils.InitLogger(&config.LogConfig{Level: "debug", Format: "json", Output: "stdout"})
+	os.Stdout = stdout
+	if err != nil {
+		t.Fatalf("初始化日志器失败: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = utils.CloseLogger()
+		file.Close()
+	})
+
+	return func() []map[string]interface{} {
+		t.Helper()
+		data, err := os.ReadFile(file.Name())
+		if err != nil {
+			t.Fatalf("读取日志失败: %v", err)
+		}
+		var entries []map[string]interface{}
+		scanner := bufio.NewScanner(strings.NewReader(string(data)))
+		for scanner.Scan() {
+			var entry map[string]interface{}
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				t.Fatalf("日志不是合法的JSON: %q", scanner.Text())
+			}
+			entries = append(entries, entry)
+		}
+		return entries
+	}
+}
+
+func newAccessLogConfig(rate int) *config.Config {
+	cfg := &config.Config{}
+	cfg.Server.Mode = gin.ReleaseMode
+	cfg.LogExtended.SampleRateProduction = rate
+	cfg.LogExtended.SampleRateDevelopment = 100
+	cfg.LogExtended.SkipPaths = []string{"/health"}
+	cfg.LogExtended.RequestBodyTruncateSize = 8
+	cfg.LogExtended.AccessLogFields = []string{"method", "path", "status", "request_body", "response_body"}
+	return cfg
+}
+
+func TestShouldSample(t *testing.T) {
+	count := func(rate int) int {
+		hits := 0
+		for i := 0; i < 1000; i++ {
+			if shouldSample(rate) {
+				hits++
+			}
+		}
+		return hits
+	}
+	if hits := count(0); hits != 0 {
+		t.Fatalf("采样率为0时不应记录，实际 %d", hits)
+	}
+	if hits := count(100); hits != 1000 {
+		t.Fatalf("采样率为100时应全部记录，实际 %d", hits)
+	}
+	if hits := count(10); hits != 100 {
+		t.Fatalf("采样率为10时每100个请求应记录10个，实际 %d/1000", hits)
+	}
+}
+
+func TestGetLogSampleRate(t *testing.T) {
+	t.Setenv("LOG_SAMPLE_RATE", "")
+	cfg := newAccessLogConfig(5)
+	if rate := getLogSampleRate(cfg); rate != 5 {
+		t.Fatalf("release 模式应使用生产环境采样率，实际 %d", rate)
+	}
+	cfg.Server.Mode = gin.DebugMode
+	if rate := getLogSampleRate(cfg); rate != 100 {
+		t.Fatalf("其他模式应使用开发环境采样率，实际 %d", rate)
+	}
+
+	t.Setenv("LOG_SAMPLE_RATE", "30")
+	if rate := getLogSampleRate(cfg); rate != 30 {
+		t.Fatalf("环境变量 LOG_SAMPLE_RATE 应优先于配置，实际 %d", rate)
+	}
+	t.Setenv("LOG_SAMPLE_RATE", "150")
+	if rate := getLogSampleRate(cfg); rate != 100 {
+		t.Fatalf("超出范围的环境变量应被忽略，实际 %d", rate)
+	}
+}
+
+func TestLoggerMiddlewareSampling(t *testing.T) {
+	t.Setenv("LOG_SAMPLE_RATE", "")
+	gin.SetMode(gin.TestMode)
+	readLogs := captureAccessLogs(t)
+
+	router := gin.New()
+	router.Use(LoggerMiddleware(newAccessLogConfig(0)))
+	router.GET("/health", func(c *gin.Context) { c.Status(http.StatusInternalServerError) })
+	router.GET("/ok", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	router.GET("/fail", func(c *gin.Context) { c.String(http.StatusInternalServerError, "boom") })
+	for _, path := range []string{"/health", "/ok", "/fail"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	// 采样率为0：跳过路径和未采样的成功请求都不记录，5xx 不受采样限制
+	entries := readLogs()
+	if len(entries) != 1 {
+		t.Fatalf("应只记录1条5xx请求日志，实际 %v", entries)
+	}
+	entry := entries[0]
+	if entry["message"] != "HTTP请求失败" || entry["level"] != "error" || entry["path"] != "/fail" || entry["sampled"] != false {
+		t.Fatalf("5xx请求应以错误级别记录，实际 %v", entry)
+	}
+	if _, ok := entry["responseBody"]; ok {
+		t.Fatalf("未采样的请求不应捕获响应体，实际 %v", entry)
+	}
+}
+
+func TestLoggerMiddlewareSlowRequest(t *testing.T) {
+	t.Setenv("LOG_SAMPLE_RATE", "")
+	gin.SetMode(gin.TestMode)
+	readLogs := captureAccessLogs(t)
+
+	cfg := newAccessLogConfig(0)
+	cfg.PerformanceMonitoring.SlowRequestMS = 10
+	router := gin.New()
+	router.Use(LoggerMiddleware(cfg))
+	router.GET("/slow", func(c *gin.Context) {
+		time.Sleep(20 * time.Millisecond)
+		c.Status(http.StatusOK)
+	})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+
+	entries := readLogs()
+	if len(entries) != 1 || entries[0]["message"] != "慢请求检测" || entries[0]["level"] != "warn" {
+		t.Fatalf("慢请求不受采样限制，应以警告级别记录，实际 %v", entries)
+	}
+}
+
+func TestLoggerMiddlewareBodyCapture(t *testing.T) {
+	t.Setenv("LOG_SAMPLE_RATE", "")
+	gin.SetMode(gin.TestMode)
+	readLogs := captureAccessLogs(t)
+
+	router := gin.New()
+	router.Use(LoggerMiddleware(newAccessLogConfig(100)))
+	var received []string
+	router.POST("/echo", func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		received = append(received, string(body))
+		c.String(http.StatusOK, strings.Repeat("r", 2*maxResponseBodyCapture))
+	})
+
+	post := func(contentType, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post("application/json", `{"name":"value"}`)
+	if len(received) != 1 || received[0] != `{"name":"value"}` {
+		t.Fatalf("捕获请求体后处理函数仍应读到完整请求体，实际 %q", received)
+	}
+	if w.Body.Len() != 2*maxResponseBodyCapture {
+		t.Fatalf("捕获响应体不应影响客户端收到的响应，实际 %d 字节", w.Body.Len())
+	}
+	post("multipart/form-data; boundary=x", "--x--binary-content")
+	if len(received) != 2 || received[1] != "--x--binary-content" {
+		t.Fatalf("multipart 请求体应原样交给处理函数，实际 %q", received)
+	}
+
+	entries := readLogs()
+	if len(entries) != 2 {
+		t.Fatalf("采样率为100时应记录全部请求，实际 %v", entries)
+	}
+	if body := entries[0]["requestBody"]; body != `{"name":` {
+		t.Fatalf("请求体应截断到配置的大小，实际 %q", body)
+	}
+	if body, _ := entries[0]["responseBody"].(string); len(body) != maxResponseBodyCapture {
+		t.Fatalf("响应体最多捕获 %d 字节，实际 %d", maxResponseBodyCapture, len(body))
+	}
+	if size := entries[0]["responseBodySize"]; size != float64(2*maxResponseBodyCapture) {
+		t.Fatalf("应记录完整的响应体大小，实际 %v", size)
+	}
+	if _, ok := entries[1]["requestBody"]; ok {
+		t.Fatalf("multipart 请求体不应被捕获，实际 %v", entries[1])
+	}
+}
@@ -1,11 +1,18 @@
 package middleware
 
 import (
+	"bytes"
 	"container/list"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"gin/internal/config"
+	"gin/internal/services"
 	"gin/internal/utils"
 
 	"github.com/gin-gonic/gin"
@@ -36,30 +43,64 @@ func NewTokenBucket(capacity int, refillRate time.Duration) *TokenBucket {
 	}
 }
 
-// Allow 检查是否允许请求
-func (tb *TokenBucket) Allow(key string) bool {
+// RateLimitResult 单次限流检查结果（用于生成 X-RateLimit-* / Retry-After 响应头）
+type RateLimitResult struct {
+	Allowed    bool          // 是否放行
+	Limit      int           // 桶容量
+	Remaining  int           // 本次检查后剩余令牌数
+	RetryAfter time.Duration // 距下一个令牌可用的时间（有剩余令牌时为0）
+	Reset      time.Duration // 距令牌桶补满的时间
+}
+
+// refill 按经过的时间补充令牌（调用方需持有锁）
+// 只推进已折算成令牌的时间，避免零头时间被丢弃导致补充变慢
+func (tb *TokenBucket) refill(now time.Time) {
+	tokensToAdd := int(now.Sub(tb.lastRefill) / tb.refillRate)
+	if tokensToAdd <= 0 {
+		return
+	}
+
+	tb.tokens += tokensToAdd
+	if tb.tokens >= tb.capacity {
+		tb.tokens = tb.capacity
+		tb.lastRefill = now
+		return
+	}
+	tb.lastRefill = tb.lastRefill.Add(time.Duration(tokensToAdd) * tb.refillRate)
+}
+
+// Take 尝试消耗一个令牌，并返回剩余令牌与下次补充时间
+func (tb *TokenBucket) Take() RateLimitResult {
 	tb.mutex.Lock()
 	defer tb.mutex.Unlock()
 
 	now := time.Now()
-	// 计算需要补充的令牌数
-	elapsed := now.Sub(tb.lastRefill)
-	tokensToAdd := int(elapsed / tb.refillRate)
-
-	if tokensToAdd > 0 {
-		tb.tokens += tokensToAdd
-		if tb.tokens > tb.capacity {
-			tb.tokens = tb.capacity
-		}
-		tb.lastRefill = now
-	}
+	tb.refill(now)
 
-	// 检查是否有可用令牌
-	if tb.tokens > 0 {
+	allowed := tb.tokens > 0
+	if allowed {
 		tb.tokens--
-		return true
 	}
-	return false
+
+	result := RateLimitResult{
+		Allowed:   allowed,
+		Limit:     tb.capacity,
+		Remaining: tb.tokens,
+	}
+	if tb.tokens < tb.capacity {
+		// 满桶时 lastRefill 视为当前时间，下一个令牌在 lastRefill+refillRate 到达
+		nextToken := tb.lastRefill.Add(tb.refillRate).Sub(now)
+		if tb.tokens == 0 {
+			result.RetryAfter = nextToken
+		}
+		result.Reset = nextToken + time.Duration(tb.capacity-tb.tokens-1)*tb.refillRate
+	}
+	return result
+}
+
+// Allow 检查是否允许请求
+func (tb *TokenBucket) Allow(key string) bool {
+	return tb.Take().Allowed
 }
 
 // Reset 重置令牌桶
@@ -196,8 +237,37 @@ func (rl *LRURateLimiter) cleanup(expireMinutes int) {
 
 // Allow 检查是否允许请求
 func (rl *LRURateLimiter) Allow(key string) bool {
-	limiter := rl.GetLimiter(key)
-	return limiter.Allow(key)
+	return rl.Take(key).Allowed
+}
+
+// Take 消耗指定key的一个令牌，返回详细限流结果
+func (rl *LRURateLimiter) Take(key string) RateLimitResult {
+	return rl.GetLimiter(key).Take()
+}
+
+// UpdateLimits 运行时调整限流参数（配置热更新），已有的令牌桶同步生效
+func (rl *LRURateLimiter) UpdateLimits(capacity int, refillRate time.Duration, maxSize int) {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	rl.capacity = capacity
+	rl.refillRate = refillRate
+	rl.maxSize = maxSize
+
+	for _, entry := range rl.limiters {
+		entry.limiter.mutex.Lock()
+		entry.limiter.capacity = capacity
+		entry.limiter.refillRate = refillRate
+		if entry.limiter.tokens > capacity {
+			entry.limiter.tokens = capacity
+		}
+		entry.limiter.mutex.Unlock()
+	}
+
+	// 缩容时淘汰多余条目
+	for len(rl.limiters) > rl.maxSize {
+		rl.evictOldest()
+	}
 }
 
 // Stop 停止清理goroutine
@@ -214,17 +284,28 @@ func (rl *LRURateLimiter) Size() int {
 
 // 全局限流器（使用LRU优化）
 var (
-	globalIPRateLimiter       *LRURateLimiter
-	globalLoginRateLimiter    *LRURateLimiter
-	globalRegisterRateLimiter *LRURateLimiter
-	globalUploadRateLimiter   *LRURateLimiter // 头像上传限流器
-	rateLimiterOnce           sync.Once
+	globalIPRateLimiter         *LRURateLimiter
+	globalLoginRateLimiter      *LRURateLimiter
+	globalRegisterRateLimiter   *LRURateLimiter
+	globalUploadRateLimiter     *LRURateLimiter // 头像上传限流器
+	globalUserRateLimiter       *LRURateLimiter // 登录用户限流器（按用户ID）
+	globalSensitiveIPLimiter    *LRURateLimiter // 匿名敏感操作限流器（按IP，多个接口共享额度）
+	globalSensitiveEmailLimiter *LRURateLimiter // 匿名敏感操作限流器（按邮箱）
+	rateLimiterOnce             sync.Once
+
+	// rateLimitHeadersOnSuccess 放行的请求也返回 X-RateLimit-* 头（支持配置热更新）
+	rateLimitHeadersOnSuccess atomic.Bool
+	// sensitiveMinResponse 匿名敏感操作的最短响应时间（支持配置热更新）
+	sensitiveMinResponse atomic.Int64
 )
 
 // InitRateLimiter 初始化所有限流器（应在应用启动时调用一次）
-func InitRateLimiter(cfg *config.Config) {
+// blacklist 用于管理员豁免时检查JWT是否已吊销（与 AuthMiddleware 一致）
+func InitRateLimiter(cfg *config.Config, blacklist *services.TokenBlacklist) {
 	rateLimiterOnce.Do(func() {
+		rateLimitTokenBlacklist = blacklist
 		logger := utils.GetLogger()
+		rateLimitHeadersOnSuccess.Store(cfg.RateLimiter.HeadersOnSuccess)
 
 		// 1. 全局IP限流器
 		capacity := cfg.RateLimiter.Global.Capacity
@@ -279,10 +360,81 @@ func InitRateLimiter(cfg *config.Config) {
 			"requestsPerMinute", uploadRPM,
 			"maxSize", uploadMaxSize)
 
+		// 5. 登录用户限流器（按用户ID，过期清理与IP限流器一致）
+		userCapacity := cfg.RateLimiter.User.Capacity
+		userRPM := cfg.RateLimiter.User.RequestsPerMinute
+		userMaxSize := cfg.RateLimiter.User.MaxCacheSize
+		userRefillRate := time.Minute / time.Duration(userRPM)
+
+		globalUserRateLimiter = NewLRURateLimiter(userCapacity, userRefillRate, userMaxSize, cleanupInterval, expireTime)
+		logger.Info("用户限流器初始化完成",
+			"capacity", userCapacity,
+			"requestsPerMinute", userRPM,
+			"maxSize", userMaxSize)
+
+		// 6. 匿名敏感操作限流器（注册、找回密码、重发验证邮件共享额度）
+		sensitiveIP := cfg.RateLimiter.SensitiveIP
+		globalSensitiveIPLimiter = NewLRURateLimiter(sensitiveIP.Capacity, time.Minute/time.Duration(sensitiveIP.RequestsPerMinute),
+			sensitiveIP.MaxCacheSize, cleanupInterval, expireTime)
+		sensitiveEmail := cfg.RateLimiter.SensitiveEmail
+		globalSensitiveEmailLimiter = NewLRURateLimiter(sensitiveEmail.Capacity, time.Minute/time.Duration(sensitiveEmail.RequestsPerMinute),
+			sensitiveEmail.MaxCacheSize, cleanupInterval, expireTime)
+		sensitiveMinResponse.Store(int64(time.Duration(cfg.RateLimiter.SensitiveMinResponseMs) * time.Millisecond))
+		logger.Info("敏感操作限流器初始化完成",
+			"ipCapacity", sensitiveIP.Capacity,
+			"ipRequestsPerMinute", sensitiveIP.RequestsPerMinute,
+			"emailCapacity", sensitiveEmail.Capacity,
+			"emailRequestsPerMinute", sensitiveEmail.RequestsPerMinute,
+			"minResponseMs", cfg.RateLimiter.SensitiveMinResponseMs)
+
+		configureRateLimitExemption(cfg)
+
 		logger.Info("所有限流器初始化完成（LRU）")
 	})
 }
 
+// UpdateRateLimiters 按新配置调整全局/登录/注册/用户/敏感操作限流器（配置热更新时调用）
+func UpdateRateLimiters(cfg *config.Config) {
+	logger := utils.GetLogger()
+	rateLimitHeadersOnSuccess.Store(cfg.RateLimiter.HeadersOnSuccess)
+	configureRateLimitExemption(cfg)
+
+	items := []struct {
+		name    string
+		limiter *LRURateLimiter
+		item    config.RateLimiterItemConfig
+	}{
+		{"global", globalIPRateLimiter, cfg.RateLimiter.Global},
+		{"login", globalLoginRateLimiter, cfg.RateLimiter.Login},
+		{"register", globalRegisterRateLimiter, cfg.RateLimiter.Register},
+		{"user", globalUserRateLimiter, cfg.RateLimiter.User},
+		{"sensitive_ip", globalSensitiveIPLimiter, cfg.RateLimiter.SensitiveIP},
+		{"sensitive_email", globalSensitiveEmailLimiter, cfg.RateLimiter.SensitiveEmail},
+	}
+	if cfg.RateLimiter.SensitiveMinResponseMs >= 0 {
+		sensitiveMinResponse.Store(int64(time.Duration(cfg.RateLimiter.SensitiveMinResponseMs) * time.Millisecond))
+	}
+
+	for _, it := range items {
+		if it.limiter == nil {
+			continue
+		}
+		if it.item.Capacity <= 0 || it.item.RequestsPerMinute <= 0 || it.item.MaxCacheSize <= 0 {
+			logger.Warn("限流配置无效，保持原配置", "limiter", it.name,
+				"capacity", it.item.Capacity,
+				"requestsPerMinute", it.item.RequestsPerMinute,
+				"maxCacheSize", it.item.MaxCacheSize)
+			continue
+		}
+		refillRate := time.Minute / time.Duration(it.item.RequestsPerMinute)
+		it.limiter.UpdateLimits(it.item.Capacity, refillRate, it.item.MaxCacheSize)
+		logger.Info("限流器参数已更新", "limiter", it.name,
+			"capacity", it.item.Capacity,
+			"requestsPerMinute", it.item.RequestsPerMinute,
+			"maxSize", it.item.MaxCacheSize)
+	}
+}
+
 // ShutdownRateLimiters 优雅关闭所有限流器，释放资源
 func ShutdownRateLimiters() {
 	logger := utils.GetLogger()
@@ -304,11 +456,55 @@ func ShutdownRateLimiters() {
 		globalUploadRateLimiter.Stop()
 		logger.Info("上传限流器已关闭")
 	}
+	if globalUserRateLimiter != nil {
+		globalUserRateLimiter.Stop()
+		logger.Info("用户限流器已关闭")
+	}
+	if globalSensitiveIPLimiter != nil {
+		globalSensitiveIPLimiter.Stop()
+	}
+	if globalSensitiveEmailLimiter != nil {
+		globalSensitiveEmailLimiter.Stop()
+	}
+	if globalSensitiveIPLimiter != nil || globalSensitiveEmailLimiter != nil {
+		logger.Info("敏感操作限流器已关闭")
+	}
 
 	logger.Info("所有限流器已关闭")
 }
 
-// RateLimitMiddleware 限流中间件
+// takeWithHeaders 消耗一个令牌并写入限流响应头
+// 被限流时总是写入 Retry-After 和 X-RateLimit-*；放行时仅在 headers_on_success 开启时写入
+// X-RateLimit-Reset 为距令牌桶补满的秒数
+func takeWithHeaders(c *gin.Context, limiter *LRURateLimiter, key string) bool {
+	result := limiter.Take(key)
+	if result.Allowed && !rateLimitHeadersOnSuccess.Load() {
+		return true
+	}
+
+	header := c.Writer.Header()
+	header.Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+	header.Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	header.Set("X-RateLimit-Reset", strconv.FormatInt(ceilSeconds(result.Reset), 10))
+	if !result.Allowed {
+		header.Set("Retry-After", strconv.FormatInt(ceilSeconds(result.RetryAfter), 10))
+	}
+	return result.Allowed
+}
+
+// ceilSeconds 时长向上取整为秒（至少为1秒，避免客户端立即重试）
+func ceilSeconds(d time.Duration) int64 {
+	if d <= 0 {
+		return 0
+	}
+	secs := int64((d + time.Second - 1) / time.Second)
+	if secs < 1 {
+		secs = 1
+	}
+	return secs
+}
+
+// RateLimitMiddleware 限流中间件（豁免名单内的IP和管理员跳过）
 func RateLimitMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// globalIPRateLimiter should be initialized before routes setup
@@ -318,9 +514,44 @@ func RateLimitMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		if isRateLimitExempt(c) {
+			c.Next()
+			return
+		}
+
 		clientIP := c.ClientIP()
 
-		if !globalIPRateLimiter.Allow(clientIP) {
+		if !takeWithHeaders(c, globalIPRateLimiter, clientIP) {
+			utils.TooManyRequestsResponse(c, "请求频率过高，请稍后再试")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// UserRateLimitMiddleware 登录用户限流中间件（需放在 AuthMiddleware 之后）
+// 按用户ID计数，避免共享出口IP的用户互相影响、也防止单个账号轮换IP绕过限流；无用户信息时回退为按IP
+func UserRateLimitMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if globalUserRateLimiter == nil {
+			utils.GetLogger().Error("用户限流器未初始化")
+			c.Next()
+			return
+		}
+
+		if isRateLimitExempt(c) {
+			c.Next()
+			return
+		}
+
+		key := "ip:" + c.ClientIP()
+		if userID := c.GetString("userID"); userID != "" {
+			key = "user:" + userID
+		}
+
+		if !takeWithHeaders(c, globalUserRateLimiter, key) {
 			utils.TooManyRequestsResponse(c, "请求频率过高，请稍后再试")
 			c.Abort()
 			return
@@ -340,9 +571,14 @@ func LoginRateLimitMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		if isRateLimitExempt(c) {
+			c.Next()
+			return
+		}
+
 		clientIP := c.ClientIP()
 
-		if !globalLoginRateLimiter.Allow(clientIP) {
+		if !takeWithHeaders(c, globalLoginRateLimiter, clientIP) {
 			utils.TooManyRequestsResponse(c, "登录尝试次数过多，请稍后再试")
 			c.Abort()
 			return
@@ -362,9 +598,14 @@ func RegisterRateLimitMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		if isRateLimitExempt(c) {
+			c.Next()
+			return
+		}
+
 		clientIP := c.ClientIP()
 
-		if !globalRegisterRateLimiter.Allow(clientIP) {
+		if !takeWithHeaders(c, globalRegisterRateLimiter, clientIP) {
 			utils.TooManyRequestsResponse(c, "注册尝试次数过多，请稍后再试")
 			c.Abort()
 			return
@@ -374,6 +615,67 @@ func RegisterRateLimitMiddleware() gin.HandlerFunc {
 	}
 }
 
+// sensitiveBodyPeekLimit 读取请求体提取邮箱时最多读取的字节数
+const sensitiveBodyPeekLimit = 64 << 10
+
+// SensitiveActionRateLimitMiddleware 匿名敏感操作限流中间件（注册、找回密码、重发验证邮件等）
+// 所有接入的接口共享按IP和按邮箱两份额度，防止换接口绕过限流和邮件轰炸；
+// 放行的请求会补齐到 sensitive_min_response_ms，避免通过响应耗时判断账号是否存在
+func SensitiveActionRateLimitMiddleware(action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if globalSensitiveIPLimiter == nil || globalSensitiveEmailLimiter == nil {
+			utils.GetLogger().Error("敏感操作限流器未初始化")
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		clientIP := c.ClientIP()
+
+		if !takeWithHeaders(c, globalSensitiveIPLimiter, "ip:"+clientIP) {
+			utils.GetLogger().Warn("敏感操作触发IP限流", "action", action, "ip", clientIP)
+			utils.TooManyRequestsResponse(c, "操作过于频繁，请稍后再试")
+			c.Abort()
+			return
+		}
+
+		if email := peekRequestEmail(c); email != "" && !takeWithHeaders(c, globalSensitiveEmailLimiter, "email:"+email) {
+			utils.GetLogger().Warn("敏感操作触发邮箱限流", "action", action, "ip", clientIP, "email", utils.SanitizeEmail(email))
+			utils.TooManyRequestsResponse(c, "操作过于频繁，请稍后再试")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+
+		// 补齐最短响应时间（响应在处理器返回后才会刷出）
+		if remaining := time.Duration(sensitiveMinResponse.Load()) - time.Since(start); remaining > 0 {
+			time.Sleep(remaining)
+		}
+	}
+}
+
+// peekRequestEmail 从JSON请求体中读取 email 字段（统一小写），读取后恢复请求体供处理器绑定
+func peekRequestEmail(c *gin.Context) string {
+	if c.Request.Body == nil {
+		return ""
+	}
+
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, sensitiveBodyPeekLimit))
+	c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(data), c.Request.Body))
+	if err != nil {
+		return ""
+	}
+
+	var payload struct {
+		Email string `json:"email"`
+	}
+	if json.Unmarshal(data, &payload) != nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(payload.Email))
+}
+
 // UploadRateLimitMiddleware 头像上传限流中间件（防止频繁上传）
 func UploadRateLimitMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -386,7 +688,7 @@ func UploadRateLimitMiddleware() gin.HandlerFunc {
 
 		clientIP := c.ClientIP()
 
-		if !globalUploadRateLimiter.Allow(clientIP) {
+		if !takeWithHeaders(c, globalUploadRateLimiter, clientIP) {
 			utils.CodeErrorResponse(c, 429, utils.ErrCodeRateLimitExceeded, "上传过于频繁，请稍后再试")
 			c.Abort()
 			return
@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"net"
+	"strings"
+	"sync/atomic"
+
+	"gin/internal/config"
+	"gin/internal/services"
+	"gin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimitExemption 限流豁免规则（启动和配置热更新时构建，网段只解析一次）
+type rateLimitExemption struct {
+	networks []*net.IPNet
+	admins   bool
+	cfg      *config.Config // JWT校验使用的配置（与 AuthMiddleware 相同的校验逻辑）
+}
+
+// rateLimitTokenBlacklist 管理员豁免时用于检查JWT是否已吊销（InitRateLimiter 设置）
+var rateLimitTokenBlacklist *services.TokenBlacklist
+
+// currentRateLimitExemption 当前生效的豁免规则（nil 表示不豁免）
+var currentRateLimitExemption atomic.Pointer[rateLimitExemption]
+
+// configureRateLimitExemption 按配置构建豁免规则，无效的网段跳过并记录警告
+func configureRateLimitExemption(cfg *config.Config) {
+	exempt := cfg.RateLimiter.Exempt
+	rules := &rateLimitExemption{
+		networks: parseIPNets(exempt.CIDRs),
+		admins:   exempt.Admins,
+		cfg:      cfg,
+	}
+	currentRateLimitExemption.Store(rules)
+
+	if len(rules.networks) > 0 || rules.admins {
+		utils.GetLogger().Info("限流豁免规则已加载", "networks", len(rules.networks), "admins", rules.admins)
+	}
+}
+
+// parseIPNets 解析IP或CIDR列表（单个IP按 /32 或 /128 处理）
+func parseIPNets(entries []string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			networks = append(networks, network)
+			continue
+		}
+		ip := net.ParseIP(entry)
+		if ip == nil {
+			utils.GetLogger().Warn("忽略无效的限流豁免地址", "entry", entry)
+			continue
+		}
+		bits := 128
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 32
+		}
+		networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	}
+	return networks
+}
+
+// isRateLimitExempt 请求是否豁免限流
+// 客户端IP由 gin 按可信代理解析（c.ClientIP），非可信来源伪造的 X-Forwarded-For 不会生效
+func isRateLimitExempt(c *gin.Context) bool {
+	rules := currentRateLimitExemption.Load()
+	if rules == nil {
+		return false
+	}
+
+	if len(rules.networks) > 0 {
+		if ip := net.ParseIP(c.ClientIP()); ip != nil {
+			for _, network := range rules.networks {
+				if network.Contains(ip) {
+					return true
+				}
+			}
+		}
+	}
+
+	return rules.admins && hasAdminToken(c, rules)
+}
+
+// hasAdminToken 请求头是否携带有效的管理员JWT（限流发生在认证中间件之前，按与 AuthMiddleware 相同的逻辑
+// 校验签名、有效期、签发者，并拒绝已退出登录或已注销全部会话的token）
+func hasAdminToken(c *gin.Context, rules *rateLimitExemption) bool {
+	tokenPrefix := rules.cfg.JWTExtended.TokenPrefix
+	authHeader := c.GetHeader("Authorization")
+	if !strings.HasPrefix(authHeader, tokenPrefix) {
+		return false
+	}
+
+	claims, err := parseJWT(rules.cfg, authHeader[len(tokenPrefix):])
+	if err != nil {
+		return false
+	}
+	if blacklist := rateLimitTokenBlacklist; blacklist != nil && tokenRevocationError(c.Request.Context(), blacklist, claims) != nil {
+		return false
+	}
+	return utils.GetAdminChecker().IsAdmin(claims.Username)
+}
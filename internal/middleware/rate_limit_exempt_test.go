@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/models"
+	"gin/internal/services"
+	"gin/internal/testutil"
+	"gin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// testAuthEnv JWT认证测试环境：token_version 查询由 FakeDB 返回 currentVersion
+type testAuthEnv struct {
+	cfg            *config.Config
+	blacklist      *services.TokenBlacklist
+	currentVersion atomic.Int64
+}
+
+func newTestAuthEnv(t *testing.T) *testAuthEnv {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfg := config.Default()
+	cfg.JWT.SecretKey = "test-secret-key"
+	cfg.JWTExtended.TokenVersionCacheSec = 0
+	cfg.Admin.Usernames = []string{"admin"}
+	utils.InitAdminChecker(cfg)
+
+	env := &testAuthEnv{cfg: cfg}
+	fake := testutil.NewFakeDB()
+	fake.On(`SELECT token_version FROM user_auth WHERE id = \?`, func([]driver.Value) testutil.Response {
+		return testutil.Response{Columns: []string{"token_version"}, Rows: [][]driver.Value{{env.currentVersion.Load()}}}
+	})
+	db := services.NewDatabaseWithDB(cfg, fake.Open())
+	env.blacklist = services.NewTokenBlacklist(cfg, services.NewUserRepository(db))
+	return env
+}
+
+// sign 签发测试JWT
+func (e *testAuthEnv) sign(t *testing.T, userID uint, username, jti string, tokenVersion int) string {
+	t.Helper()
+	return signTestJWT(t, e.cfg, e.cfg.JWT.Issuer, userID, username, jti, tokenVersion)
+}
+
+func signTestJWT(t *testing.T, cfg *config.Config, issuer string, userID uint, username, jti string, tokenVersion int) string {
+	t.Helper()
+	claims := models.CreateClaims(userID, username, username+"@example.com", "", "", issuer, jti, tokenVersion, time.Hour)
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(cfg.JWT.SecretKey))
+	if err != nil {
+		t.Fatalf("签发token失败: %v", err)
+	}
+	return token
+}
+
+func exemptRequest(token string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/articles", nil)
+	c.Request.RemoteAddr = "203.0.113.10:1234"
+	if token != "" {
+		c.Request.Header.Set("Authorization", "Bearer "+token)
+	}
+	return c
+}
+
+func TestAdminRateLimitExemptionUsesAuthValidation(t *testing.T) {
+	env := newTestAuthEnv(t)
+	env.cfg.RateLimiter.Exempt.Admins = true
+	configureRateLimitExemption(env.cfg)
+	rateLimitTokenBlacklist = env.blacklist
+	t.Cleanup(func() {
+		currentRateLimitExemption.Store(nil)
+		rateLimitTokenBlacklist = nil
+	})
+
+	if !isRateLimitExempt(exemptRequest(env.sign(t, 1, "admin", "jti-valid", 0))) {
+		t.Fatal("有效的管理员token应豁免限流")
+	}
+	if isRateLimitExempt(exemptRequest(env.sign(t, 2, "alice", "jti-user", 0))) {
+		t.Fatal("普通用户不应豁免限流")
+	}
+	if isRateLimitExempt(exemptRequest("")) {
+		t.Fatal("未携带token不应豁免限流")
+	}
+
+	// 退出登录（jti 进入黑名单）后不再豁免
+	env.blacklist.Revoke("jti-logged-out", time.Now().Add(time.Hour))
+	if isRateLimitExempt(exemptRequest(env.sign(t, 1, "admin", "jti-logged-out", 0))) {
+		t.Fatal("已退出登录的管理员token不应豁免限流")
+	}
+
+	// 注销全部会话（token_version 递增）后旧token不再豁免
+	env.currentVersion.Store(1)
+	if isRateLimitExempt(exemptRequest(env.sign(t, 1, "admin", "jti-old-version", 0))) {
+		t.Fatal("注销全部会话前签发的管理员token不应豁免限流")
+	}
+	if !isRateLimitExempt(exemptRequest(env.sign(t, 1, "admin", "jti-new-version", 1))) {
+		t.Fatal("当前版本的管理员token应豁免限流")
+	}
+
+	// 签发者不匹配（AuthMiddleware 同样拒绝）
+	if isRateLimitExempt(exemptRequest(signTestJWT(t, env.cfg, "other-issuer", 1, "admin", "jti-issuer", 1))) {
+		t.Fatal("签发者不匹配的token不应豁免限流")
+	}
+}
+
+func TestAuthMiddlewareRejectsRevokedTokens(t *testing.T) {
+	env := newTestAuthEnv(t)
+
+	router := gin.New()
+	router.GET("/me", AuthMiddleware(env.cfg, nil, env.blacklist), func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString("userID"))
+	})
+	call := func(token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := call(env.sign(t, 7, "bob", "jti-a", 0)); w.Code != http.StatusOK || w.Body.String() != strconv.Itoa(7) {
+		t.Fatalf("有效token应通过认证，实际 %d %s", w.Code, w.Body.String())
+	}
+
+	env.blacklist.Revoke("jti-a", time.Now().Add(time.Hour))
+	if w := call(env.sign(t, 7, "bob", "jti-a", 0)); w.Code != http.StatusUnauthorized {
+		t.Fatalf("已退出登录的token应返回401，实际 %d", w.Code)
+	}
+
+	env.currentVersion.Store(3)
+	if w := call(env.sign(t, 7, "bob", "jti-b", 2)); w.Code != http.StatusUnauthorized {
+		t.Fatalf("旧版本token应返回401，实际 %d", w.Code)
+	}
+}
@@ -0,0 +1,247 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// useRateLimiter 替换全局限流器（补充间隔1小时，测试期间不会补充令牌；测试结束后恢复）
+func useRateLimiter(t *testing.T, target **LRURateLimiter, capacity int) *LRURateLimiter {
+	t.Helper()
+	limiter := NewLRURateLimiter(capacity, time.Hour, 100, 60, 30)
+	previous := *target
+	*target = limiter
+	t.Cleanup(func() {
+		limiter.Stop()
+		*target = previous
+	})
+	return limiter
+}
+
+func TestUserRateLimitKeyedByUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	useRateLimiter(t, &globalUserRateLimiter, 2)
+
+	router := gin.New()
+	router.GET("/api/items", func(c *gin.Context) {
+		if userID := c.GetHeader("X-Test-User"); userID != "" {
+			c.Set("userID", userID)
+		}
+	}, UserRateLimitMiddleware(), func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+	call := func(userID, ip string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/items", nil)
+		req.RemoteAddr = ip + ":1234"
+		if userID != "" {
+			req.Header.Set("X-Test-User", userID)
+		}
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	for i := 0; i < 2; i++ {
+		if got := call("1", "203.0.113.1"); got != http.StatusOK {
+			t.Fatalf("额度内的请求应放行，实际 %d", got)
+		}
+	}
+	if got := call("1", "203.0.113.1"); got != http.StatusTooManyRequests {
+		t.Fatalf("超过用户额度应返回429，实际 %d", got)
+	}
+	if got := call("1", "198.51.100.7"); got != http.StatusTooManyRequests {
+		t.Fatalf("同一用户更换IP不应绕过限流，实际 %d", got)
+	}
+	if got := call("2", "203.0.113.1"); got != http.StatusOK {
+		t.Fatalf("共享出口IP的其他用户不应受影响，实际 %d", got)
+	}
+
+	// 匿名请求按IP计数，与同IP的登录用户互不影响
+	for i := 0; i < 2; i++ {
+		if got := call("", "203.0.113.1"); got != http.StatusOK {
+			t.Fatalf("匿名请求额度内应放行，实际 %d", got)
+		}
+	}
+	if got := call("", "203.0.113.1"); got != http.StatusTooManyRequests {
+		t.Fatalf("匿名请求超过IP额度应返回429，实际 %d", got)
+	}
+}
+
+func TestUserRateLimiterExpiresIdleEntries(t *testing.T) {
+	limiter := useRateLimiter(t, &globalUserRateLimiter, 1)
+	limiter.Allow("user:1")
+	limiter.Allow("user:2")
+
+	// 让 user:1 闲置超过过期时间
+	limiter.mutex.Lock()
+	limiter.limiters["user:1"].lastAccess = time.Now().Add(-time.Hour)
+	limiter.lruList.MoveToBack(limiter.limiters["user:1"].element)
+	limiter.mutex.Unlock()
+
+	limiter.cleanup(30)
+	if limiter.Size() != 1 {
+		t.Fatalf("闲置的用户条目应被清理，剩余 %d", limiter.Size())
+	}
+	if !limiter.Allow("user:1") {
+		t.Fatal("清理后的用户应重新获得完整额度")
+	}
+}
+
+func TestTokenBucketTakeReportsRemainingAndRetry(t *testing.T) {
+	tb := NewTokenBucket(2, 10*time.Second)
+
+	first := tb.Take()
+	if !first.Allowed || first.Limit != 2 || first.Remaining != 1 || first.RetryAfter != 0 {
+		t.Fatalf("第一次请求应放行且剩余1个令牌，实际 %+v", first)
+	}
+	second := tb.Take()
+	if !second.Allowed || second.Remaining != 0 {
+		t.Fatalf("第二次请求应放行且令牌耗尽，实际 %+v", second)
+	}
+	if second.RetryAfter <= 9*time.Second || second.RetryAfter > 10*time.Second {
+		t.Fatalf("令牌耗尽后下一个令牌约在10秒后可用，实际 %v", second.RetryAfter)
+	}
+	if second.Reset <= 19*time.Second || second.Reset > 20*time.Second {
+		t.Fatalf("补满2个令牌约需20秒，实际 %v", second.Reset)
+	}
+	if denied := tb.Take(); denied.Allowed || denied.Remaining != 0 || denied.RetryAfter <= 0 {
+		t.Fatalf("令牌耗尽时应拒绝并给出重试时间，实际 %+v", denied)
+	}
+
+	// 经过15秒：补充1个令牌，剩余5秒的零头计入下一个令牌
+	tb.mutex.Lock()
+	tb.lastRefill = tb.lastRefill.Add(-15 * time.Second)
+	tb.mutex.Unlock()
+	refilled := tb.Take()
+	if !refilled.Allowed || refilled.Remaining != 0 {
+		t.Fatalf("补充后应放行一次，实际 %+v", refilled)
+	}
+	if refilled.RetryAfter <= 4*time.Second || refilled.RetryAfter > 5*time.Second {
+		t.Fatalf("下一个令牌应约在5秒后可用，实际 %v", refilled.RetryAfter)
+	}
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	useRateLimiter(t, &globalIPRateLimiter, 2)
+	t.Cleanup(func() { rateLimitHeadersOnSuccess.Store(false) })
+
+	router := gin.New()
+	router.GET("/api/items", RateLimitMiddleware(), func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	call := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/items", nil)
+		req.RemoteAddr = "203.0.113.9:1234"
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// 未开启 headers_on_success 时放行的请求不带限流头
+	if w := call(); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Remaining") != "" {
+		t.Fatalf("默认放行的请求不应带限流头，实际 %d %v", w.Code, w.Header())
+	}
+
+	rateLimitHeadersOnSuccess.Store(true)
+	w := call()
+	if w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "2" || w.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Fatalf("开启后放行的请求应带剩余令牌数，实际 %d %v", w.Code, w.Header())
+	}
+	if w.Header().Get("Retry-After") != "" {
+		t.Fatal("放行的请求不应带 Retry-After")
+	}
+
+	rateLimitHeadersOnSuccess.Store(false)
+	w = call()
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("超过额度应返回429，实际 %d", w.Code)
+	}
+	h := w.Header()
+	if h.Get("Retry-After") != "3600" || h.Get("X-RateLimit-Limit") != "2" || h.Get("X-RateLimit-Remaining") != "0" || h.Get("X-RateLimit-Reset") != "7200" {
+		t.Fatalf("被限流时应返回 Retry-After 和 X-RateLimit-* 头，实际 %v", h)
+	}
+}
+
+// newSensitiveRouter 注册、找回密码、重发验证邮件三个接口共用敏感操作限流，处理器回显请求体中的邮箱
+func newSensitiveRouter(t *testing.T, ipCapacity, emailCapacity int) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	useRateLimiter(t, &globalSensitiveIPLimiter, ipCapacity)
+	useRateLimiter(t, &globalSensitiveEmailLimiter, emailCapacity)
+
+	router := gin.New()
+	echo := func(c *gin.Context) {
+		var req struct {
+			Email string `json:"email"`
+		}
+		_ = c.ShouldBindJSON(&req)
+		c.String(http.StatusOK, req.Email)
+	}
+	router.POST("/auth/register", SensitiveActionRateLimitMiddleware("register"), echo)
+	router.POST("/auth/password/reset-request", SensitiveActionRateLimitMiddleware("reset_request"), echo)
+	router.POST("/auth/verification/resend", SensitiveActionRateLimitMiddleware("resend_verification"), echo)
+	return router
+}
+
+func sensitiveRequest(router http.Handler, path, ip, email string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"email":"`+email+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = ip + ":1234"
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestSensitiveActionsShareIPBudget(t *testing.T) {
+	router := newSensitiveRouter(t, 3, 100)
+	paths := []string{"/auth/register", "/auth/password/reset-request", "/auth/verification/resend"}
+
+	for i, path := range paths {
+		w := sensitiveRequest(router, path, "203.0.113.5", "user"+strconv.Itoa(i)+"@example.com")
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s 在额度内应放行，实际 %d", path, w.Code)
+		}
+		if w.Body.String() != "user"+strconv.Itoa(i)+"@example.com" {
+			t.Fatalf("读取邮箱后处理器仍应能绑定请求体，实际 %q", w.Body.String())
+		}
+	}
+	for _, path := range paths {
+		if w := sensitiveRequest(router, path, "203.0.113.5", "new@example.com"); w.Code != http.StatusTooManyRequests {
+			t.Fatalf("同一IP在不同接口间共享额度，%s 应被限流，实际 %d", path, w.Code)
+		}
+	}
+	if w := sensitiveRequest(router, "/auth/register", "198.51.100.1", "new@example.com"); w.Code != http.StatusOK {
+		t.Fatalf("其他IP不受影响，实际 %d", w.Code)
+	}
+}
+
+func TestSensitiveActionsShareEmailBudget(t *testing.T) {
+	router := newSensitiveRouter(t, 100, 2)
+
+	sensitiveRequest(router, "/auth/register", "203.0.113.1", "victim@example.com")
+	sensitiveRequest(router, "/auth/password/reset-request", "203.0.113.2", " Victim@Example.com ")
+	if w := sensitiveRequest(router, "/auth/verification/resend", "203.0.113.3", "VICTIM@example.com"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("同一邮箱（忽略大小写）换IP、换接口也应被限流，实际 %d", w.Code)
+	}
+	if w := sensitiveRequest(router, "/auth/register", "203.0.113.3", "other@example.com"); w.Code != http.StatusOK {
+		t.Fatalf("其他邮箱不受影响，实际 %d", w.Code)
+	}
+}
+
+func TestSensitiveActionMinResponseTime(t *testing.T) {
+	router := newSensitiveRouter(t, 100, 100)
+	sensitiveMinResponse.Store(int64(30 * time.Millisecond))
+	t.Cleanup(func() { sensitiveMinResponse.Store(0) })
+
+	start := time.Now()
+	if w := sensitiveRequest(router, "/auth/register", "203.0.113.1", "a@example.com"); w.Code != http.StatusOK {
+		t.Fatalf("请求应放行，实际 %d", w.Code)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Fatalf("放行的请求应补齐到最短响应时间，实际 %v", elapsed)
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"sync"
 	"time"
 
+	"gin/internal/utils"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -43,8 +45,9 @@ func RequestIDMiddleware() gin.HandlerFunc {
 			requestID = genRequestID()
 		}
 
-		// 将请求ID设置到上下文中
+		// 将请求ID设置到上下文中（gin上下文供中间件读取，请求context供服务层日志和异步任务读取）
 		c.Set("requestID", requestID)
+		c.Request = c.Request.WithContext(utils.WithRequestID(c.Request.Context(), requestID))
 
 		// 将请求ID添加到响应头
 		c.Header("X-Request-ID", requestID)
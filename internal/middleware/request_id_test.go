@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestIDMiddleware())
+	var ctxID, ginID string
+	router.GET("/ping", func(c *gin.Context) {
+		ctxID = utils.RequestIDFromContext(c.Request.Context())
+		ginID = c.GetString("requestID")
+		c.Status(http.StatusOK)
+	})
+
+	request := func(header string) string {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		if header != "" {
+			req.Header.Set("X-Request-ID", header)
+		}
+		router.ServeHTTP(w, req)
+		return w.Header().Get("X-Request-ID")
+	}
+
+	// 沿用上游传入的请求ID
+	if got := request("upstream-1"); got != "upstream-1" || ctxID != "upstream-1" || ginID != "upstream-1" {
+		t.Fatalf("应沿用请求头中的请求ID并写入 context，实际 响应头=%q context=%q gin=%q", got, ctxID, ginID)
+	}
+
+	// 没有请求ID时生成新的，且每个请求不同
+	first := request("")
+	if first == "" || ctxID != first || ginID != first {
+		t.Fatalf("应生成请求ID并在响应头中返回，实际 响应头=%q context=%q", first, ctxID)
+	}
+	if second := request(""); second == first {
+		t.Fatalf("每个请求应生成不同的请求ID，实际都为 %q", first)
+	}
+}
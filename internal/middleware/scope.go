@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"gin/internal/config"
+	"gin/internal/models"
+	"gin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireScope 要求API令牌具有指定权限范围（如 read:articles）
+// 登录会话（JWT）拥有全部权限，直接放行；必须放在 AuthMiddleware 之后
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if CheckTokenScope(c, scope) {
+			c.Next()
+		}
+	}
+}
+
+// RequireRouteGroupScope 按 api_token.route_scopes 配置检查路由组权限
+// GET/HEAD/OPTIONS 需要 read:<资源>，其余方法需要 write:<资源>；未配置的路由组以组名作为资源名
+func RequireRouteGroupScope(cfg *config.Config, group string) gin.HandlerFunc {
+	readScope := RouteGroupScope(cfg, group, models.APITokenScopeRead)
+	writeScope := RouteGroupScope(cfg, group, models.APITokenScopeWrite)
+
+	return func(c *gin.Context) {
+		scope := writeScope
+		switch c.Request.Method {
+		case "GET", "HEAD", "OPTIONS":
+			scope = readScope
+		}
+		if CheckTokenScope(c, scope) {
+			c.Next()
+		}
+	}
+}
+
+// RouteGroupScope 获取路由组对应的权限范围，如 RouteGroupScope(cfg, "chat", "write") = "write:chat"
+func RouteGroupScope(cfg *config.Config, group, action string) string {
+	resource := cfg.APIToken.RouteScopes[group]
+	if resource == "" {
+		resource = group
+	}
+	return models.APITokenScope(action, resource)
+}
+
+// CheckTokenScope 检查当前请求的令牌权限，不满足时返回403并中止（供按参数决定权限的处理器调用）
+func CheckTokenScope(c *gin.Context, scope string) bool {
+	if c.GetString("authType") != AuthTypeAPIToken {
+		return true
+	}
+
+	scopes, _ := c.Get("apiTokenScopes")
+	granted, _ := scopes.([]string)
+	if models.ScopeAllows(granted, scope) {
+		return true
+	}
+
+	utils.GetLogger().Warn("API令牌权限不足",
+		"tokenID", c.GetUint("apiTokenID"),
+		"userID", c.GetString("userID"),
+		"requiredScope", scope,
+		"path", c.Request.URL.Path)
+	utils.ForbiddenResponse(c, "API令牌缺少权限: "+scope)
+	c.Abort()
+	return false
+}
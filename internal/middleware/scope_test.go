@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gin/internal/config"
+	"gin/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newScopeRouter 模拟认证结果（API令牌及其权限，或登录会话）后挂载路由组权限检查
+func newScopeRouter(cfg *config.Config, group string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	fakeAuth := func(c *gin.Context) {
+		if scopes := c.GetHeader("X-Test-Scopes"); scopes != "" {
+			c.Set("authType", AuthTypeAPIToken)
+			c.Set("apiTokenScopes", strings.Split(scopes, ","))
+		}
+	}
+	g := router.Group("/", fakeAuth, RequireRouteGroupScope(cfg, group))
+	ok := func(c *gin.Context) { c.String(http.StatusOK, "ok") }
+	g.GET("/items", ok)
+	g.POST("/items", ok)
+	g.DELETE("/items", ok)
+	return router
+}
+
+func scopeRequest(router http.Handler, method, scopes string) int {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, "/items", nil)
+	if scopes != "" {
+		req.Header.Set("X-Test-Scopes", scopes)
+	}
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestRequireRouteGroupScope(t *testing.T) {
+	cfg := config.Default()
+	router := newScopeRouter(cfg, "articles")
+
+	cases := []struct {
+		name   string
+		scopes string // 空表示登录会话
+		method string
+		want   int
+	}{
+		{"只读令牌可以GET", "read:articles", http.MethodGet, http.StatusOK},
+		{"只读令牌不能POST", "read:articles", http.MethodPost, http.StatusForbidden},
+		{"只读令牌不能DELETE", "read:articles", http.MethodDelete, http.StatusForbidden},
+		{"写令牌可以GET", "write:articles", http.MethodGet, http.StatusOK},
+		{"写令牌可以POST", "write:articles", http.MethodPost, http.StatusOK},
+		{"其他资源的令牌被拒绝", "write:resources", http.MethodGet, http.StatusForbidden},
+		{"不带资源的read覆盖所有资源", "read", http.MethodGet, http.StatusOK},
+		{"不带资源的read不能写", "read", http.MethodPost, http.StatusForbidden},
+		{"登录会话不受限制", "", http.MethodPost, http.StatusOK},
+		{"登录会话可以DELETE", "", http.MethodDelete, http.StatusOK},
+	}
+	for _, tc := range cases {
+		if got := scopeRequest(router, tc.method, tc.scopes); got != tc.want {
+			t.Errorf("%s: 状态码 %d，期望 %d", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestRequireRouteGroupScopeUsesConfiguredResource(t *testing.T) {
+	cfg := config.Default()
+	cfg.APIToken.RouteScopes["resources"] = "files"
+	router := newScopeRouter(cfg, "resources")
+
+	if got := scopeRequest(router, http.MethodGet, "read:files"); got != http.StatusOK {
+		t.Fatalf("按配置的资源名授权，实际 %d", got)
+	}
+	if got := scopeRequest(router, http.MethodGet, "read:resources"); got != http.StatusForbidden {
+		t.Fatalf("路由组配置了资源名后不应再接受组名，实际 %d", got)
+	}
+	if got := RouteGroupScope(cfg, "unknown", models.APITokenScopeWrite); got != "write:unknown" {
+		t.Fatalf("未配置的路由组应以组名作为资源名，实际 %q", got)
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/items", func(c *gin.Context) {
+		c.Set("authType", AuthTypeAPIToken)
+		c.Set("apiTokenScopes", strings.Split(c.GetHeader("X-Test-Scopes"), ","))
+	}, RequireScope("write:resources"), func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+	if got := scopeRequest(router, http.MethodPost, "read:resources,write:articles"); got != http.StatusForbidden {
+		t.Fatalf("缺少 write:resources 应返回403，实际 %d", got)
+	}
+	if got := scopeRequest(router, http.MethodPost, "read:articles,write:resources"); got != http.StatusOK {
+		t.Fatalf("拥有 write:resources 应放行，实际 %d", got)
+	}
+}
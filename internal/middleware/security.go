@@ -35,8 +35,15 @@ func SecurityHeadersMiddleware(cfg *config.Config) gin.HandlerFunc {
 }
 
 // RequestSizeLimitMiddleware 限制请求体大小
+// Content-Length 已超限时直接返回413；未声明长度（分块传输）时由 MaxBytesReader 在读取超限时报错
 func RequestSizeLimitMiddleware(maxBytes int64) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if c.Request.ContentLength > maxBytes {
+			utils.RequestTooLargeResponse(c, maxBytes)
+			c.Abort()
+			return
+		}
+
 		// 限制请求体大小，防止大文件攻击
 		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
 
@@ -49,7 +56,7 @@ func PanicRecoveryMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		defer func() {
 			if err := recover(); err != nil {
-				logger := utils.GetLogger()
+				logger := utils.LoggerFromContext(c.Request.Context())
 				logger.Error("请求处理发生panic",
 					"error", err,
 					"path", c.Request.URL.Path,
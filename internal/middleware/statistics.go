@@ -12,7 +12,7 @@ import (
 )
 
 // StatisticsMiddleware 统计中间件（自动收集数据）
-func StatisticsMiddleware(statsRepo *services.StatisticsRepository, cumulativeRepo *services.CumulativeStatsRepository) gin.HandlerFunc {
+func StatisticsMiddleware(statsAgg *services.StatisticsAggregator, cumulativeRepo *services.CumulativeStatsRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 		path := c.Request.URL.Path
@@ -36,6 +36,12 @@ func StatisticsMiddleware(statsRepo *services.StatisticsRepository, cumulativeRe
 		latency := time.Since(start)
 		status := c.Writer.Status()
 
+		// 接口告警统计和延迟分位数（按路由模板，未匹配路由的请求不统计）
+		services.GetEndpointAlertMonitor().Record(method, c.FullPath(), status, latency, time.Now())
+		if fullPath := c.FullPath(); fullPath != "" {
+			utils.GetGlobalProfiler().RecordEndpointLatency(method+" "+fullPath, latency)
+		}
+
 		// 在请求处理完成后，尝试获取用户ID（用于活跃用户统计）
 		userIDForActive := uint(0)
 		if uid, exists := c.Get("userID"); exists {
@@ -52,7 +58,7 @@ func StatisticsMiddleware(statsRepo *services.StatisticsRepository, cumulativeRe
 
 		// 使用Worker Pool记录统计数据（避免goroutine泄漏）
 		taskID := "stats_" + path + "_" + strconv.FormatInt(time.Now().UnixNano(), 36)
-		_ = utils.SubmitTask(taskID, func(ctx context.Context) error {
+		_ = utils.SubmitTaskWithContext(c.Request.Context(), taskID, func(ctx context.Context) error {
 			// 使用UTC确保与所有写入/读取每日指标的逻辑一致，防止跨时区日期不一致
 			date := time.Now().UTC().Format("2006-01-02")
 
@@ -72,9 +78,7 @@ func StatisticsMiddleware(statsRepo *services.StatisticsRepository, cumulativeRe
 			// 1. 记录登录统计
 			if path == "/api/auth/login" && method == "POST" && status == 200 {
 				// 按天统计
-				if err := statsRepo.IncrementLoginCount(date); err != nil {
-					utils.GetLogger().Error("记录登录统计失败", "date", date, "error", err.Error())
-				}
+				statsAgg.RecordLogin(date)
 
 				// 累计统计
 				if cumulativeRepo != nil {
@@ -90,9 +94,7 @@ func StatisticsMiddleware(statsRepo *services.StatisticsRepository, cumulativeRe
 				dailyMgr.RecordRegister()
 
 				// 按天统计
-				if err := statsRepo.IncrementRegisterCount(date); err != nil {
-					utils.GetLogger().Error("记录注册统计失败", "date", date, "error", err.Error())
-				}
+				statsAgg.RecordRegister(date)
 				// 累计统计
 				if err := cumulativeRepo.IncrementCumulativeStat("total_users", 1); err != nil {
 					utils.GetLogger().Error("更新累计用户统计失败", "error", err.Error())
@@ -104,13 +106,7 @@ func StatisticsMiddleware(statsRepo *services.StatisticsRepository, cumulativeRe
 			dailyMgr.RecordRequest(path, latency.Milliseconds(), isSuccess, isError)
 
 			// 按天按接口统计
-			if err := statsRepo.RecordApiCall(date, path, method, isSuccess, isError, latency.Milliseconds()); err != nil {
-				utils.GetLogger().Error("记录API统计失败",
-					"date", date,
-					"endpoint", path,
-					"method", method,
-					"error", err.Error())
-			}
+			statsAgg.RecordApiCall(date, path, method, isSuccess, isError, latency.Milliseconds())
 
 			// 累计统计更新
 			if err := cumulativeRepo.IncrementCumulativeStat("total_api_calls", 1); err != nil {
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// API令牌权限范围
+// 形如 read:articles / write:resources；不带资源的 read / write 表示对所有资源生效
+const (
+	APITokenScopeRead  = "read"  // 只读（GET/HEAD）
+	APITokenScopeWrite = "write" // 读写（包含创建、修改、删除）
+)
+
+// APITokenScope 拼接资源权限范围，如 APITokenScope("read", "articles") = "read:articles"
+func APITokenScope(action, resource string) string {
+	return action + ":" + resource
+}
+
+// parseAPITokenScope 拆分权限范围为操作和资源（资源为空表示全部资源）
+func parseAPITokenScope(scope string) (action, resource string) {
+	action, resource, _ = strings.Cut(scope, ":")
+	return action, resource
+}
+
+// IsValidAPITokenScope 判断权限范围是否可申请（resources 为已配置的资源名集合）
+func IsValidAPITokenScope(scope string, resources map[string]bool) bool {
+	action, resource := parseAPITokenScope(scope)
+	if action != APITokenScopeRead && action != APITokenScopeWrite {
+		return false
+	}
+	if resource == "" {
+		return !strings.Contains(scope, ":")
+	}
+	return resources[resource]
+}
+
+// ScopeAllows 判断已授予的权限范围是否满足要求
+// write 隐含同资源的 read；不带资源的 read / write 覆盖所有资源
+func ScopeAllows(granted []string, required string) bool {
+	reqAction, reqResource := parseAPITokenScope(required)
+	for _, s := range granted {
+		action, resource := parseAPITokenScope(s)
+		if resource != "" && resource != reqResource {
+			continue
+		}
+		if action == reqAction || (reqAction == APITokenScopeRead && action == APITokenScopeWrite) {
+			return true
+		}
+	}
+	return false
+}
+
+// APIToken 个人访问令牌（不包含明文与哈希）
+type APIToken struct {
+	ID          uint       `json:"id" db:"id"`
+	UserID      uint       `json:"user_id" db:"user_id"`
+	Name        string     `json:"name" db:"name"`
+	TokenPrefix string     `json:"token_prefix" db:"token_prefix"` // 明文前若干位，便于用户识别
+	Scopes      []string   `json:"scopes" db:"scopes"`
+	LastUsedAt  *time.Time `json:"last_used_at" db:"last_used_at"`
+	ExpiresAt   *time.Time `json:"expires_at" db:"expires_at"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+}
+
+// APITokenIdentity API令牌认证结果
+type APITokenIdentity struct {
+	TokenID  uint
+	UserID   uint
+	Username string
+	Email    string
+	Scopes   []string
+}
+
+// HasScope 判断令牌是否拥有指定权限（规则见 ScopeAllows）
+func (i *APITokenIdentity) HasScope(scope string) bool {
+	return ScopeAllows(i.Scopes, scope)
+}
+
+// CreateAPITokenRequest 创建API令牌请求
+type CreateAPITokenRequest struct {
+	Name          string   `json:"name" binding:"required,min=1,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`           // 如 read:articles、write:resources；read / write 表示全部资源
+	ExpiresInDays int      `json:"expires_in_days" binding:"omitempty,min=1"` // 有效期（天），0表示使用最长有效期
+}
+
+// CreateAPITokenResponse 创建API令牌响应（明文令牌仅返回这一次）
+type CreateAPITokenResponse struct {
+	Token string `json:"token"`
+	APIToken
+}
@@ -13,6 +13,7 @@ type Article struct {
 	ViewCount    int       `json:"view_count" db:"view_count"`
 	LikeCount    int       `json:"like_count" db:"like_count"`
 	CommentCount int       `json:"comment_count" db:"comment_count"`
+	Version      int       `json:"version" db:"version"` // 编辑版本号，更新文章时需回传
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
 }
@@ -60,11 +61,70 @@ type ArticleComment struct {
 	Content       string    `json:"content" db:"content"`
 	LikeCount     int       `json:"like_count" db:"like_count"`
 	ReplyCount    int       `json:"reply_count" db:"reply_count"`
-	Status        int       `json:"status" db:"status"` // 0-已删除，1-正常，2-已折叠
+	Status        int       `json:"status" db:"status"`       // 0-已删除，1-正常，2-已折叠
+	IsEdited      bool      `json:"is_edited" db:"is_edited"` // 是否编辑过
 	CreatedAt     time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// CommentEditHistory 评论编辑历史（保存编辑前的内容）
+type CommentEditHistory struct {
+	ID        uint      `json:"id" db:"id"`
+	CommentID uint      `json:"comment_id" db:"comment_id"`
+	Content   string    `json:"content" db:"content"`
+	EditedAt  time.Time `json:"edited_at" db:"edited_at"`
+}
+
+// ArticleRevision 文章修订（保存修改前的标题、描述和正文）
+type ArticleRevision struct {
+	ID          uint      `json:"id" db:"id"`
+	ArticleID   uint      `json:"article_id" db:"article_id"`
+	UserID      uint      `json:"user_id" db:"user_id"` // 修改者
+	Title       string    `json:"title" db:"title"`
+	Description string    `json:"description" db:"description"`
+	Content     string    `json:"content,omitempty" db:"content"` // 列表接口不返回正文
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// ArticleRevisionCurrent 修订ID为0时表示文章当前线上版本
+const ArticleRevisionCurrent = 0
+
+// 行级差异类型
+const (
+	DiffOpAdd    = "add"    // 新增行
+	DiffOpRemove = "remove" // 删除行
+	DiffOpChange = "change" // 修改行（同一位置的删除+新增）
+)
+
+// DiffLine 行级差异（行号从1开始，新增行没有旧行号，删除行没有新行号）
+type DiffLine struct {
+	Op      string `json:"op"`
+	OldLine int    `json:"old_line,omitempty"`
+	NewLine int    `json:"new_line,omitempty"`
+	OldText string `json:"old_text,omitempty"`
+	NewText string `json:"new_text,omitempty"`
+}
+
+// FieldDiff 字段差异
+type FieldDiff struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// ArticleRevisionDiff 两个文章版本之间的差异（只包含有变化的行，版本相同时为空）
+type ArticleRevisionDiff struct {
+	ArticleID      uint       `json:"article_id"`
+	FromRevisionID uint       `json:"from_revision_id"` // 0表示当前版本
+	ToRevisionID   uint       `json:"to_revision_id"`   // 0表示当前版本
+	Title          *FieldDiff `json:"title,omitempty"`
+	Description    *FieldDiff `json:"description,omitempty"`
+	Lines          []DiffLine `json:"lines"`
+	Added          int        `json:"added"`
+	Removed        int        `json:"removed"`
+	Changed        int        `json:"changed"`
+	Identical      bool       `json:"identical"`
+}
+
 // ArticleLike 文章点赞结构体
 type ArticleLike struct {
 	ID        uint      `json:"id" db:"id"`
@@ -91,10 +151,96 @@ type ArticleReport struct {
 	Status      int        `json:"status" db:"status"` // 0-待处理，1-已处理，2-已驳回
 	HandlerID   *uint      `json:"handler_id" db:"handler_id"`
 	HandlerNote string     `json:"handler_note" db:"handler_note"`
+	Action      string     `json:"action" db:"action"` // 处理动作：dismiss / hide_content / delete_content
 	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
 	HandledAt   *time.Time `json:"handled_at" db:"handled_at"`
 }
 
+// 举报状态
+const (
+	ReportStatusAll       = -1 // 查询时表示不限状态
+	ReportStatusPending   = 0  // 待处理
+	ReportStatusResolved  = 1  // 已处理（内容已隐藏或删除）
+	ReportStatusDismissed = 2  // 已驳回
+)
+
+// 举报处理动作
+const (
+	ReportActionDismiss       = "dismiss"        // 驳回举报
+	ReportActionHideContent   = "hide_content"   // 隐藏内容（文章退回草稿，评论折叠）
+	ReportActionDeleteContent = "delete_content" // 删除内容
+)
+
+// ValidReportActions 允许的举报处理动作
+var ValidReportActions = map[string]bool{
+	ReportActionDismiss:       true,
+	ReportActionHideContent:   true,
+	ReportActionDeleteContent: true,
+}
+
+// 举报对象类型
+const (
+	ReportTargetArticle = "article" // 举报文章
+	ReportTargetComment = "comment" // 举报评论（comment_id 不为空）
+)
+
+// ReportListItem 举报列表项（附带被举报对象和相关用户信息）
+type ReportListItem struct {
+	ArticleReport
+	TargetType     string `json:"target_type"`               // article / comment
+	ArticleTitle   string `json:"article_title"`             // 所属文章标题
+	CommentContent string `json:"comment_content,omitempty"` // 被举报评论内容
+	ReporterName   string `json:"reporter_name"`             // 举报人用户名
+	HandlerName    string `json:"handler_name,omitempty"`    // 处理人用户名
+}
+
+// ReportsResponse 举报列表响应
+type ReportsResponse struct {
+	Reports    []ReportListItem `json:"reports"`
+	Total      int              `json:"total"`
+	Page       int              `json:"page"`
+	PageSize   int              `json:"page_size"`
+	TotalPages int              `json:"total_pages"`
+}
+
+// ResolveReportRequest 处理举报请求
+type ResolveReportRequest struct {
+	Action string `json:"action" binding:"required,oneof=dismiss hide_content delete_content"`
+}
+
+// RenameTagRequest 重命名标签请求
+type RenameTagRequest struct {
+	Name string `json:"name" binding:"required,max=50"`
+}
+
+// MergeTagsRequest 合并标签请求（路径中的标签合并到 target_tag_id）
+type MergeTagsRequest struct {
+	TargetTagID uint `json:"target_tag_id" binding:"required"`
+}
+
+// BulkArticleStatusRequest 批量修改文章状态请求（管理员，0=草稿 1=发布 2=删除）
+type BulkArticleStatusRequest struct {
+	ArticleIDs []uint `json:"article_ids" binding:"required,min=1,dive,min=1"`
+	Status     *int   `json:"status" binding:"required,oneof=0 1 2"`
+}
+
+// BulkArticleStatusResult 批量修改文章状态结果
+type BulkArticleStatusResult struct {
+	Status     int    `json:"status"`
+	UpdatedIDs []uint `json:"updated_ids"` // 状态被修改的文章
+	SkippedIDs []uint `json:"skipped_ids"` // 已处于目标状态的文章
+	MissingIDs []uint `json:"missing_ids"` // 不存在的文章
+}
+
+// TagMergeResult 合并标签结果
+type TagMergeResult struct {
+	SourceTagID      uint       `json:"source_tag_id"`
+	Target           ArticleTag `json:"target"`
+	MovedRelations   int64      `json:"moved_relations"`   // 改指向目标标签的关联数
+	DedupedRelations int64      `json:"deduped_relations"` // 因文章已有目标标签而删除的关联数
+	ArticleIDs       []uint     `json:"article_ids"`       // 原来使用源标签的文章
+}
+
 // ========== 请求/响应 DTO ==========
 
 // ArticleAuthor 文章作者信息
@@ -121,6 +267,28 @@ type ArticleDetailResponse struct {
 	Categories []ArticleCategory  `json:"categories"`
 	Tags       []ArticleTag       `json:"tags"`
 	IsLiked    bool               `json:"is_liked"`
+	Partial    bool               `json:"-"` // 关联数据子查询失败、以空结果降级（不写入缓存）
+}
+
+// ArticleVersion 文章详情的版本信息（用于计算 ETag）
+// 不包含浏览次数：每次访问都会增加，包含后条件请求永远无法命中
+type ArticleVersion struct {
+	Version         int // 编辑版本号（updated_at 只精确到秒，同一秒内的多次编辑靠它区分）
+	UpdatedAt       time.Time
+	AuthorUpdatedAt time.Time // 作者资料（昵称、头像）更新时间
+	LikeCount       int
+	CommentCount    int
+	IsLiked         bool // 当前用户是否点赞（未登录为 false）
+}
+
+// ArticlePlainText 文章纯文本（去除Markdown格式和代码块，用于搜索索引、预览和阅读时长估算）
+type ArticlePlainText struct {
+	ArticleID      uint   `json:"article_id"`
+	Title          string `json:"title"`
+	Text           string `json:"text"`            // 纯文本正文，段落之间以空行分隔
+	WordCount      int    `json:"word_count"`      // 字数（中文按字、英文按词计）
+	ReadingMinutes int    `json:"reading_minutes"` // 预计阅读时长（分钟）
+	CodeBlockCount int    `json:"code_block_count"`
 }
 
 // ArticleListItem 文章列表项
@@ -136,6 +304,7 @@ type ArticleListItem struct {
 	CommentCount int               `json:"comment_count"`
 	CreatedAt    time.Time         `json:"created_at"`
 	UpdatedAt    time.Time         `json:"updated_at"`
+	Score        *float64          `json:"score,omitempty"` // trending 排序的衰减热度分（便于调试）
 }
 
 // ArticleListResponse 文章列表响应
@@ -169,6 +338,7 @@ type CreateArticleCodeBlock struct {
 
 // UpdateArticleRequest 更新文章请求
 type UpdateArticleRequest struct {
+	Version     int                      `json:"version" binding:"required,min=1"` // 客户端最后读取到的版本号（乐观锁）
 	Title       *string                  `json:"title" binding:"omitempty,min=1,max=200"`
 	Description *string                  `json:"description" binding:"omitempty,max=500"`
 	Content     *string                  `json:"content" binding:"omitempty,min=1"`
@@ -186,6 +356,11 @@ type CreateCommentRequest struct {
 	ReplyToUserID *uint  `json:"reply_to_user_id"` // 回复的用户ID
 }
 
+// UpdateCommentRequest 编辑评论请求
+type UpdateCommentRequest struct {
+	Content string `json:"content" binding:"required,min=1"`
+}
+
 // CommentDetailResponse 评论详情响应
 type CommentDetailResponse struct {
 	ArticleComment
@@ -193,6 +368,36 @@ type CommentDetailResponse struct {
 	ReplyToUser *CommentAuthor          `json:"reply_to_user,omitempty"` // 回复的用户信息
 	Replies     []CommentDetailResponse `json:"replies"`                 // 子评论列表
 	IsLiked     bool                    `json:"is_liked"`                // 当前用户是否点赞
+
+	// 分页加载回复时使用：还有未返回的直接回复时，用 replies_cursor 作为 after 参数继续加载
+	HasMoreReplies bool `json:"has_more_replies"`
+	RepliesCursor  uint `json:"replies_cursor,omitempty"`
+}
+
+// CommentRepliesResponse 单条评论的直接回复分页响应（按ID升序，游标为本页最后一条回复的ID）
+type CommentRepliesResponse struct {
+	ParentID   uint                    `json:"parent_id"`
+	Replies    []CommentDetailResponse `json:"replies"`
+	HasMore    bool                    `json:"has_more"`
+	NextCursor uint                    `json:"next_cursor,omitempty"`
+}
+
+// CommentAncestor 回复链中的一层评论（面包屑只返回作者和内容摘要）
+type CommentAncestor struct {
+	ID        uint          `json:"id"`
+	ParentID  uint          `json:"parent_id"`
+	Author    CommentAuthor `json:"author"`
+	Snippet   string        `json:"snippet"`
+	Deleted   bool          `json:"deleted"` // 已删除的评论不返回摘要
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// CommentAncestryResponse 评论回复链响应
+type CommentAncestryResponse struct {
+	CommentID uint              `json:"comment_id"`
+	ArticleID uint              `json:"article_id"`
+	Ancestors []CommentAncestor `json:"ancestors"` // 从一级评论到目标评论的父评论（不含目标评论本身）
+	Truncated bool              `json:"truncated"` // 超过最大层级或数据存在环时截断
 }
 
 // CommentsResponse 评论列表响应
@@ -202,6 +407,24 @@ type CommentsResponse struct {
 	Page       int                     `json:"page"`
 	PageSize   int                     `json:"page_size"`
 	TotalPages int                     `json:"total_pages"`
+	Sort       string                  `json:"sort"` // 一级评论的排序方式
+}
+
+// 一级评论排序方式（回复始终按时间正序）
+const (
+	CommentSortNewest = "newest" // 最新发表在前（默认）
+	CommentSortOldest = "oldest" // 最早发表在前
+	CommentSortTop    = "top"    // 点赞数多的在前，相同时最新在前
+)
+
+// NormalizeCommentSort 校验排序参数，无效或为空时返回默认的 newest
+func NormalizeCommentSort(sort string) string {
+	switch sort {
+	case CommentSortOldest, CommentSortTop:
+		return sort
+	default:
+		return CommentSortNewest
+	}
 }
 
 // CreateReportRequest 创建举报请求
@@ -220,5 +443,11 @@ type ArticleListQuery struct {
 	UserID     uint   `form:"user_id"`
 	Status     int    `form:"status"`
 	Keyword    string `form:"keyword"`
-	SortBy     string `form:"sort_by"` // latest, hot, popular
+	SortBy     string `form:"sort_by"`                                  // latest, hot, popular, trending
+	Feed       string `form:"feed" binding:"omitempty,oneof=following"` // following=只看关注用户的文章
+	ViewerID   uint   `form:"-"`                                        // 当前用户ID，用于过滤其屏蔽的作者
+	AuthorIDs  []uint `form:"-"`                                        // 关注流的作者ID（由处理器填充）
 }
+
+// ArticleFeedFollowing 关注流
+const ArticleFeedFollowing = "following"
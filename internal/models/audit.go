@@ -0,0 +1,57 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 管理员操作类型
+const (
+	AuditActionResolveReport  = "report.resolve"       // 处理举报
+	AuditActionRevokeSessions = "user.revoke_sessions" // 注销用户全部会话
+	AuditActionRenameTag      = "tag.rename"           // 重命名标签
+	AuditActionMergeTags      = "tag.merge"            // 合并标签
+	AuditActionArticleStatus  = "article.bulk_status"  // 批量修改文章状态
+	AuditActionDeleteArticles = "user.delete_articles" // 删除用户全部文章
+)
+
+// 审计目标类型
+const (
+	AuditTargetReport  = "report"
+	AuditTargetUser    = "user"
+	AuditTargetTag     = "tag"
+	AuditTargetArticle = "article"
+)
+
+// AdminAuditLog 管理员操作审计日志
+type AdminAuditLog struct {
+	ID         uint            `json:"id" db:"id"`
+	ActorID    uint            `json:"actor_id" db:"actor_id"`
+	ActorName  string          `json:"actor_name,omitempty"` // 列表接口返回
+	Action     string          `json:"action" db:"action"`
+	TargetType string          `json:"target_type" db:"target_type"`
+	TargetID   uint            `json:"target_id" db:"target_id"`
+	Before     json.RawMessage `json:"before,omitempty" db:"before_data"` // 操作前快照（JSON）
+	After      json.RawMessage `json:"after,omitempty" db:"after_data"`   // 操作后快照（JSON）
+	IPAddress  string          `json:"ip_address" db:"ip_address"`
+	CreatedAt  time.Time       `json:"created_at" db:"created_at"`
+}
+
+// AuditLogQuery 审计日志查询条件（零值表示不限）
+type AuditLogQuery struct {
+	ActorID    uint   `form:"actor_id"`
+	Action     string `form:"action"`
+	TargetType string `form:"target_type"`
+	TargetID   uint   `form:"target_id"`
+	Page       int    `form:"page"`
+	PageSize   int    `form:"page_size"`
+}
+
+// AuditLogListResponse 审计日志列表响应
+type AuditLogListResponse struct {
+	Logs       []AdminAuditLog `json:"logs"`
+	Total      int             `json:"total"`
+	Page       int             `json:"page"`
+	PageSize   int             `json:"page_size"`
+	TotalPages int             `json:"total_pages"`
+}
@@ -6,6 +6,7 @@ import "time"
 type ChatMessage struct {
 	ID          uint      `json:"id" db:"id"`
 	UserID      uint      `json:"user_id" db:"user_id"`
+	ClientMsgID string    `json:"client_msg_id,omitempty" db:"client_msg_id"` // 客户端生成的消息ID（可选，用于重发去重）
 	Username    string    `json:"username" db:"username"`
 	Nickname    string    `json:"nickname" db:"nickname"`
 	Avatar      string    `json:"avatar" db:"avatar"`
@@ -19,7 +20,8 @@ type ChatMessage struct {
 
 // SendMessageRequest 发送消息请求
 type SendMessageRequest struct {
-	Content string `json:"content" binding:"required,min=1,max=500"`
+	Content     string `json:"content" binding:"required,min=1,max=500"`
+	ClientMsgID string `json:"client_msg_id" binding:"omitempty,max=64"` // 可选，重复提交同一ID时返回已保存的消息
 }
 
 // SendMessageResponse 发送消息响应
@@ -1,19 +1,29 @@
 package models
 
-import "time"
+import (
+	"strings"
+	"time"
+	"unicode"
+)
 
 // CodeSnippet 代码片段结构体
 type CodeSnippet struct {
-	ID          uint      `json:"id" db:"id"`
-	UserID      uint      `json:"user_id" db:"user_id"`
-	Title       string    `json:"title" db:"title"`
-	Language    string    `json:"language" db:"language"`
-	Code        string    `json:"code" db:"code"`
-	Description string    `json:"description" db:"description"`
-	IsPublic    bool      `json:"is_public" db:"is_public"`
-	ShareToken  *string   `json:"share_token,omitempty" db:"share_token"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	ID             uint       `json:"id" db:"id"`
+	UserID         uint       `json:"user_id" db:"user_id"`
+	Title          string     `json:"title" db:"title"`
+	Language       string     `json:"language" db:"language"`
+	Code           string     `json:"code" db:"code"`
+	Description    string     `json:"description" db:"description"`
+	IsPublic       bool       `json:"is_public" db:"is_public"`
+	ShareToken     *string    `json:"share_token,omitempty" db:"share_token"`
+	ShareExpiresAt *time.Time `json:"share_expires_at,omitempty" db:"share_expires_at"` // 分享链接过期时间
+	ShareMaxViews  *int64     `json:"share_max_views,omitempty" db:"share_max_views"`   // 分享链接最多访问次数
+	ShareViewCount int64      `json:"share_view_count" db:"share_view_count"`           // 当前分享链接的访问次数
+	ExecutionCount int64      `json:"execution_count" db:"execution_count"`             // 公开期间的执行次数
+	ForkCount      int64      `json:"fork_count" db:"fork_count"`                       // 被复制次数
+	ForkedFrom     *uint      `json:"forked_from,omitempty" db:"forked_from"`           // 复制来源代码片段ID
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
 }
 
 // CodeExecution 代码执行记录结构体
@@ -44,10 +54,11 @@ type CodeCollaboration struct {
 
 // ExecuteCodeRequest 执行代码请求
 type ExecuteCodeRequest struct {
-	Language string `json:"language" binding:"required"`
-	Code     string `json:"code" binding:"required"`
-	Stdin    string `json:"stdin"`
-	SaveAs   string `json:"save_as"` // 可选：保存代码片段的标题
+	Language  string `json:"language" binding:"required"`
+	Code      string `json:"code" binding:"required"`
+	Stdin     string `json:"stdin"`
+	SaveAs    string `json:"save_as"`    // 可选：保存代码片段的标题
+	SnippetID *uint  `json:"snippet_id"` // 可选：运行的代码片段（公开片段计入执行次数）
 }
 
 // ExecuteCodeResponse 执行代码响应
@@ -77,10 +88,18 @@ type UpdateSnippetRequest struct {
 	IsPublic    *bool  `json:"is_public"`
 }
 
+// ShareSnippetRequest 生成分享链接请求（均可选）
+type ShareSnippetRequest struct {
+	ExpiresInHours *int   `json:"expires_in_hours" binding:"omitempty,min=0"` // 有效期（小时），不传使用默认值，0表示永不过期
+	MaxViews       *int64 `json:"max_views" binding:"omitempty,min=1"`        // 最多访问次数，不传表示不限
+}
+
 // ShareSnippetResponse 分享代码片段响应
 type ShareSnippetResponse struct {
-	ShareToken string `json:"share_token"`
-	ShareURL   string `json:"share_url"`
+	ShareToken string     `json:"share_token"`
+	ShareURL   string     `json:"share_url"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	MaxViews   *int64     `json:"max_views,omitempty"`
 }
 
 // LanguageInfo 支持的语言信息
@@ -120,24 +139,110 @@ type PistonExecuteResponse struct {
 
 // CodeSnippetListItem 代码片段列表项（简化版）
 type CodeSnippetListItem struct {
-	ID        uint      `json:"id"`
-	Title     string    `json:"title"`
-	Language  string    `json:"language"`
-	IsPublic  bool      `json:"is_public"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID             uint      `json:"id"`
+	Title          string    `json:"title"`
+	Language       string    `json:"language"`
+	IsPublic       bool      `json:"is_public"`
+	ExecutionCount int64     `json:"execution_count"`
+	ForkCount      int64     `json:"fork_count"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
 }
 
 // CodeSnippetWithUser 代码片段及用户信息
 type CodeSnippetWithUser struct {
-	ID          uint      `json:"id"`
-	UserID      uint      `json:"user_id"`
-	Username    string    `json:"username"`
-	Title       string    `json:"title"`
-	Language    string    `json:"language"`
-	Code        string    `json:"code"`
-	Description string    `json:"description"`
-	ShareToken  *string   `json:"share_token,omitempty"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID             uint      `json:"id"`
+	UserID         uint      `json:"user_id"`
+	Username       string    `json:"username"`
+	Title          string    `json:"title"`
+	Language       string    `json:"language"`
+	Code           string    `json:"code"`
+	Description    string    `json:"description"`
+	ShareToken     *string   `json:"share_token,omitempty"`
+	ExecutionCount int64     `json:"execution_count"`
+	ForkCount      int64     `json:"fork_count"`
+	ForkedFrom     *uint     `json:"forked_from,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// 公开代码片段排序方式
+const (
+	SnippetSortRecent       = "recent"        // 最新创建
+	SnippetSortMostExecuted = "most_executed" // 执行次数最多
+)
+
+// PublicSnippetQuery 公开代码片段列表查询条件
+type PublicSnippetQuery struct {
+	Language   string // 语言筛选（可选）
+	Keyword    string // 匹配标题和描述（可选）
+	SearchCode bool   // 关键词同时匹配代码内容
+	SortBy     string // recent（默认）/ most_executed
+	Limit      int
+	Offset     int
+}
+
+// SnippetFileType 代码片段下载时使用的文件扩展名和Content-Type
+type SnippetFileType struct {
+	Extension   string
+	ContentType string
+}
+
+// defaultSnippetFileType 未知语言按纯文本下载
+var defaultSnippetFileType = SnippetFileType{Extension: ".txt", ContentType: "text/plain; charset=utf-8"}
+
+// snippetFileTypes 语言 -> 下载文件类型（与代码执行器支持的语言保持一致）
+var snippetFileTypes = map[string]SnippetFileType{
+	"python":     {Extension: ".py", ContentType: "text/x-python; charset=utf-8"},
+	"javascript": {Extension: ".js", ContentType: "text/javascript; charset=utf-8"},
+	"java":       {Extension: ".java", ContentType: "text/x-java-source; charset=utf-8"},
+	"cpp":        {Extension: ".cpp", ContentType: "text/x-c++src; charset=utf-8"},
+	"c":          {Extension: ".c", ContentType: "text/x-csrc; charset=utf-8"},
+	"go":         {Extension: ".go", ContentType: "text/x-go; charset=utf-8"},
+	"rust":       {Extension: ".rs", ContentType: "text/x-rust; charset=utf-8"},
+	"php":        {Extension: ".php", ContentType: "text/x-php; charset=utf-8"},
+	"ruby":       {Extension: ".rb", ContentType: "text/x-ruby; charset=utf-8"},
+	"swift":      {Extension: ".swift", ContentType: "text/x-swift; charset=utf-8"},
+	"bash":       {Extension: ".sh", ContentType: "text/x-shellscript; charset=utf-8"},
+	"lua":        {Extension: ".lua", ContentType: "text/x-lua; charset=utf-8"},
+	"scala":      {Extension: ".scala", ContentType: "text/x-scala; charset=utf-8"},
+	"haskell":    {Extension: ".hs", ContentType: "text/x-haskell; charset=utf-8"},
+	"perl":       {Extension: ".pl", ContentType: "text/x-perl; charset=utf-8"},
+}
+
+// maxSnippetFilenameRunes 下载文件名（不含扩展名）的最大字符数
+const maxSnippetFilenameRunes = 100
+
+// GetSnippetFileType 获取语言对应的下载文件类型（未知语言返回 .txt）
+func GetSnippetFileType(language string) SnippetFileType {
+	if ft, ok := snippetFileTypes[strings.ToLower(language)]; ok {
+		return ft
+	}
+	return defaultSnippetFileType
+}
+
+// DownloadFilename 根据标题和语言生成下载文件名（去除路径分隔符等非法字符，标题已带扩展名时不重复追加）
+func (s *CodeSnippet) DownloadFilename() string {
+	ext := GetSnippetFileType(s.Language).Extension
+
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsControl(r), strings.ContainsRune(`/\:*?"<>|`, r):
+			return '_'
+		case unicode.IsSpace(r):
+			return ' '
+		}
+		return r
+	}, s.Title)
+	name = strings.Trim(name, " .")
+	if strings.HasSuffix(strings.ToLower(name), ext) {
+		name = strings.TrimRight(name[:len(name)-len(ext)], " .")
+	}
+	if runes := []rune(name); len(runes) > maxSnippetFilenameRunes {
+		name = strings.TrimRight(string(runes[:maxSnippetFilenameRunes]), " .")
+	}
+	if name == "" {
+		name = "snippet"
+	}
+	return name + ext
 }
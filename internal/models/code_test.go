@@ -0,0 +1,65 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetSnippetFileType(t *testing.T) {
+	cases := map[string]string{
+		"python":     ".py",
+		"javascript": ".js",
+		"java":       ".java",
+		"cpp":        ".cpp",
+		"c":          ".c",
+		"go":         ".go",
+		"rust":       ".rs",
+		"php":        ".php",
+		"ruby":       ".rb",
+		"swift":      ".swift",
+		"bash":       ".sh",
+		"lua":        ".lua",
+		"scala":      ".scala",
+		"haskell":    ".hs",
+		"perl":       ".pl",
+		"Python":     ".py",
+		"brainfuck":  ".txt",
+		"":           ".txt",
+	}
+	for language, want := range cases {
+		ft := GetSnippetFileType(language)
+		if ft.Extension != want {
+			t.Errorf("%q: 扩展名 %q，期望 %q", language, ft.Extension, want)
+		}
+		if !strings.HasSuffix(ft.ContentType, "; charset=utf-8") {
+			t.Errorf("%q: Content-Type 应声明 utf-8，实际 %q", language, ft.ContentType)
+		}
+	}
+}
+
+func TestSnippetDownloadFilename(t *testing.T) {
+	cases := []struct {
+		title, language, want string
+	}{
+		{"quick sort", "python", "quick sort.py"},
+		{"main.go", "go", "main.go"},
+		{"MAIN.GO", "go", "MAIN.go"},
+		{"../../etc/passwd", "bash", "_.._etc_passwd.sh"},
+		{"a:b*c?\"d\"<e>|f", "c", "a_b_c__d__e__f.c"},
+		{"line\nbreak", "ruby", "line_break.rb"},
+		{"  ...  ", "java", "snippet.java"},
+		{"", "unknown", "snippet.txt"},
+		{"排序算法", "cpp", "排序算法.cpp"},
+	}
+	for _, tc := range cases {
+		s := &CodeSnippet{Title: tc.title, Language: tc.language}
+		if got := s.DownloadFilename(); got != tc.want {
+			t.Errorf("标题 %q (%s): 文件名 %q，期望 %q", tc.title, tc.language, got, tc.want)
+		}
+	}
+
+	long := &CodeSnippet{Title: strings.Repeat("长", 150), Language: "go"}
+	if got := []rune(long.DownloadFilename()); len(got) != maxSnippetFilenameRunes+len(".go") {
+		t.Fatalf("过长的标题应截断为 %d 个字符，实际 %d", maxSnippetFilenameRunes, len(got)-len(".go"))
+	}
+}
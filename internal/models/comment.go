@@ -0,0 +1,248 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// 评论所属实体类型
+const (
+	CommentTargetArticle  = "article"  // 文章评论
+	CommentTargetResource = "resource" // 资源评论
+)
+
+// ValidCommentTargets 统一评论接口支持的实体类型
+var ValidCommentTargets = map[string]bool{
+	CommentTargetArticle:  true,
+	CommentTargetResource: true,
+}
+
+// Comment 统一评论结构（文章与资源评论使用相同的字段和嵌套方式）
+type Comment struct {
+	ID          uint           `json:"id"`
+	TargetType  string         `json:"target_type"` // article / resource
+	TargetID    uint           `json:"target_id"`
+	UserID      uint           `json:"user_id"`
+	ParentID    uint           `json:"parent_id"`
+	RootID      uint           `json:"root_id"`
+	Content     string         `json:"content"`
+	LikeCount   int            `json:"like_count"`
+	ReplyCount  int            `json:"reply_count"`
+	IsLiked     bool           `json:"is_liked"`
+	IsEdited    bool           `json:"is_edited"`
+	Author      CommentAuthor  `json:"author"`
+	ReplyToUser *CommentAuthor `json:"reply_to_user,omitempty"`
+	Replies     []Comment      `json:"replies"`
+	CreatedAt   time.Time      `json:"created_at"`
+
+	HasMoreReplies bool `json:"has_more_replies"`         // 还有未返回的直接回复（仅文章评论分页加载时）
+	RepliesCursor  uint `json:"replies_cursor,omitempty"` // 继续加载回复的游标
+}
+
+// UnifiedCommentsResponse 统一评论列表响应
+type UnifiedCommentsResponse struct {
+	TargetType string    `json:"target_type"`
+	TargetID   uint      `json:"target_id"`
+	Comments   []Comment `json:"comments"`
+	Total      int       `json:"total"`
+	Page       int       `json:"page"`
+	PageSize   int       `json:"page_size"`
+	TotalPages int       `json:"total_pages"`
+	Sort       string    `json:"sort"` // 一级评论的排序方式（资源评论固定为 newest）
+}
+
+// buildCommentTree 把任意评论树转换为统一结构（convert 返回当前节点及其子评论）
+func buildCommentTree[T any](items []T, convert func(T) (Comment, []T)) []Comment {
+	result := make([]Comment, 0, len(items))
+	for _, item := range items {
+		comment, children := convert(item)
+		comment.Replies = buildCommentTree(children, convert)
+		result = append(result, comment)
+	}
+	return result
+}
+
+// NewUnifiedArticleComments 把文章评论列表转换为统一结构
+func NewUnifiedArticleComments(articleID uint, resp *CommentsResponse) *UnifiedCommentsResponse {
+	comments := buildCommentTree(resp.Comments, func(c CommentDetailResponse) (Comment, []CommentDetailResponse) {
+		return Comment{
+			ID:          c.ID,
+			TargetType:  CommentTargetArticle,
+			TargetID:    c.ArticleID,
+			UserID:      c.UserID,
+			ParentID:    c.ParentID,
+			RootID:      c.RootID,
+			Content:     c.Content,
+			LikeCount:   c.LikeCount,
+			ReplyCount:  c.ReplyCount,
+			IsLiked:     c.IsLiked,
+			IsEdited:    c.IsEdited,
+			Author:      c.Author,
+			ReplyToUser: c.ReplyToUser,
+			CreatedAt:   c.CreatedAt,
+
+			HasMoreReplies: c.HasMoreReplies,
+			RepliesCursor:  c.RepliesCursor,
+		}, c.Replies
+	})
+
+	return &UnifiedCommentsResponse{
+		TargetType: CommentTargetArticle,
+		TargetID:   articleID,
+		Comments:   comments,
+		Total:      resp.Total,
+		Page:       resp.Page,
+		PageSize:   resp.PageSize,
+		TotalPages: resp.TotalPages,
+		Sort:       resp.Sort,
+	}
+}
+
+// NewUnifiedResourceComments 把资源评论列表转换为统一结构
+func NewUnifiedResourceComments(resourceID uint, resp *ResourceCommentsResponse) *UnifiedCommentsResponse {
+	comments := buildCommentTree(resp.Comments, func(c ResourceCommentResponse) (Comment, []ResourceCommentResponse) {
+		comment := Comment{
+			ID:          c.ID,
+			TargetType:  CommentTargetResource,
+			TargetID:    c.ResourceID,
+			UserID:      c.UserID,
+			ParentID:    c.ParentID,
+			RootID:      c.RootID,
+			Content:     c.Content,
+			LikeCount:   c.LikeCount,
+			ReplyCount:  c.ReplyCount,
+			IsLiked:     c.IsLiked,
+			ReplyToUser: commentUserToAuthor(c.ReplyToUser),
+			CreatedAt:   c.CreatedAt,
+		}
+		if author := commentUserToAuthor(c.User); author != nil {
+			comment.Author = *author
+		}
+		return comment, c.Replies
+	})
+
+	return &UnifiedCommentsResponse{
+		TargetType: CommentTargetResource,
+		TargetID:   resourceID,
+		Comments:   comments,
+		Total:      resp.Total,
+		Page:       resp.Page,
+		PageSize:   resp.PageSize,
+		TotalPages: resp.TotalPages,
+		Sort:       CommentSortNewest, // 资源评论固定按最新发表排序
+	}
+}
+
+// commentUserToAuthor 资源评论用户信息转换为评论作者
+func commentUserToAuthor(u *CommentUser) *CommentAuthor {
+	if u == nil {
+		return nil
+	}
+	return &CommentAuthor{
+		ID:       u.ID,
+		Username: u.Username,
+		Nickname: u.Nickname,
+		Avatar:   u.Avatar,
+	}
+}
+
+// commentNode 评论树节点（文章评论与资源评论共用层级限制和平铺逻辑）
+type commentNode[T any] interface {
+	*T
+	replyList() *[]T
+	createdTime() time.Time
+	// inheritReplyTo 被挂到非直接父评论下时，未指定被回复用户则记为原父评论作者
+	inheritReplyTo(parent *T)
+}
+
+// capCommentDepth 限制评论树层级（items 位于第 depth 层，一级评论为第1层）
+// 超过 maxDepth 的回复按时间顺序挂到第 maxDepth-1 层的祖先评论下
+func capCommentDepth[T any, P commentNode[T]](items []T, depth, maxDepth int) {
+	for i := range items {
+		replies := P(&items[i]).replyList()
+		if depth+1 >= maxDepth {
+			*replies = flattenReplies[T, P](*replies)
+			continue
+		}
+		capCommentDepth[T, P](*replies, depth+1, maxDepth)
+	}
+}
+
+// flattenReplies 把回复树展开为按时间升序排列的单层列表（保留 parent_id 作为父评论引用）
+func flattenReplies[T any, P commentNode[T]](replies []T) []T {
+	flat := make([]T, 0, len(replies))
+
+	var walk func(parent *T, items []T)
+	walk = func(parent *T, items []T) {
+		for _, item := range items {
+			children := *P(&item).replyList()
+			if parent != nil {
+				P(&item).inheritReplyTo(parent)
+			}
+			*P(&item).replyList() = make([]T, 0)
+			flat = append(flat, item)
+			walk(&item, children)
+		}
+	}
+	walk(nil, replies)
+
+	sort.SliceStable(flat, func(i, j int) bool {
+		return P(&flat[i]).createdTime().Before(P(&flat[j]).createdTime())
+	})
+	return flat
+}
+
+func (c *CommentDetailResponse) replyList() *[]CommentDetailResponse { return &c.Replies }
+
+func (c *CommentDetailResponse) createdTime() time.Time { return c.CreatedAt }
+
+func (c *CommentDetailResponse) inheritReplyTo(parent *CommentDetailResponse) {
+	if c.ReplyToUser != nil {
+		return
+	}
+	author := parent.Author
+	c.ReplyToUser = &author
+	if c.ReplyToUserID == nil {
+		userID := parent.UserID
+		c.ReplyToUserID = &userID
+	}
+}
+
+func (c *ResourceCommentResponse) replyList() *[]ResourceCommentResponse { return &c.Replies }
+
+func (c *ResourceCommentResponse) createdTime() time.Time { return c.CreatedAt }
+
+func (c *ResourceCommentResponse) inheritReplyTo(parent *ResourceCommentResponse) {
+	if c.ReplyToUser == nil && parent.User != nil {
+		user := *parent.User
+		c.ReplyToUser = &user
+	}
+}
+
+// CapDepth 在读取时限制评论树层级（maxDepth<=0 表示不限制），已有的深层数据仍可正常展示
+func (r *CommentsResponse) CapDepth(maxDepth int) {
+	if maxDepth > 0 {
+		capCommentDepth[CommentDetailResponse](r.Comments, 1, maxDepth)
+	}
+}
+
+// Flatten 把每条一级评论的全部回复展开为按时间排序的单层列表
+func (r *CommentsResponse) Flatten() {
+	for i := range r.Comments {
+		r.Comments[i].Replies = flattenReplies[CommentDetailResponse](r.Comments[i].Replies)
+	}
+}
+
+// CapDepth 在读取时限制评论树层级（maxDepth<=0 表示不限制），已有的深层数据仍可正常展示
+func (r *ResourceCommentsResponse) CapDepth(maxDepth int) {
+	if maxDepth > 0 {
+		capCommentDepth[ResourceCommentResponse](r.Comments, 1, maxDepth)
+	}
+}
+
+// Flatten 把每条一级评论的全部回复展开为按时间排序的单层列表
+func (r *ResourceCommentsResponse) Flatten() {
+	for i := range r.Comments {
+		r.Comments[i].Replies = flattenReplies[ResourceCommentResponse](r.Comments[i].Replies)
+	}
+}
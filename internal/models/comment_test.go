@@ -0,0 +1,192 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// jsonShape 收集JSON中所有字段路径（数组元素合并为 []），用于比较响应结构
+func jsonShape(t *testing.T, v interface{}) []string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("编码失败: %v", err)
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("解码失败: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	var walk func(prefix string, node interface{})
+	walk = func(prefix string, node interface{}) {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			for k, child := range n {
+				seen[prefix+"."+k] = true
+				walk(prefix+"."+k, child)
+			}
+		case []interface{}:
+			for _, child := range n {
+				walk(prefix+"[]", child)
+			}
+		}
+	}
+	walk("", decoded)
+
+	paths := make([]string, 0, len(seen))
+	for p := range seen {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func TestUnifiedCommentsHaveIdenticalShape(t *testing.T) {
+	now := time.Now().UTC()
+	author := CommentAuthor{ID: 1, Username: "alice", Nickname: "Alice"}
+	replyTo := CommentAuthor{ID: 2, Username: "bob", Nickname: "Bob"}
+
+	articleReply := CommentDetailResponse{Author: replyTo, ReplyToUser: &author, Replies: []CommentDetailResponse{}}
+	articleReply.ID, articleReply.ArticleID, articleReply.UserID, articleReply.ParentID, articleReply.RootID = 11, 5, 2, 10, 10
+	articleRoot := CommentDetailResponse{Author: author, Replies: []CommentDetailResponse{articleReply}}
+	articleRoot.ID, articleRoot.ArticleID, articleRoot.UserID, articleRoot.Content, articleRoot.CreatedAt = 10, 5, 1, "hi", now
+	article := NewUnifiedArticleComments(5, &CommentsResponse{
+		Comments: []CommentDetailResponse{articleRoot}, Total: 1, Page: 1, PageSize: 20, TotalPages: 1, Sort: CommentSortNewest,
+	})
+
+	resource := NewUnifiedResourceComments(6, &ResourceCommentsResponse{
+		Comments: []ResourceCommentResponse{{
+			ID: 20, ResourceID: 6, UserID: 1, Content: "hi", CreatedAt: now,
+			User: &CommentUser{ID: 1, Username: "alice", Nickname: "Alice"},
+			Replies: []ResourceCommentResponse{{
+				ID: 21, ResourceID: 6, UserID: 2, ParentID: 20, RootID: 20, CreatedAt: now,
+				User:        &CommentUser{ID: 2, Username: "bob", Nickname: "Bob"},
+				ReplyToUser: &CommentUser{ID: 1, Username: "alice", Nickname: "Alice"},
+			}},
+		}},
+		Total: 1, Page: 1, PageSize: 20, TotalPages: 1,
+	})
+
+	if a, r := jsonShape(t, article), jsonShape(t, resource); !reflect.DeepEqual(a, r) {
+		t.Fatalf("文章与资源评论的响应结构应一致\n文章: %v\n资源: %v", a, r)
+	}
+	if article.TargetType != CommentTargetArticle || resource.TargetType != CommentTargetResource {
+		t.Fatalf("target_type 错误: %q %q", article.TargetType, resource.TargetType)
+	}
+	if got := resource.Comments[0].Replies[0]; got.Author.Username != "bob" || got.ReplyToUser.Username != "alice" || got.TargetID != 6 {
+		t.Fatalf("资源评论回复转换错误: %+v", got)
+	}
+	if resource.Comments[0].Replies[0].Replies == nil {
+		t.Fatal("没有回复时 replies 也应为空数组")
+	}
+}
+
+// articleComment 构造文章评论（作者用户名为 u<userID>）
+func articleComment(id, userID uint, at time.Time, replies ...CommentDetailResponse) CommentDetailResponse {
+	c := CommentDetailResponse{
+		Author:  CommentAuthor{ID: userID, Username: "u" + string(rune('0'+userID))},
+		Replies: append([]CommentDetailResponse{}, replies...),
+	}
+	c.ID, c.UserID, c.CreatedAt = id, userID, at
+	return c
+}
+
+// deepArticleComments 一级评论1 -> 2 -> 3 -> 4 -> 5 的单链，外加评论1的另一条直接回复6（时间最晚）
+func deepArticleComments() *CommentsResponse {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(m int) time.Time { return base.Add(time.Duration(m) * time.Minute) }
+	chain := articleComment(2, 2, at(2),
+		articleComment(3, 3, at(3),
+			articleComment(4, 4, at(5),
+				articleComment(5, 5, at(6)))))
+	return &CommentsResponse{Comments: []CommentDetailResponse{
+		articleComment(1, 1, at(1), chain, articleComment(6, 6, at(4))),
+	}}
+}
+
+func commentIDs(items []CommentDetailResponse) []uint {
+	ids := make([]uint, 0, len(items))
+	for _, c := range items {
+		ids = append(ids, c.ID)
+	}
+	return ids
+}
+
+func TestCommentsCapDepth(t *testing.T) {
+	resp := deepArticleComments()
+	resp.CapDepth(3)
+
+	root := resp.Comments[0]
+	if got := commentIDs(root.Replies); !reflect.DeepEqual(got, []uint{2, 6}) {
+		t.Fatalf("第2层回复应保持不变，实际 %v", got)
+	}
+	third := root.Replies[0].Replies
+	if got := commentIDs(third); !reflect.DeepEqual(got, []uint{3, 4, 5}) {
+		t.Fatalf("超过3层的回复应按时间挂到第2层评论下，实际 %v", got)
+	}
+	for _, c := range third {
+		if len(c.Replies) != 0 {
+			t.Fatalf("第3层评论不应再有回复: %d", c.ID)
+		}
+	}
+	if third[0].ReplyToUser != nil {
+		t.Fatalf("仍挂在原父评论下的回复不需要补充被回复用户，实际 %+v", third[0].ReplyToUser)
+	}
+	if third[1].ReplyToUser == nil || third[1].ReplyToUser.ID != 3 || *third[1].ReplyToUserID != 3 {
+		t.Fatalf("重新挂载的回复应标注原被回复用户 u3，实际 %+v", third[1].ReplyToUser)
+	}
+	if third[2].ReplyToUser.ID != 4 {
+		t.Fatalf("重新挂载的回复应标注原被回复用户 u4，实际 %+v", third[2].ReplyToUser)
+	}
+
+	unlimited := deepArticleComments()
+	unlimited.CapDepth(0)
+	if !reflect.DeepEqual(unlimited, deepArticleComments()) {
+		t.Fatal("max_depth=0 时不应修改评论树")
+	}
+}
+
+func TestCommentsFlatten(t *testing.T) {
+	resp := deepArticleComments()
+	resp.Flatten()
+
+	replies := resp.Comments[0].Replies
+	if got := commentIDs(replies); !reflect.DeepEqual(got, []uint{2, 3, 6, 4, 5}) {
+		t.Fatalf("平铺后应按时间排序，实际 %v", got)
+	}
+	for _, c := range replies {
+		if len(c.Replies) != 0 {
+			t.Fatalf("平铺后的回复不应再嵌套: %d", c.ID)
+		}
+	}
+	if replies[1].ReplyToUser == nil || replies[1].ReplyToUser.ID != 2 {
+		t.Fatalf("平铺后应保留被回复用户，实际 %+v", replies[1].ReplyToUser)
+	}
+}
+
+func TestResourceCommentsCapDepth(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	user := func(id uint) *CommentUser { return &CommentUser{ID: id, Username: "u" + string(rune('0'+id))} }
+	resp := &ResourceCommentsResponse{Comments: []ResourceCommentResponse{{
+		ID: 1, User: user(1), CreatedAt: base,
+		Replies: []ResourceCommentResponse{{
+			ID: 2, ParentID: 1, User: user(2), CreatedAt: base.Add(time.Minute),
+			Replies: []ResourceCommentResponse{{
+				ID: 3, ParentID: 2, User: user(3), CreatedAt: base.Add(2 * time.Minute),
+			}},
+		}},
+	}}}
+	resp.CapDepth(2)
+
+	replies := resp.Comments[0].Replies
+	if len(replies) != 2 || replies[0].ID != 2 || replies[1].ID != 3 {
+		t.Fatalf("max_depth=2 时所有回复应挂到一级评论下，实际 %+v", replies)
+	}
+	if replies[1].ParentID != 2 || replies[1].ReplyToUser == nil || replies[1].ReplyToUser.ID != 2 {
+		t.Fatalf("重新挂载的回复应保留父评论引用并标注原被回复用户，实际 %+v", replies[1])
+	}
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// 可转移所有权的内容类型
+const (
+	TransferContentArticle  = "article"
+	TransferContentResource = "resource"
+)
+
+// 所有权转移状态
+const (
+	TransferStatusPending   = 0 // 等待接收者确认
+	TransferStatusAccepted  = 1 // 已接受（内容已归属接收者）
+	TransferStatusRejected  = 2 // 接收者已拒绝
+	TransferStatusCancelled = 3 // 发起者已取消，或内容在确认前已变更
+)
+
+// ContentTransfer 内容所有权转移记录（同时作为审计日志保留）
+type ContentTransfer struct {
+	ID           uint       `json:"id" db:"id"`
+	ContentType  string     `json:"content_type" db:"content_type"`
+	ContentID    uint       `json:"content_id" db:"content_id"`
+	ContentTitle string     `json:"content_title,omitempty"` // 列表接口返回，便于接收者确认
+	FromUserID   uint       `json:"from_user_id" db:"from_user_id"`
+	ToUserID     uint       `json:"to_user_id" db:"to_user_id"`
+	Status       int        `json:"status" db:"status"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	RespondedAt  *time.Time `json:"responded_at,omitempty" db:"responded_at"`
+}
+
+// CreateContentTransferRequest 发起所有权转移请求
+type CreateContentTransferRequest struct {
+	ContentType string `json:"content_type" binding:"required,oneof=article resource"`
+	ContentID   uint   `json:"content_id" binding:"required"`
+	ToUserID    uint   `json:"to_user_id" binding:"required"`
+}
@@ -9,25 +9,27 @@ import (
 
 // Claims JWT声明结构体
 type Claims struct {
-	UserID   uint   `json:"user_id"`
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	Province string `json:"province,omitempty"`
-	City     string `json:"city,omitempty"`
+	UserID       uint   `json:"user_id"`
+	Username     string `json:"username"`
+	Email        string `json:"email"`
+	Province     string `json:"province,omitempty"`
+	City         string `json:"city,omitempty"`
+	TokenVersion int    `json:"tv"` // 签发时的用户token版本（低于当前版本即失效）
 	jwt.RegisteredClaims
 }
 
-// CreateClaims 创建JWT声明
-func CreateClaims(userID uint, username, email, province, city, issuer string, expireHours int) *Claims {
+// CreateClaims 创建JWT声明（jti 为随机唯一ID，用于退出登录时吊销单个token）
+func CreateClaims(userID uint, username, email, province, city, issuer, jti string, tokenVersion int, expire time.Duration) *Claims {
 	now := time.Now().UTC()
-	expirationTime := now.Add(time.Duration(expireHours) * time.Hour)
+	expirationTime := now.Add(expire)
 
 	return &Claims{
-		UserID:   userID,
-		Username: username,
-		Email:    email,
-		Province: province,
-		City:     city,
+		UserID:       userID,
+		Username:     username,
+		Email:        email,
+		Province:     province,
+		City:         city,
+		TokenVersion: tokenVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Subject:   strconv.FormatUint(uint64(userID), 10), // 用户ID作为Subject
 			Issuer:    issuer,                                 // 使用配置的Issuer
@@ -35,7 +37,7 @@ func CreateClaims(userID uint, username, email, province, city, issuer string, e
 			ExpiresAt: jwt.NewNumericDate(expirationTime),     // 过期时间
 			NotBefore: jwt.NewNumericDate(now),                // 生效时间
 			IssuedAt:  jwt.NewNumericDate(now),                // 签发时间
-			ID:        strconv.FormatUint(uint64(userID), 10), // JWT ID
+			ID:        jti,                                    // JWT ID
 		},
 	}
 }
@@ -0,0 +1,137 @@
+package models
+
+import "time"
+
+// 通知事件类型
+const (
+	NotificationEventComment    = "comment"     // 新评论
+	NotificationEventReply      = "reply"       // 评论被回复
+	NotificationEventMention    = "mention"     // 被@提及
+	NotificationEventFollow     = "follow"      // 被关注
+	NotificationEventLike       = "like"        // 被点赞
+	NotificationEventNewContent = "new_content" // 新发布的文章、资源、代码片段
+)
+
+// NotificationEventTypes 支持设置偏好的事件类型（按展示顺序）
+var NotificationEventTypes = []string{
+	NotificationEventComment,
+	NotificationEventReply,
+	NotificationEventMention,
+	NotificationEventFollow,
+	NotificationEventLike,
+	NotificationEventNewContent,
+}
+
+// IsValidNotificationEvent 判断事件类型是否支持设置偏好
+func IsValidNotificationEvent(eventType string) bool {
+	for _, t := range NotificationEventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// NotificationPreference 单个事件类型的通知偏好
+type NotificationPreference struct {
+	EventType string     `json:"event_type"`
+	InApp     bool       `json:"in_app"`               // 站内（WebSocket）通知
+	Email     bool       `json:"email"`                // 邮件通知（预留）
+	UpdatedAt *time.Time `json:"updated_at,omitempty"` // 从未修改过时为空
+}
+
+// NotificationPreferences 用户通知偏好（未设置过的事件类型默认全部开启）
+type NotificationPreferences struct {
+	UserID      uint                     `json:"user_id"`
+	Preferences []NotificationPreference `json:"preferences"`
+}
+
+// DefaultNotificationPreferences 创建全部开启的默认偏好
+func DefaultNotificationPreferences(userID uint) *NotificationPreferences {
+	prefs := make([]NotificationPreference, 0, len(NotificationEventTypes))
+	for _, t := range NotificationEventTypes {
+		prefs = append(prefs, NotificationPreference{EventType: t, InApp: true, Email: true})
+	}
+	return &NotificationPreferences{UserID: userID, Preferences: prefs}
+}
+
+// Get 获取指定事件类型的偏好（未知类型视为全部开启）
+func (p *NotificationPreferences) Get(eventType string) NotificationPreference {
+	for _, pref := range p.Preferences {
+		if pref.EventType == eventType {
+			return pref
+		}
+	}
+	return NotificationPreference{EventType: eventType, InApp: true, Email: true}
+}
+
+// NotificationPreferenceUpdate 单个事件类型的偏好修改（未传的字段保持不变）
+type NotificationPreferenceUpdate struct {
+	EventType string `json:"event_type" binding:"required"`
+	InApp     *bool  `json:"in_app"`
+	Email     *bool  `json:"email"`
+}
+
+// UpdateNotificationPreferencesRequest 修改通知偏好请求
+type UpdateNotificationPreferencesRequest struct {
+	Preferences []NotificationPreferenceUpdate `json:"preferences" binding:"required,min=1,dive"`
+}
+
+// DoNotDisturbStatus 通知免打扰状态（免打扰期间不推送实时通知，通知仍写入收件箱）
+type DoNotDisturbStatus struct {
+	Active    bool       `json:"active"`
+	MuteUntil *time.Time `json:"mute_until"` // 未开启或已过期时为空
+}
+
+// SetDoNotDisturbRequest 设置通知免打扰请求（mute_until 为空时关闭免打扰）
+type SetDoNotDisturbRequest struct {
+	MuteUntil *time.Time `json:"mute_until"`
+}
+
+// NotificationEventMessage 私信通知（不支持在偏好中关闭）
+const NotificationEventMessage = "message"
+
+// 通知关联的内容类型
+const (
+	NotificationTargetArticle  = "article"  // 文章
+	NotificationTargetResource = "resource" // 资源
+	NotificationTargetMessage  = "message"  // 私信会话
+)
+
+// Notification 通知记录（离线用户上线后可查询）
+type Notification struct {
+	ID         uint              `json:"id"`
+	UserID     uint              `json:"user_id"`    // 接收者
+	ActorID    uint              `json:"actor_id"`   // 触发者
+	EventType  string            `json:"event_type"` // comment/reply/message 等
+	TargetType string            `json:"target_type"`
+	TargetID   uint              `json:"target_id"`            // 文章/资源ID，私信为会话ID
+	CommentID  uint              `json:"comment_id,omitempty"` // 评论类通知对应的评论ID
+	Content    string            `json:"content"`              // 内容摘要
+	IsRead     bool              `json:"is_read"`
+	Actor      NotificationActor `json:"actor"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+// NotificationActor 通知触发者信息
+type NotificationActor struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+	Nickname string `json:"nickname"`
+	Avatar   string `json:"avatar"`
+}
+
+// NotificationListResponse 通知列表响应
+type NotificationListResponse struct {
+	Notifications []Notification `json:"notifications"`
+	Total         int            `json:"total"`
+	UnreadCount   int            `json:"unread_count"`
+	Page          int            `json:"page"`
+	PageSize      int            `json:"page_size"`
+	TotalPages    int            `json:"total_pages"`
+}
+
+// MarkNotificationsReadRequest 标记通知已读请求（ids 为空时标记全部）
+type MarkNotificationsReadRequest struct {
+	IDs []uint `json:"ids"`
+}
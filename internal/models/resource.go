@@ -4,35 +4,74 @@ import "time"
 
 // Resource 资源主表
 type Resource struct {
+	ID             uint      `json:"id" db:"id"`
+	UserID         uint      `json:"user_id" db:"user_id"`
+	Title          string    `json:"title" db:"title"`
+	Description    string    `json:"description" db:"description"`
+	Document       string    `json:"document" db:"document"`
+	CategoryID     *uint     `json:"category_id" db:"category_id"`
+	FileName       string    `json:"file_name" db:"file_name"`
+	FileSize       int64     `json:"file_size" db:"file_size"`
+	FileType       string    `json:"file_type" db:"file_type"`
+	FileExtension  string    `json:"file_extension" db:"file_extension"`
+	FileHash       string    `json:"file_hash" db:"file_hash"`
+	StoragePath    string    `json:"storage_path" db:"storage_path"`
+	TotalChunks    int       `json:"total_chunks" db:"total_chunks"`       // 分片总数（新方案：用于前端下载合并）
+	CurrentVersion int       `json:"current_version" db:"current_version"` // 当前版本号（文件字段为该版本的文件）
+	DownloadCount  int       `json:"download_count" db:"download_count"`
+	ViewCount      int       `json:"view_count" db:"view_count"`
+	LikeCount      int       `json:"like_count" db:"like_count"`
+	Status         int       `json:"status" db:"status"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// 资源状态
+const (
+	ResourceStatusDeleted   = 0 // 已删除
+	ResourceStatusNormal    = 1 // 正常
+	ResourceStatusReviewing = 2 // 审核中
+)
+
+// ResourceVersion 资源文件版本（重新上传时新增，旧版本仍可下载）
+type ResourceVersion struct {
 	ID            uint      `json:"id" db:"id"`
+	ResourceID    uint      `json:"resource_id" db:"resource_id"`
+	VersionNo     int       `json:"version_no" db:"version_no"` // 版本序号（从1递增，下载时通过 ?version= 指定）
+	Version       string    `json:"version" db:"version"`       // 版本名称（如 v1.2.3）
 	UserID        uint      `json:"user_id" db:"user_id"`
-	Title         string    `json:"title" db:"title"`
-	Description   string    `json:"description" db:"description"`
-	Document      string    `json:"document" db:"document"`
-	CategoryID    *uint     `json:"category_id" db:"category_id"`
 	FileName      string    `json:"file_name" db:"file_name"`
 	FileSize      int64     `json:"file_size" db:"file_size"`
 	FileType      string    `json:"file_type" db:"file_type"`
 	FileExtension string    `json:"file_extension" db:"file_extension"`
 	FileHash      string    `json:"file_hash" db:"file_hash"`
-	StoragePath   string    `json:"storage_path" db:"storage_path"`
-	TotalChunks   int       `json:"total_chunks" db:"total_chunks"` // 分片总数（新方案：用于前端下载合并）
-	DownloadCount int       `json:"download_count" db:"download_count"`
-	ViewCount     int       `json:"view_count" db:"view_count"`
-	LikeCount     int       `json:"like_count" db:"like_count"`
-	Status        int       `json:"status" db:"status"`
+	StoragePath   string    `json:"-" db:"storage_path"` // 下载地址通过下载接口获取
+	TotalChunks   int       `json:"total_chunks" db:"total_chunks"`
+	Notes         string    `json:"notes" db:"notes"`
 	CreatedAt     time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// AddResourceVersionRequest 上传资源新版本请求
+type AddResourceVersionRequest struct {
+	Version     string `json:"version" binding:"max=50"` // 为空时使用 v+版本序号
+	FileName    string `json:"file_name" binding:"required"`
+	FileSize    int64  `json:"file_size" binding:"required"`
+	FileType    string `json:"file_type"`
+	FileHash    string `json:"file_hash" binding:"required"`
+	StoragePath string `json:"storage_path" binding:"required"`
+	TotalChunks int    `json:"total_chunks"`
+	Notes       string `json:"notes" binding:"max=1000"`
 }
 
 // ResourceImage 资源预览图
 type ResourceImage struct {
-	ID         uint      `json:"id" db:"id"`
-	ResourceID uint      `json:"resource_id" db:"resource_id"`
-	ImageURL   string    `json:"image_url" db:"image_url"`
-	ImageOrder int       `json:"image_order" db:"image_order"`
-	IsCover    bool      `json:"is_cover" db:"is_cover"`
-	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	ID           uint      `json:"id" db:"id"`
+	ResourceID   uint      `json:"resource_id" db:"resource_id"`
+	ImageURL     string    `json:"image_url" db:"image_url"`
+	ThumbnailURL string    `json:"thumbnail_url,omitempty" db:"thumbnail_url"` // 缩略图URL（原图较小时为空）
+	ImageOrder   int       `json:"image_order" db:"image_order"`
+	IsCover      bool      `json:"is_cover" db:"is_cover"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
 }
 
 // ResourceCategory 资源分类
@@ -53,6 +92,15 @@ type ResourceTag struct {
 	CreatedAt  time.Time `json:"created_at" db:"created_at"`
 }
 
+// 分片上传状态
+const (
+	UploadStatusUploading = 0 // 上传中
+	UploadStatusCompleted = 1 // 已完成
+	UploadStatusCancelled = 2 // 已取消
+	UploadStatusRejected  = 3 // 未通过安全扫描
+	UploadStatusMerging   = 4 // 合并校验中（不再接受分片写入）
+)
+
 // UploadChunk 断点续传记录
 type UploadChunk struct {
 	ID             uint      `json:"id" db:"id"`
@@ -114,6 +162,7 @@ type ResourceDetailResponse struct {
 	Category *ResourceCategory `json:"category"`
 	Tags     []string          `json:"tags"`
 	IsLiked  bool              `json:"is_liked"`
+	Versions []ResourceVersion `json:"versions"` // 版本列表（新版本在前）
 }
 
 // ResourceListItem 资源列表项
@@ -131,6 +180,7 @@ type ResourceListItem struct {
 	DownloadCount int               `json:"download_count"`
 	ViewCount     int               `json:"view_count"`
 	LikeCount     int               `json:"like_count"`
+	CommentCount  int               `json:"comment_count"`
 	CreatedAt     time.Time         `json:"created_at"`
 }
 
@@ -22,6 +22,9 @@ type ApiStatistics struct {
 	ErrorCount   int       `json:"error_count" db:"error_count"`
 	TotalCount   int       `json:"total_count" db:"total_count"`
 	AvgLatencyMs float64   `json:"avg_latency_ms" db:"avg_latency_ms"`
+	P50LatencyMs int64     `json:"p50_latency_ms" db:"p50_latency_ms"` // 分位数按延迟分桶近似
+	P95LatencyMs int64     `json:"p95_latency_ms" db:"p95_latency_ms"`
+	P99LatencyMs int64     `json:"p99_latency_ms" db:"p99_latency_ms"`
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
 }
@@ -63,3 +66,54 @@ type EndpointRanking struct {
 	SuccessRate  float64 `json:"success_rate"`
 	AvgLatencyMs float64 `json:"avg_latency_ms"`
 }
+
+// EndpointAlert 接口告警（错误率或P99延迟超过阈值）
+type EndpointAlert struct {
+	Endpoint              string   `json:"endpoint"`
+	Method                string   `json:"method"`
+	TotalCount            int64    `json:"total_count"`              // 窗口内请求数
+	ErrorCount            int64    `json:"error_count"`              // 窗口内5xx响应数
+	ErrorRate             float64  `json:"error_rate"`               // 当前错误率（百分比）
+	ErrorRateThreshold    float64  `json:"error_rate_threshold"`     // 错误率阈值（百分比）
+	P99LatencyMs          int64    `json:"p99_latency_ms"`           // 当前P99延迟（毫秒，按直方图分桶近似）
+	P99LatencyThresholdMs int64    `json:"p99_latency_threshold_ms"` // P99延迟阈值（毫秒）
+	Reasons               []string `json:"reasons"`                  // 告警原因：error_rate / p99_latency
+}
+
+// EndpointLatency 接口延迟分位数（来自内存中最近的延迟样本）
+type EndpointLatency struct {
+	Endpoint    string  `json:"endpoint"` // METHOD 路由模板
+	Count       int     `json:"count"`    // 样本数
+	P50Ms       float64 `json:"p50_ms"`
+	P95Ms       float64 `json:"p95_ms"`
+	P99Ms       float64 `json:"p99_ms"`
+	MaxMs       float64 `json:"max_ms"`
+	Approximate bool    `json:"approximate"` // 样本窗口已滚动，分位数只反映最近的样本
+}
+
+// EndpointLatencyResponse 接口延迟分位数列表
+type EndpointLatencyResponse struct {
+	MaxSamples  int               `json:"max_samples"` // 每个接口保留的最大样本数
+	GeneratedAt time.Time         `json:"generated_at"`
+	Endpoints   []EndpointLatency `json:"endpoints"`
+}
+
+// SlowQueryAlert 慢查询告警（同一形状的查询在窗口内频繁变慢）
+type SlowQueryAlert struct {
+	Fingerprint   string    `json:"fingerprint"`     // 查询指纹（字面量替换为 ?）
+	SampleQuery   string    `json:"sample_query"`    // 首次出现时的原始查询（截断）
+	Count         int64     `json:"count"`           // 窗口内慢查询次数
+	MinCount      int       `json:"min_count"`       // 告警阈值（次数）
+	WindowMinutes int       `json:"window_minutes"`  // 统计窗口（分钟）
+	MaxDurationMs int64     `json:"max_duration_ms"` // 记录到的最长耗时（毫秒）
+	LastSeenAt    time.Time `json:"last_seen_at"`
+}
+
+// EndpointAlertsResponse 接口告警列表
+type EndpointAlertsResponse struct {
+	WindowMinutes   int              `json:"window_minutes"` // 统计窗口（分钟）
+	MinRequests     int              `json:"min_requests"`   // 参与告警的最少请求数
+	GeneratedAt     time.Time        `json:"generated_at"`
+	Alerts          []EndpointAlert  `json:"alerts"`
+	SlowQueryAlerts []SlowQueryAlert `json:"slow_query_alerts"` // 频繁出现的慢查询
+}
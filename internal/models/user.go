@@ -14,10 +14,23 @@ type User struct {
 	LastLoginTime    *time.Time `json:"last_login_time" db:"last_login_time"`
 	LastLoginIP      *string    `json:"last_login_ip" db:"last_login_ip"`
 	FailedLoginCount int        `json:"failed_login_count" db:"failed_login_count"`
+	LockedUntil      *time.Time `json:"-" db:"locked_until"`  // 登录锁定截止时间
+	TokenVersion     int        `json:"-" db:"token_version"` // 登录token版本（递增后旧token失效）
 	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt        time.Time  `json:"updated_at" db:"updated_at"`
 }
 
+// 账户状态
+const (
+	AccountStatusDisabled = 0 // 禁用
+	AccountStatusNormal   = 1 // 正常
+	AccountStatusLocked   = 2 // 锁定
+	AccountStatusDeleted  = 3 // 已注销（个人信息已匿名化）
+)
+
+// DeletedUserNickname 已注销用户的显示昵称
+const DeletedUserNickname = "已注销用户"
+
 // UserProfile 用户基本信息（用于登录注册响应）
 type UserProfile struct {
 	ID            uint   `json:"id"`
@@ -53,11 +66,30 @@ type LoginResponse struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
 	Data    struct {
-		Token string      `json:"token"`
-		User  UserProfile `json:"user"`
+		Token        string      `json:"token"`
+		RefreshToken string      `json:"refresh_token"` // 刷新token（用于换取新的访问token）
+		ExpiresIn    int         `json:"expires_in"`    // 访问token有效期（秒）
+		User         UserProfile `json:"user"`
 	} `json:"data"`
 }
 
+// RefreshTokenRequest 刷新访问token请求结构体
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// RefreshTokenResponse 刷新访问token响应（刷新token每次都会轮换）
+type RefreshTokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"` // 访问token有效期（秒）
+}
+
+// LogoutRequest 退出登录请求结构体（刷新token可选，提供时一并作废）
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
 // CommonResponse 通用响应结构体
 type CommonResponse struct {
 	Code      int         `json:"code"`
@@ -74,22 +106,99 @@ type UserExtraProfile struct {
 	Bio       string    `json:"bio" db:"bio"`
 	AvatarURL string    `json:"avatar_url" db:"avatar_url"`
 	Phone     string    `json:"phone" db:"phone"`
-	Gender    *int      `json:"gender" db:"gender"`     // 0-未知，1-男，2-女
-	Birthday  *string   `json:"birthday" db:"birthday"` // 日期格式
-	Province  string    `json:"province" db:"province"` // 省份
-	City      string    `json:"city" db:"city"`         // 城市
-	Website   string    `json:"website" db:"website"`   // 个人网站
-	Github    string    `json:"github" db:"github"`     // GitHub用户名
+	Gender    *int      `json:"gender,omitempty" db:"gender"`     // 0-未知，1-男，2-女（未设置时不返回）
+	Birthday  *string   `json:"birthday,omitempty" db:"birthday"` // 日期格式 YYYY-MM-DD（未设置时不返回）
+	Province  string    `json:"province" db:"province"`           // 省份
+	City      string    `json:"city" db:"city"`                   // 城市
+	Website   string    `json:"website" db:"website"`             // 个人网站
+	Github    string    `json:"github" db:"github"`               // GitHub用户名
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// FollowCounts 用户关注数和粉丝数
+type FollowCounts struct {
+	FollowersCount int `json:"followers_count"` // 粉丝数
+	FollowingCount int `json:"following_count"` // 关注数
+}
+
+// PublicUserProfile 用户公开主页（不含邮箱、手机号等私密字段）
+// 已注销用户只返回ID、占位昵称和 is_deleted 标记
+type PublicUserProfile struct {
+	ID             uint       `json:"id"`
+	Username       string     `json:"username"`
+	Nickname       string     `json:"nickname"`
+	Avatar         string     `json:"avatar"`
+	Bio            string     `json:"bio"`
+	Website        string     `json:"website"`
+	Github         string     `json:"github"`
+	IsDeleted      bool       `json:"is_deleted"`
+	JoinedAt       *time.Time `json:"joined_at,omitempty"` // 注册时间（已注销用户不返回）
+	ArticleCount   int        `json:"article_count"`       // 已发布文章数
+	ResourceCount  int        `json:"resource_count"`      // 正常状态资源数
+	LikesReceived  int        `json:"likes_received"`      // 文章和资源累计获赞数
+	FollowersCount int        `json:"followers_count"`     // 粉丝数
+	FollowingCount int        `json:"following_count"`     // 关注数
+}
+
+// FollowUser 关注/粉丝列表中的用户
+type FollowUser struct {
+	ID         uint      `json:"id"`
+	Username   string    `json:"username"`
+	Nickname   string    `json:"nickname"`
+	Avatar     string    `json:"avatar"`
+	FollowedAt time.Time `json:"followed_at"` // 关注时间
+}
+
+// FollowListResponse 关注/粉丝列表响应
+type FollowListResponse struct {
+	Users      []FollowUser `json:"users"`
+	Total      int          `json:"total"`
+	Page       int          `json:"page"`
+	PageSize   int          `json:"page_size"`
+	TotalPages int          `json:"total_pages"`
+}
+
+// Liker 点赞用户列表中的用户
+type Liker struct {
+	ID       uint      `json:"id"`
+	Username string    `json:"username"`
+	Nickname string    `json:"nickname"`
+	Avatar   string    `json:"avatar"`
+	LikedAt  time.Time `json:"liked_at"` // 点赞时间
+}
+
+// LikersResponse 点赞用户列表响应
+type LikersResponse struct {
+	Users      []Liker `json:"users"`
+	Total      int     `json:"total"`
+	Page       int     `json:"page"`
+	PageSize   int     `json:"page_size"`
+	TotalPages int     `json:"total_pages"`
+}
+
 // ChangePasswordRequest 修改密码请求结构体
 type ChangePasswordRequest struct {
 	CurrentPassword string `json:"currentPassword" binding:"required"`
 	NewPassword     string `json:"newPassword" binding:"required"`
 }
 
+// DeleteAccountRequest 注销账号请求结构体（需验证当前密码）
+type DeleteAccountRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// ForgotPasswordRequest 找回密码请求结构体
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+// ResetPasswordRequest 重置密码请求结构体
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"newPassword" binding:"required"`
+}
+
 // Validate 验证用户数据
 func (u *User) Validate() error {
 	if u.Username == "" {
@@ -116,6 +225,16 @@ func (u *User) SanitizeForJSON() *UserProfile {
 	}
 }
 
+// BatchDeleteRequest 批量删除自己内容的请求（评论/代码片段/资源）
+type BatchDeleteRequest struct {
+	IDs []uint `json:"ids" binding:"required,min=1"`
+}
+
+// BatchDeleteResponse 批量删除结果（整批在一个事务中完成，要么全部成功要么全部失败）
+type BatchDeleteResponse struct {
+	DeletedIDs []uint `json:"deleted_ids"`
+}
+
 // ValidationError 验证错误
 type ValidationError struct {
 	Field   string
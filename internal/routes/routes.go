@@ -17,6 +17,12 @@ func SetupRoutes(cfg *config.Config, ctn *bootstrap.Container) *gin.Engine {
 
 	r := gin.New() // 使用 gin.New() 而不是 gin.Default()，手动控制中间件
 
+	// 只采信可信代理转发的客户端IP，防止伪造 X-Forwarded-For 绕过限流和豁免名单
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		utils.GetLogger().Error("可信代理配置无效，不信任任何代理", "error", err.Error())
+		_ = r.SetTrustedProxies(nil)
+	}
+
 	// 设置上传文件的内存限制（超过此大小将写入临时文件）
 	// 设置为32MB，支持大文件分片上传
 	r.MaxMultipartMemory = 32 << 20 // 32 MB
@@ -27,12 +33,13 @@ func SetupRoutes(cfg *config.Config, ctn *bootstrap.Container) *gin.Engine {
 	r.Use(middleware.SecurityHeadersMiddleware(cfg))                                                 // 3. 安全响应头（从配置读取）
 	r.Use(middleware.CORSMiddleware(cfg))                                                            // 4. CORS跨域
 	r.Use(middleware.RequestSizeLimitMiddleware(int64(cfg.Security.MaxRequestSizeMB) * 1024 * 1024)) // 5. 请求体大小限制（从配置读取）
-	r.Use(middleware.FastCompressionMiddleware())                                                    // 6. 响应压缩（速度优先）
+	r.Use(middleware.CompressionMiddlewareWithConfig(cfg))                                           // 6. 响应压缩（从配置读取，跳过已压缩格式）
 	r.Use(middleware.LoggerMiddleware(cfg))                                                          // 7. 详细日志（包含请求/响应体，从配置读取）
 	r.Use(middleware.PerformanceMiddleware(ctn.DB))                                                  // 8. 性能追踪（内存、CPU、数据库连接池）
 	r.Use(middleware.MetricsMiddleware())                                                            // 9. 性能监控中间件
 	r.Use(middleware.RateLimitMiddleware())                                                          // 10. 添加全局限流
-	r.Use(middleware.StatisticsMiddleware(ctn.StatsRepo, ctn.CumulativeRepo))                        // 11. 统计中间件（自动收集数据）
+	r.Use(middleware.StatisticsMiddleware(ctn.StatsAggregator, ctn.CumulativeRepo))                  // 11. 统计中间件（自动收集数据）
+	r.Use(middleware.RequestConcurrencyMiddleware(cfg))                                              // 12. 单请求并发预算（限制请求内并行查询的goroutine数）
 
 	// 初始化处理器
 	// 头像大小限制：从7桶配置读取
@@ -40,30 +47,42 @@ func SetupRoutes(cfg *config.Config, ctn *bootstrap.Container) *gin.Engine {
 	if uploadMaxBytes <= 0 {
 		uploadMaxBytes = 5 * 1024 // 默认5KB
 	}
-	authHandler := handlers.NewAuthHandler(ctn.Auth, cfg)
+	authHandler := handlers.NewAuthHandler(ctn.Auth, ctn.AuditRepo, cfg)
 	userHandler := handlers.NewUserHandler(ctn.UserSvc, ctn.HistoryRepo, cfg)
-	healthHandler := handlers.NewHealthHandler(ctn.DB)
+	healthHandler := handlers.NewHealthHandler(ctn.DB, ctn.MultiBucket, ctn.CodeExecutor, cfg)
 	uploadHandler := handlers.NewUploadHandler(ctn.MultiBucket, ctn.UserSvc, uploadMaxBytes, cfg.BucketUserAvatars.MaxHistory, ctn.HistoryRepo, cfg)
 	statsHandler := handlers.NewStatisticsHandler(ctn.StatsRepo, cfg)
 	historyHandler := handlers.NewHistoryHandler(ctn.HistoryRepo, cfg)
 	cumulativeHandler := handlers.NewCumulativeStatsHandler(ctn.CumulativeRepo)
 	chatHandler := handlers.NewChatHandler(ctn.ChatRepo, ctn.UserRepo, cfg)
-	articleHandler := handlers.NewArticleHandler(ctn.ArticleRepo, ctn.UserRepo, ctn.CacheSvc, cfg)
+	articleHandler := handlers.NewArticleHandler(ctn.ArticleRepo, ctn.UserRepo, ctn.CacheSvc, ctn.AuditRepo, ctn.ViewDedup, cfg)
 	privateMsgHandler := handlers.NewPrivateMessageHandler(ctn.PrivateMsgRepo, ctn.UserRepo, cfg)
-	resourceHandler := handlers.NewResourceHandler(ctn.ResourceRepo, ctn.ResourceCommentRepo, ctn.ResourceImageSvc, ctn.UserRepo, cfg)
+	resourceHandler := handlers.NewResourceHandler(ctn.ResourceRepo, ctn.ResourceCommentRepo, ctn.ResourceImageSvc, ctn.MultiBucket, ctn.UploadMgr, ctn.UserRepo, ctn.ViewDedup, cfg)
 	chunkUploadHandler := handlers.NewChunkUploadHandler(ctn.UploadMgr)
 	codeHandler := handlers.NewCodeHandler(ctn.CodeRepo, ctn.CodeExecutor, cfg)
+	apiTokenHandler := handlers.NewAPITokenHandler(ctn.APITokenRepo, cfg)
+	prometheusHandler := handlers.NewPrometheusHandler(ctn.DB, ctn.CacheSvc, cfg)
+	commentHandler := handlers.NewCommentHandler(ctn.ArticleRepo, ctn.ResourceCommentRepo, cfg)
+	notifyPrefHandler := handlers.NewNotificationPreferenceHandler(ctn.NotifyPrefRepo)
+	notificationHandler := handlers.NewNotificationHandler(ctn.NotificationRepo, cfg)
+	userBlockHandler := handlers.NewUserBlockHandler(ctn.UserRepo)
+	followHandler := handlers.NewFollowHandler(ctn.UserRepo, cfg)
+	transferHandler := handlers.NewContentTransferHandler(ctn.ContentTransferRepo, ctn.CacheSvc)
+	auditHandler := handlers.NewAuditHandler(ctn.AuditRepo)
 
 	// Initialize WebSocket connection hub
-	handlers.InitConnectionHub(ctn.ChatRepo, ctn.UserRepo, ctn.Config)
+	handlers.InitConnectionHub(ctn.ChatRepo, ctn.UserRepo, ctn.NotifyPrefRepo, ctn.NotificationRepo, ctn.Config)
+	handlers.InitCollabHub(ctn.CodeRepo, ctn.UserRepo, ctn.Config)
 
 	// 健康检查路由
 	r.GET("/health", healthHandler.Check)
 	r.GET("/ready", healthHandler.Ready)
 	r.GET("/live", healthHandler.Live)
+	r.GET("/api/version", healthHandler.Version) // 构建与运行时信息（便于排查问题）
 
 	// 性能监控路由
-	r.GET("/metrics", middleware.MetricsHandler)
+	r.GET("/metrics", prometheusHandler.Metrics)         // Prometheus文本格式
+	r.GET("/metrics/summary", middleware.MetricsHandler) // JSON格式汇总
 	r.GET("/metrics/compression", func(c *gin.Context) {
 		stats := middleware.GetCompressionStats()
 		c.JSON(200, gin.H{
@@ -133,113 +152,192 @@ func SetupRoutes(cfg *config.Config, ctn *bootstrap.Container) *gin.Engine {
 	api := r.Group("/api")
 	{
 		// 用户认证相关路由（使用专门的限流）
-		api.POST("/auth/register", middleware.RegisterRateLimitMiddleware(), authHandler.Register)
+		// 注册与找回密码、重发验证邮件等匿名敏感操作共享按IP/邮箱的合并限流
+		api.POST("/auth/register", middleware.RegisterRateLimitMiddleware(), middleware.SensitiveActionRateLimitMiddleware("register"), authHandler.Register)
 		api.POST("/auth/login", middleware.LoginRateLimitMiddleware(), authHandler.Login)
+		api.POST("/auth/refresh", middleware.LoginRateLimitMiddleware(), authHandler.RefreshToken)
+		api.POST("/auth/forgot-password", middleware.SensitiveActionRateLimitMiddleware("forgot_password"), authHandler.ForgotPassword)
+		api.POST("/auth/reset-password", middleware.SensitiveActionRateLimitMiddleware("reset_password"), authHandler.ResetPassword)
 
 		// 需要认证的路由
 		auth := api.Group("/")
-		auth.Use(middleware.AuthMiddleware(cfg))
+		auth.Use(middleware.AuthMiddleware(cfg, ctn.APITokenRepo, ctn.TokenBlacklist))
+		auth.Use(middleware.UserRateLimitMiddleware())
+
+		// 按路由组检查API令牌权限范围（登录会话不受限制）
+		account := auth.Group("/", middleware.RequireRouteGroupScope(cfg, "account"))
+		chat := auth.Group("/", middleware.RequireRouteGroupScope(cfg, "chat"))
+		articles := auth.Group("/", middleware.RequireRouteGroupScope(cfg, "articles"))
+		messages := auth.Group("/", middleware.RequireRouteGroupScope(cfg, "messages"))
+		resources := auth.Group("/", middleware.RequireRouteGroupScope(cfg, "resources"))
+		code := auth.Group("/", middleware.RequireRouteGroupScope(cfg, "code"))
 		{
 			// 前端期望的统一接口
-			auth.GET("/auth/me", userHandler.GetMe)                        // 获取当前用户信息
-			auth.PUT("/auth/me", userHandler.UpdateMe)                     // 更新当前用户信息
-			auth.POST("/auth/change-password", authHandler.ChangePassword) // 修改密码
+			account.GET("/auth/me", userHandler.GetMe)                        // 获取当前用户信息
+			account.PUT("/auth/me", userHandler.UpdateMe)                     // 更新当前用户信息
+			account.POST("/auth/change-password", authHandler.ChangePassword) // 修改密码
+			account.DELETE("/auth/me", authHandler.DeleteAccount)             // 注销账号（匿名化个人信息）
 
 			// 文件上传接口（添加专用限流）
-			auth.POST("/upload", middleware.UploadRateLimitMiddleware(), uploadHandler.UploadAvatar)
-			auth.POST("/resources/images/upload", uploadHandler.UploadResourceImage)    // 上传资源预览图
-			auth.POST("/resources/documents/upload", uploadHandler.UploadDocumentImage) // 上传文档图片
+			account.POST("/upload", middleware.BodyLimitMiddleware(cfg, middleware.BodyLimitAvatar), middleware.UploadRateLimitMiddleware(), uploadHandler.UploadAvatar)
+			resources.POST("/resources/images/upload", middleware.BodyLimitMiddleware(cfg, middleware.BodyLimitImage), uploadHandler.UploadResourceImage)    // 上传资源预览图
+			resources.POST("/resources/documents/upload", middleware.BodyLimitMiddleware(cfg, middleware.BodyLimitImage), uploadHandler.UploadDocumentImage) // 上传文档图片
 
-			// 退出登录（JWT无状态，主要用于客户端清除token）
-			auth.POST("/auth/logout", authHandler.Logout)
+			// 退出登录（当前token加入黑名单并删除刷新token）
+			account.POST("/auth/logout", authHandler.Logout)
 
 			// 用户信息接口
-			auth.GET("/user/:id", userHandler.GetUserByID)
-			auth.GET("/user/avatar/history", uploadHandler.ListAvatarHistory)
+			account.GET("/user/:id", userHandler.GetUserByID)
+			account.GET("/users/:id/profile", userHandler.GetPublicProfile) // 用户公开主页及统计
+			account.GET("/user/avatar/history", uploadHandler.ListAvatarHistory)
+
+			// 个人访问令牌（仅登录会话可管理）
+			account.GET("/users/me/tokens", apiTokenHandler.ListTokens)         // 获取令牌列表
+			account.POST("/users/me/tokens", apiTokenHandler.CreateToken)       // 创建令牌（明文仅返回一次）
+			account.DELETE("/users/me/tokens/:id", apiTokenHandler.RevokeToken) // 吊销令牌
+
+			// 通知偏好（未设置的事件类型默认全部开启）
+			account.GET("/users/me/notification-preferences", notifyPrefHandler.GetPreferences)    // 获取通知偏好
+			account.PUT("/users/me/notification-preferences", notifyPrefHandler.UpdatePreferences) // 修改通知偏好
+			account.GET("/users/me/notification-dnd", notifyPrefHandler.GetDoNotDisturb)           // 获取通知免打扰状态
+			account.PUT("/users/me/notification-dnd", notifyPrefHandler.SetDoNotDisturb)           // 设置通知免打扰
+
+			// 屏蔽用户（屏蔽后不再看到对方的文章、评论和聊天消息）
+			account.GET("/users/me/blocks", userBlockHandler.ListBlocks)         // 获取屏蔽列表
+			account.PUT("/users/me/blocks/:id", userBlockHandler.BlockUser)      // 屏蔽用户
+			account.DELETE("/users/me/blocks/:id", userBlockHandler.UnblockUser) // 取消屏蔽
+
+			// 关注用户（文章列表 ?feed=following 只看关注用户的文章）
+			account.GET("/users/me/following", followHandler.ListFollowing)   // 获取关注列表
+			account.GET("/users/me/followers", followHandler.ListFollowers)   // 获取粉丝列表
+			account.PUT("/users/me/following/:id", followHandler.Follow)      // 关注用户
+			account.DELETE("/users/me/following/:id", followHandler.Unfollow) // 取消关注
+
+			// 文章/资源所有权转移（作者发起，接收者确认后生效）
+			account.POST("/content-transfers", transferHandler.CreateTransfer)            // 发起转移
+			account.GET("/content-transfers", transferHandler.ListTransfers)              // 转移列表 ?direction=incoming|outgoing&pending=true
+			account.POST("/content-transfers/:id/accept", transferHandler.AcceptTransfer) // 接受转移
+			account.POST("/content-transfers/:id/reject", transferHandler.RejectTransfer) // 拒绝转移
+			account.POST("/content-transfers/:id/cancel", transferHandler.CancelTransfer) // 取消转移
+
+			// 站内通知（离线期间的评论、回复和私信）
+			account.GET("/users/me/notifications", notificationHandler.ListNotifications)           // 获取通知列表
+			account.GET("/users/me/notifications/unread-count", notificationHandler.GetUnreadCount) // 获取未读通知数
+			account.POST("/users/me/notifications/read", notificationHandler.MarkRead)              // 标记通知已读
 
 			// 历史记录接口（用户查看自己的历史）
-			auth.GET("/history/login", historyHandler.GetLoginHistory)
-			auth.GET("/history/operations", historyHandler.GetOperationHistory)
-			auth.GET("/history/profile-changes", historyHandler.GetProfileChangeHistory)
+			account.GET("/history/login", historyHandler.GetLoginHistory)
+			account.GET("/history/operations", historyHandler.GetOperationHistory)
+			account.GET("/history/profile-changes", historyHandler.GetProfileChangeHistory)
 
 			// 聊天室接口（所有登录用户可访问）
-			auth.GET("/chat/ws", chatHandler.HandleWebSocket)            // WebSocket 连接（主要通信方式）
-			auth.POST("/chat/send", chatHandler.SendMessage)             // 发送消息（HTTP 降级支持）
-			auth.GET("/chat/messages", chatHandler.GetMessages)          // 获取历史消息
-			auth.GET("/chat/messages/new", chatHandler.GetNewMessages)   // 获取新消息（轮询，降级支持）
-			auth.DELETE("/chat/messages/:id", chatHandler.DeleteMessage) // 删除消息
-			auth.GET("/chat/online-count", chatHandler.GetOnlineCountWS) // 获取在线用户数（优先使用 WebSocket）
-			auth.GET("/chat/online-users", chatHandler.GetOnlineUsersWS) // 获取在线用户列表
+			chat.GET("/chat/ws", middleware.RequireScope(middleware.RouteGroupScope(cfg, "chat", "write")), chatHandler.HandleWebSocket) // WebSocket 连接（可发送消息，需要写权限）
+			chat.POST("/chat/send", chatHandler.SendMessage)                                                                             // 发送消息（HTTP 降级支持）
+			chat.GET("/chat/messages", chatHandler.GetMessages)                                                                          // 获取历史消息
+			chat.GET("/chat/messages/new", chatHandler.GetNewMessages)                                                                   // 获取新消息（轮询，降级支持）
+			chat.DELETE("/chat/messages/:id", chatHandler.DeleteMessage)                                                                 // 删除消息
+			chat.GET("/chat/online-count", chatHandler.GetOnlineCountWS)                                                                 // 获取在线用户数（优先使用 WebSocket）
+			chat.GET("/chat/online-users", chatHandler.GetOnlineUsersWS)                                                                 // 获取在线用户列表（?fresh=true 从数据库读取最新资料）
 
 			// 文章相关接口
-			auth.POST("/articles", articleHandler.CreateArticle)              // 创建文章
-			auth.GET("/articles/:id", articleHandler.GetArticleDetail)        // 获取文章详情
-			auth.PUT("/articles/:id", articleHandler.UpdateArticle)           // 更新文章
-			auth.DELETE("/articles/:id", articleHandler.DeleteArticle)        // 删除文章
-			auth.POST("/articles/:id/like", articleHandler.ToggleArticleLike) // 点赞/取消点赞
-			auth.POST("/articles/:id/comments", articleHandler.CreateComment) // 发表评论
-			auth.GET("/articles/:id/comments", articleHandler.GetComments)    // 获取评论
-			auth.POST("/comments/:id/like", articleHandler.ToggleCommentLike) // 评论点赞
-			auth.DELETE("/comments/:id", articleHandler.DeleteComment)        // 删除评论
-			auth.POST("/articles/report", articleHandler.CreateReport)        // 举报文章/评论
-			auth.GET("/articles", articleHandler.GetArticleList)              // 获取文章列表
-			auth.GET("/articles/categories", articleHandler.GetCategories)    // 获取分类列表
-			auth.GET("/articles/tags", articleHandler.GetTags)                // 获取标签列表
+			articles.POST("/articles", articleHandler.CreateArticle)                    // 创建文章
+			articles.GET("/articles/:id", articleHandler.GetArticleDetail)              // 获取文章详情
+			articles.PUT("/articles/:id", articleHandler.UpdateArticle)                 // 更新文章
+			articles.DELETE("/articles/:id", articleHandler.DeleteArticle)              // 删除文章
+			articles.GET("/articles/:id/revisions", articleHandler.ListRevisions)       // 获取修订列表
+			articles.GET("/articles/:id/revisions/diff", articleHandler.DiffRevisions)  // 比较两个版本 ?from=&to=（current 表示当前版本）
+			articles.POST("/articles/:id/like", articleHandler.ToggleArticleLike)       // 点赞/取消点赞
+			articles.GET("/articles/:id/likes", articleHandler.GetArticleLikers)        // 获取点赞用户列表
+			articles.GET("/articles/:id/text", articleHandler.GetArticlePlainText)      // 获取纯文本正文及阅读时长
+			articles.POST("/articles/:id/comments", articleHandler.CreateComment)       // 发表评论
+			articles.GET("/articles/:id/comments", articleHandler.GetComments)          // 获取评论
+			articles.POST("/comments/:id/like", articleHandler.ToggleCommentLike)       // 评论点赞
+			articles.PUT("/comments/:id", articleHandler.UpdateComment)                 // 编辑评论
+			articles.DELETE("/comments/:id", articleHandler.DeleteComment)              // 删除评论
+			articles.POST("/comments/batch-delete", articleHandler.BatchDeleteComments) // 批量删除自己的评论
+			articles.GET("/comments/:id/ancestry", articleHandler.GetCommentAncestry)   // 获取评论回复链（面包屑）
+			articles.GET("/comments/:id/replies", articleHandler.GetCommentReplies)     // 分页获取直接回复 ?after=&limit=
+			articles.POST("/articles/report", articleHandler.CreateReport)              // 举报文章/评论
+			articles.GET("/articles", articleHandler.GetArticleList)                    // 获取文章列表
+			articles.GET("/articles/categories", articleHandler.GetCategories)          // 获取分类列表
+			articles.GET("/articles/tags", articleHandler.GetTags)                      // 获取标签列表
+
+			// 统一评论接口（文章/资源评论返回相同结构，令牌权限按 type 检查）
+			auth.GET("/comments", commentHandler.GetComments) // 获取评论树 ?type=article|resource&id=
 
 			// 私信相关接口
-			auth.GET("/conversations", privateMsgHandler.GetConversations)                      // 获取会话列表
-			auth.GET("/conversations/:id/messages", privateMsgHandler.GetMessages)              // 获取会话消息
-			auth.POST("/messages/send", privateMsgHandler.SendMessage)                          // 发送消息
-			auth.GET("/conversations/unread-count", privateMsgHandler.GetUnreadCount)           // 获取未读数
-			auth.POST("/conversations/start/:userId", privateMsgHandler.StartConversation)      // 开始会话
-			auth.POST("/conversations/:id/mark-read", privateMsgHandler.MarkConversationAsRead) // 标记会话为已读
+			messages.GET("/conversations", privateMsgHandler.GetConversations)                      // 获取会话列表
+			messages.GET("/conversations/:id/messages", privateMsgHandler.GetMessages)              // 获取会话消息
+			messages.POST("/messages/send", privateMsgHandler.SendMessage)                          // 发送消息
+			messages.GET("/conversations/unread-count", privateMsgHandler.GetUnreadCount)           // 获取未读数
+			messages.POST("/conversations/start/:userId", privateMsgHandler.StartConversation)      // 开始会话
+			messages.POST("/conversations/:id/mark-read", privateMsgHandler.MarkConversationAsRead) // 标记会话为已读
 
 			// 资源相关接口
-			auth.POST("/resources", resourceHandler.CreateResource)                             // 创建资源
-			auth.GET("/resources", resourceHandler.GetResourceList)                             // 获取资源列表
-			auth.GET("/resources/:id", resourceHandler.GetResourceDetail)                       // 获取资源详情
-			auth.DELETE("/resources/:id", resourceHandler.DeleteResource)                       // 删除资源
-			auth.POST("/resources/:id/like", resourceHandler.ToggleResourceLike)                // 点赞资源
-			auth.GET("/resources/:id/download", resourceHandler.DownloadResource)               // 下载资源（返回直接链接）
-			auth.GET("/resources/:id/proxy-download", resourceHandler.ProxyDownloadResource)    // 代理下载资源（支持Range和大文件）
-			auth.GET("/resource-categories", resourceHandler.GetCategories)                     // 获取资源分类
-			auth.POST("/resources/:id/comments", resourceHandler.CreateResourceComment)         // 发表资源评论
-			auth.GET("/resources/:id/comments", resourceHandler.GetResourceComments)            // 获取资源评论
-			auth.POST("/resource-comments/:id/like", resourceHandler.ToggleResourceCommentLike) // 资源评论点赞
+			resources.POST("/resources", middleware.BodyLimitMiddleware(cfg, middleware.BodyLimitJSON), resourceHandler.CreateResource)                  // 创建资源
+			resources.GET("/resources", resourceHandler.GetResourceList)                                                                                 // 获取资源列表
+			resources.GET("/resources/:id", resourceHandler.GetResourceDetail)                                                                           // 获取资源详情
+			resources.POST("/resources/:id/versions", middleware.BodyLimitMiddleware(cfg, middleware.BodyLimitJSON), resourceHandler.AddResourceVersion) // 上传资源新版本（作者）
+			resources.DELETE("/resources/:id", resourceHandler.DeleteResource)                                                                           // 删除资源
+			resources.POST("/resources/batch-delete", resourceHandler.BatchDeleteResources)                                                              // 批量删除自己的资源
+			resources.POST("/resources/:id/like", resourceHandler.ToggleResourceLike)                                                                    // 点赞资源
+			resources.GET("/resources/:id/likes", resourceHandler.GetResourceLikers)                                                                     // 获取点赞用户列表
+			resources.GET("/resources/:id/download", resourceHandler.DownloadResource)                                                                   // 下载资源（返回直接链接）
+			resources.GET("/resources/:id/download-url", resourceHandler.GetResourceDownloadURL)                                                         // 获取限时预签名下载链接
+			resources.GET("/resources/:id/proxy-download", resourceHandler.ProxyDownloadResource)                                                        // 代理下载资源（支持Range和大文件）
+			resources.GET("/resource-categories", resourceHandler.GetCategories)                                                                         // 获取资源分类
+			resources.POST("/resources/:id/comments", resourceHandler.CreateResourceComment)                                                             // 发表资源评论
+			resources.GET("/resources/:id/comments", resourceHandler.GetResourceComments)                                                                // 获取资源评论
+			resources.POST("/resource-comments/:id/like", resourceHandler.ToggleResourceCommentLike)                                                     // 资源评论点赞
 
 			// 分片上传接口
-			auth.POST("/upload/init", chunkUploadHandler.InitUpload)                  // 初始化上传
-			auth.POST("/upload/chunk", chunkUploadHandler.UploadChunk)                // 上传分片
-			auth.POST("/upload/merge", chunkUploadHandler.MergeChunks)                // 合并分片
-			auth.GET("/upload/status/:upload_id", chunkUploadHandler.GetUploadStatus) // 查询进度
-			auth.POST("/upload/cancel/:upload_id", chunkUploadHandler.CancelUpload)   // 取消上传
+			resources.POST("/upload/init", middleware.BodyLimitMiddleware(cfg, middleware.BodyLimitJSON), chunkUploadHandler.InitUpload)    // 初始化上传
+			resources.POST("/upload/chunk", middleware.BodyLimitMiddleware(cfg, middleware.BodyLimitChunk), chunkUploadHandler.UploadChunk) // 上传分片
+			resources.POST("/upload/merge", middleware.BodyLimitMiddleware(cfg, middleware.BodyLimitJSON), chunkUploadHandler.MergeChunks)  // 合并分片
+			resources.GET("/upload/status/:upload_id", chunkUploadHandler.GetUploadStatus)                                                  // 查询进度
+			resources.POST("/upload/cancel/:upload_id", chunkUploadHandler.CancelUpload)                                                    // 取消上传
 
 			// 在线代码执行相关接口
-			auth.POST("/code/execute", codeHandler.ExecuteCode)                  // 执行代码
-			auth.POST("/code/snippets", codeHandler.CreateSnippet)               // 保存代码片段
-			auth.GET("/code/snippets", codeHandler.GetSnippets)                  // 获取代码片段列表
-			auth.GET("/code/public", codeHandler.GetPublicSnippets)              // 获取公开代码片段列表
-			auth.GET("/code/snippets/:id", codeHandler.GetSnippetByID)           // 获取代码片段详情
-			auth.PUT("/code/snippets/:id", codeHandler.UpdateSnippet)            // 更新代码片段
-			auth.DELETE("/code/snippets/:id", codeHandler.DeleteSnippet)         // 删除代码片段
-			auth.GET("/code/executions", codeHandler.GetExecutions)              // 获取执行记录
-			auth.POST("/code/snippets/:id/share", codeHandler.GenerateShareLink) // 生成分享链接
-			auth.GET("/code/languages", codeHandler.GetLanguages)                // 获取支持的语言列表
+			code.POST("/code/execute", codeHandler.ExecuteCode)                                                                                          // 执行代码
+			code.POST("/code/snippets", codeHandler.CreateSnippet)                                                                                       // 保存代码片段
+			code.GET("/code/snippets", codeHandler.GetSnippets)                                                                                          // 获取代码片段列表
+			code.GET("/code/public", codeHandler.GetPublicSnippets)                                                                                      // 获取公开代码片段列表
+			code.GET("/code/snippets/:id", codeHandler.GetSnippetByID)                                                                                   // 获取代码片段详情
+			code.PUT("/code/snippets/:id", codeHandler.UpdateSnippet)                                                                                    // 更新代码片段
+			code.DELETE("/code/snippets/:id", codeHandler.DeleteSnippet)                                                                                 // 删除代码片段
+			code.POST("/code/snippets/batch-delete", codeHandler.BatchDeleteSnippets)                                                                    // 批量删除自己的代码片段
+			code.GET("/code/executions", codeHandler.GetExecutions)                                                                                      // 获取执行记录
+			code.GET("/code/snippets/:id/download", codeHandler.DownloadSnippet)                                                                         // 下载代码片段
+			code.POST("/code/snippets/:id/share", codeHandler.GenerateShareLink)                                                                         // 生成分享链接
+			code.DELETE("/code/snippets/:id/share", codeHandler.RevokeShareLink)                                                                         // 吊销分享链接
+			code.POST("/code/snippets/:id/fork", codeHandler.ForkSnippet)                                                                                // 复制公开代码片段
+			code.POST("/code/snippets/:id/collab", codeHandler.CreateCollaboration)                                                                      // 创建协作编辑会话（仅创建者）
+			code.GET("/code/collab/:token/ws", middleware.RequireScope(middleware.RouteGroupScope(cfg, "code", "write")), codeHandler.JoinCollaboration) // 加入协作编辑（WebSocket，可修改代码，需要写权限）
+			code.GET("/code/languages", codeHandler.GetLanguages)                                                                                        // 获取支持的语言列表
 		}
 
+		// 用户当前头像（无需认证，头像不公开时跳转到预签名URL）
+		api.GET("/avatars/:username", uploadHandler.GetAvatar)
+
 		// 公开访问的代码分享（无需认证）
-		api.GET("/code/share/:token", codeHandler.GetSharedSnippet) // 通过分享令牌访问代码
+		api.GET("/code/share/:token", codeHandler.GetSharedSnippet)               // 通过分享令牌访问代码
+		api.GET("/code/share/:token/download", codeHandler.DownloadSharedSnippet) // 通过分享令牌下载代码
 
 		// 管理员专用路由
 		admin := api.Group("/")
-		admin.Use(middleware.AuthMiddleware(cfg))
+		admin.Use(middleware.AuthMiddleware(cfg, ctn.APITokenRepo, ctn.TokenBlacklist))
+		admin.Use(middleware.UserRateLimitMiddleware())
 		admin.Use(middleware.AdminMiddleware(cfg))
+		admin.Use(middleware.RequireRouteGroupScope(cfg, "admin"))
 		{
 			// 统计相关接口（仅管理员可访问）
 			admin.GET("/statistics/overview", statsHandler.GetOverview)
 			admin.GET("/statistics/users", statsHandler.GetUserStatistics)
 			admin.GET("/statistics/apis", statsHandler.GetApiStatistics)
 			admin.GET("/statistics/ranking", statsHandler.GetEndpointRanking)
+			admin.GET("/statistics/export", statsHandler.ExportStatistics)
+			admin.GET("/admin/alerts", statsHandler.GetAlerts)   // 接口错误率/P99延迟告警
+			admin.GET("/admin/latency", statsHandler.GetLatency) // 各接口延迟分位数（P50/P95/P99）
 
 			// 地区分布统计
 			admin.GET("/location/distribution", historyHandler.GetLocationDistribution)
@@ -248,6 +346,24 @@ func SetupRoutes(cfg *config.Config, ctn *bootstrap.Container) *gin.Engine {
 			admin.GET("/cumulative-stats", cumulativeHandler.GetCumulativeStats)
 			admin.GET("/daily-metrics", cumulativeHandler.GetDailyMetrics)
 			admin.GET("/realtime-metrics", cumulativeHandler.GetRealtimeMetrics)
+
+			// 举报处理
+			admin.GET("/reports", articleHandler.ListReports)                // 获取举报列表
+			admin.POST("/reports/:id/resolve", articleHandler.ResolveReport) // 处理举报（dismiss / hide_content / delete_content）
+
+			// 标签管理
+			admin.PUT("/admin/tags/:id", articleHandler.RenameTag)       // 重命名标签
+			admin.POST("/admin/tags/:id/merge", articleHandler.MergeTag) // 合并到 target_tag_id 并删除该标签
+
+			// 文章批量管理
+			admin.POST("/admin/articles/status", articleHandler.BulkUpdateArticleStatus)      // 批量修改文章状态（0草稿 1发布 2删除）
+			admin.POST("/admin/users/:id/articles/delete", articleHandler.DeleteUserArticles) // 删除用户全部文章
+
+			// 会话管理
+			admin.POST("/users/:id/revoke-sessions", authHandler.RevokeUserSessions) // 注销用户全部会话
+
+			// 管理员操作审计日志
+			admin.GET("/admin/audit-log", auditHandler.ListAuditLog) // ?actor_id=&action=&target_type=&target_id=&page=&page_size=
 		}
 	}
 
@@ -267,6 +383,7 @@ func SetupRoutes(cfg *config.Config, ctn *bootstrap.Container) *gin.Engine {
 			"9.Metrics",
 			"10.RateLimit",
 			"11.Statistics",
+			"12.RequestConcurrency",
 		})
 	logger.Debug("中间件详情",
 		"panicRecovery", "全局panic恢复，防止服务崩溃",
@@ -275,6 +392,7 @@ func SetupRoutes(cfg *config.Config, ctn *bootstrap.Container) *gin.Engine {
 		"logger", "捕获请求/响应体，记录详细头部信息",
 		"performance", "追踪内存使用、Goroutine数量、数据库连接池状态",
 		"metrics", "性能指标统计",
-		"rateLimit", "全局和特定路由限流（LRU优化）")
+		"rateLimit", "全局和特定路由限流（LRU优化）",
+		"requestConcurrency", "单请求并行查询共享goroutine预算")
 	return r
 }
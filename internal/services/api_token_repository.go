@@ -0,0 +1,215 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/models"
+	"gin/internal/utils"
+)
+
+// APITokenRepository 个人访问令牌数据访问层
+type APITokenRepository struct {
+	db     *Database
+	logger utils.Logger
+	config *config.Config
+}
+
+// NewAPITokenRepository 创建个人访问令牌数据访问层
+func NewAPITokenRepository(db *Database, cfg *config.Config) *APITokenRepository {
+	return &APITokenRepository{
+		db:     db,
+		logger: utils.GetLogger(),
+		config: cfg,
+	}
+}
+
+// hashAPIToken 计算令牌哈希（数据库只保存哈希，不保存明文）
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateAPIToken 创建令牌，返回明文令牌（仅此一次）和令牌记录
+func (r *APITokenRepository) CreateAPIToken(ctx context.Context, userID uint, name string, scopes []string, expiresAt *time.Time) (string, *models.APIToken, error) {
+	start := time.Now().UTC()
+
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
+	defer cancel()
+
+	// 检查有效令牌数量上限
+	var activeCount int
+	countQuery := `SELECT COUNT(*) FROM user_api_tokens
+				   WHERE user_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)`
+	if err := r.db.QueryRowWithCache(ctx, countQuery, userID, start).Scan(&activeCount); err != nil {
+		r.logger.Error("查询API令牌数量失败", "userID", userID, "error", err.Error())
+		return "", nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+	}
+	if maxTokens := r.config.APIToken.MaxTokensPerUser; maxTokens > 0 && activeCount >= maxTokens {
+		return "", nil, utils.NewAppError(utils.ErrValidationFailed, fmt.Sprintf("最多只能持有%d个有效的API令牌", maxTokens), 400)
+	}
+
+	// 生成随机令牌
+	randomBytes := make([]byte, 32)
+	if _, err := rand.Read(randomBytes); err != nil {
+		r.logger.Error("生成API令牌失败", "userID", userID, "error", err.Error())
+		return "", nil, utils.ErrInternalServerError
+	}
+	plaintext := r.config.APIToken.TokenPrefix + hex.EncodeToString(randomBytes)
+	displayPrefix := plaintext[:len(r.config.APIToken.TokenPrefix)+6]
+
+	token := &models.APIToken{
+		UserID:      userID,
+		Name:        name,
+		TokenPrefix: displayPrefix,
+		Scopes:      scopes,
+		ExpiresAt:   expiresAt,
+		CreatedAt:   start,
+	}
+
+	query := `INSERT INTO user_api_tokens (user_id, name, token_hash, token_prefix, scopes, expires_at, created_at)
+			  VALUES (?, ?, ?, ?, ?, ?, ?)`
+	result, err := r.db.ExecWithCache(ctx, query,
+		userID, name, hashAPIToken(plaintext), displayPrefix, strings.Join(scopes, ","), expiresAt, start)
+	if err != nil {
+		r.logger.Error("创建API令牌失败", "userID", userID, "name", name, "error", err.Error())
+		return "", nil, fmt.Errorf("%w: %w", utils.ErrDatabaseInsert, err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		r.logger.Error("获取API令牌ID失败", "userID", userID, "error", err.Error())
+		return "", nil, fmt.Errorf("%w: %w", utils.ErrDatabaseInsert, err)
+	}
+	token.ID = uint(id)
+
+	r.logger.Info("创建API令牌成功",
+		"userID", userID,
+		"tokenID", token.ID,
+		"scopes", scopes,
+		"duration", time.Since(start))
+	return plaintext, token, nil
+}
+
+// ListAPITokens 获取用户的令牌列表（包含已吊销的，便于审计）
+func (r *APITokenRepository) ListAPITokens(ctx context.Context, userID uint) ([]models.APIToken, error) {
+	query := `SELECT id, user_id, name, token_prefix, scopes, last_used_at, expires_at, revoked_at, created_at
+			  FROM user_api_tokens WHERE user_id = ? ORDER BY created_at DESC`
+
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetQueryTimeout())
+	defer cancel()
+
+	rows, err := r.db.QueryWithCache(ctx, query, userID)
+	if err != nil {
+		r.logger.Error("查询API令牌列表失败", "userID", userID, "error", err.Error())
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+	}
+	defer rows.Close()
+
+	tokens := make([]models.APIToken, 0)
+	for rows.Next() {
+		var token models.APIToken
+		var scopes string
+		if err := rows.Scan(&token.ID, &token.UserID, &token.Name, &token.TokenPrefix, &scopes,
+			&token.LastUsedAt, &token.ExpiresAt, &token.RevokedAt, &token.CreatedAt); err != nil {
+			r.logger.Warn("解析API令牌失败", "userID", userID, "error", err.Error())
+			continue
+		}
+		token.Scopes = splitScopes(scopes)
+		tokens = append(tokens, token)
+	}
+
+	return tokens, nil
+}
+
+// RevokeAPIToken 吊销令牌（只能吊销自己的令牌）
+func (r *APITokenRepository) RevokeAPIToken(ctx context.Context, userID, tokenID uint) error {
+	query := `UPDATE user_api_tokens SET revoked_at = ? WHERE id = ? AND user_id = ? AND revoked_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
+	defer cancel()
+
+	result, err := r.db.ExecWithCache(ctx, query, time.Now().UTC(), tokenID, userID)
+	if err != nil {
+		r.logger.Error("吊销API令牌失败", "userID", userID, "tokenID", tokenID, "error", err.Error())
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
+	}
+	if affected == 0 {
+		return utils.ErrResourceNotFound
+	}
+
+	r.logger.Info("吊销API令牌成功", "userID", userID, "tokenID", tokenID)
+	return nil
+}
+
+// AuthenticateAPIToken 校验明文令牌，返回令牌所属用户及权限范围
+func (r *APITokenRepository) AuthenticateAPIToken(ctx context.Context, plaintext string) (*models.APITokenIdentity, error) {
+	query := `SELECT t.id, t.user_id, t.scopes, t.expires_at, t.revoked_at, ua.username, ua.email, ua.account_status
+			  FROM user_api_tokens t
+			  INNER JOIN user_auth ua ON t.user_id = ua.id
+			  WHERE t.token_hash = ?`
+
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetQueryTimeout())
+	defer cancel()
+
+	identity := &models.APITokenIdentity{}
+	var scopes string
+	var expiresAt, revokedAt *time.Time
+	var accountStatus int
+	err := r.db.QueryRowWithCache(ctx, query, hashAPIToken(plaintext)).Scan(
+		&identity.TokenID, &identity.UserID, &scopes, &expiresAt, &revokedAt,
+		&identity.Username, &identity.Email, &accountStatus)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, utils.ErrInvalidToken
+		}
+		r.logger.Error("查询API令牌失败", "error", err.Error())
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+	}
+
+	if revokedAt != nil {
+		return nil, utils.ErrInvalidToken
+	}
+	if expiresAt != nil && expiresAt.Before(time.Now().UTC()) {
+		return nil, utils.ErrTokenExpired
+	}
+	if accountStatus != 1 {
+		return nil, utils.ErrAccountDisabled
+	}
+
+	identity.Scopes = splitScopes(scopes)
+	return identity, nil
+}
+
+// TouchAPIToken 更新令牌最后使用时间
+func (r *APITokenRepository) TouchAPIToken(ctx context.Context, tokenID uint) error {
+	query := `UPDATE user_api_tokens SET last_used_at = ? WHERE id = ?`
+	if _, err := r.db.ExecWithCache(ctx, query, time.Now().UTC(), tokenID); err != nil {
+		r.logger.Warn("更新API令牌使用时间失败", "tokenID", tokenID, "error", err.Error())
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
+	}
+	return nil
+}
+
+// splitScopes 解析逗号分隔的权限范围
+func splitScopes(scopes string) []string {
+	result := make([]string, 0)
+	for _, s := range strings.Split(scopes, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			result = append(result, s)
+		}
+	}
+	return result
+}
@@ -0,0 +1,414 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/models"
+	"gin/internal/testutil"
+	"gin/internal/utils"
+)
+
+// newCommentEditRepo 评论 10 属于用户 7，发布于 createdAgo 之前，内容为 "old"
+func newCommentEditRepo(t *testing.T, createdAgo time.Duration) (*testutil.FakeDB, *ArticleRepository, *config.Config) {
+	t.Helper()
+	fake, db := newFakeDatabase(t)
+	cfg := config.Default()
+	cfg.Comments.EditWindowMin = 15
+	cfg.Comments.EditHistoryLimit = 5
+	cfg.ValidationExtended.CommentContentMax = 10
+
+	fake.On(`SELECT user_id, content, created_at FROM article_comments WHERE id = \? AND status = 1 FOR UPDATE`, func(args []driver.Value) testutil.Response {
+		resp := testutil.Response{Columns: []string{"user_id", "content", "created_at"}}
+		if args[0] == int64(10) {
+			resp.Rows = [][]driver.Value{{int64(7), "old", time.Now().UTC().Add(-createdAgo)}}
+		}
+		return resp
+	})
+	fake.OnExec(`INSERT INTO comment_edit_history`, 1, 1)
+	fake.OnExec(`DELETE FROM comment_edit_history`, 0, 0)
+	fake.OnExec(`UPDATE article_comments SET content = \?, is_edited = 1`, 0, 1)
+	return fake, NewArticleRepository(db, cfg), cfg
+}
+
+func TestUpdateCommentByOwner(t *testing.T) {
+	fake, repo, _ := newCommentEditRepo(t, 5*time.Minute)
+
+	if err := repo.UpdateComment(context.Background(), 10, 7, "  new  "); err != nil {
+		t.Fatalf("作者在时间窗口内应能编辑，实际 %v", err)
+	}
+
+	history := fake.Calls(`INSERT INTO comment_edit_history`)
+	if len(history) != 1 || history[0].Args[1] != "old" {
+		t.Fatalf("应保存编辑前的内容，实际 %+v", history)
+	}
+	prune := fake.Calls(`DELETE FROM comment_edit_history`)
+	if len(prune) != 1 || prune[0].Args[2] != int64(5) {
+		t.Fatalf("应按保留条数清理旧的编辑历史，实际 %+v", prune)
+	}
+	update := fake.Calls(`UPDATE article_comments SET content`)
+	if len(update) != 1 || update[0].Args[0] != "new" || update[0].Args[2] != int64(10) {
+		t.Fatalf("应更新内容并标记 is_edited，实际 %+v", update)
+	}
+	if len(fake.Calls(`^COMMIT$`)) != 1 {
+		t.Fatal("编辑应在事务中提交")
+	}
+}
+
+func TestUpdateCommentRejections(t *testing.T) {
+	cases := []struct {
+		name       string
+		createdAgo time.Duration
+		commentID  uint
+		userID     uint
+		content    string
+		wantCode   int
+		wantMsg    string
+	}{
+		{"非作者", time.Minute, 10, 8, "new", 403, ""},
+		{"超过编辑时间窗口", 20 * time.Minute, 10, 7, "new", 403, "超过15分钟"},
+		{"内容超长", time.Minute, 10, 7, strings.Repeat("字", 11), 400, "不能超过10个字符"},
+		{"内容为空", time.Minute, 10, 7, "   ", 400, "不能为空"},
+		{"评论不存在", time.Minute, 99, 7, "new", 404, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fake, repo, _ := newCommentEditRepo(t, tc.createdAgo)
+			err := repo.UpdateComment(context.Background(), tc.commentID, tc.userID, tc.content)
+			if err == nil || utils.GetHTTPStatusCode(err) != tc.wantCode {
+				t.Fatalf("应返回 %d，实际 %v", tc.wantCode, err)
+			}
+			var appErr *utils.AppError
+			if tc.wantMsg != "" && (!errors.As(err, &appErr) || !strings.Contains(appErr.Message, tc.wantMsg)) {
+				t.Fatalf("错误信息应包含 %q，实际 %v", tc.wantMsg, err)
+			}
+			if calls := fake.Calls(`UPDATE article_comments|comment_edit_history`); len(calls) != 0 {
+				t.Fatalf("被拒绝的编辑不应写入数据库，实际 %+v", calls)
+			}
+		})
+	}
+}
+
+func TestUpdateCommentWithoutHistory(t *testing.T) {
+	fake, repo, cfg := newCommentEditRepo(t, time.Minute)
+	cfg.Comments.EditHistoryLimit = 0
+
+	if err := repo.UpdateComment(context.Background(), 10, 7, "old"); err != nil {
+		t.Fatalf("内容未变化时应直接成功，实际 %v", err)
+	}
+	if len(fake.Calls(`UPDATE article_comments`)) != 0 {
+		t.Fatal("内容未变化时不应更新")
+	}
+
+	if err := repo.UpdateComment(context.Background(), 10, 7, "new"); err != nil {
+		t.Fatalf("编辑失败: %v", err)
+	}
+	if len(fake.Calls(`comment_edit_history`)) != 0 {
+		t.Fatal("edit_history_limit=0 时不应保存编辑历史")
+	}
+}
+
+func TestGetCommentsSurfacesEditedFlag(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	cfg := config.Default()
+	cfg.Comments.InlineReplies = 0
+	now := time.Now().UTC()
+	columns := []string{"id", "article_id", "user_id", "parent_id", "root_id", "reply_to_user_id", "content", "like_count",
+		"reply_count", "status", "is_edited", "created_at", "updated_at", "username", "nickname", "avatar"}
+
+	fake.OnRows(`WHERE ac.article_id = \? AND ac.parent_id = 0`, columns,
+		[]driver.Value{int64(1), int64(5), int64(7), int64(0), int64(0), nil, "root", int64(0), int64(1), int64(1), true, now, now, "alice", "Alice", ""})
+	fake.OnRows(`WHERE ac.article_id = \? AND ac.parent_id > 0`, columns,
+		[]driver.Value{int64(2), int64(5), int64(8), int64(1), int64(1), nil, "reply", int64(0), int64(0), int64(1), false, now, now, "bob", "Bob", ""})
+
+	// COUNT 查询与列表查询条件相同，最后注册以优先匹配
+	fake.OnRows(`SELECT COUNT\(\*\) FROM article_comments`, []string{"count"}, []driver.Value{int64(1)})
+
+	resp, err := NewArticleRepository(db, cfg).GetComments(context.Background(), 5, 1, 20, 0, "")
+	if err != nil {
+		t.Fatalf("获取评论失败: %v", err)
+	}
+	if len(resp.Comments) != 1 || !resp.Comments[0].IsEdited {
+		t.Fatalf("编辑过的评论应返回 is_edited=true，实际 %+v", resp.Comments)
+	}
+	if replies := resp.Comments[0].Replies; len(replies) != 1 || replies[0].IsEdited {
+		t.Fatalf("未编辑的回复应返回 is_edited=false，实际 %+v", replies)
+	}
+}
+
+// commentRow 回复链测试中的评论数据
+type commentRow struct {
+	articleID, parentID, status int64
+	content                     string
+}
+
+// onCommentAncestors 用内存表模拟回复链查询
+func onCommentAncestors(fake *testutil.FakeDB, comments map[int64]commentRow) {
+	fake.On(`FROM article_comments ac LEFT JOIN user_auth ua`, func(args []driver.Value) testutil.Response {
+		resp := testutil.Response{Columns: []string{"id", "article_id", "parent_id", "status", "snippet", "created_at",
+			"user_id", "username", "nickname", "avatar"}}
+		id := args[1].(int64)
+		if c, ok := comments[id]; ok {
+			resp.Rows = [][]driver.Value{{id, c.articleID, c.parentID, c.status, c.content, time.Now().UTC(),
+				id + 100, "user", "昵称", ""}}
+		}
+		return resp
+	})
+}
+
+func TestGetCommentAncestryOrder(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	onCommentAncestors(fake, map[int64]commentRow{
+		1: {5, 0, 1, "一级评论"},
+		2: {5, 1, 0, "已删除的回复"},
+		3: {5, 2, 1, strings.Repeat("长", 80)},
+		4: {5, 3, 1, "目标评论"},
+	})
+	repo := NewArticleRepository(db, config.Default())
+
+	resp, err := repo.GetCommentAncestry(context.Background(), 4)
+	if err != nil {
+		t.Fatalf("获取回复链失败: %v", err)
+	}
+	if resp.ArticleID != 5 || resp.Truncated || len(resp.Ancestors) != 3 {
+		t.Fatalf("应返回3层祖先且未截断，实际 %+v", resp)
+	}
+	for i, want := range []uint{1, 2, 3} {
+		if resp.Ancestors[i].ID != want {
+			t.Fatalf("祖先应按从根到叶排序 [1 2 3]，实际第 %d 个为 %d", i, resp.Ancestors[i].ID)
+		}
+	}
+	if a := resp.Ancestors[1]; !a.Deleted || a.Snippet != "" {
+		t.Fatalf("已删除的祖先不应返回摘要，实际 %+v", a)
+	}
+	if s := resp.Ancestors[2].Snippet; s != strings.Repeat("长", commentSnippetLength)+"..." {
+		t.Fatalf("摘要应截断为 %d 个字符，实际 %q", commentSnippetLength, s)
+	}
+	if author := resp.Ancestors[0].Author; author.ID != 101 || author.Nickname != "昵称" {
+		t.Fatalf("应返回作者信息，实际 %+v", author)
+	}
+	if args := fake.Calls(`FROM article_comments ac`)[0].Args; args[0] != int64(commentSnippetLength+1) {
+		t.Fatalf("应只查询摘要长度的内容，实际参数 %v", args)
+	}
+}
+
+func TestGetCommentAncestryRootAndMissing(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	onCommentAncestors(fake, map[int64]commentRow{
+		1: {5, 0, 1, "一级评论"},
+		2: {5, 0, 0, "已删除"},
+	})
+	repo := NewArticleRepository(db, config.Default())
+
+	resp, err := repo.GetCommentAncestry(context.Background(), 1)
+	if err != nil || resp.Ancestors == nil || len(resp.Ancestors) != 0 || resp.Truncated {
+		t.Fatalf("一级评论应返回空列表，实际 %+v %v", resp, err)
+	}
+	for _, id := range []uint{2, 9} {
+		if _, err := repo.GetCommentAncestry(context.Background(), id); !errors.Is(err, utils.ErrResourceNotFound) {
+			t.Fatalf("评论 %d 已删除或不存在时应返回 ErrResourceNotFound，实际 %v", id, err)
+		}
+	}
+}
+
+func TestGetCommentAncestryStopsOnBadChains(t *testing.T) {
+	cases := []struct {
+		name     string
+		comments map[int64]commentRow
+		want     int
+	}{
+		{"parent_id 成环", map[int64]commentRow{
+			1: {5, 3, 1, "a"}, 2: {5, 1, 1, "b"}, 3: {5, 2, 1, "c"}, 4: {5, 3, 1, "目标"},
+		}, 3},
+		{"父评论不存在", map[int64]commentRow{
+			2: {5, 1, 1, "b"}, 4: {5, 2, 1, "目标"},
+		}, 1},
+		{"父评论属于其他文章", map[int64]commentRow{
+			1: {6, 0, 1, "a"}, 2: {5, 1, 1, "b"}, 4: {5, 2, 1, "目标"},
+		}, 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fake, db := newFakeDatabase(t)
+			onCommentAncestors(fake, tc.comments)
+			resp, err := NewArticleRepository(db, config.Default()).GetCommentAncestry(context.Background(), 4)
+			if err != nil {
+				t.Fatalf("获取回复链失败: %v", err)
+			}
+			if !resp.Truncated || len(resp.Ancestors) != tc.want {
+				t.Fatalf("应截断并保留 %d 层，实际 %+v", tc.want, resp)
+			}
+		})
+	}
+}
+
+func TestGetCommentAncestryDepthCap(t *testing.T) {
+	comments := make(map[int64]commentRow)
+	for id := int64(1); id <= commentAncestryMaxDepth+10; id++ {
+		comments[id] = commentRow{5, id - 1, 1, "回复"}
+	}
+	fake, db := newFakeDatabase(t)
+	onCommentAncestors(fake, comments)
+
+	target := uint(commentAncestryMaxDepth + 10)
+	resp, err := NewArticleRepository(db, config.Default()).GetCommentAncestry(context.Background(), target)
+	if err != nil {
+		t.Fatalf("获取回复链失败: %v", err)
+	}
+	if !resp.Truncated || len(resp.Ancestors) != commentAncestryMaxDepth {
+		t.Fatalf("应最多追溯 %d 层，实际 %d 层 truncated=%v", commentAncestryMaxDepth, len(resp.Ancestors), resp.Truncated)
+	}
+	// 截断时保留离目标最近的祖先
+	if last := resp.Ancestors[len(resp.Ancestors)-1]; last.ID != target-1 {
+		t.Fatalf("最后一层应为直接父评论 %d，实际 %d", target-1, last.ID)
+	}
+	if calls := fake.Calls(`FROM article_comments ac`); len(calls) != commentAncestryMaxDepth+1 {
+		t.Fatalf("查询次数应受层级上限限制，实际 %d 次", len(calls))
+	}
+}
+
+// onCommentOwners 预设批量删除评论时锁定到的评论：id -> {user_id, article_id}
+func onCommentOwners(fake *testutil.FakeDB, comments map[int64][2]int64) {
+	fake.On(`SELECT id, user_id, article_id FROM article_comments WHERE id IN`, func(args []driver.Value) testutil.Response {
+		var rows [][]driver.Value
+		for _, arg := range args {
+			if c, ok := comments[arg.(int64)]; ok {
+				rows = append(rows, []driver.Value{arg, c[0], c[1]})
+			}
+		}
+		return testutil.Response{Columns: []string{"id", "user_id", "article_id"}, Rows: rows}
+	})
+	fake.OnExec(`UPDATE article_comments SET status = 0`, 0, int64(len(comments)))
+	fake.OnExec(`UPDATE articles SET comment_count`, 0, 1)
+}
+
+func TestDeleteCommentsByUser(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	onCommentOwners(fake, map[int64][2]int64{1: {7, 100}, 2: {7, 100}, 3: {7, 200}})
+	repo := NewArticleRepository(db, config.Default())
+
+	deleted, err := repo.DeleteCommentsByUser(context.Background(), 7, []uint{1, 2, 1, 3, 2})
+	if err != nil {
+		t.Fatalf("批量删除评论失败: %v", err)
+	}
+	if len(deleted) != 3 || deleted[0] != 1 || deleted[1] != 2 || deleted[2] != 3 {
+		t.Fatalf("重复的ID只应删除并返回一次，实际 %v", deleted)
+	}
+	for _, pattern := range []string{`SELECT id, user_id, article_id FROM article_comments`, `UPDATE article_comments SET status = 0`} {
+		if call := fake.Calls(pattern)[0]; !strings.Contains(call.Query, "IN (?,?,?)") {
+			t.Fatalf("IN 列表应去重: %s", call.Query)
+		}
+	}
+
+	// 每篇文章按实际删除的评论数扣减一次
+	counts := map[int64]int64{}
+	for _, call := range fake.Calls(`UPDATE articles SET comment_count`) {
+		if !strings.Contains(call.Query, "GREATEST(comment_count - ?, 0)") {
+			t.Fatalf("评论数不应扣减为负: %s", call.Query)
+		}
+		counts[call.Args[1].(int64)] += call.Args[0].(int64)
+	}
+	if len(counts) != 2 || counts[100] != 2 || counts[200] != 1 {
+		t.Fatalf("应按文章扣减评论数，实际 %v", counts)
+	}
+	if len(fake.Calls(`^COMMIT$`)) != 1 {
+		t.Fatal("批量删除应在事务中提交")
+	}
+}
+
+func TestDeleteCommentsByUserOwnership(t *testing.T) {
+	cases := []struct {
+		name     string
+		ids      []uint
+		wantCode int
+		wantMsg  string
+	}{
+		{"评论不存在或已删除", []uint{1, 9}, 404, "9"},
+		{"评论不属于该用户", []uint{1, 4}, 403, "4"},
+	}
+	for _, tc := range cases {
+		fake, db := newFakeDatabase(t)
+		onCommentOwners(fake, map[int64][2]int64{1: {7, 100}, 4: {8, 100}})
+		repo := NewArticleRepository(db, config.Default())
+
+		deleted, err := repo.DeleteCommentsByUser(context.Background(), 7, tc.ids)
+		var appErr *utils.AppError
+		if !errors.As(err, &appErr) || appErr.Code != tc.wantCode || !strings.Contains(appErr.Message, tc.wantMsg) {
+			t.Fatalf("%s: 应返回 %d 并列出有问题的ID，实际 %v", tc.name, tc.wantCode, err)
+		}
+		if deleted != nil {
+			t.Fatalf("%s: 整批失败时不应返回已删除的ID，实际 %v", tc.name, deleted)
+		}
+		if len(fake.Calls(`UPDATE article_comments|UPDATE articles`)) != 0 || len(fake.Calls(`^ROLLBACK$`)) != 1 {
+			t.Fatalf("%s: 任一评论校验失败时应整批回滚", tc.name)
+		}
+	}
+}
+
+func TestGetCommentsSortOrder(t *testing.T) {
+	cases := []struct {
+		sort, want, orderBy string
+	}{
+		{"", models.CommentSortNewest, "ORDER BY ac.created_at DESC, ac.id DESC LIMIT"},
+		{"newest", models.CommentSortNewest, "ORDER BY ac.created_at DESC, ac.id DESC LIMIT"},
+		{"oldest", models.CommentSortOldest, "ORDER BY ac.created_at ASC, ac.id ASC LIMIT"},
+		{"top", models.CommentSortTop, "ORDER BY ac.like_count DESC, ac.created_at DESC, ac.id DESC LIMIT"},
+		{"random; DROP TABLE", models.CommentSortNewest, "ORDER BY ac.created_at DESC, ac.id DESC LIMIT"},
+	}
+	for _, tc := range cases {
+		fake, db := newFakeDatabase(t)
+		fake.OnRows(`WHERE ac.article_id = \? AND ac.parent_id = 0`, []string{"id"})
+		fake.OnRows(`SELECT COUNT\(\*\) FROM article_comments`, []string{"count"}, []driver.Value{int64(25)})
+
+		resp, err := NewArticleRepository(db, config.Default()).GetComments(context.Background(), 5, 2, 10, 0, tc.sort)
+		if err != nil {
+			t.Fatalf("%q: 获取评论失败: %v", tc.sort, err)
+		}
+		if resp.Sort != tc.want || resp.Total != 25 || resp.TotalPages != 3 {
+			t.Errorf("%q: 应按 %s 排序且分页信息不受排序影响，实际 %+v", tc.sort, tc.want, resp)
+		}
+		list := fake.Calls(`FROM article_comments ac INNER JOIN user_auth`)
+		if len(list) != 1 || !strings.Contains(list[0].Query, tc.orderBy) {
+			t.Fatalf("%q: 一级评论应使用排序 %q，实际 %v", tc.sort, tc.orderBy, list)
+		}
+		if list[0].Args[2] != int64(10) || list[0].Args[3] != int64(10) {
+			t.Errorf("%q: 分页参数应为 LIMIT 10 OFFSET 10，实际 %v", tc.sort, list[0].Args)
+		}
+		if count := fake.Calls(`SELECT COUNT\(\*\) FROM article_comments`); strings.Contains(count[0].Query, "ORDER BY") {
+			t.Errorf("%q: COUNT 查询不应受排序影响，实际 %q", tc.sort, count[0].Query)
+		}
+	}
+}
+
+func TestGetCommentsSortKeepsRepliesChronological(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	cfg := config.Default()
+	cfg.Comments.InlineReplies = 0
+	now := time.Now().UTC()
+	columns := []string{"id", "article_id", "user_id", "parent_id", "root_id", "reply_to_user_id", "content", "like_count",
+		"reply_count", "status", "is_edited", "created_at", "updated_at", "username", "nickname", "avatar"}
+	fake.OnRows(`WHERE ac.article_id = \? AND ac.parent_id = 0`, columns,
+		[]driver.Value{int64(1), int64(5), int64(7), int64(0), int64(0), nil, "popular", int64(9), int64(2), int64(1), false, now, now, "alice", "Alice", ""})
+	fake.OnRows(`WHERE ac.article_id = \? AND ac.parent_id > 0`, columns,
+		[]driver.Value{int64(2), int64(5), int64(8), int64(1), int64(1), nil, "first", int64(0), int64(0), int64(1), false, now, now, "bob", "Bob", ""},
+		[]driver.Value{int64(3), int64(5), int64(8), int64(1), int64(1), nil, "second", int64(5), int64(0), int64(1), false, now.Add(time.Minute), now, "bob", "Bob", ""})
+	fake.OnRows(`SELECT COUNT\(\*\) FROM article_comments`, []string{"count"}, []driver.Value{int64(1)})
+
+	resp, err := NewArticleRepository(db, cfg).GetComments(context.Background(), 5, 1, 20, 0, models.CommentSortTop)
+	if err != nil {
+		t.Fatalf("获取评论失败: %v", err)
+	}
+	// 按点赞排序只影响一级评论，回复仍按时间正序
+	replies := fake.Calls(`WHERE ac.article_id = \? AND ac.parent_id > 0`)
+	if len(replies) != 1 || !strings.HasSuffix(replies[0].Query, "ORDER BY ac.created_at ASC") {
+		t.Fatalf("回复应按时间正序查询，实际 %v", replies)
+	}
+	if r := resp.Comments[0].Replies; len(r) != 2 || r[0].Content != "first" || r[1].Content != "second" {
+		t.Fatalf("回复应按时间正序返回，实际 %+v", r)
+	}
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/models"
+	"gin/internal/testutil"
+)
+
+// rankedArticle 排序测试中的文章（发布时间相同，reports 为未驳回的举报人数）
+type rankedArticle struct {
+	id, likes, views, reports int64
+}
+
+// onHotArticleRanking 按查询参数模拟热度排序：降权开启时参数依次为
+// gravity、已驳回状态、举报阈值、最低浏览量、最低点赞率、降权系数、limit
+func onHotArticleRanking(fake *testutil.FakeDB, articles []rankedArticle) {
+	fake.On(`SELECT a.id FROM articles a WHERE a.status = 1 ORDER BY`, func(args []driver.Value) testutil.Response {
+		score := func(a rankedArticle) float64 {
+			s := float64(a.likes*3) + float64(a.views)*0.1
+			if len(args) == 7 {
+				threshold, minViews := args[2].(int64), args[3].(int64)
+				ratio, factor := args[4].(float64), args[5].(float64)
+				if a.reports >= threshold || (a.views >= minViews && float64(a.likes) < float64(a.views)*ratio) {
+					s *= factor
+				}
+			}
+			return s
+		}
+		sorted := slices.Clone(articles)
+		sort.SliceStable(sorted, func(i, j int) bool { return score(sorted[i]) > score(sorted[j]) })
+
+		resp := testutil.Response{Columns: []string{"id"}}
+		for i, a := range sorted {
+			if int64(i) >= args[len(args)-1].(int64) {
+				break
+			}
+			resp.Rows = append(resp.Rows, []driver.Value{a.id})
+		}
+		return resp
+	})
+}
+
+func TestHotRankingDemotesReportedArticles(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	onHotArticleRanking(fake, []rankedArticle{
+		{id: 1, likes: 10, views: 100},
+		{id: 2, likes: 10, views: 100, reports: 6}, // 与1相同，但被多人举报
+		{id: 3, likes: 5, views: 100},
+	})
+	cfg := config.Default()
+
+	ids, err := NewArticleRepository(db, cfg).GetHotArticleIDs(context.Background(), 10, 1.5)
+	if err != nil {
+		t.Fatalf("查询热门文章失败: %v", err)
+	}
+	if !slices.Equal(ids, []uint{1, 3, 2}) {
+		t.Fatalf("被多人举报的文章应排在同等的正常文章之后，实际 %v", ids)
+	}
+
+	call := fake.Calls(`SELECT a.id FROM articles a`)[0]
+	if !strings.Contains(call.Query, "ar.status != ?") || call.Args[1] != int64(models.ReportStatusDismissed) || call.Args[2] != int64(cfg.Demotion.ReportThreshold) {
+		t.Fatalf("应只统计未被驳回的举报并使用配置的阈值: %s %v", call.Query, call.Args)
+	}
+	if factor := call.Args[5].(float64); factor <= 0 || factor != cfg.Demotion.Factor {
+		t.Fatalf("降权系数应为配置的正数，文章只被压低不被隐藏，实际 %v", factor)
+	}
+}
+
+func TestHotRankingDemotesLowLikeRatio(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	onHotArticleRanking(fake, []rankedArticle{
+		{id: 1, likes: 1, views: 600}, // 点赞率低于 0.002
+		{id: 2, likes: 2, views: 600},
+		{id: 3, likes: 0, views: 150}, // 浏览量不足，不按点赞率判断
+	})
+
+	ids, err := NewArticleRepository(db, config.Default()).GetHotArticleIDs(context.Background(), 10, 1.5)
+	if err != nil {
+		t.Fatalf("查询热门文章失败: %v", err)
+	}
+	if !slices.Equal(ids, []uint{2, 1, 3}) {
+		t.Fatalf("点赞率过低的文章应降权但仍在列表中，实际 %v", ids)
+	}
+}
+
+func TestHotRankingWithoutDemotion(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	onHotArticleRanking(fake, []rankedArticle{
+		{id: 1, likes: 10, views: 100, reports: 6},
+		{id: 2, likes: 5, views: 100},
+	})
+	cfg := config.Default()
+	cfg.Demotion.Enabled = false
+
+	ids, err := NewArticleRepository(db, cfg).GetHotArticleIDs(context.Background(), 10, 1.5)
+	if err != nil || !slices.Equal(ids, []uint{1, 2}) {
+		t.Fatalf("关闭降权后应按原始热度排序，实际 %v %v", ids, err)
+	}
+	if call := fake.Calls(`SELECT a.id FROM articles a`)[0]; strings.Contains(call.Query, "CASE WHEN") || len(call.Args) != 2 {
+		t.Fatalf("关闭降权时不应附加降权条件: %s %v", call.Query, call.Args)
+	}
+}
+
+func TestArticleListSortAppliesDemotion(t *testing.T) {
+	cases := []struct {
+		sortBy      string
+		wantDemoted bool
+	}{
+		{"hot", true},
+		{"popular", true},
+		{"latest", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.sortBy, func(t *testing.T) {
+			fake, db := newFakeDatabase(t)
+			fake.OnRows(`FROM articles a INNER JOIN user_auth ua`, []string{"id"})
+			fake.OnRows(`SELECT COUNT\(\*\) FROM articles a`, []string{"count"}, []driver.Value{int64(0)})
+
+			_, err := NewArticleRepository(db, config.Default()).ListArticles(context.Background(),
+				models.ArticleListQuery{SortBy: tc.sortBy, Page: 1, PageSize: 20})
+			if err != nil {
+				t.Fatalf("查询文章列表失败: %v", err)
+			}
+			call := fake.Calls(`FROM articles a INNER JOIN user_auth ua`)[0]
+			if got := strings.Contains(call.Query, "* (CASE WHEN"); got != tc.wantDemoted {
+				t.Fatalf("排序 %s 是否降权应为 %v: %s", tc.sortBy, tc.wantDemoted, call.Query)
+			}
+			// 分页参数始终在最后
+			if n := len(call.Args); call.Args[n-2] != int64(20) || call.Args[n-1] != int64(0) {
+				t.Fatalf("分页参数应在降权参数之后，实际 %v", call.Args)
+			}
+		})
+	}
+}
+
+func TestArticleListTrendingSort(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	now := time.Now().UTC()
+	fake.OnRows(`FROM articles a INNER JOIN user_auth ua`,
+		[]string{"id", "user_id", "title", "description", "view_count", "like_count", "comment_count", "created_at", "updated_at",
+			"username", "nickname", "avatar", "score"},
+		[]driver.Value{int64(3), int64(1), "新文章", "", int64(10), int64(5), int64(1), now, now, "alice", "Alice", "", 1.25},
+		[]driver.Value{int64(2), int64(1), "旧文章", "", int64(900), int64(80), int64(9), now, now, "alice", "Alice", "", 0.5})
+	fake.OnRows(`SELECT COUNT\(\*\) FROM articles a`, []string{"count"}, []driver.Value{int64(2)})
+	fake.OnRows(`FROM article_categories`, []string{"article_id"})
+	fake.OnRows(`FROM article_tags at`, []string{"article_id"})
+	cfg := config.Default()
+	cfg.Cache.HotArticles.DecayGravity = 1.8
+	cfg.StatisticsQueryExtended.DefaultDateRangeDays = 14
+	repo := NewArticleRepository(db, cfg)
+
+	resp, err := repo.ListArticles(context.Background(), models.ArticleListQuery{SortBy: "trending", Page: 1, PageSize: 20})
+	if err != nil {
+		t.Fatalf("查询文章列表失败: %v", err)
+	}
+	if len(resp.Articles) != 2 || resp.Articles[0].Score == nil || *resp.Articles[0].Score != 1.25 {
+		t.Fatalf("trending 排序应返回衰减热度分，实际 %+v", resp.Articles)
+	}
+
+	call := fake.Calls(`FROM articles a INNER JOIN user_auth ua`)[0]
+	if !strings.Contains(call.Query, "POW(TIMESTAMPDIFF(HOUR, a.created_at, UTC_TIMESTAMP()) + 2, ?)") ||
+		!strings.Contains(call.Query, "ORDER BY score DESC, a.id DESC") {
+		t.Fatalf("应按时间衰减的热度分排序: %s", call.Query)
+	}
+	// 热度分参数在 SELECT 中，排在 WHERE 参数之前
+	if call.Args[0] != 1.8 {
+		t.Fatalf("第一个参数应为配置的衰减指数，实际 %v", call.Args)
+	}
+	// 只在最近N天的文章中计算，列表和总数使用同样的范围
+	for _, call := range fake.Calls(`FROM articles a`) {
+		if !strings.Contains(call.Query, "a.created_at >= UTC_TIMESTAMP() - INTERVAL ? DAY") || !containsArg(call.Args, int64(14)) {
+			t.Fatalf("trending 排序应限制扫描范围: %s %v", call.Query, call.Args)
+		}
+	}
+
+	// 其他排序不计算热度分，也不限制发布时间
+	fake.OnRows(`FROM articles a INNER JOIN user_auth ua`,
+		[]string{"id", "user_id", "title", "description", "view_count", "like_count", "comment_count", "created_at", "updated_at",
+			"username", "nickname", "avatar", "score"},
+		[]driver.Value{int64(2), int64(1), "旧文章", "", int64(900), int64(80), int64(9), now, now, "alice", "Alice", "", nil})
+	before := len(fake.Calls(""))
+	resp, err = repo.ListArticles(context.Background(), models.ArticleListQuery{SortBy: "hot", Page: 1, PageSize: 20})
+	if err != nil || len(resp.Articles) != 1 || resp.Articles[0].Score != nil {
+		t.Fatalf("hot 排序不应返回热度分，实际 %+v %v", resp, err)
+	}
+	for _, call := range fake.Calls("")[before:] {
+		if strings.Contains(call.Query, "INTERVAL ? DAY") {
+			t.Fatalf("非 trending 排序不应限制发布时间: %s", call.Query)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"slices"
+	"testing"
+
+	"gin/internal/config"
+	"gin/internal/testutil"
+	"gin/internal/utils"
+)
+
+// onArticleStatuses 预设批量修改状态的语句：statuses 为文章ID到当前状态的映射，不在其中的文章视为不存在
+func onArticleStatuses(fake *testutil.FakeDB, statuses map[int64]int64) {
+	fake.On(`SELECT id, status FROM articles WHERE id IN \(.*\) FOR UPDATE`, func(args []driver.Value) testutil.Response {
+		resp := testutil.Response{Columns: []string{"id", "status"}}
+		for _, arg := range args {
+			if status, ok := statuses[arg.(int64)]; ok {
+				resp.Rows = append(resp.Rows, []driver.Value{arg, status})
+			}
+		}
+		return resp
+	})
+	fake.OnExec(`UPDATE articles SET status = \?, updated_at = \? WHERE id IN`, 0, 1)
+	fake.OnExec(`UPDATE article_categories c JOIN`, 0, 1)
+	fake.OnExec(`UPDATE article_tags t JOIN`, 0, 1)
+}
+
+// idArgs 把 IN 子句的参数转换为文章ID
+func idArgs(args []driver.Value) []int64 {
+	ids := make([]int64, 0, len(args))
+	for _, arg := range args {
+		ids = append(ids, arg.(int64))
+	}
+	return ids
+}
+
+func TestAdminBulkUpdateStatusDelete(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	// 1 已发布，2 已删除，3 草稿，4 不存在
+	onArticleStatuses(fake, map[int64]int64{1: 1, 2: 2, 3: 0})
+	repo := NewArticleRepository(db, config.Default())
+
+	result, err := repo.AdminBulkUpdateStatus(context.Background(), []uint{1, 2, 3, 4}, 2, 9)
+	if err != nil {
+		t.Fatalf("批量修改文章状态失败: %v", err)
+	}
+	if !slices.Equal(result.UpdatedIDs, []uint{1, 3}) || !slices.Equal(result.SkippedIDs, []uint{2}) ||
+		!slices.Equal(result.MissingIDs, []uint{4}) || result.Status != 2 {
+		t.Fatalf("应区分修改、跳过和不存在的文章，实际 %+v", result)
+	}
+
+	// 只用一条语句修改实际变化的文章
+	updates := fake.Calls(`UPDATE articles SET status`)
+	if len(updates) != 1 || updates[0].Args[0] != int64(2) || !slices.Equal(idArgs(updates[0].Args[2:]), []int64{1, 3}) {
+		t.Fatalf("应一次修改文章 1、3 的状态，实际 %v", updates)
+	}
+	// 已删除的文章不参与重算，避免重复扣减
+	for _, pattern := range []string{`UPDATE article_categories c JOIN`, `UPDATE article_tags t JOIN`} {
+		calls := fake.Calls(pattern)
+		if len(calls) != 1 || !slices.Equal(idArgs(calls[0].Args), []int64{1, 3}) {
+			t.Fatalf("应按文章 1、3 重算关联的文章数，实际 %v", calls)
+		}
+	}
+	if len(fake.Calls(`^COMMIT$`)) != 1 {
+		t.Fatal("状态修改和计数重算应在同一事务中提交")
+	}
+}
+
+func TestAdminBulkUpdateStatusRecountOnlyOnDeleteOrRestore(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	onArticleStatuses(fake, map[int64]int64{1: 1, 2: 2, 3: 0})
+	repo := NewArticleRepository(db, config.Default())
+
+	// 发布：2 从删除恢复需要重算，3 从草稿发布不影响计数，1 已发布跳过
+	result, err := repo.AdminBulkUpdateStatus(context.Background(), []uint{1, 2, 3}, 1, 9)
+	if err != nil {
+		t.Fatalf("批量修改文章状态失败: %v", err)
+	}
+	if !slices.Equal(result.UpdatedIDs, []uint{2, 3}) || !slices.Equal(result.SkippedIDs, []uint{1}) {
+		t.Fatalf("应修改文章 2、3 并跳过 1，实际 %+v", result)
+	}
+	if calls := fake.Calls(`UPDATE article_categories c JOIN`); len(calls) != 1 || !slices.Equal(idArgs(calls[0].Args), []int64{2}) {
+		t.Fatalf("只有恢复的文章需要重算分类文章数，实际 %v", calls)
+	}
+
+	// 草稿和发布之间切换不重算
+	fake, db = newFakeDatabase(t)
+	onArticleStatuses(fake, map[int64]int64{1: 1})
+	repo = NewArticleRepository(db, config.Default())
+	if _, err := repo.AdminBulkUpdateStatus(context.Background(), []uint{1}, 0, 9); err != nil {
+		t.Fatalf("批量修改文章状态失败: %v", err)
+	}
+	if len(fake.Calls(`UPDATE articles SET status`)) != 1 || len(fake.Calls(`UPDATE article_(categories|tags)`)) != 0 {
+		t.Fatal("草稿和发布之间切换只应修改状态，不应重算文章数")
+	}
+
+	// 全部已处于目标状态时不执行更新
+	fake, db = newFakeDatabase(t)
+	onArticleStatuses(fake, map[int64]int64{1: 1})
+	repo = NewArticleRepository(db, config.Default())
+	result, err = repo.AdminBulkUpdateStatus(context.Background(), []uint{1}, 1, 9)
+	if err != nil || len(result.UpdatedIDs) != 0 || len(fake.Calls(`^UPDATE`)) != 0 {
+		t.Fatalf("没有需要修改的文章时不应执行更新，实际 %+v %v", result, err)
+	}
+}
+
+func TestAdminBulkUpdateStatusRollsBack(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	onArticleStatuses(fake, map[int64]int64{1: 1})
+	fake.OnError(`UPDATE article_tags t JOIN`, errors.New("deadlock"))
+	repo := NewArticleRepository(db, config.Default())
+
+	if _, err := repo.AdminBulkUpdateStatus(context.Background(), []uint{1}, 2, 9); !errors.Is(err, utils.ErrDatabaseUpdate) {
+		t.Fatalf("重算失败时应返回 ErrDatabaseUpdate，实际 %v", err)
+	}
+	if len(fake.Calls(`^ROLLBACK$`)) != 1 || len(fake.Calls(`^COMMIT$`)) != 0 {
+		t.Fatal("重算失败时应回滚状态修改")
+	}
+}
+
+func TestAdminDeleteByUser(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	fake.On(`SELECT id FROM articles WHERE user_id = \? AND status != 2 FOR UPDATE`, func(args []driver.Value) testutil.Response {
+		resp := testutil.Response{Columns: []string{"id"}}
+		if args[0] == int64(7) {
+			resp.Rows = [][]driver.Value{{int64(5)}, {int64(6)}}
+		}
+		return resp
+	})
+	fake.OnExec(`UPDATE articles SET status = \?, updated_at = \? WHERE id IN`, 0, 2)
+	fake.OnExec(`UPDATE article_categories c JOIN`, 0, 1)
+	fake.OnExec(`UPDATE article_tags t JOIN`, 0, 1)
+	repo := NewArticleRepository(db, config.Default())
+
+	deleted, err := repo.AdminDeleteByUser(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("删除用户文章失败: %v", err)
+	}
+	if !slices.Equal(deleted, []uint{5, 6}) {
+		t.Fatalf("应返回被删除的文章ID，实际 %v", deleted)
+	}
+	updates := fake.Calls(`UPDATE articles SET status`)
+	if len(updates) != 1 || updates[0].Args[0] != int64(2) || !slices.Equal(idArgs(updates[0].Args[2:]), []int64{5, 6}) {
+		t.Fatalf("应一次软删除文章 5、6，实际 %v", updates)
+	}
+	if calls := fake.Calls(`UPDATE article_tags t JOIN`); len(calls) != 1 || !slices.Equal(idArgs(calls[0].Args), []int64{5, 6}) {
+		t.Fatalf("应重算被删除文章关联的标签文章数，实际 %v", calls)
+	}
+
+	// 用户没有未删除的文章时不执行更新
+	deleted, err = repo.AdminDeleteByUser(context.Background(), 8)
+	if err != nil || len(deleted) != 0 || len(fake.Calls(`UPDATE articles SET status`)) != 1 {
+		t.Fatalf("没有文章时应返回空列表且不执行更新，实际 %v %v", deleted, err)
+	}
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	"gin/internal/config"
+	"gin/internal/utils"
+)
+
+func TestGetArticlePlainText(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	content := "# 标题\n\n" + strings.Repeat("word ", 250) + "\n\n```go\n" + strings.Repeat("code ", 500) + "\n```\n\n**中文**内容"
+	fake.OnRows(`SELECT a.title, a.content, \(SELECT COUNT\(\*\) FROM article_code_blocks`,
+		[]string{"title", "content", "count"}, []driver.Value{"Hello", content, int64(2)})
+	cfg := config.Default()
+	cfg.Reading.WordsPerMinute = 100
+
+	text, err := NewArticleRepository(db, cfg).GetArticlePlainText(context.Background(), 5)
+	if err != nil {
+		t.Fatalf("获取纯文本失败: %v", err)
+	}
+	if text.ArticleID != 5 || text.Title != "Hello" || text.CodeBlockCount != 2 {
+		t.Fatalf("文章信息错误: %+v", text)
+	}
+	if strings.Contains(text.Text, "code") || strings.Contains(text.Text, "#") || strings.Contains(text.Text, "*") {
+		t.Fatalf("纯文本不应包含代码块和Markdown标记: %q", text.Text)
+	}
+	// 标题2字 + 250个单词 + 内容4字
+	if text.WordCount != 256 {
+		t.Fatalf("字数应为256，实际 %d", text.WordCount)
+	}
+	if text.ReadingMinutes != 3 {
+		t.Fatalf("每分钟100字时阅读时长应为3分钟，实际 %d", text.ReadingMinutes)
+	}
+}
+
+func TestGetArticlePlainTextNotFound(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	fake.OnRows(`SELECT a.title, a.content`, []string{"title", "content", "count"})
+
+	_, err := NewArticleRepository(db, config.Default()).GetArticlePlainText(context.Background(), 9)
+	if !errors.Is(err, utils.ErrResourceNotFound) {
+		t.Fatalf("文章不存在或已删除时应返回不存在，实际 %v", err)
+	}
+	if query := fake.Calls(`SELECT a.title, a.content`)[0].Query; !strings.Contains(query, "a.status != 2") {
+		t.Fatalf("已删除的文章不应返回正文: %s", query)
+	}
+}
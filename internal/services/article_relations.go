@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gin/internal/models"
+	"gin/internal/utils"
+)
+
+// articleRelationLoaders 文章详情的关联数据子查询
+type articleRelationLoaders struct {
+	codeBlocks func(ctx context.Context) ([]models.ArticleCodeBlock, error)
+	categories func(ctx context.Context) ([]models.ArticleCategory, error)
+	tags       func(ctx context.Context) ([]models.ArticleTag, error)
+	isLiked    func(ctx context.Context) bool
+}
+
+// articleRelations 文章详情的关联数据（失败的子查询对应字段为空切片）
+type articleRelations struct {
+	codeBlocks []models.ArticleCodeBlock
+	categories []models.ArticleCategory
+	tags       []models.ArticleTag
+	isLiked    bool
+	err        error // 所有失败子查询的错误汇总（nil 表示全部成功）
+}
+
+// loadArticleRelations 在共享的子context（带 timeout）中并行执行关联子查询
+// 代码块是正文的一部分，查询失败时结果已不完整，立即取消其余子查询释放连接；
+// 分类和标签查询失败只降级为空。各子查询直接写入各自的字段，返回前等待全部结束，
+// 父context取消时子context随之取消，不会留下阻塞的goroutine
+func loadArticleRelations(ctx context.Context, timeout time.Duration, loaders articleRelationLoaders) articleRelations {
+	subCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result := articleRelations{
+		codeBlocks: make([]models.ArticleCodeBlock, 0),
+		categories: make([]models.ArticleCategory, 0),
+		tags:       make([]models.ArticleTag, 0),
+	}
+	var codeBlocksErr, categoriesErr, tagsErr error
+
+	utils.Parallel(ctx,
+		func() {
+			blocks, err := loaders.codeBlocks(subCtx)
+			if err != nil {
+				codeBlocksErr = err
+				cancel()
+				return
+			}
+			result.codeBlocks = blocks
+		},
+		func() {
+			cats, err := loaders.categories(subCtx)
+			if err != nil {
+				categoriesErr = err
+				return
+			}
+			result.categories = cats
+		},
+		func() {
+			tags, err := loaders.tags(subCtx)
+			if err != nil {
+				tagsErr = err
+				return
+			}
+			result.tags = tags
+		},
+		func() {
+			result.isLiked = loaders.isLiked(subCtx)
+		},
+	)
+
+	result.err = errors.Join(
+		relationError("代码块", codeBlocksErr),
+		relationError("分类", categoriesErr),
+		relationError("标签", tagsErr),
+	)
+	return result
+}
+
+// relationError 为子查询错误加上关联数据名称
+func relationError(name string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("查询%s失败: %w", name, err)
+}
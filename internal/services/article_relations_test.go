@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/models"
+)
+
+// fastRelationLoaders 立即返回一个代码块、分类和标签的子查询，点赞状态为 true
+func fastRelationLoaders() articleRelationLoaders {
+	return articleRelationLoaders{
+		codeBlocks: func(context.Context) ([]models.ArticleCodeBlock, error) {
+			return []models.ArticleCodeBlock{{ID: 1}}, nil
+		},
+		categories: func(context.Context) ([]models.ArticleCategory, error) {
+			return []models.ArticleCategory{{ID: 2}}, nil
+		},
+		tags: func(context.Context) ([]models.ArticleTag, error) {
+			return []models.ArticleTag{{ID: 3}}, nil
+		},
+		isLiked: func(context.Context) bool { return true },
+	}
+}
+
+// blockUntilDone 阻塞到 context 结束的子查询
+func blockUntilDone[T any](ctx context.Context) ([]T, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestLoadArticleRelationsAllSucceed(t *testing.T) {
+	rel := loadArticleRelations(context.Background(), time.Second, fastRelationLoaders())
+	if rel.err != nil || len(rel.codeBlocks) != 1 || len(rel.categories) != 1 || len(rel.tags) != 1 || !rel.isLiked {
+		t.Fatalf("全部成功时应返回所有关联数据，实际 %+v", rel)
+	}
+}
+
+func TestLoadArticleRelationsSlowCategoryDegrades(t *testing.T) {
+	loaders := fastRelationLoaders()
+	var othersDone atomic.Int64
+	tags := loaders.tags
+	loaders.tags = func(ctx context.Context) ([]models.ArticleTag, error) {
+		defer othersDone.Store(time.Now().UnixNano())
+		return tags(ctx)
+	}
+	loaders.categories = blockUntilDone[models.ArticleCategory]
+
+	start := time.Now()
+	rel := loadArticleRelations(context.Background(), 100*time.Millisecond, loaders)
+	elapsed := time.Since(start)
+
+	if elapsed < 100*time.Millisecond || elapsed > time.Second {
+		t.Fatalf("慢查询应在超时后结束，实际耗时 %v", elapsed)
+	}
+	if done := time.Duration(othersDone.Load() - start.UnixNano()); done <= 0 || done > 50*time.Millisecond {
+		t.Fatalf("其他子查询不应被慢查询阻塞，实际 %v 后完成", done)
+	}
+	if len(rel.codeBlocks) != 1 || len(rel.tags) != 1 || !rel.isLiked {
+		t.Fatalf("其他关联数据应正常返回，实际 %+v", rel)
+	}
+	if rel.categories == nil || len(rel.categories) != 0 {
+		t.Fatalf("超时的分类应降级为空切片，实际 %v", rel.categories)
+	}
+	if !errors.Is(rel.err, context.DeadlineExceeded) || !strings.Contains(rel.err.Error(), "分类") {
+		t.Fatalf("应汇总分类查询的超时错误，实际 %v", rel.err)
+	}
+}
+
+func TestLoadArticleRelationsCodeBlockFailureCancelsOthers(t *testing.T) {
+	loaders := fastRelationLoaders()
+	loaders.codeBlocks = func(context.Context) ([]models.ArticleCodeBlock, error) {
+		time.Sleep(20 * time.Millisecond)
+		return nil, errors.New("connection reset")
+	}
+	loaders.categories = blockUntilDone[models.ArticleCategory]
+	loaders.tags = blockUntilDone[models.ArticleTag]
+
+	start := time.Now()
+	rel := loadArticleRelations(context.Background(), 5*time.Second, loaders)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("代码块查询失败后应立即取消其余子查询，实际耗时 %v", elapsed)
+	}
+	if len(rel.codeBlocks) != 0 || len(rel.categories) != 0 || len(rel.tags) != 0 {
+		t.Fatalf("失败的子查询应降级为空，实际 %+v", rel)
+	}
+	for _, name := range []string{"代码块", "分类", "标签"} {
+		if !strings.Contains(rel.err.Error(), name) {
+			t.Fatalf("错误汇总应包含%s，实际 %v", name, rel.err)
+		}
+	}
+	if !errors.Is(rel.err, context.Canceled) {
+		t.Fatalf("被取消的子查询应返回取消错误，实际 %v", rel.err)
+	}
+}
+
+func TestLoadArticleRelationsParentCancelNoLeak(t *testing.T) {
+	before := runtime.NumGoroutine()
+	loaders := articleRelationLoaders{
+		codeBlocks: blockUntilDone[models.ArticleCodeBlock],
+		categories: blockUntilDone[models.ArticleCategory],
+		tags:       blockUntilDone[models.ArticleTag],
+		isLiked: func(ctx context.Context) bool {
+			<-ctx.Done()
+			return false
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	start := time.Now()
+	rel := loadArticleRelations(ctx, 5*time.Second, loaders)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("父context取消后应立即返回，实际耗时 %v", elapsed)
+	}
+	if !errors.Is(rel.err, context.Canceled) {
+		t.Fatalf("应返回取消错误，实际 %v", rel.err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Fatalf("不应留下阻塞的goroutine，之前 %d 个，之后 %d 个", before, n)
+	}
+}
+
+func TestGetArticleByIDDegradesFailedRelation(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	onArticleDetail(fake)
+	fake.OnError(`FROM article_categories ac`, errors.New("lock wait timeout"))
+	cfg := config.Default()
+	repo := NewArticleRepository(db, cfg)
+
+	article, err := repo.GetArticleByID(context.Background(), 5, 0)
+	if err != nil {
+		t.Fatalf("分类查询失败不应使整个请求失败: %v", err)
+	}
+	if !article.Partial || article.Categories == nil || len(article.Categories) != 0 {
+		t.Fatalf("分类应降级为空并标记为不完整，实际 %+v", article)
+	}
+
+	// 不完整的结果不写入缓存
+	cacheSvc := NewCacheService(repo, cfg)
+	if _, err := cacheSvc.GetArticleDetail(context.Background(), 5, 0); err != nil {
+		t.Fatalf("获取文章详情失败: %v", err)
+	}
+	if _, ok := cacheSvc.ArticleDetailTTL(5); ok {
+		t.Fatal("降级的文章详情不应写入缓存")
+	}
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/models"
+	"gin/internal/testutil"
+	"gin/internal/utils"
+)
+
+// recordingInvalidator 记录文章缓存失效调用
+type recordingInvalidator struct {
+	articles []uint
+	lists    int
+}
+
+func (r *recordingInvalidator) InvalidateArticle(articleID uint) {
+	r.articles = append(r.articles, articleID)
+}
+func (r *recordingInvalidator) InvalidateArticleLists() { r.lists++ }
+func (r *recordingInvalidator) InvalidateArticleTags()  {}
+
+// newReportRepo 预设一条举报（articleID / commentID 为 nil 表示 NULL）和被举报评论
+func newReportRepo(t *testing.T, articleID, commentID interface{}, status int64) (*testutil.FakeDB, *ArticleRepository, *recordingInvalidator) {
+	t.Helper()
+	fake, db := newFakeDatabase(t)
+	fake.On(`SELECT article_id, comment_id, user_id, status FROM article_reports WHERE id = \? FOR UPDATE`, func(args []driver.Value) testutil.Response {
+		resp := testutil.Response{Columns: []string{"article_id", "comment_id", "user_id", "status"}}
+		if args[0] == int64(1) {
+			resp.Rows = [][]driver.Value{{articleID, commentID, int64(3), status}}
+		}
+		return resp
+	})
+	fake.OnRows(`SELECT article_id, status FROM article_comments WHERE id = \? FOR UPDATE`,
+		[]string{"article_id", "status"}, []driver.Value{int64(9), int64(1)})
+	fake.OnExec(`UPDATE articles SET`, 0, 1)
+	fake.OnExec(`UPDATE article_comments SET status`, 0, 1)
+	fake.OnExec(`UPDATE article_reports SET`, 0, 1)
+
+	repo := NewArticleRepository(db, config.Default())
+	invalidator := &recordingInvalidator{}
+	repo.SetCacheInvalidator(invalidator)
+	return fake, repo, invalidator
+}
+
+// assertReportRecorded 检查举报记录了处理结果、处理人和处理时间
+func assertReportRecorded(t *testing.T, fake *testutil.FakeDB, status int, action string) {
+	t.Helper()
+	updates := fake.Calls(`UPDATE article_reports SET status = \?, action = \?, handler_id = \?, handled_at = \?`)
+	if len(updates) != 1 {
+		t.Fatalf("应更新一次举报状态，实际 %d", len(updates))
+	}
+	args := updates[0].Args
+	if args[0] != int64(status) || args[1] != action || args[2] != int64(42) || args[4] != int64(1) {
+		t.Fatalf("举报应记录状态、动作和处理人，实际 %v", args)
+	}
+	if handledAt, ok := args[3].(time.Time); !ok || time.Since(handledAt) > time.Minute {
+		t.Fatalf("举报应记录处理时间，实际 %v", args[3])
+	}
+	if len(fake.Calls(`^COMMIT$`)) != 1 {
+		t.Fatal("处理举报应在事务中提交")
+	}
+}
+
+func TestResolveArticleReportHidesArticle(t *testing.T) {
+	fake, repo, invalidator := newReportRepo(t, int64(5), nil, models.ReportStatusPending)
+
+	report, err := repo.ResolveReport(context.Background(), 1, 42, models.ReportActionHideContent)
+	if err != nil {
+		t.Fatalf("处理举报失败: %v", err)
+	}
+	hide := fake.Calls(`UPDATE articles SET status = 0`)
+	if len(hide) != 1 || hide[0].Args[1] != int64(5) {
+		t.Fatalf("hide_content 应把文章退回草稿，实际 %+v", hide)
+	}
+	if len(fake.Calls(`article_comments`)) != 0 {
+		t.Fatal("文章举报不应处理评论")
+	}
+	assertReportRecorded(t, fake, models.ReportStatusResolved, models.ReportActionHideContent)
+	if report.Status != models.ReportStatusResolved || *report.HandlerID != 42 || report.HandledAt == nil {
+		t.Fatalf("返回的举报应包含处理结果，实际 %+v", report)
+	}
+	if len(invalidator.articles) != 1 || invalidator.articles[0] != 5 || invalidator.lists != 1 {
+		t.Fatalf("文章被隐藏后应失效详情和列表缓存，实际 %+v", invalidator)
+	}
+}
+
+func TestResolveCommentReport(t *testing.T) {
+	cases := []struct {
+		action     string
+		wantStatus int64
+	}{
+		{models.ReportActionHideContent, 2},
+		{models.ReportActionDeleteContent, 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.action, func(t *testing.T) {
+			// 只举报评论时 article_id 为空
+			fake, repo, invalidator := newReportRepo(t, nil, int64(7), models.ReportStatusPending)
+
+			report, err := repo.ResolveReport(context.Background(), 1, 42, tc.action)
+			if err != nil {
+				t.Fatalf("处理举报失败: %v", err)
+			}
+			update := fake.Calls(`UPDATE article_comments SET status = \?`)
+			if len(update) != 1 || update[0].Args[0] != tc.wantStatus || update[0].Args[2] != int64(7) {
+				t.Fatalf("评论状态应改为 %d，实际 %+v", tc.wantStatus, update)
+			}
+			if len(fake.Calls(`UPDATE articles SET comment_count = GREATEST`)) != 1 {
+				t.Fatal("正常评论被处理后应减少文章评论数")
+			}
+			if len(fake.Calls(`UPDATE articles SET status`)) != 0 {
+				t.Fatal("评论举报不应修改文章状态")
+			}
+			assertReportRecorded(t, fake, models.ReportStatusResolved, tc.action)
+			if report.ArticleID == nil || *report.ArticleID != 9 || report.CommentID == nil || *report.CommentID != 7 {
+				t.Fatalf("评论举报应返回评论及其所属文章，实际 %+v", report)
+			}
+			if len(invalidator.articles) != 1 || invalidator.articles[0] != 9 || invalidator.lists != 0 {
+				t.Fatalf("评论被处理后只需失效所属文章，实际 %+v", invalidator)
+			}
+		})
+	}
+}
+
+func TestResolveReportDismiss(t *testing.T) {
+	fake, repo, invalidator := newReportRepo(t, int64(5), int64(7), models.ReportStatusPending)
+
+	if _, err := repo.ResolveReport(context.Background(), 1, 42, models.ReportActionDismiss); err != nil {
+		t.Fatalf("驳回举报失败: %v", err)
+	}
+	if calls := fake.Calls(`UPDATE articles|article_comments`); len(calls) != 0 {
+		t.Fatalf("驳回不应修改内容，实际 %+v", calls)
+	}
+	assertReportRecorded(t, fake, models.ReportStatusDismissed, models.ReportActionDismiss)
+	if len(invalidator.articles) != 0 {
+		t.Fatal("驳回不应失效缓存")
+	}
+}
+
+func TestResolveReportRejections(t *testing.T) {
+	_, repo, _ := newReportRepo(t, int64(5), nil, models.ReportStatusResolved)
+	if _, err := repo.ResolveReport(context.Background(), 1, 42, models.ReportActionDismiss); utils.GetHTTPStatusCode(err) != 409 {
+		t.Fatalf("已处理的举报应返回409，实际 %v", err)
+	}
+	if _, err := repo.ResolveReport(context.Background(), 2, 42, models.ReportActionDismiss); utils.GetHTTPStatusCode(err) != 404 {
+		t.Fatalf("不存在的举报应返回404，实际 %v", err)
+	}
+
+	fake, repo, _ := newReportRepo(t, int64(5), nil, models.ReportStatusPending)
+	if _, err := repo.ResolveReport(context.Background(), 1, 42, "ban_user"); utils.GetHTTPStatusCode(err) != 400 {
+		t.Fatalf("无效的处理动作应返回400，实际 %v", err)
+	}
+	if len(fake.Calls("")) != 0 {
+		t.Fatal("无效的处理动作不应访问数据库")
+	}
+}
+
+func TestListReportsTargets(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	now := time.Now().UTC()
+	columns := []string{"id", "article_id", "comment_id", "user_id", "reason", "status", "handler_id", "handler_note",
+		"action", "created_at", "handled_at", "title", "content", "reporter", "handler"}
+	fake.OnRows(`FROM article_reports r LEFT JOIN`, columns,
+		[]driver.Value{int64(2), int64(9), int64(7), int64(3), "spam", int64(0), nil, "", "", now, nil, "文章", "评论内容", "bob", ""},
+		[]driver.Value{int64(1), int64(5), nil, int64(3), "abuse", int64(1), int64(42), "", "hide_content", now, now, "文章", "", "bob", "admin"})
+	fake.OnRows(`SELECT COUNT\(\*\) FROM article_reports r`, []string{"count"}, []driver.Value{int64(2)})
+	repo := NewArticleRepository(db, config.Default())
+
+	resp, err := repo.ListReports(context.Background(), models.ReportStatusAll, 1, 20)
+	if err != nil {
+		t.Fatalf("获取举报列表失败: %v", err)
+	}
+	if resp.Total != 2 || len(resp.Reports) != 2 {
+		t.Fatalf("应返回2条举报，实际 %+v", resp)
+	}
+	comment, article := resp.Reports[0], resp.Reports[1]
+	if comment.TargetType != models.ReportTargetComment || *comment.CommentID != 7 || *comment.ArticleID != 9 {
+		t.Fatalf("评论举报应带评论ID和所属文章，实际 %+v", comment)
+	}
+	if article.TargetType != models.ReportTargetArticle || article.CommentID != nil || *article.ArticleID != 5 ||
+		*article.HandlerID != 42 || article.HandledAt == nil || article.HandlerName != "admin" {
+		t.Fatalf("文章举报应带处理人和处理时间，实际 %+v", article)
+	}
+	if calls := fake.Calls(`article_reports r WHERE`); len(calls) != 0 {
+		t.Fatal("不限状态时不应按状态过滤")
+	}
+
+	if _, err := repo.ListReports(context.Background(), models.ReportStatusPending, 1, 20); err != nil {
+		t.Fatalf("按状态获取举报失败: %v", err)
+	}
+	filtered := fake.Calls(`SELECT COUNT\(\*\) FROM article_reports r WHERE r.status = \?`)
+	if len(filtered) != 1 || filtered[0].Args[0] != int64(models.ReportStatusPending) {
+		t.Fatalf("应按状态过滤，实际 %+v", filtered)
+	}
+}
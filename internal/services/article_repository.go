@@ -3,9 +3,11 @@ package services
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"gin/internal/config"
 	"gin/internal/models"
@@ -17,6 +19,8 @@ type ArticleRepository struct {
 	db     *Database
 	logger utils.Logger
 	config *config.Config
+
+	cacheInvalidator ArticleCacheInvalidator // 数据变更后失效缓存（未设置时跳过）
 }
 
 // NewArticleRepository 创建文章仓库
@@ -28,6 +32,32 @@ func NewArticleRepository(db *Database, cfg *config.Config) *ArticleRepository {
 	}
 }
 
+// SetCacheInvalidator 设置缓存失效回调（启动时由缓存服务注册）
+func (r *ArticleRepository) SetCacheInvalidator(invalidator ArticleCacheInvalidator) {
+	r.cacheInvalidator = invalidator
+}
+
+// invalidateArticle 失效单篇文章的缓存
+func (r *ArticleRepository) invalidateArticle(articleID uint) {
+	if r.cacheInvalidator != nil {
+		r.cacheInvalidator.InvalidateArticle(articleID)
+	}
+}
+
+// invalidateArticleLists 失效文章列表相关的缓存（分类/标签计数等）
+func (r *ArticleRepository) invalidateArticleLists() {
+	if r.cacheInvalidator != nil {
+		r.cacheInvalidator.InvalidateArticleLists()
+	}
+}
+
+// invalidateArticleTags 失效标签列表缓存（标签新建、重命名、合并后）
+func (r *ArticleRepository) invalidateArticleTags() {
+	if r.cacheInvalidator != nil {
+		r.cacheInvalidator.InvalidateArticleTags()
+	}
+}
+
 // CreateArticle 创建文章
 func (r *ArticleRepository) CreateArticle(ctx context.Context, article *models.Article, codeBlocks []models.CreateArticleCodeBlock, categoryIDs, tagIDs []uint) error {
 	start := time.Now().UTC()
@@ -42,7 +72,7 @@ func (r *ArticleRepository) CreateArticle(ctx context.Context, article *models.A
 	tx, err := r.db.DB.BeginTx(ctx, nil)
 	if err != nil {
 		r.logger.Error("开启事务失败", "error", err.Error())
-		return utils.ErrDatabaseQuery
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
 	}
 	defer tx.Rollback()
 
@@ -55,13 +85,13 @@ func (r *ArticleRepository) CreateArticle(ctx context.Context, article *models.A
 		article.Status, article.CreatedAt, article.UpdatedAt)
 	if err != nil {
 		r.logger.Error("插入文章失败", "error", err.Error())
-		return utils.ErrDatabaseInsert
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseInsert, err)
 	}
 
 	articleID, err := result.LastInsertId()
 	if err != nil {
 		r.logger.Error("获取文章ID失败", "error", err.Error())
-		return utils.ErrDatabaseInsert
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseInsert, err)
 	}
 	article.ID = uint(articleID)
 
@@ -81,7 +111,7 @@ func (r *ArticleRepository) CreateArticle(ctx context.Context, article *models.A
 		_, err := tx.ExecContext(ctx, blockQuery, blockArgs...)
 		if err != nil {
 			r.logger.Error("批量插入代码块失败", "error", err.Error())
-			return utils.ErrDatabaseInsert
+			return fmt.Errorf("%w: %w", utils.ErrDatabaseInsert, err)
 		}
 	}
 
@@ -101,7 +131,7 @@ func (r *ArticleRepository) CreateArticle(ctx context.Context, article *models.A
 		_, err := tx.ExecContext(ctx, catQuery, catArgs...)
 		if err != nil {
 			r.logger.Error("批量关联分类失败", "error", err.Error())
-			return utils.ErrDatabaseInsert
+			return fmt.Errorf("%w: %w", utils.ErrDatabaseInsert, err)
 		}
 
 		// 批量更新分类文章计数
@@ -129,7 +159,7 @@ func (r *ArticleRepository) CreateArticle(ctx context.Context, article *models.A
 		_, err := tx.ExecContext(ctx, tagQuery, tagArgs...)
 		if err != nil {
 			r.logger.Error("批量关联标签失败", "error", err.Error())
-			return utils.ErrDatabaseInsert
+			return fmt.Errorf("%w: %w", utils.ErrDatabaseInsert, err)
 		}
 
 		// 批量更新标签文章计数
@@ -144,9 +174,11 @@ func (r *ArticleRepository) CreateArticle(ctx context.Context, article *models.A
 	// 提交事务
 	if err := tx.Commit(); err != nil {
 		r.logger.Error("提交事务失败", "error", err.Error())
-		return utils.ErrDatabaseQuery
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
 	}
 
+	r.invalidateArticleLists()
+
 	r.logger.Info("创建文章成功",
 		"articleID", article.ID,
 		"userID", article.UserID,
@@ -165,7 +197,7 @@ func (r *ArticleRepository) GetArticleByID(ctx context.Context, articleID uint,
 	query := `
 		SELECT 
 			a.id, a.user_id, a.title, a.description, a.content, 
-			a.status, a.view_count, a.like_count, a.comment_count, 
+			a.status, a.view_count, a.like_count, a.comment_count, a.version,
 			a.created_at, a.updated_at,
 			ua.username, 
 			COALESCE(up.nickname, ua.username) as nickname, 
@@ -182,7 +214,7 @@ func (r *ArticleRepository) GetArticleByID(ctx context.Context, articleID uint,
 
 	err := r.db.DB.QueryRowContext(ctx, query, articleID).Scan(
 		&article.ID, &article.UserID, &article.Title, &article.Description, &article.Content,
-		&article.Status, &article.ViewCount, &article.LikeCount, &article.CommentCount,
+		&article.Status, &article.ViewCount, &article.LikeCount, &article.CommentCount, &article.Version,
 		&article.CreatedAt, &article.UpdatedAt,
 		&authorUsername, &authorNickname, &authorAvatar)
 
@@ -191,7 +223,7 @@ func (r *ArticleRepository) GetArticleByID(ctx context.Context, articleID uint,
 			return nil, utils.ErrUserNotFound
 		}
 		r.logger.Error("查询文章失败", "error", err.Error())
-		return nil, utils.ErrDatabaseQuery
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
 	}
 
 	authorID = article.UserID
@@ -211,91 +243,29 @@ func (r *ArticleRepository) GetArticleByID(ctx context.Context, articleID uint,
 	}
 
 	// 第二步：并行获取其他信息（代码块、分类、标签、点赞状态）
-	// 使用goroutine并行查询，减少总耗时
-	type queryResult struct {
-		codeBlocks []models.ArticleCodeBlock
-		categories []models.ArticleCategory
-		tags       []models.ArticleTag
-		isLiked    bool
-	}
-
-	resultChan := make(chan queryResult, 1)
-
-	go func() {
-		result := queryResult{}
-
-		// 创建子context，设置超时
-		subCtx, cancel := context.WithTimeout(ctx, r.db.GetAsyncTaskTimeout())
-		defer cancel()
-
-		// 并行查询多个关联数据（使用子goroutine）
-		var (
-			codeBlocksChan = make(chan []models.ArticleCodeBlock, 1)
-			categoriesChan = make(chan []models.ArticleCategory, 1)
-			tagsChan       = make(chan []models.ArticleTag, 1)
-			likeChan       = make(chan bool, 1)
-			errChan        = make(chan error, 4)
-		)
-
-		// 查询代码块
-		go func() {
-			blocks, err := r.getCodeBlocks(subCtx, articleID)
-			if err != nil {
-				errChan <- err
-				codeBlocksChan <- make([]models.ArticleCodeBlock, 0)
-			} else {
-				codeBlocksChan <- blocks
-			}
-		}()
-
-		// 查询分类（使用JOIN优化）
-		go func() {
-			cats, err := r.getCategoriesByArticleID(subCtx, articleID)
-			if err != nil {
-				errChan <- err
-				categoriesChan <- make([]models.ArticleCategory, 0)
-			} else {
-				categoriesChan <- cats
-			}
-		}()
-
-		// 查询标签（使用JOIN优化）
-		go func() {
-			tags, err := r.getTagsByArticleID(subCtx, articleID)
-			if err != nil {
-				errChan <- err
-				tagsChan <- make([]models.ArticleTag, 0)
-			} else {
-				tagsChan <- tags
-			}
-		}()
-
-		// 查询点赞状态
-		go func() {
-			if userID > 0 {
-				liked := r.checkArticleLike(subCtx, articleID, userID)
-				likeChan <- liked
-			} else {
-				likeChan <- false
-			}
-		}()
-
-		// 收集结果
-		result.codeBlocks = <-codeBlocksChan
-		result.categories = <-categoriesChan
-		result.tags = <-tagsChan
-		result.isLiked = <-likeChan
-
-		resultChan <- result
-	}()
-
-	// 等待结果
-	result := <-resultChan
+	relations := loadArticleRelations(ctx, r.db.GetAsyncTaskTimeout(), articleRelationLoaders{
+		codeBlocks: func(ctx context.Context) ([]models.ArticleCodeBlock, error) {
+			return r.getCodeBlocks(ctx, articleID)
+		},
+		categories: func(ctx context.Context) ([]models.ArticleCategory, error) {
+			return r.getCategoriesByArticleID(ctx, articleID)
+		},
+		tags: func(ctx context.Context) ([]models.ArticleTag, error) {
+			return r.getTagsByArticleID(ctx, articleID)
+		},
+		isLiked: func(ctx context.Context) bool {
+			return userID > 0 && r.checkArticleLike(ctx, articleID, userID)
+		},
+	})
 
-	response.CodeBlocks = result.codeBlocks
-	response.Categories = result.categories
-	response.Tags = result.tags
-	response.IsLiked = result.isLiked
+	response.CodeBlocks = relations.codeBlocks
+	response.Categories = relations.categories
+	response.Tags = relations.tags
+	response.IsLiked = relations.isLiked
+	if relations.err != nil {
+		response.Partial = true
+		r.logger.Warn("文章关联数据加载失败，以空结果降级", "articleID", articleID, "error", relations.err.Error())
+	}
 
 	duration := time.Since(start)
 	r.logger.Info("获取文章详情成功（优化版）",
@@ -342,42 +312,81 @@ func (r *ArticleRepository) ListArticles(ctx context.Context, query models.Artic
 		args = append(args, query.UserID)
 	}
 
+	// 关注流只查询关注用户的文章（作者列表由调用方按上限截取）
+	if query.Feed == models.ArticleFeedFollowing {
+		if len(query.AuthorIDs) == 0 {
+			return &models.ArticleListResponse{
+				Articles: make([]models.ArticleListItem, 0),
+				Page:     query.Page,
+				PageSize: query.PageSize,
+			}, nil
+		}
+		conditions = append(conditions, "a.user_id IN (?"+strings.Repeat(",?", len(query.AuthorIDs)-1)+")")
+		for _, id := range query.AuthorIDs {
+			args = append(args, id)
+		}
+	}
+
+	// 过滤当前用户屏蔽的作者（其他用户不受影响）
+	if query.ViewerID > 0 {
+		conditions = append(conditions, "NOT EXISTS (SELECT 1 FROM user_blocks ub WHERE ub.blocker_id = ? AND ub.blocked_id = a.user_id)")
+		args = append(args, query.ViewerID)
+	}
+
 	if query.Keyword != "" {
 		conditions = append(conditions, "(a.title LIKE ? OR a.description LIKE ? OR a.content LIKE ?)")
 		keyword := "%" + query.Keyword + "%"
 		args = append(args, keyword, keyword, keyword)
 	}
 
+	// 趋势排序的衰减热度无法使用索引，只在最近N天发布的文章中计算，控制扫描范围
+	if query.SortBy == "trending" {
+		conditions = append(conditions, "a.created_at >= UTC_TIMESTAMP() - INTERVAL ? DAY")
+		args = append(args, r.config.StatisticsQueryExtended.DefaultDateRangeDays)
+	}
+
 	whereClause := ""
 	if len(conditions) > 0 {
 		whereClause = "WHERE " + strings.Join(conditions, " AND ")
 	}
 
-	// 排序
+	// 排序（热门和最受欢迎排序对低质量文章降权，最新排序保持时间顺序）
 	orderBy := "a.created_at DESC"
+	scoreColumn := "NULL"
+	var orderArgs, scoreArgs []interface{}
 	switch query.SortBy {
+	case "trending":
+		demotion, demotionArgs := articleDemotionExpr(r.config.Demotion)
+		scoreColumn = hotScoreExpr + " * " + demotion
+		scoreArgs = append([]interface{}{r.config.Cache.HotArticles.DecayGravity}, demotionArgs...)
+		orderBy = "score DESC, a.id DESC"
 	case "hot":
-		orderBy = "a.like_count DESC, a.view_count DESC, a.created_at DESC"
+		demotion, demotionArgs := articleDemotionExpr(r.config.Demotion)
+		orderBy = "a.like_count * " + demotion + " DESC, a.view_count DESC, a.created_at DESC"
+		orderArgs = demotionArgs
 	case "popular":
-		orderBy = "a.view_count DESC, a.like_count DESC, a.created_at DESC"
+		demotion, demotionArgs := articleDemotionExpr(r.config.Demotion)
+		orderBy = "a.view_count * " + demotion + " DESC, a.like_count DESC, a.created_at DESC"
+		orderArgs = demotionArgs
 	}
 
 	// 并行执行COUNT和列表查询（优化性能）
 	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM articles a %s", whereClause)
 	listQuery := fmt.Sprintf(`
 		SELECT a.id, a.user_id, a.title, a.description, a.view_count, a.like_count, a.comment_count, a.created_at, a.updated_at,
-			   ua.username, COALESCE(up.nickname, ua.username) as nickname, COALESCE(up.avatar_url, '') as avatar
+			   ua.username, COALESCE(up.nickname, ua.username) as nickname, COALESCE(up.avatar_url, '') as avatar,
+			   %s AS score
 		FROM articles a
 		INNER JOIN user_auth ua ON a.user_id = ua.id
 		LEFT JOIN user_profile up ON ua.id = up.user_id
 		%s
 		ORDER BY %s
-		LIMIT ? OFFSET ?`, whereClause, orderBy)
+		LIMIT ? OFFSET ?`, scoreColumn, whereClause, orderBy)
 
-	// 准备参数
+	// 准备参数（SELECT 中的热度分参数在 WHERE 参数之前）
 	countArgs := make([]interface{}, len(args))
 	copy(countArgs, args)
-	listArgs := append(args, query.PageSize, offset)
+	listArgs := append(append(append(scoreArgs, args...), orderArgs...), query.PageSize, offset)
 
 	// 并行查询
 	type countResult struct {
@@ -392,18 +401,19 @@ func (r *ArticleRepository) ListArticles(ctx context.Context, query models.Artic
 	countChan := make(chan countResult, 1)
 	listChan := make(chan listResult, 1)
 
-	// 并行执行COUNT
-	go func() {
-		var total int
-		err := r.db.DB.QueryRowContext(ctx, countQuery, countArgs...).Scan(&total)
-		countChan <- countResult{total: total, err: err}
-	}()
-
-	// 并行执行列表查询
-	go func() {
-		rows, err := r.db.DB.QueryContext(ctx, listQuery, listArgs...)
-		listChan <- listResult{rows: rows, err: err}
-	}()
+	utils.Parallel(ctx,
+		// 并行执行COUNT
+		func() {
+			var total int
+			err := r.db.DB.QueryRowContext(ctx, countQuery, countArgs...).Scan(&total)
+			countChan <- countResult{total: total, err: err}
+		},
+		// 并行执行列表查询
+		func() {
+			rows, err := r.db.DB.QueryContext(ctx, listQuery, listArgs...)
+			listChan <- listResult{rows: rows, err: err}
+		},
+	)
 
 	// 收集结果
 	countRes := <-countChan
@@ -414,12 +424,12 @@ func (r *ArticleRepository) ListArticles(ctx context.Context, query models.Artic
 			listRes.rows.Close()
 		}
 		r.logger.Error("查询文章总数失败", "error", countRes.err.Error())
-		return nil, utils.ErrDatabaseQuery
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, countRes.err)
 	}
 
 	if listRes.err != nil {
 		r.logger.Error("查询文章列表失败", "error", listRes.err.Error())
-		return nil, utils.ErrDatabaseQuery
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, listRes.err)
 	}
 
 	total := countRes.total
@@ -438,14 +448,18 @@ func (r *ArticleRepository) ListArticles(ctx context.Context, query models.Artic
 		item.Categories = make([]models.ArticleCategory, 0)
 		item.Tags = make([]models.ArticleTag, 0)
 
+		var score sql.NullFloat64
 		err := rows.Scan(
 			&item.ID, &item.Author.ID, &item.Title, &item.Description,
 			&item.ViewCount, &item.LikeCount, &item.CommentCount,
 			&item.CreatedAt, &item.UpdatedAt,
-			&item.Author.Username, &item.Author.Nickname, &item.Author.Avatar)
+			&item.Author.Username, &item.Author.Nickname, &item.Author.Avatar, &score)
 		if err != nil {
 			continue
 		}
+		if score.Valid {
+			item.Score = &score.Float64
+		}
 
 		articleIDs = append(articleIDs, item.ID)
 		articles = append(articles, item)
@@ -519,31 +533,42 @@ func (r *ArticleRepository) ListArticles(ctx context.Context, query models.Artic
 }
 
 // UpdateArticle 更新文章
-func (r *ArticleRepository) UpdateArticle(ctx context.Context, articleID, userID uint, req models.UpdateArticleRequest) error {
+func (r *ArticleRepository) UpdateArticle(ctx context.Context, articleID, userID uint, req models.UpdateArticleRequest) (int, error) {
 	start := time.Now().UTC()
 
-	// 检查文章是否存在且属于当前用户
-	checkQuery := `SELECT user_id FROM articles WHERE id = ? AND status != 2`
+	// 检查文章是否存在且属于当前用户，版本号不一致时直接返回冲突（提交时还会在UPDATE中再次校验）
+	checkQuery := `SELECT user_id, version FROM articles WHERE id = ? AND status != 2`
 	var ownerID uint
-	err := r.db.DB.QueryRowContext(ctx, checkQuery, articleID).Scan(&ownerID)
+	var currentVersion int
+	err := r.db.DB.QueryRowContext(ctx, checkQuery, articleID).Scan(&ownerID, &currentVersion)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return utils.ErrUserNotFound
+			return 0, utils.ErrUserNotFound
 		}
-		return utils.ErrDatabaseQuery
+		return 0, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
 	}
 	if ownerID != userID {
-		return utils.ErrUnauthorized
+		return 0, utils.ErrUnauthorized
+	}
+	if currentVersion != req.Version {
+		return 0, utils.ErrVersionConflict
 	}
 
 	// 开启事务
 	tx, err := r.db.DB.BeginTx(ctx, nil)
 	if err != nil {
-		return utils.ErrDatabaseQuery
+		return 0, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
 	}
 	defer tx.Rollback()
 
-	// 构建更新语句
+	// 标题/描述/正文有变化时先保存修改前的版本
+	if req.Title != nil || req.Description != nil || req.Content != nil {
+		if err := r.saveRevision(ctx, tx, articleID, userID, req); err != nil {
+			return 0, err
+		}
+	}
+
+	// 构建更新语句（任何修改都会递增版本号，只修改代码块、分类或标签时也一样）
 	var updates []string
 	var args []interface{}
 
@@ -564,17 +589,18 @@ func (r *ArticleRepository) UpdateArticle(ctx context.Context, articleID, userID
 		args = append(args, *req.Status)
 	}
 
-	if len(updates) > 0 {
-		updates = append(updates, "updated_at = ?")
-		args = append(args, time.Now().UTC())
-		args = append(args, articleID)
+	updates = append(updates, "version = version + 1", "updated_at = ?")
+	args = append(args, time.Now().UTC(), articleID, userID, req.Version)
 
-		updateQuery := fmt.Sprintf("UPDATE articles SET %s WHERE id = ?", strings.Join(updates, ", "))
-		_, err := tx.ExecContext(ctx, updateQuery, args...)
-		if err != nil {
-			r.logger.Error("更新文章失败", "error", err.Error())
-			return utils.ErrDatabaseUpdate
-		}
+	updateQuery := fmt.Sprintf("UPDATE articles SET %s WHERE id = ? AND user_id = ? AND status != 2 AND version = ?", strings.Join(updates, ", "))
+	result, err := tx.ExecContext(ctx, updateQuery, args...)
+	if err != nil {
+		r.logger.Error("更新文章失败", "error", err.Error())
+		return 0, fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		// 检查之后被其他请求修改、删除或转移
+		return 0, r.updateConflictReason(ctx, articleID, userID)
 	}
 
 	// 更新代码块（先删除再批量插入）
@@ -595,7 +621,7 @@ func (r *ArticleRepository) UpdateArticle(ctx context.Context, articleID, userID
 			_, err := tx.ExecContext(ctx, blockQuery, blockArgs...)
 			if err != nil {
 				r.logger.Error("批量插入代码块失败", "error", err.Error())
-				return utils.ErrDatabaseInsert
+				return 0, fmt.Errorf("%w: %w", utils.ErrDatabaseInsert, err)
 			}
 		}
 	}
@@ -644,13 +670,167 @@ func (r *ArticleRepository) UpdateArticle(ctx context.Context, articleID, userID
 
 	if err := tx.Commit(); err != nil {
 		r.logger.Error("提交事务失败", "error", err.Error())
-		return utils.ErrDatabaseQuery
+		return 0, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+	}
+
+	r.invalidateArticle(articleID)
+	if req.CategoryIDs != nil || req.TagIDs != nil || req.Status != nil {
+		r.invalidateArticleLists()
+	}
+
+	newVersion := req.Version + 1
+	r.logger.Info("更新文章成功", "articleID", articleID, "version", newVersion, "duration", time.Since(start))
+	return newVersion, nil
+}
+
+// updateConflictReason 版本校验的UPDATE未命中时判断原因：文章已删除、已不属于当前用户，或版本已变化
+func (r *ArticleRepository) updateConflictReason(ctx context.Context, articleID, userID uint) error {
+	var ownerID uint
+	err := r.db.DB.QueryRowContext(ctx,
+		`SELECT user_id FROM articles WHERE id = ? AND status != 2`, articleID).Scan(&ownerID)
+	switch {
+	case err == sql.ErrNoRows:
+		return utils.ErrResourceNotFound
+	case err != nil:
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+	case ownerID != userID:
+		return utils.ErrUnauthorized
+	default:
+		return utils.ErrVersionConflict
+	}
+}
+
+// saveRevision 在事务内保存文章修改前的标题、描述和正文（内容没有变化时不保存）
+func (r *ArticleRepository) saveRevision(ctx context.Context, tx *sql.Tx, articleID, userID uint, req models.UpdateArticleRequest) error {
+	var title, description, content string
+	err := tx.QueryRowContext(ctx,
+		`SELECT title, COALESCE(description, ''), content FROM articles WHERE id = ? FOR UPDATE`,
+		articleID).Scan(&title, &description, &content)
+	if err != nil {
+		r.logger.Error("读取文章当前版本失败", "articleID", articleID, "error", err.Error())
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+	}
+
+	changed := (req.Title != nil && *req.Title != title) ||
+		(req.Description != nil && *req.Description != description) ||
+		(req.Content != nil && *req.Content != content)
+	if !changed {
+		return nil
 	}
 
-	r.logger.Info("更新文章成功", "articleID", articleID, "duration", time.Since(start))
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO article_revisions (article_id, user_id, title, description, content, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		articleID, userID, title, description, content, time.Now().UTC()); err != nil {
+		r.logger.Error("保存文章修订失败", "articleID", articleID, "error", err.Error())
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseInsert, err)
+	}
 	return nil
 }
 
+// ListRevisions 获取文章的修订列表（按时间倒序，不含正文）
+func (r *ArticleRepository) ListRevisions(ctx context.Context, articleID uint) ([]models.ArticleRevision, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetQueryTimeout())
+	defer cancel()
+
+	if _, err := r.getRevision(ctx, articleID, models.ArticleRevisionCurrent); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.DB.QueryContext(ctx,
+		`SELECT id, article_id, user_id, title, COALESCE(description, ''), created_at
+		 FROM article_revisions WHERE article_id = ? ORDER BY id DESC`, articleID)
+	if err != nil {
+		r.logger.Error("查询文章修订列表失败", "articleID", articleID, "error", err.Error())
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+	}
+	defer rows.Close()
+
+	revisions := make([]models.ArticleRevision, 0)
+	for rows.Next() {
+		var rev models.ArticleRevision
+		if err := rows.Scan(&rev.ID, &rev.ArticleID, &rev.UserID, &rev.Title, &rev.Description, &rev.CreatedAt); err != nil {
+			r.logger.Warn("扫描文章修订失败", "error", err.Error())
+			continue
+		}
+		revisions = append(revisions, rev)
+	}
+	return revisions, nil
+}
+
+// getRevision 获取文章的某个修订（revisionID 为0时返回当前线上版本）
+func (r *ArticleRepository) getRevision(ctx context.Context, articleID, revisionID uint) (*models.ArticleRevision, error) {
+	rev := &models.ArticleRevision{ID: revisionID, ArticleID: articleID}
+
+	var err error
+	if revisionID == models.ArticleRevisionCurrent {
+		err = r.db.DB.QueryRowContext(ctx,
+			`SELECT user_id, title, COALESCE(description, ''), content, updated_at FROM articles WHERE id = ? AND status != 2`,
+			articleID).Scan(&rev.UserID, &rev.Title, &rev.Description, &rev.Content, &rev.CreatedAt)
+	} else {
+		err = r.db.DB.QueryRowContext(ctx,
+			`SELECT ar.user_id, ar.title, COALESCE(ar.description, ''), ar.content, ar.created_at
+			 FROM article_revisions ar
+			 INNER JOIN articles a ON a.id = ar.article_id AND a.status != 2
+			 WHERE ar.id = ? AND ar.article_id = ?`,
+			revisionID, articleID).Scan(&rev.UserID, &rev.Title, &rev.Description, &rev.Content, &rev.CreatedAt)
+	}
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, utils.NewAppError(utils.ErrResourceNotFound, "文章或修订版本不存在", 404)
+		}
+		r.logger.Error("查询文章修订失败", "articleID", articleID, "revisionID", revisionID, "error", err.Error())
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+	}
+	return rev, nil
+}
+
+// DiffRevisions 比较文章的两个版本（修订ID为0表示当前线上版本）
+// 正文按行比较，标题和描述按字段比较
+func (r *ArticleRepository) DiffRevisions(ctx context.Context, articleID, fromRevisionID, toRevisionID uint) (*models.ArticleRevisionDiff, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetQueryTimeout())
+	defer cancel()
+
+	from, err := r.getRevision(ctx, articleID, fromRevisionID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := r.getRevision(ctx, articleID, toRevisionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return BuildRevisionDiff(from, to), nil
+}
+
+// BuildRevisionDiff 计算两个文章版本之间的差异
+func BuildRevisionDiff(from, to *models.ArticleRevision) *models.ArticleRevisionDiff {
+	diff := &models.ArticleRevisionDiff{
+		ArticleID:      from.ArticleID,
+		FromRevisionID: from.ID,
+		ToRevisionID:   to.ID,
+		Lines:          utils.DiffLines(from.Content, to.Content),
+	}
+	if from.Title != to.Title {
+		diff.Title = &models.FieldDiff{From: from.Title, To: to.Title}
+	}
+	if from.Description != to.Description {
+		diff.Description = &models.FieldDiff{From: from.Description, To: to.Description}
+	}
+
+	for _, line := range diff.Lines {
+		switch line.Op {
+		case models.DiffOpAdd:
+			diff.Added++
+		case models.DiffOpRemove:
+			diff.Removed++
+		case models.DiffOpChange:
+			diff.Changed++
+		}
+	}
+	diff.Identical = len(diff.Lines) == 0 && diff.Title == nil && diff.Description == nil
+	return diff
+}
+
 // DeleteArticle 删除文章（软删除）
 func (r *ArticleRepository) DeleteArticle(ctx context.Context, articleID, userID uint) error {
 	start := time.Now().UTC()
@@ -663,7 +843,7 @@ func (r *ArticleRepository) DeleteArticle(ctx context.Context, articleID, userID
 		if err == sql.ErrNoRows {
 			return utils.ErrUserNotFound
 		}
-		return utils.ErrDatabaseQuery
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
 	}
 	if ownerID != userID {
 		return utils.ErrUnauthorized
@@ -674,13 +854,180 @@ func (r *ArticleRepository) DeleteArticle(ctx context.Context, articleID, userID
 	_, err = r.db.DB.ExecContext(ctx, query, time.Now().UTC(), articleID)
 	if err != nil {
 		r.logger.Error("删除文章失败", "error", err.Error())
-		return utils.ErrDatabaseUpdate
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
 	}
 
+	r.invalidateArticle(articleID)
+	r.invalidateArticleLists()
+
 	r.logger.Info("删除文章成功", "articleID", articleID, "duration", time.Since(start))
 	return nil
 }
 
+// AdminBulkUpdateStatus 管理员批量修改文章状态（一条语句更新，已处于目标状态的文章跳过）
+// 涉及删除或恢复时，按未删除文章的关联数重算受影响分类和标签的文章数
+func (r *ArticleRepository) AdminBulkUpdateStatus(ctx context.Context, articleIDs []uint, status int, moderatorID uint) (*models.BulkArticleStatusResult, error) {
+	start := time.Now().UTC()
+	result := &models.BulkArticleStatusResult{
+		Status:     status,
+		UpdatedIDs: make([]uint, 0),
+		SkippedIDs: make([]uint, 0),
+		MissingIDs: make([]uint, 0),
+	}
+	if len(articleIDs) == 0 {
+		return result, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
+	defer cancel()
+
+	err := r.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		placeholders, args := inPlaceholders(articleIDs)
+		rows, err := tx.QueryContext(ctx,
+			`SELECT id, status FROM articles WHERE id IN (`+placeholders+`) FOR UPDATE`, args...)
+		if err != nil {
+			return err
+		}
+		current := make(map[uint]int, len(articleIDs))
+		for rows.Next() {
+			var id uint
+			var st int
+			if err := rows.Scan(&id, &st); err != nil {
+				rows.Close()
+				return err
+			}
+			current[id] = st
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, id := range articleIDs {
+			st, ok := current[id]
+			switch {
+			case !ok:
+				result.MissingIDs = append(result.MissingIDs, id)
+			case st == status:
+				result.SkippedIDs = append(result.SkippedIDs, id)
+			default:
+				result.UpdatedIDs = append(result.UpdatedIDs, id)
+			}
+		}
+
+		// 只有删除和恢复会改变未删除文章数，草稿/发布之间切换不影响计数
+		var recount []uint
+		for _, id := range result.UpdatedIDs {
+			if (current[id] == 2) != (status == 2) {
+				recount = append(recount, id)
+			}
+		}
+		return r.applyArticleStatus(ctx, tx, result.UpdatedIDs, recount, status, start)
+	})
+	if err != nil {
+		r.logger.Error("批量修改文章状态失败", "moderatorID", moderatorID, "status", status, "error", err.Error())
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
+	}
+
+	r.invalidateStatusChange(result.UpdatedIDs)
+	r.logger.Info("批量修改文章状态成功", "moderatorID", moderatorID, "status", status,
+		"updated", len(result.UpdatedIDs), "skipped", len(result.SkippedIDs), "missing", len(result.MissingIDs),
+		"duration", time.Since(start))
+	return result, nil
+}
+
+// AdminDeleteByUser 管理员软删除某个用户的全部文章，返回被删除的文章ID
+func (r *ArticleRepository) AdminDeleteByUser(ctx context.Context, targetUserID uint) ([]uint, error) {
+	start := time.Now().UTC()
+
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
+	defer cancel()
+
+	articleIDs := make([]uint, 0)
+	err := r.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx,
+			`SELECT id FROM articles WHERE user_id = ? AND status != 2 FOR UPDATE`, targetUserID)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var id uint
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			articleIDs = append(articleIDs, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return r.applyArticleStatus(ctx, tx, articleIDs, articleIDs, 2, start)
+	})
+	if err != nil {
+		r.logger.Error("删除用户文章失败", "targetUserID", targetUserID, "error", err.Error())
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
+	}
+
+	r.invalidateStatusChange(articleIDs)
+	r.logger.Info("删除用户文章成功", "targetUserID", targetUserID, "count", len(articleIDs), "duration", time.Since(start))
+	return articleIDs, nil
+}
+
+// applyArticleStatus 在事务中修改文章状态，并重算 recountIDs 关联的分类和标签的文章数（不含已删除文章）
+func (r *ArticleRepository) applyArticleStatus(ctx context.Context, tx *sql.Tx, articleIDs, recountIDs []uint, status int, now time.Time) error {
+	if len(articleIDs) == 0 {
+		return nil
+	}
+
+	placeholders, args := inPlaceholders(articleIDs)
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE articles SET status = ?, updated_at = ? WHERE id IN (`+placeholders+`)`,
+		append([]interface{}{status, now}, args...)...); err != nil {
+		return err
+	}
+	if len(recountIDs) == 0 {
+		return nil
+	}
+
+	placeholders, args = inPlaceholders(recountIDs)
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE article_categories c
+		 JOIN (SELECT DISTINCT category_id FROM article_category_relations WHERE article_id IN (`+placeholders+`)) affected
+		   ON affected.category_id = c.id
+		 SET c.article_count = (
+		   SELECT COUNT(*) FROM article_category_relations rel
+		   JOIN articles a ON a.id = rel.article_id
+		   WHERE rel.category_id = c.id AND a.status != 2)`, args...); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx,
+		`UPDATE article_tags t
+		 JOIN (SELECT DISTINCT tag_id FROM article_tag_relations WHERE article_id IN (`+placeholders+`)) affected
+		   ON affected.tag_id = t.id
+		 SET t.article_count = (
+		   SELECT COUNT(*) FROM article_tag_relations rel
+		   JOIN articles a ON a.id = rel.article_id
+		   WHERE rel.tag_id = t.id AND a.status != 2)`, args...)
+	return err
+}
+
+// invalidateStatusChange 状态修改后失效文章详情和列表缓存
+func (r *ArticleRepository) invalidateStatusChange(articleIDs []uint) {
+	if len(articleIDs) == 0 {
+		return
+	}
+	for _, id := range articleIDs {
+		r.invalidateArticle(id)
+	}
+	r.invalidateArticleLists()
+}
+
+// GetArticleLikers 分页获取文章的点赞用户（按点赞时间倒序）
+func (r *ArticleRepository) GetArticleLikers(ctx context.Context, articleID uint, page, pageSize int) (*models.LikersResponse, error) {
+	return listLikers(ctx, r.db, r.logger, "article_likes", articleID, page, pageSize)
+}
+
 // ToggleArticleLike 切换文章点赞
 func (r *ArticleRepository) ToggleArticleLike(ctx context.Context, articleID, userID uint) (bool, error) {
 	start := time.Now().UTC()
@@ -698,7 +1045,10 @@ func (r *ArticleRepository) ToggleArticleLike(ctx context.Context, articleID, us
 		_, err := r.db.DB.ExecContext(ctx, insertQuery, articleID, userID, time.Now().UTC())
 		if err != nil {
 			r.logger.Error("点赞失败", "error", err.Error())
-			return false, utils.ErrDatabaseInsert
+			if isDuplicateKeyError(err) {
+				return false, duplicateEntryError("已经点过赞了")
+			}
+			return false, fmt.Errorf("%w: %w", utils.ErrDatabaseInsert, err)
 		}
 		// 更新文章点赞数
 		_, _ = r.db.DB.ExecContext(ctx, `UPDATE articles SET like_count = like_count + 1 WHERE id = ?`, articleID)
@@ -709,15 +1059,18 @@ func (r *ArticleRepository) ToggleArticleLike(ctx context.Context, articleID, us
 		_, err := r.db.DB.ExecContext(ctx, deleteQuery, articleID, userID)
 		if err != nil {
 			r.logger.Error("取消点赞失败", "error", err.Error())
-			return false, utils.ErrDatabaseUpdate
+			return false, fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
 		}
 		// 更新文章点赞数
 		_, _ = r.db.DB.ExecContext(ctx, `UPDATE articles SET like_count = GREATEST(like_count - 1, 0) WHERE id = ?`, articleID)
 		isLiked = false
 	default:
-		return false, utils.ErrDatabaseQuery
+		return false, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
 	}
 
+	// 点赞数已变化
+	r.invalidateArticle(articleID)
+
 	r.logger.Info("切换文章点赞成功", "articleID", articleID, "userID", userID, "isLiked", isLiked, "duration", time.Since(start))
 	return isLiked, nil
 }
@@ -728,7 +1081,7 @@ func (r *ArticleRepository) IncrementViewCount(ctx context.Context, articleID ui
 	_, err := r.db.DB.ExecContext(ctx, query, articleID)
 	if err != nil {
 		r.logger.Error("增加浏览次数失败", "articleID", articleID, "error", err.Error())
-		return utils.ErrDatabaseUpdate
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
 	}
 	return nil
 }
@@ -748,7 +1101,7 @@ func (r *ArticleRepository) CreateComment(ctx context.Context, comment *models.A
 				if err == sql.ErrNoRows {
 					return utils.ErrUserNotFound
 				}
-				return utils.ErrDatabaseQuery
+				return fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
 			}
 			comment.RootID = rootID
 		}
@@ -762,7 +1115,7 @@ func (r *ArticleRepository) CreateComment(ctx context.Context, comment *models.A
 		comment.ReplyToUserID, comment.Content, comment.CreatedAt, comment.UpdatedAt)
 	if err != nil {
 		r.logger.Error("插入评论失败", "error", err.Error())
-		return utils.ErrDatabaseInsert
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseInsert, err)
 	}
 
 	commentID, _ := result.LastInsertId()
@@ -776,13 +1129,24 @@ func (r *ArticleRepository) CreateComment(ctx context.Context, comment *models.A
 		_, _ = r.db.DB.ExecContext(ctx, `UPDATE article_comments SET reply_count = reply_count + 1 WHERE id = ?`, comment.ParentID)
 	}
 
+	// 评论数已变化
+	r.invalidateArticle(comment.ArticleID)
+
 	r.logger.Info("创建评论成功", "commentID", comment.ID, "articleID", comment.ArticleID, "duration", time.Since(start))
 	return nil
 }
 
-// GetComments 获取评论列表
-func (r *ArticleRepository) GetComments(ctx context.Context, articleID uint, page, pageSize int, userID uint) (*models.CommentsResponse, error) {
+// commentOrderBy 一级评论的排序子句（末尾按ID排序，保证分页时顺序稳定）
+var commentOrderBy = map[string]string{
+	models.CommentSortNewest: "ac.created_at DESC, ac.id DESC",
+	models.CommentSortOldest: "ac.created_at ASC, ac.id ASC",
+	models.CommentSortTop:    "ac.like_count DESC, ac.created_at DESC, ac.id DESC",
+}
+
+// GetComments 获取评论列表（sort 见 models.CommentSort*，无效值按 newest 处理）
+func (r *ArticleRepository) GetComments(ctx context.Context, articleID uint, page, pageSize int, userID uint, sort string) (*models.CommentsResponse, error) {
 	start := time.Now().UTC()
+	sort = models.NormalizeCommentSort(sort)
 
 	if page <= 0 {
 		page = 1
@@ -793,15 +1157,19 @@ func (r *ArticleRepository) GetComments(ctx context.Context, articleID uint, pag
 	offset := (page - 1) * pageSize
 
 	// 并行执行COUNT和评论列表查询
-	countQuery := `SELECT COUNT(*) FROM article_comments WHERE article_id = ? AND parent_id = 0 AND status = 1`
+	// 当前用户屏蔽的作者发表的评论不返回（未登录时 userID=0 不会命中）
+	countQuery := `SELECT COUNT(*) FROM article_comments ac
+				   WHERE ac.article_id = ? AND ac.parent_id = 0 AND ac.status = 1
+				   AND NOT EXISTS (SELECT 1 FROM user_blocks ub WHERE ub.blocker_id = ? AND ub.blocked_id = ac.user_id)`
 	listQuery := `SELECT ac.id, ac.article_id, ac.user_id, ac.parent_id, ac.root_id, ac.reply_to_user_id, ac.content, 
-					 ac.like_count, ac.reply_count, ac.status, ac.created_at, ac.updated_at,
+					 ac.like_count, ac.reply_count, ac.status, ac.is_edited, ac.created_at, ac.updated_at,
 					 ua.username, COALESCE(up.nickname, ua.username) as nickname, COALESCE(up.avatar_url, '') as avatar
 			  FROM article_comments ac
 			  INNER JOIN user_auth ua ON ac.user_id = ua.id
 			  LEFT JOIN user_profile up ON ua.id = up.user_id
 			  WHERE ac.article_id = ? AND ac.parent_id = 0 AND ac.status = 1
-			  ORDER BY ac.created_at DESC
+			  AND NOT EXISTS (SELECT 1 FROM user_blocks ub WHERE ub.blocker_id = ? AND ub.blocked_id = ac.user_id)
+			  ORDER BY ` + commentOrderBy[sort] + `
 			  LIMIT ? OFFSET ?`
 
 	type countResult struct {
@@ -816,17 +1184,18 @@ func (r *ArticleRepository) GetComments(ctx context.Context, articleID uint, pag
 	countChan := make(chan countResult, 1)
 	listChan := make(chan listResult, 1)
 
-	// 并行执行
-	go func() {
-		var total int
-		err := r.db.DB.QueryRowContext(ctx, countQuery, articleID).Scan(&total)
-		countChan <- countResult{total: total, err: err}
-	}()
-
-	go func() {
-		rows, err := r.db.DB.QueryContext(ctx, listQuery, articleID, pageSize, offset)
-		listChan <- listResult{rows: rows, err: err}
-	}()
+	utils.Parallel(ctx,
+		// 并行执行
+		func() {
+			var total int
+			err := r.db.DB.QueryRowContext(ctx, countQuery, articleID, userID).Scan(&total)
+			countChan <- countResult{total: total, err: err}
+		},
+		func() {
+			rows, err := r.db.DB.QueryContext(ctx, listQuery, articleID, userID, pageSize, offset)
+			listChan <- listResult{rows: rows, err: err}
+		},
+	)
 
 	// 收集结果
 	countRes := <-countChan
@@ -836,12 +1205,12 @@ func (r *ArticleRepository) GetComments(ctx context.Context, articleID uint, pag
 		if listRes.rows != nil {
 			listRes.rows.Close()
 		}
-		return nil, utils.ErrDatabaseQuery
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, countRes.err)
 	}
 
 	if listRes.err != nil {
 		r.logger.Error("查询评论失败", "error", listRes.err.Error())
-		return nil, utils.ErrDatabaseQuery
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, listRes.err)
 	}
 
 	total := countRes.total
@@ -860,7 +1229,7 @@ func (r *ArticleRepository) GetComments(ctx context.Context, articleID uint, pag
 		err := rows.Scan(
 			&comment.ID, &comment.ArticleID, &comment.UserID, &comment.ParentID, &comment.RootID,
 			&comment.ReplyToUserID, &comment.Content, &comment.LikeCount, &comment.ReplyCount,
-			&comment.Status, &comment.CreatedAt, &comment.UpdatedAt,
+			&comment.Status, &comment.IsEdited, &comment.CreatedAt, &comment.UpdatedAt,
 			&comment.Author.Username, &comment.Author.Nickname, &comment.Author.Avatar)
 		if err != nil {
 			continue
@@ -878,6 +1247,7 @@ func (r *ArticleRepository) GetComments(ctx context.Context, articleID uint, pag
 			Page:       page,
 			PageSize:   pageSize,
 			TotalPages: (total + pageSize - 1) / pageSize,
+			Sort:       sort,
 		}, nil
 	}
 
@@ -889,7 +1259,28 @@ func (r *ArticleRepository) GetComments(ctx context.Context, articleID uint, pag
 		}
 	}
 
-	// 第三步：批量获取所有子评论（优化递归N+1）
+	// 第三步：配置了内联回复数时每条评论只返回前几条直接回复，其余由前端分页加载
+	if inline := r.config.Comments.InlineReplies; inline > 0 {
+		pages := r.batchGetReplyPages(ctx, commentIDs, 0, inline, userID)
+		for i := range comments {
+			replyPage := pages[comments[i].ID]
+			comments[i].Replies = replyPage.Replies
+			comments[i].HasMoreReplies = replyPage.HasMore
+			comments[i].RepliesCursor = replyPage.NextCursor
+		}
+
+		r.logger.Info("获取评论列表成功", "articleID", articleID, "total", total, "inlineReplies", inline, "duration", time.Since(start))
+		return &models.CommentsResponse{
+			Comments:   comments,
+			Total:      total,
+			Page:       page,
+			PageSize:   pageSize,
+			TotalPages: (total + pageSize - 1) / pageSize,
+			Sort:       sort,
+		}, nil
+	}
+
+	// 批量获取所有子评论（优化递归N+1）
 	childCommentsMap := r.batchGetChildComments(ctx, articleID, commentIDs, userID)
 	r.logger.Info("批量获取文章子评论", "commentCount", len(commentIDs), "childMapSize", len(childCommentsMap))
 	for i := range comments {
@@ -909,12 +1300,148 @@ func (r *ArticleRepository) GetComments(ctx context.Context, articleID uint, pag
 		Page:       page,
 		PageSize:   pageSize,
 		TotalPages: totalPages,
+		Sort:       sort,
 	}
+	// 读取时限制层级，历史深层回复同样适用
+	response.CapDepth(r.config.Comments.MaxDepth)
 
 	r.logger.Info("获取评论列表成功", "articleID", articleID, "total", total, "duration", time.Since(start))
 	return response, nil
 }
 
+// GetReplies 按ID游标分页获取单条评论的直接回复（afterID=0 表示从第一条开始）
+// 点赞状态和被回复用户只针对本页回复批量查询
+func (r *ArticleRepository) GetReplies(ctx context.Context, rootCommentID, afterID uint, limit int, userID uint) (*models.CommentRepliesResponse, error) {
+	if limit <= 0 || limit > r.config.Pagination.MaxPageSize {
+		limit = r.config.Pagination.DefaultPageSize
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetQueryTimeout())
+	defer cancel()
+
+	var exists bool
+	err := r.db.DB.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM article_comments WHERE id = ? AND status = 1)`, rootCommentID).Scan(&exists)
+	if err != nil {
+		r.logger.Error("查询评论失败", "commentID", rootCommentID, "error", err.Error())
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+	}
+	if !exists {
+		return nil, utils.ErrResourceNotFound
+	}
+
+	page := r.batchGetReplyPages(ctx, []uint{rootCommentID}, afterID, limit, userID)[rootCommentID]
+	return &page, nil
+}
+
+// batchGetReplyPages 批量获取多条评论的一页直接回复（每条父评论最多 limit 条，按ID升序）
+// 每个父评论单独 LIMIT 后用 UNION ALL 合并为一次查询，热门评论的大量回复不会被整体加载
+func (r *ArticleRepository) batchGetReplyPages(ctx context.Context, parentIDs []uint, afterID uint, limit int, userID uint) map[uint]models.CommentRepliesResponse {
+	pages := make(map[uint]models.CommentRepliesResponse, len(parentIDs))
+	for _, id := range parentIDs {
+		pages[id] = models.CommentRepliesResponse{ParentID: id, Replies: make([]models.CommentDetailResponse, 0)}
+	}
+	if len(parentIDs) == 0 {
+		return pages
+	}
+
+	// 多取一条用于判断是否还有更多
+	part := `(SELECT ac.id, ac.article_id, ac.user_id, ac.parent_id, ac.root_id, ac.reply_to_user_id, ac.content,
+					 ac.like_count, ac.reply_count, ac.status, ac.is_edited, ac.created_at, ac.updated_at,
+					 ua.username, COALESCE(up.nickname, ua.username) as nickname, COALESCE(up.avatar_url, '') as avatar
+			  FROM article_comments ac
+			  INNER JOIN user_auth ua ON ac.user_id = ua.id
+			  LEFT JOIN user_profile up ON ua.id = up.user_id
+			  WHERE ac.parent_id = ? AND ac.id > ? AND ac.status = 1
+			  AND NOT EXISTS (SELECT 1 FROM user_blocks ub WHERE ub.blocker_id = ? AND ub.blocked_id = ac.user_id)
+			  ORDER BY ac.id ASC
+			  LIMIT ?)`
+	parts := make([]string, len(parentIDs))
+	args := make([]interface{}, 0, len(parentIDs)*4)
+	for i, id := range parentIDs {
+		parts[i] = part
+		args = append(args, id, afterID, userID, limit+1)
+	}
+
+	rows, err := r.db.DB.QueryContext(ctx, strings.Join(parts, " UNION ALL "), args...)
+	if err != nil {
+		r.logger.Error("批量查询评论回复失败", "parentCount", len(parentIDs), "error", err.Error())
+		return pages
+	}
+	defer rows.Close()
+
+	replies := make([]models.CommentDetailResponse, 0)
+	for rows.Next() {
+		var reply models.CommentDetailResponse
+		reply.Replies = make([]models.CommentDetailResponse, 0)
+		err := rows.Scan(
+			&reply.ID, &reply.ArticleID, &reply.UserID, &reply.ParentID, &reply.RootID,
+			&reply.ReplyToUserID, &reply.Content, &reply.LikeCount, &reply.ReplyCount,
+			&reply.Status, &reply.IsEdited, &reply.CreatedAt, &reply.UpdatedAt,
+			&reply.Author.Username, &reply.Author.Nickname, &reply.Author.Avatar)
+		if err != nil {
+			continue
+		}
+		reply.Author.ID = reply.UserID
+		replies = append(replies, reply)
+	}
+
+	// 按父评论截取一页，多出的一条只用于标记 has_more
+	kept := make([]models.CommentDetailResponse, 0, len(replies))
+	counts := make(map[uint]int, len(parentIDs))
+	for _, reply := range replies {
+		counts[reply.ParentID]++
+		if counts[reply.ParentID] > limit {
+			page := pages[reply.ParentID]
+			page.HasMore = true
+			pages[reply.ParentID] = page
+			continue
+		}
+		kept = append(kept, reply)
+	}
+
+	// 只对本页回复批量查询点赞状态和被回复用户
+	if userID > 0 && len(kept) > 0 {
+		ids := make([]uint, len(kept))
+		for i := range kept {
+			ids[i] = kept[i].ID
+		}
+		likedMap := r.batchCheckCommentLikes(ctx, ids, userID)
+		for i := range kept {
+			kept[i].IsLiked = likedMap[kept[i].ID]
+		}
+	}
+	replyToUserIDs := make([]uint, 0)
+	for i := range kept {
+		if kept[i].ReplyToUserID != nil && *kept[i].ReplyToUserID > 0 {
+			replyToUserIDs = append(replyToUserIDs, *kept[i].ReplyToUserID)
+		}
+	}
+	if len(replyToUserIDs) > 0 {
+		replyToUserMap := r.batchGetCommentUsers(ctx, replyToUserIDs)
+		for i := range kept {
+			if kept[i].ReplyToUserID != nil {
+				kept[i].ReplyToUser = replyToUserMap[*kept[i].ReplyToUserID]
+			}
+		}
+	}
+
+	for _, reply := range kept {
+		page := pages[reply.ParentID]
+		page.Replies = append(page.Replies, reply)
+		page.NextCursor = reply.ID
+		pages[reply.ParentID] = page
+	}
+	// 没有更多时不返回游标
+	for id, page := range pages {
+		if !page.HasMore {
+			page.NextCursor = 0
+			pages[id] = page
+		}
+	}
+	return pages
+}
+
 // batchCheckCommentLikes 批量检查评论点赞状态（优化N+1）
 func (r *ArticleRepository) batchCheckCommentLikes(ctx context.Context, commentIDs []uint, userID uint) map[uint]bool {
 	likedMap := make(map[uint]bool, len(commentIDs)) // 预分配容量
@@ -969,15 +1496,16 @@ func (r *ArticleRepository) batchGetChildComments(ctx context.Context, articleID
 
 	// 一次性查询文章的所有子评论（包括所有层级）
 	query := `SELECT ac.id, ac.article_id, ac.user_id, ac.parent_id, ac.root_id, ac.reply_to_user_id, ac.content,
-					 ac.like_count, ac.reply_count, ac.status, ac.created_at, ac.updated_at,
+					 ac.like_count, ac.reply_count, ac.status, ac.is_edited, ac.created_at, ac.updated_at,
 					 ua.username, COALESCE(up.nickname, ua.username) as nickname, COALESCE(up.avatar_url, '') as avatar
 			  FROM article_comments ac
 			  INNER JOIN user_auth ua ON ac.user_id = ua.id
 			  LEFT JOIN user_profile up ON ua.id = up.user_id
 			  WHERE ac.article_id = ? AND ac.parent_id > 0 AND ac.status = 1
+			  AND NOT EXISTS (SELECT 1 FROM user_blocks ub WHERE ub.blocker_id = ? AND ub.blocked_id = ac.user_id)
 			  ORDER BY ac.created_at ASC`
 
-	rows, err := r.db.DB.QueryContext(ctx, query, articleID)
+	rows, err := r.db.DB.QueryContext(ctx, query, articleID, userID)
 	if err != nil {
 		return childMap
 	}
@@ -995,7 +1523,7 @@ func (r *ArticleRepository) batchGetChildComments(ctx context.Context, articleID
 		err := rows.Scan(
 			&child.ID, &child.ArticleID, &child.UserID, &child.ParentID, &child.RootID,
 			&child.ReplyToUserID, &child.Content, &child.LikeCount, &child.ReplyCount,
-			&child.Status, &child.CreatedAt, &child.UpdatedAt,
+			&child.Status, &child.IsEdited, &child.CreatedAt, &child.UpdatedAt,
 			&child.Author.Username, &child.Author.Nickname, &child.Author.Avatar)
 		if err != nil {
 			continue
@@ -1144,7 +1672,10 @@ func (r *ArticleRepository) ToggleCommentLike(ctx context.Context, commentID, us
 		_, err := r.db.DB.ExecContext(ctx, insertQuery, commentID, userID, time.Now().UTC())
 		if err != nil {
 			r.logger.Error("点赞评论失败", "error", err.Error())
-			return false, utils.ErrDatabaseInsert
+			if isDuplicateKeyError(err) {
+				return false, duplicateEntryError("已经点过赞了")
+			}
+			return false, fmt.Errorf("%w: %w", utils.ErrDatabaseInsert, err)
 		}
 		// 更新评论点赞数
 		_, _ = r.db.DB.ExecContext(ctx, `UPDATE article_comments SET like_count = like_count + 1 WHERE id = ?`, commentID)
@@ -1155,13 +1686,13 @@ func (r *ArticleRepository) ToggleCommentLike(ctx context.Context, commentID, us
 		_, err := r.db.DB.ExecContext(ctx, deleteQuery, commentID, userID)
 		if err != nil {
 			r.logger.Error("取消点赞评论失败", "error", err.Error())
-			return false, utils.ErrDatabaseUpdate
+			return false, fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
 		}
 		// 更新评论点赞数
 		_, _ = r.db.DB.ExecContext(ctx, `UPDATE article_comments SET like_count = GREATEST(like_count - 1, 0) WHERE id = ?`, commentID)
 		isLiked = false
 	default:
-		return false, utils.ErrDatabaseQuery
+		return false, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
 	}
 
 	r.logger.Info("切换评论点赞成功", "commentID", commentID, "userID", userID, "isLiked", isLiked, "duration", time.Since(start))
@@ -1180,7 +1711,7 @@ func (r *ArticleRepository) DeleteComment(ctx context.Context, commentID, userID
 		if err == sql.ErrNoRows {
 			return utils.ErrUserNotFound
 		}
-		return utils.ErrDatabaseQuery
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
 	}
 	if ownerID != userID {
 		return utils.ErrUnauthorized
@@ -1191,53 +1722,672 @@ func (r *ArticleRepository) DeleteComment(ctx context.Context, commentID, userID
 	_, err = r.db.DB.ExecContext(ctx, query, time.Now().UTC(), commentID)
 	if err != nil {
 		r.logger.Error("删除评论失败", "error", err.Error())
-		return utils.ErrDatabaseUpdate
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
 	}
 
 	// 更新文章评论数
 	_, _ = r.db.DB.ExecContext(ctx, `UPDATE articles SET comment_count = GREATEST(comment_count - 1, 0) WHERE id = ?`, articleID)
 
+	r.invalidateArticle(articleID)
+
 	r.logger.Info("删除评论成功", "commentID", commentID, "duration", time.Since(start))
 	return nil
 }
 
-// CreateReport 创建举报
-func (r *ArticleRepository) CreateReport(ctx context.Context, report *models.ArticleReport) error {
+// DeleteCommentsByUser 批量删除自己的评论（软删除）
+// 整批在一个事务中完成，任一评论不存在或不属于该用户时全部回滚；成功后按文章扣减评论数
+// 重复的ID只删除一次，返回实际删除的评论ID
+func (r *ArticleRepository) DeleteCommentsByUser(ctx context.Context, userID uint, commentIDs []uint) ([]uint, error) {
 	start := time.Now().UTC()
+	commentIDs = uniqueIDs(commentIDs)
+	if len(commentIDs) == 0 {
+		return []uint{}, nil
+	}
 
-	query := `INSERT INTO article_reports (article_id, comment_id, user_id, reason, status, created_at)
-			  VALUES (?, ?, ?, ?, 0, ?)`
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
+	defer cancel()
 
-	result, err := r.db.DB.ExecContext(ctx, query,
-		report.ArticleID, report.CommentID, report.UserID, report.Reason, report.CreatedAt)
+	placeholders, args := inPlaceholders(commentIDs)
+	articleCounts := make(map[uint]int)
+	err := r.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		// 锁定评论并检查所有权
+		rows, err := tx.QueryContext(ctx,
+			`SELECT id, user_id, article_id FROM article_comments WHERE id IN (`+placeholders+`) AND status != 0 FOR UPDATE`, args...)
+		if err != nil {
+			return fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+		}
+		owners := make(map[uint]uint, len(commentIDs))
+		commentArticles := make(map[uint]uint, len(commentIDs))
+		for rows.Next() {
+			var id, ownerID, articleID uint
+			if err := rows.Scan(&id, &ownerID, &articleID); err != nil {
+				rows.Close()
+				return fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+			}
+			owners[id] = ownerID
+			commentArticles[id] = articleID
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+		}
+		if err := checkBatchOwnership(commentIDs, owners, userID); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE article_comments SET status = 0, updated_at = ? WHERE id IN (`+placeholders+`)`,
+			append([]interface{}{start}, args...)...); err != nil {
+			r.logger.Error("批量删除评论失败", "userID", userID, "error", err.Error())
+			return fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
+		}
+
+		// 更新文章评论数
+		for _, articleID := range commentArticles {
+			articleCounts[articleID]++
+		}
+		for articleID, count := range articleCounts {
+			if _, err := tx.ExecContext(ctx,
+				`UPDATE articles SET comment_count = GREATEST(comment_count - ?, 0) WHERE id = ?`, count, articleID); err != nil {
+				return fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		r.logger.Error("插入举报失败", "error", err.Error())
-		return utils.ErrDatabaseInsert
+		return nil, err
 	}
 
-	reportID, _ := result.LastInsertId()
-	report.ID = uint(reportID)
+	for articleID := range articleCounts {
+		r.invalidateArticle(articleID)
+	}
 
-	r.logger.Info("创建举报成功", "reportID", report.ID, "duration", time.Since(start))
-	return nil
+	r.logger.Info("批量删除评论成功", "userID", userID, "count", len(commentIDs), "duration", time.Since(start))
+	return commentIDs, nil
 }
 
-// GetAllCategories 获取所有分类
-func (r *ArticleRepository) GetAllCategories(ctx context.Context) ([]models.ArticleCategory, error) {
-	query := `SELECT id, name, slug, description, parent_id, article_count, sort_order, created_at
-			  FROM article_categories ORDER BY sort_order ASC, id ASC`
+// UpdateComment 编辑评论（仅作者本人、发布后 edit_window_min 分钟内可编辑）
+// 编辑前的内容写入 comment_edit_history，每条评论只保留最近 edit_history_limit 条
+func (r *ArticleRepository) UpdateComment(ctx context.Context, commentID, userID uint, content string) error {
+	start := time.Now().UTC()
 
-	rows, err := r.db.DB.QueryContext(ctx, query)
-	if err != nil {
-		r.logger.Error("查询分类失败", "error", err.Error())
-		return nil, utils.ErrDatabaseQuery
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return utils.NewAppError(utils.ErrValidationFailed, "评论内容不能为空", 400)
+	}
+	if maxLen := r.config.ValidationExtended.CommentContentMax; maxLen > 0 && utf8.RuneCountInString(content) > maxLen {
+		return utils.NewAppError(utils.ErrValidationFailed, fmt.Sprintf("评论内容不能超过%d个字符", maxLen), 400)
 	}
-	defer rows.Close()
 
-	var categories []models.ArticleCategory
-	for rows.Next() {
-		var cat models.ArticleCategory
-		if err := rows.Scan(&cat.ID, &cat.Name, &cat.Slug, &cat.Description,
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
+	defer cancel()
+
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("开启事务失败", "error", err.Error())
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+	}
+	defer tx.Rollback()
+
+	// 检查评论所有权和编辑时间窗口（加锁防止并发编辑丢失历史）
+	var ownerID uint
+	var oldContent string
+	var createdAt time.Time
+	err = tx.QueryRowContext(ctx,
+		`SELECT user_id, content, created_at FROM article_comments WHERE id = ? AND status = 1 FOR UPDATE`,
+		commentID).Scan(&ownerID, &oldContent, &createdAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return utils.ErrUserNotFound
+		}
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+	}
+	if ownerID != userID {
+		return utils.ErrUnauthorized
+	}
+	if window := r.config.Comments.EditWindowMin; window > 0 && start.Sub(createdAt) > time.Duration(window)*time.Minute {
+		return utils.NewAppError(utils.ErrAccessDenied, fmt.Sprintf("评论发布超过%d分钟，已不能编辑", window), 403)
+	}
+	if oldContent == content {
+		return nil
+	}
+
+	// 保存编辑前的内容，并清理超出保留条数的旧记录
+	if limit := r.config.Comments.EditHistoryLimit; limit > 0 {
+		if _, err = tx.ExecContext(ctx,
+			`INSERT INTO comment_edit_history (comment_id, content, edited_at) VALUES (?, ?, ?)`,
+			commentID, oldContent, start); err != nil {
+			r.logger.Error("保存评论编辑历史失败", "commentID", commentID, "error", err.Error())
+			return fmt.Errorf("%w: %w", utils.ErrDatabaseInsert, err)
+		}
+		// MySQL不支持在同表子查询中直接使用LIMIT，借助派生表定位需保留的最早一条
+		if _, err = tx.ExecContext(ctx,
+			`DELETE FROM comment_edit_history WHERE comment_id = ? AND id < (
+				SELECT min_id FROM (
+					SELECT MIN(id) AS min_id FROM (
+						SELECT id FROM comment_edit_history WHERE comment_id = ? ORDER BY id DESC LIMIT ?
+					) AS latest
+				) AS keep_from
+			)`, commentID, commentID, limit); err != nil {
+			r.logger.Error("清理评论编辑历史失败", "commentID", commentID, "error", err.Error())
+			return fmt.Errorf("%w: %w", utils.ErrDatabaseDelete, err)
+		}
+	}
+
+	if _, err = tx.ExecContext(ctx,
+		`UPDATE article_comments SET content = ?, is_edited = 1, updated_at = ? WHERE id = ?`,
+		content, start, commentID); err != nil {
+		r.logger.Error("编辑评论失败", "commentID", commentID, "error", err.Error())
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.logger.Error("提交事务失败", "error", err.Error())
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
+	}
+
+	r.logger.Info("编辑评论成功", "commentID", commentID, "userID", userID, "duration", time.Since(start))
+	return nil
+}
+
+const (
+	// commentAncestryMaxDepth 回复链最多向上追溯的层数，防止异常数据（如parent_id成环）导致无限查询
+	commentAncestryMaxDepth = 50
+	// commentSnippetLength 回复链中评论摘要的最大字符数
+	commentSnippetLength = 60
+)
+
+// GetCommentAncestry 获取评论的回复链（从一级评论到目标评论的父评论，按层级排序）
+// 一级评论返回空列表；超过最大层级或遇到环时从最近的祖先开始保留，并标记截断
+func (r *ArticleRepository) GetCommentAncestry(ctx context.Context, commentID uint) (*models.CommentAncestryResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetQueryTimeout())
+	defer cancel()
+
+	target, err := r.getCommentAncestor(ctx, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if target.Deleted {
+		return nil, utils.ErrResourceNotFound
+	}
+
+	ancestors, truncated, err := walkCommentAncestry(target.ParentID, target.ArticleID, commentAncestryMaxDepth, func(id uint) (*models.CommentAncestor, uint, error) {
+		ancestor, err := r.getCommentAncestor(ctx, id)
+		if err != nil {
+			return nil, 0, err
+		}
+		return &ancestor.CommentAncestor, ancestor.ArticleID, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.CommentAncestryResponse{
+		CommentID: commentID,
+		ArticleID: target.ArticleID,
+		Ancestors: ancestors,
+		Truncated: truncated,
+	}, nil
+}
+
+// walkCommentAncestry 沿 parent_id 向上追溯，返回按根到叶排序的祖先列表
+// load 返回评论及其所属文章；父评论不存在、属于其他文章、重复出现或超过 maxDepth 时停止
+func walkCommentAncestry(parentID, articleID uint, maxDepth int, load func(id uint) (*models.CommentAncestor, uint, error)) ([]models.CommentAncestor, bool, error) {
+	ancestors := make([]models.CommentAncestor, 0)
+	visited := make(map[uint]bool)
+	truncated := false
+
+	for parentID != 0 {
+		if len(ancestors) >= maxDepth || visited[parentID] {
+			truncated = true
+			break
+		}
+		visited[parentID] = true
+
+		ancestor, ancestorArticleID, err := load(parentID)
+		if err != nil {
+			if errors.Is(err, utils.ErrResourceNotFound) {
+				truncated = true
+				break
+			}
+			return nil, false, err
+		}
+		if ancestorArticleID != articleID {
+			truncated = true
+			break
+		}
+		ancestors = append(ancestors, *ancestor)
+		parentID = ancestor.ParentID
+	}
+
+	// 追溯顺序是从近到远，反转为从根到叶
+	for i, j := 0, len(ancestors)-1; i < j; i, j = i+1, j-1 {
+		ancestors[i], ancestors[j] = ancestors[j], ancestors[i]
+	}
+	return ancestors, truncated, nil
+}
+
+// commentAncestorRow 回复链查询结果
+type commentAncestorRow struct {
+	models.CommentAncestor
+	ArticleID uint
+}
+
+// getCommentAncestor 查询单条评论的作者和内容摘要
+func (r *ArticleRepository) getCommentAncestor(ctx context.Context, commentID uint) (*commentAncestorRow, error) {
+	query := `SELECT ac.id, ac.article_id, ac.parent_id, ac.status, LEFT(ac.content, ?), ac.created_at,
+			  ac.user_id, COALESCE(ua.username, ''), COALESCE(up.nickname, ua.username, ''), COALESCE(up.avatar_url, '')
+			  FROM article_comments ac
+			  LEFT JOIN user_auth ua ON ac.user_id = ua.id
+			  LEFT JOIN user_profile up ON ac.user_id = up.user_id
+			  WHERE ac.id = ?`
+
+	row := &commentAncestorRow{}
+	var status int
+	err := r.db.DB.QueryRowContext(ctx, query, commentSnippetLength+1, commentID).Scan(
+		&row.ID, &row.ArticleID, &row.ParentID, &status, &row.Snippet, &row.CreatedAt,
+		&row.Author.ID, &row.Author.Username, &row.Author.Nickname, &row.Author.Avatar)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, utils.ErrResourceNotFound
+		}
+		r.logger.Error("查询评论回复链失败", "commentID", commentID, "error", err.Error())
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+	}
+
+	if status == 0 {
+		row.Deleted = true
+		row.Snippet = ""
+	} else if utf8.RuneCountInString(row.Snippet) > commentSnippetLength {
+		row.Snippet = string([]rune(row.Snippet)[:commentSnippetLength]) + "..."
+	}
+	return row, nil
+}
+
+// CreateReport 创建举报
+func (r *ArticleRepository) CreateReport(ctx context.Context, report *models.ArticleReport) error {
+	start := time.Now().UTC()
+
+	query := `INSERT INTO article_reports (article_id, comment_id, user_id, reason, status, created_at)
+			  VALUES (?, ?, ?, ?, 0, ?)`
+
+	result, err := r.db.DB.ExecContext(ctx, query,
+		report.ArticleID, report.CommentID, report.UserID, report.Reason, report.CreatedAt)
+	if err != nil {
+		r.logger.Error("插入举报失败", "error", err.Error())
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseInsert, err)
+	}
+
+	reportID, _ := result.LastInsertId()
+	report.ID = uint(reportID)
+
+	r.logger.Info("创建举报成功", "reportID", report.ID, "duration", time.Since(start))
+	return nil
+}
+
+// ListReports 分页获取举报列表（status 为 models.ReportStatusAll 时不限状态，按时间倒序）
+// 只举报评论时 article_id 为空，所属文章取自评论
+func (r *ArticleRepository) ListReports(ctx context.Context, status, page, pageSize int) (*models.ReportsResponse, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > r.config.Pagination.MaxPageSize {
+		pageSize = r.config.Pagination.DefaultPageSize
+	}
+	offset := (page - 1) * pageSize
+
+	where := ""
+	args := make([]interface{}, 0, 3)
+	if status != models.ReportStatusAll {
+		where = "WHERE r.status = ?"
+		args = append(args, status)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetQueryTimeout())
+	defer cancel()
+
+	var total int
+	if err := r.db.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM article_reports r `+where, args...).Scan(&total); err != nil {
+		r.logger.Error("查询举报总数失败", "error", err.Error())
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+	}
+
+	query := `SELECT r.id, COALESCE(r.article_id, ac.article_id), r.comment_id, r.user_id, r.reason, r.status,
+					 r.handler_id, COALESCE(r.handler_note, ''), COALESCE(r.action, ''), r.created_at, r.handled_at,
+					 COALESCE(a.title, ''), COALESCE(ac.content, ''),
+					 COALESCE(reporter.username, ''), COALESCE(handler.username, '')
+			  FROM article_reports r
+			  LEFT JOIN article_comments ac ON r.comment_id = ac.id
+			  LEFT JOIN articles a ON a.id = COALESCE(r.article_id, ac.article_id)
+			  LEFT JOIN user_auth reporter ON r.user_id = reporter.id
+			  LEFT JOIN user_auth handler ON r.handler_id = handler.id
+			  ` + where + `
+			  ORDER BY r.created_at DESC, r.id DESC
+			  LIMIT ? OFFSET ?`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, append(args, pageSize, offset)...)
+	if err != nil {
+		r.logger.Error("查询举报列表失败", "error", err.Error())
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+	}
+	defer rows.Close()
+
+	reports := make([]models.ReportListItem, 0, pageSize)
+	for rows.Next() {
+		var item models.ReportListItem
+		var articleID, commentID, handlerID sql.NullInt64
+		var handledAt sql.NullTime
+		if err := rows.Scan(&item.ID, &articleID, &commentID, &item.UserID, &item.Reason, &item.Status,
+			&handlerID, &item.HandlerNote, &item.Action, &item.CreatedAt, &handledAt,
+			&item.ArticleTitle, &item.CommentContent, &item.ReporterName, &item.HandlerName); err != nil {
+			r.logger.Warn("扫描举报记录失败", "error", err.Error())
+			continue
+		}
+
+		item.TargetType = models.ReportTargetArticle
+		if commentID.Valid {
+			id := uint(commentID.Int64)
+			item.CommentID = &id
+			item.TargetType = models.ReportTargetComment
+		}
+		if articleID.Valid {
+			id := uint(articleID.Int64)
+			item.ArticleID = &id
+		}
+		if handlerID.Valid {
+			id := uint(handlerID.Int64)
+			item.HandlerID = &id
+		}
+		if handledAt.Valid {
+			item.HandledAt = &handledAt.Time
+		}
+		reports = append(reports, item)
+	}
+
+	return &models.ReportsResponse{
+		Reports:    reports,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: (total + pageSize - 1) / pageSize,
+	}, nil
+}
+
+// ResolveReport 处理举报并记录处理人和处理时间
+// dismiss 仅驳回；hide_content 将文章退回草稿或折叠评论；delete_content 软删除文章或评论
+// 举报同时带有 comment_id 时以评论为处理对象；返回处理后的举报（ArticleID 为内容所属文章）
+func (r *ArticleRepository) ResolveReport(ctx context.Context, reportID, moderatorID uint, action string) (*models.ArticleReport, error) {
+	start := time.Now().UTC()
+
+	if !models.ValidReportActions[action] {
+		return nil, utils.NewAppError(utils.ErrValidationFailed, "无效的处理动作", 400)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
+	defer cancel()
+
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("开启事务失败", "error", err.Error())
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+	}
+	defer tx.Rollback()
+
+	report := &models.ArticleReport{ID: reportID}
+	var articleID, commentID sql.NullInt64
+	err = tx.QueryRowContext(ctx,
+		`SELECT article_id, comment_id, user_id, status FROM article_reports WHERE id = ? FOR UPDATE`,
+		reportID).Scan(&articleID, &commentID, &report.UserID, &report.Status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, utils.NewAppError(utils.ErrResourceNotFound, "举报不存在", 404)
+		}
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+	}
+	if report.Status != models.ReportStatusPending {
+		return nil, utils.NewAppError(utils.ErrInvalidRequest, "该举报已处理", 409)
+	}
+	if articleID.Valid {
+		id := uint(articleID.Int64)
+		report.ArticleID = &id
+	}
+
+	if action != models.ReportActionDismiss {
+		if commentID.Valid {
+			id := uint(commentID.Int64)
+			report.CommentID = &id
+			ownerArticleID, err := r.moderateComment(ctx, tx, id, action)
+			if err != nil {
+				return nil, err
+			}
+			if ownerArticleID > 0 {
+				report.ArticleID = &ownerArticleID
+			}
+		} else if report.ArticleID != nil {
+			if err := r.moderateArticle(ctx, tx, *report.ArticleID, action); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	report.Status = models.ReportStatusResolved
+	if action == models.ReportActionDismiss {
+		report.Status = models.ReportStatusDismissed
+	}
+	report.Action = action
+	report.HandlerID = &moderatorID
+	report.HandledAt = &start
+
+	if _, err = tx.ExecContext(ctx,
+		`UPDATE article_reports SET status = ?, action = ?, handler_id = ?, handled_at = ? WHERE id = ?`,
+		report.Status, action, moderatorID, start, reportID); err != nil {
+		r.logger.Error("更新举报状态失败", "reportID", reportID, "error", err.Error())
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.logger.Error("提交事务失败", "error", err.Error())
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
+	}
+
+	// 文章或评论状态已变化
+	if action != models.ReportActionDismiss && report.ArticleID != nil {
+		r.invalidateArticle(*report.ArticleID)
+		if report.CommentID == nil {
+			r.invalidateArticleLists()
+		}
+	}
+
+	r.logger.Info("处理举报成功", "reportID", reportID, "moderatorID", moderatorID, "action", action, "duration", time.Since(start))
+	return report, nil
+}
+
+// moderateArticle 按处理动作隐藏（退回草稿）或软删除文章
+func (r *ArticleRepository) moderateArticle(ctx context.Context, tx *sql.Tx, articleID uint, action string) error {
+	query := `UPDATE articles SET status = 0, updated_at = ? WHERE id = ? AND status = 1`
+	if action == models.ReportActionDeleteContent {
+		query = `UPDATE articles SET status = 2, updated_at = ? WHERE id = ? AND status != 2`
+	}
+	if _, err := tx.ExecContext(ctx, query, time.Now().UTC(), articleID); err != nil {
+		r.logger.Error("处理被举报文章失败", "articleID", articleID, "action", action, "error", err.Error())
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
+	}
+	return nil
+}
+
+// moderateComment 按处理动作折叠或软删除评论，返回评论所属文章ID（评论不存在时为0）
+func (r *ArticleRepository) moderateComment(ctx context.Context, tx *sql.Tx, commentID uint, action string) (uint, error) {
+	var articleID uint
+	var status int
+	err := tx.QueryRowContext(ctx,
+		`SELECT article_id, status FROM article_comments WHERE id = ? FOR UPDATE`, commentID).Scan(&articleID, &status)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+	}
+
+	newStatus := 2 // 折叠
+	if action == models.ReportActionDeleteContent {
+		newStatus = 0
+	}
+	if status == newStatus || status == 0 {
+		return articleID, nil
+	}
+
+	if _, err = tx.ExecContext(ctx, `UPDATE article_comments SET status = ?, updated_at = ? WHERE id = ?`,
+		newStatus, time.Now().UTC(), commentID); err != nil {
+		r.logger.Error("处理被举报评论失败", "commentID", commentID, "action", action, "error", err.Error())
+		return 0, fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
+	}
+	// 正常评论被折叠或删除后不再计入文章评论数
+	if status == 1 {
+		if _, err = tx.ExecContext(ctx,
+			`UPDATE articles SET comment_count = GREATEST(comment_count - 1, 0) WHERE id = ?`, articleID); err != nil {
+			return 0, fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
+		}
+	}
+	return articleID, nil
+}
+
+// hotScoreExpr 文章热度（互动分按发布时长衰减，参数为衰减指数）
+const hotScoreExpr = `(a.like_count * 3 + a.comment_count * 2 + a.view_count * 0.1) /
+	POW(TIMESTAMPDIFF(HOUR, a.created_at, UTC_TIMESTAMP()) + 2, ?)`
+
+// articleDemotionExpr 文章排序分的降权系数表达式：未驳回的举报人数达到阈值，或浏览量足够但点赞率过低时为
+// cfg.Factor，否则为1。系数大于0，只压低排名不会把文章从列表中去掉
+func articleDemotionExpr(cfg config.DemotionConfig) (string, []interface{}) {
+	if !cfg.Enabled {
+		return "1", nil
+	}
+
+	var conditions []string
+	var args []interface{}
+	if cfg.ReportThreshold > 0 {
+		conditions = append(conditions, `(SELECT COUNT(DISTINCT ar.user_id) FROM article_reports ar
+			WHERE ar.article_id = a.id AND ar.comment_id IS NULL AND ar.status != ?) >= ?`)
+		args = append(args, models.ReportStatusDismissed, cfg.ReportThreshold)
+	}
+	if cfg.MinLikeRatio > 0 {
+		conditions = append(conditions, "(a.view_count >= ? AND a.like_count < a.view_count * ?)")
+		args = append(args, cfg.MinViews, cfg.MinLikeRatio)
+	}
+	if len(conditions) == 0 {
+		return "1", nil
+	}
+
+	args = append(args, cfg.Factor)
+	return "(CASE WHEN " + strings.Join(conditions, " OR ") + " THEN ? ELSE 1 END)", args
+}
+
+// GetHotArticleIDs 按衰减热度获取前 limit 篇已发布文章的ID（热度从高到低，低质量文章降权）
+func (r *ArticleRepository) GetHotArticleIDs(ctx context.Context, limit int, gravity float64) ([]uint, error) {
+	demotion, demotionArgs := articleDemotionExpr(r.config.Demotion)
+	query := `SELECT a.id FROM articles a WHERE a.status = 1
+			  ORDER BY ` + hotScoreExpr + ` * ` + demotion + ` DESC, a.id DESC
+			  LIMIT ?`
+
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetQueryTimeout())
+	defer cancel()
+
+	args := append([]interface{}{gravity}, demotionArgs...)
+	args = append(args, limit)
+	rows, err := r.db.QueryWithCache(ctx, query, args...)
+	if err != nil {
+		r.logger.Error("查询热门文章失败", "limit", limit, "error", err.Error())
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+	}
+	defer rows.Close()
+
+	ids := make([]uint, 0, limit)
+	for rows.Next() {
+		var id uint
+		if err := rows.Scan(&id); err != nil {
+			r.logger.Warn("解析热门文章ID失败", "error", err.Error())
+			continue
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// GetArticleVersion 获取文章详情的版本信息（不加载正文和关联数据，用于条件请求）
+func (r *ArticleRepository) GetArticleVersion(ctx context.Context, articleID uint, userID uint) (*models.ArticleVersion, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetQueryTimeout())
+	defer cancel()
+
+	query := `SELECT a.version, a.updated_at, COALESCE(up.updated_at, a.updated_at), a.like_count, a.comment_count,
+			  EXISTS(SELECT 1 FROM article_likes WHERE article_id = a.id AND user_id = ?)
+			  FROM articles a
+			  LEFT JOIN user_profile up ON up.user_id = a.user_id
+			  WHERE a.id = ? AND a.status != 2`
+
+	var version models.ArticleVersion
+	err := r.db.DB.QueryRowContext(ctx, query, userID, articleID).Scan(
+		&version.Version, &version.UpdatedAt, &version.AuthorUpdatedAt, &version.LikeCount, &version.CommentCount, &version.IsLiked)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, utils.ErrResourceNotFound
+		}
+		r.logger.Error("查询文章版本失败", "articleID", articleID, "error", err.Error())
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+	}
+	return &version, nil
+}
+
+// GetArticlePlainText 获取文章的纯文本正文、字数和预计阅读时长
+// 代码块单独存储在 article_code_blocks 中，不计入字数；正文中内嵌的围栏代码块也会被去除
+func (r *ArticleRepository) GetArticlePlainText(ctx context.Context, articleID uint) (*models.ArticlePlainText, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetQueryTimeout())
+	defer cancel()
+
+	query := `SELECT a.title, a.content,
+			  (SELECT COUNT(*) FROM article_code_blocks WHERE article_id = a.id)
+			  FROM articles a
+			  WHERE a.id = ? AND a.status != 2`
+
+	var content string
+	result := &models.ArticlePlainText{ArticleID: articleID}
+	err := r.db.DB.QueryRowContext(ctx, query, articleID).Scan(&result.Title, &content, &result.CodeBlockCount)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, utils.ErrResourceNotFound
+		}
+		r.logger.Error("查询文章正文失败", "articleID", articleID, "error", err.Error())
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+	}
+
+	result.Text = utils.StripMarkdown(content)
+	result.WordCount = utils.CountWords(result.Text)
+	result.ReadingMinutes = utils.EstimateReadingMinutes(result.WordCount, r.config.Reading.WordsPerMinute)
+	return result, nil
+}
+
+// IsArticleLiked 判断用户是否点赞了文章
+func (r *ArticleRepository) IsArticleLiked(ctx context.Context, articleID uint, userID uint) bool {
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetQueryTimeout())
+	defer cancel()
+	return r.checkArticleLike(ctx, articleID, userID)
+}
+
+// GetAllCategories 获取所有分类
+func (r *ArticleRepository) GetAllCategories(ctx context.Context) ([]models.ArticleCategory, error) {
+	query := `SELECT id, name, slug, description, parent_id, article_count, sort_order, created_at
+			  FROM article_categories ORDER BY sort_order ASC, id ASC`
+
+	rows, err := r.db.DB.QueryContext(ctx, query)
+	if err != nil {
+		r.logger.Error("查询分类失败", "error", err.Error())
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+	}
+	defer rows.Close()
+
+	var categories []models.ArticleCategory
+	for rows.Next() {
+		var cat models.ArticleCategory
+		if err := rows.Scan(&cat.ID, &cat.Name, &cat.Slug, &cat.Description,
 			&cat.ParentID, &cat.ArticleCount, &cat.SortOrder, &cat.CreatedAt); err == nil {
 			categories = append(categories, cat)
 		}
@@ -1254,7 +2404,7 @@ func (r *ArticleRepository) GetAllTags(ctx context.Context) ([]models.ArticleTag
 	rows, err := r.db.DB.QueryContext(ctx, query)
 	if err != nil {
 		r.logger.Error("查询标签失败", "error", err.Error())
-		return nil, utils.ErrDatabaseQuery
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
 	}
 	defer rows.Close()
 
@@ -1271,25 +2421,305 @@ func (r *ArticleRepository) GetAllTags(ctx context.Context) ([]models.ArticleTag
 
 // CreateOrGetTag 创建或获取标签
 func (r *ArticleRepository) CreateOrGetTag(ctx context.Context, tagName string) (uint, error) {
-	// 先查询是否存在
-	var tagID uint
-	query := `SELECT id FROM article_tags WHERE name = ?`
-	err := r.db.DB.QueryRowContext(ctx, query, tagName).Scan(&tagID)
-	if err == nil {
-		return tagID, nil
-	}
-
-	// 不存在则创建
-	slug := strings.ToLower(strings.ReplaceAll(tagName, " ", "-"))
-	insertQuery := `INSERT INTO article_tags (name, slug, created_at) VALUES (?, ?, ?)`
-	result, err := r.db.DB.ExecContext(ctx, insertQuery, tagName, slug, time.Now().UTC())
+	tagIDs, err := r.CreateOrGetTags(ctx, []string{tagName})
+	if err != nil {
+		return 0, err
+	}
+	tagID, ok := tagIDs[strings.TrimSpace(tagName)]
+	if !ok {
+		return 0, utils.ErrInvalidParameter
+	}
+	return tagID, nil
+}
+
+// tagSlug 由标签名生成slug（不区分大小写，空格替换为连字符）
+func tagSlug(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, " ", "-"))
+}
+
+// CreateOrGetTags 批量创建或获取标签，返回 标签名 -> 标签ID
+// 一次查询已存在的标签，一次 INSERT IGNORE 创建缺失的标签；slug 相同（含大小写不同）的名称视为同一标签
+// 并发创建同一新标签时依赖 uk_slug 唯一索引去重，插入后按 slug 回查ID
+func (r *ArticleRepository) CreateOrGetTags(ctx context.Context, names []string) (map[string]uint, error) {
+	result := make(map[string]uint, len(names))
+
+	// 按slug去重，保留每个slug第一次出现的名称作为新标签名
+	slugNames := make(map[string]string)
+	var slugs []string
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		slug := tagSlug(name)
+		if _, exists := slugNames[slug]; !exists {
+			slugNames[slug] = name
+			slugs = append(slugs, slug)
+		}
+	}
+	if len(slugs) == 0 {
+		return result, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
+	defer cancel()
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(slugs)), ",")
+	args := make([]interface{}, len(slugs))
+	for i, slug := range slugs {
+		args[i] = slug
+	}
+
+	slugIDs, err := r.queryTagIDsBySlug(ctx, placeholders, args)
+	if err != nil {
+		r.logger.Error("批量查询标签失败", "count", len(slugs), "error", err.Error())
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+	}
+
+	var missing []string
+	for _, slug := range slugs {
+		if _, ok := slugIDs[slug]; !ok {
+			missing = append(missing, slug)
+		}
+	}
+
+	if len(missing) > 0 {
+		now := time.Now().UTC()
+		values := make([]string, 0, len(missing))
+		insertArgs := make([]interface{}, 0, len(missing)*3)
+		missingArgs := make([]interface{}, 0, len(missing))
+		for _, slug := range missing {
+			values = append(values, "(?, ?, ?)")
+			insertArgs = append(insertArgs, slugNames[slug], slug, now)
+			missingArgs = append(missingArgs, slug)
+		}
+
+		insertQuery := `INSERT IGNORE INTO article_tags (name, slug, created_at) VALUES ` + strings.Join(values, ", ")
+		insertResult, err := r.db.DB.ExecContext(ctx, insertQuery, insertArgs...)
+		if err != nil {
+			r.logger.Error("批量创建标签失败", "count", len(missing), "error", err.Error())
+			return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseInsert, err)
+		}
+		if inserted, _ := insertResult.RowsAffected(); inserted > 0 {
+			r.invalidateArticleTags()
+		}
+
+		// 回查新建（或被并发请求抢先创建）的标签ID
+		created, err := r.queryTagIDsBySlug(ctx, strings.TrimSuffix(strings.Repeat("?,", len(missing)), ","), missingArgs)
+		if err != nil {
+			r.logger.Error("查询新建标签失败", "count", len(missing), "error", err.Error())
+			return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+		}
+		for slug, id := range created {
+			slugIDs[slug] = id
+		}
+	}
+
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if id, ok := slugIDs[tagSlug(name)]; ok && name != "" {
+			result[name] = id
+		}
+	}
+	return result, nil
+}
+
+// queryTagIDsBySlug 按slug批量查询标签ID
+func (r *ArticleRepository) queryTagIDsBySlug(ctx context.Context, placeholders string, args []interface{}) (map[string]uint, error) {
+	rows, err := r.db.DB.QueryContext(ctx, `SELECT id, slug FROM article_tags WHERE slug IN (`+placeholders+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[string]uint, len(args))
+	for rows.Next() {
+		var id uint
+		var slug string
+		if err := rows.Scan(&id, &slug); err != nil {
+			return nil, err
+		}
+		// 数据库排序规则不区分大小写，统一用小写作为键
+		ids[strings.ToLower(slug)] = id
+	}
+	return ids, rows.Err()
+}
+
+// getTagByID 获取单个标签
+func (r *ArticleRepository) getTagByID(ctx context.Context, tagID uint) (*models.ArticleTag, error) {
+	var tag models.ArticleTag
+	err := r.db.DB.QueryRowContext(ctx,
+		`SELECT id, name, slug, article_count, created_at FROM article_tags WHERE id = ?`, tagID).
+		Scan(&tag.ID, &tag.Name, &tag.Slug, &tag.ArticleCount, &tag.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// GetTagArticleIDs 获取使用某个标签的文章ID
+func (r *ArticleRepository) GetTagArticleIDs(ctx context.Context, tagID uint) ([]uint, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetQueryTimeout())
+	defer cancel()
+
+	rows, err := r.db.DB.QueryContext(ctx, `SELECT article_id FROM article_tag_relations WHERE tag_id = ?`, tagID)
+	if err != nil {
+		r.logger.Error("查询标签文章失败", "tagID", tagID, "error", err.Error())
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+	}
+	defer rows.Close()
+
+	articleIDs := make([]uint, 0)
+	for rows.Next() {
+		var articleID uint
+		if err := rows.Scan(&articleID); err != nil {
+			return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+		}
+		articleIDs = append(articleIDs, articleID)
+	}
+	return articleIDs, rows.Err()
+}
+
+// RenameTag 重命名标签并重新生成slug（管理员）
+// 新slug与其他标签冲突时返回 ErrDuplicateEntry，此时应改用合并
+func (r *ArticleRepository) RenameTag(ctx context.Context, tagID uint, newName string) (*models.ArticleTag, error) {
+	newName = strings.TrimSpace(newName)
+	if newName == "" {
+		return nil, utils.ErrInvalidParameter
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
+	defer cancel()
+
+	result, err := r.db.DB.ExecContext(ctx,
+		`UPDATE article_tags SET name = ?, slug = ? WHERE id = ?`, newName, tagSlug(newName), tagID)
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return nil, utils.ErrDuplicateEntry
+		}
+		r.logger.Error("重命名标签失败", "tagID", tagID, "newName", newName, "error", err.Error())
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
+	}
+
+	tag, err := r.getTagByID(ctx, tagID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, utils.ErrResourceNotFound
+		}
+		r.logger.Error("查询标签失败", "tagID", tagID, "error", err.Error())
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+	}
+
+	if affected, _ := result.RowsAffected(); affected > 0 {
+		r.invalidateArticleTags()
+		r.logger.Info("重命名标签成功", "tagID", tagID, "name", tag.Name, "slug", tag.Slug)
+	}
+	return tag, nil
+}
+
+// MergeTags 把源标签合并到目标标签（管理员）
+// 在一个事务中：删除会与目标标签重复的文章关联，其余关联改指向目标标签，重算目标标签的文章数，最后删除源标签
+func (r *ArticleRepository) MergeTags(ctx context.Context, sourceTagID, targetTagID uint) (*models.TagMergeResult, error) {
+	if sourceTagID == targetTagID {
+		return nil, utils.ErrInvalidParameter
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
+	defer cancel()
+
+	result := &models.TagMergeResult{SourceTagID: sourceTagID, ArticleIDs: make([]uint, 0)}
+	err := r.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		// 按ID顺序锁定两个标签，避免并发合并时死锁
+		rows, err := tx.QueryContext(ctx,
+			`SELECT id FROM article_tags WHERE id IN (?, ?) ORDER BY id FOR UPDATE`, sourceTagID, targetTagID)
+		if err != nil {
+			return err
+		}
+		locked := 0
+		for rows.Next() {
+			locked++
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if locked != 2 {
+			return utils.ErrResourceNotFound
+		}
+
+		// 记录受影响的文章（用于清理详情缓存）
+		articleRows, err := tx.QueryContext(ctx,
+			`SELECT article_id FROM article_tag_relations WHERE tag_id = ? FOR UPDATE`, sourceTagID)
+		if err != nil {
+			return err
+		}
+		for articleRows.Next() {
+			var articleID uint
+			if err := articleRows.Scan(&articleID); err != nil {
+				articleRows.Close()
+				return err
+			}
+			result.ArticleIDs = append(result.ArticleIDs, articleID)
+		}
+		articleRows.Close()
+		if err := articleRows.Err(); err != nil {
+			return err
+		}
+
+		// 文章同时有两个标签时，直接删除源标签关联
+		deleted, err := tx.ExecContext(ctx,
+			`DELETE s FROM article_tag_relations s
+			 JOIN article_tag_relations t ON t.article_id = s.article_id AND t.tag_id = ?
+			 WHERE s.tag_id = ?`, targetTagID, sourceTagID)
+		if err != nil {
+			return err
+		}
+		result.DedupedRelations, _ = deleted.RowsAffected()
+
+		moved, err := tx.ExecContext(ctx,
+			`UPDATE article_tag_relations SET tag_id = ? WHERE tag_id = ?`, targetTagID, sourceTagID)
+		if err != nil {
+			return err
+		}
+		result.MovedRelations, _ = moved.RowsAffected()
+
+		// 确认源标签不再有关联后才删除，避免留下孤立的关联
+		var remaining int
+		if err := tx.QueryRowContext(ctx,
+			`SELECT COUNT(*) FROM article_tag_relations WHERE tag_id = ?`, sourceTagID).Scan(&remaining); err != nil {
+			return err
+		}
+		if remaining > 0 {
+			return fmt.Errorf("源标签仍有 %d 条文章关联", remaining)
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE article_tags SET article_count = (SELECT COUNT(*) FROM article_tag_relations WHERE tag_id = ?) WHERE id = ?`,
+			targetTagID, targetTagID); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `DELETE FROM article_tags WHERE id = ?`, sourceTagID)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, utils.ErrResourceNotFound) {
+			return nil, err
+		}
+		r.logger.Error("合并标签失败", "sourceTagID", sourceTagID, "targetTagID", targetTagID, "error", err.Error())
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
+	}
+	r.invalidateArticleTags()
+
+	target, err := r.getTagByID(ctx, targetTagID)
 	if err != nil {
-		r.logger.Error("创建标签失败", "tagName", tagName, "error", err.Error())
-		return 0, utils.ErrDatabaseInsert
+		r.logger.Error("查询标签失败", "tagID", targetTagID, "error", err.Error())
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
 	}
+	result.Target = *target
 
-	id, _ := result.LastInsertId()
-	return uint(id), nil
+	r.logger.Info("合并标签成功", "sourceTagID", sourceTagID, "targetTagID", targetTagID,
+		"moved", result.MovedRelations, "deduped", result.DedupedRelations)
+	return result, nil
 }
 
 // getCodeBlocks 获取代码块（辅助方法）
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/models"
+	"gin/internal/testutil"
+	"gin/internal/utils"
+)
+
+// onArticleRevisions 预设文章5的线上版本和修订3（修订中的文章已删除时两者都查不到）
+func onArticleRevisions(fake *testutil.FakeDB, current, revision [3]string) {
+	now := time.Now().UTC()
+	fake.On(`SELECT user_id, title, COALESCE\(description, ''\), content, updated_at FROM articles WHERE id = \? AND status != 2`,
+		func(args []driver.Value) testutil.Response {
+			resp := testutil.Response{Columns: []string{"user_id", "title", "description", "content", "updated_at"}}
+			if args[0] == int64(5) {
+				resp.Rows = [][]driver.Value{{int64(7), current[0], current[1], current[2], now}}
+			}
+			return resp
+		})
+	fake.On(`FROM article_revisions ar INNER JOIN articles a`, func(args []driver.Value) testutil.Response {
+		resp := testutil.Response{Columns: []string{"user_id", "title", "description", "content", "created_at"}}
+		if args[0] == int64(3) && args[1] == int64(5) {
+			resp.Rows = [][]driver.Value{{int64(7), revision[0], revision[1], revision[2], now}}
+		}
+		return resp
+	})
+}
+
+func TestDiffRevisionsAgainstCurrentVersion(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	onArticleRevisions(fake,
+		[3]string{"新标题", "描述", "第一行\n第二行（修改）\n第三行\n新增行"},
+		[3]string{"旧标题", "描述", "第一行\n第二行\n第三行\n删除行"})
+	repo := NewArticleRepository(db, config.Default())
+
+	diff, err := repo.DiffRevisions(context.Background(), 5, 3, models.ArticleRevisionCurrent)
+	if err != nil {
+		t.Fatalf("比较修订失败: %v", err)
+	}
+	if diff.Title == nil || diff.Title.From != "旧标题" || diff.Title.To != "新标题" {
+		t.Fatalf("应包含标题变化，实际 %+v", diff.Title)
+	}
+	if diff.Description != nil {
+		t.Fatalf("描述未变化时不应返回，实际 %+v", diff.Description)
+	}
+	if diff.Changed != 2 || diff.Added != 0 || diff.Removed != 0 || diff.Identical {
+		t.Fatalf("应识别出2行修改，实际 %+v", diff)
+	}
+	if diff.FromRevisionID != 3 || diff.ToRevisionID != models.ArticleRevisionCurrent {
+		t.Fatalf("应返回比较的修订ID，实际 %d -> %d", diff.FromRevisionID, diff.ToRevisionID)
+	}
+
+	same, err := repo.DiffRevisions(context.Background(), 5, models.ArticleRevisionCurrent, models.ArticleRevisionCurrent)
+	if err != nil {
+		t.Fatalf("比较修订失败: %v", err)
+	}
+	if !same.Identical || len(same.Lines) != 0 || same.Title != nil {
+		t.Fatalf("相同版本的差异应为空，实际 %+v", same)
+	}
+}
+
+func TestDiffRevisionsMissingRevision(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	onArticleRevisions(fake, [3]string{"t", "d", "c"}, [3]string{"t", "d", "c"})
+	repo := NewArticleRepository(db, config.Default())
+
+	for _, ids := range [][3]uint{{5, 99, 0}, {6, 0, 0}, {6, 3, 0}} {
+		_, err := repo.DiffRevisions(context.Background(), ids[0], ids[1], ids[2])
+		if utils.GetHTTPStatusCode(err) != 404 {
+			t.Fatalf("文章 %d 修订 %d 不存在时应返回404，实际 %v", ids[0], ids[1], err)
+		}
+	}
+}
+
+func TestUpdateArticleSavesRevisionOnlyWhenContentChanges(t *testing.T) {
+	cases := []struct {
+		name    string
+		title   string
+		wantRev bool
+	}{
+		{"标题变化时保存修改前的版本", "新标题", true},
+		{"内容没有变化时不保存", "旧标题", false},
+	}
+	for _, tc := range cases {
+		fake, db := newFakeDatabase(t)
+		fake.OnRows(`SELECT user_id, version FROM articles WHERE id = \?`, []string{"user_id", "version"}, []driver.Value{int64(7), int64(1)})
+		fake.OnRows(`SELECT title, COALESCE\(description, ''\), content FROM articles WHERE id = \? FOR UPDATE`,
+			[]string{"title", "description", "content"}, []driver.Value{"旧标题", "描述", "正文"})
+		fake.OnExec(`INSERT INTO article_revisions`, 1, 1)
+		fake.OnExec(`UPDATE articles SET`, 0, 1)
+
+		title := tc.title
+		if _, err := NewArticleRepository(db, config.Default()).UpdateArticle(context.Background(), 5, 7,
+			models.UpdateArticleRequest{Version: 1, Title: &title}); err != nil {
+			t.Fatalf("%s: 更新文章失败: %v", tc.name, err)
+		}
+
+		revisions := fake.Calls(`INSERT INTO article_revisions`)
+		if (len(revisions) == 1) != tc.wantRev {
+			t.Fatalf("%s: 保存修订 %d 次", tc.name, len(revisions))
+		}
+		if tc.wantRev && (revisions[0].Args[2] != "旧标题" || revisions[0].Args[4] != "正文") {
+			t.Fatalf("%s: 应保存修改前的内容，实际 %v", tc.name, revisions[0].Args)
+		}
+	}
+}
+
+// onArticleUpdate 预设更新文章用到的查询：文章 5 属于用户 7，当前版本为 version，UPDATE 影响 affected 行
+func onArticleUpdate(fake *testutil.FakeDB, version, affected int64) {
+	fake.OnRows(`SELECT user_id, version FROM articles WHERE id = \?`, []string{"user_id", "version"}, []driver.Value{int64(7), version})
+	fake.OnExec(`UPDATE articles SET`, 0, affected)
+	fake.OnExec(`article_tag_relations`, 0, 1)
+}
+
+func TestUpdateArticleOptimisticLock(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	onArticleUpdate(fake, 3, 1)
+	repo := NewArticleRepository(db, config.Default())
+
+	// 只修改标签时同样递增版本号，UPDATE 带上客户端读取到的版本号
+	version, err := repo.UpdateArticle(context.Background(), 5, 7, models.UpdateArticleRequest{Version: 3, TagIDs: []uint{1}})
+	if err != nil || version != 4 {
+		t.Fatalf("更新成功时应返回新版本号 4，实际 %d %v", version, err)
+	}
+	update := fake.Calls(`UPDATE articles SET`)
+	if len(update) != 1 || !strings.Contains(update[0].Query, "version = version + 1") ||
+		!strings.HasSuffix(update[0].Query, "AND version = ?") || update[0].Args[len(update[0].Args)-1] != int64(3) {
+		t.Fatalf("UPDATE 应校验并递增版本号，实际 %v", update)
+	}
+
+	// 客户端版本已过期：检查时直接返回冲突，不开启事务
+	fake, db = newFakeDatabase(t)
+	onArticleUpdate(fake, 4, 1)
+	_, err = NewArticleRepository(db, config.Default()).UpdateArticle(context.Background(), 5, 7, models.UpdateArticleRequest{Version: 3})
+	if !errors.Is(err, utils.ErrVersionConflict) || utils.GetHTTPStatusCode(err) != 409 {
+		t.Fatalf("版本号不一致时应返回409冲突，实际 %v", err)
+	}
+	if len(fake.Calls(`^BEGIN$`)) != 0 || len(fake.Calls(`UPDATE articles SET`)) != 0 {
+		t.Fatal("版本号不一致时不应更新文章")
+	}
+
+	// 其他用户的文章返回无权限而不是冲突
+	_, err = NewArticleRepository(db, config.Default()).UpdateArticle(context.Background(), 5, 8, models.UpdateArticleRequest{Version: 4})
+	if !errors.Is(err, utils.ErrUnauthorized) {
+		t.Fatalf("非作者更新应返回无权限，实际 %v", err)
+	}
+}
+
+func TestUpdateArticleConcurrentChange(t *testing.T) {
+	// 检查通过后文章被其他请求修改、删除或转移：UPDATE 未命中，按原因返回不同错误并回滚
+	cases := []struct {
+		name  string
+		owner []driver.Value // 再次查询到的作者，nil 表示文章已删除
+		want  error
+	}{
+		{"版本已被其他编辑递增", []driver.Value{int64(7)}, utils.ErrVersionConflict},
+		{"文章已删除", nil, utils.ErrResourceNotFound},
+		{"文章已不属于当前用户", []driver.Value{int64(8)}, utils.ErrUnauthorized},
+	}
+	for _, tc := range cases {
+		fake, db := newFakeDatabase(t)
+		onArticleUpdate(fake, 3, 0)
+		if tc.owner != nil {
+			fake.OnRows(`SELECT user_id FROM articles WHERE id = \? AND status != 2`, []string{"user_id"}, tc.owner)
+		} else {
+			fake.OnRows(`SELECT user_id FROM articles WHERE id = \? AND status != 2`, []string{"user_id"})
+		}
+
+		_, err := NewArticleRepository(db, config.Default()).UpdateArticle(context.Background(), 5, 7,
+			models.UpdateArticleRequest{Version: 3, TagIDs: []uint{1}})
+		if !errors.Is(err, tc.want) {
+			t.Errorf("%s: 应返回 %v，实际 %v", tc.name, tc.want, err)
+		}
+		if len(fake.Calls(`article_tag_relations`)) != 0 || len(fake.Calls(`^ROLLBACK$`)) != 1 {
+			t.Errorf("%s: UPDATE 未命中时不应继续修改关联数据，事务应回滚", tc.name)
+		}
+	}
+}
+
+func TestGetArticleByIDSurfacesVersion(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	onArticleDetail(fake)
+	article, err := NewArticleRepository(db, config.Default()).GetArticleByID(context.Background(), 5, 0)
+	if err != nil || article.Version != 1 {
+		t.Fatalf("文章详情应返回当前版本号，实际 %+v %v", article, err)
+	}
+}
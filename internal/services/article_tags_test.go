@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"sync"
+	"testing"
+
+	"gin/internal/config"
+	"gin/internal/testutil"
+)
+
+// tagStore 模拟 article_tags 表（slug 唯一索引，排序规则不区分大小写）
+type tagStore struct {
+	mu     sync.Mutex
+	nextID int64
+	slugs  map[string]int64
+	// beforeInsert 在插入前执行，用于模拟并发请求抢先创建同一标签
+	beforeInsert func()
+}
+
+func (s *tagStore) add(slug string) int64 {
+	key := strings.ToLower(slug)
+	if id, ok := s.slugs[key]; ok {
+		return id
+	}
+	s.nextID++
+	s.slugs[key] = s.nextID
+	return s.nextID
+}
+
+func onTags(fake *testutil.FakeDB, store *tagStore) {
+	fake.On(`SELECT id, slug FROM article_tags WHERE slug IN`, func(args []driver.Value) testutil.Response {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		resp := testutil.Response{Columns: []string{"id", "slug"}}
+		for _, arg := range args {
+			if id, ok := store.slugs[strings.ToLower(arg.(string))]; ok {
+				// 返回数据库中保存的大写形式，验证按小写匹配
+				resp.Rows = append(resp.Rows, []driver.Value{id, strings.ToUpper(arg.(string))})
+			}
+		}
+		return resp
+	})
+	fake.On(`INSERT IGNORE INTO article_tags`, func(args []driver.Value) testutil.Response {
+		if store.beforeInsert != nil {
+			store.beforeInsert()
+		}
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		var affected int64
+		for i := 0; i+2 < len(args); i += 3 {
+			if _, exists := store.slugs[strings.ToLower(args[i+1].(string))]; !exists {
+				store.add(args[i+1].(string))
+				affected++
+			}
+		}
+		return testutil.Response{RowsAffected: affected}
+	})
+}
+
+func TestCreateOrGetTagsBatches(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	store := &tagStore{slugs: map[string]int64{}}
+	store.add("go")
+	onTags(fake, store)
+
+	ids, err := NewArticleRepository(db, config.Default()).CreateOrGetTags(context.Background(),
+		[]string{"Go", "go", " Rust ", "Web Dev", "web dev", "", "   "})
+	if err != nil {
+		t.Fatalf("批量处理标签失败: %v", err)
+	}
+	if ids["Go"] != 1 || ids["go"] != 1 {
+		t.Fatalf("大小写不同的名称应对应已有标签，实际 %v", ids)
+	}
+	if ids["Rust"] == 0 || ids["Web Dev"] == 0 || ids["Web Dev"] != ids["web dev"] || ids["Rust"] == ids["Web Dev"] {
+		t.Fatalf("新标签应创建且 slug 相同的名称对应同一标签，实际 %v", ids)
+	}
+	if _, ok := ids[""]; ok || len(ids) != 5 {
+		t.Fatalf("空名称应忽略，实际 %v", ids)
+	}
+
+	inserts := fake.Calls(`INSERT IGNORE INTO article_tags`)
+	if len(inserts) != 1 || len(inserts[0].Args) != 6 {
+		t.Fatalf("缺失的标签应一次批量插入（rust、web-dev），实际 %v", inserts)
+	}
+	if inserts[0].Args[0] != "Rust" || inserts[0].Args[1] != "rust" || inserts[0].Args[3] != "Web Dev" || inserts[0].Args[4] != "web-dev" {
+		t.Fatalf("新标签名应取第一次出现的写法，slug 为小写，实际 %v", inserts[0].Args)
+	}
+	selects := fake.Calls(`SELECT id, slug FROM article_tags`)
+	if len(selects) != 2 || len(selects[0].Args) != 3 || len(selects[1].Args) != 2 {
+		t.Fatalf("应先一次查询全部标签，再一次回查新建标签，实际 %v", selects)
+	}
+}
+
+func TestCreateOrGetTagsAllExisting(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	store := &tagStore{slugs: map[string]int64{}}
+	store.add("go")
+	store.add("rust")
+	onTags(fake, store)
+
+	ids, err := NewArticleRepository(db, config.Default()).CreateOrGetTags(context.Background(), []string{"GO", "Rust"})
+	if err != nil || ids["GO"] != 1 || ids["Rust"] != 2 {
+		t.Fatalf("已有标签应直接返回ID，实际 %v %v", ids, err)
+	}
+	if calls := fake.Calls(`INSERT`); len(calls) != 0 {
+		t.Fatal("标签都已存在时不应插入")
+	}
+	if calls := fake.Calls(""); len(calls) != 1 {
+		t.Fatalf("标签都已存在时只应查询一次，实际 %d 次", len(calls))
+	}
+}
+
+func TestCreateOrGetTagsConcurrentCreation(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	store := &tagStore{slugs: map[string]int64{}}
+	// 查询后、插入前另一个请求已创建同一标签
+	store.beforeInsert = func() {
+		store.mu.Lock()
+		store.add("kotlin")
+		store.mu.Unlock()
+	}
+	onTags(fake, store)
+	repo := NewArticleRepository(db, config.Default())
+
+	ids, err := repo.CreateOrGetTags(context.Background(), []string{"Kotlin"})
+	if err != nil || ids["Kotlin"] != 1 {
+		t.Fatalf("唯一索引冲突时应回查并复用已创建的标签，实际 %v %v", ids, err)
+	}
+
+	// 多个请求同时创建同一新标签，都应得到同一个ID
+	store.beforeInsert = nil
+	var wg sync.WaitGroup
+	results := make([]uint, 8)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			got, err := repo.CreateOrGetTags(context.Background(), []string{"Swift"})
+			if err == nil {
+				results[i] = got["Swift"]
+			}
+		}(i)
+	}
+	wg.Wait()
+	for _, id := range results {
+		if id == 0 || id != results[0] {
+			t.Fatalf("并发创建同一标签应得到同一ID，实际 %v", results)
+		}
+	}
+	if len(store.slugs) != 2 {
+		t.Fatalf("并发创建不应产生重复标签，实际 %v", store.slugs)
+	}
+}
+
+func TestCreateOrGetTagUsesBatch(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	store := &tagStore{slugs: map[string]int64{}}
+	onTags(fake, store)
+	repo := NewArticleRepository(db, config.Default())
+
+	id, err := repo.CreateOrGetTag(context.Background(), " Zig ")
+	if err != nil || id != 1 {
+		t.Fatalf("创建单个标签失败: %d %v", id, err)
+	}
+	if _, err := repo.CreateOrGetTag(context.Background(), "  "); err == nil {
+		t.Fatal("空标签名应返回错误")
+	}
+}
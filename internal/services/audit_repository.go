@@ -0,0 +1,192 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/models"
+	"gin/internal/utils"
+)
+
+// auditMaxAttempts 审计日志写入遇到可重试的数据库错误时的最大执行次数
+const auditMaxAttempts = 3
+
+// AuditRepository 管理员操作审计日志
+// 写入通过 Worker Pool 异步执行，不阻塞请求；优雅关闭时 Worker Pool 会执行完队列中的任务
+type AuditRepository struct {
+	db      *Database
+	logger  utils.Logger
+	config  *config.Config
+	timeout time.Duration
+}
+
+// NewAuditRepository 创建审计日志仓库
+func NewAuditRepository(db *Database, cfg *config.Config) *AuditRepository {
+	return &AuditRepository{
+		db:      db,
+		logger:  utils.GetLogger(),
+		config:  cfg,
+		timeout: time.Duration(cfg.AsyncTasks.AdminAuditTimeout) * time.Second,
+	}
+}
+
+// Record 异步记录一条管理员操作，before/after 为操作前后的快照（nil 表示无）
+// 可重试的数据库错误最多执行 auditMaxAttempts 次，仍失败时完整记录到错误日志；任务队列已满时同步写入，保证审计记录不丢失
+func (r *AuditRepository) Record(actorID uint, action, targetType string, targetID uint, before, after interface{}, ip string) {
+	entry := &models.AdminAuditLog{
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Before:     auditSnapshot(before),
+		After:      auditSnapshot(after),
+		IPAddress:  ip,
+		CreatedAt:  time.Now().UTC(),
+	}
+
+	taskID := fmt.Sprintf("admin_audit_%s_%d", action, targetID)
+	err := utils.SubmitTask(taskID, func(ctx context.Context) error {
+		return r.insert(ctx, entry)
+	}, r.timeout, utils.WithRetry(auditMaxAttempts, 0), utils.WithDeadLetter(func(_ utils.Task, err error) {
+		r.deadLetter(entry, err)
+	}))
+	if err != nil {
+		r.logger.Warn("审计日志异步提交失败，改为同步写入", "action", action, "error", err.Error())
+		ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+		defer cancel()
+		_ = r.insert(ctx, entry)
+	}
+}
+
+// auditSnapshot 序列化快照，失败时记录为 null
+func auditSnapshot(v interface{}) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return json.RawMessage("null")
+	}
+	return data
+}
+
+// insert 写入审计日志
+func (r *AuditRepository) insert(ctx context.Context, entry *models.AdminAuditLog) error {
+	query := `INSERT INTO admin_audit_log (actor_id, action, target_type, target_id, before_data, after_data, ip_address, created_at)
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+	if _, err := r.db.DB.ExecContext(ctx, query, entry.ActorID, entry.Action, entry.TargetType, entry.TargetID,
+		nullableJSON(entry.Before), nullableJSON(entry.After), entry.IPAddress, entry.CreatedAt); err != nil {
+		r.logger.Error("写入审计日志失败", "actorID", entry.ActorID, "action", entry.Action,
+			"targetType", entry.TargetType, "targetID", entry.TargetID, "error", err.Error())
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseInsert, err)
+	}
+	return nil
+}
+
+// deadLetter 重试用尽仍未写入的审计记录完整输出到错误日志，便于人工补录
+func (r *AuditRepository) deadLetter(entry *models.AdminAuditLog, err error) {
+	data, _ := json.Marshal(entry)
+	r.logger.Error("审计日志写入重试用尽，记录已转入错误日志",
+		"action", entry.Action,
+		"targetID", entry.TargetID,
+		"entry", string(data),
+		"error", err.Error())
+}
+
+// nullableJSON 空快照写入 NULL
+func nullableJSON(data json.RawMessage) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	return string(data)
+}
+
+// ListAuditLog 按条件分页查询审计日志（按时间倒序）
+func (r *AuditRepository) ListAuditLog(ctx context.Context, query models.AuditLogQuery) (*models.AuditLogListResponse, error) {
+	if query.Page <= 0 {
+		query.Page = 1
+	}
+	if query.PageSize <= 0 || query.PageSize > r.config.Pagination.MaxPageSize {
+		query.PageSize = r.config.Pagination.DefaultPageSize
+	}
+	offset := (query.Page - 1) * query.PageSize
+
+	var conditions []string
+	var args []interface{}
+	if query.ActorID > 0 {
+		conditions = append(conditions, "l.actor_id = ?")
+		args = append(args, query.ActorID)
+	}
+	if query.Action != "" {
+		conditions = append(conditions, "l.action = ?")
+		args = append(args, query.Action)
+	}
+	if query.TargetType != "" {
+		conditions = append(conditions, "l.target_type = ?")
+		args = append(args, query.TargetType)
+	}
+	if query.TargetID > 0 {
+		conditions = append(conditions, "l.target_id = ?")
+		args = append(args, query.TargetID)
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetQueryTimeout())
+	defer cancel()
+
+	var total int
+	if err := r.db.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM admin_audit_log l `+where, args...).Scan(&total); err != nil {
+		r.logger.Error("查询审计日志总数失败", "error", err.Error())
+		return nil, utils.ErrDatabaseQuery
+	}
+
+	listQuery := `SELECT l.id, l.actor_id, COALESCE(ua.username, ''), l.action, l.target_type, l.target_id,
+				  l.before_data, l.after_data, l.ip_address, l.created_at
+				  FROM admin_audit_log l
+				  LEFT JOIN user_auth ua ON l.actor_id = ua.id
+				  ` + where + `
+				  ORDER BY l.created_at DESC, l.id DESC
+				  LIMIT ? OFFSET ?`
+
+	rows, err := r.db.DB.QueryContext(ctx, listQuery, append(args, query.PageSize, offset)...)
+	if err != nil {
+		r.logger.Error("查询审计日志失败", "error", err.Error())
+		return nil, utils.ErrDatabaseQuery
+	}
+	defer rows.Close()
+
+	logs := make([]models.AdminAuditLog, 0, query.PageSize)
+	for rows.Next() {
+		var item models.AdminAuditLog
+		var before, after sql.NullString
+		if err := rows.Scan(&item.ID, &item.ActorID, &item.ActorName, &item.Action, &item.TargetType, &item.TargetID,
+			&before, &after, &item.IPAddress, &item.CreatedAt); err != nil {
+			r.logger.Warn("扫描审计日志失败", "error", err.Error())
+			continue
+		}
+		if before.Valid {
+			item.Before = json.RawMessage(before.String)
+		}
+		if after.Valid {
+			item.After = json.RawMessage(after.String)
+		}
+		logs = append(logs, item)
+	}
+
+	return &models.AuditLogListResponse{
+		Logs:       logs,
+		Total:      total,
+		Page:       query.Page,
+		PageSize:   query.PageSize,
+		TotalPages: (total + query.PageSize - 1) / query.PageSize,
+	}, nil
+}
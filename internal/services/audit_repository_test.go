@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/models"
+	"gin/internal/testutil"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// waitAuditInserts 等待审计日志写入次数达到 n
+func waitAuditInserts(t *testing.T, fake *testutil.FakeDB, n int) []testutil.Call {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if calls := fake.Calls(`INSERT INTO admin_audit_log`); len(calls) >= n {
+			return calls
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("审计日志应写入 %d 次，实际 %d 次", n, len(fake.Calls(`INSERT INTO admin_audit_log`)))
+	return nil
+}
+
+func TestAuditRecordWritesAsynchronously(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	release := make(chan struct{})
+	fake.On(`INSERT INTO admin_audit_log`, func([]driver.Value) testutil.Response {
+		<-release
+		return testutil.Response{LastInsertID: 1, RowsAffected: 1}
+	})
+
+	start := time.Now()
+	NewAuditRepository(db, config.Default()).Record(1, models.AuditActionRevokeSessions, models.AuditTargetUser, 7,
+		nil, map[string]bool{"sessions_revoked": true}, "10.0.0.1")
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("记录审计日志不应等待写入完成，耗时 %v", elapsed)
+	}
+	close(release)
+
+	args := waitAuditInserts(t, fake, 1)[0].Args
+	if args[0] != int64(1) || args[1] != models.AuditActionRevokeSessions || args[2] != models.AuditTargetUser || args[3] != int64(7) {
+		t.Fatalf("操作人和操作对象错误: %v", args)
+	}
+	if args[4] != nil || args[5] != `{"sessions_revoked":true}` || args[6] != "10.0.0.1" {
+		t.Fatalf("空快照应写入 NULL，操作后快照应为JSON，实际 %v", args)
+	}
+}
+
+func TestAuditRecordRetriesRetriableErrors(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	var attempts atomic.Int32
+	fake.On(`INSERT INTO admin_audit_log`, func([]driver.Value) testutil.Response {
+		if attempts.Add(1) == 1 {
+			return testutil.Response{Err: &mysql.MySQLError{Number: 1213, Message: "Deadlock found"}}
+		}
+		return testutil.Response{LastInsertID: 1, RowsAffected: 1}
+	})
+
+	NewAuditRepository(db, config.Default()).Record(1, models.AuditActionDeleteArticles, models.AuditTargetUser, 3,
+		map[string]int{"status": 1}, nil, "")
+	waitAuditInserts(t, fake, 2)
+	time.Sleep(50 * time.Millisecond)
+	if n := attempts.Load(); n != 2 {
+		t.Fatalf("死锁后应重试一次并成功，实际执行 %d 次", n)
+	}
+}
+
+func TestAuditRecordGivesUpAfterMaxAttempts(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	fake.OnError(`INSERT INTO admin_audit_log`, &mysql.MySQLError{Number: 1205, Message: "Lock wait timeout"})
+
+	NewAuditRepository(db, config.Default()).Record(1, models.AuditActionDeleteArticles, models.AuditTargetUser, 3, nil, nil, "")
+	waitAuditInserts(t, fake, auditMaxAttempts)
+	time.Sleep(50 * time.Millisecond)
+	if n := len(fake.Calls(`INSERT INTO admin_audit_log`)); n != auditMaxAttempts {
+		t.Fatalf("可重试错误最多执行 %d 次，实际 %d 次", auditMaxAttempts, n)
+	}
+}
+
+func TestListAuditLogFiltersAndPaging(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	cfg := config.Default()
+	now := time.Now().UTC()
+	fake.OnRows(`SELECT COUNT\(\*\) FROM admin_audit_log l`, []string{"count"}, []driver.Value{int64(25)})
+	fake.OnRows(`FROM admin_audit_log l LEFT JOIN user_auth ua`,
+		[]string{"id", "actor_id", "username", "action", "target_type", "target_id", "before_data", "after_data", "ip_address", "created_at"},
+		[]driver.Value{int64(9), int64(1), "admin", models.AuditActionRevokeSessions, models.AuditTargetUser, int64(7), nil, `{"sessions_revoked":true}`, "10.0.0.1", now})
+
+	result, err := NewAuditRepository(db, cfg).ListAuditLog(context.Background(), models.AuditLogQuery{
+		ActorID: 1, Action: models.AuditActionRevokeSessions, TargetType: models.AuditTargetUser, TargetID: 7,
+		Page: 2, PageSize: 10,
+	})
+	if err != nil {
+		t.Fatalf("查询审计日志失败: %v", err)
+	}
+	if result.Total != 25 || result.Page != 2 || result.TotalPages != 3 || len(result.Logs) != 1 {
+		t.Fatalf("分页信息错误: %+v", result)
+	}
+	log := result.Logs[0]
+	if log.ActorName != "admin" || log.Before != nil || string(log.After) != `{"sessions_revoked":true}` {
+		t.Fatalf("NULL 快照应为空，JSON 快照原样返回，实际 %+v", log)
+	}
+
+	call := fake.Calls(`FROM admin_audit_log l LEFT JOIN user_auth ua`)[0]
+	for _, cond := range []string{"l.actor_id = ?", "l.action = ?", "l.target_type = ?", "l.target_id = ?"} {
+		if !strings.Contains(call.Query, cond) {
+			t.Fatalf("查询应包含条件 %s: %s", cond, call.Query)
+		}
+	}
+	if n := len(call.Args); n != 6 || call.Args[n-2] != int64(10) || call.Args[n-1] != int64(10) {
+		t.Fatalf("LIMIT/OFFSET 应为 10/10，实际 %v", call.Args)
+	}
+}
+
+func TestListAuditLogDefaultPaging(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	cfg := config.Default()
+	fake.OnRows(`SELECT COUNT\(\*\) FROM admin_audit_log l`, []string{"count"}, []driver.Value{int64(0)})
+	fake.OnRows(`FROM admin_audit_log l LEFT JOIN user_auth ua`, []string{"id"})
+
+	result, err := NewAuditRepository(db, cfg).ListAuditLog(context.Background(),
+		models.AuditLogQuery{PageSize: cfg.Pagination.MaxPageSize + 1})
+	if err != nil {
+		t.Fatalf("查询审计日志失败: %v", err)
+	}
+	if result.Page != 1 || result.PageSize != cfg.Pagination.DefaultPageSize || result.Logs == nil {
+		t.Fatalf("超出上限的分页应回落为默认值，实际 %+v", result)
+	}
+	call := fake.Calls(`FROM admin_audit_log l LEFT JOIN user_auth ua`)[0]
+	if strings.Contains(call.Query, "WHERE") || len(call.Args) != 2 {
+		t.Fatalf("无筛选条件时不应附加 WHERE: %s %v", call.Query, call.Args)
+	}
+}
@@ -2,7 +2,13 @@ package services
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
 	"gin/internal/config"
@@ -17,15 +23,23 @@ type AuthService struct {
 	config      *config.Config
 	userRepo    *UserRepository
 	historyRepo *HistoryRepository
+	refreshRepo *RefreshTokenRepository
+	blacklist   *TokenBlacklist
+	emailSender EmailSender
+	passwords   *utils.PasswordValidator
 	logger      utils.Logger
 }
 
 // NewAuthService 创建认证服务
-func NewAuthService(cfg *config.Config, userRepo *UserRepository, historyRepo *HistoryRepository) *AuthService {
+func NewAuthService(cfg *config.Config, userRepo *UserRepository, historyRepo *HistoryRepository, refreshRepo *RefreshTokenRepository, blacklist *TokenBlacklist, emailSender EmailSender) *AuthService {
 	return &AuthService{
 		config:      cfg,
 		userRepo:    userRepo,
 		historyRepo: historyRepo,
+		refreshRepo: refreshRepo,
+		blacklist:   blacklist,
+		emailSender: emailSender,
+		passwords:   utils.NewPasswordValidator(&cfg.Validation.Password),
 		logger:      utils.GetLogger(),
 	}
 }
@@ -50,43 +64,77 @@ func (s *AuthService) Login(ctx context.Context, username, password, clientIP, p
 		return nil, utils.ErrAccountDisabled
 	}
 
-	// 检查登录失败次数
-	if user.FailedLoginCount >= s.config.Security.MaxLoginAttempts {
-		s.logger.Warn("登录失败：登录尝试次数过多",
+	// 检查登录锁定（在校验密码之前，锁定期间不做哈希比对，避免通过耗时判断密码是否正确）
+	now := time.Now().UTC()
+	if user.LockedUntil != nil && now.Before(*user.LockedUntil) {
+		s.logger.Warn("登录失败：账户已临时锁定",
 			"userID", user.ID,
 			"username", username,
-			"failedCount", user.FailedLoginCount,
+			"lockedUntil", *user.LockedUntil,
 			"ip", clientIP)
-		return nil, utils.ErrTooManyLoginAttempts
+		return nil, lockedError(user.LockedUntil.Sub(now))
+	}
+	// 锁定已到期（或历史数据中失败次数已达上限但没有锁定时间），自动解锁并清零失败次数
+	if user.LockedUntil != nil || user.FailedLoginCount >= s.config.Security.MaxLoginAttempts {
+		if err := s.userRepo.ResetFailedLoginCount(ctx, user.ID); err != nil {
+			s.logger.Error("解除登录锁定失败", "userID", user.ID, "error", err.Error())
+		}
+		user.FailedLoginCount = 0
 	}
 
 	// 验证密码
 	passwordValid := utils.CheckPasswordHash(password, user.PasswordHash)
 	if !passwordValid {
-		// 增加登录失败次数
-		if err := s.userRepo.IncrementFailedLoginCount(ctx, user.ID); err != nil {
-			s.logger.Error("更新登录失败次数失败", "userID", user.ID, "error", err.Error())
-		}
 		s.logger.Warn("登录失败：密码错误",
 			"userID", user.ID,
 			"username", username,
 			"ip", clientIP)
+
+		// 增加登录失败次数，达到上限时锁定账户
+		lockout := time.Duration(s.config.Security.LockoutMinutes) * time.Minute
+		failedCount, err := s.userRepo.IncrementFailedLoginCount(ctx, user.ID, s.config.Security.MaxLoginAttempts, now.Add(lockout))
+		if err != nil {
+			s.logger.Error("更新登录失败次数失败", "userID", user.ID, "error", err.Error())
+			return nil, utils.ErrInvalidCredentials
+		}
+
+		remaining := s.config.Security.MaxLoginAttempts - failedCount
+		if remaining <= 0 {
+			s.logger.Warn("连续登录失败次数过多，账户已临时锁定",
+				"userID", user.ID,
+				"username", username,
+				"failedCount", failedCount,
+				"ip", clientIP)
+			return nil, lockedError(lockout)
+		}
+		if remaining <= s.config.Security.LoginWarnRemaining {
+			return nil, utils.NewAppError(utils.ErrInvalidCredentials,
+				fmt.Sprintf("用户名或密码错误，还可尝试%d次", remaining), 401)
+		}
 		return nil, utils.ErrInvalidCredentials
 	}
 
-	// 更新登录信息
-	now := time.Now().UTC()
+	// 存储的哈希成本低于当前成本时透明升级
+	if s.config.SecurityPassword.RehashOnLogin && utils.NeedsRehash(user.PasswordHash) {
+		s.upgradePasswordHash(ctx, user.ID, password, user.PasswordHash)
+	}
+
+	// 更新登录信息（同时清零失败次数）
 	err = s.userRepo.UpdateLoginInfo(ctx, user.ID, now, clientIP)
 	if err != nil {
 		s.logger.Error("更新登录信息失败", "userID", user.ID, "error", err.Error())
 	}
 
-	// 生成JWT token（包含邮箱和地址信息）
-	token, err := s.generateJWT(user.ID, user.Username, user.Email, province, city)
+	// 生成JWT访问token（包含邮箱和地址信息）和刷新token
+	token, err := s.generateJWT(user, province, city)
 	if err != nil {
 		s.logger.Error("生成JWT token失败", "userID", user.ID, "error", err.Error())
 		return nil, utils.ErrInternalServerError
 	}
+	refreshToken, err := s.refreshRepo.IssueRefreshToken(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
 
 	// 读取扩展资料
 	extra, _ := s.userRepo.GetUserProfile(ctx, user.ID)
@@ -107,10 +155,14 @@ func (s *AuthService) Login(ctx context.Context, username, password, clientIP, p
 		Code:    200,
 		Message: "登录成功",
 		Data: struct {
-			Token string             `json:"token"`
-			User  models.UserProfile `json:"user"`
+			Token        string             `json:"token"`
+			RefreshToken string             `json:"refresh_token"`
+			ExpiresIn    int                `json:"expires_in"`
+			User         models.UserProfile `json:"user"`
 		}{
-			Token: token,
+			Token:        token,
+			RefreshToken: refreshToken,
+			ExpiresIn:    int(s.accessTokenTTL().Seconds()),
 			User: models.UserProfile{
 				ID:            user.ID,
 				Username:      user.Username,
@@ -139,7 +191,8 @@ func (s *AuthService) Login(ctx context.Context, username, password, clientIP, p
 		prov := province
 		ct := city
 
-		err := utils.SubmitTask(
+		err := utils.SubmitTaskWithContext(
+			ctx,
 			fmt.Sprintf("login-history-%d-%d", userID, time.Now().UTC().Unix()),
 			func(ctx context.Context) error {
 				userAgentStr := ""
@@ -167,28 +220,27 @@ func (s *AuthService) Login(ctx context.Context, username, password, clientIP, p
 func (s *AuthService) Register(ctx context.Context, username, password, email, clientIP, userAgent, province, city string) (*models.LoginResponse, error) {
 	startTime := time.Now().UTC()
 
-	// 检查用户名是否已存在
+	// 检查用户名和邮箱是否已存在
+	// 两项都检查完再返回，且不区分具体冲突项，避免通过响应内容或耗时枚举已注册账号
 	usernameExists, err := s.userRepo.CheckUsernameExists(ctx, username)
 	if err != nil {
 		s.logger.Error("检查用户名失败", "username", username, "error", err.Error())
 		return nil, utils.ErrDatabaseQuery
 	}
 
-	if usernameExists {
-		s.logger.Warn("注册失败：用户名已存在", "username", username)
-		return nil, utils.ErrUserAlreadyExists
-	}
-
-	// 检查邮箱是否已存在
 	emailExists, err := s.userRepo.CheckEmailExists(ctx, email)
 	if err != nil {
 		s.logger.Error("检查邮箱失败", "email", utils.SanitizeEmail(email), "error", err.Error())
 		return nil, utils.ErrDatabaseQuery
 	}
 
-	if emailExists {
-		s.logger.Warn("注册失败：邮箱已被注册", "email", utils.SanitizeEmail(email))
-		return nil, utils.ErrEmailAlreadyExists
+	if usernameExists || emailExists {
+		s.logger.Warn("注册失败：用户名或邮箱已存在",
+			"username", username,
+			"email", utils.SanitizeEmail(email),
+			"usernameExists", usernameExists,
+			"emailExists", emailExists)
+		return nil, utils.NewAppError(utils.ErrUserAlreadyExists, "用户名或邮箱已被使用", 409)
 	}
 
 	// 加密密码
@@ -216,6 +268,9 @@ func (s *AuthService) Register(ctx context.Context, username, password, email, c
 	err = s.userRepo.CreateUser(ctx, user)
 	if err != nil {
 		s.logger.Error("创建用户失败", "username", username, "error", err.Error())
+		if errors.Is(err, utils.ErrUserAlreadyExists) {
+			return nil, err
+		}
 		return nil, utils.ErrDatabaseInsert
 	}
 
@@ -226,12 +281,16 @@ func (s *AuthService) Register(ctx context.Context, username, password, email, c
 		return nil, utils.ErrDatabaseQuery
 	}
 
-	// 生成JWT token（包含邮箱和地址信息）
-	token, err := s.generateJWT(user.ID, user.Username, user.Email, province, city)
+	// 生成JWT访问token（包含邮箱和地址信息）和刷新token
+	token, err := s.generateJWT(user, province, city)
 	if err != nil {
 		s.logger.Error("生成JWT token失败", "userID", user.ID, "error", err.Error())
 		return nil, utils.ErrInternalServerError
 	}
+	refreshToken, err := s.refreshRepo.IssueRefreshToken(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
 
 	// 读取扩展资料
 	extra, _ := s.userRepo.GetUserProfile(ctx, user.ID)
@@ -252,10 +311,14 @@ func (s *AuthService) Register(ctx context.Context, username, password, email, c
 		Code:    201,
 		Message: "注册成功",
 		Data: struct {
-			Token string             `json:"token"`
-			User  models.UserProfile `json:"user"`
+			Token        string             `json:"token"`
+			RefreshToken string             `json:"refresh_token"`
+			ExpiresIn    int                `json:"expires_in"`
+			User         models.UserProfile `json:"user"`
 		}{
-			Token: token,
+			Token:        token,
+			RefreshToken: refreshToken,
+			ExpiresIn:    int(s.accessTokenTTL().Seconds()),
 			User: models.UserProfile{
 				ID:            user.ID,
 				Username:      user.Username,
@@ -285,7 +348,8 @@ func (s *AuthService) Register(ctx context.Context, username, password, email, c
 		prov := province
 		ct := city
 
-		err := utils.SubmitTask(
+		err := utils.SubmitTaskWithContext(
+			ctx,
 			fmt.Sprintf("register-history-%d-%d", userID, time.Now().UTC().Unix()),
 			func(ctx context.Context) error {
 				if err := s.historyRepo.RecordOperationHistory(userID, userName, "注册", "用户注册账号", userIP); err != nil {
@@ -308,18 +372,158 @@ func (s *AuthService) Register(ctx context.Context, username, password, email, c
 	return response, nil
 }
 
+// lockedError 构造账户锁定错误（剩余时间向上取整到分钟）
+func lockedError(remaining time.Duration) error {
+	minutes := int((remaining + time.Minute - 1) / time.Minute)
+	return utils.NewAppError(utils.ErrAccountLocked,
+		fmt.Sprintf("登录失败次数过多，账户已临时锁定，请%d分钟后再试", minutes), 423)
+}
+
+// accessTokenTTL 访问token有效期（未配置 jwt_extended.access_token_minutes 时沿用 jwt.expire_hours）
+func (s *AuthService) accessTokenTTL() time.Duration {
+	if minutes := s.config.JWTExtended.AccessTokenMinutes; minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	return time.Duration(s.config.JWT.ExpireHours) * time.Hour
+}
+
 // generateJWT 生成JWT token（包含用户邮箱和地址信息用于日志记录）
-func (s *AuthService) generateJWT(userID uint, username, email, province, city string) (string, error) {
-	claims := models.CreateClaims(userID, username, email, province, city, s.config.JWT.Issuer, s.config.JWT.ExpireHours)
+func (s *AuthService) generateJWT(user *models.User, province, city string) (string, error) {
+	jtiBytes := make([]byte, 16)
+	if _, err := rand.Read(jtiBytes); err != nil {
+		s.logger.Error("生成token ID失败", "userID", user.ID, "error", err.Error())
+		return "", err
+	}
+
+	claims := models.CreateClaims(user.ID, user.Username, user.Email, province, city, s.config.JWT.Issuer,
+		hex.EncodeToString(jtiBytes), user.TokenVersion, s.accessTokenTTL())
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	signedToken, err := token.SignedString([]byte(s.config.JWT.SecretKey))
 	if err != nil {
-		s.logger.Error("token签名失败", "userID", userID, "error", err.Error())
+		s.logger.Error("token签名失败", "userID", user.ID, "error", err.Error())
 		return "", err
 	}
 	return signedToken, nil
 }
 
+// RefreshToken 使用刷新token换取新的访问token，刷新token同时轮换（旧token立即失效）
+func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (*models.RefreshTokenResponse, error) {
+	userID, newRefreshToken, err := s.refreshRepo.RotateRefreshToken(ctx, strings.TrimSpace(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		s.logger.Warn("刷新token失败：用户不存在", "userID", userID)
+		return nil, utils.ErrInvalidToken
+	}
+	if user.AccountStatus != 1 {
+		s.logger.Warn("刷新token失败：账户已被禁用", "userID", userID)
+		return nil, utils.ErrAccountDisabled
+	}
+
+	// 刷新请求不携带登录地区信息，地区字段留空
+	token, err := s.generateJWT(user, "", "")
+	if err != nil {
+		s.logger.Error("生成JWT token失败", "userID", user.ID, "error", err.Error())
+		return nil, utils.ErrInternalServerError
+	}
+
+	return &models.RefreshTokenResponse{
+		Token:        token,
+		RefreshToken: newRefreshToken,
+		ExpiresIn:    int(s.accessTokenTTL().Seconds()),
+	}, nil
+}
+
+// Logout 退出登录：当前访问token加入黑名单，并删除刷新token（提供时）
+func (s *AuthService) Logout(ctx context.Context, userID uint, jti string, expiresAt time.Time, refreshToken string) error {
+	s.blacklist.Revoke(jti, expiresAt)
+
+	refreshToken = strings.TrimSpace(refreshToken)
+	if refreshToken == "" {
+		return nil
+	}
+	return s.refreshRepo.RevokeRefreshTokenFamily(ctx, userID, refreshToken)
+}
+
+// RevokeAllSessions 注销用户的全部会话：已签发的访问token全部失效，刷新token全部删除
+func (s *AuthService) RevokeAllSessions(ctx context.Context, userID uint) error {
+	version, err := s.blacklist.RevokeAllForUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.refreshRepo.RevokeAllForUser(ctx, userID); err != nil {
+		return err
+	}
+
+	s.logger.Info("已注销用户全部会话", "userID", userID, "tokenVersion", version)
+	return nil
+}
+
+// DeleteAccount 用户注销账号：验证密码后匿名化个人信息，已签发的token全部失效
+func (s *AuthService) DeleteAccount(ctx context.Context, userID uint, password, clientIP string) error {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		s.logger.Warn("注销账号失败：用户不存在", "userID", userID)
+		return utils.ErrUserNotFound
+	}
+
+	if !utils.CheckPasswordHash(password, user.PasswordHash) {
+		s.logger.Warn("注销账号失败：密码错误", "userID", userID, "ip", clientIP)
+		return utils.ErrInvalidCredentials
+	}
+
+	if err := s.userRepo.AnonymizeUser(ctx, userID); err != nil {
+		return err
+	}
+	// token版本已在注销事务中递增，清除本实例缓存使其立即生效
+	s.blacklist.ForgetVersion(userID)
+
+	s.logger.Info("用户注销账号",
+		"userID", userID,
+		"username", user.Username,
+		"email", utils.SanitizeEmail(user.Email),
+		"ip", clientIP)
+
+	// 异步记录操作历史（保留原用户名便于审计）
+	if s.historyRepo != nil {
+		userName := user.Username
+		err := utils.SubmitTaskWithContext(
+			ctx,
+			fmt.Sprintf("delete-account-history-%d-%d", userID, time.Now().UTC().Unix()),
+			func(ctx context.Context) error {
+				if err := s.historyRepo.RecordOperationHistory(userID, userName, "注销账号", "用户注销账号，个人信息已匿名化", clientIP); err != nil {
+					s.logger.Error("记录操作历史失败", "userID", userID, "error", err.Error())
+					return err
+				}
+				return nil
+			},
+			time.Duration(s.config.AuthPolicy.AsyncTaskTimeout)*time.Second,
+		)
+		if err != nil {
+			s.logger.Warn("提交注销账号历史记录任务失败", "error", err.Error())
+		}
+	}
+
+	return nil
+}
+
+// upgradePasswordHash 异步用当前成本重新哈希密码（失败只记录日志，不影响登录）
+func (s *AuthService) upgradePasswordHash(ctx context.Context, userID uint, password, oldHash string) {
+	err := utils.SubmitTaskWithContext(ctx, fmt.Sprintf("rehash-password-%d", userID), func(ctx context.Context) error {
+		newHash, err := utils.HashPassword(password)
+		if err != nil {
+			return err
+		}
+		return s.userRepo.UpgradePasswordHash(ctx, userID, oldHash, newHash)
+	}, time.Duration(s.config.AuthPolicy.AsyncTaskTimeout)*time.Second)
+	if err != nil {
+		s.logger.Warn("提交密码哈希升级任务失败", "userID", userID, "error", err.Error())
+	}
+}
+
 // ChangePassword 修改密码
 func (s *AuthService) ChangePassword(ctx context.Context, userID uint, currentPassword, newPassword string) error {
 	startTime := time.Now().UTC()
@@ -339,9 +543,9 @@ func (s *AuthService) ChangePassword(ctx context.Context, userID uint, currentPa
 	}
 
 	// 验证新密码强度
-	if !utils.ValidatePassword(newPassword) {
-		s.logger.Warn("修改密码失败：新密码强度不够", "userID", userID)
-		return utils.ErrInvalidPassword
+	if err := s.passwords.Validate(newPassword, user.Username, user.Email); err != nil {
+		s.logger.Warn("修改密码失败：新密码强度不够", "userID", userID, "reason", err.Error())
+		return err
 	}
 
 	// 加密新密码
@@ -361,3 +565,102 @@ func (s *AuthService) ChangePassword(ctx context.Context, userID uint, currentPa
 	s.logger.Info("密码修改成功", "userID", userID, "duration", time.Since(startTime))
 	return nil
 }
+
+// RequestPasswordReset 为邮箱生成密码重置token并异步发送重置邮件
+// 邮箱未注册或发送失败只记录日志，调用方应始终返回相同响应，避免暴露邮箱是否存在
+func (s *AuthService) RequestPasswordReset(ctx context.Context, email, clientIP string) error {
+	email = strings.TrimSpace(email)
+
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, utils.ErrUserNotFound) {
+			s.logger.Info("找回密码：邮箱未注册", "email", utils.SanitizeEmail(email), "ip", clientIP)
+			return nil
+		}
+		return err
+	}
+
+	tokenBytes := make([]byte, s.config.AuthPolicy.ResetTokenBytes)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		s.logger.Error("生成密码重置token失败", "userID", user.ID, "error", err.Error())
+		return utils.ErrInternalServerError
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	expireMinutes := s.config.AuthPolicy.PasswordResetTokenExpireMinutes
+	expiresAt := time.Now().UTC().Add(time.Duration(expireMinutes) * time.Minute)
+	if err := s.userRepo.CreatePasswordResetToken(ctx, user.Email, hashResetToken(token), expiresAt); err != nil {
+		return err
+	}
+
+	link := s.config.AuthPolicy.PasswordResetURL + "?token=" + url.QueryEscape(token)
+	if strings.Contains(s.config.AuthPolicy.PasswordResetURL, "?") {
+		link = s.config.AuthPolicy.PasswordResetURL + "&token=" + url.QueryEscape(token)
+	}
+	subject := "重置密码"
+	body := fmt.Sprintf("%s，你好：\n\n我们收到了重置你账号密码的请求，请在 %d 分钟内打开以下链接设置新密码：\n\n%s\n\n如果这不是你本人的操作，请忽略本邮件，你的密码不会被修改。\n",
+		user.Username, expireMinutes, link)
+
+	to := user.Email
+	userID := user.ID
+	err = utils.SubmitTaskWithContext(
+		ctx,
+		fmt.Sprintf("password-reset-email-%d-%d", userID, time.Now().UTC().Unix()),
+		func(ctx context.Context) error {
+			if err := s.emailSender.Send(ctx, to, subject, body); err != nil {
+				s.logger.Error("发送密码重置邮件失败", "userID", userID, "error", err.Error())
+				return err
+			}
+			s.logger.Info("密码重置邮件已发送", "userID", userID)
+			return nil
+		},
+		time.Duration(s.config.AuthPolicy.AsyncTaskTimeout)*time.Second,
+	)
+	if err != nil {
+		s.logger.Warn("提交密码重置邮件任务失败", "userID", userID, "error", err.Error())
+	}
+
+	return nil
+}
+
+// ResetPassword 使用密码重置token设置新密码（token 只能使用一次），并注销用户的全部会话
+// 新密码先按策略校验（需要token对应的用户信息），校验通过后才消耗token，密码不合规时用户可用同一链接重试
+func (s *AuthService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	tokenHash := hashResetToken(strings.TrimSpace(token))
+	email, err := s.userRepo.GetPasswordResetTokenEmail(ctx, tokenHash)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		s.logger.Warn("重置密码失败：用户不存在", "email", utils.SanitizeEmail(email))
+		return err
+	}
+
+	if err := s.passwords.Validate(newPassword, user.Username, user.Email); err != nil {
+		return err
+	}
+
+	// 先完成哈希再开启事务，token作废与密码更新同时生效
+	hashedPassword, err := utils.HashPassword(newPassword)
+	if err != nil {
+		s.logger.Error("新密码加密失败", "userID", user.ID, "error", err.Error())
+		return utils.ErrInternalServerError
+	}
+
+	if err := s.userRepo.ResetPasswordWithToken(ctx, tokenHash, user.ID, hashedPassword); err != nil {
+		return err
+	}
+	// token版本已在重置事务中递增，清除本实例缓存使其立即生效
+	s.blacklist.ForgetVersion(user.ID)
+
+	s.logger.Info("密码重置成功", "userID", user.ID)
+	return nil
+}
+
+// hashResetToken 计算密码重置token的哈希（数据库只保存哈希）
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,315 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/testutil"
+	"gin/internal/utils"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestRegisterConflictDoesNotRevealWhichFieldExists(t *testing.T) {
+	cases := []struct {
+		name                      string
+		usernameTaken, emailTaken bool
+	}{
+		{"用户名已存在", true, false},
+		{"邮箱已存在", false, true},
+		{"都已存在", true, true},
+	}
+
+	var messages []string
+	for _, tc := range cases {
+		fake, db := newFakeDatabase(t)
+		count := func(taken bool) int64 {
+			if taken {
+				return 1
+			}
+			return 0
+		}
+		fake.OnRows(`SELECT COUNT\(\*\) FROM user_auth WHERE username = \?`, []string{"count"}, []driver.Value{count(tc.usernameTaken)})
+		fake.OnRows(`SELECT COUNT\(\*\) FROM user_auth WHERE email = \?`, []string{"count"}, []driver.Value{count(tc.emailTaken)})
+
+		svc := NewAuthService(config.Default(), NewUserRepository(db), nil, nil, nil, nil)
+		_, err := svc.Register(context.Background(), "alice", "Passw0rd!", "alice@example.com", "127.0.0.1", "", "", "")
+		if err == nil || utils.GetHTTPStatusCode(err) != 409 {
+			t.Fatalf("%s: 应返回409，实际 %v", tc.name, err)
+		}
+		// 两项都要检查，避免通过耗时差异判断哪一项已存在
+		if len(fake.Calls(`WHERE username = \?`)) != 1 || len(fake.Calls(`WHERE email = \?`)) != 1 {
+			t.Fatalf("%s: 用户名和邮箱都应检查", tc.name)
+		}
+		messages = append(messages, err.Error())
+	}
+
+	for _, msg := range messages[1:] {
+		if msg != messages[0] {
+			t.Fatalf("不同冲突项应返回相同的错误信息，实际 %q / %q", messages[0], msg)
+		}
+	}
+}
+
+// recordingEmailSender 记录发送的邮件，err 不为空时模拟发送失败
+type recordingEmailSender struct {
+	sent chan [3]string // to, subject, body
+	err  error
+}
+
+func (s *recordingEmailSender) Send(_ context.Context, to, subject, body string) error {
+	s.sent <- [3]string{to, subject, body}
+	return s.err
+}
+
+// onUserByEmail 预设 GetUserByEmail：只有 alice@example.com 已注册
+func onUserByEmail(fake *testutil.FakeDB) {
+	now := time.Now().UTC()
+	fake.On(`FROM user_auth WHERE email = \?`, func(args []driver.Value) testutil.Response {
+		resp := testutil.Response{Columns: []string{"id", "username", "password_hash", "email", "auth_status", "account_status",
+			"last_login_time", "last_login_ip", "failed_login_count", "locked_until", "token_version", "created_at", "updated_at"}}
+		if args[0] == "alice@example.com" {
+			resp.Rows = [][]driver.Value{{int64(1), "alice", "hash", "alice@example.com", int64(1), int64(1),
+				nil, nil, int64(0), nil, int64(0), now, now}}
+		}
+		return resp
+	})
+	fake.OnExec(`UPDATE password_reset_tokens SET used = 1 WHERE email = \?`, 0, 0)
+	fake.OnExec(`INSERT INTO password_reset_tokens`, 1, 1)
+}
+
+func TestRequestPasswordResetSendsLinkAsynchronously(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	onUserByEmail(fake)
+	cfg := config.Default()
+	cfg.AuthPolicy.PasswordResetURL = "https://example.com/reset"
+	sender := &recordingEmailSender{sent: make(chan [3]string, 1)}
+	svc := NewAuthService(cfg, NewUserRepository(db), nil, nil, nil, sender)
+
+	if err := svc.RequestPasswordReset(context.Background(), " alice@example.com ", "127.0.0.1"); err != nil {
+		t.Fatalf("找回密码失败: %v", err)
+	}
+
+	var mail [3]string
+	select {
+	case mail = <-sender.sent:
+	case <-time.After(2 * time.Second):
+		t.Fatal("应通过 Worker Pool 发送重置邮件")
+	}
+	if mail[0] != "alice@example.com" {
+		t.Fatalf("邮件应发送到注册邮箱，实际 %q", mail[0])
+	}
+	match := regexp.MustCompile(`https://example\.com/reset\?token=([0-9a-f]+)`).FindStringSubmatch(mail[2])
+	if match == nil {
+		t.Fatalf("邮件正文应包含重置链接，实际 %q", mail[2])
+	}
+
+	// 数据库只保存token的哈希，与链接中的明文token对应
+	insert := fake.Calls(`INSERT INTO password_reset_tokens`)
+	if len(insert) != 1 || insert[0].Args[1] != hashResetToken(match[1]) || insert[0].Args[1] == match[1] {
+		t.Fatalf("应保存链接中token的哈希，实际 %v", insert)
+	}
+}
+
+func TestRequestPasswordResetDoesNotRevealEmail(t *testing.T) {
+	cases := []struct {
+		name    string
+		email   string
+		sendErr error
+	}{
+		{"邮箱未注册", "nobody@example.com", nil},
+		{"邮件发送失败", "alice@example.com", errors.New("smtp down")},
+	}
+	for _, tc := range cases {
+		fake, db := newFakeDatabase(t)
+		onUserByEmail(fake)
+		sender := &recordingEmailSender{sent: make(chan [3]string, 1), err: tc.sendErr}
+		svc := NewAuthService(config.Default(), NewUserRepository(db), nil, nil, nil, sender)
+
+		if err := svc.RequestPasswordReset(context.Background(), tc.email, "127.0.0.1"); err != nil {
+			t.Fatalf("%s: 应返回与成功相同的结果，实际 %v", tc.name, err)
+		}
+
+		if tc.sendErr != nil {
+			select {
+			case <-sender.sent:
+			case <-time.After(2 * time.Second):
+				t.Fatalf("%s: 应尝试发送邮件", tc.name)
+			}
+		} else if len(fake.Calls(`password_reset_tokens`)) != 0 || len(sender.sent) != 0 {
+			t.Fatalf("%s: 未注册的邮箱不应生成token或发送邮件", tc.name)
+		}
+	}
+}
+
+func TestNewEmailSender(t *testing.T) {
+	cfg := config.Default().SMTP
+	if _, ok := NewEmailSender(&cfg).(*NoopEmailSender); !ok {
+		t.Fatal("默认配置应使用 noop 发送器，未配置SMTP时流程仍可用")
+	}
+	cfg.Driver = "smtp"
+	if _, ok := NewEmailSender(&cfg).(*SMTPEmailSender); !ok {
+		t.Fatal("driver=smtp 时应使用SMTP发送器")
+	}
+}
+
+// onResetToken 预设密码重置token查询：valid 为 false 时模拟token已被并发使用
+func onResetToken(fake *testutil.FakeDB, tokenHash string, valid bool) {
+	fake.OnRows(`SELECT email FROM password_reset_tokens WHERE token = \?`, []string{"email"}, []driver.Value{"alice@example.com"})
+	fake.On(`SELECT t.id FROM password_reset_tokens t`, func(args []driver.Value) testutil.Response {
+		resp := testutil.Response{Columns: []string{"id"}}
+		if valid && args[0] == tokenHash {
+			resp.Rows = [][]driver.Value{{int64(5)}}
+		}
+		return resp
+	})
+	fake.OnExec(`UPDATE password_reset_tokens SET used = 1 WHERE id = \?`, 0, 1)
+	fake.OnExec(`UPDATE user_auth SET password_hash = \?`, 0, 1)
+	fake.OnExec(`DELETE FROM refresh_tokens WHERE user_id = \?`, 0, 2)
+}
+
+func TestResetPasswordConsumesTokenAndRevokesSessions(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	onUserByEmail(fake)
+	onResetToken(fake, hashResetToken("reset-token"), true)
+	cfg := config.Default()
+	repo := NewUserRepository(db)
+	blacklist := NewTokenBlacklist(cfg, repo)
+	svc := NewAuthService(cfg, repo, nil, nil, blacklist, nil)
+
+	// 缓存中的旧版本在重置后应被清除
+	fake.OnRows(`SELECT token_version FROM user_auth WHERE id = \?`, []string{"token_version"}, []driver.Value{int64(0)})
+	if _, err := blacklist.CurrentVersion(context.Background(), 1); err != nil {
+		t.Fatalf("查询token版本失败: %v", err)
+	}
+
+	if err := svc.ResetPassword(context.Background(), " reset-token ", "N3wPassw0rd!"); err != nil {
+		t.Fatalf("重置密码失败: %v", err)
+	}
+
+	// token作废、密码更新、会话注销在同一事务内按顺序执行
+	var statements []string
+	for _, call := range fake.Calls(`password_reset_tokens t|SET used = 1 WHERE id|SET password_hash|refresh_tokens|^COMMIT$|^ROLLBACK$`) {
+		statements = append(statements, call.Query)
+	}
+	if len(statements) != 5 || statements[4] != "COMMIT" {
+		t.Fatalf("应在一个事务内完成重置并提交，实际 %q", statements)
+	}
+	update := fake.Calls(`UPDATE user_auth SET password_hash = \?`)
+	if !regexp.MustCompile(`token_version = token_version \+ 1`).MatchString(update[0].Query) {
+		t.Fatalf("重置密码应递增token版本，实际 %q", update[0].Query)
+	}
+	if hash, _ := update[0].Args[0].(string); !utils.CheckPasswordHash("N3wPassw0rd!", hash) || update[0].Args[2] != int64(1) {
+		t.Fatalf("应保存新密码的哈希，实际 %v", update[0].Args)
+	}
+	if calls := fake.Calls(`DELETE FROM refresh_tokens WHERE user_id = \?`); calls[0].Args[0] != int64(1) {
+		t.Fatalf("应删除用户的全部刷新token，实际 %v", calls[0].Args)
+	}
+
+	if _, err := blacklist.CurrentVersion(context.Background(), 1); err != nil {
+		t.Fatalf("查询token版本失败: %v", err)
+	}
+	if calls := fake.Calls(`SELECT token_version FROM user_auth`); len(calls) != 2 {
+		t.Fatalf("重置后应重新查询token版本，实际查询 %d 次", len(calls))
+	}
+}
+
+func TestResetPasswordTokenAlreadyUsed(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	onUserByEmail(fake)
+	onResetToken(fake, hashResetToken("reset-token"), false)
+	cfg := config.Default()
+	repo := NewUserRepository(db)
+	svc := NewAuthService(cfg, repo, nil, nil, NewTokenBlacklist(cfg, repo), nil)
+
+	err := svc.ResetPassword(context.Background(), "reset-token", "N3wPassw0rd!")
+	if err == nil || utils.GetHTTPStatusCode(err) != 400 {
+		t.Fatalf("token已被使用时应返回400，实际 %v", err)
+	}
+	if len(fake.Calls(`SET password_hash`)) != 0 || len(fake.Calls(`refresh_tokens`)) != 0 {
+		t.Fatal("token无效时不应修改密码或注销会话")
+	}
+	if len(fake.Calls(`^ROLLBACK$`)) != 1 {
+		t.Fatal("token无效时事务应回滚")
+	}
+
+	// 新密码不合规时不消耗token，用户可用同一链接重试
+	fake, db = newFakeDatabase(t)
+	onUserByEmail(fake)
+	onResetToken(fake, hashResetToken("reset-token"), true)
+	repo = NewUserRepository(db)
+	svc = NewAuthService(cfg, repo, nil, nil, NewTokenBlacklist(cfg, repo), nil)
+	if err := svc.ResetPassword(context.Background(), "reset-token", "123"); err == nil {
+		t.Fatal("弱密码应被拒绝")
+	}
+	if len(fake.Calls(`password_reset_tokens t`)) != 0 {
+		t.Fatal("新密码不合规时不应消耗token")
+	}
+}
+
+func TestLoginUpgradesWeakerHash(t *testing.T) {
+	cfg := config.Default()
+	cfg.SecurityPassword = config.SecurityPasswordConfig{BcryptCostMin: 4, BcryptCostMax: 6, BcryptCost: 5, PasswordMaxBytes: 72, RehashOnLogin: true}
+	utils.ConfigurePasswordHashing(&cfg.SecurityPassword)
+	t.Cleanup(func() { utils.ConfigurePasswordHashing(&config.Default().SecurityPassword) })
+
+	login := func(storedCost int, rehash bool) *testutil.FakeDB {
+		fake, db := newFakeDatabase(t)
+		hash, _ := utils.HashPasswordWithConfig("Passw0rd!", storedCost, &cfg.SecurityPassword)
+		now := time.Now().UTC()
+		fake.OnRows(`FROM user_auth WHERE username = \?`, []string{"id", "username", "password_hash", "email", "auth_status", "account_status",
+			"last_login_time", "last_login_ip", "failed_login_count", "locked_until", "token_version", "created_at", "updated_at"},
+			[]driver.Value{int64(1), "alice", hash, "alice@example.com", int64(1), int64(1), nil, nil, int64(0), nil, int64(0), now, now})
+		fake.OnExec(`UPDATE user_auth SET last_login_time`, 0, 1)
+		fake.OnExec(`INSERT INTO refresh_tokens`, 1, 1)
+		fake.OnRows(`FROM user_profile WHERE user_id = \?`, []string{"user_id"})
+		fake.OnExec(`UPDATE user_auth SET password_hash = \? WHERE id = \? AND password_hash = \?`, 0, 1)
+
+		c := *cfg
+		c.SecurityPassword.RehashOnLogin = rehash
+		svc := NewAuthService(&c, NewUserRepository(db), nil, NewRefreshTokenRepository(db, &c), nil, nil)
+		if _, err := svc.Login(context.Background(), "alice", "Passw0rd!", "127.0.0.1", "", ""); err != nil {
+			t.Fatalf("登录失败: %v", err)
+		}
+		return fake
+	}
+	waitUpgrade := func(fake *testutil.FakeDB, timeout time.Duration) []testutil.Call {
+		deadline := time.Now().Add(timeout)
+		for time.Now().Before(deadline) {
+			if calls := fake.Calls(`SET password_hash = \? WHERE id = \? AND password_hash = \?`); len(calls) > 0 {
+				return calls
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return nil
+	}
+
+	// 存储的哈希成本低于当前成本：登录成功后异步升级，且只在哈希未被修改时覆盖
+	fake := login(4, true)
+	calls := waitUpgrade(fake, 2*time.Second)
+	if len(calls) != 1 {
+		t.Fatal("成本较低的哈希应在登录成功后升级")
+	}
+	newHash, _ := calls[0].Args[0].(string)
+	if cost, _ := bcrypt.Cost([]byte(newHash)); cost != 5 || !utils.CheckPasswordHash("Passw0rd!", newHash) {
+		t.Fatalf("应使用当前成本重新哈希同一密码，实际成本 %d", cost)
+	}
+	if oldHash, _ := calls[0].Args[2].(string); !utils.NeedsRehash(oldHash) {
+		t.Fatalf("更新条件应为旧哈希，避免覆盖期间修改的密码，实际 %v", calls[0].Args)
+	}
+
+	// 成本已是当前成本或关闭升级时不重新哈希
+	for _, tc := range []struct {
+		cost   int
+		rehash bool
+	}{{5, true}, {4, false}} {
+		if calls := waitUpgrade(login(tc.cost, tc.rehash), 200*time.Millisecond); calls != nil {
+			t.Fatalf("成本 %d、升级开关 %v 时不应升级哈希", tc.cost, tc.rehash)
+		}
+	}
+}
@@ -3,10 +3,12 @@ package services
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
 	"gin/internal/models"
+	"gin/internal/utils"
 )
 
 // BatchRepository 批量查询仓库（解决N+1查询问题）
@@ -347,3 +349,47 @@ func (r *BatchRepository) BatchGetCommentCounts(ctx context.Context, articleIDs
 
 	return counts, rows.Err()
 }
+
+// inPlaceholders 生成 IN 查询的占位符（如 ?,?,?）和对应参数
+func inPlaceholders(ids []uint) (string, []interface{}) {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return strings.TrimSuffix(strings.Repeat("?,", len(ids)), ","), args
+}
+
+// uniqueIDs 按原顺序去除重复的ID
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	unique := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// checkBatchOwnership 校验批量操作中的每个ID都存在且属于 userID（owners 为查询到的 id -> user_id）
+// 不存在返回404、不属于该用户返回403，错误信息中列出有问题的ID
+func checkBatchOwnership(ids []uint, owners map[uint]uint, userID uint) error {
+	var missing, foreign []string
+	for _, id := range ids {
+		ownerID, ok := owners[id]
+		switch {
+		case !ok:
+			missing = append(missing, strconv.FormatUint(uint64(id), 10))
+		case ownerID != userID:
+			foreign = append(foreign, strconv.FormatUint(uint64(id), 10))
+		}
+	}
+	if len(missing) > 0 {
+		return utils.NewAppError(utils.ErrResourceNotFound, "以下ID不存在或已删除: "+strings.Join(missing, ","), 404)
+	}
+	if len(foreign) > 0 {
+		return utils.NewAppError(utils.ErrUnauthorized, "只能删除自己的内容: "+strings.Join(foreign, ","), 403)
+	}
+	return nil
+}
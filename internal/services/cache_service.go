@@ -2,8 +2,10 @@ package services
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"gin/internal/config"
@@ -17,12 +19,102 @@ type CacheService struct {
 	cache       *utils.MemoryCache
 	articleRepo *ArticleRepository
 	logger      utils.Logger
-	config      *config.CacheConfig
+	config      atomic.Pointer[config.CacheConfig] // 支持配置热更新时原子替换
 
 	// 分组缓存（不同类型数据使用不同的LRU缓存）
 	articleCache *utils.LRUCache // 文章缓存
 	userCache    *utils.LRUCache // 用户缓存
 	listCache    *utils.LRUCache // 列表缓存
+
+	// 分类/标签列表缓存：并发未命中合并为一次查询，失效时递增代数丢弃进行中的旧加载结果
+	taxonomyLoads utils.CallGroup
+	categories    taxonomyCacheState
+	tags          taxonomyCacheState
+
+	// 文章详情：同一文章的并发未命中只查询一次；文章详情失效时递增该文章的代数，丢弃进行中的旧加载结果
+	detailLoads       utils.CallGroup
+	detailGenerations detailGenerations
+}
+
+// detailGenerationShards 文章详情代数的分片数量
+const detailGenerationShards = 32
+
+// detailGenerations 按文章ID分片记录的文章详情缓存代数（一篇文章失效不影响其他文章进行中的加载）
+type detailGenerations struct {
+	epoch  atomic.Uint64 // 清空全部缓存时递增
+	shards [detailGenerationShards]detailGenerationShard
+}
+
+// detailGenerationShard 一个分片内各文章的代数（只记录失效过的文章）
+type detailGenerationShard struct {
+	mu          sync.Mutex
+	generations map[uint]uint64
+}
+
+// detailGeneration 某篇文章详情在某一时刻的代数
+type detailGeneration struct {
+	epoch   uint64
+	article uint64
+}
+
+func (g *detailGenerations) shard(articleID uint) *detailGenerationShard {
+	return &g.shards[articleID%detailGenerationShards]
+}
+
+// load 返回文章当前的代数
+func (g *detailGenerations) load(articleID uint) detailGeneration {
+	epoch := g.epoch.Load()
+	sh := g.shard(articleID)
+	sh.mu.Lock()
+	article := sh.generations[articleID]
+	sh.mu.Unlock()
+	return detailGeneration{epoch: epoch, article: article}
+}
+
+// bump 递增文章的代数
+func (g *detailGenerations) bump(articleID uint) {
+	sh := g.shard(articleID)
+	sh.mu.Lock()
+	if sh.generations == nil {
+		sh.generations = make(map[uint]uint64)
+	}
+	sh.generations[articleID]++
+	sh.mu.Unlock()
+}
+
+// reset 使所有文章的代数失效并释放记录（递增 epoch 后各文章代数可以从0重新计数）
+func (g *detailGenerations) reset() {
+	g.epoch.Add(1)
+	for i := range g.shards {
+		sh := &g.shards[i]
+		sh.mu.Lock()
+		sh.generations = nil
+		sh.mu.Unlock()
+	}
+}
+
+// taxonomyCacheState 分类或标签列表缓存的代数和命中统计
+type taxonomyCacheState struct {
+	generation atomic.Uint64
+	hits       atomic.Uint64
+	misses     atomic.Uint64
+}
+
+// TaxonomyCacheStats 分类或标签列表缓存的命中统计
+type TaxonomyCacheStats struct {
+	Hits     uint64  `json:"hits"`
+	Misses   uint64  `json:"misses"`
+	HitRatio float64 `json:"hit_ratio"` // 0-1，尚无请求时为0
+}
+
+// stats 返回命中统计快照
+func (st *taxonomyCacheState) stats() TaxonomyCacheStats {
+	hits, misses := st.hits.Load(), st.misses.Load()
+	result := TaxonomyCacheStats{Hits: hits, Misses: misses}
+	if total := hits + misses; total > 0 {
+		result.HitRatio = float64(hits) / float64(total)
+	}
+	return result
 }
 
 // NewCacheService 创建缓存服务
@@ -33,7 +125,6 @@ func NewCacheService(articleRepo *ArticleRepository, cfg *config.Config) *CacheS
 		cache:       utils.GetCache(),
 		articleRepo: articleRepo,
 		logger:      logger,
-		config:      &cfg.Cache,
 
 		// 创建分组缓存（从配置读取）
 		articleCache: utils.NewLRUCache(utils.LRUCacheConfig{
@@ -53,6 +144,11 @@ func NewCacheService(articleRepo *ArticleRepository, cfg *config.Config) *CacheS
 		}),
 	}
 
+	service.config.Store(&cfg.Cache)
+
+	// 文章仓库在写入成功后直接失效缓存，避免遗漏调用方
+	articleRepo.SetCacheInvalidator(service)
+
 	logger.Info("缓存服务已初始化",
 		"articleCacheCapacity", cfg.Cache.Article.Capacity,
 		"userCacheCapacity", cfg.Cache.User.Capacity,
@@ -72,24 +168,34 @@ const (
 	cacheKeyOnlineCount       = "chat:online:count"
 )
 
+// UpdateConfig 替换缓存配置（配置热更新），新TTL对之后写入的缓存生效
+func (s *CacheService) UpdateConfig(cfg *config.CacheConfig) {
+	s.config.Store(cfg)
+	s.logger.Info("缓存配置已更新",
+		"categoriesTTLMinutes", cfg.CategoriesTTLMinutes,
+		"tagsTTLMinutes", cfg.TagsTTLMinutes,
+		"articleDetailTTLMinutes", cfg.ArticleDetailTTLMinutes,
+		"onlineCountTTLSeconds", cfg.OnlineCountTTLSeconds)
+}
+
 // getCategoriesTTL 获取分类缓存TTL
 func (s *CacheService) getCategoriesTTL() time.Duration {
-	return time.Duration(s.config.CategoriesTTLMinutes) * time.Minute
+	return time.Duration(s.config.Load().CategoriesTTLMinutes) * time.Minute
 }
 
 // getTagsTTL 获取标签缓存TTL
 func (s *CacheService) getTagsTTL() time.Duration {
-	return time.Duration(s.config.TagsTTLMinutes) * time.Minute
+	return time.Duration(s.config.Load().TagsTTLMinutes) * time.Minute
 }
 
 // getArticleDetailTTL 获取文章详情缓存TTL
 func (s *CacheService) getArticleDetailTTL() time.Duration {
-	return time.Duration(s.config.ArticleDetailTTLMinutes) * time.Minute
+	return time.Duration(s.config.Load().ArticleDetailTTLMinutes) * time.Minute
 }
 
 // getOnlineCountTTL 获取在线人数缓存TTL
 func (s *CacheService) getOnlineCountTTL() time.Duration {
-	return time.Duration(s.config.OnlineCountTTLSeconds) * time.Second
+	return time.Duration(s.config.Load().OnlineCountTTLSeconds) * time.Second
 }
 
 // =============================================================================
@@ -98,30 +204,32 @@ func (s *CacheService) getOnlineCountTTL() time.Duration {
 
 // GetArticleCategories 获取文章分类（带缓存）
 func (s *CacheService) GetArticleCategories(ctx context.Context) ([]models.ArticleCategory, error) {
-	// 尝试从缓存获取
 	if cached, ok := s.cache.Get(cacheKeyArticleCategories); ok {
 		if categories, ok := cached.([]models.ArticleCategory); ok {
+			s.categories.hits.Add(1)
 			return categories, nil
 		}
 	}
+	s.categories.misses.Add(1)
+	return s.loadArticleCategories(ctx)
+}
 
-	// 缓存未命中，从数据库获取
-	categories, err := s.articleRepo.GetAllCategories(ctx)
+// loadArticleCategories 从数据库加载分类并写入缓存（并发调用合并为一次查询）
+func (s *CacheService) loadArticleCategories(ctx context.Context) ([]models.ArticleCategory, error) {
+	result, err := s.loadTaxonomy(ctx, cacheKeyArticleCategories, &s.categories, s.getCategoriesTTL(),
+		func(ctx context.Context) (interface{}, int, error) {
+			categories, err := s.articleRepo.GetAllCategories(ctx)
+			return categories, len(categories), err
+		})
 	if err != nil {
 		return nil, err
 	}
-
-	// 写入缓存
-	ttl := s.getCategoriesTTL()
-	s.cache.SetWithTTL(cacheKeyArticleCategories, categories, ttl)
-	s.logger.Info("分类数据已缓存", "count", len(categories), "ttl", ttl)
-
-	return categories, nil
+	return result.([]models.ArticleCategory), nil
 }
 
 // InvalidateArticleCategories 使分类缓存失效
 func (s *CacheService) InvalidateArticleCategories() {
-	s.cache.Delete(cacheKeyArticleCategories)
+	s.invalidateTaxonomy(cacheKeyArticleCategories, &s.categories)
 	s.logger.Info("分类缓存已失效")
 }
 
@@ -131,79 +239,150 @@ func (s *CacheService) InvalidateArticleCategories() {
 
 // GetArticleTags 获取文章标签（带缓存）
 func (s *CacheService) GetArticleTags(ctx context.Context) ([]models.ArticleTag, error) {
-	// 尝试从缓存获取
 	if cached, ok := s.cache.Get(cacheKeyArticleTags); ok {
 		if tags, ok := cached.([]models.ArticleTag); ok {
+			s.tags.hits.Add(1)
 			return tags, nil
 		}
 	}
+	s.tags.misses.Add(1)
+	return s.loadArticleTags(ctx)
+}
 
-	// 缓存未命中，从数据库获取
-	tags, err := s.articleRepo.GetAllTags(ctx)
+// loadArticleTags 从数据库加载标签并写入缓存（并发调用合并为一次查询）
+func (s *CacheService) loadArticleTags(ctx context.Context) ([]models.ArticleTag, error) {
+	result, err := s.loadTaxonomy(ctx, cacheKeyArticleTags, &s.tags, s.getTagsTTL(),
+		func(ctx context.Context) (interface{}, int, error) {
+			tags, err := s.articleRepo.GetAllTags(ctx)
+			return tags, len(tags), err
+		})
 	if err != nil {
 		return nil, err
 	}
-
-	// 写入缓存
-	ttl := s.getTagsTTL()
-	s.cache.SetWithTTL(cacheKeyArticleTags, tags, ttl)
-	s.logger.Info("标签数据已缓存", "count", len(tags), "ttl", ttl)
-
-	return tags, nil
+	return result.([]models.ArticleTag), nil
 }
 
-// InvalidateArticleTags 使标签缓存失效
+// InvalidateArticleTags 使标签缓存失效（标签新建、重命名、合并后调用）
 func (s *CacheService) InvalidateArticleTags() {
-	s.cache.Delete(cacheKeyArticleTags)
+	s.invalidateTaxonomy(cacheKeyArticleTags, &s.tags)
 	s.logger.Info("标签缓存已失效")
 }
 
+// loadTaxonomy 合并并发加载并写入缓存
+// 查询不随首个调用方的请求取消而中断（结果会共享给其他等待方），使用独立的查询超时；
+// 加载期间缓存被失效时不写入结果，避免旧数据覆盖失效
+func (s *CacheService) loadTaxonomy(ctx context.Context, key string, state *taxonomyCacheState, ttl time.Duration,
+	load func(ctx context.Context) (interface{}, int, error)) (interface{}, error) {
+	generation := state.generation.Load()
+	flightKey := key + "#" + strconv.FormatUint(generation, 10)
+
+	result, err, _ := s.taxonomyLoads.Do(flightKey, func() (interface{}, error) {
+		loadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.articleRepo.db.GetQueryTimeout())
+		defer cancel()
+
+		value, count, err := load(loadCtx)
+		if err != nil {
+			return nil, err
+		}
+		if state.generation.Load() == generation {
+			s.cache.SetWithTTL(key, value, ttl)
+			s.logger.Info("列表数据已缓存", "key", key, "count", count, "ttl", ttl)
+		}
+		return value, nil
+	})
+	return result, err
+}
+
+// invalidateTaxonomy 删除缓存并递增代数
+func (s *CacheService) invalidateTaxonomy(key string, state *taxonomyCacheState) {
+	state.generation.Add(1)
+	s.cache.Delete(key)
+}
+
 // =============================================================================
 // 文章详情缓存
 // =============================================================================
 
+// articleDetailKey 文章详情缓存键（缓存与用户无关的部分，点赞状态按请求补充）
+func articleDetailKey(articleID uint) string {
+	return cacheKeyArticlePrefix + strconv.FormatUint(uint64(articleID), 10)
+}
+
 // GetArticleDetail 获取文章详情（带缓存）
 func (s *CacheService) GetArticleDetail(ctx context.Context, articleID uint, userID uint) (*models.ArticleDetailResponse, error) {
-	cacheKey := fmt.Sprintf("%s%d:user%d", cacheKeyArticlePrefix, articleID, userID)
-
-	// 尝试从缓存获取
-	if cached, ok := s.cache.Get(cacheKey); ok {
-		// 尝试类型断言
-		if article, ok := cached.(*models.ArticleDetailResponse); ok {
-			return article, nil
-		}
+	var cachedArticle *models.ArticleDetailResponse
+	if cached, ok := s.articleCache.Get(articleDetailKey(articleID)); ok {
+		cachedArticle, _ = cached.(*models.ArticleDetailResponse)
+	}
 
-		// 如果类型断言失败，尝试JSON反序列化
-		if jsonData, ok := cached.(string); ok {
-			var article models.ArticleDetailResponse
-			if err := json.Unmarshal([]byte(jsonData), &article); err == nil {
-				return &article, nil
-			}
+	// 缓存未命中，从数据库获取（不带用户，结果可被所有用户共享；并发请求只查询一次）
+	if cachedArticle == nil {
+		article, err := s.RefreshArticleDetail(ctx, articleID)
+		if err != nil {
+			return nil, err
 		}
+		cachedArticle = article
 	}
 
-	// 缓存未命中，从数据库获取
+	// 复制一份再填充点赞状态，避免修改共享的缓存对象
+	article := *cachedArticle
+	if userID > 0 {
+		article.IsLiked = s.articleRepo.IsArticleLiked(ctx, articleID, userID)
+	}
+	return &article, nil
+}
 
-	// 使用优化版本的查询（JOIN减少查询次数）
-	article, err := s.articleRepo.GetArticleByID(ctx, articleID, userID)
+// RefreshArticleDetail 从数据库重新加载文章详情并写入缓存
+// 同一文章的并发调用合并为一次查询，返回的对象被所有调用方共享，不能修改
+func (s *CacheService) RefreshArticleDetail(ctx context.Context, articleID uint) (*models.ArticleDetailResponse, error) {
+	generation := s.detailGenerations.load(articleID)
+	key := articleDetailKey(articleID)
+	flightKey := key + "#" + strconv.FormatUint(generation.epoch, 10) + "." + strconv.FormatUint(generation.article, 10)
+
+	result, err, _ := s.detailLoads.Do(flightKey, func() (interface{}, error) {
+		// 不随首个调用方的请求取消而中断（结果会共享给其他等待方），使用独立的查询超时
+		loadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.articleRepo.db.GetQueryTimeout())
+		defer cancel()
+
+		article, err := s.articleRepo.GetArticleByID(loadCtx, articleID, 0)
+		if err != nil {
+			return nil, err
+		}
+		// 降级结果只返回给本次调用方，不缓存，下次请求重新加载
+		if !article.Partial && s.detailGenerations.load(articleID) == generation {
+			s.articleCache.SetWithTTL(key, article, s.getArticleDetailTTL())
+		}
+		return article, nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	return result.(*models.ArticleDetailResponse), nil
+}
 
-	// 写入缓存（使用较短的TTL）
-	s.cache.SetWithTTL(cacheKey, article, s.getArticleDetailTTL())
-	return article, nil
+// ArticleDetailTTL 获取文章详情缓存的剩余有效期（未缓存返回false）
+func (s *CacheService) ArticleDetailTTL(articleID uint) (time.Duration, bool) {
+	return s.articleCache.TTL(articleDetailKey(articleID))
 }
 
 // InvalidateArticleDetail 使文章详情缓存失效
 func (s *CacheService) InvalidateArticleDetail(articleID uint) {
-	// 文章详情缓存包含用户ID，需要清除所有相关缓存
-	// 简单方案：使用前缀匹配删除（内存缓存不支持模式匹配，这里手动处理）
-	// 更好的方案是使用Redis的SCAN + DEL
+	s.detailGenerations.bump(articleID)
+	s.articleCache.Delete(articleDetailKey(articleID))
+	s.logger.Debug("文章详情缓存已失效", "articleID", articleID)
+}
+
+// InvalidateArticle 使单篇文章相关的缓存失效（缓存中没有该文章时为空操作）
+func (s *CacheService) InvalidateArticle(articleID uint) {
+	s.InvalidateArticleDetail(articleID)
+}
 
-	// 由于TTL较短，简单记录日志即可
-	// 缓存会自动过期
-	s.logger.Info("文章详情缓存将在TTL后自动失效", "articleID", articleID, "ttl", s.getArticleDetailTTL())
+// InvalidateArticleLists 使文章列表相关的缓存失效（分类/标签中的文章计数、列表缓存）
+func (s *CacheService) InvalidateArticleLists() {
+	s.invalidateTaxonomy(cacheKeyArticleCategories, &s.categories)
+	s.invalidateTaxonomy(cacheKeyArticleTags, &s.tags)
+	s.listCache.Clear()
+	s.logger.Debug("文章列表缓存已失效")
 }
 
 // =============================================================================
@@ -244,6 +423,9 @@ func (s *CacheService) GetCacheStats() map[string]interface{} {
 
 // ClearAllCache 清空所有缓存（谨慎使用）
 func (s *CacheService) ClearAllCache() {
+	s.detailGenerations.reset()
+	s.categories.generation.Add(1)
+	s.tags.generation.Add(1)
 	s.cache.Clear()
 	s.articleCache.Clear()
 	s.userCache.Clear()
@@ -253,31 +435,38 @@ func (s *CacheService) ClearAllCache() {
 
 // warmupCache 缓存预热
 func (s *CacheService) warmupCache() {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(s.config.WarmupTimeout)*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(s.config.Load().WarmupTimeout)*time.Second)
 	defer cancel()
 
 	s.logger.Info("开始缓存预热...")
 
 	// 预热分类和标签（最常访问的数据）
-	if categories, err := s.articleRepo.GetAllCategories(ctx); err == nil {
-		s.cache.SetWithTTL(cacheKeyArticleCategories, categories, s.getCategoriesTTL())
+	if categories, err := s.loadArticleCategories(ctx); err == nil {
 		s.logger.Info("分类数据已预热", "count", len(categories))
 	}
 
-	if tags, err := s.articleRepo.GetAllTags(ctx); err == nil {
-		s.cache.SetWithTTL(cacheKeyArticleTags, tags, s.getTagsTTL())
+	if tags, err := s.loadArticleTags(ctx); err == nil {
 		s.logger.Info("标签数据已预热", "count", len(tags))
 	}
 
 	s.logger.Info("缓存预热完成")
 }
 
+// TaxonomyCacheStats 获取分类和标签列表缓存的命中统计
+func (s *CacheService) TaxonomyCacheStats() map[string]TaxonomyCacheStats {
+	return map[string]TaxonomyCacheStats{
+		"article_categories": s.categories.stats(),
+		"article_tags":       s.tags.stats(),
+	}
+}
+
 // GetAllCacheStats 获取所有缓存统计
 func (s *CacheService) GetAllCacheStats() map[string]interface{} {
 	return map[string]interface{}{
-		"global":  s.cache.Stats(),
-		"article": s.articleCache.Stats(),
-		"user":    s.userCache.Stats(),
-		"list":    s.listCache.Stats(),
+		"global":   s.cache.Stats(),
+		"article":  s.articleCache.Stats(),
+		"user":     s.userCache.Stats(),
+		"list":     s.listCache.Stats(),
+		"taxonomy": s.TaxonomyCacheStats(),
 	}
 }
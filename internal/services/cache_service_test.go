@@ -0,0 +1,306 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/testutil"
+	"gin/internal/utils"
+)
+
+func TestDetailGenerationsArePerArticle(t *testing.T) {
+	var g detailGenerations
+	const a, b = uint(1), uint(1 + detailGenerationShards) // 同一分片的两篇文章
+
+	before := g.load(a)
+	g.bump(b)
+	g.bump(2)
+	if g.load(a) != before {
+		t.Fatal("其他文章失效不应影响该文章进行中的加载")
+	}
+
+	g.bump(a)
+	if g.load(a) == before {
+		t.Fatal("文章失效后代数应变化")
+	}
+
+	aBefore, bBefore := g.load(a), g.load(b)
+	g.reset()
+	if g.load(a) == aBefore || g.load(b) == bBefore {
+		t.Fatal("清空全部缓存后所有文章的代数都应变化")
+	}
+}
+
+func TestDetailGenerationsConcurrentBump(t *testing.T) {
+	var g detailGenerations
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(id uint) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				g.bump(id % 3)
+				_ = g.load(id)
+			}
+		}(uint(i))
+	}
+	wg.Wait()
+
+	total := g.load(0).article + g.load(1).article + g.load(2).article
+	if total != 800 {
+		t.Fatalf("并发递增后代数总和应为 800，实际 %d", total)
+	}
+}
+
+// onLikeCountedArticle 预设文章详情和点赞查询，点赞数保存在 likes 中随点赞/取消点赞变化
+func onLikeCountedArticle(fake *testutil.FakeDB, likes *atomic.Int64) {
+	onArticleDetail(fake)
+	now := time.Now().UTC()
+	fake.On(`FROM articles a INNER JOIN user_auth ua ON a.user_id = ua.id LEFT JOIN user_profile up ON ua.id = up.user_id WHERE a.id = \? AND a.status != 2`,
+		func(args []driver.Value) testutil.Response {
+			return testutil.Response{
+				Columns: []string{"id", "user_id", "title", "description", "content", "status", "view_count", "like_count",
+					"comment_count", "version", "created_at", "updated_at", "username", "nickname", "avatar"},
+				Rows: [][]driver.Value{{args[0], int64(7), "title", "desc", "content", int64(1), int64(0), likes.Load(),
+					int64(0), int64(1), now, now, "alice", "Alice", ""}},
+			}
+		})
+	fake.On(`SELECT id FROM article_likes WHERE article_id = \? AND user_id = \?`, func([]driver.Value) testutil.Response {
+		resp := testutil.Response{Columns: []string{"id"}}
+		if likes.Load() > 0 {
+			resp.Rows = [][]driver.Value{{int64(1)}}
+		}
+		return resp
+	})
+	fake.OnExec(`INSERT INTO article_likes`, 1, 1)
+	fake.OnExec(`DELETE FROM article_likes`, 0, 1)
+	fake.On(`UPDATE articles SET like_count = like_count \+ 1`, func([]driver.Value) testutil.Response {
+		likes.Add(1)
+		return testutil.Response{RowsAffected: 1}
+	})
+	fake.On(`UPDATE articles SET like_count = GREATEST`, func([]driver.Value) testutil.Response {
+		likes.Add(-1)
+		return testutil.Response{RowsAffected: 1}
+	})
+}
+
+func TestToggleArticleLikeInvalidatesDetailCache(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	var likes atomic.Int64
+	onLikeCountedArticle(fake, &likes)
+	cfg := config.Default()
+	repo := NewArticleRepository(db, cfg)
+	cacheSvc := NewCacheService(repo, cfg)
+	ctx := context.Background()
+
+	article, err := cacheSvc.GetArticleDetail(ctx, 5, 0)
+	if err != nil || article.LikeCount != 0 {
+		t.Fatalf("获取文章详情失败: %v %+v", err, article)
+	}
+	if _, err := repo.ToggleArticleLike(ctx, 5, 1); err != nil {
+		t.Fatalf("点赞失败: %v", err)
+	}
+	if article, _ := cacheSvc.GetArticleDetail(ctx, 5, 0); article.LikeCount != 1 {
+		t.Fatalf("点赞后缓存应失效并返回新的点赞数，实际 %d", article.LikeCount)
+	}
+	if _, err := repo.ToggleArticleLike(ctx, 5, 1); err != nil {
+		t.Fatalf("取消点赞失败: %v", err)
+	}
+	if article, _ := cacheSvc.GetArticleDetail(ctx, 5, 0); article.LikeCount != 0 {
+		t.Fatalf("取消点赞后缓存应失效，实际 %d", article.LikeCount)
+	}
+
+	// 写入失败时保留缓存
+	fake.OnError(`INSERT INTO article_likes`, errors.New("connection reset"))
+	if _, err := repo.ToggleArticleLike(ctx, 5, 1); err == nil {
+		t.Fatal("插入失败时应返回错误")
+	}
+	if _, ok := cacheSvc.ArticleDetailTTL(5); !ok {
+		t.Fatal("写入失败时不应失效缓存")
+	}
+}
+
+func TestDeleteArticleInvalidatesDetailAndLists(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	onArticleDetail(fake)
+	fake.OnRows(`FROM article_categories ORDER BY`, []string{"id"})
+	fake.OnRows(`FROM article_tags ORDER BY`, []string{"id"})
+	fake.OnRows(`SELECT user_id FROM articles WHERE id = \? AND status != 2`, []string{"user_id"}, []driver.Value{int64(1)})
+	fake.OnExec(`UPDATE articles SET status = 2`, 0, 1)
+	cfg := config.Default()
+	repo := NewArticleRepository(db, cfg)
+	cacheSvc := NewCacheService(repo, cfg)
+
+	if _, err := cacheSvc.GetArticleDetail(context.Background(), 5, 0); err != nil {
+		t.Fatalf("获取文章详情失败: %v", err)
+	}
+	categoriesGen, tagsGen := cacheSvc.categories.generation.Load(), cacheSvc.tags.generation.Load()
+
+	if err := repo.DeleteArticle(context.Background(), 5, 1); err != nil {
+		t.Fatalf("删除文章失败: %v", err)
+	}
+	if _, ok := cacheSvc.ArticleDetailTTL(5); ok {
+		t.Fatal("删除文章后详情缓存应失效")
+	}
+	if cacheSvc.categories.generation.Load() == categoriesGen || cacheSvc.tags.generation.Load() == tagsGen {
+		t.Fatal("删除文章后分类和标签列表缓存应失效")
+	}
+}
+
+func TestInvalidateArticleWithColdCache(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	fake.OnRows(`FROM article_categories ORDER BY`, []string{"id"})
+	fake.OnRows(`FROM article_tags ORDER BY`, []string{"id"})
+	cfg := config.Default()
+	cacheSvc := NewCacheService(NewArticleRepository(db, cfg), cfg)
+
+	// 缓存中没有该文章时失效为空操作
+	cacheSvc.InvalidateArticle(404)
+	cacheSvc.InvalidateArticleLists()
+	if _, ok := cacheSvc.ArticleDetailTTL(404); ok {
+		t.Fatal("冷缓存失效后不应出现缓存项")
+	}
+
+	// 未注册缓存服务的仓库同样可以写入
+	var likes atomic.Int64
+	onLikeCountedArticle(fake, &likes)
+	if _, err := NewArticleRepository(db, cfg).ToggleArticleLike(context.Background(), 5, 1); err != nil {
+		t.Fatalf("未设置缓存失效回调时点赞应成功: %v", err)
+	}
+}
+
+// newTaxonomyCacheService 创建使用独立内存缓存、不做预热的缓存服务
+func newTaxonomyCacheService(repo *ArticleRepository, cfg *config.Config) *CacheService {
+	svc := &CacheService{cache: utils.NewMemoryCache(time.Minute), articleRepo: repo, logger: utils.GetLogger()}
+	svc.config.Store(&cfg.Cache)
+	repo.SetCacheInvalidator(svc)
+	return svc
+}
+
+// onBlockingTaxonomy 预设分类和标签查询：每次查询先通知 started，等待 release 后返回一行
+func onBlockingTaxonomy(fake *testutil.FakeDB, started chan<- string, release <-chan struct{}) {
+	now := time.Now().UTC()
+	fake.On(`FROM article_categories ORDER BY`, func([]driver.Value) testutil.Response {
+		started <- "categories"
+		<-release
+		return testutil.Response{
+			Columns: []string{"id", "name", "slug", "description", "parent_id", "article_count", "sort_order", "created_at"},
+			Rows:    [][]driver.Value{{int64(1), "Go", "go", "", int64(0), int64(3), int64(0), now}},
+		}
+	})
+	fake.On(`FROM article_tags ORDER BY`, func([]driver.Value) testutil.Response {
+		started <- "tags"
+		<-release
+		return testutil.Response{
+			Columns: []string{"id", "name", "slug", "article_count", "created_at"},
+			Rows:    [][]driver.Value{{int64(1), "gin", "gin", int64(2), now}},
+		}
+	})
+}
+
+func TestArticleCategoriesSingleFlight(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	started, release := make(chan string, 16), make(chan struct{})
+	onBlockingTaxonomy(fake, started, release)
+	cfg := config.Default()
+	svc := newTaxonomyCacheService(NewArticleRepository(db, cfg), cfg)
+
+	// 冷缓存下的并发请求合并为一次查询，首个调用方取消不影响其他等待方
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	results := make(chan int, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			callCtx := context.Background()
+			if i == 0 {
+				callCtx = ctx
+			}
+			categories, err := svc.GetArticleCategories(callCtx)
+			if err != nil {
+				t.Errorf("获取分类失败: %v", err)
+			}
+			results <- len(categories)
+		}(i)
+	}
+	<-started
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	for n := range results {
+		if n != 1 {
+			t.Fatalf("所有调用方都应拿到查询结果，实际 %d 条", n)
+		}
+	}
+	if calls := fake.Calls(`FROM article_categories ORDER BY`); len(calls) != 1 {
+		t.Fatalf("并发未命中应只查询一次数据库，实际 %d 次", len(calls))
+	}
+
+	if _, err := svc.GetArticleCategories(context.Background()); err != nil {
+		t.Fatalf("获取分类失败: %v", err)
+	}
+	stats := svc.TaxonomyCacheStats()["article_categories"]
+	if stats.Hits+stats.Misses != 11 || stats.Hits < 1 || stats.HitRatio != float64(stats.Hits)/11 {
+		t.Fatalf("命中统计应覆盖全部请求，实际 %+v", stats)
+	}
+	if len(fake.Calls(`FROM article_categories ORDER BY`)) != 1 {
+		t.Fatal("缓存有效期内不应再次查询")
+	}
+}
+
+func TestArticleTagsInvalidation(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	started, release := make(chan string, 16), make(chan struct{})
+	onBlockingTaxonomy(fake, started, release)
+	fake.OnExec(`UPDATE article_tags SET name = \?, slug = \? WHERE id = \?`, 0, 1)
+	fake.OnRows(`FROM article_tags WHERE id = \?`, []string{"id", "name", "slug", "article_count", "created_at"},
+		[]driver.Value{int64(1), "gin-gonic", "gin-gonic", int64(2), time.Now().UTC()})
+	cfg := config.Default()
+	repo := NewArticleRepository(db, cfg)
+	svc := newTaxonomyCacheService(repo, cfg)
+
+	// 加载期间缓存被失效：本次结果照常返回，但不写入缓存
+	done := make(chan error, 1)
+	go func() {
+		_, err := svc.GetArticleTags(context.Background())
+		done <- err
+	}()
+	<-started
+	svc.InvalidateArticleTags()
+	release <- struct{}{}
+	if err := <-done; err != nil {
+		t.Fatalf("获取标签失败: %v", err)
+	}
+	if _, ok := svc.cache.Get(cacheKeyArticleTags); ok {
+		t.Fatal("加载期间缓存被失效时不应写入旧结果")
+	}
+
+	close(release)
+	if _, err := svc.GetArticleTags(context.Background()); err != nil {
+		t.Fatalf("获取标签失败: %v", err)
+	}
+	if _, ok := svc.cache.Get(cacheKeyArticleTags); !ok {
+		t.Fatal("未被失效的加载结果应写入缓存")
+	}
+
+	// 重命名标签后标签列表缓存失效
+	if _, err := repo.RenameTag(context.Background(), 1, "gin-gonic"); err != nil {
+		t.Fatalf("重命名标签失败: %v", err)
+	}
+	if _, ok := svc.cache.Get(cacheKeyArticleTags); ok {
+		t.Fatal("重命名标签后应失效标签列表缓存")
+	}
+	if len(fake.Calls(`FROM article_tags ORDER BY`)) != 2 {
+		t.Fatal("每次未命中各应查询一次数据库")
+	}
+}
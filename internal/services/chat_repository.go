@@ -30,29 +30,44 @@ func NewChatRepository(db *Database, cfg *config.Config) *ChatRepository {
 }
 
 // SendMessage 发送消息
-func (r *ChatRepository) SendMessage(userID uint, username, nickname, avatar, content, ipAddress string) (*models.ChatMessage, error) {
+// clientMsgID 非空时按 (user_id, client_msg_id) 去重：重复发送不会新增消息，返回已保存的消息且 replayed 为 true
+func (r *ChatRepository) SendMessage(userID uint, username, nickname, avatar, content, ipAddress, clientMsgID string) (*models.ChatMessage, bool, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
 	defer cancel()
 
+	var clientID interface{}
+	if clientMsgID != "" {
+		clientID = clientMsgID
+	}
+
 	now := time.Now().UTC()
-	query := `INSERT INTO chat_messages (user_id, username, nickname, avatar, content, message_type, send_time, ip_address, status, created_at)
-			  VALUES (?, ?, ?, ?, ?, 1, ?, ?, 1, ?)`
+	query := `INSERT INTO chat_messages (user_id, client_msg_id, username, nickname, avatar, content, message_type, send_time, ip_address, status, created_at)
+			  VALUES (?, ?, ?, ?, ?, ?, 1, ?, ?, 1, ?)`
 
-	result, err := r.db.DB.ExecContext(ctx, query, userID, username, nickname, avatar, content, now, ipAddress, now)
+	result, err := r.db.DB.ExecContext(ctx, query, userID, clientID, username, nickname, avatar, content, now, ipAddress, now)
 	if err != nil {
+		if clientMsgID != "" && isDuplicateKeyError(err) {
+			existing, err := r.getMessageByClientID(ctx, userID, clientMsgID)
+			if err != nil {
+				return nil, false, err
+			}
+			r.logger.Debug("重复的客户端消息ID，返回已保存的消息", "userID", userID, "clientMsgID", clientMsgID, "messageID", existing.ID)
+			return existing, true, nil
+		}
 		r.logger.Error("发送消息失败", "error", err.Error())
-		return nil, utils.ErrDatabaseQuery
+		return nil, false, utils.ErrDatabaseQuery
 	}
 
 	messageID, err := result.LastInsertId()
 	if err != nil {
 		r.logger.Error("获取消息ID失败", "error", err.Error())
-		return nil, utils.ErrDatabaseQuery
+		return nil, false, utils.ErrDatabaseQuery
 	}
 
 	return &models.ChatMessage{
 		ID:          uint(messageID),
 		UserID:      userID,
+		ClientMsgID: clientMsgID,
 		Username:    username,
 		Nickname:    nickname,
 		Avatar:      avatar,
@@ -62,7 +77,27 @@ func (r *ChatRepository) SendMessage(userID uint, username, nickname, avatar, co
 		IPAddress:   ipAddress,
 		Status:      1,
 		CreatedAt:   now,
-	}, nil
+	}, false, nil
+}
+
+// getMessageByClientID 按客户端消息ID查询用户已保存的消息
+func (r *ChatRepository) getMessageByClientID(ctx context.Context, userID uint, clientMsgID string) (*models.ChatMessage, error) {
+	query := `SELECT id, user_id, username, nickname, avatar, content, message_type, send_time, status, created_at
+			  FROM chat_messages
+			  WHERE user_id = ? AND client_msg_id = ?`
+
+	var msg models.ChatMessage
+	var nickname, avatar sql.NullString
+	err := r.db.DB.QueryRowContext(ctx, query, userID, clientMsgID).Scan(&msg.ID, &msg.UserID, &msg.Username,
+		&nickname, &avatar, &msg.Content, &msg.MessageType, &msg.SendTime, &msg.Status, &msg.CreatedAt)
+	if err != nil {
+		r.logger.Error("查询已保存的客户端消息失败", "userID", userID, "clientMsgID", clientMsgID, "error", err.Error())
+		return nil, utils.ErrDatabaseQuery
+	}
+	msg.ClientMsgID = clientMsgID
+	msg.Nickname = nickname.String
+	msg.Avatar = avatar.String
+	return &msg, nil
 }
 
 // GetMessages 获取消息列表（分页）
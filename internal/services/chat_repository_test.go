@@ -0,0 +1,49 @@
+package services
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"gin/internal/config"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestSendMessageDeduplicatesClientMsgID(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	fake.OnExec(`INSERT INTO chat_messages`, 42, 1)
+	repo := NewChatRepository(db, config.Default())
+
+	msg, replayed, err := repo.SendMessage(1, "alice", "Alice", "", "你好", "127.0.0.1", "c-1")
+	if err != nil || replayed || msg.ID != 42 || msg.ClientMsgID != "c-1" {
+		t.Fatalf("首次发送应新增消息，实际 %+v %v %v", msg, replayed, err)
+	}
+	if args := fake.Calls(`INSERT INTO chat_messages`)[0].Args; args[1] != "c-1" {
+		t.Fatalf("应保存客户端消息ID，实际参数 %v", args)
+	}
+
+	// 重连后重放同一条消息：唯一索引冲突时返回已保存的消息
+	sendTime := time.Now().UTC().Add(-time.Minute)
+	fake.OnError(`INSERT INTO chat_messages`, &mysql.MySQLError{Number: 1062, Message: "Duplicate entry '1-c-1' for key 'uk_user_client_msg'"})
+	fake.OnRows(`FROM chat_messages WHERE user_id = \? AND client_msg_id = \?`,
+		[]string{"id", "user_id", "username", "nickname", "avatar", "content", "message_type", "send_time", "status", "created_at"},
+		[]driver.Value{int64(42), int64(1), "alice", nil, nil, "你好", int64(1), sendTime, int64(1), sendTime})
+	msg, replayed, err = repo.SendMessage(1, "alice", "Alice", "", "你好", "127.0.0.1", "c-1")
+	if err != nil || !replayed || msg.ID != 42 || !msg.SendTime.Equal(sendTime) {
+		t.Fatalf("重放的消息应返回已保存的消息，实际 %+v %v %v", msg, replayed, err)
+	}
+	if args := fake.Calls(`AND client_msg_id = \?`)[0].Args; args[0] != int64(1) || args[1] != "c-1" {
+		t.Fatalf("应按用户和客户端消息ID查询，实际参数 %v", args)
+	}
+
+	// 没有客户端消息ID时保持原有行为，冲突不会被当作重放
+	fake.OnExec(`INSERT INTO chat_messages`, 43, 1)
+	if msg, replayed, err := repo.SendMessage(1, "alice", "Alice", "", "再见", "127.0.0.1", ""); err != nil || replayed || msg.ID != 43 {
+		t.Fatalf("未提供客户端消息ID时应直接新增，实际 %+v %v %v", msg, replayed, err)
+	}
+	calls := fake.Calls(`INSERT INTO chat_messages`)
+	if args := calls[len(calls)-1].Args; args[1] != nil {
+		t.Fatalf("未提供客户端消息ID时应保存为 NULL，实际 %v", args[1])
+	}
+}
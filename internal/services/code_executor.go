@@ -15,6 +15,7 @@ import (
 type CodeExecutor interface {
 	Execute(ctx context.Context, language, code, stdin string) (*models.ExecuteCodeResponse, error)
 	GetSupportedLanguages() []models.LanguageInfo
+	Ping(ctx context.Context) error
 }
 
 // PistonCodeExecutor Piston API 代码执行器实现
@@ -331,6 +332,25 @@ func (e *PistonCodeExecutor) Execute(ctx context.Context, language, code, stdin
 	return result, nil
 }
 
+// Ping 检查 Piston API 是否可用（请求运行时列表，不执行代码）
+func (e *PistonCodeExecutor) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", e.apiURL+"/runtimes", nil)
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %w", err)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Piston API 不可用: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Piston API 返回状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
+
 // GetSupportedLanguages 获取支持的语言列表
 func (e *PistonCodeExecutor) GetSupportedLanguages() []models.LanguageInfo {
 	languages := make([]models.LanguageInfo, 0, len(supportedLanguages))
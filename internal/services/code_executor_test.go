@@ -0,0 +1,34 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPistonPing(t *testing.T) {
+	status := http.StatusOK
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+	executor := NewPistonCodeExecutor(server.URL, time.Second, 1, 1, 1)
+
+	if err := executor.Ping(context.Background()); err != nil || path != "/runtimes" {
+		t.Fatalf("Piston 可用时 Ping 应成功并请求 /runtimes，实际 %v %s", err, path)
+	}
+
+	status = http.StatusBadGateway
+	if err := executor.Ping(context.Background()); err == nil {
+		t.Fatal("Piston 返回非200时 Ping 应失败")
+	}
+
+	server.Close()
+	if err := executor.Ping(context.Background()); err == nil {
+		t.Fatal("Piston 不可达时 Ping 应失败")
+	}
+}
@@ -3,9 +3,11 @@ package services
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"gin/internal/models"
 	"gin/internal/utils"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -17,11 +19,15 @@ type CodeRepository interface {
 	GetSnippetByID(id uint) (*models.CodeSnippet, error)
 	GetSnippetWithUserByID(id uint) (*models.CodeSnippetWithUser, error)
 	GetSnippetsByUserID(userID uint, limit, offset int) ([]models.CodeSnippetListItem, int, error)
-	GetPublicSnippets(language string, limit, offset int) ([]models.CodeSnippetWithUser, int, error)
+	GetPublicSnippets(query models.PublicSnippetQuery) ([]models.CodeSnippetWithUser, int, error)
 	UpdateSnippet(snippet *models.CodeSnippet) error
 	DeleteSnippet(id uint, userID uint) error
+	DeleteSnippets(ctx context.Context, ids []uint, userID uint) ([]uint, error)
 	GetSnippetByShareToken(token string) (*models.CodeSnippet, error)
-	GenerateShareToken(snippetID uint, userID uint) (string, error)
+	GenerateShareToken(snippetID uint, userID uint, expiresAt *time.Time, maxViews *int64) (string, error)
+	RevokeShareToken(snippetID uint, userID uint) error
+	ForkSnippet(ctx context.Context, snippetID, userID uint) (*models.CodeSnippet, error)
+	IncrementExecutionCount(ctx context.Context, snippetID uint) error
 
 	// 执行记录相关
 	CreateExecution(execution *models.CodeExecution) error
@@ -32,6 +38,7 @@ type CodeRepository interface {
 	CreateCollaboration(collab *models.CodeCollaboration) error
 	GetCollaborationByToken(token string) (*models.CodeCollaboration, error)
 	UpdateCollaborationUsers(token string, activeUsers string) error
+	UpdateSnippetCode(ctx context.Context, snippetID uint, code string) error
 }
 
 // CodeRepositoryImpl 代码仓库实现
@@ -82,7 +89,9 @@ func (r *CodeRepositoryImpl) CreateSnippet(snippet *models.CodeSnippet) error {
 // GetSnippetByID 根据ID获取代码片段
 func (r *CodeRepositoryImpl) GetSnippetByID(id uint) (*models.CodeSnippet, error) {
 	var snippet models.CodeSnippet
-	query := `SELECT id, user_id, title, language, code, description, is_public, share_token, created_at, updated_at 
+	query := `SELECT id, user_id, title, language, code, description, is_public, share_token,
+			  share_expires_at, share_max_views, share_view_count,
+			  execution_count, fork_count, forked_from, created_at, updated_at
 			  FROM code_snippets WHERE id = ?`
 
 	ctx, cancel := context.WithTimeout(context.Background(), r.db.GetUpdateTimeout())
@@ -91,6 +100,8 @@ func (r *CodeRepositoryImpl) GetSnippetByID(id uint) (*models.CodeSnippet, error
 	row := r.db.QueryRowWithCache(ctx, query, id)
 	err := row.Scan(&snippet.ID, &snippet.UserID, &snippet.Title, &snippet.Language,
 		&snippet.Code, &snippet.Description, &snippet.IsPublic, &snippet.ShareToken,
+		&snippet.ShareExpiresAt, &snippet.ShareMaxViews, &snippet.ShareViewCount,
+		&snippet.ExecutionCount, &snippet.ForkCount, &snippet.ForkedFrom,
 		&snippet.CreatedAt, &snippet.UpdatedAt)
 
 	if err != nil {
@@ -105,7 +116,8 @@ func (r *CodeRepositoryImpl) GetSnippetByID(id uint) (*models.CodeSnippet, error
 // GetSnippetWithUserByID 根据ID获取代码片段（包含用户信息）
 func (r *CodeRepositoryImpl) GetSnippetWithUserByID(id uint) (*models.CodeSnippetWithUser, error) {
 	query := `
-		SELECT cs.id, cs.user_id, u.username, cs.title, cs.language, cs.code, cs.description, cs.share_token, cs.created_at, cs.updated_at
+		SELECT cs.id, cs.user_id, u.username, cs.title, cs.language, cs.code, cs.description, cs.share_token,
+		       cs.execution_count, cs.fork_count, cs.forked_from, cs.created_at, cs.updated_at
 		FROM code_snippets cs
 		LEFT JOIN user_auth u ON cs.user_id = u.id
 		WHERE cs.id = ?
@@ -119,7 +131,8 @@ func (r *CodeRepositoryImpl) GetSnippetWithUserByID(id uint) (*models.CodeSnippe
 	var snippet models.CodeSnippetWithUser
 	var username sql.NullString
 	err := row.Scan(&snippet.ID, &snippet.UserID, &username, &snippet.Title, &snippet.Language,
-		&snippet.Code, &snippet.Description, &snippet.ShareToken, &snippet.CreatedAt, &snippet.UpdatedAt)
+		&snippet.Code, &snippet.Description, &snippet.ShareToken,
+		&snippet.ExecutionCount, &snippet.ForkCount, &snippet.ForkedFrom, &snippet.CreatedAt, &snippet.UpdatedAt)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -145,7 +158,7 @@ func (r *CodeRepositoryImpl) GetSnippetsByUserID(userID uint, limit, offset int)
 	// 并行执行COUNT和列表查询
 	countQuery := `SELECT COUNT(*) FROM code_snippets WHERE user_id = ?`
 	listQuery := `
-		SELECT id, title, language, is_public, created_at, updated_at
+		SELECT id, title, language, is_public, execution_count, fork_count, created_at, updated_at
 		FROM code_snippets
 		WHERE user_id = ?
 		ORDER BY updated_at DESC
@@ -164,19 +177,20 @@ func (r *CodeRepositoryImpl) GetSnippetsByUserID(userID uint, limit, offset int)
 	countChan := make(chan countResult, 1)
 	listChan := make(chan listResult, 1)
 
-	// 并行执行COUNT
-	go func() {
-		var total int
-		row := r.db.QueryRowWithCache(ctx, countQuery, userID)
-		err := row.Scan(&total)
-		countChan <- countResult{total: total, err: err}
-	}()
-
-	// 并行执行列表查询
-	go func() {
-		rows, err := r.db.QueryWithCache(ctx, listQuery, userID, limit, offset)
-		listChan <- listResult{rows: rows, err: err}
-	}()
+	utils.Parallel(ctx,
+		// 并行执行COUNT
+		func() {
+			var total int
+			row := r.db.QueryRowWithCache(ctx, countQuery, userID)
+			err := row.Scan(&total)
+			countChan <- countResult{total: total, err: err}
+		},
+		// 并行执行列表查询
+		func() {
+			rows, err := r.db.QueryWithCache(ctx, listQuery, userID, limit, offset)
+			listChan <- listResult{rows: rows, err: err}
+		},
+	)
 
 	// 收集结果
 	countRes := <-countChan
@@ -201,7 +215,7 @@ func (r *CodeRepositoryImpl) GetSnippetsByUserID(userID uint, limit, offset int)
 	for rows.Next() {
 		var snippet models.CodeSnippetListItem
 		if err := rows.Scan(&snippet.ID, &snippet.Title, &snippet.Language, &snippet.IsPublic,
-			&snippet.CreatedAt, &snippet.UpdatedAt); err != nil {
+			&snippet.ExecutionCount, &snippet.ForkCount, &snippet.CreatedAt, &snippet.UpdatedAt); err != nil {
 			return nil, 0, fmt.Errorf("扫描代码片段失败: %w", err)
 		}
 		snippets = append(snippets, snippet)
@@ -273,18 +287,97 @@ func (r *CodeRepositoryImpl) DeleteSnippet(id uint, userID uint) error {
 	return nil
 }
 
+// DeleteSnippets 批量删除自己的代码片段
+// 整批在一个事务中完成，任一片段不存在或不属于该用户时全部回滚
+func (r *CodeRepositoryImpl) DeleteSnippets(ctx context.Context, ids []uint, userID uint) ([]uint, error) {
+	if len(ids) == 0 {
+		return []uint{}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
+	defer cancel()
+
+	placeholders, args := inPlaceholders(ids)
+	err := r.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx,
+			`SELECT id, user_id FROM code_snippets WHERE id IN (`+placeholders+`) FOR UPDATE`, args...)
+		if err != nil {
+			return err
+		}
+		owners := make(map[uint]uint, len(ids))
+		for rows.Next() {
+			var id, ownerID uint
+			if err := rows.Scan(&id, &ownerID); err != nil {
+				rows.Close()
+				return err
+			}
+			owners[id] = ownerID
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if err := checkBatchOwnership(ids, owners, userID); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `DELETE FROM code_snippets WHERE id IN (`+placeholders+`)`, args...)
+		return err
+	})
+	if err != nil {
+		var appErr *utils.AppError
+		if errors.As(err, &appErr) {
+			return nil, err
+		}
+		utils.GetLogger().Error("批量删除代码片段失败", "user_id", userID, "error", err.Error())
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
+	}
+
+	utils.GetLogger().Info("批量删除代码片段成功",
+		"user_id", userID,
+		"count", len(ids))
+
+	return ids, nil
+}
+
 // GetSnippetByShareToken 通过分享令牌获取代码片段
+// 已过期或访问次数用完的令牌视为无效；每次成功获取计入一次访问（与检查在同一条UPDATE中完成，并发时不会超出次数）
 func (r *CodeRepositoryImpl) GetSnippetByShareToken(token string) (*models.CodeSnippet, error) {
-	var snippet models.CodeSnippet
-	query := `SELECT id, user_id, title, language, code, description, is_public, share_token, created_at, updated_at 
-			  FROM code_snippets WHERE share_token = ?`
-
 	ctx, cancel := context.WithTimeout(context.Background(), r.db.GetUpdateTimeout())
 	defer cancel()
 
+	result, err := r.db.DB.ExecContext(ctx, `
+		UPDATE code_snippets
+		SET share_view_count = share_view_count + 1
+		WHERE share_token = ?
+		  AND (share_expires_at IS NULL OR share_expires_at > ?)
+		  AND (share_max_views IS NULL OR share_view_count < share_max_views)`,
+		token, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("更新分享访问次数失败: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if affected == 0 {
+		return nil, fmt.Errorf("分享链接无效或已过期")
+	}
+
+	return r.getSnippetByShareToken(ctx, token)
+}
+
+// getSnippetByShareToken 按分享令牌查询代码片段（不检查有效期和次数）
+func (r *CodeRepositoryImpl) getSnippetByShareToken(ctx context.Context, token string) (*models.CodeSnippet, error) {
+	var snippet models.CodeSnippet
+	query := `SELECT id, user_id, title, language, code, description, is_public, share_token,
+			  share_expires_at, share_max_views, share_view_count,
+			  execution_count, fork_count, forked_from, created_at, updated_at
+			  FROM code_snippets WHERE share_token = ?`
+
 	row := r.db.QueryRowWithCache(ctx, query, token)
 	err := row.Scan(&snippet.ID, &snippet.UserID, &snippet.Title, &snippet.Language,
 		&snippet.Code, &snippet.Description, &snippet.IsPublic, &snippet.ShareToken,
+		&snippet.ShareExpiresAt, &snippet.ShareMaxViews, &snippet.ShareViewCount,
+		&snippet.ExecutionCount, &snippet.ForkCount, &snippet.ForkedFrom,
 		&snippet.CreatedAt, &snippet.UpdatedAt)
 
 	if err != nil {
@@ -296,20 +389,31 @@ func (r *CodeRepositoryImpl) GetSnippetByShareToken(token string) (*models.CodeS
 	return &snippet, nil
 }
 
-// GenerateShareToken 生成分享令牌
-func (r *CodeRepositoryImpl) GenerateShareToken(snippetID uint, userID uint) (string, error) {
-	// 生成 UUID 作为分享令牌
-	token := uuid.New().String()
+// shareTokenAttempts 生成分享令牌时遇到唯一键冲突的最大尝试次数
+const shareTokenAttempts = 3
 
+// GenerateShareToken 生成分享令牌（替换旧令牌并重置访问次数）
+// expiresAt 为 nil 表示永不过期，maxViews 为 nil 表示不限次数
+func (r *CodeRepositoryImpl) GenerateShareToken(snippetID uint, userID uint, expiresAt *time.Time, maxViews *int64) (string, error) {
 	query := `
 		UPDATE code_snippets
-		SET share_token = ?
+		SET share_token = ?, share_expires_at = ?, share_max_views = ?, share_view_count = 0
 		WHERE id = ? AND user_id = ?
 	`
 	ctx, cancel := context.WithTimeout(context.Background(), r.db.GetUpdateTimeout())
 	defer cancel()
 
-	result, err := r.db.ExecWithCache(ctx, query, token, snippetID, userID)
+	// 生成 UUID 作为分享令牌；share_token 有唯一索引，冲突时重新生成
+	var token string
+	var result sql.Result
+	var err error
+	for attempt := 0; attempt < shareTokenAttempts; attempt++ {
+		token = uuid.New().String()
+		result, err = r.db.ExecWithCache(ctx, query, token, expiresAt, maxViews, snippetID, userID)
+		if err == nil || !isDuplicateKeyError(err) {
+			break
+		}
+	}
 	if err != nil {
 		return "", fmt.Errorf("生成分享令牌失败: %w", err)
 	}
@@ -330,6 +434,120 @@ func (r *CodeRepositoryImpl) GenerateShareToken(snippetID uint, userID uint) (st
 	return token, nil
 }
 
+// RevokeShareToken 吊销分享令牌（之后可重新生成新的令牌）
+func (r *CodeRepositoryImpl) RevokeShareToken(snippetID uint, userID uint) error {
+	query := `
+		UPDATE code_snippets
+		SET share_token = NULL, share_expires_at = NULL, share_max_views = NULL, share_view_count = 0
+		WHERE id = ? AND user_id = ?
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), r.db.GetUpdateTimeout())
+	defer cancel()
+
+	result, err := r.db.ExecWithCache(ctx, query, snippetID, userID)
+	if err != nil {
+		return fmt.Errorf("吊销分享令牌失败: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("代码片段不存在或无权限")
+	}
+
+	utils.GetLogger().Info("吊销分享令牌成功",
+		"snippet_id", snippetID,
+		"user_id", userID)
+
+	return nil
+}
+
+// ForkSnippet 将公开（或自己的）代码片段复制到用户名下
+// 副本为私有且没有分享令牌，forked_from 指向来源；来源片段的 fork_count 加1
+func (r *CodeRepositoryImpl) ForkSnippet(ctx context.Context, snippetID, userID uint) (*models.CodeSnippet, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
+	defer cancel()
+
+	var fork *models.CodeSnippet
+	err := r.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		var source models.CodeSnippet
+		err := tx.QueryRowContext(ctx,
+			`SELECT id, user_id, title, language, code, description, is_public
+			 FROM code_snippets WHERE id = ? FOR UPDATE`, snippetID).
+			Scan(&source.ID, &source.UserID, &source.Title, &source.Language, &source.Code, &source.Description, &source.IsPublic)
+		if err == sql.ErrNoRows {
+			return utils.ErrResourceNotFound
+		}
+		if err != nil {
+			return err
+		}
+		// 私有片段对他人不可见，按不存在处理
+		if !source.IsPublic && source.UserID != userID {
+			return utils.ErrResourceNotFound
+		}
+
+		result, err := tx.ExecContext(ctx,
+			`INSERT INTO code_snippets (user_id, title, language, code, description, is_public, forked_from)
+			 VALUES (?, ?, ?, ?, ?, 0, ?)`,
+			userID, source.Title, source.Language, source.Code, source.Description, source.ID)
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE code_snippets SET fork_count = fork_count + 1 WHERE id = ?`, source.ID); err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		forkedFrom := source.ID
+		fork = &models.CodeSnippet{
+			ID:          uint(id),
+			UserID:      userID,
+			Title:       source.Title,
+			Language:    source.Language,
+			Code:        source.Code,
+			Description: source.Description,
+			IsPublic:    false,
+			ForkedFrom:  &forkedFrom,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		return nil
+	})
+	if err != nil {
+		if err == utils.ErrResourceNotFound {
+			return nil, err
+		}
+		utils.GetLogger().Error("复制代码片段失败", "snippet_id", snippetID, "user_id", userID, "error", err.Error())
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseInsert, err)
+	}
+
+	utils.GetLogger().Info("复制代码片段成功",
+		"snippet_id", fork.ID,
+		"forked_from", snippetID,
+		"user_id", userID)
+
+	return fork, nil
+}
+
+// IncrementExecutionCount 公开代码片段执行次数加1（私有片段的执行不计入）
+func (r *CodeRepositoryImpl) IncrementExecutionCount(ctx context.Context, snippetID uint) error {
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
+	defer cancel()
+
+	if _, err := r.db.DB.ExecContext(ctx,
+		`UPDATE code_snippets SET execution_count = execution_count + 1 WHERE id = ? AND is_public = 1`, snippetID); err != nil {
+		return fmt.Errorf("更新代码片段执行次数失败: %w", err)
+	}
+	return nil
+}
+
 // CreateExecution 创建执行记录
 func (r *CodeRepositoryImpl) CreateExecution(execution *models.CodeExecution) error {
 	query := `
@@ -391,19 +609,20 @@ func (r *CodeRepositoryImpl) GetExecutionsByUserID(userID uint, limit, offset in
 	countChan := make(chan countResult, 1)
 	listChan := make(chan listResult, 1)
 
-	// 并行执行COUNT
-	go func() {
-		var total int
-		row := r.db.QueryRowWithCache(ctx, countQuery, userID)
-		err := row.Scan(&total)
-		countChan <- countResult{total: total, err: err}
-	}()
-
-	// 并行执行列表查询
-	go func() {
-		rows, err := r.db.QueryWithCache(ctx, listQuery, userID, limit, offset)
-		listChan <- listResult{rows: rows, err: err}
-	}()
+	utils.Parallel(ctx,
+		// 并行执行COUNT
+		func() {
+			var total int
+			row := r.db.QueryRowWithCache(ctx, countQuery, userID)
+			err := row.Scan(&total)
+			countChan <- countResult{total: total, err: err}
+		},
+		// 并行执行列表查询
+		func() {
+			rows, err := r.db.QueryWithCache(ctx, listQuery, userID, limit, offset)
+			listChan <- listResult{rows: rows, err: err}
+		},
+	)
 
 	// 收集结果
 	countRes := <-countChan
@@ -533,44 +752,58 @@ func (r *CodeRepositoryImpl) UpdateCollaborationUsers(token string, activeUsers
 	return nil
 }
 
-// GetPublicSnippets 获取公开的代码片段列表
-func (r *CodeRepositoryImpl) GetPublicSnippets(language string, limit, offset int) ([]models.CodeSnippetWithUser, int, error) {
+// UpdateSnippetCode 保存协作编辑的代码快照（权限在创建协作会话时已校验）
+func (r *CodeRepositoryImpl) UpdateSnippetCode(ctx context.Context, snippetID uint, code string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
+	defer cancel()
+
+	if _, err := r.db.DB.ExecContext(ctx, `UPDATE code_snippets SET code = ? WHERE id = ?`, code, snippetID); err != nil {
+		return fmt.Errorf("保存协作代码快照失败: %w", err)
+	}
+	return nil
+}
+
+// GetPublicSnippets 获取公开的代码片段列表（只返回 is_public=1 的片段，可按语言和关键词筛选）
+func (r *CodeRepositoryImpl) GetPublicSnippets(query models.PublicSnippetQuery) ([]models.CodeSnippetWithUser, int, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), r.db.GetUpdateTimeout())
 	defer cancel()
 
-	// 构建查询条件
-	var countQuery, listQuery string
-	var args []interface{}
+	// 构建查询条件（COUNT 与列表使用相同的条件）
+	where := "cs.is_public = 1"
+	var countArgs []interface{}
+	if query.Language != "" {
+		where += " AND cs.language = ?"
+		countArgs = append(countArgs, query.Language)
+	}
+	if query.Keyword != "" {
+		keyword := "%" + query.Keyword + "%"
+		if query.SearchCode {
+			where += " AND (cs.title LIKE ? OR cs.description LIKE ? OR cs.code LIKE ?)"
+			countArgs = append(countArgs, keyword, keyword, keyword)
+		} else {
+			where += " AND (cs.title LIKE ? OR cs.description LIKE ?)"
+			countArgs = append(countArgs, keyword, keyword)
+		}
+	}
 
-	if language != "" {
-		countQuery = `SELECT COUNT(*) FROM code_snippets WHERE is_public = 1 AND language = ?`
-		listQuery = `
-			SELECT cs.id, cs.user_id, u.username, cs.title, cs.language, cs.code, cs.description, cs.share_token, cs.created_at, cs.updated_at
-			FROM code_snippets cs
-			LEFT JOIN user_auth u ON cs.user_id = u.id
-			WHERE cs.is_public = 1 AND cs.language = ?
-			ORDER BY cs.created_at DESC
-			LIMIT ? OFFSET ?
-		`
-		args = []interface{}{language, limit, offset}
-	} else {
-		countQuery = `SELECT COUNT(*) FROM code_snippets WHERE is_public = 1`
-		listQuery = `
-			SELECT cs.id, cs.user_id, u.username, cs.title, cs.language, cs.code, cs.description, cs.share_token, cs.created_at, cs.updated_at
+	orderBy := "cs.created_at DESC, cs.id DESC"
+	if query.SortBy == models.SnippetSortMostExecuted {
+		orderBy = "cs.execution_count DESC, cs.created_at DESC, cs.id DESC"
+	}
+
+	countQuery := `SELECT COUNT(*) FROM code_snippets cs WHERE ` + where
+	listQuery := `
+			SELECT cs.id, cs.user_id, u.username, cs.title, cs.language, cs.code, cs.description, cs.share_token,
+			       cs.execution_count, cs.fork_count, cs.forked_from, cs.created_at, cs.updated_at
 			FROM code_snippets cs
 			LEFT JOIN user_auth u ON cs.user_id = u.id
-			WHERE cs.is_public = 1
-			ORDER BY cs.created_at DESC
+			WHERE ` + where + `
+			ORDER BY ` + orderBy + `
 			LIMIT ? OFFSET ?
 		`
-		args = []interface{}{limit, offset}
-	}
+	args := append(append([]interface{}{}, countArgs...), query.Limit, query.Offset)
 
 	// 并行执行COUNT和列表查询（优化性能）
-	var countArgs []interface{}
-	if language != "" {
-		countArgs = []interface{}{language}
-	}
 
 	type countResult struct {
 		total int
@@ -584,19 +817,20 @@ func (r *CodeRepositoryImpl) GetPublicSnippets(language string, limit, offset in
 	countChan := make(chan countResult, 1)
 	listChan := make(chan listResult, 1)
 
-	// 并行执行COUNT
-	go func() {
-		var total int
-		row := r.db.QueryRowWithCache(ctx, countQuery, countArgs...)
-		err := row.Scan(&total)
-		countChan <- countResult{total: total, err: err}
-	}()
-
-	// 并行执行列表查询
-	go func() {
-		rows, err := r.db.QueryWithCache(ctx, listQuery, args...)
-		listChan <- listResult{rows: rows, err: err}
-	}()
+	utils.Parallel(ctx,
+		// 并行执行COUNT
+		func() {
+			var total int
+			row := r.db.QueryRowWithCache(ctx, countQuery, countArgs...)
+			err := row.Scan(&total)
+			countChan <- countResult{total: total, err: err}
+		},
+		// 并行执行列表查询
+		func() {
+			rows, err := r.db.QueryWithCache(ctx, listQuery, args...)
+			listChan <- listResult{rows: rows, err: err}
+		},
+	)
 
 	// 收集结果
 	countRes := <-countChan
@@ -622,7 +856,8 @@ func (r *CodeRepositoryImpl) GetPublicSnippets(language string, limit, offset in
 		var snippet models.CodeSnippetWithUser
 		var username sql.NullString
 		if err := rows.Scan(&snippet.ID, &snippet.UserID, &username, &snippet.Title, &snippet.Language,
-			&snippet.Code, &snippet.Description, &snippet.ShareToken, &snippet.CreatedAt, &snippet.UpdatedAt); err != nil {
+			&snippet.Code, &snippet.Description, &snippet.ShareToken,
+			&snippet.ExecutionCount, &snippet.ForkCount, &snippet.ForkedFrom, &snippet.CreatedAt, &snippet.UpdatedAt); err != nil {
 			return nil, 0, fmt.Errorf("扫描公开代码片段失败: %w", err)
 		}
 		if username.Valid {
@@ -635,9 +870,11 @@ func (r *CodeRepositoryImpl) GetPublicSnippets(language string, limit, offset in
 
 	utils.GetLogger().Info("查询公开代码片段成功",
 		"total", total,
-		"language", language,
-		"limit", limit,
-		"offset", offset)
+		"language", query.Language,
+		"keyword", query.Keyword,
+		"sortBy", query.SortBy,
+		"limit", query.Limit,
+		"offset", query.Offset)
 
 	return snippets, total, nil
 }
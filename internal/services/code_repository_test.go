@@ -0,0 +1,236 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	"gin/internal/models"
+	"gin/internal/testutil"
+	"gin/internal/utils"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// snippetListColumns 公开代码片段列表查询的列
+var snippetListColumns = []string{"id", "user_id", "username", "title", "language", "code", "description", "share_token",
+	"execution_count", "fork_count", "forked_from", "created_at", "updated_at"}
+
+func TestGetPublicSnippetsFilters(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	now := time.Now().UTC()
+	fake.OnRows(`SELECT COUNT\(\*\) FROM code_snippets cs WHERE`, []string{"count"}, []driver.Value{int64(1)})
+	fake.OnRows(`FROM code_snippets cs LEFT JOIN user_auth u`, snippetListColumns,
+		[]driver.Value{int64(3), int64(9), nil, "快速排序", "go", "package main", "", nil, int64(7), int64(0), nil, now, now})
+	repo := NewCodeRepository(db)
+
+	snippets, total, err := repo.GetPublicSnippets(models.PublicSnippetQuery{
+		Language: "go", Keyword: "排序", SortBy: models.SnippetSortRecent, Limit: 20, Offset: 40,
+	})
+	if err != nil || total != 1 || len(snippets) != 1 {
+		t.Fatalf("查询公开代码片段失败: %v %d %v", snippets, total, err)
+	}
+	if snippets[0].Username != "未知用户" {
+		t.Fatalf("作者已删除时应显示为未知用户，实际 %q", snippets[0].Username)
+	}
+
+	count := fake.Calls(`SELECT COUNT\(\*\) FROM code_snippets`)[0]
+	list := fake.Calls(`FROM code_snippets cs LEFT JOIN user_auth u`)[0]
+	where := "WHERE cs.is_public = 1 AND cs.language = ? AND (cs.title LIKE ? OR cs.description LIKE ?)"
+	if !strings.Contains(count.Query, where) || !strings.Contains(list.Query, where) {
+		t.Fatalf("总数和列表应使用相同的筛选条件:\n%s\n%s", count.Query, list.Query)
+	}
+	if len(count.Args) != 3 || count.Args[0] != "go" || count.Args[1] != "%排序%" {
+		t.Fatalf("总数查询参数不正确: %v", count.Args)
+	}
+	if n := len(list.Args); n != 5 || list.Args[n-2] != int64(20) || list.Args[n-1] != int64(40) {
+		t.Fatalf("列表查询应在筛选参数后带上分页参数: %v", list.Args)
+	}
+	if !strings.Contains(list.Query, "ORDER BY cs.created_at DESC, cs.id DESC") {
+		t.Fatalf("默认按发布时间排序: %s", list.Query)
+	}
+
+	// 搜索代码内容并按运行次数排序
+	before := len(fake.Calls(""))
+	if _, _, err := repo.GetPublicSnippets(models.PublicSnippetQuery{
+		Keyword: "main", SearchCode: true, SortBy: models.SnippetSortMostExecuted, Limit: 20,
+	}); err != nil {
+		t.Fatalf("查询公开代码片段失败: %v", err)
+	}
+	for _, call := range fake.Calls("")[before:] {
+		if !strings.Contains(call.Query, "WHERE cs.is_public = 1 AND (cs.title LIKE ? OR cs.description LIKE ? OR cs.code LIKE ?)") ||
+			strings.Contains(call.Query, "cs.language = ?") {
+			t.Fatalf("search_code 时应同时匹配代码内容，且未指定语言时不按语言筛选: %s", call.Query)
+		}
+		if strings.Contains(call.Query, "LIMIT") && !strings.Contains(call.Query, "ORDER BY cs.execution_count DESC, cs.created_at DESC") {
+			t.Fatalf("most_executed 应按运行次数排序: %s", call.Query)
+		}
+	}
+}
+
+// onForkSource 预设被复制的来源代码片段
+func onForkSource(fake *testutil.FakeDB, ownerID int64, isPublic bool) {
+	fake.OnRows(`FROM code_snippets WHERE id = \? FOR UPDATE`,
+		[]string{"id", "user_id", "title", "language", "code", "description", "is_public"},
+		[]driver.Value{int64(5), ownerID, "快速排序", "go", "package main", "说明", isPublic})
+	fake.OnExec(`INSERT INTO code_snippets`, 12, 1)
+	fake.OnExec(`UPDATE code_snippets SET fork_count = fork_count \+ 1`, 0, 1)
+}
+
+func TestForkSnippet(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	onForkSource(fake, 9, true)
+	repo := NewCodeRepository(db)
+
+	fork, err := repo.ForkSnippet(context.Background(), 5, 3)
+	if err != nil {
+		t.Fatalf("复制公开代码片段失败: %v", err)
+	}
+	if fork.ID != 12 || fork.UserID != 3 || fork.IsPublic || fork.ShareToken != nil ||
+		fork.ForkedFrom == nil || *fork.ForkedFrom != 5 || fork.Code != "package main" {
+		t.Fatalf("副本应归属当前用户、私有、没有分享令牌并指向来源: %+v", fork)
+	}
+
+	insert := fake.Calls(`INSERT INTO code_snippets`)[0]
+	if !strings.Contains(insert.Query, "VALUES (?, ?, ?, ?, ?, 0, ?)") ||
+		insert.Args[0] != int64(3) || insert.Args[len(insert.Args)-1] != int64(5) {
+		t.Fatalf("副本应以私有状态插入并记录 forked_from: %s %v", insert.Query, insert.Args)
+	}
+	if strings.Contains(insert.Query, "share_token") {
+		t.Fatalf("副本不应复制分享令牌: %s", insert.Query)
+	}
+	update := fake.Calls(`fork_count = fork_count \+ 1`)
+	if len(update) != 1 || update[0].Args[0] != int64(5) {
+		t.Fatalf("来源代码片段的 fork_count 应加1: %v", update)
+	}
+	if len(fake.Calls(`^COMMIT$`)) != 1 {
+		t.Fatal("复制应在事务中提交")
+	}
+}
+
+func TestForkSnippetPrivate(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	onForkSource(fake, 9, false)
+	repo := NewCodeRepository(db)
+
+	if _, err := repo.ForkSnippet(context.Background(), 5, 3); err != utils.ErrResourceNotFound {
+		t.Fatalf("他人的私有代码片段应按不存在处理，实际 %v", err)
+	}
+	if len(fake.Calls(`INSERT INTO code_snippets|fork_count`)) != 0 || len(fake.Calls(`^ROLLBACK$`)) != 1 {
+		t.Fatal("复制失败时不应写入副本或修改来源计数")
+	}
+
+	// 创建者可以复制自己的私有代码片段
+	if _, err := repo.ForkSnippet(context.Background(), 5, 9); err != nil {
+		t.Fatalf("创建者复制自己的私有代码片段失败: %v", err)
+	}
+
+	fake.OnRows(`FROM code_snippets WHERE id = \? FOR UPDATE`, []string{"id"})
+	if _, err := repo.ForkSnippet(context.Background(), 6, 3); err != utils.ErrResourceNotFound {
+		t.Fatalf("不存在的代码片段应返回 ErrResourceNotFound，实际 %v", err)
+	}
+}
+
+func TestIncrementExecutionCountPublicOnly(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	fake.OnExec(`UPDATE code_snippets SET execution_count`, 0, 1)
+	repo := NewCodeRepository(db)
+
+	if err := repo.IncrementExecutionCount(context.Background(), 5); err != nil {
+		t.Fatalf("更新执行次数失败: %v", err)
+	}
+	call := fake.Calls(`UPDATE code_snippets SET execution_count`)[0]
+	if !strings.Contains(call.Query, "execution_count = execution_count + 1 WHERE id = ? AND is_public = 1") || call.Args[0] != int64(5) {
+		t.Fatalf("只有公开代码片段计入执行次数: %s %v", call.Query, call.Args)
+	}
+}
+
+func TestGetSnippetByShareTokenLimits(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	now := time.Now().UTC()
+	fake.OnExec(`SET share_view_count = share_view_count \+ 1`, 0, 0)
+	fake.OnRows(`FROM code_snippets WHERE share_token = \?`,
+		[]string{"id", "user_id", "title", "language", "code", "description", "is_public", "share_token",
+			"share_expires_at", "share_max_views", "share_view_count",
+			"execution_count", "fork_count", "forked_from", "created_at", "updated_at"},
+		[]driver.Value{int64(5), int64(1), "t", "go", "package main", "", false, "tok",
+			now.Add(time.Hour), int64(3), int64(1), int64(0), int64(0), nil, now, now})
+	repo := NewCodeRepository(db)
+
+	// 过期、次数用完或已吊销的令牌都不会更新访问次数
+	if _, err := repo.GetSnippetByShareToken("tok"); err == nil || err.Error() != "分享链接无效或已过期" {
+		t.Fatalf("无效的分享令牌应返回“分享链接无效或已过期”，实际 %v", err)
+	}
+	if len(fake.Calls(`SELECT .* FROM code_snippets WHERE share_token`)) != 0 {
+		t.Fatal("令牌无效时不应再查询代码片段")
+	}
+	update := fake.Calls(`SET share_view_count`)[0]
+	if !strings.Contains(update.Query, "(share_expires_at IS NULL OR share_expires_at > ?)") ||
+		!strings.Contains(update.Query, "(share_max_views IS NULL OR share_view_count < share_max_views)") {
+		t.Fatalf("访问计数应在同一条语句中检查有效期和次数上限: %s", update.Query)
+	}
+	if update.Args[0] != "tok" {
+		t.Fatalf("访问计数参数不正确: %v", update.Args)
+	}
+
+	fake.OnExec(`SET share_view_count = share_view_count \+ 1`, 0, 1)
+	snippet, err := repo.GetSnippetByShareToken("tok")
+	if err != nil || snippet.ID != 5 || snippet.ShareMaxViews == nil || *snippet.ShareMaxViews != 3 {
+		t.Fatalf("有效的分享令牌应返回代码片段: %+v %v", snippet, err)
+	}
+}
+
+func TestGenerateShareTokenRetriesDuplicate(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	attempts := 0
+	fake.On(`SET share_token = \?, share_expires_at = \?, share_max_views = \?, share_view_count = 0`, func([]driver.Value) testutil.Response {
+		attempts++
+		if attempts == 1 {
+			return testutil.Response{Err: &mysql.MySQLError{Number: 1062, Message: "Duplicate entry for key 'share_token'"}}
+		}
+		return testutil.Response{RowsAffected: 1}
+	})
+	repo := NewCodeRepository(db)
+
+	expiresAt := time.Now().UTC().Add(time.Hour)
+	maxViews := int64(10)
+	token, err := repo.GenerateShareToken(5, 1, &expiresAt, &maxViews)
+	if err != nil {
+		t.Fatalf("令牌冲突时应重新生成: %v", err)
+	}
+	calls := fake.Calls(`SET share_token`)
+	if len(calls) != 2 || calls[0].Args[0] == calls[1].Args[0] || calls[1].Args[0] != token {
+		t.Fatalf("冲突后应使用新令牌重试: %v", calls)
+	}
+	if calls[1].Args[1] != expiresAt || calls[1].Args[2] != maxViews {
+		t.Fatalf("应保存有效期和次数上限: %v", calls[1].Args)
+	}
+
+	// 不是创建者（或代码片段不存在）
+	fake.OnExec(`SET share_token = \?`, 0, 0)
+	if _, err := repo.GenerateShareToken(5, 2, nil, nil); err == nil {
+		t.Fatal("非创建者不能生成分享令牌")
+	}
+}
+
+func TestRevokeShareToken(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	fake.OnExec(`SET share_token = NULL`, 0, 1)
+	repo := NewCodeRepository(db)
+
+	if err := repo.RevokeShareToken(5, 1); err != nil {
+		t.Fatalf("吊销分享令牌失败: %v", err)
+	}
+	call := fake.Calls(`SET share_token = NULL`)[0]
+	if !strings.Contains(call.Query, "share_expires_at = NULL, share_max_views = NULL, share_view_count = 0 WHERE id = ? AND user_id = ?") ||
+		call.Args[0] != int64(5) || call.Args[1] != int64(1) {
+		t.Fatalf("吊销应清空令牌及其限制且只能由创建者操作: %s %v", call.Query, call.Args)
+	}
+
+	fake.OnExec(`SET share_token = NULL`, 0, 0)
+	if err := repo.RevokeShareToken(5, 2); err == nil {
+		t.Fatal("非创建者不能吊销分享令牌")
+	}
+}
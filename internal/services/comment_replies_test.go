@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/models"
+	"gin/internal/testutil"
+	"gin/internal/utils"
+)
+
+// replyRow 评论回复（replyTo 为 0 表示没有被回复用户）
+type replyRow struct {
+	id, parentID, userID, replyTo int64
+}
+
+var commentColumns = []string{"id", "article_id", "user_id", "parent_id", "root_id", "reply_to_user_id", "content",
+	"like_count", "reply_count", "status", "is_edited", "created_at", "updated_at", "username", "nickname", "avatar"}
+
+func commentValues(id, parentID, userID, replyTo int64) []driver.Value {
+	var replyToValue driver.Value
+	if replyTo > 0 {
+		replyToValue = replyTo
+	}
+	now := time.Now().UTC()
+	return []driver.Value{id, int64(5), userID, parentID, parentID, replyToValue, "content",
+		int64(0), int64(0), int64(1), false, now, now, "user", "user", ""}
+}
+
+// onReplyPages 按 UNION ALL 的每段参数（父评论、游标、当前用户、LIMIT）返回回复
+func onReplyPages(fake *testutil.FakeDB, replies []replyRow) {
+	fake.On(`WHERE ac.parent_id = \? AND ac.id > \? AND ac.status = 1`, func(args []driver.Value) testutil.Response {
+		resp := testutil.Response{Columns: commentColumns}
+		for i := 0; i+3 < len(args); i += 4 {
+			parentID, afterID, limit := args[i].(int64), args[i+1].(int64), args[i+3].(int64)
+			n := int64(0)
+			for _, r := range replies {
+				if r.parentID == parentID && r.id > afterID && n < limit {
+					resp.Rows = append(resp.Rows, commentValues(r.id, r.parentID, r.userID, r.replyTo))
+					n++
+				}
+			}
+		}
+		return resp
+	})
+	fake.OnRows(`SELECT comment_id FROM article_comment_likes`, []string{"comment_id"}, []driver.Value{int64(12)}, []driver.Value{int64(14)})
+	fake.OnRows(`SELECT ua.id, ua.username, COALESCE\(up.nickname, ua.username\) as nickname, COALESCE\(up.avatar_url, ''\) as avatar FROM user_auth ua`,
+		[]string{"id", "username", "nickname", "avatar"}, []driver.Value{int64(3), "carol", "Carol", ""})
+}
+
+func replyIDs(replies []models.CommentDetailResponse) []uint {
+	ids := make([]uint, 0, len(replies))
+	for _, r := range replies {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
+func TestGetRepliesKeysetPaging(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	fake.OnRows(`SELECT EXISTS\(SELECT 1 FROM article_comments`, []string{"exists"}, []driver.Value{true})
+	onReplyPages(fake, []replyRow{
+		{id: 11, parentID: 10, userID: 2},
+		{id: 12, parentID: 10, userID: 2, replyTo: 3},
+		{id: 13, parentID: 10, userID: 4},
+		{id: 14, parentID: 10, userID: 4},
+		{id: 15, parentID: 10, userID: 4},
+		{id: 16, parentID: 11, userID: 4}, // 二级回复不属于直接回复
+	})
+	repo := NewArticleRepository(db, config.Default())
+
+	page, err := repo.GetReplies(context.Background(), 10, 0, 2, 7)
+	if err != nil {
+		t.Fatalf("获取回复失败: %v", err)
+	}
+	if ids := replyIDs(page.Replies); !slices.Equal(ids, []uint{11, 12}) || !page.HasMore || page.NextCursor != 12 {
+		t.Fatalf("第一页应为 [11 12] 且有更多，实际 %v has_more=%v cursor=%d", ids, page.HasMore, page.NextCursor)
+	}
+	if page.Replies[0].IsLiked || !page.Replies[1].IsLiked {
+		t.Fatal("点赞状态应按本页回复批量填充")
+	}
+	if page.Replies[1].ReplyToUser == nil || page.Replies[1].ReplyToUser.Username != "carol" || page.Replies[0].ReplyToUser != nil {
+		t.Fatalf("被回复用户解析错误: %+v", page.Replies[1].ReplyToUser)
+	}
+	likes := fake.Calls(`SELECT comment_id FROM article_comment_likes`)
+	if len(likes) != 1 || !slices.Equal(likes[0].Args, []driver.Value{int64(11), int64(12), int64(7)}) {
+		t.Fatalf("点赞状态只应查询本页回复，实际 %v", likes)
+	}
+	if call := fake.Calls(`WHERE ac.parent_id = \?`)[0]; call.Args[3] != int64(3) {
+		t.Fatalf("应多取一条用于判断是否还有更多，实际 LIMIT %v", call.Args[3])
+	}
+
+	page, _ = repo.GetReplies(context.Background(), 10, 12, 2, 7)
+	if ids := replyIDs(page.Replies); !slices.Equal(ids, []uint{13, 14}) || !page.HasMore || page.NextCursor != 14 {
+		t.Fatalf("第二页应为 [13 14]，实际 %v has_more=%v cursor=%d", ids, page.HasMore, page.NextCursor)
+	}
+
+	page, _ = repo.GetReplies(context.Background(), 10, 14, 2, 0)
+	if ids := replyIDs(page.Replies); !slices.Equal(ids, []uint{15}) || page.HasMore || page.NextCursor != 0 {
+		t.Fatalf("最后一页没有更多时不应返回游标，实际 %v has_more=%v cursor=%d", ids, page.HasMore, page.NextCursor)
+	}
+	if len(fake.Calls(`SELECT comment_id FROM article_comment_likes`)) != 2 {
+		t.Fatal("未登录时不应查询点赞状态")
+	}
+}
+
+func TestGetRepliesMissingComment(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	fake.OnRows(`SELECT EXISTS\(SELECT 1 FROM article_comments`, []string{"exists"}, []driver.Value{false})
+	onReplyPages(fake, nil)
+
+	_, err := NewArticleRepository(db, config.Default()).GetReplies(context.Background(), 99, 0, 10, 0)
+	if !errors.Is(err, utils.ErrResourceNotFound) {
+		t.Fatalf("评论不存在时应返回不存在，实际 %v", err)
+	}
+	if len(fake.Calls(`WHERE ac.parent_id = \?`)) != 0 {
+		t.Fatal("评论不存在时不应查询回复")
+	}
+}
+
+func TestGetCommentsInlinesFirstReplies(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	fake.OnRows(`SELECT COUNT\(\*\) FROM article_comments ac`, []string{"count"}, []driver.Value{int64(2)})
+	fake.OnRows(`^SELECT ac.id, .* WHERE ac.article_id = \? AND ac.parent_id = 0`, commentColumns,
+		commentValues(10, 0, 2, 0), commentValues(20, 0, 2, 0))
+	onReplyPages(fake, []replyRow{
+		{id: 11, parentID: 10, userID: 2},
+		{id: 12, parentID: 10, userID: 2, replyTo: 3},
+		{id: 13, parentID: 10, userID: 4},
+		{id: 21, parentID: 20, userID: 4},
+	})
+	cfg := config.Default()
+	cfg.Comments.InlineReplies = 2
+
+	resp, err := NewArticleRepository(db, cfg).GetComments(context.Background(), 5, 1, 10, 7, "")
+	if err != nil || len(resp.Comments) != 2 {
+		t.Fatalf("获取评论失败: %v %+v", err, resp)
+	}
+	first, second := resp.Comments[0], resp.Comments[1]
+	if ids := replyIDs(first.Replies); !slices.Equal(ids, []uint{11, 12}) || !first.HasMoreReplies || first.RepliesCursor != 12 {
+		t.Fatalf("第一条评论应内联2条回复并返回游标，实际 %v has_more=%v cursor=%d", ids, first.HasMoreReplies, first.RepliesCursor)
+	}
+	if ids := replyIDs(second.Replies); !slices.Equal(ids, []uint{21}) || second.HasMoreReplies || second.RepliesCursor != 0 {
+		t.Fatalf("回复不足一页时不应有更多，实际 %v has_more=%v", ids, second.HasMoreReplies)
+	}
+	if !first.Replies[1].IsLiked || first.Replies[1].ReplyToUser == nil {
+		t.Fatal("内联回复应填充点赞状态和被回复用户")
+	}
+	if calls := fake.Calls(`WHERE ac.parent_id = \?`); len(calls) != 1 {
+		t.Fatalf("所有评论的内联回复应一次查询，实际 %d 次", len(calls))
+	}
+}
@@ -0,0 +1,297 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"gin/internal/models"
+	"gin/internal/utils"
+)
+
+// ContentTransferRepository 文章/资源所有权转移数据访问层
+// 转移需要接收者确认：发起时只记录待确认的转移，接受后才修改内容的 user_id；转移记录保留作为审计日志
+type ContentTransferRepository struct {
+	db     *Database
+	logger utils.Logger
+}
+
+// NewContentTransferRepository 创建所有权转移数据访问层
+func NewContentTransferRepository(db *Database) *ContentTransferRepository {
+	return &ContentTransferRepository{
+		db:     db,
+		logger: utils.GetLogger(),
+	}
+}
+
+// transferContentQueries 各内容类型的查询语句（已删除的内容不能转移）
+var transferContentQueries = map[string]struct {
+	owner  string // 查询并锁定作者
+	title  string // 标题
+	update string // 修改作者
+}{
+	models.TransferContentArticle: {
+		owner:  `SELECT user_id FROM articles WHERE id = ? AND status != 2 FOR UPDATE`,
+		title:  `(SELECT title FROM articles WHERE id = t.content_id)`,
+		update: `UPDATE articles SET user_id = ? WHERE id = ? AND user_id = ?`,
+	},
+	models.TransferContentResource: {
+		owner:  `SELECT user_id FROM resources WHERE id = ? AND status != 0 FOR UPDATE`,
+		title:  `(SELECT title FROM resources WHERE id = t.content_id)`,
+		update: `UPDATE resources SET user_id = ? WHERE id = ? AND user_id = ?`,
+	},
+}
+
+// TransferContentOwnership 发起所有权转移：校验当前用户是作者、接收者账户正常，且该内容没有待确认的转移
+func (r *ContentTransferRepository) TransferContentOwnership(ctx context.Context, contentType string, contentID, currentOwnerID, newOwnerID uint) (*models.ContentTransfer, error) {
+	queries, ok := transferContentQueries[contentType]
+	if !ok {
+		return nil, utils.NewAppError(utils.ErrInvalidParameter, "不支持的内容类型", 400)
+	}
+	if currentOwnerID == newOwnerID {
+		return nil, utils.NewAppError(utils.ErrInvalidParameter, "不能转移给自己", 400)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
+	defer cancel()
+
+	transfer := &models.ContentTransfer{
+		ContentType: contentType,
+		ContentID:   contentID,
+		FromUserID:  currentOwnerID,
+		ToUserID:    newOwnerID,
+		Status:      models.TransferStatusPending,
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	err := r.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		// 锁定内容行，避免并发发起多个转移
+		var ownerID uint
+		if err := tx.QueryRowContext(ctx, queries.owner, contentID).Scan(&ownerID); err != nil {
+			if err == sql.ErrNoRows {
+				return utils.ErrResourceNotFound
+			}
+			return utils.ErrDatabaseQuery
+		}
+		if ownerID != currentOwnerID {
+			return utils.NewAppError(utils.ErrUnauthorized, "只有作者本人可以转移所有权", 403)
+		}
+
+		// 已禁用、锁定或注销的账户不能作为接收者
+		var accountStatus int
+		if err := tx.QueryRowContext(ctx, `SELECT account_status FROM user_auth WHERE id = ?`, newOwnerID).Scan(&accountStatus); err != nil {
+			if err == sql.ErrNoRows {
+				return utils.ErrUserNotFound
+			}
+			return utils.ErrDatabaseQuery
+		}
+		if accountStatus != models.AccountStatusNormal {
+			return utils.ErrRecipientUnavailable
+		}
+
+		var pending int
+		if err := tx.QueryRowContext(ctx,
+			`SELECT COUNT(*) FROM content_ownership_transfers WHERE content_type = ? AND content_id = ? AND status = ?`,
+			contentType, contentID, models.TransferStatusPending).Scan(&pending); err != nil {
+			return utils.ErrDatabaseQuery
+		}
+		if pending > 0 {
+			return duplicateEntryError("该内容已有待确认的转移")
+		}
+
+		result, err := tx.ExecContext(ctx,
+			`INSERT INTO content_ownership_transfers (content_type, content_id, from_user_id, to_user_id, status, created_at)
+			 VALUES (?, ?, ?, ?, ?, ?)`,
+			contentType, contentID, currentOwnerID, newOwnerID, models.TransferStatusPending, transfer.CreatedAt)
+		if err != nil {
+			return utils.ErrDatabaseInsert
+		}
+		id, _ := result.LastInsertId()
+		transfer.ID = uint(id)
+		return nil
+	})
+	if err != nil {
+		r.logger.Warn("发起所有权转移失败", "contentType", contentType, "contentID", contentID,
+			"fromUserID", currentOwnerID, "toUserID", newOwnerID, "error", err.Error())
+		return nil, err
+	}
+
+	r.logger.Info("发起所有权转移", "transferID", transfer.ID, "contentType", contentType, "contentID", contentID,
+		"fromUserID", currentOwnerID, "toUserID", newOwnerID)
+	return transfer, nil
+}
+
+// AcceptTransfer 接收者接受转移：修改内容作者并记录处理时间
+// 发起后内容已被删除或作者已变更时，转移标记为已取消并返回409
+func (r *ContentTransferRepository) AcceptTransfer(ctx context.Context, transferID, userID uint) (*models.ContentTransfer, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
+	defer cancel()
+
+	var transfer *models.ContentTransfer
+	var stale bool
+	err := r.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		var err error
+		transfer, err = r.lockPendingTransfer(ctx, tx, transferID)
+		if err != nil {
+			return err
+		}
+		if transfer.ToUserID != userID {
+			return utils.NewAppError(utils.ErrUnauthorized, "只有接收者可以接受转移", 403)
+		}
+
+		queries := transferContentQueries[transfer.ContentType]
+		var ownerID uint
+		err = tx.QueryRowContext(ctx, queries.owner, transfer.ContentID).Scan(&ownerID)
+		if err != nil && err != sql.ErrNoRows {
+			return utils.ErrDatabaseQuery
+		}
+		now := time.Now().UTC()
+		transfer.RespondedAt = &now
+
+		if err == sql.ErrNoRows || ownerID != transfer.FromUserID {
+			// 内容已失效：取消转移（提交事务保留记录）
+			stale = true
+			transfer.Status = models.TransferStatusCancelled
+			return r.setTransferStatus(ctx, tx, transfer)
+		}
+
+		if _, err := tx.ExecContext(ctx, queries.update, transfer.ToUserID, transfer.ContentID, transfer.FromUserID); err != nil {
+			return utils.ErrDatabaseUpdate
+		}
+		transfer.Status = models.TransferStatusAccepted
+		return r.setTransferStatus(ctx, tx, transfer)
+	})
+	if err != nil {
+		r.logger.Warn("接受所有权转移失败", "transferID", transferID, "userID", userID, "error", err.Error())
+		return nil, err
+	}
+	if stale {
+		return nil, utils.NewAppError(utils.ErrInvalidRequest, "内容已删除或作者已变更，转移已取消", 409)
+	}
+
+	r.logger.Info("所有权转移完成", "transferID", transfer.ID, "contentType", transfer.ContentType, "contentID", transfer.ContentID,
+		"fromUserID", transfer.FromUserID, "toUserID", transfer.ToUserID)
+	return transfer, nil
+}
+
+// RejectTransfer 接收者拒绝转移
+func (r *ContentTransferRepository) RejectTransfer(ctx context.Context, transferID, userID uint) (*models.ContentTransfer, error) {
+	return r.closeTransfer(ctx, transferID, userID, models.TransferStatusRejected)
+}
+
+// CancelTransfer 发起者取消转移
+func (r *ContentTransferRepository) CancelTransfer(ctx context.Context, transferID, userID uint) (*models.ContentTransfer, error) {
+	return r.closeTransfer(ctx, transferID, userID, models.TransferStatusCancelled)
+}
+
+// closeTransfer 结束待确认的转移（拒绝只能由接收者操作，取消只能由发起者操作）
+func (r *ContentTransferRepository) closeTransfer(ctx context.Context, transferID, userID uint, status int) (*models.ContentTransfer, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
+	defer cancel()
+
+	var transfer *models.ContentTransfer
+	err := r.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		var err error
+		transfer, err = r.lockPendingTransfer(ctx, tx, transferID)
+		if err != nil {
+			return err
+		}
+		if status == models.TransferStatusRejected && transfer.ToUserID != userID {
+			return utils.NewAppError(utils.ErrUnauthorized, "只有接收者可以拒绝转移", 403)
+		}
+		if status == models.TransferStatusCancelled && transfer.FromUserID != userID {
+			return utils.NewAppError(utils.ErrUnauthorized, "只有发起者可以取消转移", 403)
+		}
+
+		now := time.Now().UTC()
+		transfer.Status = status
+		transfer.RespondedAt = &now
+		return r.setTransferStatus(ctx, tx, transfer)
+	})
+	if err != nil {
+		r.logger.Warn("结束所有权转移失败", "transferID", transferID, "userID", userID, "status", status, "error", err.Error())
+		return nil, err
+	}
+
+	r.logger.Info("所有权转移已结束", "transferID", transferID, "userID", userID, "status", status)
+	return transfer, nil
+}
+
+// lockPendingTransfer 查询并锁定待确认的转移，不存在或已处理时返回错误
+func (r *ContentTransferRepository) lockPendingTransfer(ctx context.Context, tx *sql.Tx, transferID uint) (*models.ContentTransfer, error) {
+	t := &models.ContentTransfer{}
+	err := tx.QueryRowContext(ctx,
+		`SELECT id, content_type, content_id, from_user_id, to_user_id, status, created_at
+		 FROM content_ownership_transfers WHERE id = ? FOR UPDATE`, transferID).Scan(
+		&t.ID, &t.ContentType, &t.ContentID, &t.FromUserID, &t.ToUserID, &t.Status, &t.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, utils.ErrResourceNotFound
+		}
+		return nil, utils.ErrDatabaseQuery
+	}
+	if t.Status != models.TransferStatusPending {
+		return nil, utils.NewAppError(utils.ErrInvalidRequest, "该转移已处理", 409)
+	}
+	if _, ok := transferContentQueries[t.ContentType]; !ok {
+		return nil, utils.NewAppError(utils.ErrInvalidParameter, "不支持的内容类型", 400)
+	}
+	return t, nil
+}
+
+// setTransferStatus 更新转移状态和处理时间
+func (r *ContentTransferRepository) setTransferStatus(ctx context.Context, tx *sql.Tx, t *models.ContentTransfer) error {
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE content_ownership_transfers SET status = ?, responded_at = ? WHERE id = ?`,
+		t.Status, t.RespondedAt, t.ID); err != nil {
+		return utils.ErrDatabaseUpdate
+	}
+	return nil
+}
+
+// ListTransfers 获取用户收到（incoming 为 true）或发起的转移，按时间倒序
+// pendingOnly 为 true 时只返回待确认的转移
+func (r *ContentTransferRepository) ListTransfers(ctx context.Context, userID uint, incoming, pendingOnly bool, limit int) ([]models.ContentTransfer, error) {
+	userColumn := "t.from_user_id"
+	if incoming {
+		userColumn = "t.to_user_id"
+	}
+	query := `SELECT t.id, t.content_type, t.content_id, t.from_user_id, t.to_user_id, t.status, t.created_at, t.responded_at,
+			  COALESCE(CASE t.content_type WHEN ? THEN ` + transferContentQueries[models.TransferContentArticle].title + `
+			  WHEN ? THEN ` + transferContentQueries[models.TransferContentResource].title + ` END, '')
+			  FROM content_ownership_transfers t
+			  WHERE ` + userColumn + ` = ?`
+	args := []interface{}{models.TransferContentArticle, models.TransferContentResource, userID}
+	if pendingOnly {
+		query += ` AND t.status = ?`
+		args = append(args, models.TransferStatusPending)
+	}
+	query += ` ORDER BY t.created_at DESC, t.id DESC LIMIT ?`
+	args = append(args, limit)
+
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetQueryTimeout())
+	defer cancel()
+
+	rows, err := r.db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.logger.Error("查询所有权转移失败", "userID", userID, "error", err.Error())
+		return nil, utils.ErrDatabaseQuery
+	}
+	defer rows.Close()
+
+	transfers := make([]models.ContentTransfer, 0)
+	for rows.Next() {
+		var t models.ContentTransfer
+		var respondedAt sql.NullTime
+		if err := rows.Scan(&t.ID, &t.ContentType, &t.ContentID, &t.FromUserID, &t.ToUserID, &t.Status,
+			&t.CreatedAt, &respondedAt, &t.ContentTitle); err != nil {
+			r.logger.Warn("解析所有权转移失败", "error", err.Error())
+			continue
+		}
+		if respondedAt.Valid {
+			t.RespondedAt = &respondedAt.Time
+		}
+		transfers = append(transfers, t)
+	}
+	return transfers, nil
+}
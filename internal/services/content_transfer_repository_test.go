@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"gin/internal/models"
+	"gin/internal/utils"
+)
+
+func TestTransferContentOwnershipRecipientChecks(t *testing.T) {
+	cases := []struct {
+		name          string
+		accountStatus []driver.Value // nil 表示用户不存在
+		wantErr       error
+		wantStatus    int
+	}{
+		{"接收者不存在", nil, utils.ErrUserNotFound, 404},
+		{"接收者已被禁用", []driver.Value{int64(2)}, utils.ErrRecipientUnavailable, 422},
+		{"接收者已注销", []driver.Value{int64(3)}, utils.ErrRecipientUnavailable, 422},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fake, db := newFakeDatabase(t)
+			fake.OnRows(`SELECT user_id FROM resources WHERE id = \? AND status != 0 FOR UPDATE`, []string{"user_id"}, []driver.Value{int64(1)})
+			if tc.accountStatus != nil {
+				fake.OnRows(`SELECT account_status FROM user_auth`, []string{"account_status"}, tc.accountStatus)
+			} else {
+				fake.OnRows(`SELECT account_status FROM user_auth`, []string{"account_status"})
+			}
+
+			_, err := NewContentTransferRepository(db).TransferContentOwnership(context.Background(), models.TransferContentResource, 5, 1, 2)
+			if !errors.Is(err, tc.wantErr) || utils.GetHTTPStatusCode(err) != tc.wantStatus {
+				t.Fatalf("应返回 %v (%d)，实际 %v", tc.wantErr, tc.wantStatus, err)
+			}
+			if calls := fake.Calls(`INSERT INTO content_ownership_transfers`); len(calls) != 0 {
+				t.Fatal("接收者不可用时不应创建转移")
+			}
+		})
+	}
+}
+
+func TestTransferContentOwnershipRecordsPending(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	fake.OnRows(`SELECT user_id FROM resources WHERE id = \? AND status != 0 FOR UPDATE`, []string{"user_id"}, []driver.Value{int64(1)})
+	fake.OnRows(`SELECT account_status FROM user_auth`, []string{"account_status"}, []driver.Value{int64(models.AccountStatusNormal)})
+	fake.OnRows(`SELECT COUNT\(\*\) FROM content_ownership_transfers`, []string{"count"}, []driver.Value{int64(0)})
+	fake.OnExec(`INSERT INTO content_ownership_transfers`, 7, 1)
+
+	transfer, err := NewContentTransferRepository(db).TransferContentOwnership(context.Background(), models.TransferContentResource, 5, 1, 2)
+	if err != nil {
+		t.Fatalf("发起转移失败: %v", err)
+	}
+	if transfer.ID != 7 || transfer.Status != models.TransferStatusPending {
+		t.Fatalf("应返回待确认的转移，实际 %+v", transfer)
+	}
+	args := fake.Calls(`INSERT INTO content_ownership_transfers`)[0].Args
+	if args[0] != models.TransferContentResource || args[1] != int64(5) || args[2] != int64(1) || args[3] != int64(2) || args[4] != int64(models.TransferStatusPending) {
+		t.Fatalf("审计记录内容错误: %v", args)
+	}
+	if calls := fake.Calls(`UPDATE resources SET user_id`); len(calls) != 0 {
+		t.Fatal("发起时不应修改资源作者")
+	}
+}
+
+func TestAcceptTransferCancelsStaleTransfer(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	fake.OnRows(`FROM content_ownership_transfers WHERE id = \? FOR UPDATE`,
+		[]string{"id", "content_type", "content_id", "from_user_id", "to_user_id", "status", "created_at"},
+		[]driver.Value{int64(3), models.TransferContentArticle, int64(5), int64(1), int64(2), int64(models.TransferStatusPending), time.Now().UTC()})
+	// 发起后作者已变更
+	fake.OnRows(`SELECT user_id FROM articles WHERE id = \? AND status != 2 FOR UPDATE`, []string{"user_id"}, []driver.Value{int64(9)})
+	fake.OnExec(`UPDATE content_ownership_transfers SET status`, 0, 1)
+
+	_, err := NewContentTransferRepository(db).AcceptTransfer(context.Background(), 3, 2)
+	if utils.GetHTTPStatusCode(err) != 409 {
+		t.Fatalf("内容作者已变更时应返回409，实际 %v", err)
+	}
+	if calls := fake.Calls(`UPDATE articles SET user_id`); len(calls) != 0 {
+		t.Fatal("内容已变更时不应修改作者")
+	}
+	update := fake.Calls(`UPDATE content_ownership_transfers SET status`)
+	if len(update) != 1 || update[0].Args[0] != int64(models.TransferStatusCancelled) {
+		t.Fatalf("失效的转移应标记为已取消，实际 %v", update)
+	}
+	if len(fake.Calls(`^COMMIT$`)) != 1 {
+		t.Fatal("取消状态应提交保存")
+	}
+}
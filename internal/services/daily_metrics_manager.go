@@ -168,6 +168,21 @@ func (m *DailyMetricsManager) GetTodayMetrics() (activeUsers, newUsers, totalReq
 	return
 }
 
+// RangeEndpointCalls 遍历今日各接口调用次数（持读锁回调，避免复制map；回调中不要做耗时操作）
+func (m *DailyMetricsManager) RangeEndpointCalls(fn func(endpoint string, calls int64)) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for endpoint, calls := range m.endpointCallCount {
+		fn(endpoint, calls)
+	}
+}
+
+// GetRequestTotals 获取今日请求总数、成功数、错误数
+func (m *DailyMetricsManager) GetRequestTotals() (total, success, errors int64) {
+	return atomic.LoadInt64(&m.totalRequests), atomic.LoadInt64(&m.successRequests), atomic.LoadInt64(&m.errorRequests)
+}
+
 // checkDateAndReset 轻量级检查日期（优化：使用读锁先检查，避免频繁加写锁）
 func (m *DailyMetricsManager) checkDateAndReset() {
 	dateFormat := "2006-01-02"
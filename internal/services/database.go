@@ -4,8 +4,10 @@ import (
 	"container/list"
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"hash/fnv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -13,9 +15,23 @@ import (
 	"gin/internal/config"
 	"gin/internal/utils"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 )
 
+// mysqlErrDuplicateEntry MySQL唯一键冲突错误码
+const mysqlErrDuplicateEntry = 1062
+
+// isDuplicateKeyError 判断是否为唯一键冲突（MySQL 1062 Duplicate entry）
+func isDuplicateKeyError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry
+}
+
+// duplicateEntryError 唯一键冲突转换为409错误（message 为返回给用户的提示）
+func duplicateEntryError(message string) error {
+	return utils.NewAppError(utils.ErrDuplicateEntry, message, 409)
+}
+
 // stmtCacheEntry Prepared Statement 缓存条目（LRU）
 type stmtCacheEntry struct {
 	query      string
@@ -45,8 +61,10 @@ type Database struct {
 	logger              utils.Logger
 	stopMonitor         chan struct{}  // 用于停止监控goroutine
 	monitorWg           sync.WaitGroup // 等待监控goroutine退出
+	poolMonitor         *poolMonitor   // 连接池峰值和等待趋势
 	stmtShards          [numShards]*stmtCacheShard
 	stmtMaxSizePerShard int
+	slowQueryThreshold  atomic.Int64 // 慢查询阈值（纳秒，支持配置热更新）
 	ctx                 context.Context
 	cancel              context.CancelFunc
 }
@@ -55,18 +73,9 @@ type Database struct {
 func NewDatabase(cfg *config.Config) (*Database, error) {
 	logger := utils.GetLogger()
 
-	// 构建数据库连接字符串（使用配置的超时参数，时区统一为UTC）
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=%s&parseTime=True&loc=UTC&timeout=%ds&readTimeout=%ds&writeTimeout=%ds&interpolateParams=true",
-		cfg.Database.Username,
-		cfg.Database.Password,
-		cfg.Database.Host,
-		cfg.Database.Port,
-		cfg.Database.Database,
-		cfg.Database.Charset,
-		cfg.DatabaseTimeouts.ConnectionTimeout,
-		cfg.DatabaseTimeouts.ReadTimeout,
-		cfg.DatabaseTimeouts.WriteTimeout,
-	)
+	// 会话级语句超时（0表示不设置）
+	maxExecutionTimeMS := statementTimeoutMS(cfg)
+	dsn := databaseDSN(cfg)
 
 	// 连接数据库
 	db, err := sql.Open("mysql", dsn)
@@ -87,7 +96,57 @@ func NewDatabase(cfg *config.Config) (*Database, error) {
 	}
 	db.SetConnMaxIdleTime(idleTimeout)
 
-	// 创建上下文
+	// 创建数据库实例
+	dbInstance := NewDatabaseWithDB(cfg, db)
+	ctx, cancel := dbInstance.ctx, dbInstance.cancel
+
+	// 测试连接（数据库暂不可用时按 startup_retry 配置指数退避重试，每次使用配置的超时）
+	testTimeout := time.Duration(cfg.DatabaseTimeouts.TestConnectionTimeout) * time.Second
+	err = utils.RetryWithBackoff(ctx, utils.NewStartupBackoff(&cfg.StartupRetry), "数据库", func(ctx context.Context) error {
+		pingCtx, pingCancel := context.WithTimeout(ctx, testTimeout)
+		defer pingCancel()
+		return db.PingContext(pingCtx)
+	})
+	if err != nil {
+		logger.Error("数据库连接测试失败", "error", err.Error())
+		cancel()
+		db.Close()
+		return nil, fmt.Errorf("数据库连接测试失败: %v", err)
+	}
+
+	// 启动连接池监控（使用配置的监控间隔，期间按采样间隔记录峰值）
+	dbInstance.poolMonitor = newPoolMonitor(db, cfg, logger)
+	dbInstance.monitorWg.Add(1)
+	go func() {
+		defer dbInstance.monitorWg.Done()
+		dbInstance.poolMonitor.run(time.Duration(cfg.DatabaseTimeouts.PoolMonitorInterval)*time.Minute, dbInstance.stopMonitor)
+	}()
+
+	// 校验会话变量已在连接上生效
+	if maxExecutionTimeMS > 0 {
+		verifyCtx, verifyCancel := context.WithTimeout(context.Background(), testTimeout)
+		dbInstance.verifyStatementTimeout(verifyCtx, maxExecutionTimeMS)
+		verifyCancel()
+	}
+
+	logger.Info("数据库连接成功",
+		"host", cfg.Database.Host,
+		"port", cfg.Database.Port,
+		"database", cfg.Database.Database,
+		"maxOpenConns", cfg.Database.MaxOpenConns,
+		"maxIdleConns", cfg.Database.MaxIdleConns,
+		"connMaxLifetime", cfg.Database.ConnMaxLifetime,
+		"connMaxIdleTime", idleTimeout,
+		"maxExecutionTimeMS", maxExecutionTimeMS)
+
+	// 预热连接池（创建初始连接，避免首次请求慢）
+	go dbInstance.warmupConnectionPool(cfg.Database.MaxIdleConns)
+
+	return dbInstance, nil
+}
+
+// NewDatabaseWithDB 用已打开的连接池创建数据库实例（不测试连接、不启动连接池监控）
+func NewDatabaseWithDB(cfg *config.Config, db *sql.DB) *Database {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	// 从配置读取 Prepared Statement 缓存大小限制（默认1000）
@@ -96,7 +155,6 @@ func NewDatabase(cfg *config.Config) (*Database, error) {
 		stmtMaxSize = cfg.DatabaseQueryAdvanced.PreparedStmtCacheSize
 	}
 
-	// 创建数据库实例
 	dbInstance := &Database{
 		DB:                  db,
 		config:              &cfg.Database,
@@ -105,13 +163,15 @@ func NewDatabase(cfg *config.Config) (*Database, error) {
 		queryAdvanced:       &cfg.DatabaseQueryAdvanced,
 		repositoryTimeouts:  &cfg.RepositoryTimeouts,
 		asyncTasksTimeouts:  &cfg.AsyncTasks,
-		logger:              logger,
+		logger:              utils.GetLogger(),
 		stopMonitor:         make(chan struct{}),
 		stmtMaxSizePerShard: stmtMaxSize / numShards, // 每个分片的容量
 		ctx:                 ctx,
 		cancel:              cancel,
 	}
 
+	dbInstance.SetSlowQueryThreshold(cfg.DatabaseQuery.SlowQueryThresholdMS)
+
 	// 初始化所有分片
 	for i := 0; i < numShards; i++ {
 		dbInstance.stmtShards[i] = &stmtCacheShard{
@@ -119,54 +179,83 @@ func NewDatabase(cfg *config.Config) (*Database, error) {
 			lruList: list.New(),
 		}
 	}
+	return dbInstance
+}
 
-	// 启动连接池监控（使用配置的监控间隔）
-	dbInstance.monitorWg.Add(1)
-	go func() {
-		defer dbInstance.monitorWg.Done()
-		ticker := time.NewTicker(time.Duration(cfg.DatabaseTimeouts.PoolMonitorInterval) * time.Minute)
-		defer ticker.Stop()
-		for {
-			select {
-			case <-ticker.C:
-				stats := db.Stats()
-				if stats.OpenConnections > int(float64(cfg.Database.MaxOpenConns)*0.8) {
-					logger.Warn("数据库连接池使用率过高",
-						"openConnections", stats.OpenConnections,
-						"maxOpenConns", cfg.Database.MaxOpenConns,
-						"inUse", stats.InUse,
-						"idle", stats.Idle)
-				}
-			case <-dbInstance.stopMonitor:
-				logger.Info("数据库连接池监控已停止")
-				return
-			}
-		}
-	}()
+// databaseDSN 构建数据库连接字符串（使用配置的超时参数，时区统一为UTC）
+func databaseDSN(cfg *config.Config) string {
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=%s&parseTime=True&loc=UTC&timeout=%ds&readTimeout=%ds&writeTimeout=%ds&interpolateParams=true",
+		cfg.Database.Username,
+		cfg.Database.Password,
+		cfg.Database.Host,
+		cfg.Database.Port,
+		cfg.Database.Database,
+		cfg.Database.Charset,
+		cfg.DatabaseTimeouts.ConnectionTimeout,
+		cfg.DatabaseTimeouts.ReadTimeout,
+		cfg.DatabaseTimeouts.WriteTimeout,
+	)
 
-	// 测试连接（使用配置的超时）
-	ctx, cancel = context.WithTimeout(context.Background(), time.Duration(cfg.DatabaseTimeouts.TestConnectionTimeout)*time.Second)
-	defer cancel()
+	// 会话级语句超时：驱动在每个新建连接上执行 SET max_execution_time=N，
+	// 由 MySQL 服务端主动终止超时的 SELECT，避免客户端放弃后查询仍占用资源
+	if maxExecutionTimeMS := statementTimeoutMS(cfg); maxExecutionTimeMS > 0 {
+		dsn += fmt.Sprintf("&max_execution_time=%d", maxExecutionTimeMS)
+	}
+	return dsn
+}
 
-	if err := db.PingContext(ctx); err != nil {
-		logger.Error("数据库连接测试失败", "error", err.Error())
-		db.Close()
-		return nil, fmt.Errorf("数据库连接测试失败: %v", err)
+// statementTimeoutMS 计算会话级 max_execution_time（毫秒），返回0表示不设置
+func statementTimeoutMS(cfg *config.Config) int {
+	if !cfg.DatabaseTimeouts.StatementTimeoutEnabled {
+		return 0
+	}
+	if cfg.DatabaseTimeouts.MaxExecutionTimeMS > 0 {
+		return cfg.DatabaseTimeouts.MaxExecutionTimeMS
+	}
+	if cfg.RepositoryTimeouts.DefaultQueryTimeout > 0 {
+		return cfg.RepositoryTimeouts.DefaultQueryTimeout * 1000
 	}
+	return 5000 // 与 GetQueryTimeout 默认值一致
+}
 
-	logger.Info("数据库连接成功",
-		"host", cfg.Database.Host,
-		"port", cfg.Database.Port,
-		"database", cfg.Database.Database,
-		"maxOpenConns", cfg.Database.MaxOpenConns,
-		"maxIdleConns", cfg.Database.MaxIdleConns,
-		"connMaxLifetime", cfg.Database.ConnMaxLifetime,
-		"connMaxIdleTime", idleTimeout)
+// WithStatementTimeout 按 ctx 的剩余时间为 SELECT 添加 MAX_EXECUTION_TIME 优化器提示
+// 语句级提示优先于会话级 max_execution_time，导出、报表等允许长时间运行的查询用它放宽服务端超时；
+// ctx 没有截止时间或语句不是 SELECT 时原样返回
+func WithStatementTimeout(ctx context.Context, query string) string {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return query
+	}
+	trimmed := strings.TrimLeft(query, " \t\r\n")
+	if len(trimmed) < 6 || !strings.EqualFold(trimmed[:6], "SELECT") {
+		return query
+	}
+	ms := time.Until(deadline).Milliseconds()
+	if ms < 1 {
+		ms = 1
+	}
+	return fmt.Sprintf("SELECT /*+ MAX_EXECUTION_TIME(%d) */%s", ms, trimmed[6:])
+}
 
-	// 预热连接池（创建初始连接，避免首次请求慢）
-	go dbInstance.warmupConnectionPool(cfg.Database.MaxIdleConns)
+// verifyStatementTimeout 读取当前连接的 max_execution_time，确认连接初始化时已设置
+func (d *Database) verifyStatementTimeout(ctx context.Context, expectedMS int) {
+	var actual int
+	if err := d.DB.QueryRowContext(ctx, "SELECT @@SESSION.max_execution_time").Scan(&actual); err != nil {
+		d.logger.Warn("读取会话变量 max_execution_time 失败", "error", err.Error())
+		return
+	}
+	if actual != expectedMS {
+		d.logger.Warn("会话变量 max_execution_time 未按配置生效",
+			"expected", expectedMS,
+			"actual", actual)
+		return
+	}
+	d.logger.Debug("会话变量 max_execution_time 已生效", "value", actual)
+}
 
-	return dbInstance, nil
+// SetSlowQueryThreshold 设置慢查询阈值（毫秒）
+func (d *Database) SetSlowQueryThreshold(thresholdMS int) {
+	d.slowQueryThreshold.Store(int64(time.Duration(thresholdMS) * time.Millisecond))
 }
 
 // warmupConnectionPool 预热连接池
@@ -259,6 +348,14 @@ func (d *Database) GetStats() sql.DBStats {
 	return d.DB.Stats()
 }
 
+// GetPoolTrend 获取最近一个监控周期的连接池趋势（峰值、等待增量和调优建议）
+func (d *Database) GetPoolTrend() PoolTrend {
+	if d.poolMonitor == nil {
+		return PoolTrend{}
+	}
+	return d.poolMonitor.Trend()
+}
+
 // WithTransaction 事务辅助方法
 func (d *Database) WithTransaction(ctx context.Context, fn func(*sql.Tx) error) error {
 	tx, err := d.DB.BeginTx(ctx, &sql.TxOptions{
@@ -335,40 +432,9 @@ func (d *Database) RetryQuery(ctx context.Context, maxRetries int, fn func() err
 	return fmt.Errorf("查询重试%d次后仍然失败: %w", maxRetries, err)
 }
 
-// isRetriableError 判断是否为可重试的错误
+// isRetriableError 判断是否为可重试的错误（与异步任务重试共用 utils.IsRetriableDBError）
 func isRetriableError(err error) bool {
-	if err == nil {
-		return false
-	}
-
-	errMsg := err.Error()
-	// MySQL可重试错误
-	retriableErrors := []string{
-		"connection refused",
-		"connection reset",
-		"broken pipe",
-		"too many connections",
-		"lock wait timeout",
-		"deadlock",
-	}
-
-	for _, retryErr := range retriableErrors {
-		if contains(errMsg, retryErr) {
-			return true
-		}
-	}
-	return false
-}
-
-// contains 检查字符串是否包含子串
-func contains(s, substr string) bool {
-	// 使用简单的字符串包含检查
-	for i := 0; i+len(substr) <= len(s); i++ {
-		if s[i:i+len(substr)] == substr {
-			return true
-		}
-	}
-	return false
+	return utils.IsRetriableDBError(err)
 }
 
 // getShardForQuery 获取查询对应的分片
@@ -468,10 +534,11 @@ func (d *Database) evictOldestInShard(shard *stmtCacheShard) {
 // ExecWithCache 使用缓存的prepared statement执行查询
 func (d *Database) ExecWithCache(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
 	start := time.Now()
+	logger := utils.ContextLogger(ctx, d.logger)
 
 	stmt, err := d.PrepareStmt(ctx, query)
 	if err != nil {
-		d.logger.Error("SQL执行失败: prepare失败",
+		logger.Error("SQL执行失败: prepare失败",
 			"query", utils.TruncateString(query, d.queryAdvanced.QueryLogTruncateLength),
 			"error", err.Error(),
 			"duration", time.Since(start))
@@ -482,7 +549,7 @@ func (d *Database) ExecWithCache(ctx context.Context, query string, args ...inte
 	duration := time.Since(start)
 
 	if err != nil {
-		d.logger.Error("SQL执行失败",
+		logger.Error("SQL执行失败",
 			"query", utils.TruncateString(query, d.queryAdvanced.QueryLogTruncateLength),
 			"error", err.Error(),
 			"duration", duration)
@@ -492,22 +559,23 @@ func (d *Database) ExecWithCache(ctx context.Context, query string, args ...inte
 	rowsAffected, _ := result.RowsAffected()
 	lastInsertID, _ := result.LastInsertId()
 
-	d.logger.Info("SQL执行成功[ExecWithCache]",
+	logger.Info("SQL执行成功[ExecWithCache]",
 		"query", utils.TruncateString(query, d.queryAdvanced.QueryLogTruncateLength),
 		"rowsAffected", rowsAffected,
 		"lastInsertID", lastInsertID,
 		"duration", duration,
 		"durationMs", duration.Milliseconds())
 
-	// 慢查询警告（从配置读取阈值）
-	slowQueryThreshold := time.Duration(d.queryConfig.SlowQueryThresholdMS) * time.Millisecond
+	// 慢查询警告（从配置读取阈值）；同时交给慢查询检测器按指纹统计频次
+	utils.GetGlobalSlowQueryDetector().Record(query, duration, args)
+	slowQueryThreshold := time.Duration(d.slowQueryThreshold.Load())
 	if duration > slowQueryThreshold {
-		d.logger.Warn("检测到慢查询[ExecWithCache]",
+		logger.Warn("检测到慢查询[ExecWithCache]",
 			"query", utils.TruncateString(query, d.queryAdvanced.QueryLogTruncateLength),
 			"duration", duration,
 			"durationMs", duration.Milliseconds(),
 			"threshold", slowQueryThreshold.String(),
-			"params", utils.FormatSQLParams(args))
+			"params", utils.FormatSQLParams(utils.RedactSQLParams(query, args)))
 	}
 
 	return result, nil
@@ -516,10 +584,11 @@ func (d *Database) ExecWithCache(ctx context.Context, query string, args ...inte
 // QueryRowWithCache 使用缓存的prepared statement执行单行查询
 func (d *Database) QueryRowWithCache(ctx context.Context, query string, args ...interface{}) *sql.Row {
 	start := time.Now()
+	logger := utils.ContextLogger(ctx, d.logger)
 
 	stmt, err := d.PrepareStmt(ctx, query)
 	if err != nil {
-		d.logger.Warn("SQL查询: prepare失败，回退到普通查询",
+		logger.Warn("SQL查询: prepare失败，回退到普通查询",
 			"query", utils.TruncateString(query, d.queryAdvanced.QueryLogTruncateLength),
 			"error", err.Error())
 		// 如果prepare失败，回退到普通查询
@@ -529,15 +598,16 @@ func (d *Database) QueryRowWithCache(ctx context.Context, query string, args ...
 	row := stmt.QueryRowContext(ctx, args...)
 	duration := time.Since(start)
 
-	// 慢查询警告（从配置读取阈值）
-	slowQueryThreshold := time.Duration(d.queryConfig.SlowQueryThresholdMS) * time.Millisecond
+	// 慢查询警告（从配置读取阈值）；同时交给慢查询检测器按指纹统计频次
+	utils.GetGlobalSlowQueryDetector().Record(query, duration, args)
+	slowQueryThreshold := time.Duration(d.slowQueryThreshold.Load())
 	if duration > slowQueryThreshold {
-		d.logger.Warn("检测到慢查询[QueryRowWithCache]",
+		logger.Warn("检测到慢查询[QueryRowWithCache]",
 			"query", utils.TruncateString(query, d.queryAdvanced.QueryLogTruncateLength),
 			"duration", duration,
 			"durationMs", duration.Milliseconds(),
 			"threshold", slowQueryThreshold.String(),
-			"params", utils.FormatSQLParams(args))
+			"params", utils.FormatSQLParams(utils.RedactSQLParams(query, args)))
 	}
 
 	return row
@@ -546,10 +616,11 @@ func (d *Database) QueryRowWithCache(ctx context.Context, query string, args ...
 // QueryWithCache 使用缓存的prepared statement执行多行查询
 func (d *Database) QueryWithCache(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
 	start := time.Now()
+	logger := utils.ContextLogger(ctx, d.logger)
 
 	stmt, err := d.PrepareStmt(ctx, query)
 	if err != nil {
-		d.logger.Error("SQL查询失败: prepare失败",
+		logger.Error("SQL查询失败: prepare失败",
 			"query", utils.TruncateString(query, d.queryAdvanced.QueryLogTruncateLength),
 			"error", err.Error(),
 			"duration", time.Since(start))
@@ -560,27 +631,28 @@ func (d *Database) QueryWithCache(ctx context.Context, query string, args ...int
 	duration := time.Since(start)
 
 	if err != nil {
-		d.logger.Error("SQL查询失败",
+		logger.Error("SQL查询失败",
 			"query", utils.TruncateString(query, d.queryAdvanced.QueryLogTruncateLength),
 			"error", err.Error(),
 			"duration", duration)
 		return nil, err
 	}
 
-	d.logger.Info("SQL查询成功[QueryWithCache]",
+	logger.Info("SQL查询成功[QueryWithCache]",
 		"query", utils.TruncateString(query, d.queryAdvanced.QueryLogTruncateLength),
 		"duration", duration,
 		"durationMs", duration.Milliseconds())
 
-	// 慢查询警告（从配置读取阈值）
-	slowQueryThreshold := time.Duration(d.queryConfig.SlowQueryThresholdMS) * time.Millisecond
+	// 慢查询警告（从配置读取阈值）；同时交给慢查询检测器按指纹统计频次
+	utils.GetGlobalSlowQueryDetector().Record(query, duration, args)
+	slowQueryThreshold := time.Duration(d.slowQueryThreshold.Load())
 	if duration > slowQueryThreshold {
-		d.logger.Warn("检测到慢查询[QueryWithCache]",
+		logger.Warn("检测到慢查询[QueryWithCache]",
 			"query", utils.TruncateString(query, d.queryAdvanced.QueryLogTruncateLength),
 			"duration", duration,
 			"durationMs", duration.Milliseconds(),
 			"threshold", slowQueryThreshold.String(),
-			"params", utils.FormatSQLParams(args))
+			"params", utils.FormatSQLParams(utils.RedactSQLParams(query, args)))
 	}
 
 	return rows, nil
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"gin/internal/models"
+	"gin/internal/utils"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestIsDuplicateKeyError(t *testing.T) {
+	duplicate := &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'alice' for key 'username'"}
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"唯一键冲突", duplicate, true},
+		{"包装后的唯一键冲突", fmt.Errorf("insert: %w", duplicate), true},
+		{"其他MySQL错误", &mysql.MySQLError{Number: 1213, Message: "Deadlock found"}, false},
+		{"非MySQL错误", errors.New("Duplicate entry"), false},
+		{"nil", nil, false},
+	}
+	for _, tc := range cases {
+		if got := isDuplicateKeyError(tc.err); got != tc.want {
+			t.Errorf("%s: isDuplicateKeyError = %v，期望 %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestCreateUserDuplicateKeyReturnsConflict(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	fake.OnError(`INSERT INTO user_auth`, &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"})
+
+	err := NewUserRepository(db).CreateUser(context.Background(), &models.User{Username: "alice", Email: "alice@example.com"})
+	if !errors.Is(err, utils.ErrUserAlreadyExists) || utils.GetHTTPStatusCode(err) != 409 {
+		t.Fatalf("并发注册的唯一键冲突应返回409，实际 %v (%d)", err, utils.GetHTTPStatusCode(err))
+	}
+
+	fake.OnError(`INSERT INTO user_auth`, errors.New("connection reset"))
+	err = NewUserRepository(db).CreateUser(context.Background(), &models.User{Username: "bob"})
+	if !errors.Is(err, utils.ErrDatabaseInsert) || utils.GetHTTPStatusCode(err) != 500 {
+		t.Fatalf("其他数据库错误仍应返回500，实际 %v", err)
+	}
+}
@@ -0,0 +1,347 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/utils"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// fakeMySQLServer 只实现握手和对所有命令回复OK的MySQL服务端，记录每个连接执行的语句
+type fakeMySQLServer struct {
+	ln      net.Listener
+	mu      sync.Mutex
+	queries map[int][]string // 连接序号 -> 执行过的语句
+}
+
+func newFakeMySQLServer(t *testing.T) *fakeMySQLServer {
+	t.Helper()
+	return startFakeMySQLServer(t, "127.0.0.1:0")
+}
+
+// startFakeMySQLServer 在指定地址启动假服务端
+func startFakeMySQLServer(t *testing.T, addr string) *fakeMySQLServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	s := &fakeMySQLServer{ln: ln, queries: make(map[int][]string)}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for connID := 1; ; connID++ {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn, connID)
+		}
+	}()
+	return s
+}
+
+func (s *fakeMySQLServer) addr() (host, port string) {
+	host, port, _ = net.SplitHostPort(s.ln.Addr().String())
+	return host, port
+}
+
+// connQueries 返回每个连接执行过的语句
+func (s *fakeMySQLServer) connQueries() map[int][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := make(map[int][]string, len(s.queries))
+	for id, q := range s.queries {
+		result[id] = append([]string(nil), q...)
+	}
+	return result
+}
+
+func (s *fakeMySQLServer) serve(conn net.Conn, connID int) {
+	defer conn.Close()
+	s.mu.Lock()
+	s.queries[connID] = nil
+	s.mu.Unlock()
+
+	// 初始握手包（协议版本10，mysql_native_password）
+	handshake := []byte{10}
+	handshake = append(handshake, "8.0.36\x00"...)
+	handshake = binary.LittleEndian.AppendUint32(handshake, uint32(connID))
+	handshake = append(handshake, "abcdefgh\x00"...)
+	handshake = binary.LittleEndian.AppendUint16(handshake, 0xf7ff) // 低16位能力标志（不含SSL）
+	handshake = append(handshake, 45)                               // utf8mb4_general_ci
+	handshake = binary.LittleEndian.AppendUint16(handshake, 2)      // SERVER_STATUS_AUTOCOMMIT
+	handshake = binary.LittleEndian.AppendUint16(handshake, 0x0008) // 高16位：CLIENT_PLUGIN_AUTH
+	handshake = append(handshake, 21)
+	handshake = append(handshake, make([]byte, 10)...)
+	handshake = append(handshake, "ijklmnopqrst\x00"...)
+	handshake = append(handshake, "mysql_native_password\x00"...)
+	if writeMySQLPacket(conn, 0, handshake) != nil {
+		return
+	}
+	if _, _, err := readMySQLPacket(conn); err != nil { // 客户端认证响应
+		return
+	}
+	if writeMySQLPacket(conn, 2, mysqlOKPacket) != nil {
+		return
+	}
+
+	for {
+		seq, payload, err := readMySQLPacket(conn)
+		if err != nil || len(payload) == 0 || payload[0] == 0x01 { // COM_QUIT
+			return
+		}
+		if payload[0] == 0x03 { // COM_QUERY
+			s.mu.Lock()
+			s.queries[connID] = append(s.queries[connID], string(payload[1:]))
+			s.mu.Unlock()
+		}
+		if writeMySQLPacket(conn, seq+1, mysqlOKPacket) != nil {
+			return
+		}
+	}
+}
+
+// mysqlOKPacket OK包：影响行数0、插入ID 0、AUTOCOMMIT、无警告
+var mysqlOKPacket = []byte{0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00}
+
+func writeMySQLPacket(w io.Writer, seq byte, payload []byte) error {
+	header := []byte{byte(len(payload)), byte(len(payload) >> 8), byte(len(payload) >> 16), seq}
+	_, err := w.Write(append(header, payload...))
+	return err
+}
+
+func readMySQLPacket(r io.Reader) (byte, []byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, int(header[0])|int(header[1])<<8|int(header[2])<<16)
+	_, err := io.ReadFull(r, payload)
+	return header[3], payload, err
+}
+
+func TestStatementTimeoutMS(t *testing.T) {
+	cfg := config.Default()
+	cfg.DatabaseTimeouts.StatementTimeoutEnabled = true
+	cfg.DatabaseTimeouts.MaxExecutionTimeMS = 0
+	cfg.RepositoryTimeouts.DefaultQueryTimeout = 7
+	if got := statementTimeoutMS(cfg); got != 7000 {
+		t.Fatalf("未单独配置时应取默认查询超时 7000ms，实际 %d", got)
+	}
+
+	cfg.DatabaseTimeouts.MaxExecutionTimeMS = 1500
+	if got := statementTimeoutMS(cfg); got != 1500 {
+		t.Fatalf("应使用配置的 1500ms，实际 %d", got)
+	}
+
+	cfg.DatabaseTimeouts.StatementTimeoutEnabled = false
+	if got := statementTimeoutMS(cfg); got != 0 {
+		t.Fatalf("关闭后不应设置，实际 %d", got)
+	}
+	if strings.Contains(databaseDSN(cfg), "max_execution_time") {
+		t.Fatal("关闭后连接字符串不应包含 max_execution_time")
+	}
+}
+
+func TestStatementTimeoutSetOnEveryConnection(t *testing.T) {
+	server := newFakeMySQLServer(t)
+	cfg := config.Default()
+	cfg.Database.Host, cfg.Database.Port = server.addr()
+	cfg.DatabaseTimeouts.StatementTimeoutEnabled = true
+	cfg.DatabaseTimeouts.MaxExecutionTimeMS = 1234
+
+	db, err := sql.Open("mysql", databaseDSN(cfg))
+	if err != nil {
+		t.Fatalf("打开连接池失败: %v", err)
+	}
+	defer db.Close()
+
+	// 同时持有两个连接，保证驱动新建了两次连接
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	first, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("建立连接失败: %v", err)
+	}
+	defer first.Close()
+	second, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("建立连接失败: %v", err)
+	}
+	defer second.Close()
+
+	queries := server.connQueries()
+	if len(queries) != 2 {
+		t.Fatalf("应建立2个连接，实际 %d", len(queries))
+	}
+	for id, q := range queries {
+		found := false
+		for _, stmt := range q {
+			if strings.ReplaceAll(stmt, " ", "") == "SETmax_execution_time=1234" {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("连接 %d 未设置 max_execution_time，执行的语句: %v", id, q)
+		}
+	}
+}
+
+// unusedAddr 返回当前没有服务监听的本地地址
+func unusedAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	return addr
+}
+
+// startupRetryConfig 快速重试的启动配置（数据库地址为 addr）
+func startupRetryConfig(addr string, maxElapsedSeconds int) *config.Config {
+	cfg := config.Default()
+	cfg.Database.Host, cfg.Database.Port, _ = net.SplitHostPort(addr)
+	cfg.DatabaseTimeouts.StatementTimeoutEnabled = false
+	cfg.DatabaseTimeouts.ConnectionTimeout = 1
+	cfg.StartupRetry = config.StartupRetryConfig{MaxElapsedSeconds: maxElapsedSeconds, InitialIntervalMS: 50, MaxIntervalMS: 200, Multiplier: 2}
+	return cfg
+}
+
+func TestNewDatabaseWaitsForDatabaseToComeUp(t *testing.T) {
+	addr := unusedAddr(t)
+	cfg := startupRetryConfig(addr, 10)
+
+	// 前几次连接被拒绝，之后数据库启动
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		startFakeMySQLServer(t, addr)
+	}()
+
+	db, err := NewDatabase(cfg)
+	if err != nil {
+		t.Fatalf("数据库短暂不可用时应重试后启动成功，实际 %v", err)
+	}
+	db.Close()
+}
+
+func TestNewDatabaseFailsWhenDatabaseStaysDown(t *testing.T) {
+	cfg := startupRetryConfig(unusedAddr(t), 1)
+
+	start := time.Now()
+	if _, err := NewDatabase(cfg); err == nil {
+		t.Fatal("数据库一直不可用时应启动失败")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("应在重试总时长后放弃，实际耗时 %v", elapsed)
+	}
+}
+
+func TestWithStatementTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+	defer cancel()
+
+	got := WithStatementTimeout(ctx, "\n\t  select id FROM user_statistics")
+	var ms int
+	if _, err := fmt.Sscanf(got, "SELECT /*+ MAX_EXECUTION_TIME(%d) */ id FROM user_statistics", &ms); err != nil {
+		t.Fatalf("应在 SELECT 后添加语句级超时提示，实际 %q", got)
+	}
+	if ms <= 119000 || ms > 120000 {
+		t.Fatalf("提示的超时应为 ctx 的剩余时间（约120000ms），实际 %d", ms)
+	}
+
+	if got := WithStatementTimeout(context.Background(), "SELECT 1"); got != "SELECT 1" {
+		t.Fatalf("ctx 没有截止时间时应原样返回，实际 %q", got)
+	}
+	if got := WithStatementTimeout(ctx, "DELETE FROM t"); got != "DELETE FROM t" {
+		t.Fatalf("非 SELECT 语句应原样返回，实际 %q", got)
+	}
+}
+
+// recordingLogger 记录日志消息及其固定字段的日志器
+type recordingLogger struct {
+	mu      *sync.Mutex
+	fields  []interface{}
+	entries *[]recordedLog
+}
+
+// recordedLog 一条日志（fields 包含 With 添加的固定字段）
+type recordedLog struct {
+	msg    string
+	fields []interface{}
+}
+
+func newRecordingLogger() *recordingLogger {
+	return &recordingLogger{mu: &sync.Mutex{}, entries: &[]recordedLog{}}
+}
+
+func (l *recordingLogger) record(msg string, fields []interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	*l.entries = append(*l.entries, recordedLog{msg: msg, fields: append(append([]interface{}{}, l.fields...), fields...)})
+}
+
+func (l *recordingLogger) Info(msg string, fields ...interface{})  { l.record(msg, fields) }
+func (l *recordingLogger) Warn(msg string, fields ...interface{})  { l.record(msg, fields) }
+func (l *recordingLogger) Error(msg string, fields ...interface{}) { l.record(msg, fields) }
+func (l *recordingLogger) Debug(msg string, fields ...interface{}) { l.record(msg, fields) }
+func (l *recordingLogger) Fatal(msg string, fields ...interface{}) { l.record(msg, fields) }
+func (l *recordingLogger) Close() error                            { return nil }
+func (l *recordingLogger) With(fields ...interface{}) utils.Logger {
+	return &recordingLogger{mu: l.mu, fields: append(append([]interface{}{}, l.fields...), fields...), entries: l.entries}
+}
+
+// find 返回第一条消息以 prefix 开头的日志中 key 对应的字段值
+func (l *recordingLogger) find(prefix, key string) (interface{}, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, entry := range *l.entries {
+		if !strings.HasPrefix(entry.msg, prefix) {
+			continue
+		}
+		for i := 0; i+1 < len(entry.fields); i += 2 {
+			if entry.fields[i] == key {
+				return entry.fields[i+1], true
+			}
+		}
+		return nil, false
+	}
+	return nil, false
+}
+
+func TestSlowQueryLogCarriesRequestID(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	fake.OnExec(`UPDATE articles`, 0, 1)
+	fake.OnRows(`SELECT id FROM articles`, []string{"id"}, []driver.Value{int64(1)})
+	logger := newRecordingLogger()
+	db.logger = logger
+	db.slowQueryThreshold.Store(-1) // 所有查询都视为慢查询
+
+	ctx := utils.WithRequestID(context.Background(), "req-db")
+	if _, err := db.ExecWithCache(ctx, "UPDATE articles SET view_count = view_count + 1 WHERE id = ?", 1); err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	var id int64
+	if err := db.QueryRowWithCache(ctx, "SELECT id FROM articles WHERE id = ?", 1).Scan(&id); err != nil {
+		t.Fatalf("查询失败: %v", err)
+	}
+
+	for _, msg := range []string{"检测到慢查询[ExecWithCache]", "检测到慢查询[QueryRowWithCache]", "SQL执行成功[ExecWithCache]"} {
+		if got, ok := logger.find(msg, "request_id"); !ok || got != "req-db" {
+			t.Fatalf("%s 日志应携带请求ID，实际 %v", msg, got)
+		}
+	}
+}
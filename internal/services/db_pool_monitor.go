@@ -0,0 +1,130 @@
+package services
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/utils"
+)
+
+// PoolTrend 最近一个监控周期内的连接池趋势
+type PoolTrend struct {
+	PeakInUse            int           `json:"peak_in_use"`             // 周期内使用中连接数的峰值
+	WaitCountDelta       int64         `json:"wait_count_delta"`        // 周期内新增的等待次数
+	WaitDurationDelta    time.Duration `json:"wait_duration_delta"`     // 周期内新增的等待总时长
+	SuggestedMaxOpenConn int           `json:"suggested_max_open_conn"` // 建议的 max_open_conns（无需调整时为0）
+	EndedAt              time.Time     `json:"ended_at"`                // 周期结束时间（零值表示尚未完成第一个周期）
+}
+
+// poolMonitor 连接池监控：按采样间隔记录峰值，每个监控周期汇总一次等待增量并给出调优建议
+type poolMonitor struct {
+	db           *sql.DB
+	maxOpenConns int
+	perf         *config.PerformanceMonitoringConfig
+	logger       utils.Logger
+
+	mu               sync.Mutex
+	peakInUse        int
+	lastWaitCount    int64
+	lastWaitDuration time.Duration
+	trend            PoolTrend
+}
+
+// newPoolMonitor 创建连接池监控（以当前累计等待数作为基线）
+func newPoolMonitor(db *sql.DB, cfg *config.Config, logger utils.Logger) *poolMonitor {
+	stats := db.Stats()
+	return &poolMonitor{
+		db:               db,
+		maxOpenConns:     cfg.Database.MaxOpenConns,
+		perf:             &cfg.PerformanceMonitoring,
+		logger:           logger,
+		lastWaitCount:    stats.WaitCount,
+		lastWaitDuration: stats.WaitDuration,
+	}
+}
+
+// run 采样和汇总循环，stop 关闭时退出
+func (m *poolMonitor) run(interval time.Duration, stop <-chan struct{}) {
+	sampleTicker := time.NewTicker(time.Duration(m.perf.DBPoolSampleSeconds) * time.Second)
+	defer sampleTicker.Stop()
+	reportTicker := time.NewTicker(interval)
+	defer reportTicker.Stop()
+
+	for {
+		select {
+		case <-sampleTicker.C:
+			m.sample(m.db.Stats())
+		case <-reportTicker.C:
+			m.report(m.db.Stats(), time.Now().UTC())
+		case <-stop:
+			m.logger.Info("数据库连接池监控已停止")
+			return
+		}
+	}
+}
+
+// sample 记录使用中连接数的峰值
+func (m *poolMonitor) sample(stats sql.DBStats) {
+	m.mu.Lock()
+	m.peakInUse = max(m.peakInUse, stats.InUse)
+	m.mu.Unlock()
+}
+
+// report 汇总本周期的峰值和等待增量，保存趋势并输出告警/建议日志
+func (m *poolMonitor) report(stats sql.DBStats, now time.Time) PoolTrend {
+	m.mu.Lock()
+	trend := PoolTrend{
+		PeakInUse:         max(m.peakInUse, stats.InUse),
+		WaitCountDelta:    stats.WaitCount - m.lastWaitCount,
+		WaitDurationDelta: stats.WaitDuration - m.lastWaitDuration,
+		EndedAt:           now,
+	}
+	if m.shouldSuggest(trend) {
+		trend.SuggestedMaxOpenConn = m.suggestMaxOpenConns(trend.PeakInUse)
+	}
+	m.trend = trend
+	m.peakInUse = stats.InUse
+	m.lastWaitCount = stats.WaitCount
+	m.lastWaitDuration = stats.WaitDuration
+	m.mu.Unlock()
+
+	if stats.OpenConnections > int(float64(m.maxOpenConns)*0.8) {
+		m.logger.Warn("数据库连接池使用率过高",
+			"openConnections", stats.OpenConnections,
+			"maxOpenConns", m.maxOpenConns,
+			"inUse", stats.InUse,
+			"idle", stats.Idle,
+			"peakInUse", trend.PeakInUse)
+	}
+	if trend.SuggestedMaxOpenConn > 0 {
+		m.logger.Warn("数据库连接池出现等待，建议调大 database.max_open_conns",
+			"maxOpenConns", m.maxOpenConns,
+			"peakInUse", trend.PeakInUse,
+			"waitCountDelta", trend.WaitCountDelta,
+			"waitDurationDelta", trend.WaitDurationDelta,
+			"suggestedMaxOpenConns", trend.SuggestedMaxOpenConn)
+	}
+	return trend
+}
+
+// shouldSuggest 本周期的新增等待是否同时达到次数和时长阈值
+func (m *poolMonitor) shouldSuggest(trend PoolTrend) bool {
+	return trend.WaitCountDelta >= m.perf.DBPoolWaitCountWarning &&
+		trend.WaitDurationDelta >= time.Duration(m.perf.DBPoolWaitDurationWarningMS)*time.Millisecond
+}
+
+// suggestMaxOpenConns 按观测峰值（不低于当前上限）加上配置的余量计算建议值，至少比当前上限多1
+func (m *poolMonitor) suggestMaxOpenConns(peakInUse int) int {
+	base := max(peakInUse, m.maxOpenConns)
+	suggested := (base*(100+m.perf.DBPoolSuggestHeadroomPercent) + 99) / 100
+	return max(suggested, m.maxOpenConns+1)
+}
+
+// Trend 返回最近一个完成的监控周期的趋势
+func (m *poolMonitor) Trend() PoolTrend {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.trend
+}
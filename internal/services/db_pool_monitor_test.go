@@ -0,0 +1,99 @@
+package services
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/testutil"
+	"gin/internal/utils"
+)
+
+// newTestPoolMonitor 创建 max_open_conns 为10、余量为25%的连接池监控
+func newTestPoolMonitor(t *testing.T) *poolMonitor {
+	t.Helper()
+	db := testutil.NewFakeDB().Open()
+	t.Cleanup(func() { db.Close() })
+	cfg := config.Default()
+	cfg.Database.MaxOpenConns = 10
+	cfg.PerformanceMonitoring.DBPoolWaitCountWarning = 2
+	cfg.PerformanceMonitoring.DBPoolWaitDurationWarningMS = 100
+	cfg.PerformanceMonitoring.DBPoolSuggestHeadroomPercent = 25
+	return newPoolMonitor(db, cfg, utils.GetLogger())
+}
+
+func TestPoolMonitorPeakPerInterval(t *testing.T) {
+	m := newTestPoolMonitor(t)
+	for _, inUse := range []int{3, 7, 2} {
+		m.sample(sql.DBStats{InUse: inUse})
+	}
+	now := time.Now().UTC()
+	trend := m.report(sql.DBStats{InUse: 1}, now)
+	if trend.PeakInUse != 7 || !trend.EndedAt.Equal(now) {
+		t.Fatalf("应记录周期内的使用中连接数峰值，实际 %+v", trend)
+	}
+	if m.Trend() != trend {
+		t.Fatalf("Trend 应返回最近一个周期的趋势，实际 %+v", m.Trend())
+	}
+
+	// 新周期的峰值从汇总时的使用中连接数重新开始
+	m.sample(sql.DBStats{InUse: 2})
+	if trend := m.report(sql.DBStats{InUse: 0}, now); trend.PeakInUse != 2 {
+		t.Fatalf("每个周期应重新统计峰值，实际 %d", trend.PeakInUse)
+	}
+}
+
+func TestPoolMonitorWaitDeltaAndSuggestion(t *testing.T) {
+	m := newTestPoolMonitor(t)
+	m.sample(sql.DBStats{InUse: 12})
+	trend := m.report(sql.DBStats{WaitCount: 5, WaitDuration: 300 * time.Millisecond}, time.Now())
+	if trend.WaitCountDelta != 5 || trend.WaitDurationDelta != 300*time.Millisecond {
+		t.Fatalf("应统计本周期新增的等待，实际 %+v", trend)
+	}
+	if trend.SuggestedMaxOpenConn != 15 {
+		t.Fatalf("建议值应为峰值12加25%%余量，实际 %d", trend.SuggestedMaxOpenConn)
+	}
+
+	// 累计值没有变化：本周期没有新增等待，不再给出建议
+	trend = m.report(sql.DBStats{WaitCount: 5, WaitDuration: 300 * time.Millisecond}, time.Now())
+	if trend.WaitCountDelta != 0 || trend.WaitDurationDelta != 0 || trend.SuggestedMaxOpenConn != 0 {
+		t.Fatalf("没有新增等待时不应给出建议，实际 %+v", trend)
+	}
+
+	// 次数和时长都达到阈值才给出建议
+	for _, stats := range []sql.DBStats{
+		{WaitCount: 6, WaitDuration: 900 * time.Millisecond},  // 次数不足
+		{WaitCount: 16, WaitDuration: 950 * time.Millisecond}, // 时长不足
+	} {
+		if trend := m.report(stats, time.Now()); trend.SuggestedMaxOpenConn != 0 {
+			t.Fatalf("未同时达到次数和时长阈值时不应给出建议，实际 %+v", trend)
+		}
+	}
+}
+
+func TestPoolMonitorSuggestMaxOpenConns(t *testing.T) {
+	m := newTestPoolMonitor(t)
+	cases := []struct {
+		peak     int
+		headroom int
+		want     int
+	}{
+		{12, 25, 15}, // 峰值超过上限时以峰值为基准
+		{4, 25, 13},  // 峰值低于上限时以当前上限为基准
+		{4, 0, 11},   // 至少比当前上限多1
+	}
+	for _, tc := range cases {
+		m.perf.DBPoolSuggestHeadroomPercent = tc.headroom
+		if got := m.suggestMaxOpenConns(tc.peak); got != tc.want {
+			t.Errorf("峰值 %d、余量 %d%% 时建议值应为 %d，实际 %d", tc.peak, tc.headroom, tc.want, got)
+		}
+	}
+}
+
+func TestGetPoolTrendWithoutMonitor(t *testing.T) {
+	_, db := newFakeDatabase(t)
+	if trend := db.GetPoolTrend(); trend != (PoolTrend{}) {
+		t.Fatalf("未启动监控时应返回零值趋势，实际 %+v", trend)
+	}
+}
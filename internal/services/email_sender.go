@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/utils"
+)
+
+// EmailSender 邮件发送接口
+type EmailSender interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// NewEmailSender 根据配置创建邮件发送器（driver 为 noop 时不实际发送）
+func NewEmailSender(cfg *config.SMTPConfig) EmailSender {
+	if cfg.Driver == "smtp" {
+		return &SMTPEmailSender{config: cfg, logger: utils.GetLogger()}
+	}
+	return &NoopEmailSender{logger: utils.GetLogger()}
+}
+
+// NoopEmailSender 开发环境使用的邮件发送器，只记录日志
+type NoopEmailSender struct {
+	logger utils.Logger
+}
+
+// Send 记录邮件内容而不发送
+func (s *NoopEmailSender) Send(ctx context.Context, to, subject, body string) error {
+	s.logger.Info("邮件未发送（noop）", "to", utils.SanitizeEmail(to), "subject", subject, "body", body)
+	return nil
+}
+
+// SMTPEmailSender 通过SMTP发送纯文本邮件
+type SMTPEmailSender struct {
+	config *config.SMTPConfig
+	logger utils.Logger
+}
+
+// Send 发送邮件（465端口使用隐式TLS，其他端口在服务器支持时使用STARTTLS）
+func (s *SMTPEmailSender) Send(ctx context.Context, to, subject, body string) error {
+	addr := net.JoinHostPort(s.config.Host, strconv.Itoa(s.config.Port))
+	tlsConfig := &tls.Config{ServerName: s.config.Host, MinVersion: tls.VersionTLS12}
+
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("连接SMTP服务器失败: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	if s.config.Port == 465 {
+		conn = tls.Client(conn, tlsConfig)
+	}
+
+	client, err := smtp.NewClient(conn, s.config.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("创建SMTP客户端失败: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok && s.config.Port != 465 {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("STARTTLS失败: %w", err)
+		}
+	}
+	if s.config.Username != "" {
+		auth := smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("SMTP认证失败: %w", err)
+		}
+	}
+
+	if err := client.Mail(s.config.From); err != nil {
+		return fmt.Errorf("设置发件人失败: %w", err)
+	}
+	if err := client.Rcpt(to); err != nil {
+		return fmt.Errorf("设置收件人失败: %w", err)
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("写入邮件失败: %w", err)
+	}
+	if _, err := w.Write(s.buildMessage(to, subject, body)); err != nil {
+		w.Close()
+		return fmt.Errorf("写入邮件失败: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("写入邮件失败: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// buildMessage 构造邮件头和正文（主题按RFC 2047编码以支持中文）
+func (s *SMTPEmailSender) buildMessage(to, subject, body string) []byte {
+	from := (&mail.Address{Name: s.config.FromName, Address: s.config.From}).String()
+
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + to + "\r\n")
+	b.WriteString("Subject: " + mime.BEncoding.Encode("UTF-8", subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().UTC().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return []byte(b.String())
+}
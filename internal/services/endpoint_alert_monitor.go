@@ -0,0 +1,225 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/models"
+)
+
+// 告警原因
+const (
+	AlertReasonErrorRate  = "error_rate"
+	AlertReasonP99Latency = "p99_latency"
+)
+
+// latencyBucketsMs 延迟直方图分桶上界（毫秒），超过最后一个上界的请求计入溢出桶
+var latencyBucketsMs = []int64{5, 10, 25, 50, 100, 250, 500, 1000, 2000, 3000, 5000, 10000}
+
+// minuteBucket 单个接口一分钟内的请求统计
+type minuteBucket struct {
+	minute       int64 // Unix分钟数
+	total        int64
+	errors       int64
+	latency      []int64 // 各延迟分桶的请求数（最后一个为溢出桶）
+	maxLatencyMs int64
+}
+
+// endpointWindow 单个接口最近窗口内的分钟统计（环形数组）
+type endpointWindow struct {
+	method   string
+	endpoint string
+	minutes  []minuteBucket
+}
+
+// EndpointAlertMonitor 接口告警监控
+// 在内存中按分钟统计每个接口（路由模板）最近窗口内的请求数、5xx数和延迟直方图，
+// 错误率或P99延迟超过阈值时生成告警；只反映本实例的流量
+type EndpointAlertMonitor struct {
+	mu        sync.Mutex
+	config    config.AlertsConfig
+	endpoints map[string]*endpointWindow // "METHOD endpoint" -> 窗口统计
+}
+
+var (
+	globalEndpointAlertMonitor *EndpointAlertMonitor
+	endpointAlertOnce          sync.Once
+	endpointAlertConfig        *config.AlertsConfig
+)
+
+// NewEndpointAlertMonitor 创建接口告警监控
+func NewEndpointAlertMonitor(cfg config.AlertsConfig) *EndpointAlertMonitor {
+	if cfg.WindowMinutes <= 0 {
+		cfg.WindowMinutes = 5
+	}
+	if cfg.MaxEndpoints <= 0 {
+		cfg.MaxEndpoints = 500
+	}
+	return &EndpointAlertMonitor{
+		config:    cfg,
+		endpoints: make(map[string]*endpointWindow),
+	}
+}
+
+// InitEndpointAlertMonitor 设置全局接口告警监控的配置（需在首次使用前调用）
+func InitEndpointAlertMonitor(cfg *config.Config) {
+	endpointAlertConfig = &cfg.Alerts
+}
+
+// GetEndpointAlertMonitor 获取全局接口告警监控
+func GetEndpointAlertMonitor() *EndpointAlertMonitor {
+	endpointAlertOnce.Do(func() {
+		var cfg config.AlertsConfig
+		if endpointAlertConfig != nil {
+			cfg = *endpointAlertConfig
+		}
+		globalEndpointAlertMonitor = NewEndpointAlertMonitor(cfg)
+	})
+	return globalEndpointAlertMonitor
+}
+
+// Record 记录一次请求（endpoint 应为路由模板，避免路径参数导致接口数膨胀）
+func (m *EndpointAlertMonitor) Record(method, endpoint string, status int, latency time.Duration, now time.Time) {
+	if endpoint == "" {
+		return
+	}
+	key := method + " " + endpoint
+	minute := now.Unix() / 60
+	latencyMs := latency.Milliseconds()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w, ok := m.endpoints[key]
+	if !ok {
+		if len(m.endpoints) >= m.config.MaxEndpoints {
+			return
+		}
+		w = &endpointWindow{
+			method:   method,
+			endpoint: endpoint,
+			minutes:  make([]minuteBucket, m.config.WindowMinutes),
+		}
+		m.endpoints[key] = w
+	}
+
+	b := &w.minutes[minute%int64(len(w.minutes))]
+	if b.minute != minute {
+		*b = minuteBucket{minute: minute, latency: make([]int64, len(latencyBucketsMs)+1)}
+	}
+	b.total++
+	if status >= 500 {
+		b.errors++
+	}
+	b.latency[latencyBucketIndex(latencyMs)]++
+	if latencyMs > b.maxLatencyMs {
+		b.maxLatencyMs = latencyMs
+	}
+}
+
+// Alerts 计算当前窗口内超过阈值的接口，按错误率、P99延迟降序排列
+func (m *EndpointAlertMonitor) Alerts(now time.Time) []models.EndpointAlert {
+	currentMinute := now.Unix() / 60
+	oldestMinute := currentMinute - int64(m.config.WindowMinutes) + 1
+	errorThreshold := m.config.ErrorRatePercent
+	latencyThreshold := int64(m.config.P99LatencyMs)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	alerts := make([]models.EndpointAlert, 0)
+	for key, w := range m.endpoints {
+		var total, errors, maxLatency int64
+		histogram := make([]int64, len(latencyBucketsMs)+1)
+		for _, b := range w.minutes {
+			if b.minute < oldestMinute || b.minute > currentMinute || b.total == 0 {
+				continue
+			}
+			total += b.total
+			errors += b.errors
+			for i, n := range b.latency {
+				histogram[i] += n
+			}
+			if b.maxLatencyMs > maxLatency {
+				maxLatency = b.maxLatencyMs
+			}
+		}
+
+		// 窗口内没有请求的接口不再保留
+		if total == 0 {
+			delete(m.endpoints, key)
+			continue
+		}
+		if total < int64(m.config.MinRequests) {
+			continue
+		}
+
+		errorRate := float64(errors) * 100 / float64(total)
+		p99 := histogramPercentile(histogram, total, 0.99, maxLatency)
+
+		reasons := make([]string, 0, 2)
+		if errorRate > errorThreshold {
+			reasons = append(reasons, AlertReasonErrorRate)
+		}
+		if p99 > latencyThreshold {
+			reasons = append(reasons, AlertReasonP99Latency)
+		}
+		if len(reasons) == 0 {
+			continue
+		}
+
+		alerts = append(alerts, models.EndpointAlert{
+			Endpoint:              w.endpoint,
+			Method:                w.method,
+			TotalCount:            total,
+			ErrorCount:            errors,
+			ErrorRate:             errorRate,
+			ErrorRateThreshold:    errorThreshold,
+			P99LatencyMs:          p99,
+			P99LatencyThresholdMs: latencyThreshold,
+			Reasons:               reasons,
+		})
+	}
+
+	sort.Slice(alerts, func(i, j int) bool {
+		if alerts[i].ErrorRate != alerts[j].ErrorRate {
+			return alerts[i].ErrorRate > alerts[j].ErrorRate
+		}
+		return alerts[i].P99LatencyMs > alerts[j].P99LatencyMs
+	})
+	return alerts
+}
+
+// Config 获取告警配置
+func (m *EndpointAlertMonitor) Config() config.AlertsConfig {
+	return m.config
+}
+
+// latencyBucketIndex 延迟所在的直方图分桶下标
+func latencyBucketIndex(latencyMs int64) int {
+	return sort.Search(len(latencyBucketsMs), func(i int) bool {
+		return latencyMs <= latencyBucketsMs[i]
+	})
+}
+
+// histogramPercentile 按直方图估算分位数：返回分位点所在分桶的上界（不超过窗口内最大延迟）
+func histogramPercentile(histogram []int64, total int64, quantile float64, maxLatency int64) int64 {
+	rank := int64(math.Ceil(float64(total) * quantile))
+	if rank < 1 {
+		rank = 1
+	}
+	var seen int64
+	for i, n := range histogram {
+		seen += n
+		if seen >= rank && i < len(latencyBucketsMs) && latencyBucketsMs[i] < maxLatency {
+			return latencyBucketsMs[i]
+		}
+		if seen >= rank {
+			break
+		}
+	}
+	return maxLatency
+}
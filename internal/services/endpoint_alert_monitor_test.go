@@ -0,0 +1,97 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"gin/internal/config"
+)
+
+func newTestAlertMonitor() *EndpointAlertMonitor {
+	return NewEndpointAlertMonitor(config.AlertsConfig{
+		WindowMinutes:    5,
+		MinRequests:      10,
+		ErrorRatePercent: 5,
+		P99LatencyMs:     1000,
+		MaxEndpoints:     10,
+	})
+}
+
+// recordRequests 记录 n 次相同状态码和延迟的请求
+func recordRequests(m *EndpointAlertMonitor, endpoint string, n, status int, latency time.Duration, now time.Time) {
+	for i := 0; i < n; i++ {
+		m.Record("GET", endpoint, status, latency, now)
+	}
+}
+
+func TestEndpointAlertsFlagOnlyEndpointsOverThreshold(t *testing.T) {
+	m := newTestAlertMonitor()
+	now := time.Now()
+
+	// 错误率 10% > 5%
+	recordRequests(m, "/api/errors", 90, 200, 20*time.Millisecond, now)
+	recordRequests(m, "/api/errors", 10, 500, 20*time.Millisecond, now)
+	// 错误率 4%，且4xx不计入错误
+	recordRequests(m, "/api/healthy", 86, 200, 20*time.Millisecond, now)
+	recordRequests(m, "/api/healthy", 10, 404, 20*time.Millisecond, now)
+	recordRequests(m, "/api/healthy", 4, 503, 20*time.Millisecond, now)
+	// P99 超过 1000ms
+	recordRequests(m, "/api/slow", 95, 200, 20*time.Millisecond, now)
+	recordRequests(m, "/api/slow", 5, 200, 3*time.Second, now)
+	// 请求数不足时不告警
+	recordRequests(m, "/api/rare", 5, 500, 20*time.Millisecond, now)
+
+	alerts := m.Alerts(now)
+	if len(alerts) != 2 {
+		t.Fatalf("应只有2个接口告警，实际 %+v", alerts)
+	}
+
+	errAlert := alerts[0]
+	if errAlert.Endpoint != "/api/errors" || errAlert.ErrorRate != 10 || errAlert.ErrorRateThreshold != 5 ||
+		errAlert.TotalCount != 100 || errAlert.ErrorCount != 10 {
+		t.Fatalf("错误率告警应包含当前值和阈值，实际 %+v", errAlert)
+	}
+	if len(errAlert.Reasons) != 1 || errAlert.Reasons[0] != AlertReasonErrorRate {
+		t.Fatalf("告警原因应为错误率，实际 %v", errAlert.Reasons)
+	}
+
+	slowAlert := alerts[1]
+	if slowAlert.Endpoint != "/api/slow" || slowAlert.P99LatencyMs <= 1000 || slowAlert.P99LatencyThresholdMs != 1000 {
+		t.Fatalf("P99告警应包含当前值和阈值，实际 %+v", slowAlert)
+	}
+	if len(slowAlert.Reasons) != 1 || slowAlert.Reasons[0] != AlertReasonP99Latency {
+		t.Fatalf("告警原因应为P99延迟，实际 %v", slowAlert.Reasons)
+	}
+}
+
+func TestEndpointAlertsOnlyCountRecentWindow(t *testing.T) {
+	m := newTestAlertMonitor()
+	now := time.Now()
+
+	// 窗口之前的错误不再计入
+	recordRequests(m, "/api/flaky", 50, 500, 20*time.Millisecond, now.Add(-10*time.Minute))
+	recordRequests(m, "/api/flaky", 50, 200, 20*time.Millisecond, now)
+	if alerts := m.Alerts(now); len(alerts) != 0 {
+		t.Fatalf("窗口外的错误不应触发告警，实际 %+v", alerts)
+	}
+
+	// 窗口内没有请求的接口被清除
+	recordRequests(m, "/api/old", 20, 500, 20*time.Millisecond, now.Add(-10*time.Minute))
+	m.Alerts(now)
+	if _, ok := m.endpoints["GET /api/old"]; ok {
+		t.Fatal("窗口内没有请求的接口应被清除")
+	}
+}
+
+func TestHistogramPercentile(t *testing.T) {
+	histogram := make([]int64, len(latencyBucketsMs)+1)
+	histogram[latencyBucketIndex(8)] = 99
+	histogram[latencyBucketIndex(400)] = 1
+
+	if p := histogramPercentile(histogram, 100, 0.99, 400); p != 10 {
+		t.Fatalf("P99应落在10ms分桶，实际 %d", p)
+	}
+	if p := histogramPercentile(histogram, 100, 1, 400); p != 400 {
+		t.Fatalf("最大分位不应超过实际最大延迟，实际 %d", p)
+	}
+}
@@ -0,0 +1,258 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/utils"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// ScanResult 文件扫描结果
+type ScanResult struct {
+	Infected  bool   // 是否检出病毒
+	Signature string // 检出的病毒特征名
+	TooLarge  bool   // 超过扫描器的大小上限（未完成扫描，按拒绝处理）
+}
+
+// FileScanner 文件病毒扫描接口（返回 error 表示扫描器不可用或扫描未完成）
+type FileScanner interface {
+	Scan(ctx context.Context, r io.Reader) (ScanResult, error)
+}
+
+// clamdStreamChunkSize INSTREAM 每次发送的数据块大小
+const clamdStreamChunkSize = 64 * 1024
+
+// ClamdScanner 通过 clamd 的 TCP INSTREAM 命令扫描文件
+type ClamdScanner struct {
+	address string
+	dialer  net.Dialer
+}
+
+// NewClamdScanner 创建 clamd 扫描器
+func NewClamdScanner(address string) *ClamdScanner {
+	return &ClamdScanner{address: address}
+}
+
+// Scan 把数据流按块发送给 clamd 并解析扫描结果（ctx 的截止时间同时作用于连接读写）
+func (s *ClamdScanner) Scan(ctx context.Context, r io.Reader) (ScanResult, error) {
+	conn, err := s.dialer.DialContext(ctx, "tcp", s.address)
+	if err != nil {
+		return ScanResult{}, fmt.Errorf("连接clamd失败: %w", err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	// ctx 被取消时关闭连接，中断阻塞的读写
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return ScanResult{}, fmt.Errorf("发送扫描命令失败: %w", err)
+	}
+
+	buf := make([]byte, clamdStreamChunkSize)
+	var size [4]byte
+	for {
+		n, readErr := r.Read(buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size[:], uint32(n))
+			if _, err := conn.Write(size[:]); err != nil {
+				return writeFailed(conn, err)
+			}
+			if _, err := conn.Write(buf[:n]); err != nil {
+				return writeFailed(conn, err)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return ScanResult{}, fmt.Errorf("读取待扫描文件失败: %w", readErr)
+		}
+	}
+	// 长度为0的块表示数据结束
+	binary.BigEndian.PutUint32(size[:], 0)
+	if _, err := conn.Write(size[:]); err != nil {
+		return writeFailed(conn, err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && reply == "" {
+		return ScanResult{}, fmt.Errorf("读取扫描结果失败: %w", err)
+	}
+	return parseClamdReply(reply)
+}
+
+// writeFailed 发送数据失败时读取 clamd 已经返回的响应（数据超过 StreamMaxLength 时 clamd 先回复错误再断开连接）
+func writeFailed(conn net.Conn, writeErr error) (ScanResult, error) {
+	if reply, _ := bufio.NewReader(conn).ReadString(0); reply != "" {
+		if result, err := parseClamdReply(reply); err == nil {
+			return result, nil
+		}
+	}
+	return ScanResult{}, fmt.Errorf("发送扫描数据失败: %w", writeErr)
+}
+
+// parseClamdReply 解析 clamd 响应：stream: OK / stream: <特征名> FOUND / INSTREAM size limit exceeded. ERROR / ... ERROR
+func parseClamdReply(reply string) (ScanResult, error) {
+	reply = strings.TrimSpace(strings.TrimRight(reply, "\x00"))
+	reply = strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case reply == "OK":
+		return ScanResult{}, nil
+	case strings.HasSuffix(reply, " FOUND"):
+		return ScanResult{Infected: true, Signature: strings.TrimSuffix(reply, " FOUND")}, nil
+	case strings.Contains(reply, "size limit exceeded"):
+		return ScanResult{TooLarge: true}, nil
+	default:
+		return ScanResult{}, fmt.Errorf("clamd返回错误: %s", reply)
+	}
+}
+
+// UploadScanService 上传文件扫描服务：在超时时间内异步扫描对象存储中的文件，并按配置处理扫描器不可用的情况
+type UploadScanService struct {
+	scanner     FileScanner
+	storage     *MultiBucketStorage
+	timeout     time.Duration
+	reject      bool // 扫描器不可用时拒绝
+	skipBuckets map[BucketType]bool
+	logger      utils.Logger
+}
+
+// NewUploadScanService 按配置创建扫描服务（未启用时返回nil，nil服务的扫描直接放行）
+func NewUploadScanService(storage *MultiBucketStorage, cfg *config.AntivirusConfig) *UploadScanService {
+	if !cfg.Enabled {
+		return nil
+	}
+	skip := make(map[BucketType]bool, len(cfg.SkipBuckets))
+	for _, bucket := range cfg.SkipBuckets {
+		skip[BucketType(bucket)] = true
+	}
+	utils.GetLogger().Info("上传文件病毒扫描已启用", "clamd", cfg.ClamdAddress, "onUnavailable", cfg.OnUnavailable)
+	return &UploadScanService{
+		scanner:     NewClamdScanner(cfg.ClamdAddress),
+		storage:     storage,
+		timeout:     time.Duration(cfg.TimeoutSeconds) * time.Second,
+		reject:      cfg.OnUnavailable == config.AntivirusPolicyReject,
+		skipBuckets: skip,
+		logger:      utils.GetLogger(),
+	}
+}
+
+// Enabled 是否会扫描该桶中的文件
+func (s *UploadScanService) Enabled(bucket BucketType) bool {
+	return s != nil && !s.skipBuckets[bucket]
+}
+
+// ScanObjects 把 keys 对应的对象按顺序拼接为一个文件扫描（分片上传的各个分片）
+// 检出病毒返回 ErrFileInfected，超过扫描器大小上限返回 ErrFileTooLargeToScan（不受策略影响）；
+// 扫描器不可用或超时时按策略放行（记录警告）或返回 ErrScanUnavailable
+func (s *UploadScanService) ScanObjects(ctx context.Context, bucket BucketType, keys []string) error {
+	if !s.Enabled(bucket) || len(keys) == 0 {
+		return nil
+	}
+
+	start := time.Now()
+	parent := ctx
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	type outcome struct {
+		result ScanResult
+		err    error
+	}
+	done := make(chan outcome, 1) // 带缓冲：超时返回后扫描goroutine仍能写入并退出
+	go func() {
+		reader := &objectSequenceReader{ctx: ctx, storage: s.storage, bucket: bucket, keys: keys}
+		defer reader.Close()
+		result, err := s.scanner.Scan(ctx, reader)
+		done <- outcome{result: result, err: err}
+	}()
+
+	var out outcome
+	select {
+	case out = <-done:
+	case <-ctx.Done():
+		out.err = ctx.Err()
+	}
+
+	if out.err != nil {
+		// 调用方已取消（如客户端断开），不是扫描器的问题，不按策略放行
+		if err := parent.Err(); err != nil {
+			return err
+		}
+		if s.reject {
+			s.logger.Warn("文件扫描失败，按策略拒绝", "bucket", bucket, "object", keys[0], "error", out.err.Error())
+			return utils.ErrScanUnavailable
+		}
+		s.logger.Warn("文件扫描失败，按策略放行", "bucket", bucket, "object", keys[0], "error", out.err.Error())
+		return nil
+	}
+	if out.result.Infected {
+		s.logger.Warn("上传文件检出病毒", "bucket", bucket, "object", keys[0], "signature", out.result.Signature)
+		return utils.ErrFileInfected
+	}
+	if out.result.TooLarge {
+		s.logger.Warn("上传文件超过扫描大小上限", "bucket", bucket, "object", keys[0], "parts", len(keys))
+		return utils.ErrFileTooLargeToScan
+	}
+
+	s.logger.Info("文件扫描通过", "bucket", bucket, "object", keys[0], "parts", len(keys), "duration", time.Since(start))
+	return nil
+}
+
+// objectSequenceReader 按顺序读取多个对象，当前对象读完后再打开下一个（不在内存中合并）
+type objectSequenceReader struct {
+	ctx     context.Context
+	storage *MultiBucketStorage
+	bucket  BucketType
+	keys    []string
+	current io.ReadCloser
+}
+
+func (r *objectSequenceReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.keys) == 0 {
+				return 0, io.EOF
+			}
+			obj, err := r.storage.GetObject(r.ctx, r.bucket, r.keys[0], minio.GetObjectOptions{})
+			if err != nil {
+				return 0, err
+			}
+			r.current = obj
+			r.keys = r.keys[1:]
+		}
+
+		n, err := r.current.Read(p)
+		if errors.Is(err, io.EOF) {
+			r.current.Close()
+			r.current = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+// Close 关闭正在读取的对象
+func (r *objectSequenceReader) Close() error {
+	if r.current != nil {
+		return r.current.Close()
+	}
+	return nil
+}
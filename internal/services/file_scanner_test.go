@@ -0,0 +1,112 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"gin/internal/utils"
+)
+
+func TestParseClamdReply(t *testing.T) {
+	cases := []struct {
+		reply   string
+		want    ScanResult
+		wantErr bool
+	}{
+		{"stream: OK\x00", ScanResult{}, false},
+		{"stream: Eicar-Test-Signature FOUND\x00", ScanResult{Infected: true, Signature: "Eicar-Test-Signature"}, false},
+		{"INSTREAM size limit exceeded. ERROR\x00", ScanResult{TooLarge: true}, false},
+		{"stream: Can't allocate memory ERROR\x00", ScanResult{}, true},
+	}
+	for _, tc := range cases {
+		got, err := parseClamdReply(tc.reply)
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("parseClamdReply(%q) = %+v, %v", tc.reply, got, err)
+		}
+	}
+}
+
+// fakeClamd 模拟 clamd：读取命令后回复 reply，并继续读取剩余数据直到客户端关闭
+func fakeClamd(t *testing.T, reply string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		if _, err := r.ReadString(0); err != nil {
+			return
+		}
+		_, _ = conn.Write([]byte(reply))
+		_, _ = io.Copy(io.Discard, r)
+	}()
+	return ln.Addr().String()
+}
+
+func TestClamdScannerSizeLimitIsDistinctResult(t *testing.T) {
+	scanner := NewClamdScanner(fakeClamd(t, "INSTREAM size limit exceeded. ERROR\x00"))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := scanner.Scan(ctx, bytes.NewReader(make([]byte, 256*1024)))
+	if err != nil {
+		t.Fatalf("超过大小上限不应视为扫描器不可用: %v", err)
+	}
+	if !result.TooLarge || result.Infected {
+		t.Fatalf("应返回超过大小上限的结果，实际 %+v", result)
+	}
+}
+
+// stubScanner 返回固定结果的扫描器（不读取数据）
+type stubScanner struct {
+	result ScanResult
+	err    error
+}
+
+func (s stubScanner) Scan(context.Context, io.Reader) (ScanResult, error) {
+	return s.result, s.err
+}
+
+func TestScanObjectsPolicies(t *testing.T) {
+	unavailable := errors.New("连接clamd失败")
+	cases := []struct {
+		name    string
+		scanner stubScanner
+		reject  bool
+		want    error
+	}{
+		{"通过", stubScanner{}, false, nil},
+		{"检出病毒", stubScanner{result: ScanResult{Infected: true, Signature: "Eicar"}}, false, utils.ErrFileInfected},
+		{"超过大小上限时即使策略为放行也拒绝", stubScanner{result: ScanResult{TooLarge: true}}, false, utils.ErrFileTooLargeToScan},
+		{"扫描器不可用按策略放行", stubScanner{err: unavailable}, false, nil},
+		{"扫描器不可用按策略拒绝", stubScanner{err: unavailable}, true, utils.ErrScanUnavailable},
+	}
+	for _, tc := range cases {
+		svc := &UploadScanService{
+			scanner:     tc.scanner,
+			timeout:     time.Second,
+			reject:      tc.reject,
+			skipBuckets: map[BucketType]bool{BucketTypeArticleImages: true},
+			logger:      utils.GetLogger(),
+		}
+		if err := svc.ScanObjects(context.Background(), BucketTypeResourceChunks, []string{"u1/chunk_0"}); !errors.Is(err, tc.want) {
+			t.Errorf("%s: 期望 %v，实际 %v", tc.name, tc.want, err)
+		}
+		if svc.Enabled(BucketTypeArticleImages) {
+			t.Errorf("%s: 跳过的桶不应扫描", tc.name)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/testutil"
+)
+
+// newFakeDatabase 创建使用可编程驱动的数据库实例
+func newFakeDatabase(t *testing.T) (*testutil.FakeDB, *Database) {
+	t.Helper()
+	fake := testutil.NewFakeDB()
+	db := fake.Open()
+	t.Cleanup(func() { db.Close() })
+	return fake, NewDatabaseWithDB(config.Default(), db)
+}
+
+// onArticleDetail 预设 GetArticleByID 的查询：articles 中的任意ID都返回一篇已发布文章（无代码块、分类和标签）
+func onArticleDetail(fake *testutil.FakeDB) {
+	now := time.Now().UTC()
+	fake.On(`FROM articles a INNER JOIN user_auth ua ON a.user_id = ua.id LEFT JOIN user_profile up ON ua.id = up.user_id WHERE a.id = \? AND a.status != 2`,
+		func(args []driver.Value) testutil.Response {
+			return testutil.Response{
+				Columns: []string{"id", "user_id", "title", "description", "content", "status", "view_count", "like_count",
+					"comment_count", "version", "created_at", "updated_at", "username", "nickname", "avatar"},
+				Rows: [][]driver.Value{{args[0], int64(7), "title", "desc", "content", int64(1), int64(0), int64(0),
+					int64(0), int64(1), now, now, "alice", "Alice", ""}},
+			}
+		})
+	fake.OnRows(`FROM article_code_blocks WHERE article_id = \?`, []string{"id"})
+	fake.OnRows(`FROM article_categories ac`, []string{"id"})
+	fake.OnRows(`FROM article_tags at`, []string{"id"})
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"time"
 
 	"gin/internal/config"
@@ -104,7 +105,7 @@ func (r *HistoryRepository) GetLoginHistory(userID uint, limit int) ([]models.Us
 		r.logger.Error("查询登录历史失败",
 			"userID", userID,
 			"error", err.Error())
-		return nil, utils.ErrDatabaseQuery
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
 	}
 	defer rows.Close()
 
@@ -157,7 +158,7 @@ func (r *HistoryRepository) GetOperationHistory(userID uint, limit int) ([]model
 		r.logger.Error("查询操作历史失败",
 			"userID", userID,
 			"error", err.Error())
-		return nil, utils.ErrDatabaseQuery
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
 	}
 	defer rows.Close()
 
@@ -201,7 +202,7 @@ func (r *HistoryRepository) GetProfileChangeHistory(userID uint, limit int) ([]m
 		r.logger.Error("查询资料修改历史失败",
 			"userID", userID,
 			"error", err.Error())
-		return nil, utils.ErrDatabaseQuery
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
 	}
 	defer rows.Close()
 
@@ -244,7 +245,7 @@ func (r *HistoryRepository) GetLocationDistribution() (*models.LocationDistribut
 	provinceRows, err := r.db.DB.QueryContext(ctx, provinceQuery)
 	if err != nil {
 		r.logger.Error("查询省份统计失败", "error", err.Error())
-		return nil, utils.ErrDatabaseQuery
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
 	}
 	defer provinceRows.Close()
 
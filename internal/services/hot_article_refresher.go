@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/utils"
+)
+
+// HotArticleRefresher 热门文章缓存后台刷新器
+// 定期按衰减热度取前N篇文章，在其详情缓存过期前通过Worker Pool重建，避免热门文章出现冷缓存
+type HotArticleRefresher struct {
+	cacheSvc    *CacheService
+	articleRepo *ArticleRepository
+	config      config.HotArticlesConfig
+	logger      utils.Logger
+	stopCh      chan struct{}
+	stopOnce    sync.Once
+}
+
+// NewHotArticleRefresher 创建热门文章缓存刷新器
+func NewHotArticleRefresher(cacheSvc *CacheService, articleRepo *ArticleRepository, cfg *config.Config) *HotArticleRefresher {
+	return &HotArticleRefresher{
+		cacheSvc:    cacheSvc,
+		articleRepo: articleRepo,
+		config:      cfg.Cache.HotArticles,
+		logger:      utils.GetLogger(),
+		stopCh:      make(chan struct{}),
+	}
+}
+
+// Start 启动后台刷新（未启用时直接返回）
+func (h *HotArticleRefresher) Start() {
+	if !h.config.Enabled {
+		h.logger.Info("热门文章缓存刷新未启用")
+		return
+	}
+
+	go h.run()
+	h.logger.Info("热门文章缓存刷新已启动",
+		"topN", h.config.TopN,
+		"interval", time.Duration(h.config.RefreshIntervalSec)*time.Second,
+		"refreshAhead", time.Duration(h.config.RefreshAheadSec)*time.Second)
+}
+
+// Stop 停止后台刷新
+func (h *HotArticleRefresher) Stop() {
+	h.stopOnce.Do(func() {
+		close(h.stopCh)
+	})
+}
+
+// run 定时检查循环
+func (h *HotArticleRefresher) run() {
+	ticker := time.NewTicker(time.Duration(h.config.RefreshIntervalSec) * time.Second)
+	defer ticker.Stop()
+
+	// 启动时先预热一次
+	h.RefreshOnce(context.Background())
+
+	for {
+		select {
+		case <-ticker.C:
+			h.RefreshOnce(context.Background())
+		case <-h.stopCh:
+			return
+		}
+	}
+}
+
+// RefreshOnce 执行一轮刷新，返回提交刷新任务的文章ID
+// 只处理热度前N的文章：未缓存或剩余有效期低于 refresh_ahead_sec 的才会提交刷新
+func (h *HotArticleRefresher) RefreshOnce(ctx context.Context) []uint {
+	ids, err := h.articleRepo.GetHotArticleIDs(ctx, h.config.TopN, h.config.DecayGravity)
+	if err != nil {
+		h.logger.Warn("获取热门文章失败，跳过本轮刷新", "error", err.Error())
+		return nil
+	}
+
+	refreshAhead := time.Duration(h.config.RefreshAheadSec) * time.Second
+	timeout := time.Duration(h.config.TaskTimeoutSec) * time.Second
+	submitted := make([]uint, 0, len(ids))
+
+	for _, id := range ids {
+		if remaining, ok := h.cacheSvc.ArticleDetailTTL(id); ok && remaining > refreshAhead {
+			continue
+		}
+
+		articleID := id
+		err := utils.SubmitTask(fmt.Sprintf("refresh_hot_article_%d", articleID), func(taskCtx context.Context) error {
+			_, err := h.cacheSvc.RefreshArticleDetail(taskCtx, articleID)
+			return err
+		}, timeout)
+		if err != nil {
+			h.logger.Debug("提交热门文章刷新任务失败", "articleID", articleID, "error", err.Error())
+			continue
+		}
+		submitted = append(submitted, articleID)
+	}
+
+	if len(submitted) > 0 {
+		h.logger.Debug("热门文章缓存刷新", "hot", len(ids), "refreshed", len(submitted))
+	}
+	return submitted
+}
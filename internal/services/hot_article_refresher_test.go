@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"slices"
+	"testing"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/models"
+)
+
+// waitForArticleCached 等待Worker Pool中的刷新任务把文章写入缓存，返回剩余有效期
+func waitForArticleCached(t *testing.T, cacheSvc *CacheService, articleID uint, minTTL time.Duration) time.Duration {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if ttl, ok := cacheSvc.ArticleDetailTTL(articleID); ok && ttl > minTTL {
+			return ttl
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("文章 %d 未被刷新到缓存", articleID)
+	return 0
+}
+
+func TestHotArticleRefresherRefreshesOnlyHotArticles(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	cfg := config.Default()
+	cfg.Cache.HotArticles.TopN = 3
+	cfg.Cache.HotArticles.RefreshAheadSec = 60
+	onArticleDetail(fake)
+	fake.OnRows(`SELECT a.id FROM articles a WHERE a.status = 1 ORDER BY`, []string{"id"},
+		[]driver.Value{int64(1)}, []driver.Value{int64(2)}, []driver.Value{int64(3)})
+
+	cacheSvc := NewCacheService(NewArticleRepository(db, cfg), cfg)
+	refresher := NewHotArticleRefresher(cacheSvc, cacheSvc.articleRepo, cfg)
+
+	// 1: 即将过期；2: 剩余有效期充足；3: 未缓存；9: 不是热门文章
+	cacheSvc.articleCache.SetWithTTL(articleDetailKey(1), &models.ArticleDetailResponse{}, time.Second)
+	cacheSvc.articleCache.SetWithTTL(articleDetailKey(2), &models.ArticleDetailResponse{}, 10*time.Minute)
+
+	submitted := refresher.RefreshOnce(context.Background())
+	slices.Sort(submitted)
+	if !slices.Equal(submitted, []uint{1, 3}) {
+		t.Fatalf("应只刷新即将过期或未缓存的热门文章 [1 3]，实际 %v", submitted)
+	}
+
+	articleTTL := time.Duration(cfg.Cache.ArticleDetailTTLMinutes) * time.Minute
+	waitForArticleCached(t, cacheSvc, 1, time.Minute)
+	if ttl := waitForArticleCached(t, cacheSvc, 3, time.Minute); ttl > articleTTL {
+		t.Fatalf("刷新后的有效期不应超过文章详情TTL，实际 %v", ttl)
+	}
+	if _, ok := cacheSvc.ArticleDetailTTL(9); ok {
+		t.Fatal("非热门文章不应被预热")
+	}
+	if calls := fake.Calls(`WHERE a.id = \? AND a.status != 2`); len(calls) != 2 {
+		t.Fatalf("应只加载2篇文章详情，实际 %d 次", len(calls))
+	}
+
+	hotQuery := fake.Calls(`SELECT a.id FROM articles a WHERE a.status = 1 ORDER BY`)
+	if len(hotQuery) != 1 || hotQuery[0].Args[len(hotQuery[0].Args)-1] != int64(3) {
+		t.Fatalf("热门文章查询应使用配置的 top_n=3，实际 %+v", hotQuery)
+	}
+
+	// 缓存都已新鲜时，下一轮不再提交任务
+	if again := refresher.RefreshOnce(context.Background()); len(again) != 0 {
+		t.Fatalf("缓存新鲜时不应重复刷新，实际 %v", again)
+	}
+}
@@ -11,7 +11,13 @@ import (
 type AuthServiceInterface interface {
 	Login(ctx context.Context, username, password, clientIP, province, city string) (*models.LoginResponse, error)
 	Register(ctx context.Context, username, password, email, clientIP, userAgent, province, city string) (*models.LoginResponse, error)
+	RefreshToken(ctx context.Context, refreshToken string) (*models.RefreshTokenResponse, error)
+	Logout(ctx context.Context, userID uint, jti string, expiresAt time.Time, refreshToken string) error
+	RevokeAllSessions(ctx context.Context, userID uint) error
+	DeleteAccount(ctx context.Context, userID uint, password, clientIP string) error
 	ChangePassword(ctx context.Context, userID uint, currentPassword, newPassword string) error
+	RequestPasswordReset(ctx context.Context, email, clientIP string) error
+	ResetPassword(ctx context.Context, token, newPassword string) error
 }
 
 // UserServiceInterface 用户服务接口
@@ -19,7 +25,10 @@ type UserServiceInterface interface {
 	GetUserByID(ctx context.Context, id uint) (*models.User, error)
 	GetUserProfile(ctx context.Context, userID uint) (*models.UserExtraProfile, error)
 	UpsertUserProfile(ctx context.Context, profile *models.UserExtraProfile) error
+	UpsertFullUserProfile(ctx context.Context, profile *models.UserExtraProfile) error
 	UpdateUserAvatar(ctx context.Context, profile *models.UserExtraProfile) error
+	GetFollowCounts(ctx context.Context, userID uint) (*models.FollowCounts, error)
+	GetPublicProfile(ctx context.Context, userID uint) (*models.PublicUserProfile, error)
 }
 
 // ObjectInfo 对象元信息（用于列举）
@@ -35,7 +44,7 @@ type ArticleRepositoryInterface interface {
 	CreateArticle(ctx context.Context, article *models.Article, codeBlocks []models.ArticleCodeBlock, categoryIDs, tagIDs []uint) error
 	GetArticleByID(ctx context.Context, articleID uint, userID uint) (*models.ArticleDetailResponse, error)
 	ListArticles(ctx context.Context, query models.ArticleListQuery) (*models.ArticleListResponse, error)
-	UpdateArticle(ctx context.Context, articleID uint, userID uint, req models.UpdateArticleRequest) error
+	UpdateArticle(ctx context.Context, articleID uint, userID uint, req models.UpdateArticleRequest) (int, error)
 	DeleteArticle(ctx context.Context, articleID uint, userID uint) error
 
 	// 文章交互
@@ -44,14 +53,16 @@ type ArticleRepositoryInterface interface {
 
 	// 评论
 	CreateComment(ctx context.Context, comment *models.ArticleComment) error
-	GetComments(ctx context.Context, articleID uint, page, pageSize int, userID uint) (*models.CommentsResponse, error)
+	GetComments(ctx context.Context, articleID uint, page, pageSize int, userID uint, sort string) (*models.CommentsResponse, error)
 	ToggleCommentLike(ctx context.Context, commentID uint, userID uint) (bool, error)
 	DeleteComment(ctx context.Context, commentID uint, userID uint) error
+	DeleteCommentsByUser(ctx context.Context, userID uint, commentIDs []uint) ([]uint, error)
 
 	// 分类和标签
 	GetAllCategories(ctx context.Context) ([]models.ArticleCategory, error)
 	GetAllTags(ctx context.Context) ([]models.ArticleTag, error)
 	CreateOrGetTag(ctx context.Context, tagName string) (uint, error)
+	CreateOrGetTags(ctx context.Context, names []string) (map[string]uint, error)
 
 	// 举报
 	CreateReport(ctx context.Context, report *models.ArticleReport) error
@@ -72,10 +83,17 @@ type UserRepositoryInterface interface {
 
 	// 认证
 	UpdateLastLogin(ctx context.Context, userID uint, ip string) error
-	IncrementFailedLoginCount(ctx context.Context, userID uint) error
+	IncrementFailedLoginCount(ctx context.Context, userID uint, maxAttempts int, lockUntil time.Time) (int, error)
 	ResetFailedLoginCount(ctx context.Context, userID uint) error
 }
 
+// ArticleCacheInvalidator 文章缓存失效接口（由缓存服务实现，文章仓库在数据变更提交成功后调用）
+type ArticleCacheInvalidator interface {
+	InvalidateArticle(articleID uint)
+	InvalidateArticleLists()
+	InvalidateArticleTags()
+}
+
 // CacheServiceInterface 缓存操作接口
 type CacheServiceInterface interface {
 	// 文章缓存
@@ -87,6 +105,8 @@ type CacheServiceInterface interface {
 	InvalidateArticleCategories()
 	InvalidateArticleTags()
 	InvalidateArticleDetail(articleID uint)
+	InvalidateArticle(articleID uint)
+	InvalidateArticleLists()
 
 	// 在线人数
 	SetOnlineCount(count int)
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"gin/internal/models"
+	"gin/internal/utils"
+)
+
+// likerTables 点赞表及其内容ID列、内容可见性查询（草稿和已删除的内容不展示点赞用户）
+var likerTables = map[string]struct {
+	column  string
+	visible string
+}{
+	"article_likes":  {column: "article_id", visible: `SELECT COUNT(*) FROM articles WHERE id = ? AND status = 1`},
+	"resource_likes": {column: "resource_id", visible: `SELECT COUNT(*) FROM resources WHERE id = ? AND status = 1`},
+}
+
+// listLikers 分页查询点赞用户（按点赞时间倒序）
+// 取消点赞会删除点赞记录，因此只包含当前仍点赞的用户；已注销的账号不展示，且只返回公开的展示信息
+func listLikers(ctx context.Context, db *Database, logger utils.Logger, table string, contentID uint, page, pageSize int) (*models.LikersResponse, error) {
+	queries, ok := likerTables[table]
+	if !ok {
+		return nil, fmt.Errorf("unknown likes table %q", table)
+	}
+	column := queries.column
+	offset := (page - 1) * pageSize
+
+	ctx, cancel := context.WithTimeout(ctx, db.GetQueryTimeout())
+	defer cancel()
+
+	var visible int
+	if err := db.QueryRowWithCache(ctx, queries.visible, contentID).Scan(&visible); err != nil {
+		logger.Error("查询内容状态失败", "table", table, "contentID", contentID, "error", err.Error())
+		return nil, utils.ErrDatabaseQuery
+	}
+	if visible == 0 {
+		return nil, utils.ErrResourceNotFound
+	}
+
+	var total int
+	countQuery := fmt.Sprintf(`SELECT COUNT(*) FROM %s l
+		INNER JOIN user_auth ua ON ua.id = l.user_id
+		WHERE l.%s = ? AND ua.account_status != ?`, table, column)
+	if err := db.QueryRowWithCache(ctx, countQuery, contentID, models.AccountStatusDeleted).Scan(&total); err != nil {
+		logger.Error("查询点赞用户数量失败", "table", table, "contentID", contentID, "error", err.Error())
+		return nil, utils.ErrDatabaseQuery
+	}
+
+	listQuery := fmt.Sprintf(`
+		SELECT ua.id, ua.username, COALESCE(up.nickname, ua.username) as nickname,
+		       COALESCE(up.avatar_url, '') as avatar, l.created_at
+		FROM %s l
+		INNER JOIN user_auth ua ON ua.id = l.user_id
+		LEFT JOIN user_profile up ON up.user_id = ua.id
+		WHERE l.%s = ? AND ua.account_status != ?
+		ORDER BY l.created_at DESC, l.id DESC
+		LIMIT ? OFFSET ?`, table, column)
+
+	rows, err := db.QueryWithCache(ctx, listQuery, contentID, models.AccountStatusDeleted, pageSize, offset)
+	if err != nil {
+		logger.Error("查询点赞用户失败", "table", table, "contentID", contentID, "error", err.Error())
+		return nil, utils.ErrDatabaseQuery
+	}
+	defer rows.Close()
+
+	users := make([]models.Liker, 0, pageSize)
+	for rows.Next() {
+		var u models.Liker
+		if err := rows.Scan(&u.ID, &u.Username, &u.Nickname, &u.Avatar, &u.LikedAt); err != nil {
+			logger.Warn("扫描点赞用户失败", "error", err.Error())
+			continue
+		}
+		users = append(users, u)
+	}
+
+	return &models.LikersResponse{
+		Users:      users,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: (total + pageSize - 1) / pageSize,
+	}, nil
+}
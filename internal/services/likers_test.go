@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/models"
+	"gin/internal/testutil"
+	"gin/internal/utils"
+)
+
+// likeRow 点赞表中的一条记录及点赞用户的账户信息
+type likeRow struct {
+	userID, contentID int64
+	username          string
+	accountStatus     int64
+	likedAt           time.Time
+}
+
+// likeStore 模拟点赞表，取消点赞即删除记录
+type likeStore struct {
+	mu   sync.Mutex
+	rows []likeRow
+}
+
+func (s *likeStore) unlike(userID, contentID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rows = slices.DeleteFunc(s.rows, func(r likeRow) bool { return r.userID == userID && r.contentID == contentID })
+}
+
+// matching 返回内容的点赞记录（排除指定账户状态），按点赞时间倒序
+func (s *likeStore) matching(contentID, excludedStatus int64) []likeRow {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []likeRow
+	for _, r := range s.rows {
+		if r.contentID == contentID && r.accountStatus != excludedStatus {
+			out = append(out, r)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].likedAt.After(out[j].likedAt) })
+	return out
+}
+
+// onLikers 按 likeStore 响应点赞用户的查询，visibleIDs 为已发布的内容
+func onLikers(fake *testutil.FakeDB, store *likeStore, contentTable string, visibleIDs ...int64) {
+	fake.On(`SELECT COUNT\(\*\) FROM `+contentTable+` WHERE id = \? AND status = 1`, func(args []driver.Value) testutil.Response {
+		count := int64(0)
+		if slices.Contains(visibleIDs, args[0].(int64)) {
+			count = 1
+		}
+		return testutil.Response{Columns: []string{"count"}, Rows: [][]driver.Value{{count}}}
+	})
+	fake.On(`SELECT COUNT\(\*\) FROM \w+_likes l`, func(args []driver.Value) testutil.Response {
+		n := len(store.matching(args[0].(int64), args[1].(int64)))
+		return testutil.Response{Columns: []string{"count"}, Rows: [][]driver.Value{{int64(n)}}}
+	})
+	fake.On(`SELECT ua.id, ua.username`, func(args []driver.Value) testutil.Response {
+		rows := store.matching(args[0].(int64), args[1].(int64))
+		limit, offset := int(args[2].(int64)), int(args[3].(int64))
+		resp := testutil.Response{Columns: []string{"id", "username", "nickname", "avatar", "created_at"}}
+		for i := offset; i < len(rows) && i < offset+limit; i++ {
+			r := rows[i]
+			resp.Rows = append(resp.Rows, []driver.Value{r.userID, r.username, r.username, "", r.likedAt})
+		}
+		return resp
+	})
+}
+
+func likerIDs(resp *models.LikersResponse) []uint {
+	ids := make([]uint, 0, len(resp.Users))
+	for _, u := range resp.Users {
+		ids = append(ids, u.ID)
+	}
+	return ids
+}
+
+func TestGetArticleLikersOrderedAndPaged(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	now := time.Now().UTC()
+	store := &likeStore{rows: []likeRow{
+		{userID: 1, contentID: 5, username: "alice", likedAt: now.Add(-3 * time.Hour)},
+		{userID: 2, contentID: 5, username: "bob", likedAt: now.Add(-time.Hour)},
+		{userID: 3, contentID: 5, username: "carol", likedAt: now.Add(-2 * time.Hour)},
+		{userID: 4, contentID: 5, username: "gone", accountStatus: int64(models.AccountStatusDeleted), likedAt: now},
+		{userID: 5, contentID: 6, username: "other", likedAt: now},
+	}}
+	onLikers(fake, store, "articles", 5)
+	repo := NewArticleRepository(db, config.Default())
+
+	resp, err := repo.GetArticleLikers(context.Background(), 5, 1, 10)
+	if err != nil {
+		t.Fatalf("查询点赞用户失败: %v", err)
+	}
+	if ids := likerIDs(resp); !slices.Equal(ids, []uint{2, 3, 1}) || resp.Total != 3 {
+		t.Fatalf("应按点赞时间倒序返回，且不含已注销账号，实际 %v total=%d", ids, resp.Total)
+	}
+
+	resp, _ = repo.GetArticleLikers(context.Background(), 5, 2, 2)
+	if ids := likerIDs(resp); !slices.Equal(ids, []uint{1}) || resp.TotalPages != 2 {
+		t.Fatalf("第2页应只有最早点赞的用户，实际 %v pages=%d", ids, resp.TotalPages)
+	}
+
+	// 取消点赞后不再出现在列表中
+	store.unlike(3, 5)
+	resp, _ = repo.GetArticleLikers(context.Background(), 5, 1, 10)
+	if ids := likerIDs(resp); !slices.Equal(ids, []uint{2, 1}) || resp.Total != 2 {
+		t.Fatalf("取消点赞的用户不应出现，实际 %v total=%d", ids, resp.Total)
+	}
+}
+
+func TestGetLikersOnlyPublicFields(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	store := &likeStore{rows: []likeRow{{userID: 1, contentID: 5, username: "alice", likedAt: time.Now().UTC()}}}
+	onLikers(fake, store, "resources", 5)
+
+	resp, err := NewResourceRepository(db, config.Default()).GetResourceLikers(context.Background(), 5, 1, 10)
+	if err != nil || len(resp.Users) != 1 {
+		t.Fatalf("查询资源点赞用户失败: %v %+v", err, resp)
+	}
+	query := fake.Calls(`SELECT ua.id, ua.username`)[0].Query
+	if !strings.Contains(query, "FROM resource_likes l") {
+		t.Fatalf("资源应查询 resource_likes: %s", query)
+	}
+	for _, field := range []string{"email", "phone", "password", "ip"} {
+		if strings.Contains(query, "ua."+field) || strings.Contains(query, "up."+field) {
+			t.Fatalf("点赞用户列表不应查询私密字段 %s: %s", field, query)
+		}
+	}
+
+	data, _ := json.Marshal(resp.Users[0])
+	var fields map[string]interface{}
+	_ = json.Unmarshal(data, &fields)
+	for key := range fields {
+		if !slices.Contains([]string{"id", "username", "nickname", "avatar", "liked_at"}, key) {
+			t.Fatalf("点赞用户只应返回公开的展示信息，实际包含 %s", key)
+		}
+	}
+}
+
+func TestGetLikersHiddenContent(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	onLikers(fake, &likeStore{}, "articles", 5)
+
+	_, err := NewArticleRepository(db, config.Default()).GetArticleLikers(context.Background(), 9, 1, 10)
+	if !errors.Is(err, utils.ErrResourceNotFound) {
+		t.Fatalf("未发布或已删除的内容应返回不存在，实际 %v", err)
+	}
+	if calls := fake.Calls(`SELECT ua.id, ua.username`); len(calls) != 0 {
+		t.Fatal("内容不可见时不应查询点赞用户")
+	}
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/testutil"
+	"gin/internal/utils"
+)
+
+// loginUserRow 内存中 alice 的 user_auth 记录
+type loginUserRow struct {
+	mu          sync.Mutex
+	hash        string
+	failed      int64
+	lockedUntil interface{}
+}
+
+// newLoginService 创建登录服务，user_auth 中只有密码为 Passw0rd! 的 alice
+func newLoginService(t *testing.T) (*AuthService, *testutil.FakeDB, *loginUserRow) {
+	t.Helper()
+	fake, db := newFakeDatabase(t)
+	hash, err := utils.HashPassword("Passw0rd!")
+	if err != nil {
+		t.Fatalf("生成密码哈希失败: %v", err)
+	}
+	row := &loginUserRow{hash: hash}
+	now := time.Now().UTC()
+
+	fake.On(`FROM user_auth WHERE username = \?`, func([]driver.Value) testutil.Response {
+		row.mu.Lock()
+		defer row.mu.Unlock()
+		return testutil.Response{
+			Columns: []string{"id", "username", "password_hash", "email", "auth_status", "account_status",
+				"last_login_time", "last_login_ip", "failed_login_count", "locked_until", "token_version", "created_at", "updated_at"},
+			Rows: [][]driver.Value{{int64(1), "alice", row.hash, "alice@example.com", int64(1), int64(1),
+				nil, nil, row.failed, row.lockedUntil, int64(0), now, now}},
+		}
+	})
+	fake.On(`UPDATE user_auth SET failed_login_count = failed_login_count \+ 1`, func(args []driver.Value) testutil.Response {
+		row.mu.Lock()
+		defer row.mu.Unlock()
+		row.failed++
+		if row.failed >= args[0].(int64) {
+			row.lockedUntil = args[1]
+		}
+		return testutil.Response{RowsAffected: 1}
+	})
+	fake.On(`SELECT failed_login_count FROM user_auth`, func([]driver.Value) testutil.Response {
+		row.mu.Lock()
+		defer row.mu.Unlock()
+		return testutil.Response{Columns: []string{"failed_login_count"}, Rows: [][]driver.Value{{row.failed}}}
+	})
+	reset := func([]driver.Value) testutil.Response {
+		row.mu.Lock()
+		defer row.mu.Unlock()
+		row.failed, row.lockedUntil = 0, nil
+		return testutil.Response{RowsAffected: 1}
+	}
+	fake.On(`UPDATE user_auth SET failed_login_count = 0, locked_until = NULL`, reset)
+	fake.On(`UPDATE user_auth SET last_login_time`, reset)
+	fake.OnExec(`INSERT INTO refresh_tokens`, 1, 1)
+	fake.OnRows(`FROM user_profile WHERE user_id = \?`, []string{"user_id"})
+
+	cfg := config.Default()
+	cfg.JWT.SecretKey = "test-secret-key"
+	cfg.Security.MaxLoginAttempts = 3
+	cfg.Security.LockoutMinutes = 15
+	cfg.Security.LoginWarnRemaining = 1
+	cfg.SecurityPassword.RehashOnLogin = false
+	svc := NewAuthService(cfg, NewUserRepository(db), nil, NewRefreshTokenRepository(db, cfg), nil, nil)
+	return svc, fake, row
+}
+
+func TestLoginLocksAccountAfterMaxAttempts(t *testing.T) {
+	svc, fake, row := newLoginService(t)
+	ctx := context.Background()
+
+	// 第1次失败：普通错误；第2次：提示剩余次数；第3次：锁定
+	if _, err := svc.Login(ctx, "alice", "wrong", "127.0.0.1", "", ""); !errors.Is(err, utils.ErrInvalidCredentials) || strings.Contains(err.Error(), "还可尝试") {
+		t.Fatalf("第1次失败应返回普通的凭证错误，实际 %v", err)
+	}
+	if _, err := svc.Login(ctx, "alice", "wrong", "127.0.0.1", "", ""); err == nil || !strings.Contains(err.Error(), "还可尝试1次") {
+		t.Fatalf("接近上限时应提示剩余次数，实际 %v", err)
+	}
+	_, err := svc.Login(ctx, "alice", "wrong", "127.0.0.1", "", "")
+	if !errors.Is(err, utils.ErrAccountLocked) || utils.GetHTTPStatusCode(err) != 423 || !strings.Contains(err.Error(), "15分钟") {
+		t.Fatalf("达到上限时应锁定15分钟，实际 %v", err)
+	}
+
+	// 锁定期间即使密码正确也拒绝，且不再累计失败次数
+	increments := len(fake.Calls(`failed_login_count \+ 1`))
+	if _, err := svc.Login(ctx, "alice", "Passw0rd!", "127.0.0.1", "", ""); !errors.Is(err, utils.ErrAccountLocked) {
+		t.Fatalf("锁定期间应拒绝正确的密码，实际 %v", err)
+	}
+	if _, err := svc.Login(ctx, "alice", "wrong", "127.0.0.1", "", ""); !errors.Is(err, utils.ErrAccountLocked) {
+		t.Fatalf("锁定期间应直接拒绝，实际 %v", err)
+	}
+	if len(fake.Calls(`failed_login_count \+ 1`)) != increments {
+		t.Fatal("锁定期间在校验密码之前拒绝，不应累计失败次数")
+	}
+
+	// 锁定到期后自动解锁，登录成功
+	row.mu.Lock()
+	row.lockedUntil = time.Now().UTC().Add(-time.Minute)
+	row.mu.Unlock()
+	if _, err := svc.Login(ctx, "alice", "Passw0rd!", "127.0.0.1", "", ""); err != nil {
+		t.Fatalf("锁定到期后应能登录，实际 %v", err)
+	}
+	if row.failed != 0 || row.lockedUntil != nil {
+		t.Fatalf("登录成功后应清零失败次数并解除锁定，实际 %d %v", row.failed, row.lockedUntil)
+	}
+}
+
+func TestLoginSuccessResetsFailedCount(t *testing.T) {
+	svc, _, row := newLoginService(t)
+	ctx := context.Background()
+
+	if _, err := svc.Login(ctx, "alice", "wrong", "127.0.0.1", "", ""); err == nil {
+		t.Fatal("密码错误应登录失败")
+	}
+	if _, err := svc.Login(ctx, "alice", "Passw0rd!", "127.0.0.1", "", ""); err != nil {
+		t.Fatalf("登录失败: %v", err)
+	}
+	if row.failed != 0 {
+		t.Fatalf("登录成功应清零失败次数，实际 %d", row.failed)
+	}
+
+	// 清零后重新计数，再失败两次不会锁定
+	for i := 0; i < 2; i++ {
+		if _, err := svc.Login(ctx, "alice", "wrong", "127.0.0.1", "", ""); errors.Is(err, utils.ErrAccountLocked) {
+			t.Fatalf("清零后第 %d 次失败不应锁定", i+1)
+		}
+	}
+}
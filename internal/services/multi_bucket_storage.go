@@ -1,9 +1,15 @@
 package services
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
+	"net/http"
+	"strings"
 	"time"
 
 	"gin/internal/config"
@@ -66,8 +72,11 @@ func NewMultiBucketStorage(cfg *config.Config) (*MultiBucketStorage, error) {
 		buckets: buckets,
 	}
 
-	// 初始化所有桶
-	if err := storage.initializeBuckets(); err != nil {
+	// 初始化所有桶（MinIO暂不可用时按 startup_retry 配置指数退避重试，桶初始化可重复执行）
+	err = utils.RetryWithBackoff(context.Background(), utils.NewStartupBackoff(&cfg.StartupRetry), "MinIO", func(ctx context.Context) error {
+		return storage.initializeBuckets()
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -134,6 +143,19 @@ func (s *MultiBucketStorage) initializeBuckets() error {
 	return nil
 }
 
+// HealthCheck 检查MinIO是否可达（以资源分片桶存在为准）
+func (s *MultiBucketStorage) HealthCheck(ctx context.Context) error {
+	bucketName := s.buckets[BucketTypeResourceChunks].Name
+	exists, err := s.client.BucketExists(ctx, bucketName)
+	if err != nil {
+		return fmt.Errorf("检查桶 %s 失败: %w", bucketName, err)
+	}
+	if !exists {
+		return fmt.Errorf("桶 %s 不存在", bucketName)
+	}
+	return nil
+}
+
 // setBucketPolicy 设置桶策略
 func (s *MultiBucketStorage) setBucketPolicy(ctx context.Context, bucketName string, bucketCfg config.BucketConfig) error {
 	// 判断是否公开读取（默认为true）
@@ -151,7 +173,19 @@ func (s *MultiBucketStorage) setBucketPolicy(ctx context.Context, bucketName str
 		return nil
 	}
 
-	// 公开只读策略
+	// 公开只读策略（配置了 public_paths 时只公开匹配的对象）
+	patterns := bucketCfg.PublicPaths
+	if len(patterns) == 0 {
+		patterns = []string{"*"}
+	}
+	resources := make([]string, len(patterns))
+	for i, pattern := range patterns {
+		resources[i] = fmt.Sprintf("arn:aws:s3:::%s/%s", bucketName, pattern)
+	}
+	resourceJSON, err := json.Marshal(resources)
+	if err != nil {
+		return fmt.Errorf("生成桶策略失败: %w", err)
+	}
 	policy := fmt.Sprintf(`{
 		"Version": "2012-10-17",
 		"Statement": [
@@ -160,16 +194,16 @@ func (s *MultiBucketStorage) setBucketPolicy(ctx context.Context, bucketName str
 				"Effect": "Allow",
 				"Principal": "*",
 				"Action": "s3:GetObject",
-				"Resource": "arn:aws:s3:::%s/*"
+				"Resource": %s
 			}
 		]
-	}`, bucketName)
+	}`, resourceJSON)
 
 	if err := s.client.SetBucketPolicy(ctx, bucketName, policy); err != nil {
 		return fmt.Errorf("设置公开策略失败: %w", err)
 	}
 
-	s.logger.Info("🌐 桶设置为公开只读", "bucket", bucketName)
+	s.logger.Info("🌐 桶设置为公开只读", "bucket", bucketName, "paths", patterns)
 	return nil
 }
 
@@ -180,12 +214,39 @@ func (s *MultiBucketStorage) PutObject(ctx context.Context, bucketType BucketTyp
 		return "", fmt.Errorf("未知的桶类型: %s", bucketType)
 	}
 
+	if err := s.checkQuota(ctx, bucketType, objectPath, size); err != nil {
+		s.logger.Warn("上传超出桶配额", "bucket", bucketCfg.Name, "object", objectPath, "size", size, "error", err.Error())
+		return "", err
+	}
+
 	opts := minio.PutObjectOptions{
 		ContentType:  contentType,
 		CacheControl: bucketCfg.CacheControl,
 	}
 
-	_, err := s.client.PutObject(ctx, bucketCfg.Name, objectPath, reader, size, opts)
+	// 重试时需要从头重新读取请求体
+	body, rewind, err := s.rewindableReader(reader, size)
+	if err != nil {
+		s.logger.Error("读取上传内容失败", "bucket", bucketCfg.Name, "object", objectPath, "error", err.Error())
+		return "", err
+	}
+	maxRetries := s.cfg.MinioAdvanced.MaxRetries
+	if rewind == nil {
+		s.logger.Debug("上传流不可重读，只尝试一次", "bucket", bucketCfg.Name, "object", objectPath, "size", size)
+		maxRetries = 0
+	}
+
+	attempt := 0
+	err = s.withRetry(ctx, "上传文件", maxRetries, func() error {
+		if attempt > 0 {
+			if err := rewind(); err != nil {
+				return fmt.Errorf("重置上传流失败: %w", err)
+			}
+		}
+		attempt++
+		_, err := s.client.PutObject(ctx, bucketCfg.Name, objectPath, body, size, opts)
+		return err
+	})
 	if err != nil {
 		s.logger.Error("上传文件失败", "bucket", bucketCfg.Name, "object", objectPath, "error", err.Error())
 		return "", err
@@ -214,6 +275,45 @@ func (s *MultiBucketStorage) GetObject(ctx context.Context, bucketType BucketTyp
 	return obj, nil
 }
 
+// PresignGetObject 生成对象的限时下载URL（客户端直接从MinIO下载，不经过应用服务器）
+func (s *MultiBucketStorage) PresignGetObject(ctx context.Context, bucketType BucketType, objectPath string, expiry time.Duration) (string, error) {
+	bucketCfg, ok := s.buckets[bucketType]
+	if !ok {
+		return "", fmt.Errorf("未知的桶类型: %s", bucketType)
+	}
+
+	presigned, err := s.client.PresignedGetObject(ctx, bucketCfg.Name, objectPath, expiry, nil)
+	if err != nil {
+		s.logger.Error("生成预签名下载URL失败", "bucket", bucketCfg.Name, "object", objectPath, "error", err.Error())
+		return "", err
+	}
+
+	return presigned.String(), nil
+}
+
+// ObjectURL 返回对象的访问URL：公开对象返回公共URL，其余返回按桶配置有效期的预签名URL
+// expiresAt 为预签名URL的过期时间（公开对象为零值）
+func (s *MultiBucketStorage) ObjectURL(ctx context.Context, bucketType BucketType, objectPath string) (string, time.Time, error) {
+	bucketCfg, ok := s.buckets[bucketType]
+	if !ok {
+		return "", time.Time{}, fmt.Errorf("未知的桶类型: %s", bucketType)
+	}
+	if bucketCfg.IsPublicObject(objectPath) {
+		return fmt.Sprintf("%s/%s", strings.TrimSuffix(bucketCfg.PublicBaseURL, "/"), objectPath), time.Time{}, nil
+	}
+
+	expiry := time.Duration(bucketCfg.PresignExpiryMinutes) * time.Minute
+	if expiry <= 0 {
+		expiry = 10 * time.Minute
+	}
+	expiresAt := time.Now().UTC().Add(expiry)
+	presigned, err := s.PresignGetObject(ctx, bucketType, objectPath, expiry)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return presigned, expiresAt, nil
+}
+
 // ObjectExists 检查对象是否存在
 func (s *MultiBucketStorage) ObjectExists(ctx context.Context, bucketType BucketType, objectPath string) (bool, error) {
 	bucketCfg, ok := s.buckets[bucketType]
@@ -221,17 +321,21 @@ func (s *MultiBucketStorage) ObjectExists(ctx context.Context, bucketType Bucket
 		return false, fmt.Errorf("未知的桶类型: %s", bucketType)
 	}
 
-	_, err := s.client.StatObject(ctx, bucketCfg.Name, objectPath, minio.StatObjectOptions{})
-	if err != nil {
+	exists := true
+	err := s.withRetry(ctx, "检查对象", s.cfg.MinioAdvanced.MaxRetries, func() error {
+		_, err := s.client.StatObject(ctx, bucketCfg.Name, objectPath, minio.StatObjectOptions{})
 		// 检查是否是对象不存在错误
-		errResponse := minio.ToErrorResponse(err)
-		if errResponse.Code == "NoSuchKey" {
-			return false, nil
+		if err != nil && minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			exists = false
+			return nil
 		}
+		return err
+	})
+	if err != nil {
 		return false, err
 	}
 
-	return true, nil
+	return exists, nil
 }
 
 // RemoveObject 删除对象
@@ -241,7 +345,9 @@ func (s *MultiBucketStorage) RemoveObject(ctx context.Context, bucketType Bucket
 		return fmt.Errorf("未知的桶类型: %s", bucketType)
 	}
 
-	err := s.client.RemoveObject(ctx, bucketCfg.Name, objectPath, minio.RemoveObjectOptions{})
+	err := s.withRetry(ctx, "删除对象", s.cfg.MinioAdvanced.MaxRetries, func() error {
+		return s.client.RemoveObject(ctx, bucketCfg.Name, objectPath, minio.RemoveObjectOptions{})
+	})
 	if err != nil {
 		s.logger.Error("删除对象失败", "bucket", bucketCfg.Name, "object", objectPath, "error", err.Error())
 		return err
@@ -263,6 +369,11 @@ func (s *MultiBucketStorage) CopyObject(ctx context.Context, srcBucketType, dstB
 		return fmt.Errorf("未知的目标桶类型: %s", dstBucketType)
 	}
 
+	if err := s.checkQuota(ctx, dstBucketType, dstPath, -1); err != nil {
+		s.logger.Warn("复制超出桶配额", "bucket", dstBucketCfg.Name, "object", dstPath, "error", err.Error())
+		return err
+	}
+
 	src := minio.CopySrcOptions{
 		Bucket: srcBucketCfg.Name,
 		Object: srcPath,
@@ -273,7 +384,10 @@ func (s *MultiBucketStorage) CopyObject(ctx context.Context, srcBucketType, dstB
 		Object: dstPath,
 	}
 
-	_, err := s.client.CopyObject(ctx, dst, src)
+	err := s.withRetry(ctx, "复制对象", s.cfg.MinioAdvanced.MaxRetries, func() error {
+		_, err := s.client.CopyObject(ctx, dst, src)
+		return err
+	})
 	if err != nil {
 		s.logger.Error("复制对象失败",
 			"srcBucket", srcBucketCfg.Name,
@@ -324,6 +438,20 @@ func (s *MultiBucketStorage) GetPublicBaseURL(bucketType BucketType) string {
 	return ""
 }
 
+// ObjectFromPublicURL 根据公共访问URL解析所在的桶和对象路径（不属于任何桶时返回 false）
+func (s *MultiBucketStorage) ObjectFromPublicURL(rawURL string) (BucketType, string, bool) {
+	for bucketType, bucketCfg := range s.buckets {
+		base := strings.TrimSuffix(bucketCfg.PublicBaseURL, "/")
+		if base == "" || !strings.HasPrefix(rawURL, base+"/") {
+			continue
+		}
+		if objectPath := strings.TrimPrefix(rawURL, base+"/"); objectPath != "" {
+			return bucketType, objectPath, true
+		}
+	}
+	return "", "", false
+}
+
 // GetBucketName 获取桶名称
 func (s *MultiBucketStorage) GetBucketName(bucketType BucketType) string {
 	if bucketCfg, ok := s.buckets[bucketType]; ok {
@@ -337,3 +465,166 @@ func (s *MultiBucketStorage) GetBucketConfig(bucketType BucketType) (config.Buck
 	cfg, ok := s.buckets[bucketType]
 	return cfg, ok
 }
+
+// checkQuota 检查写入是否超出桶配额，超出时返回 utils.ErrStorageQuotaExceeded
+// 对象大小：user-avatars 按 max_avatar_size_mb，图片桶按 max_image_size_kb（size < 0 表示大小未知，如复制对象，不检查）
+// 对象数量：见 quotaCountRule；覆盖已有对象不增加数量
+func (s *MultiBucketStorage) checkQuota(ctx context.Context, bucketType BucketType, objectPath string, size int64) error {
+	bucketCfg := s.buckets[bucketType]
+	if maxBytes := maxObjectBytes(bucketCfg); maxBytes > 0 && size > maxBytes {
+		return utils.NewAppError(utils.ErrStorageQuotaExceeded,
+			fmt.Sprintf("文件大小超过限制（最大%.0fKB）", float64(maxBytes)/1024), 413)
+	}
+
+	prefix, limit, counted := quotaCountRule(bucketType, bucketCfg, objectPath)
+	if limit <= 0 {
+		return nil
+	}
+	objects, err := s.ListObjects(ctx, bucketType, prefix)
+	if err != nil {
+		return err
+	}
+	count := 0
+	for _, obj := range objects {
+		if obj.Key == objectPath {
+			return nil
+		}
+		if counted(obj.Key) {
+			count++
+		}
+	}
+	if count >= limit {
+		return utils.NewAppError(utils.ErrStorageQuotaExceeded,
+			fmt.Sprintf("已达到存储配额（最多%d个文件）", limit), 409)
+	}
+	return nil
+}
+
+// maxObjectBytes 桶配置的单个对象大小上限（字节，0表示不限制）
+func maxObjectBytes(bucketCfg config.BucketConfig) int64 {
+	switch {
+	case bucketCfg.MaxAvatarSizeMB > 0:
+		return int64(bucketCfg.MaxAvatarSizeMB * 1024 * 1024)
+	case bucketCfg.MaxImageSizeKB > 0:
+		return int64(bucketCfg.MaxImageSizeKB) * 1024
+	}
+	return 0
+}
+
+// quotaCountRule 返回对象所在的计数前缀、数量上限和计数条件（limit <= 0 表示不限制）
+// resource-previews：{resourceID}/ 下最多 max_images_per_resource 张预览图，缩略图不计入
+// user-avatars：{username}/history/ 下最多 max_history 个历史版本；归档新版本后才由清理任务删除最旧的，因此允许多出一个
+func quotaCountRule(bucketType BucketType, bucketCfg config.BucketConfig, objectPath string) (string, int, func(string) bool) {
+	isThumbnail := func(key string) bool { return strings.HasSuffix(key, "_thumb.jpg") } // 见 PreviewThumbnailPath
+
+	switch bucketType {
+	case BucketTypeResourcePreviews:
+		idx := strings.Index(objectPath, "/")
+		if idx <= 0 || isThumbnail(objectPath) {
+			return "", 0, nil
+		}
+		return objectPath[:idx+1], bucketCfg.MaxImagesPerResource, func(key string) bool { return !isThumbnail(key) }
+	case BucketTypeUserAvatars:
+		idx := strings.Index(objectPath, "/history/")
+		if idx <= 0 || bucketCfg.MaxHistory <= 0 {
+			return "", 0, nil
+		}
+		return objectPath[:idx+len("/history/")], bucketCfg.MaxHistory + 1, func(string) bool { return true }
+	}
+	return "", 0, nil
+}
+
+// rewindableReader 返回可在重试前回到起始位置的读取器
+// 可Seek的流（如 multipart.File）记录当前位置；不可Seek的流在大小已知且不超过 retry_buffer_max_mb 时缓冲到内存，
+// 否则（如直接转发的请求体流）无法重读，rewind 返回nil，调用方只尝试一次
+func (s *MultiBucketStorage) rewindableReader(reader io.Reader, size int64) (io.Reader, func() error, error) {
+	if seeker, ok := reader.(io.Seeker); ok {
+		if start, err := seeker.Seek(0, io.SeekCurrent); err == nil {
+			return reader, func() error {
+				_, err := seeker.Seek(start, io.SeekStart)
+				return err
+			}, nil
+		}
+	}
+
+	maxBytes := int64(s.cfg.MinioAdvanced.RetryBufferMaxMB) << 20
+	if s.cfg.MinioAdvanced.MaxRetries == 0 || size < 0 || size > maxBytes {
+		return reader, nil, nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(reader, size))
+	if err != nil {
+		return nil, nil, err
+	}
+	buffered := bytes.NewReader(data)
+	return buffered, func() error {
+		_, err := buffered.Seek(0, io.SeekStart)
+		return err
+	}, nil
+}
+
+// withRetry 执行对象操作，遇到可重试错误时按指数退避重试（与 Database.RetryQuery 相同的策略）
+// 退避总时长不超过 minio.operation_timeout；无权限、桶不存在等不可重试的错误立即返回
+func (s *MultiBucketStorage) withRetry(ctx context.Context, op string, maxRetries int, fn func() error) error {
+	deadline := time.Now().Add(time.Duration(s.cfg.MinIO.OperationTimeout) * time.Second)
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= maxRetries || !isRetriableMinioError(err) {
+			return err
+		}
+
+		backoff := time.Duration(1<<uint(attempt)) * time.Duration(s.cfg.MinioAdvanced.RetryBackoffBaseMS) * time.Millisecond
+		if time.Now().Add(backoff).After(deadline) {
+			s.logger.Warn(op+"失败，重试将超出操作超时时间", "attempt", attempt+1, "error", err.Error())
+			return err
+		}
+		s.logger.Warn(op+"失败，准备重试",
+			"attempt", attempt+1,
+			"maxRetries", maxRetries,
+			"backoff", backoff,
+			"error", err.Error())
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// nonRetriableMinioCodes 不可重试的MinIO错误码（重试也不会成功）
+var nonRetriableMinioCodes = map[string]bool{
+	"AccessDenied":          true,
+	"NoSuchBucket":          true,
+	"NoSuchKey":             true,
+	"InvalidAccessKeyId":    true,
+	"SignatureDoesNotMatch": true,
+	"InvalidBucketName":     true,
+	"InvalidObjectName":     true,
+	"EntityTooLarge":        true,
+}
+
+// isRetriableMinioError 判断MinIO错误是否可重试（网络错误、5xx、限流）
+func isRetriableMinioError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	resp := minio.ToErrorResponse(err)
+	if nonRetriableMinioCodes[resp.Code] {
+		return false
+	}
+	if resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests ||
+		resp.Code == "SlowDown" || resp.Code == "RequestTimeout" {
+		return true
+	}
+	if resp.StatusCode != 0 {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	return isRetriableError(err)
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"gin/internal/config"
+	"gin/internal/utils"
+
+	"github.com/minio/minio-go/v7"
+)
+
+func TestObjectFromPublicURL(t *testing.T) {
+	storage := &MultiBucketStorage{buckets: map[BucketType]config.BucketConfig{
+		BucketTypeTempFiles:        {PublicBaseURL: "http://minio.local:9000/temp-files"},
+		BucketTypeResourcePreviews: {PublicBaseURL: "http://minio.local:9000/resource-previews/"},
+		BucketTypeSystemAssets:     {},
+	}}
+	cases := []struct {
+		name       string
+		rawURL     string
+		wantBucket BucketType
+		wantPath   string
+		wantFound  bool
+	}{
+		{"普通对象", "http://minio.local:9000/temp-files/uploads/a.zip", BucketTypeTempFiles, "uploads/a.zip", true},
+		{"基础地址带斜杠", "http://minio.local:9000/resource-previews/1/p.png", BucketTypeResourcePreviews, "1/p.png", true},
+		{"桶名前缀相同但不是同一个桶", "http://minio.local:9000/temp-files-old/a.zip", "", "", false},
+		{"其他主机", "https://pan.example.com/temp-files/a.zip", "", "", false},
+		{"没有对象路径", "http://minio.local:9000/temp-files/", "", "", false},
+		{"存储路径不是URL", "upload-1", "", "", false},
+	}
+	for _, tc := range cases {
+		bucket, path, found := storage.ObjectFromPublicURL(tc.rawURL)
+		if bucket != tc.wantBucket || path != tc.wantPath || found != tc.wantFound {
+			t.Errorf("%s: ObjectFromPublicURL(%q) = (%q, %q, %v)，期望 (%q, %q, %v)",
+				tc.name, tc.rawURL, bucket, path, found, tc.wantBucket, tc.wantPath, tc.wantFound)
+		}
+	}
+}
+
+func TestObjectURLPublicObject(t *testing.T) {
+	storage := &MultiBucketStorage{buckets: map[BucketType]config.BucketConfig{
+		BucketTypeUserAvatars: {PublicBaseURL: "http://minio.local:9000/user-avatars/", PublicPaths: []string{"*/current.jpg"}},
+	}}
+	url, expiresAt, err := storage.ObjectURL(context.Background(), BucketTypeUserAvatars, "alice/current.jpg")
+	if err != nil || url != "http://minio.local:9000/user-avatars/alice/current.jpg" || !expiresAt.IsZero() {
+		t.Fatalf("公开对象应返回不过期的公共URL，实际 %q %v %v", url, expiresAt, err)
+	}
+	if _, _, err := storage.ObjectURL(context.Background(), BucketTypeTempFiles, "a.zip"); err == nil {
+		t.Fatal("未配置的桶应返回错误")
+	}
+}
+
+// newRetryTestStorage 只用于测试重试逻辑的存储（不连接MinIO）
+func newRetryTestStorage(maxRetries int) *MultiBucketStorage {
+	cfg := config.Default()
+	cfg.MinIO.OperationTimeout = 5
+	cfg.MinioAdvanced.MaxRetries = maxRetries
+	cfg.MinioAdvanced.RetryBackoffBaseMS = 1
+	cfg.MinioAdvanced.RetryBufferMaxMB = 1
+	return &MultiBucketStorage{cfg: cfg, logger: utils.GetLogger()}
+}
+
+func TestIsRetriableMinioError(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"服务端5xx", minio.ErrorResponse{Code: "InternalError", StatusCode: http.StatusServiceUnavailable}, true},
+		{"限流", minio.ErrorResponse{Code: "SlowDown", StatusCode: http.StatusServiceUnavailable}, true},
+		{"请求超时", minio.ErrorResponse{Code: "RequestTimeout", StatusCode: http.StatusBadRequest}, true},
+		{"无权限", minio.ErrorResponse{Code: "AccessDenied", StatusCode: http.StatusForbidden}, false},
+		{"桶不存在", minio.ErrorResponse{Code: "NoSuchBucket", StatusCode: http.StatusNotFound}, false},
+		{"其他4xx", minio.ErrorResponse{Code: "InvalidArgument", StatusCode: http.StatusBadRequest}, false},
+		{"网络错误", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+		{"连接中断", io.ErrUnexpectedEOF, true},
+		{"请求已取消", context.Canceled, false},
+	}
+	for _, tc := range cases {
+		if got := isRetriableMinioError(tc.err); got != tc.want {
+			t.Errorf("%s: isRetriableMinioError = %v，期望 %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestMinioWithRetry(t *testing.T) {
+	storage := newRetryTestStorage(3)
+	transient := minio.ErrorResponse{Code: "InternalError", StatusCode: http.StatusInternalServerError}
+
+	attempts := 0
+	err := storage.withRetry(context.Background(), "上传文件", 3, func() error {
+		attempts++
+		if attempts < 3 {
+			return transient
+		}
+		return nil
+	})
+	if err != nil || attempts != 3 {
+		t.Fatalf("可重试错误应重试直到成功，实际 %v，尝试 %d 次", err, attempts)
+	}
+
+	attempts = 0
+	err = storage.withRetry(context.Background(), "上传文件", 3, func() error {
+		attempts++
+		return transient
+	})
+	if err == nil || attempts != 4 {
+		t.Fatalf("超过最大重试次数后应返回错误，实际 %v，尝试 %d 次", err, attempts)
+	}
+
+	attempts = 0
+	denied := minio.ErrorResponse{Code: "AccessDenied", StatusCode: http.StatusForbidden}
+	if err := storage.withRetry(context.Background(), "删除对象", 3, func() error {
+		attempts++
+		return denied
+	}); err == nil || attempts != 1 {
+		t.Fatalf("不可重试的错误应立即返回，实际 %v，尝试 %d 次", err, attempts)
+	}
+
+	// 退避时间超出操作超时时不再重试
+	storage.cfg.MinIO.OperationTimeout = 0
+	attempts = 0
+	if err := storage.withRetry(context.Background(), "复制对象", 3, func() error {
+		attempts++
+		return transient
+	}); err == nil || attempts != 1 {
+		t.Fatalf("重试会超出操作超时时应直接返回，实际 %v，尝试 %d 次", err, attempts)
+	}
+}
+
+func TestRewindableReader(t *testing.T) {
+	storage := newRetryTestStorage(2)
+
+	// 可Seek的流从当前位置重读
+	seekable := strings.NewReader("skip-content")
+	seekable.Seek(5, io.SeekStart)
+	body, rewind, err := storage.rewindableReader(seekable, 7)
+	if err != nil || rewind == nil {
+		t.Fatalf("可Seek的流应可以重读: %v", err)
+	}
+	first, _ := io.ReadAll(body)
+	if err := rewind(); err != nil {
+		t.Fatalf("重置上传流失败: %v", err)
+	}
+	second, _ := io.ReadAll(body)
+	if string(first) != "content" || string(second) != "content" {
+		t.Fatalf("重读应从原来的位置开始，实际 %q %q", first, second)
+	}
+
+	// 不可Seek的小流缓冲到内存
+	body, rewind, err = storage.rewindableReader(io.MultiReader(strings.NewReader("abc")), 3)
+	if err != nil || rewind == nil {
+		t.Fatalf("大小已知的小流应缓冲后重读: %v", err)
+	}
+	io.ReadAll(body)
+	rewind()
+	if data, _ := io.ReadAll(body); string(data) != "abc" {
+		t.Fatalf("缓冲的内容不正确: %q", data)
+	}
+
+	// 大小未知或超过缓冲上限的流只尝试一次
+	for _, size := range []int64{-1, 2 << 20} {
+		if _, rewind, err := storage.rewindableReader(io.MultiReader(strings.NewReader("abc")), size); err != nil || rewind != nil {
+			t.Fatalf("size=%d 的不可Seek流不应缓冲，实际 rewind=%v err=%v", size, rewind != nil, err)
+		}
+	}
+	storage.cfg.MinioAdvanced.MaxRetries = 0
+	if _, rewind, _ := storage.rewindableReader(io.MultiReader(strings.NewReader("abc")), 3); rewind != nil {
+		t.Fatal("不重试时不应缓冲上传流")
+	}
+}
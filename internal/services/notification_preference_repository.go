@@ -0,0 +1,314 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/models"
+	"gin/internal/utils"
+)
+
+// NotificationPreferenceRepository 用户通知偏好数据访问层
+// 只保存用户修改过的事件类型，未保存的默认全部开启；偏好按用户缓存，修改时失效
+type NotificationPreferenceRepository struct {
+	db     *Database
+	logger utils.Logger
+	cache  *utils.LRUCache
+}
+
+// NewNotificationPreferenceRepository 创建通知偏好数据访问层
+func NewNotificationPreferenceRepository(db *Database, cfg *config.Config) *NotificationPreferenceRepository {
+	return &NotificationPreferenceRepository{
+		db:     db,
+		logger: utils.GetLogger(),
+		cache: utils.NewLRUCache(utils.LRUCacheConfig{
+			Capacity:   cfg.Cache.User.Capacity,
+			MaxMemory:  int64(cfg.Cache.User.MaxMemoryMB) * 1024 * 1024,
+			DefaultTTL: time.Duration(cfg.Cache.User.TTLMinutes) * time.Minute,
+		}),
+	}
+}
+
+// notificationPrefsKey 通知偏好缓存键
+func notificationPrefsKey(userID uint) string {
+	return "notification:prefs:" + strconv.FormatUint(uint64(userID), 10)
+}
+
+// notificationDNDKey 免打扰截止时间缓存键
+func notificationDNDKey(userID uint) string {
+	return "notification:dnd:" + strconv.FormatUint(uint64(userID), 10)
+}
+
+// GetNotificationPreferences 获取用户通知偏好（包含全部事件类型）
+func (r *NotificationPreferenceRepository) GetNotificationPreferences(ctx context.Context, userID uint) (*models.NotificationPreferences, error) {
+	if cached, ok := r.cache.Get(notificationPrefsKey(userID)); ok {
+		return cached.(*models.NotificationPreferences), nil
+	}
+
+	prefsMap, err := r.loadPreferences(ctx, []uint{userID})
+	if err != nil {
+		return nil, err
+	}
+	return prefsMap[userID], nil
+}
+
+// UpdateNotificationPreferences 修改用户通知偏好，返回修改后的完整偏好
+func (r *NotificationPreferenceRepository) UpdateNotificationPreferences(ctx context.Context, userID uint, updates []models.NotificationPreferenceUpdate) (*models.NotificationPreferences, error) {
+	for _, u := range updates {
+		if !models.IsValidNotificationEvent(u.EventType) {
+			return nil, utils.NewAppError(utils.ErrValidationFailed, "不支持的通知类型: "+u.EventType, 400)
+		}
+	}
+
+	start := time.Now().UTC()
+	updateCtx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
+	defer cancel()
+
+	// 新记录中未传的字段取默认值（开启），已有记录中未传的字段保持不变
+	query := `INSERT INTO notification_preferences (user_id, event_type, in_app, email, updated_at)
+			  VALUES (?, ?, ?, ?, ?)
+			  ON DUPLICATE KEY UPDATE in_app = COALESCE(?, in_app), email = COALESCE(?, email), updated_at = VALUES(updated_at)`
+	err := r.db.WithTransaction(updateCtx, func(tx *sql.Tx) error {
+		for _, u := range updates {
+			inApp, email := true, true
+			if u.InApp != nil {
+				inApp = *u.InApp
+			}
+			if u.Email != nil {
+				email = *u.Email
+			}
+			if _, err := tx.ExecContext(updateCtx, query,
+				userID, u.EventType, inApp, email, start, u.InApp, u.Email); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	r.cache.Delete(notificationPrefsKey(userID))
+	if err != nil {
+		r.logger.Error("更新通知偏好失败", "userID", userID, "error", err.Error())
+		return nil, utils.ErrDatabaseUpdate
+	}
+
+	r.logger.Info("更新通知偏好成功", "userID", userID, "count", len(updates), "duration", time.Since(start))
+	return r.GetNotificationPreferences(ctx, userID)
+}
+
+// FilterInAppRecipients 过滤出开启了该类型站内通知的用户（查询失败时按默认开启处理，不影响通知发送）
+func (r *NotificationPreferenceRepository) FilterInAppRecipients(ctx context.Context, eventType string, userIDs []uint) []uint {
+	prefsMap := make(map[uint]*models.NotificationPreferences, len(userIDs))
+	missing := make([]uint, 0)
+	for _, id := range userIDs {
+		if cached, ok := r.cache.Get(notificationPrefsKey(id)); ok {
+			prefsMap[id] = cached.(*models.NotificationPreferences)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+
+	if len(missing) > 0 {
+		loaded, err := r.loadPreferences(ctx, missing)
+		if err != nil {
+			r.logger.Warn("批量读取通知偏好失败，按默认开启发送", "eventType", eventType, "error", err.Error())
+		}
+		for id, prefs := range loaded {
+			prefsMap[id] = prefs
+		}
+	}
+
+	recipients := make([]uint, 0, len(userIDs))
+	for _, id := range userIDs {
+		if prefs, ok := prefsMap[id]; ok && !prefs.Get(eventType).InApp {
+			continue
+		}
+		recipients = append(recipients, id)
+	}
+	return recipients
+}
+
+// loadPreferences 批量读取用户偏好并写入缓存（没有记录的用户得到默认偏好）
+func (r *NotificationPreferenceRepository) loadPreferences(ctx context.Context, userIDs []uint) (map[uint]*models.NotificationPreferences, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetQueryTimeout())
+	defer cancel()
+
+	query := `SELECT user_id, event_type, in_app, email, updated_at FROM notification_preferences
+			  WHERE user_id IN (?` + strings.Repeat(",?", len(userIDs)-1) + `)`
+	args := make([]interface{}, len(userIDs))
+	for i, id := range userIDs {
+		args[i] = id
+	}
+
+	rows, err := r.db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, utils.ErrDatabaseQuery
+	}
+	defer rows.Close()
+
+	result := make(map[uint]*models.NotificationPreferences, len(userIDs))
+	for _, id := range userIDs {
+		result[id] = models.DefaultNotificationPreferences(id)
+	}
+
+	for rows.Next() {
+		var userID uint
+		var eventType string
+		var inApp, email bool
+		var updatedAt time.Time
+		if err := rows.Scan(&userID, &eventType, &inApp, &email, &updatedAt); err != nil {
+			r.logger.Warn("扫描通知偏好失败", "error", err.Error())
+			continue
+		}
+		prefs, ok := result[userID]
+		if !ok {
+			continue
+		}
+		for i := range prefs.Preferences {
+			if prefs.Preferences[i].EventType == eventType {
+				prefs.Preferences[i].InApp = inApp
+				prefs.Preferences[i].Email = email
+				prefs.Preferences[i].UpdatedAt = &updatedAt
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, utils.ErrDatabaseQuery
+	}
+
+	for id, prefs := range result {
+		r.cache.Set(notificationPrefsKey(id), prefs)
+	}
+	return result, nil
+}
+
+// GetDoNotDisturb 获取用户免打扰状态（已过期视为未开启）
+func (r *NotificationPreferenceRepository) GetDoNotDisturb(ctx context.Context, userID uint) (*models.DoNotDisturbStatus, error) {
+	until, err := r.muteUntil(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	status := &models.DoNotDisturbStatus{}
+	if until.After(time.Now().UTC()) {
+		status.Active = true
+		status.MuteUntil = &until
+	}
+	return status, nil
+}
+
+// SetDoNotDisturb 设置免打扰截止时间，until 为零值或已过去时关闭免打扰
+func (r *NotificationPreferenceRepository) SetDoNotDisturb(ctx context.Context, userID uint, until time.Time) (*models.DoNotDisturbStatus, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
+	defer cancel()
+
+	until = until.UTC().Truncate(time.Second)
+	var err error
+	if until.After(time.Now().UTC()) {
+		_, err = r.db.DB.ExecContext(ctx,
+			`INSERT INTO notification_dnd (user_id, mute_until, updated_at) VALUES (?, ?, ?)
+			 ON DUPLICATE KEY UPDATE mute_until = VALUES(mute_until), updated_at = VALUES(updated_at)`,
+			userID, until, time.Now().UTC())
+	} else {
+		until = time.Time{}
+		_, err = r.db.DB.ExecContext(ctx, `DELETE FROM notification_dnd WHERE user_id = ?`, userID)
+	}
+	if err != nil {
+		r.cache.Delete(notificationDNDKey(userID))
+		r.logger.Error("设置通知免打扰失败", "userID", userID, "error", err.Error())
+		return nil, utils.ErrDatabaseUpdate
+	}
+	r.cache.Set(notificationDNDKey(userID), until)
+
+	r.logger.Info("设置通知免打扰成功", "userID", userID, "muteUntil", until)
+	return r.GetDoNotDisturb(ctx, userID)
+}
+
+// FilterPushRecipients 过滤掉处于免打扰期间的用户，用于实时推送（查询失败时按未开启处理）
+func (r *NotificationPreferenceRepository) FilterPushRecipients(ctx context.Context, userIDs []uint) []uint {
+	untilMap := make(map[uint]time.Time, len(userIDs))
+	missing := make([]uint, 0)
+	for _, id := range userIDs {
+		if cached, ok := r.cache.Get(notificationDNDKey(id)); ok {
+			untilMap[id] = cached.(time.Time)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+
+	if len(missing) > 0 {
+		loaded, err := r.loadMuteUntil(ctx, missing)
+		if err != nil {
+			r.logger.Warn("批量读取通知免打扰失败，按未开启推送", "error", err.Error())
+		}
+		for id, until := range loaded {
+			untilMap[id] = until
+		}
+	}
+
+	now := time.Now().UTC()
+	recipients := make([]uint, 0, len(userIDs))
+	for _, id := range userIDs {
+		if untilMap[id].After(now) {
+			continue
+		}
+		recipients = append(recipients, id)
+	}
+	return recipients
+}
+
+// muteUntil 读取免打扰截止时间（未设置返回零值）
+func (r *NotificationPreferenceRepository) muteUntil(ctx context.Context, userID uint) (time.Time, error) {
+	if cached, ok := r.cache.Get(notificationDNDKey(userID)); ok {
+		return cached.(time.Time), nil
+	}
+
+	loaded, err := r.loadMuteUntil(ctx, []uint{userID})
+	if err != nil {
+		return time.Time{}, err
+	}
+	return loaded[userID], nil
+}
+
+// loadMuteUntil 批量读取免打扰截止时间并写入缓存（没有记录的用户为零值）
+func (r *NotificationPreferenceRepository) loadMuteUntil(ctx context.Context, userIDs []uint) (map[uint]time.Time, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetQueryTimeout())
+	defer cancel()
+
+	query := `SELECT user_id, mute_until FROM notification_dnd WHERE user_id IN (?` + strings.Repeat(",?", len(userIDs)-1) + `)`
+	args := make([]interface{}, len(userIDs))
+	for i, id := range userIDs {
+		args[i] = id
+	}
+
+	rows, err := r.db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, utils.ErrDatabaseQuery
+	}
+	defer rows.Close()
+
+	result := make(map[uint]time.Time, len(userIDs))
+	for _, id := range userIDs {
+		result[id] = time.Time{}
+	}
+	for rows.Next() {
+		var userID uint
+		var until time.Time
+		if err := rows.Scan(&userID, &until); err != nil {
+			r.logger.Warn("扫描通知免打扰失败", "error", err.Error())
+			continue
+		}
+		if _, ok := result[userID]; ok {
+			result[userID] = until
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, utils.ErrDatabaseQuery
+	}
+
+	for id, until := range result {
+		r.cache.Set(notificationDNDKey(id), until)
+	}
+	return result, nil
+}
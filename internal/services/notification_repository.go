@@ -0,0 +1,261 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"gin/internal/models"
+	"gin/internal/utils"
+)
+
+// notificationBatchSize 批量写入通知时每条 INSERT 的最大行数
+const notificationBatchSize = 200
+
+// notificationContentMax 通知内容摘要最大字符数
+const notificationContentMax = 100
+
+// NotificationRepository 通知记录数据访问层
+type NotificationRepository struct {
+	db        *Database
+	prefsRepo *NotificationPreferenceRepository
+	logger    utils.Logger
+}
+
+// NewNotificationRepository 创建通知记录数据访问层（prefsRepo 用于跳过关闭了站内通知的接收者）
+func NewNotificationRepository(db *Database, prefsRepo *NotificationPreferenceRepository) *NotificationRepository {
+	return &NotificationRepository{
+		db:        db,
+		prefsRepo: prefsRepo,
+		logger:    utils.GetLogger(),
+	}
+}
+
+// Create 批量写入通知（同一操作通知多个接收者时一次写入，跳过通知自己的记录）
+func (r *NotificationRepository) Create(ctx context.Context, notifications []models.Notification) error {
+	rows := make([]models.Notification, 0, len(notifications))
+	for _, n := range notifications {
+		if n.UserID == 0 || n.UserID == n.ActorID {
+			continue
+		}
+		rows = append(rows, n)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
+	defer cancel()
+
+	now := time.Now().UTC()
+	for start := 0; start < len(rows); start += notificationBatchSize {
+		end := start + notificationBatchSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+		batch := rows[start:end]
+
+		query := `INSERT INTO notifications (user_id, actor_id, event_type, target_type, target_id, comment_id, content, created_at)
+				  VALUES (?, ?, ?, ?, ?, ?, ?, ?)` + strings.Repeat(", (?, ?, ?, ?, ?, ?, ?, ?)", len(batch)-1)
+		args := make([]interface{}, 0, len(batch)*8)
+		for _, n := range batch {
+			args = append(args, n.UserID, n.ActorID, n.EventType, n.TargetType, n.TargetID, n.CommentID,
+				utils.TruncateText(n.Content, notificationContentMax), now)
+		}
+
+		if _, err := r.db.DB.ExecContext(ctx, query, args...); err != nil {
+			r.logger.Error("写入通知失败", "count", len(batch), "error", err.Error())
+			return fmt.Errorf("%w: %w", utils.ErrDatabaseInsert, err)
+		}
+	}
+
+	r.logger.Debug("写入通知成功", "count", len(rows))
+	return nil
+}
+
+// CreateCommentNotifications 为新评论写入通知：一级评论通知内容作者，回复通知父评论作者和被回复用户
+// 关闭了对应类型站内通知的用户不会收到记录
+func (r *NotificationRepository) CreateCommentNotifications(ctx context.Context, targetType string, targetID, commentID, parentID, actorID uint, replyToUserID *uint, content string) error {
+	eventType := models.NotificationEventComment
+	var recipients []uint
+	if parentID == 0 {
+		ownerID, err := r.targetOwnerID(ctx, targetType, targetID)
+		if err != nil {
+			return err
+		}
+		recipients = append(recipients, ownerID)
+	} else {
+		eventType = models.NotificationEventReply
+		parentAuthorID, err := r.commentAuthorID(ctx, targetType, parentID)
+		if err != nil {
+			return err
+		}
+		recipients = append(recipients, parentAuthorID)
+		if replyToUserID != nil {
+			recipients = append(recipients, *replyToUserID)
+		}
+	}
+
+	// 去重并排除自己
+	seen := make(map[uint]bool, len(recipients))
+	unique := make([]uint, 0, len(recipients))
+	for _, id := range recipients {
+		if id == 0 || id == actorID || seen[id] {
+			continue
+		}
+		seen[id] = true
+		unique = append(unique, id)
+	}
+	if len(unique) == 0 {
+		return nil
+	}
+	if r.prefsRepo != nil {
+		unique = r.prefsRepo.FilterInAppRecipients(ctx, eventType, unique)
+	}
+
+	notifications := make([]models.Notification, 0, len(unique))
+	for _, userID := range unique {
+		notifications = append(notifications, models.Notification{
+			UserID:     userID,
+			ActorID:    actorID,
+			EventType:  eventType,
+			TargetType: targetType,
+			TargetID:   targetID,
+			CommentID:  commentID,
+			Content:    content,
+		})
+	}
+	return r.Create(ctx, notifications)
+}
+
+// targetOwnerID 获取文章/资源的作者ID
+func (r *NotificationRepository) targetOwnerID(ctx context.Context, targetType string, targetID uint) (uint, error) {
+	table := "articles"
+	if targetType == models.NotificationTargetResource {
+		table = "resources"
+	}
+	return r.queryUserID(ctx, fmt.Sprintf("SELECT user_id FROM %s WHERE id = ?", table), targetID)
+}
+
+// commentAuthorID 获取文章/资源评论的作者ID
+func (r *NotificationRepository) commentAuthorID(ctx context.Context, targetType string, commentID uint) (uint, error) {
+	table := "article_comments"
+	if targetType == models.NotificationTargetResource {
+		table = "resource_comments"
+	}
+	return r.queryUserID(ctx, fmt.Sprintf("SELECT user_id FROM %s WHERE id = ?", table), commentID)
+}
+
+func (r *NotificationRepository) queryUserID(ctx context.Context, query string, id uint) (uint, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetQueryTimeout())
+	defer cancel()
+
+	var userID uint
+	if err := r.db.QueryRowWithCache(ctx, query, id).Scan(&userID); err != nil {
+		r.logger.Warn("查询通知接收者失败", "id", id, "error", err.Error())
+		return 0, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+	}
+	return userID, nil
+}
+
+// ListForUser 分页获取用户的通知（按时间倒序，unreadOnly 为 true 时只返回未读）
+func (r *NotificationRepository) ListForUser(ctx context.Context, userID uint, page, pageSize int, unreadOnly bool) (*models.NotificationListResponse, error) {
+	offset := (page - 1) * pageSize
+
+	where := "n.user_id = ?"
+	if unreadOnly {
+		where += " AND n.is_read = 0"
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetQueryTimeout())
+	defer cancel()
+
+	var total int
+	if err := r.db.QueryRowWithCache(ctx, "SELECT COUNT(*) FROM notifications n WHERE "+where, userID).Scan(&total); err != nil {
+		r.logger.Error("查询通知总数失败", "userID", userID, "error", err.Error())
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+	}
+
+	query := `SELECT n.id, n.user_id, n.actor_id, n.event_type, n.target_type, n.target_id, n.comment_id,
+					 n.content, n.is_read, n.created_at,
+					 COALESCE(ua.username, ''), COALESCE(up.nickname, ua.username, ''), COALESCE(up.avatar_url, '')
+			  FROM notifications n
+			  LEFT JOIN user_auth ua ON ua.id = n.actor_id
+			  LEFT JOIN user_profile up ON up.user_id = n.actor_id
+			  WHERE ` + where + `
+			  ORDER BY n.created_at DESC, n.id DESC
+			  LIMIT ? OFFSET ?`
+
+	rows, err := r.db.QueryWithCache(ctx, query, userID, pageSize, offset)
+	if err != nil {
+		r.logger.Error("查询通知列表失败", "userID", userID, "error", err.Error())
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+	}
+	defer rows.Close()
+
+	notifications := make([]models.Notification, 0, pageSize)
+	for rows.Next() {
+		var n models.Notification
+		if err := rows.Scan(&n.ID, &n.UserID, &n.ActorID, &n.EventType, &n.TargetType, &n.TargetID, &n.CommentID,
+			&n.Content, &n.IsRead, &n.CreatedAt,
+			&n.Actor.Username, &n.Actor.Nickname, &n.Actor.Avatar); err != nil {
+			r.logger.Warn("扫描通知失败", "error", err.Error())
+			continue
+		}
+		n.Actor.ID = n.ActorID
+		notifications = append(notifications, n)
+	}
+
+	unread, err := r.UnreadCount(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.NotificationListResponse{
+		Notifications: notifications,
+		Total:         total,
+		UnreadCount:   unread,
+		Page:          page,
+		PageSize:      pageSize,
+		TotalPages:    (total + pageSize - 1) / pageSize,
+	}, nil
+}
+
+// MarkRead 标记通知已读（ids 为空时标记全部），返回实际标记的数量
+func (r *NotificationRepository) MarkRead(ctx context.Context, userID uint, ids []uint) (int64, error) {
+	query := `UPDATE notifications SET is_read = 1 WHERE user_id = ? AND is_read = 0`
+	args := []interface{}{userID}
+	if len(ids) > 0 {
+		query += " AND id IN (?" + strings.Repeat(",?", len(ids)-1) + ")"
+		for _, id := range ids {
+			args = append(args, id)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
+	defer cancel()
+
+	result, err := r.db.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		r.logger.Error("标记通知已读失败", "userID", userID, "error", err.Error())
+		return 0, fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
+	}
+
+	affected, _ := result.RowsAffected()
+	return affected, nil
+}
+
+// UnreadCount 获取用户未读通知数
+func (r *NotificationRepository) UnreadCount(ctx context.Context, userID uint) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetQueryTimeout())
+	defer cancel()
+
+	var count int
+	query := `SELECT COUNT(*) FROM notifications WHERE user_id = ? AND is_read = 0`
+	if err := r.db.QueryRowWithCache(ctx, query, userID).Scan(&count); err != nil {
+		r.logger.Error("查询未读通知数失败", "userID", userID, "error", err.Error())
+		return 0, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+	}
+	return count, nil
+}
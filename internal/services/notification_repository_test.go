@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/models"
+	"gin/internal/testutil"
+)
+
+// insertedRecipients 从 INSERT INTO notifications 调用中取出全部接收者ID（每行8个参数，第1个为 user_id）
+func insertedRecipients(fake *testutil.FakeDB) []int64 {
+	var ids []int64
+	for _, call := range fake.Calls(`INSERT INTO notifications`) {
+		for i := 0; i < len(call.Args); i += 8 {
+			ids = append(ids, call.Args[i].(int64))
+		}
+	}
+	return ids
+}
+
+func TestCreateNotificationsSkipsSelfAndBatches(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	fake.OnExec(`INSERT INTO notifications`, 0, 1)
+	repo := NewNotificationRepository(db, nil)
+
+	err := repo.Create(context.Background(), []models.Notification{
+		{UserID: 2, ActorID: 1, EventType: models.NotificationEventComment},
+		{UserID: 1, ActorID: 1, EventType: models.NotificationEventComment}, // 自己的操作
+		{UserID: 0, ActorID: 1, EventType: models.NotificationEventComment},
+		{UserID: 3, ActorID: 1, EventType: models.NotificationEventComment},
+	})
+	if err != nil {
+		t.Fatalf("写入通知失败: %v", err)
+	}
+	calls := fake.Calls(`INSERT INTO notifications`)
+	if len(calls) != 1 {
+		t.Fatalf("多个接收者应一次批量写入，实际 %d 条语句", len(calls))
+	}
+	if got := insertedRecipients(fake); len(got) != 2 || got[0] != 2 || got[1] != 3 {
+		t.Fatalf("应只通知 [2 3]，不通知操作者自己，实际 %v", got)
+	}
+
+	// 只有自己时不写入
+	if err := repo.Create(context.Background(), []models.Notification{{UserID: 1, ActorID: 1}}); err != nil {
+		t.Fatalf("写入通知失败: %v", err)
+	}
+	if len(fake.Calls(`INSERT INTO notifications`)) != 1 {
+		t.Fatal("只有操作者自己时不应写入通知")
+	}
+
+	// 超过单批上限时拆分为多条语句
+	many := make([]models.Notification, notificationBatchSize+1)
+	for i := range many {
+		many[i] = models.Notification{UserID: uint(i + 10), ActorID: 1}
+	}
+	if err := repo.Create(context.Background(), many); err != nil {
+		t.Fatalf("写入通知失败: %v", err)
+	}
+	if calls := fake.Calls(`INSERT INTO notifications`); len(calls) != 3 {
+		t.Fatalf("%d 个接收者应拆分为2批，实际共 %d 条语句", len(many), len(calls)-1)
+	}
+}
+
+func TestCreateCommentNotificationsRecipients(t *testing.T) {
+	cases := []struct {
+		name        string
+		parentID    uint
+		actorID     uint
+		replyToUser uint
+		want        []int64
+		wantEvent   string
+	}{
+		{"评论别人的文章通知文章作者", 0, 1, 0, []int64{7}, models.NotificationEventComment},
+		{"评论自己的文章不通知", 0, 7, 0, nil, ""},
+		{"回复通知父评论作者和被回复用户", 20, 1, 9, []int64{8, 9}, models.NotificationEventReply},
+		{"被回复用户就是父评论作者时只通知一次", 20, 1, 8, []int64{8}, models.NotificationEventReply},
+		{"回复自己的评论不通知", 20, 8, 8, nil, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fake, db := newFakeDatabase(t)
+			fake.OnRows(`SELECT user_id FROM articles WHERE id = \?`, []string{"user_id"}, []driver.Value{int64(7)})
+			fake.OnRows(`SELECT user_id FROM article_comments WHERE id = \?`, []string{"user_id"}, []driver.Value{int64(8)})
+			fake.OnExec(`INSERT INTO notifications`, 0, 1)
+
+			var replyTo *uint
+			if tc.replyToUser > 0 {
+				replyTo = &tc.replyToUser
+			}
+			err := NewNotificationRepository(db, nil).CreateCommentNotifications(context.Background(),
+				models.NotificationTargetArticle, 5, 30, tc.parentID, tc.actorID, replyTo, "内容")
+			if err != nil {
+				t.Fatalf("写入评论通知失败: %v", err)
+			}
+
+			got := insertedRecipients(fake)
+			if len(got) != len(tc.want) {
+				t.Fatalf("接收者应为 %v，实际 %v", tc.want, got)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Fatalf("接收者应为 %v，实际 %v", tc.want, got)
+				}
+			}
+			for _, call := range fake.Calls(`INSERT INTO notifications`) {
+				if call.Args[2] != tc.wantEvent || call.Args[3] != models.NotificationTargetArticle {
+					t.Fatalf("通知类型应为 %s/article，实际 %v", tc.wantEvent, call.Args)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateCommentNotificationsRespectsPreferences(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	fake.OnRows(`SELECT user_id FROM article_comments WHERE id = \?`, []string{"user_id"}, []driver.Value{int64(8)})
+	// 用户8关闭了回复的站内通知
+	fake.OnRows(`FROM notification_preferences`, []string{"user_id", "event_type", "in_app", "email", "updated_at"},
+		[]driver.Value{int64(8), models.NotificationEventReply, false, true, time.Now().UTC()})
+	fake.OnExec(`INSERT INTO notifications`, 0, 1)
+
+	prefs := NewNotificationPreferenceRepository(db, config.Default())
+	replyTo := uint(9)
+	if err := NewNotificationRepository(db, prefs).CreateCommentNotifications(context.Background(),
+		models.NotificationTargetArticle, 5, 30, 20, 1, &replyTo, "内容"); err != nil {
+		t.Fatalf("写入评论通知失败: %v", err)
+	}
+	if got := insertedRecipients(fake); len(got) != 1 || got[0] != 9 {
+		t.Fatalf("关闭回复通知的用户不应收到记录，实际接收者 %v", got)
+	}
+}
+
+func TestMarkReadLimitsToOwnUnreadNotifications(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	fake.OnExec(`UPDATE notifications SET is_read = 1`, 0, 2)
+	repo := NewNotificationRepository(db, nil)
+
+	n, err := repo.MarkRead(context.Background(), 4, []uint{11, 12})
+	if err != nil || n != 2 {
+		t.Fatalf("应标记2条，实际 %d %v", n, err)
+	}
+	call := fake.Calls(`UPDATE notifications`)[0]
+	if !strings.Contains(call.Query, "WHERE user_id = ? AND is_read = 0 AND id IN (?,?)") ||
+		call.Args[0] != int64(4) || call.Args[1] != int64(11) || call.Args[2] != int64(12) {
+		t.Fatalf("只能标记自己的指定通知: %s %v", call.Query, call.Args)
+	}
+
+	if _, err := repo.MarkRead(context.Background(), 4, nil); err != nil {
+		t.Fatalf("标记全部已读失败: %v", err)
+	}
+	if all := fake.Calls(`UPDATE notifications`)[1]; strings.Contains(all.Query, "id IN") || len(all.Args) != 1 {
+		t.Fatalf("未指定ID时应标记自己的全部未读通知: %s %v", all.Query, all.Args)
+	}
+}
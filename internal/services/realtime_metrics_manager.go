@@ -2,11 +2,13 @@ package services
 
 import (
 	"runtime"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"gin/internal/config"
+	"gin/internal/utils"
 )
 
 // RealtimeMetricsManager 实时指标管理器
@@ -47,6 +49,7 @@ func GetRealtimeMetricsManagerWithConfig(cfg *config.MetricsConfig) *RealtimeMet
 			OnlineUsersInitialCapacity: 1000,
 			OnlineUserCleanupInterval:  1,
 			OnlineUserExpireTime:       5,
+			OnlineUsersMaxMultiplier:   10,
 			CPUGoroutineBaseline:       200,
 		}
 
@@ -132,21 +135,63 @@ func (m *RealtimeMetricsManager) GetSystemMetrics() (cpuPercent, memoryPercent f
 	return
 }
 
-// cleanupOnlineUsers 定期清理超时的在线用户（使用配置的过期时间）
+// cleanupOnlineUsers 定期清理超时的在线用户（使用配置的间隔和过期时间）
 func (m *RealtimeMetricsManager) cleanupOnlineUsers() {
 	ticker := time.NewTicker(time.Duration(m.config.OnlineUserCleanupInterval) * time.Minute)
 	defer ticker.Stop()
 
 	for range ticker.C {
-		m.mu.Lock()
-		now := time.Now().UTC()
-		expireTime := time.Duration(m.config.OnlineUserExpireTime) * time.Minute
+		expired, evicted, remaining := m.pruneOnlineUsers(time.Now().UTC())
+		if expired > 0 || evicted > 0 {
+			utils.GetLogger().Info("清理在线用户",
+				"expired", expired,
+				"evicted", evicted,
+				"remaining", remaining)
+		}
+	}
+}
+
+// pruneOnlineUsers 删除超过过期时间无活动的用户；剩余数量仍超过上限时，按最后活跃时间淘汰最早的
+// 返回过期删除数、超限淘汰数和剩余数量
+func (m *RealtimeMetricsManager) pruneOnlineUsers(now time.Time) (expired, evicted, remaining int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	expireTime := time.Duration(m.config.OnlineUserExpireTime) * time.Minute
+	for userID, lastActive := range m.onlineUsers {
+		// 超过配置的时间无活动，视为离线
+		if now.Sub(lastActive) > expireTime {
+			delete(m.onlineUsers, userID)
+			expired++
+		}
+	}
+
+	if limit := m.maxOnlineUsers(); len(m.onlineUsers) > limit {
+		type entry struct {
+			userID     uint
+			lastActive time.Time
+		}
+		entries := make([]entry, 0, len(m.onlineUsers))
 		for userID, lastActive := range m.onlineUsers {
-			// 超过配置的时间无活动，视为离线
-			if now.Sub(lastActive) > expireTime {
-				delete(m.onlineUsers, userID)
-			}
+			entries = append(entries, entry{userID, lastActive})
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].lastActive.Before(entries[j].lastActive)
+		})
+		for _, e := range entries[:len(entries)-limit] {
+			delete(m.onlineUsers, e.userID)
+			evicted++
 		}
-		m.mu.Unlock()
 	}
+
+	return expired, evicted, len(m.onlineUsers)
+}
+
+// maxOnlineUsers 在线用户map的最大条目数（初始容量 × 倍数）
+func (m *RealtimeMetricsManager) maxOnlineUsers() int {
+	multiplier := m.config.OnlineUsersMaxMultiplier
+	if multiplier <= 0 {
+		multiplier = 10
+	}
+	return m.config.OnlineUsersInitialCapacity * multiplier
 }
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"gin/internal/config"
+)
+
+func newTestRealtimeMetrics(capacity, multiplier int) *RealtimeMetricsManager {
+	return &RealtimeMetricsManager{
+		onlineUsers: make(map[uint]time.Time),
+		config: &config.MetricsConfig{
+			OnlineUsersInitialCapacity: capacity,
+			OnlineUserCleanupInterval:  1,
+			OnlineUserExpireTime:       5,
+			OnlineUsersMaxMultiplier:   multiplier,
+		},
+	}
+}
+
+func TestPruneOnlineUsersRemovesStaleEntries(t *testing.T) {
+	m := newTestRealtimeMetrics(100, 10)
+	now := time.Now().UTC()
+	m.onlineUsers[1] = now.Add(-6 * time.Minute) // 超过5分钟无活动
+	m.onlineUsers[2] = now.Add(-4 * time.Minute)
+	m.onlineUsers[3] = now
+
+	expired, evicted, remaining := m.pruneOnlineUsers(now)
+	if expired != 1 || evicted != 0 || remaining != 2 {
+		t.Fatalf("应删除1个过期用户并保留2个，实际 expired=%d evicted=%d remaining=%d", expired, evicted, remaining)
+	}
+	if _, ok := m.onlineUsers[1]; ok {
+		t.Fatal("超过过期时间的用户应被删除")
+	}
+	for _, id := range []uint{2, 3} {
+		if _, ok := m.onlineUsers[id]; !ok {
+			t.Fatalf("仍活跃的用户 %d 应保留", id)
+		}
+	}
+}
+
+func TestPruneOnlineUsersEvictsOldestOverCap(t *testing.T) {
+	m := newTestRealtimeMetrics(2, 2) // 上限4
+	now := time.Now().UTC()
+	for id := uint(1); id <= 6; id++ {
+		m.onlineUsers[id] = now.Add(-time.Duration(7-id) * time.Second) // ID越大越近活跃
+	}
+
+	expired, evicted, remaining := m.pruneOnlineUsers(now)
+	if expired != 0 || evicted != 2 || remaining != 4 {
+		t.Fatalf("超过上限时应淘汰2个，实际 expired=%d evicted=%d remaining=%d", expired, evicted, remaining)
+	}
+	for _, id := range []uint{1, 2} {
+		if _, ok := m.onlineUsers[id]; ok {
+			t.Fatalf("应优先淘汰最久未活跃的用户 %d", id)
+		}
+	}
+	if got := m.GetOnlineUsers(); got != 4 {
+		t.Fatalf("淘汰后在线人数应为4，实际 %d", got)
+	}
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/utils"
+)
+
+// RefreshTokenRepository 刷新token数据访问层
+// 每次登录创建一个token家族，刷新时旧token标记为已轮换并在同一家族下签发新token；
+// 已轮换的token再次出现说明可能已泄露，此时吊销整个家族
+type RefreshTokenRepository struct {
+	db     *Database
+	logger utils.Logger
+	config *config.Config
+}
+
+// NewRefreshTokenRepository 创建刷新token数据访问层
+func NewRefreshTokenRepository(db *Database, cfg *config.Config) *RefreshTokenRepository {
+	return &RefreshTokenRepository{
+		db:     db,
+		logger: utils.GetLogger(),
+		config: cfg,
+	}
+}
+
+// newRefreshToken 生成随机刷新token明文
+func (r *RefreshTokenRepository) newRefreshToken() (string, error) {
+	randomBytes := make([]byte, r.config.JWTExtended.RefreshTokenBytes)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(randomBytes), nil
+}
+
+// refreshTokenTTL 刷新token有效期
+func (r *RefreshTokenRepository) refreshTokenTTL() time.Duration {
+	return time.Duration(r.config.JWTExtended.RefreshTokenDays) * 24 * time.Hour
+}
+
+// IssueRefreshToken 为新的登录会话签发刷新token（新建家族），返回明文token
+func (r *RefreshTokenRepository) IssueRefreshToken(ctx context.Context, userID uint) (string, error) {
+	plaintext, err := r.newRefreshToken()
+	if err != nil {
+		r.logger.Error("生成刷新token失败", "userID", userID, "error", err.Error())
+		return "", utils.ErrInternalServerError
+	}
+	familyBytes := make([]byte, 16)
+	if _, err := rand.Read(familyBytes); err != nil {
+		r.logger.Error("生成刷新token家族ID失败", "userID", userID, "error", err.Error())
+		return "", utils.ErrInternalServerError
+	}
+
+	query := `INSERT INTO refresh_tokens (user_id, token_hash, family_id, expires_at, created_at) VALUES (?, ?, ?, ?, ?)`
+
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
+	defer cancel()
+
+	now := time.Now().UTC()
+	if _, err := r.db.ExecWithCache(ctx, query,
+		userID, hashAPIToken(plaintext), hex.EncodeToString(familyBytes), now.Add(r.refreshTokenTTL()), now); err != nil {
+		r.logger.Error("保存刷新token失败", "userID", userID, "error", err.Error())
+		return "", utils.ErrDatabaseInsert
+	}
+	return plaintext, nil
+}
+
+// RotateRefreshToken 校验刷新token并轮换为新token，返回所属用户ID和新的明文token
+// token 不存在返回 ErrInvalidToken，过期返回 ErrTokenExpired；
+// token 已被轮换过（重放）时吊销整个家族并返回 ErrTokenAlreadyUsed
+func (r *RefreshTokenRepository) RotateRefreshToken(ctx context.Context, plaintext string) (uint, string, error) {
+	newPlaintext, err := r.newRefreshToken()
+	if err != nil {
+		r.logger.Error("生成刷新token失败", "error", err.Error())
+		return 0, "", utils.ErrInternalServerError
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
+	defer cancel()
+
+	var (
+		userID   uint
+		familyID string
+		reused   bool
+		expired  bool
+	)
+	now := time.Now().UTC()
+	err = r.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		var id uint64
+		var expiresAt time.Time
+		var rotatedAt *time.Time
+		err := tx.QueryRowContext(ctx,
+			`SELECT id, user_id, family_id, expires_at, rotated_at FROM refresh_tokens WHERE token_hash = ? FOR UPDATE`,
+			hashAPIToken(plaintext)).Scan(&id, &userID, &familyID, &expiresAt, &rotatedAt)
+		if err != nil {
+			return err
+		}
+
+		// 重放已轮换的token：删除整个家族（需要提交事务，因此不返回错误）
+		if rotatedAt != nil {
+			reused = true
+			_, err = tx.ExecContext(ctx, `DELETE FROM refresh_tokens WHERE family_id = ?`, familyID)
+			return err
+		}
+		if !expiresAt.After(now) {
+			expired = true
+			_, err = tx.ExecContext(ctx, `DELETE FROM refresh_tokens WHERE id = ?`, id)
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE refresh_tokens SET rotated_at = ? WHERE id = ?`, now, id); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO refresh_tokens (user_id, token_hash, family_id, expires_at, created_at) VALUES (?, ?, ?, ?, ?)`,
+			userID, hashAPIToken(newPlaintext), familyID, now.Add(r.refreshTokenTTL()), now)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, "", utils.ErrInvalidToken
+		}
+		r.logger.Error("轮换刷新token失败", "error", err.Error())
+		return 0, "", utils.ErrDatabaseUpdate
+	}
+
+	if reused {
+		r.logger.Warn("检测到已轮换的刷新token被重复使用，已吊销整个token家族",
+			"userID", userID,
+			"familyID", familyID)
+		return 0, "", utils.ErrTokenAlreadyUsed
+	}
+	if expired {
+		return 0, "", utils.ErrTokenExpired
+	}
+	return userID, newPlaintext, nil
+}
+
+// RevokeRefreshTokenFamily 删除用户刷新token所在家族的全部记录（用于退出登录）
+func (r *RefreshTokenRepository) RevokeRefreshTokenFamily(ctx context.Context, userID uint, plaintext string) error {
+	query := `DELETE t FROM refresh_tokens t
+			  INNER JOIN refresh_tokens cur ON cur.family_id = t.family_id
+			  WHERE cur.token_hash = ? AND cur.user_id = ?`
+
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
+	defer cancel()
+
+	if _, err := r.db.ExecWithCache(ctx, query, hashAPIToken(plaintext), userID); err != nil {
+		r.logger.Error("删除刷新token失败", "userID", userID, "error", err.Error())
+		return utils.ErrDatabaseDelete
+	}
+	return nil
+}
+
+// RevokeAllForUser 删除用户的全部刷新token（用于注销全部会话）
+func (r *RefreshTokenRepository) RevokeAllForUser(ctx context.Context, userID uint) error {
+	query := `DELETE FROM refresh_tokens WHERE user_id = ?`
+
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
+	defer cancel()
+
+	if _, err := r.db.ExecWithCache(ctx, query, userID); err != nil {
+		r.logger.Error("删除用户刷新token失败", "userID", userID, "error", err.Error())
+		return utils.ErrDatabaseDelete
+	}
+	return nil
+}
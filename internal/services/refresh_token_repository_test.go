@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/testutil"
+	"gin/internal/utils"
+)
+
+// refreshTokenRow 内存中的 refresh_tokens 记录
+type refreshTokenRow struct {
+	id        int64
+	userID    int64
+	hash      string
+	familyID  string
+	expiresAt time.Time
+	rotatedAt *time.Time
+}
+
+// refreshTokenStore 用内存表模拟 refresh_tokens 的插入、轮换和删除
+type refreshTokenStore struct {
+	mu     sync.Mutex
+	nextID int64
+	rows   map[int64]*refreshTokenRow
+}
+
+func (s *refreshTokenStore) byHash(hash string) *refreshTokenRow {
+	for _, row := range s.rows {
+		if row.hash == hash {
+			return row
+		}
+	}
+	return nil
+}
+
+func (s *refreshTokenStore) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.rows)
+}
+
+// newRefreshTokenRepo 创建使用内存 refresh_tokens 表的刷新token仓储
+func newRefreshTokenRepo(t *testing.T) (*RefreshTokenRepository, *refreshTokenStore) {
+	t.Helper()
+	fake, db := newFakeDatabase(t)
+	store := &refreshTokenStore{rows: make(map[int64]*refreshTokenRow)}
+
+	fake.On(`INSERT INTO refresh_tokens`, func(args []driver.Value) testutil.Response {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		store.nextID++
+		store.rows[store.nextID] = &refreshTokenRow{
+			id: store.nextID, userID: args[0].(int64), hash: args[1].(string),
+			familyID: args[2].(string), expiresAt: args[3].(time.Time),
+		}
+		return testutil.Response{LastInsertID: store.nextID, RowsAffected: 1}
+	})
+	fake.On(`SELECT id, user_id, family_id, expires_at, rotated_at FROM refresh_tokens WHERE token_hash = \?`, func(args []driver.Value) testutil.Response {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		resp := testutil.Response{Columns: []string{"id", "user_id", "family_id", "expires_at", "rotated_at"}}
+		if row := store.byHash(args[0].(string)); row != nil {
+			var rotatedAt driver.Value
+			if row.rotatedAt != nil {
+				rotatedAt = *row.rotatedAt
+			}
+			resp.Rows = [][]driver.Value{{row.id, row.userID, row.familyID, row.expiresAt, rotatedAt}}
+		}
+		return resp
+	})
+	fake.On(`UPDATE refresh_tokens SET rotated_at = \? WHERE id = \?`, func(args []driver.Value) testutil.Response {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		rotatedAt := args[0].(time.Time)
+		store.rows[args[1].(int64)].rotatedAt = &rotatedAt
+		return testutil.Response{RowsAffected: 1}
+	})
+	fake.On(`DELETE FROM refresh_tokens WHERE id = \?`, func(args []driver.Value) testutil.Response {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		delete(store.rows, args[0].(int64))
+		return testutil.Response{RowsAffected: 1}
+	})
+	fake.On(`DELETE FROM refresh_tokens WHERE family_id = \?`, func(args []driver.Value) testutil.Response {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		for id, row := range store.rows {
+			if row.familyID == args[0].(string) {
+				delete(store.rows, id)
+			}
+		}
+		return testutil.Response{RowsAffected: 1}
+	})
+	fake.On(`DELETE t FROM refresh_tokens t`, func(args []driver.Value) testutil.Response {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		cur := store.byHash(args[0].(string))
+		if cur == nil || cur.userID != args[1].(int64) {
+			return testutil.Response{}
+		}
+		for id, row := range store.rows {
+			if row.familyID == cur.familyID {
+				delete(store.rows, id)
+			}
+		}
+		return testutil.Response{RowsAffected: 1}
+	})
+
+	return NewRefreshTokenRepository(db, config.Default()), store
+}
+
+func TestRotateRefreshTokenInvalidatesOldToken(t *testing.T) {
+	repo, store := newRefreshTokenRepo(t)
+	ctx := context.Background()
+
+	first, err := repo.IssueRefreshToken(ctx, 5)
+	if err != nil {
+		t.Fatalf("签发刷新token失败: %v", err)
+	}
+	if row := store.byHash(hashAPIToken(first)); row == nil || row.hash == first {
+		t.Fatal("刷新token应只以哈希形式保存")
+	}
+
+	userID, second, err := repo.RotateRefreshToken(ctx, first)
+	if err != nil || userID != 5 {
+		t.Fatalf("轮换应成功并返回用户5，实际 %d %v", userID, err)
+	}
+	if second == "" || second == first {
+		t.Fatal("轮换后应签发新的刷新token")
+	}
+	oldRow, newRow := store.byHash(hashAPIToken(first)), store.byHash(hashAPIToken(second))
+	if oldRow == nil || oldRow.rotatedAt == nil {
+		t.Fatal("旧token应标记为已轮换")
+	}
+	if newRow == nil || newRow.familyID != oldRow.familyID {
+		t.Fatal("新token应属于同一家族")
+	}
+
+	if _, third, err := repo.RotateRefreshToken(ctx, second); err != nil || third == second {
+		t.Fatalf("新token应可继续轮换，实际 %v", err)
+	}
+}
+
+func TestRotateRefreshTokenReuseRevokesFamily(t *testing.T) {
+	repo, store := newRefreshTokenRepo(t)
+	ctx := context.Background()
+
+	first, _ := repo.IssueRefreshToken(ctx, 5)
+	other, _ := repo.IssueRefreshToken(ctx, 5) // 另一个登录会话
+	_, second, err := repo.RotateRefreshToken(ctx, first)
+	if err != nil {
+		t.Fatalf("轮换失败: %v", err)
+	}
+
+	if _, _, err := repo.RotateRefreshToken(ctx, first); !errors.Is(err, utils.ErrTokenAlreadyUsed) {
+		t.Fatalf("重放已轮换的token应返回 ErrTokenAlreadyUsed，实际 %v", err)
+	}
+	if _, _, err := repo.RotateRefreshToken(ctx, second); !errors.Is(err, utils.ErrInvalidToken) {
+		t.Fatalf("重放后整个家族都应被吊销，实际 %v", err)
+	}
+	if store.count() != 1 || store.byHash(hashAPIToken(other)) == nil {
+		t.Fatal("其他登录会话的刷新token不应受影响")
+	}
+}
+
+func TestRotateRefreshTokenRejectsUnknownAndExpired(t *testing.T) {
+	repo, store := newRefreshTokenRepo(t)
+	ctx := context.Background()
+
+	if _, _, err := repo.RotateRefreshToken(ctx, "not-a-token"); !errors.Is(err, utils.ErrInvalidToken) {
+		t.Fatalf("不存在的token应返回 ErrInvalidToken，实际 %v", err)
+	}
+
+	token, _ := repo.IssueRefreshToken(ctx, 5)
+	store.byHash(hashAPIToken(token)).expiresAt = time.Now().UTC().Add(-time.Minute)
+	if _, _, err := repo.RotateRefreshToken(ctx, token); !errors.Is(err, utils.ErrTokenExpired) {
+		t.Fatalf("过期token应返回 ErrTokenExpired，实际 %v", err)
+	}
+	if store.count() != 0 {
+		t.Fatal("过期token应被删除")
+	}
+}
+
+func TestRevokeRefreshTokenFamilyOnLogout(t *testing.T) {
+	repo, store := newRefreshTokenRepo(t)
+	ctx := context.Background()
+
+	first, _ := repo.IssueRefreshToken(ctx, 5)
+	_, second, _ := repo.RotateRefreshToken(ctx, first)
+
+	// 其他用户不能删除别人的刷新token
+	if err := repo.RevokeRefreshTokenFamily(ctx, 6, second); err != nil || store.count() != 2 {
+		t.Fatalf("不应删除其他用户的刷新token，实际剩余 %d %v", store.count(), err)
+	}
+	if err := repo.RevokeRefreshTokenFamily(ctx, 5, second); err != nil {
+		t.Fatalf("删除刷新token失败: %v", err)
+	}
+	if store.count() != 0 {
+		t.Fatalf("退出登录后家族内的刷新token应全部删除，实际剩余 %d", store.count())
+	}
+	if _, _, err := repo.RotateRefreshToken(ctx, second); !errors.Is(err, utils.ErrInvalidToken) {
+		t.Fatalf("退出登录后刷新token应失效，实际 %v", err)
+	}
+}
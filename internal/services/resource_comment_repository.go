@@ -152,13 +152,16 @@ func (r *ResourceCommentRepository) GetCommentsByResourceID(ctx context.Context,
 
 	totalPages := (total + pageSize - 1) / pageSize
 
-	return &models.ResourceCommentsResponse{
+	response := &models.ResourceCommentsResponse{
 		Comments:   comments,
 		Total:      total,
 		Page:       page,
 		PageSize:   pageSize,
 		TotalPages: totalPages,
-	}, nil
+	}
+	// 读取时限制层级，历史深层回复同样适用
+	response.CapDepth(r.config.Comments.MaxDepth)
+	return response, nil
 }
 
 // scanComment 扫描评论数据
@@ -213,6 +216,9 @@ func (r *ResourceCommentRepository) ToggleCommentLike(ctx context.Context, comme
 		_, err := r.db.DB.ExecContext(ctx, `INSERT INTO resource_comment_likes (comment_id, user_id, created_at) VALUES (?, ?, ?)`,
 			commentID, userID, time.Now().UTC())
 		if err != nil {
+			if isDuplicateKeyError(err) {
+				return false, duplicateEntryError("已经点过赞了")
+			}
 			return false, utils.ErrDatabaseInsert
 		}
 		_, _ = r.db.DB.ExecContext(ctx, `UPDATE resource_comments SET like_count = like_count + 1 WHERE id = ?`, commentID)
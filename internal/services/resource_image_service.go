@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"fmt"
+	"net/url"
 	"path/filepath"
 	"strings"
 
@@ -23,13 +24,19 @@ func NewResourceImageService(multiBucket *MultiBucketStorage) *ResourceImageServ
 	}
 }
 
-// MovePreviewImagesToFormal 将临时预览图移动到正式目录
-func (s *ResourceImageService) MovePreviewImagesToFormal(ctx context.Context, tempURLs []string, resourceID uint) ([]string, error) {
+// PreviewThumbnailPath 根据预览图对象路径生成缩略图对象路径（缩略图统一为JPEG）
+func PreviewThumbnailPath(objectPath string) string {
+	return strings.TrimSuffix(objectPath, filepath.Ext(objectPath)) + "_thumb.jpg"
+}
+
+// MovePreviewImagesToFormal 将临时预览图（及其缩略图）移动到正式目录
+// 返回的缩略图URL与图片URL一一对应，没有缩略图时为空字符串
+func (s *ResourceImageService) MovePreviewImagesToFormal(ctx context.Context, tempURLs []string, resourceID uint) ([]string, []string, error) {
 	if s.multiBucket == nil {
-		return nil, fmt.Errorf("多桶存储服务未初始化")
+		return nil, nil, fmt.Errorf("多桶存储服务未初始化")
 	}
 
-	var finalURLs []string
+	var finalURLs, thumbnailURLs []string
 	tempBucket := BucketTypeTempFiles
 	formalBucket := BucketTypeResourcePreviews
 	baseURL := s.multiBucket.GetPublicBaseURL(formalBucket)
@@ -59,11 +66,29 @@ func (s *ResourceImageService) MovePreviewImagesToFormal(ctx context.Context, te
 		// 构建最终URL
 		finalURL := fmt.Sprintf("%s/%s", baseURL, finalPath)
 		finalURLs = append(finalURLs, finalURL)
+		thumbnailURLs = append(thumbnailURLs, s.moveThumbnail(ctx, tempPath, PreviewThumbnailPath(finalPath)))
 
 		s.logger.Info("成功移动预览图", "from", tempPath, "to", finalPath)
 	}
 
-	return finalURLs, nil
+	return finalURLs, thumbnailURLs, nil
+}
+
+// moveThumbnail 移动预览图对应的缩略图（原图较小时上传阶段不会生成），返回缩略图URL，不存在或失败时返回空
+func (s *ResourceImageService) moveThumbnail(ctx context.Context, tempPath, finalThumbPath string) string {
+	tempThumbPath := PreviewThumbnailPath(tempPath)
+	exists, err := s.multiBucket.ObjectExists(ctx, BucketTypeTempFiles, tempThumbPath)
+	if err != nil || !exists {
+		return ""
+	}
+
+	if err := s.multiBucket.CopyObject(ctx, BucketTypeTempFiles, BucketTypeResourcePreviews, tempThumbPath, finalThumbPath); err != nil {
+		s.logger.Warn("移动缩略图失败", "src", tempThumbPath, "dst", finalThumbPath, "error", err.Error())
+		return ""
+	}
+	_ = s.multiBucket.RemoveObject(ctx, BucketTypeTempFiles, tempThumbPath)
+
+	return fmt.Sprintf("%s/%s", s.multiBucket.GetPublicBaseURL(BucketTypeResourcePreviews), finalThumbPath)
 }
 
 // DeleteResourceImages 删除资源的所有预览图
@@ -95,3 +120,47 @@ func (s *ResourceImageService) DeleteResourceImages(ctx context.Context, resourc
 	return nil
 }
 
+// ValidateImageURLs 校验资源预览图：数量不超过 maxImages（<=0 表示不限制），
+// 且地址必须来自临时文件桶（新上传）或资源预览图桶（已关联的图片）
+func (s *ResourceImageService) ValidateImageURLs(imageURLs []string, maxImages int) error {
+	if err := ValidateResourceImageCount(imageURLs, maxImages); err != nil {
+		return err
+	}
+	if s.multiBucket == nil {
+		return fmt.Errorf("多桶存储服务未初始化")
+	}
+
+	allowedBases := []string{
+		s.multiBucket.GetPublicBaseURL(BucketTypeTempFiles),
+		s.multiBucket.GetPublicBaseURL(BucketTypeResourcePreviews),
+	}
+	for _, imageURL := range imageURLs {
+		if !isURLUnderBase(imageURL, allowedBases) {
+			return utils.NewAppError(utils.ErrValidationFailed, "预览图地址不合法，请使用本站上传的图片: "+imageURL, 400)
+		}
+	}
+	return nil
+}
+
+// ValidateResourceImageCount 校验资源预览图数量（maxImages<=0 表示不限制）
+func ValidateResourceImageCount(imageURLs []string, maxImages int) error {
+	if maxImages > 0 && len(imageURLs) > maxImages {
+		return utils.NewAppError(utils.ErrValidationFailed,
+			fmt.Sprintf("预览图数量超出限制：最多%d张，当前%d张", maxImages, len(imageURLs)), 400)
+	}
+	return nil
+}
+
+// isURLUnderBase 判断URL是否位于某个允许的桶地址下（拒绝路径穿越）
+func isURLUnderBase(rawURL string, bases []string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || strings.Contains(u.Path, "..") {
+		return false
+	}
+	for _, base := range bases {
+		if base != "" && strings.HasPrefix(rawURL, strings.TrimSuffix(base, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"gin/internal/config"
+	"gin/internal/utils"
+)
+
+func newTestImageService() *ResourceImageService {
+	return NewResourceImageService(&MultiBucketStorage{buckets: map[BucketType]config.BucketConfig{
+		BucketTypeTempFiles:        {PublicBaseURL: "http://minio.local:9000/temp-files"},
+		BucketTypeResourcePreviews: {PublicBaseURL: "http://minio.local:9000/resource-previews/"},
+		BucketTypeUserAvatars:      {PublicBaseURL: "http://minio.local:9000/user-avatars"},
+	}})
+}
+
+func previewURLs(n int) []string {
+	urls := make([]string, n)
+	for i := range urls {
+		urls[i] = fmt.Sprintf("http://minio.local:9000/temp-files/preview/%d.png", i)
+	}
+	return urls
+}
+
+func TestValidateImageURLsCount(t *testing.T) {
+	svc := newTestImageService()
+
+	if err := svc.ValidateImageURLs(previewURLs(3), 3); err != nil {
+		t.Fatalf("达到上限时应通过，实际 %v", err)
+	}
+	if err := svc.ValidateImageURLs(previewURLs(10), 0); err != nil {
+		t.Fatalf("上限为0表示不限制，实际 %v", err)
+	}
+
+	err := svc.ValidateImageURLs(previewURLs(4), 3)
+	var appErr *utils.AppError
+	if !errors.As(err, &appErr) || appErr.Code != 400 || !strings.Contains(appErr.Message, "最多3张") {
+		t.Fatalf("超出上限应返回包含上限的400错误，实际 %v", err)
+	}
+}
+
+func TestValidateImageURLsOrigin(t *testing.T) {
+	svc := newTestImageService()
+
+	allowed := []string{
+		"http://minio.local:9000/temp-files/preview/a.png",
+		"http://minio.local:9000/resource-previews/5/preview_0.png",
+	}
+	if err := svc.ValidateImageURLs(allowed, 5); err != nil {
+		t.Fatalf("临时桶和预览图桶的地址应通过，实际 %v", err)
+	}
+
+	for _, bad := range []string{
+		"https://evil.example.com/temp-files/a.png",
+		"http://minio.local:9000/user-avatars/1.png",
+		"http://minio.local:9000/temp-files-other/a.png",
+		"http://minio.local:9000/temp-files/../user-avatars/1.png",
+		"javascript:alert(1)",
+	} {
+		if err := svc.ValidateImageURLs([]string{allowed[0], bad}, 5); err == nil {
+			t.Errorf("不允许的来源应被拒绝: %s", bad)
+		}
+	}
+}
+
+func TestUpdateResourceImagesEnforcesLimit(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	cfg := config.Default()
+	cfg.BucketResourcePreviews.MaxImagesPerResource = 2
+	repo := NewResourceRepository(db, cfg)
+
+	if err := repo.UpdateResourceImages(context.Background(), 1, previewURLs(3), nil); err == nil {
+		t.Fatal("更新时超出上限应被拒绝")
+	}
+	if calls := fake.Calls(""); len(calls) != 0 {
+		t.Fatalf("超出上限时不应访问数据库，实际执行 %v", calls)
+	}
+
+	fake.OnExec(`DELETE FROM resource_images`, 0, 0)
+	fake.OnExec(`INSERT INTO resource_images`, 1, 1)
+	if err := repo.UpdateResourceImages(context.Background(), 1, previewURLs(2), nil); err != nil {
+		t.Fatalf("达到上限时应更新成功，实际 %v", err)
+	}
+	if inserts := fake.Calls(`INSERT INTO resource_images`); len(inserts) != 2 {
+		t.Fatalf("应插入2条图片记录，实际 %d", len(inserts))
+	}
+}
+
+func TestPreviewThumbnailPath(t *testing.T) {
+	cases := map[string]string{
+		"preview/1/abc.png":  "preview/1/abc_thumb.jpg",
+		"5/preview_0.gif":    "5/preview_0_thumb.jpg",
+		"preview/noext":      "preview/noext_thumb.jpg",
+		"a.b/preview_1.jpeg": "a.b/preview_1_thumb.jpg",
+	}
+	for path, want := range cases {
+		if got := PreviewThumbnailPath(path); got != want {
+			t.Errorf("PreviewThumbnailPath(%q) = %q，期望 %q", path, got, want)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package services
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"strings"
 	"time"
 
@@ -32,7 +34,7 @@ func (r *ResourceRepository) CreateResource(ctx context.Context, resource *model
 	// 开启事务
 	tx, err := r.db.DB.BeginTx(ctx, nil)
 	if err != nil {
-		return utils.ErrDatabaseQuery
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
 	}
 	defer tx.Rollback()
 
@@ -49,11 +51,23 @@ func (r *ResourceRepository) CreateResource(ctx context.Context, resource *model
 
 	if err != nil {
 		r.logger.Error("插入资源失败", "error", err.Error())
-		return utils.ErrDatabaseInsert
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseInsert, err)
 	}
 
 	resourceID, _ := result.LastInsertId()
 	resource.ID = uint(resourceID)
+	resource.CurrentVersion = 1
+
+	// 首次上传的文件作为第1个版本，之后重新上传时仍可下载
+	if err := insertResourceVersion(ctx, tx, &models.ResourceVersion{
+		ResourceID: resource.ID, VersionNo: 1, Version: "v1", UserID: resource.UserID,
+		FileName: resource.FileName, FileSize: resource.FileSize, FileType: resource.FileType,
+		FileExtension: resource.FileExtension, FileHash: resource.FileHash, StoragePath: resource.StoragePath,
+		TotalChunks: resource.TotalChunks, CreatedAt: resource.CreatedAt,
+	}); err != nil {
+		r.logger.Error("插入资源版本失败", "resourceID", resource.ID, "error", err.Error())
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseInsert, err)
+	}
 
 	// 批量插入预览图（性能优化）
 	if len(imageURLs) > 0 {
@@ -78,7 +92,7 @@ func (r *ResourceRepository) CreateResource(ctx context.Context, resource *model
 		_, err := tx.ExecContext(ctx, imgQuery, imgArgs...)
 		if err != nil {
 			r.logger.Error("批量插入预览图失败", "error", err.Error())
-			return utils.ErrDatabaseInsert
+			return fmt.Errorf("%w: %w", utils.ErrDatabaseInsert, err)
 		}
 		r.logger.Info("预览图批量插入成功", "count", len(imageURLs))
 	}
@@ -121,7 +135,7 @@ func (r *ResourceRepository) CreateResource(ctx context.Context, resource *model
 
 	// 提交事务
 	if err := tx.Commit(); err != nil {
-		return utils.ErrDatabaseInsert
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseInsert, err)
 	}
 
 	r.logger.Info("创建资源成功", "resourceID", resource.ID, "title", resource.Title)
@@ -132,7 +146,7 @@ func (r *ResourceRepository) CreateResource(ctx context.Context, resource *model
 func (r *ResourceRepository) GetResourceByID(ctx context.Context, resourceID, userID uint) (*models.ResourceDetailResponse, error) {
 	// 查询资源基本信息
 	query := `SELECT id, user_id, title, description, document, category_id, file_name, file_size,
-	          file_type, file_extension, file_hash, storage_path, total_chunks, download_count, view_count, like_count,
+	          file_type, file_extension, file_hash, storage_path, total_chunks, current_version, download_count, view_count, like_count,
 	          status, created_at, updated_at FROM resources WHERE id = ? AND status != 0`
 
 	var resource models.Resource
@@ -142,7 +156,7 @@ func (r *ResourceRepository) GetResourceByID(ctx context.Context, resourceID, us
 		&resource.ID, &resource.UserID, &resource.Title, &resource.Description,
 		&resource.Document, &categoryID, &resource.FileName, &resource.FileSize,
 		&resource.FileType, &resource.FileExtension, &resource.FileHash, &resource.StoragePath,
-		&resource.TotalChunks, &resource.DownloadCount, &resource.ViewCount, &resource.LikeCount,
+		&resource.TotalChunks, &resource.CurrentVersion, &resource.DownloadCount, &resource.ViewCount, &resource.LikeCount,
 		&resource.Status, &resource.CreatedAt, &resource.UpdatedAt,
 	)
 
@@ -150,7 +164,7 @@ func (r *ResourceRepository) GetResourceByID(ctx context.Context, resourceID, us
 		if err == sql.ErrNoRows {
 			return nil, utils.ErrUserNotFound
 		}
-		return nil, utils.ErrDatabaseQuery
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
 	}
 
 	if categoryID.Valid {
@@ -161,8 +175,9 @@ func (r *ResourceRepository) GetResourceByID(ctx context.Context, resourceID, us
 	response := &models.ResourceDetailResponse{
 		Resource: resource,
 		// 初始化空数组，避免返回null
-		Images: make([]models.ResourceImage, 0),
-		Tags:   make([]string, 0),
+		Images:   make([]models.ResourceImage, 0),
+		Tags:     make([]string, 0),
+		Versions: make([]models.ResourceVersion, 0),
 	}
 
 	// 获取作者信息
@@ -176,7 +191,7 @@ func (r *ResourceRepository) GetResourceByID(ctx context.Context, resourceID, us
 	)
 
 	// 获取预览图
-	imgQuery := `SELECT id, resource_id, image_url, image_order, is_cover, created_at 
+	imgQuery := `SELECT id, resource_id, image_url, COALESCE(thumbnail_url, ''), image_order, is_cover, created_at 
 	             FROM resource_images WHERE resource_id = ? ORDER BY image_order ASC`
 	rows, err := r.db.DB.QueryContext(ctx, imgQuery, resourceID)
 	if err != nil {
@@ -187,7 +202,7 @@ func (r *ResourceRepository) GetResourceByID(ctx context.Context, resourceID, us
 		for rows.Next() {
 			var img models.ResourceImage
 			var isCover int
-			if err := rows.Scan(&img.ID, &img.ResourceID, &img.ImageURL, &img.ImageOrder, &isCover, &img.CreatedAt); err == nil {
+			if err := rows.Scan(&img.ID, &img.ResourceID, &img.ImageURL, &img.ThumbnailURL, &img.ImageOrder, &isCover, &img.CreatedAt); err == nil {
 				img.IsCover = isCover == 1
 				response.Images = append(response.Images, img)
 				imageCount++
@@ -223,6 +238,13 @@ func (r *ResourceRepository) GetResourceByID(ctx context.Context, resourceID, us
 		}
 	}
 
+	// 获取版本列表
+	if versions, err := r.listResourceVersions(ctx, resourceID); err != nil {
+		r.logger.Warn("查询资源版本失败", "resourceID", resourceID, "error", err.Error())
+	} else {
+		response.Versions = versions
+	}
+
 	// 检查当前用户是否点赞
 	if userID > 0 {
 		likeQuery := `SELECT id FROM resource_likes WHERE resource_id = ? AND user_id = ?`
@@ -277,9 +299,9 @@ func (r *ResourceRepository) ListResources(ctx context.Context, query models.Res
 	// 并行执行COUNT和列表查询（优化性能）
 	countQuery := "SELECT COUNT(*) FROM resources r " + whereClause
 	listQueryOptimized := `SELECT r.id, r.user_id, r.title, r.description, r.category_id, r.file_name,
-	              r.file_size, r.file_extension, r.file_hash, r.download_count, r.view_count, r.like_count, r.created_at,
+	              r.file_size, r.file_extension, r.file_hash, r.download_count, r.view_count, r.like_count, COALESCE(r.comment_count, 0), r.created_at,
 	              ua.username, COALESCE(up.nickname, ua.username) as nickname, COALESCE(up.avatar_url, '') as avatar,
-	              COALESCE(ri.image_url, '') as cover_image,
+	              COALESCE(ri.thumbnail_url, ri.image_url, '') as cover_image,
 	              rc.id as cat_id, rc.name as cat_name, rc.slug as cat_slug
 	              FROM resources r
 	              INNER JOIN user_auth ua ON r.user_id = ua.id
@@ -306,18 +328,19 @@ func (r *ResourceRepository) ListResources(ctx context.Context, query models.Res
 	countChan := make(chan countResult, 1)
 	listChan := make(chan listResult, 1)
 
-	// 并行执行COUNT
-	go func() {
-		var total int
-		err := r.db.DB.QueryRowContext(ctx, countQuery, countArgs...).Scan(&total)
-		countChan <- countResult{total: total, err: err}
-	}()
-
-	// 并行执行列表查询
-	go func() {
-		rows, err := r.db.DB.QueryContext(ctx, listQueryOptimized, listArgs...)
-		listChan <- listResult{rows: rows, err: err}
-	}()
+	utils.Parallel(ctx,
+		// 并行执行COUNT
+		func() {
+			var total int
+			err := r.db.DB.QueryRowContext(ctx, countQuery, countArgs...).Scan(&total)
+			countChan <- countResult{total: total, err: err}
+		},
+		// 并行执行列表查询
+		func() {
+			rows, err := r.db.DB.QueryContext(ctx, listQueryOptimized, listArgs...)
+			listChan <- listResult{rows: rows, err: err}
+		},
+	)
 
 	// 收集结果
 	countRes := <-countChan
@@ -327,11 +350,11 @@ func (r *ResourceRepository) ListResources(ctx context.Context, query models.Res
 		if listRes.rows != nil {
 			listRes.rows.Close()
 		}
-		return nil, utils.ErrDatabaseQuery
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, countRes.err)
 	}
 
 	if listRes.err != nil {
-		return nil, utils.ErrDatabaseQuery
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, listRes.err)
 	}
 
 	total := countRes.total
@@ -349,7 +372,7 @@ func (r *ResourceRepository) ListResources(ctx context.Context, query models.Res
 		err := rows.Scan(
 			&item.ID, &item.Author.ID, &item.Title, &item.Description, &categoryID,
 			&item.FileName, &item.FileSize, &item.FileExtension, &item.FileHash,
-			&item.DownloadCount, &item.ViewCount, &item.LikeCount, &item.CreatedAt,
+			&item.DownloadCount, &item.ViewCount, &item.LikeCount, &item.CommentCount, &item.CreatedAt,
 			&item.Author.Username, &item.Author.Nickname, &item.Author.Avatar,
 			&item.CoverImage,
 			&catID, &catName, &catSlug,
@@ -381,6 +404,11 @@ func (r *ResourceRepository) ListResources(ctx context.Context, query models.Res
 	}, nil
 }
 
+// GetResourceLikers 分页获取资源的点赞用户（按点赞时间倒序）
+func (r *ResourceRepository) GetResourceLikers(ctx context.Context, resourceID uint, page, pageSize int) (*models.LikersResponse, error) {
+	return listLikers(ctx, r.db, r.logger, "resource_likes", resourceID, page, pageSize)
+}
+
 // ToggleResourceLike 切换资源点赞
 func (r *ResourceRepository) ToggleResourceLike(ctx context.Context, resourceID, userID uint) (bool, error) {
 	// 检查是否已点赞
@@ -395,7 +423,10 @@ func (r *ResourceRepository) ToggleResourceLike(ctx context.Context, resourceID,
 		_, err := r.db.DB.ExecContext(ctx, `INSERT INTO resource_likes (resource_id, user_id, created_at) VALUES (?, ?, ?)`,
 			resourceID, userID, time.Now().UTC())
 		if err != nil {
-			return false, utils.ErrDatabaseInsert
+			if isDuplicateKeyError(err) {
+				return false, duplicateEntryError("已经点过赞了")
+			}
+			return false, fmt.Errorf("%w: %w", utils.ErrDatabaseInsert, err)
 		}
 		_, _ = r.db.DB.ExecContext(ctx, `UPDATE resources SET like_count = like_count + 1 WHERE id = ?`, resourceID)
 		isLiked = true
@@ -403,12 +434,12 @@ func (r *ResourceRepository) ToggleResourceLike(ctx context.Context, resourceID,
 		// 已点赞，取消点赞
 		_, err := r.db.DB.ExecContext(ctx, `DELETE FROM resource_likes WHERE resource_id = ? AND user_id = ?`, resourceID, userID)
 		if err != nil {
-			return false, utils.ErrDatabaseUpdate
+			return false, fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
 		}
 		_, _ = r.db.DB.ExecContext(ctx, `UPDATE resources SET like_count = GREATEST(like_count - 1, 0) WHERE id = ?`, resourceID)
 		isLiked = false
 	default:
-		return false, utils.ErrDatabaseQuery
+		return false, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
 	}
 
 	return isLiked, nil
@@ -435,7 +466,7 @@ func (r *ResourceRepository) DeleteResource(ctx context.Context, resourceID, use
 		if err == sql.ErrNoRows {
 			return utils.ErrUserNotFound
 		}
-		return utils.ErrDatabaseQuery
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
 	}
 
 	if ownerID != userID {
@@ -447,6 +478,201 @@ func (r *ResourceRepository) DeleteResource(ctx context.Context, resourceID, use
 	return err
 }
 
+// DeleteResourcesByUser 批量删除自己的资源（软删除）
+// 整批在一个事务中完成，任一资源不存在或不属于该用户时全部回滚；成功后按分类扣减资源数
+func (r *ResourceRepository) DeleteResourcesByUser(ctx context.Context, userID uint, resourceIDs []uint) ([]uint, error) {
+	if len(resourceIDs) == 0 {
+		return []uint{}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
+	defer cancel()
+
+	placeholders, args := inPlaceholders(resourceIDs)
+	err := r.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		// 锁定资源并检查所有权
+		rows, err := tx.QueryContext(ctx,
+			`SELECT id, user_id, category_id FROM resources WHERE id IN (`+placeholders+`) AND status != 0 FOR UPDATE`, args...)
+		if err != nil {
+			return fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+		}
+		owners := make(map[uint]uint, len(resourceIDs))
+		categoryCounts := make(map[int64]int)
+		for rows.Next() {
+			var id, ownerID uint
+			var categoryID sql.NullInt64
+			if err := rows.Scan(&id, &ownerID, &categoryID); err != nil {
+				rows.Close()
+				return fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+			}
+			owners[id] = ownerID
+			if categoryID.Valid {
+				categoryCounts[categoryID.Int64]++
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+		}
+		if err := checkBatchOwnership(resourceIDs, owners, userID); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE resources SET status = 0, updated_at = ? WHERE id IN (`+placeholders+`)`,
+			append([]interface{}{time.Now().UTC()}, args...)...); err != nil {
+			r.logger.Error("批量删除资源失败", "userID", userID, "error", err.Error())
+			return fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
+		}
+
+		// 更新分类资源数
+		for categoryID, count := range categoryCounts {
+			if _, err := tx.ExecContext(ctx,
+				`UPDATE resource_categories SET resource_count = GREATEST(resource_count - ?, 0) WHERE id = ?`, count, categoryID); err != nil {
+				return fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r.logger.Info("批量删除资源成功", "userID", userID, "count", len(resourceIDs))
+	return resourceIDs, nil
+}
+
+// resourceVersionColumns 资源版本查询字段（与 scanResourceVersion 顺序一致）
+const resourceVersionColumns = `id, resource_id, version_no, version, user_id, file_name, file_size, COALESCE(file_type, ''),
+	COALESCE(file_extension, ''), file_hash, storage_path, total_chunks, COALESCE(notes, ''), created_at`
+
+// scanResourceVersion 扫描一行资源版本
+func scanResourceVersion(scanner interface{ Scan(...interface{}) error }, v *models.ResourceVersion) error {
+	return scanner.Scan(&v.ID, &v.ResourceID, &v.VersionNo, &v.Version, &v.UserID, &v.FileName, &v.FileSize, &v.FileType,
+		&v.FileExtension, &v.FileHash, &v.StoragePath, &v.TotalChunks, &v.Notes, &v.CreatedAt)
+}
+
+// insertResourceVersion 在事务中写入一个资源版本
+func insertResourceVersion(ctx context.Context, tx *sql.Tx, v *models.ResourceVersion) error {
+	result, err := tx.ExecContext(ctx,
+		`INSERT INTO resource_versions (resource_id, version_no, version, user_id, file_name, file_size, file_type,
+			file_extension, file_hash, storage_path, total_chunks, notes, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?)`,
+		v.ResourceID, v.VersionNo, v.Version, v.UserID, v.FileName, v.FileSize, v.FileType,
+		v.FileExtension, v.FileHash, v.StoragePath, v.TotalChunks, v.Notes, v.CreatedAt)
+	if err != nil {
+		return err
+	}
+	id, _ := result.LastInsertId()
+	v.ID = uint(id)
+	return nil
+}
+
+// listResourceVersions 获取资源的版本列表（新版本在前）
+func (r *ResourceRepository) listResourceVersions(ctx context.Context, resourceID uint) ([]models.ResourceVersion, error) {
+	rows, err := r.db.DB.QueryContext(ctx,
+		`SELECT `+resourceVersionColumns+` FROM resource_versions WHERE resource_id = ? ORDER BY version_no DESC`, resourceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := make([]models.ResourceVersion, 0)
+	for rows.Next() {
+		var v models.ResourceVersion
+		if err := scanResourceVersion(rows, &v); err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
+
+// GetResourceVersion 获取资源的指定版本
+func (r *ResourceRepository) GetResourceVersion(ctx context.Context, resourceID uint, versionNo int) (*models.ResourceVersion, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetQueryTimeout())
+	defer cancel()
+
+	var v models.ResourceVersion
+	row := r.db.DB.QueryRowContext(ctx,
+		`SELECT `+resourceVersionColumns+` FROM resource_versions WHERE resource_id = ? AND version_no = ?`, resourceID, versionNo)
+	if err := scanResourceVersion(row, &v); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, utils.ErrResourceNotFound
+		}
+		r.logger.Error("查询资源版本失败", "resourceID", resourceID, "versionNo", versionNo, "error", err.Error())
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+	}
+	return &v, nil
+}
+
+// AddVersion 作者上传资源新版本：写入版本记录，并把资源的文件字段和当前版本号指向新版本
+// 旧版本记录保留（早于版本功能创建的资源会先把当前文件补记为当前版本）
+func (r *ResourceRepository) AddVersion(ctx context.Context, resourceID, userID uint, req models.AddResourceVersionRequest, fileExtension string) (*models.ResourceVersion, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
+	defer cancel()
+
+	now := time.Now().UTC()
+	version := &models.ResourceVersion{
+		ResourceID: resourceID, Version: req.Version, UserID: userID,
+		FileName: req.FileName, FileSize: req.FileSize, FileType: req.FileType, FileExtension: fileExtension,
+		FileHash: req.FileHash, StoragePath: req.StoragePath, TotalChunks: req.TotalChunks,
+		Notes: req.Notes, CreatedAt: now,
+	}
+
+	err := r.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		var ownerID uint
+		var currentVersion int
+		err := tx.QueryRowContext(ctx,
+			`SELECT user_id, current_version FROM resources WHERE id = ? AND status != 0 FOR UPDATE`, resourceID).Scan(&ownerID, &currentVersion)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return utils.ErrResourceNotFound
+			}
+			return err
+		}
+		if ownerID != userID {
+			return utils.ErrUnauthorized
+		}
+
+		// 补记当前文件为当前版本（已存在时忽略）
+		if _, err := tx.ExecContext(ctx,
+			`INSERT IGNORE INTO resource_versions (resource_id, version_no, version, user_id, file_name, file_size, file_type,
+				file_extension, file_hash, storage_path, total_chunks, created_at)
+			 SELECT id, current_version, CONCAT('v', current_version), user_id, file_name, file_size, file_type,
+				file_extension, file_hash, storage_path, total_chunks, updated_at
+			 FROM resources WHERE id = ?`, resourceID); err != nil {
+			return err
+		}
+
+		version.VersionNo = currentVersion + 1
+		if version.Version == "" {
+			version.Version = fmt.Sprintf("v%d", version.VersionNo)
+		}
+		if err := insertResourceVersion(ctx, tx, version); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`UPDATE resources SET file_name = ?, file_size = ?, file_type = ?, file_extension = ?, file_hash = ?,
+				storage_path = ?, total_chunks = ?, current_version = ?, updated_at = ?
+			 WHERE id = ?`,
+			version.FileName, version.FileSize, version.FileType, version.FileExtension, version.FileHash,
+			version.StoragePath, version.TotalChunks, version.VersionNo, now, resourceID)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, utils.ErrResourceNotFound) || errors.Is(err, utils.ErrUnauthorized) {
+			return nil, err
+		}
+		r.logger.Error("上传资源新版本失败", "resourceID", resourceID, "userID", userID, "error", err.Error())
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
+	}
+
+	r.logger.Info("上传资源新版本成功", "resourceID", resourceID, "versionNo", version.VersionNo, "version", version.Version)
+	return version, nil
+}
+
 // GetAllCategories 获取所有资源分类
 func (r *ResourceRepository) GetAllCategories(ctx context.Context) ([]models.ResourceCategory, error) {
 	query := `SELECT id, name, slug, description, resource_count, created_at FROM resource_categories ORDER BY id ASC`
@@ -467,11 +693,15 @@ func (r *ResourceRepository) GetAllCategories(ctx context.Context) ([]models.Res
 	return categories, nil
 }
 
-// UpdateResourceImages 更新资源的图片列表
-func (r *ResourceRepository) UpdateResourceImages(ctx context.Context, resourceID uint, imageURLs []string) error {
+// UpdateResourceImages 更新资源的图片列表，thumbnailURLs 与 imageURLs 按下标对应（可为空）
+func (r *ResourceRepository) UpdateResourceImages(ctx context.Context, resourceID uint, imageURLs []string, thumbnailURLs []string) error {
+	if err := ValidateResourceImageCount(imageURLs, r.config.BucketResourcePreviews.MaxImagesPerResource); err != nil {
+		return err
+	}
+
 	tx, err := r.db.DB.BeginTx(ctx, nil)
 	if err != nil {
-		return utils.ErrDatabaseQuery
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
 	}
 	defer tx.Rollback()
 
@@ -479,27 +709,31 @@ func (r *ResourceRepository) UpdateResourceImages(ctx context.Context, resourceI
 	_, err = tx.ExecContext(ctx, `DELETE FROM resource_images WHERE resource_id = ?`, resourceID)
 	if err != nil {
 		r.logger.Error("删除旧图片记录失败", "resourceID", resourceID, "error", err.Error())
-		return utils.ErrDatabaseUpdate
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
 	}
 
 	// 插入新的图片记录
 	if len(imageURLs) > 0 {
-		imgQuery := `INSERT INTO resource_images (resource_id, image_url, image_order, is_cover, created_at) VALUES (?, ?, ?, ?, ?)`
+		imgQuery := `INSERT INTO resource_images (resource_id, image_url, thumbnail_url, image_order, is_cover, created_at) VALUES (?, ?, ?, ?, ?, ?)`
 		for i, url := range imageURLs {
 			isCover := 0
 			if i == 0 {
 				isCover = 1
 			}
-			_, err := tx.ExecContext(ctx, imgQuery, resourceID, url, i, isCover, time.Now().UTC())
+			var thumbnailURL interface{}
+			if i < len(thumbnailURLs) && thumbnailURLs[i] != "" {
+				thumbnailURL = thumbnailURLs[i]
+			}
+			_, err := tx.ExecContext(ctx, imgQuery, resourceID, url, thumbnailURL, i, isCover, time.Now().UTC())
 			if err != nil {
 				r.logger.Error("插入新图片记录失败", "resourceID", resourceID, "index", i, "error", err.Error())
-				return utils.ErrDatabaseInsert
+				return fmt.Errorf("%w: %w", utils.ErrDatabaseInsert, err)
 			}
 		}
 	}
 
 	if err := tx.Commit(); err != nil {
-		return utils.ErrDatabaseUpdate
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
 	}
 
 	r.logger.Info("更新资源图片成功", "resourceID", resourceID, "count", len(imageURLs))
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/models"
+)
+
+func TestUpdateResourceImagesRecordsThumbnails(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	fake.OnExec(`DELETE FROM resource_images WHERE resource_id = \?`, 0, 0)
+	fake.OnExec(`INSERT INTO resource_images`, 1, 1)
+	repo := NewResourceRepository(db, config.Default())
+
+	images := []string{"https://cdn/5/preview_0.png", "https://cdn/5/preview_1.png", "https://cdn/5/preview_2.png"}
+	// 第二张原图较小没有缩略图，第三张没有对应的缩略图条目
+	thumbs := []string{"https://cdn/5/preview_0_thumb.jpg", ""}
+	if err := repo.UpdateResourceImages(context.Background(), 5, images, thumbs); err != nil {
+		t.Fatalf("更新资源图片失败: %v", err)
+	}
+
+	calls := fake.Calls(`INSERT INTO resource_images`)
+	if len(calls) != 3 {
+		t.Fatalf("应插入3条图片记录，实际 %d", len(calls))
+	}
+	if calls[0].Args[1] != images[0] || calls[0].Args[2] != thumbs[0] || calls[0].Args[4] != int64(1) {
+		t.Fatalf("第一张应记录原图和缩略图并设为封面，实际 %v", calls[0].Args)
+	}
+	for _, call := range calls[1:] {
+		if call.Args[2] != nil {
+			t.Fatalf("没有缩略图时 thumbnail_url 应为NULL，实际 %v", call.Args)
+		}
+	}
+}
+
+func TestListResourcesIncludesCommentCount(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	now := time.Now().UTC()
+	columns := []string{"id", "user_id", "title", "description", "category_id", "file_name", "file_size", "file_extension",
+		"file_hash", "download_count", "view_count", "like_count", "comment_count", "created_at",
+		"username", "nickname", "avatar", "cover_image", "cat_id", "cat_name", "cat_slug"}
+	resource := func(id, comments int64) []driver.Value {
+		return []driver.Value{id, int64(1), "title", "desc", nil, "a.zip", int64(1024), "zip",
+			"hash", int64(2), int64(10), int64(4), comments, now,
+			"alice", "Alice", "", "", nil, nil, nil}
+	}
+	fake.OnRows(`SELECT COUNT\(\*\) FROM resources r`, []string{"count"}, []driver.Value{int64(3)})
+	fake.OnRows(`FROM resources r INNER JOIN user_auth ua`, columns, resource(1, 3), resource(2, 0), resource(3, 12))
+
+	result, err := NewResourceRepository(db, config.Default()).ListResources(context.Background(), models.ResourceListQuery{Page: 1, PageSize: 20})
+	if err != nil {
+		t.Fatalf("查询资源列表失败: %v", err)
+	}
+	want := map[uint]int{1: 3, 2: 0, 3: 12}
+	if len(result.Resources) != 3 {
+		t.Fatalf("应返回3个资源，实际 %d", len(result.Resources))
+	}
+	for _, item := range result.Resources {
+		if item.CommentCount != want[item.ID] {
+			t.Fatalf("资源 %d 的评论数应为 %d，实际 %d", item.ID, want[item.ID], item.CommentCount)
+		}
+	}
+
+	// 评论数来自资源表的计数列，不按资源逐个查询
+	if calls := fake.Calls(""); len(calls) != 2 {
+		t.Fatalf("列表只应执行COUNT和列表两条查询，实际 %d 条", len(calls))
+	}
+	if calls := fake.Calls(`resource_comments`); len(calls) != 0 {
+		t.Fatalf("不应查询评论表，实际 %v", calls)
+	}
+}
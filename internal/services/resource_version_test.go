@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/models"
+	"gin/internal/testutil"
+	"gin/internal/utils"
+)
+
+var resourceVersionColumnNames = []string{"id", "resource_id", "version_no", "version", "user_id", "file_name", "file_size",
+	"file_type", "file_extension", "file_hash", "storage_path", "total_chunks", "notes", "created_at"}
+
+func resourceVersionValues(versionNo int64, storagePath string) []driver.Value {
+	return []driver.Value{versionNo + 100, int64(5), versionNo, fmt.Sprintf("v%d", versionNo), int64(1), "demo.zip", int64(1024),
+		"application/zip", "zip", "hash", storagePath, int64(0), "", time.Now().UTC()}
+}
+
+// onVersionedResource 资源5属于用户1，当前版本为 currentVersion
+func onVersionedResource(fake *testutil.FakeDB, currentVersion int64) {
+	fake.OnRows(`SELECT user_id, current_version FROM resources WHERE id = \? AND status != 0 FOR UPDATE`,
+		[]string{"user_id", "current_version"}, []driver.Value{int64(1), currentVersion})
+	fake.OnExec(`INSERT IGNORE INTO resource_versions`, 0, 1)
+	fake.OnExec(`^INSERT INTO resource_versions`, 42, 1)
+	fake.OnExec(`UPDATE resources SET file_name`, 0, 1)
+}
+
+func TestAddResourceVersion(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	onVersionedResource(fake, 2)
+
+	req := models.AddResourceVersionRequest{FileName: "demo-v3.zip", FileSize: 2048, FileHash: "h3",
+		StoragePath: "http://cdn/demo-v3.zip", Notes: "修复问题"}
+	version, err := NewResourceRepository(db, config.Default()).AddVersion(context.Background(), 5, 1, req, "zip")
+	if err != nil {
+		t.Fatalf("上传新版本失败: %v", err)
+	}
+	if version.ID != 42 || version.VersionNo != 3 || version.Version != "v3" || version.FileName != "demo-v3.zip" {
+		t.Fatalf("版本号应在当前版本上递增，未指定名称时为 v+序号，实际 %+v", version)
+	}
+
+	if calls := fake.Calls(`INSERT IGNORE INTO resource_versions`); len(calls) != 1 || calls[0].Args[0] != int64(5) {
+		t.Fatalf("应先补记当前文件为旧版本，实际 %v", calls)
+	}
+	insert := fake.Calls(`^INSERT INTO resource_versions`)
+	if len(insert) != 1 || insert[0].Args[1] != int64(3) || insert[0].Args[4] != "demo-v3.zip" || insert[0].Args[11] != "修复问题" {
+		t.Fatalf("版本记录内容错误: %v", insert)
+	}
+	update := fake.Calls(`UPDATE resources SET file_name`)
+	if len(update) != 1 {
+		t.Fatalf("应更新资源的当前文件，实际 %d 次", len(update))
+	}
+	if args := update[0].Args; args[0] != "demo-v3.zip" || args[5] != "http://cdn/demo-v3.zip" || args[7] != int64(3) || args[9] != int64(5) {
+		t.Fatalf("资源应指向新版本，实际 %v", args)
+	}
+	if len(fake.Calls(`^COMMIT$`)) != 1 {
+		t.Fatal("上传新版本应在事务中提交")
+	}
+}
+
+func TestAddResourceVersionChecksOwnership(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	onVersionedResource(fake, 1)
+	repo := NewResourceRepository(db, config.Default())
+	req := models.AddResourceVersionRequest{Version: "v2.0.0", FileName: "x.zip", FileSize: 1, FileHash: "h", StoragePath: "p"}
+
+	if _, err := repo.AddVersion(context.Background(), 5, 2, req, "zip"); !errors.Is(err, utils.ErrUnauthorized) {
+		t.Fatalf("非作者上传新版本应被拒绝，实际 %v", err)
+	}
+	if len(fake.Calls(`INSERT`)) != 0 || len(fake.Calls(`UPDATE resources`)) != 0 {
+		t.Fatal("非作者不应写入版本或修改资源")
+	}
+
+	fake.OnRows(`SELECT user_id, current_version FROM resources`, []string{"user_id", "current_version"})
+	if _, err := repo.AddVersion(context.Background(), 9, 1, req, "zip"); !errors.Is(err, utils.ErrResourceNotFound) {
+		t.Fatalf("资源不存在时应返回不存在，实际 %v", err)
+	}
+}
+
+func TestGetResourceVersion(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	fake.On(`FROM resource_versions WHERE resource_id = \? AND version_no = \?`, func(args []driver.Value) testutil.Response {
+		resp := testutil.Response{Columns: resourceVersionColumnNames}
+		if args[1] == int64(1) {
+			resp.Rows = [][]driver.Value{resourceVersionValues(1, "http://cdn/demo-v1.zip")}
+		}
+		return resp
+	})
+	repo := NewResourceRepository(db, config.Default())
+
+	version, err := repo.GetResourceVersion(context.Background(), 5, 1)
+	if err != nil || version.VersionNo != 1 || version.StoragePath != "http://cdn/demo-v1.zip" {
+		t.Fatalf("旧版本应仍可获取，实际 %+v %v", version, err)
+	}
+	if _, err := repo.GetResourceVersion(context.Background(), 5, 7); !errors.Is(err, utils.ErrResourceNotFound) {
+		t.Fatalf("版本不存在时应返回不存在，实际 %v", err)
+	}
+}
+
+func TestGetResourceByIDIncludesVersions(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	now := time.Now().UTC()
+	fake.OnRows(`FROM resources WHERE id = \? AND status != 0`, []string{
+		"id", "user_id", "title", "description", "document", "category_id", "file_name", "file_size", "file_type", "file_extension",
+		"file_hash", "storage_path", "total_chunks", "current_version", "download_count", "view_count", "like_count", "status", "created_at", "updated_at",
+	}, []driver.Value{int64(5), int64(1), "资源", "", "", nil, "demo.zip", int64(1024), "application/zip", "zip",
+		"hash", "http://cdn/demo-v2.zip", int64(0), int64(2), int64(0), int64(0), int64(0), int64(1), now, now})
+	fake.OnRows(`FROM resource_images`, []string{"id"})
+	fake.OnRows(`FROM resource_tags`, []string{"tag_name"})
+	fake.OnRows(`FROM resource_versions WHERE resource_id = \? ORDER BY version_no DESC`, resourceVersionColumnNames,
+		resourceVersionValues(2, "http://cdn/demo-v2.zip"), resourceVersionValues(1, "http://cdn/demo-v1.zip"))
+
+	detail, err := NewResourceRepository(db, config.Default()).GetResourceByID(context.Background(), 5, 0)
+	if err != nil {
+		t.Fatalf("获取资源详情失败: %v", err)
+	}
+	nos := make([]int, 0, len(detail.Versions))
+	for _, v := range detail.Versions {
+		nos = append(nos, v.VersionNo)
+	}
+	if detail.CurrentVersion != 2 || !slices.Equal(nos, []int{2, 1}) {
+		t.Fatalf("详情应返回当前版本和版本列表（新版本在前），实际 current=%d versions=%v", detail.CurrentVersion, nos)
+	}
+}
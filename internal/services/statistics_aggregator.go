@@ -0,0 +1,319 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/models"
+	"gin/internal/utils"
+)
+
+// apiStatsKey 接口统计键
+type apiStatsKey struct {
+	endpoint string
+	method   string
+}
+
+// apiStatsCounter 单个接口一天内的调用统计
+// 分位数按延迟直方图（与接口告警相同的分桶）近似，内存占用固定
+type apiStatsCounter struct {
+	total        int64
+	success      int64
+	errors       int64
+	latencySum   float64 // 累计响应时间（毫秒）
+	latency      []int64 // 本进程记录的各延迟分桶请求数（最后一个为溢出桶）
+	sampled      int64   // 直方图中的请求数
+	maxLatencyMs int64
+
+	// 数据库中已有的分位数（重启前写入）；本进程有新请求后按新请求的直方图计算
+	baselineP50, baselineP95, baselineP99 int64
+}
+
+// newApiStatsCounter 创建接口统计
+func newApiStatsCounter() *apiStatsCounter {
+	return &apiStatsCounter{latency: make([]int64, len(latencyBucketsMs)+1)}
+}
+
+// percentiles 计算 P50/P95/P99（毫秒）
+func (c *apiStatsCounter) percentiles() (p50, p95, p99 int64) {
+	if c.sampled == 0 {
+		return c.baselineP50, c.baselineP95, c.baselineP99
+	}
+	return histogramPercentile(c.latency, c.sampled, 0.50, c.maxLatencyMs),
+		histogramPercentile(c.latency, c.sampled, 0.95, c.maxLatencyMs),
+		histogramPercentile(c.latency, c.sampled, 0.99, c.maxLatencyMs)
+}
+
+// dailyStatsBucket 一天的汇总数据
+type dailyStatsBucket struct {
+	logins    int64
+	registers int64
+	apis      map[apiStatsKey]*apiStatsCounter
+	updates   int64 // 记录次数，用于判断写入后是否有新数据
+	// 首次写入前先把数据库中该日期已有的数据并入内存（例如重启前写入的部分），之后按日期覆盖写入
+	baselineLoaded bool
+}
+
+// StatisticsAggregator 每日统计汇总
+// 启用时登录/注册/接口调用在内存中按天累计，定时按日期覆盖写入 user_statistics / api_statistics；
+// 每天 run_at 汇总前一天的数据，优雅关闭时写入当天的部分数据。未启用时直接累加到数据库
+type StatisticsAggregator struct {
+	statsRepo *StatisticsRepository
+	config    config.StatsAggregationConfig
+	logger    utils.Logger
+
+	mu   sync.Mutex
+	days map[string]*dailyStatsBucket // 日期 -> 汇总数据
+
+	flushMu  sync.Mutex // 保证同一时间只有一次写入
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// NewStatisticsAggregator 创建每日统计汇总
+func NewStatisticsAggregator(statsRepo *StatisticsRepository, cfg *config.Config) *StatisticsAggregator {
+	return &StatisticsAggregator{
+		statsRepo: statsRepo,
+		config:    cfg.StatsAggregation,
+		logger:    utils.GetLogger(),
+		days:      make(map[string]*dailyStatsBucket),
+		stopCh:    make(chan struct{}),
+		doneCh:    make(chan struct{}),
+	}
+}
+
+// Start 启动定时汇总（未启用时直接返回）
+func (a *StatisticsAggregator) Start() {
+	if !a.config.Enabled {
+		close(a.doneCh)
+		a.logger.Info("每日统计汇总未启用，统计直接写入数据库")
+		return
+	}
+
+	go a.run()
+	a.logger.Info("每日统计汇总已启动",
+		"runAt", a.config.RunAt,
+		"flushInterval", time.Duration(a.config.FlushIntervalMinutes)*time.Minute)
+}
+
+// Stop 停止定时汇总并写入内存中的数据（应在 Worker Pool 关闭后调用，确保排队的统计任务已计入）
+func (a *StatisticsAggregator) Stop(ctx context.Context) {
+	a.stopOnce.Do(func() {
+		close(a.stopCh)
+	})
+	<-a.doneCh
+
+	if a.config.Enabled {
+		a.Flush(ctx, time.Now().UTC())
+	}
+}
+
+// run 定时写入循环：按间隔写入当天数据，每天 run_at 汇总并清理前一天的数据
+func (a *StatisticsAggregator) run() {
+	defer close(a.doneCh)
+
+	ticker := time.NewTicker(time.Duration(a.config.FlushIntervalMinutes) * time.Minute)
+	defer ticker.Stop()
+	daily := time.NewTimer(time.Until(a.nextRunAt(time.Now().UTC())))
+	defer daily.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			a.Flush(context.Background(), time.Now().UTC())
+		case <-daily.C:
+			now := time.Now().UTC()
+			a.Flush(context.Background(), now)
+			daily.Reset(time.Until(a.nextRunAt(now)))
+		case <-a.stopCh:
+			return
+		}
+	}
+}
+
+// nextRunAt 下一次每日汇总的时间（配置已校验为 HH:MM）
+func (a *StatisticsAggregator) nextRunAt(now time.Time) time.Time {
+	runAt, _ := time.Parse("15:04", a.config.RunAt)
+	next := time.Date(now.Year(), now.Month(), now.Day(), runAt.Hour(), runAt.Minute(), 0, 0, time.UTC)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// bucket 获取某天的汇总数据（调用方持有锁）
+func (a *StatisticsAggregator) bucket(date string) *dailyStatsBucket {
+	b, ok := a.days[date]
+	if !ok {
+		b = &dailyStatsBucket{apis: make(map[apiStatsKey]*apiStatsCounter)}
+		a.days[date] = b
+	}
+	return b
+}
+
+// RecordLogin 记录一次登录
+func (a *StatisticsAggregator) RecordLogin(date string) {
+	if !a.config.Enabled {
+		_ = a.statsRepo.IncrementLoginCount(date)
+		return
+	}
+
+	a.mu.Lock()
+	b := a.bucket(date)
+	b.logins++
+	b.updates++
+	a.mu.Unlock()
+}
+
+// RecordRegister 记录一次注册
+func (a *StatisticsAggregator) RecordRegister(date string) {
+	if !a.config.Enabled {
+		_ = a.statsRepo.IncrementRegisterCount(date)
+		return
+	}
+
+	a.mu.Lock()
+	b := a.bucket(date)
+	b.registers++
+	b.updates++
+	a.mu.Unlock()
+}
+
+// RecordApiCall 记录一次接口调用
+func (a *StatisticsAggregator) RecordApiCall(date, endpoint, method string, isSuccess, isError bool, latencyMs int64) {
+	if !a.config.Enabled {
+		_ = a.statsRepo.RecordApiCall(date, endpoint, method, isSuccess, isError, latencyMs)
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	b := a.bucket(date)
+	b.updates++
+	key := apiStatsKey{endpoint: endpoint, method: method}
+	counter, ok := b.apis[key]
+	if !ok {
+		counter = newApiStatsCounter()
+		b.apis[key] = counter
+	}
+	counter.total++
+	counter.latencySum += float64(latencyMs)
+	counter.latency[latencyBucketIndex(latencyMs)]++
+	counter.sampled++
+	if latencyMs > counter.maxLatencyMs {
+		counter.maxLatencyMs = latencyMs
+	}
+	if isSuccess {
+		counter.success++
+	}
+	if isError {
+		counter.errors++
+	}
+}
+
+// Flush 把内存中各天的汇总覆盖写入数据库；早于 now 当天的数据写入成功后从内存中移除
+// 写入失败的日期保留在内存中，下次重试（覆盖写入，不会重复计数）
+func (a *StatisticsAggregator) Flush(ctx context.Context, now time.Time) {
+	a.flushMu.Lock()
+	defer a.flushMu.Unlock()
+
+	today := now.UTC().Format("2006-01-02")
+
+	a.mu.Lock()
+	dates := make([]string, 0, len(a.days))
+	pending := make([]string, 0)
+	for date, b := range a.days {
+		dates = append(dates, date)
+		if !b.baselineLoaded {
+			pending = append(pending, date)
+		}
+	}
+	a.mu.Unlock()
+
+	// 首次写入某天前先并入数据库中已有的数据（查询不持有锁，避免阻塞请求）
+	for _, date := range pending {
+		user, apis, err := a.statsRepo.GetDailyStatistics(ctx, date)
+		if err != nil {
+			a.logger.Warn("读取已有统计失败，稍后重试", "date", date, "error", err.Error())
+			continue
+		}
+		a.mu.Lock()
+		a.bucket(date).mergeBaseline(user, apis)
+		a.mu.Unlock()
+	}
+
+	for _, date := range dates {
+		a.mu.Lock()
+		b := a.days[date]
+		if b == nil || !b.baselineLoaded {
+			a.mu.Unlock()
+			continue
+		}
+		user, apis := b.snapshot()
+		updates := b.updates
+		a.mu.Unlock()
+
+		if err := a.statsRepo.SaveDailyStatistics(ctx, date, user, apis); err != nil {
+			continue
+		}
+
+		// 写入期间又有新数据时保留，下次再写入
+		if date < today {
+			a.mu.Lock()
+			if a.days[date] == b && b.updates == updates {
+				delete(a.days, date)
+			}
+			a.mu.Unlock()
+			a.logger.Info("每日统计汇总完成", "date", date, "logins", user.LoginCount,
+				"registers", user.RegisterCount, "endpoints", len(apis))
+		}
+	}
+}
+
+// mergeBaseline 把数据库中已有的数据并入内存（调用方持有锁）
+func (b *dailyStatsBucket) mergeBaseline(user *models.UserStatistics, apis []models.ApiStatistics) {
+	if b.baselineLoaded {
+		return
+	}
+	b.logins += int64(user.LoginCount)
+	b.registers += int64(user.RegisterCount)
+	for _, stat := range apis {
+		key := apiStatsKey{endpoint: stat.Endpoint, method: stat.Method}
+		counter, ok := b.apis[key]
+		if !ok {
+			counter = newApiStatsCounter()
+			b.apis[key] = counter
+		}
+		counter.total += int64(stat.TotalCount)
+		counter.success += int64(stat.SuccessCount)
+		counter.errors += int64(stat.ErrorCount)
+		counter.latencySum += stat.AvgLatencyMs * float64(stat.TotalCount)
+		counter.baselineP50, counter.baselineP95, counter.baselineP99 = stat.P50LatencyMs, stat.P95LatencyMs, stat.P99LatencyMs
+	}
+	b.baselineLoaded = true
+}
+
+// snapshot 复制一天的汇总数据用于写入（调用方持有锁）
+func (b *dailyStatsBucket) snapshot() (models.UserStatistics, []models.ApiStatistics) {
+	user := models.UserStatistics{LoginCount: int(b.logins), RegisterCount: int(b.registers)}
+	apis := make([]models.ApiStatistics, 0, len(b.apis))
+	for key, counter := range b.apis {
+		stat := models.ApiStatistics{
+			Endpoint:     key.endpoint,
+			Method:       key.method,
+			TotalCount:   int(counter.total),
+			SuccessCount: int(counter.success),
+			ErrorCount:   int(counter.errors),
+		}
+		if counter.total > 0 {
+			stat.AvgLatencyMs = counter.latencySum / float64(counter.total)
+		}
+		stat.P50LatencyMs, stat.P95LatencyMs, stat.P99LatencyMs = counter.percentiles()
+		apis = append(apis, stat)
+	}
+	return user, apis
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/testutil"
+)
+
+// dailyStatsStore 模拟 user_statistics / api_statistics 表（按日期、接口唯一）
+type dailyStatsStore struct {
+	mu     sync.Mutex
+	users  map[string][2]int64           // date -> login_count, register_count
+	apis   map[string]map[string][]int64 // date -> endpoint -> total, success, error, p50, p95, p99
+	avg    map[string]map[string]float64 // date -> endpoint -> avg_latency_ms
+	failOn map[string]bool               // 写入这些日期时失败
+}
+
+func newDailyStatsStore() *dailyStatsStore {
+	return &dailyStatsStore{
+		users:  make(map[string][2]int64),
+		apis:   make(map[string]map[string][]int64),
+		avg:    make(map[string]map[string]float64),
+		failOn: make(map[string]bool),
+	}
+}
+
+func onDailyStats(fake *testutil.FakeDB, store *dailyStatsStore) {
+	fake.On(`SELECT login_count, register_count FROM user_statistics WHERE date = \?`, func(args []driver.Value) testutil.Response {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		resp := testutil.Response{Columns: []string{"login_count", "register_count"}}
+		if row, ok := store.users[args[0].(string)]; ok {
+			resp.Rows = [][]driver.Value{{row[0], row[1]}}
+		}
+		return resp
+	})
+	fake.On(`FROM api_statistics WHERE date = \?`, func(args []driver.Value) testutil.Response {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		date := args[0].(string)
+		resp := testutil.Response{Columns: []string{"endpoint", "method", "total_count", "success_count", "error_count",
+			"avg_latency_ms", "p50_latency_ms", "p95_latency_ms", "p99_latency_ms"}}
+		for endpoint, v := range store.apis[date] {
+			resp.Rows = append(resp.Rows, []driver.Value{endpoint, "GET", v[0], v[1], v[2], store.avg[date][endpoint], v[3], v[4], v[5]})
+		}
+		return resp
+	})
+	fake.On(`INSERT INTO user_statistics \(date, login_count, register_count\) VALUES \(\?, \?, \?\)`, func(args []driver.Value) testutil.Response {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		date := args[0].(string)
+		if store.failOn[date] {
+			return testutil.Response{Err: errors.New("connection refused")}
+		}
+		store.users[date] = [2]int64{args[1].(int64), args[2].(int64)}
+		return testutil.Response{RowsAffected: 1}
+	})
+	fake.On(`INSERT INTO api_statistics \(date, endpoint, method, total_count, success_count, error_count, avg_latency_ms, p50`, func(args []driver.Value) testutil.Response {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		for i := 0; i+9 < len(args); i += 10 {
+			date, endpoint := args[i].(string), args[i+1].(string)
+			if store.apis[date] == nil {
+				store.apis[date] = make(map[string][]int64)
+				store.avg[date] = make(map[string]float64)
+			}
+			store.apis[date][endpoint] = []int64{args[i+3].(int64), args[i+4].(int64), args[i+5].(int64),
+				args[i+7].(int64), args[i+8].(int64), args[i+9].(int64)}
+			store.avg[date][endpoint] = args[i+6].(float64)
+		}
+		return testutil.Response{RowsAffected: 1}
+	})
+}
+
+func newTestAggregator(t *testing.T) (*StatisticsAggregator, *testutil.FakeDB, *dailyStatsStore) {
+	t.Helper()
+	fake, db := newFakeDatabase(t)
+	store := newDailyStatsStore()
+	onDailyStats(fake, store)
+	cfg := config.Default()
+	cfg.StatsAggregation.Enabled = true
+	return NewStatisticsAggregator(NewStatisticsRepository(db, cfg), cfg), fake, store
+}
+
+func TestStatisticsAggregatorFlushIsIdempotent(t *testing.T) {
+	agg, fake, store := newTestAggregator(t)
+	now := time.Date(2024, 3, 2, 15, 0, 0, 0, time.UTC)
+	today := "2024-03-02"
+
+	// 重启前已写入的部分数据
+	store.users[today] = [2]int64{5, 1}
+	store.apis[today] = map[string][]int64{"/api/articles": {10, 9, 1, 25, 100, 250}}
+	store.avg[today] = map[string]float64{"/api/articles": 20}
+
+	agg.RecordLogin(today)
+	agg.RecordLogin(today)
+	agg.RecordRegister(today)
+	agg.RecordApiCall(today, "/api/articles", "GET", true, false, 40)
+	agg.Flush(context.Background(), now)
+
+	if got := store.users[today]; got != [2]int64{7, 2} {
+		t.Fatalf("应在已有数据上累加本进程的统计，实际 %v", got)
+	}
+	if got := store.apis[today]["/api/articles"]; got[0] != 11 || got[1] != 10 || got[2] != 1 {
+		t.Fatalf("接口调用数应累加，实际 %v", got)
+	}
+	if avg := store.avg[today]["/api/articles"]; avg != (20*10+40)/11.0 {
+		t.Fatalf("平均延迟应按总次数加权，实际 %v", avg)
+	}
+
+	// 没有新数据时再次写入结果不变，已有数据只并入一次
+	agg.Flush(context.Background(), now)
+	agg.Flush(context.Background(), now)
+	if got := store.users[today]; got != [2]int64{7, 2} {
+		t.Fatalf("重复写入不应重复计数，实际 %v", got)
+	}
+	if calls := fake.Calls(`SELECT login_count, register_count FROM user_statistics`); len(calls) != 1 {
+		t.Fatalf("每天只应读取一次已有数据，实际 %d 次", len(calls))
+	}
+
+	// 当天的数据保留在内存中继续累计
+	agg.RecordLogin(today)
+	agg.Flush(context.Background(), now)
+	if got := store.users[today]; got != [2]int64{8, 2} {
+		t.Fatalf("当天新增的登录应继续累计，实际 %v", got)
+	}
+}
+
+func TestStatisticsAggregatorRollsUpPreviousDay(t *testing.T) {
+	agg, _, store := newTestAggregator(t)
+	agg.RecordLogin("2024-03-01")
+	agg.RecordLogin("2024-03-02")
+	store.failOn["2024-03-01"] = true
+
+	now := time.Date(2024, 3, 2, 0, 10, 0, 0, time.UTC)
+	agg.Flush(context.Background(), now)
+	agg.mu.Lock()
+	_, kept := agg.days["2024-03-01"]
+	agg.mu.Unlock()
+	if !kept {
+		t.Fatal("写入失败的日期应保留在内存中等待重试")
+	}
+
+	store.failOn["2024-03-01"] = false
+	agg.Flush(context.Background(), now)
+	agg.mu.Lock()
+	_, kept = agg.days["2024-03-01"]
+	_, keptToday := agg.days["2024-03-02"]
+	agg.mu.Unlock()
+	if kept || !keptToday {
+		t.Fatal("前一天写入成功后应从内存中移除，当天的数据应保留")
+	}
+	if store.users["2024-03-01"] != [2]int64{1, 0} {
+		t.Fatalf("前一天的数据应写入数据库，实际 %v", store.users["2024-03-01"])
+	}
+}
+
+func TestStatisticsAggregatorPercentiles(t *testing.T) {
+	agg, _, store := newTestAggregator(t)
+	now := time.Date(2024, 3, 2, 15, 0, 0, 0, time.UTC)
+	today := "2024-03-02"
+
+	// 重启前写入的分位数在本进程没有新请求时原样保留
+	store.apis[today] = map[string][]int64{"/api/users": {4, 4, 0, 10, 50, 100}}
+	store.avg[today] = map[string]float64{"/api/users": 12}
+	agg.RecordLogin(today)
+
+	for i := 0; i < 98; i++ {
+		agg.RecordApiCall(today, "/api/articles", "GET", true, false, 8)
+	}
+	agg.RecordApiCall(today, "/api/articles", "GET", true, false, 400)
+	agg.RecordApiCall(today, "/api/articles", "GET", false, true, 1800)
+	agg.Flush(context.Background(), now)
+
+	if got := store.apis[today]["/api/users"]; got[3] != 10 || got[4] != 50 || got[5] != 100 {
+		t.Fatalf("没有新请求时应保留已有的分位数，实际 %v", got)
+	}
+	// 分位数为所在延迟分桶的上界
+	if got := store.apis[today]["/api/articles"]; got[3] != 10 || got[4] != 10 || got[5] != 500 {
+		t.Fatalf("P50/P95/P99 应为 10/10/500，实际 %v", got[3:])
+	}
+}
+
+func TestStatisticsAggregatorNextRunAt(t *testing.T) {
+	cfg := config.Default()
+	cfg.StatsAggregation.RunAt = "00:10"
+	agg := NewStatisticsAggregator(nil, cfg)
+
+	before := time.Date(2024, 3, 2, 0, 5, 0, 0, time.UTC)
+	if next := agg.nextRunAt(before); !next.Equal(time.Date(2024, 3, 2, 0, 10, 0, 0, time.UTC)) {
+		t.Fatalf("当天还未到汇总时间时应在当天执行，实际 %v", next)
+	}
+	after := time.Date(2024, 3, 2, 0, 10, 0, 0, time.UTC)
+	if next := agg.nextRunAt(after); !next.Equal(time.Date(2024, 3, 3, 0, 10, 0, 0, time.UTC)) {
+		t.Fatalf("已过汇总时间时应在次日执行，实际 %v", next)
+	}
+}
+
+func TestStatisticsAggregatorStopFlushesPartialDay(t *testing.T) {
+	agg, _, store := newTestAggregator(t)
+	agg.config.FlushIntervalMinutes = 60
+	agg.Start()
+
+	today := time.Now().UTC().Format("2006-01-02")
+	agg.RecordLogin(today)
+	agg.Stop(context.Background())
+	if store.users[today] != [2]int64{1, 0} {
+		t.Fatalf("优雅关闭时应写入当天的部分数据，实际 %v", store.users[today])
+	}
+}
+
+func TestStatisticsAggregatorDisabledWritesThrough(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	fake.OnExec(`INSERT INTO user_statistics \(date, login_count, register_count\)\s*VALUES \(\?, 1, 0\)`, 0, 1)
+	cfg := config.Default()
+	cfg.StatsAggregation.Enabled = false
+	agg := NewStatisticsAggregator(NewStatisticsRepository(db, cfg), cfg)
+	agg.Start()
+	defer agg.Stop(context.Background())
+
+	agg.RecordLogin("2024-03-02")
+	if calls := fake.Calls(`VALUES \(\?, 1, 0\)`); len(calls) != 1 {
+		t.Fatalf("未启用汇总时应直接累加到数据库，实际 %d 次", len(calls))
+	}
+	agg.mu.Lock()
+	defer agg.mu.Unlock()
+	if len(agg.days) != 0 {
+		t.Fatal("未启用汇总时不应在内存中累计")
+	}
+}
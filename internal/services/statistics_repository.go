@@ -3,6 +3,8 @@ package services
 import (
 	"context"
 	"database/sql"
+	"strconv"
+	"strings"
 	"time"
 
 	"gin/internal/config"
@@ -99,6 +101,97 @@ func (r *StatisticsRepository) RecordApiCall(date, endpoint, method string, isSu
 	return nil
 }
 
+// apiStatisticsUpsertBatch 覆盖写入 api_statistics 时每条语句的最大行数
+const apiStatisticsUpsertBatch = 200
+
+// GetDailyStatistics 获取某一天已写入的登录/注册统计和各接口统计（没有记录时计数为0）
+func (r *StatisticsRepository) GetDailyStatistics(ctx context.Context, date string) (*models.UserStatistics, []models.ApiStatistics, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetQueryTimeout())
+	defer cancel()
+
+	user := &models.UserStatistics{}
+	err := r.db.DB.QueryRowContext(ctx,
+		`SELECT login_count, register_count FROM user_statistics WHERE date = ?`, date).
+		Scan(&user.LoginCount, &user.RegisterCount)
+	if err != nil && err != sql.ErrNoRows {
+		r.logger.Error("查询当日用户统计失败", "date", date, "error", err.Error())
+		return nil, nil, utils.ErrDatabaseQuery
+	}
+
+	rows, err := r.db.DB.QueryContext(ctx,
+		`SELECT endpoint, method, total_count, success_count, error_count, avg_latency_ms,
+		        p50_latency_ms, p95_latency_ms, p99_latency_ms
+		 FROM api_statistics WHERE date = ?`, date)
+	if err != nil {
+		r.logger.Error("查询当日API统计失败", "date", date, "error", err.Error())
+		return nil, nil, utils.ErrDatabaseQuery
+	}
+	defer rows.Close()
+
+	apis := make([]models.ApiStatistics, 0)
+	for rows.Next() {
+		var stat models.ApiStatistics
+		if err := rows.Scan(&stat.Endpoint, &stat.Method, &stat.TotalCount, &stat.SuccessCount,
+			&stat.ErrorCount, &stat.AvgLatencyMs, &stat.P50LatencyMs, &stat.P95LatencyMs, &stat.P99LatencyMs); err != nil {
+			return nil, nil, utils.ErrDatabaseQuery
+		}
+		apis = append(apis, stat)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, utils.ErrDatabaseQuery
+	}
+	return user, apis, nil
+}
+
+// SaveDailyStatistics 按日期覆盖写入一天的汇总统计（重复执行结果相同）
+func (r *StatisticsRepository) SaveDailyStatistics(ctx context.Context, date string, user models.UserStatistics, apis []models.ApiStatistics) error {
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
+	defer cancel()
+
+	err := r.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO user_statistics (date, login_count, register_count) VALUES (?, ?, ?)
+			 ON DUPLICATE KEY UPDATE login_count = VALUES(login_count), register_count = VALUES(register_count)`,
+			date, user.LoginCount, user.RegisterCount); err != nil {
+			return err
+		}
+
+		for start := 0; start < len(apis); start += apiStatisticsUpsertBatch {
+			end := start + apiStatisticsUpsertBatch
+			if end > len(apis) {
+				end = len(apis)
+			}
+			batch := apis[start:end]
+
+			args := make([]interface{}, 0, len(batch)*10)
+			for _, stat := range batch {
+				args = append(args, date, stat.Endpoint, stat.Method, stat.TotalCount, stat.SuccessCount, stat.ErrorCount,
+					stat.AvgLatencyMs, stat.P50LatencyMs, stat.P95LatencyMs, stat.P99LatencyMs)
+			}
+			query := `INSERT INTO api_statistics (date, endpoint, method, total_count, success_count, error_count,
+					    avg_latency_ms, p50_latency_ms, p95_latency_ms, p99_latency_ms)
+					  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)` + strings.Repeat(", (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", len(batch)-1) + `
+					  ON DUPLICATE KEY UPDATE
+					    total_count = VALUES(total_count),
+					    success_count = VALUES(success_count),
+					    error_count = VALUES(error_count),
+					    avg_latency_ms = VALUES(avg_latency_ms),
+					    p50_latency_ms = VALUES(p50_latency_ms),
+					    p95_latency_ms = VALUES(p95_latency_ms),
+					    p99_latency_ms = VALUES(p99_latency_ms)`
+			if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		r.logger.Error("写入每日汇总统计失败", "date", date, "apis", len(apis), "error", err.Error())
+		return utils.ErrDatabaseUpdate
+	}
+	return nil
+}
+
 // GetUserStatistics 获取用户统计数据
 func (r *StatisticsRepository) GetUserStatistics(startDate, endDate string) ([]models.UserStatistics, error) {
 	query := `SELECT id, date, login_count, register_count, created_at, updated_at 
@@ -323,3 +416,91 @@ func (r *StatisticsRepository) GetTodayOverview() (*models.StatisticsOverview, e
 
 	return overview, nil
 }
+
+// 可导出的统计类型
+const (
+	StatisticsExportUsers = "users" // 用户注册登录统计（user_statistics）
+	StatisticsExportAPIs  = "apis"  // API接口访问统计（api_statistics）
+)
+
+// ExportStatistics 按日期范围逐行导出统计数据（直接从数据库游标读取，不在内存中汇总）
+// 第一次调用 emit 传入表头，之后每行调用一次；emit 返回错误时停止导出
+func (r *StatisticsRepository) ExportStatistics(ctx context.Context, exportType, startDate, endDate string, emit func(record []string) error) error {
+	var query string
+	var header []string
+	switch exportType {
+	case StatisticsExportUsers:
+		query = `SELECT date, login_count, register_count, updated_at
+				  FROM user_statistics
+				  WHERE date >= ? AND date <= ?
+				  ORDER BY date`
+		header = []string{"date", "login_count", "register_count", "updated_at"}
+	case StatisticsExportAPIs:
+		query = `SELECT date, endpoint, method, success_count, error_count, total_count, avg_latency_ms, updated_at
+				  FROM api_statistics
+				  WHERE date >= ? AND date <= ?
+				  ORDER BY date, endpoint, method`
+		header = []string{"date", "endpoint", "method", "success_count", "error_count", "total_count", "avg_latency_ms", "updated_at"}
+	default:
+		return utils.ErrInvalidParameter
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(r.config.StatisticsQueryExtended.ExportTimeoutSec)*time.Second)
+	defer cancel()
+
+	// 导出允许运行到 export_timeout_sec，用语句级提示覆盖会话级的 max_execution_time
+	rows, err := r.db.DB.QueryContext(ctx, WithStatementTimeout(ctx, query), startDate, endDate)
+	if err != nil {
+		r.logger.Error("导出统计数据失败",
+			"type", exportType,
+			"startDate", startDate,
+			"endDate", endDate,
+			"error", err.Error())
+		return utils.ErrDatabaseQuery
+	}
+	defer rows.Close()
+
+	if err := emit(header); err != nil {
+		return err
+	}
+
+	dateFormat := r.config.DateTimeFormats.DateOnly
+	datetimeFormat := r.config.DateTimeFormats.DatetimeFull
+	record := make([]string, len(header))
+	for rows.Next() {
+		if exportType == StatisticsExportUsers {
+			var stat models.UserStatistics
+			if err := rows.Scan(&stat.Date, &stat.LoginCount, &stat.RegisterCount, &stat.UpdatedAt); err != nil {
+				r.logger.Error("扫描用户统计数据失败", "error", err.Error())
+				return utils.ErrDatabaseQuery
+			}
+			record[0] = stat.Date.Format(dateFormat)
+			record[1] = strconv.Itoa(stat.LoginCount)
+			record[2] = strconv.Itoa(stat.RegisterCount)
+			record[3] = stat.UpdatedAt.Format(datetimeFormat)
+		} else {
+			var stat models.ApiStatistics
+			if err := rows.Scan(&stat.Date, &stat.Endpoint, &stat.Method, &stat.SuccessCount, &stat.ErrorCount,
+				&stat.TotalCount, &stat.AvgLatencyMs, &stat.UpdatedAt); err != nil {
+				r.logger.Error("扫描API统计数据失败", "error", err.Error())
+				return utils.ErrDatabaseQuery
+			}
+			record[0] = stat.Date.Format(dateFormat)
+			record[1] = stat.Endpoint
+			record[2] = stat.Method
+			record[3] = strconv.Itoa(stat.SuccessCount)
+			record[4] = strconv.Itoa(stat.ErrorCount)
+			record[5] = strconv.Itoa(stat.TotalCount)
+			record[6] = strconv.FormatFloat(stat.AvgLatencyMs, 'f', 2, 64)
+			record[7] = stat.UpdatedAt.Format(datetimeFormat)
+		}
+		if err := emit(record); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error("读取统计数据失败", "type", exportType, "error", err.Error())
+		return utils.ErrDatabaseQuery
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/testutil"
+	"gin/internal/utils"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// onTagMerge 预设合并标签的各条语句：locked 为 FOR UPDATE 锁定到的标签数，remaining 为改指向后源标签剩余的关联数
+func onTagMerge(fake *testutil.FakeDB, locked int, remaining int64) {
+	var rows [][]driver.Value
+	for i := 0; i < locked; i++ {
+		rows = append(rows, []driver.Value{int64(i + 1)})
+	}
+	fake.On(`SELECT id FROM article_tags WHERE id IN \(\?, \?\) ORDER BY id FOR UPDATE`, func([]driver.Value) testutil.Response {
+		return testutil.Response{Columns: []string{"id"}, Rows: rows}
+	})
+	fake.OnRows(`SELECT article_id FROM article_tag_relations WHERE tag_id = \? FOR UPDATE`, []string{"article_id"},
+		[]driver.Value{int64(10)}, []driver.Value{int64(11)}, []driver.Value{int64(12)})
+	fake.OnExec(`DELETE s FROM article_tag_relations s`, 0, 1)
+	fake.OnExec(`UPDATE article_tag_relations SET tag_id = \?`, 0, 2)
+	fake.OnRows(`SELECT COUNT\(\*\) FROM article_tag_relations WHERE tag_id = \?$`, []string{"count"}, []driver.Value{remaining})
+	fake.OnExec(`UPDATE article_tags SET article_count`, 0, 1)
+	fake.OnExec(`DELETE FROM article_tags WHERE id = \?`, 0, 1)
+	fake.OnRows(`SELECT id, name, slug, article_count, created_at FROM article_tags WHERE id = \?`,
+		[]string{"id", "name", "slug", "article_count", "created_at"},
+		[]driver.Value{int64(2), "Go", "go", int64(5), time.Now()})
+}
+
+func TestMergeTags(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	onTagMerge(fake, 2, 0)
+	repo := NewArticleRepository(db, config.Default())
+
+	result, err := repo.MergeTags(context.Background(), 1, 2)
+	if err != nil {
+		t.Fatalf("合并标签失败: %v", err)
+	}
+	if result.MovedRelations != 2 || result.DedupedRelations != 1 || !slices.Equal(result.ArticleIDs, []uint{10, 11, 12}) {
+		t.Fatalf("合并结果不正确: %+v", result)
+	}
+	if result.Target.ID != 2 || result.Target.ArticleCount != 5 {
+		t.Fatalf("应返回合并后的目标标签，实际 %+v", result.Target)
+	}
+
+	// 先删除会重复的关联，再改指向目标标签，最后重算数量并删除源标签，全部在一个事务内
+	steps := []string{`DELETE s FROM`, `UPDATE article_tag_relations`, `SET article_count`, `DELETE FROM article_tags`, `^COMMIT$`}
+	var order []string
+	for _, call := range fake.Calls(strings.Join(steps, "|")) {
+		for _, step := range steps {
+			if regexp.MustCompile(step).MatchString(call.Query) {
+				order = append(order, step)
+			}
+		}
+	}
+	if !slices.Equal(order, steps) {
+		t.Fatalf("合并语句顺序不正确，实际 %q", order)
+	}
+	if args := fake.Calls(`DELETE s FROM article_tag_relations s`)[0].Args; args[0] != int64(2) || args[1] != int64(1) {
+		t.Fatalf("应删除文章已有目标标签的源关联，实际参数 %v", args)
+	}
+	if args := fake.Calls(`UPDATE article_tags SET article_count`)[0].Args; args[0] != int64(2) || args[1] != int64(2) {
+		t.Fatalf("应按关联重算目标标签的文章数，实际参数 %v", args)
+	}
+	if args := fake.Calls(`DELETE FROM article_tags WHERE id = \?`)[0].Args; args[0] != int64(1) {
+		t.Fatalf("应删除源标签，实际参数 %v", args)
+	}
+}
+
+func TestMergeTagsRejected(t *testing.T) {
+	repo := NewArticleRepository(nil, config.Default())
+	if _, err := repo.MergeTags(context.Background(), 3, 3); !errors.Is(err, utils.ErrInvalidParameter) {
+		t.Fatalf("不能把标签合并到自身，实际 %v", err)
+	}
+
+	// 任一标签不存在
+	fake, db := newFakeDatabase(t)
+	onTagMerge(fake, 1, 0)
+	if _, err := NewArticleRepository(db, config.Default()).MergeTags(context.Background(), 1, 2); !errors.Is(err, utils.ErrResourceNotFound) {
+		t.Fatalf("标签不存在时应返回 ErrResourceNotFound，实际 %v", err)
+	}
+	if len(fake.Calls(`article_tag_relations`)) != 0 || len(fake.Calls(`^ROLLBACK$`)) != 1 {
+		t.Fatal("标签不存在时不应修改关联，事务应回滚")
+	}
+
+	// 改指向后仍有关联时不删除源标签，避免留下孤立的关联
+	fake, db = newFakeDatabase(t)
+	onTagMerge(fake, 2, 1)
+	if _, err := NewArticleRepository(db, config.Default()).MergeTags(context.Background(), 1, 2); !errors.Is(err, utils.ErrDatabaseUpdate) {
+		t.Fatalf("源标签仍有关联时应失败，实际 %v", err)
+	}
+	if len(fake.Calls(`DELETE FROM article_tags`)) != 0 || len(fake.Calls(`^ROLLBACK$`)) != 1 {
+		t.Fatal("源标签仍有关联时不应删除标签，事务应回滚")
+	}
+}
+
+func TestRenameTag(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	fake.OnExec(`UPDATE article_tags SET name = \?, slug = \?`, 0, 1)
+	fake.OnRows(`SELECT id, name, slug, article_count, created_at FROM article_tags WHERE id = \?`,
+		[]string{"id", "name", "slug", "article_count", "created_at"},
+		[]driver.Value{int64(4), "Go Lang", "go-lang", int64(3), time.Now()})
+	repo := NewArticleRepository(db, config.Default())
+
+	tag, err := repo.RenameTag(context.Background(), 4, "  Go Lang ")
+	if err != nil || tag.Slug != "go-lang" {
+		t.Fatalf("重命名标签失败: %+v %v", tag, err)
+	}
+	if args := fake.Calls(`UPDATE article_tags SET name`)[0].Args; args[0] != "Go Lang" || args[1] != "go-lang" || args[2] != int64(4) {
+		t.Fatalf("应按新名称重新生成slug，实际参数 %v", args)
+	}
+
+	if _, err := repo.RenameTag(context.Background(), 4, "   "); !errors.Is(err, utils.ErrInvalidParameter) {
+		t.Fatalf("空名称应被拒绝，实际 %v", err)
+	}
+
+	// 新slug与其他标签冲突时应改用合并
+	fake.OnError(`UPDATE article_tags SET name = \?, slug = \?`, &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'go' for key 'uk_slug'"})
+	if _, err := repo.RenameTag(context.Background(), 4, "Go"); !errors.Is(err, utils.ErrDuplicateEntry) {
+		t.Fatalf("slug冲突时应返回 ErrDuplicateEntry，实际 %v", err)
+	}
+
+	fake.OnExec(`UPDATE article_tags SET name = \?, slug = \?`, 0, 0)
+	fake.OnRows(`SELECT id, name, slug, article_count, created_at FROM article_tags WHERE id = \?`,
+		[]string{"id", "name", "slug", "article_count", "created_at"})
+	if _, err := repo.RenameTag(context.Background(), 99, "Rust"); !errors.Is(err, utils.ErrResourceNotFound) {
+		t.Fatalf("标签不存在时应返回 ErrResourceNotFound，实际 %v", err)
+	}
+}
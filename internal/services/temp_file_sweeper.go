@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/utils"
+)
+
+// TempFileSweeper temp-files桶过期对象定时清理器
+// 删除最后修改时间早于 auto_expire_hours 的对象（如上传后未被资源引用的预览图），
+// 对象路径包含进行中的分片上传ID时跳过，避免删除仍在使用的临时文件
+type TempFileSweeper struct {
+	db       *Database
+	storage  *MultiBucketStorage
+	interval time.Duration
+	expiry   time.Duration
+	logger   utils.Logger
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// NewTempFileSweeper 创建临时文件清理器
+func NewTempFileSweeper(db *Database, storage *MultiBucketStorage, cfg *config.Config) *TempFileSweeper {
+	return &TempFileSweeper{
+		db:       db,
+		storage:  storage,
+		interval: time.Duration(cfg.MinioAdvanced.TempSweepIntervalMinutes) * time.Minute,
+		expiry:   time.Duration(cfg.BucketTempFiles.AutoExpireHours) * time.Hour,
+		logger:   utils.GetLogger(),
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// Start 启动定时清理（未配置间隔或过期时间时直接返回）
+func (s *TempFileSweeper) Start() {
+	if s.interval <= 0 || s.expiry <= 0 {
+		s.logger.Info("临时文件清理未启用")
+		return
+	}
+
+	go s.run()
+	s.logger.Info("临时文件清理已启动", "interval", s.interval, "expiry", s.expiry)
+}
+
+// Stop 停止定时清理（正在执行的清理在当前对象处理完后退出）
+func (s *TempFileSweeper) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stopCh)
+	})
+}
+
+// run 定时清理循环
+func (s *TempFileSweeper) run() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := s.SweepOnce(context.Background(), time.Now().UTC()); err != nil {
+				s.logger.Warn("清理临时文件失败", "error", err.Error())
+			}
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// SweepOnce 执行一轮清理，返回删除的对象数
+func (s *TempFileSweeper) SweepOnce(ctx context.Context, now time.Time) (int, error) {
+	pending, err := s.pendingUploadIDs(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	objects, err := s.storage.ListObjects(ctx, BucketTypeTempFiles, "")
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := now.Add(-s.expiry)
+	deleted := 0
+	for _, obj := range objects {
+		select {
+		case <-s.stopCh:
+			return deleted, nil
+		default:
+		}
+
+		if !obj.LastModified.Before(cutoff) || referencesPendingUpload(obj.Key, pending) {
+			continue
+		}
+		if err := s.storage.RemoveObject(ctx, BucketTypeTempFiles, obj.Key); err != nil {
+			s.logger.Warn("删除过期临时文件失败", "key", obj.Key, "error", err.Error())
+			continue
+		}
+		deleted++
+	}
+
+	if deleted > 0 {
+		s.logger.Info("清理过期临时文件", "deleted", deleted, "cutoff", cutoff)
+	}
+	return deleted, nil
+}
+
+// pendingUploadIDs 查询进行中（未完成且未过期）的分片上传ID
+func (s *TempFileSweeper) pendingUploadIDs(ctx context.Context, now time.Time) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.db.GetQueryTimeout())
+	defer cancel()
+
+	rows, err := s.db.DB.QueryContext(ctx,
+		`SELECT upload_id FROM upload_chunks WHERE status IN (0, 4) AND expires_at > ?`, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// referencesPendingUpload 对象路径是否包含进行中的上传ID
+func referencesPendingUpload(key string, pendingIDs []string) bool {
+	for _, id := range pendingIDs {
+		if id != "" && strings.Contains(key, id) {
+			return true
+		}
+	}
+	return false
+}
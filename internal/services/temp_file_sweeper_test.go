@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/utils"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// fakeS3 本地模拟的S3服务：支持列举、上传和删除对象（对象只记录最后修改时间）
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]time.Time // "bucket/key" -> 最后修改时间
+	deleted []string
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	switch {
+	case r.URL.Query().Has("location"):
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/">us-east-1</LocationConstraint>`))
+	case r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2":
+		bucket := strings.TrimSuffix(path, "/")
+		prefix := bucket + "/" + r.URL.Query().Get("prefix")
+		type content struct {
+			Key          string
+			LastModified string
+			Size         int64
+		}
+		result := struct {
+			XMLName     xml.Name `xml:"ListBucketResult"`
+			Name        string
+			IsTruncated bool
+			Contents    []content
+		}{Name: bucket}
+		for key, modified := range f.objects {
+			if strings.HasPrefix(key, prefix) {
+				result.Contents = append(result.Contents, content{
+					Key: strings.TrimPrefix(key, bucket+"/"), LastModified: modified.Format(time.RFC3339), Size: 1,
+				})
+			}
+		}
+		sort.Slice(result.Contents, func(i, j int) bool { return result.Contents[i].Key < result.Contents[j].Key })
+		w.Header().Set("Content-Type", "application/xml")
+		xml.NewEncoder(w).Encode(result)
+	case r.Method == http.MethodPut:
+		f.objects[path] = time.Now().UTC()
+		w.Header().Set("ETag", `"etag"`)
+	case r.Method == http.MethodDelete:
+		delete(f.objects, path)
+		f.deleted = append(f.deleted, path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// newFakeS3Storage 连接本地模拟S3的多桶存储（跳过桶初始化），桶名与桶类型相同
+func newFakeS3Storage(t *testing.T, cfg *config.Config, objects map[string]time.Time) (*MultiBucketStorage, *fakeS3) {
+	t.Helper()
+	s3 := &fakeS3{objects: objects}
+	server := httptest.NewServer(s3)
+	t.Cleanup(server.Close)
+
+	client, err := minio.New(strings.TrimPrefix(server.URL, "http://"), &minio.Options{
+		Creds: credentials.NewStaticV4("key", "secret", ""),
+	})
+	if err != nil {
+		t.Fatalf("创建MinIO客户端失败: %v", err)
+	}
+	cfg.MinioAdvanced.MaxRetries = 0
+	buckets := map[BucketType]config.BucketConfig{}
+	for bucketType, bucketCfg := range map[BucketType]config.BucketConfig{
+		BucketTypeUserAvatars:      cfg.BucketUserAvatars,
+		BucketTypeResourcePreviews: cfg.BucketResourcePreviews,
+		BucketTypeTempFiles:        cfg.BucketTempFiles,
+	} {
+		bucketCfg.Name = string(bucketType)
+		buckets[bucketType] = bucketCfg
+	}
+	return &MultiBucketStorage{client: client, cfg: cfg, logger: utils.GetLogger(), buckets: buckets}, s3
+}
+
+func TestPutObjectQuota(t *testing.T) {
+	cfg := config.Default()
+	cfg.BucketResourcePreviews.MaxImagesPerResource = 2
+	cfg.BucketResourcePreviews.MaxImageSizeKB = 1
+	cfg.BucketUserAvatars.MaxAvatarSizeMB = 0
+	cfg.BucketUserAvatars.MaxHistory = 1
+	previews, avatars := string(BucketTypeResourcePreviews), string(BucketTypeUserAvatars)
+	now := time.Now().UTC()
+	storage, _ := newFakeS3Storage(t, cfg, map[string]time.Time{
+		previews + "/5/a.jpg": now, previews + "/5/a_thumb.jpg": now, previews + "/5/b.jpg": now,
+		avatars + "/alice/history/1.jpg": now, avatars + "/alice/history/2.jpg": now,
+	})
+	put := func(bucketType BucketType, path string, size int64) error {
+		_, err := storage.PutObject(context.Background(), bucketType, path, "image/jpeg", strings.NewReader(strings.Repeat("x", int(size))), size)
+		return err
+	}
+	quotaError := func(err error, code int) bool {
+		var appErr *utils.AppError
+		return errors.Is(err, utils.ErrStorageQuotaExceeded) && errors.As(err, &appErr) && appErr.Code == code
+	}
+
+	if err := put(BucketTypeResourcePreviews, "5/c.jpg", 10); !quotaError(err, 409) {
+		t.Fatalf("预览图数量达到上限时应返回409配额错误，实际 %v", err)
+	}
+	if err := put(BucketTypeResourcePreviews, "5/b.jpg", 10); err != nil {
+		t.Fatalf("覆盖已有对象不增加数量: %v", err)
+	}
+	if err := put(BucketTypeResourcePreviews, "5/b_thumb.jpg", 10); err != nil {
+		t.Fatalf("缩略图不计入预览图数量: %v", err)
+	}
+	if err := put(BucketTypeResourcePreviews, "6/a.jpg", 10); err != nil {
+		t.Fatalf("其他资源的预览图单独计数: %v", err)
+	}
+	if err := put(BucketTypeResourcePreviews, "6/b.jpg", 1025); !quotaError(err, 413) {
+		t.Fatalf("超过 max_image_size_kb 时应返回413配额错误，实际 %v", err)
+	}
+
+	// 头像历史允许比 max_history 多一个（归档后再由清理任务删除最旧的）
+	if err := put(BucketTypeUserAvatars, "alice/history/3.jpg", 10); !quotaError(err, 409) {
+		t.Fatalf("头像历史版本超过上限时应返回配额错误，实际 %v", err)
+	}
+	if err := put(BucketTypeUserAvatars, "bob/history/1.jpg", 10); err != nil {
+		t.Fatalf("其他用户的头像历史单独计数: %v", err)
+	}
+}
+
+func TestTempFileSweeperSkipsPendingUploads(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	fake.OnRows(`SELECT upload_id FROM upload_chunks WHERE .* AND expires_at > \?`, []string{"upload_id"},
+		[]driver.Value{"upload-1"})
+	cfg := config.Default()
+	cfg.BucketTempFiles.AutoExpireHours = 24
+	cfg.MinioAdvanced.TempSweepIntervalMinutes = 60
+	temp := string(BucketTypeTempFiles)
+	now := time.Now().UTC()
+	storage, s3 := newFakeS3Storage(t, cfg, map[string]time.Time{
+		temp + "/old.png":                now.Add(-48 * time.Hour),
+		temp + "/upload-1/preview.png":   now.Add(-48 * time.Hour),
+		temp + "/recent.png":             now.Add(-time.Hour),
+		temp + "/nested/dir/expired.txt": now.Add(-25 * time.Hour),
+	})
+
+	deleted, err := NewTempFileSweeper(db, storage, cfg).SweepOnce(context.Background(), now)
+	if err != nil {
+		t.Fatalf("清理临时文件失败: %v", err)
+	}
+	sort.Strings(s3.deleted)
+	if deleted != 2 || len(s3.deleted) != 2 || s3.deleted[0] != temp+"/nested/dir/expired.txt" || s3.deleted[1] != temp+"/old.png" {
+		t.Fatalf("只应删除过期且未被进行中的上传引用的对象，实际 %d %v", deleted, s3.deleted)
+	}
+	if call := fake.Calls(`FROM upload_chunks`)[0]; call.Args[0] != now {
+		t.Fatalf("进行中的上传应按当前时间判断是否过期: %v", call.Args)
+	}
+
+	// 查询进行中的上传失败时不删除任何对象
+	fake.OnError(`FROM upload_chunks`, errors.New("connection reset"))
+	if _, err := NewTempFileSweeper(db, storage, cfg).SweepOnce(context.Background(), now.Add(48*time.Hour)); err == nil || len(s3.deleted) != 2 {
+		t.Fatalf("无法确认进行中的上传时不应删除对象，实际 %v %v", err, s3.deleted)
+	}
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/utils"
+)
+
+// TokenBlacklist JWT吊销检查
+// 单个token退出登录时按 jti 加入内存黑名单（TTL为token剩余有效期，到期自动清理）；
+// 注销用户全部会话时递增 user_auth.token_version，版本低于当前值的token全部失效
+type TokenBlacklist struct {
+	revoked    *utils.LRUCache // jti -> struct{}
+	versions   *utils.LRUCache // userID -> 当前token版本（短期缓存，减少数据库查询）
+	versionTTL time.Duration
+	userRepo   *UserRepository
+	logger     utils.Logger
+}
+
+// NewTokenBlacklist 创建JWT吊销检查
+func NewTokenBlacklist(cfg *config.Config, userRepo *UserRepository) *TokenBlacklist {
+	versionTTL := time.Duration(cfg.JWTExtended.TokenVersionCacheSec) * time.Second
+	return &TokenBlacklist{
+		revoked: utils.NewLRUCache(utils.LRUCacheConfig{
+			Capacity: cfg.JWTExtended.BlacklistCapacity,
+		}),
+		versions: utils.NewLRUCache(utils.LRUCacheConfig{
+			Capacity:   cfg.LRUCacheDefaults.Capacity,
+			DefaultTTL: versionTTL,
+		}),
+		versionTTL: versionTTL,
+		userRepo:   userRepo,
+		logger:     utils.GetLogger(),
+	}
+}
+
+// Revoke 吊销单个token（已过期的token无需记录）
+func (b *TokenBlacklist) Revoke(jti string, expiresAt time.Time) {
+	if jti == "" {
+		return
+	}
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return
+	}
+	b.revoked.SetWithTTL(jti, struct{}{}, ttl)
+}
+
+// IsRevoked 检查token是否已被吊销
+func (b *TokenBlacklist) IsRevoked(jti string) bool {
+	if jti == "" {
+		return false
+	}
+	_, ok := b.revoked.Get(jti)
+	return ok
+}
+
+// CurrentVersion 获取用户当前的token版本（带短期缓存）
+func (b *TokenBlacklist) CurrentVersion(ctx context.Context, userID uint) (int, error) {
+	key := strconv.FormatUint(uint64(userID), 10)
+	if cached, ok := b.versions.Get(key); ok {
+		return cached.(int), nil
+	}
+
+	version, err := b.userRepo.GetTokenVersion(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	b.versions.SetWithTTL(key, version, b.versionTTL)
+	return version, nil
+}
+
+// RevokeAllForUser 注销用户的全部会话（递增token版本），返回新版本
+func (b *TokenBlacklist) RevokeAllForUser(ctx context.Context, userID uint) (int, error) {
+	version, err := b.userRepo.IncrementTokenVersion(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	// 立即更新本实例缓存，其他实例在缓存过期后生效
+	b.versions.SetWithTTL(strconv.FormatUint(uint64(userID), 10), version, b.versionTTL)
+	return version, nil
+}
+
+// ForgetVersion 清除本实例缓存的用户token版本（版本在其他流程中已变更时调用）
+func (b *TokenBlacklist) ForgetVersion(userID uint) {
+	b.versions.Delete(strconv.FormatUint(uint64(userID), 10))
+}
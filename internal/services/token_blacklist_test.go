@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/utils"
+)
+
+func TestTokenBlacklistRevokeExpiresWithToken(t *testing.T) {
+	_, db := newFakeDatabase(t)
+	blacklist := NewTokenBlacklist(config.Default(), NewUserRepository(db))
+
+	blacklist.Revoke("expired", time.Now().Add(-time.Minute))
+	if blacklist.IsRevoked("expired") {
+		t.Fatal("已过期的token无需加入黑名单")
+	}
+	if blacklist.IsRevoked("") {
+		t.Fatal("没有jti的token不应视为已吊销")
+	}
+
+	blacklist.Revoke("short", time.Now().Add(50*time.Millisecond))
+	if !blacklist.IsRevoked("short") {
+		t.Fatal("退出登录后token应在黑名单中")
+	}
+	time.Sleep(100 * time.Millisecond)
+	if blacklist.IsRevoked("short") {
+		t.Fatal("token原有效期结束后黑名单记录应自动清除")
+	}
+}
+
+func TestTokenBlacklistRevokeAllForUser(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	fake.OnExec(`UPDATE user_auth SET token_version = token_version \+ 1`, 0, 1)
+	fake.OnRows(`SELECT token_version FROM user_auth WHERE id = \?`, []string{"token_version"}, []driver.Value{int64(3)})
+	blacklist := NewTokenBlacklist(config.Default(), NewUserRepository(db))
+
+	version, err := blacklist.RevokeAllForUser(context.Background(), 5)
+	if err != nil || version != 3 {
+		t.Fatalf("应递增并返回新的token版本3，实际 %d %v", version, err)
+	}
+	if calls := fake.Calls(`UPDATE user_auth SET token_version`); len(calls) != 1 || calls[0].Args[1] != int64(5) {
+		t.Fatalf("应只递增该用户的token版本，实际 %v", calls)
+	}
+
+	// 本实例立即生效，无需再查询数据库
+	queries := len(fake.Calls(`SELECT token_version`))
+	if v, err := blacklist.CurrentVersion(context.Background(), 5); err != nil || v != 3 {
+		t.Fatalf("注销后本实例应立即使用新版本，实际 %d %v", v, err)
+	}
+	if len(fake.Calls(`SELECT token_version`)) != queries {
+		t.Fatal("新版本应已缓存")
+	}
+
+	fake.OnExec(`UPDATE user_auth SET token_version = token_version \+ 1`, 0, 0)
+	if _, err := blacklist.RevokeAllForUser(context.Background(), 42); !errors.Is(err, utils.ErrUserNotFound) {
+		t.Fatalf("用户不存在时应返回 ErrUserNotFound，实际 %v", err)
+	}
+}
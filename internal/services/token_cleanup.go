@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/utils"
+)
+
+// tokenCleanupTarget 单个令牌表的清理规则
+// where 中的 ? 都绑定同一个截止时间（当前时间减去宽限期），只匹配已过期/已使用/已吊销的行
+type tokenCleanupTarget struct {
+	table  string
+	where  string
+	params int
+	config config.TokenCleanupTableConfig
+}
+
+// TokenCleaner 过期令牌定时清理器
+// 按表分批删除失效超过宽限期的令牌，每批单独提交，避免长时间锁表
+type TokenCleaner struct {
+	db       *Database
+	config   config.TokenCleanupConfig
+	logger   utils.Logger
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// NewTokenCleaner 创建过期令牌清理器
+func NewTokenCleaner(db *Database, cfg *config.Config) *TokenCleaner {
+	return &TokenCleaner{
+		db:     db,
+		config: cfg.TokenCleanup,
+		logger: utils.GetLogger(),
+		stopCh: make(chan struct{}),
+	}
+}
+
+// targets 返回各令牌表的清理规则
+func (t *TokenCleaner) targets() []tokenCleanupTarget {
+	return []tokenCleanupTarget{
+		{
+			// 已使用的token没有使用时间，按创建时间判断
+			table:  "password_reset_tokens",
+			where:  "expires_at < ? OR (used = 1 AND created_at < ?)",
+			params: 2,
+			config: t.config.PasswordReset,
+		},
+		{
+			// 已轮换的token在过期前仍用于检测重用（重用时吊销整个家族），因此只按过期时间删除
+			table:  "refresh_tokens",
+			where:  "expires_at < ?",
+			params: 1,
+			config: t.config.RefreshTokens,
+		},
+		{
+			table:  "user_api_tokens",
+			where:  "(expires_at IS NOT NULL AND expires_at < ?) OR (revoked_at IS NOT NULL AND revoked_at < ?)",
+			params: 2,
+			config: t.config.APITokens,
+		},
+	}
+}
+
+// Start 启动定时清理（未启用时直接返回）
+func (t *TokenCleaner) Start() {
+	if !t.config.Enabled {
+		t.logger.Info("过期令牌清理未启用")
+		return
+	}
+
+	go t.run()
+	t.logger.Info("过期令牌清理已启动",
+		"interval", time.Duration(t.config.IntervalMinutes)*time.Minute,
+		"batchSize", t.config.BatchSize)
+}
+
+// Stop 停止定时清理（正在执行的清理在当前批次结束后退出）
+func (t *TokenCleaner) Stop() {
+	t.stopOnce.Do(func() {
+		close(t.stopCh)
+	})
+}
+
+// run 定时清理循环
+func (t *TokenCleaner) run() {
+	ticker := time.NewTicker(time.Duration(t.config.IntervalMinutes) * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			t.CleanupOnce(context.Background(), time.Now().UTC())
+		case <-t.stopCh:
+			return
+		}
+	}
+}
+
+// CleanupOnce 执行一轮清理，返回各表删除的行数
+// 单表失败只记录日志，不影响其他表
+func (t *TokenCleaner) CleanupOnce(ctx context.Context, now time.Time) map[string]int64 {
+	deleted := make(map[string]int64)
+	for _, target := range t.targets() {
+		if !target.config.Enabled {
+			continue
+		}
+		cutoff := now.Add(-time.Duration(target.config.GraceHours) * time.Hour)
+		count, err := t.cleanupTable(ctx, target, cutoff)
+		deleted[target.table] = count
+		if err != nil {
+			t.logger.Warn("清理过期令牌失败", "table", target.table, "deleted", count, "error", err.Error())
+			continue
+		}
+		if count > 0 {
+			t.logger.Info("清理过期令牌", "table", target.table, "deleted", count, "cutoff", cutoff)
+		}
+	}
+	return deleted
+}
+
+// cleanupTable 分批删除单个表中失效早于 cutoff 的令牌，直到不足一批或收到停止信号
+func (t *TokenCleaner) cleanupTable(ctx context.Context, target tokenCleanupTarget, cutoff time.Time) (int64, error) {
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s ORDER BY id LIMIT ?", target.table, target.where)
+	args := make([]interface{}, 0, target.params+1)
+	for i := 0; i < target.params; i++ {
+		args = append(args, cutoff)
+	}
+	args = append(args, t.config.BatchSize)
+
+	var total int64
+	for {
+		select {
+		case <-t.stopCh:
+			return total, nil
+		default:
+		}
+
+		batchCtx, cancel := context.WithTimeout(ctx, t.db.GetUpdateTimeout())
+		result, err := t.db.DB.ExecContext(batchCtx, query, args...)
+		cancel()
+		if err != nil {
+			return total, err
+		}
+		affected, _ := result.RowsAffected()
+		total += affected
+		if affected < int64(t.config.BatchSize) {
+			return total, nil
+		}
+	}
+}
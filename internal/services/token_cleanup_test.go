@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/testutil"
+)
+
+// tokenRow 令牌表中的一行（nil 时间表示 NULL）
+type tokenRow struct {
+	id        int64
+	used      bool
+	createdAt time.Time
+	expiresAt *time.Time
+	revokedAt *time.Time
+}
+
+// tokenTables 模拟各令牌表，按清理条件分批删除
+type tokenTables struct {
+	mu     sync.Mutex
+	tables map[string][]tokenRow
+}
+
+// onTokenCleanup 按表名解释清理语句：参数为截止时间（1或2个）和批大小
+func onTokenCleanup(fake *testutil.FakeDB, store *tokenTables) {
+	before := func(ts *time.Time, cutoff time.Time) bool { return ts != nil && ts.Before(cutoff) }
+	matches := map[string]func(tokenRow, time.Time) bool{
+		"password_reset_tokens": func(r tokenRow, cutoff time.Time) bool {
+			return before(r.expiresAt, cutoff) || (r.used && r.createdAt.Before(cutoff))
+		},
+		"refresh_tokens": func(r tokenRow, cutoff time.Time) bool { return before(r.expiresAt, cutoff) },
+		"user_api_tokens": func(r tokenRow, cutoff time.Time) bool {
+			return before(r.expiresAt, cutoff) || before(r.revokedAt, cutoff)
+		},
+	}
+	for table, match := range matches {
+		fake.On(`^DELETE FROM `+table+` WHERE .* ORDER BY id LIMIT \?$`, func(args []driver.Value) testutil.Response {
+			store.mu.Lock()
+			defer store.mu.Unlock()
+			cutoff, limit := args[0].(time.Time), args[len(args)-1].(int64)
+			rows := store.tables[table]
+			sort.Slice(rows, func(i, j int) bool { return rows[i].id < rows[j].id })
+			var kept []tokenRow
+			var deleted int64
+			for _, r := range rows {
+				if deleted < limit && match(r, cutoff) {
+					deleted++
+					continue
+				}
+				kept = append(kept, r)
+			}
+			store.tables[table] = kept
+			return testutil.Response{RowsAffected: deleted}
+		})
+	}
+}
+
+func (s *tokenTables) ids(table string) []int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ids []int64
+	for _, r := range s.tables[table] {
+		ids = append(ids, r.id)
+	}
+	return ids
+}
+
+func hoursAgo(now time.Time, h int) *time.Time {
+	ts := now.Add(-time.Duration(h) * time.Hour)
+	return &ts
+}
+
+func TestTokenCleanupRemovesOnlyExpiredPastGrace(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	now := time.Now().UTC()
+	old := now.Add(-48 * time.Hour)
+	store := &tokenTables{tables: map[string][]tokenRow{
+		"password_reset_tokens": {
+			{id: 1, createdAt: now, expiresAt: hoursAgo(now, -1)},                // 有效
+			{id: 2, createdAt: old, expiresAt: hoursAgo(now, 47)},                // 已过期超过宽限期
+			{id: 3, createdAt: now, expiresAt: hoursAgo(now, 2)},                 // 刚过期，仍在宽限期内
+			{id: 4, used: true, createdAt: old, expiresAt: hoursAgo(now, -1)},    // 已使用且超过宽限期
+			{id: 5, used: true, createdAt: now, expiresAt: hoursAgo(now, -1)},    // 刚使用
+			{id: 6, createdAt: old, expiresAt: hoursAgo(now, 30)},                // 已过期超过宽限期
+			{id: 7, used: true, createdAt: old.Add(-time.Hour), expiresAt: &old}, // 已使用且已过期
+		},
+		"refresh_tokens": {
+			{id: 1, createdAt: old, expiresAt: hoursAgo(now, -100)}, // 已轮换但未过期，保留用于重用检测
+			{id: 2, createdAt: old, expiresAt: hoursAgo(now, 25)},
+		},
+		"user_api_tokens": {
+			{id: 1, createdAt: old},                                    // 永不过期
+			{id: 2, createdAt: old, revokedAt: hoursAgo(now, 1000)},    // 已吊销超过宽限期
+			{id: 3, createdAt: old, revokedAt: hoursAgo(now, 1)},       // 刚吊销
+			{id: 4, createdAt: old, expiresAt: hoursAgo(now, 800)},     // 已过期超过宽限期
+			{id: 5, createdAt: old, expiresAt: hoursAgo(now, -24*365)}, // 有效
+		},
+	}}
+	onTokenCleanup(fake, store)
+	cfg := config.Default()
+	cfg.TokenCleanup.BatchSize = 2
+
+	deleted := NewTokenCleaner(db, cfg).CleanupOnce(context.Background(), now)
+	if deleted["password_reset_tokens"] != 4 || deleted["refresh_tokens"] != 1 || deleted["user_api_tokens"] != 2 {
+		t.Fatalf("各表删除的行数错误: %v", deleted)
+	}
+	for table, want := range map[string][]int64{
+		"password_reset_tokens": {1, 3, 5},
+		"refresh_tokens":        {1},
+		"user_api_tokens":       {1, 3, 5},
+	} {
+		if got := store.ids(table); !slices.Equal(got, want) {
+			t.Fatalf("%s 应保留 %v，实际 %v", table, want, got)
+		}
+	}
+
+	// 按批删除：4行需要3批（2+2+0），不足一批时停止
+	if calls := fake.Calls(`DELETE FROM password_reset_tokens`); len(calls) != 3 {
+		t.Fatalf("password_reset_tokens 应分3批删除，实际 %d 批", len(calls))
+	}
+	if calls := fake.Calls(`DELETE FROM refresh_tokens`); len(calls) != 1 {
+		t.Fatalf("refresh_tokens 不足一批时只应执行1次，实际 %d 次", len(calls))
+	}
+}
+
+func TestTokenCleanupPerTableConfig(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	now := time.Now().UTC()
+	store := &tokenTables{tables: map[string][]tokenRow{
+		"password_reset_tokens": {{id: 1, createdAt: now, expiresAt: hoursAgo(now, 2)}},
+		"refresh_tokens":        {{id: 1, createdAt: now, expiresAt: hoursAgo(now, 2)}},
+		"user_api_tokens":       {{id: 1, createdAt: now, expiresAt: hoursAgo(now, 2)}},
+	}}
+	onTokenCleanup(fake, store)
+	fake.OnError(`DELETE FROM refresh_tokens`, errors.New("lock wait timeout"))
+	cfg := config.Default()
+	cfg.TokenCleanup.PasswordReset.GraceHours = 1 // 宽限期按表配置
+	cfg.TokenCleanup.APITokens.Enabled = false
+
+	deleted := NewTokenCleaner(db, cfg).CleanupOnce(context.Background(), now)
+	if deleted["password_reset_tokens"] != 1 || len(store.ids("password_reset_tokens")) != 0 {
+		t.Fatalf("宽限期为1小时时应删除过期2小时的token，实际 %v", deleted)
+	}
+	if calls := fake.Calls(`DELETE FROM user_api_tokens`); len(calls) != 0 {
+		t.Fatal("未启用的表不应清理")
+	}
+	if _, ok := deleted["user_api_tokens"]; ok {
+		t.Fatal("未启用的表不应出现在结果中")
+	}
+	if len(store.ids("refresh_tokens")) != 1 {
+		t.Fatal("清理失败的表不应删除数据")
+	}
+}
+
+func TestTokenCleanerStopEndsCleanup(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	onTokenCleanup(fake, &tokenTables{tables: map[string][]tokenRow{}})
+	cleaner := NewTokenCleaner(db, config.Default())
+	cleaner.Stop()
+	cleaner.Stop() // 重复调用不会 panic
+
+	if deleted := cleaner.CleanupOnce(context.Background(), time.Now().UTC()); deleted["password_reset_tokens"] != 0 {
+		t.Fatalf("停止后不应再删除，实际 %v", deleted)
+	}
+	if calls := fake.Calls(`^DELETE`); len(calls) != 0 {
+		t.Fatalf("停止后不应执行删除，实际 %d 次", len(calls))
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -17,18 +18,20 @@ import (
 type UploadManager struct {
 	db          *Database
 	multiBucket *MultiBucketStorage // 多桶存储
+	scanner     *UploadScanService  // 病毒扫描（nil 表示未启用）
 	logger      utils.Logger
 	chunkSize   int
 	expireTime  time.Duration
 }
 
 // NewUploadManager 创建上传管理器（7桶架构）
-func NewUploadManager(db *Database, multiBucket *MultiBucketStorage, cfg *config.Config) *UploadManager {
+func NewUploadManager(db *Database, multiBucket *MultiBucketStorage, scanner *UploadScanService, cfg *config.Config) *UploadManager {
 	chunkSize := cfg.FileUpload.ChunkSizeMB * 1024 * 1024
 	expireTime := time.Duration(cfg.FileUpload.UploadExpireHours) * time.Hour
 	return &UploadManager{
 		db:          db,
 		multiBucket: multiBucket,
+		scanner:     scanner,
 		logger:      utils.GetLogger(),
 		chunkSize:   chunkSize,
 		expireTime:  expireTime,
@@ -159,19 +162,9 @@ func (m *UploadManager) InitUpload(ctx context.Context, userID uint, req models.
 }
 
 // UploadChunk 上传分片（7桶架构）
-func (m *UploadManager) UploadChunk(ctx context.Context, uploadID string, chunkIndex int, chunkData []byte) error {
-	// 7桶架构：上传到resource-chunks桶，路径：{upload_id}/chunk_{index}
-	objectKey := fmt.Sprintf("%s/chunk_%d", uploadID, chunkIndex)
-
-	// 将[]byte转换为io.Reader并上传到resource-chunks桶
-	reader := bytes.NewReader(chunkData)
-	_, err := m.multiBucket.PutObject(ctx, BucketTypeResourceChunks, objectKey, "application/octet-stream", reader, int64(len(chunkData)))
-	if err != nil {
-		m.logger.Error("保存分片失败", "uploadID", uploadID, "chunkIndex", chunkIndex, "error", err.Error())
-		return fmt.Errorf("上传失败，请检查网络连接")
-	}
-
-	// 使用事务和行锁更新上传记录，避免并发问题
+func (m *UploadManager) UploadChunk(ctx context.Context, uploadID string, userID uint, chunkIndex int, chunkData []byte) error {
+	// 使用事务和行锁更新上传记录，避免并发问题；
+	// 写入分片期间持有行锁，合并前必须先拿到行锁把状态改为合并校验中，因此开始合并后不会再有分片被写入或覆盖
 	tx, err := m.db.DB.BeginTx(ctx, nil)
 	if err != nil {
 		m.logger.Error("开启事务失败", "uploadID", uploadID, "error", err.Error())
@@ -179,16 +172,37 @@ func (m *UploadManager) UploadChunk(ctx context.Context, uploadID string, chunkI
 	}
 	defer tx.Rollback()
 
-	// 使用 FOR UPDATE 行锁防止并发更新冲突
-	var uploadedChunks []int
-	query := `SELECT uploaded_chunks FROM upload_chunks WHERE upload_id = ? FOR UPDATE`
+	var ownerID uint
+	var status int
 	var chunksJSON string
-	err = tx.QueryRowContext(ctx, query, uploadID).Scan(&chunksJSON)
+	query := `SELECT user_id, status, uploaded_chunks FROM upload_chunks WHERE upload_id = ? FOR UPDATE`
+	err = tx.QueryRowContext(ctx, query, uploadID).Scan(&ownerID, &status, &chunksJSON)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return utils.NewAppError(utils.ErrInvalidParameter, "上传任务不存在", 400)
+		}
 		m.logger.Error("查询上传记录失败", "uploadID", uploadID, "error", err.Error())
 		return fmt.Errorf("上传失败，请稍后重试")
 	}
+	if ownerID != userID {
+		return utils.ErrUnauthorized
+	}
+	if status != models.UploadStatusUploading {
+		return errUploadNotWritable
+	}
+
+	// 7桶架构：上传到resource-chunks桶，路径：{upload_id}/chunk_{index}
+	objectKey := fmt.Sprintf("%s/chunk_%d", uploadID, chunkIndex)
+
+	// 将[]byte转换为io.Reader并上传到resource-chunks桶
+	reader := bytes.NewReader(chunkData)
+	_, err = m.multiBucket.PutObject(ctx, BucketTypeResourceChunks, objectKey, "application/octet-stream", reader, int64(len(chunkData)))
+	if err != nil {
+		m.logger.Error("保存分片失败", "uploadID", uploadID, "chunkIndex", chunkIndex, "error", err.Error())
+		return fmt.Errorf("上传失败，请检查网络连接")
+	}
 
+	var uploadedChunks []int
 	if chunksJSON != "" {
 		_ = json.Unmarshal([]byte(chunksJSON), &uploadedChunks)
 	}
@@ -223,19 +237,57 @@ func (m *UploadManager) UploadChunk(ctx context.Context, uploadID string, chunkI
 	return nil
 }
 
+// errUploadNotWritable 上传任务已开始合并、已完成或已取消，不再接受分片
+var errUploadNotWritable = utils.NewAppError(utils.ErrInvalidParameter, "上传任务已结束，不能继续上传分片", 409)
+
 // MergeChunks 合并分片（真正实现文件合并）
-func (m *UploadManager) MergeChunks(ctx context.Context, uploadID string) (*models.MergeChunksResponse, error) {
+func (m *UploadManager) MergeChunks(ctx context.Context, uploadID string, userID uint) (*models.MergeChunksResponse, error) {
 	// 获取上传记录
 	var chunk models.UploadChunk
-	query := `SELECT user_id, file_name, file_size, total_chunks, uploaded_chunks FROM upload_chunks WHERE upload_id = ?`
-	var chunksJSON string
+	var storagePathValue sql.NullString
+	query := `SELECT user_id, file_name, file_size, total_chunks, status, storage_path FROM upload_chunks WHERE upload_id = ?`
 	err := m.db.DB.QueryRowContext(ctx, query, uploadID).Scan(
-		&chunk.UserID, &chunk.FileName, &chunk.FileSize, &chunk.TotalChunks, &chunksJSON,
+		&chunk.UserID, &chunk.FileName, &chunk.FileSize, &chunk.TotalChunks, &chunk.Status, &storagePathValue,
 	)
 	if err != nil {
 		m.logger.Error("查询上传记录失败", "uploadID", uploadID, "error", err.Error())
 		return nil, fmt.Errorf("文件信息查询失败，请稍后重试")
 	}
+	if chunk.UserID != userID {
+		return nil, utils.ErrUnauthorized
+	}
+
+	// 已完成的上传（哈希去重复用）已经扫描过，直接返回
+	if chunk.Status == models.UploadStatusCompleted && storagePathValue.String != "" {
+		return m.mergeResponse(storagePathValue.String, chunk.TotalChunks), nil
+	}
+	if chunk.Status != models.UploadStatusUploading {
+		return nil, errUploadNotWritable
+	}
+
+	// 把状态改为合并校验中：需要等待正在写入的分片释放行锁，之后的分片写入会被拒绝，
+	// 保证扫描的内容就是最终保存的内容
+	result, err := m.db.DB.ExecContext(ctx,
+		`UPDATE upload_chunks SET status = 4, updated_at = ? WHERE upload_id = ? AND status = 0`, time.Now().UTC(), uploadID)
+	if err != nil {
+		m.logger.Error("更新上传状态失败", "uploadID", uploadID, "error", err.Error())
+		return nil, fmt.Errorf("文件保存失败，请稍后重试")
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return nil, errUploadNotWritable
+	}
+
+	completed := false
+	defer func() {
+		if completed {
+			return
+		}
+		// 合并未完成（且未因扫描被拒绝）时恢复为上传中，允许补传分片后重试
+		if _, err := m.db.DB.ExecContext(context.WithoutCancel(ctx),
+			`UPDATE upload_chunks SET status = 0, updated_at = ? WHERE upload_id = ? AND status = 4`, time.Now().UTC(), uploadID); err != nil {
+			m.logger.Error("恢复上传状态失败", "uploadID", uploadID, "error", err.Error())
+		}
+	}()
 
 	// 检查是否所有分片都已上传（通过检查MinIO中的实际文件）
 	// 这样可以避免并发更新导致的数据库记录不准确问题
@@ -261,30 +313,103 @@ func (m *UploadManager) MergeChunks(ctx context.Context, uploadID string) (*mode
 
 	m.logger.Info("所有分片验证通过", "uploadID", uploadID, "totalChunks", chunk.TotalChunks)
 
+	// 标记完成前扫描文件，未通过时删除分片并拒绝
+	if err := m.scanChunks(ctx, uploadID, chunk.TotalChunks); err != nil {
+		return nil, err
+	}
+
 	// 7桶架构：不合并分片，直接保存分片信息，由前端下载时合并
 	// 存储路径就是upload_id（在resource-chunks桶中）
 	storagePath := uploadID
 
 	m.logger.Info("分片上传完成，保存分片信息", "uploadID", uploadID, "totalChunks", chunk.TotalChunks, "storagePath", storagePath)
 
-	// 更新状态为已完成，保存分片路径前缀
-	updateQuery := `UPDATE upload_chunks SET status = 1, storage_path = ?, updated_at = ? WHERE upload_id = ?`
-	_, err = m.db.DB.ExecContext(ctx, updateQuery, storagePath, time.Now().UTC(), uploadID)
+	// 更新状态为已完成，保存分片路径前缀（合并期间任务被重新初始化时不标记完成）
+	updateQuery := `UPDATE upload_chunks SET status = 1, storage_path = ?, updated_at = ? WHERE upload_id = ? AND status = 4`
+	result, err = m.db.DB.ExecContext(ctx, updateQuery, storagePath, time.Now().UTC(), uploadID)
 	if err != nil {
 		m.logger.Error("更新上传状态失败", "uploadID", uploadID, "error", err.Error())
 		return nil, fmt.Errorf("文件保存失败，请稍后重试")
 	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		m.logger.Warn("合并期间上传任务已被重置", "uploadID", uploadID)
+		return nil, fmt.Errorf("文件上传不完整，请重新上传")
+	}
+	completed = true
 
 	// 不再清理分片文件，保留用于下载
-	// 构建分片基础URL（前端下载时会拼接chunk_0, chunk_1等）
-	fileURL := fmt.Sprintf("%s/%s", m.multiBucket.GetPublicBaseURL(BucketTypeResourceChunks), uploadID)
+	response := m.mergeResponse(storagePath, chunk.TotalChunks)
+	m.logger.Info("分片信息保存成功", "uploadID", uploadID, "storagePath", storagePath, "fileURL", response.FileURL)
+	return response, nil
+}
 
-	m.logger.Info("分片信息保存成功", "uploadID", uploadID, "storagePath", storagePath, "fileURL", fileURL)
+// mergeResponse 构建合并结果（前端下载时用分片基础URL拼接chunk_0, chunk_1等）
+func (m *UploadManager) mergeResponse(storagePath string, totalChunks int) *models.MergeChunksResponse {
+	fileURL := fmt.Sprintf("%s/%s", m.multiBucket.GetPublicBaseURL(BucketTypeResourceChunks), storagePath)
 	return &models.MergeChunksResponse{
-		StoragePath: storagePath,       // 返回分片路径前缀（用于数据库保存）
-		FileURL:     fileURL,           // 返回分片基础URL（前端会用这个拼接下载）
-		TotalChunks: chunk.TotalChunks, // 返回总分片数（前端需要知道要下载多少个分片）
-	}, nil
+		StoragePath: storagePath, // 返回分片路径前缀（用于数据库保存）
+		FileURL:     fileURL,     // 返回分片基础URL（前端会用这个拼接下载）
+		TotalChunks: totalChunks, // 返回总分片数（前端需要知道要下载多少个分片）
+	}
+}
+
+// scanChunks 按顺序拼接所有分片扫描；检出病毒或超过扫描大小上限时删除分片并把上传标记为未通过安全扫描
+func (m *UploadManager) scanChunks(ctx context.Context, uploadID string, totalChunks int) error {
+	if !m.scanner.Enabled(BucketTypeResourceChunks) {
+		return nil
+	}
+
+	keys := make([]string, totalChunks)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("%s/chunk_%d", uploadID, i)
+	}
+	err := m.scanner.ScanObjects(ctx, BucketTypeResourceChunks, keys)
+	if !errors.Is(err, utils.ErrFileInfected) && !errors.Is(err, utils.ErrFileTooLargeToScan) {
+		return err
+	}
+
+	// 客户端可能已断开，清理不随请求取消
+	cleanupCtx := context.WithoutCancel(ctx)
+	for _, key := range keys {
+		if removeErr := m.multiBucket.RemoveObject(cleanupCtx, BucketTypeResourceChunks, key); removeErr != nil {
+			m.logger.Warn("删除未通过扫描的分片失败", "uploadID", uploadID, "object", key, "error", removeErr.Error())
+		}
+	}
+	if _, updateErr := m.db.DB.ExecContext(cleanupCtx,
+		`UPDATE upload_chunks SET status = 3, uploaded_chunks = '[]', storage_path = NULL, updated_at = ? WHERE upload_id = ?`,
+		time.Now().UTC(), uploadID); updateErr != nil {
+		m.logger.Error("标记上传未通过扫描失败", "uploadID", uploadID, "error", updateErr.Error())
+	}
+	return err
+}
+
+// EnsureUploadScanned 创建资源（或新版本）前确认分片上传属于当前用户，且已完成合并并通过扫描（未启用扫描时只校验归属）
+func (m *UploadManager) EnsureUploadScanned(ctx context.Context, uploadID string, userID uint) error {
+	var ownerID uint
+	var status int
+	err := m.db.DB.QueryRowContext(ctx, `SELECT user_id, status FROM upload_chunks WHERE upload_id = ?`, uploadID).Scan(&ownerID, &status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return utils.NewAppError(utils.ErrInvalidParameter, "上传任务不存在", 400)
+		}
+		m.logger.Error("查询上传记录失败", "uploadID", uploadID, "error", err.Error())
+		return utils.ErrDatabaseQuery
+	}
+	if ownerID != userID {
+		return utils.NewAppError(utils.ErrUnauthorized, "无权使用该上传文件", 403)
+	}
+	if !m.scanner.Enabled(BucketTypeResourceChunks) {
+		return nil
+	}
+
+	switch status {
+	case models.UploadStatusCompleted:
+		return nil
+	case models.UploadStatusRejected:
+		return utils.ErrFileInfected
+	default:
+		return utils.NewAppError(utils.ErrInvalidParameter, "文件尚未完成上传", 400)
+	}
 }
 
 // GetUploadStatus 查询上传进度
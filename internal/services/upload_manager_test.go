@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"gin/internal/config"
+	"gin/internal/models"
+	"gin/internal/utils"
+)
+
+// newTestUploadManager 创建上传管理器（不连接对象存储：被拒绝的写入在访问存储前返回）
+func newTestUploadManager(t *testing.T, scanner *UploadScanService) (*UploadManager, func(pattern string, columns []string, rows ...[]driver.Value), func(pattern string) int) {
+	t.Helper()
+	fake, db := newFakeDatabase(t)
+	fake.OnExec(`^(BEGIN|COMMIT|ROLLBACK)$`, 0, 0)
+	manager := NewUploadManager(db, nil, scanner, config.Default())
+	count := func(pattern string) int { return len(fake.Calls(pattern)) }
+	return manager, fake.OnRows, count
+}
+
+var uploadRowColumns = []string{"user_id", "status", "uploaded_chunks"}
+
+func TestUploadChunkRejectsUploadsThatAreNoLongerWritable(t *testing.T) {
+	for _, status := range []int{models.UploadStatusCompleted, models.UploadStatusCancelled, models.UploadStatusRejected, models.UploadStatusMerging} {
+		manager, onRows, count := newTestUploadManager(t, nil)
+		onRows(`SELECT user_id, status, uploaded_chunks FROM upload_chunks WHERE upload_id = \? FOR UPDATE`,
+			uploadRowColumns, []driver.Value{int64(7), int64(status), "[0]"})
+
+		err := manager.UploadChunk(context.Background(), "u1", 7, 1, []byte("data"))
+		if utils.GetHTTPStatusCode(err) != 409 {
+			t.Fatalf("状态 %d 的上传应拒绝写入分片(409)，实际: %v", status, err)
+		}
+		if n := count(`UPDATE upload_chunks`); n != 0 {
+			t.Fatalf("被拒绝的分片不应更新上传记录，实际更新 %d 次", n)
+		}
+	}
+}
+
+func TestUploadChunkRejectsOtherUsers(t *testing.T) {
+	manager, onRows, _ := newTestUploadManager(t, nil)
+	onRows(`FROM upload_chunks WHERE upload_id = \? FOR UPDATE`,
+		uploadRowColumns, []driver.Value{int64(7), int64(models.UploadStatusUploading), "[]"})
+
+	if err := manager.UploadChunk(context.Background(), "u1", 8, 0, []byte("data")); !errors.Is(err, utils.ErrUnauthorized) {
+		t.Fatalf("其他用户写入分片应返回 ErrUnauthorized，实际: %v", err)
+	}
+}
+
+func TestUploadChunkUnknownUpload(t *testing.T) {
+	manager, onRows, _ := newTestUploadManager(t, nil)
+	onRows(`FROM upload_chunks WHERE upload_id = \? FOR UPDATE`, uploadRowColumns)
+
+	if err := manager.UploadChunk(context.Background(), "missing", 7, 0, []byte("data")); utils.GetHTTPStatusCode(err) != 400 {
+		t.Fatalf("不存在的上传任务应返回400，实际: %v", err)
+	}
+}
+
+func TestMergeChunksRequiresOwnerAndUploadingStatus(t *testing.T) {
+	columns := []string{"user_id", "file_name", "file_size", "total_chunks", "status", "storage_path"}
+
+	manager, onRows, count := newTestUploadManager(t, nil)
+	onRows(`SELECT user_id, file_name, file_size, total_chunks, status, storage_path FROM upload_chunks`,
+		columns, []driver.Value{int64(7), "a.zip", int64(10), int64(1), int64(models.UploadStatusUploading), nil})
+	if _, err := manager.MergeChunks(context.Background(), "u1", 8); !errors.Is(err, utils.ErrUnauthorized) {
+		t.Fatalf("其他用户合并应返回 ErrUnauthorized，实际: %v", err)
+	}
+
+	onRows(`SELECT user_id, file_name, file_size, total_chunks, status, storage_path FROM upload_chunks`,
+		columns, []driver.Value{int64(7), "a.zip", int64(10), int64(1), int64(models.UploadStatusMerging), nil})
+	if _, err := manager.MergeChunks(context.Background(), "u1", 7); utils.GetHTTPStatusCode(err) != 409 {
+		t.Fatalf("正在合并的上传不能再次合并(409)，实际: %v", err)
+	}
+	if n := count(`UPDATE upload_chunks`); n != 0 {
+		t.Fatalf("被拒绝的合并不应修改上传状态，实际更新 %d 次", n)
+	}
+}
+
+func TestEnsureUploadScannedChecksOwner(t *testing.T) {
+	enabled := &UploadScanService{skipBuckets: map[BucketType]bool{}}
+	cases := []struct {
+		name    string
+		scanner *UploadScanService
+		owner   int64
+		status  int
+		want    func(error) bool
+	}{
+		{"未启用扫描时校验归属", nil, 8, models.UploadStatusCompleted, func(err error) bool { return utils.GetHTTPStatusCode(err) == 403 }},
+		{"未启用扫描时本人通过", nil, 7, models.UploadStatusUploading, func(err error) bool { return err == nil }},
+		{"启用扫描时校验归属", enabled, 8, models.UploadStatusCompleted, func(err error) bool { return utils.GetHTTPStatusCode(err) == 403 }},
+		{"已通过扫描", enabled, 7, models.UploadStatusCompleted, func(err error) bool { return err == nil }},
+		{"未通过扫描", enabled, 7, models.UploadStatusRejected, func(err error) bool { return errors.Is(err, utils.ErrFileInfected) }},
+		{"尚未合并", enabled, 7, models.UploadStatusMerging, func(err error) bool { return utils.GetHTTPStatusCode(err) == 400 }},
+	}
+	for _, tc := range cases {
+		manager, onRows, _ := newTestUploadManager(t, tc.scanner)
+		onRows(`SELECT user_id, status FROM upload_chunks WHERE upload_id = \?`,
+			[]string{"user_id", "status"}, []driver.Value{tc.owner, int64(tc.status)})
+		if err := manager.EnsureUploadScanned(context.Background(), "u1", 7); !tc.want(err) {
+			t.Errorf("%s: 结果不符合预期: %v", tc.name, err)
+		}
+	}
+}
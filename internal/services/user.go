@@ -52,6 +52,17 @@ func (s *UserService) UpsertUserProfile(ctx context.Context, profile *models.Use
 	return nil
 }
 
+// UpsertFullUserProfile 创建或更新全部扩展资料（头像除外）
+func (s *UserService) UpsertFullUserProfile(ctx context.Context, profile *models.UserExtraProfile) error {
+	err := s.userRepo.UpsertFullUserProfile(ctx, profile)
+	if err != nil {
+		s.logger.Error("更新用户完整资料失败", "userID", profile.UserID, "error", err.Error())
+		return err
+	}
+	s.logger.Info("更新用户完整资料成功", "userID", profile.UserID, "nickname", profile.Nickname)
+	return nil
+}
+
 // UpdateUserAvatar 更新用户头像URL
 func (s *UserService) UpdateUserAvatar(ctx context.Context, profile *models.UserExtraProfile) error {
 	err := s.userRepo.UpdateUserAvatar(ctx, profile)
@@ -62,3 +73,23 @@ func (s *UserService) UpdateUserAvatar(ctx context.Context, profile *models.User
 	s.logger.Info("更新用户头像成功", "userID", profile.UserID)
 	return nil
 }
+
+// GetFollowCounts 获取粉丝数和关注数
+func (s *UserService) GetFollowCounts(ctx context.Context, userID uint) (*models.FollowCounts, error) {
+	counts, err := s.userRepo.GetFollowCounts(ctx, userID)
+	if err != nil {
+		s.logger.Warn("获取关注统计失败", "userID", userID, "error", err.Error())
+		return nil, err
+	}
+	return counts, nil
+}
+
+// GetPublicProfile 获取用户公开主页信息
+func (s *UserService) GetPublicProfile(ctx context.Context, userID uint) (*models.PublicUserProfile, error) {
+	profile, err := s.userRepo.GetPublicProfile(ctx, userID)
+	if err != nil {
+		s.logger.Warn("获取用户公开资料失败", "userID", userID, "error", err.Error())
+		return nil, err
+	}
+	return profile, nil
+}
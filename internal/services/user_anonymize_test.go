@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/models"
+	"gin/internal/testutil"
+	"gin/internal/utils"
+)
+
+// anonymizeUserRow 内存中用户1的 user_auth 记录
+type anonymizeUserRow struct {
+	mu       sync.Mutex
+	username string
+	email    string
+	hash     string
+	status   int64
+}
+
+// newAnonymizeService 创建账号注销测试用的认证服务，user_auth 中只有用户1（alice，密码 Passw0rd!）
+func newAnonymizeService(t *testing.T) (*AuthService, *testutil.FakeDB, *anonymizeUserRow) {
+	t.Helper()
+	fake, db := newFakeDatabase(t)
+	hash, err := utils.HashPassword("Passw0rd!")
+	if err != nil {
+		t.Fatalf("生成密码哈希失败: %v", err)
+	}
+	row := &anonymizeUserRow{username: "alice", email: "alice@example.com", hash: hash, status: 1}
+	now := time.Now().UTC()
+
+	userRows := func(match func() bool) testutil.Response {
+		row.mu.Lock()
+		defer row.mu.Unlock()
+		resp := testutil.Response{Columns: []string{"id", "username", "password_hash", "email", "auth_status", "account_status",
+			"last_login_time", "last_login_ip", "failed_login_count", "locked_until", "token_version", "created_at", "updated_at"}}
+		if match() {
+			resp.Rows = [][]driver.Value{{int64(1), row.username, row.hash, row.email, int64(1), row.status,
+				nil, nil, int64(0), nil, int64(0), now, now}}
+		}
+		return resp
+	}
+	fake.On(`FROM user_auth WHERE username = \?`, func(args []driver.Value) testutil.Response {
+		return userRows(func() bool { return row.username == args[0] })
+	})
+	fake.On(`FROM user_auth WHERE id = \?`, func(args []driver.Value) testutil.Response {
+		return userRows(func() bool { return args[0] == int64(1) })
+	})
+	fake.On(`SELECT COUNT\(\*\) FROM user_auth WHERE email = \?`, func(args []driver.Value) testutil.Response {
+		row.mu.Lock()
+		defer row.mu.Unlock()
+		count := int64(0)
+		if row.email == args[0] {
+			count = 1
+		}
+		return testutil.Response{Columns: []string{"count"}, Rows: [][]driver.Value{{count}}}
+	})
+	fake.On(`SELECT email, account_status FROM user_auth WHERE id = \? FOR UPDATE`, func(args []driver.Value) testutil.Response {
+		row.mu.Lock()
+		defer row.mu.Unlock()
+		resp := testutil.Response{Columns: []string{"email", "account_status"}}
+		if args[0] == int64(1) {
+			resp.Rows = [][]driver.Value{{row.email, row.status}}
+		}
+		return resp
+	})
+	fake.On(`UPDATE user_auth SET username = \?, email = \?, password_hash = ''`, func(args []driver.Value) testutil.Response {
+		row.mu.Lock()
+		defer row.mu.Unlock()
+		row.username, row.email, row.hash, row.status = args[0].(string), args[1].(string), "", args[2].(int64)
+		return testutil.Response{RowsAffected: 1}
+	})
+	fake.OnExec(`INSERT INTO user_profile`, 0, 1)
+	fake.OnExec(`DELETE FROM password_reset_tokens`, 0, 0)
+	fake.OnExec(`DELETE FROM refresh_tokens WHERE user_id = \?`, 0, 1)
+	fake.OnExec(`UPDATE user_api_tokens SET revoked_at`, 0, 1)
+
+	cfg := config.Default()
+	cfg.JWT.SecretKey = "test-secret-key"
+	userRepo := NewUserRepository(db)
+	svc := NewAuthService(cfg, userRepo, nil, NewRefreshTokenRepository(db, cfg), NewTokenBlacklist(cfg, userRepo), nil)
+	return svc, fake, row
+}
+
+func TestDeleteAccountAnonymizesUser(t *testing.T) {
+	svc, fake, row := newAnonymizeService(t)
+	ctx := context.Background()
+
+	if err := svc.DeleteAccount(ctx, 1, "wrong", "127.0.0.1"); !errors.Is(err, utils.ErrInvalidCredentials) {
+		t.Fatalf("密码错误应返回 ErrInvalidCredentials，实际 %v", err)
+	}
+	if len(fake.Calls(`UPDATE user_auth SET username`)) != 0 {
+		t.Fatal("密码错误时不应注销账号")
+	}
+
+	if err := svc.DeleteAccount(ctx, 1, "Passw0rd!", "127.0.0.1"); err != nil {
+		t.Fatalf("注销账号失败: %v", err)
+	}
+	if row.username != "deleted_1" || row.status != models.AccountStatusDeleted || row.hash != "" {
+		t.Fatalf("用户名应替换为占位值并标记为已注销，实际 %+v", row)
+	}
+	if strings.Contains(row.email, "alice") || !strings.HasSuffix(row.email, "@deleted.invalid") {
+		t.Fatalf("邮箱应替换为不含原信息的占位值，实际 %s", row.email)
+	}
+
+	profile := fake.Calls(`INSERT INTO user_profile`)
+	if len(profile) != 1 || profile[0].Args[1] != models.DeletedUserNickname ||
+		!strings.Contains(profile[0].Query, "avatar_url = NULL") || !strings.Contains(profile[0].Query, "website = NULL") {
+		t.Fatalf("资料应匿名化为\"已注销用户\"并清除头像、简介和链接，实际 %v", profile)
+	}
+	if len(fake.Calls(`DELETE FROM refresh_tokens`)) != 1 || len(fake.Calls(`UPDATE user_api_tokens SET revoked_at`)) != 1 {
+		t.Fatal("应删除刷新token并吊销API令牌")
+	}
+
+	// 原用户名无法再登录，原邮箱可重新注册
+	if _, err := svc.Login(ctx, "alice", "Passw0rd!", "127.0.0.1", "", ""); !errors.Is(err, utils.ErrInvalidCredentials) {
+		t.Fatalf("注销后无法使用原用户名登录，实际 %v", err)
+	}
+	if _, err := svc.Login(ctx, "deleted_1", "", "127.0.0.1", "", ""); err == nil {
+		t.Fatal("注销后的占位账号不能登录")
+	}
+	if exists, err := svc.userRepo.CheckEmailExists(ctx, "alice@example.com"); err != nil || exists {
+		t.Fatalf("注销后原邮箱应可重新注册，实际 %v %v", exists, err)
+	}
+}
+
+func TestAnonymizeUserIdempotentAndMissingUser(t *testing.T) {
+	svc, fake, _ := newAnonymizeService(t)
+	repo := svc.userRepo
+	ctx := context.Background()
+
+	if err := repo.AnonymizeUser(ctx, 1); err != nil {
+		t.Fatalf("注销账号失败: %v", err)
+	}
+	if err := repo.AnonymizeUser(ctx, 1); err != nil {
+		t.Fatalf("重复注销应成功，实际 %v", err)
+	}
+	if calls := fake.Calls(`UPDATE user_auth SET username`); len(calls) != 1 {
+		t.Fatalf("已注销的账号不应重复匿名化，实际 %d 次", len(calls))
+	}
+
+	if err := repo.AnonymizeUser(ctx, 42); !errors.Is(err, utils.ErrUserNotFound) {
+		t.Fatalf("用户不存在时应返回 ErrUserNotFound，实际 %v", err)
+	}
+}
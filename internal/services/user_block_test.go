@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"gin/internal/config"
+	"gin/internal/models"
+	"gin/internal/testutil"
+	"gin/internal/utils"
+)
+
+// onUserBlocks 用内存表模拟 user_blocks（INSERT IGNORE 幂等）；user_auth 中只有 existing 里的用户
+func onUserBlocks(fake *testutil.FakeDB, existing ...int64) {
+	var mu sync.Mutex
+	blocks := make(map[[2]int64]bool)
+
+	fake.On(`SELECT COUNT\(\*\) FROM user_auth WHERE id = \?`, func(args []driver.Value) testutil.Response {
+		count := int64(0)
+		for _, id := range existing {
+			if id == args[0].(int64) {
+				count = 1
+			}
+		}
+		return testutil.Response{Columns: []string{"count"}, Rows: [][]driver.Value{{count}}}
+	})
+	fake.On(`INSERT IGNORE INTO user_blocks`, func(args []driver.Value) testutil.Response {
+		mu.Lock()
+		defer mu.Unlock()
+		key := [2]int64{args[0].(int64), args[1].(int64)}
+		if blocks[key] {
+			return testutil.Response{}
+		}
+		blocks[key] = true
+		return testutil.Response{RowsAffected: 1}
+	})
+	fake.On(`DELETE FROM user_blocks`, func(args []driver.Value) testutil.Response {
+		mu.Lock()
+		defer mu.Unlock()
+		key := [2]int64{args[0].(int64), args[1].(int64)}
+		if !blocks[key] {
+			return testutil.Response{}
+		}
+		delete(blocks, key)
+		return testutil.Response{RowsAffected: 1}
+	})
+	fake.On(`SELECT blocked_id FROM user_blocks WHERE blocker_id = \?`, func(args []driver.Value) testutil.Response {
+		mu.Lock()
+		defer mu.Unlock()
+		resp := testutil.Response{Columns: []string{"blocked_id"}}
+		for key := range blocks {
+			if key[0] == args[0].(int64) {
+				resp.Rows = append(resp.Rows, []driver.Value{key[1]})
+			}
+		}
+		return resp
+	})
+}
+
+func TestBlockUserIdempotent(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	onUserBlocks(fake, 1, 2)
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := repo.BlockUser(ctx, 1, 2); err != nil {
+			t.Fatalf("第 %d 次屏蔽应成功（幂等），实际 %v", i+1, err)
+		}
+	}
+	ids, err := repo.GetBlockedIDs(ctx, 1)
+	if err != nil || len(ids) != 1 || ids[0] != 2 {
+		t.Fatalf("重复屏蔽后屏蔽列表应只有 [2]，实际 %v %v", ids, err)
+	}
+	if ids, _ := repo.GetBlockedIDs(ctx, 2); len(ids) != 0 {
+		t.Fatalf("屏蔽关系是单向的，被屏蔽者的列表应为空，实际 %v", ids)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := repo.UnblockUser(ctx, 1, 2); err != nil {
+			t.Fatalf("第 %d 次取消屏蔽应成功（幂等），实际 %v", i+1, err)
+		}
+	}
+	if ids, _ := repo.GetBlockedIDs(ctx, 1); len(ids) != 0 {
+		t.Fatalf("取消屏蔽后列表应为空，实际 %v", ids)
+	}
+}
+
+func TestBlockUserRejectsSelfAndMissingUser(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	onUserBlocks(fake, 1)
+	repo := NewUserRepository(db)
+
+	err := repo.BlockUser(context.Background(), 1, 1)
+	var appErr *utils.AppError
+	if !errors.As(err, &appErr) || appErr.Code != 400 {
+		t.Fatalf("屏蔽自己应返回400，实际 %v", err)
+	}
+	if calls := fake.Calls(`user_blocks`); len(calls) != 0 {
+		t.Fatalf("屏蔽自己不应写入数据库，实际 %v", calls)
+	}
+
+	if err := repo.BlockUser(context.Background(), 1, 99); !errors.Is(err, utils.ErrUserNotFound) {
+		t.Fatalf("屏蔽不存在的用户应返回 ErrUserNotFound，实际 %v", err)
+	}
+}
+
+func TestListArticlesFiltersBlockedAuthorsForViewerOnly(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	fake.OnRows(`FROM articles a INNER JOIN user_auth ua`, []string{"id"})
+	fake.OnRows(`SELECT COUNT\(\*\) FROM articles a`, []string{"count"}, []driver.Value{int64(0)})
+	repo := NewArticleRepository(db, config.Default())
+
+	if _, err := repo.ListArticles(context.Background(), models.ArticleListQuery{ViewerID: 3}); err != nil {
+		t.Fatalf("查询文章列表失败: %v", err)
+	}
+	for _, call := range fake.Calls(`FROM articles a`) {
+		if !strings.Contains(call.Query, "NOT EXISTS (SELECT 1 FROM user_blocks ub WHERE ub.blocker_id = ? AND ub.blocked_id = a.user_id)") {
+			t.Fatalf("登录用户的列表查询应过滤其屏蔽的作者: %s", call.Query)
+		}
+		if !containsArg(call.Args, int64(3)) {
+			t.Fatalf("屏蔽过滤应使用当前用户ID，实际参数 %v", call.Args)
+		}
+	}
+
+	before := len(fake.Calls(""))
+	if _, err := repo.ListArticles(context.Background(), models.ArticleListQuery{}); err != nil {
+		t.Fatalf("查询文章列表失败: %v", err)
+	}
+	for _, call := range fake.Calls("")[before:] {
+		if strings.Contains(call.Query, "user_blocks") {
+			t.Fatalf("未登录时不应按屏蔽关系过滤: %s", call.Query)
+		}
+	}
+}
+
+func TestGetCommentsFiltersBlockedAuthors(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	fake.OnRows(`WHERE ac.article_id = \? AND ac.parent_id = 0`, []string{"id"})
+	fake.OnRows(`SELECT COUNT\(\*\) FROM article_comments`, []string{"count"}, []driver.Value{int64(0)})
+
+	if _, err := NewArticleRepository(db, config.Default()).GetComments(context.Background(), 5, 1, 20, 3, ""); err != nil {
+		t.Fatalf("获取评论失败: %v", err)
+	}
+	calls := fake.Calls(`FROM article_comments ac`)
+	if len(calls) == 0 {
+		t.Fatal("应查询评论")
+	}
+	for _, call := range calls {
+		if !strings.Contains(call.Query, "ub.blocker_id = ? AND ub.blocked_id = ac.user_id") || !containsArg(call.Args, int64(3)) {
+			t.Fatalf("评论查询应过滤当前用户屏蔽的作者: %s %v", call.Query, call.Args)
+		}
+	}
+}
+
+// containsArg 判断SQL参数中是否包含 v
+func containsArg(args []driver.Value, v driver.Value) bool {
+	for _, a := range args {
+		if a == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"gin/internal/config"
+	"gin/internal/models"
+	"gin/internal/testutil"
+	"gin/internal/utils"
+)
+
+// onUserFollows 用内存表模拟 user_follows（INSERT IGNORE 幂等），user_auth 中有 1~9 号用户
+func onUserFollows(fake *testutil.FakeDB) {
+	var mu sync.Mutex
+	follows := make(map[[2]int64]bool)
+
+	fake.On(`SELECT COUNT\(\*\) FROM user_auth WHERE id = \?`, func(args []driver.Value) testutil.Response {
+		count := int64(0)
+		if id := args[0].(int64); id >= 1 && id <= 9 {
+			count = 1
+		}
+		return testutil.Response{Columns: []string{"count"}, Rows: [][]driver.Value{{count}}}
+	})
+	fake.On(`INSERT IGNORE INTO user_follows`, func(args []driver.Value) testutil.Response {
+		mu.Lock()
+		defer mu.Unlock()
+		follows[[2]int64{args[0].(int64), args[1].(int64)}] = true
+		return testutil.Response{RowsAffected: 1}
+	})
+	fake.On(`DELETE FROM user_follows`, func(args []driver.Value) testutil.Response {
+		mu.Lock()
+		defer mu.Unlock()
+		delete(follows, [2]int64{args[0].(int64), args[1].(int64)})
+		return testutil.Response{RowsAffected: 1}
+	})
+	fake.On(`SELECT \(SELECT COUNT\(\*\) FROM user_follows WHERE following_id = \?\)`, func(args []driver.Value) testutil.Response {
+		mu.Lock()
+		defer mu.Unlock()
+		var followers, following int64
+		for key := range follows {
+			if key[1] == args[0].(int64) {
+				followers++
+			}
+			if key[0] == args[1].(int64) {
+				following++
+			}
+		}
+		return testutil.Response{Columns: []string{"followers", "following"}, Rows: [][]driver.Value{{followers, following}}}
+	})
+}
+
+func TestFollowIdempotentAndCounts(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	onUserFollows(fake)
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := repo.Follow(ctx, 1, 2); err != nil {
+			t.Fatalf("第 %d 次关注应成功（幂等），实际 %v", i+1, err)
+		}
+	}
+	if err := repo.Follow(ctx, 3, 2); err != nil {
+		t.Fatalf("关注失败: %v", err)
+	}
+
+	counts, err := repo.GetFollowCounts(ctx, 2)
+	if err != nil || counts.FollowersCount != 2 || counts.FollowingCount != 0 {
+		t.Fatalf("用户2应有2个粉丝、0个关注，实际 %+v %v", counts, err)
+	}
+	if counts, _ := repo.GetFollowCounts(ctx, 1); counts.FollowersCount != 0 || counts.FollowingCount != 1 {
+		t.Fatalf("重复关注只计一次，用户1应关注1人，实际 %+v", counts)
+	}
+
+	if err := repo.Unfollow(ctx, 1, 2); err != nil {
+		t.Fatalf("取消关注失败: %v", err)
+	}
+	if err := repo.Unfollow(ctx, 1, 2); err != nil {
+		t.Fatalf("重复取消关注应成功，实际 %v", err)
+	}
+	if counts, _ := repo.GetFollowCounts(ctx, 2); counts.FollowersCount != 1 {
+		t.Fatalf("取消关注后用户2应剩1个粉丝，实际 %+v", counts)
+	}
+}
+
+func TestFollowRejectsSelfAndMissingUser(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	onUserFollows(fake)
+	repo := NewUserRepository(db)
+
+	err := repo.Follow(context.Background(), 4, 4)
+	var appErr *utils.AppError
+	if !errors.As(err, &appErr) || appErr.Code != 400 {
+		t.Fatalf("关注自己应返回400，实际 %v", err)
+	}
+	if calls := fake.Calls(""); len(calls) != 0 {
+		t.Fatalf("关注自己不应访问数据库，实际 %v", calls)
+	}
+
+	if err := repo.Follow(context.Background(), 4, 42); !errors.Is(err, utils.ErrUserNotFound) {
+		t.Fatalf("关注不存在的用户应返回 ErrUserNotFound，实际 %v", err)
+	}
+}
+
+func TestGetFollowingIDsCapsAuthorCount(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	fake.OnRows(`SELECT following_id FROM user_follows WHERE follower_id = \? ORDER BY created_at DESC LIMIT \?`,
+		[]string{"following_id"}, []driver.Value{int64(5)}, []driver.Value{int64(6)})
+
+	ids, err := NewUserRepository(db).GetFollowingIDs(context.Background(), 1, 500)
+	if err != nil || len(ids) != 2 || ids[0] != 5 || ids[1] != 6 {
+		t.Fatalf("应返回关注的用户ID [5 6]，实际 %v %v", ids, err)
+	}
+	if args := fake.Calls(`FROM user_follows`)[0].Args; args[1] != int64(500) {
+		t.Fatalf("应按配置上限限制关注用户数，实际参数 %v", args)
+	}
+}
+
+func TestFollowingFeed(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	fake.OnRows(`FROM articles a INNER JOIN user_auth ua`, []string{"id"})
+	fake.OnRows(`SELECT COUNT\(\*\) FROM articles a`, []string{"count"}, []driver.Value{int64(0)})
+	repo := NewArticleRepository(db, config.Default())
+
+	// 没有关注任何人时返回空列表，而不是全部文章
+	resp, err := repo.ListArticles(context.Background(), models.ArticleListQuery{Feed: models.ArticleFeedFollowing, ViewerID: 1})
+	if err != nil {
+		t.Fatalf("查询关注流失败: %v", err)
+	}
+	if len(resp.Articles) != 0 || resp.Total != 0 {
+		t.Fatalf("未关注任何人时应返回空列表，实际 %+v", resp)
+	}
+	if calls := fake.Calls(`FROM articles`); len(calls) != 0 {
+		t.Fatalf("未关注任何人时不应查询文章，实际 %v", calls)
+	}
+
+	if _, err := repo.ListArticles(context.Background(), models.ArticleListQuery{
+		Feed: models.ArticleFeedFollowing, ViewerID: 1, AuthorIDs: []uint{5, 6},
+	}); err != nil {
+		t.Fatalf("查询关注流失败: %v", err)
+	}
+	calls := fake.Calls(`FROM articles a`)
+	if len(calls) == 0 {
+		t.Fatal("应查询文章")
+	}
+	for _, call := range calls {
+		if !strings.Contains(call.Query, "a.user_id IN (?,?)") || !containsArg(call.Args, int64(5)) || !containsArg(call.Args, int64(6)) {
+			t.Fatalf("关注流应只查询关注用户的文章: %s %v", call.Query, call.Args)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -63,13 +64,17 @@ func (r *UserRepository) CreateUser(ctx context.Context, user *models.User) erro
 		r.logger.Error("创建用户失败",
 			"username", user.Username,
 			"error", err.Error())
-		return utils.ErrDatabaseInsert
+		// 并发注册时可能在存在性检查之后才发生唯一键冲突
+		if isDuplicateKeyError(err) {
+			return utils.NewAppError(utils.ErrUserAlreadyExists, "用户名或邮箱已被使用", 409)
+		}
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseInsert, err)
 	}
 
 	id, err := result.LastInsertId()
 	if err != nil {
 		r.logger.Error("获取用户ID失败", "username", user.Username, "error", err.Error())
-		return utils.ErrDatabaseInsert
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseInsert, err)
 	}
 
 	user.ID = uint(id)
@@ -83,7 +88,7 @@ func (r *UserRepository) CreateUser(ctx context.Context, user *models.User) erro
 // GetUserByUsername 根据用户名获取用户
 func (r *UserRepository) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
 	query := `SELECT id, username, password_hash, email, auth_status, account_status, 
-			  last_login_time, last_login_ip, failed_login_count, created_at, updated_at 
+			  last_login_time, last_login_ip, failed_login_count, locked_until, token_version, created_at, updated_at 
 			  FROM user_auth WHERE username = ?`
 
 	ctx, cancel := context.WithTimeout(ctx, r.db.GetQueryTimeout())
@@ -100,6 +105,8 @@ func (r *UserRepository) GetUserByUsername(ctx context.Context, username string)
 		&user.LastLoginTime,
 		&user.LastLoginIP,
 		&user.FailedLoginCount,
+		&user.LockedUntil,
+		&user.TokenVersion,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -109,7 +116,7 @@ func (r *UserRepository) GetUserByUsername(ctx context.Context, username string)
 			return nil, utils.ErrUserNotFound
 		}
 		r.logger.Error("查询用户失败", "username", username, "error", err.Error())
-		return nil, utils.ErrDatabaseQuery
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
 	}
 
 	return user, nil
@@ -118,7 +125,7 @@ func (r *UserRepository) GetUserByUsername(ctx context.Context, username string)
 // GetUserByEmail 根据邮箱获取用户
 func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
 	query := `SELECT id, username, password_hash, email, auth_status, account_status, 
-			  last_login_time, last_login_ip, failed_login_count, created_at, updated_at 
+			  last_login_time, last_login_ip, failed_login_count, locked_until, token_version, created_at, updated_at 
 			  FROM user_auth WHERE email = ?`
 
 	ctx, cancel := context.WithTimeout(ctx, r.db.GetQueryTimeout())
@@ -135,6 +142,8 @@ func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*mod
 		&user.LastLoginTime,
 		&user.LastLoginIP,
 		&user.FailedLoginCount,
+		&user.LockedUntil,
+		&user.TokenVersion,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -144,7 +153,7 @@ func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*mod
 			return nil, utils.ErrUserNotFound
 		}
 		r.logger.Error("查询用户失败", "email", email, "error", err.Error())
-		return nil, utils.ErrDatabaseQuery
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
 	}
 
 	return user, nil
@@ -153,7 +162,7 @@ func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*mod
 // GetUserByID 根据ID获取用户
 func (r *UserRepository) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
 	query := `SELECT id, username, password_hash, email, auth_status, account_status, 
-			  last_login_time, last_login_ip, failed_login_count, created_at, updated_at 
+			  last_login_time, last_login_ip, failed_login_count, locked_until, token_version, created_at, updated_at 
 			  FROM user_auth WHERE id = ?`
 
 	ctx, cancel := context.WithTimeout(ctx, r.db.GetQueryTimeout())
@@ -170,6 +179,8 @@ func (r *UserRepository) GetUserByID(ctx context.Context, id uint) (*models.User
 		&user.LastLoginTime,
 		&user.LastLoginIP,
 		&user.FailedLoginCount,
+		&user.LockedUntil,
+		&user.TokenVersion,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -179,7 +190,7 @@ func (r *UserRepository) GetUserByID(ctx context.Context, id uint) (*models.User
 			return nil, utils.ErrUserNotFound
 		}
 		r.logger.Error("查询用户失败", "userID", id, "error", err.Error())
-		return nil, utils.ErrDatabaseQuery
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
 	}
 
 	return user, nil
@@ -207,13 +218,13 @@ func (r *UserRepository) UpdateUser(ctx context.Context, user *models.User) erro
 
 	if err != nil {
 		r.logger.Error("更新用户失败", "userID", user.ID, "error", err.Error())
-		return utils.ErrDatabaseUpdate
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		r.logger.Error("获取更新行数失败", "userID", user.ID, "error", err.Error())
-		return utils.ErrDatabaseUpdate
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
 	}
 
 	if rowsAffected == 0 {
@@ -227,19 +238,29 @@ func (r *UserRepository) UpdateUser(ctx context.Context, user *models.User) erro
 
 // GetUserProfile 读取扩展资料
 func (r *UserRepository) GetUserProfile(ctx context.Context, userID uint) (*models.UserExtraProfile, error) {
-	query := `SELECT user_id, nickname, bio, avatar_url, created_at, updated_at FROM user_profile WHERE user_id = ?`
+	query := `SELECT user_id, nickname, bio, avatar_url, phone, gender, birthday, province, city, website, github, created_at, updated_at
+			  FROM user_profile WHERE user_id = ?`
 
 	ctx, cancel := context.WithTimeout(ctx, r.db.GetQueryTimeout())
 	defer cancel()
 
 	prof := &models.UserExtraProfile{}
-	var nickname, bio, avatarURL sql.NullString
+	var nickname, bio, avatarURL, phone, province, city, website, github sql.NullString
+	var gender sql.NullInt64
+	var birthday sql.NullTime
 
 	err := r.db.QueryRowWithCache(ctx, query, userID).Scan(
 		&prof.UserID,
 		&nickname,
 		&bio,
 		&avatarURL,
+		&phone,
+		&gender,
+		&birthday,
+		&province,
+		&city,
+		&website,
+		&github,
 		&prof.CreatedAt,
 		&prof.UpdatedAt,
 	)
@@ -249,34 +270,52 @@ func (r *UserRepository) GetUserProfile(ctx context.Context, userID uint) (*mode
 			return &models.UserExtraProfile{UserID: userID}, nil
 		}
 		r.logger.Error("查询用户扩展资料失败", "userID", userID, "error", err.Error())
-		return nil, utils.ErrDatabaseQuery
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
 	}
 
 	prof.Nickname = nickname.String
 	prof.Bio = bio.String
 	prof.AvatarURL = avatarURL.String
+	prof.Phone = phone.String
+	prof.Province = province.String
+	prof.City = city.String
+	prof.Website = website.String
+	prof.Github = github.String
+	// 可选字段未设置时保持nil（JSON中省略）
+	if gender.Valid {
+		g := int(gender.Int64)
+		prof.Gender = &g
+	}
+	if birthday.Valid {
+		b := birthday.Time.Format("2006-01-02")
+		prof.Birthday = &b
+	}
 
 	return prof, nil
 }
 
-// UpsertUserProfile 创建或更新扩展资料（昵称/简介）
+// UpsertUserProfile 创建或更新扩展资料（昵称/简介/个人链接）
+// 链接字段按传入值整体覆盖（空字符串表示清除），调用方需先合并原有值并完成校验
 func (r *UserRepository) UpsertUserProfile(ctx context.Context, profile *models.UserExtraProfile) error {
 	start := time.Now().UTC()
 
-	query := `INSERT INTO user_profile (user_id, nickname, bio, avatar_url, created_at, updated_at)
-              VALUES (?, ?, ?, COALESCE(?, NULL), NOW(), NOW())
+	query := `INSERT INTO user_profile (user_id, nickname, bio, avatar_url, website, github, created_at, updated_at)
+              VALUES (?, ?, ?, COALESCE(?, NULL), NULLIF(?, ''), NULLIF(?, ''), NOW(), NOW())
               ON DUPLICATE KEY UPDATE 
                 nickname = CASE WHEN VALUES(nickname) != '' THEN VALUES(nickname) ELSE nickname END,
                 bio = CASE WHEN VALUES(bio) != '' THEN VALUES(bio) ELSE bio END,
+                website = VALUES(website),
+                github = VALUES(github),
                 updated_at = NOW()`
 
 	ctx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
 	defer cancel()
 
-	result, err := r.db.ExecWithCache(ctx, query, profile.UserID, profile.Nickname, profile.Bio, profile.AvatarURL)
+	result, err := r.db.ExecWithCache(ctx, query, profile.UserID, profile.Nickname, profile.Bio, profile.AvatarURL,
+		profile.Website, profile.Github)
 	if err != nil {
 		r.logger.Error("保存用户扩展资料失败", "userID", profile.UserID, "error", err.Error())
-		return utils.ErrDatabaseUpdate
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
 	}
 
 	rowsAffected, _ := result.RowsAffected()
@@ -288,6 +327,35 @@ func (r *UserRepository) UpsertUserProfile(ctx context.Context, profile *models.
 	return nil
 }
 
+// UpsertFullUserProfile 创建或更新全部扩展资料（头像除外）
+// 所有字段按传入值整体覆盖：空字符串和nil写入NULL，调用方需先合并原有值并完成校验
+func (r *UserRepository) UpsertFullUserProfile(ctx context.Context, profile *models.UserExtraProfile) error {
+	query := `INSERT INTO user_profile (user_id, nickname, bio, phone, gender, birthday, province, city, website, github, created_at, updated_at)
+              VALUES (?, NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), ?, ?, NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), NOW(), NOW())
+              ON DUPLICATE KEY UPDATE
+                nickname = VALUES(nickname),
+                bio = VALUES(bio),
+                phone = VALUES(phone),
+                gender = VALUES(gender),
+                birthday = VALUES(birthday),
+                province = VALUES(province),
+                city = VALUES(city),
+                website = VALUES(website),
+                github = VALUES(github),
+                updated_at = NOW()`
+
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
+	defer cancel()
+
+	_, err := r.db.ExecWithCache(ctx, query, profile.UserID, profile.Nickname, profile.Bio, profile.Phone,
+		profile.Gender, profile.Birthday, profile.Province, profile.City, profile.Website, profile.Github)
+	if err != nil {
+		r.logger.Error("保存用户完整资料失败", "userID", profile.UserID, "error", err.Error())
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
+	}
+	return nil
+}
+
 // UpdateUserAvatar 仅更新头像URL
 func (r *UserRepository) UpdateUserAvatar(ctx context.Context, profile *models.UserExtraProfile) error {
 	query := `INSERT INTO user_profile (user_id, avatar_url, created_at, updated_at)
@@ -300,7 +368,7 @@ func (r *UserRepository) UpdateUserAvatar(ctx context.Context, profile *models.U
 	_, err := r.db.ExecWithCache(ctx, query, profile.UserID, profile.AvatarURL)
 	if err != nil {
 		r.logger.Error("更新用户头像失败", "userID", profile.UserID, "error", err.Error())
-		return utils.ErrDatabaseUpdate
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
 	}
 	r.logger.Info("更新用户头像成功", "userID", profile.UserID)
 	return nil
@@ -308,7 +376,7 @@ func (r *UserRepository) UpdateUserAvatar(ctx context.Context, profile *models.U
 
 // UpdateLoginInfo 更新登录信息
 func (r *UserRepository) UpdateLoginInfo(ctx context.Context, userID uint, loginTime time.Time, loginIP string) error {
-	query := `UPDATE user_auth SET last_login_time = ?, last_login_ip = ?, failed_login_count = 0, updated_at = ? WHERE id = ?`
+	query := `UPDATE user_auth SET last_login_time = ?, last_login_ip = ?, failed_login_count = 0, locked_until = NULL, updated_at = ? WHERE id = ?`
 
 	ctx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
 	defer cancel()
@@ -316,7 +384,7 @@ func (r *UserRepository) UpdateLoginInfo(ctx context.Context, userID uint, login
 	result, err := r.db.ExecWithCache(ctx, query, loginTime, loginIP, time.Now().UTC(), userID)
 	if err != nil {
 		r.logger.Error("更新登录信息失败", "userID", userID, "error", err.Error())
-		return utils.ErrDatabaseUpdate
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
 	}
 
 	rowsAffected, _ := result.RowsAffected()
@@ -324,22 +392,158 @@ func (r *UserRepository) UpdateLoginInfo(ctx context.Context, userID uint, login
 	return nil
 }
 
-// IncrementFailedLoginCount 增加登录失败次数
-func (r *UserRepository) IncrementFailedLoginCount(ctx context.Context, userID uint) error {
-	query := `UPDATE user_auth SET failed_login_count = failed_login_count + 1, updated_at = ? WHERE id = ?`
-
+// IncrementFailedLoginCount 增加登录失败次数，达到 maxAttempts 时锁定账户到 lockUntil
+// 返回增加后的连续失败次数
+func (r *UserRepository) IncrementFailedLoginCount(ctx context.Context, userID uint, maxAttempts int, lockUntil time.Time) (int, error) {
 	ctx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
 	defer cancel()
 
-	_, err := r.db.ExecWithCache(ctx, query, time.Now().UTC(), userID)
+	var count int
+	err := r.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		// MySQL 按顺序执行 SET，locked_until 中引用的是已加1后的失败次数
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE user_auth SET failed_login_count = failed_login_count + 1,
+			        locked_until = IF(failed_login_count >= ?, ?, locked_until), updated_at = ?
+			 WHERE id = ?`,
+			maxAttempts, lockUntil, time.Now().UTC(), userID); err != nil {
+			return err
+		}
+		return tx.QueryRowContext(ctx, `SELECT failed_login_count FROM user_auth WHERE id = ?`, userID).Scan(&count)
+	})
 	if err != nil {
 		r.logger.Error("更新登录失败次数失败", "userID", userID, "error", err.Error())
-		return utils.ErrDatabaseUpdate
+		return 0, fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
+	}
+
+	return count, nil
+}
+
+// ResetFailedLoginCount 清零登录失败次数并解除锁定（锁定到期后调用）
+func (r *UserRepository) ResetFailedLoginCount(ctx context.Context, userID uint) error {
+	query := `UPDATE user_auth SET failed_login_count = 0, locked_until = NULL, updated_at = ? WHERE id = ?`
+
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
+	defer cancel()
+
+	if _, err := r.db.ExecWithCache(ctx, query, time.Now().UTC(), userID); err != nil {
+		r.logger.Error("重置登录失败次数失败", "userID", userID, "error", err.Error())
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
 	}
 
 	return nil
 }
 
+// GetTokenVersion 获取用户当前的登录token版本
+func (r *UserRepository) GetTokenVersion(ctx context.Context, userID uint) (int, error) {
+	query := `SELECT token_version FROM user_auth WHERE id = ?`
+
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetQueryTimeout())
+	defer cancel()
+
+	var version int
+	if err := r.db.QueryRowWithCache(ctx, query, userID).Scan(&version); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, utils.ErrUserNotFound
+		}
+		r.logger.Error("查询token版本失败", "userID", userID, "error", err.Error())
+		return 0, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+	}
+	return version, nil
+}
+
+// IncrementTokenVersion 递增用户的登录token版本（此前签发的token全部失效），返回新版本
+func (r *UserRepository) IncrementTokenVersion(ctx context.Context, userID uint) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
+	defer cancel()
+
+	var version int
+	err := r.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx,
+			`UPDATE user_auth SET token_version = token_version + 1, updated_at = ? WHERE id = ?`, time.Now().UTC(), userID)
+		if err != nil {
+			return err
+		}
+		if affected, err := result.RowsAffected(); err != nil {
+			return err
+		} else if affected == 0 {
+			return sql.ErrNoRows
+		}
+		return tx.QueryRowContext(ctx, `SELECT token_version FROM user_auth WHERE id = ?`, userID).Scan(&version)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, utils.ErrUserNotFound
+		}
+		r.logger.Error("递增token版本失败", "userID", userID, "error", err.Error())
+		return 0, fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
+	}
+
+	r.logger.Info("已递增token版本", "userID", userID, "tokenVersion", version)
+	return version, nil
+}
+
+// AnonymizeUser 注销账号：在同一事务内清除用户的个人信息并将账户标记为已注销
+// 用户名和邮箱替换为占位值（原用户名和邮箱可重新注册），昵称改为"已注销用户"，
+// 用户ID保持不变，已发布的内容仍归属于该匿名账号；同时删除刷新token、吊销API令牌
+func (r *UserRepository) AnonymizeUser(ctx context.Context, userID uint) error {
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
+	defer cancel()
+
+	now := time.Now().UTC()
+	err := r.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		var email string
+		var accountStatus int
+		err := tx.QueryRowContext(ctx,
+			`SELECT email, account_status FROM user_auth WHERE id = ? FOR UPDATE`, userID).Scan(&email, &accountStatus)
+		if err != nil {
+			return err
+		}
+		if accountStatus == models.AccountStatusDeleted {
+			return nil
+		}
+
+		placeholderUsername := fmt.Sprintf("deleted_%d", userID)
+		placeholderEmail := fmt.Sprintf("deleted_%d_%s@deleted.invalid", userID, hashAPIToken(email)[:16])
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE user_auth SET username = ?, email = ?, password_hash = '', account_status = ?,
+				last_login_ip = NULL, failed_login_count = 0, locked_until = NULL,
+				token_version = token_version + 1, updated_at = ?
+			 WHERE id = ?`,
+			placeholderUsername, placeholderEmail, models.AccountStatusDeleted, now, userID); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO user_profile (user_id, nickname, created_at, updated_at) VALUES (?, ?, ?, ?)
+			 ON DUPLICATE KEY UPDATE nickname = VALUES(nickname), bio = NULL, avatar_url = NULL, phone = NULL,
+				gender = NULL, birthday = NULL, province = NULL, city = NULL, website = NULL, github = NULL,
+				updated_at = VALUES(updated_at)`,
+			userID, models.DeletedUserNickname, now, now); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM password_reset_tokens WHERE email = ?`, email); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM refresh_tokens WHERE user_id = ?`, userID); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx,
+			`UPDATE user_api_tokens SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL`, now, userID)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.ErrUserNotFound
+		}
+		r.logger.Error("注销账号失败", "userID", userID, "error", err.Error())
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
+	}
+
+	r.logger.Info("账号已注销并匿名化", "userID", userID)
+	return nil
+}
+
 // CheckUsernameExists 检查用户名是否存在
 func (r *UserRepository) CheckUsernameExists(ctx context.Context, username string) (bool, error) {
 	query := `SELECT COUNT(*) FROM user_auth WHERE username = ?`
@@ -351,7 +555,7 @@ func (r *UserRepository) CheckUsernameExists(ctx context.Context, username strin
 	err := r.db.QueryRowWithCache(ctx, query, username).Scan(&count)
 	if err != nil {
 		r.logger.Error("检查用户名失败", "username", username, "error", err.Error())
-		return false, utils.ErrDatabaseQuery
+		return false, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
 	}
 
 	return count > 0, nil
@@ -397,7 +601,7 @@ func (r *UserRepository) BatchGetUserProfiles(ctx context.Context, userIDs []uin
 	rows, err := r.db.DB.QueryContext(ctx, query, args...)
 	if err != nil {
 		r.logger.Error("批量查询用户信息失败", "error", err.Error())
-		return nil, utils.ErrDatabaseQuery
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
 	}
 	defer rows.Close()
 
@@ -423,6 +627,43 @@ func (r *UserRepository) BatchGetUserProfiles(ctx context.Context, userIDs []uin
 	return users, nil
 }
 
+// BatchGetUserBriefs 批量获取用户名、昵称和头像（单次查询，昵称为空时使用用户名）
+func (r *UserRepository) BatchGetUserBriefs(ctx context.Context, userIDs []uint) (map[uint]*models.CommentAuthor, error) {
+	briefs := make(map[uint]*models.CommentAuthor, len(userIDs))
+	if len(userIDs) == 0 {
+		return briefs, nil
+	}
+
+	args := make([]interface{}, len(userIDs))
+	for i, id := range userIDs {
+		args[i] = id
+	}
+	query := `SELECT ua.id, ua.username, COALESCE(NULLIF(up.nickname, ''), ua.username), COALESCE(up.avatar_url, '')
+			  FROM user_auth ua
+			  LEFT JOIN user_profile up ON ua.id = up.user_id
+			  WHERE ua.id IN (?` + strings.Repeat(",?", len(userIDs)-1) + `)`
+
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetQueryTimeout())
+	defer cancel()
+
+	rows, err := r.db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.logger.Error("批量查询用户资料失败", "count", len(userIDs), "error", err.Error())
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		brief := &models.CommentAuthor{}
+		if err := rows.Scan(&brief.ID, &brief.Username, &brief.Nickname, &brief.Avatar); err != nil {
+			r.logger.Warn("扫描用户资料失败", "error", err.Error())
+			continue
+		}
+		briefs[brief.ID] = brief
+	}
+	return briefs, nil
+}
+
 // CheckEmailExists 检查邮箱是否存在
 func (r *UserRepository) CheckEmailExists(ctx context.Context, email string) (bool, error) {
 	query := `SELECT COUNT(*) FROM user_auth WHERE email = ?`
@@ -434,7 +675,7 @@ func (r *UserRepository) CheckEmailExists(ctx context.Context, email string) (bo
 	err := r.db.QueryRowWithCache(ctx, query, email).Scan(&count)
 	if err != nil {
 		r.logger.Error("检查邮箱失败", "email", utils.SanitizeEmail(email), "error", err.Error())
-		return false, utils.ErrDatabaseQuery
+		return false, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
 	}
 
 	return count > 0, nil
@@ -452,7 +693,7 @@ func (r *UserRepository) UpdatePassword(ctx context.Context, userID uint, newPas
 	result, err := r.db.ExecWithCache(ctx, query, newPasswordHash, time.Now().UTC(), userID)
 	if err != nil {
 		r.logger.Error("更新密码失败", "userID", userID, "error", err.Error())
-		return utils.ErrDatabaseUpdate
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
 	}
 
 	rowsAffected, _ := result.RowsAffected()
@@ -465,3 +706,379 @@ func (r *UserRepository) UpdatePassword(ctx context.Context, userID uint, newPas
 	return nil
 }
 
+// UpgradePasswordHash 用新成本的哈希替换旧哈希（仅当密码哈希仍为 oldHash 时更新，避免覆盖期间修改的密码）
+func (r *UserRepository) UpgradePasswordHash(ctx context.Context, userID uint, oldHash, newHash string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
+	defer cancel()
+
+	result, err := r.db.DB.ExecContext(ctx,
+		`UPDATE user_auth SET password_hash = ? WHERE id = ? AND password_hash = ?`, newHash, userID, oldHash)
+	if err != nil {
+		r.logger.Error("升级密码哈希失败", "userID", userID, "error", err.Error())
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
+	}
+	if rows, _ := result.RowsAffected(); rows > 0 {
+		r.logger.Info("密码哈希已升级", "userID", userID)
+	}
+	return nil
+}
+
+// CreatePasswordResetToken 保存密码重置token（只存哈希），同时作废该邮箱之前未使用的token
+func (r *UserRepository) CreatePasswordResetToken(ctx context.Context, email, tokenHash string, expiresAt time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
+	defer cancel()
+
+	err := r.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `UPDATE password_reset_tokens SET used = 1 WHERE email = ? AND used = 0`, email); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO password_reset_tokens (email, token, expires_at, created_at) VALUES (?, ?, ?, ?)`,
+			email, tokenHash, expiresAt, time.Now().UTC())
+		return err
+	})
+	if err != nil {
+		r.logger.Error("保存密码重置token失败", "email", utils.SanitizeEmail(email), "error", err.Error())
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseInsert, err)
+	}
+	return nil
+}
+
+// GetPasswordResetTokenEmail 查询有效密码重置token对应的邮箱，不作废token（无效或过期时返回400错误）
+func (r *UserRepository) GetPasswordResetTokenEmail(ctx context.Context, tokenHash string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetQueryTimeout())
+	defer cancel()
+
+	var email string
+	err := r.db.DB.QueryRowContext(ctx,
+		`SELECT email FROM password_reset_tokens WHERE token = ? AND used = 0 AND expires_at > ?`,
+		tokenHash, time.Now().UTC()).Scan(&email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", utils.NewAppError(utils.ErrInvalidParameter, "重置链接无效或已过期", 400)
+		}
+		r.logger.Error("查询密码重置token失败", "error", err.Error())
+		return "", fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+	}
+	return email, nil
+}
+
+// ResetPasswordWithToken 在同一事务内作废密码重置token并更新用户密码（无效或过期时返回400错误）
+// 同时递增token版本并删除刷新token，重置前签发的会话全部失效
+func (r *UserRepository) ResetPasswordWithToken(ctx context.Context, tokenHash string, userID uint, newPasswordHash string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
+	defer cancel()
+
+	now := time.Now().UTC()
+	err := r.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		var id uint64
+		err := tx.QueryRowContext(ctx,
+			`SELECT t.id FROM password_reset_tokens t
+			 INNER JOIN user_auth u ON u.email = t.email
+			 WHERE t.token = ? AND t.used = 0 AND t.expires_at > ? AND u.id = ? FOR UPDATE`,
+			tokenHash, now, userID).Scan(&id)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE password_reset_tokens SET used = 1 WHERE id = ?`, id); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE user_auth SET password_hash = ?, token_version = token_version + 1, updated_at = ? WHERE id = ?`,
+			newPasswordHash, now, userID); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `DELETE FROM refresh_tokens WHERE user_id = ?`, userID)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.NewAppError(utils.ErrInvalidParameter, "重置链接无效或已过期", 400)
+		}
+		r.logger.Error("重置密码失败", "userID", userID, "error", err.Error())
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseUpdate, err)
+	}
+
+	r.logger.Info("重置密码成功", "userID", userID)
+	return nil
+}
+
+// BlockUser 屏蔽用户（重复屏蔽直接成功，不能屏蔽自己）
+func (r *UserRepository) BlockUser(ctx context.Context, blockerID, blockedID uint) error {
+	if blockerID == blockedID {
+		return utils.NewAppError(utils.ErrInvalidParameter, "不能屏蔽自己", 400)
+	}
+
+	exists, err := r.userExists(ctx, blockedID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return utils.ErrUserNotFound
+	}
+
+	query := `INSERT IGNORE INTO user_blocks (blocker_id, blocked_id, created_at) VALUES (?, ?, ?)`
+
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
+	defer cancel()
+
+	if _, err := r.db.ExecWithCache(ctx, query, blockerID, blockedID, time.Now().UTC()); err != nil {
+		r.logger.Error("屏蔽用户失败", "blockerID", blockerID, "blockedID", blockedID, "error", err.Error())
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseInsert, err)
+	}
+
+	r.logger.Info("屏蔽用户成功", "blockerID", blockerID, "blockedID", blockedID)
+	return nil
+}
+
+// UnblockUser 取消屏蔽用户（未屏蔽时直接成功）
+func (r *UserRepository) UnblockUser(ctx context.Context, blockerID, blockedID uint) error {
+	query := `DELETE FROM user_blocks WHERE blocker_id = ? AND blocked_id = ?`
+
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
+	defer cancel()
+
+	if _, err := r.db.ExecWithCache(ctx, query, blockerID, blockedID); err != nil {
+		r.logger.Error("取消屏蔽用户失败", "blockerID", blockerID, "blockedID", blockedID, "error", err.Error())
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseDelete, err)
+	}
+
+	r.logger.Info("取消屏蔽用户成功", "blockerID", blockerID, "blockedID", blockedID)
+	return nil
+}
+
+// GetBlockedIDs 获取用户屏蔽的全部用户ID（按屏蔽时间倒序）
+func (r *UserRepository) GetBlockedIDs(ctx context.Context, blockerID uint) ([]uint, error) {
+	query := `SELECT blocked_id FROM user_blocks WHERE blocker_id = ? ORDER BY created_at DESC`
+
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetQueryTimeout())
+	defer cancel()
+
+	rows, err := r.db.QueryWithCache(ctx, query, blockerID)
+	if err != nil {
+		r.logger.Error("查询屏蔽列表失败", "blockerID", blockerID, "error", err.Error())
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+	}
+	defer rows.Close()
+
+	ids := make([]uint, 0)
+	for rows.Next() {
+		var id uint
+		if err := rows.Scan(&id); err != nil {
+			r.logger.Warn("扫描屏蔽用户失败", "error", err.Error())
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// userExists 检查用户ID是否存在
+func (r *UserRepository) userExists(ctx context.Context, userID uint) (bool, error) {
+	query := `SELECT COUNT(*) FROM user_auth WHERE id = ?`
+
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetQueryTimeout())
+	defer cancel()
+
+	var count int
+	if err := r.db.QueryRowWithCache(ctx, query, userID).Scan(&count); err != nil {
+		r.logger.Error("检查用户是否存在失败", "userID", userID, "error", err.Error())
+		return false, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+	}
+	return count > 0, nil
+}
+
+// Follow 关注用户（重复关注直接成功，不能关注自己）
+func (r *UserRepository) Follow(ctx context.Context, followerID, followingID uint) error {
+	if followerID == followingID {
+		return utils.NewAppError(utils.ErrInvalidParameter, "不能关注自己", 400)
+	}
+
+	exists, err := r.userExists(ctx, followingID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return utils.ErrUserNotFound
+	}
+
+	query := `INSERT IGNORE INTO user_follows (follower_id, following_id, created_at) VALUES (?, ?, ?)`
+
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
+	defer cancel()
+
+	if _, err := r.db.ExecWithCache(ctx, query, followerID, followingID, time.Now().UTC()); err != nil {
+		r.logger.Error("关注用户失败", "followerID", followerID, "followingID", followingID, "error", err.Error())
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseInsert, err)
+	}
+
+	r.logger.Info("关注用户成功", "followerID", followerID, "followingID", followingID)
+	return nil
+}
+
+// Unfollow 取消关注（未关注时直接成功）
+func (r *UserRepository) Unfollow(ctx context.Context, followerID, followingID uint) error {
+	query := `DELETE FROM user_follows WHERE follower_id = ? AND following_id = ?`
+
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetUpdateTimeout())
+	defer cancel()
+
+	if _, err := r.db.ExecWithCache(ctx, query, followerID, followingID); err != nil {
+		r.logger.Error("取消关注失败", "followerID", followerID, "followingID", followingID, "error", err.Error())
+		return fmt.Errorf("%w: %w", utils.ErrDatabaseDelete, err)
+	}
+
+	r.logger.Info("取消关注成功", "followerID", followerID, "followingID", followingID)
+	return nil
+}
+
+// GetFollowing 分页获取用户关注的人（按关注时间倒序）
+func (r *UserRepository) GetFollowing(ctx context.Context, userID uint, page, pageSize int) (*models.FollowListResponse, error) {
+	return r.listFollows(ctx, "follower_id", "following_id", userID, page, pageSize)
+}
+
+// GetFollowers 分页获取用户的粉丝（按关注时间倒序）
+func (r *UserRepository) GetFollowers(ctx context.Context, userID uint, page, pageSize int) (*models.FollowListResponse, error) {
+	return r.listFollows(ctx, "following_id", "follower_id", userID, page, pageSize)
+}
+
+// listFollows 关注/粉丝列表查询（ownerColumn 为查询用户所在列，targetColumn 为列表中用户所在列）
+func (r *UserRepository) listFollows(ctx context.Context, ownerColumn, targetColumn string, userID uint, page, pageSize int) (*models.FollowListResponse, error) {
+	offset := (page - 1) * pageSize
+
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetQueryTimeout())
+	defer cancel()
+
+	var total int
+	countQuery := fmt.Sprintf(`SELECT COUNT(*) FROM user_follows WHERE %s = ?`, ownerColumn)
+	if err := r.db.QueryRowWithCache(ctx, countQuery, userID).Scan(&total); err != nil {
+		r.logger.Error("查询关注数量失败", "userID", userID, "column", ownerColumn, "error", err.Error())
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+	}
+
+	listQuery := fmt.Sprintf(`
+		SELECT ua.id, ua.username, COALESCE(up.nickname, ua.username) as nickname,
+		       COALESCE(up.avatar_url, '') as avatar, uf.created_at
+		FROM user_follows uf
+		INNER JOIN user_auth ua ON ua.id = uf.%s
+		LEFT JOIN user_profile up ON up.user_id = ua.id
+		WHERE uf.%s = ?
+		ORDER BY uf.created_at DESC
+		LIMIT ? OFFSET ?`, targetColumn, ownerColumn)
+
+	rows, err := r.db.QueryWithCache(ctx, listQuery, userID, pageSize, offset)
+	if err != nil {
+		r.logger.Error("查询关注列表失败", "userID", userID, "column", ownerColumn, "error", err.Error())
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+	}
+	defer rows.Close()
+
+	users := make([]models.FollowUser, 0, pageSize)
+	for rows.Next() {
+		var u models.FollowUser
+		if err := rows.Scan(&u.ID, &u.Username, &u.Nickname, &u.Avatar, &u.FollowedAt); err != nil {
+			r.logger.Warn("扫描关注用户失败", "error", err.Error())
+			continue
+		}
+		users = append(users, u)
+	}
+
+	return &models.FollowListResponse{
+		Users:      users,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: (total + pageSize - 1) / pageSize,
+	}, nil
+}
+
+// GetFollowingIDs 获取用户最近关注的至多 limit 个用户ID（用于关注流，限制 IN 子句大小）
+func (r *UserRepository) GetFollowingIDs(ctx context.Context, userID uint, limit int) ([]uint, error) {
+	query := `SELECT following_id FROM user_follows WHERE follower_id = ? ORDER BY created_at DESC LIMIT ?`
+
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetQueryTimeout())
+	defer cancel()
+
+	rows, err := r.db.QueryWithCache(ctx, query, userID, limit)
+	if err != nil {
+		r.logger.Error("查询关注用户ID失败", "userID", userID, "error", err.Error())
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+	}
+	defer rows.Close()
+
+	ids := make([]uint, 0)
+	for rows.Next() {
+		var id uint
+		if err := rows.Scan(&id); err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// GetFollowCounts 获取用户的粉丝数和关注数
+func (r *UserRepository) GetFollowCounts(ctx context.Context, userID uint) (*models.FollowCounts, error) {
+	query := `SELECT
+				(SELECT COUNT(*) FROM user_follows WHERE following_id = ?),
+				(SELECT COUNT(*) FROM user_follows WHERE follower_id = ?)`
+
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetQueryTimeout())
+	defer cancel()
+
+	counts := &models.FollowCounts{}
+	if err := r.db.QueryRowWithCache(ctx, query, userID, userID).Scan(&counts.FollowersCount, &counts.FollowingCount); err != nil {
+		r.logger.Error("查询关注统计失败", "userID", userID, "error", err.Error())
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+	}
+	return counts, nil
+}
+
+// GetPublicProfile 获取用户公开主页信息及统计（单条查询，统计使用关联子查询）
+// 已注销用户返回只含ID和占位昵称的资料，不返回个人信息和统计
+func (r *UserRepository) GetPublicProfile(ctx context.Context, userID uint) (*models.PublicUserProfile, error) {
+	query := `SELECT ua.username, ua.account_status, ua.created_at,
+				COALESCE(up.nickname, ''), COALESCE(up.avatar_url, ''), COALESCE(up.bio, ''),
+				COALESCE(up.website, ''), COALESCE(up.github, ''),
+				(SELECT COUNT(*) FROM articles WHERE user_id = ua.id AND status = 1),
+				(SELECT COUNT(*) FROM resources WHERE user_id = ua.id AND status = 1),
+				(SELECT COALESCE(SUM(like_count), 0) FROM articles WHERE user_id = ua.id AND status = 1) +
+				(SELECT COALESCE(SUM(like_count), 0) FROM resources WHERE user_id = ua.id AND status = 1),
+				(SELECT COUNT(*) FROM user_follows WHERE following_id = ua.id),
+				(SELECT COUNT(*) FROM user_follows WHERE follower_id = ua.id)
+			  FROM user_auth ua
+			  LEFT JOIN user_profile up ON up.user_id = ua.id
+			  WHERE ua.id = ?`
+
+	ctx, cancel := context.WithTimeout(ctx, r.db.GetQueryTimeout())
+	defer cancel()
+
+	profile := &models.PublicUserProfile{ID: userID}
+	var accountStatus int
+	var joinedAt time.Time
+	err := r.db.QueryRowWithCache(ctx, query, userID).Scan(
+		&profile.Username, &accountStatus, &joinedAt,
+		&profile.Nickname, &profile.Avatar, &profile.Bio,
+		&profile.Website, &profile.Github,
+		&profile.ArticleCount, &profile.ResourceCount, &profile.LikesReceived,
+		&profile.FollowersCount, &profile.FollowingCount,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, utils.ErrUserNotFound
+		}
+		r.logger.Error("查询用户公开资料失败", "userID", userID, "error", err.Error())
+		return nil, fmt.Errorf("%w: %w", utils.ErrDatabaseQuery, err)
+	}
+
+	if accountStatus == models.AccountStatusDeleted {
+		return &models.PublicUserProfile{
+			ID:        userID,
+			Nickname:  models.DeletedUserNickname,
+			IsDeleted: true,
+		}, nil
+	}
+
+	profile.JoinedAt = &joinedAt
+	return profile, nil
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gin/internal/models"
+	"gin/internal/testutil"
+	"gin/internal/utils"
+)
+
+// userProfileColumns GetUserProfile 查询的列
+var userProfileColumns = []string{"user_id", "nickname", "bio", "avatar_url", "phone", "gender", "birthday",
+	"province", "city", "website", "github", "created_at", "updated_at"}
+
+func TestGetUserProfileAllFields(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	now := time.Now().UTC()
+	fake.OnRows(`FROM user_profile WHERE user_id = \?`, userProfileColumns,
+		[]driver.Value{int64(1), "Alice", "bio", "", "13800138000", int64(2), time.Date(1995, 6, 1, 0, 0, 0, 0, time.UTC),
+			"浙江", "杭州", "https://alice.dev", "alice", now, now})
+
+	prof, err := NewUserRepository(db).GetUserProfile(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("读取资料失败: %v", err)
+	}
+	if prof.Phone != "13800138000" || prof.Province != "浙江" || prof.City != "杭州" || prof.Website != "https://alice.dev" || prof.Github != "alice" {
+		t.Fatalf("字符串字段读取错误: %+v", prof)
+	}
+	if prof.Gender == nil || *prof.Gender != 2 || prof.Birthday == nil || *prof.Birthday != "1995-06-01" {
+		t.Fatalf("性别和生日读取错误: %v %v", prof.Gender, prof.Birthday)
+	}
+}
+
+func TestGetUserProfileNullFields(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	now := time.Now().UTC()
+	fake.OnRows(`FROM user_profile WHERE user_id = \?`, userProfileColumns,
+		[]driver.Value{int64(1), nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, now, now})
+
+	prof, err := NewUserRepository(db).GetUserProfile(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("读取资料失败: %v", err)
+	}
+	if prof.Nickname != "" || prof.Phone != "" || prof.Province != "" || prof.City != "" || prof.Website != "" {
+		t.Fatalf("NULL 的字符串字段应为空字符串: %+v", prof)
+	}
+	if prof.Gender != nil || prof.Birthday != nil {
+		t.Fatalf("NULL 的性别和生日应为 nil: %v %v", prof.Gender, prof.Birthday)
+	}
+
+	// 没有资料记录时返回空资料而不是错误
+	fake.OnRows(`FROM user_profile WHERE user_id = \?`, userProfileColumns)
+	if prof, err := NewUserRepository(db).GetUserProfile(context.Background(), 2); err != nil || prof.UserID != 2 || prof.Gender != nil {
+		t.Fatalf("没有资料时应返回空资料，实际 %+v %v", prof, err)
+	}
+}
+
+func TestUpsertFullUserProfile(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	fake.OnExec(`INSERT INTO user_profile`, 0, 1)
+	gender, birthday := 1, "1990-01-02"
+	repo := NewUserRepository(db)
+
+	err := repo.UpsertFullUserProfile(context.Background(), &models.UserExtraProfile{UserID: 1, Nickname: "Bob",
+		Phone: "13800138000", Gender: &gender, Birthday: &birthday, City: "杭州"})
+	if err != nil {
+		t.Fatalf("保存资料失败: %v", err)
+	}
+	args := fake.Calls(`INSERT INTO user_profile`)[0].Args
+	if args[0] != int64(1) || args[1] != "Bob" || args[3] != "13800138000" || args[4] != int64(1) || args[5] != birthday || args[7] != "杭州" {
+		t.Fatalf("写入的资料错误: %v", args)
+	}
+
+	// 未设置的性别和生日写入 NULL，空字符串由 NULLIF 转为 NULL
+	if err := repo.UpsertFullUserProfile(context.Background(), &models.UserExtraProfile{UserID: 1}); err != nil {
+		t.Fatalf("保存资料失败: %v", err)
+	}
+	call := fake.Calls(`INSERT INTO user_profile`)[1]
+	if call.Args[4] != nil || call.Args[5] != nil {
+		t.Fatalf("未设置的性别和生日应写入 NULL，实际 %v", call.Args)
+	}
+	for _, column := range []string{"phone = VALUES(phone)", "birthday = VALUES(birthday)", "github = VALUES(github)"} {
+		if !strings.Contains(call.Query, column) {
+			t.Fatalf("更新时应覆盖 %s: %s", column, call.Query)
+		}
+	}
+}
+
+// publicProfileColumns GetPublicProfile 查询的列
+var publicProfileColumns = []string{"username", "account_status", "created_at", "nickname", "avatar", "bio", "website", "github",
+	"article_count", "resource_count", "likes_received", "followers", "following"}
+
+func TestGetPublicProfileStats(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	joined := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	fake.OnRows(`FROM user_auth ua LEFT JOIN user_profile up ON up.user_id = ua.id WHERE ua.id = \?`, publicProfileColumns,
+		[]driver.Value{"alice", int64(models.AccountStatusNormal), joined, "Alice", "", "bio", "", "alice",
+			int64(4), int64(2), int64(37), int64(10), int64(3)})
+
+	profile, err := NewUserRepository(db).GetPublicProfile(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("获取公开资料失败: %v", err)
+	}
+	if profile.ArticleCount != 4 || profile.ResourceCount != 2 || profile.LikesReceived != 37 ||
+		profile.FollowersCount != 10 || profile.FollowingCount != 3 {
+		t.Fatalf("统计数据错误: %+v", profile)
+	}
+	if profile.Username != "alice" || profile.JoinedAt == nil || !profile.JoinedAt.Equal(joined) || profile.IsDeleted {
+		t.Fatalf("基本信息错误: %+v", profile)
+	}
+
+	// 一条查询完成统计，只统计已发布的内容，且不查询私密字段
+	calls := fake.Calls("")
+	if len(calls) != 1 {
+		t.Fatalf("公开资料应由一条查询完成，实际 %d 条", len(calls))
+	}
+	query := calls[0].Query
+	if strings.Count(query, "status = 1") != 4 {
+		t.Fatalf("文章数、资源数和获赞数都只应统计已发布的内容: %s", query)
+	}
+	for _, field := range []string{"email", "phone", "failed_login", "password"} {
+		if strings.Contains(query, field) {
+			t.Fatalf("不应查询私密字段 %s: %s", field, query)
+		}
+	}
+}
+
+func TestGetPublicProfileDeletedAndMissing(t *testing.T) {
+	fake, db := newFakeDatabase(t)
+	fake.On(`FROM user_auth ua LEFT JOIN user_profile up ON up.user_id = ua.id WHERE ua.id = \?`, func(args []driver.Value) testutil.Response {
+		resp := testutil.Response{Columns: publicProfileColumns}
+		if args[0] == int64(2) {
+			resp.Rows = [][]driver.Value{{"deleted_2", int64(models.AccountStatusDeleted), time.Now().UTC(), "", "", "", "", "",
+				int64(3), int64(0), int64(5), int64(0), int64(0)}}
+		}
+		return resp
+	})
+	repo := NewUserRepository(db)
+
+	profile, err := repo.GetPublicProfile(context.Background(), 2)
+	if err != nil {
+		t.Fatalf("已注销用户应返回占位资料而不是错误: %v", err)
+	}
+	if !profile.IsDeleted || profile.Nickname != models.DeletedUserNickname || profile.Username != "" ||
+		profile.JoinedAt != nil || profile.ArticleCount != 0 || profile.LikesReceived != 0 {
+		t.Fatalf("已注销用户只应返回最少的占位信息，实际 %+v", profile)
+	}
+
+	if _, err := repo.GetPublicProfile(context.Background(), 9); !errors.Is(err, utils.ErrUserNotFound) {
+		t.Fatalf("用户不存在应返回 ErrUserNotFound，实际 %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/utils"
+)
+
+// 浏览去重的内容类型
+const (
+	ViewTargetArticle  = "article"
+	ViewTargetResource = "resource"
+)
+
+// ViewDeduplicator 浏览次数去重器
+// 同一访客在窗口内重复浏览同一内容只计一次（登录用户按用户ID，匿名访客按IP哈希，不在内存中保存原始IP）；
+// 记录保存在进程内LRU中，容量淘汰或重启后会重新计数，因此浏览数是近似值，但刷新页面或简单脚本无法再刷量
+type ViewDeduplicator struct {
+	seen   *utils.LRUCache // 为nil时不去重
+	window time.Duration
+}
+
+// NewViewDeduplicator 按配置创建浏览去重器（window_minutes 为0时每次浏览都计数）
+func NewViewDeduplicator(cfg *config.Config) *ViewDeduplicator {
+	dedup := cfg.Cache.ViewDedup
+	if dedup.WindowMinutes <= 0 {
+		return &ViewDeduplicator{}
+	}
+	window := time.Duration(dedup.WindowMinutes) * time.Minute
+	return &ViewDeduplicator{
+		seen: utils.NewLRUCache(utils.LRUCacheConfig{
+			Capacity:   dedup.Capacity,
+			DefaultTTL: window,
+		}),
+		window: window,
+	}
+}
+
+// ShouldCount 本次浏览是否计数（窗口内首次浏览返回true，并记录该访客；去重器为nil时总是计数）
+func (d *ViewDeduplicator) ShouldCount(target string, targetID uint, userID uint, clientIP string) bool {
+	if d == nil || d.seen == nil {
+		return true
+	}
+	key := target + ":" + strconv.FormatUint(uint64(targetID), 10) + ":" + viewerIdentity(userID, clientIP)
+	return d.seen.SetIfAbsent(key, struct{}{}, d.window)
+}
+
+// viewerIdentity 访客标识：登录用户为用户ID，匿名访客为IP的哈希前缀
+func viewerIdentity(userID uint, clientIP string) string {
+	if userID > 0 {
+		return "u" + strconv.FormatUint(uint64(userID), 10)
+	}
+	sum := sha256.Sum256([]byte(clientIP))
+	return "ip" + hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gin/internal/config"
+)
+
+func TestViewDeduplicatorShouldCount(t *testing.T) {
+	cfg := config.Default()
+	cfg.Cache.ViewDedup = config.ViewDedupConfig{WindowMinutes: 30, Capacity: 100}
+	d := NewViewDeduplicator(cfg)
+
+	if !d.ShouldCount(ViewTargetArticle, 1, 0, "10.0.0.1") {
+		t.Fatal("首次浏览应计数")
+	}
+	if d.ShouldCount(ViewTargetArticle, 1, 0, "10.0.0.1") {
+		t.Fatal("同一IP在窗口内重复浏览不应计数")
+	}
+	// 内容、内容类型或访客不同时分别计数
+	for _, tc := range []struct {
+		target   string
+		targetID uint
+		userID   uint
+		ip       string
+	}{
+		{ViewTargetArticle, 2, 0, "10.0.0.1"},
+		{ViewTargetResource, 1, 0, "10.0.0.1"},
+		{ViewTargetArticle, 1, 0, "10.0.0.2"},
+		{ViewTargetArticle, 1, 7, "10.0.0.1"},
+	} {
+		if !d.ShouldCount(tc.target, tc.targetID, tc.userID, tc.ip) {
+			t.Errorf("%+v 应单独计数", tc)
+		}
+	}
+	// 登录用户按用户ID去重，换IP也不重复计数
+	if d.ShouldCount(ViewTargetArticle, 1, 7, "192.168.1.1") {
+		t.Fatal("登录用户换IP后不应重复计数")
+	}
+
+	// 匿名访客只保存IP的哈希
+	if id := viewerIdentity(0, "10.0.0.1"); strings.Contains(id, "10.0.0.1") || id != viewerIdentity(0, "10.0.0.1") {
+		t.Fatalf("匿名访客标识应为稳定的IP哈希，实际 %q", id)
+	}
+}
+
+func TestViewDeduplicatorWindow(t *testing.T) {
+	// 窗口为0时每次浏览都计数；未配置去重器时同样计数
+	cfg := config.Default()
+	cfg.Cache.ViewDedup.WindowMinutes = 0
+	d := NewViewDeduplicator(cfg)
+	var nilDedup *ViewDeduplicator
+	for i := 0; i < 3; i++ {
+		if !d.ShouldCount(ViewTargetArticle, 1, 7, "") || !nilDedup.ShouldCount(ViewTargetArticle, 1, 7, "") {
+			t.Fatal("关闭去重时每次浏览都应计数")
+		}
+	}
+
+	// 窗口过期后重新计数
+	cfg.Cache.ViewDedup = config.ViewDedupConfig{WindowMinutes: 1, Capacity: 100}
+	d = NewViewDeduplicator(cfg)
+	d.window = 50 * time.Millisecond
+	d.ShouldCount(ViewTargetResource, 3, 7, "")
+	if d.ShouldCount(ViewTargetResource, 3, 7, "") {
+		t.Fatal("窗口内重复浏览不应计数")
+	}
+	time.Sleep(80 * time.Millisecond)
+	if !d.ShouldCount(ViewTargetResource, 3, 7, "") {
+		t.Fatal("窗口过期后应重新计数")
+	}
+}
+
+func TestViewDeduplicatorConcurrent(t *testing.T) {
+	cfg := config.Default()
+	d := NewViewDeduplicator(cfg)
+	var counted atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if d.ShouldCount(ViewTargetArticle, 9, 0, "10.0.0.9") {
+				counted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if counted.Load() != 1 {
+		t.Fatalf("同一访客的并发浏览应只计数一次，实际 %d", counted.Load())
+	}
+}
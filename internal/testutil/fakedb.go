@@ -0,0 +1,214 @@
+// Package testutil 提供测试用的辅助实现（不在生产代码中使用）
+package testutil
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Response 预设的SQL执行结果
+type Response struct {
+	Columns      []string
+	Rows         [][]driver.Value
+	LastInsertID int64
+	RowsAffected int64
+	Err          error
+}
+
+// Call 一次SQL执行记录
+type Call struct {
+	Query string
+	Args  []driver.Value
+}
+
+type fakeRule struct {
+	pattern *regexp.Regexp
+	handle  func(args []driver.Value) Response
+}
+
+// FakeDB 可编程的 database/sql 驱动：按正则匹配SQL返回预设结果，并记录执行过的语句
+// 后注册的规则优先匹配（便于在通用规则之上覆盖个别语句）；未匹配的语句返回错误
+type FakeDB struct {
+	mu    sync.Mutex
+	rules []fakeRule
+	calls []Call
+}
+
+// NewFakeDB 创建可编程数据库
+func NewFakeDB() *FakeDB {
+	return &FakeDB{}
+}
+
+// On 注册SQL处理函数（pattern 为不区分大小写的正则，空白会被规范化为单个空格后再匹配）
+func (f *FakeDB) On(pattern string, handle func(args []driver.Value) Response) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rules = append(f.rules, fakeRule{pattern: regexp.MustCompile("(?is)" + pattern), handle: handle})
+}
+
+// OnRows 注册返回固定结果集的查询
+func (f *FakeDB) OnRows(pattern string, columns []string, rows ...[]driver.Value) {
+	f.On(pattern, func([]driver.Value) Response {
+		return Response{Columns: columns, Rows: rows}
+	})
+}
+
+// OnExec 注册返回固定影响行数的写语句
+func (f *FakeDB) OnExec(pattern string, lastInsertID, rowsAffected int64) {
+	f.On(pattern, func([]driver.Value) Response {
+		return Response{LastInsertID: lastInsertID, RowsAffected: rowsAffected}
+	})
+}
+
+// OnError 注册返回错误的语句
+func (f *FakeDB) OnError(pattern string, err error) {
+	f.On(pattern, func([]driver.Value) Response {
+		return Response{Err: err}
+	})
+}
+
+// Calls 返回匹配 pattern 的执行记录（pattern 为空时返回全部）
+func (f *FakeDB) Calls(pattern string) []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var re *regexp.Regexp
+	if pattern != "" {
+		re = regexp.MustCompile("(?is)" + pattern)
+	}
+	var result []Call
+	for _, c := range f.calls {
+		if re == nil || re.MatchString(c.Query) {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// Open 返回使用该驱动的连接池
+func (f *FakeDB) Open() *sql.DB {
+	return sql.OpenDB(fakeConnector{db: f})
+}
+
+func (f *FakeDB) run(query string, args []driver.Value) Response {
+	normalized := strings.Join(strings.Fields(query), " ")
+
+	f.mu.Lock()
+	f.calls = append(f.calls, Call{Query: normalized, Args: args})
+	var handle func([]driver.Value) Response
+	for i := len(f.rules) - 1; i >= 0; i-- {
+		if f.rules[i].pattern.MatchString(normalized) {
+			handle = f.rules[i].handle
+			break
+		}
+	}
+	f.mu.Unlock()
+
+	if handle == nil {
+		return Response{Err: fmt.Errorf("fakedb: 未预设的SQL: %s", normalized)}
+	}
+	return handle(args)
+}
+
+type fakeConnector struct{ db *FakeDB }
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) { return &fakeConn{db: c.db}, nil }
+func (c fakeConnector) Driver() driver.Driver                        { return fakeDriver{db: c.db} }
+
+type fakeDriver struct{ db *FakeDB }
+
+func (d fakeDriver) Open(string) (driver.Conn, error) { return &fakeConn{db: d.db}, nil }
+
+type fakeConn struct{ db *FakeDB }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{conn: c, query: query}, nil
+}
+func (c *fakeConn) Close() error { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.db.run("BEGIN", nil)
+	return fakeTx{conn: c}, nil
+}
+
+// BeginTx 接受任意隔离级别（WithTransaction 使用 READ COMMITTED）
+func (c *fakeConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	return c.Begin()
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.query(query, namedValues(args))
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.exec(query, namedValues(args))
+}
+
+func (c *fakeConn) query(query string, args []driver.Value) (driver.Rows, error) {
+	resp := c.db.run(query, args)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return &fakeRows{columns: resp.Columns, rows: resp.Rows}, nil
+}
+
+func (c *fakeConn) exec(query string, args []driver.Value) (driver.Result, error) {
+	resp := c.db.run(query, args)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return fakeResult{lastID: resp.LastInsertID, affected: resp.RowsAffected}, nil
+}
+
+type fakeTx struct{ conn *fakeConn }
+
+func (t fakeTx) Commit() error   { t.conn.db.run("COMMIT", nil); return nil }
+func (t fakeTx) Rollback() error { t.conn.db.run("ROLLBACK", nil); return nil }
+
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.conn.exec(s.query, args)
+}
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.conn.query(s.query, args)
+}
+
+type fakeResult struct{ lastID, affected int64 }
+
+func (r fakeResult) LastInsertId() (int64, error) { return r.lastID, nil }
+func (r fakeResult) RowsAffected() (int64, error) { return r.affected, nil }
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+	pos     int
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.pos])
+	r.pos++
+	return nil
+}
+
+func namedValues(args []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, len(args))
+	for i, a := range args {
+		values[i] = a.Value
+	}
+	return values
+}
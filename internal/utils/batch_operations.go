@@ -220,34 +220,24 @@ func (bp *BatchProcessor) ParallelBatchProcess(
 	// 创建信号量控制并发
 	sem := make(chan struct{}, concurrency)
 
-	// 使用WaitGroup等待所有任务完成
-	var wg sync.WaitGroup
-
-	for i, item := range items {
-		wg.Add(1)
-		go func(index int, data interface{}) {
-			defer wg.Done()
-
-			// 获取信号量
-			sem <- struct{}{}
-			defer func() { <-sem }()
-
-			// 创建带超时的context
-			taskCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
-			defer cancel()
-
-			// 处理数据
-			if err := processor(taskCtx, data); err != nil {
-				bp.logger.Error("并行处理失败",
-					"index", index,
-					"error", err.Error())
-				errChan <- err
-			}
-		}(i, item)
-	}
-
-	// 等待所有任务完成
-	wg.Wait()
+	// 并行处理并等待所有任务完成（goroutine数同时受请求并发预算限制）
+	ParallelForEach(ctx, len(items), func(index int) {
+		// 获取信号量
+		sem <- struct{}{}
+		defer func() { <-sem }()
+
+		// 创建带超时的context
+		taskCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+
+		// 处理数据
+		if err := processor(taskCtx, items[index]); err != nil {
+			bp.logger.Error("并行处理失败",
+				"index", index,
+				"error", err.Error())
+			errChan <- err
+		}
+	})
 	close(errChan)
 
 	// 检查是否有错误
@@ -451,29 +441,15 @@ func (bp *BatchProcessor) OptimizedBatchGet(
 	}
 	defer stmt.Close()
 
-	// 并行查询（带并发控制）
-	// 注意：并发数可通过配置调整，这里使用默认值10
-	maxConcurrency := 10
-	sem := make(chan struct{}, maxConcurrency)
-	var wg sync.WaitGroup
+	// 并行查询（并发数受请求并发预算限制，见 worker_pool.max_goroutines_per_request）
 	errChan := make(chan error, len(ids))
 
-	for _, id := range ids {
-		wg.Add(1)
-		go func(itemID uint) {
-			defer wg.Done()
-
-			sem <- struct{}{}
-			defer func() { <-sem }()
-
-			row := stmt.QueryRowContext(ctx, itemID)
-			if err := scanFunc(row); err != nil && err != sql.ErrNoRows {
-				errChan <- err
-			}
-		}(id)
-	}
-
-	wg.Wait()
+	ParallelForEach(ctx, len(ids), func(i int) {
+		row := stmt.QueryRowContext(ctx, ids[i])
+		if err := scanFunc(row); err != nil && err != sql.ErrNoRows {
+			errChan <- err
+		}
+	})
 	close(errChan)
 
 	// 收集错误
@@ -535,35 +511,29 @@ func (bp *BatchProcessor) CachedBatchGet(
 	defer stmt.Close()
 
 	var mu sync.Mutex
-	var wg sync.WaitGroup
 	errChan := make(chan error, len(missingIDs))
 
-	for _, id := range missingIDs {
-		wg.Add(1)
-		go func(itemID uint) {
-			defer wg.Done()
-
-			row := stmt.QueryRowContext(ctx, itemID)
-			data, err := scanFunc(row, itemID)
-			if err != nil {
-				if err != sql.ErrNoRows {
-					errChan <- err
-				}
-				return
+	// 并行查询（并发数受请求并发预算限制）
+	ParallelForEach(ctx, len(missingIDs), func(i int) {
+		itemID := missingIDs[i]
+		row := stmt.QueryRowContext(ctx, itemID)
+		data, err := scanFunc(row, itemID)
+		if err != nil {
+			if err != sql.ErrNoRows {
+				errChan <- err
 			}
+			return
+		}
 
-			// 写入结果
-			mu.Lock()
-			result[itemID] = data
-			mu.Unlock()
-
-			// 写入缓存
-			cacheKey := fmt.Sprintf("%s%d", cacheKeyPrefix, itemID)
-			cache.SetWithTTL(cacheKey, data, cacheTTL)
-		}(id)
-	}
+		// 写入结果
+		mu.Lock()
+		result[itemID] = data
+		mu.Unlock()
 
-	wg.Wait()
+		// 写入缓存
+		cacheKey := fmt.Sprintf("%s%d", cacheKeyPrefix, itemID)
+		cache.SetWithTTL(cacheKey, data, cacheTTL)
+	})
 	close(errChan)
 
 	// 检查错误
@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"runtime"
+	"time"
+)
+
+// 构建信息，编译时通过 ldflags 注入，例如：
+//
+//	go build -ldflags "-X gin/internal/utils.GitCommit=$(git rev-parse --short HEAD) -X gin/internal/utils.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// 未注入时为空字符串
+var (
+	GitCommit string
+	BuildTime string
+)
+
+// processStartTime 进程启动时间（用于计算运行时长）
+var processStartTime = time.Now()
+
+// BuildInfo 构建与运行时信息（不包含任何配置密钥）
+type BuildInfo struct {
+	Name          string `json:"name"`
+	Version       string `json:"version"`
+	GoVersion     string `json:"go_version"`
+	GitCommit     string `json:"git_commit"`
+	BuildTime     string `json:"build_time"`
+	Mode          string `json:"mode"`
+	StartedAt     string `json:"started_at"`
+	UptimeSeconds int64  `json:"uptime_seconds"`
+}
+
+// GetBuildInfo 获取构建与运行时信息
+func GetBuildInfo(name, version, mode string) BuildInfo {
+	return BuildInfo{
+		Name:          name,
+		Version:       version,
+		GoVersion:     runtime.Version(),
+		GitCommit:     GitCommit,
+		BuildTime:     BuildTime,
+		Mode:          mode,
+		StartedAt:     processStartTime.UTC().Format(time.RFC3339),
+		UptimeSeconds: int64(time.Since(processStartTime).Seconds()),
+	}
+}
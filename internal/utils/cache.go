@@ -124,6 +124,32 @@ func (c *LRUCache) SetWithTTL(key string, value interface{}, ttl time.Duration)
 	atomic.AddInt64(&c.currentMem, item.Size)
 }
 
+// SetIfAbsent 键不存在（或已过期）时写入并返回true；键仍有效时不修改并返回false
+func (c *LRUCache) SetIfAbsent(key string, value interface{}, ttl time.Duration) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if elem, exists := c.items[key]; exists {
+		if !elem.Value.(*CacheItem).IsExpired() {
+			atomic.AddUint64(&c.hits, 1)
+			return false
+		}
+		c.removeElement(elem)
+	}
+	atomic.AddUint64(&c.misses, 1)
+
+	item := &CacheItem{
+		Key:        key,
+		Value:      value,
+		ExpireTime: time.Now().Add(ttl),
+		Size:       estimateSize(value),
+	}
+	c.evictIfNeeded(item.Size)
+	c.items[key] = c.lruList.PushFront(item)
+	atomic.AddInt64(&c.currentMem, item.Size)
+	return true
+}
+
 // Get 获取缓存项
 func (c *LRUCache) Get(key string) (interface{}, bool) {
 	c.mutex.Lock()
@@ -168,6 +194,23 @@ func (c *LRUCache) GetWithoutUpdate(key string) (interface{}, bool) {
 	return item.Value, true
 }
 
+// TTL 获取缓存项剩余有效期（不存在或已过期返回false，不更新LRU顺序和命中统计）
+func (c *LRUCache) TTL(key string) (time.Duration, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	elem, exists := c.items[key]
+	if !exists {
+		return 0, false
+	}
+
+	remaining := time.Until(elem.Value.(*CacheItem).ExpireTime)
+	if remaining <= 0 {
+		return 0, false
+	}
+	return remaining, true
+}
+
 // Delete 删除缓存项
 func (c *LRUCache) Delete(key string) {
 	c.mutex.Lock()
@@ -385,3 +428,42 @@ func GetCache() *MemoryCache {
 	})
 	return globalCache
 }
+
+// inflightCall 进行中的加载调用
+type inflightCall struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// CallGroup 合并同一键的并发加载（冷缓存时只有一个请求查库，其余等待并共享结果）
+type CallGroup struct {
+	mu    sync.Mutex
+	calls map[string]*inflightCall
+}
+
+// Do 执行 fn 并返回结果；同一键已有调用进行中时等待其结果，shared 表示结果来自其他调用
+func (g *CallGroup) Do(key string, fn func() (interface{}, error)) (value interface{}, err error, shared bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*inflightCall)
+	}
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		<-call.done
+		return call.value, call.err, true
+	}
+	// fn 发生panic时等待方收到 ErrServiceUnavailable，而不是空结果
+	call := &inflightCall{done: make(chan struct{}), err: ErrServiceUnavailable}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(call.done)
+	}()
+	call.value, call.err = fn()
+	return call.value, call.err, false
+}
@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCallGroupDo(t *testing.T) {
+	var g CallGroup
+	var calls atomic.Int32
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	var wg sync.WaitGroup
+	var sharedCount atomic.Int32
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err, shared := g.Do("key", func() (interface{}, error) {
+				calls.Add(1)
+				close(started)
+				<-release
+				return 42, nil
+			})
+			if value != 42 || err != nil {
+				t.Errorf("应共享加载结果，实际 %v %v", value, err)
+			}
+			if shared {
+				sharedCount.Add(1)
+			}
+		}()
+		if i == 0 {
+			<-started
+		}
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if calls.Load() != 1 || sharedCount.Load() != 4 {
+		t.Fatalf("同一键的并发调用应只执行一次，实际执行 %d 次，共享 %d 次", calls.Load(), sharedCount.Load())
+	}
+
+	// 调用结束后同一键可以再次执行；错误同样共享
+	loadErr := errors.New("load failed")
+	if _, err, shared := g.Do("key", func() (interface{}, error) { return nil, loadErr }); err != loadErr || shared {
+		t.Fatalf("调用结束后应重新执行，实际 %v shared=%v", err, shared)
+	}
+}
+
+func TestCallGroupDoPanic(t *testing.T) {
+	var g CallGroup
+	started, release := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer func() { _ = recover() }()
+		_, _, _ = g.Do("key", func() (interface{}, error) {
+			close(started)
+			<-release
+			panic("boom")
+		})
+	}()
+	<-started
+
+	done := make(chan error, 1)
+	go func() {
+		_, err, _ := g.Do("key", func() (interface{}, error) { return "unexpected", nil })
+		done <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	if err := <-done; !errors.Is(err, ErrServiceUnavailable) {
+		t.Fatalf("执行方panic时等待方应收到 ErrServiceUnavailable，实际 %v", err)
+	}
+}
@@ -0,0 +1,171 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// ContentFilter 内容过滤器（可替换实现，如接入第三方审核服务）
+type ContentFilter interface {
+	// Check 检查文本，返回命中的敏感词或规则（去重，未命中返回空）
+	Check(text string) []string
+	// Mask 将命中的部分替换为掩码字符
+	Mask(text string, mask rune) string
+}
+
+// KeywordFilter 基于敏感词和正则规则的过滤器
+// 匹配前做 NFKC 归一化（全角转半角、兼容字符转普通字符）并转小写；
+// 敏感词匹配时忽略空格、标点、符号和零宽字符，"加 微-信"、"ＡＢＣ" 等写法同样命中
+type KeywordFilter struct {
+	words    []string // 归一化后的敏感词
+	rawWords []string // 原始敏感词（用于返回命中结果）
+	patterns []*regexp.Regexp
+}
+
+// NewKeywordFilter 创建敏感词过滤器，正则规则无法编译时返回错误
+func NewKeywordFilter(words, patterns []string) (*KeywordFilter, error) {
+	f := &KeywordFilter{}
+	seen := make(map[string]bool, len(words))
+	for _, word := range words {
+		normalized := normalizeForMatch(word, true).text
+		if normalized == "" || seen[normalized] {
+			continue
+		}
+		seen[normalized] = true
+		f.words = append(f.words, normalized)
+		f.rawWords = append(f.rawWords, word)
+	}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("无效的审核正则 %q: %w", pattern, err)
+		}
+		f.patterns = append(f.patterns, re)
+	}
+	return f, nil
+}
+
+// Check 检查文本，返回命中的敏感词或规则
+func (f *KeywordFilter) Check(text string) []string {
+	hits, _ := f.find(text)
+	return hits
+}
+
+// Mask 将命中的部分替换为掩码字符（敏感词中间夹杂的空格符号一并替换）
+func (f *KeywordFilter) Mask(text string, mask rune) string {
+	_, marked := f.find(text)
+	if marked == nil {
+		return text
+	}
+	runes := []rune(text)
+	for i := range runes {
+		if marked[i] {
+			runes[i] = mask
+		}
+	}
+	return string(runes)
+}
+
+// find 查找命中项，返回命中列表和原文中需要替换的字符（按rune下标）
+func (f *KeywordFilter) find(text string) ([]string, []bool) {
+	if len(f.words) == 0 && len(f.patterns) == 0 {
+		return nil, nil
+	}
+
+	var hits []string
+	var marked []bool
+	mark := func(m *normalizedText, byteStart, byteEnd int) {
+		if marked == nil {
+			marked = make([]bool, m.origLen)
+		}
+		first, last := m.runeAt[byteStart], m.runeAt[byteEnd]-1
+		for j := m.origIdx[first]; j <= m.origIdx[last]; j++ {
+			marked[j] = true
+		}
+	}
+
+	if len(f.words) > 0 {
+		compact := normalizeForMatch(text, true)
+		for i, word := range f.words {
+			found := false
+			for offset := 0; offset < len(compact.text); {
+				idx := strings.Index(compact.text[offset:], word)
+				if idx < 0 {
+					break
+				}
+				start := offset + idx
+				mark(compact, start, start+len(word))
+				found = true
+				offset = start + len(word)
+			}
+			if found {
+				hits = append(hits, f.rawWords[i])
+			}
+		}
+	}
+
+	if len(f.patterns) > 0 {
+		folded := normalizeForMatch(text, false)
+		for _, re := range f.patterns {
+			locs := re.FindAllStringIndex(folded.text, -1)
+			for _, loc := range locs {
+				if loc[1] > loc[0] {
+					mark(folded, loc[0], loc[1])
+				}
+			}
+			if len(locs) > 0 {
+				hits = append(hits, re.String())
+			}
+		}
+	}
+
+	return hits, marked
+}
+
+// normalizedText 归一化后的文本及其与原文的位置映射
+type normalizedText struct {
+	text    string
+	origIdx []int // 第 i 个归一化字符对应的原文rune下标
+	byteOf  []int // 第 i 个归一化字符在 text 中的字节偏移
+	runeAt  []int // text 中字节偏移对应的归一化字符下标
+	origLen int   // 原文rune数
+}
+
+// normalizeForMatch 逐字符做 NFKC 归一化并转小写；compact 为 true 时去掉空格、标点、符号、
+// 组合附加符和零宽等格式字符
+func normalizeForMatch(text string, compact bool) *normalizedText {
+	m := &normalizedText{}
+	var sb strings.Builder
+	origIdx := 0
+	for _, r := range text {
+		for _, nr := range norm.NFKC.String(string(r)) {
+			nr = unicode.ToLower(nr)
+			if compact && isMatchSeparator(nr) {
+				continue
+			}
+			m.origIdx = append(m.origIdx, origIdx)
+			m.byteOf = append(m.byteOf, sb.Len())
+			sb.WriteRune(nr)
+		}
+		origIdx++
+	}
+	m.text = sb.String()
+	m.origLen = origIdx
+
+	m.runeAt = make([]int, len(m.text)+1)
+	for i, b := range m.byteOf {
+		m.runeAt[b] = i
+	}
+	m.runeAt[len(m.text)] = len(m.byteOf)
+	return m
+}
+
+// isMatchSeparator 判断字符是否在敏感词匹配时忽略
+func isMatchSeparator(r rune) bool {
+	return unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r) ||
+		unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Cf, r) || unicode.IsControl(r)
+}
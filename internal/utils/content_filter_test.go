@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"testing"
+)
+
+func TestKeywordFilterCheck(t *testing.T) {
+	f, err := NewKeywordFilter([]string{"加微信", "spam", "SPAM", ""}, []string{`\d{3}-\d{4}-\d{4}`})
+	if err != nil {
+		t.Fatalf("创建过滤器失败: %v", err)
+	}
+	cases := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"正常文本", "今天天气不错", nil},
+		{"直接命中", "有问题加微信聊", []string{"加微信"}},
+		{"字间夹杂空格和符号", "加 微-信。", []string{"加微信"}},
+		{"零宽字符", "加\u200b微\u200d信", []string{"加微信"}},
+		{"全角字母", "ＳＰＡＭ", []string{"spam"}},
+		{"大小写混写", "SpAm here", []string{"spam"}},
+		{"全角数字命中正则", "电话 １３８-１２３４-５６７８", []string{`\d{3}-\d{4}-\d{4}`}},
+		{"同时命中多项", "spam 加微信", []string{"加微信", "spam"}},
+	}
+	for _, tc := range cases {
+		got := f.Check(tc.text)
+		if len(got) != len(tc.want) {
+			t.Errorf("%s: Check(%q) = %v，期望 %v", tc.name, tc.text, got, tc.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("%s: Check(%q) = %v，期望 %v", tc.name, tc.text, got, tc.want)
+				break
+			}
+		}
+	}
+}
+
+func TestKeywordFilterMask(t *testing.T) {
+	f, err := NewKeywordFilter([]string{"加微信"}, []string{`\d{11}`})
+	if err != nil {
+		t.Fatalf("创建过滤器失败: %v", err)
+	}
+	cases := []struct {
+		text string
+		want string
+	}{
+		{"请加 微信联系", "请****联系"},
+		{"加微信，加微信", "***，***"},
+		{"号码13800138000。", "号码***********。"},
+		{"没有敏感词", "没有敏感词"},
+	}
+	for _, tc := range cases {
+		if got := f.Mask(tc.text, '*'); got != tc.want {
+			t.Errorf("Mask(%q) = %q，期望 %q", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestNewKeywordFilterRejectsInvalidPattern(t *testing.T) {
+	if _, err := NewKeywordFilter(nil, []string{`(`}); err == nil {
+		t.Fatal("无法编译的正则应返回错误")
+	}
+	f, err := NewKeywordFilter(nil, nil)
+	if err != nil || f.Check("任何内容") != nil {
+		t.Fatalf("空词表不应命中任何内容，实际 %v", err)
+	}
+}
@@ -0,0 +1,159 @@
+package utils
+
+import (
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"gin/internal/config"
+)
+
+// ContentModerator 内容审核：过滤敏感词，并对窗口内多次违规的用户临时禁言（只限制聊天）
+// 违规记录和禁言状态保存在内存中，单实例内有效
+type ContentModerator struct {
+	mu     sync.RWMutex
+	filter ContentFilter
+	cfg    config.ModerationConfig
+
+	stateMu    sync.Mutex
+	violations map[uint][]time.Time // 用户在窗口内的违规时间
+	mutedUntil map[uint]time.Time   // 用户禁言截止时间
+}
+
+// moderationStateSweepSize 违规记录超过该数量时清理过期条目
+const moderationStateSweepSize = 10000
+
+var globalContentModerator = &ContentModerator{
+	violations: make(map[uint][]time.Time),
+	mutedUntil: make(map[uint]time.Time),
+}
+
+// ConfigureContentModeration 按配置重建全局敏感词过滤器（启动和配置热更新时调用）
+func ConfigureContentModeration(cfg *config.ModerationConfig) error {
+	filter, err := NewKeywordFilter(cfg.Words, cfg.Patterns)
+	if err != nil {
+		return err
+	}
+
+	m := globalContentModerator
+	m.mu.Lock()
+	m.filter = filter
+	m.cfg = *cfg
+	m.mu.Unlock()
+	return nil
+}
+
+// SetContentFilter 替换全局内容过滤器（处理方式和禁言规则仍使用配置）
+func SetContentFilter(filter ContentFilter) {
+	m := globalContentModerator
+	m.mu.Lock()
+	m.filter = filter
+	m.mu.Unlock()
+}
+
+// ModerateTexts 审核同一次提交中的多段文本（如文章标题和正文）
+// reject 模式下命中时返回 ErrContentRejected；mask 模式下直接替换 texts 中的命中部分。
+// 每次提交只记一次违规，窗口内违规次数达到阈值时禁言
+func ModerateTexts(userID uint, texts ...*string) error {
+	return globalContentModerator.Moderate(userID, texts...)
+}
+
+// ChatMutedUntil 获取用户的聊天禁言截止时间，未被禁言返回 false
+func ChatMutedUntil(userID uint) (time.Time, bool) {
+	return globalContentModerator.MutedUntil(userID)
+}
+
+// Moderate 审核文本，见 ModerateTexts
+func (m *ContentModerator) Moderate(userID uint, texts ...*string) error {
+	m.mu.RLock()
+	filter, cfg := m.filter, m.cfg
+	m.mu.RUnlock()
+	if filter == nil || !cfg.Enabled {
+		return nil
+	}
+
+	var hits []string
+	for _, text := range texts {
+		if text == nil || *text == "" {
+			continue
+		}
+		hits = append(hits, filter.Check(*text)...)
+	}
+	if len(hits) == 0 {
+		return nil
+	}
+
+	muted := m.recordViolation(userID, &cfg)
+	GetLogger().Warn("内容审核命中", "userID", userID, "hits", strings.Join(hits, ","), "action", cfg.Action, "muted", muted)
+
+	if cfg.Action == "mask" {
+		mask, _ := utf8.DecodeRuneInString(cfg.MaskChar)
+		for _, text := range texts {
+			if text != nil && *text != "" {
+				*text = filter.Mask(*text, mask)
+			}
+		}
+		return nil
+	}
+	return ErrContentRejected
+}
+
+// MutedUntil 获取用户的禁言截止时间（已过期的禁言视为未禁言）
+func (m *ContentModerator) MutedUntil(userID uint) (time.Time, bool) {
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+
+	until, ok := m.mutedUntil[userID]
+	if !ok {
+		return time.Time{}, false
+	}
+	if !time.Now().Before(until) {
+		delete(m.mutedUntil, userID)
+		return time.Time{}, false
+	}
+	return until, true
+}
+
+// recordViolation 记录一次违规，达到阈值时禁言并清空计数，返回是否触发禁言
+func (m *ContentModerator) recordViolation(userID uint, cfg *config.ModerationConfig) bool {
+	now := time.Now()
+	window := time.Duration(cfg.ViolationWindowMinutes) * time.Minute
+
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+
+	if len(m.violations) > moderationStateSweepSize {
+		m.sweepLocked(now, window)
+	}
+
+	recent := make([]time.Time, 0, len(m.violations[userID])+1)
+	for _, t := range m.violations[userID] {
+		if now.Sub(t) < window {
+			recent = append(recent, t)
+		}
+	}
+	recent = append(recent, now)
+
+	if cfg.ViolationThreshold > 0 && len(recent) >= cfg.ViolationThreshold {
+		delete(m.violations, userID)
+		m.mutedUntil[userID] = now.Add(time.Duration(cfg.MuteMinutes) * time.Minute)
+		return true
+	}
+	m.violations[userID] = recent
+	return false
+}
+
+// sweepLocked 清理过期的违规记录和禁言（调用方需持有 stateMu）
+func (m *ContentModerator) sweepLocked(now time.Time, window time.Duration) {
+	for userID, times := range m.violations {
+		if len(times) == 0 || now.Sub(times[len(times)-1]) >= window {
+			delete(m.violations, userID)
+		}
+	}
+	for userID, until := range m.mutedUntil {
+		if !now.Before(until) {
+			delete(m.mutedUntil, userID)
+		}
+	}
+}
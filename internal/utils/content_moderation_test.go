@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gin/internal/config"
+)
+
+// newTestModerator 创建独立的审核器（不影响全局状态）
+func newTestModerator(t *testing.T, action string, threshold int) *ContentModerator {
+	t.Helper()
+	filter, err := NewKeywordFilter([]string{"广告"}, nil)
+	if err != nil {
+		t.Fatalf("创建过滤器失败: %v", err)
+	}
+	cfg := config.Default().Moderation
+	cfg.Action = action
+	cfg.ViolationThreshold = threshold
+	return &ContentModerator{
+		filter:     filter,
+		cfg:        cfg,
+		violations: make(map[uint][]time.Time),
+		mutedUntil: make(map[uint]time.Time),
+	}
+}
+
+func TestModerateRejectAndMask(t *testing.T) {
+	m := newTestModerator(t, "reject", 0)
+	title, body := "标题", "这是广告"
+	err := m.Moderate(1, &title, &body)
+	if !errors.Is(err, ErrContentRejected) || GetHTTPStatusCode(err) != 400 || GetErrorCode(err) != ErrCodeContentRejected {
+		t.Fatalf("reject 模式命中时应返回400，实际 %v", err)
+	}
+	if body != "这是广告" {
+		t.Fatalf("reject 模式不应修改文本，实际 %q", body)
+	}
+	clean := "正常内容"
+	if err := m.Moderate(1, &clean, nil); err != nil {
+		t.Fatalf("未命中时应通过，实际 %v", err)
+	}
+
+	m = newTestModerator(t, "mask", 0)
+	title, body = "广告标题", "正文"
+	if err := m.Moderate(1, &title, &body); err != nil {
+		t.Fatalf("mask 模式不应拒绝，实际 %v", err)
+	}
+	if title != "**标题" || body != "正文" {
+		t.Fatalf("mask 模式应只替换命中部分，实际 %q %q", title, body)
+	}
+
+	m.cfg.Enabled = false
+	body = "广告"
+	if err := m.Moderate(1, &body); err != nil || body != "广告" {
+		t.Fatalf("关闭审核后不应处理，实际 %q %v", body, err)
+	}
+}
+
+func TestModerateEscalatesToMute(t *testing.T) {
+	m := newTestModerator(t, "reject", 3)
+
+	// 一次提交多段文本只记一次违规
+	a, b := "广告", "广告"
+	_ = m.Moderate(1, &a, &b)
+	_ = m.Moderate(1, &a)
+	if _, muted := m.MutedUntil(1); muted {
+		t.Fatal("未达到阈值时不应禁言")
+	}
+	_ = m.Moderate(2, &a)
+	if _, muted := m.MutedUntil(2); muted {
+		t.Fatal("违规次数按用户分别计算")
+	}
+
+	_ = m.Moderate(1, &a)
+	until, muted := m.MutedUntil(1)
+	if !muted || time.Until(until) < 29*time.Minute {
+		t.Fatalf("窗口内第3次违规应禁言30分钟，实际 %v %v", until, muted)
+	}
+	if len(m.violations[1]) != 0 {
+		t.Fatal("禁言后应清空违规计数")
+	}
+
+	// 禁言过期后恢复
+	m.mutedUntil[1] = time.Now().Add(-time.Second)
+	if _, muted := m.MutedUntil(1); muted {
+		t.Fatal("禁言过期后应恢复")
+	}
+}
+
+func TestModerateViolationWindow(t *testing.T) {
+	m := newTestModerator(t, "reject", 2)
+	// 窗口之外的违规不计数
+	m.violations[1] = []time.Time{time.Now().Add(-11 * time.Minute)}
+
+	text := "广告"
+	_ = m.Moderate(1, &text)
+	if _, muted := m.MutedUntil(1); muted {
+		t.Fatal("窗口外的违规不应计入")
+	}
+	if len(m.violations[1]) != 1 {
+		t.Fatalf("应只保留窗口内的违规，实际 %v", m.violations[1])
+	}
+	_ = m.Moderate(1, &text)
+	if _, muted := m.MutedUntil(1); !muted {
+		t.Fatal("窗口内达到阈值应禁言")
+	}
+}
+
+func TestConfigureContentModerationReloadsWords(t *testing.T) {
+	t.Cleanup(func() { _ = ConfigureContentModeration(&config.Default().Moderation) })
+
+	cfg := config.Default().Moderation
+	cfg.Words = []string{"旧词"}
+	if err := ConfigureContentModeration(&cfg); err != nil {
+		t.Fatalf("配置审核失败: %v", err)
+	}
+	text := "旧词"
+	if err := ModerateTexts(100, &text); !errors.Is(err, ErrContentRejected) {
+		t.Fatalf("应拒绝配置的敏感词，实际 %v", err)
+	}
+
+	cfg.Words = []string{"新词"}
+	if err := ConfigureContentModeration(&cfg); err != nil {
+		t.Fatalf("重新加载失败: %v", err)
+	}
+	if err := ModerateTexts(100, &text); err != nil {
+		t.Fatalf("热更新后旧词不应再命中，实际 %v", err)
+	}
+
+	// 正则无效时保留原过滤器
+	bad := cfg
+	bad.Patterns = []string{`[`}
+	if err := ConfigureContentModeration(&bad); err == nil {
+		t.Fatal("无效正则应返回错误")
+	}
+	text = "新词"
+	if err := ModerateTexts(100, &text); !errors.Is(err, ErrContentRejected) {
+		t.Fatalf("加载失败时应继续使用原词表，实际 %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"strings"
+
+	"gin/internal/models"
+)
+
+// diffOpEqual 内部使用的相同行标记，用于分隔变化块，不出现在结果中
+const diffOpEqual = "equal"
+
+// diffMaxCells LCS 矩阵的最大单元数，超过后中间部分按整体替换处理，避免超长文本占用过多内存
+const diffMaxCells = 4 << 20
+
+// DiffLines 计算两段文本的行级差异，只返回有变化的行
+// 相邻的删除和新增按顺序两两合并为修改行
+func DiffLines(oldText, newText string) []models.DiffLine {
+	oldLines := splitLines(oldText)
+	newLines := splitLines(newText)
+
+	// 去掉公共前缀和后缀，缩小需要比较的范围
+	prefix := 0
+	for prefix < len(oldLines) && prefix < len(newLines) && oldLines[prefix] == newLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldLines)-prefix && suffix < len(newLines)-prefix &&
+		oldLines[len(oldLines)-1-suffix] == newLines[len(newLines)-1-suffix] {
+		suffix++
+	}
+	a := oldLines[prefix : len(oldLines)-suffix]
+	b := newLines[prefix : len(newLines)-suffix]
+
+	var ops []models.DiffLine
+	if len(a)*len(b) > diffMaxCells {
+		for i, line := range a {
+			ops = append(ops, models.DiffLine{Op: models.DiffOpRemove, OldLine: prefix + i + 1, OldText: line})
+		}
+		for j, line := range b {
+			ops = append(ops, models.DiffLine{Op: models.DiffOpAdd, NewLine: prefix + j + 1, NewText: line})
+		}
+	} else {
+		ops = lcsDiff(a, b, prefix)
+	}
+
+	return mergeChanges(ops)
+}
+
+// splitLines 按行拆分文本（统一换行符，空文本视为没有行）
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// lcsDiff 基于最长公共子序列计算相同/删除/新增行，offset 为已跳过的公共前缀行数
+func lcsDiff(a, b []string, offset int) []models.DiffLine {
+	n, m := len(a), len(b)
+	// lcs[i][j] 为 a[i:] 与 b[j:] 的最长公共子序列长度
+	lcs := make([][]int32, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var ops []models.DiffLine
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && a[i] == b[j]:
+			ops = append(ops, models.DiffLine{Op: diffOpEqual})
+			i++
+			j++
+		case j >= m || (i < n && lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, models.DiffLine{Op: models.DiffOpRemove, OldLine: offset + i + 1, OldText: a[i]})
+			i++
+		default:
+			ops = append(ops, models.DiffLine{Op: models.DiffOpAdd, NewLine: offset + j + 1, NewText: b[j]})
+			j++
+		}
+	}
+	return ops
+}
+
+// mergeChanges 将连续的删除行与紧随其后的新增行按顺序合并为修改行，并去掉相同行
+func mergeChanges(ops []models.DiffLine) []models.DiffLine {
+	result := make([]models.DiffLine, 0, len(ops))
+	for k := 0; k < len(ops); {
+		if ops[k].Op != models.DiffOpRemove {
+			if ops[k].Op != diffOpEqual {
+				result = append(result, ops[k])
+			}
+			k++
+			continue
+		}
+
+		removeEnd := k
+		for removeEnd < len(ops) && ops[removeEnd].Op == models.DiffOpRemove {
+			removeEnd++
+		}
+		addEnd := removeEnd
+		for addEnd < len(ops) && ops[addEnd].Op == models.DiffOpAdd {
+			addEnd++
+		}
+
+		removes, adds := ops[k:removeEnd], ops[removeEnd:addEnd]
+		paired := len(removes)
+		if len(adds) < paired {
+			paired = len(adds)
+		}
+		for p := 0; p < paired; p++ {
+			result = append(result, models.DiffLine{
+				Op:      models.DiffOpChange,
+				OldLine: removes[p].OldLine,
+				NewLine: adds[p].NewLine,
+				OldText: removes[p].OldText,
+				NewText: adds[p].NewText,
+			})
+		}
+		result = append(result, removes[paired:]...)
+		result = append(result, adds[paired:]...)
+		k = addEnd
+	}
+	return result
+}
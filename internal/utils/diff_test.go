@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	"gin/internal/models"
+)
+
+func TestDiffLines(t *testing.T) {
+	cases := []struct {
+		name     string
+		old, new string
+		want     []models.DiffLine
+	}{
+		{"内容相同", "a\nb\nc", "a\nb\nc", []models.DiffLine{}},
+		{"都为空", "", "", []models.DiffLine{}},
+		{"只有换行符不同", "a\r\nb\r\n", "a\nb", []models.DiffLine{}},
+		{"新增行", "a\nc", "a\nb\nc", []models.DiffLine{
+			{Op: models.DiffOpAdd, NewLine: 2, NewText: "b"},
+		}},
+		{"删除行", "a\nb\nc", "a\nc", []models.DiffLine{
+			{Op: models.DiffOpRemove, OldLine: 2, OldText: "b"},
+		}},
+		{"修改行", "a\nb\nc", "a\nB\nc", []models.DiffLine{
+			{Op: models.DiffOpChange, OldLine: 2, NewLine: 2, OldText: "b", NewText: "B"},
+		}},
+		{"删除多于新增时剩余部分为删除", "a\nb\nc\nd", "a\nX\nd", []models.DiffLine{
+			{Op: models.DiffOpChange, OldLine: 2, NewLine: 2, OldText: "b", NewText: "X"},
+			{Op: models.DiffOpRemove, OldLine: 3, OldText: "c"},
+		}},
+		{"从空文本新增", "", "a\nb", []models.DiffLine{
+			{Op: models.DiffOpAdd, NewLine: 1, NewText: "a"},
+			{Op: models.DiffOpAdd, NewLine: 2, NewText: "b"},
+		}},
+		{"多处变化保持各自行号", "a\nb\nc\nd\ne", "a\nc\nd\nE\ne\nf", []models.DiffLine{
+			{Op: models.DiffOpRemove, OldLine: 2, OldText: "b"},
+			{Op: models.DiffOpAdd, NewLine: 4, NewText: "E"},
+			{Op: models.DiffOpAdd, NewLine: 6, NewText: "f"},
+		}},
+	}
+	for _, tc := range cases {
+		got := DiffLines(tc.old, tc.new)
+		if len(got) == 0 && len(tc.want) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: DiffLines = %+v，期望 %+v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package utils
 import (
 	"errors"
 	"fmt"
+	"net/http"
 )
 
 // 定义常用错误
@@ -16,14 +17,16 @@ var (
 	ErrInvalidCredentials   = errors.New("用户名或密码错误")
 	ErrAccountDisabled      = errors.New("账户已被禁用")
 	ErrTooManyLoginAttempts = errors.New("登录尝试次数过多，请稍后再试")
+	ErrAccountLocked        = errors.New("账户已临时锁定，请稍后再试")
 
 	// 用户相关错误
-	ErrUserNotFound       = errors.New("用户不存在")
-	ErrUserAlreadyExists  = errors.New("用户已存在")
-	ErrEmailAlreadyExists = errors.New("邮箱已被注册")
-	ErrInvalidEmail       = errors.New("无效的邮箱格式")
-	ErrInvalidUsername    = errors.New("无效的用户名格式")
-	ErrInvalidPassword    = errors.New("无效的密码格式")
+	ErrUserNotFound         = errors.New("用户不存在")
+	ErrUserAlreadyExists    = errors.New("用户已存在")
+	ErrEmailAlreadyExists   = errors.New("邮箱已被注册")
+	ErrInvalidEmail         = errors.New("无效的邮箱格式")
+	ErrInvalidUsername      = errors.New("无效的用户名格式")
+	ErrInvalidPassword      = errors.New("无效的密码格式")
+	ErrRecipientUnavailable = errors.New("接收者账户不可用")
 
 	// 数据库相关错误
 	ErrDatabaseConnection = errors.New("数据库连接失败")
@@ -31,6 +34,8 @@ var (
 	ErrDatabaseInsert     = errors.New("数据库插入失败")
 	ErrDatabaseUpdate     = errors.New("数据库更新失败")
 	ErrDatabaseDelete     = errors.New("数据库删除失败")
+	ErrDuplicateEntry     = errors.New("数据已存在")
+	ErrVersionConflict    = errors.New("内容已被修改，请刷新后重试")
 
 	// 请求相关错误
 	ErrInvalidRequest       = errors.New("无效的请求")
@@ -39,6 +44,20 @@ var (
 	ErrValidationFailed     = errors.New("参数验证失败")
 	ErrRequestTooLarge      = errors.New("请求体过大")
 	ErrUnsupportedMediaType = errors.New("不支持的媒体类型")
+	ErrRedirectNotAllowed   = errors.New("跳转目标不在允许范围内")
+	ErrContentRejected      = errors.New("内容包含违规词汇，请修改后重试")
+	ErrUserMuted            = errors.New("您已被临时禁言")
+
+	// 图片相关错误
+	ErrInvalidImage     = errors.New("无效的图片文件")
+	ErrImageTooLarge    = errors.New("图片尺寸过大")
+	ErrImageAspectRatio = errors.New("图片宽高比不符合要求")
+
+	// 存储相关错误
+	ErrStorageQuotaExceeded = errors.New("超出存储配额")
+	ErrFileInfected         = errors.New("文件未通过安全扫描，已被拒绝")
+	ErrScanUnavailable      = errors.New("文件安全扫描暂不可用，请稍后重试")
+	ErrFileTooLargeToScan   = errors.New("文件超过安全扫描的大小上限，已被拒绝")
 
 	// 权限相关错误
 	ErrInsufficientPermissions = errors.New("权限不足")
@@ -51,6 +70,8 @@ var (
 	ErrServiceUnavailable  = errors.New("服务不可用")
 	ErrRateLimitExceeded   = errors.New("请求频率过高")
 	ErrMaintenanceMode     = errors.New("系统维护中")
+	ErrWorkerPoolClosed    = errors.New("worker pool已关闭")
+	ErrWorkerPoolQueueFull = errors.New("任务队列已满")
 
 	// 配置相关错误
 	ErrInvalidConfig  = errors.New("无效的配置")
@@ -64,27 +85,38 @@ const (
 	ErrCodeInvalidToken       = "INVALID_TOKEN"
 	ErrCodeTokenExpired       = "TOKEN_EXPIRED"
 	ErrCodeInvalidCredentials = "INVALID_CREDENTIALS"
+	ErrCodeAccountLocked      = "ACCOUNT_LOCKED"
 	ErrCodePermissionDenied   = "PERMISSION_DENIED"
 
 	// 用户管理
-	ErrCodeUserNotFound = "USER_NOT_FOUND"
-	ErrCodeUserExists   = "USER_EXISTS"
-	ErrCodeEmailExists  = "EMAIL_EXISTS"
+	ErrCodeUserNotFound         = "USER_NOT_FOUND"
+	ErrCodeUserExists           = "USER_EXISTS"
+	ErrCodeEmailExists          = "EMAIL_EXISTS"
+	ErrCodeRecipientUnavailable = "RECIPIENT_UNAVAILABLE"
 
 	// 数据验证
 	ErrCodeInvalidInput     = "INVALID_INPUT"
 	ErrCodeMissingParam     = "MISSING_PARAMETER"
 	ErrCodeValidationFailed = "VALIDATION_FAILED"
+	ErrCodeMessageTooLarge  = "MESSAGE_TOO_LARGE"
+	ErrCodeRequestTooLarge  = "REQUEST_TOO_LARGE"
 
 	// 文件上传
 	ErrCodeUploadInvalidType = "UPLOAD_INVALID_TYPE"
 	ErrCodeUploadTooLarge    = "UPLOAD_TOO_LARGE"
 	ErrCodeUploadFailed      = "UPLOAD_FAILED"
+	ErrCodeStorageQuota      = "STORAGE_QUOTA_EXCEEDED"
+	ErrCodeFileInfected      = "FILE_INFECTED"
 
 	// 数据库
-	ErrCodeDatabaseError  = "DATABASE_ERROR"
-	ErrCodeRecordNotFound = "RECORD_NOT_FOUND"
-	ErrCodeDuplicateEntry = "DUPLICATE_ENTRY"
+	ErrCodeDatabaseError   = "DATABASE_ERROR"
+	ErrCodeRecordNotFound  = "RECORD_NOT_FOUND"
+	ErrCodeDuplicateEntry  = "DUPLICATE_ENTRY"
+	ErrCodeVersionConflict = "VERSION_CONFLICT"
+
+	// 内容审核
+	ErrCodeContentRejected = "CONTENT_REJECTED"
+	ErrCodeUserMuted       = "USER_MUTED"
 
 	// 限流
 	ErrCodeRateLimitExceeded = "RATE_LIMIT_EXCEEDED"
@@ -142,12 +174,26 @@ func WrapError(err error, message string) error {
 	return fmt.Errorf("%s: %w", message, err)
 }
 
+// MaxBytesLimit 错误是否由请求体超过 http.MaxBytesReader 限制引起，是则返回该限制（字节）
+func MaxBytesLimit(err error) (int64, bool) {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return maxErr.Limit, true
+	}
+	return 0, false
+}
+
 // GetHTTPStatusCode 返回错误对应的HTTP状态码
 func GetHTTPStatusCode(err error) int {
 	if err == nil {
 		return 200
 	}
 
+	// 数据层错误按500处理（其中可能包装了驱动错误或事务内返回的其他错误）
+	if isDatabaseError(err) {
+		return 500
+	}
+
 	// 检查是否为AppError
 	var appErr *AppError
 	if errors.As(err, &appErr) {
@@ -160,36 +206,60 @@ func GetHTTPStatusCode(err error) int {
 		return 401
 	case errors.Is(err, ErrInvalidCredentials) || errors.Is(err, ErrAccountDisabled) || errors.Is(err, ErrTooManyLoginAttempts):
 		return 401
-	case errors.Is(err, ErrInsufficientPermissions) || errors.Is(err, ErrAccessDenied) || errors.Is(err, ErrUnauthorized):
+	case errors.Is(err, ErrAccountLocked):
+		return 423
+	case errors.Is(err, ErrInsufficientPermissions) || errors.Is(err, ErrAccessDenied) || errors.Is(err, ErrUnauthorized) ||
+		errors.Is(err, ErrUserMuted):
 		return 403
 	case errors.Is(err, ErrUserNotFound) || errors.Is(err, ErrResourceNotFound):
 		return 404
-	case errors.Is(err, ErrUserAlreadyExists) || errors.Is(err, ErrEmailAlreadyExists):
+	case errors.Is(err, ErrUserAlreadyExists) || errors.Is(err, ErrEmailAlreadyExists) || errors.Is(err, ErrDuplicateEntry) ||
+		errors.Is(err, ErrVersionConflict):
 		return 409
 	case errors.Is(err, ErrInvalidRequest) || errors.Is(err, ErrMissingParameter) ||
-		errors.Is(err, ErrInvalidParameter) || errors.Is(err, ErrValidationFailed):
+		errors.Is(err, ErrInvalidParameter) || errors.Is(err, ErrValidationFailed) || errors.Is(err, ErrRedirectNotAllowed) ||
+		errors.Is(err, ErrContentRejected):
 		return 400
 	case errors.Is(err, ErrInvalidUsername) || errors.Is(err, ErrInvalidEmail) || errors.Is(err, ErrInvalidPassword):
 		return 400
-	case errors.Is(err, ErrRequestTooLarge):
+	case errors.Is(err, ErrRequestTooLarge) || errors.Is(err, ErrImageTooLarge) || errors.Is(err, ErrStorageQuotaExceeded) ||
+		errors.Is(err, ErrFileTooLargeToScan):
 		return 413
-	case errors.Is(err, ErrUnsupportedMediaType):
+	case errors.Is(err, ErrImageAspectRatio):
+		return 400
+	case errors.Is(err, ErrFileInfected) || errors.Is(err, ErrRecipientUnavailable):
+		return 422
+	case errors.Is(err, ErrUnsupportedMediaType) || errors.Is(err, ErrInvalidImage):
 		return 415
 	case errors.Is(err, ErrRateLimitExceeded):
 		return 429
-	case errors.Is(err, ErrServiceUnavailable) || errors.Is(err, ErrMaintenanceMode):
+	case errors.Is(err, ErrServiceUnavailable) || errors.Is(err, ErrMaintenanceMode) || errors.Is(err, ErrScanUnavailable):
 		return 503
 	default:
 		return 500
 	}
 }
 
+// isDatabaseError 是否为数据层返回的数据库错误
+func isDatabaseError(err error) bool {
+	return errors.Is(err, ErrDatabaseConnection) || errors.Is(err, ErrDatabaseQuery) ||
+		errors.Is(err, ErrDatabaseInsert) || errors.Is(err, ErrDatabaseUpdate) || errors.Is(err, ErrDatabaseDelete)
+}
+
 // GetErrorCode 返回API响应的错误码字符串
 func GetErrorCode(err error) string {
 	if err == nil {
 		return ""
 	}
 
+	// 数据层错误只返回数据库错误码（不暴露其中包装的其他错误）
+	if isDatabaseError(err) {
+		if errors.Is(err, ErrDatabaseQuery) || errors.Is(err, ErrDatabaseConnection) {
+			return ErrCodeDatabaseError
+		}
+		return ErrCodeInternalError
+	}
+
 	// 检查自定义错误码
 	var appErr *AppError
 	if errors.As(err, &appErr) {
@@ -206,20 +276,38 @@ func GetErrorCode(err error) string {
 		return ErrCodeTokenExpired
 	case errors.Is(err, ErrInvalidCredentials):
 		return ErrCodeInvalidCredentials
+	case errors.Is(err, ErrAccountLocked):
+		return ErrCodeAccountLocked
 	case errors.Is(err, ErrUserNotFound):
 		return ErrCodeUserNotFound
+	case errors.Is(err, ErrRecipientUnavailable):
+		return ErrCodeRecipientUnavailable
 	case errors.Is(err, ErrUserAlreadyExists):
 		return ErrCodeUserExists
 	case errors.Is(err, ErrEmailAlreadyExists):
 		return ErrCodeEmailExists
+	case errors.Is(err, ErrDuplicateEntry):
+		return ErrCodeDuplicateEntry
+	case errors.Is(err, ErrVersionConflict):
+		return ErrCodeVersionConflict
+	case errors.Is(err, ErrFileInfected):
+		return ErrCodeFileInfected
+	case errors.Is(err, ErrContentRejected):
+		return ErrCodeContentRejected
+	case errors.Is(err, ErrUserMuted):
+		return ErrCodeUserMuted
 	case errors.Is(err, ErrInvalidParameter) || errors.Is(err, ErrValidationFailed):
 		return ErrCodeInvalidInput
 	case errors.Is(err, ErrMissingParameter):
 		return ErrCodeMissingParam
 	case errors.Is(err, ErrRateLimitExceeded):
 		return ErrCodeRateLimitExceeded
-	case errors.Is(err, ErrDatabaseQuery) || errors.Is(err, ErrDatabaseConnection):
-		return ErrCodeDatabaseError
+	case errors.Is(err, ErrStorageQuotaExceeded):
+		return ErrCodeStorageQuota
+	case errors.Is(err, ErrFileTooLargeToScan):
+		return ErrCodeUploadTooLarge
+	case errors.Is(err, ErrScanUnavailable):
+		return ErrCodeServiceUnavailable
 	default:
 		return ErrCodeInternalError
 	}
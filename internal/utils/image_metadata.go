@@ -0,0 +1,189 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// StripImageMetadata 移除图片中的隐私元数据（EXIF/XMP等，可能包含GPS位置和设备信息），返回处理后的数据和移除的字节数
+// JPEG 移除 APP1（EXIF/XMP）和 APP13（IPTC）段，保留方向信息以免照片显示旋转；
+// PNG 移除 eXIf 和文本块；WebP 移除 EXIF 和 XMP 块；其他格式或数据异常时原样返回
+func StripImageMetadata(data []byte) ([]byte, int) {
+	var stripped []byte
+	switch {
+	case len(data) >= 2 && data[0] == 0xFF && data[1] == 0xD8:
+		stripped = stripJPEGMetadata(data)
+	case bytes.HasPrefix(data, pngSignature):
+		stripped = stripPNGMetadata(data)
+	case isWebP(data):
+		stripped = stripWebPMetadata(data)
+	}
+	if stripped == nil || len(stripped) >= len(data) {
+		return data, 0
+	}
+	return stripped, len(data) - len(stripped)
+}
+
+// stripJPEGMetadata 逐段复制JPEG，跳过元数据段（遇到SOS后剩余的压缩数据原样复制），格式异常返回 nil
+func stripJPEGMetadata(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xFF, 0xD8)
+	orientation := 0
+	exifAt := len(out) // 方向信息段插入位置：SOI之后（有JFIF头时放在其后）
+
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return nil
+		}
+		marker := data[pos+1]
+		if marker == 0xFF { // 填充字节
+			pos++
+			continue
+		}
+		if marker == 0xD9 || marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) { // 无长度的独立标记
+			out = append(out, data[pos:pos+2]...)
+			pos += 2
+			continue
+		}
+
+		end := pos + 2 + int(binary.BigEndian.Uint16(data[pos+2:pos+4]))
+		if end > len(data) || end < pos+4 {
+			return nil
+		}
+		segment := data[pos:end]
+
+		switch marker {
+		case 0xE1: // APP1：EXIF 或 XMP
+			if o := exifOrientation(segment[4:]); o > 1 {
+				orientation = o
+			}
+		case 0xED: // APP13：Photoshop/IPTC
+		case 0xDA: // SOS：之后是压缩数据，原样复制
+			if orientation > 1 {
+				exif := minimalOrientationEXIF(orientation)
+				out = append(out[:exifAt], append(exif, out[exifAt:]...)...)
+			}
+			return append(out, data[pos:]...)
+		default:
+			if marker == 0xE0 && exifAt == len(out) {
+				exifAt += len(segment)
+			}
+			out = append(out, segment...)
+		}
+		pos = end
+	}
+	return nil
+}
+
+// exifOrientation 从APP1段数据中读取IFD0的方向标签（0x0112），不存在返回0
+func exifOrientation(payload []byte) int {
+	if !bytes.HasPrefix(payload, []byte("Exif\x00\x00")) {
+		return 0
+	}
+	tiff := payload[6:]
+	if len(tiff) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(tiff[0:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd+2 > len(tiff) {
+		return 0
+	}
+	count := int(order.Uint16(tiff[ifd : ifd+2]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:entry+2]) == 0x0112 {
+			if o := int(order.Uint16(tiff[entry+8 : entry+10])); o >= 1 && o <= 8 {
+				return o
+			}
+			return 0
+		}
+	}
+	return 0
+}
+
+// minimalOrientationEXIF 构造只包含方向标签的APP1段
+func minimalOrientationEXIF(orientation int) []byte {
+	seg := []byte{
+		0xFF, 0xE1, 0x00, 0x22, // APP1，长度34
+		'E', 'x', 'i', 'f', 0x00, 0x00,
+		'M', 'M', 0x00, 0x2A, 0x00, 0x00, 0x00, 0x08, // TIFF头（大端），IFD0偏移8
+		0x00, 0x01, // 1个条目
+		0x01, 0x12, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, // Orientation, SHORT, 1
+		0x00, 0x00, 0x00, 0x00, // 无下一个IFD
+	}
+	seg[29] = byte(orientation)
+	return seg
+}
+
+// stripPNGMetadata 移除PNG的 eXIf/tEXt/zTXt/iTXt 块，格式异常返回 nil
+func stripPNGMetadata(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	out = append(out, pngSignature...)
+
+	pos := len(pngSignature)
+	for pos+12 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[pos : pos+4]))
+		end := pos + 12 + length
+		if length < 0 || end > len(data) {
+			return nil
+		}
+		switch string(data[pos+4 : pos+8]) {
+		case "eXIf", "tEXt", "zTXt", "iTXt":
+		default:
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+	}
+	if pos != len(data) {
+		return nil
+	}
+	return out
+}
+
+// stripWebPMetadata 移除WebP的 EXIF/XMP 块并清除VP8X中对应的标志位，格式异常返回 nil
+func stripWebPMetadata(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:12]...)
+
+	pos := 12
+	for pos+8 <= len(data) {
+		size := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		end := pos + 8 + size + size%2 // 块数据按偶数字节对齐
+		if size < 0 || end > len(data) {
+			return nil
+		}
+		switch string(data[pos : pos+4]) {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunk := append([]byte(nil), data[pos:end]...)
+			if len(chunk) > 8 {
+				chunk[8] &^= 0x08 | 0x04 // EXIF、XMP 标志位
+			}
+			out = append(out, chunk...)
+		default:
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+	}
+	if pos != len(data) {
+		return nil
+	}
+	binary.LittleEndian.PutUint32(out[4:8], uint32(len(out)-8))
+	return out
+}
@@ -0,0 +1,138 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// exifSegment 构造包含方向标签和GPS信息的APP1段
+func exifSegment(orientation int) []byte {
+	tiff := []byte{
+		'M', 'M', 0x00, 0x2A, 0x00, 0x00, 0x00, 0x08,
+		0x00, 0x02, // 2个条目
+		0x01, 0x12, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01, 0x00, byte(orientation), 0x00, 0x00, // Orientation
+		0x88, 0x25, 0x00, 0x04, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, // GPSInfo
+		0x00, 0x00, 0x00, 0x00,
+	}
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	payload = append(payload, []byte("GPS 31.2304N 121.4737E iPhone")...)
+	seg := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(seg[2:], uint16(len(payload)+2))
+	return append(seg, payload...)
+}
+
+func testJPEG(t *testing.T) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	if err := jpeg.Encode(buf, image.NewGray(image.Rect(0, 0, 16, 16)), nil); err != nil {
+		t.Fatalf("生成JPEG失败: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// withSegment 在SOI之后插入段
+func withSegment(jpg, segment []byte) []byte {
+	return append(append(append([]byte{}, jpg[:2]...), segment...), jpg[2:]...)
+}
+
+func TestStripImageMetadataJPEG(t *testing.T) {
+	plain := testJPEG(t)
+	withExif := withSegment(plain, exifSegment(1))
+
+	out, removed := StripImageMetadata(withExif)
+	if removed != len(withExif)-len(plain) || !bytes.Equal(out, plain) {
+		t.Fatalf("应移除整个EXIF段，实际移除 %d 字节", removed)
+	}
+	if bytes.Contains(out, []byte("GPS")) {
+		t.Fatal("处理后不应包含GPS信息")
+	}
+
+	if out, removed := StripImageMetadata(plain); removed != 0 || !bytes.Equal(out, plain) {
+		t.Fatal("没有元数据的JPEG应原样返回")
+	}
+}
+
+func TestStripImageMetadataJPEGKeepsOrientation(t *testing.T) {
+	out, removed := StripImageMetadata(withSegment(testJPEG(t), exifSegment(6)))
+	if removed == 0 || bytes.Contains(out, []byte("GPS")) {
+		t.Fatalf("应移除GPS等信息，实际移除 %d 字节", removed)
+	}
+	// 插入的最小EXIF段紧跟在SOI之后
+	if out[2] != 0xFF || out[3] != 0xE1 || exifOrientation(out[6:]) != 6 {
+		t.Fatal("应保留方向信息，避免照片显示旋转")
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(out)); err != nil {
+		t.Fatalf("处理后应仍是有效的JPEG: %v", err)
+	}
+}
+
+func TestStripImageMetadataPNG(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, image.NewGray(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatalf("生成PNG失败: %v", err)
+	}
+	plain := buf.Bytes()
+
+	// 在IHDR之后插入tEXt块
+	text := []byte("tEXtComment\x00taken at home")
+	chunk := make([]byte, 4, 12+len(text)-4)
+	binary.BigEndian.PutUint32(chunk, uint32(len(text)-4))
+	chunk = append(chunk, text...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(text))
+	ihdrEnd := 8 + 25
+	withText := append(append(append([]byte{}, plain[:ihdrEnd]...), chunk...), plain[ihdrEnd:]...)
+
+	out, removed := StripImageMetadata(withText)
+	if removed != len(chunk) || !bytes.Equal(out, plain) {
+		t.Fatalf("应移除文本块，实际移除 %d 字节", removed)
+	}
+	if _, err := png.Decode(bytes.NewReader(out)); err != nil {
+		t.Fatalf("处理后应仍是有效的PNG: %v", err)
+	}
+}
+
+func TestStripImageMetadataWebP(t *testing.T) {
+	chunk := func(fourCC string, data []byte) []byte {
+		c := append([]byte(fourCC), 0, 0, 0, 0)
+		binary.LittleEndian.PutUint32(c[4:], uint32(len(data)))
+		c = append(c, data...)
+		if len(data)%2 == 1 {
+			c = append(c, 0)
+		}
+		return c
+	}
+	vp8x := chunk("VP8X", []byte{0x08 | 0x04 | 0x10, 0, 0, 0, 9, 0, 0, 9, 0, 0}) // EXIF、XMP、Alpha 标志
+	webp := append([]byte("RIFF\x00\x00\x00\x00WEBP"), vp8x...)
+	webp = append(webp, chunk("VP8L", []byte{0x2f, 1, 2, 3, 4})...)
+	webp = append(webp, chunk("EXIF", []byte("GPS data"))...)
+	webp = append(webp, chunk("XMP ", []byte("<xmp/>"))...)
+	binary.LittleEndian.PutUint32(webp[4:8], uint32(len(webp)-8))
+
+	out, removed := StripImageMetadata(webp)
+	if removed == 0 || bytes.Contains(out, []byte("EXIF")) || bytes.Contains(out, []byte("XMP ")) {
+		t.Fatalf("应移除EXIF和XMP块，实际移除 %d 字节", removed)
+	}
+	if flags := out[20]; flags&(0x08|0x04) != 0 || flags&0x10 == 0 {
+		t.Fatalf("应只清除EXIF和XMP标志位，实际 %#x", flags)
+	}
+	if size := binary.LittleEndian.Uint32(out[4:8]); int(size) != len(out)-8 {
+		t.Fatalf("RIFF长度应更新为 %d，实际 %d", len(out)-8, size)
+	}
+}
+
+func TestStripImageMetadataLeavesOtherDataUnchanged(t *testing.T) {
+	for _, data := range [][]byte{
+		[]byte("GIF89a not really"),
+		{0xFF, 0xD8, 0x00, 0x01, 0x02},                         // 损坏的JPEG
+		append(append([]byte{}, pngSignature...), 0, 0, 0, 99), // 截断的PNG
+	} {
+		if out, removed := StripImageMetadata(data); removed != 0 || !bytes.Equal(out, data) {
+			t.Fatalf("无法识别或格式异常的数据应原样返回: %q", data)
+		}
+	}
+}
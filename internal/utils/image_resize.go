@@ -0,0 +1,112 @@
+package utils
+
+import (
+	"bytes"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"io"
+)
+
+// ResizeSquareJPEG 解码图片，居中裁剪为正方形并缩放到 size×size（原图更小时不放大），重新编码为JPEG
+func ResizeSquareJPEG(r io.Reader, size, quality int) ([]byte, error) {
+	src, _, err := image.Decode(r)
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+
+	// 居中裁剪为正方形
+	bounds := src.Bounds()
+	side := bounds.Dx()
+	if bounds.Dy() < side {
+		side = bounds.Dy()
+	}
+	if side <= 0 {
+		return nil, ErrInvalidImage
+	}
+	cropMin := image.Pt(bounds.Min.X+(bounds.Dx()-side)/2, bounds.Min.Y+(bounds.Dy()-side)/2)
+	cropped := image.NewRGBA(image.Rect(0, 0, side, side))
+	// 先铺白底，透明PNG转为JPEG后不会变黑
+	draw.Draw(cropped, cropped.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(cropped, cropped.Bounds(), src, cropMin, draw.Over)
+
+	dst := cropped
+	if side > size {
+		dst = downscaleBox(cropped, size, size)
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, WrapError(err, "图片编码失败")
+	}
+	return buf.Bytes(), nil
+}
+
+// ThumbnailJPEG 解码图片并等比缩小到最长边为 maxEdge，重新编码为JPEG
+// 动图只取第一帧；原图最长边不超过 maxEdge 时返回 false，表示无需生成缩略图
+func ThumbnailJPEG(r io.Reader, maxEdge, quality int) ([]byte, bool, error) {
+	src, _, err := image.Decode(r)
+	if err != nil {
+		return nil, false, ErrInvalidImage
+	}
+
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	if srcW <= 0 || srcH <= 0 {
+		return nil, false, ErrInvalidImage
+	}
+	if srcW <= maxEdge && srcH <= maxEdge {
+		return nil, false, nil
+	}
+
+	dstW, dstH := maxEdge, srcH*maxEdge/srcW
+	if srcH > srcW {
+		dstW, dstH = srcW*maxEdge/srcH, maxEdge
+	}
+	if dstW < 1 {
+		dstW = 1
+	}
+	if dstH < 1 {
+		dstH = 1
+	}
+
+	canvas := image.NewRGBA(image.Rect(0, 0, srcW, srcH))
+	draw.Draw(canvas, canvas.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(canvas, canvas.Bounds(), src, bounds.Min, draw.Over)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, downscaleBox(canvas, dstW, dstH), &jpeg.Options{Quality: quality}); err != nil {
+		return nil, false, WrapError(err, "图片编码失败")
+	}
+	return buf.Bytes(), true, nil
+}
+
+// downscaleBox 按区域平均将图片缩小到 width×height（缩小时比最近邻更平滑）
+func downscaleBox(src *image.RGBA, width, height int) *image.RGBA {
+	srcW, srcH := src.Bounds().Dx(), src.Bounds().Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for dy := 0; dy < height; dy++ {
+		y0, y1 := dy*srcH/height, (dy+1)*srcH/height
+		for dx := 0; dx < width; dx++ {
+			x0, x1 := dx*srcW/width, (dx+1)*srcW/width
+			var sumR, sumG, sumB, sumA, count uint64
+			for y := y0; y < y1; y++ {
+				row := src.Pix[y*src.Stride:]
+				for x := x0; x < x1; x++ {
+					p := row[x*4 : x*4+4]
+					sumR += uint64(p[0])
+					sumG += uint64(p[1])
+					sumB += uint64(p[2])
+					sumA += uint64(p[3])
+					count++
+				}
+			}
+			o := dst.PixOffset(dx, dy)
+			dst.Pix[o] = uint8(sumR / count)
+			dst.Pix[o+1] = uint8(sumG / count)
+			dst.Pix[o+2] = uint8(sumB / count)
+			dst.Pix[o+3] = uint8(sumA / count)
+		}
+	}
+	return dst
+}
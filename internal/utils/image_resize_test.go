@@ -0,0 +1,139 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// encodePNG 将图片编码为PNG
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, img); err != nil {
+		t.Fatalf("编码PNG失败: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// decodeJPEG 解码JPEG，失败时测试失败
+func decodeJPEG(t *testing.T, data []byte) image.Image {
+	t.Helper()
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("输出应为JPEG: %v", err)
+	}
+	return img
+}
+
+func TestResizeSquareJPEGCropsAndScales(t *testing.T) {
+	// 左中右三等分为红绿蓝，居中裁剪后应只剩绿色
+	src := image.NewRGBA(image.Rect(0, 0, 900, 300))
+	for x := 0; x < 900; x++ {
+		c := color.RGBA{255, 0, 0, 255}
+		if x >= 300 && x < 600 {
+			c = color.RGBA{0, 255, 0, 255}
+		} else if x >= 600 {
+			c = color.RGBA{0, 0, 255, 255}
+		}
+		for y := 0; y < 300; y++ {
+			src.Set(x, y, c)
+		}
+	}
+
+	out, err := ResizeSquareJPEG(bytes.NewReader(encodePNG(t, src)), 128, 85)
+	if err != nil {
+		t.Fatalf("处理图片失败: %v", err)
+	}
+	img := decodeJPEG(t, out)
+	if b := img.Bounds(); b.Dx() != 128 || b.Dy() != 128 {
+		t.Fatalf("应缩放为128×128，实际 %v", b)
+	}
+	for _, p := range []image.Point{{1, 1}, {64, 64}, {126, 126}} {
+		r, g, b, _ := img.At(p.X, p.Y).RGBA()
+		if g>>8 < 200 || r>>8 > 60 || b>>8 > 60 {
+			t.Fatalf("应居中裁剪，(%d,%d) 应为绿色，实际 %d %d %d", p.X, p.Y, r>>8, g>>8, b>>8)
+		}
+	}
+}
+
+func TestResizeSquareJPEGDoesNotUpscale(t *testing.T) {
+	out, err := ResizeSquareJPEG(bytes.NewReader(encodePNG(t, image.NewGray(image.Rect(0, 0, 80, 100)))), 256, 85)
+	if err != nil {
+		t.Fatalf("处理图片失败: %v", err)
+	}
+	if b := decodeJPEG(t, out).Bounds(); b.Dx() != 80 || b.Dy() != 80 {
+		t.Fatalf("小图只裁剪不放大，应为80×80，实际 %v", b)
+	}
+}
+
+func TestResizeSquareJPEGTransparentBecomesWhite(t *testing.T) {
+	out, err := ResizeSquareJPEG(bytes.NewReader(encodePNG(t, image.NewNRGBA(image.Rect(0, 0, 50, 50)))), 256, 85)
+	if err != nil {
+		t.Fatalf("处理图片失败: %v", err)
+	}
+	if r, g, b, _ := decodeJPEG(t, out).At(25, 25).RGBA(); r>>8 < 240 || g>>8 < 240 || b>>8 < 240 {
+		t.Fatalf("透明背景应转为白色，实际 %d %d %d", r>>8, g>>8, b>>8)
+	}
+}
+
+func TestResizeSquareJPEGRejectsInvalidImage(t *testing.T) {
+	if _, err := ResizeSquareJPEG(strings.NewReader("not an image"), 256, 85); !errors.Is(err, ErrInvalidImage) {
+		t.Fatalf("无法解码时应返回 ErrInvalidImage，实际 %v", err)
+	}
+}
+
+func TestThumbnailJPEG(t *testing.T) {
+	out, ok, err := ThumbnailJPEG(bytes.NewReader(encodePNG(t, image.NewGray(image.Rect(0, 0, 1000, 500)))), 320, 80)
+	if err != nil || !ok {
+		t.Fatalf("大图应生成缩略图，实际 %v %v", ok, err)
+	}
+	if b := decodeJPEG(t, out).Bounds(); b.Dx() != 320 || b.Dy() != 160 {
+		t.Fatalf("应等比缩小到最长边320，实际 %v", b)
+	}
+
+	out, ok, err = ThumbnailJPEG(bytes.NewReader(encodePNG(t, image.NewGray(image.Rect(0, 0, 200, 900)))), 320, 80)
+	if err != nil || !ok {
+		t.Fatalf("竖图应生成缩略图，实际 %v %v", ok, err)
+	}
+	if b := decodeJPEG(t, out).Bounds(); b.Dx() != 71 || b.Dy() != 320 {
+		t.Fatalf("竖图应按高度缩小，实际 %v", b)
+	}
+
+	if out, ok, err := ThumbnailJPEG(bytes.NewReader(encodePNG(t, image.NewGray(image.Rect(0, 0, 320, 100)))), 320, 80); err != nil || ok || out != nil {
+		t.Fatalf("原图不超过目标尺寸时应跳过，实际 %v %v", ok, err)
+	}
+
+	if _, _, err := ThumbnailJPEG(strings.NewReader("not an image"), 320, 80); !errors.Is(err, ErrInvalidImage) {
+		t.Fatalf("无法解码时应返回 ErrInvalidImage，实际 %v", err)
+	}
+}
+
+func TestThumbnailJPEGUsesFirstGIFFrame(t *testing.T) {
+	palette := color.Palette{color.White, color.RGBA{255, 0, 0, 255}, color.RGBA{0, 0, 255, 255}}
+	frame := func(index uint8) *image.Paletted {
+		img := image.NewPaletted(image.Rect(0, 0, 640, 640), palette)
+		for i := range img.Pix {
+			img.Pix[i] = index
+		}
+		return img
+	}
+	buf := &bytes.Buffer{}
+	if err := gif.EncodeAll(buf, &gif.GIF{Image: []*image.Paletted{frame(1), frame(2)}, Delay: []int{10, 10}}); err != nil {
+		t.Fatalf("生成GIF失败: %v", err)
+	}
+
+	out, ok, err := ThumbnailJPEG(buf, 320, 80)
+	if err != nil || !ok {
+		t.Fatalf("动图应生成缩略图，实际 %v %v", ok, err)
+	}
+	if r, _, b, _ := decodeJPEG(t, out).At(160, 160).RGBA(); r>>8 < 200 || b>>8 > 60 {
+		t.Fatalf("动图应取第一帧（红色），实际 r=%d b=%d", r>>8, b>>8)
+	}
+}
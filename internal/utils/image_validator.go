@@ -0,0 +1,149 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	_ "image/gif"  // 注册GIF解码器
+	_ "image/jpeg" // 注册JPEG解码器
+	_ "image/png"  // 注册PNG解码器
+	"io"
+	"mime/multipart"
+	"strings"
+)
+
+// ImageInfo 图片头部信息
+type ImageInfo struct {
+	Format string // 实际格式：png/jpeg/gif/webp
+	Width  int
+	Height int
+}
+
+// ImageValidator 图片验证器：解码图片头部，校验真实格式、尺寸和宽高比
+type ImageValidator struct {
+	MaxWidth       int     // 最大宽度（像素，0表示不限制）
+	MaxHeight      int     // 最大高度（像素，0表示不限制）
+	MaxAspectRatio float64 // 长边/短边的最大比值（0表示不限制）
+}
+
+// NewImageValidator 创建图片验证器
+func NewImageValidator(maxWidth, maxHeight int, maxAspectRatio float64) *ImageValidator {
+	return &ImageValidator{
+		MaxWidth:       maxWidth,
+		MaxHeight:      maxHeight,
+		MaxAspectRatio: maxAspectRatio,
+	}
+}
+
+// Validate 验证图片文件（只读取头部，不解码像素数据）
+// 无法解码或声明的MIME与真实格式不符返回 ErrInvalidImage，尺寸超限返回 ErrImageTooLarge，
+// 宽高比超限返回 ErrImageAspectRatio
+func (iv *ImageValidator) Validate(fileHeader *multipart.FileHeader) (*ImageInfo, error) {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, WrapError(err, "无法打开文件")
+	}
+	defer file.Close()
+
+	info, err := decodeImageInfo(file)
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+
+	if declared := declaredImageFormat(fileHeader.Header.Get("Content-Type")); declared != "" && declared != info.Format {
+		return info, ErrInvalidImage
+	}
+
+	if info.Width <= 0 || info.Height <= 0 {
+		return info, ErrInvalidImage
+	}
+	if (iv.MaxWidth > 0 && info.Width > iv.MaxWidth) || (iv.MaxHeight > 0 && info.Height > iv.MaxHeight) {
+		return info, ErrImageTooLarge
+	}
+	if iv.MaxAspectRatio > 0 {
+		long, short := info.Width, info.Height
+		if short > long {
+			long, short = short, long
+		}
+		if float64(long)/float64(short) > iv.MaxAspectRatio {
+			return info, ErrImageAspectRatio
+		}
+	}
+
+	return info, nil
+}
+
+// decodeImageInfo 解码图片头部获取格式和尺寸
+func decodeImageInfo(r io.Reader) (*ImageInfo, error) {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(30)
+	if isWebP(head) {
+		width, height, err := decodeWebPSize(head)
+		if err != nil {
+			return nil, err
+		}
+		return &ImageInfo{Format: "webp", Width: width, Height: height}, nil
+	}
+
+	cfg, format, err := image.DecodeConfig(br)
+	if err != nil {
+		return nil, err
+	}
+	return &ImageInfo{Format: format, Width: cfg.Width, Height: cfg.Height}, nil
+}
+
+// declaredImageFormat 将声明的MIME类型转换为格式名（非图片类型返回空，表示不校验）
+func declaredImageFormat(contentType string) string {
+	mimeType := strings.ToLower(strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0]))
+	switch mimeType {
+	case "image/png":
+		return "png"
+	case "image/jpeg", "image/jpg", "image/pjpeg":
+		return "jpeg"
+	case "image/gif":
+		return "gif"
+	case "image/webp":
+		return "webp"
+	}
+	if strings.HasPrefix(mimeType, "image/") {
+		return mimeType // 其他图片类型一定与真实格式不符
+	}
+	return ""
+}
+
+// isWebP 检查是否为WebP文件头（RIFF....WEBP）
+func isWebP(head []byte) bool {
+	return len(head) >= 12 && bytes.Equal(head[0:4], []byte("RIFF")) && bytes.Equal(head[8:12], []byte("WEBP"))
+}
+
+// decodeWebPSize 从WebP文件头解析画布尺寸（支持 VP8/VP8L/VP8X）
+func decodeWebPSize(head []byte) (int, int, error) {
+	if len(head) < 30 {
+		return 0, 0, errors.New("webp头部不完整")
+	}
+	switch string(head[12:16]) {
+	case "VP8 ":
+		// 有损格式：帧标签后是起始码 9d 01 2a，随后是14位宽高
+		if !bytes.Equal(head[23:26], []byte{0x9d, 0x01, 0x2a}) {
+			return 0, 0, errors.New("无效的VP8头部")
+		}
+		width := int(binary.LittleEndian.Uint16(head[26:28]) & 0x3fff)
+		height := int(binary.LittleEndian.Uint16(head[28:30]) & 0x3fff)
+		return width, height, nil
+	case "VP8L":
+		// 无损格式：签名0x2f后依次是14位(宽-1)和14位(高-1)
+		if head[20] != 0x2f {
+			return 0, 0, errors.New("无效的VP8L头部")
+		}
+		bits := binary.LittleEndian.Uint32(head[21:25])
+		return int(bits&0x3fff) + 1, int((bits>>14)&0x3fff) + 1, nil
+	case "VP8X":
+		// 扩展格式：4字节标志后是24位(宽-1)和24位(高-1)
+		width := int(head[24]) | int(head[25])<<8 | int(head[26])<<16
+		height := int(head[27]) | int(head[28])<<8 | int(head[29])<<16
+		return width + 1, height + 1, nil
+	}
+	return 0, 0, errors.New("未知的webp格式")
+}
@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http/httptest"
+	"net/textproto"
+	"testing"
+)
+
+// newImageFileHeader 构造上传文件头（声明的Content-Type可与内容不符）
+func newImageFileHeader(t *testing.T, contentType string, data []byte) *multipart.FileHeader {
+	t.Helper()
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", `form-data; name="file"; filename="upload"`)
+	header.Set("Content-Type", contentType)
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		t.Fatalf("创建表单失败: %v", err)
+	}
+	part.Write(data)
+	writer.Close()
+
+	req := httptest.NewRequest("POST", "/upload", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	if err := req.ParseMultipartForm(1 << 20); err != nil {
+		t.Fatalf("解析表单失败: %v", err)
+	}
+	return req.MultipartForm.File["file"][0]
+}
+
+func pngBytes(t *testing.T, width, height int) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatalf("生成PNG失败: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// webpLosslessHeader 构造VP8L格式的WebP文件头
+func webpLosslessHeader(width, height int) []byte {
+	head := make([]byte, 30)
+	copy(head[0:4], "RIFF")
+	copy(head[8:12], "WEBP")
+	copy(head[12:16], "VP8L")
+	head[20] = 0x2f
+	binary.LittleEndian.PutUint32(head[21:25], uint32(width-1)|uint32(height-1)<<14)
+	return head
+}
+
+func TestImageValidatorValidate(t *testing.T) {
+	avatar := NewImageValidator(1024, 1024, 1.2)
+	cases := []struct {
+		name        string
+		contentType string
+		data        []byte
+		wantErr     error
+	}{
+		{"正方形PNG", "image/png", pngBytes(t, 200, 200), nil},
+		{"宽高比在范围内", "image/png", pngBytes(t, 240, 200), nil},
+		{"WebP", "image/webp", webpLosslessHeader(300, 300), nil},
+		{"宽度超限", "image/png", pngBytes(t, 1025, 1000), ErrImageTooLarge},
+		{"宽高比过大", "image/png", pngBytes(t, 400, 200), ErrImageAspectRatio},
+		{"声明的MIME与真实格式不符", "image/jpeg", pngBytes(t, 200, 200), ErrInvalidImage},
+		{"声明为未知图片类型", "image/bmp", pngBytes(t, 200, 200), ErrInvalidImage},
+		{"无法解码", "image/png", []byte("not an image at all"), ErrInvalidImage},
+		{"截断的PNG", "image/png", pngBytes(t, 200, 200)[:20], ErrInvalidImage},
+	}
+	for _, tc := range cases {
+		info, err := avatar.Validate(newImageFileHeader(t, tc.contentType, tc.data))
+		if !errors.Is(err, tc.wantErr) {
+			t.Errorf("%s: 期望错误 %v，实际 %v", tc.name, tc.wantErr, err)
+		}
+		if tc.wantErr == nil && (info == nil || info.Width == 0) {
+			t.Errorf("%s: 应返回图片尺寸，实际 %+v", tc.name, info)
+		}
+	}
+}
+
+func TestImageValidatorWithoutLimits(t *testing.T) {
+	info, err := NewImageValidator(0, 0, 0).Validate(newImageFileHeader(t, "image/png", pngBytes(t, 3000, 100)))
+	if err != nil || info.Format != "png" || info.Width != 3000 || info.Height != 100 {
+		t.Fatalf("不限制尺寸和宽高比时应通过，实际 %+v %v", info, err)
+	}
+}
+
+func TestImageErrorsStatusCodes(t *testing.T) {
+	if code := GetHTTPStatusCode(ErrImageTooLarge); code != 413 {
+		t.Fatalf("尺寸超限应返回413，实际 %d", code)
+	}
+	if code := GetHTTPStatusCode(ErrImageAspectRatio); code != 400 {
+		t.Fatalf("宽高比不符应返回400，实际 %d", code)
+	}
+	if code := GetHTTPStatusCode(ErrInvalidImage); code != 415 {
+		t.Fatalf("无效图片应返回415，实际 %d", code)
+	}
+}
@@ -0,0 +1,143 @@
+package utils
+
+import (
+	"fmt"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// 日志输出格式
+const (
+	LogFormatJSON = "json"
+	LogFormatText = "text"
+)
+
+// newLogEncoder 按格式创建编码器（json 使用生产配置，text 使用便于阅读的控制台格式）
+func newLogEncoder(format string) zapcore.Encoder {
+	var encoderConfig zapcore.EncoderConfig
+	if format == LogFormatJSON {
+		encoderConfig = zap.NewProductionEncoderConfig()
+	} else {
+		encoderConfig = zap.NewDevelopmentEncoderConfig()
+	}
+
+	// 自定义时间格式
+	encoderConfig.TimeKey = "timestamp"
+	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	encoderConfig.LevelKey = "level"
+	encoderConfig.MessageKey = "message"
+	encoderConfig.CallerKey = "caller"
+	encoderConfig.EncodeCaller = zapcore.ShortCallerEncoder
+
+	if format == LogFormatJSON {
+		return zapcore.NewJSONEncoder(encoderConfig)
+	}
+	return zapcore.NewConsoleEncoder(encoderConfig)
+}
+
+// logEncoderSwitch 可在运行时替换的编码器，日志器的所有 Core 以及 With 派生的子日志器共享同一个实例
+type logEncoderSwitch struct {
+	mu      sync.RWMutex
+	format  string
+	encoder zapcore.Encoder
+}
+
+func newLogEncoderSwitch(format string) *logEncoderSwitch {
+	return &logEncoderSwitch{format: format, encoder: newLogEncoder(format)}
+}
+
+// set 替换编码器，已编码写出的日志不受影响
+func (s *logEncoderSwitch) set(format string) {
+	encoder := newLogEncoder(format)
+	s.mu.Lock()
+	s.format = format
+	s.encoder = encoder
+	s.mu.Unlock()
+}
+
+// clone 复制当前编码器（编码器带状态，每次写入使用独立副本）
+func (s *logEncoderSwitch) clone() zapcore.Encoder {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.encoder.Clone()
+}
+
+// switchableCore 与 zapcore.NewCore 行为一致，但每次写入时从 logEncoderSwitch 取当前编码器，
+// With 附加的字段保存为字段列表，切换格式后子日志器也按新格式输出
+type switchableCore struct {
+	zapcore.LevelEnabler
+	encoders *logEncoderSwitch
+	fields   []zapcore.Field
+	out      zapcore.WriteSyncer
+}
+
+func newSwitchableCore(encoders *logEncoderSwitch, out zapcore.WriteSyncer, enab zapcore.LevelEnabler) zapcore.Core {
+	return &switchableCore{LevelEnabler: enab, encoders: encoders, out: out}
+}
+
+// With 返回附加字段的副本（不修改原 Core 的字段列表）
+func (c *switchableCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = append(c.fields[:len(c.fields):len(c.fields)], fields...)
+	return &clone
+}
+
+func (c *switchableCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *switchableCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	enc := c.encoders.clone()
+	for i := range c.fields {
+		c.fields[i].AddTo(enc)
+	}
+	buf, err := enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	_, err = c.out.Write(buf.Bytes())
+	buf.Free()
+	if err != nil {
+		return err
+	}
+	if ent.Level > zapcore.ErrorLevel {
+		// 与 zapcore 一致：Panic/Fatal 写入后立即同步，避免进程退出丢日志
+		_ = c.out.Sync()
+	}
+	return nil
+}
+
+func (c *switchableCore) Sync() error {
+	return c.out.Sync()
+}
+
+// SetFormat 运行时切换日志格式（json/text）
+// 只替换编码器：写入器、级别过滤和输出目标保持不变，切换前已写出的日志保持原格式，不会丢失
+func (l *ZapLogger) SetFormat(format string) error {
+	if format != LogFormatJSON && format != LogFormatText {
+		return fmt.Errorf("不支持的日志格式: %s（可选 json、text）", format)
+	}
+	l.encoders.set(format)
+	return nil
+}
+
+// Format 当前日志格式
+func (l *ZapLogger) Format() string {
+	l.encoders.mu.RLock()
+	defer l.encoders.mu.RUnlock()
+	return l.encoders.format
+}
+
+// SetLogFormat 运行时切换全局日志器的输出格式
+func SetLogFormat(format string) error {
+	zl, ok := GetLogger().(*ZapLogger)
+	if !ok {
+		return fmt.Errorf("当前日志器不支持动态切换格式")
+	}
+	return zl.SetFormat(format)
+}
@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+
+	"gin/internal/config"
+)
+
+func TestSetFormatSwitchesEncoder(t *testing.T) {
+	readLines := captureLogLines(t, &config.LogConfig{Level: "debug", Format: LogFormatJSON, Output: "stdout"})
+	logger := GetLogger()
+	child := logger.With("module", "chat")
+
+	logger.Info("切换前", "step", 1)
+	if err := SetLogFormat(LogFormatText); err != nil {
+		t.Fatalf("切换为 text 失败: %v", err)
+	}
+	logger.Info("切换后", "step", 2)
+	child.Warn("子日志器", "step", 3) // 切换前创建的子日志器也按新格式输出
+	if err := SetLogFormat(LogFormatJSON); err != nil {
+		t.Fatalf("切换回 json 失败: %v", err)
+	}
+	child.Info("切回", "step", 4)
+
+	lines := readLines()
+	if len(lines) != 4 {
+		t.Fatalf("切换格式不应丢失日志，应有4行，实际 %q", lines)
+	}
+	isJSON := func(line string) bool { return json.Valid([]byte(line)) }
+	if !isJSON(lines[0]) || !strings.Contains(lines[0], "切换前") {
+		t.Fatalf("切换前的日志应为JSON，实际 %q", lines[0])
+	}
+	for _, line := range lines[1:3] {
+		if isJSON(line) || !strings.Contains(line, `"step": `) {
+			t.Fatalf("切换为 text 后应输出控制台格式，实际 %q", line)
+		}
+	}
+	if !strings.Contains(lines[2], "子日志器") || !strings.Contains(lines[2], `"module": "chat"`) {
+		t.Fatalf("子日志器的固定字段应保留，实际 %q", lines[2])
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[3]), &entry); err != nil || entry["module"] != "chat" || entry["message"] != "切回" {
+		t.Fatalf("切回 json 后子日志器应输出带固定字段的JSON，实际 %q", lines[3])
+	}
+}
+
+func TestSetFormatRejectsUnknown(t *testing.T) {
+	captureLogLines(t, &config.LogConfig{Level: "info", Format: LogFormatJSON, Output: "stdout"})
+	logger := GetLogger().(*ZapLogger)
+	if err := logger.SetFormat("xml"); err == nil {
+		t.Fatal("未知的日志格式应被拒绝")
+	}
+	if logger.Format() != LogFormatJSON {
+		t.Fatalf("拒绝切换后应保持原格式，实际 %q", logger.Format())
+	}
+	if err := logger.SetFormat(LogFormatText); err != nil || logger.Format() != LogFormatText {
+		t.Fatalf("切换为 text 失败: %v", err)
+	}
+}
+
+func TestSetFormatConcurrentWrites(t *testing.T) {
+	readLines := captureLogLines(t, &config.LogConfig{Level: "info", Format: LogFormatJSON, Output: "stdout"})
+	logger := GetLogger()
+
+	const writers, perWriter = 4, 50
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perWriter; j++ {
+				logger.Info("并发写入", "seq", j)
+			}
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		format := LogFormatText
+		if i%2 == 1 {
+			format = LogFormatJSON
+		}
+		if err := SetLogFormat(format); err != nil {
+			t.Fatalf("切换格式失败: %v", err)
+		}
+	}
+	wg.Wait()
+
+	// 每条日志完整地按某一种格式输出
+	lines := readLines()
+	if len(lines) != writers*perWriter {
+		t.Fatalf("并发切换格式时不应丢失日志，应有 %d 行，实际 %d", writers*perWriter, len(lines))
+	}
+	for _, line := range lines {
+		if !json.Valid([]byte(line)) && !strings.Contains(line, "并发写入") {
+			t.Fatalf("日志行不完整: %q", line)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"gin/internal/config"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// redactedValue 脱敏后的占位值
+const redactedValue = "***"
+
+// logRedactor 日志脱敏规则：字段名（或SQL列名）归一化后等于或以某个敏感键结尾时脱敏
+// 归一化为小写并去掉 _ 和 -，因此 password 同时匹配 old_password、newPassword 等
+type logRedactor struct {
+	keys []string // 归一化后的敏感键
+}
+
+// currentLogRedactor 当前生效的脱敏规则（启动和配置热更新时替换）
+var currentLogRedactor atomic.Pointer[logRedactor]
+
+func init() {
+	currentLogRedactor.Store(newLogRedactor(config.DefaultSensitiveLogKeys))
+}
+
+// ConfigureLogRedaction 按配置重建日志脱敏规则（空列表表示关闭脱敏）
+func ConfigureLogRedaction(keys []string) {
+	currentLogRedactor.Store(newLogRedactor(keys))
+}
+
+func newLogRedactor(keys []string) *logRedactor {
+	r := &logRedactor{keys: make([]string, 0, len(keys))}
+	for _, key := range keys {
+		if normalized := normalizeLogKey(key); normalized != "" {
+			r.keys = append(r.keys, normalized)
+		}
+	}
+	return r
+}
+
+// normalizeLogKey 字段名归一化：小写并去掉 _、- 和反引号
+func normalizeLogKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '_', '-', '`':
+			return -1
+		}
+		if r >= 'A' && r <= 'Z' {
+			return r + ('a' - 'A')
+		}
+		return r
+	}, key)
+}
+
+// match 返回字段名命中的敏感键（归一化后）
+func (r *logRedactor) match(key string) (string, bool) {
+	if len(r.keys) == 0 {
+		return "", false
+	}
+	normalized := normalizeLogKey(key)
+	for _, k := range r.keys {
+		if strings.HasSuffix(normalized, k) {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+// maskLogValue 脱敏单个值：邮箱保留前两位和域名便于排查，其余替换为占位值；nil 和空字符串保持不变
+func maskLogValue(matched string, v interface{}) interface{} {
+	switch val := v.(type) {
+	case nil:
+		return nil
+	case string:
+		if val == "" {
+			return val
+		}
+		if matched == "email" {
+			return SanitizeEmail(val)
+		}
+	}
+	return redactedValue
+}
+
+// redactFields 脱敏键值对形式的日志字段（同时支持 zap.Field），无需脱敏时原样返回
+func (r *logRedactor) redactFields(fields []interface{}) []interface{} {
+	if len(r.keys) == 0 {
+		return fields
+	}
+
+	var out []interface{} // 首次需要修改时才复制
+	set := func(i int, v interface{}) {
+		if out == nil {
+			out = append([]interface{}(nil), fields...)
+		}
+		out[i] = v
+	}
+
+	for i := 0; i < len(fields); {
+		if f, ok := fields[i].(zap.Field); ok {
+			if matched, hit := r.match(f.Key); hit {
+				if f.Type == zapcore.StringType && matched == "email" {
+					set(i, zap.String(f.Key, SanitizeEmail(f.String)))
+				} else {
+					set(i, zap.String(f.Key, redactedValue))
+				}
+			}
+			i++
+			continue
+		}
+		if i+1 >= len(fields) {
+			break
+		}
+		if key, ok := fields[i].(string); ok {
+			if matched, hit := r.match(key); hit {
+				set(i+1, maskLogValue(matched, fields[i+1]))
+			} else if m, ok := fields[i+1].(map[string]interface{}); ok {
+				if redacted := r.redactMap(m); redacted != nil {
+					set(i+1, redacted)
+				}
+			}
+		}
+		i += 2
+	}
+
+	if out == nil {
+		return fields
+	}
+	return out
+}
+
+// redactMap 脱敏嵌套的 map 字段，无需脱敏时返回 nil
+func (r *logRedactor) redactMap(m map[string]interface{}) map[string]interface{} {
+	var out map[string]interface{}
+	for k, v := range m {
+		var next interface{}
+		if matched, hit := r.match(k); hit {
+			next = maskLogValue(matched, v)
+		} else if nested, ok := v.(map[string]interface{}); ok {
+			redacted := r.redactMap(nested)
+			if redacted == nil {
+				continue
+			}
+			next = redacted
+		} else {
+			continue
+		}
+
+		if out == nil {
+			out = make(map[string]interface{}, len(m))
+			for key, val := range m {
+				out[key] = val
+			}
+		}
+		out[k] = next
+	}
+	return out
+}
+
+// redactLogFields 按当前规则脱敏日志字段（ZapLogger 写日志前调用）
+func redactLogFields(fields []interface{}) []interface{} {
+	return currentLogRedactor.Load().redactFields(fields)
+}
+
+// sqlInsertColumnsRegex 匹配 INSERT/REPLACE 的列清单，结束位置为 VALUES 之后
+var sqlInsertColumnsRegex = regexp.MustCompile("(?is)^\\s*(?:insert|replace)\\s+(?:ignore\\s+)?into\\s+[\\w.`]+\\s*\\(([^)]*)\\)\\s*values\\s*")
+
+// sqlComparisonColumnRegex 匹配占位符前的 `列 操作符`（含 IN 列表中已出现的占位符）
+var sqlComparisonColumnRegex = regexp.MustCompile("(?i)([\\w.`]+)\\s*(?:<=>|!=|<>|<=|>=|=|<|>|(?:\\s+not)?\\s+like|(?:\\s+not)?\\s+in\\s*\\((?:\\s*\\?\\s*,)*)\\s*$")
+
+// RedactSQLParams 按占位符对应的列名脱敏SQL参数（如 email = ?、INSERT 列清单中的 password_hash），
+// 用于慢查询等会输出参数的日志；无法确定列名的参数保持不变，无需脱敏时返回原切片
+func RedactSQLParams(query string, params []interface{}) []interface{} {
+	r := currentLogRedactor.Load()
+	if len(params) == 0 || len(r.keys) == 0 {
+		return params
+	}
+
+	// 快速路径：语句中没有出现任何敏感列名
+	normalizedQuery := normalizeLogKey(query)
+	mentioned := false
+	for _, k := range r.keys {
+		if strings.Contains(normalizedQuery, k) {
+			mentioned = true
+			break
+		}
+	}
+	if !mentioned {
+		return params
+	}
+
+	var out []interface{}
+	for i, column := range sqlPlaceholderColumns(query) {
+		if i >= len(params) {
+			break
+		}
+		if column == "" {
+			continue
+		}
+		if matched, hit := r.match(column); hit {
+			if out == nil {
+				out = append([]interface{}(nil), params...)
+			}
+			out[i] = maskLogValue(matched, params[i])
+		}
+	}
+	if out == nil {
+		return params
+	}
+	return out
+}
+
+// sqlPlaceholderColumns 按顺序返回每个 ? 占位符对应的列名（无法确定时为空字符串）
+func sqlPlaceholderColumns(query string) []string {
+	var insertColumns []string
+	valuesStart := -1
+	if m := sqlInsertColumnsRegex.FindStringSubmatchIndex(query); m != nil {
+		for _, col := range strings.Split(query[m[2]:m[3]], ",") {
+			insertColumns = append(insertColumns, strings.Trim(strings.TrimSpace(col), "`"))
+		}
+		valuesStart = m[1]
+	}
+
+	var columns []string
+	inValues := valuesStart >= 0
+	depth, col := 0, 0
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		ch := query[i]
+		if quote != 0 {
+			if ch == '\\' {
+				i++
+			} else if ch == quote {
+				quote = 0
+			}
+			continue
+		}
+		if ch == '\'' || ch == '"' {
+			quote = ch
+			continue
+		}
+
+		if inValues && i >= valuesStart {
+			switch {
+			case ch == '(':
+				depth++
+				if depth == 1 {
+					col = 0
+				}
+				continue
+			case ch == ')':
+				depth--
+				continue
+			case ch == ',' && depth == 1:
+				col++
+				continue
+			case depth == 0 && ch != ',' && ch != ' ' && ch != '\t' && ch != '\n' && ch != '\r':
+				inValues = false // VALUES 列表结束（如 ON DUPLICATE KEY UPDATE）
+			}
+		}
+
+		if ch != '?' {
+			continue
+		}
+		if inValues && depth >= 1 {
+			name := ""
+			if col < len(insertColumns) {
+				name = insertColumns[col]
+			}
+			columns = append(columns, name)
+			continue
+		}
+		name := ""
+		if m := sqlComparisonColumnRegex.FindStringSubmatch(query[:i]); m != nil {
+			name = m[1]
+			if dot := strings.LastIndexByte(name, '.'); dot >= 0 {
+				name = name[dot+1:]
+			}
+		}
+		columns = append(columns, name)
+	}
+	return columns
+}
@@ -0,0 +1,199 @@
+package utils
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gin/internal/config"
+
+	"go.uber.org/zap"
+)
+
+// useLogRedaction 按 keys 配置日志脱敏，测试结束时恢复默认规则
+func useLogRedaction(t *testing.T, keys []string) {
+	t.Helper()
+	t.Cleanup(func() { ConfigureLogRedaction(config.DefaultSensitiveLogKeys) })
+	ConfigureLogRedaction(keys)
+}
+
+// captureLogs 把全局日志器换成写入临时文件的 JSON 日志器，返回读取已写入日志的函数
+func captureLogs(t *testing.T, cfg *config.LogConfig) func() []map[string]interface{} {
+	t.Helper()
+	readLines := captureLogLines(t, cfg)
+	return func() []map[string]interface{} {
+		t.Helper()
+		var entries []map[string]interface{}
+		for _, line := range readLines() {
+			var entry map[string]interface{}
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				t.Fatalf("日志不是合法的JSON: %q", line)
+			}
+			entries = append(entries, entry)
+		}
+		return entries
+	}
+}
+
+// captureLogLines 把全局日志器换成写入临时文件的日志器，返回按行读取已写入日志的函数
+func captureLogLines(t *testing.T, cfg *config.LogConfig) func() []string {
+	t.Helper()
+	file, err := os.Create(filepath.Join(t.TempDir(), "stdout.log"))
+	if err != nil {
+		t.Fatalf("创建日志文件失败: %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = file // stdout 输出的写入目标在创建日志器时确定
+	err = InitLogger(cfg)
+	os.Stdout = stdout
+	if err != nil {
+		t.Fatalf("初始化日志器失败: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = CloseLogger()
+		file.Close()
+	})
+
+	return func() []string {
+		t.Helper()
+		data, err := os.ReadFile(file.Name())
+		if err != nil {
+			t.Fatalf("读取日志失败: %v", err)
+		}
+		var lines []string
+		scanner := bufio.NewScanner(strings.NewReader(string(data)))
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		return lines
+	}
+}
+
+func TestRedactLogFields(t *testing.T) {
+	useLogRedaction(t, config.DefaultSensitiveLogKeys)
+
+	nested := map[string]interface{}{"api_key": "k", "name": "n", "inner": map[string]interface{}{"phone": "123"}}
+	fields := []interface{}{
+		"userID", 1,
+		"newPassword", "secret",
+		"email", "alice@example.com",
+		"refresh_token", "", // 空值保持不变，便于排查缺失的参数
+		"data", nested,
+		zap.String("Authorization", "Bearer x"),
+		zap.String("userEmail", "bob@example.com"),
+	}
+	got := redactLogFields(fields)
+
+	want := []interface{}{
+		"userID", 1,
+		"newPassword", redactedValue,
+		"email", "al***@example.com",
+		"refresh_token", "",
+		"data", map[string]interface{}{"api_key": redactedValue, "name": "n", "inner": map[string]interface{}{"phone": redactedValue}},
+		zap.String("Authorization", redactedValue),
+		zap.String("userEmail", "bo*@example.com"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("脱敏结果不正确:\n实际 %v\n期望 %v", got, want)
+	}
+	if fields[3] != "secret" || nested["api_key"] != "k" {
+		t.Fatal("脱敏不应修改调用方的字段")
+	}
+
+	// 没有敏感字段时原样返回，不复制
+	plain := []interface{}{"userID", 1, "path", "/api"}
+	if got := redactLogFields(plain); &got[0] != &plain[0] {
+		t.Fatal("无需脱敏时应返回原切片")
+	}
+
+	// 空列表关闭脱敏
+	useLogRedaction(t, nil)
+	if got := redactLogFields(fields); got[3] != "secret" {
+		t.Fatalf("关闭脱敏后应原样输出，实际 %v", got)
+	}
+}
+
+func TestRedactSQLParams(t *testing.T) {
+	useLogRedaction(t, config.DefaultSensitiveLogKeys)
+
+	cases := []struct {
+		name   string
+		query  string
+		params []interface{}
+		want   []interface{}
+	}{
+		{"比较条件", "SELECT id FROM user_auth WHERE email = ? AND status = ?",
+			[]interface{}{"alice@example.com", 1}, []interface{}{"al***@example.com", 1}},
+		{"带表别名的IN列表", "SELECT id FROM user_auth ua WHERE ua.id = ? AND ua.`email` IN (?, ?)",
+			[]interface{}{5, "alice@example.com", "x"}, []interface{}{5, "al***@example.com", "***@***"}},
+		{"INSERT列清单", "INSERT INTO user_auth (username, password_hash, email) VALUES (?, ?, ?)",
+			[]interface{}{"alice", "$2a$10$hash", "alice@example.com"}, []interface{}{"alice", redactedValue, "al***@example.com"}},
+		{"INSERT之后的更新子句", "INSERT INTO api_tokens (user_id, token) VALUES (?, ?) ON DUPLICATE KEY UPDATE token = ?",
+			[]interface{}{1, "a", "b"}, []interface{}{1, redactedValue, redactedValue}},
+		{"UPDATE赋值", "UPDATE user_auth SET password_hash = ?, updated_at = ? WHERE id = ?",
+			[]interface{}{"$2a$10$hash", "now", 5}, []interface{}{redactedValue, "now", 5}},
+		{"字符串中的问号不是占位符", "SELECT id FROM notes WHERE body = 'email = ?' AND token = ?",
+			[]interface{}{"t"}, []interface{}{redactedValue}},
+	}
+	for _, tc := range cases {
+		if got := RedactSQLParams(tc.query, tc.params); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: 实际 %v，期望 %v", tc.name, got, tc.want)
+		}
+	}
+
+	params := []interface{}{5, "title"}
+	if got := RedactSQLParams("SELECT id FROM articles WHERE id = ? AND title = ?", params); &got[0] != &params[0] {
+		t.Fatal("语句不涉及敏感列时应返回原切片")
+	}
+	if params[1] != "title" {
+		t.Fatal("脱敏不应修改调用方的参数")
+	}
+}
+
+func TestSanitizeParamsUsesConfiguredKeys(t *testing.T) {
+	useLogRedaction(t, []string{"nickname"})
+	got := SanitizeParams(map[string]interface{}{"user_nickname": "bob", "password": "p"})
+	if got["user_nickname"] != redactedValue || got["password"] != "p" {
+		t.Fatalf("应按配置的字段脱敏，实际 %v", got)
+	}
+}
+
+func TestLoggerRedactsFields(t *testing.T) {
+	useLogRedaction(t, config.DefaultSensitiveLogKeys)
+	readLogs := captureLogs(t, &config.LogConfig{Level: "debug", Format: "json", Output: "stdout"})
+
+	logger := GetLogger()
+	logger.Info("登录成功", "email", "alice@example.com", "accessToken", "jwt")
+	logger.Warn("刷新失败", map[string]interface{}{"password": "p"})
+	logger.Error("请求失败", "Authorization", "Bearer x", "path", "/api")
+
+	entries := readLogs()
+	if len(entries) != 3 {
+		t.Fatalf("应写入3条日志，实际 %v", entries)
+	}
+	if entries[0]["email"] != "al***@example.com" || entries[0]["accessToken"] != redactedValue {
+		t.Errorf("Info 日志应脱敏邮箱和令牌，实际 %v", entries[0])
+	}
+	if entries[1]["password"] != redactedValue {
+		t.Errorf("Warn 日志应脱敏密码，实际 %v", entries[1])
+	}
+	if entries[2]["Authorization"] != redactedValue || entries[2]["path"] != "/api" {
+		t.Errorf("Error 日志应只脱敏认证头，实际 %v", entries[2])
+	}
+}
+
+func TestSlowQueryDetectorRedactsParams(t *testing.T) {
+	useLogRedaction(t, config.DefaultSensitiveLogKeys)
+	d := NewSlowQueryDetector(time.Millisecond, 10, 50)
+	d.Record("SELECT id FROM user_auth WHERE email = ?", time.Second, []interface{}{"alice@example.com"})
+
+	records := d.GetSlowQueries()
+	if len(records) != 1 || !reflect.DeepEqual(records[0].Params, []interface{}{"al***@example.com"}) {
+		t.Fatalf("慢查询记录的参数应脱敏，实际 %+v", records)
+	}
+}
@@ -23,6 +23,7 @@ type Logger interface {
 	Error(msg string, fields ...interface{})
 	Debug(msg string, fields ...interface{})
 	Fatal(msg string, fields ...interface{})
+	With(fields ...interface{}) Logger // 返回附带固定字段的子日志器
 	Close() error
 }
 
@@ -232,59 +233,46 @@ func (w *levelRotateWriter) Close() error {
 	return nil
 }
 
-// ZapLogger 基于 Zap 的日志器实现
+// ZapLogger 基于 Zap 的日志器实现（写入前按 log_extended.sensitive_keys 脱敏字段）
 type ZapLogger struct {
-	logger  *zap.Logger
-	sugar   *zap.SugaredLogger
-	config  *config.LogConfig
-	writers []*levelRotateWriter // 持有所有写入器以便关闭
-	mu      sync.Mutex
+	logger   *zap.Logger
+	sugar    *zap.SugaredLogger
+	config   *config.LogConfig
+	level    zap.AtomicLevel      // 最小日志级别（支持运行时调整）
+	encoders *logEncoderSwitch    // 编码器（支持运行时切换格式）
+	writers  []*levelRotateWriter // 持有所有写入器以便关闭
+	mu       sync.Mutex
 }
 
-// NewLogger 创建新的日志器
-func NewLogger(cfg *config.LogConfig) (Logger, error) {
-	// 确定最小日志级别
-	var minLevel zapcore.Level
-	switch strings.ToLower(cfg.Level) {
+// parseLogLevel 解析日志级别字符串（未知级别回退为 info）
+func parseLogLevel(level string) zapcore.Level {
+	switch strings.ToLower(level) {
 	case "debug":
-		minLevel = zapcore.DebugLevel
+		return zapcore.DebugLevel
 	case "info":
-		minLevel = zapcore.InfoLevel
+		return zapcore.InfoLevel
 	case "warn":
-		minLevel = zapcore.WarnLevel
+		return zapcore.WarnLevel
 	case "error":
-		minLevel = zapcore.ErrorLevel
+		return zapcore.ErrorLevel
 	default:
-		minLevel = zapcore.InfoLevel
-	}
-
-	// 创建编码器配置
-	var encoderConfig zapcore.EncoderConfig
-	if cfg.Format == "json" {
-		encoderConfig = zap.NewProductionEncoderConfig()
-	} else {
-		encoderConfig = zap.NewDevelopmentEncoderConfig()
+		return zapcore.InfoLevel
 	}
+}
 
-	// 自定义时间格式
-	encoderConfig.TimeKey = "timestamp"
-	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
-	encoderConfig.LevelKey = "level"
-	encoderConfig.MessageKey = "message"
-	encoderConfig.CallerKey = "caller"
-	encoderConfig.EncodeCaller = zapcore.ShortCallerEncoder
+// NewLogger 创建新的日志器
+func NewLogger(cfg *config.LogConfig) (Logger, error) {
+	// 确定最小日志级别（AtomicLevel 便于配置热更新时调整）
+	minLevel := zap.NewAtomicLevelAt(parseLogLevel(cfg.Level))
 
-	// 创建编码器
-	var encoder zapcore.Encoder
-	if cfg.Format == "json" {
-		encoder = zapcore.NewJSONEncoder(encoderConfig)
-	} else {
-		encoder = zapcore.NewConsoleEncoder(encoderConfig)
-	}
+	// 创建可在运行时切换格式的编码器
+	encoders := newLogEncoderSwitch(cfg.Format)
 
 	zapLogger := &ZapLogger{
-		config:  cfg,
-		writers: make([]*levelRotateWriter, 0),
+		config:   cfg,
+		level:    minLevel,
+		encoders: encoders,
+		writers:  make([]*levelRotateWriter, 0),
 	}
 
 	var cores []zapcore.Core
@@ -312,17 +300,17 @@ func NewLogger(cfg *config.LogConfig) (Logger, error) {
 
 			// 创建级别过滤器：只记录当前级别的日志
 			levelFilter := zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
-				return lvl == l.level && lvl >= minLevel
+				return lvl == l.level && minLevel.Enabled(lvl)
 			})
 
 			// 创建 Core
-			core := zapcore.NewCore(encoder, zapcore.AddSync(w), levelFilter)
+			core := newSwitchableCore(encoders, zapcore.AddSync(w), levelFilter)
 			cores = append(cores, core)
 		}
 	} else {
 		// stdout 输出：单个 Core
-		core := zapcore.NewCore(
-			encoder,
+		core := newSwitchableCore(
+			encoders,
 			zapcore.AddSync(os.Stdout),
 			minLevel,
 		)
@@ -362,13 +350,13 @@ func (l *ZapLogger) Info(msg string, fields ...interface{}) {
 	// 支持 map 形式（中间件使用）
 	if len(fields) == 1 {
 		if m, ok := fields[0].(map[string]interface{}); ok {
-			l.sugar.Infow(msg, convertMapToFields(m)...)
+			l.sugar.Infow(msg, redactLogFields(convertMapToFields(m))...)
 			return
 		}
 	}
 
 	// 支持键值对形式
-	l.sugar.Infow(msg, fields...)
+	l.sugar.Infow(msg, redactLogFields(fields)...)
 }
 
 // Warn 记录警告日志
@@ -380,12 +368,12 @@ func (l *ZapLogger) Warn(msg string, fields ...interface{}) {
 
 	if len(fields) == 1 {
 		if m, ok := fields[0].(map[string]interface{}); ok {
-			l.sugar.Warnw(msg, convertMapToFields(m)...)
+			l.sugar.Warnw(msg, redactLogFields(convertMapToFields(m))...)
 			return
 		}
 	}
 
-	l.sugar.Warnw(msg, fields...)
+	l.sugar.Warnw(msg, redactLogFields(fields)...)
 }
 
 // Error 记录错误日志
@@ -397,12 +385,12 @@ func (l *ZapLogger) Error(msg string, fields ...interface{}) {
 
 	if len(fields) == 1 {
 		if m, ok := fields[0].(map[string]interface{}); ok {
-			l.sugar.Errorw(msg, convertMapToFields(m)...)
+			l.sugar.Errorw(msg, redactLogFields(convertMapToFields(m))...)
 			return
 		}
 	}
 
-	l.sugar.Errorw(msg, fields...)
+	l.sugar.Errorw(msg, redactLogFields(fields)...)
 }
 
 // Debug 记录调试日志
@@ -414,12 +402,12 @@ func (l *ZapLogger) Debug(msg string, fields ...interface{}) {
 
 	if len(fields) == 1 {
 		if m, ok := fields[0].(map[string]interface{}); ok {
-			l.sugar.Debugw(msg, convertMapToFields(m)...)
+			l.sugar.Debugw(msg, redactLogFields(convertMapToFields(m))...)
 			return
 		}
 	}
 
-	l.sugar.Debugw(msg, fields...)
+	l.sugar.Debugw(msg, redactLogFields(fields)...)
 }
 
 // Fatal 记录致命错误日志并退出程序
@@ -432,12 +420,25 @@ func (l *ZapLogger) Fatal(msg string, fields ...interface{}) {
 
 	if len(fields) == 1 {
 		if m, ok := fields[0].(map[string]interface{}); ok {
-			l.sugar.Fatalw(msg, convertMapToFields(m)...)
+			l.sugar.Fatalw(msg, redactLogFields(convertMapToFields(m))...)
 			return
 		}
 	}
 
-	l.sugar.Fatalw(msg, fields...)
+	l.sugar.Fatalw(msg, redactLogFields(fields)...)
+}
+
+// With 返回附带固定字段的子日志器
+// 子日志器与父日志器共享输出、级别和格式，不持有写入器，Close 不会关闭共享的日志文件
+func (l *ZapLogger) With(fields ...interface{}) Logger {
+	sugar := l.sugar.With(redactLogFields(fields)...)
+	return &ZapLogger{
+		logger:   sugar.Desugar(),
+		sugar:    sugar,
+		config:   l.config,
+		level:    l.level,
+		encoders: l.encoders,
+	}
 }
 
 // Close 关闭日志器
@@ -509,6 +510,16 @@ func GetLogger() Logger {
 	return globalLogger
 }
 
+// SetLogLevel 运行时调整全局日志器的最小级别
+func SetLogLevel(level string) error {
+	zl, ok := GetLogger().(*ZapLogger)
+	if !ok {
+		return fmt.Errorf("当前日志器不支持动态调整级别")
+	}
+	zl.level.SetLevel(parseLogLevel(level))
+	return nil
+}
+
 // CloseLogger 优雅关闭全局日志器
 func CloseLogger() error {
 	globalMu.Lock()
@@ -546,21 +557,13 @@ func SanitizeHeaders(headers map[string][]string) map[string]interface{} {
 	return result
 }
 
-// SanitizeParams 脱敏查询参数（隐藏密码等敏感字段）
+// SanitizeParams 脱敏查询参数（按 log_extended.sensitive_keys 隐藏密码等敏感字段）
 func SanitizeParams(params map[string]interface{}) map[string]interface{} {
-	result := make(map[string]interface{})
-	sensitiveKeys := map[string]bool{
-		"password":     true,
-		"old_password": true,
-		"new_password": true,
-		"token":        true,
-		"secret":       true,
-		"api_key":      true,
-	}
-
+	r := currentLogRedactor.Load()
+	result := make(map[string]interface{}, len(params))
 	for k, v := range params {
-		if sensitiveKeys[k] {
-			result[k] = "***"
+		if matched, hit := r.match(k); hit {
+			result[k] = maskLogValue(matched, v)
 		} else {
 			result[k] = v
 		}
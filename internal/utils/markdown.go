@@ -0,0 +1,146 @@
+package utils
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Markdown 语法正则（按行处理，不解析嵌套结构）
+var (
+	mdFenceRegex       = regexp.MustCompile("^\\s{0,3}(`{3,}|~{3,})")
+	mdRefDefRegex      = regexp.MustCompile(`^\s{0,3}\[[^\]]+\]:\s*\S+`)
+	mdRuleRegex        = regexp.MustCompile(`^\s{0,3}([-*_])(\s*[-*_]){2,}\s*$`)
+	mdTableSepRegex    = regexp.MustCompile(`^\s*\|?\s*:?-{3,}:?\s*(\|\s*:?-{3,}:?\s*)*\|?\s*$`)
+	mdHeadingRegex     = regexp.MustCompile(`^\s{0,3}#{1,6}\s+`)
+	mdHeadingTailRegex = regexp.MustCompile(`\s+#+\s*$`)
+	mdQuoteRegex       = regexp.MustCompile(`^\s{0,3}(>\s?)+`)
+	mdListRegex        = regexp.MustCompile(`^\s*([-*+]|\d{1,9}[.)])\s+(\[[ xX]\]\s+)?`)
+	mdImageRegex       = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	mdLinkRegex        = regexp.MustCompile(`\[([^\]]+)\](\([^)]*\)|\[[^\]]*\])`)
+	mdAutoLinkRegex    = regexp.MustCompile(`<((https?|ftp|mailto):[^>\s]+)>`)
+	mdInlineCodeRegex  = regexp.MustCompile("`+([^`]+)`+")
+	mdStrongRegex      = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	mdEmphasisRegex    = regexp.MustCompile(`\*([^*\s][^*]*)\*`)
+	mdUnderscoreRegex  = regexp.MustCompile(`(^|[^\p{L}\p{N}_])_([^_\s][^_]*)_([^\p{L}\p{N}_]|$)`)
+	mdStrikeRegex      = regexp.MustCompile(`~~([^~]+)~~`)
+)
+
+// StripMarkdown 去除Markdown格式和围栏代码块，返回纯文本
+// 段落之间以空行分隔，段落内的换行合并为空格；链接和图片保留文字部分，行内代码保留内容
+func StripMarkdown(content string) string {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+
+	var paragraphs []string
+	var current []string
+	flush := func() {
+		if len(current) > 0 {
+			paragraphs = append(paragraphs, strings.Join(current, " "))
+			current = current[:0]
+		}
+	}
+
+	fence := ""
+	for _, line := range lines {
+		// 围栏代码块：跳过开始行到结束行之间的全部内容（未闭合时跳到文末）
+		if m := mdFenceRegex.FindStringSubmatch(line); m != nil {
+			if fence == "" {
+				fence = m[1]
+				flush()
+				continue
+			}
+			if m[1][0] == fence[0] && len(m[1]) >= len(fence) {
+				fence = ""
+				continue
+			}
+		}
+		if fence != "" {
+			continue
+		}
+
+		if mdRefDefRegex.MatchString(line) || mdRuleRegex.MatchString(line) || mdTableSepRegex.MatchString(line) {
+			flush()
+			continue
+		}
+
+		text := stripMarkdownLine(line)
+		if text == "" {
+			flush()
+			continue
+		}
+		current = append(current, text)
+	}
+	flush()
+
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// stripMarkdownLine 去除单行中的块级标记和行内格式
+func stripMarkdownLine(line string) string {
+	if mdHeadingRegex.MatchString(line) {
+		line = mdHeadingRegex.ReplaceAllString(line, "")
+		line = mdHeadingTailRegex.ReplaceAllString(line, "")
+	}
+	line = mdQuoteRegex.ReplaceAllString(line, "")
+	line = mdListRegex.ReplaceAllString(line, "")
+
+	line = mdImageRegex.ReplaceAllString(line, "$1")
+	line = mdLinkRegex.ReplaceAllString(line, "$1")
+	line = mdAutoLinkRegex.ReplaceAllString(line, "$1")
+	line = mdInlineCodeRegex.ReplaceAllString(line, "$1")
+	line = htmlTagRegex.ReplaceAllString(line, "")
+	line = mdStrongRegex.ReplaceAllString(line, "$1$2")
+	line = mdEmphasisRegex.ReplaceAllString(line, "$1")
+	line = mdUnderscoreRegex.ReplaceAllString(line, "$1$2$3")
+	line = mdStrikeRegex.ReplaceAllString(line, "$1")
+
+	// 表格行：单元格之间用空格分隔
+	if strings.Contains(line, "|") && strings.Count(line, "|") >= 2 {
+		cells := strings.Split(strings.Trim(strings.TrimSpace(line), "|"), "|")
+		for i := range cells {
+			cells[i] = strings.TrimSpace(cells[i])
+		}
+		line = strings.Join(cells, " ")
+	}
+
+	line = html.UnescapeString(line)
+	return strings.Join(strings.Fields(line), " ")
+}
+
+// CountWords 统计字数：中日韩文字每个字计1，其他语言按连续的字母/数字计1个词
+// 词内的撇号、连字符和下划线不拆分单词（如 don't、real-time）
+func CountWords(text string) int {
+	count := 0
+	inWord := false
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			count++
+			inWord = false
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if !inWord {
+				count++
+				inWord = true
+			}
+		case inWord && (r == '\'' || r == '’' || r == '-' || r == '_'):
+			// 保持在词内
+		default:
+			inWord = false
+		}
+	}
+	return count
+}
+
+// isCJK 是否为中日韩文字
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// EstimateReadingMinutes 按每分钟阅读字数估算阅读时长（向上取整，有内容时至少1分钟）
+func EstimateReadingMinutes(wordCount, wordsPerMinute int) int {
+	if wordCount <= 0 || wordsPerMinute <= 0 {
+		return 0
+	}
+	return (wordCount + wordsPerMinute - 1) / wordsPerMinute
+}
@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStripMarkdown(t *testing.T) {
+	cases := []struct {
+		name, in, want string
+	}{
+		{"标题", "# 标题 #\n## Sub title", "标题 Sub title"},
+		{"强调和删除线", "**bold** *em* __strong__ _under_ ~~del~~", "bold em strong under del"},
+		{"链接和图片保留文字", "see [docs](https://x.io) and ![logo](a.png) <https://y.io>", "see docs and logo https://y.io"},
+		{"行内代码保留内容", "run `go test` now", "run go test now"},
+		{"列表和引用", "- a\n* b\n1. c\n- [x] d\n> quote", "a b c d quote"},
+		{"段落以空行分隔", "first\nline\n\nsecond", "first line\n\nsecond"},
+		{"分隔线和引用定义", "a\n\n---\n\n[ref]: https://x.io\nb", "a\n\nb"},
+		{"表格分隔行拆分段落", "| h1 | h2 |\n|---|:---:|\n| c1 | c2 |", "h1 h2\n\nc1 c2"},
+		{"HTML标签和实体", "<b>hi</b> &amp; bye", "hi & bye"},
+		{"词内下划线不处理", "snake_case_name", "snake_case_name"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := StripMarkdown(tc.in); got != tc.want {
+				t.Fatalf("StripMarkdown(%q) = %q，期望 %q", tc.in, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestStripMarkdownRemovesCodeFences(t *testing.T) {
+	in := "intro\n```go\nfunc main() {}\n```\nmiddle\n~~~~\n```\nstill code\n~~~~\noutro"
+	got := StripMarkdown(in)
+	if got != "intro\n\nmiddle\n\noutro" {
+		t.Fatalf("围栏代码块应被去除，实际 %q", got)
+	}
+
+	// 未闭合的代码块一直跳到文末
+	if got := StripMarkdown("text\n```\ncode\nmore code"); got != "text" {
+		t.Fatalf("未闭合的代码块应跳到文末，实际 %q", got)
+	}
+	if got := StripMarkdown("a\n   ~~~\nb\n   ~~~\nc"); strings.Contains(got, "~") || strings.Contains(got, "b") {
+		t.Fatalf("缩进不超过3个空格的围栏同样应去除，实际 %q", got)
+	}
+}
+
+func TestCountWords(t *testing.T) {
+	cases := []struct {
+		in   string
+		want int
+	}{
+		{"", 0},
+		{"hello world", 2},
+		{"don't stop real-time snake_case", 4},
+		{"中文字数", 4},
+		{"Go语言 3.14 版本", 7},
+		{"  多个   空格\n\n换行  ", 6},
+		{"日本語のテキスト", 8},
+	}
+	for _, tc := range cases {
+		if got := CountWords(tc.in); got != tc.want {
+			t.Fatalf("CountWords(%q) = %d，期望 %d", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestEstimateReadingMinutes(t *testing.T) {
+	cases := []struct {
+		words, wpm, want int
+	}{
+		{0, 300, 0},
+		{1, 300, 1},
+		{300, 300, 1},
+		{301, 300, 2},
+		{900, 200, 5},
+		{100, 0, 0},
+	}
+	for _, tc := range cases {
+		if got := EstimateReadingMinutes(tc.words, tc.wpm); got != tc.want {
+			t.Fatalf("EstimateReadingMinutes(%d, %d) = %d，期望 %d", tc.words, tc.wpm, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// goroutineBudgetKey 请求并发预算在 context 中的键
+type goroutineBudgetKey struct{}
+
+// defaultGoroutineBudget 没有请求预算时（如后台任务）单次并行调用的并发上限
+var defaultGoroutineBudget atomic.Int64
+
+func init() {
+	defaultGoroutineBudget.Store(8)
+}
+
+// SetDefaultGoroutineBudget 设置没有请求预算时单次并行调用的并发上限
+func SetDefaultGoroutineBudget(limit int) {
+	if limit > 0 {
+		defaultGoroutineBudget.Store(int64(limit))
+	}
+}
+
+// WithGoroutineBudget 为请求设置并发预算：同一请求内所有 Parallel/ParallelForEach
+// 额外启动的 goroutine 总数不超过 limit（limit<=0 时不设置）
+func WithGoroutineBudget(ctx context.Context, limit int) context.Context {
+	if limit <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, goroutineBudgetKey{}, make(chan struct{}, limit))
+}
+
+// budgetFromContext 获取请求的并发预算，没有时按默认上限创建一次性预算
+func budgetFromContext(ctx context.Context) chan struct{} {
+	if sem, ok := ctx.Value(goroutineBudgetKey{}).(chan struct{}); ok {
+		return sem
+	}
+	return make(chan struct{}, defaultGoroutineBudget.Load())
+}
+
+// Parallel 并行执行任务并等待全部完成，并发受请求预算限制
+func Parallel(ctx context.Context, tasks ...func()) {
+	ParallelForEach(ctx, len(tasks), func(i int) {
+		tasks[i]()
+	})
+}
+
+// ParallelForEach 对 [0, n) 的每个下标执行 fn 并等待全部完成
+// 只在拿到请求预算时启动新的 goroutine，其余工作由当前 goroutine 执行，
+// 因此无论 n 多大，额外的 goroutine 数都不超过预算，嵌套调用也不会因等待预算而死锁
+func ParallelForEach(ctx context.Context, n int, fn func(i int)) {
+	if n <= 0 {
+		return
+	}
+	if n == 1 {
+		fn(0)
+		return
+	}
+
+	sem := budgetFromContext(ctx)
+	var next atomic.Int64
+	work := func() {
+		for {
+			i := int(next.Add(1) - 1)
+			if i >= n {
+				return
+			}
+			fn(i)
+		}
+	}
+
+	var wg sync.WaitGroup
+	// 当前 goroutine 也参与执行，最多再借 n-1 个预算
+spawn:
+	for started := 0; started < n-1; started++ {
+		select {
+		case sem <- struct{}{}:
+		default:
+			break spawn // 预算已用完
+		}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			work()
+		}()
+	}
+
+	work()
+	wg.Wait()
+}
@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// concurrencyProbe 记录同时执行的任务数峰值
+type concurrencyProbe struct {
+	running, peak atomic.Int64
+}
+
+func (p *concurrencyProbe) run() {
+	n := p.running.Add(1)
+	for {
+		peak := p.peak.Load()
+		if n <= peak || p.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(time.Millisecond)
+	p.running.Add(-1)
+}
+
+func TestParallelForEachRespectsRequestBudget(t *testing.T) {
+	ctx := WithGoroutineBudget(context.Background(), 4)
+	var probe concurrencyProbe
+	var done [500]atomic.Int32
+
+	ParallelForEach(ctx, len(done), func(i int) {
+		probe.run()
+		done[i].Add(1)
+	})
+
+	for i := range done {
+		if got := done[i].Load(); got != 1 {
+			t.Fatalf("任务 %d 应执行1次，实际 %d 次", i, got)
+		}
+	}
+	// 预算内的4个goroutine加上调用方自身
+	if peak := probe.peak.Load(); peak > 5 {
+		t.Fatalf("并发峰值应不超过 预算+1=5，实际 %d", peak)
+	}
+}
+
+func TestNestedParallelSharesBudget(t *testing.T) {
+	ctx := WithGoroutineBudget(context.Background(), 3)
+	var probe concurrencyProbe
+	var total atomic.Int64
+
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ParallelForEach(ctx, 10, func(int) {
+			ParallelForEach(ctx, 10, func(int) {
+				probe.run()
+				total.Add(1)
+			})
+		})
+	}()
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("嵌套调用在预算用完时不应死锁")
+	}
+
+	if got := total.Load(); got != 100 {
+		t.Fatalf("应完成全部100个子任务，实际 %d", got)
+	}
+	if peak := probe.peak.Load(); peak > 4 {
+		t.Fatalf("嵌套调用共享同一请求预算，并发峰值应不超过4，实际 %d", peak)
+	}
+}
+
+func TestParallelWithoutBudgetUsesDefault(t *testing.T) {
+	SetDefaultGoroutineBudget(2)
+	t.Cleanup(func() { SetDefaultGoroutineBudget(8) })
+
+	var probe concurrencyProbe
+	tasks := make([]func(), 20)
+	for i := range tasks {
+		tasks[i] = probe.run
+	}
+	Parallel(context.Background(), tasks...)
+
+	if peak := probe.peak.Load(); peak > 3 {
+		t.Fatalf("没有请求预算时应使用默认上限2，并发峰值应不超过3，实际 %d", peak)
+	}
+}
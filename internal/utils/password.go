@@ -2,6 +2,8 @@ package utils
 
 import (
 	"crypto/subtle"
+	"sync/atomic"
+	"time"
 
 	"gin/internal/config"
 
@@ -14,10 +16,66 @@ const (
 	DefaultBcryptCost = 12
 )
 
+// 当前生效的密码哈希配置（启动时由 ConfigurePasswordHashing 设置，未设置时使用默认值）
+var (
+	passwordConfig    atomic.Pointer[config.SecurityPasswordConfig]
+	currentBcryptCost atomic.Int32
+)
+
+// ConfigurePasswordHashing 设置密码哈希配置并确定新哈希使用的bcrypt成本，返回选定的成本
+// 启用校准时在 [BcryptCostMin, BcryptCostMax] 内测量本机耗时选择成本，否则使用 BcryptCost
+func ConfigurePasswordHashing(cfg *config.SecurityPasswordConfig) int {
+	cost := cfg.BcryptCost
+	if cfg.CalibrateCost {
+		cost = CalibrateBcryptCost(cfg.BcryptCostMin, cfg.BcryptCostMax, time.Duration(cfg.CalibrationTargetMs)*time.Millisecond)
+	}
+	cost = max(cfg.BcryptCostMin, min(cost, cfg.BcryptCostMax))
+
+	c := *cfg
+	passwordConfig.Store(&c)
+	currentBcryptCost.Store(int32(cost))
+	return cost
+}
+
+// CalibrateBcryptCost 选择单次哈希耗时不超过 target 的最高成本（最低成本也超时时返回 minCost）
+// 成本每加1耗时约翻倍，预计下一档会超时时提前结束，避免启动时做一次过慢的哈希
+func CalibrateBcryptCost(minCost, maxCost int, target time.Duration) int {
+	chosen := minCost
+	for cost := minCost; cost <= maxCost; cost++ {
+		start := time.Now()
+		if _, err := bcrypt.GenerateFromPassword([]byte("bcrypt-cost-calibration"), cost); err != nil {
+			break
+		}
+		elapsed := time.Since(start)
+		if elapsed > target {
+			break
+		}
+		chosen = cost
+		if elapsed*2 > target {
+			break
+		}
+	}
+	return chosen
+}
+
+// CurrentBcryptCost 新密码哈希使用的bcrypt成本
+func CurrentBcryptCost() int {
+	if cost := currentBcryptCost.Load(); cost > 0 {
+		return int(cost)
+	}
+	return DefaultBcryptCost
+}
+
+// NeedsRehash 哈希的成本是否低于当前成本（需要在登录成功后升级）
+func NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err == nil && cost < CurrentBcryptCost()
+}
+
 // HashPassword 生成密码哈希
-// 使用可配置的 bcrypt cost 提供安全性与性能的平衡
+// 使用当前生效的 bcrypt cost 和密码配置
 func HashPassword(password string) (string, error) {
-	return HashPasswordWithCost(password, DefaultBcryptCost)
+	return HashPasswordWithConfig(password, CurrentBcryptCost(), passwordConfig.Load())
 }
 
 // HashPasswordWithCost 使用指定成本生成密码哈希
@@ -63,7 +121,7 @@ func HashPasswordWithConfig(password string, cost int, cfg *config.SecurityPassw
 // CheckPasswordHash 验证密码哈希
 // bcrypt.CompareHashAndPassword 内部已使用常量时间比较，无需额外处理
 func CheckPasswordHash(password, hash string) bool {
-	return CheckPasswordHashWithConfig(password, hash, nil)
+	return CheckPasswordHashWithConfig(password, hash, passwordConfig.Load())
 }
 
 // CheckPasswordHashWithConfig 验证密码哈希（使用配置）
@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"gin/internal/config"
+)
+
+// commonPasswords 常见弱密码表（按使用频率排序，全部小写）
+var commonPasswords = []string{
+	"123456", "password", "12345678", "qwerty", "123456789", "12345", "1234", "111111", "1234567", "dragon",
+	"123123", "baseball", "abc123", "football", "monkey", "letmein", "696969", "shadow", "master", "666666",
+	"qwertyuiop", "123321", "mustang", "1234567890", "michael", "654321", "superman", "1qaz2wsx", "7777777", "121212",
+	"000000", "qazwsx", "123qwe", "killer", "trustno1", "jordan", "jennifer", "zxcvbnm", "asdfgh", "hunter",
+	"buster", "soccer", "harley", "batman", "andrew", "tigger", "sunshine", "iloveyou", "2000", "charlie",
+	"robert", "thomas", "hockey", "ranger", "daniel", "starwars", "klaster", "112233", "george", "computer",
+	"michelle", "jessica", "pepper", "1111", "zxcvbn", "555555", "11111111", "131313", "freedom", "777777",
+	"pass", "maggie", "159753", "aaaaaa", "ginger", "princess", "joshua", "cheese", "amanda", "summer",
+	"love", "ashley", "nicole", "chelsea", "biteme", "matthew", "access", "yankees", "987654321", "dallas",
+	"austin", "thunder", "taylor", "matrix", "password1", "qwerty123", "admin123", "a123456", "123456a", "woaini1314",
+}
+
+// PasswordValidator 密码策略校验器（注册、修改和重置密码时使用，登录时只校验长度）
+type PasswordValidator struct {
+	cfg    *config.ValidationPasswordConfig
+	common map[string]struct{}
+}
+
+// NewPasswordValidator 按配置创建密码校验器
+func NewPasswordValidator(cfg *config.ValidationPasswordConfig) *PasswordValidator {
+	v := &PasswordValidator{cfg: cfg}
+	if cfg.Policy.RejectCommon {
+		list := commonPasswords
+		if n := cfg.Policy.CommonTopN; n > 0 && n < len(list) {
+			list = list[:n]
+		}
+		v.common = make(map[string]struct{}, len(list))
+		for _, p := range list {
+			v.common[p] = struct{}{}
+		}
+	}
+	return v
+}
+
+// Validate 校验新密码，未通过时返回 ErrInvalidPassword 类型的 AppError，消息列出所有未满足的规则
+func (v *PasswordValidator) Validate(password, username, email string) error {
+	if violations := v.Violations(password, username, email); len(violations) > 0 {
+		return NewAppError(ErrInvalidPassword, strings.Join(violations, "；"), 400)
+	}
+	return nil
+}
+
+// Violations 返回密码未满足的规则说明（全部满足时为空）
+func (v *PasswordValidator) Violations(password, username, email string) []string {
+	var violations []string
+	if len(password) < v.cfg.MinLength || len(password) > v.cfg.MaxLength {
+		violations = append(violations, fmt.Sprintf("密码长度应为%d-%d位", v.cfg.MinLength, v.cfg.MaxLength))
+	}
+
+	var letters, upper, lower, digits, special int
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			letters++
+			upper++
+		case unicode.IsLower(r):
+			letters++
+			lower++
+		case unicode.IsLetter(r):
+			letters++
+		case unicode.IsDigit(r):
+			digits++
+		case !unicode.IsSpace(r):
+			special++
+		}
+	}
+
+	policy := v.cfg.Policy
+	checks := []struct {
+		count, min int
+		name       string
+	}{
+		{letters, policy.MinLetters, "字母"},
+		{upper, policy.MinUppercase, "大写字母"},
+		{lower, policy.MinLowercase, "小写字母"},
+		{digits, policy.MinDigits, "数字"},
+		{special, policy.MinSpecial, "特殊字符"},
+	}
+	for _, check := range checks {
+		if check.count < check.min {
+			violations = append(violations, fmt.Sprintf("密码至少包含%d个%s", check.min, check.name))
+		}
+	}
+
+	lowered := strings.ToLower(password)
+	if v.common != nil {
+		if _, ok := v.common[lowered]; ok {
+			violations = append(violations, "密码过于常见，请更换")
+		}
+	}
+	if policy.RejectUserInfo && containsUserInfo(lowered, username, email) {
+		violations = append(violations, "密码不能包含用户名或邮箱")
+	}
+	return violations
+}
+
+// containsUserInfo 密码（已转小写）是否包含用户名或邮箱前缀（少于3个字符的不检查，避免误判）
+func containsUserInfo(lowered, username, email string) bool {
+	candidates := []string{strings.ToLower(username)}
+	if at := strings.Index(email, "@"); at > 0 {
+		candidates = append(candidates, strings.ToLower(email[:at]))
+	}
+	for _, candidate := range candidates {
+		if len(candidate) >= 3 && strings.Contains(lowered, candidate) {
+			return true
+		}
+	}
+	return false
+}
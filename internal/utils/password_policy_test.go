@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"gin/internal/config"
+)
+
+func TestPasswordValidatorRules(t *testing.T) {
+	cfg := config.ValidationPasswordConfig{
+		MinLength: 8,
+		MaxLength: 20,
+		Policy: config.PasswordPolicyConfig{
+			MinUppercase:   1,
+			MinLowercase:   1,
+			MinDigits:      2,
+			MinSpecial:     1,
+			RejectCommon:   true,
+			RejectUserInfo: true,
+		},
+	}
+	v := NewPasswordValidator(&cfg)
+
+	cases := []struct {
+		name     string
+		password string
+		want     string // 期望的违规说明，空表示应通过
+	}{
+		{"满足全部规则", "Str0ng!Pa55", ""},
+		{"过短", "S0!a1", "密码长度应为8-20位"},
+		{"过长", "S0!a1" + strings.Repeat("x", 20), "密码长度应为8-20位"},
+		{"缺少大写字母", "str0ng!pa55", "密码至少包含1个大写字母"},
+		{"缺少小写字母", "STR0NG!PA55", "密码至少包含1个小写字母"},
+		{"数字不足", "Strong!Pass5", "密码至少包含2个数字"},
+		{"缺少特殊字符", "Str0ngPa55", "密码至少包含1个特殊字符"},
+		{"包含用户名（忽略大小写）", "Al1ce-Secret9", "密码不能包含用户名或邮箱"},
+		{"包含邮箱前缀", "Wonder7!Land0", "密码不能包含用户名或邮箱"},
+	}
+	for _, tc := range cases {
+		violations := v.Violations(tc.password, "al1ce", "wonder7@example.com")
+		if tc.want == "" {
+			if len(violations) != 0 {
+				t.Errorf("%s: 应通过校验，实际 %v", tc.name, violations)
+			}
+			continue
+		}
+		if len(violations) != 1 || violations[0] != tc.want {
+			t.Errorf("%s: 应只提示 %q，实际 %v", tc.name, tc.want, violations)
+		}
+	}
+
+	// 未通过时返回 ErrInvalidPassword，消息列出所有未满足的规则
+	err := v.Validate("password", "bob", "bob@example.com")
+	if !errors.Is(err, ErrInvalidPassword) || GetHTTPStatusCode(err) != 400 {
+		t.Fatalf("应返回400的 ErrInvalidPassword，实际 %v", err)
+	}
+	for _, rule := range []string{"大写字母", "数字", "特殊字符", "密码过于常见"} {
+		if !strings.Contains(err.Error(), rule) {
+			t.Errorf("错误消息应包含未满足的规则 %q，实际 %q", rule, err.Error())
+		}
+	}
+}
+
+func TestPasswordValidatorToggles(t *testing.T) {
+	// 数量为0、开关为false的规则不启用，只校验长度
+	relaxed := NewPasswordValidator(&config.ValidationPasswordConfig{MinLength: 6, MaxLength: 50})
+	if err := relaxed.Validate("password", "password", "password@example.com"); err != nil {
+		t.Fatalf("关闭全部规则后只应校验长度，实际 %v", err)
+	}
+
+	// 常见密码表只取前N个，比较时忽略大小写
+	cfg := config.ValidationPasswordConfig{MinLength: 4, MaxLength: 50, Policy: config.PasswordPolicyConfig{RejectCommon: true, CommonTopN: 2}}
+	v := NewPasswordValidator(&cfg)
+	if err := v.Validate("PassWord", "", ""); err == nil {
+		t.Fatal("常见密码表前N个中的密码应被拒绝（忽略大小写）")
+	}
+	if err := v.Validate("qwerty", "", ""); err != nil {
+		t.Fatalf("不在前N个中的密码不应被拒绝，实际 %v", err)
+	}
+
+	// 少于3个字符的用户名不参与包含检查，避免误判
+	cfg = config.ValidationPasswordConfig{MinLength: 4, MaxLength: 50, Policy: config.PasswordPolicyConfig{RejectUserInfo: true}}
+	v = NewPasswordValidator(&cfg)
+	if err := v.Validate("jo-secret", "jo", "x@example.com"); err != nil {
+		t.Fatalf("过短的用户名不应参与包含检查，实际 %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gin/internal/config"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// usePasswordHashing 按 cfg 配置密码哈希，测试结束时恢复默认配置
+func usePasswordHashing(t *testing.T, cfg config.SecurityPasswordConfig) int {
+	t.Helper()
+	t.Cleanup(func() { ConfigurePasswordHashing(&config.Default().SecurityPassword) })
+	return ConfigurePasswordHashing(&cfg)
+}
+
+func TestCalibrateBcryptCost(t *testing.T) {
+	if cost := CalibrateBcryptCost(4, 6, time.Nanosecond); cost != 4 {
+		t.Fatalf("最低成本也超时时应返回最低成本，实际 %d", cost)
+	}
+	if cost := CalibrateBcryptCost(4, 5, time.Minute); cost != 5 {
+		t.Fatalf("所有成本都在目标耗时内时应选择最高成本，实际 %d", cost)
+	}
+}
+
+func TestConfigurePasswordHashing(t *testing.T) {
+	cfg := config.SecurityPasswordConfig{BcryptCostMin: 4, BcryptCostMax: 6, BcryptCost: 5, PasswordMaxBytes: 16}
+	if cost := usePasswordHashing(t, cfg); cost != 5 || CurrentBcryptCost() != 5 {
+		t.Fatalf("未启用校准时应使用配置的成本，实际 %d", cost)
+	}
+
+	hash, err := HashPassword("secret-password")
+	if err != nil {
+		t.Fatalf("生成哈希失败: %v", err)
+	}
+	if cost, _ := bcrypt.Cost([]byte(hash)); cost != 5 {
+		t.Fatalf("新哈希应使用当前成本，实际 %d", cost)
+	}
+	if !CheckPasswordHash("secret-password", hash) {
+		t.Fatal("哈希应能通过校验")
+	}
+
+	// 超过 PasswordMaxBytes 的密码既不能哈希也不能通过校验
+	long := strings.Repeat("a", 17)
+	if _, err := HashPassword(long); err == nil {
+		t.Fatal("超过最大字节数的密码不应生成哈希")
+	}
+	longHash, _ := bcrypt.GenerateFromPassword([]byte(long), 4)
+	if CheckPasswordHash(long, string(longHash)) {
+		t.Fatal("超过最大字节数的密码不应通过校验")
+	}
+
+	// 只有成本低于当前成本的哈希需要升级
+	weak, _ := bcrypt.GenerateFromPassword([]byte("secret-password"), 4)
+	if !NeedsRehash(string(weak)) || NeedsRehash(hash) || NeedsRehash("not-a-bcrypt-hash") {
+		t.Fatal("只有成本低于当前成本的bcrypt哈希需要升级")
+	}
+
+	// 启用校准时结果限制在 [BcryptCostMin, BcryptCostMax] 内
+	cfg.CalibrateCost = true
+	cfg.CalibrationTargetMs = 60000
+	if cost := usePasswordHashing(t, cfg); cost != 6 {
+		t.Fatalf("校准应选择目标耗时内的最高成本，实际 %d", cost)
+	}
+}
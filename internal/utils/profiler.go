@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"gin/internal/config"
+	"gin/internal/models"
 )
 
 // Profiler 性能分析工具
@@ -22,6 +23,9 @@ type Profiler struct {
 	maxLatencies int // 保留最近N个延迟记录
 	cleanupRatio int // 清理百分比
 
+	// 按接口的延迟记录（每个接口单独的滑动窗口，容量和清理比例与全局相同，受 latencyMutex 保护）
+	endpointLatencies map[string]*endpointLatencyBuffer
+
 	// Goroutine泄漏检测
 	initialGoroutines   int
 	goroutineLeakThreshold int
@@ -53,6 +57,7 @@ func NewProfiler(cfg *config.ProfilerConfig) *Profiler {
 		latencies:              make([]time.Duration, 0, maxLatencies),
 		maxLatencies:           maxLatencies,
 		cleanupRatio:           cleanupRatio,
+		endpointLatencies:      make(map[string]*endpointLatencyBuffer),
 		initialGoroutines:      runtime.NumGoroutine(),
 		goroutineLeakThreshold: goroutineLeakThreshold,
 	}
@@ -76,6 +81,81 @@ func (p *Profiler) RecordLatency(latency time.Duration) {
 	p.latencies = append(p.latencies, latency)
 }
 
+// endpointLatencyBuffer 单个接口的延迟滑动窗口
+type endpointLatencyBuffer struct {
+	samples []time.Duration
+	wrapped bool // 是否已清理过旧记录（此时分位数只反映最近的样本）
+}
+
+// RecordEndpointLatency 记录接口延迟（同时计入全局延迟），endpoint 应为 "METHOD 路由模板"
+func (p *Profiler) RecordEndpointLatency(endpoint string, latency time.Duration) {
+	p.RecordLatency(latency)
+
+	p.latencyMutex.Lock()
+	defer p.latencyMutex.Unlock()
+
+	buf, ok := p.endpointLatencies[endpoint]
+	if !ok {
+		buf = &endpointLatencyBuffer{}
+		p.endpointLatencies[endpoint] = buf
+	}
+	if len(buf.samples) >= p.maxLatencies {
+		removeCount := p.maxLatencies * p.cleanupRatio / 100
+		if removeCount < 1 {
+			removeCount = 1
+		}
+		buf.samples = buf.samples[removeCount:]
+		buf.wrapped = true
+	}
+	buf.samples = append(buf.samples, latency)
+}
+
+// GetEndpointLatencyStats 获取各接口的延迟分位数（按样本数降序）
+// 每个接口只保留最近 LatencyMaxRecords 个样本，超出后按 LatencyCleanupRatio 丢弃最旧的记录，
+// 此时分位数是最近样本的近似值（Approximate 为 true）
+func (p *Profiler) GetEndpointLatencyStats() []models.EndpointLatency {
+	p.latencyMutex.Lock()
+	snapshots := make(map[string]*endpointLatencyBuffer, len(p.endpointLatencies))
+	for endpoint, buf := range p.endpointLatencies {
+		samples := make([]time.Duration, len(buf.samples))
+		copy(samples, buf.samples)
+		snapshots[endpoint] = &endpointLatencyBuffer{samples: samples, wrapped: buf.wrapped}
+	}
+	p.latencyMutex.Unlock()
+
+	stats := make([]models.EndpointLatency, 0, len(snapshots))
+	for endpoint, buf := range snapshots {
+		if len(buf.samples) == 0 {
+			continue
+		}
+		sort.Slice(buf.samples, func(i, j int) bool {
+			return buf.samples[i] < buf.samples[j]
+		})
+		stats = append(stats, models.EndpointLatency{
+			Endpoint:    endpoint,
+			Count:       len(buf.samples),
+			P50Ms:       durationMs(percentile(buf.samples, 50)),
+			P95Ms:       durationMs(percentile(buf.samples, 95)),
+			P99Ms:       durationMs(percentile(buf.samples, 99)),
+			MaxMs:       durationMs(buf.samples[len(buf.samples)-1]),
+			Approximate: buf.wrapped,
+		})
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Count != stats[j].Count {
+			return stats[i].Count > stats[j].Count
+		}
+		return stats[i].Endpoint < stats[j].Endpoint
+	})
+	return stats
+}
+
+// durationMs 转换为毫秒（保留小数）
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
 // GetLatencyStats 获取延迟统计（P50, P95, P99）（优化：使用sort.Slice）
 func (p *Profiler) GetLatencyStats() LatencyStats {
 	p.latencyMutex.Lock()
@@ -230,6 +310,10 @@ type SlowQueryDetector struct {
 	cleanupRatio   int // 清理百分比
 	totalQueries   uint64
 	slowQueries    uint64
+
+	// 按查询指纹统计窗口内的出现次数，频繁出现的慢查询升级为告警
+	patterns  map[string]*slowQueryPattern
+	alertRule SlowQueryAlertRule
 }
 
 // SlowQueryRecord 慢查询记录
@@ -253,6 +337,8 @@ func NewSlowQueryDetector(threshold time.Duration, maxRecords int, cleanupRatio
 		queries:      make([]SlowQueryRecord, 0, maxRecords),
 		maxQueries:   maxRecords,
 		cleanupRatio: cleanupRatio,
+		patterns:     make(map[string]*slowQueryPattern),
+		alertRule:    defaultSlowQueryAlertRule,
 	}
 }
 
@@ -260,11 +346,13 @@ func NewSlowQueryDetector(threshold time.Duration, maxRecords int, cleanupRatio
 func (d *SlowQueryDetector) Record(query string, duration time.Duration, params []interface{}) {
 	atomic.AddUint64(&d.totalQueries, 1)
 
-	if duration <= d.threshold {
+	threshold := time.Duration(atomic.LoadInt64((*int64)(&d.threshold)))
+	if duration <= threshold {
 		return
 	}
 
 	atomic.AddUint64(&d.slowQueries, 1)
+	params = RedactSQLParams(query, params)
 
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
@@ -279,18 +367,20 @@ func (d *SlowQueryDetector) Record(query string, duration time.Duration, params
 		d.queries = d.queries[removeCount:]
 	}
 
+	now := time.Now()
 	d.queries = append(d.queries, SlowQueryRecord{
 		Query:     query,
 		Duration:  duration,
-		Timestamp: time.Now(),
+		Timestamp: now,
 		Params:    params,
 	})
+	d.recordPattern(query, duration, now)
 
-	// 记录到日志
-	GetLogger().Warn("检测到慢查询",
+	// 记录到日志（数据库层已按来源输出警告，这里只保留调试日志）
+	GetLogger().Debug("检测到慢查询",
 		"query", TruncateString(query, 200),
 		"duration", duration,
-		"threshold", d.threshold,
+		"threshold", threshold,
 		"params", FormatSQLParams(params))
 }
 
@@ -318,10 +408,15 @@ func (d *SlowQueryDetector) GetStats() SlowQueryStats {
 		TotalQueries: total,
 		SlowQueries:  slow,
 		SlowRate:     slowRate,
-		Threshold:    d.threshold,
+		Threshold:    time.Duration(atomic.LoadInt64((*int64)(&d.threshold))),
 	}
 }
 
+// SetThreshold 运行时调整慢查询阈值（配置热更新）
+func (d *SlowQueryDetector) SetThreshold(threshold time.Duration) {
+	atomic.StoreInt64((*int64)(&d.threshold), int64(threshold))
+}
+
 // SlowQueryStats 慢查询统计
 type SlowQueryStats struct {
 	TotalQueries uint64
@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"gin/internal/config"
+)
+
+func TestEndpointLatencyStats(t *testing.T) {
+	p := NewProfiler(&config.ProfilerConfig{LatencyMaxRecords: 200, LatencyCleanupRatio: 10})
+	for i := 100; i >= 1; i-- {
+		p.RecordEndpointLatency("GET /api/articles", time.Duration(i)*time.Millisecond)
+	}
+	p.RecordEndpointLatency("POST /api/auth/login", 300*time.Millisecond)
+
+	stats := p.GetEndpointLatencyStats()
+	if len(stats) != 2 || stats[0].Endpoint != "GET /api/articles" || stats[1].Endpoint != "POST /api/auth/login" {
+		t.Fatalf("应按接口分别统计并按样本数降序，实际 %+v", stats)
+	}
+	articles := stats[0]
+	if articles.Count != 100 || articles.P50Ms != 51 || articles.P95Ms != 96 || articles.P99Ms != 100 || articles.MaxMs != 100 {
+		t.Fatalf("分位数不正确: %+v", articles)
+	}
+	if articles.Approximate {
+		t.Fatal("样本未超出上限时不应标记为近似值")
+	}
+	if login := stats[1]; login.P50Ms != 300 || login.P99Ms != 300 {
+		t.Fatalf("其他接口的延迟不应混入，实际 %+v", login)
+	}
+
+	// 接口延迟同时计入全局延迟
+	if global := p.GetLatencyStats(); global.Count != 101 {
+		t.Fatalf("全局延迟应包含所有接口的样本，实际 %d", global.Count)
+	}
+}
+
+func TestEndpointLatencyStatsBounded(t *testing.T) {
+	p := NewProfiler(&config.ProfilerConfig{LatencyMaxRecords: 10, LatencyCleanupRatio: 50})
+	for i := 1; i <= 11; i++ {
+		p.RecordEndpointLatency("GET /api/articles", time.Duration(i)*time.Millisecond)
+	}
+
+	stats := p.GetEndpointLatencyStats()
+	// 达到上限后丢弃最旧的 50%，只保留最近的样本
+	if len(stats) != 1 || stats[0].Count != 6 || !stats[0].Approximate {
+		t.Fatalf("样本数应受上限约束并标记为近似值，实际 %+v", stats)
+	}
+	if stats[0].P50Ms != 9 || stats[0].MaxMs != 11 {
+		t.Fatalf("分位数应只反映最近的样本，实际 %+v", stats[0])
+	}
+}
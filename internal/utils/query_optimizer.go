@@ -268,18 +268,19 @@ func (qo *QueryOptimizer) ParallelCountAndQuery(
 	countChan := make(chan countResult, 1)
 	queryChan := make(chan queryResult, 1)
 
-	// 并行执行COUNT查询
-	go func() {
-		var count int
-		err := qo.db.QueryRowContext(ctx, countQuery, countArgs...).Scan(&count)
-		countChan <- countResult{count: count, err: err}
-	}()
-
-	// 并行执行列表查询
-	go func() {
-		rows, err := qo.db.QueryContext(ctx, listQuery, listArgs...)
-		queryChan <- queryResult{rows: rows, err: err}
-	}()
+	Parallel(ctx,
+		// 并行执行COUNT查询
+		func() {
+			var count int
+			err := qo.db.QueryRowContext(ctx, countQuery, countArgs...).Scan(&count)
+			countChan <- countResult{count: count, err: err}
+		},
+		// 并行执行列表查询
+		func() {
+			rows, err := qo.db.QueryContext(ctx, listQuery, listArgs...)
+			queryChan <- queryResult{rows: rows, err: err}
+		},
+	)
 
 	// 收集结果
 	countRes := <-countChan
@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+
+	"gin/internal/config"
+)
+
+func TestRedirectValidatorValidate(t *testing.T) {
+	v := NewRedirectValidator([]string{"cdn.example.com", "*.assets.example.com"}, []string{"https"})
+	cases := []struct {
+		name   string
+		target string
+		want   string
+		wantOK bool
+	}{
+		{"白名单主机", "https://cdn.example.com/a.png", "https://cdn.example.com/a.png", true},
+		{"主机大小写不敏感", "https://CDN.example.com/a.png", "https://CDN.example.com/a.png", true},
+		{"通配子域名", "https://img.assets.example.com/x", "https://img.assets.example.com/x", true},
+		{"站内相对路径", "/api/resources/1", "/api/resources/1", true},
+		{"任意外部地址", "https://evil.com/phish", "", false},
+		{"白名单主机后缀伪装", "https://cdn.example.com.evil.com/", "", false},
+		{"通配后缀伪装", "https://evilassets.example.com/", "", false},
+		{"协议不在白名单", "http://cdn.example.com/a.png", "", false},
+		{"javascript协议", "javascript:alert(1)", "", false},
+		{"协议相对URL", "//evil.com/x", "", false},
+		{"反斜杠绕过", "/\\evil.com", "", false},
+		{"不以斜杠开头的相对路径", "evil.com/x", "", false},
+		{"带账号信息", "https://cdn.example.com@evil.com/", "", false},
+		{"控制字符", "/ok\n//evil.com", "", false},
+		{"空地址", "", "", false},
+	}
+	for _, tc := range cases {
+		got, err := v.Validate(tc.target)
+		if tc.wantOK {
+			if err != nil || got != tc.want {
+				t.Errorf("%s: Validate(%q) = (%q, %v)，期望 (%q, nil)", tc.name, tc.target, got, err, tc.want)
+			}
+		} else if !errors.Is(err, ErrRedirectNotAllowed) {
+			t.Errorf("%s: Validate(%q) 应返回 ErrRedirectNotAllowed，实际 (%q, %v)", tc.name, tc.target, got, err)
+		}
+	}
+}
+
+func TestRedirectAllowedHostsIncludeBucketPublicURLs(t *testing.T) {
+	cfg := config.Default()
+	cfg.Redirect.AllowedHosts = []string{"example.com"}
+	cfg.BucketArticleImages.PublicBaseURL = "https://img.example.net/article-images"
+
+	v := NewRedirectValidator(cfg.RedirectAllowedHosts(), cfg.Redirect.AllowedSchemes)
+	for _, target := range []string{"https://example.com/", "https://img.example.net/article-images/1.png"} {
+		if _, err := v.Validate(target); err != nil {
+			t.Fatalf("%s 应被允许，实际 %v", target, err)
+		}
+	}
+	if _, err := v.Validate("https://other.example.net/"); err == nil {
+		t.Fatal("未配置的主机应被拒绝")
+	}
+}
+
+func TestValidateRedirectTargetWithoutInitAllowsOnlyRelativePaths(t *testing.T) {
+	previous := globalRedirectValidator
+	globalRedirectValidator = nil
+	t.Cleanup(func() { globalRedirectValidator = previous })
+
+	if _, err := ValidateRedirectTarget("/download/1"); err != nil {
+		t.Fatalf("未初始化时站内相对路径应允许，实际 %v", err)
+	}
+	if _, err := ValidateRedirectTarget("https://example.com/"); err == nil {
+		t.Fatal("未初始化时绝对地址应被拒绝")
+	}
+}
@@ -0,0 +1,36 @@
+package utils
+
+import "context"
+
+// requestIDKey 请求ID在 context 中的键
+type requestIDKey struct{}
+
+// WithRequestID 将请求ID写入 context（空ID时原样返回）
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	if requestID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext 从 context 读取请求ID，不存在时返回空串
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// ContextLogger 返回携带 context 中请求ID的日志器（无请求ID时返回 base）
+func ContextLogger(ctx context.Context, base Logger) Logger {
+	if id := RequestIDFromContext(ctx); id != "" {
+		return base.With("request_id", id)
+	}
+	return base
+}
+
+// LoggerFromContext 返回携带请求ID的全局日志器
+func LoggerFromContext(ctx context.Context) Logger {
+	return ContextLogger(ctx, GetLogger())
+}
@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// newObservedLogger 返回把日志记录到内存的日志器
+func newObservedLogger() (*ZapLogger, *observer.ObservedLogs) {
+	core, logs := observer.New(zap.DebugLevel)
+	logger := zap.New(core)
+	return &ZapLogger{logger: logger, sugar: logger.Sugar(), level: zap.NewAtomicLevelAt(zap.DebugLevel)}, logs
+}
+
+func TestContextLoggerRequestID(t *testing.T) {
+	base, logs := newObservedLogger()
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	if got := RequestIDFromContext(ctx); got != "req-1" {
+		t.Fatalf("应能从 context 读取请求ID，实际 %q", got)
+	}
+	if WithRequestID(context.Background(), "") != context.Background() || RequestIDFromContext(nil) != "" {
+		t.Fatal("空请求ID不应写入 context，nil context 应返回空串")
+	}
+
+	ContextLogger(ctx, base).Info("处理请求", "userID", 1)
+	ContextLogger(context.Background(), base).Info("后台任务")
+	entries := logs.All()
+	if len(entries) != 2 {
+		t.Fatalf("应记录2条日志，实际 %d", len(entries))
+	}
+	if fields := entries[0].ContextMap(); fields["request_id"] != "req-1" || fields["userID"] != int64(1) {
+		t.Fatalf("请求内的日志应携带请求ID，实际 %v", fields)
+	}
+	if _, ok := entries[1].ContextMap()["request_id"]; ok {
+		t.Fatal("没有请求ID时不应添加 request_id 字段")
+	}
+}
+
+func TestWorkerPoolTaskRequestID(t *testing.T) {
+	logger, logs := newObservedLogger()
+	pool := NewWorkerPool(1, 10, time.Second)
+	pool.logger = logger
+	defer pool.Shutdown(context.Background())
+
+	got := make(chan string, 1)
+	if err := pool.Submit(Task{ID: "with_request_id", RequestID: "req-2", Execute: func(ctx context.Context) error {
+		got <- RequestIDFromContext(ctx)
+		return errors.New("写入失败")
+	}}); err != nil {
+		t.Fatalf("提交任务失败: %v", err)
+	}
+	select {
+	case id := <-got:
+		if id != "req-2" {
+			t.Fatalf("任务 context 应携带发起请求的ID，实际 %q", id)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("任务未执行")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for logs.FilterMessage("任务执行失败").Len() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	failed := logs.FilterMessage("任务执行失败").All()
+	if len(failed) != 1 || failed[0].ContextMap()["request_id"] != "req-2" {
+		t.Fatalf("任务日志应携带请求ID，实际 %v", failed)
+	}
+}
+
+func TestSubmitTaskWithContextRequestID(t *testing.T) {
+	// 使用独立的全局池，测试结束后恢复未初始化状态（其他测试需要按配置初始化全局池）
+	t.Cleanup(func() {
+		if globalPool != nil {
+			globalPool.Shutdown(context.Background())
+		}
+		globalPool, poolOnce = nil, sync.Once{}
+	})
+
+	reqCtx, cancel := context.WithCancel(WithRequestID(context.Background(), "req-3"))
+	got := make(chan string, 1)
+	if err := SubmitTaskWithContext(reqCtx, "with_context", func(ctx context.Context) error {
+		<-time.After(50 * time.Millisecond)
+		if ctx.Err() != nil {
+			got <- "canceled"
+			return ctx.Err()
+		}
+		got <- RequestIDFromContext(ctx)
+		return nil
+	}, time.Second); err != nil {
+		t.Fatalf("提交任务失败: %v", err)
+	}
+	// 请求结束（context 取消）不影响已提交的任务
+	cancel()
+
+	select {
+	case id := <-got:
+		if id != "req-3" {
+			t.Fatalf("任务应携带请求ID且不随请求取消，实际 %q", id)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("任务未执行")
+	}
+}
@@ -44,6 +44,16 @@ func (rh *ResponseHandler) CodeErrorResponse(c *gin.Context, code int, errorCode
 	c.JSON(code, response)
 }
 
+// AppErrorResponse 按错误类型返回状态码和错误码；5xx错误不暴露内部信息，使用 fallbackMessage
+func (rh *ResponseHandler) AppErrorResponse(c *gin.Context, err error, fallbackMessage string) {
+	code := GetHTTPStatusCode(err)
+	message := fallbackMessage
+	if code < http.StatusInternalServerError {
+		message = err.Error()
+	}
+	rh.CodeErrorResponse(c, code, GetErrorCode(err), message)
+}
+
 // BadRequestResponse 400错误响应
 func (rh *ResponseHandler) BadRequestResponse(c *gin.Context, message string) {
 	rh.ErrorResponse(c, http.StatusBadRequest, message)
@@ -84,6 +94,15 @@ func (rh *ResponseHandler) TooManyRequestsResponse(c *gin.Context, message strin
 	rh.ErrorResponse(c, http.StatusTooManyRequests, message)
 }
 
+// RequestTooLargeResponse 请求体超过大小限制（413）
+func (rh *ResponseHandler) RequestTooLargeResponse(c *gin.Context, limit int64) {
+	message := "请求体过大"
+	if limit > 0 {
+		message = "请求体过大，最大允许 " + formatBytes(uint64(limit))
+	}
+	rh.CodeErrorResponse(c, http.StatusRequestEntityTooLarge, ErrCodeRequestTooLarge, message)
+}
+
 // 全局响应处理器实例
 var globalResponseHandler *ResponseHandler
 
@@ -108,6 +127,10 @@ func CodeErrorResponse(c *gin.Context, code int, errorCode string, message strin
 	GetResponseHandler().CodeErrorResponse(c, code, errorCode, message)
 }
 
+func AppErrorResponse(c *gin.Context, err error, fallbackMessage string) {
+	GetResponseHandler().AppErrorResponse(c, err, fallbackMessage)
+}
+
 func BadRequestResponse(c *gin.Context, message string) {
 	GetResponseHandler().BadRequestResponse(c, message)
 }
@@ -140,6 +163,10 @@ func TooManyRequestsResponse(c *gin.Context, message string) {
 	GetResponseHandler().TooManyRequestsResponse(c, message)
 }
 
+func RequestTooLargeResponse(c *gin.Context, limit int64) {
+	GetResponseHandler().RequestTooLargeResponse(c, limit)
+}
+
 // ParseUintParam 解析URL参数中的uint类型
 func ParseUintParam(c *gin.Context, param string) (uint, error) {
 	idStr := c.Param(param)
@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gin/internal/config"
+)
+
+// BackoffPolicy 指数退避重试策略
+type BackoffPolicy struct {
+	InitialInterval time.Duration // 首次重试间隔
+	MaxInterval     time.Duration // 最大重试间隔
+	Multiplier      float64       // 间隔增长倍数
+	MaxElapsed      time.Duration // 重试总时长（0表示只尝试一次）
+}
+
+// NewStartupBackoff 根据启动重试配置创建退避策略
+func NewStartupBackoff(cfg *config.StartupRetryConfig) BackoffPolicy {
+	return BackoffPolicy{
+		InitialInterval: time.Duration(cfg.InitialIntervalMS) * time.Millisecond,
+		MaxInterval:     time.Duration(cfg.MaxIntervalMS) * time.Millisecond,
+		Multiplier:      cfg.Multiplier,
+		MaxElapsed:      time.Duration(cfg.MaxElapsedSeconds) * time.Second,
+	}
+}
+
+// RetryWithBackoff 按退避策略重试 fn，直到成功、超过总时长或 ctx 取消，每次失败都会记录日志
+// 返回最后一次失败的错误
+func RetryWithBackoff(ctx context.Context, policy BackoffPolicy, name string, fn func(ctx context.Context) error) error {
+	logger := GetLogger()
+	start := time.Now()
+	interval := policy.InitialInterval
+
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			if attempt > 1 {
+				logger.Info("重试成功", "target", name, "attempts", attempt, "elapsed", time.Since(start))
+			}
+			return nil
+		}
+
+		elapsed := time.Since(start)
+		if elapsed+interval > policy.MaxElapsed {
+			logger.Error("重试次数已用尽", "target", name, "attempts", attempt, "elapsed", elapsed, "error", err.Error())
+			return fmt.Errorf("%s 在 %d 次尝试后仍失败: %w", name, attempt, err)
+		}
+
+		logger.Warn("连接失败，稍后重试",
+			"target", name,
+			"attempt", attempt,
+			"retryIn", interval,
+			"error", err.Error())
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%s 重试已取消: %w", name, err)
+		case <-timer.C:
+		}
+
+		interval = time.Duration(float64(interval) * policy.Multiplier)
+		if interval > policy.MaxInterval {
+			interval = policy.MaxInterval
+		}
+	}
+}
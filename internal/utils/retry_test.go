@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryWithBackoffSucceedsAfterTransientFailures(t *testing.T) {
+	policy := BackoffPolicy{InitialInterval: time.Millisecond, MaxInterval: 4 * time.Millisecond, Multiplier: 2, MaxElapsed: time.Second}
+
+	attempts := 0
+	err := RetryWithBackoff(context.Background(), policy, "test", func(context.Context) error {
+		attempts++
+		if attempts < 4 {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("前几次失败后恢复应成功，实际 %v", err)
+	}
+	if attempts != 4 {
+		t.Fatalf("应尝试4次，实际 %d 次", attempts)
+	}
+}
+
+func TestRetryWithBackoffGivesUpAfterMaxElapsed(t *testing.T) {
+	policy := BackoffPolicy{InitialInterval: 10 * time.Millisecond, MaxInterval: 20 * time.Millisecond, Multiplier: 2, MaxElapsed: 100 * time.Millisecond}
+	cause := errors.New("connection refused")
+
+	attempts := 0
+	start := time.Now()
+	err := RetryWithBackoff(context.Background(), policy, "test", func(context.Context) error {
+		attempts++
+		return cause
+	})
+	if !errors.Is(err, cause) {
+		t.Fatalf("一直不可用时应返回最后一次的错误，实际 %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("应在总时长内放弃，实际耗时 %v", elapsed)
+	}
+	if attempts < 2 {
+		t.Fatalf("放弃前应重试多次，实际 %d 次", attempts)
+	}
+}
+
+func TestRetryWithBackoffZeroElapsedTriesOnce(t *testing.T) {
+	attempts := 0
+	err := RetryWithBackoff(context.Background(), BackoffPolicy{InitialInterval: time.Millisecond}, "test", func(context.Context) error {
+		attempts++
+		return errors.New("down")
+	})
+	if err == nil || attempts != 1 {
+		t.Fatalf("总时长为0时应只尝试一次并返回错误，实际 %d 次 %v", attempts, err)
+	}
+}
+
+func TestRetryWithBackoffStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	policy := BackoffPolicy{InitialInterval: time.Hour, MaxInterval: time.Hour, Multiplier: 2, MaxElapsed: 2 * time.Hour}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- RetryWithBackoff(ctx, policy, "test", func(context.Context) error { return errors.New("down") })
+	}()
+	cancel()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatalf("取消后应返回错误，实际 %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("取消后应立即停止重试")
+	}
+}
@@ -0,0 +1,188 @@
+package utils
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gin/internal/models"
+)
+
+// 查询指纹归一化正则（字面量替换为 ?，IN 列表和多行 VALUES 折叠）
+var (
+	fingerprintStringRegex = regexp.MustCompile(`'(?:[^'\\]|\\.)*'|"(?:[^"\\]|\\.)*"`)
+	fingerprintNumberRegex = regexp.MustCompile(`\b\d+(\.\d+)?\b`)
+	fingerprintInListRegex = regexp.MustCompile(`\bin\s*\(\s*\?(\s*,\s*\?)*\s*\)`)
+	fingerprintValuesRegex = regexp.MustCompile(`(\(\s*\?(\s*,\s*\?)*\s*\))(\s*,\s*\(\s*\?(\s*,\s*\?)*\s*\))+`)
+)
+
+// SlowQueryAlertRule 慢查询频次告警规则：同一指纹在 Window 内出现 MinCount 次及以上时告警
+type SlowQueryAlertRule struct {
+	Window      time.Duration
+	MinCount    int
+	MaxPatterns int // 最多跟踪的指纹数（超出的新指纹不统计）
+}
+
+var defaultSlowQueryAlertRule = SlowQueryAlertRule{Window: 10 * time.Minute, MinCount: 5, MaxPatterns: 200}
+
+// slowQueryMinute 单个指纹一分钟内的出现次数
+type slowQueryMinute struct {
+	minute int64 // Unix分钟数
+	count  int64
+}
+
+// slowQueryPattern 单个查询指纹的窗口统计（按分钟的环形数组）
+type slowQueryPattern struct {
+	sample      string
+	minutes     []slowQueryMinute
+	maxDuration time.Duration
+	lastSeen    time.Time
+	alertedAt   time.Time // 最近一次告警时间，同一窗口内只通知一次
+}
+
+// SlowQueryFingerprint 生成查询指纹：去掉字面量和多余空白，同一形状的查询得到相同指纹
+func SlowQueryFingerprint(query string) string {
+	fp := strings.ToLower(strings.Join(strings.Fields(query), " "))
+	fp = fingerprintStringRegex.ReplaceAllString(fp, "?")
+	fp = fingerprintNumberRegex.ReplaceAllString(fp, "?")
+	fp = fingerprintInListRegex.ReplaceAllString(fp, "in (?+)")
+	fp = fingerprintValuesRegex.ReplaceAllString(fp, "$1")
+	return fp
+}
+
+// SetAlertRule 设置慢查询频次告警规则（非正数的字段保留当前值）
+func (d *SlowQueryDetector) SetAlertRule(rule SlowQueryAlertRule) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if rule.Window <= 0 {
+		rule.Window = d.alertRule.Window
+	}
+	if rule.MinCount <= 0 {
+		rule.MinCount = d.alertRule.MinCount
+	}
+	if rule.MaxPatterns <= 0 {
+		rule.MaxPatterns = d.alertRule.MaxPatterns
+	}
+	if windowMinutes(rule.Window) != windowMinutes(d.alertRule.Window) {
+		// 窗口长度变化后环形数组不再适用，重新统计
+		d.patterns = make(map[string]*slowQueryPattern)
+	}
+	d.alertRule = rule
+}
+
+// windowMinutes 窗口包含的分钟数（至少1分钟）
+func windowMinutes(window time.Duration) int {
+	if minutes := int(window / time.Minute); minutes > 0 {
+		return minutes
+	}
+	return 1
+}
+
+// recordPattern 按指纹累计一次慢查询，窗口内次数达到阈值时输出告警日志（每个窗口最多一次，调用方持有锁）
+func (d *SlowQueryDetector) recordPattern(query string, duration time.Duration, now time.Time) {
+	fingerprint := SlowQueryFingerprint(query)
+	p, ok := d.patterns[fingerprint]
+	if !ok {
+		if len(d.patterns) >= d.alertRule.MaxPatterns {
+			d.prunePatterns(now)
+			if len(d.patterns) >= d.alertRule.MaxPatterns {
+				return
+			}
+		}
+		p = &slowQueryPattern{
+			sample:  TruncateString(query, 500),
+			minutes: make([]slowQueryMinute, windowMinutes(d.alertRule.Window)),
+		}
+		d.patterns[fingerprint] = p
+	}
+
+	minute := now.Unix() / 60
+	b := &p.minutes[minute%int64(len(p.minutes))]
+	if b.minute != minute {
+		*b = slowQueryMinute{minute: minute}
+	}
+	b.count++
+	p.lastSeen = now
+	if duration > p.maxDuration {
+		p.maxDuration = duration
+	}
+
+	count := p.windowCount(minute)
+	if count < int64(d.alertRule.MinCount) || (!p.alertedAt.IsZero() && now.Sub(p.alertedAt) < d.alertRule.Window) {
+		return
+	}
+	p.alertedAt = now
+
+	GetLogger().Error("慢查询频繁出现",
+		"fingerprint", TruncateString(fingerprint, 200),
+		"count", count,
+		"windowMinutes", len(p.minutes),
+		"maxDuration", p.maxDuration)
+}
+
+// windowCount 截至 currentMinute 的窗口内出现次数
+func (p *slowQueryPattern) windowCount(currentMinute int64) int64 {
+	oldestMinute := currentMinute - int64(len(p.minutes)) + 1
+	var count int64
+	for _, b := range p.minutes {
+		if b.minute >= oldestMinute && b.minute <= currentMinute {
+			count += b.count
+		}
+	}
+	return count
+}
+
+// prunePatterns 删除窗口内没有出现过的指纹（调用方持有锁）
+func (d *SlowQueryDetector) prunePatterns(now time.Time) {
+	minute := now.Unix() / 60
+	for fingerprint, p := range d.patterns {
+		if p.windowCount(minute) == 0 {
+			delete(d.patterns, fingerprint)
+		}
+	}
+}
+
+// buildAlert 生成指纹的告警信息
+func (d *SlowQueryDetector) buildAlert(fingerprint string, p *slowQueryPattern, count int64) models.SlowQueryAlert {
+	return models.SlowQueryAlert{
+		Fingerprint:   fingerprint,
+		SampleQuery:   p.sample,
+		Count:         count,
+		MinCount:      d.alertRule.MinCount,
+		WindowMinutes: len(p.minutes),
+		MaxDurationMs: p.maxDuration.Milliseconds(),
+		LastSeenAt:    p.lastSeen.UTC(),
+	}
+}
+
+// Alerts 获取窗口内出现次数达到阈值的慢查询指纹，按次数降序排列
+func (d *SlowQueryDetector) Alerts(now time.Time) []models.SlowQueryAlert {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.prunePatterns(now)
+	minute := now.Unix() / 60
+	alerts := make([]models.SlowQueryAlert, 0)
+	for fingerprint, p := range d.patterns {
+		if count := p.windowCount(minute); count >= int64(d.alertRule.MinCount) {
+			alerts = append(alerts, d.buildAlert(fingerprint, p, count))
+		}
+	}
+
+	sort.Slice(alerts, func(i, j int) bool {
+		if alerts[i].Count != alerts[j].Count {
+			return alerts[i].Count > alerts[j].Count
+		}
+		return alerts[i].MaxDurationMs > alerts[j].MaxDurationMs
+	})
+	return alerts
+}
+
+// AlertRule 获取当前的慢查询频次告警规则
+func (d *SlowQueryDetector) AlertRule() SlowQueryAlertRule {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.alertRule
+}
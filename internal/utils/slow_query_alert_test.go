@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// newAlertTestDetector 慢查询阈值10ms，同一指纹10分钟内出现3次告警
+func newAlertTestDetector() *SlowQueryDetector {
+	d := NewSlowQueryDetector(10*time.Millisecond, 100, 20)
+	d.SetAlertRule(SlowQueryAlertRule{Window: 10 * time.Minute, MinCount: 3, MaxPatterns: 2})
+	return d
+}
+
+// recordAt 在指定时间记录一次慢查询
+func recordAt(d *SlowQueryDetector, query string, duration time.Duration, now time.Time) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.recordPattern(query, duration, now)
+}
+
+func TestSlowQueryFingerprint(t *testing.T) {
+	same := [][2]string{
+		{"SELECT * FROM articles WHERE id = 5", "select *  from articles\n WHERE id = 12"},
+		{"SELECT * FROM user_auth WHERE username = 'alice'", `SELECT * FROM user_auth WHERE username = "bob"`},
+		{"SELECT id FROM tags WHERE id IN (?, ?)", "SELECT id FROM tags WHERE id IN (?,?,?,?)"},
+		{"INSERT INTO t (a, b) VALUES (?, ?)", "INSERT INTO t (a, b) VALUES (?, ?), (?, ?), (?, ?)"},
+		{"SELECT * FROM t WHERE score > 1.5", "SELECT * FROM t WHERE score > 30"},
+	}
+	for _, pair := range same {
+		if a, b := SlowQueryFingerprint(pair[0]), SlowQueryFingerprint(pair[1]); a != b {
+			t.Errorf("同一形状的查询应得到相同指纹:\n%q -> %q\n%q -> %q", pair[0], a, pair[1], b)
+		}
+	}
+
+	if SlowQueryFingerprint("SELECT * FROM articles WHERE id = 1") == SlowQueryFingerprint("SELECT * FROM resources WHERE id = 1") {
+		t.Error("不同形状的查询指纹应不同")
+	}
+	if fp := SlowQueryFingerprint("SELECT * FROM t2 WHERE name = 'x1'"); fp != "select * from t2 where name = ?" {
+		t.Errorf("标识符中的数字不应被替换，实际 %q", fp)
+	}
+}
+
+func TestRepeatedSlowQueriesTriggerAlert(t *testing.T) {
+	d := newAlertTestDetector()
+
+	for i := 0; i < 2; i++ {
+		d.Record(fmt.Sprintf("SELECT * FROM articles WHERE id = %d", i), 20*time.Millisecond, nil)
+	}
+	d.Record("SELECT * FROM resources WHERE id = 1", 50*time.Millisecond, nil) // 只出现一次
+	for i := 0; i < 5; i++ {
+		d.Record("SELECT * FROM articles WHERE id = 9", time.Millisecond, nil) // 未超过慢查询阈值
+	}
+	if alerts := d.Alerts(time.Now()); len(alerts) != 0 {
+		t.Fatalf("未达到次数阈值时不应告警，实际 %+v", alerts)
+	}
+
+	d.Record("SELECT * FROM articles WHERE id = 7", 40*time.Millisecond, nil)
+	alerts := d.Alerts(time.Now())
+	if len(alerts) != 1 {
+		t.Fatalf("同一形状的慢查询达到3次应告警，且一次性的慢查询不告警，实际 %+v", alerts)
+	}
+	alert := alerts[0]
+	if alert.Fingerprint != "select * from articles where id = ?" || alert.Count != 3 || alert.MinCount != 3 || alert.WindowMinutes != 10 {
+		t.Fatalf("告警信息错误: %+v", alert)
+	}
+	if alert.SampleQuery != "SELECT * FROM articles WHERE id = 0" || alert.MaxDurationMs != 40 {
+		t.Fatalf("应记录首次出现的原始查询和最长耗时，实际 %+v", alert)
+	}
+}
+
+func TestSlowQueryAlertWindow(t *testing.T) {
+	d := newAlertTestDetector()
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	query := "SELECT * FROM articles WHERE id = 1"
+
+	recordAt(d, query, 20*time.Millisecond, start)
+	recordAt(d, query, 20*time.Millisecond, start.Add(4*time.Minute))
+	recordAt(d, query, 20*time.Millisecond, start.Add(11*time.Minute)) // 第一次已滑出窗口
+	if alerts := d.Alerts(start.Add(11 * time.Minute)); len(alerts) != 0 {
+		t.Fatalf("窗口外的次数不应计入，实际 %+v", alerts)
+	}
+
+	recordAt(d, query, 20*time.Millisecond, start.Add(12*time.Minute))
+	if alerts := d.Alerts(start.Add(12 * time.Minute)); len(alerts) != 1 || alerts[0].Count != 3 {
+		t.Fatalf("窗口内达到3次应告警，实际 %+v", alerts)
+	}
+
+	// 窗口过后没有再出现，告警消失且指纹被清理
+	if alerts := d.Alerts(start.Add(30 * time.Minute)); len(alerts) != 0 {
+		t.Fatalf("窗口内没有再出现时告警应消失，实际 %+v", alerts)
+	}
+	if len(d.patterns) != 0 {
+		t.Fatalf("过期的指纹应被清理，实际 %d 个", len(d.patterns))
+	}
+}
+
+func TestSlowQueryAlertMaxPatterns(t *testing.T) {
+	d := newAlertTestDetector()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	recordAt(d, "SELECT * FROM a WHERE id = 1", 20*time.Millisecond, now)
+	recordAt(d, "SELECT * FROM b WHERE id = 1", 20*time.Millisecond, now)
+	for i := 0; i < 3; i++ {
+		recordAt(d, "SELECT * FROM c WHERE id = 1", 20*time.Millisecond, now)
+	}
+	if _, ok := d.patterns["select * from c where id = ?"]; ok || len(d.patterns) != 2 {
+		t.Fatalf("超过指纹上限的新指纹不应统计，实际 %d 个", len(d.patterns))
+	}
+
+	// 旧指纹过期后可以统计新指纹
+	later := now.Add(20 * time.Minute)
+	recordAt(d, "SELECT * FROM c WHERE id = 1", 20*time.Millisecond, later)
+	if _, ok := d.patterns["select * from c where id = ?"]; !ok || len(d.patterns) != 1 {
+		t.Fatalf("清理过期指纹后应统计新指纹，实际 %v", d.patterns)
+	}
+}
+
+func TestSetSlowQueryAlertRule(t *testing.T) {
+	d := newAlertTestDetector()
+	recordAt(d, "SELECT 1", 20*time.Millisecond, time.Now())
+
+	d.SetAlertRule(SlowQueryAlertRule{MinCount: 7})
+	if rule := d.AlertRule(); rule.MinCount != 7 || rule.Window != 10*time.Minute || rule.MaxPatterns != 2 {
+		t.Fatalf("非正数字段应保留当前值，实际 %+v", rule)
+	}
+	if len(d.patterns) != 1 {
+		t.Fatal("窗口不变时应保留已有统计")
+	}
+
+	d.SetAlertRule(SlowQueryAlertRule{Window: 5 * time.Minute})
+	if len(d.patterns) != 0 {
+		t.Fatal("窗口长度变化后应重新统计")
+	}
+}
@@ -1,8 +1,11 @@
 package utils
 
 import (
+	"net"
+	"net/url"
 	"regexp"
 	"strings"
+	"time"
 	"unicode"
 
 	"gin/internal/config"
@@ -11,6 +14,7 @@ import (
 var (
 	emailRegex    = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
 	usernameRegex = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+	hostnameRegex = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)*[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
 )
 
 // ValidateEmail 验证邮箱格式
@@ -179,6 +183,86 @@ func ValidateURL(url string) bool {
 	return (len(url) >= 7 && url[:7] == "http://") || (len(url) >= 8 && url[:8] == "https://")
 }
 
+// SanitizeProfileURL 校验并规范化个人资料中的链接（个人网站、GitHub）
+// 空字符串表示清除链接，视为有效；只允许 http/https，拒绝 javascript:、data: 等可能导致XSS的协议
+func SanitizeProfileURL(rawURL string, cfg *config.ValidationExtendedConfig) (string, bool) {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return "", true
+	}
+	if len(rawURL) < cfg.URLMinLength || (cfg.URLMaxLength > 0 && len(rawURL) > cfg.URLMaxLength) {
+		return "", false
+	}
+	// 不允许空白和控制字符（防止协议名中夹带字符绕过检查）
+	for _, r := range rawURL {
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			return "", false
+		}
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", false
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if scheme != "http" && scheme != "https" {
+		return "", false
+	}
+	// 不允许带账号信息（https://good.com@evil.com 形式的伪装链接）
+	if u.User != nil {
+		return "", false
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if host == "" {
+		return "", false
+	}
+	if net.ParseIP(host) == nil && (len(host) > 253 || !hostnameRegex.MatchString(host)) {
+		return "", false
+	}
+
+	u.Scheme = scheme
+	if port := u.Port(); port != "" {
+		u.Host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		u.Host = "[" + host + "]"
+	} else {
+		u.Host = host
+	}
+	return u.String(), true
+}
+
+// ValidatePhoneWithConfig 验证手机号（11位数字，首位和第二位按配置限制）
+func ValidatePhoneWithConfig(phone string, cfg *config.ValidationExtendedConfig) bool {
+	if len(phone) != 11 {
+		return false
+	}
+	for i := 0; i < len(phone); i++ {
+		if phone[i] < '0' || phone[i] > '9' {
+			return false
+		}
+	}
+	if cfg.PhoneFirstDigit != "" && phone[:1] != cfg.PhoneFirstDigit {
+		return false
+	}
+	if cfg.PhoneSecondDigitMin != "" && phone[1:2] < cfg.PhoneSecondDigitMin {
+		return false
+	}
+	if cfg.PhoneSecondDigitMax != "" && phone[1:2] > cfg.PhoneSecondDigitMax {
+		return false
+	}
+	return true
+}
+
+// ValidateBirthday 验证生日（YYYY-MM-DD，不早于1900年且不晚于今天）
+func ValidateBirthday(birthday string) bool {
+	t, err := time.Parse("2006-01-02", birthday)
+	if err != nil {
+		return false
+	}
+	return t.Year() >= 1900 && !t.After(time.Now().UTC())
+}
+
 // ValidatePositiveInt 验证正整数
 func ValidatePositiveInt(n int) bool {
 	return n > 0
@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gin/internal/config"
+)
+
+func TestSanitizeProfileURL(t *testing.T) {
+	cfg := &config.Default().ValidationExtended
+	cases := []struct {
+		name   string
+		input  string
+		want   string
+		wantOK bool
+	}{
+		{"有效的https链接", "https://example.com/me", "https://example.com/me", true},
+		{"协议和域名规范化为小写", " HTTPS://GitHub.com/Alice ", "https://github.com/Alice", true},
+		{"空字符串表示清除", "", "", true},
+		{"只有空白视为清除", "   ", "", true},
+		{"javascript协议", "javascript:alert(1)", "", false},
+		{"大写javascript协议", "JavaScript:alert(document.cookie)", "", false},
+		{"data协议", "data:text/html;base64,PHNjcmlwdD4=", "", false},
+		{"协议名夹带控制字符", "java\tscript:alert(1)", "", false},
+		{"超长链接", "https://example.com/" + strings.Repeat("a", cfg.URLMaxLength), "", false},
+		{"过短链接", "http:/", "", false},
+		{"缺少主机", "https:///path", "", false},
+		{"带账号信息的伪装链接", "https://good.com@evil.com/", "", false},
+		{"非法主机名", "https://exa_mple..com/", "", false},
+		{"ftp协议", "ftp://example.com/file", "", false},
+	}
+	for _, tc := range cases {
+		got, ok := SanitizeProfileURL(tc.input, cfg)
+		if ok != tc.wantOK || got != tc.want {
+			t.Errorf("%s: SanitizeProfileURL(%q) = (%q, %v)，期望 (%q, %v)", tc.name, tc.input, got, ok, tc.want, tc.wantOK)
+		}
+	}
+}
+
+func TestSanitizeProfileURLUsesConfiguredMaxLength(t *testing.T) {
+	cfg := config.Default().ValidationExtended
+	url := "https://example.com/" + strings.Repeat("a", 30)
+
+	cfg.URLMaxLength = len(url)
+	if _, ok := SanitizeProfileURL(url, &cfg); !ok {
+		t.Fatal("长度等于上限的链接应有效")
+	}
+	cfg.URLMaxLength = len(url) - 1
+	if _, ok := SanitizeProfileURL(url, &cfg); ok {
+		t.Fatal("超过配置上限的链接应被拒绝")
+	}
+}
+
+func TestValidatePhoneWithConfig(t *testing.T) {
+	cfg := &config.Default().ValidationExtended
+	cases := map[string]bool{
+		"13800138000":  true,
+		"19912345678":  true,
+		"12800138000":  false, // 第二位小于3
+		"23800138000":  false, // 首位不是1
+		"1380013800":   false,
+		"138001380000": false,
+		"1380013800a":  false,
+		"":             false,
+	}
+	for phone, want := range cases {
+		if got := ValidatePhoneWithConfig(phone, cfg); got != want {
+			t.Errorf("ValidatePhoneWithConfig(%q) = %v，期望 %v", phone, got, want)
+		}
+	}
+}
+
+func TestValidateBirthday(t *testing.T) {
+	today := time.Now().UTC().Format("2006-01-02")
+	cases := map[string]bool{
+		"1995-06-01": true,
+		today:        true,
+		"1900-01-01": true,
+		"1899-12-31": false,
+		"2999-01-01": false,
+		"1995-02-30": false,
+		"1995/06/01": false,
+		"":           false,
+	}
+	for birthday, want := range cases {
+		if got := ValidateBirthday(birthday); got != want {
+			t.Errorf("ValidateBirthday(%q) = %v，期望 %v", birthday, got, want)
+		}
+	}
+}
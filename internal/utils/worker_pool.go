@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"gin/internal/config"
@@ -12,11 +13,29 @@ import (
 
 // Task 表示一个异步任务
 type Task struct {
-	ID      string
-	Execute func(context.Context) error
-	Timeout time.Duration
+	ID        string
+	Execute   func(context.Context) error
+	Timeout   time.Duration
+	RequestID string // 发起任务的请求ID（可选），注入任务 context 和日志
+
+	// 可重试的数据库错误的重试设置（通过 WithRetry/WithDeadLetter 设置，零值使用池的默认策略）
+	MaxAttempts  int
+	RetryBackoff time.Duration
+	OnDeadLetter func(task Task, err error)
 }
 
+// 池关闭后提交任务的处理策略
+const (
+	PoolShutdownInline = "inline" // 在提交者goroutine中同步执行
+	PoolShutdownReject = "reject" // 返回 ErrWorkerPoolClosed
+)
+
+// 队列已满时提交任务的处理策略
+const (
+	PoolQueueFullReject = "reject" // 立即返回 ErrWorkerPoolQueueFull
+	PoolQueueFullBlock  = "block"  // 等待队列空位，超过 queueFullWait 仍无空位时返回 ErrWorkerPoolQueueFull
+)
+
 // WorkerPool Goroutine 池
 type WorkerPool struct {
 	workers        int
@@ -26,8 +45,22 @@ type WorkerPool struct {
 	cancel         context.CancelFunc
 	logger         Logger
 	metrics        *PoolMetrics
+	taskTypes      map[string]*TaskTypeMetrics // 按任务类型（任务ID前缀）的统计，受 metricsMux 保护
 	metricsMux     sync.RWMutex
 	defaultTimeout time.Duration // 默认任务超时
+
+	queueFullPolicy string        // 队列已满时的处理策略
+	queueFullWait   time.Duration // block 策略下等待队列空位的最长时间
+	highWaterMark   atomic.Int64  // 队列长度告警阈值（0 表示不告警）
+	aboveHighWater  atomic.Bool   // 是否已输出高水位告警
+	lastRejectLog   atomic.Int64  // 最近一次输出拒绝日志的时间（UnixNano）
+
+	defaultRetry atomic.Pointer[TaskRetryPolicy] // 默认重试策略（nil 表示不重试）
+
+	// closeMu 保护 closed 与 taskQueue 的关闭：提交时持读锁，关闭时持写锁，避免向已关闭的队列发送导致panic
+	closeMu        sync.RWMutex
+	closed         bool
+	shutdownPolicy string // 关闭后提交任务的处理策略
 }
 
 // PoolMetrics 池指标
@@ -36,8 +69,11 @@ type PoolMetrics struct {
 	TasksCompleted  uint64
 	TasksFailed     uint64
 	TasksTimeout    uint64
+	TasksRejected   uint64 // 队列已满被拒绝的任务数
+	RunningTasks    int    // 执行中的任务数
 	ActiveWorkers   int
 	QueuedTasks     int
+	QueueCapacity   int
 	TotalExecutions uint64
 }
 
@@ -49,15 +85,18 @@ func NewWorkerPool(workers int, queueSize int, defaultTimeout time.Duration) *Wo
 	
 	ctx, cancel := context.WithCancel(context.Background())
 	pool := &WorkerPool{
-		workers:        workers,
-		taskQueue:      make(chan Task, queueSize),
-		ctx:            ctx,
-		cancel:         cancel,
-		logger:         GetLogger(),
-		defaultTimeout: defaultTimeout,
+		workers:         workers,
+		taskQueue:       make(chan Task, queueSize),
+		ctx:             ctx,
+		cancel:          cancel,
+		logger:          GetLogger(),
+		defaultTimeout:  defaultTimeout,
+		shutdownPolicy:  PoolShutdownInline,
+		queueFullPolicy: PoolQueueFullReject,
 		metrics: &PoolMetrics{
 			ActiveWorkers: 0,
 		},
+		taskTypes: make(map[string]*TaskTypeMetrics),
 	}
 
 	pool.start()
@@ -87,6 +126,7 @@ func (p *WorkerPool) worker(id int) {
 	for {
 		select {
 		case <-p.ctx.Done():
+			p.dropQueued()
 			p.logger.Debug("Worker退出", "workerID", id, "reason", "context cancelled")
 			return
 
@@ -95,6 +135,11 @@ func (p *WorkerPool) worker(id int) {
 				p.logger.Debug("Worker退出", "workerID", id, "reason", "queue closed")
 				return
 			}
+			if p.ctx.Err() != nil {
+				// 强制关闭后不再执行排队中的任务（Shutdown 已计入放弃数）
+				p.taskDropped(task)
+				continue
+			}
 
 			p.executeTask(id, task)
 		}
@@ -104,12 +149,15 @@ func (p *WorkerPool) worker(id int) {
 // executeTask 执行任务
 func (p *WorkerPool) executeTask(workerID int, task Task) {
 	startTime := time.Now()
+	logger := p.taskLogger(task)
 	p.metricsMux.Lock()
 	p.metrics.TotalExecutions++
 	execNum := p.metrics.TotalExecutions
 	p.metricsMux.Unlock()
 
-	p.logger.Debug("Worker开始执行任务",
+	p.taskStarted(task)
+
+	logger.Debug("Worker开始执行任务",
 		"workerID", workerID,
 		"taskID", task.ID,
 		"execNum", execNum)
@@ -120,7 +168,7 @@ func (p *WorkerPool) executeTask(workerID int, task Task) {
 		timeout = p.defaultTimeout // 使用pool配置的默认超时
 	}
 
-	taskCtx, cancel := context.WithTimeout(p.ctx, timeout)
+	taskCtx, cancel := context.WithTimeout(WithRequestID(p.ctx, task.RequestID), timeout)
 	defer cancel()
 
 	// 使用 channel 接收任务结果
@@ -128,7 +176,7 @@ func (p *WorkerPool) executeTask(workerID int, task Task) {
 	go func() {
 		defer func() {
 			if r := recover(); r != nil {
-				p.logger.Error("任务执行发生panic",
+				logger.Error("任务执行发生panic",
 					"workerID", workerID,
 					"taskID", task.ID,
 					"panic", r)
@@ -136,7 +184,7 @@ func (p *WorkerPool) executeTask(workerID int, task Task) {
 			}
 		}()
 
-		done <- task.Execute(taskCtx)
+		done <- p.runWithRetry(taskCtx, task, logger)
 	}()
 
 	// 等待任务完成或超时
@@ -144,23 +192,23 @@ func (p *WorkerPool) executeTask(workerID int, task Task) {
 	case err := <-done:
 		duration := time.Since(startTime)
 		if err != nil {
-			p.incrementFailedTasks()
-			p.logger.Error("任务执行失败",
+			p.taskFinished(task, taskOutcomeFailed, duration, true)
+			logger.Error("任务执行失败",
 				"workerID", workerID,
 				"taskID", task.ID,
 				"error", err.Error(),
 				"duration", duration)
 		} else {
-			p.incrementCompletedTasks()
-			p.logger.Debug("任务执行成功",
+			p.taskFinished(task, taskOutcomeCompleted, duration, true)
+			logger.Debug("任务执行成功",
 				"workerID", workerID,
 				"taskID", task.ID,
 				"duration", duration)
 		}
 
 	case <-taskCtx.Done():
-		p.incrementTimeoutTasks()
-		p.logger.Warn("任务执行超时",
+		p.taskFinished(task, taskOutcomeTimeout, time.Since(startTime), true)
+		logger.Warn("任务执行超时",
 			"workerID", workerID,
 			"taskID", task.ID,
 			"timeout", timeout,
@@ -168,45 +216,172 @@ func (p *WorkerPool) executeTask(workerID int, task Task) {
 	}
 }
 
-// Submit 提交任务
+// taskLogger 返回携带任务请求ID的日志器
+func (p *WorkerPool) taskLogger(task Task) Logger {
+	if task.RequestID == "" {
+		return p.logger
+	}
+	return p.logger.With("request_id", task.RequestID)
+}
+
+// SetShutdownPolicy 设置池关闭后提交任务的处理策略（inline 或 reject）
+func (p *WorkerPool) SetShutdownPolicy(policy string) {
+	p.closeMu.Lock()
+	defer p.closeMu.Unlock()
+	p.shutdownPolicy = policy
+}
+
+// submitAfterClose 处理池关闭后提交的任务：inline 策略同步执行并返回 nil，reject 策略返回 ErrWorkerPoolClosed
+// 调用时不能持有 closeMu：同步执行的任务可能再次提交任务，持锁执行会与等待写锁的 Shutdown 互相等待
+func (p *WorkerPool) submitAfterClose(task Task, policy string) error {
+	if policy != PoolShutdownInline {
+		p.logger.Warn("Worker Pool已关闭，拒绝任务", "taskID", task.ID, "taskType", TaskTypeFromID(task.ID))
+		return ErrWorkerPoolClosed
+	}
+	p.logger.Warn("Worker Pool已关闭，任务改为同步执行", "taskID", task.ID)
+	p.runInline(task)
+	return nil
+}
+
+// SetQueueFullPolicy 设置队列已满时的处理策略（reject 或 block）、block 策略的最长等待时间和队列高水位告警阈值
+func (p *WorkerPool) SetQueueFullPolicy(policy string, wait time.Duration, highWaterMark int) {
+	p.closeMu.Lock()
+	defer p.closeMu.Unlock()
+	p.queueFullPolicy = policy
+	p.queueFullWait = wait
+	p.highWaterMark.Store(int64(highWaterMark))
+}
+
+// runInline 在当前goroutine中执行任务（不依赖池的context，强制关闭后仍可执行）
+func (p *WorkerPool) runInline(task Task) {
+	start := time.Now()
+	timeout := task.Timeout
+	if timeout == 0 {
+		timeout = p.defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(WithRequestID(context.Background(), task.RequestID), timeout)
+	defer cancel()
+
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				p.taskLogger(task).Error("同步执行任务发生panic", "taskID", task.ID, "panic", r)
+				err = fmt.Errorf("panic: %v", r)
+			}
+		}()
+		return p.runWithRetry(ctx, task, p.taskLogger(task))
+	}()
+	if err != nil {
+		p.taskFinished(task, taskOutcomeFailed, time.Since(start), false)
+		p.taskLogger(task).Error("同步执行任务失败", "taskID", task.ID, "error", err.Error())
+		return
+	}
+	p.taskFinished(task, taskOutcomeCompleted, time.Since(start), false)
+}
+
+// Submit 提交任务（池关闭后按 shutdownPolicy 处理，不会panic）
+// 队列已满时按 queueFullPolicy 立即拒绝或等待空位，最终仍无空位时返回 ErrWorkerPoolQueueFull
 func (p *WorkerPool) Submit(task Task) error {
 	p.incrementSubmittedTasks()
 
-	select {
-	case <-p.ctx.Done():
-		return fmt.Errorf("worker pool已关闭")
-	case p.taskQueue <- task:
-		p.logger.Debug("任务已提交", "taskID", task.ID, "queueLength", len(p.taskQueue))
-		return nil
-	default:
-		return fmt.Errorf("任务队列已满")
+	queued, closed, policy := p.enqueue(task, -1)
+	if closed {
+		return p.submitAfterClose(task, policy)
+	}
+	if !queued {
+		return ErrWorkerPoolQueueFull
 	}
+	return nil
 }
 
-// SubmitWithTimeout 提交任务（带超时）
+// SubmitWithTimeout 提交任务（带超时，池关闭后按 shutdownPolicy 处理）
 func (p *WorkerPool) SubmitWithTimeout(task Task, submitTimeout time.Duration) error {
 	p.incrementSubmittedTasks()
 
-	ctx, cancel := context.WithTimeout(context.Background(), submitTimeout)
-	defer cancel()
+	if submitTimeout < 0 {
+		submitTimeout = 0
+	}
+	queued, closed, policy := p.enqueue(task, submitTimeout)
+	if closed {
+		return p.submitAfterClose(task, policy)
+	}
+	if !queued {
+		return fmt.Errorf("提交任务超时")
+	}
+	return nil
+}
+
+// enqueue 持有 closeMu 读锁将任务放入队列，队列已满时最多等待 wait（wait < 0 表示按 queueFullPolicy 决定）
+// 池已关闭时不入队，返回 closed 和当时的关闭策略，由调用方释放读锁后再处理
+func (p *WorkerPool) enqueue(task Task, wait time.Duration) (queued, closed bool, shutdownPolicy string) {
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+	if p.closed {
+		return false, true, p.shutdownPolicy
+	}
 
 	select {
 	case <-p.ctx.Done():
-		return fmt.Errorf("worker pool已关闭")
-	case <-ctx.Done():
-		return fmt.Errorf("提交任务超时")
+		return false, true, p.shutdownPolicy
 	case p.taskQueue <- task:
-		p.logger.Debug("任务已提交", "taskID", task.ID)
-		return nil
+		p.taskQueued(task)
+		return true, false, ""
+	default:
+	}
+
+	if wait < 0 {
+		wait = 0
+		if p.queueFullPolicy == PoolQueueFullBlock {
+			wait = p.queueFullWait
+		}
+	}
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-p.ctx.Done():
+			return false, true, p.shutdownPolicy
+		case p.taskQueue <- task:
+			p.taskQueued(task)
+			return true, false, ""
+		case <-timer.C:
+		}
 	}
+
+	p.taskRejected(task)
+	return false, false, ""
 }
 
-// Shutdown 优雅关闭池
-func (p *WorkerPool) Shutdown(timeout time.Duration) error {
-	p.logger.Info("开始关闭Worker Pool", "timeout", timeout)
+// dropQueued 强制关闭后丢弃队列中剩余的任务（队列已由 Shutdown 关闭，不会阻塞）
+func (p *WorkerPool) dropQueued() {
+	for {
+		select {
+		case task, ok := <-p.taskQueue:
+			if !ok {
+				return
+			}
+			p.taskDropped(task)
+		default:
+			return
+		}
+	}
+}
 
-	// 停止接收新任务
-	close(p.taskQueue)
+// Shutdown 优雅关闭池：停止接收新任务（之后提交的任务按 shutdownPolicy 处理），
+// 等待排队和执行中的任务完成；ctx 到期时取消剩余任务，记录并返回放弃的任务数
+func (p *WorkerPool) Shutdown(ctx context.Context) error {
+	before := p.GetMetrics()
+	p.logger.Info("开始关闭Worker Pool",
+		"queuedTasks", before.QueuedTasks,
+		"runningTasks", before.RunningTasks)
+
+	// 停止接收新任务（等待进行中的提交完成后再关闭队列；重复调用只关闭一次）
+	p.closeMu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.taskQueue)
+	}
+	p.closeMu.Unlock()
 
 	// 等待所有任务完成或超时
 	done := make(chan struct{})
@@ -217,17 +392,25 @@ func (p *WorkerPool) Shutdown(timeout time.Duration) error {
 
 	select {
 	case <-done:
+		metrics := p.GetMetrics()
 		p.logger.Info("Worker Pool已优雅关闭",
-			"completedTasks", p.metrics.TasksCompleted,
-			"failedTasks", p.metrics.TasksFailed,
-			"timeoutTasks", p.metrics.TasksTimeout)
+			"completedTasks", metrics.TasksCompleted,
+			"failedTasks", metrics.TasksFailed,
+			"timeoutTasks", metrics.TasksTimeout,
+			"rejectedTasks", metrics.TasksRejected)
 		return nil
 
-	case <-time.After(timeout):
+	case <-ctx.Done():
+		// 先统计再取消：取消后执行中的任务会按超时结束
+		metrics := p.GetMetrics()
+		backlog := p.backlogSummary(5)
 		p.cancel() // 强制取消所有任务
-		p.logger.Warn("Worker Pool关闭超时，强制终止",
-			"timeout", timeout)
-		return fmt.Errorf("关闭超时")
+		p.logger.Warn("Worker Pool关闭超时，放弃未完成的任务",
+			"abandonedQueued", metrics.QueuedTasks,
+			"abandonedRunning", metrics.RunningTasks,
+			"backlog", backlog)
+		return fmt.Errorf("关闭超时，放弃%d个排队任务和%d个执行中任务: %w",
+			metrics.QueuedTasks, metrics.RunningTasks, ctx.Err())
 	}
 }
 
@@ -238,6 +421,7 @@ func (p *WorkerPool) GetMetrics() PoolMetrics {
 
 	metrics := *p.metrics
 	metrics.QueuedTasks = len(p.taskQueue)
+	metrics.QueueCapacity = cap(p.taskQueue)
 	return metrics
 }
 
@@ -248,24 +432,6 @@ func (p *WorkerPool) incrementSubmittedTasks() {
 	p.metricsMux.Unlock()
 }
 
-func (p *WorkerPool) incrementCompletedTasks() {
-	p.metricsMux.Lock()
-	p.metrics.TasksCompleted++
-	p.metricsMux.Unlock()
-}
-
-func (p *WorkerPool) incrementFailedTasks() {
-	p.metricsMux.Lock()
-	p.metrics.TasksFailed++
-	p.metricsMux.Unlock()
-}
-
-func (p *WorkerPool) incrementTimeoutTasks() {
-	p.metricsMux.Lock()
-	p.metrics.TasksTimeout++
-	p.metricsMux.Unlock()
-}
-
 func (p *WorkerPool) incrementActiveWorkers() {
 	p.metricsMux.Lock()
 	p.metrics.ActiveWorkers++
@@ -291,8 +457,11 @@ func GetGlobalPool() *WorkerPool {
 }
 
 // GetGlobalPoolWithConfig 使用配置获取全局 Worker Pool
+// 全局池只创建一次：如果在此之前已经按默认值创建（如配置加载前提交了任务），配置不会生效，输出告警
 func GetGlobalPoolWithConfig(cfg *config.WorkerPoolConfig) *WorkerPool {
+	created := false
 	poolOnce.Do(func() {
+		created = true
 		// 使用默认配置
 		workers := 10
 		queueSize := 1000
@@ -306,22 +475,53 @@ func GetGlobalPoolWithConfig(cfg *config.WorkerPoolConfig) *WorkerPool {
 		}
 
 		globalPool = NewWorkerPool(workers, queueSize, defaultTimeout)
+		if cfg != nil && cfg.ShutdownPolicy != "" {
+			globalPool.SetShutdownPolicy(cfg.ShutdownPolicy)
+		}
+		if cfg != nil && cfg.QueueFullPolicy != "" {
+			globalPool.SetQueueFullPolicy(cfg.QueueFullPolicy,
+				time.Duration(cfg.QueueFullWaitMS)*time.Millisecond,
+				cfg.QueueHighWaterMark)
+		}
 	})
+	if cfg != nil && !created {
+		globalPool.logger.Warn("全局Worker Pool已创建，worker_pool 配置未生效（需在首次提交任务前初始化）")
+	}
 	return globalPool
 }
 
 // InitGlobalPool 初始化全局 Worker Pool（带配置）
 func InitGlobalPool(cfg *config.Config) {
-	GetGlobalPoolWithConfig(&cfg.WorkerPool)
+	pool := GetGlobalPoolWithConfig(&cfg.WorkerPool)
+	pool.SetDefaultRetry(cfg.AsyncTasks.RetryMaxAttempts,
+		time.Duration(cfg.AsyncTasks.RetryBackoffBaseMS)*time.Millisecond)
 }
 
-// SubmitTask 提交任务到全局池
-func SubmitTask(taskID string, fn func(context.Context) error, timeout time.Duration) error {
+// SubmitTask 提交任务到全局池（opts 可设置重试和死信回调）
+func SubmitTask(taskID string, fn func(context.Context) error, timeout time.Duration, opts ...TaskOption) error {
 	task := Task{
 		ID:      taskID,
 		Execute: fn,
 		Timeout: timeout,
 	}
+	for _, opt := range opts {
+		opt(&task)
+	}
+	return GetGlobalPool().Submit(task)
+}
+
+// SubmitTaskWithContext 提交任务到全局池，并携带 ctx 中的请求ID
+// 任务执行时的 context 与请求 context 的取消无关，仅传递请求ID
+func SubmitTaskWithContext(ctx context.Context, taskID string, fn func(context.Context) error, timeout time.Duration, opts ...TaskOption) error {
+	task := Task{
+		ID:        taskID,
+		Execute:   fn,
+		Timeout:   timeout,
+		RequestID: RequestIDFromContext(ctx),
+	}
+	for _, opt := range opts {
+		opt(&task)
+	}
 	return GetGlobalPool().Submit(task)
 }
 
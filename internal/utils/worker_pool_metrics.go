@@ -0,0 +1,211 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TaskDurationBuckets 任务耗时直方图的桶上限（秒）
+var TaskDurationBuckets = []float64{0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30}
+
+// maxTrackedTaskTypes 最多单独统计的任务类型数，超出的归入 other（防止任务ID格式异常导致标签过多）
+const maxTrackedTaskTypes = 100
+
+// otherTaskType 无法识别或超出统计上限的任务类型
+const otherTaskType = "other"
+
+// 任务结束状态
+const (
+	taskOutcomeCompleted = iota
+	taskOutcomeFailed
+	taskOutcomeTimeout
+)
+
+// TaskTypeMetrics 单个任务类型的统计
+type TaskTypeMetrics struct {
+	Type            string
+	Queued          int64    // 当前排队中的任务数
+	Running         int64    // 当前执行中的任务数
+	Submitted       uint64   // 成功入队的任务数
+	Rejected        uint64   // 队列已满被拒绝的任务数
+	Completed       uint64   // 执行成功的任务数
+	Failed          uint64   // 执行失败（含panic）的任务数
+	Timeout         uint64   // 执行超时的任务数
+	DurationBuckets []uint64 // 各耗时桶的累计计数（与 TaskDurationBuckets 一一对应）
+	DurationSum     float64  // 执行总耗时（秒）
+	DurationCount   uint64   // 已记录耗时的任务数
+}
+
+// TaskTypeFromID 从任务ID前缀推导任务类型：取开头最多两段纯字母片段（以 _ 或 - 分隔），
+// 如 incr_view_12 → incr_view，login-history-5-1700000000 → login-history，stats_/api/x_abc → stats
+func TaskTypeFromID(id string) string {
+	end, segments, start := 0, 0, 0
+	for i := 0; i <= len(id) && segments < 2; i++ {
+		if i < len(id) && id[i] != '_' && id[i] != '-' {
+			continue
+		}
+		if !isLetters(id[start:i]) {
+			break
+		}
+		end = i
+		segments++
+		start = i + 1
+	}
+	if end == 0 {
+		return otherTaskType
+	}
+	return id[:end]
+}
+
+// isLetters 是否为非空的纯ASCII字母串
+func isLetters(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i] | 0x20; c < 'a' || c > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+// typeMetrics 返回任务类型的统计项（调用方持有 metricsMux 写锁）
+func (p *WorkerPool) typeMetrics(taskType string) *TaskTypeMetrics {
+	if m, ok := p.taskTypes[taskType]; ok {
+		return m
+	}
+	if len(p.taskTypes) >= maxTrackedTaskTypes {
+		taskType = otherTaskType
+		if m, ok := p.taskTypes[taskType]; ok {
+			return m
+		}
+	}
+	m := &TaskTypeMetrics{Type: taskType, DurationBuckets: make([]uint64, len(TaskDurationBuckets))}
+	p.taskTypes[taskType] = m
+	return m
+}
+
+// taskQueued 记录任务入队，队列长度达到高水位时输出一次告警（回落到高水位一半以下后才会再次告警）
+func (p *WorkerPool) taskQueued(task Task) {
+	p.metricsMux.Lock()
+	m := p.typeMetrics(TaskTypeFromID(task.ID))
+	m.Submitted++
+	m.Queued++
+	p.metricsMux.Unlock()
+
+	queueLength := len(p.taskQueue)
+	p.logger.Debug("任务已提交", "taskID", task.ID, "queueLength", queueLength)
+	mark := int(p.highWaterMark.Load())
+	if mark > 0 && queueLength >= mark && p.aboveHighWater.CompareAndSwap(false, true) {
+		p.logger.Warn("Worker Pool队列超过高水位",
+			"queueLength", queueLength,
+			"queueSize", cap(p.taskQueue),
+			"highWaterMark", mark,
+			"backlog", p.backlogSummary(5))
+	}
+}
+
+// taskRejected 记录因队列已满被拒绝的任务（告警日志每秒最多一条）
+func (p *WorkerPool) taskRejected(task Task) {
+	p.metricsMux.Lock()
+	p.metrics.TasksRejected++
+	p.typeMetrics(TaskTypeFromID(task.ID)).Rejected++
+	p.metricsMux.Unlock()
+
+	now := time.Now().UnixNano()
+	last := p.lastRejectLog.Load()
+	if now-last >= int64(time.Second) && p.lastRejectLog.CompareAndSwap(last, now) {
+		p.logger.Warn("Worker Pool队列已满，拒绝任务",
+			"taskID", task.ID,
+			"queueSize", cap(p.taskQueue),
+			"policy", p.queueFullPolicy,
+			"backlog", p.backlogSummary(5))
+	}
+}
+
+// taskStarted 记录任务出队开始执行
+func (p *WorkerPool) taskStarted(task Task) {
+	p.metricsMux.Lock()
+	p.metrics.RunningTasks++
+	m := p.typeMetrics(TaskTypeFromID(task.ID))
+	m.Queued--
+	m.Running++
+	p.metricsMux.Unlock()
+
+	if mark := int(p.highWaterMark.Load()); mark > 0 && p.aboveHighWater.Load() && len(p.taskQueue) < mark/2 {
+		p.aboveHighWater.Store(false)
+	}
+}
+
+// taskDropped 记录强制关闭后未执行就丢弃的排队任务
+func (p *WorkerPool) taskDropped(task Task) {
+	p.metricsMux.Lock()
+	p.typeMetrics(TaskTypeFromID(task.ID)).Queued--
+	p.metricsMux.Unlock()
+	p.logger.Debug("Worker Pool已强制关闭，丢弃排队任务", "taskID", task.ID)
+}
+
+// taskFinished 记录任务结束状态和耗时（running 表示任务是否由 taskStarted 计入了执行中）
+func (p *WorkerPool) taskFinished(task Task, outcome int, duration time.Duration, running bool) {
+	seconds := duration.Seconds()
+
+	p.metricsMux.Lock()
+	defer p.metricsMux.Unlock()
+
+	m := p.typeMetrics(TaskTypeFromID(task.ID))
+	if running {
+		p.metrics.RunningTasks--
+		m.Running--
+	}
+	switch outcome {
+	case taskOutcomeCompleted:
+		p.metrics.TasksCompleted++
+		m.Completed++
+	case taskOutcomeFailed:
+		p.metrics.TasksFailed++
+		m.Failed++
+	case taskOutcomeTimeout:
+		p.metrics.TasksTimeout++
+		m.Timeout++
+	}
+	for i, bound := range TaskDurationBuckets {
+		if seconds <= bound {
+			m.DurationBuckets[i]++
+		}
+	}
+	m.DurationSum += seconds
+	m.DurationCount++
+}
+
+// GetTaskTypeMetrics 获取按任务类型的统计（按类型名排序）
+func (p *WorkerPool) GetTaskTypeMetrics() []TaskTypeMetrics {
+	p.metricsMux.RLock()
+	defer p.metricsMux.RUnlock()
+
+	result := make([]TaskTypeMetrics, 0, len(p.taskTypes))
+	for _, m := range p.taskTypes {
+		item := *m
+		item.DurationBuckets = append([]uint64(nil), m.DurationBuckets...)
+		result = append(result, item)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Type < result[j].Type })
+	return result
+}
+
+// backlogSummary 排队最多的前 n 个任务类型，格式为 type=count
+func (p *WorkerPool) backlogSummary(n int) string {
+	metrics := p.GetTaskTypeMetrics()
+	sort.SliceStable(metrics, func(i, j int) bool { return metrics[i].Queued > metrics[j].Queued })
+
+	parts := make([]string, 0, n)
+	for _, m := range metrics {
+		if len(parts) == n || m.Queued <= 0 {
+			break
+		}
+		parts = append(parts, fmt.Sprintf("%s=%d", m.Type, m.Queued))
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// retriableMySQLErrors 可重试的MySQL错误码
+var retriableMySQLErrors = map[uint16]bool{
+	1040: true, // ER_CON_COUNT_ERROR：连接数已满
+	1205: true, // ER_LOCK_WAIT_TIMEOUT：锁等待超时
+	1213: true, // ER_LOCK_DEADLOCK：死锁
+	2006: true, // CR_SERVER_GONE_ERROR：服务器连接已断开
+	2013: true, // CR_SERVER_LOST：查询过程中连接丢失
+}
+
+// IsRetriableDBError 判断是否为可重试的数据库错误：按 *mysql.MySQLError 的错误码（连接数已满、锁等待超时、死锁、连接断开）
+// 以及驱动的失效连接错误判断，错误需由数据层用 %w 包装传出
+func IsRetriableDBError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return retriableMySQLErrors[mysqlErr.Number]
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && !netErr.Timeout()
+}
+
+// TaskRetryPolicy 任务失败重试策略
+type TaskRetryPolicy struct {
+	MaxAttempts int           // 最大执行次数（1 表示不重试）
+	BaseBackoff time.Duration // 首次重试前的等待时间，之后每次翻倍
+}
+
+// TaskOption 提交任务时的可选设置
+type TaskOption func(*Task)
+
+// WithRetry 为任务单独设置重试次数和退避时间（maxAttempts 为 0 或 baseBackoff 为 0 时沿用池的默认值）
+func WithRetry(maxAttempts int, baseBackoff time.Duration) TaskOption {
+	return func(t *Task) {
+		t.MaxAttempts = maxAttempts
+		t.RetryBackoff = baseBackoff
+	}
+}
+
+// WithDeadLetter 设置死信回调：任务因可重试错误用尽重试次数（或等待重试时超时）后调用，用于关键任务的兜底处理
+func WithDeadLetter(fn func(task Task, err error)) TaskOption {
+	return func(t *Task) {
+		t.OnDeadLetter = fn
+	}
+}
+
+// SetDefaultRetry 设置未单独指定重试策略的任务使用的默认策略
+func (p *WorkerPool) SetDefaultRetry(maxAttempts int, baseBackoff time.Duration) {
+	p.defaultRetry.Store(&TaskRetryPolicy{MaxAttempts: maxAttempts, BaseBackoff: baseBackoff})
+}
+
+// retryPolicy 合并任务和池的重试策略
+func (p *WorkerPool) retryPolicy(task Task) TaskRetryPolicy {
+	policy := TaskRetryPolicy{MaxAttempts: 1}
+	if def := p.defaultRetry.Load(); def != nil {
+		policy = *def
+	}
+	if task.MaxAttempts > 0 {
+		policy.MaxAttempts = task.MaxAttempts
+	}
+	if task.RetryBackoff > 0 {
+		policy.BaseBackoff = task.RetryBackoff
+	}
+	return policy
+}
+
+// runWithRetry 执行任务：可重试的数据库错误按指数退避重试，其余错误直接返回（由调用方记录后丢弃）；
+// 重试用尽或等待重试时 ctx 结束，调用死信回调后返回最后一次的错误
+func (p *WorkerPool) runWithRetry(ctx context.Context, task Task, logger Logger) error {
+	policy := p.retryPolicy(task)
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = task.Execute(ctx)
+		if err == nil || !IsRetriableDBError(err) {
+			return err
+		}
+		if attempt >= policy.MaxAttempts {
+			if policy.MaxAttempts > 1 {
+				err = fmt.Errorf("重试%d次后仍然失败: %w", policy.MaxAttempts-1, err)
+			}
+			break
+		}
+
+		backoff := policy.BaseBackoff << (attempt - 1)
+		logger.Warn("任务失败，准备重试",
+			"taskID", task.ID,
+			"attempt", attempt,
+			"maxAttempts", policy.MaxAttempts,
+			"backoff", backoff,
+			"error", err.Error())
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+			continue
+		case <-ctx.Done():
+			timer.Stop()
+			err = fmt.Errorf("等待重试时任务结束(%v): %w", ctx.Err(), err)
+		}
+		break
+	}
+
+	p.deadLetter(task, err, logger)
+	return err
+}
+
+// deadLetter 调用任务的死信回调（回调panic不影响worker）
+func (p *WorkerPool) deadLetter(task Task, err error, logger Logger) {
+	if task.OnDeadLetter == nil {
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("任务死信回调发生panic", "taskID", task.ID, "panic", r)
+		}
+	}()
+	task.OnDeadLetter(task, err)
+}
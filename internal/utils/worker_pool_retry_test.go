@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestIsRetriableDBError(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"锁等待超时", &mysql.MySQLError{Number: 1205}, true},
+		{"死锁", &mysql.MySQLError{Number: 1213}, true},
+		{"连接断开", &mysql.MySQLError{Number: 2006}, true},
+		{"连接丢失", &mysql.MySQLError{Number: 2013}, true},
+		{"唯一键冲突", &mysql.MySQLError{Number: 1062}, false},
+		{"数据层包装的死锁", fmt.Errorf("%w: %w", ErrDatabaseUpdate, &mysql.MySQLError{Number: 1213}), true},
+		{"数据层包装的语法错误", fmt.Errorf("%w: %w", ErrDatabaseQuery, &mysql.MySQLError{Number: 1064}), false},
+		{"失效连接", driver.ErrBadConn, true},
+		{"驱动连接错误", fmt.Errorf("%w: %w", ErrDatabaseInsert, mysql.ErrInvalidConn), true},
+		{"未包装驱动错误的哨兵错误", ErrDatabaseUpdate, false},
+		{"仅错误文本相似", errors.New("Deadlock found when trying to get lock"), false},
+	}
+	for _, tc := range cases {
+		if got := IsRetriableDBError(tc.err); got != tc.want {
+			t.Errorf("%s: IsRetriableDBError = %v，期望 %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestTaskRetriesRetriableErrorThenSucceeds(t *testing.T) {
+	pool := NewWorkerPool(1, 10, time.Second)
+	defer pool.Shutdown(context.Background())
+
+	var attempts atomic.Int32
+	var deadLettered atomic.Bool
+	done := make(chan struct{})
+	task := Task{ID: "retry_ok", Execute: func(ctx context.Context) error {
+		if attempts.Add(1) < 3 {
+			return fmt.Errorf("%w: %w", ErrDatabaseInsert, &mysql.MySQLError{Number: 1213})
+		}
+		close(done)
+		return nil
+	}}
+	WithRetry(3, time.Millisecond)(&task)
+	WithDeadLetter(func(Task, error) { deadLettered.Store(true) })(&task)
+
+	if err := pool.Submit(task); err != nil {
+		t.Fatalf("提交失败: %v", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("任务未在重试后成功")
+	}
+	if got := attempts.Load(); got != 3 {
+		t.Fatalf("应执行3次，实际 %d 次", got)
+	}
+	if deadLettered.Load() {
+		t.Fatal("重试成功后不应调用死信回调")
+	}
+}
+
+func TestTaskDeadLetterAfterRetriesExhausted(t *testing.T) {
+	pool := NewWorkerPool(1, 10, time.Second)
+	defer pool.Shutdown(context.Background())
+
+	var attempts atomic.Int32
+	deadLetter := make(chan error, 1)
+	task := Task{ID: "retry_fail", Execute: func(ctx context.Context) error {
+		attempts.Add(1)
+		return fmt.Errorf("%w: %w", ErrDatabaseUpdate, &mysql.MySQLError{Number: 1205})
+	}}
+	WithRetry(2, time.Millisecond)(&task)
+	WithDeadLetter(func(_ Task, err error) { deadLetter <- err })(&task)
+
+	if err := pool.Submit(task); err != nil {
+		t.Fatalf("提交失败: %v", err)
+	}
+	select {
+	case err := <-deadLetter:
+		if !errors.Is(err, ErrDatabaseUpdate) {
+			t.Fatalf("死信错误应保留原始错误，实际: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("重试用尽后应调用死信回调")
+	}
+	if got := attempts.Load(); got != 2 {
+		t.Fatalf("应执行2次，实际 %d 次", got)
+	}
+}
+
+func TestTaskNonRetriableErrorNotRetried(t *testing.T) {
+	pool := NewWorkerPool(1, 10, time.Second)
+	pool.SetDefaultRetry(5, time.Millisecond)
+
+	var attempts atomic.Int32
+	var deadLettered atomic.Bool
+	task := Task{ID: "no_retry", Execute: func(ctx context.Context) error {
+		attempts.Add(1)
+		return fmt.Errorf("%w: %w", ErrDatabaseInsert, &mysql.MySQLError{Number: 1062})
+	}}
+	WithDeadLetter(func(Task, error) { deadLettered.Store(true) })(&task)
+
+	if err := pool.Submit(task); err != nil {
+		t.Fatalf("提交失败: %v", err)
+	}
+	if err := pool.Shutdown(context.Background()); err != nil {
+		t.Fatalf("关闭池失败: %v", err)
+	}
+	if got := attempts.Load(); got != 1 {
+		t.Fatalf("不可重试的错误应只执行1次，实际 %d 次", got)
+	}
+	if deadLettered.Load() {
+		t.Fatal("不可重试的错误不应进入死信")
+	}
+}
@@ -0,0 +1,323 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gin/internal/config"
+)
+
+// closedPool 创建一个已关闭的池
+func closedPool(t *testing.T, policy string) *WorkerPool {
+	t.Helper()
+	pool := NewWorkerPool(2, 10, time.Second)
+	pool.SetShutdownPolicy(policy)
+	if err := pool.Shutdown(context.Background()); err != nil {
+		t.Fatalf("关闭池失败: %v", err)
+	}
+	return pool
+}
+
+func TestSubmitAfterShutdownRejectPolicy(t *testing.T) {
+	pool := closedPool(t, PoolShutdownReject)
+
+	var ran atomic.Bool
+	task := Task{ID: "late_reject", Execute: func(ctx context.Context) error {
+		ran.Store(true)
+		return nil
+	}}
+
+	if err := pool.Submit(task); !errors.Is(err, ErrWorkerPoolClosed) {
+		t.Fatalf("Submit 应返回 ErrWorkerPoolClosed，实际: %v", err)
+	}
+	if err := pool.SubmitWithTimeout(task, 10*time.Millisecond); !errors.Is(err, ErrWorkerPoolClosed) {
+		t.Fatalf("SubmitWithTimeout 应返回 ErrWorkerPoolClosed，实际: %v", err)
+	}
+	if ran.Load() {
+		t.Fatal("reject 策略下任务不应执行")
+	}
+}
+
+func TestSubmitAfterShutdownInlinePolicy(t *testing.T) {
+	pool := closedPool(t, PoolShutdownInline)
+
+	var runs atomic.Int32
+	task := Task{ID: "late_inline", Execute: func(ctx context.Context) error {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("同步执行的任务应带超时")
+		}
+		runs.Add(1)
+		return nil
+	}}
+
+	if err := pool.Submit(task); err != nil {
+		t.Fatalf("inline 策略下 Submit 应返回 nil，实际: %v", err)
+	}
+	if err := pool.SubmitWithTimeout(task, 10*time.Millisecond); err != nil {
+		t.Fatalf("inline 策略下 SubmitWithTimeout 应返回 nil，实际: %v", err)
+	}
+	// 同步执行：返回时任务已经执行完
+	if got := runs.Load(); got != 2 {
+		t.Fatalf("任务应同步执行2次，实际 %d 次", got)
+	}
+}
+
+func TestSubmitAfterForcedShutdownDoesNotPanic(t *testing.T) {
+	pool := NewWorkerPool(1, 10, time.Second)
+	pool.SetShutdownPolicy(PoolShutdownReject)
+
+	started := make(chan struct{})
+	_ = pool.Submit(Task{ID: "slow", Execute: func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := pool.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("强制关闭应返回超时错误，实际: %v", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		err := pool.Submit(Task{ID: "late", Execute: func(ctx context.Context) error { return nil }})
+		if !errors.Is(err, ErrWorkerPoolClosed) {
+			t.Fatalf("强制关闭后提交应返回 ErrWorkerPoolClosed，实际: %v", err)
+		}
+	}
+	// 重复关闭不应panic
+	if err := pool.Shutdown(context.Background()); err != nil {
+		t.Fatalf("重复关闭失败: %v", err)
+	}
+}
+
+// 同步执行的任务再次提交任务时，若此时有写锁在等待，持读锁执行会死锁
+func TestInlineTaskResubmitWhileWriterWaiting(t *testing.T) {
+	pool := closedPool(t, PoolShutdownInline)
+
+	var nested atomic.Bool
+	done := make(chan error, 1)
+	go func() {
+		done <- pool.Submit(Task{ID: "outer", Execute: func(ctx context.Context) error {
+			writerDone := make(chan struct{})
+			go func() {
+				pool.SetShutdownPolicy(PoolShutdownInline) // 等待 closeMu 写锁
+				close(writerDone)
+			}()
+			select {
+			case <-writerDone:
+			case <-time.After(50 * time.Millisecond):
+			}
+			return pool.Submit(Task{ID: "inner", Execute: func(ctx context.Context) error {
+				nested.Store(true)
+				return nil
+			}})
+		}})
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("提交失败: %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("同步执行的任务再次提交时死锁")
+	}
+	if !nested.Load() {
+		t.Fatal("嵌套提交的任务应同步执行")
+	}
+}
+
+func TestInitGlobalPoolAppliesConfig(t *testing.T) {
+	cfg := &config.Config{
+		WorkerPool: config.WorkerPoolConfig{
+			Workers:            2,
+			QueueSize:          8,
+			DefaultTaskTimeout: 5,
+			ShutdownPolicy:     PoolShutdownReject,
+			QueueFullPolicy:    PoolQueueFullBlock,
+			QueueFullWaitMS:    250,
+			QueueHighWaterMark: 6,
+		},
+	}
+	InitGlobalPool(cfg)
+
+	pool := GetGlobalPool()
+	if got := cap(pool.taskQueue); got != 8 {
+		t.Fatalf("队列大小应为配置值 8，实际 %d", got)
+	}
+	if pool.shutdownPolicy != PoolShutdownReject {
+		t.Fatalf("关闭策略应为 reject，实际 %q", pool.shutdownPolicy)
+	}
+	if pool.queueFullPolicy != PoolQueueFullBlock || pool.queueFullWait != 250*time.Millisecond {
+		t.Fatalf("队列策略应为 block/250ms，实际 %q/%v", pool.queueFullPolicy, pool.queueFullWait)
+	}
+	if got := pool.highWaterMark.Load(); got != 6 {
+		t.Fatalf("高水位应为 6，实际 %d", got)
+	}
+}
+
+// fullPool 创建一个 worker 被占住、队列已满的池，返回释放 worker 的函数
+func fullPool(t *testing.T, policy string, wait time.Duration) (*WorkerPool, func()) {
+	t.Helper()
+	pool := NewWorkerPool(1, 1, 5*time.Second)
+	pool.SetQueueFullPolicy(policy, wait, 0)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	noop := func(ctx context.Context) error { return nil }
+	if err := pool.Submit(Task{ID: "hold_worker", Execute: func(ctx context.Context) error {
+		close(started)
+		<-release
+		return nil
+	}}); err != nil {
+		t.Fatalf("提交失败: %v", err)
+	}
+	<-started
+	if err := pool.Submit(Task{ID: "fill_queue", Execute: noop}); err != nil {
+		t.Fatalf("提交失败: %v", err)
+	}
+	t.Cleanup(func() { _ = pool.Shutdown(context.Background()) })
+	return pool, func() { close(release) }
+}
+
+func TestQueueFullRejectPolicy(t *testing.T) {
+	pool, release := fullPool(t, PoolQueueFullReject, 0)
+	defer release()
+
+	err := pool.Submit(Task{ID: "incr_view_1", Execute: func(ctx context.Context) error { return nil }})
+	if !errors.Is(err, ErrWorkerPoolQueueFull) {
+		t.Fatalf("队列已满应返回 ErrWorkerPoolQueueFull，实际: %v", err)
+	}
+	if got := pool.GetMetrics().TasksRejected; got != 1 {
+		t.Fatalf("拒绝计数应为 1，实际 %d", got)
+	}
+	for _, m := range pool.GetTaskTypeMetrics() {
+		if m.Type == "incr_view" && m.Rejected != 1 {
+			t.Fatalf("incr_view 类型拒绝计数应为 1，实际 %d", m.Rejected)
+		}
+	}
+}
+
+func TestQueueFullBlockPolicyWaitsForSpace(t *testing.T) {
+	pool, release := fullPool(t, PoolQueueFullBlock, 2*time.Second)
+
+	time.AfterFunc(50*time.Millisecond, release)
+	start := time.Now()
+	if err := pool.Submit(Task{ID: "blocked", Execute: func(ctx context.Context) error { return nil }}); err != nil {
+		t.Fatalf("block 策略在等待期间有空位时应提交成功，实际: %v", err)
+	}
+	if time.Since(start) < 40*time.Millisecond {
+		t.Fatal("block 策略应等待队列空位")
+	}
+}
+
+func TestQueueFullBlockPolicyTimesOut(t *testing.T) {
+	pool, release := fullPool(t, PoolQueueFullBlock, 30*time.Millisecond)
+	defer release()
+
+	err := pool.Submit(Task{ID: "blocked", Execute: func(ctx context.Context) error { return nil }})
+	if !errors.Is(err, ErrWorkerPoolQueueFull) {
+		t.Fatalf("等待超时后应返回 ErrWorkerPoolQueueFull，实际: %v", err)
+	}
+}
+
+func TestTaskTypeFromID(t *testing.T) {
+	cases := map[string]string{
+		"incr_view_12":                "incr_view",
+		"login-history-5-1700000000":  "login-history",
+		"stats_/api/x_abc":            "stats",
+		"123":                         otherTaskType,
+		"":                            otherTaskType,
+		"cleanup_avatar_7_1700000000": "cleanup_avatar",
+		"refresh_hot_article_42":      "refresh_hot",
+	}
+	for id, want := range cases {
+		if got := TaskTypeFromID(id); got != want {
+			t.Errorf("TaskTypeFromID(%q) = %q，期望 %q", id, got, want)
+		}
+	}
+}
+
+func TestShutdownRunsQueuedTasks(t *testing.T) {
+	pool := NewWorkerPool(1, 20, time.Second)
+	var runs atomic.Int32
+	for i := 0; i < 20; i++ {
+		if err := pool.Submit(Task{ID: "queued", Execute: func(ctx context.Context) error {
+			time.Sleep(2 * time.Millisecond)
+			runs.Add(1)
+			return nil
+		}}); err != nil {
+			t.Fatalf("提交失败: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := pool.Shutdown(ctx); err != nil {
+		t.Fatalf("优雅关闭失败: %v", err)
+	}
+	if n := runs.Load(); n != 20 {
+		t.Fatalf("优雅关闭应执行完队列中的任务，实际执行 %d 个", n)
+	}
+}
+
+func TestShutdownAbandonsTasksAfterDeadline(t *testing.T) {
+	readLogs := captureLogs(t, &config.LogConfig{Level: "info", Format: LogFormatJSON, Output: "stdout"})
+	pool := NewWorkerPool(1, 10, time.Second)
+	pool.SetShutdownPolicy(PoolShutdownReject)
+
+	started := make(chan struct{})
+	_ = pool.Submit(Task{ID: "history_slow", Execute: func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+	<-started
+	var runs atomic.Int32
+	for i := 0; i < 3; i++ {
+		if err := pool.Submit(Task{ID: "history_queued", Execute: func(ctx context.Context) error {
+			runs.Add(1)
+			return nil
+		}}); err != nil {
+			t.Fatalf("提交失败: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := pool.Shutdown(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "放弃3个排队任务和1个执行中任务") {
+		t.Fatalf("关闭超时应返回放弃的任务数，实际: %v", err)
+	}
+
+	// 强制关闭后排队中的任务不再执行，也不再计入排队数
+	deadline := time.Now().Add(2 * time.Second)
+	for pool.GetMetrics().RunningTasks > 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if n := runs.Load(); n != 0 {
+		t.Fatalf("强制关闭后不应执行排队中的任务，实际执行 %d 个", n)
+	}
+	for _, m := range pool.GetTaskTypeMetrics() {
+		if m.Type == TaskTypeFromID("history_queued") && (m.Queued != 0 || m.Running != 0) {
+			t.Fatalf("丢弃的任务不应留在排队或执行中指标里，实际 %+v", m)
+		}
+	}
+
+	var warned map[string]interface{}
+	for _, entry := range readLogs() {
+		if entry["message"] == "Worker Pool关闭超时，放弃未完成的任务" {
+			warned = entry
+		}
+	}
+	if warned == nil || warned["abandonedQueued"] != float64(3) || warned["abandonedRunning"] != float64(1) {
+		t.Fatalf("应记录放弃的排队和执行中任务数，实际 %v", warned)
+	}
+}
@@ -12,6 +12,7 @@ import (
 
 	"gin/internal/bootstrap"
 	"gin/internal/config"
+	"gin/internal/handlers"
 	"gin/internal/middleware"
 	"gin/internal/routes"
 	"gin/internal/services"
@@ -33,20 +34,37 @@ func main() {
 	// 初始化每日指标管理器
 	services.InitDailyMetricsManager(cfg)
 
+	// 初始化接口告警监控
+	services.InitEndpointAlertMonitor(cfg)
+
 	// 初始化日志系统
 	if err := utils.InitLogger(&cfg.Log); err != nil {
 		fmt.Printf("初始化日志系统失败: %v\n", err)
 		os.Exit(1)
 	}
+	utils.ConfigureLogRedaction(cfg.LogExtended.SensitiveKeys)
+
+	// 初始化异步任务 Worker Pool（必须在首次提交任务前按配置创建，否则关闭策略、队列策略和重试策略都不会生效）
+	utils.InitGlobalPool(cfg)
 
 	// 初始化性能分析器
 	utils.InitGlobalProfiler(&cfg.Profiler)
 	utils.InitGlobalSlowQueryDetector(&cfg.Profiler)
+	utils.GetGlobalSlowQueryDetector().SetAlertRule(utils.SlowQueryAlertRule{
+		Window:      time.Duration(cfg.Alerts.SlowQueryWindowMinutes) * time.Minute,
+		MinCount:    cfg.Alerts.SlowQueryMinCount,
+		MaxPatterns: cfg.Alerts.SlowQueryMaxPatterns,
+	})
+
+	// 初始化实时指标管理器（在线用户按配置定期清理）
+	services.GetRealtimeMetricsManagerWithConfig(&cfg.Metrics)
 
 	logger := utils.GetLogger()
 	logger.Info("应用启动",
 		"app", cfg.App.Name,
 		"version", cfg.App.Version,
+		"gitCommit", utils.GitCommit,
+		"buildTime", utils.BuildTime,
 		"mode", cfg.Server.Mode,
 		"host", cfg.Server.Host,
 		"port", cfg.Server.Port,
@@ -102,7 +120,7 @@ func main() {
 	}
 
 	// 初始化限流器（必须在设置路由之前）
-	middleware.InitRateLimiter(cfg)
+	middleware.InitRateLimiter(cfg, container.TokenBlacklist)
 	logger.Info("限流器初始化完成")
 
 	// 设置路由
@@ -144,10 +162,16 @@ func main() {
 
 	logger.Info("✅ 应用启动完成，等待请求...")
 
-	// 等待中断信号
+	// 等待中断信号（SIGHUP 触发配置热更新，不退出）
 	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	sig := <-quit
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	var sig os.Signal
+	for sig = range quit {
+		if sig != syscall.SIGHUP {
+			break
+		}
+		reloadConfig(container)
+	}
 
 	logger.Info("收到关闭信号，正在优雅关闭服务器...",
 		"signal", sig.String())
@@ -168,16 +192,34 @@ func main() {
 		logger.Info("服务器已优雅关闭")
 	}
 
+	// 断开 WebSocket 连接（Shutdown 不处理已升级的连接，需通知客户端并等待发送完队列中的消息）
+	logger.Info("正在断开WebSocket连接...")
+	if err := handlers.ShutdownConnectionHub(ctx); err != nil {
+		logger.Warn("WebSocket连接断开超时", "error", err.Error())
+	}
+	handlers.ShutdownCollabHub(ctx) // 关闭协作编辑房间并保存未写回的代码
+
 	// 关闭限流器（释放goroutine和内存）
 	logger.Info("正在关闭限流器...")
 	middleware.ShutdownRateLimiters()
 
-	// 关闭Worker Pool
+	// 停止后台缓存刷新和令牌清理（在Worker Pool关闭前，避免继续提交任务）
+	container.HotArticleRefresher.Stop()
+	container.TokenCleaner.Stop()
+	container.TempFileSweeper.Stop()
+
+	// 关闭Worker Pool（执行完队列中的任务，确保审计日志等异步写入在关闭数据库前落库）
+	// 使用独立的超时：服务器关闭可能已耗尽 ctx 的大部分时间
 	logger.Info("正在关闭Worker Pool...")
-	pool := utils.GetGlobalPool()
-	if err := pool.Shutdown(10 * time.Second); err != nil {
+	poolCtx, poolCancel := context.WithTimeout(context.Background(), time.Duration(cfg.WorkerPool.ShutdownTimeout)*time.Second)
+	if err := utils.GetGlobalPool().Shutdown(poolCtx); err != nil {
 		logger.Warn("Worker Pool关闭超时", "error", err.Error())
 	}
+	poolCancel()
+
+	// 写入内存中的当天统计（Worker Pool 已执行完排队的统计任务）
+	logger.Info("正在写入每日统计...")
+	container.StatsAggregator.Stop(ctx)
 
 	// 关闭日志（flush 异步队列）
 	logger.Info("正在关闭日志系统...")
@@ -188,6 +230,55 @@ func main() {
 	}
 }
 
+// reloadConfig 重新加载配置文件并应用可热更新的配置项
+func reloadConfig(container *bootstrap.Container) {
+	logger := utils.GetLogger()
+	logger.Info("收到SIGHUP信号，开始重新加载配置...")
+
+	newCfg, changes, err := config.Reload()
+	if err != nil {
+		logger.Error("配置重载失败，继续使用当前配置", "error", err.Error())
+		return
+	}
+	if len(changes) == 0 {
+		logger.Info("配置重载完成，没有变更")
+		return
+	}
+
+	applied := 0
+	for _, change := range changes {
+		if !change.Applied {
+			logger.Warn("配置变更已忽略（需要重启生效）", "field", change.Field)
+			continue
+		}
+		applied++
+		logger.Info("配置变更已应用", "field", change.Field, "old", change.Old, "new", change.New)
+	}
+	if applied == 0 {
+		return
+	}
+
+	// 将新值下发到持有运行时状态的组件
+	if err := utils.SetLogLevel(newCfg.Log.Level); err != nil {
+		logger.Warn("更新日志级别失败", "error", err.Error())
+	}
+	if err := utils.SetLogFormat(newCfg.Log.Format); err != nil {
+		logger.Warn("切换日志格式失败", "error", err.Error())
+	}
+	utils.ConfigureLogRedaction(newCfg.LogExtended.SensitiveKeys)
+	middleware.UpdateRateLimiters(newCfg)
+	container.CacheSvc.UpdateConfig(&newCfg.Cache)
+	container.DB.SetSlowQueryThreshold(newCfg.DatabaseQuery.SlowQueryThresholdMS)
+	if err := utils.ConfigureContentModeration(&newCfg.Moderation); err != nil {
+		logger.Warn("更新内容审核配置失败", "error", err.Error())
+	}
+	if newCfg.Profiler.SlowQueryThresholdMS > 0 {
+		utils.GetGlobalSlowQueryDetector().SetThreshold(time.Duration(newCfg.Profiler.SlowQueryThresholdMS) * time.Millisecond)
+	}
+
+	logger.Info("配置热更新完成", "applied", applied, "ignored", len(changes)-applied)
+}
+
 // checkServerHealth 检查服务器健康状态
 func checkServerHealth(cfg *config.Config) error {
 	url := fmt.Sprintf("http://%s:%s/health", cfg.Server.Host, cfg.Server.Port)
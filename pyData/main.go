@@ -4,6 +4,7 @@ import (
 	"crypto/sha256"
 	"database/sql"
 	"fmt"
+	"hash/fnv"
 	"log"
 	"math/rand"
 	"os"
@@ -11,6 +12,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
@@ -37,9 +39,66 @@ var (
 
 var workerCount = determineWorkerCount()
 
-// 添加一个全局的用户名映射来确保用户名唯一性
-var usedUsernames = make(map[string]bool)
-var usernameMutex sync.Mutex
+// 内存中用户名去重表：避免重复用户名白白往返数据库，唯一性最终由数据库唯一约束保证
+var usedUsernames = newUsernameSet(determineUsernameCacheMax())
+
+// usernameShardCount 用户名去重表的分片数（按哈希分片，减少大量 worker 并发时的锁竞争）
+const usernameShardCount = 64
+
+// usernameSet 分片的用户名去重表，最多记录 maxSize 个用户名
+type usernameSet struct {
+	shards  [usernameShardCount]usernameShard
+	maxSize int64
+	size    atomic.Int64
+}
+
+type usernameShard struct {
+	mu    sync.Mutex
+	names map[string]struct{}
+}
+
+func newUsernameSet(maxSize int) *usernameSet {
+	s := &usernameSet{maxSize: int64(maxSize)}
+	for i := range s.shards {
+		s.shards[i].names = make(map[string]struct{})
+	}
+	return s
+}
+
+// reserve 预占用户名，已被占用返回 false
+// 表已满（或 maxSize 为 0）时不再记录，直接返回 true，由插入时的重复键重试兜底
+func (s *usernameSet) reserve(username string) bool {
+	if s.maxSize <= 0 {
+		return true
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(username))
+	shard := &s.shards[h.Sum32()%usernameShardCount]
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	if _, used := shard.names[username]; used {
+		return false
+	}
+	if s.size.Load() >= s.maxSize {
+		return true
+	}
+	shard.names[username] = struct{}{}
+	s.size.Add(1)
+	return true
+}
+
+// determineUsernameCacheMax 用户名去重表容量，默认与用户数量相同
+// 环境变量 USERNAME_CACHE_MAX 可调小以限制内存，设为 0 则完全依赖数据库唯一约束
+func determineUsernameCacheMax() int {
+	if override := strings.TrimSpace(os.Getenv("USERNAME_CACHE_MAX")); override != "" {
+		if parsed, err := strconv.Atoi(override); err == nil && parsed >= 0 {
+			return parsed
+		}
+	}
+	return USER_COUNT
+}
 
 type articleTopic struct {
 	Title    string
@@ -821,13 +880,9 @@ func generateUsers(db *sql.DB) {
 				username = strings.ToLower(handle)
 
 				// 检查用户名是否已使用
-				usernameMutex.Lock()
-				if !usedUsernames[username] {
-					usedUsernames[username] = true
-					usernameMutex.Unlock()
+				if usedUsernames.reserve(username) {
 					break
 				}
-				usernameMutex.Unlock()
 			}
 
 			passwordHash := randomPasswordHash(rnd)
@@ -860,10 +915,7 @@ func generateUsers(db *sql.DB) {
 			if err != nil {
 				// 检查是否是重复键错误
 				if strings.Contains(err.Error(), "Duplicate entry") {
-					// 如果是重复键错误，标记该用户名为未使用并重新生成
-					usernameMutex.Lock()
-					delete(usedUsernames, username)
-					usernameMutex.Unlock()
+					// 用户名已存在于数据库（去重表已满或之前的数据），重新生成
 					continue
 				} else {
 					log.Fatalf("插入用户认证信息失败: %v", err)
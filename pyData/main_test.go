@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// globalUsernameMap 原先的实现：单个互斥锁保护的全局 map，记录全部用户名
+type globalUsernameMap struct {
+	mu    sync.Mutex
+	names map[string]bool
+}
+
+func (m *globalUsernameMap) reserve(username string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.names[username] {
+		return false
+	}
+	m.names[username] = true
+	return true
+}
+
+type usernameReserver interface {
+	reserve(username string) bool
+}
+
+var usernameReserverImpls = []struct {
+	name string
+	new  func(n int) usernameReserver
+}{
+	{"global_map", func(int) usernameReserver { return &globalUsernameMap{names: make(map[string]bool)} }},
+	{"sharded_set", func(n int) usernameReserver { return newUsernameSet(n) }},
+	{"no_cache", func(int) usernameReserver { return newUsernameSet(0) }},
+}
+
+// BenchmarkUsernameReserveParallel 比较并发预占用户名时全局 map 与分片去重表的锁竞争（每个CPU parallelism 个goroutine）
+func BenchmarkUsernameReserveParallel(b *testing.B) {
+	for _, parallelism := range []int{1, 16, 64} {
+		for _, impl := range usernameReserverImpls {
+			b.Run(fmt.Sprintf("%s/p=%d", impl.name, parallelism), func(b *testing.B) {
+				set := impl.new(b.N)
+				var goroutines atomic.Int64
+				b.SetParallelism(parallelism)
+				b.ReportAllocs()
+				b.ResetTimer()
+				b.RunParallel(func(pb *testing.PB) {
+					// 每个goroutine使用自己的前缀和计数，避免基准本身的共享计数器成为竞争点
+					prefix := "user" + strconv.FormatInt(goroutines.Add(1), 10) + "_"
+					for i := 0; pb.Next(); i++ {
+						set.reserve(prefix + strconv.Itoa(i))
+					}
+				})
+			})
+		}
+	}
+}
+
+// BenchmarkUsernameReserve 比较大量 worker 按生成器的方式生成用户名时全局 map 与分片去重表的吞吐
+func BenchmarkUsernameReserve(b *testing.B) {
+	for _, workers := range []int{64, 256, 1024} {
+		for _, impl := range usernameReserverImpls {
+			b.Run(fmt.Sprintf("%s/workers=%d", impl.name, workers), func(b *testing.B) {
+				set := impl.new(b.N)
+				b.ReportAllocs()
+				b.ResetTimer()
+				runWorkers(b.N, workers, func(idx int, rnd *rand.Rand) {
+					for {
+						handle := fmt.Sprintf("%s_%04d_%d", randomChoice(rnd, englishHandles), 1000+rnd.Intn(9000), idx)
+						if set.reserve(strings.ToLower(handle)) {
+							return
+						}
+					}
+				})
+			})
+		}
+	}
+}
+
+func TestUsernameSetCapsSize(t *testing.T) {
+	set := newUsernameSet(2)
+	if !set.reserve("a") || !set.reserve("b") {
+		t.Fatal("未满时应能预占新用户名")
+	}
+	if set.reserve("a") {
+		t.Fatal("已记录的用户名不能重复预占")
+	}
+	// 表已满：不再记录，交给数据库唯一约束兜底
+	if !set.reserve("c") || !set.reserve("c") {
+		t.Fatal("表已满时应直接放行")
+	}
+	if got := set.size.Load(); got != 2 {
+		t.Fatalf("记录数应不超过上限 2，实际 %d", got)
+	}
+}
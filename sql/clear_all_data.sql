@@ -21,17 +21,28 @@ SET FOREIGN_KEY_CHECKS = 0;
 TRUNCATE TABLE `user_auth`;
 TRUNCATE TABLE `user_profile`;
 TRUNCATE TABLE `password_reset_tokens`;
+TRUNCATE TABLE `user_api_tokens`;
+TRUNCATE TABLE `refresh_tokens`;
+TRUNCATE TABLE `notification_preferences`;
+TRUNCATE TABLE `notification_dnd`;
+TRUNCATE TABLE `user_blocks`;
+TRUNCATE TABLE `user_follows`;
+TRUNCATE TABLE `notifications`;
+TRUNCATE TABLE `content_ownership_transfers`;
+TRUNCATE TABLE `admin_audit_log`;
 
 -- =====================================================
 -- 第二部分：清空文章系统表
 -- =====================================================
 TRUNCATE TABLE `article_reports`;
 TRUNCATE TABLE `article_comment_likes`;
+TRUNCATE TABLE `comment_edit_history`;
 TRUNCATE TABLE `article_comments`;
 TRUNCATE TABLE `article_likes`;
 TRUNCATE TABLE `article_tag_relations`;
 TRUNCATE TABLE `article_category_relations`;
 TRUNCATE TABLE `article_code_blocks`;
+TRUNCATE TABLE `article_revisions`;
 TRUNCATE TABLE `articles`;
 TRUNCATE TABLE `article_tags`;
 TRUNCATE TABLE `article_categories`;
@@ -57,6 +68,7 @@ TRUNCATE TABLE `resource_comments`;
 TRUNCATE TABLE `resource_likes`;
 TRUNCATE TABLE `resource_tags`;
 TRUNCATE TABLE `resource_images`;
+TRUNCATE TABLE `resource_versions`;
 TRUNCATE TABLE `upload_chunks`;
 TRUNCATE TABLE `resources`;
 TRUNCATE TABLE `resource_categories`;
@@ -25,10 +25,12 @@ CREATE TABLE IF NOT EXISTS `user_auth` (
   `email` varchar(100) NOT NULL COMMENT '邮箱地址',
   `role` varchar(20) NOT NULL DEFAULT 'user' COMMENT '用户角色：admin-管理员，user-普通用户',
   `auth_status` tinyint(1) NOT NULL DEFAULT 0 COMMENT '认证状态：0-未认证，1-已认证',
-  `account_status` tinyint(1) NOT NULL DEFAULT 1 COMMENT '账户状态：0-禁用，1-正常，2-锁定',
+  `account_status` tinyint(1) NOT NULL DEFAULT 1 COMMENT '账户状态：0-禁用，1-正常，2-锁定，3-已注销',
   `last_login_time` datetime DEFAULT NULL COMMENT '最后登录时间',
   `last_login_ip` varchar(50) DEFAULT NULL COMMENT '最后登录IP',
   `failed_login_count` int(11) NOT NULL DEFAULT 0 COMMENT '连续登录失败次数',
+  `locked_until` datetime DEFAULT NULL COMMENT '登录锁定截止时间（连续失败过多时设置）',
+  `token_version` int(11) NOT NULL DEFAULT 0 COMMENT '登录token版本（递增后此前签发的token全部失效）',
   `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
   `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
   PRIMARY KEY (`id`),
@@ -74,6 +76,126 @@ CREATE TABLE IF NOT EXISTS `password_reset_tokens` (
   KEY `idx_expires_at` (`expires_at`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='密码重置token表';
 
+-- 37. 用户API令牌表（个人访问令牌）
+CREATE TABLE IF NOT EXISTS `user_api_tokens` (
+  `id` int(10) UNSIGNED NOT NULL AUTO_INCREMENT COMMENT '令牌ID',
+  `user_id` int(10) UNSIGNED NOT NULL COMMENT '所属用户ID',
+  `name` varchar(100) NOT NULL COMMENT '令牌名称',
+  `token_hash` char(64) NOT NULL COMMENT '令牌SHA-256哈希（明文只在创建时返回一次）',
+  `token_prefix` varchar(16) NOT NULL COMMENT '令牌明文前缀（用于识别）',
+  `scopes` varchar(255) NOT NULL DEFAULT '' COMMENT '权限范围（逗号分隔）',
+  `last_used_at` datetime DEFAULT NULL COMMENT '最后使用时间',
+  `expires_at` datetime DEFAULT NULL COMMENT '过期时间（NULL表示永不过期）',
+  `revoked_at` datetime DEFAULT NULL COMMENT '吊销时间',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_token_hash` (`token_hash`) COMMENT '令牌哈希唯一索引',
+  KEY `idx_user_revoked` (`user_id`, `revoked_at`) COMMENT '用户有效令牌索引'
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='用户API令牌表';
+
+-- 44. 刷新token表（轮换时旧token标记为已轮换，同一登录会话的token属于同一家族）
+CREATE TABLE IF NOT EXISTS `refresh_tokens` (
+  `id` BIGINT(20) NOT NULL AUTO_INCREMENT COMMENT '记录ID',
+  `user_id` int(10) UNSIGNED NOT NULL COMMENT '所属用户ID',
+  `token_hash` char(64) NOT NULL COMMENT 'token SHA-256哈希（不保存明文）',
+  `family_id` char(32) NOT NULL COMMENT 'token家族ID（同一次登录轮换出的token共享）',
+  `expires_at` datetime NOT NULL COMMENT '过期时间',
+  `rotated_at` datetime DEFAULT NULL COMMENT '轮换时间（非空表示已被新token替换）',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_token_hash` (`token_hash`) COMMENT 'token哈希唯一索引',
+  KEY `idx_family` (`family_id`) COMMENT '家族索引（用于整链吊销）',
+  KEY `idx_user` (`user_id`) COMMENT '用户索引'
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='刷新token表';
+
+-- 39. 用户通知偏好表（只保存修改过的事件类型，未保存的默认全部开启）
+CREATE TABLE IF NOT EXISTS `notification_preferences` (
+  `user_id` int(10) UNSIGNED NOT NULL COMMENT '用户ID',
+  `event_type` varchar(32) NOT NULL COMMENT '事件类型：comment/reply/mention/follow/like/new_content',
+  `in_app` TINYINT(1) NOT NULL DEFAULT 1 COMMENT '站内通知：0=关闭，1=开启',
+  `email` TINYINT(1) NOT NULL DEFAULT 1 COMMENT '邮件通知（预留）：0=关闭，1=开启',
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  PRIMARY KEY (`user_id`, `event_type`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='用户通知偏好表';
+
+-- 45. 通知免打扰表（免打扰期间不推送实时通知，通知仍写入收件箱；到期自动失效）
+CREATE TABLE IF NOT EXISTS `notification_dnd` (
+  `user_id` int(10) UNSIGNED NOT NULL COMMENT '用户ID',
+  `mute_until` datetime NOT NULL COMMENT '免打扰截止时间（UTC）',
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  PRIMARY KEY (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='通知免打扰表';
+
+-- 40. 用户屏蔽表（屏蔽者不再看到被屏蔽者的内容）
+CREATE TABLE IF NOT EXISTS `user_blocks` (
+  `blocker_id` int(10) UNSIGNED NOT NULL COMMENT '屏蔽者用户ID',
+  `blocked_id` int(10) UNSIGNED NOT NULL COMMENT '被屏蔽用户ID',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '屏蔽时间',
+  PRIMARY KEY (`blocker_id`, `blocked_id`),
+  KEY `idx_blocked_id` (`blocked_id`) COMMENT '被屏蔽用户索引'
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='用户屏蔽表';
+
+-- 41. 用户关注表
+CREATE TABLE IF NOT EXISTS `user_follows` (
+  `follower_id` int(10) UNSIGNED NOT NULL COMMENT '关注者用户ID',
+  `following_id` int(10) UNSIGNED NOT NULL COMMENT '被关注用户ID',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '关注时间',
+  PRIMARY KEY (`follower_id`, `following_id`),
+  KEY `idx_follower_created` (`follower_id`, `created_at`) COMMENT '关注列表索引',
+  KEY `idx_following_created` (`following_id`, `created_at`) COMMENT '粉丝列表索引'
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='用户关注表';
+
+-- 42. 站内通知表
+CREATE TABLE IF NOT EXISTS `notifications` (
+  `id` BIGINT(20) NOT NULL AUTO_INCREMENT COMMENT '通知ID',
+  `user_id` int(10) UNSIGNED NOT NULL COMMENT '接收者用户ID',
+  `actor_id` int(10) UNSIGNED NOT NULL COMMENT '触发者用户ID',
+  `event_type` VARCHAR(32) NOT NULL COMMENT '事件类型：comment/reply/message 等',
+  `target_type` VARCHAR(20) NOT NULL COMMENT '关联类型：article/resource/message',
+  `target_id` BIGINT(20) NOT NULL COMMENT '关联ID（私信为会话ID）',
+  `comment_id` BIGINT(20) NOT NULL DEFAULT 0 COMMENT '评论ID（非评论类通知为0）',
+  `content` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '内容摘要',
+  `is_read` TINYINT(1) NOT NULL DEFAULT 0 COMMENT '是否已读',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_user_read_created` (`user_id`, `is_read`, `created_at`) COMMENT '未读通知索引',
+  KEY `idx_user_created` (`user_id`, `created_at`) COMMENT '通知列表索引'
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='站内通知表';
+
+-- 46. 内容所有权转移表（接收者确认后修改文章/资源作者；记录保留作为审计日志）
+CREATE TABLE IF NOT EXISTS `content_ownership_transfers` (
+  `id` BIGINT(20) NOT NULL AUTO_INCREMENT COMMENT '转移ID',
+  `content_type` VARCHAR(20) NOT NULL COMMENT '内容类型：article/resource',
+  `content_id` BIGINT(20) NOT NULL COMMENT '内容ID',
+  `from_user_id` int(10) UNSIGNED NOT NULL COMMENT '原作者ID',
+  `to_user_id` int(10) UNSIGNED NOT NULL COMMENT '接收者ID',
+  `status` TINYINT(1) NOT NULL DEFAULT 0 COMMENT '状态：0-待确认，1-已接受，2-已拒绝，3-已取消',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '发起时间',
+  `responded_at` datetime DEFAULT NULL COMMENT '处理时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_content_status` (`content_type`, `content_id`, `status`),
+  KEY `idx_to_user_created` (`to_user_id`, `created_at`),
+  KEY `idx_from_user_created` (`from_user_id`, `created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='内容所有权转移表';
+
+-- 47. 管理员操作审计日志表
+CREATE TABLE IF NOT EXISTS `admin_audit_log` (
+  `id` BIGINT(20) NOT NULL AUTO_INCREMENT COMMENT '日志ID',
+  `actor_id` int(10) UNSIGNED NOT NULL COMMENT '操作的管理员ID',
+  `action` VARCHAR(64) NOT NULL COMMENT '操作类型：report.resolve/user.revoke_sessions 等',
+  `target_type` VARCHAR(32) NOT NULL COMMENT '目标类型：report/user 等',
+  `target_id` BIGINT(20) NOT NULL COMMENT '目标ID',
+  `before_data` JSON DEFAULT NULL COMMENT '操作前快照',
+  `after_data` JSON DEFAULT NULL COMMENT '操作后快照',
+  `ip_address` VARCHAR(45) NOT NULL DEFAULT '' COMMENT '操作IP',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '操作时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_actor_created` (`actor_id`, `created_at`),
+  KEY `idx_action_created` (`action`, `created_at`),
+  KEY `idx_target` (`target_type`, `target_id`),
+  KEY `idx_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='管理员操作审计日志表';
+
 -- =====================================================
 -- 第二部分：文章系统表
 -- =====================================================
@@ -89,6 +211,7 @@ CREATE TABLE IF NOT EXISTS `articles` (
   `view_count` INT(11) DEFAULT 0 COMMENT '浏览次数',
   `like_count` INT(11) DEFAULT 0 COMMENT '点赞数',
   `comment_count` INT(11) DEFAULT 0 COMMENT '评论数',
+  `version` INT(11) NOT NULL DEFAULT 1 COMMENT '编辑版本号（乐观锁，每次作者编辑后递增）',
   `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
   `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
   PRIMARY KEY (`id`),
@@ -98,6 +221,19 @@ CREATE TABLE IF NOT EXISTS `articles` (
   KEY `idx_hot` (`like_count`, `view_count`, `comment_count`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='文章表';
 
+-- 43. 文章修订历史表（每次修改标题/描述/正文前保存旧版本）
+CREATE TABLE IF NOT EXISTS `article_revisions` (
+  `id` BIGINT(20) NOT NULL AUTO_INCREMENT COMMENT '修订ID',
+  `article_id` BIGINT(20) NOT NULL COMMENT '文章ID',
+  `user_id` int(10) UNSIGNED NOT NULL COMMENT '修改者ID',
+  `title` VARCHAR(200) NOT NULL COMMENT '修改前的标题',
+  `description` VARCHAR(500) DEFAULT NULL COMMENT '修改前的描述',
+  `content` TEXT NOT NULL COMMENT '修改前的正文',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '修订时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_article_created` (`article_id`, `created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='文章修订历史表';
+
 -- 5. 文章代码块表
 CREATE TABLE IF NOT EXISTS `article_code_blocks` (
   `id` BIGINT(20) NOT NULL AUTO_INCREMENT COMMENT '代码块ID',
@@ -169,7 +305,8 @@ CREATE TABLE IF NOT EXISTS `article_likes` (
   `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '点赞时间',
   PRIMARY KEY (`id`),
   UNIQUE KEY `uk_article_user` (`article_id`, `user_id`),
-  KEY `idx_user_id` (`user_id`)
+  KEY `idx_user_id` (`user_id`),
+  KEY `idx_article_created` (`article_id`, `created_at`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='文章点赞表';
 
 -- 11. 文章评论表
@@ -184,6 +321,7 @@ CREATE TABLE IF NOT EXISTS `article_comments` (
   `like_count` INT(11) DEFAULT 0 COMMENT '点赞数',
   `reply_count` INT(11) DEFAULT 0 COMMENT '回复数',
   `status` TINYINT(1) DEFAULT 1 COMMENT '状态：0-已删除，1-正常，2-已折叠',
+  `is_edited` TINYINT(1) NOT NULL DEFAULT 0 COMMENT '是否编辑过',
   `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '评论时间',
   `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
   PRIMARY KEY (`id`),
@@ -194,6 +332,16 @@ CREATE TABLE IF NOT EXISTS `article_comments` (
   KEY `idx_created_at` (`created_at`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='文章评论表';
 
+-- 38. 评论编辑历史表（仅保留最近几次编辑前的内容）
+CREATE TABLE IF NOT EXISTS `comment_edit_history` (
+  `id` BIGINT(20) NOT NULL AUTO_INCREMENT,
+  `comment_id` BIGINT(20) NOT NULL COMMENT '评论ID',
+  `content` TEXT NOT NULL COMMENT '编辑前的内容',
+  `edited_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '编辑时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_comment_edited` (`comment_id`, `edited_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='评论编辑历史表';
+
 -- 12. 评论点赞表
 CREATE TABLE IF NOT EXISTS `article_comment_likes` (
   `id` BIGINT(20) NOT NULL AUTO_INCREMENT,
@@ -215,6 +363,7 @@ CREATE TABLE IF NOT EXISTS `article_reports` (
   `status` TINYINT(1) DEFAULT 0 COMMENT '状态：0-待处理，1-已处理，2-已驳回',
   `handler_id` int(10) UNSIGNED DEFAULT NULL COMMENT '处理人ID',
   `handler_note` VARCHAR(500) DEFAULT NULL COMMENT '处理备注',
+  `action` VARCHAR(20) DEFAULT NULL COMMENT '处理动作：dismiss/hide_content/delete_content',
   `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '举报时间',
   `handled_at` DATETIME DEFAULT NULL COMMENT '处理时间',
   PRIMARY KEY (`id`),
@@ -232,6 +381,7 @@ CREATE TABLE IF NOT EXISTS `article_reports` (
 CREATE TABLE IF NOT EXISTS `chat_messages` (
   `id` bigint(20) NOT NULL AUTO_INCREMENT COMMENT '消息ID',
   `user_id` int(10) UNSIGNED NOT NULL COMMENT '用户ID',
+  `client_msg_id` varchar(64) DEFAULT NULL COMMENT '客户端消息ID（重连重发时去重）',
   `username` varchar(50) NOT NULL COMMENT '用户名',
   `nickname` varchar(100) DEFAULT NULL COMMENT '用户昵称',
   `avatar` varchar(500) DEFAULT NULL COMMENT '用户头像URL',
@@ -242,6 +392,7 @@ CREATE TABLE IF NOT EXISTS `chat_messages` (
   `status` tinyint(1) DEFAULT 1 COMMENT '状态：0-已删除，1-正常',
   `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '记录创建时间',
   PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_chat_user_client_msg` (`user_id`, `client_msg_id`) COMMENT '同一用户的客户端消息ID唯一（NULL不参与）',
   KEY `idx_user_id` (`user_id`) COMMENT '用户ID索引',
   KEY `idx_send_time` (`send_time`) COMMENT '发送时间索引',
   KEY `idx_status` (`status`) COMMENT '状态索引'
@@ -273,6 +424,12 @@ CREATE TABLE IF NOT EXISTS `code_snippets` (
   `description` TEXT COMMENT '代码描述',
   `is_public` TINYINT(1) NOT NULL DEFAULT 0 COMMENT '是否公开: 0-私有, 1-公开',
   `share_token` VARCHAR(64) UNIQUE COMMENT '分享令牌（唯一）',
+  `share_expires_at` DATETIME DEFAULT NULL COMMENT '分享链接过期时间（NULL表示永不过期）',
+  `share_max_views` INT UNSIGNED DEFAULT NULL COMMENT '分享链接最多访问次数（NULL表示不限）',
+  `share_view_count` INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '当前分享链接的访问次数',
+  `execution_count` INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '公开期间的执行次数',
+  `fork_count` INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '被复制次数',
+  `forked_from` BIGINT UNSIGNED DEFAULT NULL COMMENT '复制来源代码片段ID',
   `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
   `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
   INDEX idx_user_id (user_id),
@@ -335,6 +492,7 @@ CREATE TABLE IF NOT EXISTS `resources` (
   `file_hash` varchar(64) NOT NULL DEFAULT '' COMMENT '文件内容哈希（用于去重）',
   `storage_path` varchar(500) NOT NULL COMMENT 'MinIO存储路径',
   `total_chunks` int(11) NOT NULL DEFAULT 0 COMMENT '分片总数（用于前端下载合并，0表示非分片文件）',
+  `current_version` int(11) NOT NULL DEFAULT 1 COMMENT '当前版本号（文件字段为该版本的文件）',
   `download_count` int(11) DEFAULT 0 COMMENT '下载次数',
   `view_count` int(11) DEFAULT 0 COMMENT '浏览次数',
   `like_count` int(11) DEFAULT 0 COMMENT '点赞数',
@@ -350,11 +508,32 @@ CREATE TABLE IF NOT EXISTS `resources` (
   KEY `idx_created_at` (`created_at`) COMMENT '创建时间索引'
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='资源文件表';
 
+-- 48. 资源版本表（每次重新上传新增一个版本，旧版本文件仍可下载）
+CREATE TABLE IF NOT EXISTS `resource_versions` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT COMMENT '版本ID',
+  `resource_id` bigint(20) NOT NULL COMMENT '资源ID',
+  `version_no` int(11) NOT NULL COMMENT '版本序号（从1递增）',
+  `version` varchar(50) NOT NULL COMMENT '版本名称（如 v1.2.3）',
+  `user_id` int(10) UNSIGNED NOT NULL COMMENT '上传者ID',
+  `file_name` varchar(255) NOT NULL COMMENT '文件原始名称',
+  `file_size` bigint(20) NOT NULL COMMENT '文件大小（字节）',
+  `file_type` varchar(100) DEFAULT NULL COMMENT '文件类型(MIME)',
+  `file_extension` varchar(20) DEFAULT NULL COMMENT '文件扩展名',
+  `file_hash` varchar(64) NOT NULL DEFAULT '' COMMENT '文件内容哈希',
+  `storage_path` varchar(500) NOT NULL COMMENT 'MinIO存储路径',
+  `total_chunks` int(11) NOT NULL DEFAULT 0 COMMENT '分片总数（0表示非分片文件）',
+  `notes` varchar(1000) DEFAULT NULL COMMENT '版本说明',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_resource_version` (`resource_id`, `version_no`) COMMENT '同一资源版本序号唯一'
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='资源版本表';
+
 -- 20. 资源图片表
 CREATE TABLE IF NOT EXISTS `resource_images` (
   `id` bigint(20) NOT NULL AUTO_INCREMENT COMMENT '图片ID',
   `resource_id` bigint(20) NOT NULL COMMENT '资源ID',
   `image_url` varchar(500) NOT NULL COMMENT '图片URL',
+  `thumbnail_url` varchar(500) DEFAULT NULL COMMENT '缩略图URL（原图较小时为空）',
   `image_order` int(11) DEFAULT 0 COMMENT '图片顺序',
   `is_cover` tinyint(1) DEFAULT 0 COMMENT '是否封面图：0-否，1-是',
   `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
@@ -396,7 +575,7 @@ CREATE TABLE IF NOT EXISTS `upload_chunks` (
   `total_chunks` int(11) NOT NULL COMMENT '总分片数',
   `uploaded_chunks` text COMMENT '已上传分片列表（JSON数组）',
   `storage_path` varchar(500) DEFAULT NULL COMMENT '合并后的存储路径',
-  `status` tinyint(1) DEFAULT 0 COMMENT '状态：0-上传中，1-已完成，2-已取消',
+  `status` tinyint(1) DEFAULT 0 COMMENT '状态：0-上传中，1-已完成，2-已取消，3-未通过安全扫描，4-合并校验中',
   `expires_at` datetime NOT NULL COMMENT '过期时间',
   `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
   `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
@@ -415,7 +594,8 @@ CREATE TABLE IF NOT EXISTS `resource_likes` (
   `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
   PRIMARY KEY (`id`),
   UNIQUE KEY `uk_resource_user` (`resource_id`, `user_id`) COMMENT '资源用户唯一索引',
-  KEY `idx_user` (`user_id`) COMMENT '用户索引'
+  KEY `idx_user` (`user_id`) COMMENT '用户索引',
+  KEY `idx_resource_created` (`resource_id`, `created_at`) COMMENT '点赞用户列表索引'
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='资源点赞表';
 
 -- 25. 资源评论表
@@ -616,6 +796,9 @@ CREATE TABLE IF NOT EXISTS `api_statistics` (
   `error_count` int(11) NOT NULL DEFAULT 0 COMMENT '失败请求数(4xx,5xx)',
   `total_count` int(11) NOT NULL DEFAULT 0 COMMENT '总请求数',
   `avg_latency_ms` decimal(10,2) NOT NULL DEFAULT 0.00 COMMENT '平均响应时间(毫秒)',
+  `p50_latency_ms` int(11) NOT NULL DEFAULT 0 COMMENT 'P50响应时间(毫秒，按分桶近似)',
+  `p95_latency_ms` int(11) NOT NULL DEFAULT 0 COMMENT 'P95响应时间(毫秒，按分桶近似)',
+  `p99_latency_ms` int(11) NOT NULL DEFAULT 0 COMMENT 'P99响应时间(毫秒，按分桶近似)',
   `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
   `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
   PRIMARY KEY (`id`),
@@ -654,6 +837,52 @@ END$$
 
 DELIMITER ;
 
+-- 为已存在的表补充新增字段（CREATE TABLE IF NOT EXISTS 不会修改旧表结构）
+DELIMITER $$
+
+DROP PROCEDURE IF EXISTS AddColumnIfNotExists$$
+CREATE PROCEDURE AddColumnIfNotExists(
+    IN tableName VARCHAR(128),
+    IN columnName VARCHAR(128),
+    IN columnDefinition VARCHAR(512)
+)
+BEGIN
+    DECLARE column_exists INT DEFAULT 0;
+    SELECT COUNT(1) INTO column_exists
+    FROM information_schema.columns
+    WHERE table_schema = DATABASE()
+    AND table_name = tableName
+    AND column_name = columnName;
+
+    IF column_exists = 0 THEN
+        SET @sql = CONCAT('ALTER TABLE ', tableName, ' ADD COLUMN ', columnName, ' ', columnDefinition);
+        PREPARE stmt FROM @sql;
+        EXECUTE stmt;
+        DEALLOCATE PREPARE stmt;
+    END IF;
+END$$
+
+DELIMITER ;
+
+CALL AddColumnIfNotExists('user_auth', 'locked_until', "DATETIME DEFAULT NULL COMMENT '登录锁定截止时间（连续失败过多时设置）' AFTER failed_login_count");
+CALL AddColumnIfNotExists('user_auth', 'token_version', "INT(11) NOT NULL DEFAULT 0 COMMENT '登录token版本（递增后此前签发的token全部失效）' AFTER locked_until");
+CALL AddColumnIfNotExists('article_comments', 'is_edited', "TINYINT(1) NOT NULL DEFAULT 0 COMMENT '是否编辑过' AFTER status");
+CALL AddColumnIfNotExists('article_reports', 'action', "VARCHAR(20) DEFAULT NULL COMMENT '处理动作：dismiss/hide_content/delete_content' AFTER handler_note");
+CALL AddColumnIfNotExists('api_statistics', 'p50_latency_ms', "INT(11) NOT NULL DEFAULT 0 COMMENT 'P50响应时间(毫秒，按分桶近似)' AFTER avg_latency_ms");
+CALL AddColumnIfNotExists('api_statistics', 'p95_latency_ms', "INT(11) NOT NULL DEFAULT 0 COMMENT 'P95响应时间(毫秒，按分桶近似)' AFTER p50_latency_ms");
+CALL AddColumnIfNotExists('api_statistics', 'p99_latency_ms', "INT(11) NOT NULL DEFAULT 0 COMMENT 'P99响应时间(毫秒，按分桶近似)' AFTER p95_latency_ms");
+-- 唯一索引随字段一起添加，字段已存在时不会重复执行
+CALL AddColumnIfNotExists('chat_messages', 'client_msg_id', "VARCHAR(64) DEFAULT NULL COMMENT '客户端消息ID（重连重发时去重）' AFTER user_id, ADD UNIQUE KEY uk_chat_user_client_msg (user_id, client_msg_id)");
+CALL AddColumnIfNotExists('code_snippets', 'share_expires_at', "DATETIME DEFAULT NULL COMMENT '分享链接过期时间（NULL表示永不过期）' AFTER share_token");
+CALL AddColumnIfNotExists('code_snippets', 'share_max_views', "INT UNSIGNED DEFAULT NULL COMMENT '分享链接最多访问次数（NULL表示不限）' AFTER share_expires_at");
+CALL AddColumnIfNotExists('code_snippets', 'share_view_count', "INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '当前分享链接的访问次数' AFTER share_max_views");
+CALL AddColumnIfNotExists('code_snippets', 'execution_count', "INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '公开期间的执行次数' AFTER share_view_count");
+CALL AddColumnIfNotExists('code_snippets', 'fork_count', "INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '被复制次数' AFTER execution_count");
+CALL AddColumnIfNotExists('code_snippets', 'forked_from', "BIGINT UNSIGNED DEFAULT NULL COMMENT '复制来源代码片段ID' AFTER fork_count");
+CALL AddColumnIfNotExists('resources', 'current_version', "INT(11) NOT NULL DEFAULT 1 COMMENT '当前版本号（文件字段为该版本的文件）' AFTER total_chunks");
+CALL AddColumnIfNotExists('articles', 'version', "INT(11) NOT NULL DEFAULT 1 COMMENT '编辑版本号（乐观锁，每次作者编辑后递增）' AFTER comment_count");
+CALL AddColumnIfNotExists('resource_images', 'thumbnail_url', "VARCHAR(500) DEFAULT NULL COMMENT '缩略图URL（原图较小时为空）' AFTER image_url");
+
 CALL CreateIndexIfNotExists('articles', 'idx_articles_status_created', 'status, created_at DESC');
 CALL CreateIndexIfNotExists('articles', 'idx_articles_likes_views', 'like_count DESC, view_count DESC, created_at DESC');
 CALL CreateIndexIfNotExists('articles', 'idx_articles_user_status_created', 'user_id, status, created_at DESC');
@@ -706,6 +935,7 @@ CALL CreateIndexIfNotExists('code_snippets', 'idx_code_snippets_public', 'is_pub
 CALL CreateIndexIfNotExists('code_snippets', 'idx_code_snippets_language_public', 'language, is_public, created_at DESC');
 
 CALL CreateIndexIfNotExists('code_executions', 'idx_code_executions_user_created', 'user_id, created_at DESC');
+CALL CreateIndexIfNotExists('code_snippets', 'idx_code_snippets_public_executions', 'is_public, execution_count DESC');
 CALL CreateIndexIfNotExists('code_executions', 'idx_code_executions_snippet', 'snippet_id, created_at DESC');
 
 -- 上传系统优化索引